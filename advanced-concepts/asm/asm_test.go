@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// asm - Tests
+// ===========
+// Run with:
+//
+//   cd advanced-concepts/asm
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// Every test builds testdata/bytesum in a sandbox. vet and the -S
+// listings cross-compile, so they run on any machine; running the
+// assembly needs an amd64 or arm64 one.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// newSandbox is a sandbox in the test's temp dir, for a test that runs
+// the go command
+func newSandbox(t *testing.T) *Sandbox {
+	t.Helper()
+	requireGo(t)
+	sb, err := NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sb
+}
+
+// 1. Builds
+// =========
+
+func TestBuildsAgree(t *testing.T) {
+	sb := newSandbox(t)
+	inputs := []string{"", "a", "hello", strings.Repeat("gopher", 20)}
+	var sums [][]string
+	for _, b := range []Build{native, purego} {
+		out, code, err := sb.Exec(t.Context(), b, append([]string{"sum"}, inputs...)...)
+		if err != nil || code != 0 {
+			t.Fatalf("%s: exit %d, %v\n%s", b, code, err, out)
+		}
+		want := "(pure Go)"
+		if hasAsm() && len(b) == 0 {
+			want = "(assembly)"
+		}
+		if !strings.Contains(out, want) {
+			t.Errorf("%s: not built as %s:\n%s", b, want, out)
+		}
+		var s []string
+		for line := range strings.Lines(out) {
+			s = append(s, strings.Fields(line)[0])
+		}
+		sums = append(sums, s)
+	}
+	if !slices.Equal(sums[0], sums[1]) {
+		t.Errorf("native %v\npurego %v", sums[0], sums[1])
+	}
+}
+
+func TestProgramTestsPass(t *testing.T) {
+	sb := newSandbox(t)
+	for _, b := range []Build{native, purego} {
+		if out, err := sb.Go(t.Context(), b, "test", "-bench", ".", "-benchtime", "10x", "."); err != nil {
+			t.Errorf("%s: %v\n%s", b, err, out)
+		}
+	}
+}
+
+// Every GOARCH builds: the assembly where there is some, the fallback
+// everywhere else
+func TestCrossBuilds(t *testing.T) {
+	sb := newSandbox(t)
+	for _, arch := range []string{"amd64", "arm64", "386", "riscv64"} {
+		out, err := sb.Go(t.Context(), Build{"GOOS=linux", "GOARCH=" + arch}, "list", "-f", "{{.GoFiles}} {{.SFiles}}", ".")
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", arch, err, out)
+		}
+		want := "[main.go sum.go sum_generic.go] []"
+		if arch == "amd64" || arch == "arm64" {
+			want = fmt.Sprintf("[main.go sum.go sum_asm.go] [sum_%s.s]", arch)
+		}
+		if got := strings.TrimSpace(out); got != want {
+			t.Errorf("%s: %s, want %s", arch, got, want)
+		}
+		if out, err := sb.Go(t.Context(), Build{"GOOS=linux", "GOARCH=" + arch}, "build", "-o", "bytesum.exe", "."); err != nil {
+			t.Errorf("%s: %v\n%s", arch, err, out)
+		}
+	}
+}
+
+// 2. go vet
+// =========
+
+func TestVetChecksFrameOffsets(t *testing.T) {
+	for _, arch := range []string{"amd64", "arm64"} {
+		sb := newSandbox(t)
+		b := Build{"GOARCH=" + arch}
+		if out, err := sb.Go(t.Context(), b, "vet", "."); err != nil {
+			t.Fatalf("%s: %v\n%s", arch, err, out)
+		}
+		if err := sb.Replace("sum_"+arch+".s", "b_len+8(FP)", "b_len+16(FP)"); err != nil {
+			t.Fatal(err)
+		}
+		out, err := sb.Go(t.Context(), b, "vet", ".")
+		if got := vetLine(out, err); !strings.Contains(got, "expected b_len+8(FP)") {
+			t.Errorf("%s: vet said %q", arch, got)
+		}
+	}
+}
+
+// 3. Intrinsics
+// =============
+
+func TestIntrinsics(t *testing.T) {
+	sb := newSandbox(t)
+	tests := []struct {
+		build Build
+		want  string
+		check bool // a CPU feature check before the instruction
+	}{
+		{Build{"GOARCH=amd64", "GOAMD64=v1"}, "POPCNTQ", true},
+		{Build{"GOARCH=amd64", "GOAMD64=v2"}, "POPCNTQ", false},
+		{Build{"GOARCH=arm64"}, "VCNT", false},
+	}
+	for _, tt := range tests {
+		out, err := sb.Go(t.Context(), tt.build, "build", "-gcflags=-S", "-o", "bytesum.exe", ".")
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", tt.build, err, out)
+		}
+		ins := strings.Join(instructions(out, "main.popcountBits", "POPCNT", "x86HasPOPCNT", "VCNT"), "\n")
+		if !strings.Contains(ins, tt.want) || strings.Contains(ins, "x86HasPOPCNT") != tt.check {
+			t.Errorf("%s:\n%s", tt.build, ins)
+		}
+	}
+}
+
+func TestInstructions(t *testing.T) {
+	listing := "main.f STEXT size=10\n" +
+		"\t0x0000 00000 (a.go:3)\tPOPCNTQ\tSI, SI\n" +
+		"\t0x0004 00004 (a.go:3)\tRET\n" +
+		"main.g STEXT size=10\n" +
+		"\t0x0000 00000 (a.go:7)\tPOPCNTQ\tAX, AX\n"
+	if got := instructions(listing, "main.f", "POPCNT"); !slices.Equal(got, []string{"POPCNTQ SI, SI"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+// 4. Examples
+// ===========
+
+func ExampleBuild() {
+	fmt.Println(purego)
+	fmt.Println(Build{"GOARCH=arm64"})
+	fmt.Println(native)
+	// Output:
+	// GOFLAGS=-tags=purego
+	// GOARCH=arm64
+	// (defaults)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Go Assembly and Intrinsics
+// ==========================
+// A function declared in Go without a body can be written in assembly,
+// in a file named for its architecture: sum_amd64.s, sum_arm64.s. The
+// assembler is Go's own, with one syntax for every target - operands
+// read source, then destination - and four pseudo-registers:
+//
+//	SB   static base: ·sumAsm(SB) is the symbol main.sumAsm
+//	FP   the arguments: b_base+0(FP), b_len+8(FP), ret+24(FP)
+//	SP   the local frame
+//	PC   the program counter, for jumps
+//
+// ABI basics:
+//
+//	TEXT ·sumAsm(SB), NOSPLIT, $0-32
+//	      |            |        |  `- bytes of arguments and results:
+//	      |            |        |     a slice is 24, the uint64 is 8
+//	      |            |        `---- bytes of locals
+//	      |            `------------- no stack-growth check: only for
+//	      |                           leaves with a small frame
+//	      `-------------------------- the Go declaration it implements
+//
+// Assembly uses ABI0: arguments and results on the stack, every
+// register the callee's to clobber. Go code uses ABIInternal, which
+// passes them in registers and keeps the goroutine in R14 and zero in
+// X15 on amd64. The linker puts a small wrapper between the two, so
+// assembly never has to know about ABIInternal.
+//
+// What assembly costs:
+//
+//	no inlining                   every call pays the call; short
+//	                              inputs may lose to Go
+//	no escape analysis            without //go:noescape every pointer
+//	                              argument escapes to the heap
+//	a file per architecture       and a pure-Go fallback for the rest,
+//	                              selectable with -tags purego
+//	offsets written by hand       go vet's asmdecl check compares them
+//	                              with the Go declaration
+//
+// Often the compiler already has the instruction: math/bits, math and
+// sync/atomic functions are intrinsics, replaced by one instruction
+// where the CPU has it. Reach for assembly for what the compiler does
+// not do - here, SIMD: summing 16 or 32 bytes per loop.
+//
+// Run with:
+//
+//	cd advanced-concepts/asm
+//	go run main.go sandbox.go
+//	go test -v *.go
+//
+// This directory has no assembly of its own: the lesson builds
+// testdata/bytesum.
+
+var (
+	native = Build{}
+	purego = Build{"GOFLAGS=-tags=purego"}
+)
+
+// hasAsm reports whether the machine running the lesson gets the
+// assembly build
+func hasAsm() bool { return runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64" }
+
+func main() {
+	fmt.Println("=== Go Assembly and Intrinsics ===")
+	ctx := context.Background()
+	work, err := os.MkdirTemp("", "asm-")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(work)
+	sb, err := NewSandbox(work)
+	if err != nil {
+		fail(err)
+	}
+
+	// 1. One function, three builds
+	threeBuilds(ctx, sb)
+
+	// 2. What the assembly buys
+	if hasAsm() {
+		speedup(ctx, sb)
+	} else {
+		fmt.Printf("\n2. No assembly for %s: the benchmarks would race Go against Go.\n", runtime.GOARCH)
+	}
+
+	// 3. The ABI, checked by go vet
+	vetted(ctx, work)
+
+	// 4. Intrinsics
+	intrinsics(ctx, sb)
+}
+
+// 1. One Function, Three Builds
+// =============================
+func threeBuilds(ctx context.Context, sb *Sandbox) {
+	fmt.Println("\n1. ONE FUNCTION, THREE BUILDS:")
+	for _, b := range []Build{native, purego} {
+		out, code, err := sb.Exec(ctx, b, "sum", "hello", "gopher")
+		if err != nil || code != 0 {
+			fmt.Printf("   %s: exit %d, %v\n%s", b, code, err, indent(out))
+			continue
+		}
+		fmt.Printf("   $ %s go build && ./bytesum sum hello gopher\n%s", b, indent(out))
+	}
+
+	// Which files each architecture compiles
+	format := `GoFiles=[{{join .GoFiles " "}}] SFiles=[{{join .SFiles " "}}]`
+	for _, arch := range []string{"amd64", "arm64", "riscv64"} {
+		b := Build{"GOARCH=" + arch}
+		out, err := sb.Go(ctx, b, "list", "-f", format, ".")
+		if err != nil {
+			out = err.Error() + "\n"
+		}
+		fmt.Printf("   %-14s %s", b, out)
+	}
+	fmt.Println("   The _amd64.s suffix is a build constraint; riscv64 gets sum_generic.go.")
+}
+
+// 2. What the Assembly Buys
+// =========================
+func speedup(ctx context.Context, sb *Sandbox) {
+	fmt.Println("\n2. WHAT THE ASSEMBLY BUYS (go test -bench in testdata/bytesum):")
+	out, err := sb.Go(ctx, native, "test", "-run", "^$", "-bench", ".", "-benchtime", "200ms", ".")
+	if err != nil {
+		fmt.Printf("   %v\n%s", err, indent(out))
+		return
+	}
+	for line := range strings.Lines(out) {
+		if strings.HasPrefix(line, "Benchmark") {
+			fmt.Print("   " + line)
+		}
+	}
+	fmt.Println("   At 15 bytes the call and the byte-at-a-time tail are most of the work;")
+	fmt.Println("   from 1 KiB on, 16 bytes per instruction beat one per iteration by 10x+.")
+	fmt.Println("   The popcount pair needs no assembly: bits.OnesCount64 is an intrinsic.")
+}
+
+// 3. The ABI, Checked by go vet
+// =============================
+func vetted(ctx context.Context, work string) {
+	fmt.Println("\n3. THE ABI, CHECKED BY GO VET:")
+
+	// vet reads the assembly for any GOARCH; nothing has to run
+	for _, arch := range []string{"amd64", "arm64"} {
+		broken, err := os.MkdirTemp(work, arch+"-")
+		if err != nil {
+			fmt.Println("  ", err)
+			return
+		}
+		sb, err := NewSandbox(broken)
+		if err != nil {
+			fmt.Println("  ", err)
+			return
+		}
+		b := Build{"GOARCH=" + arch}
+		if out, err := sb.Go(ctx, b, "vet", "."); err != nil {
+			fmt.Printf("   %s: %v\n%s", b, err, indent(out))
+			continue
+		}
+		fmt.Printf("   %-14s go vet: ok\n", b)
+
+		// The result one word too early: it would overwrite b's length
+		// in the caller's frame, and the program would still build
+		if err := sb.Replace("sum_"+arch+".s", "ret+24(FP)", "ret+16(FP)"); err != nil {
+			fmt.Println("  ", err)
+			return
+		}
+		out, err := sb.Go(ctx, b, "vet", ".")
+		fmt.Printf("   %-14s ret+16(FP): %s\n", b, vetLine(out, err))
+	}
+	fmt.Println("   Run go vet on every GOARCH you ship assembly for.")
+}
+
+// vetLine returns the first diagnostic vet printed
+func vetLine(out string, err error) string {
+	if err == nil {
+		return "no complaint"
+	}
+	for line := range strings.Lines(out) {
+		if strings.Contains(line, ".s:") {
+			_, msg, _ := strings.Cut(strings.TrimSpace(line), ": ")
+			return msg
+		}
+	}
+	return err.Error()
+}
+
+// 4. Intrinsics: The Compiler's Own Assembly
+// ==========================================
+func intrinsics(ctx context.Context, sb *Sandbox) {
+	fmt.Println("\n4. INTRINSICS: THE COMPILER'S OWN ASSEMBLY:")
+	fmt.Println("   bits.OnesCount64 in popcountBits, from go build -gcflags=-S:")
+	for _, b := range []Build{
+		{"GOARCH=amd64", "GOAMD64=v1"},
+		{"GOARCH=amd64", "GOAMD64=v2"},
+		{"GOARCH=arm64"},
+	} {
+		out, err := sb.Go(ctx, b, "build", "-gcflags=-S", "-o", os.DevNull, ".")
+		if err != nil {
+			fmt.Printf("   %s: %v\n", b, err)
+			continue
+		}
+		fmt.Printf("   %s\n", b)
+		for _, ins := range instructions(out, "main.popcountBits", "POPCNT", "x86HasPOPCNT", "VCNT") {
+			fmt.Printf("      %s\n", ins)
+		}
+	}
+	fmt.Println("   GOAMD64=v1 checks the CPU before POPCNTQ; v2 guarantees it. arm64 has no")
+	fmt.Println("   scalar popcount, so the compiler counts in a NEON register.")
+}
+
+// instructions returns the instructions of fn in a -S listing whose
+// text contains one of the words, the listing's address and source
+// position stripped
+func instructions(listing, fn string, words ...string) []string {
+	var out []string
+	in := false
+	for line := range strings.Lines(listing) {
+		if strings.Contains(line, " STEXT") {
+			in = strings.HasPrefix(line, fn+" ")
+			continue
+		}
+		if !in || !strings.HasPrefix(line, "\t0x") {
+			continue
+		}
+		// "\t0x0040 00064 (/tmp/.../sum.go:33)\tPOPCNTQ\tSI, SI"
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, w := range words {
+			if strings.Contains(fields[1], w) {
+				out = append(out, strings.Join(strings.Fields(fields[1]), " "))
+				break
+			}
+		}
+	}
+	return out
+}
+
+func indent(s string) string {
+	var b strings.Builder
+	for line := range strings.Lines(s) {
+		b.WriteString("   " + line)
+	}
+	return b.String()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
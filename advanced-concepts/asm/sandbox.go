@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Building the Assembly
+// =====================
+// go run takes .go files only, and testdata/bytesum has .s files and a
+// file per build, so it only builds as a package. Sandbox copies it
+// into a temp module and runs the go command there with the
+// environment a Build names - GOARCH, GOAMD64, or GOFLAGS=-tags=purego.
+//
+// Build, Sandbox, Go and Exec are copies of cgo/sandbox.go's, with the
+// program renamed and Replace added: without a module the two lessons
+// cannot share a package.
+
+// programDir is the program this lesson builds
+const programDir = "testdata/bytesum"
+
+// Build is the environment of one build. Anything it leaves out keeps
+// the go command's default.
+type Build []string
+
+func (b Build) String() string {
+	if len(b) == 0 {
+		return "(defaults)"
+	}
+	return strings.Join(b, " ")
+}
+
+// Sandbox is a temp module holding a copy of the program
+type Sandbox struct {
+	Dir string
+}
+
+// NewSandbox copies programDir into dir and adds a go.mod
+func NewSandbox(dir string) (*Sandbox, error) {
+	entries, err := os.ReadDir(programDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(programDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	gomod := "module example.com/bytesum\n\ngo 1.24\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		return nil, err
+	}
+	return &Sandbox{Dir: dir}, nil
+}
+
+// Replace edits one file of the sandbox's copy, to break it on purpose
+func (s *Sandbox) Replace(name, old, new string) error {
+	path := filepath.Join(s.Dir, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(data), old) {
+		return fmt.Errorf("%s: no %q", name, old)
+	}
+	return os.WriteFile(path, []byte(strings.Replace(string(data), old, new, 1)), 0o644)
+}
+
+// Go runs a go subcommand in the sandbox; the output is stdout and
+// stderr together
+func (s *Sandbox) Go(ctx context.Context, b Build, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = s.Dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "GOPROXY=off", "GOTOOLCHAIN=local")
+	cmd.Env = append(cmd.Env, b...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// Exec builds the program and runs it with args, returning its own
+// exit code
+func (s *Sandbox) Exec(ctx context.Context, b Build, args ...string) (string, int, error) {
+	bin := filepath.Join(s.Dir, "bytesum.exe")
+	if out, err := s.Go(ctx, b, "build", "-o", bin, "."); err != nil {
+		return out, 0, err
+	}
+	out, err := exec.CommandContext(ctx, bin, args...).CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return string(out), exit.ExitCode(), nil
+	}
+	return string(out), 0, err
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// A Byte Sum, in Assembly Where It Can Be
+// =======================================
+// One program, three ways to build it:
+//
+//	sum_asm.go      amd64 || arm64, !purego   Sum calls sumAsm
+//	sum_amd64.s     SSE2, 32 bytes a loop
+//	sum_arm64.s     NEON, 16 bytes a loop
+//	sum_generic.go  any other GOARCH, or -tags purego: Sum is sumGo
+//	sum.go          sumGo and the popcount pair, in every build
+//
+// Usage:
+//
+//	bytesum sum text...    the byte sum of each argument, and who computed it
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "sum" {
+		fmt.Fprintln(os.Stderr, "usage: bytesum sum text...")
+		os.Exit(2)
+	}
+	for _, s := range os.Args[2:] {
+		fmt.Printf("%6d  %q  (%s)\n", Sum([]byte(s)), s, impl)
+	}
+}
@@ -0,0 +1,68 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// func sumAsm(b []byte) uint64
+//
+// ABI0: the arguments and the result live in the caller's frame, named
+// by offsets from the FP pseudo-register - b_base+0, b_len+8, b_cap+16
+// and ret+24. $0-32 is the frame size (no locals) and the size of the
+// arguments plus results.
+//
+// PSADBW sums the absolute differences of 16 bytes against zero: two
+// 64-bit lanes, each the sum of 8 bytes. SSE2 is part of every amd64
+// CPU, so no feature check is needed.
+TEXT ·sumAsm(SB), NOSPLIT, $0-32
+	MOVQ  b_base+0(FP), SI
+	MOVQ  b_len+8(FP), CX
+	PXOR  X0, X0           // zero, to diff against
+	PXOR  X1, X1           // two running sums
+	PXOR  X3, X3           // two more, so the loop has two chains
+	XORQ  AX, AX
+
+	CMPQ  CX, $32
+	JB    blocks16
+
+loop32:
+	MOVOU (SI), X2
+	MOVOU 16(SI), X4
+	PSADBW X0, X2
+	PSADBW X0, X4
+	PADDQ X2, X1
+	PADDQ X4, X3
+	ADDQ  $32, SI
+	SUBQ  $32, CX
+	CMPQ  CX, $32
+	JAE   loop32
+
+blocks16:
+	CMPQ  CX, $16
+	JB    fold
+	MOVOU (SI), X2
+	PSADBW X0, X2
+	PADDQ X2, X1
+	ADDQ  $16, SI
+	SUBQ  $16, CX
+
+fold:
+	// Add the four lanes into AX
+	PADDQ X3, X1
+	MOVQ  X1, AX
+	PSRLDQ $8, X1
+	MOVQ  X1, DX
+	ADDQ  DX, AX
+
+	TESTQ CX, CX
+	JZ    done
+
+tail:
+	// Fewer than 16 bytes: one at a time
+	MOVBQZX (SI), DX
+	ADDQ  DX, AX
+	INCQ  SI
+	DECQ  CX
+	JNZ   tail
+
+done:
+	MOVQ  AX, ret+24(FP)
+	RET
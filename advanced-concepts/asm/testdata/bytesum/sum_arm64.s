@@ -0,0 +1,40 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// func sumAsm(b []byte) uint64
+//
+// The same frame as on amd64: b_base+0, b_len+8, b_cap+16, ret+24. R0
+// to R3 are scratch; ZR reads as zero.
+//
+// VUADDLV adds the 16 bytes of a vector register into one 16-bit
+// lane (at most 16*255 = 4080, no overflow), and VMOV moves that lane
+// to a general register.
+TEXT ·sumAsm(SB), NOSPLIT, $0-32
+	MOVD b_base+0(FP), R0
+	MOVD b_len+8(FP), R1
+	MOVD ZR, R2
+
+	CMP  $16, R1
+	BLT  tail
+
+loop16:
+	VLD1.P 16(R0), [V0.B16]
+	VUADDLV V0.B16, V1
+	VMOV V1.H[0], R3
+	ADD  R3, R2
+	SUB  $16, R1
+	CMP  $16, R1
+	BGE  loop16
+
+tail:
+	// Fewer than 16 bytes: one at a time
+	CBZ  R1, done
+	MOVBU.P 1(R0), R3
+	ADD  R3, R2
+	SUB  $1, R1
+	B    tail
+
+done:
+	MOVD R2, ret+24(FP)
+	RET
@@ -0,0 +1,19 @@
+//go:build (amd64 || arm64) && !purego
+
+package main
+
+// On amd64 and arm64 Sum is assembly: sum_amd64.s and sum_arm64.s.
+// The file name picks the architecture; the purego tag, a convention
+// golang.org/x/crypto uses too, switches the assembly off.
+
+const impl = "assembly"
+
+// sumAsm is implemented in sum_$GOARCH.s. It has no body here, only
+// the signature go vet checks the assembly against. noescape promises
+// the assembly keeps no pointer to b, so b may stay on the stack.
+//
+//go:noescape
+func sumAsm(b []byte) uint64
+
+// Sum adds up the bytes of b
+func Sum(b []byte) uint64 { return sumAsm(b) }
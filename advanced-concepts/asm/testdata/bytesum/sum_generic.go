@@ -0,0 +1,12 @@
+//go:build (!amd64 && !arm64) || purego
+
+package main
+
+// Every other architecture, and any build with -tags purego, gets the
+// Go loop. Without this file a GOARCH=riscv64 build fails with
+// "missing function body".
+
+const impl = "pure Go"
+
+// Sum adds up the bytes of b
+func Sum(b []byte) uint64 { return sumGo(b) }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+	"testing"
+)
+
+// bytesum - Tests
+// ===============
+// Run by the lesson, in a temp module, in each build:
+//
+//   go test -bench . .
+//   go test -tags purego -bench . .
+//
+// The assembly must agree with sumGo at every length around its block
+// sizes and at every alignment; the benchmarks measure the speedup.
+
+func TestSumMatchesGo(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	buf := make([]byte, 300)
+	for i := range buf {
+		buf[i] = byte(r.Uint32())
+	}
+	for off := range 16 {
+		for n := 0; off+n <= len(buf); n++ {
+			b := buf[off : off+n]
+			if got, want := Sum(b), sumGo(b); got != want {
+				t.Fatalf("%s: Sum(buf[%d:%d]) = %d, want %d", impl, off, off+n, got, want)
+			}
+		}
+	}
+}
+
+// All 0xff is the largest sum a block can hold: no lane may overflow
+func TestSumSaturated(t *testing.T) {
+	b := make([]byte, 1<<20+7)
+	for i := range b {
+		b[i] = 0xff
+	}
+	if got, want := Sum(b), uint64(len(b))*255; got != want {
+		t.Errorf("%s: Sum = %d, want %d", impl, got, want)
+	}
+}
+
+func TestPopcount(t *testing.T) {
+	for _, s := range []string{"", "a", "\xff\xff\xff\xff\xff\xff\xff\xff\x01", "the quick brown fox jumps"} {
+		want := 0
+		for _, c := range []byte(s) {
+			want += bits.OnesCount8(c)
+		}
+		if got := popcountLoop([]byte(s)); got != want {
+			t.Errorf("popcountLoop(%q) = %d, want %d", s, got, want)
+		}
+		if got := popcountBits([]byte(s)); got != want {
+			t.Errorf("popcountBits(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func FuzzSum(f *testing.F) {
+	f.Add([]byte("hello, gopher"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if got, want := Sum(b), sumGo(b); got != want {
+			t.Errorf("Sum = %d, want %d", got, want)
+		}
+	})
+}
+
+func BenchmarkSum(b *testing.B) {
+	for _, n := range []int{15, 1 << 10, 64 << 10} {
+		data := make([]byte, n)
+		b.Run(fmt.Sprintf("go/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for b.Loop() {
+				sumGo(data)
+			}
+		})
+		b.Run(fmt.Sprintf("sum/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for b.Loop() {
+				Sum(data)
+			}
+		})
+	}
+}
+
+func BenchmarkPopcount(b *testing.B) {
+	data := make([]byte, 64<<10)
+	for i := range data {
+		data[i] = byte(i * 37)
+	}
+	b.Run("loop", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			popcountLoop(data)
+		}
+	})
+	b.Run("bits", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			popcountBits(data)
+		}
+	})
+}
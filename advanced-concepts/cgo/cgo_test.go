@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// cgo - Tests
+// ===========
+// Run with:
+//
+//   cd advanced-concepts/cgo
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// Every test builds testdata/fnv in a sandbox. The ones that need cgo
+// skip themselves without a C compiler; the pure-Go build must work
+// regardless.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// newSandbox is a sandbox in the test's temp dir, for a test that runs
+// the go command
+func newSandbox(t *testing.T) *Sandbox {
+	t.Helper()
+	requireGo(t)
+	sb, err := NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sb
+}
+
+func requireCC(t *testing.T) {
+	t.Helper()
+	if err := HaveCC(t.Context()); err != nil {
+		t.Skip("no C compiler:", err)
+	}
+}
+
+// 1. Both Builds
+// ==============
+
+func TestBothBuildsAgree(t *testing.T) {
+	sb := newSandbox(t)
+	requireCC(t)
+	inputs := []string{"", "a", "hello", "héllo, 世界"}
+	sums := map[string][]string{}
+	for _, b := range []Build{withCgo, withoutCgo} {
+		out, code, err := sb.Exec(t.Context(), b, append([]string{"sum"}, inputs...)...)
+		if err != nil || code != 0 {
+			t.Fatalf("%s: exit %d, %v\n%s", b, code, err, out)
+		}
+		for line := range strings.Lines(out) {
+			sums[b.String()] = append(sums[b.String()], strings.Fields(line)[0])
+		}
+		want := map[string]string{withCgo.String(): "(C, through cgo)", withoutCgo.String(): "(pure Go)"}[b.String()]
+		if !strings.Contains(out, want) {
+			t.Errorf("%s: not built as %s:\n%s", b, want, out)
+		}
+	}
+	if c, g := strings.Join(sums[withCgo.String()], " "), strings.Join(sums[withoutCgo.String()], " "); c != g {
+		t.Errorf("cgo %s\npure %s", c, g)
+	}
+}
+
+func TestProgramTestsPassInBothBuilds(t *testing.T) {
+	sb := newSandbox(t)
+	builds := []Build{withoutCgo}
+	if HaveCC(t.Context()) == nil {
+		builds = append(builds, withCgo)
+	}
+	for _, b := range builds {
+		if out, err := sb.Go(t.Context(), b, "test", "-bench", ".", "-benchtime", "10x", "."); err != nil {
+			t.Errorf("%s: %v\n%s", b, err, out)
+		}
+	}
+}
+
+// 2. Pointer Rules
+// ================
+
+func TestAllowedRulesRun(t *testing.T) {
+	sb := newSandbox(t)
+	requireCC(t)
+	out, code, err := sb.Exec(t.Context(), withCgo, "rules")
+	if err != nil || code != 0 {
+		t.Fatalf("exit %d, %v\n%s", code, err, out)
+	}
+	for _, name := range []string{"slice", "pinned", "cmemory", "cstring", "handle"} {
+		if !strings.Contains(out, name+" ") {
+			t.Errorf("%s missing:\n%s", name, out)
+		}
+	}
+	if !strings.Contains(out, "C called back [0 1 2]") {
+		t.Errorf("the callback did not run:\n%s", out)
+	}
+}
+
+func TestGoPointerInGoMemoryPanics(t *testing.T) {
+	sb := newSandbox(t)
+	requireCC(t)
+	out, code, err := sb.Exec(t.Context(), withCgo, "rules", "struct")
+	if err != nil || code != 2 || !strings.Contains(out, "Go pointer to unpinned Go pointer") {
+		t.Errorf("exit %d, %v\n%s", code, err, out)
+	}
+}
+
+func TestRulesNeedCgo(t *testing.T) {
+	sb := newSandbox(t)
+	out, code, err := sb.Exec(t.Context(), withoutCgo, "rules")
+	if err != nil || code != 3 || !strings.Contains(out, "built without cgo") {
+		t.Errorf("exit %d, %v\n%s", code, err, out)
+	}
+}
+
+// 3. No C Compiler
+// ================
+
+func TestNoCompiler(t *testing.T) {
+	sb := newSandbox(t)
+	if out, err := sb.Go(t.Context(), Build{"CGO_ENABLED=1", "CC=no-such-cc"}, "build", "-o", "fnv.exe", "."); err == nil || !strings.Contains(out, `C compiler "no-such-cc" not found`) {
+		t.Errorf("cgo without a compiler built: %v\n%s", err, out)
+	}
+	out, code, err := sb.Exec(t.Context(), Build{"CGO_ENABLED=0", "CC=no-such-cc"}, "sum", "hello")
+	if err != nil || code != 0 || !strings.Contains(out, "4f9f2cab") {
+		t.Errorf("the fallback: exit %d, %v\n%s", code, err, out)
+	}
+}
+
+func TestCrossCompilingPicksTheFallback(t *testing.T) {
+	sb := newSandbox(t)
+	cross := "windows"
+	if runtime.GOOS == "windows" {
+		cross = "linux"
+	}
+	out, err := sb.Go(t.Context(), Build{"GOOS=" + cross}, "list", "-f", "{{.CgoFiles}} {{.GoFiles}} {{.CFiles}}", ".")
+	if err != nil || strings.TrimSpace(out) != "[] [cgo_off.go fnv.go main.go] []" {
+		t.Errorf("%v: %s", err, out)
+	}
+}
+
+// 4. Examples
+// ===========
+
+func ExampleBuild() {
+	fmt.Println(withCgo)
+	fmt.Println(Build{"CGO_ENABLED=1", "CC=clang"})
+	fmt.Println(Build{})
+	// Output:
+	// CGO_ENABLED=1
+	// CGO_ENABLED=1 CC=clang
+	// (defaults)
+}
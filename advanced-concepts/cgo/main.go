@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// cgo: Calling C From Go
+// ======================
+// A Go file that imports "C" is processed by cgo: the comment right
+// above the import is C code, and everything it declares is reachable
+// as C.name - functions, types (C.size_t, C.buffer), constants:
+//
+//	C.fnv1a(p, n)              a call into C
+//	C.CString(s), C.CBytes(b)  copy Go data into C memory (malloc)
+//	C.GoString(p), C.GoBytes   copy C data into Go memory
+//	C.free(unsafe.Pointer(p))  C memory is never garbage collected
+//	//export goVisit           a Go function C can call
+//
+// Every call crosses between two worlds: goroutine stack to C stack,
+// the scheduler told this thread is in C, no inlining, no escape
+// analysis across the line. That costs tens of nanoseconds - as much
+// as hashing a few dozen bytes - so cgo pays off for large pieces of
+// work, and a hot loop should call C once with a batch, not per item.
+//
+// testdata/fnv computes FNV-1a in C, with a pure-Go twin chosen by
+// //go:build !cgo, so the program builds everywhere. This lesson runs
+// both builds, the pointer-passing demos, the benchmarks, and a build
+// with no C compiler at all.
+//
+// When cgo is on:
+//
+//	CGO_ENABLED=1         the default, when the C compiler in $CC (or
+//	                      gcc/clang) is installed
+//	CGO_ENABLED=0         the default when none is found, and when
+//	                      cross-compiling; then "cgo" is a false tag
+//
+// Pitfalls:
+//
+//	C keeping a Go pointer        not detected; the GC may move or free
+//	                              it. Copy to C memory or use a handle
+//	a Go pointer inside what      panics at run time: "argument of cgo
+//	you pass                      function has Go pointer to unpinned
+//	                              Go pointer"
+//	C.CString without C.free      a leak the Go heap profile cannot see
+//	definitions in a preamble     "multiple definition" at link time;
+//	with //export                 put them in a .c file
+//	a .c file without //go:build  CGO_ENABLED=0 refuses the package
+//	cgo                           instead of using the fallback
+//	cgo in a static container     the binary links libc dynamically;
+//	                              CGO_ENABLED=0 builds a static one
+//
+// Run with:
+//
+//	cd advanced-concepts/cgo
+//	go run main.go sandbox.go
+//	go test -v *.go
+//
+// This directory has no cgo of its own: the lesson builds testdata/fnv.
+
+var (
+	withCgo    = Build{"CGO_ENABLED=1"}
+	withoutCgo = Build{"CGO_ENABLED=0"}
+)
+
+func main() {
+	fmt.Println("=== cgo: Calling C From Go ===")
+	ctx := context.Background()
+	work, err := os.MkdirTemp("", "cgo-")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(work)
+	sb, err := NewSandbox(work)
+	if err != nil {
+		fail(err)
+	}
+
+	cc := HaveCC(ctx)
+	if cc != nil {
+		fmt.Printf("\nNo C compiler (%v): only the pure-Go build can run.\n", cc)
+	}
+	twoBuilds(ctx, sb, cc == nil)
+	if cc == nil {
+		pointerRules(ctx, sb)
+		callCost(ctx, sb)
+	}
+	withoutACompiler(ctx, sb)
+}
+
+// 1. One Program, Two Builds
+// ==========================
+func twoBuilds(ctx context.Context, sb *Sandbox, cgo bool) {
+	fmt.Println("\n1. ONE PROGRAM, TWO BUILDS:")
+	builds := []Build{withoutCgo}
+	if cgo {
+		builds = []Build{withCgo, withoutCgo}
+	}
+	for _, b := range builds {
+		out, code, err := sb.Exec(ctx, b, "sum", "hello", "gopher")
+		if err != nil || code != 0 {
+			fmt.Printf("   %s: exit %d, %v\n%s", b, code, err, indent(out))
+			continue
+		}
+		fmt.Printf("   $ %s go build && ./fnv sum hello gopher\n%s", b, indent(out))
+	}
+	fmt.Println("   Same answers: the fallback is a build choice, invisible to callers.")
+}
+
+// 2. The Pointer-Passing Rules
+// ============================
+func pointerRules(ctx context.Context, sb *Sandbox) {
+	fmt.Println("\n2. THE POINTER-PASSING RULES:")
+	out, _, err := sb.Exec(ctx, withCgo, "rules")
+	if err != nil {
+		fmt.Printf("   %v\n%s", err, indent(out))
+		return
+	}
+	fmt.Print(indent(out))
+
+	fmt.Println("\n   $ ./fnv rules struct    (a C struct in Go memory, pointing at Go memory)")
+	out, code, err := sb.Exec(ctx, withCgo, "rules", "struct")
+	if err != nil {
+		fmt.Printf("   %v\n", err)
+		return
+	}
+	first, _, _ := strings.Cut(out, "\n")
+	fmt.Printf("   %s\n   exit status %d\n", first, code)
+	fmt.Println("   cgocheck inspects every argument before the call; it cannot see what C")
+	fmt.Println("   keeps afterwards, so that rule is yours to follow.")
+}
+
+// 3. What a Call Costs
+// ====================
+func callCost(ctx context.Context, sb *Sandbox) {
+	fmt.Println("\n3. WHAT A CALL COSTS (go test -bench in testdata/fnv):")
+	out, err := sb.Go(ctx, withCgo, "test", "-run", "^$", "-bench", ".", "-benchtime", "200ms", ".")
+	if err != nil {
+		fmt.Printf("   %v\n%s", err, indent(out))
+		return
+	}
+	for line := range strings.Lines(out) {
+		if strings.HasPrefix(line, "Benchmark") {
+			fmt.Print("   " + line)
+		}
+	}
+	fmt.Println("   Call/c is pure overhead. Sum/8 pays it for 8 bytes of work; at 64 KiB it")
+	fmt.Println("   disappears in the loop - and C's loop is no faster than Go's.")
+}
+
+// 4. Without a C Compiler
+// =======================
+func withoutACompiler(ctx context.Context, sb *Sandbox) {
+	fmt.Println("\n4. WITHOUT A C COMPILER:")
+	for _, b := range []Build{
+		{"CGO_ENABLED=1", "CC=no-such-cc"},
+		{"CGO_ENABLED=0", "CC=no-such-cc"},
+	} {
+		out, code, err := sb.Exec(ctx, b, "sum", "hello")
+		status := strings.TrimSpace(out)
+		if err != nil {
+			status = "build fails: " + lastLine(out)
+		} else if code != 0 {
+			status = fmt.Sprintf("exit %d", code)
+		}
+		fmt.Printf("   %-30s %s\n", b, status)
+	}
+	cross := "windows"
+	if runtime.GOOS == "windows" {
+		cross = "linux"
+	}
+	b := Build{"GOOS=" + cross}
+	out, err := sb.Go(ctx, b, "list", "-f", "CgoFiles=[{{join .CgoFiles \" \"}}] GoFiles=[{{join .GoFiles \" \"}}]", ".")
+	if err != nil {
+		out = err.Error()
+	}
+	fmt.Printf("   %-30s %s", b, out)
+	fmt.Println("   Cross-compiling turns cgo off unless CC is set for the target, so the")
+	fmt.Println("   fallback is also what makes GOOS=... go build just work.")
+}
+
+func indent(s string) string {
+	var b strings.Builder
+	for line := range strings.Lines(s) {
+		b.WriteString("   " + line)
+	}
+	return b.String()
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
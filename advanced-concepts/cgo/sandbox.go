@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Building With and Without cgo
+// =============================
+// testdata/fnv has a file per build (cgo_on.go, cgo_off.go), so it only
+// builds as a package: go run *.go would compile both. Sandbox copies
+// it - .c and .h files included - into a temp module and runs the go
+// command there with the environment a Build names.
+// asm/sandbox.go has a copy; a fix here belongs there too.
+
+// programDir is the program this lesson builds
+const programDir = "testdata/fnv"
+
+// Build is the environment of one build: CGO_ENABLED, CC, GOOS...
+// Anything it leaves out keeps the go command's default.
+type Build []string
+
+func (b Build) String() string {
+	if len(b) == 0 {
+		return "(defaults)"
+	}
+	return strings.Join(b, " ")
+}
+
+// Sandbox is a temp module holding a copy of the program
+type Sandbox struct {
+	Dir string
+}
+
+// NewSandbox copies programDir into dir and adds a go.mod
+func NewSandbox(dir string) (*Sandbox, error) {
+	entries, err := os.ReadDir(programDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(programDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	gomod := "module example.com/fnv\n\ngo 1.24\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		return nil, err
+	}
+	return &Sandbox{Dir: dir}, nil
+}
+
+// Go runs a go subcommand in the sandbox; the output is stdout and
+// stderr together
+func (s *Sandbox) Go(ctx context.Context, b Build, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = s.Dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "GOPROXY=off", "GOTOOLCHAIN=local")
+	cmd.Env = append(cmd.Env, b...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// Exec builds the program and runs it with args, returning its own
+// exit code
+func (s *Sandbox) Exec(ctx context.Context, b Build, args ...string) (string, int, error) {
+	bin := filepath.Join(s.Dir, "fnv.exe")
+	if out, err := s.Go(ctx, b, "build", "-o", bin, "."); err != nil {
+		return out, 0, err
+	}
+	out, err := exec.CommandContext(ctx, bin, args...).CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return string(out), exit.ExitCode(), nil
+	}
+	return string(out), 0, err
+}
+
+// HaveCC reports whether the C compiler the go command would use is
+// installed - the condition for CGO_ENABLED to default to 1
+func HaveCC(ctx context.Context) error {
+	out, err := exec.CommandContext(ctx, "go", "env", "CC").Output()
+	if err != nil {
+		return fmt.Errorf("go env CC: %w", err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return errors.New("go env CC is empty")
+	}
+	_, err = exec.LookPath(fields[0])
+	return err
+}
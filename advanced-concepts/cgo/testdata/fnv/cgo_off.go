@@ -0,0 +1,18 @@
+//go:build !cgo
+
+package main
+
+// Without cgo the program still builds and gives the same answers;
+// only the pointer demos are missing. This file is what makes
+// CGO_ENABLED=0 a supported build, and a cross-compile that needs no C
+// toolchain.
+
+const impl = "pure Go"
+
+// Sum hashes b with FNV-1a
+func Sum(b []byte) uint32 { return fnvGo(b) }
+
+// cNoop stands in for the C call; the benchmark skips itself
+func cNoop() {}
+
+var rules map[string]func() string
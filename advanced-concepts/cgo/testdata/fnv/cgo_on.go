@@ -0,0 +1,99 @@
+//go:build cgo
+
+package main
+
+/*
+#include <stdlib.h>
+#include "fnv.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// The comment right above import "C" is the preamble: C code cgo
+// compiles and exposes as C.name. The rules for pointers that cross
+// the boundary (https://pkg.go.dev/cmd/cgo#hdr-Passing_pointers):
+//
+//	Go memory to C        allowed for the duration of the call, if
+//	                      the memory holds no unpinned Go pointers
+//	C keeping it          never: C may not store a Go pointer after
+//	                      the call returns
+//	pinned Go memory      runtime.Pinner lifts the first rule for
+//	                      the pointers it pins, until Unpin
+//	C memory              C.malloc, C.CString, C.CBytes: C's to
+//	                      keep, and yours to C.free
+//	Go values through C   cgo.NewHandle gives an integer that C can
+//	                      hold and hand back
+//
+// The first rule is checked at run time (GODEBUG=cgocheck=1, the
+// default) and panics; the second is not checked at all.
+
+const impl = "C, through cgo"
+
+// Sum hashes b with fnv1a in C. &b[0] is Go memory with no pointers in
+// it, so passing it is allowed; C must not keep it.
+func Sum(b []byte) uint32 {
+	if len(b) == 0 {
+		return uint32(C.fnv1a(nil, 0))
+	}
+	return uint32(C.fnv1a((*C.uint8_t)(unsafe.Pointer(&b[0])), C.size_t(len(b))))
+}
+
+// cNoop crosses into C and back
+func cNoop() { C.noop() }
+
+var rules = map[string]func() string{
+	"slice": func() string {
+		b := []byte("hello")
+		return fmt.Sprintf("%08x: a []byte's backing array, passed for one call", Sum(b))
+	},
+	"struct": func() string {
+		// buf is Go memory, and buf.data a Go pointer inside it: the
+		// runtime panics before C runs
+		b := []byte("hello")
+		buf := C.buffer{data: (*C.uint8_t)(unsafe.Pointer(&b[0])), len: C.size_t(len(b))}
+		return fmt.Sprintf("%08x: not reached", uint32(C.fnv1a_buffer(&buf)))
+	},
+	"pinned": func() string {
+		b := []byte("hello")
+		var pin runtime.Pinner
+		pin.Pin(&b[0])
+		defer pin.Unpin()
+		buf := C.buffer{data: (*C.uint8_t)(unsafe.Pointer(&b[0])), len: C.size_t(len(b))}
+		return fmt.Sprintf("%08x: the same struct, with its pointer pinned", uint32(C.fnv1a_buffer(&buf)))
+	},
+	"cmemory": func() string {
+		buf := (*C.buffer)(C.malloc(C.sizeof_buffer))
+		defer C.free(unsafe.Pointer(buf))
+		data := C.CBytes([]byte("hello"))
+		defer C.free(data)
+		buf.data, buf.len = (*C.uint8_t)(data), 5
+		return fmt.Sprintf("%08x: struct and bytes copied to C memory, freed by hand", uint32(C.fnv1a_buffer(buf)))
+	},
+	"cstring": func() string {
+		s := C.CString("hello")
+		defer C.free(unsafe.Pointer(s))
+		return fmt.Sprintf("%08x: C.CString copies, adds a NUL, and must be freed; C.GoString(s) = %q",
+			uint32(C.fnv1a((*C.uint8_t)(unsafe.Pointer(s)), 5)), C.GoString(s))
+	},
+	"handle": func() string {
+		var seen []int
+		h := cgo.NewHandle(func(i int) { seen = append(seen, i) })
+		defer h.Delete()
+		C.visit(C.uintptr_t(h), 3)
+		return fmt.Sprintf("C called back %v: a Go func passed as a cgo.Handle", seen)
+	},
+}
+
+// goVisit is called from visit in fnv.c. //export makes it a C symbol,
+// declared for C in _cgo_export.h.
+//
+//export goVisit
+func goVisit(h C.uintptr_t, i C.int) {
+	cgo.Handle(h).Value().(func(int))(int(i))
+}
@@ -0,0 +1,29 @@
+//go:build cgo
+
+// Compiled by cgo along with the Go files. Build constraints apply to
+// .c files too: without this line, CGO_ENABLED=0 refuses the package
+// ("C source files not allowed when not using cgo").
+
+#include "fnv.h"
+#include "_cgo_export.h"
+
+uint32_t fnv1a(const uint8_t *p, size_t n) {
+	uint32_t h = 2166136261u;
+	for (size_t i = 0; i < n; i++) {
+		h ^= p[i];
+		h *= 16777619u;
+	}
+	return h;
+}
+
+void noop(void) {}
+
+uint32_t fnv1a_buffer(const buffer *b) {
+	return fnv1a(b->data, b->len);
+}
+
+void visit(uintptr_t handle, int n) {
+	for (int i = 0; i < n; i++) {
+		goVisit(handle, i);
+	}
+}
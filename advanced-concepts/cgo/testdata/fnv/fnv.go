@@ -0,0 +1,17 @@
+package main
+
+// fnvGo is fnv1a from fnv.c, line for line. Both builds have it: the
+// pure-Go build uses it as Sum, and the benchmarks race it against C.
+func fnvGo(b []byte) uint32 {
+	h := uint32(2166136261)
+	for _, c := range b {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	return h
+}
+
+// goNoop is the Go side of the empty-call benchmark
+//
+//go:noinline
+func goNoop() {}
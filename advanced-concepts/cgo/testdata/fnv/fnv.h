@@ -0,0 +1,30 @@
+// fnv.h - the C side of the cgo lesson. Only declarations: a Go file
+// with //export may not define anything in its preamble, so the
+// definitions live in fnv.c. The guard is needed because
+// _cgo_export.h repeats the preamble, includes and all.
+
+#ifndef FNV_H
+#define FNV_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+// fnv1a hashes n bytes at p with 32-bit FNV-1a
+uint32_t fnv1a(const uint8_t *p, size_t n);
+
+// noop does nothing: calling it measures the cost of crossing into C
+void noop(void);
+
+// buffer is a C struct holding a pointer - passing one that points into
+// Go memory is what the pointer-passing rules forbid
+typedef struct {
+	const uint8_t *data;
+	size_t len;
+} buffer;
+
+uint32_t fnv1a_buffer(const buffer *b);
+
+// visit calls back into Go n times with an opaque handle
+void visit(uintptr_t handle, int n);
+
+#endif
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"testing"
+)
+
+// FNV - Tests
+// ===========
+// Run by the lesson, in a temp module, once per build:
+//
+//   CGO_ENABLED=1 go test -bench . .
+//   CGO_ENABLED=0 go test -bench . .
+//
+// Both builds must agree with hash/fnv. The benchmarks show what a cgo
+// call costs: nothing useful happens in BenchmarkCall, so the C row is
+// all overhead.
+
+func TestSumMatchesHashFNV(t *testing.T) {
+	for _, s := range []string{"", "a", "hello", "the quick brown fox", string(make([]byte, 4096))} {
+		h := fnv.New32a()
+		h.Write([]byte(s))
+		if got, want := Sum([]byte(s)), h.Sum32(); got != want {
+			t.Errorf("%s: Sum(%.10q) = %08x, want %08x", impl, s, got, want)
+		}
+		if got, want := fnvGo([]byte(s)), h.Sum32(); got != want {
+			t.Errorf("fnvGo(%.10q) = %08x, want %08x", s, got, want)
+		}
+	}
+}
+
+func TestAllowedRules(t *testing.T) {
+	for name, rule := range rules {
+		if name == "struct" {
+			continue
+		}
+		t.Logf("%s: %s", name, rule())
+	}
+}
+
+func BenchmarkCall(b *testing.B) {
+	b.Run("go", func(b *testing.B) {
+		for b.Loop() {
+			goNoop()
+		}
+	})
+	b.Run("c", func(b *testing.B) {
+		if rules == nil {
+			b.Skip("built without cgo")
+		}
+		for b.Loop() {
+			cNoop()
+		}
+	})
+}
+
+func BenchmarkSum(b *testing.B) {
+	for _, n := range []int{8, 1 << 10, 64 << 10} {
+		data := make([]byte, n)
+		b.Run(fmt.Sprintf("go/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for b.Loop() {
+				fnvGo(data)
+			}
+		})
+		b.Run(fmt.Sprintf("sum/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for b.Loop() {
+				Sum(data)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// FNV-1a, in C When It Can Be
+// ===========================
+// One program, two builds. With cgo, Sum calls fnv1a in fnv.c; without
+// it - CGO_ENABLED=0, or no C compiler - the same Sum is pure Go:
+//
+//	cgo_on.go    //go:build cgo    import "C", the pointer rules
+//	fnv.c        //go:build cgo    the C definitions
+//	cgo_off.go   //go:build !cgo   Sum is fnvGo; no rules to show
+//	fnv.go                         fnvGo, in both builds
+//
+// Usage:
+//
+//	fnv sum text...    the hash of each argument, and who computed it
+//	fnv rules          the pointer-passing demos that are allowed
+//	fnv rules NAME     one demo; "struct" breaks a rule and panics
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "sum":
+		for _, s := range os.Args[2:] {
+			fmt.Printf("%08x  %q  (%s)\n", Sum([]byte(s)), s, impl)
+		}
+	case "rules":
+		if len(rules) == 0 {
+			fmt.Println("built without cgo: no pointers cross into C")
+			os.Exit(3)
+		}
+		names := os.Args[2:]
+		if len(names) == 0 {
+			names = slices.DeleteFunc(slices.Sorted(maps.Keys(rules)), func(n string) bool { return n == "struct" })
+		}
+		for _, name := range names {
+			rule, ok := rules[name]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown rule %q (known: %s)\n", name, strings.Join(slices.Sorted(maps.Keys(rules)), ", "))
+				os.Exit(2)
+			}
+			fmt.Printf("%-8s %s\n", name, rule())
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: fnv sum text... | fnv rules [name...]")
+	os.Exit(2)
+}
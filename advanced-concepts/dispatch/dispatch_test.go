@@ -0,0 +1,172 @@
+package main
+
+import (
+	"hash/fnv"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// dispatch - Tests
+// ================
+// Run with:
+//
+//   cd advanced-concepts/dispatch
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//   go test -run '^$' -bench . *.go
+//
+// The compiler tests pin the claims main.go prints: if a Go release
+// starts devirtualizing the interface form, or stenciling generics per
+// type, they fail and the lesson needs rewriting.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// 1. The Forms Agree
+// ==================
+
+func TestFormsAgree(t *testing.T) {
+	for _, data := range []string{"", "a", "gopher", strings.Repeat("dispatch", 100)} {
+		h := fnv.New64a()
+		h.Write([]byte(data))
+		for _, f := range forms {
+			if got := f.fn([]byte(data)); got != h.Sum64() {
+				t.Errorf("%s(%.10q) = %#x, want %#x", f.name, data, got, h.Sum64())
+			}
+		}
+		var want uint64
+		for _, b := range []byte(data) {
+			want += uint64(b)
+		}
+		for _, f := range sums {
+			if got := f.fn([]byte(data)); got != want {
+				t.Errorf("%s(%.10q) = %d, want %d", f.name, data, got, want)
+			}
+		}
+	}
+}
+
+func TestNoAllocations(t *testing.T) {
+	data := []byte("gopher")
+	for _, f := range append(forms, sums...) {
+		if n := testing.AllocsPerRun(100, func() { hashSink = f.fn(data) }); n != 0 {
+			t.Errorf("%s: %.0f allocs, want 0", f.name, n)
+		}
+	}
+}
+
+// 2. What the Compiler Decided
+// ============================
+
+func TestDecisions(t *testing.T) {
+	requireGo(t)
+	out, err := compile(t.Context(), "-m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byFunc, err := Decisions(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	has := func(fn, msg string) bool {
+		return slices.ContainsFunc(byFunc[fn], func(d string) bool { return strings.HasPrefix(d, msg) })
+	}
+	tests := []struct {
+		fn, msg string
+		want    bool
+	}{
+		{"hashConcrete", "inlining call to FNV.Mix", true},
+		{"hashDevirtualized", "devirtualizing m.Mix to FNV", true},
+		{"hashDevirtualized", "inlining call to FNV.Mix", true},
+		{"hashInterface", "inlining call to", false},
+		{"hashGeneric", "inlining call to mixGeneric[go.shape.struct {}]", true},
+		{"hashGeneric", "devirtualizing", false},
+		{"hashGeneric", "inlining call to FNV.Mix", false},
+	}
+	for _, tt := range tests {
+		if has(tt.fn, tt.msg) != tt.want {
+			t.Errorf("%s: %q reported = %t, want %t; got %q", tt.fn, tt.msg, !tt.want, tt.want, byFunc[tt.fn])
+		}
+	}
+}
+
+func TestDecisionsByFunction(t *testing.T) {
+	out := "# command-line-arguments\n" +
+		"./kernels.go:31:6: can inline FNV.Mix\n" +
+		"./kernels.go:84:62: devirtualizing m.Mix to FNV\n" +
+		"./main.go:12:3: inlining call to fmt.Println\n"
+	byFunc, err := Decisions(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byFunc) != 1 || !slices.Equal(byFunc["hashDevirtualized"], []string{"devirtualizing m.Mix to FNV"}) {
+		t.Errorf("got %q", byFunc)
+	}
+}
+
+// 3. What the Loop Calls
+// ======================
+
+func TestLoopCalls(t *testing.T) {
+	requireGo(t)
+	out, err := compile(t.Context(), "-S")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range []string{"hashConcrete", "hashDevirtualized", "sumConcrete"} {
+		if calls := Calls(out, "main."+fn); len(calls) != 0 {
+			t.Errorf("%s calls %q, want none", fn, calls)
+		}
+	}
+	// An indirect call names a register, not a symbol
+	for _, fn := range []string{"mix", "hashGeneric", "sumGeneric"} {
+		calls := Calls(out, "main."+fn)
+		if len(calls) != 1 || strings.Contains(calls[0], ".") {
+			t.Errorf("%s calls %q, want one indirect CALL", fn, calls)
+		}
+	}
+}
+
+func TestCalls(t *testing.T) {
+	listing := "main.f STEXT size=100\n" +
+		"\t0x0034 00052 (k.go:82)\tCALL\tmain.mix(SB)\n" +
+		"\t0x0040 00064 (k.go:73)\tCALL\tCX\n" +
+		"\t0x0050 00080 (k.go:80)\tCALL\truntime.morestack_noctxt(SB)\n" +
+		"main.g STEXT size=10\n" +
+		"\t0x0000 00000 (k.go:90)\tCALL\tmain.h(SB)\n"
+	if got := Calls(listing, "main.f"); !slices.Equal(got, []string{"CALL main.mix", "CALL CX"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+// 4. Benchmarks
+// =============
+
+func benchmark(b *testing.B, group []form) {
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, f := range group {
+		b.Run(f.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				hashSink = f.fn(data)
+			}
+		})
+	}
+}
+
+func BenchmarkHash(b *testing.B) { benchmark(b, forms) }
+
+func BenchmarkSum(b *testing.B) { benchmark(b, sums) }
@@ -0,0 +1,100 @@
+package main
+
+// The Algorithm, Four Ways
+// ========================
+// FNV-1a over a byte slice: the hash lives in a local and a Mixer folds
+// in one byte at a time. The loop is the same in every form; only what
+// it knows about Mix changes:
+//
+//	mixFNV       FNV                  a static call the compiler inlines
+//	mix          Mixer                an itab call per byte
+//	mixInline    Mixer, inlined       the caller passes FNV: devirtualized
+//	mixGeneric   [M Mixer]            one body per GC shape; Mix through
+//	                                  the dictionary
+//
+// The compiler's view of this file is what sections 2 and 3 of main.go
+// print, so the call sites live here too.
+
+// Mixer folds one byte into a running hash
+type Mixer interface {
+	Mix(h uint64, b byte) uint64
+}
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// FNV is the 64-bit FNV-1a step
+type FNV struct{}
+
+func (FNV) Mix(h uint64, b byte) uint64 { return (h ^ uint64(b)) * fnvPrime }
+
+// Sum adds the bytes: the cheapest step there is, so what is left of
+// its time is the call. Sum and FNV have the same GC shape, struct{},
+// and share mixGeneric's code
+type Sum struct{}
+
+func (Sum) Mix(h uint64, b byte) uint64 { return h + uint64(b) }
+
+func mixFNV(m FNV, h uint64, data []byte) uint64 {
+	for _, b := range data {
+		h = m.Mix(h, b)
+	}
+	return h
+}
+
+// mix stands for any function the compiler does not inline: one too big
+// for the budget, or called through a function value. Inside it, m is
+// only an itab and a data word
+//
+//go:noinline
+func mix(m Mixer, h uint64, data []byte) uint64 {
+	for _, b := range data {
+		h = m.Mix(h, b)
+	}
+	return h
+}
+
+// mixInline is mix's twin, small enough to inline. At a call site that
+// passes an FNV, the inlined copy calls Mix on a known type
+func mixInline(m Mixer, h uint64, data []byte) uint64 {
+	for _, b := range data {
+		h = m.Mix(h, b)
+	}
+	return h
+}
+
+// mixGeneric is compiled once per GC shape, not once per type argument:
+// the body sees go.shape.struct {} and finds Mix in a dictionary passed
+// alongside
+func mixGeneric[M Mixer](m M, h uint64, data []byte) uint64 {
+	for _, b := range data {
+		h = m.Mix(h, b)
+	}
+	return h
+}
+
+// The call sites: each hashes data
+
+func hashConcrete(data []byte) uint64 { return mixFNV(FNV{}, fnvOffset, data) }
+
+func hashInterface(data []byte) uint64 { return mix(FNV{}, fnvOffset, data) }
+
+func hashDevirtualized(data []byte) uint64 { return mixInline(FNV{}, fnvOffset, data) }
+
+func hashGeneric(data []byte) uint64 { return mixGeneric(FNV{}, fnvOffset, data) }
+
+// The same forms with Sum, for the cost of the call alone
+
+func sumConcrete(data []byte) uint64 {
+	var h uint64
+	for _, b := range data {
+		h = Sum{}.Mix(h, b)
+	}
+	return h
+}
+
+func sumInterface(data []byte) uint64 { return mix(Sum{}, 0, data) }
+
+func sumGeneric(data []byte) uint64 { return mixGeneric(Sum{}, 0, data) }
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"unsafe"
+)
+
+// Go Interface Internals - iface, eface, itab and the Nil Trap
+// ============================================================
+// This file demonstrates what an interface value looks like in memory,
+// why a nil pointer stored in an interface is not a nil interface, and
+// what dynamic dispatch costs compared with a direct call
+
+// eface mirrors the runtime layout of an empty interface (interface{}):
+// a pointer to the dynamic type and a pointer to the data
+type eface struct {
+	typ  unsafe.Pointer
+	data unsafe.Pointer
+}
+
+// iface mirrors a non-empty interface: the first word points to an itab,
+// which holds the dynamic type plus the method table for this pairing
+// of interface and concrete type
+type iface struct {
+	tab  unsafe.Pointer
+	data unsafe.Pointer
+}
+
+// MyError is a pointer-receiver error type used for the nil trap
+type MyError struct {
+	Code int
+}
+
+func (e *MyError) Error() string { return fmt.Sprintf("code %d", e.Code) }
+
+// Shape is used for the dispatch benchmark
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+var areaSink float64
+
+func main() {
+	fmt.Println("=== Go Interface Internals ===")
+
+	// 1. Interfaces are two words
+	interfaceSize()
+
+	// 2. Looking inside with unsafe
+	interfaceHeaders()
+
+	// 3. The typed nil trap
+	typedNilTrap()
+
+	// 4. Avoiding the trap
+	avoidingTheTrap()
+
+	// 5. Dynamic dispatch cost
+	dispatchCost()
+}
+
+// 1. Interfaces Are Two Words
+// ===========================
+func interfaceSize() {
+	fmt.Println("\n1. INTERFACES ARE TWO WORDS:")
+
+	var e interface{}
+	var s Shape
+	fmt.Printf("   unsafe.Sizeof(interface{}): %d bytes\n", unsafe.Sizeof(e))
+	fmt.Printf("   unsafe.Sizeof(Shape): %d bytes\n", unsafe.Sizeof(s))
+	fmt.Println("   eface = (type, data) for interface{} / any")
+	fmt.Println("   iface = (itab, data) for interfaces with methods")
+	fmt.Println("   itab  = (interface type, concrete type, method pointers...)")
+}
+
+// 2. Looking Inside with unsafe
+// =============================
+func interfaceHeaders() {
+	fmt.Println("\n2. INTERFACE HEADERS:")
+
+	var nilAny interface{}
+	h := (*eface)(unsafe.Pointer(&nilAny))
+	fmt.Printf("   nil interface{}:        type=%p data=%p\n", h.typ, h.data)
+
+	var a interface{} = 42
+	h = (*eface)(unsafe.Pointer(&a))
+	fmt.Printf("   interface{}(42):        type=%p data=%p -> %d\n", h.typ, h.data, *(*int)(h.data))
+
+	var b interface{} = 7
+	hb := (*eface)(unsafe.Pointer(&b))
+	fmt.Printf("   same dynamic type shares the type pointer: %t\n", h.typ == hb.typ)
+
+	var c interface{} = "hello"
+	hc := (*eface)(unsafe.Pointer(&c))
+	fmt.Printf("   interface{}(\"hello\"):   type=%p (different type, different pointer)\n", hc.typ)
+
+	// Values that don't fit in a pointer are boxed: data points to a copy
+	sq := Square{Side: 3}
+	var shape Shape = sq
+	hs := (*iface)(unsafe.Pointer(&shape))
+	boxed := (*Square)(hs.data)
+	fmt.Printf("   Shape(Square{3}):       itab=%p data=%p -> %+v\n", hs.tab, hs.data, *boxed)
+	sq.Side = 10
+	fmt.Printf("   changing sq afterwards leaves the boxed copy alone: %+v\n", *boxed)
+
+	// With a pointer inside, the data word IS the pointer
+	ptr := &Square{Side: 4}
+	var shapePtr Shape = ptr
+	hp := (*iface)(unsafe.Pointer(&shapePtr))
+	fmt.Printf("   Shape(&Square{4}):      data == ptr: %t\n", hp.data == unsafe.Pointer(ptr))
+	fmt.Println("   (these layouts are runtime internals - never rely on them in real code)")
+}
+
+// 3. The Typed Nil Trap
+// =====================
+func typedNilTrap() {
+	fmt.Println("\n3. THE TYPED NIL TRAP:")
+
+	err := mayFail(false)
+	fmt.Printf("   mayFail(false) returned %v\n", err)
+	fmt.Printf("   err == nil: %t  <-- surprise!\n", err == nil)
+
+	h := (*iface)(unsafe.Pointer(&err))
+	fmt.Printf("   header: itab=%p (set: type *MyError) data=%p (nil pointer)\n", h.tab, h.data)
+	fmt.Println("   An interface is nil only when BOTH words are nil")
+
+	// The same thing happens with any interface, not just error
+	var sq *Square
+	var shape Shape = sq
+	fmt.Printf("   Shape holding (*Square)(nil) == nil: %t\n", shape == nil)
+
+	// Area has a value receiver, so calling it dereferences the nil pointer
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("   shape.Area() panicked: %v\n", r)
+			}
+		}()
+		shape.Area()
+	}()
+}
+
+// mayFail has the classic bug: it returns a typed nil pointer as error
+func mayFail(fail bool) error {
+	var e *MyError
+	if fail {
+		e = &MyError{Code: 500}
+	}
+	return e // BUG: wraps (*MyError)(nil) in a non-nil interface
+}
+
+// 4. Avoiding the Trap
+// ====================
+func avoidingTheTrap() {
+	fmt.Println("\n4. AVOIDING THE TRAP:")
+
+	err := mayFailFixed(false)
+	fmt.Printf("   fixed version: err == nil: %t\n", err == nil)
+
+	err = mayFailFixed(true)
+	var myErr *MyError
+	fmt.Printf("   errors.As still finds the concrete type: %t (code %d)\n", errors.As(err, &myErr), myErr.Code)
+
+	// Detecting a typed nil when you must (rarely needed)
+	trapped := mayFail(false)
+	fmt.Printf("   isNilValue(trapped): %t\n", isNilValue(trapped))
+
+	fmt.Println("   Rules of thumb:")
+	fmt.Println("   - return the literal nil for the error case, not a typed variable")
+	fmt.Println("   - declare results as error, not *MyError, in exported functions")
+	fmt.Println("   - static analyzers such as nilness catch some of these")
+}
+
+func mayFailFixed(fail bool) error {
+	if fail {
+		return &MyError{Code: 500}
+	}
+	return nil
+}
+
+// isNilValue reports whether an interface holds a nil pointer by reading
+// its data word; the header check shows exactly what the trap is
+func isNilValue(v interface{}) bool {
+	h := (*eface)(unsafe.Pointer(&v))
+	return h.typ != nil && h.data == nil
+}
+
+// 5. Dynamic Dispatch Cost
+// ========================
+func dispatchCost() {
+	fmt.Println("\n5. DYNAMIC DISPATCH COST:")
+
+	shapes := make([]Shape, 1000)
+	squares := make([]Square, 1000)
+	for i := range shapes {
+		squares[i] = Square{Side: float64(i)}
+		shapes[i] = squares[i]
+	}
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"direct call (Square.Area)", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var sum float64
+				for j := range squares {
+					sum += squares[j].Area()
+				}
+				areaSink = sum
+			}
+		}},
+		{"interface call (Shape.Area)", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var sum float64
+				for j := range shapes {
+					sum += shapes[j].Area()
+				}
+				areaSink = sum
+			}
+		}},
+		{"type assertion then direct call", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var sum float64
+				for j := range shapes {
+					if sq, ok := shapes[j].(Square); ok {
+						sum += sq.Area()
+					}
+				}
+				areaSink = sum
+			}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(bm.fn)
+		perCall := float64(r.T.Nanoseconds()) / float64(r.N) / float64(len(squares))
+		fmt.Printf("   %-30s %6.2f ns/call\n", bm.name, perCall)
+	}
+
+	fmt.Println("   Interface calls go through the itab's method table, so they")
+	fmt.Println("   cannot be inlined unless the compiler devirtualizes them. The")
+	fmt.Println("   difference is a few nanoseconds - it matters in hot loops only.")
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Go Type Switches - Sealed Interfaces and Exhaustiveness
+// =======================================================
+// This file demonstrates type switches, how to "seal" an interface so
+// only this package can implement it, and why Go cannot check that a
+// switch over a sealed set handles every case - which is what the
+// exhaustive analyzer in metaprogramming/passes adds.
+
+// Expr is a sealed interface: the unexported isExpr method means no type
+// outside this package can implement it, so the set of implementations
+// is closed and known
+type Expr interface {
+	isExpr()
+}
+
+// Num is a literal number
+type Num struct{ Value float64 }
+
+// Add is Left + Right
+type Add struct{ Left, Right Expr }
+
+// Mul is Left * Right
+type Mul struct{ Left, Right Expr }
+
+// Neg is -Operand; it is implemented on the pointer type to show that
+// *Neg, not Neg, is the member of the sealed set
+type Neg struct{ Operand Expr }
+
+func (Num) isExpr()  {}
+func (Add) isExpr()  {}
+func (Mul) isExpr()  {}
+func (*Neg) isExpr() {}
+
+func main() {
+	fmt.Println("=== Go Type Switches ===")
+
+	// 1. Type switch basics
+	typeSwitchBasics()
+
+	// 2. Sealed interfaces
+	sealedInterfaces()
+
+	// 3. Exhaustive switches
+	exhaustiveSwitches()
+
+	// 4. The missing case
+	missingCase()
+
+	// 5. Keeping switches exhaustive
+	keepingSwitchesExhaustive()
+}
+
+// 1. Type Switch Basics
+// =====================
+func typeSwitchBasics() {
+	fmt.Println("\n1. TYPE SWITCH BASICS:")
+
+	values := []any{42, "go", 3.5, []int{1, 2}, nil, fmt.Errorf("boom"), struct{}{}}
+	for _, v := range values {
+		fmt.Printf("   %-8s -> %s\n", fmt.Sprint(v), classify(v))
+	}
+}
+
+// classify shows the forms a case can take
+func classify(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil interface"
+	case int:
+		// One type per case: x has that type
+		return fmt.Sprintf("int, doubled %d", x*2)
+	case string, []int:
+		// Several types per case: x keeps the switch operand's type (any)
+		return fmt.Sprintf("string or []int (%T)", x)
+	case error:
+		// Interface cases match any value implementing the interface
+		return "error: " + x.Error()
+	default:
+		return fmt.Sprintf("something else (%T)", x)
+	}
+}
+
+// 2. Sealed Interfaces
+// ====================
+func sealedInterfaces() {
+	fmt.Println("\n2. SEALED INTERFACES:")
+
+	// (1 + 2) * -4
+	e := Mul{Add{Num{1}, Num{2}}, &Neg{Num{4}}}
+	fmt.Printf("   expression: %s\n", format(e))
+	fmt.Println("   Expr has an unexported method, so only this package can add")
+	fmt.Println("   implementations: Num, Add, Mul and *Neg are the whole set")
+	fmt.Println("   The compiler still does not know that - a type switch with a")
+	fmt.Println("   missing case compiles fine and silently falls through")
+}
+
+// 3. Exhaustive Switches
+// ======================
+func exhaustiveSwitches() {
+	fmt.Println("\n3. EXHAUSTIVE SWITCHES:")
+
+	e := Mul{Add{Num{1}, Num{2}}, &Neg{Num{4}}}
+	fmt.Printf("   eval(%s) = %g\n", format(e), eval(e))
+
+	simplified := simplify(Add{Mul{Num{1}, Num{7}}, Num{0}})
+	fmt.Printf("   simplify((1 * 7) + 0) = %s\n", format(simplified))
+}
+
+// eval handles every member of the sealed set
+func eval(e Expr) float64 {
+	switch e := e.(type) {
+	case Num:
+		return e.Value
+	case Add:
+		return eval(e.Left) + eval(e.Right)
+	case Mul:
+		return eval(e.Left) * eval(e.Right)
+	case *Neg:
+		return -eval(e.Operand)
+	default:
+		panic(fmt.Sprintf("eval: unexpected %T", e))
+	}
+}
+
+func format(e Expr) string {
+	switch e := e.(type) {
+	case Num:
+		return fmt.Sprint(e.Value)
+	case Add:
+		return "(" + format(e.Left) + " + " + format(e.Right) + ")"
+	case Mul:
+		return format(e.Left) + " * " + format(e.Right)
+	case *Neg:
+		return "-" + format(e.Operand)
+	}
+	panic(fmt.Sprintf("format: unexpected %T", e))
+}
+
+// simplify removes "+ 0" and "* 1"
+func simplify(e Expr) Expr {
+	switch e := e.(type) {
+	case Num:
+		return e
+	case Add:
+		l, r := simplify(e.Left), simplify(e.Right)
+		if isNum(r, 0) {
+			return l
+		}
+		if isNum(l, 0) {
+			return r
+		}
+		return Add{l, r}
+	case Mul:
+		l, r := simplify(e.Left), simplify(e.Right)
+		if isNum(r, 1) {
+			return l
+		}
+		if isNum(l, 1) {
+			return r
+		}
+		return Mul{l, r}
+	case *Neg:
+		return &Neg{simplify(e.Operand)}
+	}
+	panic(fmt.Sprintf("simplify: unexpected %T", e))
+}
+
+// 4. The Missing Case
+// ===================
+func missingCase() {
+	fmt.Println("\n4. THE MISSING CASE:")
+
+	e := Add{Num{1}, &Neg{Add{Num{2}, Num{3}}}}
+	fmt.Printf("   depth(%s) = %d (want 4)\n", format(e), depth(e))
+	fmt.Println("   depth forgot *Neg, so the whole negated subtree counts as 0")
+	fmt.Println("   The exhaustive analyzer reports it:")
+	fmt.Println("     go_type_switches.go:188:2: missing cases in type switch over main.Expr: *main.Neg")
+}
+
+// depth is deliberately incomplete: it has no *Neg case, and without a
+// default it returns 0 for negations. The exhaustive analyzer flags it.
+func depth(e Expr) int {
+	switch e := e.(type) {
+	case Num:
+		return 1
+	case Add:
+		return 1 + max(depth(e.Left), depth(e.Right))
+	case Mul:
+		return 1 + max(depth(e.Left), depth(e.Right))
+	}
+	return 0
+}
+
+// 5. Keeping Switches Exhaustive
+// ==============================
+func keepingSwitchesExhaustive() {
+	fmt.Println("\n5. KEEPING SWITCHES EXHAUSTIVE:")
+
+	fmt.Println("   Options, from weakest to strongest:")
+	fmt.Println("   - default: panic(...)   fails at run time, only on the bad path")
+	fmt.Println("   - an analyzer           fails in CI for every incomplete switch")
+	fmt.Println("   - an interface method   fails at compile time, but spreads every")
+	fmt.Println("                           operation across all the types")
+
+	fmt.Println("   Run the analyzer on this file from metaprogramming/passes:")
+	fmt.Println("   $ go run driver.go noprintln.go exhaustive.go main.go -check ../../advanced-concepts/go_type_switches.go")
+	fmt.Println("   Switches with a default case are treated as deliberate and skipped")
+}
+
+// Helper functions
+// ================
+func isNum(e Expr, v float64) bool {
+	n, ok := e.(Num)
+	return ok && n.Value == v
+}
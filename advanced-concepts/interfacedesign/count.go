@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Counting Errors, Three Ways
+// ===========================
+// The same job - count the lines of a log containing "ERROR" and append
+// a summary to a report - written against files, against small
+// interfaces the consumer declares, and against one interface for a
+// whole file system. main.go walks through them; count_test.go tests
+// countErrors with the fakes the small interfaces make possible.
+
+// countErrorsConcrete opens a log file by path, counts lines containing
+// "ERROR" and appends a summary to a report file. It works, but it can
+// only be exercised by creating real files, and every failure path
+// (unreadable file, full disk) is hard to reproduce.
+func countErrorsConcrete(logPath, reportPath string) (int, error) {
+	f, err := os.Open(logPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "ERROR") {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	report, err := os.OpenFile(reportPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer report.Close()
+	_, err = fmt.Fprintf(report, "%s: %d errors in %s\n", time.Now().Format(time.RFC3339), count, logPath)
+	return count, err
+}
+
+// The consumer declares only what it needs. io.Reader and io.Writer
+// already exist, so the only new interface is a one-method Clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// countErrors depends on behaviour, not on files: any io.Reader works as
+// input and any io.Writer works as the report destination
+func countErrors(name string, log io.Reader, report io.Writer, clock Clock) (int, error) {
+	count := 0
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "ERROR") {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("reading %s: %w", name, err)
+	}
+	if _, err := fmt.Fprintf(report, "%s: %d errors in %s\n", clock.Now().Format(time.RFC3339), count, name); err != nil {
+		return 0, fmt.Errorf("writing report: %w", err)
+	}
+	return count, nil
+}
+
+// Summary is returned as a concrete type: callers get every field and
+// method, and adding methods later does not break anyone
+type Summary struct {
+	Name   string
+	Errors int
+	Lines  int
+}
+
+func (s Summary) Rate() float64 {
+	if s.Lines == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Lines)
+}
+
+// Summarize accepts the narrowest interface it needs (io.Reader)
+func Summarize(name string, r io.Reader) (*Summary, error) {
+	s := &Summary{Name: name}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s.Lines++
+		if strings.Contains(scanner.Text(), "ERROR") {
+			s.Errors++
+		}
+	}
+	return s, scanner.Err()
+}
+
+// FileSystem is what you get when the producer defines one interface for
+// everything it can do. Every consumer depends on all nine methods.
+type FileSystem interface {
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	Remove(name string) error
+	Rename(from, to string) error
+	Stat(name string) (os.FileInfo, error)
+	MkdirAll(path string, perm os.FileMode) error
+	ReadDir(name string) ([]os.DirEntry, error)
+	Chmod(name string, mode os.FileMode) error
+	Now() time.Time
+}
+
+// countErrorsGod only needs Open, Create and Now, but a fake for it must
+// implement all nine methods - or embed the interface and panic on the rest
+func countErrorsGod(fs FileSystem, logPath, reportPath string) (int, error) {
+	log, err := fs.Open(logPath)
+	if err != nil {
+		return 0, err
+	}
+	defer log.Close()
+	report, err := fs.Create(reportPath)
+	if err != nil {
+		return 0, err
+	}
+	defer report.Close()
+	return countErrors(logPath, log, report, fs)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// interfacedesign - Tests
+// =======================
+// Run with:
+//
+//   cd advanced-concepts/interfacedesign
+//   go test -v *.go
+//
+// countErrors takes an io.Reader, an io.Writer and a Clock, so every
+// case - the failures included - is a few lines of fake instead of a
+// temp dir and a real clock.
+
+// fixedClock always returns the same instant
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+// failingReader returns data and then an error, like a flaky disk
+type failingReader struct {
+	data string
+	err  error
+	done bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, r.err
+	}
+	r.done = true
+	return copy(p, r.data), nil
+}
+
+// failingWriter refuses every write, like a full disk
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
+
+var (
+	errUnplugged = errors.New("disk unplugged")
+	errFull      = errors.New("disk full")
+)
+
+// 1. Small Interfaces
+// ===================
+
+func TestCountErrors(t *testing.T) {
+	clock := fixedClock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	tests := []struct {
+		name    string
+		log     io.Reader
+		report  io.Writer
+		want    int
+		wantErr error
+		output  string // the report, when it is a strings.Builder
+	}{
+		{
+			name:   "counts error lines",
+			log:    strings.NewReader("ERROR\nINFO\nERROR\n"),
+			report: &strings.Builder{},
+			want:   2,
+			output: "2024-01-02T03:04:05Z: 2 errors in test.log\n",
+		},
+		{
+			name:   "an empty log is zero errors, still reported",
+			log:    strings.NewReader(""),
+			report: &strings.Builder{},
+			output: "2024-01-02T03:04:05Z: 0 errors in test.log\n",
+		},
+		{
+			name:   "ERROR anywhere in a line counts; error does not",
+			log:    strings.NewReader("12:00 ERROR disk\nERRORS: 3\nerror: lowercase\n"),
+			report: &strings.Builder{},
+			want:   2,
+			output: "2024-01-02T03:04:05Z: 2 errors in test.log\n",
+		},
+		{
+			name:    "read failure is reported",
+			log:     &failingReader{data: "ERROR\n", err: errUnplugged},
+			report:  &strings.Builder{},
+			wantErr: errUnplugged,
+		},
+		{
+			name:    "write failure is reported",
+			log:     strings.NewReader("ERROR\n"),
+			report:  failingWriter{err: errFull},
+			wantErr: errFull,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := countErrors("test.log", tt.log, tt.report, clock)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("countErrors = %d, %v; want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+			if sb, ok := tt.report.(*strings.Builder); ok && sb.String() != tt.output {
+				t.Errorf("report %q, want %q", sb.String(), tt.output)
+			}
+		})
+	}
+}
+
+// 2. The Other Two
+// ================
+
+func TestCountErrorsConcrete(t *testing.T) {
+	// The same case as above needs a temp dir and two files
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(logPath, []byte("ERROR\nINFO\nERROR\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := countErrorsConcrete(logPath, filepath.Join(dir, "report.txt")); n != 2 || err != nil {
+		t.Errorf("countErrorsConcrete = %d, %v; want 2, nil", n, err)
+	}
+	if _, err := countErrorsConcrete(filepath.Join(dir, "missing.log"), filepath.Join(dir, "report.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing log: %v", err)
+	}
+}
+
+func TestCountErrorsGod(t *testing.T) {
+	fake := godFake{files: map[string]string{"a.log": "ERROR\nERROR\n"}, out: &strings.Builder{}}
+	if n, err := countErrorsGod(fake, "a.log", "r.txt"); n != 2 || err != nil {
+		t.Errorf("countErrorsGod = %d, %v; want 2, nil", n, err)
+	}
+	if want := "1970-01-01T00:00:00Z: 2 errors in a.log\n"; fake.out.String() != want {
+		t.Errorf("report %q, want %q", fake.out.String(), want)
+	}
+	if _, err := countErrorsGod(fake, "b.log", "r.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing log: %v", err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		log  string
+		want Summary
+		rate float64
+	}{
+		{"ERROR\nINFO\nINFO\nERROR\n", Summary{"app.log", 2, 4}, 0.5},
+		{"", Summary{"app.log", 0, 0}, 0},
+		{"INFO\nINFO\nINFO\nERROR", Summary{"app.log", 1, 4}, 0.25},
+	}
+	for _, tt := range tests {
+		s, err := Summarize("app.log", strings.NewReader(tt.log))
+		if err != nil || *s != tt.want || s.Rate() != tt.rate {
+			t.Errorf("Summarize(%q) = %+v rate %v, %v; want %+v rate %v", tt.log, s, s.Rate(), err, tt.want, tt.rate)
+		}
+	}
+}
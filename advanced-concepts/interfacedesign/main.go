@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Go Interface Design - Small Interfaces, Accept Interfaces, Return Structs
+// =========================================================================
+// This file demonstrates refactoring a concrete file-processing function
+// into one that depends on small interfaces defined by the consumer, how
+// that makes testing with fakes trivial, and why a "god interface" hurts.
+// The functions live in count.go; count_test.go tests them with fakes.
+//
+// Run with:
+//
+//   cd advanced-concepts/interfacedesign
+//   go run count.go main.go
+//   go test -v *.go
+
+func main() {
+	fmt.Println("=== Go Interface Design ===")
+
+	// 1. The concrete starting point
+	concreteVersion()
+
+	// 2. Refactoring to small interfaces
+	smallInterfaces()
+
+	// 3. Testing with fakes
+	testingWithFakes()
+
+	// 4. Accept interfaces, return structs
+	acceptInterfacesReturnStructs()
+
+	// 5. The god-interface anti-pattern
+	godInterface()
+}
+
+// 1. The Concrete Starting Point
+// ==============================
+
+func concreteVersion() {
+	fmt.Println("\n1. CONCRETE VERSION:")
+
+	dir, err := os.MkdirTemp("", "interface-design")
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "app.log")
+	os.WriteFile(logPath, []byte("INFO start\nERROR disk\nINFO ok\nERROR net\n"), 0o644)
+
+	n, err := countErrorsConcrete(logPath, filepath.Join(dir, "report.txt"))
+	fmt.Printf("   errors counted: %d (err=%v)\n", n, err)
+	fmt.Println("   To test this we needed a temp dir, two files, and the real clock")
+}
+
+// 2. Refactoring to Small Interfaces
+// ==================================
+
+func smallInterfaces() {
+	fmt.Println("\n2. SMALL INTERFACES:")
+
+	// Production wiring would pass an *os.File for both; the caller opens them
+	log := strings.NewReader("ERROR a\nINFO b\nERROR c\n")
+	var report strings.Builder
+	n, err := countErrors("inline.log", log, &report, systemClock{})
+	fmt.Printf("   errors counted: %d (err=%v)\n", n, err)
+	fmt.Printf("   report: %s", report.String())
+
+	// The same function works with other sources for free
+	multi := io.MultiReader(strings.NewReader("ERROR x\n"), strings.NewReader("ERROR y\n"))
+	n, _ = countErrors("multi", multi, io.Discard, systemClock{})
+	fmt.Printf("   from io.MultiReader: %d errors\n", n)
+}
+
+// 3. Testing with Fakes
+// =====================
+
+func testingWithFakes() {
+	fmt.Println("\n3. TESTING WITH FAKES:")
+
+	// The fakes are a few lines each; count_test.go has them and a
+	// table of cases, including the failure paths
+	fmt.Println("   fixedClock     Now() returns the same instant, so the report is exact")
+	fmt.Println("   failingReader  returns data, then an error, like a flaky disk")
+	fmt.Println("   failingWriter  refuses every write, like a full disk")
+	fmt.Println("   go test -v *.go runs them: no files, no temp dirs, no sleeping")
+}
+
+// 4. Accept Interfaces, Return Structs
+// ====================================
+
+func acceptInterfacesReturnStructs() {
+	fmt.Println("\n4. ACCEPT INTERFACES, RETURN STRUCTS:")
+
+	s, err := Summarize("app.log", strings.NewReader("ERROR\nINFO\nINFO\nERROR\n"))
+	fmt.Printf("   %+v rate=%.2f err=%v\n", *s, s.Rate(), err)
+	fmt.Println("   - parameters: the smallest interface the function actually uses")
+	fmt.Println("   - results: concrete types; let callers define interfaces if they need them")
+	fmt.Println("   - interfaces belong in the package that USES them, not the one that implements them")
+}
+
+// 5. The God-Interface Anti-Pattern
+// =================================
+
+// godFake implements the three methods the function uses and embeds the
+// interface for the rest; calling anything else panics on a nil interface
+type godFake struct {
+	FileSystem
+	files map[string]string
+	out   *strings.Builder
+}
+
+func (f godFake) Open(name string) (io.ReadCloser, error) {
+	data, ok := f.files[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func (f godFake) Create(string) (io.WriteCloser, error) { return nopWriteCloser{f.out}, nil }
+func (f godFake) Now() time.Time                        { return time.Unix(0, 0).UTC() }
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func godInterface() {
+	fmt.Println("\n5. GOD-INTERFACE ANTI-PATTERN:")
+
+	fake := godFake{files: map[string]string{"a.log": "ERROR\n"}, out: &strings.Builder{}}
+	n, err := countErrorsGod(fake, "a.log", "r.txt")
+	fmt.Printf("   countErrorsGod with a partial fake: %d errors (err=%v)\n", n, err)
+
+	fmt.Println("   Problems:")
+	fmt.Println("   - the signature hides which of the 9 methods are actually used")
+	fmt.Println("   - every new method breaks every existing implementation")
+	fmt.Println("   - fakes are either huge or rely on embedding + panics")
+	fmt.Println("   The Go proverb: \"The bigger the interface, the weaker the abstraction.\"")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Go Reflection - Dynamic Functions with reflect.MakeFunc
+// =======================================================
+// This file demonstrates creating functions at run time with
+// reflect.MakeFunc, calling them with reflect.Value.Call, and using both
+// to build a generic spy/mock that records calls and stubs the Writer
+// interface from the interfaces lesson. The spy lives in spy.go;
+// spy_test.go uses it the way a test would and checks it.
+//
+// Run with:
+//
+//   cd advanced-concepts/makefunc
+//   go run spy.go main.go
+//   go test -v *.go
+
+func main() {
+	fmt.Println("=== Go reflect.MakeFunc ===")
+
+	// 1. reflect.Value.Call
+	dynamicCalls()
+
+	// 2. reflect.MakeFunc basics
+	makeFuncBasics()
+
+	// 3. A spy that calls through
+	spyCallThrough()
+
+	// 4. Stubbing the Writer interface
+	stubbingWriter()
+}
+
+// 1. reflect.Value.Call
+// =====================
+func dynamicCalls() {
+	fmt.Println("\n1. REFLECT.VALUE.CALL:")
+
+	fn := reflect.ValueOf(strings.Repeat)
+	out := fn.Call([]reflect.Value{reflect.ValueOf("go"), reflect.ValueOf(3)})
+	fmt.Printf("   strings.Repeat via Call: %q\n", out[0].String())
+	fmt.Printf("   type: %s, in=%d out=%d\n", fn.Type(), fn.Type().NumIn(), fn.Type().NumOut())
+
+	// Variadic functions take the variadic args individually with Call,
+	// or as a single slice with CallSlice
+	join := reflect.ValueOf(fmt.Sprint)
+	out = join.Call([]reflect.Value{reflect.ValueOf("a"), reflect.ValueOf(1)})
+	fmt.Printf("   fmt.Sprint via Call: %q\n", out[0].String())
+
+	// Wrong argument types panic at run time, not compile time
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("   wrong argument type panics: %v\n", r)
+		}
+	}()
+	fn.Call([]reflect.Value{reflect.ValueOf(1), reflect.ValueOf(2)})
+}
+
+// 2. reflect.MakeFunc Basics
+// ==========================
+func makeFuncBasics() {
+	fmt.Println("\n2. MAKEFUNC BASICS:")
+
+	// swap works for any func(T, T) (T, T) - the classic example
+	swap := func(in []reflect.Value) []reflect.Value {
+		return []reflect.Value{in[1], in[0]}
+	}
+	makeSwap := func(fptr any) {
+		fn := reflect.ValueOf(fptr).Elem()
+		fn.Set(reflect.MakeFunc(fn.Type(), swap))
+	}
+
+	var intSwap func(int, int) (int, int)
+	makeSwap(&intSwap)
+	a, b := intSwap(1, 2)
+	fmt.Printf("   intSwap(1, 2): %d %d\n", a, b)
+
+	var strSwap func(string, string) (string, string)
+	makeSwap(&strSwap)
+	s1, s2 := strSwap("left", "right")
+	fmt.Printf("   strSwap(left, right): %s %s\n", s1, s2)
+
+	// A timing/logging wrapper for any function type
+	upper := strings.ToUpper
+	logged := wrapWithLog("ToUpper", upper)
+	fmt.Printf("   logged result: %s\n", logged("gopher"))
+}
+
+// wrapWithLog returns a function of the same type as fn that prints its
+// arguments and results around the real call
+func wrapWithLog[F any](name string, fn F) F {
+	v := reflect.ValueOf(fn)
+	wrapped := reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+		out := v.Call(args)
+		fmt.Printf("   %s%v -> %v\n", name, toInterfaces(args), toInterfaces(out))
+		return out
+	})
+	return wrapped.Interface().(F)
+}
+
+// 3. A Spy That Calls Through
+// ===========================
+func spyCallThrough() {
+	fmt.Println("\n3. SPY THAT CALLS THROUGH:")
+
+	var sb strings.Builder
+	mock := &WriterMock{WriteFunc: sb.Write}
+	spy := SpyOn("Write", &mock.WriteFunc)
+
+	var w Writer = mock
+	fmt.Fprintf(w, "hello %s", "world")
+	w.Write([]byte("!"))
+
+	fmt.Printf("   underlying builder got: %q\n", sb.String())
+	fmt.Printf("   spy recorded %d calls\n", spy.CallCount())
+	for i, c := range spy.Calls() {
+		fmt.Printf("   call %d: args=%q results=%v\n", i, c.Args[0], c.Results)
+	}
+}
+
+// 4. Stubbing the Writer Interface
+// ================================
+
+// saveReport is the code under test: it must report short writes and
+// write errors instead of silently losing data
+func saveReport(w Writer, lines []string) error {
+	for _, line := range lines {
+		data := []byte(line + "\n")
+		n, err := w.Write(data)
+		if err != nil {
+			return fmt.Errorf("write %q: %w", line, err)
+		}
+		if n < len(data) {
+			return fmt.Errorf("short write: %d of %d bytes", n, len(data))
+		}
+	}
+	return nil
+}
+
+func stubbingWriter() {
+	fmt.Println("\n4. STUBBING THE WRITER:")
+
+	mock := &WriterMock{}
+	spies := NewMock(mock)
+
+	spies["WriteFunc"].Returns(0, errors.New("disk full"))
+	err := saveReport(mock, []string{"a", "b"})
+	fmt.Printf("   stubbed error: %v (calls=%d)\n", err, spies["WriteFunc"].CallCount())
+
+	mock = &WriterMock{}
+	spies = NewMock(mock)
+	spies["WriteFunc"].Returns(1, nil) // always claims 1 byte written
+	err = saveReport(mock, []string{"abc"})
+	fmt.Printf("   stubbed short write: %v\n", err)
+
+	mock = &WriterMock{}
+	spies = NewMock(mock)
+	spies["WriteFunc"].Returns(2, nil)
+	err = saveReport(mock, []string{"x", "y", "z"})
+	fmt.Printf("   happy path: err=%v, calls=%d\n", err, spies["WriteFunc"].CallCount())
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Spies and Mocks with reflect.MakeFunc
+// =====================================
+// SpyOn swaps a function for a recorder of the same type, built with
+// reflect.MakeFunc; NewMock does it for every nil func field of a mock
+// struct. The recorder calls through to the original, or returns the
+// stubbed results.
+
+// Writer is the interface from ../go_other_concepts.go
+type Writer interface {
+	Write([]byte) (int, error)
+}
+
+// WriterMock implements Writer by delegating to a function field, which
+// the spy generator can replace with a recording function
+type WriterMock struct {
+	WriteFunc func([]byte) (int, error)
+}
+
+func (m *WriterMock) Write(p []byte) (int, error) { return m.WriteFunc(p) }
+
+// Call is one recorded invocation
+type Call struct {
+	Args    []any
+	Results []any
+}
+
+// Spy records calls made through a function created by MakeFunc
+type Spy struct {
+	mu      sync.Mutex
+	name    string
+	calls   []Call
+	results []reflect.Value // stubbed results; nil means zero values
+	through reflect.Value   // original function to call through to, if any
+}
+
+// Calls returns a copy of the recorded calls
+func (s *Spy) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallCount returns how many times the function was called
+func (s *Spy) CallCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+// Returns stubs the results of every future call. The values must match
+// the function's result types (nil is allowed for interfaces and pointers).
+func (s *Spy) Returns(results ...any) *Spy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = make([]reflect.Value, len(results))
+	for i, r := range results {
+		s.results[i] = reflect.ValueOf(r)
+	}
+	return s
+}
+
+// SpyOn replaces the function that fnPtr points to with a recording
+// function of the same type. By default the spy calls through to the
+// original function; call Returns to stub results instead.
+func SpyOn(name string, fnPtr any) *Spy {
+	ptr := reflect.ValueOf(fnPtr)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Func {
+		panic(fmt.Sprintf("SpyOn: want pointer to func, got %T", fnPtr))
+	}
+	fnType := ptr.Elem().Type()
+	spy := &Spy{name: name}
+	if !ptr.Elem().IsNil() {
+		// Keep our own copy of the original: ptr.Elem() is about to be
+		// overwritten with the recorder, and calling it would recurse
+		spy.through = reflect.ValueOf(ptr.Elem().Interface())
+	}
+
+	recorder := reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		results := spy.respond(fnType, args)
+		call := Call{Args: snapshot(args), Results: toInterfaces(results)}
+		spy.mu.Lock()
+		spy.calls = append(spy.calls, call)
+		spy.mu.Unlock()
+		return results
+	})
+	ptr.Elem().Set(recorder)
+	return spy
+}
+
+func (s *Spy) respond(fnType reflect.Type, args []reflect.Value) []reflect.Value {
+	s.mu.Lock()
+	stubbed := s.results
+	s.mu.Unlock()
+
+	if stubbed == nil && s.through.IsValid() {
+		if fnType.IsVariadic() {
+			return s.through.CallSlice(args)
+		}
+		return s.through.Call(args)
+	}
+
+	out := make([]reflect.Value, fnType.NumOut())
+	for i := range out {
+		t := fnType.Out(i)
+		if i < len(stubbed) && stubbed[i].IsValid() {
+			out[i] = stubbed[i].Convert(t)
+		} else {
+			out[i] = reflect.Zero(t)
+		}
+	}
+	return out
+}
+
+// NewMock fills every nil func field of the struct that ptr points to
+// with a spy returning zero values, and returns the spies by field name
+func NewMock(ptr any) map[string]*Spy {
+	v := reflect.ValueOf(ptr).Elem()
+	spies := make(map[string]*Spy)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Func || !f.CanSet() || !f.IsNil() {
+			continue
+		}
+		name := v.Type().Field(i).Name
+		spies[name] = SpyOn(name, f.Addr().Interface())
+	}
+	return spies
+}
+
+// Helper functions
+// ================
+func toInterfaces(values []reflect.Value) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v.Interface()
+	}
+	return out
+}
+
+// snapshot copies slice arguments before recording them. Callers are
+// allowed to reuse their buffers after the call returns - io.Writer
+// implementations must not retain p - so recording the slice itself
+// would show whatever the caller wrote into it later.
+func snapshot(args []reflect.Value) []any {
+	out := make([]any, len(args))
+	for i, v := range args {
+		if v.Kind() == reflect.Slice && !v.IsNil() {
+			c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			reflect.Copy(c, v)
+			v = c
+		}
+		out[i] = v.Interface()
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// makefunc - Tests
+// ================
+// Run with:
+//
+//   cd advanced-concepts/makefunc
+//   go test -v *.go
+
+// 1. Mocks
+// ========
+
+func TestMockUnstubbedReturnsZeroValues(t *testing.T) {
+	m := &WriterMock{}
+	NewMock(m)
+	n, err := m.Write([]byte("x"))
+	if n != 0 || err != nil {
+		t.Errorf("Write = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestMockRecordsArgumentsInOrder(t *testing.T) {
+	m := &WriterMock{}
+	spy := NewMock(m)["WriteFunc"]
+	m.Write([]byte("first"))
+	m.Write([]byte("second"))
+
+	calls := spy.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(calls))
+	}
+	for i, want := range []string{"first", "second"} {
+		if got := string(calls[i].Args[0].([]byte)); got != want {
+			t.Errorf("call %d: arg = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestMockSnapshotsReusedBuffers(t *testing.T) {
+	m := &WriterMock{}
+	spy := NewMock(m)["WriteFunc"]
+	buf := []byte("one")
+	m.Write(buf)
+	copy(buf, "two")
+
+	if got := string(spy.Calls()[0].Args[0].([]byte)); got != "one" {
+		t.Errorf("recorded arg = %q after the caller reused its buffer, want %q", got, "one")
+	}
+}
+
+func TestMockStubbedNilError(t *testing.T) {
+	m := &WriterMock{}
+	NewMock(m)["WriteFunc"].Returns(3, nil)
+	n, err := m.Write(nil)
+	if n != 3 || err != nil {
+		t.Errorf("Write = %d, %v; want 3, nil", n, err)
+	}
+}
+
+func TestMockConcurrentCalls(t *testing.T) {
+	m := &WriterMock{}
+	spy := NewMock(m)["WriteFunc"]
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() { defer wg.Done(); m.Write(nil) }()
+	}
+	wg.Wait()
+	if got := spy.CallCount(); got != 50 {
+		t.Errorf("CallCount = %d, want 50", got)
+	}
+}
+
+func TestMockLeavesSetFieldsAlone(t *testing.T) {
+	m := &WriterMock{WriteFunc: func([]byte) (int, error) { return 42, nil }}
+	spies := NewMock(m)
+	if len(spies) != 0 {
+		t.Errorf("NewMock returned %d spies for a mock with every field set", len(spies))
+	}
+	if n, _ := m.Write(nil); n != 42 {
+		t.Errorf("Write = %d, want the original function's 42", n)
+	}
+}
+
+// 2. Spies
+// ========
+
+func TestSpyCallsThrough(t *testing.T) {
+	var sb strings.Builder
+	m := &WriterMock{WriteFunc: sb.Write}
+	spy := SpyOn("Write", &m.WriteFunc)
+
+	n, err := m.Write([]byte("hello"))
+	if n != 5 || err != nil {
+		t.Errorf("Write = %d, %v; want 5, nil", n, err)
+	}
+	if sb.String() != "hello" {
+		t.Errorf("builder got %q, want %q", sb.String(), "hello")
+	}
+	if got := spy.Calls()[0].Results; got[0] != 5 || got[1] != nil {
+		t.Errorf("recorded results = %v, want [5 <nil>]", got)
+	}
+}
+
+func TestSpyOnRejectsNonFunc(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("SpyOn on a *int did not panic")
+		}
+	}()
+	var n int
+	SpyOn("n", &n)
+}
+
+// 3. Stubbing the Code Under Test
+// ===============================
+
+func TestSaveReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []any
+		lines   []string
+		wantErr string
+		calls   int
+	}{
+		{"write error", []any{0, errors.New("disk full")}, []string{"a", "b"}, "disk full", 1},
+		{"short write", []any{1, nil}, []string{"abc"}, "short write: 1 of 4 bytes", 1},
+		{"every line written", []any{2, nil}, []string{"x", "y", "z"}, "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &WriterMock{}
+			spy := NewMock(m)["WriteFunc"].Returns(tt.results...)
+
+			err := saveReport(m, tt.lines)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("saveReport: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("saveReport = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if got := spy.CallCount(); got != tt.calls {
+				t.Errorf("Write called %d times, want %d", got, tt.calls)
+			}
+		})
+	}
+}
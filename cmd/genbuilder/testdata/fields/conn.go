@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	u "net/url"
+	"strings"
+	"time"
+)
+
+//go:generate go run ../../main.go -type Conn -output builder_gen.go
+
+// Conn has the field shapes a builder must handle: two names in one
+// declaration, pointer, slice, map and func types, types from other
+// packages - one imported under a name - and fields the builder skips,
+// one unexported and one tagged builder:"-"
+type Conn struct {
+	Host, Port string
+	Timeout    time.Duration
+	Proxy      *u.URL
+	Tags       []string
+	Headers    map[string][]string
+	Dial       func(addr string) error
+	secret     string
+	Cache      map[string]int `builder:"-"`
+}
+
+func main() {
+	proxy, _ := u.Parse("socks5://localhost:1080")
+	c := NewConnBuilder().
+		WithHost("example.com").WithPort("443").
+		WithTimeout(2 * time.Second).
+		WithProxy(proxy).
+		WithTags([]string{"a", "b"}).
+		WithHeaders(map[string][]string{"Accept": {"*/*"}}).
+		WithDial(func(string) error { return nil }).
+		MustBuild()
+	fmt.Println(strings.Join([]string{c.Host, c.Port}, ":"), c.Timeout, c.Proxy, c.Tags, c.Headers)
+	fmt.Println(c.Dial != nil, c.secret == "", c.Cache == nil)
+}
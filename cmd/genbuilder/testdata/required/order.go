@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+//go:generate go run ../../main.go -type Order
+
+// Order has required fields and a Validate hook: Build reports every
+// missing field at once, and calls Validate only when none is missing
+type Order struct {
+	ID    int    `builder:"required"`
+	Item  string `builder:"required"`
+	Count int
+}
+
+func (o Order) Validate() error {
+	if o.Count < 0 {
+		return errors.New("negative count")
+	}
+	return nil
+}
+
+func main() {
+	o, err := NewOrderBuilder().WithID(1).WithItem("pen").WithCount(3).Build()
+	fmt.Println(o, err)
+	_, err = NewOrderBuilder().WithCount(-1).Build()
+	fmt.Println(err)
+	_, err = NewOrderBuilder().WithID(2).WithItem("ink").WithCount(-1).Build()
+	fmt.Println(err)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// genenum - Tests
+// ===============
+// Run with:
+//
+//   cd cmd/genenum
+//   go test -v *.go
+//   go test *.go -run TestGolden -update   rewrite the golden files
+//
+// Each directory in testdata is a program with a //go:generate line
+// for genenum. Its expected output is <output>.golden beside it, and
+// what the program prints with that output is stdout.golden.
+
+var update = flag.Bool("update", false, "rewrite the golden files from the generator's output")
+
+// directive returns the genenum arguments of the first //go:generate
+// line in path that runs it
+func directive(t *testing.T, path string) ([]string, bool) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, ok := strings.CutPrefix(sc.Text(), "//go:generate go run ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && (fields[0] == "../../main.go" || strings.HasSuffix(fields[0], "cmd/genenum/main.go")) {
+			return fields[1:], true
+		}
+	}
+	return nil, false
+}
+
+// configFor reads the directive in path as go generate would run it:
+// from path's directory, with $GOFILE set
+func configFor(t *testing.T, path string) config {
+	t.Helper()
+	args, ok := directive(t, path)
+	if !ok {
+		t.Fatalf("%s: no genenum directive", path)
+	}
+	cfg, err := parseArgs(args, filepath.Base(path), io.Discard)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	dir := filepath.Dir(path)
+	cfg.Src = filepath.Join(dir, cfg.Src)
+	cfg.Output = filepath.Join(dir, cfg.Output)
+	return cfg
+}
+
+func testdataPrograms(t *testing.T) []string {
+	t.Helper()
+	inputs, err := filepath.Glob("testdata/*/*.go")
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no testdata programs (%v)", err)
+	}
+	return inputs
+}
+
+// 1. Golden Files
+// ===============
+
+func TestGolden(t *testing.T) {
+	for _, input := range testdataPrograms(t) {
+		t.Run(filepath.Base(filepath.Dir(input)), func(t *testing.T) {
+			cfg := configFor(t, input)
+			got, err := generate(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := cfg.Output + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("wrote %s", golden)
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s is stale; run: go test *.go -run TestGolden -update\ngot:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// TestGeneratedPrograms compiles each program with its golden file and
+// checks what the String methods print
+func TestGeneratedPrograms(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+	for _, input := range testdataPrograms(t) {
+		t.Run(filepath.Base(filepath.Dir(input)), func(t *testing.T) {
+			cfg := configFor(t, input)
+			dir := t.TempDir()
+			for src, dst := range map[string]string{input: filepath.Base(input), cfg.Output + ".golden": filepath.Base(cfg.Output)} {
+				data, err := os.ReadFile(src)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, dst), data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := exec.CommandContext(t.Context(), "go", "run", filepath.Base(input), filepath.Base(cfg.Output))
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("go run: %v\n%s", err, out)
+			}
+			want, err := os.ReadFile(filepath.Join(filepath.Dir(input), "stdout.golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != string(want) {
+				t.Errorf("printed:\n%s\nwant:\n%s", out, want)
+			}
+		})
+	}
+}
+
+// 2. The Repository's Enums
+// =========================
+
+// TestRepositoryUpToDate finds every go:generate line for genenum in
+// the repository and checks its output file matches a fresh run, so a
+// changed enum or generator cannot go unnoticed
+func TestRepositoryUpToDate(t *testing.T) {
+	root := filepath.Join("..", "..")
+	found := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "testdata" || strings.HasPrefix(d.Name(), ".")) && path != root {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		if _, ok := directive(t, path); !ok {
+			return nil
+		}
+		found++
+		rel, _ := filepath.Rel(root, path)
+		t.Run(rel, func(t *testing.T) {
+			cfg := configFor(t, path)
+			want, err := generate(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(cfg.Output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s is stale; run: go generate %s", cfg.Output, filepath.Base(path))
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == 0 {
+		t.Error("no go:generate lines for genenum in the repository")
+	}
+}
+
+// 3. Arguments and Errors
+// =======================
+
+func TestParseArgs(t *testing.T) {
+	cfg, err := parseArgs([]string{"-type", "Status, Event", "-case", "kebab"}, "order.go", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cfg.Types, ",") != "Status,Event" || cfg.Src != "order.go" || cfg.Output != "status_string_gen.go" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	for _, args := range [][]string{
+		{},                               // no -type
+		{"-type", "A", "-case", "snake"}, // unknown case
+		{"-type", "A", "-src", "", "-x"}, // unknown flag
+	} {
+		if _, err := parseArgs(args, "a.go", io.Discard); err == nil {
+			t.Errorf("parseArgs(%q) succeeded", args)
+		}
+	}
+	if _, err := parseArgs([]string{"-type", "A"}, "", io.Discard); err == nil {
+		t.Error("no -src and no $GOFILE: want an error")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "bad.go")
+	code := `package bad
+
+import "time"
+
+type Ratio float64
+
+const Half Ratio = 0.5
+
+type Empty int
+
+type Timeout time.Duration
+`
+	if err := os.WriteFile(src, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		typ  string
+		want string
+	}{
+		{"Missing", "type Missing not found"},
+		{"Ratio", "not an integer type"},
+		{"Empty", "has no constants"},
+		// The import is not loaded, so the underlying type is unknown
+		{"Timeout", "not an integer type"},
+	}
+	for _, tt := range tests {
+		_, err := generate(config{Types: []string{tt.typ}, Src: src, Output: "out.go", Case: caseGo})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.typ, err, tt.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := map[string]string{
+		"Closed":      "closed",
+		"HalfOpen":    "half-open",
+		"HTTPTimeout": "http-timeout",
+		"NotFound":    "not-found",
+		"ServeHTTP":   "serve-http",
+		"Base64URL":   "base64-url",
+		"X":           "x",
+	}
+	for ident, want := range tests {
+		if got := caseName(ident, caseKebab); got != want {
+			t.Errorf("caseName(%q, kebab) = %q, want %q", ident, got, want)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// genenum - String() Methods for Enums
+// ====================================
+// genenum finds integer enum types - a named type and the constants
+// declared with it - and writes a String method for each. It is meant
+// to be run by go generate:
+//
+//   //go:generate go run ../../cmd/genenum/main.go -type State -case kebab
+//
+// go generate runs the command in the directory of the file holding
+// the directive, with $GOFILE set to that file's name; genenum reads
+// the type from it. The steps are those of every generator:
+//
+//   1. go/parser reads the package's files into ASTs
+//   2. go/ast finds the const declarations of each -type; an ident
+//      without a type or value repeats the line above it, as in Go
+//   3. go/types evaluates the constants, so iota, 1 << iota and
+//      references to other constants all give exact values
+//   4. text/template renders the code and go/format gofmts it
+//
+// Constants 0..n-1 become one string and an index table, as stringer
+// writes them; any other set becomes a switch. Either way a value
+// with no name prints as State(7), and the generated file fails to
+// compile if the constants change without go generate being re-run.
+
+// Names chosen by -case
+const (
+	caseGo    = "go"    // the identifier: HalfOpen
+	caseLower = "lower" // lower case: halfopen
+	caseKebab = "kebab" // words split and joined with '-': half-open
+)
+
+// config is one run of the generator
+type config struct {
+	Types  []string
+	Src    string // file holding the types; its package is loaded
+	Output string
+	Case   string
+}
+
+// parseArgs reads the command line. gofile is $GOFILE, the default
+// for -src when go generate runs the command.
+func parseArgs(args []string, gofile string, stderr io.Writer) (config, error) {
+	fs := flag.NewFlagSet("genenum", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeNames := fs.String("type", "", "comma-separated enum type names")
+	src := fs.String("src", gofile, "source file containing the types (defaults to $GOFILE)")
+	output := fs.String("output", "", "output file (defaults to <first type>_string_gen.go)")
+	nameCase := fs.String("case", caseGo, "names to print: go, lower or kebab")
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	cfg := config{Src: *src, Output: *output, Case: *nameCase}
+	for _, name := range strings.Split(*typeNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.Types = append(cfg.Types, name)
+		}
+	}
+	if len(cfg.Types) == 0 || cfg.Src == "" {
+		return config{}, errors.New("usage: genenum -type Name[,Name] [-src file.go] [-output file.go] [-case go|lower|kebab]")
+	}
+	switch cfg.Case {
+	case caseGo, caseLower, caseKebab:
+	default:
+		return config{}, fmt.Errorf("-case %q: want go, lower or kebab", cfg.Case)
+	}
+	if cfg.Output == "" {
+		cfg.Output = strings.ToLower(cfg.Types[0]) + "_string_gen.go"
+	}
+	return cfg, nil
+}
+
+// value is one named constant of an enum
+type value struct {
+	Ident string
+	Name  string // what String returns
+	Value int64
+}
+
+// enum is one type to write a String method for
+type enum struct {
+	Type     string
+	Receiver string
+	Unsigned bool
+	Values   []value // in declaration order, one per distinct value
+	Table    bool    // values are exactly 0..n-1
+	Names    string  // the table's names, concatenated
+	Index    []int   // the table's offsets into Names
+	IndexT   string  // smallest unsigned type holding len(Names)
+}
+
+// Check is the body of the compile-time guard: for each constant,
+// an index that is 0 only while the constant keeps its value
+func (e enum) Check() []string {
+	lines := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		lines[i] = fmt.Sprintf("_ = x[%s-(%d)]", v.Ident, v.Value)
+	}
+	return lines
+}
+
+// Format is the conversion used for an unnamed value
+func (e enum) Format() string {
+	if e.Unsigned {
+		return "strconv.FormatUint(uint64(" + e.Receiver + "), 10)"
+	}
+	return "strconv.FormatInt(int64(" + e.Receiver + "), 10)"
+}
+
+type templateData struct {
+	Source  string
+	Package string
+	Enums   []enum
+}
+
+var stringTemplate = template.Must(template.New("string").Parse(`// Code generated by genenum from {{.Source}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import "strconv"
+{{range .Enums}}
+func _() {
+	// An "invalid array index" error here means the {{.Type}} constants
+	// have changed; run go generate to write this file again
+	var x [1]struct{}
+{{- range .Check}}
+	{{.}}
+{{- end}}
+}
+{{if .Table}}
+const _{{.Type}}_name = "{{.Names}}"
+
+var _{{.Type}}_index = [...]{{.IndexT}}{ {{- range $i, $n := .Index}}{{if $i}}, {{end}}{{$n}}{{end -}} }
+
+// String returns the constant's name, or {{.Type}}(n) for any other value
+func ({{.Receiver}} {{.Type}}) String() string {
+	if {{if not .Unsigned}}{{.Receiver}} < 0 || {{end}}{{.Receiver}} >= {{.Type}}(len(_{{.Type}}_index)-1) {
+		return "{{.Type}}(" + {{.Format}} + ")"
+	}
+	return _{{.Type}}_name[_{{.Type}}_index[{{.Receiver}}]:_{{.Type}}_index[{{.Receiver}}+1]]
+}
+{{else}}
+// String returns the constant's name, or {{.Type}}(n) for any other value
+func ({{.Receiver}} {{.Type}}) String() string {
+	switch {{.Receiver}} {
+{{- range .Values}}
+	case {{.Ident}}:
+		return "{{.Name}}"
+{{- end}}
+	}
+	return "{{.Type}}(" + {{.Format}} + ")"
+}
+{{end}}{{end}}`))
+
+func main() {
+	cfg, err := parseArgs(os.Args[1:], os.Getenv("GOFILE"), os.Stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(2)
+	}
+
+	code, err := generate(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "genenum: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(cfg.Output, code, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "genenum: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("genenum: wrote %s\n", cfg.Output)
+}
+
+// generate loads the package holding cfg.Src and renders the String
+// methods for cfg.Types
+func generate(cfg config) ([]byte, error) {
+	fset := token.NewFileSet()
+	files, err := parsePackage(fset, cfg.Src, cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	// Type-check only for constant values. Imports are not resolved,
+	// so the errors they cause are collected and ignored: an enum's
+	// constants seldom depend on another package.
+	conf := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			return nil, fmt.Errorf("genenum does not load imports (%s)", path)
+		}),
+		Error: func(error) {},
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, info)
+
+	data := templateData{Source: filepath.Base(cfg.Src), Package: pkg.Name()}
+	for _, name := range cfg.Types {
+		e, err := findEnum(pkg, files, info, name, cfg.Case)
+		if err != nil {
+			return nil, err
+		}
+		data.Enums = append(data.Enums, e)
+	}
+
+	var buf bytes.Buffer
+	if err := stringTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, buf.String())
+	}
+	return formatted, nil
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// parsePackage parses src and the other files of its package, leaving
+// out tests and the file about to be overwritten
+func parsePackage(fset *token.FileSet, src, output string) ([]*ast.File, error) {
+	first, err := parser.ParseFile(fset, src, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	files := []*ast.File{first}
+
+	dir := filepath.Dir(src)
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{filepath.Base(src): true, filepath.Base(output): true}
+	for _, path := range paths {
+		if skip[filepath.Base(path)] || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.PackageClauseOnly)
+		if err != nil || f.Name.Name != first.Name.Name {
+			continue
+		}
+		if f, err = parser.ParseFile(fset, path, nil, 0); err == nil {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// findEnum collects the constants of type name, in the order they are
+// declared
+func findEnum(pkg *types.Package, files []*ast.File, info *types.Info, name, nameCase string) (enum, error) {
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return enum{}, fmt.Errorf("type %s not found", name)
+	}
+	basic, ok := obj.Type().Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 {
+		return enum{}, fmt.Errorf("type %s is not an integer type", name)
+	}
+
+	e := enum{
+		Type:     name,
+		Receiver: string(unicode.ToLower([]rune(name)[0])),
+		Unsigned: basic.Info()&types.IsUnsigned != 0,
+	}
+	seen := make(map[int64]bool)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				for _, ident := range spec.(*ast.ValueSpec).Names {
+					c, ok := info.Defs[ident].(*types.Const)
+					if !ok || ident.Name == "_" || !types.Identical(c.Type(), obj.Type()) {
+						continue
+					}
+					v, exact := constant.Int64Val(c.Val())
+					if !exact {
+						return enum{}, fmt.Errorf("%s: value %s does not fit in int64", ident.Name, c.Val())
+					}
+					// An alias for an earlier value cannot have a case
+					// of its own; the first name wins
+					if seen[v] {
+						continue
+					}
+					seen[v] = true
+					e.Values = append(e.Values, value{Ident: ident.Name, Name: caseName(ident.Name, nameCase), Value: v})
+				}
+			}
+		}
+	}
+	if len(e.Values) == 0 {
+		return enum{}, fmt.Errorf("type %s has no constants", name)
+	}
+
+	sorted := slices.SortedFunc(slices.Values(e.Values), func(a, b value) int {
+		return cmp.Compare(a.Value, b.Value)
+	})
+	e.Table = true
+	for i, v := range sorted {
+		if v.Value != int64(i) {
+			e.Table = false
+			break
+		}
+	}
+	if e.Table {
+		e.Index = []int{0}
+		for _, v := range sorted {
+			e.Names += v.Name
+			e.Index = append(e.Index, len(e.Names))
+		}
+		e.IndexT = indexType(len(e.Names))
+	}
+	return e, nil
+}
+
+func indexType(n int) string {
+	switch {
+	case n <= 1<<8-1:
+		return "uint8"
+	case n <= 1<<16-1:
+		return "uint16"
+	}
+	return "uint32"
+}
+
+// caseName turns an identifier into the name String returns
+func caseName(ident, nameCase string) string {
+	switch nameCase {
+	case caseLower:
+		return strings.ToLower(ident)
+	case caseKebab:
+		return strings.Join(words(ident), "-")
+	}
+	return ident
+}
+
+// words splits a MixedCaps identifier into lower-case words. A run of
+// capitals is one word, up to the capital that starts the next:
+// HTTPServer is http, server.
+func words(ident string) []string {
+	r := []rune(ident)
+	var out []string
+	start := 0
+	for i := 1; i < len(r); i++ {
+		lowerBefore := unicode.IsLower(r[i-1]) || unicode.IsDigit(r[i-1])
+		acronymEnd := unicode.IsUpper(r[i-1]) && i+1 < len(r) && unicode.IsLower(r[i+1])
+		if unicode.IsUpper(r[i]) && (lowerBefore || acronymEnd) {
+			out = append(out, strings.ToLower(string(r[start:i])))
+			start = i
+		}
+	}
+	return append(out, strings.ToLower(string(r[start:])))
+}
@@ -0,0 +1,29 @@
+package main
+
+import "fmt"
+
+//go:generate go run ../../main.go -type Perm,Level -case lower -output enums_string_gen.go
+
+// Perm is a set of bit flags: String uses a switch
+type Perm uint8
+
+const (
+	Read Perm = 1 << iota
+	Write
+	Exec
+)
+
+// Level has negative and spaced values, like slog.Level
+type Level int
+
+const (
+	Debug Level = -4
+	Info  Level = 0
+	Warn  Level = 4
+	Error Level = 8
+)
+
+func main() {
+	fmt.Println(Read, Write, Exec, Read|Write)
+	fmt.Println(Debug, Info, Warn, Error, Level(2))
+}
@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+//go:generate go run ../../main.go -type Code -case kebab
+
+// Code skips zero, spells an acronym and has an alias
+type Code int
+
+const (
+	_ Code = iota
+	OK
+	NotFound
+	HTTPTimeout
+
+	// Last is another name for HTTPTimeout; it gets no case of its own
+	Last = HTTPTimeout
+)
+
+func main() {
+	fmt.Println(Code(0), OK, NotFound, HTTPTimeout, Last)
+}
@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+//go:generate go run ../../main.go -type State -case kebab
+
+// State is a contiguous enum from 0: String uses an index table
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func main() {
+	fmt.Println(Closed, Open, HalfOpen, State(3), State(-1))
+}
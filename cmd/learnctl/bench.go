@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Benchmarks
+// ==========
+// "learnctl bench" runs the benchmarks of package lessons through
+// tools/benchdiff, which keeps a baseline per machine and compares each
+// run with it:
+//
+//	learnctl bench -save concurrency      store this machine's baselines
+//	learnctl bench --check concurrency    fail when a benchmark regressed
+//
+// The repository has no go.mod, so learnctl cannot import benchdiff as
+// a package; it runs the tool with go run, once for all the lessons,
+// and benchdiff's exit status becomes the command's.
+
+// benchdiff is the tool's path under the root
+const benchdiff = "tools/benchdiff/main.go"
+
+func (l *learnctl) benchCommand() *Command {
+	var (
+		check, save bool
+		count       int
+		bench       string
+		threshold   float64
+		skip        []string
+	)
+	return &Command{
+		Name:  "bench",
+		Args:  "[path...]",
+		Short: "run benchmarks and compare them with this machine's baseline",
+		Long: `Run the benchmarks of each package lesson under the given paths, or
+of all of them, and compare the results with the baseline stored for
+this machine in benchdata/. A change counts when the medians differ by
+more than -threshold percent and a Mann-Whitney test says it is not
+noise. With -check a regression fails the command; with -save the run
+becomes the new baseline. Lessons that need modules outside the
+standard library are skipped unless named exactly.`,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&check, "check", false, "fail if any benchmark regressed")
+			fs.BoolVar(&save, "save", false, "store the results as this machine's baseline")
+			fs.IntVar(&count, "count", 6, "runs of each benchmark; 4 or more to detect anything")
+			fs.StringVar(&bench, "bench", ".", "run only benchmarks matching `regexp`")
+			fs.Float64Var(&threshold, "threshold", 10, "smallest change that counts, in `percent`")
+			listVar(fs, &skip, "skip", "lesson `paths` to skip, comma-separated or repeated")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if check && save {
+				return Usagef("-check and -save exclude each other")
+			}
+			if count < 1 {
+				return Usagef("-count must be at least 1")
+			}
+			lessons, err := l.selectLessons(args)
+			if err != nil {
+				return err
+			}
+			var dirs []string
+			for _, ls := range lessons {
+				switch {
+				case ls.Kind != kindPackage || ls.Benches == 0, slices.Contains(skip, ls.Path):
+					continue
+				case len(ls.Modules) > 0 && !slices.Contains(args, ls.Path):
+					fmt.Fprintln(l.app.Stdout, l.paint("skip", ls.Path, "needs "+strings.Join(ls.Modules, " ")))
+					continue
+				}
+				dirs = append(dirs, ls.Path)
+			}
+			if len(dirs) == 0 {
+				return Usagef("no lessons with benchmarks selected")
+			}
+
+			goArgs := []string{"go", "run", filepath.Join(l.root, filepath.FromSlash(benchdiff)), "-root", l.root,
+				"-count", fmt.Sprint(count), "-bench", bench, "-threshold", fmt.Sprint(threshold)}
+			if check {
+				goArgs = append(goArgs, "-check")
+			}
+			if save {
+				goArgs = append(goArgs, "-save")
+			}
+			return l.exec(ctx, l.root, append(goArgs, dirs...)...)
+		},
+	}
+}
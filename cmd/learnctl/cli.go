@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Subcommands With the flag Package
+// =================================
+// The flag package parses one flat list of flags. Subcommands - "git
+// commit -m", "go test -race" - are built from it with a FlagSet per
+// command:
+//
+//	learnctl -root ~/go-learnings test -race io/streams
+//	         '---- global ----' '-cmd-' '- test's flags and args -'
+//
+// The global FlagSet parses until the first non-flag, which names the
+// command; the command's own FlagSet parses the rest. Each command
+// declares only its flags, gets its own -h, and an unknown flag is
+// reported against the command that did not define it.
+//
+// Three things the flag package leaves to you, and this file adds:
+//
+//   - Environment fallback: a flag not given on the command line is
+//     read from LEARNCTL_<COMMAND>_<FLAG>, so CI can set defaults.
+//     Precedence is flag, then environment, then the default.
+//   - Flags after arguments: flag stops at the first non-flag, so
+//     "test io/streams -race" would see "-race" as a directory.
+//     parseInterspersed keeps going; "--" still ends the flags.
+//   - Exit codes: 0 for success, 1 when the command fails, 2 when it
+//     was called wrongly - the convention flag.ExitOnError follows.
+
+// Command is one subcommand
+type Command struct {
+	Name  string
+	Args  string // the usage line after the flags, such as "dir..."
+	Short string // one line for the command list
+	Long  string // shown by "help <command>"
+
+	// Flags registers the command's flags. Their values are bound to
+	// variables the Run closure reads.
+	Flags func(fs *flag.FlagSet)
+	Run   func(ctx context.Context, args []string) error
+}
+
+// App is a program made of commands
+type App struct {
+	Name      string
+	EnvPrefix string // "LEARNCTL"
+	Stdout    io.Writer
+	Stderr    io.Writer
+	LookupEnv func(string) (string, bool) // os.LookupEnv, or a map in tests
+
+	GlobalFlags func(fs *flag.FlagSet)
+	// Before runs after all flags are parsed and before the command,
+	// to check global flags and resolve what commands share
+	Before   func() error
+	Commands []*Command
+}
+
+// UsageError is returned by a command called wrongly - a missing
+// argument, two flags that exclude each other. The app prints the
+// message and the command's usage and exits with 2.
+type UsageError struct{ msg string }
+
+func (e *UsageError) Error() string { return e.msg }
+
+// Usagef returns a *UsageError
+func Usagef(format string, args ...any) error {
+	return &UsageError{fmt.Sprintf(format, args...)}
+}
+
+// Run parses args, runs the command they name and returns the exit
+// code. It never calls os.Exit, so tests can run it as often as they
+// like.
+func (a *App) Run(ctx context.Context, args []string) int {
+	global := a.flagSet(a.Name, "", a.GlobalFlags)
+	global.Usage = func() { a.printUsage(global) }
+	if err := global.Parse(args); err != nil {
+		return exitCode(err)
+	}
+	if err := a.applyEnv(global, ""); err != nil {
+		fmt.Fprintf(a.Stderr, "%s: %v\n", a.Name, err)
+		return 2
+	}
+
+	args = global.Args()
+	if len(args) == 0 {
+		a.printUsage(global)
+		return 2
+	}
+	name, args := args[0], args[1:]
+	if name == "help" {
+		return a.help(global, args)
+	}
+	cmd := a.lookup(name)
+	if cmd == nil {
+		fmt.Fprintf(a.Stderr, "%s: unknown command %q%s\nRun '%s help' for usage.\n", a.Name, name, a.suggest(name), a.Name)
+		return 2
+	}
+
+	fs := a.flagSet(a.Name+" "+cmd.Name, cmd.Name, cmd.Flags)
+	fs.Usage = func() { a.printCommandUsage(cmd, fs) }
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitCode(err)
+	}
+	if err := a.applyEnv(fs, cmd.Name); err != nil {
+		fmt.Fprintf(a.Stderr, "%s %s: %v\n", a.Name, cmd.Name, err)
+		return 2
+	}
+
+	if a.Before != nil {
+		if err := a.Before(); err != nil {
+			fmt.Fprintf(a.Stderr, "%s: %v\n", a.Name, err)
+			return 1
+		}
+	}
+	err = cmd.Run(ctx, positional)
+	var usage *UsageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage):
+		fmt.Fprintf(a.Stderr, "%s %s: %v\n", a.Name, cmd.Name, err)
+		a.printCommandUsage(cmd, fs)
+		return 2
+	}
+	fmt.Fprintf(a.Stderr, "%s %s: %v\n", a.Name, cmd.Name, err)
+	return 1
+}
+
+// flagSet builds a FlagSet that reports errors instead of exiting, and
+// writes them where the app writes errors
+func (a *App) flagSet(name, command string, register func(*flag.FlagSet)) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	if register != nil {
+		register(fs)
+	}
+	// Name the variable in each flag's help line
+	fs.VisitAll(func(f *flag.Flag) {
+		f.Usage += fmt.Sprintf(" ($%s)", a.envName(command, f.Name))
+	})
+	return fs
+}
+
+// exitCode maps a Parse error: -h asked for help, which is not a
+// failure; anything else was a bad command line. Parse has already
+// printed the message and the usage.
+func exitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+// parseInterspersed parses flags wherever they appear among the
+// arguments and returns the arguments. After "--" everything is an
+// argument, even "-race".
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		// Parse consumed a "--" if one sits right before what is left
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// Environment Fallback
+// ====================
+
+// envName is PREFIX_COMMAND_FLAG in upper case with dashes as
+// underscores: "test" and "-timeout" give LEARNCTL_TEST_TIMEOUT
+func (a *App) envName(command, flagName string) string {
+	parts := []string{a.EnvPrefix}
+	if command != "" {
+		parts = append(parts, command)
+	}
+	parts = append(parts, flagName)
+	return strings.ToUpper(strings.ReplaceAll(strings.Join(parts, "_"), "-", "_"))
+}
+
+// applyEnv sets each flag that was not on the command line from its
+// environment variable. fs.Visit sees only the flags that were set;
+// fs.VisitAll sees every one. Going through fs.Set means the value is
+// parsed and checked exactly as on the command line.
+func (a *App) applyEnv(fs *flag.FlagSet, command string) error {
+	if a.LookupEnv == nil {
+		return nil
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] {
+			return
+		}
+		name := a.envName(command, f.Name)
+		if v, ok := a.LookupEnv(name); ok {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value %q for $%s: %v", v, name, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Help
+// ====
+
+func (a *App) lookup(name string) *Command {
+	for _, c := range a.Commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (a *App) help(global *flag.FlagSet, args []string) int {
+	if len(args) == 0 {
+		global.SetOutput(a.Stdout)
+		a.printUsage(global)
+		return 0
+	}
+	cmd := a.lookup(args[0])
+	if cmd == nil {
+		fmt.Fprintf(a.Stderr, "%s help: unknown command %q%s\n", a.Name, args[0], a.suggest(args[0]))
+		return 2
+	}
+	fs := a.flagSet(a.Name+" "+cmd.Name, cmd.Name, cmd.Flags)
+	fs.SetOutput(a.Stdout)
+	a.printCommandUsage(cmd, fs)
+	return 0
+}
+
+func (a *App) printUsage(global *flag.FlagSet) {
+	w := global.Output()
+	fmt.Fprintf(w, "Usage: %s [flags] <command> [command flags] [args]\n\nCommands:\n", a.Name)
+	width := len("help")
+	for _, c := range a.Commands {
+		width = max(width, len(c.Name))
+	}
+	for _, c := range a.Commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, c.Name, c.Short)
+	}
+	fmt.Fprintf(w, "  %-*s  %s\n", width, "help", "show help for a command")
+	fmt.Fprintln(w, "\nFlags:")
+	global.PrintDefaults()
+	fmt.Fprintf(w, "\nRun '%s help <command>' for a command's flags.\n", a.Name)
+}
+
+func (a *App) printCommandUsage(cmd *Command, fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage: %s %s [flags] %s\n", a.Name, cmd.Name, cmd.Args)
+	if cmd.Long != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(cmd.Long))
+	}
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.PrintDefaults()
+	}
+}
+
+// suggest names the closest command when one is a typo away
+func (a *App) suggest(name string) string {
+	best, bestDist := "", 3
+	for _, c := range a.Commands {
+		if d := editDistance(name, c.Name); d < bestDist {
+			best, bestDist = c.Name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance is the Levenshtein distance, one row at a time
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
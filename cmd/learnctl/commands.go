@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The learnctl Commands
+// =====================
+// Each command is built by a function that declares the command's
+// flag variables as locals: Flags binds them, Run reads them. Nothing
+// is global, so a test can build a fresh app per case.
+
+// version is set at build time:
+//
+//	go build -ldflags "-X main.version=v1.2.0" ./cmd/learnctl
+var version = "dev"
+
+// learnctl holds what the commands share: the global flags, once
+// parsed, and the way they start processes
+type learnctl struct {
+	app    *App
+	root   string
+	color  string
+	level  slog.Level
+	logger *slog.Logger
+
+	// exec runs a command in dir, streaming its output to the app's
+	// writers; tests replace it with a recorder. Leading NAME=value
+	// arguments set environment variables, as in a shell.
+	exec func(ctx context.Context, dir string, args ...string) error
+	// wasmExec finds the Go installation's wasm_exec.js
+	wasmExec func(ctx context.Context) (string, error)
+}
+
+func newApp(stdout, stderr io.Writer, lookupEnv func(string) (string, bool)) (*App, *learnctl) {
+	l := &learnctl{}
+	l.app = &App{
+		Name:      "learnctl",
+		EnvPrefix: "LEARNCTL",
+		Stdout:    stdout,
+		Stderr:    stderr,
+		LookupEnv: lookupEnv,
+		GlobalFlags: func(fs *flag.FlagSet) {
+			fs.StringVar(&l.root, "root", "", "repository root (default: the enclosing git repository)")
+			enumVar(fs, &l.color, "color", "auto", "`when` to colorize output: auto, always or never", "auto", "always", "never")
+			// slog.Level implements encoding.TextUnmarshaler, so
+			// TextVar parses "debug", "warn", "error+2"...
+			fs.TextVar(&l.level, "log-level", slog.LevelWarn, "log level for learnctl's own messages")
+		},
+		Before: l.before,
+	}
+	l.app.Commands = []*Command{l.listCommand(), l.testCommand(), l.benchCommand(), l.runCommand(), l.webCommand(), l.serveCommand(), l.topicsCommand(), l.layoutCommand(), l.versionCommand()}
+	l.exec = l.execCommand
+	l.wasmExec = goWasmExec
+	return l.app, l
+}
+
+// before resolves the root once the flags are parsed
+func (l *learnctl) before() error {
+	l.logger = slog.New(slog.NewTextHandler(l.app.Stderr, &slog.HandlerOptions{Level: l.level}))
+	if l.root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if l.root, err = findRoot(wd); err != nil {
+			return err
+		}
+	}
+	root, err := filepath.Abs(l.root)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("root %s is not a directory", l.root)
+	}
+	l.root = root
+	l.logger.Debug("resolved root", "root", root)
+	return nil
+}
+
+// findRoot walks up from dir to the directory holding .git
+func findRoot(dir string) (string, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d, nil
+		}
+		if filepath.Dir(d) == d {
+			return "", fmt.Errorf("no git repository above %s; use -root", dir)
+		}
+	}
+}
+
+// list
+// ====
+
+func (l *learnctl) listCommand() *Command {
+	var format, kind string
+	return &Command{
+		Name:  "list",
+		Args:  "[path...]",
+		Short: "list lessons",
+		Long: `List the lessons under the given paths, or all of them. A package
+lesson is a directory with tests; a program lesson is a file with
+func main.`,
+		Flags: func(fs *flag.FlagSet) {
+			enumVar(fs, &format, "format", "text", "output `format`: text or json", "text", "json")
+			enumVar(fs, &kind, "kind", "all", "`kind` of lessons to list: all, package or program", "all", string(kindPackage), string(kindProgram))
+		},
+		Run: func(ctx context.Context, args []string) error {
+			lessons, err := l.selectLessons(args)
+			if err != nil {
+				return err
+			}
+			if kind != "all" {
+				lessons = slices.DeleteFunc(lessons, func(ls lesson) bool { return string(ls.Kind) != kind })
+			}
+			if format == "json" {
+				enc := json.NewEncoder(l.app.Stdout)
+				enc.SetIndent("", "  ")
+				if lessons == nil {
+					lessons = []lesson{}
+				}
+				return enc.Encode(lessons)
+			}
+			tw := tabwriter.NewWriter(l.app.Stdout, 0, 8, 2, ' ', 0)
+			for _, ls := range lessons {
+				var notes []string
+				if ls.Tests > 0 {
+					notes = append(notes, fmt.Sprintf("%d test files", ls.Tests))
+				}
+				if ls.Benches > 0 {
+					notes = append(notes, fmt.Sprintf("%d benchmarks", ls.Benches))
+				}
+				if len(ls.Modules) > 0 {
+					notes = append(notes, "needs "+strings.Join(ls.Modules, " "))
+				}
+				if ls.Error != "" {
+					notes = append(notes, "does not parse")
+				}
+				note := strings.Join(notes, ", ")
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", ls.Path, ls.Kind, note)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+// selectLessons returns the lessons under any of paths, relative to the
+// root; no paths selects everything. A path matching nothing is an
+// error, not an empty result - it is most likely a typo.
+func (l *learnctl) selectLessons(paths []string) ([]lesson, error) {
+	all, err := findLessons(l.root)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return all, nil
+	}
+	var out []lesson
+	for _, p := range paths {
+		p = strings.Trim(filepath.ToSlash(filepath.Clean(p)), "/")
+		n := len(out)
+		for _, ls := range all {
+			if p == "." || ls.Path == p || strings.HasPrefix(ls.Path, p+"/") {
+				if !slices.ContainsFunc(out, func(o lesson) bool { return o.Path == ls.Path }) {
+					out = append(out, ls)
+				}
+			}
+		}
+		if len(out) == n {
+			return nil, Usagef("no lessons under %q", p)
+		}
+	}
+	return out, nil
+}
+
+// test
+// ====
+
+func (l *learnctl) testCommand() *Command {
+	var (
+		race, verbose, short, failfast bool
+		run                            string
+		timeout                        time.Duration
+		skip                           []string
+	)
+	return &Command{
+		Name:  "test",
+		Args:  "[path...]",
+		Short: "run the tests of package lessons",
+		Long: `Run "go test *.go" in each package lesson under the given paths, or
+in all of them, and print a summary. Lessons that need modules outside
+the standard library are skipped unless named exactly.`,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&race, "race", false, "enable the race detector")
+			fs.BoolVar(&verbose, "v", false, "verbose test output")
+			fs.BoolVar(&short, "short", false, "pass -short to the tests")
+			fs.BoolVar(&failfast, "failfast", false, "stop after the first failing lesson")
+			fs.StringVar(&run, "run", "", "run only tests matching `regexp`")
+			fs.DurationVar(&timeout, "timeout", 10*time.Minute, "time limit for each lesson")
+			listVar(fs, &skip, "skip", "lesson `paths` to skip, comma-separated or repeated")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			lessons, err := l.selectLessons(args)
+			if err != nil {
+				return err
+			}
+			goArgs := []string{"go", "test", "-timeout", timeout.String()}
+			if race {
+				goArgs = append(goArgs, "-race")
+			}
+			if verbose {
+				goArgs = append(goArgs, "-v")
+			}
+			if short {
+				goArgs = append(goArgs, "-short")
+			}
+			if run != "" {
+				goArgs = append(goArgs, "-run", run)
+			}
+
+			var summary []string
+			var failed, ran int
+			for _, ls := range lessons {
+				switch {
+				case ls.Kind != kindPackage || ls.Tests == 0:
+					continue
+				case slices.Contains(skip, ls.Path):
+					summary = append(summary, l.paint("skip", ls.Path, "-skip"))
+					continue
+				case len(ls.Modules) > 0 && !slices.Contains(args, ls.Path):
+					summary = append(summary, l.paint("skip", ls.Path, "needs "+strings.Join(ls.Modules, " ")))
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				dir := filepath.Join(l.root, filepath.FromSlash(ls.Path))
+				files, err := goFiles(dir, true)
+				if err != nil {
+					return err
+				}
+				start := time.Now()
+				ran++
+				err = l.exec(ctx, dir, append(slices.Clone(goArgs), files...)...)
+				elapsed := time.Since(start).Round(10 * time.Millisecond).String()
+				if err != nil {
+					failed++
+					summary = append(summary, l.paint("FAIL", ls.Path, elapsed))
+					if failfast {
+						break
+					}
+					continue
+				}
+				summary = append(summary, l.paint("ok", ls.Path, elapsed))
+			}
+
+			fmt.Fprintln(l.app.Stdout)
+			for _, line := range summary {
+				fmt.Fprintln(l.app.Stdout, line)
+			}
+			if ran == 0 {
+				return Usagef("no package lessons with tests selected")
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d lessons failed", failed, ran)
+			}
+			return nil
+		},
+	}
+}
+
+// run
+// ===
+
+func (l *learnctl) runCommand() *Command {
+	return &Command{
+		Name:  "run",
+		Args:  "file.go [-- program args]",
+		Short: "run a program lesson",
+		Long: `Run a program lesson with "go run", from its own directory, so paths
+the lesson opens resolve as they do in its README. Files in the same
+directory that are not programs themselves - generated code, shared
+helpers - are included when the program needs them. Arguments after "--" go to the program.`,
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) == 0 {
+				return Usagef("run needs a lesson file")
+			}
+			path, progArgs := args[0], args[1:]
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(l.root, path)
+			}
+			if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return Usagef("%s is not a program file", args[0])
+			}
+			if _, err := os.Stat(path); err != nil {
+				return err
+			}
+			dir := filepath.Dir(path)
+			lessons, err := findLessons(dir)
+			if err != nil {
+				return err
+			}
+			files, err := programFiles(path, lessons)
+			if err != nil {
+				return err
+			}
+			goArgs := append([]string{"go", "run"}, files...)
+			return l.exec(ctx, dir, append(goArgs, progArgs...)...)
+		},
+	}
+}
+
+// version
+// =======
+
+func (l *learnctl) versionCommand() *Command {
+	return &Command{
+		Name:  "version",
+		Short: "print learnctl's version",
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) > 0 {
+				return Usagef("version takes no arguments")
+			}
+			fmt.Fprintf(l.app.Stdout, "learnctl %s (%s %s/%s)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+			return nil
+		},
+	}
+}
+
+// Helpers
+// =======
+
+// goFiles lists the .go files in dir by name, with or without tests -
+// the glob a shell would expand from *.go
+func goFiles(dir string, tests bool) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, m := range matches {
+		if tests || !strings.HasSuffix(m, "_test.go") {
+			files = append(files, filepath.Base(m))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return files, nil
+}
+
+// execCommand starts a real process. On cancellation - Ctrl-C - the
+// child gets os.Interrupt and a few seconds to finish before it is
+// killed.
+func (l *learnctl) execCommand(ctx context.Context, dir string, args ...string) error {
+	l.logger.Debug("exec", "dir", dir, "args", args)
+	env := os.Environ()
+	for len(args) > 0 && strings.Contains(args[0], "=") {
+		env = append(env, args[0])
+		args = args[1:]
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = l.app.Stdout
+	cmd.Stderr = l.app.Stderr
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 5 * time.Second
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%s exited with %d", strings.Join(args[:2], " "), exitErr.ExitCode())
+	}
+	return err
+}
+
+// paint formats a summary line, in color when -color says so
+func (l *learnctl) paint(status, path, note string) string {
+	line := fmt.Sprintf("%-4s  %-32s %s", status, path, note)
+	if !l.useColor() {
+		return line
+	}
+	code := map[string]string{"ok": "32", "FAIL": "31", "skip": "33"}[status]
+	return "\x1b[" + code + "m" + line + "\x1b[0m"
+}
+
+// useColor: always and never are absolute; auto colors a terminal
+// unless NO_COLOR is set (https://no-color.org)
+func (l *learnctl) useColor() bool {
+	switch l.color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if l.app.LookupEnv != nil {
+		if _, ok := l.app.LookupEnv("NO_COLOR"); ok {
+			return false
+		}
+	}
+	f, ok := l.app.Stdout.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+)
+
+// Struct Layouts
+// ==============
+// "learnctl layout" draws the memory layout of struct types with the
+// visualizer in structs/go_layout_visualizer.go:
+//
+//	learnctl layout structs.ExampleStruct           a type the visualizer compiles in
+//	learnctl layout storage.conn slices-maps/trie.node
+//	learnctl layout -svg /tmp/l.svg structs/go_structs.go.Employee
+//
+// As with bench, learnctl cannot import a package main, so it runs the
+// visualizer with go run from the root, and its exit status becomes the
+// command's.
+
+// visualizer is the layout tool's path under the root
+const visualizer = "structs/go_layout_visualizer.go"
+
+func (l *learnctl) layoutCommand() *Command {
+	var svg string
+	return &Command{
+		Name:  "layout",
+		Args:  "[type...]",
+		Short: "draw the memory layout of struct types",
+		Long: `Draw the field offsets, sizes and padding of each type as an ASCII
+diagram. A type is <dir>.<Type> or <file.go>.<Type>, relative to the
+root, and is laid out from source with the gc compiler's sizes for this
+GOARCH; the visualizer's own example types need no path. With no types
+it draws those examples. -svg also writes the diagrams as one SVG.`,
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&svg, "svg", "", "also write an SVG diagram to `file`")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			goArgs := []string{"go", "run", filepath.Join(l.root, filepath.FromSlash(visualizer)), "-root", l.root}
+			if svg != "" {
+				// go run executes in the root: keep a relative path the
+				// user's, not the root's
+				abs, err := filepath.Abs(svg)
+				if err != nil {
+					return err
+				}
+				goArgs = append(goArgs, "-svg", abs)
+			}
+			return l.exec(ctx, l.root, append(goArgs, args...)...)
+		},
+	}
+}
@@ -0,0 +1,880 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// learnctl - Tests
+// ================
+// Run with:
+//
+//   cd cmd/learnctl
+//   go test -v *.go
+//
+// Every test builds a fresh app with buffers for output, a map for the
+// environment and a recorder in place of exec, then calls Run with a
+// command line - main without the process.
+
+// 1. Test Helpers
+// ===============
+
+type harness struct {
+	app    *App
+	l      *learnctl
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	env    map[string]string
+	calls  []call
+	fail   map[string]bool // lesson dirs whose exec fails
+	mu     sync.Mutex      // serve execs two programs at once
+}
+
+type call struct {
+	dir  string
+	args []string
+}
+
+func newHarness(t *testing.T, root string) *harness {
+	t.Helper()
+	h := &harness{env: map[string]string{}, fail: map[string]bool{}}
+	if root != "" {
+		h.env["LEARNCTL_ROOT"] = root
+	}
+	lookup := func(k string) (string, bool) { v, ok := h.env[k]; return v, ok }
+	h.app, h.l = newApp(&h.stdout, &h.stderr, lookup)
+	h.l.exec = func(ctx context.Context, dir string, args ...string) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.calls = append(h.calls, call{dir, args})
+		if h.fail[filepath.Base(dir)] {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+	return h
+}
+
+func (h *harness) run(args ...string) int {
+	h.stdout.Reset()
+	h.stderr.Reset()
+	h.calls = nil
+	return h.app.Run(context.Background(), args)
+}
+
+// writeTree creates files under a temporary root: a package lesson
+// "alpha", one needing a module "beta", a directory of programs with a
+// shared helper, and a browser lesson in alpha's testdata
+func writeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"alpha/alpha.go":         "package alpha\n",
+		"alpha/alpha_test.go":    "package alpha\n\nimport \"testing\"\n\nfunc BenchmarkA(b *testing.B) {}\n",
+		"beta/beta.go":           "package beta\n\nimport _ \"example.com/mod/x\"\n",
+		"beta/beta_test.go":      "package beta\n",
+		"progs/one.go":           "package main\n\nfunc main() { helper() }\n",
+		"progs/two.go":           "package main\n\nimport \"fmt\"\n\ntype Kind int\n\nfunc main() { fmt.Println(Kind(0)) }\n",
+		"progs/helper.go":        "package main\n\nfunc helper() {}\n",
+		"progs/kind_string.go":   "package main\n\nfunc (k Kind) String() string { return \"\" }\n",
+		"progs/broken.go":        "package main\n\nfunc main( {\n",
+		".hidden/skip.go":        "package skip\n",
+		"alpha/testdata/data.go": "package data\n",
+		// A browser lesson, and an index.html without one
+		"alpha/testdata/web/index.html": "<h1>web</h1>\n",
+		"alpha/testdata/web/main.go":    "package main\n\nimport \"syscall/js\"\n\nfunc main() { js.Global() }\n",
+		"progs/index.html":              "<h1>not wasm</h1>\n",
+		// The topic index the go/ast lesson writes
+		"topics.json": `[
+  {"path": "alpha/alpha.go", "title": "Alpha - Unsafe Tricks", "sections": ["1. Sizes", "2. Pointers"]},
+  {"path": "progs/one.go", "title": "One Program", "sections": ["1. Unsafe Sizes", "2. Maps"]}
+]`,
+	}
+	for name, body := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// 2. Dispatch and Exit Codes
+// ==========================
+
+func TestDispatch(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+	tests := []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{nil, 2, "Commands:"},
+		{[]string{"version"}, 0, ""},
+		{[]string{"version", "extra"}, 2, "takes no arguments"},
+		{[]string{"-h"}, 0, "Usage: learnctl"},
+		{[]string{"list", "-h"}, 0, "Usage: learnctl list"},
+		{[]string{"help", "nope"}, 2, "unknown command"},
+		{[]string{"lsit"}, 2, `did you mean "list"?`},
+		{[]string{"list", "-bogus"}, 2, "flag provided but not defined: -bogus"},
+		{[]string{"-bogus", "list"}, 2, "flag provided but not defined: -bogus"},
+		{[]string{"list", "missing"}, 2, `no lessons under "missing"`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if code := h.run(tt.args...); code != tt.code {
+				t.Errorf("exit code %d, want %d; stderr:\n%s", code, tt.code, &h.stderr)
+			}
+			if !strings.Contains(h.stderr.String(), tt.stderr) {
+				t.Errorf("stderr %q, want it to contain %q", &h.stderr, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.run("version")
+	if got := h.stdout.String(); !strings.HasPrefix(got, "learnctl dev (go") {
+		t.Errorf("version printed %q", got)
+	}
+}
+
+func TestRootMustExist(t *testing.T) {
+	h := newHarness(t, filepath.Join(t.TempDir(), "missing"))
+	if code := h.run("version"); code != 1 || !strings.Contains(h.stderr.String(), "not a directory") {
+		t.Errorf("exit code %d, stderr %q", code, &h.stderr)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	root := t.TempDir()
+	deep := filepath.Join(root, "a", "b")
+	os.MkdirAll(deep, 0o755)
+	os.Mkdir(filepath.Join(root, ".git"), 0o755)
+
+	got, err := findRoot(deep)
+	if err != nil || got != root {
+		t.Errorf("findRoot = %q, %v; want %q", got, err, root)
+	}
+}
+
+// 3. Environment Fallback
+// =======================
+
+func TestEnvPrecedence(t *testing.T) {
+	root := writeTree(t)
+	h := newHarness(t, root)
+
+	// Default: 10m
+	h.run("test", "alpha")
+	if got := h.calls[0].args; !slices.Contains(got, "10m0s") {
+		t.Errorf("default: %v", got)
+	}
+
+	// The environment beats the default
+	h.env["LEARNCTL_TEST_TIMEOUT"] = "30s"
+	h.env["LEARNCTL_TEST_RACE"] = "true"
+	h.run("test", "alpha")
+	if got := h.calls[0].args; !slices.Contains(got, "30s") || !slices.Contains(got, "-race") {
+		t.Errorf("env: %v", got)
+	}
+
+	// The command line beats the environment
+	h.run("test", "-timeout=1m", "-race=false", "alpha")
+	if got := h.calls[0].args; !slices.Contains(got, "1m0s") || slices.Contains(got, "-race") {
+		t.Errorf("flag: %v", got)
+	}
+}
+
+func TestEnvInvalid(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+	h.env["LEARNCTL_TEST_TIMEOUT"] = "soon"
+	if code := h.run("test", "alpha"); code != 2 {
+		t.Errorf("exit code %d, want 2", code)
+	}
+	if !strings.Contains(h.stderr.String(), "$LEARNCTL_TEST_TIMEOUT") {
+		t.Errorf("stderr does not name the variable: %q", &h.stderr)
+	}
+	if len(h.calls) != 0 {
+		t.Errorf("ran %v despite the bad value", h.calls)
+	}
+}
+
+func TestEnvGlobalFlags(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+	h.env["LEARNCTL_LOG_LEVEL"] = "debug"
+	h.run("list")
+	if h.l.level != -4 {
+		t.Errorf("log level %v, want DEBUG", h.l.level)
+	}
+	if !strings.Contains(h.stderr.String(), "resolved root") {
+		t.Errorf("no debug output: %q", &h.stderr)
+	}
+}
+
+func TestEnvNameInHelp(t *testing.T) {
+	h := newHarness(t, "")
+	// Asked for, help goes to stdout, so it can be piped to a pager
+	h.run("help", "test")
+	for _, want := range []string{"-race", "$LEARNCTL_TEST_TIMEOUT", "$LEARNCTL_TEST_SKIP"} {
+		if !strings.Contains(h.stdout.String(), want) {
+			t.Errorf("help does not mention %s", want)
+		}
+	}
+}
+
+// 4. Parsing
+// ==========
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		args []string
+		pos  []string
+		v    bool
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, false},
+		{[]string{"-v", "a"}, []string{"a"}, true},
+		{[]string{"a", "-v", "b"}, []string{"a", "b"}, true},
+		{[]string{"a", "--", "-v"}, []string{"a", "-v"}, false},
+		{[]string{"--", "a"}, []string{"a"}, false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("t", flag.ContinueOnError)
+		v := fs.Bool("v", false, "")
+		pos, err := parseInterspersed(fs, tt.args)
+		if err != nil || !slices.Equal(pos, tt.pos) || *v != tt.v {
+			t.Errorf("%q: got %q, v=%v, %v; want %q, v=%v", tt.args, pos, *v, err, tt.pos, tt.v)
+		}
+	}
+}
+
+func TestListValue(t *testing.T) {
+	l := []string{"stale"}
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	listVar(fs, &l, "skip", "")
+	if err := fs.Parse([]string{"-skip", "a,b", "-skip=c", "-skip", " d , "}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c", "d"}; !slices.Equal(l, want) {
+		t.Errorf("got %q, want %q", l, want)
+	}
+	if got := fs.Lookup("skip").Value.String(); got != "a,b,c,d" {
+		t.Errorf("String = %q", got)
+	}
+}
+
+func TestEnumValue(t *testing.T) {
+	color := "stale"
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	enumVar(fs, &color, "color", "auto", "", "auto", "always", "never")
+	if color != "auto" {
+		t.Errorf("default %q, want auto", color)
+	}
+	if err := fs.Set("color", "always"); err != nil || color != "always" {
+		t.Errorf("Set(always): %v, %q", err, color)
+	}
+	err := fs.Set("color", "sometimes")
+	if err == nil || !strings.Contains(err.Error(), "auto, always, never") {
+		t.Errorf("Set(sometimes) = %v", err)
+	}
+	if got := fs.Lookup("color").Value.(flag.Getter).Get(); got != "always" {
+		t.Errorf("a failed Set changed the value to %q", got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{{"", "", 0}, {"test", "test", 0}, {"tset", "test", 2}, {"lst", "list", 1}, {"", "run", 3}} {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// 5. Finding Lessons
+// ==================
+
+func TestFindLessons(t *testing.T) {
+	lessons, err := findLessons(writeTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ls := range lessons {
+		got = append(got, fmt.Sprintf("%s %s %d %v %t", ls.Path, ls.Kind, ls.Tests, ls.Modules, ls.Error != ""))
+	}
+	want := []string{
+		"alpha package 1 [] false",
+		"beta package 1 [example.com/mod/x] false",
+		"progs/broken.go program 0 [] true",
+		"progs/one.go program 0 [] false",
+		"progs/two.go program 0 [] false",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+
+	h.run("list", "-kind", "program", "-format=json")
+	var lessons []lesson
+	if err := json.Unmarshal(h.stdout.Bytes(), &lessons); err != nil {
+		t.Fatal(err)
+	}
+	if len(lessons) != 3 || lessons[0].Kind != kindProgram {
+		t.Errorf("got %+v", lessons)
+	}
+
+	h.run("list", "beta")
+	if got := h.stdout.String(); !strings.Contains(got, "needs example.com/mod/x") || strings.Contains(got, "alpha") {
+		t.Errorf("list beta:\n%s", got)
+	}
+
+	h.env["LEARNCTL_LIST_KIND"] = "package"
+	h.run("list", "-format", "json", "progs")
+	if got := strings.TrimSpace(h.stdout.String()); got != "[]" {
+		t.Errorf("filtered to nothing, got %s", got)
+	}
+}
+
+// 6. Commands
+// ===========
+
+func TestTestCommand(t *testing.T) {
+	root := writeTree(t)
+	h := newHarness(t, root)
+
+	// beta needs a module, so a bare "test" skips it
+	if code := h.run("test", "-v", "-run", "TestX"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	if len(h.calls) != 1 {
+		t.Fatalf("calls %v, want alpha only", h.calls)
+	}
+	c := h.calls[0]
+	want := []string{"go", "test", "-timeout", "10m0s", "-v", "-run", "TestX", "alpha.go", "alpha_test.go"}
+	if c.dir != filepath.Join(root, "alpha") || !slices.Equal(c.args, want) {
+		t.Errorf("ran %q in %s, want %q", c.args, c.dir, want)
+	}
+	if out := h.stdout.String(); !strings.Contains(out, "ok    alpha") || !strings.Contains(out, "skip  beta") {
+		t.Errorf("summary:\n%s", out)
+	}
+
+	// Named exactly, it runs
+	h.run("test", "beta")
+	if len(h.calls) != 1 || filepath.Base(h.calls[0].dir) != "beta" {
+		t.Errorf("calls %v, want beta", h.calls)
+	}
+
+	// -skip is a list
+	if code := h.run("test", "-skip", "alpha,beta", "alpha", "beta"); code != 2 || len(h.calls) != 0 {
+		t.Errorf("exit code %d, calls %v", code, h.calls)
+	}
+}
+
+func TestTestCommandFailure(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+	h.fail["alpha"] = true
+
+	if code := h.run("test", "alpha", "beta"); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if len(h.calls) != 2 {
+		t.Errorf("calls %v, want both", h.calls)
+	}
+	if !strings.Contains(h.stderr.String(), "1 of 2 lessons failed") {
+		t.Errorf("stderr %q", &h.stderr)
+	}
+
+	h.run("test", "-failfast", "alpha", "beta")
+	if len(h.calls) != 1 {
+		t.Errorf("-failfast ran %v", h.calls)
+	}
+}
+
+func TestTestCommandCanceled(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if code := h.app.Run(ctx, []string{"test", "alpha"}); code != 1 || len(h.calls) != 0 {
+		t.Errorf("exit code %d, calls %v", code, h.calls)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	root := writeTree(t)
+	h := newHarness(t, root)
+
+	if code := h.run("run", "progs/one.go", "--", "-n", "3"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	// The helper comes along; the other programs and two.go's String
+	// method do not
+	want := []string{"go", "run", "one.go", "helper.go", "-n", "3"}
+	if c := h.calls[0]; c.dir != filepath.Join(root, "progs") || !slices.Equal(c.args, want) {
+		t.Errorf("ran %q in %s, want %q", c.args, c.dir, want)
+	}
+	if code := h.run("run", "progs/two.go"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	if want := []string{"go", "run", "two.go", "kind_string.go"}; !slices.Equal(h.calls[0].args, want) {
+		t.Errorf("ran %q, want %q", h.calls[0].args, want)
+	}
+
+	for _, args := range [][]string{{"run"}, {"run", "alpha/alpha_test.go"}, {"run", "alpha"}} {
+		if code := h.run(args...); code != 2 {
+			t.Errorf("%q: exit code %d, want 2", args, code)
+		}
+	}
+}
+
+func TestBenchCommand(t *testing.T) {
+	root := writeTree(t)
+	h := newHarness(t, root)
+
+	// Only alpha has a benchmark; benchdiff runs once, in the root
+	if code := h.run("bench", "--check"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	want := []string{"go", "run", filepath.Join(root, "tools", "benchdiff", "main.go"), "-root", root,
+		"-count", "6", "-bench", ".", "-threshold", "10", "-check", "alpha"}
+	if len(h.calls) != 1 || h.calls[0].dir != root || !slices.Equal(h.calls[0].args, want) {
+		t.Errorf("calls %v, want %q in %s", h.calls, want, root)
+	}
+
+	// A regression is benchdiff exiting 1
+	h.fail[filepath.Base(root)] = true
+	if code := h.run("bench", "-check", "alpha"); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+
+	for _, args := range [][]string{{"bench", "-check", "-save"}, {"bench", "-count", "0"}, {"bench", "progs"}, {"bench", "-skip", "alpha"}} {
+		if code := h.run(args...); code != 2 || len(h.calls) != 0 {
+			t.Errorf("%q: exit code %d, calls %v", args, code, h.calls)
+		}
+	}
+}
+
+func TestLayoutCommand(t *testing.T) {
+	root := writeTree(t)
+	h := newHarness(t, root)
+
+	// The visualizer runs once, in the root, with the types as given
+	if code := h.run("layout", "structs.ExampleStruct", "storage.conn"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	want := []string{"go", "run", filepath.Join(root, "structs", "go_layout_visualizer.go"), "-root", root,
+		"structs.ExampleStruct", "storage.conn"}
+	if len(h.calls) != 1 || h.calls[0].dir != root || !slices.Equal(h.calls[0].args, want) {
+		t.Errorf("calls %v, want %q in %s", h.calls, want, root)
+	}
+
+	// -svg is relative to where learnctl runs, not to the root
+	h.run("layout", "-svg", "l.svg")
+	abs, _ := filepath.Abs("l.svg")
+	if len(h.calls) != 1 || !slices.Contains(h.calls[0].args, abs) {
+		t.Errorf("calls %v, want -svg %s", h.calls, abs)
+	}
+
+	// An unknown type is the visualizer exiting 1
+	h.fail[filepath.Base(root)] = true
+	if code := h.run("layout", "nope.T"); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+}
+
+func TestServeCommand(t *testing.T) {
+	root := writeTree(t)
+	events := filepath.Join(root, "web", "events")
+	os.MkdirAll(events, 0o755)
+	os.WriteFile(filepath.Join(events, "sse.go"), []byte("package events\n"), 0o644)
+	h := newHarness(t, root)
+	var lessons []byte
+	record := h.l.exec
+	h.l.exec = func(ctx context.Context, dir string, args ...string) error {
+		if data, err := os.ReadFile(filepath.Join(dir, "lessons.txt")); err == nil {
+			lessons = data
+		}
+		return record(ctx, dir, args...)
+	}
+
+	if code := h.run("serve", "-addr", "localhost:9000"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	// Two programs: the embed lesson's quiz site, from its own
+	// directory, and the run buttons in a sandbox, in front of it
+	if len(h.calls) != 2 {
+		t.Fatalf("calls %v, want two", h.calls)
+	}
+	slices.SortFunc(h.calls, func(a, b call) int { return strings.Compare(filepath.Base(a.dir), filepath.Base(b.dir)) })
+	quiz, buttons := h.calls[1], h.calls[0]
+	if quiz.dir != filepath.Join(root, "os-files") || !slices.Equal(quiz.args[:5], []string{"go", "run", "go_embed.go", "serve", "-addr"}) {
+		t.Errorf("quiz site: %q in %s", quiz.args, quiz.dir)
+	}
+	self, _ := os.Executable()
+	want := append(slices.Clone(sandboxEnv), "go", "run", ".", "-addr", "localhost:9000", "-quiz", "http://"+quiz.args[5], self, "-root", root, "run")
+	if !strings.HasPrefix(filepath.Base(buttons.dir), "learnctl-serve-") || !slices.Equal(buttons.args, want) {
+		t.Errorf("run buttons: %q in %s, want %q", buttons.args, buttons.dir, want)
+	}
+	// Only programs that parse and build without a module get a button
+	if want := "progs/one.go\nprogs/two.go\n"; string(lessons) != want {
+		t.Errorf("lessons.txt:\n%s\nwant:\n%s", lessons, want)
+	}
+
+	// A site that fails to start fails the command; Ctrl-C does not
+	h.fail["os-files"] = true
+	if code := h.run("serve"); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if code := h.app.Run(ctx, []string{"serve"}); code != 0 {
+		t.Errorf("interrupted: exit code %d, want 0", code)
+	}
+
+	if code := h.run("serve", "quiz"); code != 2 {
+		t.Errorf("exit code %d, want 2", code)
+	}
+}
+
+// The run buttons build against the real web/events, and pass their
+// own tests there
+func TestServeSandbox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a sandbox module")
+	}
+	root, _ := filepath.Abs(filepath.Join("..", ".."))
+	h := newHarness(t, root)
+	h.run("version") // resolves the root and sets up the logger
+	l := h.l
+	dir, err := l.newSandbox("serve", "web/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	for _, sub := range []string{"vet", "test"} {
+		args := append(slices.Clone(sandboxEnv), "go", sub, ".")
+		if err := l.execCommand(ctx, dir, args...); err != nil {
+			t.Errorf("go %s: %v\n%s%s", sub, err, &h.stdout, &h.stderr)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+	h.run("-color=always", "test", "alpha")
+	if !strings.Contains(h.stdout.String(), "\x1b[32mok") {
+		t.Errorf("no color with -color=always:\n%q", &h.stdout)
+	}
+	// auto never colors a buffer
+	h.run("test", "alpha")
+	if strings.Contains(h.stdout.String(), "\x1b[") {
+		t.Errorf("color with -color=auto into a buffer:\n%q", &h.stdout)
+	}
+}
+
+// 7. Web Mode
+// ===========
+
+func TestFindBrowserLessons(t *testing.T) {
+	got, err := findBrowserLessons(writeTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alpha/testdata/web"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWebCommand(t *testing.T) {
+	root := writeTree(t)
+	h := newHarness(t, root)
+	glue := filepath.Join(t.TempDir(), "wasm_exec.js")
+	h.l.wasmExec = func(context.Context) (string, error) { return glue, nil }
+
+	// The server runs until the context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if code := h.app.Run(ctx, []string{"web", "-addr", "127.0.0.1:0"}); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	if len(h.calls) != 1 {
+		t.Fatalf("calls %v, want one build", h.calls)
+	}
+	c := h.calls[0]
+	if c.dir != filepath.Join(root, "alpha", "testdata", "web") ||
+		!slices.Equal(c.args[:5], []string{"GOOS=js", "GOARCH=wasm", "go", "build", "-o"}) ||
+		!strings.HasSuffix(c.args[5], filepath.Join("alpha", "testdata", "web", "main.wasm")) ||
+		!slices.Equal(c.args[6:], []string{"main.go"}) {
+		t.Errorf("ran %q in %s", c.args, c.dir)
+	}
+	if out := h.stdout.String(); !strings.Contains(out, "serving 1 browser lessons at http://127.0.0.1:") {
+		t.Errorf("stdout:\n%s", out)
+	}
+
+	if code := h.run("web", "progs"); code != 2 || !strings.Contains(h.stderr.String(), `no browser lessons under "progs"`) {
+		t.Errorf("exit code %d, stderr:\n%s", code, &h.stderr)
+	}
+}
+
+func TestWebHandler(t *testing.T) {
+	root := writeTree(t)
+	build := t.TempDir()
+	wasmDir := filepath.Join(build, "alpha", "testdata", "web")
+	os.MkdirAll(wasmDir, 0o755)
+	os.WriteFile(filepath.Join(wasmDir, "main.wasm"), []byte("\x00asm"), 0o644)
+	glue := filepath.Join(t.TempDir(), "wasm_exec.js")
+	os.WriteFile(glue, []byte("globalThis.Go = class {};\n"), 0o644)
+	h := webHandler(root, build, glue, []string{"alpha/testdata/web"})
+
+	tests := []struct {
+		path, body, contentType string
+		code                    int
+	}{
+		{"/", `href="/alpha/testdata/web/"`, "text/html", 200},
+		{"/alpha/testdata/web/", "<h1>web</h1>", "text/html", 200},
+		{"/alpha/testdata/web/main.wasm", "\x00asm", "application/wasm", 200},
+		{"/alpha/testdata/web/wasm_exec.js", "globalThis.Go", "javascript", 200},
+		{"/alpha/testdata/web/main.go", "syscall/js", "", 200},
+		{"/progs/", "", "", 404},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), tt.body) ||
+			!strings.Contains(rec.Header().Get("Content-Type"), tt.contentType) {
+			t.Errorf("%s: %d %q\n%s", tt.path, rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+	}
+}
+
+// 8. The Real Runner
+// ==================
+
+func TestExecCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts processes")
+	}
+	h := newHarness(t, t.TempDir())
+	h.run("version") // resolves the root and sets up the logger
+	l := h.l
+	l.exec = l.execCommand
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := l.exec(ctx, t.TempDir(), "go", "env", "GOOS"); err != nil {
+		t.Fatal(err)
+	}
+	// Leading NAME=value arguments are the child's environment
+	h.stdout.Reset()
+	if err := l.exec(ctx, t.TempDir(), "GOOS=plan9", "go", "env", "GOOS"); err != nil || h.stdout.String() != "plan9\n" {
+		t.Errorf("GOOS=plan9 go env GOOS: %q, %v", &h.stdout, err)
+	}
+	err := l.exec(ctx, t.TempDir(), "go", "vet", "missing.go")
+	if err == nil || !strings.Contains(err.Error(), "go vet exited with 1") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// TestRunBuildsLessons builds every program lesson in the repository
+// with the files run would hand to go run
+func TestRunBuildsLessons(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts processes")
+	}
+	root := filepath.Join("..", "..")
+	lessons, err := findLessons(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	for _, ls := range lessons {
+		if ls.Kind != kindProgram || ls.Error != "" || len(ls.Modules) > 0 {
+			continue
+		}
+		t.Run(ls.Path, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(root, filepath.FromSlash(ls.Path))
+			dir := filepath.Dir(path)
+			dirLessons, err := findLessons(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := programFiles(path, dirLessons)
+			if err != nil {
+				t.Fatal(err)
+			}
+			build := func(files ...string) ([]byte, error) {
+				bin := filepath.Join(out, strings.ReplaceAll(ls.Path, "/", "_"))
+				cmd := exec.Command("go", append([]string{"build", "-o", bin}, files...)...)
+				cmd.Dir = dir
+				return cmd.CombinedOutput()
+			}
+			if b, err := build(files...); err != nil {
+				// Only what run adds is under test here; a program that
+				// fails on its own is its lesson's problem
+				if len(files) == 1 {
+					t.Skipf("%s does not build:\n%s", ls.Path, b)
+				}
+				t.Errorf("go build %s: %v\n%s", strings.Join(files, " "), err, b)
+			}
+		})
+	}
+}
+
+// 9. Examples
+// ===========
+
+func Example() {
+	root, _ := os.MkdirTemp("", "learnctl")
+	defer os.RemoveAll(root)
+	os.MkdirAll(filepath.Join(root, "hello"), 0o755)
+	os.WriteFile(filepath.Join(root, "hello", "hello.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+
+	var out bytes.Buffer
+	app, _ := newApp(&out, &out, func(string) (string, bool) { return "", false })
+	code := app.Run(context.Background(), []string{"-root", root, "list", "-format=json"})
+	fmt.Print(out.String())
+	fmt.Println("exit", code)
+	// Output:
+	// [
+	//   {
+	//     "path": "hello/hello.go",
+	//     "kind": "program",
+	//     "files": 1,
+	//     "tests": 0
+	//   }
+	// ]
+	// exit 0
+}
+
+// 8. Topics
+// =========
+
+func TestTopicsCommand(t *testing.T) {
+	h := newHarness(t, writeTree(t))
+	tests := []struct {
+		args []string
+		want string
+	}{
+		// No words: everything
+		{[]string{"topics"}, "alpha/alpha.go  Alpha - Unsafe Tricks\n    1. Sizes\n    2. Pointers\nprogs/one.go  One Program\n    1. Unsafe Sizes\n    2. Maps\n"},
+		// A title match keeps all sections; a section match keeps only it
+		{[]string{"topics", "UNSAFE"}, "alpha/alpha.go  Alpha - Unsafe Tricks\n    1. Sizes\n    2. Pointers\nprogs/one.go  One Program\n    1. Unsafe Sizes\n"},
+		// Every word must match
+		{[]string{"topics", "unsafe", "sizes"}, "alpha/alpha.go  Alpha - Unsafe Tricks\n    1. Sizes\nprogs/one.go  One Program\n    1. Unsafe Sizes\n"},
+		{[]string{"topics", "progs"}, "progs/one.go  One Program\n    1. Unsafe Sizes\n    2. Maps\n"},
+	}
+	for _, tt := range tests {
+		if code := h.run(tt.args...); code != 0 {
+			t.Fatalf("%q: exit code %d; stderr:\n%s", tt.args, code, &h.stderr)
+		}
+		if got := h.stdout.String(); got != tt.want {
+			t.Errorf("%q printed:\n%s\nwant:\n%s", tt.args, got, tt.want)
+		}
+	}
+
+	h.run("topics", "-format", "json", "maps")
+	var got []topic
+	if err := json.Unmarshal(h.stdout.Bytes(), &got); err != nil || len(got) != 1 || !slices.Equal(got[0].Sections, []string{"2. Maps"}) {
+		t.Errorf("json: %+v, %v", got, err)
+	}
+
+	if code := h.run("topics", "channels"); code != 1 || !strings.Contains(h.stderr.String(), `no topics match "channels"`) {
+		t.Errorf("exit code %d, stderr:\n%s", code, &h.stderr)
+	}
+}
+
+func TestTopicsComplete(t *testing.T) {
+	root := writeTree(t)
+	trie := filepath.Join(root, "slices-maps", "trie")
+	os.MkdirAll(trie, 0o755)
+	os.WriteFile(filepath.Join(trie, "trie.go"), []byte("package trie\n"), 0o644)
+	os.WriteFile(filepath.Join(trie, "trie_test.go"), []byte("package trie\n"), 0o644)
+	h := newHarness(t, root)
+	var sandbox []string
+	var words []byte
+	record := h.l.exec
+	h.l.exec = func(ctx context.Context, dir string, args ...string) error {
+		fs.WalkDir(os.DirFS(dir), ".", func(p string, d fs.DirEntry, err error) error {
+			if !d.IsDir() {
+				sandbox = append(sandbox, p)
+			}
+			return nil
+		})
+		words, _ = os.ReadFile(filepath.Join(dir, "words.txt"))
+		return record(ctx, dir, args...)
+	}
+
+	if code := h.run("topics", "-complete", "-limit", "3", "UNS"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	// The program runs in the sandbox, with the prefixes lowercased
+	want := append(slices.Clone(sandboxEnv), "go", "run", ".", "-limit", "3", "uns")
+	if len(h.calls) != 1 || !slices.Equal(h.calls[0].args, want) {
+		t.Fatalf("calls %v, want %q", h.calls, want)
+	}
+	// A module with the program and the lesson's files, tests left out
+	if want := []string{"go.mod", "main.go", "trie/trie.go", "words.txt"}; !slices.Equal(sandbox, want) {
+		t.Errorf("sandbox holds %q, want %q", sandbox, want)
+	}
+	if want := "alpha\nmaps\none\npointers\nprogram\nsizes\ntricks\nunsafe\n"; string(words) != want {
+		t.Errorf("words.txt:\n%s\nwant:\n%s", words, want)
+	}
+	if _, err := os.Stat(h.calls[0].dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("sandbox %s left behind: %v", h.calls[0].dir, err)
+	}
+
+	for _, args := range [][]string{{"topics", "-complete"}, {"topics", "-complete", "-format", "json", "uns"}} {
+		if code := h.run(args...); code != 2 || len(h.calls) != 0 {
+			t.Errorf("%q: exit code %d, calls %v", args, code, h.calls)
+		}
+	}
+}
+
+// The sandbox builds against the real trie and the real topics.json
+func TestTopicsCompleteRuns(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a sandbox module")
+	}
+	root, _ := filepath.Abs(filepath.Join("..", ".."))
+	h := newHarness(t, root)
+	h.l.exec = h.l.execCommand
+	if code := h.run("topics", "-complete", "unsa"); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, &h.stderr)
+	}
+	if got := h.stdout.String(); !strings.Contains(got, "unsafe\n") {
+		t.Errorf("completions of unsa:\n%s", got)
+	}
+}
+
+func TestTopicsMissingIndex(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	if code := h.run("topics"); code != 1 || !strings.Contains(h.stderr.String(), "go generate main.go") {
+		t.Errorf("exit code %d, stderr:\n%s", code, &h.stderr)
+	}
+}
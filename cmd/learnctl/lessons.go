@@ -0,0 +1,281 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Finding Lessons
+// ===============
+// The repository has no go.mod: each directory is built from its files
+// directly, "go test *.go" or "go run file.go". A lesson is one of
+//
+//	package  a directory with _test.go files, tested as a whole
+//	program  a file with func main in a directory without tests,
+//	         run on its own
+//
+// Lessons that import modules outside the standard library cannot
+// build without a module that requires them; they are listed, marked,
+// and skipped by "learnctl test" unless named.
+
+type lessonKind string
+
+const (
+	kindPackage lessonKind = "package"
+	kindProgram lessonKind = "program"
+)
+
+type lesson struct {
+	Path    string     `json:"path"` // slash-separated, relative to the root
+	Kind    lessonKind `json:"kind"`
+	Files   int        `json:"files"`
+	Tests   int        `json:"tests"`                // _test.go files
+	Benches int        `json:"benchmarks,omitempty"` // Benchmark functions in them
+	Modules []string   `json:"modules,omitempty"`    // non-standard imports
+	Error   string     `json:"error,omitempty"`      // first syntax error, if any
+}
+
+// findLessons walks root. Hidden directories and testdata are skipped,
+// as the go command skips them. A file that does not parse is not fatal:
+// its lesson is listed with the error, so one broken file cannot hide
+// the rest of the repository.
+func findLessons(root string) ([]lesson, error) {
+	byDir := map[string][]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") {
+			byDir[filepath.Dir(path)] = append(byDir[filepath.Dir(path)], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var lessons []lesson
+	fset := token.NewFileSet()
+	for dir, files := range byDir {
+		rel, _ := filepath.Rel(root, dir)
+		rel = filepath.ToSlash(rel)
+		var tests, benches int
+		var mains []string
+		dirModules := map[string]bool{}
+		fileModules := map[string][]string{}
+		fileErrs := map[string]string{}
+		var dirErr string
+		for _, path := range files {
+			if strings.HasSuffix(path, "_test.go") {
+				tests++
+			}
+			// On a syntax error ParseFile still returns what it parsed,
+			// usually enough to see the imports and func main
+			f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+			if err != nil {
+				fileErrs[path] = err.Error()
+				if dirErr == "" {
+					dirErr = err.Error()
+				}
+			}
+			if f == nil {
+				continue
+			}
+			if strings.HasSuffix(path, "_test.go") {
+				benches += countBenchmarks(f)
+			}
+			for _, imp := range f.Imports {
+				p, _ := strconv.Unquote(imp.Path.Value)
+				if first, _, _ := strings.Cut(p, "/"); strings.Contains(first, ".") {
+					dirModules[p] = true
+					fileModules[path] = append(fileModules[path], p)
+				}
+			}
+			if f.Name.Name == "main" && (hasMain(f) || fileErrs[path] != "") {
+				mains = append(mains, path)
+			}
+		}
+
+		if tests > 0 || len(mains) == 0 {
+			lessons = append(lessons, lesson{Path: rel, Kind: kindPackage, Files: len(files), Tests: tests, Benches: benches, Modules: sortedKeys(dirModules), Error: dirErr})
+			continue
+		}
+		for _, m := range mains {
+			p, _ := filepath.Rel(root, m)
+			mods := fileModules[m]
+			slices.Sort(mods)
+			lessons = append(lessons, lesson{Path: filepath.ToSlash(p), Kind: kindProgram, Files: 1, Modules: mods, Error: fileErrs[m]})
+		}
+	}
+	slices.SortFunc(lessons, func(a, b lesson) int { return strings.Compare(a.Path, b.Path) })
+	return lessons, nil
+}
+
+// programFiles returns the files "go run" needs for the program lesson
+// in file: the program first, then each file of its directory that is
+// not a program and that the chosen files need - it declares a name
+// they use, or methods on a type they declare, as a generated String
+// method does. The rest stay out: weekday_string.go belongs with
+// go_constants_iota.go only, and a builder generated for one program
+// does not compile beside another. The lessons are those findLessons
+// reports for the file's directory.
+func programFiles(file string, lessons []lesson) ([]string, error) {
+	dir := filepath.Dir(file)
+	names, err := goFiles(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	scan := func(name string) (fileScope, error) {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return fileScope{}, err
+		}
+		return scopeOf(f), nil
+	}
+	main, err := scan(filepath.Base(file))
+	if err != nil {
+		return nil, err
+	}
+	files := []string{filepath.Base(file)}
+	declared, used := main.declares, main.uses
+
+	var candidates []string
+	for _, name := range names {
+		isProgram := slices.ContainsFunc(lessons, func(ls lesson) bool { return ls.Kind == kindProgram && ls.Path == name })
+		if name != files[0] && !isProgram {
+			candidates = append(candidates, name)
+		}
+	}
+	// A file that does not parse cannot be needed in a way this can
+	// see, and would only break the build
+	scopes := map[string]fileScope{}
+	for _, name := range candidates {
+		if fs, err := scan(name); err == nil {
+			scopes[name] = fs
+		}
+	}
+	// Adding a file can make another one needed, so repeat until no
+	// file is added
+	for added := true; added; {
+		added = false
+		for _, name := range candidates {
+			fs, ok := scopes[name]
+			if !ok || slices.Contains(files, name) || !(overlaps(fs.declares, used) || overlaps(fs.methodsOn, declared)) {
+				continue
+			}
+			files = append(files, name)
+			declared = append(declared, fs.declares...)
+			used = append(used, fs.uses...)
+			added = true
+		}
+	}
+	return files, nil
+}
+
+// fileScope is what a file declares at package level and what it refers to
+type fileScope struct {
+	declares  []string // funcs, types, vars and consts
+	methodsOn []string // receiver base types
+	uses      []string // every identifier that is not a selector's field or method
+}
+
+func scopeOf(f *ast.File) fileScope {
+	var s fileScope
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				s.declares = append(s.declares, d.Name.Name)
+				continue
+			}
+			t := d.Recv.List[0].Type
+			if star, ok := t.(*ast.StarExpr); ok {
+				t = star.X
+			}
+			switch x := t.(type) {
+			case *ast.IndexExpr:
+				t = x.X
+			case *ast.IndexListExpr:
+				t = x.X
+			}
+			if id, ok := t.(*ast.Ident); ok {
+				s.methodsOn = append(s.methodsOn, id.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					s.declares = append(s.declares, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, n := range spec.Names {
+						s.declares = append(s.declares, n.Name)
+					}
+				}
+			}
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					s.uses = append(s.uses, id.Name)
+				}
+				return true
+			})
+			return false
+		case *ast.Ident:
+			s.uses = append(s.uses, n.Name)
+		}
+		return true
+	})
+	return s
+}
+
+func overlaps(a, b []string) bool {
+	return slices.ContainsFunc(a, func(x string) bool { return x != "_" && slices.Contains(b, x) })
+}
+
+func hasMain(f *ast.File) bool {
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+// countBenchmarks counts the functions go test -bench runs:
+// BenchmarkXxx(b *testing.B) at the top level
+func countBenchmarks(f *ast.File) int {
+	n := 0
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Benchmark") {
+			n++
+		}
+	}
+	return n
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
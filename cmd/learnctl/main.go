@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// learnctl - The Repository's Command Line
+// ========================================
+// learnctl finds, tests and runs the lessons in this repository:
+//
+//	learnctl list web                      lessons under web/
+//	learnctl test -race io                 go test -race *.go in each
+//	learnctl run io/go_io_composition.go   go run, from the lesson's directory
+//	learnctl bench --check concurrency     benchmarks against this machine's baseline
+//	learnctl web toolchain/wasm            browser lessons, built to wasm and served
+//	learnctl serve                         the embedded quizzes, and a run button per program
+//	learnctl topics unsafe                 lesson files and sections about unsafe
+//	learnctl topics -complete uns          words of the index starting with uns
+//	learnctl layout storage.conn           a struct's offsets, sizes and padding
+//	learnctl help test                     a command's flags and variables
+//
+// Build it once, or run it in place:
+//
+//	go build -o learnctl ./cmd/learnctl/*.go
+//	go run cmd/learnctl/*.go list      (skip the _test.go file)
+//
+// The command surface - FlagSets per command, custom flag types and
+// environment fallback - is in cli.go and values.go; the commands are
+// in commands.go, web mode in web.go, the quizzes and run buttons in
+// serve.go, the topic search in topics.go, the benchmark check in
+// bench.go, struct layouts in layout.go and the temp modules that run
+// lesson packages in sandbox.go.
+
+func main() {
+	// Ctrl-C cancels ctx: the running "go test" is interrupted and the
+	// summary still prints
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	app, _ := newApp(os.Stdout, os.Stderr, os.LookupEnv)
+	code := app.Run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Sandboxes
+// =========
+// Some commands need a lesson package: "topics -complete" the trie in
+// slices-maps/trie, "serve" the SSE handler in web/events. The
+// repository has no go.mod, so learnctl cannot import one, and go run
+// only takes the files of a single package main.
+// A sandbox is a temp module, as in advanced-concepts/asm/sandbox.go:
+//
+//	go.mod         module learnctl.local/sandbox
+//	main.go        a program from testdata/sandbox, embedded in learnctl
+//	trie/trie.go   the lesson's own files, copied as they are
+//
+// The program imports the copy as learnctl.local/sandbox/trie, and go
+// run builds the sandbox like any module. Copying at every run means
+// the command always uses the lesson's current code.
+
+//go:embed testdata/sandbox
+var sandboxPrograms embed.FS
+
+// sandboxModule is the sandboxes' module path
+const sandboxModule = "learnctl.local/sandbox"
+
+// sandboxEnv pins the go command inside a sandbox: no workspace, no
+// downloads, and the toolchain that is installed
+var sandboxEnv = []string{"GOFLAGS=", "GOWORK=off", "GOPROXY=off", "GOTOOLCHAIN=local"}
+
+// newSandbox creates a temp module holding program - a directory under
+// testdata/sandbox - and a copy of each package directory in pkgs,
+// relative to the root, imported by its base name. The caller removes
+// the directory.
+func (l *learnctl) newSandbox(program string, pkgs ...string) (string, error) {
+	dir, err := os.MkdirTemp("", "learnctl-"+program+"-")
+	if err != nil {
+		return "", err
+	}
+	if err := l.fillSandbox(dir, program, pkgs); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func (l *learnctl) fillSandbox(dir, program string, pkgs []string) error {
+	gomod := "module " + sandboxModule + "\n\ngo 1.25\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		return err
+	}
+	src, err := fs.Sub(sandboxPrograms, path.Join("testdata/sandbox", program))
+	if err != nil {
+		return err
+	}
+	if err := os.CopyFS(dir, src); err != nil {
+		return err
+	}
+	for _, p := range pkgs {
+		from := filepath.Join(l.root, filepath.FromSlash(p))
+		files, err := goFiles(from, false)
+		if err != nil {
+			return err
+		}
+		to := filepath.Join(dir, path.Base(p))
+		if err := os.Mkdir(to, 0o755); err != nil {
+			return err
+		}
+		for _, f := range files {
+			data, err := os.ReadFile(filepath.Join(from, f))
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(to, f), data, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// goRunSandbox is the command line that runs a sandbox's main package
+func goRunSandbox(args ...string) []string {
+	return append(append(append([]string{}, sandboxEnv...), "go", "run", "."), args...)
+}
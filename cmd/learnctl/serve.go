@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Serve Mode
+// ==========
+// "learnctl serve" is the repository in a browser: the quizzes, and a
+// Run button for each program lesson. It is two programs:
+//
+//   - The quiz site. The banks, the HTML templates and the stylesheet
+//     are embedded in the go:embed lesson, os-files/go_embed.go, whose
+//     serve mode is the site; as with layout, learnctl runs it with go
+//     run, from the lesson's directory, on a loopback port of its own.
+//   - The run buttons, testdata/sandbox/serve. A button starts
+//     "learnctl run <lesson>", and the output streams back as
+//     Server-Sent Events through the Broker and SSE handler of
+//     web/events, so the program runs in a sandbox with a copy of that
+//     package. It listens on -addr and proxies every other path to the
+//     quiz site.
+//
+//	learnctl serve                    http://localhost:8080
+//	learnctl serve -addr localhost:9000
+//
+// When either program stops, learnctl stops the other. The quiz site
+// takes a moment to compile, so its pages are a 502 for the first few
+// seconds.
+
+// quizSite is the embed lesson's path under the root
+const quizSite = "os-files/go_embed.go"
+
+func (l *learnctl) serveCommand() *Command {
+	var addr string
+	return &Command{
+		Name:  "serve",
+		Short: "serve the quizzes and a run button for each program lesson",
+		Long: `Serve the quiz site of os-files/go_embed.go - question banks,
+templates and stylesheet, all compiled in with //go:embed - on -addr
+until interrupted, and at /run/ a page with a Run button for each
+program lesson. A button runs "learnctl run" and streams its output
+into the page as Server-Sent Events.`,
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&addr, "addr", "localhost:8080", "`address` to listen on")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) > 0 {
+				return Usagef("serve takes no arguments")
+			}
+			lessons, err := findLessons(l.root)
+			if err != nil {
+				return err
+			}
+			var programs []string
+			for _, ls := range lessons {
+				if ls.Kind == kindProgram && len(ls.Modules) == 0 && ls.Error == "" {
+					programs = append(programs, ls.Path)
+				}
+			}
+			self, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			quizAddr, err := freePort()
+			if err != nil {
+				return err
+			}
+			dir, err := l.newSandbox("serve", "web/events")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			if err := os.WriteFile(filepath.Join(dir, "lessons.txt"), []byte(strings.Join(programs, "\n")+"\n"), 0o644); err != nil {
+				return err
+			}
+
+			both, stop := context.WithCancel(ctx)
+			defer stop()
+			errc := make(chan error, 2)
+			go func() {
+				site := filepath.Join(l.root, filepath.Dir(filepath.FromSlash(quizSite)))
+				errc <- l.exec(both, site, "go", "run", filepath.Base(quizSite), "serve", "-addr", quizAddr)
+			}()
+			go func() {
+				errc <- l.exec(both, dir, goRunSandbox("-addr", addr, "-quiz", "http://"+quizAddr, self, "-root", l.root, "run")...)
+			}()
+			err = <-errc
+			stop()
+			if other := <-errc; err == nil {
+				err = other
+			}
+			if ctx.Err() != nil {
+				return nil // Ctrl-C is how serve stops
+			}
+			return err
+		},
+	}
+}
+
+// freePort returns a loopback address no one is listening on. Another
+// process could take it before the quiz site does; on a workstation
+// that is rare enough.
+func freePort() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer ln.Close()
+	return ln.Addr().String(), nil
+}
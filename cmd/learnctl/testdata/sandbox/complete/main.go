@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"learnctl.local/sandbox/trie"
+)
+
+// Autocomplete
+// ============
+// "learnctl topics -complete" runs this program in a sandbox, beside a
+// copy of slices-maps/trie. It loads the words of topics.json, one per
+// line, into a Trie and prints the completions of each prefix in order.
+
+func main() {
+	words := flag.String("words", "words.txt", "`file` of words, one per line")
+	limit := flag.Int("limit", 10, "completions per prefix; 0 for all")
+	flag.Parse()
+
+	f, err := os.Open(*words)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	var t trie.Trie
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		t.Insert(sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, prefix := range flag.Args() {
+		for _, w := range t.PrefixSearch(prefix, *limit) {
+			fmt.Println(w)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"learnctl.local/sandbox/events"
+)
+
+// Run Buttons
+// ===========
+// "learnctl serve" runs this program in a sandbox, beside a copy of
+// web/events. It is the front of the serve mode:
+//
+//	GET  /run/          a Run button for each program lesson
+//	POST /run/          lesson=<path> starts "learnctl run <path>"
+//	GET  /run/events    the runs' output, as Server-Sent Events
+//	     everything else is proxied to the quiz site of os-files/go_embed.go
+//
+// A POST from another site's page is refused: without the check, any
+// page open in the browser could start lessons on this machine. The
+// browser says where a request comes from in Sec-Fetch-Site, or failing
+// that in Origin, and http.CrossOriginProtection compares the two with
+// the Host the request was sent to.
+//
+// Each line a run prints is published to an events.Broker, and the
+// lesson's SSE handler streams the broker to every open page. A page
+// asks for the events after the last one published when it loaded, so
+// it sees new runs only; one that reconnects resumes where it was.
+
+// runner starts one lesson at a time and publishes what it prints
+type runner struct {
+	ctx     context.Context // ends the running lesson at shutdown
+	command []string        // learnctl -root <root> run; the lesson goes last
+	lessons []string
+	broker  *events.Broker
+
+	mu      sync.Mutex
+	running string // the lesson being run, or ""
+}
+
+func (rn *runner) handler(quiz http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /run/{$}", rn.page)
+	mux.HandleFunc("POST /run/{$}", rn.start)
+	mux.Handle("GET /run/events", events.SSE(rn.broker, 15*time.Second))
+	mux.Handle("/", quiz)
+	return http.NewCrossOriginProtection().Handler(mux)
+}
+
+var runPage = template.Must(template.New("run").Parse(`<!doctype html>
+<html lang="en">
+<head><meta charset="utf-8"><title>learnctl serve - run</title></head>
+<body>
+<h1>Run a lesson</h1>
+<p><a href="/">Quizzes</a></p>
+<ul>
+{{range .Lessons}}<li><button data-lesson="{{.}}">Run</button> {{.}}</li>
+{{end}}</ul>
+<pre id="out"></pre>
+<script>
+const out = document.getElementById("out");
+const print = line => { out.textContent += line + "\n"; };
+const es = new EventSource("/run/events?after={{.After}}");
+es.addEventListener("start", e => { out.textContent = ""; print("$ learnctl run " + e.data); });
+es.addEventListener("output", e => print(e.data));
+es.addEventListener("exit", e => print("[" + e.data + "]"));
+for (const b of document.querySelectorAll("button")) {
+	b.onclick = async () => {
+		const res = await fetch("/run/", {method: "POST", body: new URLSearchParams({lesson: b.dataset.lesson})});
+		if (!res.ok) print(await res.text());
+	};
+}
+</script>
+</body>
+</html>
+`))
+
+func (rn *runner) page(w http.ResponseWriter, r *http.Request) {
+	runPage.Execute(w, struct {
+		Lessons []string
+		After   uint64
+	}{rn.lessons, rn.broker.LastID()})
+}
+
+// start answers at once; the output follows on the event stream
+func (rn *runner) start(w http.ResponseWriter, r *http.Request) {
+	lesson := r.FormValue("lesson")
+	if !slices.Contains(rn.lessons, lesson) {
+		http.Error(w, fmt.Sprintf("no program lesson %q", lesson), http.StatusNotFound)
+		return
+	}
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.running != "" {
+		http.Error(w, rn.running+" is still running", http.StatusConflict)
+		return
+	}
+	rn.running = lesson
+	go rn.run(lesson)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// run publishes "start", an "output" event per line of stdout and
+// stderr, and "exit" with the status
+func (rn *runner) run(lesson string) {
+	rn.broker.Publish("start", lesson)
+	status := "exit status 0"
+	if err := rn.stream(lesson); err != nil {
+		status = err.Error()
+	}
+	rn.mu.Lock()
+	rn.running = ""
+	rn.mu.Unlock()
+	rn.broker.Publish("exit", status)
+}
+
+func (rn *runner) stream(lesson string) error {
+	cmd := exec.CommandContext(rn.ctx, rn.command[0], append(rn.command[1:], lesson)...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout // one pipe keeps the two in the order printed
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s := bufio.NewScanner(out)
+	for s.Scan() {
+		rn.broker.Publish("output", s.Text())
+	}
+	io.Copy(io.Discard, out) // past a line too long to scan
+	return cmd.Wait()
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "`address` to listen on")
+	quiz := flag.String("quiz", "", "`URL` of the quiz site, for every path outside /run/")
+	lessons := flag.String("lessons", "lessons.txt", "`file` of program lessons, one per line")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("usage: serve [flags] learnctl-command...")
+	}
+	quizURL, err := url.Parse(*quiz)
+	if err != nil {
+		log.Fatal(err)
+	}
+	list, err := os.ReadFile(*lessons)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	rn := &runner{ctx: ctx, command: flag.Args(), lessons: strings.Fields(string(list)), broker: events.NewBroker(1000)}
+	srv := &http.Server{Addr: *addr, Handler: rn.handler(httputil.NewSingleHostReverseProxy(quizURL)), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		rn.broker.Close() // ends the streams, or Shutdown waits for them
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	fmt.Printf("serving quizzes at http://%s/ and run buttons at http://%s/run/ (Ctrl-C to stop)\n", *addr, *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"learnctl.local/sandbox/events"
+)
+
+// Run Buttons - Tests
+// ===================
+// learnctl's TestServeSandbox runs these with go test inside the
+// sandbox. The runs start this test binary again in place of learnctl,
+// as a helper process that prints a line to each stream and fails.
+
+func TestMain(m *testing.M) {
+	if os.Getenv("SERVE_HELPER") == "1" {
+		fmt.Println("hello from", os.Args[len(os.Args)-1])
+		fmt.Fprintln(os.Stderr, "to stderr")
+		os.Exit(3)
+	}
+	os.Exit(m.Run())
+}
+
+func newServer(t *testing.T) (*httptest.Server, *runner) {
+	t.Helper()
+	t.Setenv("SERVE_HELPER", "1")
+	ctx, cancel := context.WithCancel(context.Background())
+	rn := &runner{ctx: ctx, command: []string{os.Args[0]}, lessons: []string{"io/a.go", "io/b.go"}, broker: events.NewBroker(100)}
+	quiz := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "quiz ", r.URL.Path) })
+	srv := httptest.NewServer(rn.handler(quiz))
+	t.Cleanup(func() {
+		cancel()
+		rn.broker.Close()
+		srv.Close()
+	})
+	return srv, rn
+}
+
+func TestRunStreams(t *testing.T) {
+	srv, _ := newServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/run/events", nil)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	res, err := http.PostForm(srv.URL+"/run/", url.Values{"lesson": {"io/b.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /run/: %s", res.Status)
+	}
+
+	want := []events.Event{
+		{ID: 1, Type: "start", Data: "io/b.go"},
+		{ID: 2, Type: "output", Data: "hello from io/b.go"},
+		{ID: 3, Type: "output", Data: "to stderr"},
+		{ID: 4, Type: "exit", Data: "exit status 3"},
+	}
+	r := events.NewReader(stream.Body)
+	for _, w := range want {
+		e, err := r.Next()
+		if err != nil || e != w {
+			t.Fatalf("event %+v, %v; want %+v", e, err, w)
+		}
+	}
+}
+
+func TestRunRefuses(t *testing.T) {
+	srv, rn := newServer(t)
+	res, _ := http.PostForm(srv.URL+"/run/", url.Values{"lesson": {"../etc/passwd"}})
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown lesson: %s, want 404", res.Status)
+	}
+	// One run at a time
+	rn.running = "io/a.go"
+	res, _ = http.PostForm(srv.URL+"/run/", url.Values{"lesson": {"io/b.go"}})
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("during a run: %s, want 409", res.Status)
+	}
+}
+
+// A page on another site cannot start a run; the browser names the
+// site in Sec-Fetch-Site, or in Origin if it is older
+func TestRunRefusesCrossOrigin(t *testing.T) {
+	srv, rn := newServer(t)
+	post := func(header, value string) int {
+		req, _ := http.NewRequest("POST", srv.URL+"/run/", strings.NewReader("lesson=io/a.go"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set(header, value)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	for _, h := range [][2]string{{"Sec-Fetch-Site", "cross-site"}, {"Origin", "http://evil.example"}} {
+		if code := post(h[0], h[1]); code != http.StatusForbidden {
+			t.Errorf("%s: %s: %d, want 403", h[0], h[1], code)
+		}
+	}
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.running != "" {
+		t.Errorf("a cross-origin POST started %s", rn.running)
+	}
+}
+
+func TestPages(t *testing.T) {
+	srv, rn := newServer(t)
+	rn.broker.Publish("output", "an old run")
+	tests := []struct{ path, want string }{
+		// The page lists the lessons and asks only for new events
+		{"/run/", `data-lesson="io/a.go"`},
+		{"/run/", `/run/events?after=1`},
+		// Everything else is the quiz site's
+		{"/", "quiz /"},
+		{"/quiz/io", "quiz /quiz/io"},
+	}
+	for _, tt := range tests {
+		res, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if !strings.Contains(string(body), tt.want) {
+			t.Errorf("GET %s:\n%s\nwant %q", tt.path, body, tt.want)
+		}
+	}
+}
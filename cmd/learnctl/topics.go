@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Topics
+// ======
+// "learnctl topics" searches topics.json, the index of every lesson
+// file's title and section headings. The index is written by the
+// go/ast lesson, which parses the repository:
+//
+//	cd metaprogramming/astindex && go generate main.go
+//
+// Reading a file keeps learnctl fast and free of the parsing code; the
+// lesson's test fails when the index falls behind the lessons.
+//
+// -complete turns the words into prefixes and prints the words of the
+// index that start with them - autocomplete, answered by the trie from
+// slices-maps/trie running in a sandbox (sandbox.go):
+//
+//	learnctl topics -complete uns    unsafe, unsigned...
+
+const topicsFile = "topics.json"
+
+// topic is one entry of topics.json
+type topic struct {
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Sections []string `json:"sections,omitempty"`
+}
+
+func (l *learnctl) topicsCommand() *Command {
+	var (
+		format   string
+		complete bool
+		limit    int
+	)
+	return &Command{
+		Name:  "topics",
+		Args:  "[word...]",
+		Short: "search the lessons' titles and sections",
+		Long: `Search topics.json for lesson files whose path, title or section
+headings contain every word, ignoring case. With no words, list every
+topic. Sections that match are shown under their file; a file whose
+path or title matches shows all of them.
+
+With -complete each word is a prefix instead, and the command prints
+up to -limit words of the titles and headings that start with it, from
+the trie in slices-maps/trie.`,
+		Flags: func(fs *flag.FlagSet) {
+			enumVar(fs, &format, "format", "text", "output `format`: text or json", "text", "json")
+			fs.BoolVar(&complete, "complete", false, "complete the words as prefixes")
+			fs.IntVar(&limit, "limit", 10, "completions per prefix with -complete; 0 for all")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if complete && (len(args) == 0 || format != "text") {
+				return Usagef("-complete needs a prefix, and prints text")
+			}
+			topics, err := readTopics(filepath.Join(l.root, topicsFile))
+			if err != nil {
+				return err
+			}
+			if complete {
+				return l.completeTopics(ctx, topics, args, limit)
+			}
+			matches := searchTopics(topics, args)
+			if len(matches) == 0 {
+				return fmt.Errorf("no topics match %q", strings.Join(args, " "))
+			}
+			if format == "json" {
+				enc := json.NewEncoder(l.app.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(matches)
+			}
+			for _, t := range matches {
+				fmt.Fprintf(l.app.Stdout, "%s  %s\n", t.Path, t.Title)
+				for _, s := range t.Sections {
+					fmt.Fprintf(l.app.Stdout, "    %s\n", s)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func readTopics(path string) ([]topic, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no topic index at %s; write it with: cd metaprogramming/astindex && go generate main.go", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var topics []topic
+	if err := json.Unmarshal(data, &topics); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return topics, nil
+}
+
+// searchTopics keeps the topics where every word appears in the path,
+// the title or one section. The result holds only the matching
+// sections, unless the path or title matched on its own.
+func searchTopics(topics []topic, words []string) []topic {
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	contains := func(s string) bool {
+		s = strings.ToLower(s)
+		for _, w := range words {
+			if !strings.Contains(s, w) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var out []topic
+	for _, t := range topics {
+		if contains(t.Path + " " + t.Title + " " + strings.Join(t.Sections, " ")) {
+			if !contains(t.Path + " " + t.Title) {
+				var sections []string
+				for _, s := range t.Sections {
+					if contains(t.Path + " " + t.Title + " " + s) {
+						sections = append(sections, s)
+					}
+				}
+				t.Sections = sections
+			}
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// completeTopics prints the words of the index starting with each
+// prefix. The trie runs in a sandbox with the words in a file beside it.
+func (l *learnctl) completeTopics(ctx context.Context, topics []topic, prefixes []string, limit int) error {
+	dir, err := l.newSandbox("complete", "slices-maps/trie")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	words := strings.Join(topicWords(topics), "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "words.txt"), []byte(words), 0o644); err != nil {
+		return err
+	}
+	args := []string{"-limit", fmt.Sprint(limit)}
+	for _, p := range prefixes {
+		args = append(args, strings.ToLower(p))
+	}
+	return l.exec(ctx, dir, goRunSandbox(args...)...)
+}
+
+// topicWords returns the distinct words of the titles and section
+// headings, lowercased and sorted. Numbers and punctuation are left out:
+// "2. Pointers and nil" gives pointers, and, nil.
+func topicWords(topics []topic) []string {
+	var words []string
+	add := func(s string) {
+		for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if strings.IndexFunc(w, unicode.IsLetter) >= 0 {
+				words = append(words, w)
+			}
+		}
+	}
+	for _, t := range topics {
+		add(t.Title)
+		for _, s := range t.Sections {
+			add(s)
+		}
+	}
+	slices.Sort(words)
+	return slices.Compact(words)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Custom Flag Types
+// =================
+// flag.Var accepts anything with String and Set, so a flag can hold any
+// type and validate itself: Set returns an error, and the flag package
+// reports it with the flag's name - on the command line and, through
+// applyEnv, for environment variables.
+//
+// Before writing one, check what the package already offers:
+//
+//	flag.Duration        "90s", "2m" - time.ParseDuration
+//	flag.TextVar         any encoding.TextUnmarshaler: slog.Level,
+//	                     netip.Addr, big.Int, time.Time
+//	flag.Func            a one-off parser, no type needed
+//	flag.BoolFunc        a switch with an action, such as -version
+//
+// A type is worth it when several flags share it or it carries state,
+// like the list below that grows with each use.
+//
+// Each type also gets a registering function in the shape of
+// flag.StringVar: it writes the default into the variable before
+// binding it. A FlagSet built twice over the same variables - one per
+// App.Run - then starts from the default each time, not from what the
+// last command line left behind.
+
+// listValue collects a flag given several times or with commas:
+// "-skip a -skip b,c" holds [a b c]
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
+
+func listVar(fs *flag.FlagSet, p *[]string, name, usage string) {
+	*p = nil
+	fs.Var((*listValue)(p), name, usage)
+}
+
+// enumValue accepts one of a fixed set of words
+type enumValue struct {
+	value   *string
+	allowed []string
+}
+
+// enumVar defines a flag whose value must be one of allowed
+func enumVar(fs *flag.FlagSet, p *string, name, def, usage string, allowed ...string) {
+	*p = def
+	fs.Var(&enumValue{value: p, allowed: allowed}, name, usage)
+}
+
+func (e *enumValue) String() string {
+	// flag calls String on a zero value to decide whether to print the
+	// default, so a nil receiver must work
+	if e == nil || e.value == nil {
+		return ""
+	}
+	return *e.value
+}
+
+func (e *enumValue) Set(s string) error {
+	if !slices.Contains(e.allowed, s) {
+		return fmt.Errorf("must be one of %s", strings.Join(e.allowed, ", "))
+	}
+	*e.value = s
+	return nil
+}
+
+// Get makes enumValue a flag.Getter, so code holding only the
+// *flag.Flag can read the value without knowing the type
+func (e *enumValue) Get() any { return *e.value }
+
+var (
+	_ flag.Value  = (*listValue)(nil)
+	_ flag.Getter = (*enumValue)(nil)
+)
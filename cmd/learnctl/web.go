@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Web Mode
+// ========
+// "learnctl web" serves the lessons that run in a browser. A browser
+// lesson is a directory with an index.html and Go files importing
+// syscall/js. It cannot build for the host, so it usually sits in a
+// testdata directory, out of reach of "go test *.go" - which is why
+// this walk, unlike findLessons, goes into testdata.
+//
+// Each lesson is built once, at startup, with GOOS=js GOARCH=wasm, and
+// served as
+//
+//	/<path>/               index.html and the lesson's other files
+//	/<path>/main.wasm      the build
+//	/<path>/wasm_exec.js   the glue of the Go that built it
+//
+// with a list of them all at /. Restart to rebuild.
+
+func (l *learnctl) webCommand() *Command {
+	var addr string
+	return &Command{
+		Name:  "web",
+		Args:  "[path...]",
+		Short: "build browser lessons to WebAssembly and serve them",
+		Long: `Build each browser lesson under the given paths, or all of them, with
+GOOS=js GOARCH=wasm, and serve them over HTTP until interrupted. A
+browser lesson is a directory with index.html and Go files that import
+syscall/js.`,
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&addr, "addr", "localhost:8080", "`address` to listen on")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			lessons, err := l.selectBrowserLessons(args)
+			if err != nil {
+				return err
+			}
+			glue, err := l.wasmExec(ctx)
+			if err != nil {
+				return err
+			}
+			build, err := os.MkdirTemp("", "learnctl-web-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(build)
+			for _, p := range lessons {
+				if err := l.buildWasm(ctx, p, build); err != nil {
+					return fmt.Errorf("%s: %w", p, err)
+				}
+			}
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			srv := &http.Server{Handler: webHandler(l.root, build, glue, lessons), ReadHeaderTimeout: 10 * time.Second}
+			fmt.Fprintf(l.app.Stdout, "serving %d browser lessons at http://%s/ (Ctrl-C to stop)\n", len(lessons), ln.Addr())
+			for _, p := range lessons {
+				fmt.Fprintf(l.app.Stdout, "  http://%s/%s/\n", ln.Addr(), p)
+			}
+			errc := make(chan error, 1)
+			go func() { errc <- srv.Serve(ln) }()
+			select {
+			case err := <-errc:
+				return err
+			case <-ctx.Done():
+			}
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(shutdown)
+		},
+	}
+}
+
+// selectBrowserLessons filters the browser lessons by path prefix, as
+// selectLessons does for the others
+func (l *learnctl) selectBrowserLessons(paths []string) ([]string, error) {
+	all, err := findBrowserLessons(l.root)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if len(all) == 0 {
+			return nil, errors.New("no browser lessons found")
+		}
+		return all, nil
+	}
+	var out []string
+	for _, p := range paths {
+		p = strings.Trim(filepath.ToSlash(filepath.Clean(p)), "/")
+		n := len(out)
+		for _, b := range all {
+			if (p == "." || b == p || strings.HasPrefix(b, p+"/")) && !slices.Contains(out, b) {
+				out = append(out, b)
+			}
+		}
+		if len(out) == n {
+			return nil, Usagef("no browser lessons under %q", p)
+		}
+	}
+	return out, nil
+}
+
+// findBrowserLessons returns the slash paths, relative to root, of the
+// directories holding index.html and a Go file that imports syscall/js
+func findBrowserLessons(root string) ([]string, error) {
+	var found []string
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "index.html")); err != nil {
+			return nil
+		}
+		files, _ := filepath.Glob(filepath.Join(path, "*.go"))
+		for _, f := range files {
+			file, err := parser.ParseFile(fset, f, nil, parser.ImportsOnly)
+			if err != nil {
+				continue
+			}
+			if slices.ContainsFunc(file.Imports, func(imp *ast.ImportSpec) bool { return imp.Path.Value == `"syscall/js"` }) {
+				rel, _ := filepath.Rel(root, path)
+				found = append(found, filepath.ToSlash(rel))
+				break
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+// buildWasm compiles one lesson to build/<path>/main.wasm. The lesson
+// is named by its files, like every other build in this repository, so
+// all of them must be meant for js/wasm.
+func (l *learnctl) buildWasm(ctx context.Context, path, build string) error {
+	dir := filepath.Join(l.root, filepath.FromSlash(path))
+	files, err := goFiles(dir, false)
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(build, filepath.FromSlash(path), "main.wasm")
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	args := append([]string{"GOOS=js", "GOARCH=wasm", "go", "build", "-o", out}, files...)
+	return l.exec(ctx, dir, args...)
+}
+
+// goWasmExec finds wasm_exec.js in the Go installation: lib/wasm since
+// Go 1.24, misc/wasm before
+func goWasmExec(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "go", "env", "GOROOT").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOROOT: %w", err)
+	}
+	root := strings.TrimSpace(string(out))
+	for _, dir := range []string{"lib", "misc"} {
+		path := filepath.Join(root, dir, "wasm", "wasm_exec.js")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no wasm_exec.js in %s", root)
+}
+
+var indexPage = template.Must(template.New("index").Parse(`<!doctype html>
+<html lang="en">
+<head><meta charset="utf-8"><title>learnctl web</title></head>
+<body>
+<h1>Browser lessons</h1>
+<ul>
+{{range .}}<li><a href="/{{.}}/">{{.}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// webHandler serves the lessons: the build and the glue by name, and
+// everything else from the lesson's own directory
+func webHandler(root, build, glue string, lessons []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		indexPage.Execute(w, lessons)
+	})
+	for _, p := range lessons {
+		src := filepath.Join(root, filepath.FromSlash(p))
+		wasm := filepath.Join(build, filepath.FromSlash(p), "main.wasm")
+		files := http.FileServer(http.Dir(src))
+		prefix := "/" + p + "/"
+		mux.Handle("GET "+prefix, http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "main.wasm":
+				// instantiateStreaming insists on this type
+				w.Header().Set("Content-Type", "application/wasm")
+				http.ServeFile(w, r, wasm)
+			case "wasm_exec.js":
+				http.ServeFile(w, r, glue)
+			default:
+				files.ServeHTTP(w, r)
+			}
+		})))
+	}
+	return mux
+}
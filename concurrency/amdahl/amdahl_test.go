@@ -0,0 +1,175 @@
+package main
+
+import (
+	"math"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// amdahl - Tests
+// ==============
+// Run with:
+//
+//   cd concurrency/amdahl
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// Interpret is checked on made-up reports, so every branch runs on a
+// machine with one CPU too.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-3 }
+
+// report makes the rows a workload with serial fraction s would give
+// on numCPU cores, plus one row at twice numCPU that runs no faster
+func report(numCPU int, fractions map[string]float64) Report {
+	r := Report{NumCPU: numCPU}
+	for name, s := range fractions {
+		for p := 1; p <= numCPU; p++ {
+			r.Rows = append(r.Rows, amdahlRow(name, s, p))
+		}
+		over := amdahlRow(name, s, numCPU)
+		over.Procs = 2 * numCPU
+		r.Rows = append(r.Rows, over)
+	}
+	return r
+}
+
+func amdahlRow(name string, s float64, p int) Row {
+	speedup := Amdahl(s, p)
+	kf := 0.0
+	if p > 1 {
+		kf = s
+	}
+	return Row{Workload: name, Procs: p, Speedup: speedup, Efficiency: speedup / float64(p), KarpFlatt: kf}
+}
+
+// 1. The Laws
+// ===========
+
+func TestAmdahl(t *testing.T) {
+	tests := []struct {
+		s    float64
+		p    int
+		want float64
+	}{
+		{0, 8, 8},
+		{0.5, 2, 4.0 / 3},
+		{0.05, 1024, 1 / (0.05 + 0.95/1024)},
+		{1, 64, 1},
+	}
+	for _, tt := range tests {
+		if got := Amdahl(tt.s, tt.p); !near(got, tt.want) {
+			t.Errorf("Amdahl(%g, %d) = %.3f, want %.3f", tt.s, tt.p, got, tt.want)
+		}
+	}
+	// The ceiling: 1/s, however many cores
+	if got := Amdahl(0.1, 1<<30); !near(got, 10) {
+		t.Errorf("Amdahl(0.1, 2^30) = %.3f, want 10", got)
+	}
+}
+
+func TestGustafson(t *testing.T) {
+	if got := Gustafson(0.1, 64); !near(got, 57.7) {
+		t.Errorf("Gustafson(0.1, 64) = %.3f, want 57.7", got)
+	}
+	if got := Gustafson(0, 16); got != 16 {
+		t.Errorf("Gustafson(0, 16) = %.3f, want 16", got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	for _, s := range []float64{0, 0.03, 0.1, 0.4} {
+		var rows []Row
+		for p := 2; p <= 16; p *= 2 {
+			rows = append(rows, amdahlRow("x", s, p))
+		}
+		if got := Fit(rows); !near(got, s) {
+			t.Errorf("Fit = %.4f, want %.2f", got, s)
+		}
+	}
+}
+
+func TestDefaultProcs(t *testing.T) {
+	for numCPU, want := range map[int]string{
+		1:  "1,2",
+		4:  "1,2,3,4,8",
+		24: "1,2,4,8,16,24,48",
+	} {
+		if got := DefaultProcs(numCPU); got != want {
+			t.Errorf("DefaultProcs(%d) = %q, want %q", numCPU, got, want)
+		}
+	}
+}
+
+// 2. Reading the Results
+// ======================
+
+func TestInterpret(t *testing.T) {
+	got := strings.Join(Interpret(report(8, map[string]float64{"cpu": 0, "serial": 0.1})), "\n")
+	for _, want := range []string{
+		"cpu: 8.00x on 8 cores (100% efficient); Amdahl fit 0.000 serial, a ceiling of the core count",
+		"serial: 4.71x on 8 cores (59% efficient); Amdahl fit 0.100 serial, a ceiling of 10x",
+		"Karp-Flatt stayed between 0.100 and 0.100: a fixed serial part",
+		"serial was written 10% serial and fits as 10.0%",
+		"cpu at GOMAXPROCS=16: 1.00x the speed at 8",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "one CPU") {
+		t.Errorf("one-CPU caveat on eight:\n%s", got)
+	}
+}
+
+// Efficiency that falls faster than a fixed serial part explains
+func TestInterpretGrowingOverhead(t *testing.T) {
+	r := Report{NumCPU: 4, Rows: []Row{
+		{Workload: "alloc", Procs: 1, Speedup: 1, Efficiency: 1},
+		{Workload: "alloc", Procs: 2, Speedup: 1.9, Efficiency: 0.95, KarpFlatt: 0.053},
+		{Workload: "alloc", Procs: 4, Speedup: 2.5, Efficiency: 0.625, KarpFlatt: 0.2},
+	}}
+	got := strings.Join(Interpret(r), "\n")
+	if !strings.Contains(got, "Karp-Flatt grew from 0.053 to 0.200: overhead grows with p") {
+		t.Errorf("growing overhead not reported:\n%s", got)
+	}
+}
+
+func TestInterpretOneCPU(t *testing.T) {
+	got := Interpret(report(1, map[string]float64{"cpu": 0}))
+	if len(got) != 2 || !strings.HasPrefix(got[0], "one CPU") || !strings.Contains(got[1], "cpu at GOMAXPROCS=2") {
+		t.Errorf("got %q", got)
+	}
+}
+
+// 3. The Tool
+// ===========
+
+// tools/scaling runs and writes JSON this lesson can read
+func TestMeasure(t *testing.T) {
+	requireGo(t)
+	r, err := Measure(t.Context(), "1,2", 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.NumCPU < 1 || len(r.Rows) != 6 {
+		t.Fatalf("got %+v, want 3 workloads at 2 settings", r)
+	}
+	for _, row := range r.Rows {
+		if row.Seconds <= 0 || (row.Procs == 1 && row.Speedup != 1) {
+			t.Errorf("bad row %+v", row)
+		}
+	}
+}
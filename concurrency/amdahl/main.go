@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Amdahl's Law: How Far Parallelism Goes
+// ======================================
+// The other lessons in this folder ask which lock is fastest. This one
+// asks how much faster a program can get with more cores at all, and
+// answers with data: it runs tools/scaling on this machine and reads
+// the results with two laws.
+//
+//	Amdahl      a program with serial fraction s runs at most 1/s
+//	            times faster, however many cores it gets:
+//	            speedup(p) = 1 / (s + (1-s)/p)
+//	Gustafson   give p cores p times the work and the time stays the
+//	            same: speedup(p) = s + (1-s)p. Servers live here - more
+//	            cores serve more requests, not one request faster
+//	Karp-Flatt  the s that explains a measured speedup at p. If it
+//	            stays put, the program has a fixed serial part; if it
+//	            grows with p, overhead grows with p - lock contention,
+//	            GC, the memory bus
+//
+// In a Go program the serial fraction is every mutex held, every
+// channel handoff to a single goroutine, and the garbage collector's
+// stop-the-world pauses and assists.
+//
+// Run with:
+//
+//	cd concurrency/amdahl
+//	go run main.go
+//	go run main.go -procs 1,2,4,8,16 -scale 2
+//	go test -v *.go
+//
+// Section 3 runs go run on tools/scaling/main.go, so it needs the go
+// command. The tool on its own prints a table and a chart for each
+// workload: go run tools/scaling/main.go.
+
+var (
+	procsFlag = flag.String("procs", "", "GOMAXPROCS values to measure (default 1..NumCPU, then twice NumCPU)")
+	scaleFlag = flag.Float64("scale", 0.5, "multiply every workload's size")
+)
+
+// Row and Report are the JSON tools/scaling writes with -json
+type Row struct {
+	Workload   string
+	Procs      int
+	Seconds    float64
+	Speedup    float64
+	Efficiency float64
+	KarpFlatt  float64
+}
+
+type Report struct {
+	GoVersion string
+	GOOS      string
+	GOARCH    string
+	NumCPU    int
+	Rows      []Row
+}
+
+// builtInSerial is the serial fraction tools/scaling's serial workload
+// was written with: 2000 of every 20000 rounds under a global mutex
+const builtInSerial = 0.1
+
+func main() {
+	flag.Parse()
+	fmt.Println("=== Amdahl's Law: How Far Parallelism Goes ===")
+
+	// 1. Amdahl's law
+	amdahlTable()
+
+	// 2. Gustafson's law
+	gustafsonTable()
+
+	// 3. Measured on this machine
+	report, err := measured(context.Background())
+	if err != nil {
+		fmt.Printf("   (could not run tools/scaling: %v)\n", err)
+	} else {
+		// 4. Reading the results
+		fmt.Println("\n4. READING THE RESULTS:")
+		for _, line := range Interpret(report) {
+			fmt.Println("   - " + line)
+		}
+	}
+
+	// 5. What it means for Go programs
+	guidance()
+}
+
+// Amdahl is the speedup on p processors of a program whose serial
+// fraction is s
+func Amdahl(s float64, p int) float64 {
+	return 1 / (s + (1-s)/float64(p))
+}
+
+// Gustafson is the speedup on p processors when the parallel part of
+// the work grows with p
+func Gustafson(s float64, p int) float64 {
+	return s + (1-s)*float64(p)
+}
+
+var (
+	fractions = []float64{0, 0.01, 0.05, 0.1, 0.25, 0.5}
+	cores     = []int{2, 4, 8, 16, 64, 1024}
+)
+
+// 1. Amdahl's Law
+// ===============
+func amdahlTable() {
+	fmt.Println("\n1. AMDAHL'S LAW (speedup of a fixed job on p cores):")
+	lawTable(Amdahl)
+	fmt.Println("   5% serial caps a job at 20x: past 16 cores, each doubling buys")
+	fmt.Println("   less than the one before, and 1024 cores are barely better than 64.")
+}
+
+// 2. Gustafson's Law
+// ==================
+func gustafsonTable() {
+	fmt.Println("\n2. GUSTAFSON'S LAW (speedup when the job grows with p):")
+	lawTable(Gustafson)
+	fmt.Println("   The same serial fractions, no ceiling: a web server with 64 cores")
+	fmt.Println("   does not answer one request 64 times faster, but it answers nearly")
+	fmt.Println("   64 times as many.")
+}
+
+func lawTable(law func(float64, int) float64) {
+	fmt.Printf("   %-9s", "serial")
+	for _, p := range cores {
+		fmt.Printf(" %7s", "p="+strconv.Itoa(p))
+	}
+	fmt.Println()
+	for _, s := range fractions {
+		fmt.Printf("   %-9s", fmt.Sprintf("%g%%", s*100))
+		for _, p := range cores {
+			fmt.Printf(" %7.1f", law(s, p))
+		}
+		fmt.Println()
+	}
+}
+
+// 3. Measured on This Machine
+// ===========================
+func measured(ctx context.Context) (Report, error) {
+	procs := *procsFlag
+	if procs == "" {
+		procs = DefaultProcs(runtime.NumCPU())
+	}
+	fmt.Printf("\n3. MEASURED ON THIS MACHINE (tools/scaling -procs %s):\n", procs)
+	report, err := Measure(ctx, procs, *scaleFlag)
+	if err != nil {
+		return report, err
+	}
+	fmt.Printf("   %s, %d CPUs; speedup over GOMAXPROCS=1:\n", report.GoVersion, report.NumCPU)
+	var workloads []string
+	for _, r := range report.Rows {
+		if !slices.Contains(workloads, r.Workload) {
+			workloads = append(workloads, r.Workload)
+		}
+	}
+	fmt.Printf("   %-11s", "GOMAXPROCS")
+	for _, w := range workloads {
+		fmt.Printf(" %8s", w)
+	}
+	fmt.Println()
+	for _, r := range report.Rows {
+		if r.Workload != workloads[0] {
+			continue
+		}
+		fmt.Printf("   %-11d", r.Procs)
+		for _, w := range workloads {
+			fmt.Printf(" %7.2fx", row(report, w, r.Procs).Speedup)
+		}
+		if r.Procs > report.NumCPU {
+			fmt.Print("   more Ps than CPUs")
+		}
+		fmt.Println()
+	}
+	return report, nil
+}
+
+// DefaultProcs is 1 to numCPU, or powers of two on a big machine, then
+// twice numCPU to show what Ps beyond the cores do
+func DefaultProcs(numCPU int) string {
+	var procs []string
+	for p := 1; p <= numCPU; p++ {
+		if numCPU <= 16 || p&(p-1) == 0 || p == numCPU {
+			procs = append(procs, strconv.Itoa(p))
+		}
+	}
+	return strings.Join(append(procs, strconv.Itoa(2*numCPU)), ",")
+}
+
+// Measure runs tools/scaling with -json and decodes what it writes
+func Measure(ctx context.Context, procs string, scale float64) (Report, error) {
+	var report Report
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return report, fmt.Errorf("source file unknown")
+	}
+	tool := filepath.Join(filepath.Dir(file), "..", "..", "tools", "scaling", "main.go")
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "go", "run", tool, "-json", "-runs", "2",
+		"-procs", procs, "-scale", strconv.FormatFloat(scale, 'g', -1, 64))
+	cmd.Env = append(os.Environ(), "GOFLAGS=")
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return report, fmt.Errorf("go run tools/scaling/main.go: %w", err)
+	}
+	return report, json.Unmarshal(out, &report)
+}
+
+func row(report Report, workload string, procs int) Row {
+	i := slices.IndexFunc(report.Rows, func(r Row) bool { return r.Workload == workload && r.Procs == procs })
+	if i < 0 {
+		return Row{}
+	}
+	return report.Rows[i]
+}
+
+// 4. Reading the Results
+// ======================
+
+// Interpret reads the report the way section 4 explains: a serial
+// fraction fitted from the rows that had a core each, whether it is
+// fixed or grows with p, the serial workload against the fraction it
+// was written with, and what Ps beyond the cores did
+func Interpret(report Report) []string {
+	var lines []string
+	var workloads []string
+	for _, r := range report.Rows {
+		if !slices.Contains(workloads, r.Workload) {
+			workloads = append(workloads, r.Workload)
+		}
+	}
+	if report.NumCPU == 1 {
+		lines = append(lines, "one CPU: every GOMAXPROCS above 1 takes turns on it, so there is no speedup to fit. "+
+			"Run this on a machine with more cores to see the laws at work")
+	}
+
+	for _, w := range workloads {
+		var parallel []Row // rows with a core for every P
+		for _, r := range report.Rows {
+			if r.Workload == w && r.Procs > 1 && r.Procs <= report.NumCPU {
+				parallel = append(parallel, r)
+			}
+		}
+		if len(parallel) == 0 {
+			continue
+		}
+		slices.SortFunc(parallel, func(a, b Row) int { return a.Procs - b.Procs })
+		first, last := parallel[0], parallel[len(parallel)-1]
+		s := Fit(parallel)
+		line := fmt.Sprintf("%s: %.2fx on %d cores (%.0f%% efficient); Amdahl fit %.3f serial, a ceiling of %s",
+			w, last.Speedup, last.Procs, 100*last.Efficiency, s, ceiling(s))
+		switch {
+		case len(parallel) == 1:
+		case last.KarpFlatt > first.KarpFlatt+0.02 && last.KarpFlatt > 1.5*first.KarpFlatt:
+			line += fmt.Sprintf(". Karp-Flatt grew from %.3f to %.3f: overhead grows with p, so more cores will do worse than the fit",
+				first.KarpFlatt, last.KarpFlatt)
+		default:
+			line += fmt.Sprintf(". Karp-Flatt stayed between %.3f and %.3f: a fixed serial part", first.KarpFlatt, last.KarpFlatt)
+		}
+		lines = append(lines, line)
+		if w == "serial" {
+			verdict := "the law describes a real lock"
+			if s > builtInSerial+0.03 {
+				verdict = "the rest is the lock itself - waking waiters and handing it over"
+			}
+			lines = append(lines, fmt.Sprintf("serial was written %.0f%% serial and fits as %.1f%%: %s",
+				100*builtInSerial, 100*s, verdict))
+		}
+	}
+
+	for _, w := range workloads {
+		atCPUs := row(report, w, report.NumCPU)
+		for _, r := range report.Rows {
+			if r.Workload == w && r.Procs > report.NumCPU && atCPUs.Speedup > 0 {
+				lines = append(lines, fmt.Sprintf("%s at GOMAXPROCS=%d: %.2fx the speed at %d - Ps beyond the cores only take turns",
+					w, r.Procs, r.Speedup/atCPUs.Speedup, report.NumCPU))
+			}
+		}
+	}
+	return lines
+}
+
+// Fit is the serial fraction that best explains rows under Amdahl's
+// law: least squares on 1/speedup - 1/p = s(1 - 1/p), clamped to [0, 1]
+func Fit(rows []Row) float64 {
+	var xy, xx float64
+	for _, r := range rows {
+		x := 1 - 1/float64(r.Procs)
+		xy += x * (1/r.Speedup - 1/float64(r.Procs))
+		xx += x * x
+	}
+	if xx == 0 {
+		return 0
+	}
+	return min(max(xy/xx, 0), 1)
+}
+
+// ceiling is Amdahl's limit 1/s, written for a person
+func ceiling(s float64) string {
+	if s < 0.001 {
+		return "the core count"
+	}
+	return fmt.Sprintf("%.0fx", 1/s)
+}
+
+// 5. What It Means for Go Programs
+// ================================
+func guidance() {
+	fmt.Println("\n5. WHAT IT MEANS FOR GO PROGRAMS:")
+	fmt.Println("   - Find the serial fraction before adding goroutines: a mutex")
+	fmt.Println("     profile shows the locks, go tool trace shows goroutines waiting.")
+	fmt.Println("   - Shrinking s beats adding p: 10% to 5% serial doubles the ceiling.")
+	fmt.Println("     That is what the sharded map in ../maps does to one lock.")
+	fmt.Println("   - Allocation-heavy code scales worse than arithmetic: the allocator")
+	fmt.Println("     and GC are shared. Fewer allocations help every core at once.")
+	fmt.Println("   - GOMAXPROCS above the core count adds nothing. Since Go 1.25 the")
+	fmt.Println("     default also follows a container's CPU limit; leave it alone.")
+	fmt.Println("   - Measure on the machine that will run it: tools/scaling -procs")
+	fmt.Println("     takes any list, and -json feeds a report like this one.")
+}
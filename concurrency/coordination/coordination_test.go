@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// coordination - Tests
+// ====================
+// Run with:
+//
+//   cd concurrency/coordination
+//   go test -v *.go
+//   go test -race *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//   go test -run '^$' -bench . *.go
+//
+// The TestConcurrent tests are the ones section 5 of the lesson runs
+// under -race: every operation of every implementation, at once.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// 1. The Counters
+// ===============
+
+func TestCountersExact(t *testing.T) {
+	for _, impl := range counters {
+		c := impl.new()
+		Count(c, 7, 10001)
+		if got := c.Value(); got != 10001 {
+			t.Errorf("%s: %d, want 10001", impl.name, got)
+		}
+		closeCounter(c)
+	}
+}
+
+// Adds and Values at once: every Value sees a count that only grows
+func TestConcurrentCounters(t *testing.T) {
+	for _, impl := range counters {
+		c := impl.new()
+		var wg sync.WaitGroup
+		wg.Go(func() { Count(c, 4, 4000) })
+		wg.Go(func() {
+			last := int64(0)
+			for range 100 {
+				v := c.Value()
+				if v < last {
+					t.Errorf("%s: Value went from %d to %d", impl.name, last, v)
+				}
+				last = v
+			}
+		})
+		wg.Wait()
+		if got := c.Value(); got != 4000 {
+			t.Errorf("%s: %d, want 4000", impl.name, got)
+		}
+		closeCounter(c)
+	}
+}
+
+// An Add that has returned is in every later Value: the reason the
+// channel counter's add channel has no buffer
+func TestChanCounterAddThenValue(t *testing.T) {
+	c := NewChanCounter()
+	defer c.Close()
+	for i := range int64(1000) {
+		c.Add(1)
+		if got := c.Value(); got != i+1 {
+			t.Fatalf("after %d Adds, Value = %d", i+1, got)
+		}
+	}
+}
+
+// 2. The Queues
+// =============
+
+func TestQueuesFIFO(t *testing.T) {
+	for _, impl := range queues {
+		q := impl.new(4)
+		for j := range 4 {
+			q.Push(j)
+		}
+		q.Close()
+		var got []int
+		for job, ok := q.Pop(); ok; job, ok = q.Pop() {
+			got = append(got, job)
+		}
+		if want := []int{0, 1, 2, 3}; !slices.Equal(got, want) {
+			t.Errorf("%s: popped %v, want %v", impl.name, got, want)
+		}
+	}
+}
+
+// Four producers and four workers: every job is taken exactly once
+func TestConcurrentQueues(t *testing.T) {
+	for _, impl := range queues {
+		q := impl.new(8)
+		var producers, workers sync.WaitGroup
+		for p := range 4 {
+			producers.Go(func() {
+				for j := range 500 {
+					q.Push(p*500 + j)
+				}
+			})
+		}
+		seen := make([][]int, 4)
+		for w := range 4 {
+			workers.Go(func() {
+				for job, ok := q.Pop(); ok; job, ok = q.Pop() {
+					seen[w] = append(seen[w], job)
+				}
+			})
+		}
+		producers.Wait()
+		q.Close()
+		workers.Wait()
+
+		all := slices.Concat(seen...)
+		slices.Sort(all)
+		for i, job := range all {
+			if job != i {
+				t.Errorf("%s: job %d missing or repeated (%d jobs taken)", impl.name, i, len(all))
+				break
+			}
+		}
+		if len(all) != 2000 {
+			t.Errorf("%s: %d jobs taken, want 2000", impl.name, len(all))
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	want := 0
+	for j := range 1000 {
+		want += Spin(j, 5)
+	}
+	for _, impl := range queues {
+		for _, workers := range []int{1, 3, 16} {
+			if got := Process(impl.new(2), workers, 1000, func(j int) int { return Spin(j, 5) }); got != want {
+				t.Errorf("%s, %d workers: %d, want %d", impl.name, workers, got, want)
+			}
+		}
+	}
+}
+
+// waits reports whether f is still running after a short wait
+func waits(f func()) (blocked bool, done chan struct{}) {
+	done = make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return false, done
+	case <-time.After(50 * time.Millisecond):
+		return true, done
+	}
+}
+
+func TestPushWaitsWhenFull(t *testing.T) {
+	for _, impl := range queues {
+		q := impl.new(1)
+		q.Push(1)
+		blocked, done := waits(func() { q.Push(2) })
+		if !blocked {
+			t.Errorf("%s: Push on a full queue returned", impl.name)
+			continue
+		}
+		q.Pop()
+		<-done
+	}
+}
+
+func TestCloseWakesPop(t *testing.T) {
+	for _, impl := range queues {
+		q := impl.new(1)
+		var ok bool
+		blocked, done := waits(func() { _, ok = q.Pop() })
+		if !blocked {
+			t.Errorf("%s: Pop on an empty queue returned", impl.name)
+			continue
+		}
+		q.Close()
+		<-done
+		if ok {
+			t.Errorf("%s: Pop after Close returned ok", impl.name)
+		}
+	}
+}
+
+func TestPushAfterClosePanics(t *testing.T) {
+	for _, impl := range queues {
+		q := impl.new(1)
+		q.Close()
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Push after Close did not panic", impl.name)
+				}
+			}()
+			q.Push(1)
+		}()
+	}
+}
+
+// 3. What the Lesson Prints
+// =========================
+
+func TestCounterFindings(t *testing.T) {
+	results := []Result{
+		{"sync.Mutex", 1, 20}, {"sync.Mutex", 8, 40},
+		{"atomic.Int64", 1, 10}, {"atomic.Int64", 8, 10},
+		{"channel", 1, 400}, {"channel", 8, 500},
+	}
+	got := CounterFindings(results)
+	if want := "at 8 goroutines a channel Add costs 12.5x sync.Mutex and 50.0x atomic.Int64"; len(got) == 0 || got[0] != want {
+		t.Errorf("got %q, want %q first", got, want)
+	}
+}
+
+func TestQueueFindings(t *testing.T) {
+	results := []Result{
+		{"channel", 1, 100}, {"channel", 4, 40},
+		{"sync.Mutex + Cond", 1, 80}, {"sync.Mutex + Cond", 4, 50},
+	}
+	got := strings.Join(QueueFindings(Size{"tiny", 10}, 25, results), "\n")
+	for _, want := range []string{
+		"tiny jobs, workers=1: 75% of each job is the queue, 25% the work",
+		"tiny jobs, workers=4: the channel queue takes 0.80x the time of the mutex queue",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestRaceReport(t *testing.T) {
+	out := `==================
+WARNING: DATA RACE
+Read at 0x00c0000181b8 by goroutine 9:
+  main.main.func1()
+      /tmp/racy/main.go:26 +0x37
+
+Previous write at 0x00c0000181b8 by goroutine 8:
+  main.main.func1()
+      /tmp/racy/main.go:26 +0x49
+
+Goroutine 9 (running) created at:
+  sync.(*WaitGroup).Go()
+      /usr/local/go/src/sync/waitgroup.go:239 +0x6e
+==================
+`
+	got := RaceReport(out)
+	want := []string{
+		"WARNING: DATA RACE",
+		"Read by goroutine 9 at main.go:26",
+		"Previous write by goroutine 8 at main.go:26",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := RaceReport("4000\n"); got != nil {
+		t.Errorf("a clean run reported %q", got)
+	}
+}
+
+// The racy counter in testdata is caught
+func TestRaceDetector(t *testing.T) {
+	requireGo(t)
+	out, err := goCommand(t.Context(), "run", "-race", "testdata/racy/main.go")
+	if strings.Contains(out, "requires cgo") || strings.Contains(out, "not supported") {
+		t.Skip("no race detector here: " + out)
+	}
+	if err == nil {
+		t.Fatalf("go run -race succeeded:\n%s", out)
+	}
+	if report := RaceReport(out); len(report) < 3 {
+		t.Errorf("no race reported:\n%s", out)
+	}
+}
+
+func TestParseCounts(t *testing.T) {
+	got, err := parseCounts("1, 4,16")
+	if err != nil || !slices.Equal(got, []int{1, 4, 16}) {
+		t.Errorf("got %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "1,,2", "four", "0"} {
+		if _, err := parseCounts(bad); err == nil {
+			t.Errorf("parseCounts(%q) succeeded", bad)
+		}
+	}
+}
+
+// 4. Benchmarks
+// =============
+
+func BenchmarkCounter(b *testing.B) {
+	for _, impl := range counters {
+		for _, g := range []int{1, 4} {
+			b.Run(fmt.Sprintf("%s/goroutines=%d", impl.name, g), func(b *testing.B) {
+				c := impl.new()
+				defer closeCounter(c)
+				Count(c, g, b.N)
+			})
+		}
+	}
+}
+
+func BenchmarkQueue(b *testing.B) {
+	for _, size := range Sizes {
+		for _, impl := range queues {
+			for _, w := range []int{1, 4} {
+				b.Run(fmt.Sprintf("%s/%s/workers=%d", size.Name, impl.name, w), func(b *testing.B) {
+					intSink = Process(impl.new(capacity), w, b.N, func(j int) int { return Spin(j, size.Rounds) })
+				})
+			}
+		}
+	}
+}
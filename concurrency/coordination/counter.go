@@ -0,0 +1,113 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// The Counters
+// ============
+// One count that many goroutines add to, kept three ways:
+//
+//	MutexCounter    the int64 behind a sync.Mutex
+//	AtomicCounter   an atomic.Int64: one locked instruction per Add
+//	ChanCounter     the int64 owned by one goroutine; the others send
+//	                it numbers and ask it for the total
+//
+// ChanCounter is "share memory by communicating" taken literally. Its
+// add channel is unbuffered on purpose: Add returns once the owner has
+// the number, so a Value that starts after an Add returns includes it.
+// With a buffer, Add could return while the number still sat in the
+// channel, and select might answer Value first.
+
+// Counter is a count goroutines add to concurrently
+type Counter interface {
+	Add(n int64)
+	Value() int64
+}
+
+// MutexCounter is a count guarded by a sync.Mutex
+type MutexCounter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+// Add adds n to the count
+func (c *MutexCounter) Add(n int64) {
+	c.mu.Lock()
+	c.n += n
+	c.mu.Unlock()
+}
+
+// Value returns the count
+func (c *MutexCounter) Value() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// AtomicCounter is a count in an atomic.Int64
+type AtomicCounter struct {
+	n atomic.Int64
+}
+
+// Add adds n to the count
+func (c *AtomicCounter) Add(n int64) { c.n.Add(n) }
+
+// Value returns the count
+func (c *AtomicCounter) Value() int64 { return c.n.Load() }
+
+// ChanCounter is a count owned by a goroutine that only it touches
+type ChanCounter struct {
+	add  chan int64
+	get  chan int64
+	done chan struct{}
+}
+
+// NewChanCounter starts the owning goroutine. Close stops it.
+func NewChanCounter() *ChanCounter {
+	c := &ChanCounter{add: make(chan int64), get: make(chan int64), done: make(chan struct{})}
+	go c.own()
+	return c
+}
+
+// own is the only code that reads or writes total, so it needs no lock
+func (c *ChanCounter) own() {
+	var total int64
+	for {
+		select {
+		case n := <-c.add:
+			total += n
+		case c.get <- total:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Add sends n to the owner and returns once it has been counted
+func (c *ChanCounter) Add(n int64) { c.add <- n }
+
+// Value asks the owner for the count
+func (c *ChanCounter) Value() int64 { return <-c.get }
+
+// Close stops the owner. Add and Value block forever after it.
+func (c *ChanCounter) Close() { close(c.done) }
+
+// Count adds 1 to c ops times, split between goroutines goroutines, and
+// returns when all of them have finished
+func Count(c Counter, goroutines, ops int) {
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		n := ops / goroutines
+		if g < ops%goroutines {
+			n++
+		}
+		wg.Go(func() {
+			for range n {
+				c.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Channels vs Mutexes: Coordinating Goroutines
+// ============================================
+// "Share memory by communicating" is advice about design, not a rule
+// that channels are always the answer. This lesson builds two things
+// both ways and measures them:
+//
+//	a shared counter   counter.go: a mutex, an atomic, and a goroutine
+//	                   that owns the count behind channels
+//	a work queue       queue.go: a buffered channel, and a ring with a
+//	                   mutex and two condition variables
+//
+// The rule of thumb the numbers support:
+//
+//	protecting state         a mutex (or an atomic for one word): the
+//	                         caller keeps running on its own goroutine
+//	handing off work         a channel: blocking, backpressure, close
+//	                         and select come with it
+//	signalling an event      close a channel: every receiver wakes
+//
+// Every implementation runs under go test -race; section 5 shows the
+// detector catching a counter that has no synchronization at all.
+//
+// Run with:
+//
+//	cd concurrency/coordination
+//	go run counter.go main.go queue.go
+//	go run counter.go main.go queue.go -goroutines 1,4,16
+//	go test -v *.go
+//	go test -race *.go
+//	go test -run '^$' -bench . *.go
+
+var countsFlag = flag.String("goroutines", "1,2,4,8", "comma-separated goroutine and worker counts")
+
+// capacity is the queue size the lesson measures
+const capacity = 64
+
+type counterImpl struct {
+	name string
+	new  func() Counter
+}
+
+type queueImpl struct {
+	name string
+	new  func(capacity int) Queue
+}
+
+var (
+	counters = []counterImpl{
+		{"sync.Mutex", func() Counter { return &MutexCounter{} }},
+		{"atomic.Int64", func() Counter { return &AtomicCounter{} }},
+		{"channel", func() Counter { return NewChanCounter() }},
+	}
+	queues = []queueImpl{
+		{"channel", func(n int) Queue { return NewChanQueue(n) }},
+		{"sync.Mutex + Cond", func(n int) Queue { return NewMutexQueue(n) }},
+	}
+)
+
+// Size is how much work a job does: Rounds of xorshift in Spin
+type Size struct {
+	Name   string
+	Rounds int
+}
+
+// Sizes are the job sizes the queues are measured with: one where the
+// queue is all there is, and one where the work dominates
+var Sizes = []Size{{"tiny", 10}, {"heavy", 1000}}
+
+// Spin does rounds of xorshift on job: work the compiler cannot skip
+func Spin(job, rounds int) int {
+	x := uint64(job) | 1
+	for range rounds {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	return int(x >> 32)
+}
+
+var intSink int
+
+func main() {
+	testing.Init()
+	flag.Parse()
+	if !flagSet("test.benchtime") {
+		flag.Set("test.benchtime", "100ms")
+	}
+	counts, err := parseCounts(*countsFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("=== Channels vs Mutexes: Coordinating Goroutines ===")
+	ctx := context.Background()
+
+	// 1. One count, three counters
+	oneCount()
+
+	// 2. What an Add costs
+	counterCosts(counts)
+
+	// 3. One queue, two implementations
+	oneQueue()
+
+	// 4. What a job costs
+	queueCosts(counts)
+
+	// 5. The race detector
+	raceDetector(ctx)
+
+	// 6. When each is idiomatic
+	guidance()
+}
+
+// 1. One Count, Three Counters
+// ============================
+func oneCount() {
+	fmt.Println("\n1. ONE COUNT, THREE COUNTERS (8 goroutines x 10000 Adds):")
+	for _, impl := range counters {
+		c := impl.new()
+		Count(c, 8, 80000)
+		fmt.Printf("   %-13s %d\n", impl.name, c.Value())
+		closeCounter(c)
+	}
+	fmt.Println("   All exact. The channel counter has no mutex of its own: only the")
+	fmt.Println("   owner goroutine touches the int. The channel it listens on has one")
+	fmt.Println("   inside, and every Add also wakes the owner up.")
+}
+
+// closeCounter stops c's goroutine, if it has one
+func closeCounter(c Counter) {
+	if c, ok := c.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+// 2. What an Add Costs
+// ====================
+
+// Result is one implementation at one goroutine or worker count
+type Result struct {
+	Name    string
+	Count   int
+	NsPerOp float64
+}
+
+func counterCosts(counts []int) {
+	fmt.Printf("\n2. WHAT AN ADD COSTS (ns per Add, all goroutines together, GOMAXPROCS=%d):\n", runtime.GOMAXPROCS(0))
+	var results []Result
+	header("goroutines", counts)
+	for _, impl := range counters {
+		fmt.Printf("   %-18s", impl.name)
+		for _, g := range counts {
+			c := impl.new()
+			r := testing.Benchmark(func(b *testing.B) { Count(c, g, b.N) })
+			closeCounter(c)
+			results = append(results, Result{impl.name, g, nsPerOp(r)})
+			fmt.Printf(" %8.1f", nsPerOp(r))
+		}
+		fmt.Println()
+	}
+	for _, line := range CounterFindings(results) {
+		fmt.Println("   -> " + line)
+	}
+}
+
+// CounterFindings compares the channel counter with the others at the
+// most goroutines measured
+func CounterFindings(results []Result) []string {
+	high := slices.MaxFunc(results, func(a, b Result) int { return a.Count - b.Count }).Count
+	ch, ok := find(results, "channel", high)
+	if !ok {
+		return nil
+	}
+	var vs []string
+	for _, r := range results {
+		if r.Count == high && r.Name != "channel" {
+			vs = append(vs, fmt.Sprintf("%.1fx %s", ch.NsPerOp/r.NsPerOp, r.Name))
+		}
+	}
+	return []string{
+		fmt.Sprintf("at %d goroutines a channel Add costs %s", high, strings.Join(vs, " and ")),
+		"a channel Add hands the number to another goroutine and waits for it; a mutex or atomic Add stays on its own",
+	}
+}
+
+// 3. One Queue, Two Implementations
+// =================================
+func oneQueue() {
+	fmt.Printf("\n3. ONE QUEUE, TWO IMPLEMENTATIONS (10000 jobs, 4 workers, capacity %d):\n", capacity)
+	want := 0
+	for j := range 10000 {
+		want += Spin(j, 10)
+	}
+	for _, impl := range queues {
+		got := Process(impl.new(capacity), 4, 10000, func(j int) int { return Spin(j, 10) })
+		fmt.Printf("   %-18s sum %d, the same as one goroutine: %t\n", impl.name, got, got == want)
+	}
+	fmt.Println("   ChanQueue is three one-line methods. MutexQueue needs a ring, two")
+	fmt.Println("   condition variables, Wait in a loop, and Broadcast on Close - and")
+	fmt.Println("   each of those is a bug when it is missing.")
+}
+
+// 4. What a Job Costs
+// ===================
+func queueCosts(counts []int) {
+	fmt.Printf("\n4. WHAT A JOB COSTS (ns per job through the queue, capacity %d):\n", capacity)
+	for _, size := range Sizes {
+		work := testing.Benchmark(func(b *testing.B) {
+			j := 0
+			for b.Loop() {
+				intSink = Spin(j, size.Rounds)
+				j++
+			}
+		})
+		fmt.Printf("   %s jobs (Spin %d rounds, %.1f ns of work each):\n", size.Name, size.Rounds, nsPerOp(work))
+		var results []Result
+		header("workers", counts)
+		for _, impl := range queues {
+			fmt.Printf("   %-18s", impl.name)
+			for _, w := range counts {
+				r := testing.Benchmark(func(b *testing.B) {
+					intSink = Process(impl.new(capacity), w, b.N, func(j int) int { return Spin(j, size.Rounds) })
+				})
+				results = append(results, Result{impl.name, w, nsPerOp(r)})
+				fmt.Printf(" %8.1f", nsPerOp(r))
+			}
+			fmt.Println()
+		}
+		for _, line := range QueueFindings(size, nsPerOp(work), results) {
+			fmt.Println("   -> " + line)
+		}
+	}
+}
+
+// QueueFindings reads two things off one job size's results: how much
+// of a job's time is the queue rather than the work, with the fewest
+// workers, and how the two queues compare with the most
+func QueueFindings(size Size, workNs float64, results []Result) []string {
+	low := slices.MinFunc(results, func(a, b Result) int { return a.Count - b.Count }).Count
+	high := slices.MaxFunc(results, func(a, b Result) int { return a.Count - b.Count }).Count
+	var lines []string
+	for _, r := range results {
+		if r.Count == low && r.Name == "channel" {
+			lines = append(lines, fmt.Sprintf("%s jobs, workers=%d: %.0f%% of each job is the queue, %.0f%% the work",
+				size.Name, low, 100*max(0, 1-workNs/r.NsPerOp), 100*min(1, workNs/r.NsPerOp)))
+		}
+	}
+	ch, ok1 := find(results, "channel", high)
+	mu, ok2 := find(results, "sync.Mutex + Cond", high)
+	if ok1 && ok2 {
+		lines = append(lines, fmt.Sprintf("%s jobs, workers=%d: the channel queue takes %.2fx the time of the mutex queue",
+			size.Name, high, ch.NsPerOp/mu.NsPerOp))
+	}
+	return lines
+}
+
+// find returns the result for name at count
+func find(results []Result, name string, count int) (Result, bool) {
+	i := slices.IndexFunc(results, func(r Result) bool { return r.Name == name && r.Count == count })
+	if i < 0 {
+		return Result{}, false
+	}
+	return results[i], true
+}
+
+func header(what string, counts []int) {
+	fmt.Printf("   %-18s", what)
+	for _, n := range counts {
+		fmt.Printf(" %8d", n)
+	}
+	fmt.Println()
+}
+
+func nsPerOp(r testing.BenchmarkResult) float64 {
+	return float64(r.T.Nanoseconds()) / float64(r.N)
+}
+
+// 5. The Race Detector
+// ====================
+func raceDetector(ctx context.Context) {
+	fmt.Println("\n5. THE RACE DETECTOR (go run -race, go test -race):")
+	out, err := goCommand(ctx, "run", "-race", "testdata/racy/main.go")
+	if err == nil {
+		fmt.Println("   testdata/racy ran clean under -race; it should not have")
+	} else if report := RaceReport(out); len(report) > 0 {
+		fmt.Println("   testdata/racy: n++ from 4 goroutines, no lock:")
+		for _, line := range report {
+			fmt.Println("      " + line)
+		}
+	} else {
+		fmt.Printf("   (could not run the race detector: %v)\n", err)
+		return
+	}
+
+	files, _ := filepath.Glob(filepath.Join(lessonDir(), "*.go"))
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	args := append([]string{"test", "-race", "-count=1", "-run", "Concurrent"}, files...)
+	if _, err := goCommand(ctx, args...); err != nil {
+		fmt.Printf("   go test -race on this lesson failed: %v\n", err)
+		return
+	}
+	fmt.Println("   go test -race -run Concurrent *.go: ok - every counter and queue, with")
+	fmt.Println("   goroutines adding, pushing and popping at once, and no report.")
+	fmt.Println("   The detector only sees races that happen while it watches: run the")
+	fmt.Println("   tests with -race in CI, under load, not once by hand.")
+}
+
+// RaceReport picks the accesses out of a race detector report: which
+// goroutine read or wrote, and where
+func RaceReport(out string) []string {
+	var report []string
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "WARNING: DATA RACE"):
+			report = append(report, line)
+		case strings.Contains(line, " at 0x") && strings.Contains(line, " by "):
+			// "Read at 0x00c000012345 by goroutine 7:", then its frames,
+			// the first of them "      /path/main.go:27 +0x37"
+			what, _, _ := strings.Cut(line, " at 0x")
+			_, who, _ := strings.Cut(line, " by ")
+			where := ""
+			for _, frame := range lines[i+1:] {
+				if f := strings.Fields(frame); len(f) > 0 && strings.Contains(f[0], ".go:") {
+					where = filepath.Base(f[0])
+					break
+				}
+			}
+			report = append(report, fmt.Sprintf("%s by %s at %s", what, strings.TrimSuffix(who, ":"), where))
+		}
+	}
+	return report
+}
+
+// goCommand runs the go command in this lesson's directory
+func goCommand(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = lessonDir()
+	cmd.Env = append(os.Environ(), "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// lessonDir is the directory this file is in
+func lessonDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}
+
+// 6. When Each Is Idiomatic
+// =========================
+func guidance() {
+	fmt.Println("\n6. WHEN EACH IS IDIOMATIC:")
+	fmt.Println("   - A counter, a cache, a struct several goroutines update: a mutex,")
+	fmt.Println("     held for a few lines. One word on its own: sync/atomic.")
+	fmt.Println("   - A goroutine that owns state and serves requests on channels earns")
+	fmt.Println("     its cost when the state has a lifecycle of its own - a connection,")
+	fmt.Println("     a timer, a loop that selects on several events.")
+	fmt.Println("   - Jobs from producers to workers: a channel. Close it to say done,")
+	fmt.Println("     size its buffer for backpressure, and select on ctx.Done() to give up.")
+	fmt.Println("   - Mutex and sync.Cond for a queue only when a channel cannot do it:")
+	fmt.Println("     priorities, peeking, taking a batch at once.")
+	fmt.Println("   - Never both for the same data. Pick the one that makes it obvious")
+	fmt.Println("     who may touch the data, and when.")
+}
+
+// parseCounts reads "1,2,4" into goroutine counts
+func parseCounts(s string) ([]int, error) {
+	var counts []int
+	for f := range strings.SplitSeq(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("-goroutines: %q is not a goroutine count", f)
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
+}
+
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sync"
+)
+
+// The Queues
+// ==========
+// A bounded work queue: producers Push jobs, workers Pop them, and
+// Close says no more are coming. Both versions block a Push when the
+// queue is full and a Pop when it is empty, and both let workers drain
+// what is left after Close.
+//
+//	ChanQueue    make(chan int, capacity): the runtime already has the
+//	             ring buffer, the waiting and the closing
+//	MutexQueue   the same written out: a ring, a sync.Mutex, and two
+//	             sync.Conds - one for "not empty", one for "not full"
+//
+// MutexQueue is the length of this file for a reason: it is what a
+// buffered channel does for free. Reach for it only for what a channel
+// cannot do - peek, reorder by priority, drain a batch under one lock.
+
+// Queue hands jobs from producers to workers
+type Queue interface {
+	// Push adds a job, waiting while the queue is full. It panics after
+	// Close, as a send on a closed channel does.
+	Push(job int)
+	// Pop takes the oldest job, waiting while the queue is empty. ok is
+	// false once the queue is closed and empty.
+	Pop() (job int, ok bool)
+	// Close tells workers that no more jobs are coming
+	Close()
+}
+
+// ChanQueue is a Queue on a buffered channel
+type ChanQueue struct {
+	jobs chan int
+}
+
+// NewChanQueue returns a queue holding up to capacity jobs
+func NewChanQueue(capacity int) *ChanQueue {
+	return &ChanQueue{jobs: make(chan int, capacity)}
+}
+
+// Push adds a job
+func (q *ChanQueue) Push(job int) { q.jobs <- job }
+
+// Pop takes the oldest job
+func (q *ChanQueue) Pop() (int, bool) {
+	job, ok := <-q.jobs
+	return job, ok
+}
+
+// Close tells workers that no more jobs are coming
+func (q *ChanQueue) Close() { close(q.jobs) }
+
+// MutexQueue is a Queue on a ring buffer guarded by a sync.Mutex
+type MutexQueue struct {
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	ring     []int
+	head, n  int // the oldest job is ring[head]; n jobs are queued
+	closed   bool
+}
+
+// NewMutexQueue returns a queue holding up to capacity jobs. It panics
+// if capacity < 1: unlike a channel, the ring needs a slot.
+func NewMutexQueue(capacity int) *MutexQueue {
+	if capacity < 1 {
+		panic("coordination: a MutexQueue needs a capacity of at least 1")
+	}
+	q := &MutexQueue{ring: make([]int, capacity)}
+	q.notEmpty.L = &q.mu
+	q.notFull.L = &q.mu
+	return q
+}
+
+// Push adds a job
+func (q *MutexQueue) Push(job int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// Wait in a loop: another producer may fill the slot between the
+	// Signal and this goroutine getting the lock back
+	for q.n == len(q.ring) && !q.closed {
+		q.notFull.Wait()
+	}
+	if q.closed {
+		panic("coordination: Push on a closed queue")
+	}
+	q.ring[(q.head+q.n)%len(q.ring)] = job
+	q.n++
+	q.notEmpty.Signal()
+}
+
+// Pop takes the oldest job
+func (q *MutexQueue) Pop() (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.n == 0 && !q.closed {
+		q.notEmpty.Wait()
+	}
+	if q.n == 0 {
+		return 0, false
+	}
+	job := q.ring[q.head]
+	q.head = (q.head + 1) % len(q.ring)
+	q.n--
+	q.notFull.Signal()
+	return job, true
+}
+
+// Close tells workers that no more jobs are coming. Every waiting Pop
+// wakes up, so Broadcast rather than Signal
+func (q *MutexQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// Process pushes jobs 0 to jobs-1 through q from one producer, runs
+// work on each in workers goroutines, and returns the sum of the
+// results. Each worker sums its own share, so the total needs no lock
+func Process(q Queue, workers, jobs int, work func(int) int) int {
+	sums := make([]int, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Go(func() {
+			// Sum in a local: adding to sums[w] on every job would make
+			// the workers share the slice's cache line
+			sum := 0
+			for job, ok := q.Pop(); ok; job, ok = q.Pop() {
+				sum += work(job)
+			}
+			sums[w] = sum
+		})
+	}
+	for j := range jobs {
+		q.Push(j)
+	}
+	q.Close()
+	wg.Wait()
+
+	total := 0
+	for _, s := range sums {
+		total += s
+	}
+	return total
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A Racy Counter
+// ==============
+// Four goroutines add to one int with no synchronization. n++ is a
+// load, an add and a store, so two goroutines can load the same value
+// and one increment is lost. Without -race it usually prints 4000 on
+// one core and less on several; a wrong answer that is right most of
+// the time is what makes races hard to find. With -race:
+//
+//	go run -race main.go   WARNING: DATA RACE, then exit status 66
+//
+// It lives in testdata so go vet and learnctl leave it alone.
+
+func main() {
+	n := 0
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 1000 {
+				n++
+			}
+		})
+	}
+	wg.Wait()
+	fmt.Println(n)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// Concurrent Maps: sync.Map vs Mutex vs Sharded
+// =============================================
+// Which concurrent map is fastest depends on three things: the mix of
+// reads and writes, how many goroutines share the map, and how many
+// cores they run on. This lesson measures all three on the machine it
+// runs on and reads its advice off the numbers rather than asserting
+// it: the four maps are in maps.go, the workloads in workload.go.
+//
+// What to expect on a multi-core machine:
+//
+//	sync.Mutex     fine with one goroutine; the more goroutines, the
+//	               more of each operation is spent waiting
+//	sync.RWMutex   Loads run together, but RLock still writes a shared
+//	               counter, so read-heavy work scales less than hoped
+//	sharded        scales with cores for every mix, until two hot keys
+//	               share a shard
+//	sync.Map       the fastest Loads at high core counts; each Store
+//	               allocates, so it falls behind as writes grow
+//
+// Run with:
+//
+//	cd concurrency/maps
+//	go run main.go maps.go workload.go
+//	go run main.go maps.go workload.go -goroutines 1,4,16,64
+//	go run main.go maps.go workload.go -test.benchtime=1s   steadier, slower
+//	go test -v *.go
+//	go test -race *.go
+//
+// With GOMAXPROCS=1 the goroutines take turns on one core and no lock
+// is ever contended; the findings say so when that is the case.
+
+var (
+	countsFlag = flag.String("goroutines", "1,2,4,8", "comma-separated goroutine counts")
+	keysFlag   = flag.Int("keys", 10000, "keys in each map")
+)
+
+func main() {
+	testing.Init()
+	flag.Parse()
+	if !flagSet("test.benchtime") {
+		flag.Set("test.benchtime", "100ms")
+	}
+	counts, err := parseCounts(*countsFlag)
+	if err != nil {
+		fail(err)
+	}
+	if *keysFlag < 1 {
+		fail(fmt.Errorf("-keys: %d is not a key count", *keysFlag))
+	}
+
+	fmt.Println("=== Concurrent Maps: sync.Map vs Mutex vs Sharded ===")
+
+	// 1. Four maps, one behaviour
+	oneBehaviour()
+
+	// 2. The measurements
+	results := measurements(counts, *keysFlag)
+
+	// 3. What the numbers say
+	fmt.Println("\n3. WHAT THE NUMBERS SAY:")
+	for _, line := range Findings(results, runtime.GOMAXPROCS(0)) {
+		fmt.Println("   - " + line)
+	}
+
+	// 4. Choosing a map
+	guidance()
+}
+
+// 1. Four Maps, One Behaviour
+// ===========================
+func oneBehaviour() {
+	fmt.Println("\n1. FOUR MAPS, ONE BEHAVIOUR:")
+	for _, impl := range Impls {
+		if err := Check(impl, 1000, 100000); err != nil {
+			fmt.Printf("   %-13s %v\n", impl.Name, err)
+			continue
+		}
+		fmt.Printf("   %-13s the same 1000 keys and values as a plain map after 100000 operations\n", impl.Name)
+	}
+	fmt.Printf("   a shard is %d bytes, one cache line; the lesson uses %d of them\n",
+		unsafe.Sizeof(shard[int, int]{}), NewShardedMap[int, int](shards).Shards())
+}
+
+// plainMap is the unsynchronized map the others are checked against.
+// One goroutine only
+type plainMap map[int]int
+
+func (m plainMap) Load(key int) (int, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+func (m plainMap) Store(key, value int) { m[key] = value }
+
+// Check runs the same operations on impl and on a plain map from one
+// goroutine and compares every key afterwards
+func Check(impl Impl, keys, ops int) error {
+	want, got := plainMap{}, impl.New()
+	Fill(want, keys)
+	Fill(got, keys)
+	mixed := Workload{"check", 50}
+	Run(want, mixed, keys, 1, ops)
+	Run(got, mixed, keys, 1, ops)
+	for k := range keys {
+		if v, ok := got.Load(k); !ok || v != want[k] {
+			return fmt.Errorf("key %d: got %d, %t; want %d", k, v, ok, want[k])
+		}
+	}
+	if v, ok := got.Load(keys); ok {
+		return fmt.Errorf("key %d was never stored, but Load returned %d", keys, v)
+	}
+	return nil
+}
+
+// 2. The Measurements
+// ===================
+
+// Result is one map under one workload at one goroutine count
+type Result struct {
+	Impl       string
+	Workload   Workload
+	Goroutines int
+	Bench      testing.BenchmarkResult
+}
+
+// NsPerOp is the wall time per operation, all goroutines together: if
+// it halves when the goroutines double, the map scales perfectly
+func (r Result) NsPerOp() float64 {
+	return float64(r.Bench.T.Nanoseconds()) / float64(r.Bench.N)
+}
+
+// AllocsPerOp is allocations per operation, unrounded
+func (r Result) AllocsPerOp() float64 {
+	return float64(r.Bench.MemAllocs) / float64(r.Bench.N)
+}
+
+func measurements(counts []int, keys int) []Result {
+	fmt.Printf("\n2. THE MEASUREMENTS (%d keys, GOMAXPROCS=%d, %s):\n", keys, runtime.GOMAXPROCS(0), runtime.Version())
+	var results []Result
+	for _, w := range Workloads {
+		fmt.Printf("   %s (%d%% Loads), ns/op by goroutines:\n", w.Name, w.Loads)
+		fmt.Printf("   %-13s", "")
+		for _, g := range counts {
+			fmt.Printf(" %8d", g)
+		}
+		fmt.Println()
+		for _, impl := range Impls {
+			fmt.Printf("   %-13s", impl.Name)
+			for _, g := range counts {
+				r := Measure(impl, w, keys, g)
+				results = append(results, r)
+				fmt.Printf(" %8.1f", r.NsPerOp())
+			}
+			fmt.Println()
+		}
+	}
+	return results
+}
+
+// Measure fills a new map and benchmarks workload w on it
+func Measure(impl Impl, w Workload, keys, goroutines int) Result {
+	m := impl.New()
+	Fill(m, keys)
+	r := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		Run(m, w, keys, goroutines, b.N)
+	})
+	return Result{impl.Name, w, goroutines, r}
+}
+
+// 3. What the Numbers Say
+// =======================
+
+// Findings reads the advice off the results: the ranking under each
+// workload at the most goroutines, whether a plain sync.Mutex is close
+// enough to keep, how each map coped with more goroutines, and which
+// ones allocate. procs is the GOMAXPROCS the results were measured at;
+// with one, only the ranking and the allocations mean anything
+func Findings(results []Result, procs int) []string {
+	var lines []string
+	if procs == 1 {
+		lines = append(lines, "GOMAXPROCS=1: the goroutines took turns on one core, so no lock was ever contended. "+
+			"These numbers show the cost of each map alone; run on more cores before choosing one")
+	}
+
+	var impls, workloads []string
+	var counts []int
+	cell := map[string]map[string]map[int]Result{}
+	for _, r := range results {
+		if !slices.Contains(impls, r.Impl) {
+			impls = append(impls, r.Impl)
+		}
+		if !slices.Contains(workloads, r.Workload.Name) {
+			workloads = append(workloads, r.Workload.Name)
+		}
+		if !slices.Contains(counts, r.Goroutines) {
+			counts = append(counts, r.Goroutines)
+		}
+		if cell[r.Workload.Name] == nil {
+			cell[r.Workload.Name] = map[string]map[int]Result{}
+		}
+		if cell[r.Workload.Name][r.Impl] == nil {
+			cell[r.Workload.Name][r.Impl] = map[int]Result{}
+		}
+		cell[r.Workload.Name][r.Impl][r.Goroutines] = r
+	}
+	if len(results) == 0 {
+		return lines
+	}
+	slices.Sort(counts)
+	low, high := counts[0], counts[len(counts)-1]
+
+	// The ranking, and whether it is worth leaving sync.Mutex
+	for _, w := range workloads {
+		ranked := slices.Clone(impls)
+		slices.SortStableFunc(ranked, func(a, b string) int {
+			return cmp.Compare(cell[w][a][high].NsPerOp(), cell[w][b][high].NsPerOp())
+		})
+		best := cell[w][ranked[0]][high].NsPerOp()
+		var rest []string
+		for _, name := range ranked[1:] {
+			rest = append(rest, fmt.Sprintf("%s %.1fx", name, cell[w][name][high].NsPerOp()/best))
+		}
+		lines = append(lines, fmt.Sprintf("%s, %d goroutines: %s fastest at %.1f ns/op; %s",
+			w, high, ranked[0], best, strings.Join(rest, ", ")))
+
+		mutex, ok := cell[w]["sync.Mutex"][high]
+		switch {
+		case !ok || procs == 1:
+		case ranked[0] == "sync.Mutex":
+			lines = append(lines, fmt.Sprintf("%s: nothing beat a plain sync.Mutex - keep it", w))
+		case mutex.NsPerOp()/best < 1.25:
+			lines = append(lines, fmt.Sprintf("%s: sync.Mutex is within %.2fx of %s - not worth the change",
+				w, mutex.NsPerOp()/best, ranked[0]))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s is %.1fx faster than sync.Mutex - worth it once a mutex profile shows the lock",
+				w, ranked[0], mutex.NsPerOp()/best))
+		}
+	}
+
+	// How each map coped with more goroutines, under the workload with
+	// the most Stores. On one core that only measures the scheduler
+	if low != high && procs > 1 {
+		w := slices.MinFunc(results, func(a, b Result) int { return a.Workload.Loads - b.Workload.Loads }).Workload.Name
+		var growth []string
+		for _, name := range impls {
+			growth = append(growth, fmt.Sprintf("%s %.2fx", name, cell[w][name][high].NsPerOp()/cell[w][name][low].NsPerOp()))
+		}
+		lines = append(lines, fmt.Sprintf("%s, ns/op at %d goroutines over ns/op at %d (below 1 is scaling, above 1 is contention): %s",
+			w, high, low, strings.Join(growth, ", ")))
+	}
+
+	// Which maps allocate, and whether it is a fixed number per Store
+	var clean []string
+	for _, name := range impls {
+		var per []string
+		var perStore []float64
+		for _, w := range workloads {
+			r := cell[w][name][high]
+			per = append(per, fmt.Sprintf("%.2f %s", r.AllocsPerOp(), w))
+			if stores := float64(100-r.Workload.Loads) / 100; stores > 0 {
+				perStore = append(perStore, r.AllocsPerOp()/stores)
+			}
+		}
+		if !slices.ContainsFunc(workloads, func(w string) bool { return cell[w][name][high].AllocsPerOp() >= 0.01 }) {
+			clean = append(clean, name)
+			continue
+		}
+		line := fmt.Sprintf("%s allocates per op: %s", name, strings.Join(per, ", "))
+		if len(perStore) > 0 && slices.Max(perStore) < 1.2*slices.Min(perStore) {
+			line += fmt.Sprintf(" - about %.0f per Store, whatever the mix", slices.Max(perStore))
+		}
+		lines = append(lines, line)
+	}
+	if len(clean) > 0 {
+		lines = append(lines, strings.Join(clean, ", ")+" did not allocate")
+	}
+	return lines
+}
+
+// 4. Choosing a Map
+// =================
+func guidance() {
+	fmt.Println("\n4. CHOOSING A MAP:")
+	fmt.Println("   - Start with a sync.Mutex around a map. Change it when a mutex profile")
+	fmt.Println("     (go test -mutexprofile, or runtime.SetMutexProfileFraction) shows")
+	fmt.Println("     goroutines waiting on it, not before.")
+	fmt.Println("   - sync.RWMutex pays when readers hold the lock for a while - iterating,")
+	fmt.Println("     copying - not for one map lookup.")
+	fmt.Println("   - sync.Map is documented for two cases: keys written once and read many")
+	fmt.Println("     times, and goroutines working on disjoint keys. It has no Len, keys")
+	fmt.Println("     and values are boxed as any, and a Store replaces the entry rather")
+	fmt.Println("     than updating it: allocations the mutex maps never make.")
+	fmt.Println("   - Shard when many cores write: the hash spreads the lock, and padding")
+	fmt.Println("     keeps each shard's lock on its own cache line. Range and Len now take")
+	fmt.Println("     every shard's lock, and a hot key still serializes.")
+	fmt.Println("   - Run this on the machine that will run the code: the ranking moves")
+	fmt.Println("     with the core count.")
+}
+
+// parseCounts reads "1,2,4" into goroutine counts
+func parseCounts(s string) ([]int, error) {
+	var counts []int
+	for f := range strings.SplitSeq(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("-goroutines: %q is not a goroutine count", f)
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
+}
+
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"sync"
+)
+
+// The Maps
+// ========
+// Four ways to share a map between goroutines, behind one interface so
+// the benchmarks can run the same workload on each:
+//
+//	MutexMap      one sync.Mutex around a map: every Load and Store
+//	              waits for every other
+//	RWMutexMap    one sync.RWMutex: Loads share the lock, but each one
+//	              still writes the reader count, one cache line that
+//	              every core fights over
+//	ShardedMap    the keys hashed over many RWMutexMaps, each on its own
+//	              cache line: goroutines meet only when their keys land
+//	              in the same shard
+//	SyncMap       sync.Map, typed: Loads take no lock. Keys and values
+//	              are stored as any, and a Store swaps in a new entry,
+//	              so it allocates where the others do not
+//
+// A plain map is not one of them. Concurrent writes, or a write during
+// a read, are a fatal error ("concurrent map writes"), not a panic that
+// recover can catch.
+
+// Map is what the workloads need from a concurrent map
+type Map[K comparable, V any] interface {
+	Load(key K) (V, bool)
+	Store(key K, value V)
+}
+
+// MutexMap is a map guarded by a sync.Mutex
+type MutexMap[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]V
+}
+
+// NewMutexMap returns an empty MutexMap
+func NewMutexMap[K comparable, V any]() *MutexMap[K, V] {
+	return &MutexMap[K, V]{m: map[K]V{}}
+}
+
+// Load returns the value stored under key, if any
+func (m *MutexMap[K, V]) Load(key K) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.m[key]
+	return v, ok
+}
+
+// Store sets the value under key
+func (m *MutexMap[K, V]) Store(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = value
+}
+
+// RWMutexMap is a map guarded by a sync.RWMutex
+type RWMutexMap[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+// NewRWMutexMap returns an empty RWMutexMap
+func NewRWMutexMap[K comparable, V any]() *RWMutexMap[K, V] {
+	return &RWMutexMap[K, V]{m: map[K]V{}}
+}
+
+// Load returns the value stored under key, if any
+func (m *RWMutexMap[K, V]) Load(key K) (V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.m[key]
+	return v, ok
+}
+
+// Store sets the value under key
+func (m *RWMutexMap[K, V]) Store(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = value
+}
+
+// cacheLine is the size false sharing happens at on amd64 and arm64
+const cacheLine = 64
+
+// shard is one lock and its map. The padding gives each shard a cache
+// line of its own: without it, locking shard 0 would invalidate the
+// line holding shard 1's lock on every other core
+type shard[K comparable, V any] struct {
+	RWMutexMap[K, V]
+	_ [cacheLine - 32]byte // sync.RWMutex is 24 bytes, the map pointer 8
+}
+
+// ShardedMap spreads its keys over a power-of-two number of shards by
+// hash, so unrelated keys do not share a lock
+type ShardedMap[K comparable, V any] struct {
+	seed   maphash.Seed
+	mask   uint64
+	shards []shard[K, V]
+}
+
+// NewShardedMap returns an empty ShardedMap with n shards, rounded up
+// to a power of two so a mask can pick one. It panics if n < 1.
+func NewShardedMap[K comparable, V any](n int) *ShardedMap[K, V] {
+	if n < 1 {
+		panic("maps: a ShardedMap needs at least one shard")
+	}
+	n = 1 << bits.Len(uint(n-1))
+	m := &ShardedMap[K, V]{seed: maphash.MakeSeed(), mask: uint64(n - 1), shards: make([]shard[K, V], n)}
+	for i := range m.shards {
+		m.shards[i].m = map[K]V{}
+	}
+	return m
+}
+
+// shard returns the shard that holds key
+func (m *ShardedMap[K, V]) shard(key K) *shard[K, V] {
+	return &m.shards[maphash.Comparable(m.seed, key)&m.mask]
+}
+
+// Load returns the value stored under key, if any
+func (m *ShardedMap[K, V]) Load(key K) (V, bool) {
+	return m.shard(key).Load(key)
+}
+
+// Store sets the value under key
+func (m *ShardedMap[K, V]) Store(key K, value V) {
+	m.shard(key).Store(key, value)
+}
+
+// Shards returns the number of shards
+func (m *ShardedMap[K, V]) Shards() int {
+	return len(m.shards)
+}
+
+// SyncMap is a sync.Map with the type assertions written once
+type SyncMap[K comparable, V any] struct {
+	m sync.Map
+}
+
+// NewSyncMap returns an empty SyncMap. The zero value is ready to use
+// too; the constructor matches the others.
+func NewSyncMap[K comparable, V any]() *SyncMap[K, V] {
+	return &SyncMap[K, V]{}
+}
+
+// Load returns the value stored under key, if any
+func (m *SyncMap[K, V]) Load(key K) (V, bool) {
+	v, ok := m.m.Load(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return v.(V), true
+}
+
+// Store sets the value under key
+func (m *SyncMap[K, V]) Store(key K, value V) {
+	m.m.Store(key, value)
+}
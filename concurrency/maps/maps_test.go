@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// maps - Tests
+// ============
+// Run with:
+//
+//   cd concurrency/maps
+//   go test -v *.go
+//   go test -race *.go
+//   go test -run '^$' -bench . -benchmem *.go
+//
+// The timings vary by machine, so the tests pin what does not: every
+// map behaves like a plain one, survives the race detector, and the
+// findings follow from the numbers they are given.
+
+// 1. The Maps
+// ===========
+
+func TestMapsAgree(t *testing.T) {
+	for _, impl := range Impls {
+		if err := Check(impl, 100, 10000); err != nil {
+			t.Errorf("%s: %v", impl.Name, err)
+		}
+	}
+}
+
+func TestEmptyLoad(t *testing.T) {
+	for _, impl := range Impls {
+		if v, ok := impl.New().Load(42); ok || v != 0 {
+			t.Errorf("%s: Load on an empty map = %d, %t", impl.Name, v, ok)
+		}
+	}
+}
+
+// Run under -race: every workload at once on every map
+func TestConcurrentUse(t *testing.T) {
+	const keys = 64
+	for _, impl := range Impls {
+		m := impl.New()
+		Fill(m, keys)
+		done := make(chan struct{})
+		for _, w := range Workloads {
+			go func() {
+				Run(m, w, keys, 4, 2000)
+				done <- struct{}{}
+			}()
+		}
+		for range Workloads {
+			<-done
+		}
+		for k := range keys {
+			if _, ok := m.Load(k); !ok {
+				t.Errorf("%s: key %d lost", impl.Name, k)
+			}
+		}
+	}
+}
+
+func TestRunSplitsOps(t *testing.T) {
+	m := &countingMap{}
+	Run(m, Workload{"all loads", 100}, 10, 3, 1000)
+	if m.loads != 1000 || m.stores != 0 {
+		t.Errorf("got %d loads, %d stores; want 1000, 0", m.loads, m.stores)
+	}
+}
+
+// countingMap counts calls, under the lock of the MutexMap it embeds
+type countingMap struct {
+	MutexMap[int, int]
+	loads, stores int
+}
+
+func (m *countingMap) Load(key int) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loads++
+	return 0, false
+}
+
+func (m *countingMap) Store(key, value int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stores++
+}
+
+func TestShardSize(t *testing.T) {
+	if got := unsafe.Sizeof(shard[int, int]{}); got != cacheLine {
+		t.Errorf("a shard is %d bytes, want %d: fix the padding", got, cacheLine)
+	}
+}
+
+func TestShardCount(t *testing.T) {
+	for n, want := range map[int]int{1: 1, 2: 2, 3: 4, 64: 64, 65: 128} {
+		if got := NewShardedMap[int, int](n).Shards(); got != want {
+			t.Errorf("NewShardedMap(%d) has %d shards, want %d", n, got, want)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("NewShardedMap(0) did not panic")
+		}
+	}()
+	NewShardedMap[int, int](0)
+}
+
+// The hash spreads sequential keys evenly: no shard gets twice its share
+func TestShardSpread(t *testing.T) {
+	m := NewShardedMap[int, int](16)
+	Fill(m, 16000)
+	for i := range m.shards {
+		if n := len(m.shards[i].m); n < 500 || n > 2000 {
+			t.Errorf("shard %d holds %d of 16000 keys", i, n)
+		}
+	}
+}
+
+// Only sync.Map allocates: it boxes keys and values
+func TestAllocations(t *testing.T) {
+	for _, impl := range Impls {
+		m := impl.New()
+		Fill(m, 1000)
+		got := testing.AllocsPerRun(10, func() {
+			m.Store(500, 1<<40)
+			m.Load(500)
+		})
+		if want := impl.Name == "sync.Map"; (got > 0) != want {
+			t.Errorf("%s: %.0f allocs per Store and Load", impl.Name, got)
+		}
+	}
+}
+
+func TestParseCounts(t *testing.T) {
+	got, err := parseCounts("1, 4,16")
+	if err != nil || !slices.Equal(got, []int{1, 4, 16}) {
+		t.Errorf("got %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "1,,2", "four", "0", "-2"} {
+		if _, err := parseCounts(bad); err == nil {
+			t.Errorf("parseCounts(%q) succeeded", bad)
+		}
+	}
+}
+
+// 2. The Findings
+// ===============
+
+// result fakes a benchmark that took ns per op with allocs allocations
+// per op
+func result(impl string, w Workload, g int, ns, allocs float64) Result {
+	return Result{impl, w, g, testing.BenchmarkResult{
+		N: 1000, T: time.Duration(ns * 1000), MemAllocs: uint64(allocs * 1000),
+	}}
+}
+
+func TestFindings(t *testing.T) {
+	reads, writes := Workload{"read-heavy", 90}, Workload{"write-heavy", 10}
+	results := []Result{
+		result("sync.Mutex", reads, 1, 20, 0), result("sync.Mutex", reads, 8, 80, 0),
+		result("sharded", reads, 1, 25, 0), result("sharded", reads, 8, 10, 0),
+		result("sync.Map", reads, 1, 30, 0.3), result("sync.Map", reads, 8, 5, 0.3),
+		result("sync.Mutex", writes, 1, 20, 0), result("sync.Mutex", writes, 8, 22, 0),
+		result("sharded", writes, 1, 25, 0), result("sharded", writes, 8, 21, 0),
+		result("sync.Map", writes, 1, 90, 2.7), result("sync.Map", writes, 8, 60, 2.7),
+	}
+	lines := Findings(results, 8)
+	out := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"read-heavy, 8 goroutines: sync.Map fastest at 5.0 ns/op; sharded 2.0x, sync.Mutex 16.0x",
+		"read-heavy: sync.Map is 16.0x faster than sync.Mutex",
+		"write-heavy: sync.Mutex is within 1.05x of sharded - not worth the change",
+		"write-heavy, ns/op at 8 goroutines over ns/op at 1 (below 1 is scaling, above 1 is contention): sync.Mutex 1.10x, sharded 0.84x, sync.Map 0.67x",
+		"sync.Map allocates per op: 0.30 read-heavy, 2.70 write-heavy - about 3 per Store, whatever the mix",
+		"sync.Mutex, sharded did not allocate",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "GOMAXPROCS=1") {
+		t.Errorf("GOMAXPROCS=1 caveat with 8 procs:\n%s", out)
+	}
+}
+
+func TestFindingsOneCore(t *testing.T) {
+	w := Workload{"mixed", 50}
+	results := []Result{
+		result("sync.Mutex", w, 1, 30, 0), result("sync.Mutex", w, 8, 90, 0),
+		result("sharded", w, 1, 20, 0), result("sharded", w, 8, 20, 0),
+	}
+	out := strings.Join(Findings(results, 1), "\n")
+	if !strings.HasPrefix(out, "GOMAXPROCS=1") {
+		t.Errorf("no caveat first:\n%s", out)
+	}
+	// One core cannot show contention, so no advice to switch and no
+	// scaling
+	if strings.Contains(out, "worth") || strings.Contains(out, "over ns/op") {
+		t.Errorf("advice from one core:\n%s", out)
+	}
+	if !strings.Contains(out, "mixed, 8 goroutines: sharded fastest at 20.0 ns/op; sync.Mutex 4.5x") {
+		t.Errorf("no ranking:\n%s", out)
+	}
+}
+
+func TestFindingsMutexWins(t *testing.T) {
+	w := Workload{"mixed", 50}
+	results := []Result{result("sync.Mutex", w, 4, 10, 0), result("sync.Map", w, 4, 40, 1.5)}
+	out := strings.Join(Findings(results, 4), "\n")
+	for _, want := range []string{
+		"mixed: nothing beat a plain sync.Mutex - keep it",
+		"sync.Map allocates per op: 1.50 mixed - about 3 per Store",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+// 3. Benchmarks
+// =============
+
+func BenchmarkMaps(b *testing.B) {
+	const keys = 10000
+	for _, w := range Workloads {
+		for _, impl := range Impls {
+			for _, g := range []int{1, 4} {
+				m := impl.New()
+				Fill(m, keys)
+				b.Run(fmt.Sprintf("%s/%s/goroutines=%d", w.Name, impl.Name, g), func(b *testing.B) {
+					b.ReportAllocs()
+					Run(m, w, keys, g, b.N)
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// The Workloads
+// =============
+// Every workload runs on a map already holding keys 0 to keys-1, with
+// keys picked uniformly at random, so every Load hits and every Store
+// overwrites. Only the mix of operations changes:
+//
+//	read-heavy    90% Loads: a cache, a config snapshot, a routing table
+//	mixed         50% Loads: a session store, counters read back often
+//	write-heavy   10% Loads: per-request bookkeeping, stats being written
+//
+// Each goroutine draws from its own PCG, so the random numbers are not
+// one more thing the goroutines contend for.
+
+// Workload is a mix of Loads and Stores
+type Workload struct {
+	Name  string
+	Loads int // percent of operations that are Loads
+}
+
+// Workloads are the tables the lesson prints
+var Workloads = []Workload{
+	{"read-heavy", 90},
+	{"mixed", 50},
+	{"write-heavy", 10},
+}
+
+// Impl is one of the maps, and how to make an empty one
+type Impl struct {
+	Name string
+	New  func() Map[int, int]
+}
+
+// shards is the ShardedMap size the lesson measures: several per core
+// on most machines, so two goroutines rarely want the same shard
+const shards = 64
+
+// Impls are the rows of every table
+var Impls = []Impl{
+	{"sync.Mutex", func() Map[int, int] { return NewMutexMap[int, int]() }},
+	{"sync.RWMutex", func() Map[int, int] { return NewRWMutexMap[int, int]() }},
+	{"sharded", func() Map[int, int] { return NewShardedMap[int, int](shards) }},
+	{"sync.Map", func() Map[int, int] { return NewSyncMap[int, int]() }},
+}
+
+// Fill stores keys 0 to keys-1, each as its own value
+func Fill(m Map[int, int], keys int) {
+	for k := range keys {
+		m.Store(k, k)
+	}
+}
+
+// Run performs ops operations of workload w on m, split between
+// goroutines goroutines, and returns when all of them have finished
+func Run(m Map[int, int], w Workload, keys, goroutines, ops int) {
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		n := ops / goroutines
+		if g < ops%goroutines {
+			n++
+		}
+		wg.Go(func() { work(m, w, keys, uint64(g), n) })
+	}
+	wg.Wait()
+}
+
+// work is one goroutine's share of Run. A single random number picks
+// both the key and the operation, and is stored as the value: too
+// large for the runtime's preallocated small ints, so boxing it
+// allocates, as it would for most real values
+func work(m Map[int, int], w Workload, keys int, seed uint64, n int) {
+	r := rand.NewPCG(seed, seed)
+	for range n {
+		x := r.Uint64()
+		key := int(x % uint64(keys))
+		if int(x>>40%100) < w.Loads {
+			m.Load(key)
+		} else {
+			m.Store(key, int(x>>1))
+		}
+	}
+}
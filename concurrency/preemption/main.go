@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Preemption: What a Tight Loop Does to Everyone Else
+// ===================================================
+// The scheduler runs one goroutine per P at a time. A goroutine that
+// never blocks keeps its P until the scheduler takes it away, and the
+// way it does that changed in Go 1.14:
+//
+//	cooperative   the scheduler sets a flag and the goroutine notices it
+//	              in a function prologue, where the stack bound is
+//	              checked. A loop with no calls never looks
+//	asynchronous  sysmon, a runtime thread with no P, finds goroutines
+//	              that have run for 10ms and sends their thread SIGURG.
+//	              The signal handler stops the goroutine at almost any
+//	              instruction (Go 1.14+)
+//
+// GODEBUG=asyncpreemptoff=1 turns the signal off and leaves only the
+// cooperative kind: the Go 1.13 scheduler, for debugging code that
+// misbehaves with signals. This lesson runs each spinner once in its
+// own process and once in a copy of itself started with that setting,
+// and measures what the rest of the program feels:
+//
+//	a sleeper   a goroutine that sleeps 1ms at a time on the same P,
+//	            recording how late each wakeup was
+//	the GC      runtime.GC on another P, which must stop the spinner
+//	            before it can start marking
+//
+// Run with:
+//
+//	cd concurrency/preemption
+//	go run main.go preempt.go
+//	go run main.go preempt.go -spin 500ms
+//	GODEBUG=asyncpreemptoff=1 go run main.go preempt.go -report   one process, as JSON
+//	go test -v *.go
+
+var (
+	spinFlag     = flag.Duration("spin", 200*time.Millisecond, "how long each spinner runs")
+	intervalFlag = flag.Duration("interval", time.Millisecond, "how long the sleeper sleeps each time")
+	reportFlag   = flag.Bool("report", false, "measure this process only and write the report as JSON")
+)
+
+// asyncOff is the setting the lesson runs its second copy with
+const asyncOff = "asyncpreemptoff=1"
+
+func main() {
+	flag.Parse()
+	if *reportFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(Run(*spinFlag, *intervalFlag)); err != nil {
+			fmt.Fprintln(os.Stderr, "preemption:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Println("=== Preemption: What a Tight Loop Does to Everyone Else ===")
+
+	// 1. The spinners
+	spinners()
+
+	// 2. Async preemption on
+	on := Run(*spinFlag, *intervalFlag)
+	latencyTable("\n2. A SLEEPER BESIDE EACH SPINNER (GOMAXPROCS=1, async preemption on):", on)
+
+	// 3. Async preemption off
+	off, err := runOff()
+	if err != nil {
+		fmt.Printf("\n3. WITH GODEBUG=%s:\n   (could not run the lesson again: %v)\n", asyncOff, err)
+	} else {
+		latencyTable("\n3. THE SAME WITH GODEBUG="+asyncOff+" (the lesson run again):", off)
+	}
+
+	// 4. Stop the world
+	gcTable(on, off, err == nil)
+
+	// 5. What the numbers say
+	if err == nil {
+		fmt.Println("\n5. WHAT THE NUMBERS SAY:")
+		for _, line := range Findings(on, off) {
+			fmt.Println("   - " + line)
+		}
+	}
+
+	// 6. What it means for Go programs
+	guidance()
+}
+
+// 1. The Spinners
+// ===============
+func spinners() {
+	fmt.Println("\n1. THE SPINNERS:")
+	fmt.Printf("   each runs the rounds spinTight needs for about %v, allocating nothing\n", *spinFlag)
+	for _, s := range Spinners {
+		fmt.Printf("   %-8s %s\n", s.Name, s.About)
+	}
+	fmt.Println("   The calls spinner runs longer for the same rounds: a call per round costs.")
+}
+
+// 2. and 3. Latency
+// =================
+func latencyTable(title string, r Report) {
+	fmt.Println(title)
+	fmt.Printf("   %-8s %7s %10s %10s %10s %10s\n", "spinner", "wakeups", "p50 late", "p99 late", "max late", "spun")
+	for _, l := range r.Latencies {
+		fmt.Printf("   %-8s %7d %10s %10s %10s %10s\n", l.Spinner, l.Wakeups,
+			round(l.P50), round(l.P99), round(l.Max), round(l.Spun))
+	}
+}
+
+// runOff runs this program again with async preemption off and decodes
+// its report. go run leaves the binary in place until it exits, so
+// os.Executable names something that can be started
+func runOff() (Report, error) {
+	var r Report
+	exe, err := os.Executable()
+	if err != nil {
+		return r, err
+	}
+	cmd := exec.Command(exe, "-report", "-spin", spinFlag.String(), "-interval", intervalFlag.String())
+	cmd.Env = append(os.Environ(), "GODEBUG="+asyncOff)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return r, err
+	}
+	return r, json.Unmarshal(out, &r)
+}
+
+// 4. Stop the World
+// =================
+func gcTable(on, off Report, haveOff bool) {
+	fmt.Println("\n4. runtime.GC WHILE A SPINNER RUNS ON ANOTHER P (GOMAXPROCS=2):")
+	fmt.Printf("   %-8s %12s %16s\n", "spinner", "async on", asyncOff)
+	for i, g := range on.GCWaits {
+		offGC := "-"
+		if haveOff {
+			offGC = round(off.GCWaits[i].GC)
+		}
+		fmt.Printf("   %-8s %12s %16s\n", g.Spinner, round(g.GC), offGC)
+	}
+	fmt.Println("   A collection starts by stopping every goroutine. One that cannot be")
+	fmt.Println("   stopped holds up the whole program, not only its own P.")
+}
+
+func round(d time.Duration) string {
+	switch {
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	case d >= time.Microsecond:
+		return d.Round(time.Microsecond).String()
+	}
+	return d.String()
+}
+
+// 5. What the Numbers Say
+// =======================
+
+// Stopped says how a spinner let the sleeper run: it yielded before
+// anyone had to stop it, the scheduler stopped it, or the sleeper
+// waited for most of the loop
+func Stopped(l Latency) string {
+	switch {
+	case l.Max >= l.Spun/2:
+		return "never"
+	case l.Max < 5*time.Millisecond:
+		return "yields"
+	}
+	return "preempted"
+}
+
+// Findings compares the same spinners with async preemption on and off
+func Findings(on, off Report) []string {
+	var lines []string
+	if strings.Contains(on.GODEBUG, asyncOff) {
+		lines = append(lines, "this process already ran with "+asyncOff+", so both columns have async preemption off")
+	}
+	for i, l := range on.Latencies {
+		o := off.Latencies[i]
+		switch Stopped(o) {
+		case "never":
+			lines = append(lines, fmt.Sprintf("%s: woken at most %s late with async preemption, %s without - the sleeper waited for the whole loop",
+				l.Spinner, round(l.Max), round(o.Max)))
+		case "preempted":
+			lines = append(lines, fmt.Sprintf("%s: at most %s late with, %s without - the call in the loop is where the scheduler stops it, as it did before Go 1.14",
+				l.Spinner, round(l.Max), round(o.Max)))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: at most %s late either way - it gives the P up before anyone has to take it",
+				l.Spinner, round(max(l.Max, o.Max))))
+		}
+	}
+	if Stopped(on.Latencies[0]) == "preempted" {
+		lines = append(lines, fmt.Sprintf("async preemption stops a loop after sysmon sees it run 10ms; sysmon looks every 10ms or so, so the sleeper waited %s at worst",
+			round(on.Latencies[0].Max)))
+	}
+	for i, g := range on.GCWaits {
+		o := off.GCWaits[i]
+		if o.GC >= o.Spun/2 {
+			lines = append(lines, fmt.Sprintf("%s: runtime.GC took %s with async preemption, %s without - every goroutine waited for one loop",
+				g.Spinner, round(g.GC), round(o.GC)))
+		}
+	}
+	return lines
+}
+
+// 6. What It Means for Go Programs
+// ================================
+func guidance() {
+	fmt.Println("\n6. WHAT IT MEANS FOR GO PROGRAMS:")
+	fmt.Println("   - Since Go 1.14 a busy loop cannot hang the program, but it still")
+	fmt.Println("     holds its P for up to 10-20ms at a time. Timers, network")
+	fmt.Println("     readiness and other goroutines on that P wait that long.")
+	fmt.Println("   - Latency-sensitive code shares the machine with batch work: give")
+	fmt.Println("     the batch work fewer goroutines than GOMAXPROCS, or a Gosched")
+	fmt.Println("     in long loops, rather than relying on preemption.")
+	fmt.Println("   - asyncpreemptoff=1 is for debugging: code under a profiler or a")
+	fmt.Println("     C library that handles signals badly. In production it brings")
+	fmt.Println("     back unbounded GC pauses behind any tight loop.")
+	fmt.Println("   - go tool trace shows each preemption; runtime/metrics reports")
+	fmt.Println("     /sched/latencies:seconds, the time goroutines wait for a P.")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Spinner keeps a goroutine busy for n rounds of arithmetic without
+// allocating. The three differ only in where the scheduler can stop
+// them
+type Spinner struct {
+	Name, About string
+	Spin        func(n int) uint64
+}
+
+var Spinners = []Spinner{
+	{"tight", "a loop with no function calls", spinTight},
+	{"calls", "the same loop, calling a function every round", spinCalls},
+	{"gosched", "the same loop, calling runtime.Gosched every 65536 rounds", spinGosched},
+}
+
+// sink keeps the spinners' results alive, so the compiler cannot drop
+// the loops
+var sink atomic.Uint64
+
+func xorshift(x uint64) uint64 {
+	x ^= x << 13
+	x ^= x >> 7
+	x ^= x << 17
+	return x
+}
+
+// spinTight has no call in its loop, so no safe point the goroutine
+// reaches on its own. Only a signal - async preemption - can stop it
+//
+//go:noinline
+func spinTight(n int) uint64 {
+	x := uint64(n) | 1
+	for range n {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	return x
+}
+
+// spinCalls calls step every round. step's prologue checks the stack
+// bound, which is how the scheduler asked goroutines to stop before Go
+// 1.14
+//
+//go:noinline
+func spinCalls(n int) uint64 {
+	x := uint64(n) | 1
+	for range n {
+		x = step(x)
+	}
+	return x
+}
+
+// step calls another function: a small leaf function gets no stack
+// check at all, and so would be no preemption point
+//
+//go:noinline
+func step(x uint64) uint64 {
+	return mix(x)
+}
+
+//go:noinline
+func mix(x uint64) uint64 {
+	return xorshift(x)
+}
+
+// spinGosched gives up the P itself, often enough that nothing waits
+// long for it
+//
+//go:noinline
+func spinGosched(n int) uint64 {
+	x := uint64(n) | 1
+	for i := range n {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+		if i&0xffff == 0 {
+			runtime.Gosched()
+		}
+	}
+	return x
+}
+
+// Calibrate returns the rounds spinTight needs to run for about d
+func Calibrate(d time.Duration) int {
+	const probe = 1 << 22
+	start := time.Now()
+	sink.Add(spinTight(probe))
+	per := float64(time.Since(start)) / probe
+	return max(1, int(float64(d)/per))
+}
+
+// Latency is how late a goroutine sleeping for a fixed interval woke
+// up, while a spinner ran on the only P
+type Latency struct {
+	Spinner string
+	Wakeups int
+	P50     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+	Spun    time.Duration // how long the spinner ran
+}
+
+// GCWait is how long runtime.GC took while a spinner ran on another P:
+// the collector has to stop every goroutine, the spinner included
+type GCWait struct {
+	Spinner string
+	GC      time.Duration
+	Spun    time.Duration
+}
+
+// Report is everything measured in one process
+type Report struct {
+	GODEBUG   string
+	Latencies []Latency
+	GCWaits   []GCWait
+}
+
+// Measure runs s for n rounds at GOMAXPROCS=1 while another goroutine
+// sleeps for interval again and again, and reports how late it woke.
+// The sleeper needs the P the spinner holds, so every wakeup waits
+// until the spinner is stopped or stops itself
+func Measure(s Spinner, n int, interval time.Duration) Latency {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	var late []time.Duration
+	var stop atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for !stop.Load() {
+			want := time.Now().Add(interval)
+			time.Sleep(interval)
+			late = append(late, time.Since(want))
+		}
+	}()
+	runtime.Gosched() // the sleeper is asleep before the spinner starts
+
+	start := time.Now()
+	sink.Add(s.Spin(n))
+	spun := time.Since(start)
+	stop.Store(true)
+	<-done
+	return summarize(s.Name, late, spun)
+}
+
+func summarize(name string, late []time.Duration, spun time.Duration) Latency {
+	l := Latency{Spinner: name, Wakeups: len(late), Spun: spun}
+	if len(late) == 0 {
+		return l
+	}
+	slices.Sort(late)
+	l.P50 = late[len(late)/2]
+	l.P99 = late[(len(late)*99)/100]
+	l.Max = late[len(late)-1]
+	return l
+}
+
+// MeasureGC runs s for n rounds at GOMAXPROCS=2 and times a
+// runtime.GC called from the other P while it spins
+func MeasureGC(s Spinner, n int) GCWait {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+
+	running := make(chan struct{})
+	spun := make(chan time.Duration)
+	go func() {
+		close(running)
+		start := time.Now()
+		sink.Add(s.Spin(n))
+		spun <- time.Since(start)
+	}()
+	<-running
+	time.Sleep(time.Millisecond) // the spinner has the other P to itself
+
+	start := time.Now()
+	runtime.GC()
+	gc := time.Since(start)
+	return GCWait{Spinner: s.Name, GC: gc, Spun: <-spun}
+}
+
+// Run measures every spinner for about spin each
+func Run(spin time.Duration, interval time.Duration) Report {
+	n := Calibrate(spin)
+	r := Report{GODEBUG: os.Getenv("GODEBUG")}
+	for _, s := range Spinners {
+		r.Latencies = append(r.Latencies, Measure(s, n, interval))
+	}
+	for _, s := range Spinners {
+		r.GCWaits = append(r.GCWaits, MeasureGC(s, n))
+	}
+	return r
+}
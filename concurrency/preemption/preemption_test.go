@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// preemption - Tests
+// ==================
+// Run with:
+//
+//   cd concurrency/preemption
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that neither run the go
+//                            command nor measure
+//
+// The measurements run for 50ms per spinner; the bounds they are held
+// to are loose, because the test shares the machine with everything
+// else.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+func requireAsync(t *testing.T) {
+	t.Helper()
+	if strings.Contains(os.Getenv("GODEBUG"), asyncOff) {
+		t.Skip("GODEBUG has " + asyncOff)
+	}
+}
+
+// measuring skips a test whose result depends on how busy the machine is
+func measuring(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("measures scheduling")
+	}
+}
+
+func spinner(t *testing.T, name string) Spinner {
+	t.Helper()
+	for _, s := range Spinners {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no spinner %q", name)
+	return Spinner{}
+}
+
+// 1. The Spinners
+// ===============
+
+// Every spinner does the same arithmetic, whatever it calls on the way
+func TestSpinnersAgree(t *testing.T) {
+	want := spinTight(100000)
+	for _, s := range Spinners {
+		if got := s.Spin(100000); got != want {
+			t.Errorf("%s: %#x, want %#x", s.Name, got, want)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	n := Calibrate(20 * time.Millisecond)
+	start := time.Now()
+	spinTight(n)
+	if took := time.Since(start); took < 5*time.Millisecond || took > 200*time.Millisecond {
+		t.Errorf("%d rounds took %v, want about 20ms", n, took)
+	}
+}
+
+// 2. The Measurements
+// ===================
+
+// With async preemption the sleeper gets a turn during the tight loop
+func TestTightLoopIsPreempted(t *testing.T) {
+	measuring(t)
+	requireAsync(t)
+	l := Measure(spinner(t, "tight"), Calibrate(100*time.Millisecond), time.Millisecond)
+	if l.Wakeups < 3 || Stopped(l) == "never" {
+		t.Errorf("%d wakeups, at most %v late in %v: the tight loop was not preempted", l.Wakeups, l.Max, l.Spun)
+	}
+}
+
+// A loaded machine can hold the sleeper back for most of the spin, so
+// only a few of the ~50 wakeups are required
+func TestGoschedYields(t *testing.T) {
+	measuring(t)
+	l := Measure(spinner(t, "gosched"), Calibrate(50*time.Millisecond), time.Millisecond)
+	if l.Wakeups < 3 {
+		t.Errorf("%d wakeups in %v, want one about every millisecond", l.Wakeups, l.Spun)
+	}
+}
+
+func TestMeasureGC(t *testing.T) {
+	measuring(t)
+	requireAsync(t)
+	g := MeasureGC(spinner(t, "tight"), Calibrate(100*time.Millisecond))
+	if g.GC >= g.Spun/2 {
+		t.Errorf("runtime.GC took %v of a %v spin", g.GC, g.Spun)
+	}
+}
+
+// Without async preemption nothing stops the tight loop: run the
+// lesson with GODEBUG set, as section 3 does
+func TestAsyncPreemptOff(t *testing.T) {
+	requireGo(t)
+	cmd := exec.CommandContext(t.Context(), "go", "run", "main.go", "preempt.go", "-report", "-spin", "50ms")
+	cmd.Env = append(os.Environ(), "GODEBUG="+asyncOff, "GOFLAGS=")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Report
+	if err := json.Unmarshal(out, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.GODEBUG != asyncOff {
+		t.Errorf("GODEBUG %q in the report", r.GODEBUG)
+	}
+	if l := r.Latencies[0]; Stopped(l) != "never" {
+		t.Errorf("tight: at most %v late in %v: the loop was stopped", l.Max, l.Spun)
+	}
+	if g := r.GCWaits[0]; g.GC < g.Spun/2 {
+		t.Errorf("tight: runtime.GC took %v of a %v spin", g.GC, g.Spun)
+	}
+}
+
+// 3. What the Lesson Prints
+// =========================
+
+func TestStopped(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		l    Latency
+		want string
+	}{
+		{Latency{Max: 190 * ms, Spun: 200 * ms}, "never"},
+		{Latency{Max: 19 * ms, Spun: 200 * ms}, "preempted"},
+		{Latency{Max: ms, Spun: 200 * ms}, "yields"},
+	}
+	for _, tt := range tests {
+		if got := Stopped(tt.l); got != tt.want {
+			t.Errorf("Stopped(max %v of %v) = %s, want %s", tt.l.Max, tt.l.Spun, got, tt.want)
+		}
+	}
+}
+
+func TestFindings(t *testing.T) {
+	ms := time.Millisecond
+	lat := func(name string, late time.Duration) Latency {
+		return Latency{Spinner: name, Max: late, Spun: 200 * ms}
+	}
+	on := Report{
+		Latencies: []Latency{lat("tight", 19*ms), lat("calls", 19*ms), lat("gosched", ms/2)},
+		GCWaits:   []GCWait{{"tight", 10 * ms, 200 * ms}, {"calls", 5 * ms, 200 * ms}, {"gosched", 5 * ms, 200 * ms}},
+	}
+	off := Report{
+		GODEBUG:   asyncOff,
+		Latencies: []Latency{lat("tight", 190*ms), lat("calls", 19*ms), lat("gosched", ms)},
+		GCWaits:   []GCWait{{"tight", 200 * ms, 200 * ms}, {"calls", 5 * ms, 200 * ms}, {"gosched", 5 * ms, 200 * ms}},
+	}
+	got := strings.Join(Findings(on, off), "\n")
+	for _, want := range []string{
+		"tight: woken at most 19ms late with async preemption, 190ms without",
+		"calls: at most 19ms late with, 19ms without - the call in the loop",
+		"gosched: at most 1ms late either way",
+		"tight: runtime.GC took 10ms with async preemption, 200ms without",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "calls: runtime.GC") {
+		t.Errorf("a GC finding for a loop that was stopped:\n%s", got)
+	}
+}
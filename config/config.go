@@ -0,0 +1,407 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// config - Layered Configuration From Struct Tags
+// ===============================================
+// A service reads its settings from four places, each overriding the
+// one before:
+//
+//	defaults   default:"..." tags - what the program ships with
+//	file       a JSON file - what the deployment checks in
+//	env        APP_HTTP_ADDR - what the platform injects
+//	flags      -http.addr - what the person at the terminal types
+//
+// One struct describes all of it. Tags name each field once, and the
+// name is derived for every layer:
+//
+//	type Config struct {
+//		HTTP struct {
+//			Addr    string        `config:"addr" default:":8080" validate:"required"`
+//			Timeout time.Duration `config:"timeout" default:"5s" validate:"min=1ms"`
+//		} `config:"http"`
+//		Password string `config:"password,secret" env:"DB_PASSWORD"`
+//	}
+//
+// gives the key "http.addr", the file path {"http": {"addr": ...}}, the
+// variable APP_HTTP_ADDR and the flag -http.addr. The env tag overrides
+// the variable's name, for names the platform fixes.
+//
+// Every layer hands the loader strings - flags and environment
+// variables are nothing else - so there is one conversion per type,
+// shared by all of them. Each value's origin is kept: a validation
+// error says "from env APP_HTTP_TIMEOUT", not just "invalid".
+//
+// The order has one wrinkle. The flag -config chooses the file, so the
+// flags are parsed first - they must be to find the file - but applied
+// last. Parsing only records them.
+
+// Loader loads configuration into a struct. The zero Loader reads no
+// file, the environment without a prefix, and no arguments.
+type Loader struct {
+	// Name is the program name in flag errors and usage
+	Name string
+
+	// EnvPrefix is prepended to derived variable names: "APP" gives
+	// APP_HTTP_ADDR. Names from env tags are used as written.
+	EnvPrefix string
+
+	// File is the configuration file read when neither -config nor
+	// <PREFIX>_CONFIG names one. A missing default file is not an
+	// error; a missing file someone asked for is.
+	File string
+
+	// Args are the command-line arguments, without the program name
+	Args []string
+
+	// LookupEnv and ReadFile default to os.LookupEnv and os.ReadFile;
+	// tests replace them
+	LookupEnv func(string) (string, bool)
+	ReadFile  func(string) ([]byte, error)
+
+	// Output receives flag errors and -h usage; the default is
+	// os.Stderr
+	Output io.Writer
+}
+
+// Source is the layer a value came from
+type Source string
+
+const (
+	FromDefault Source = "default"
+	FromFile    Source = "file"
+	FromEnv     Source = "env"
+	FromFlag    Source = "flag"
+)
+
+// Origin says where a value came from: the layer, and the file,
+// variable or flag within it
+type Origin struct {
+	Source Source
+	Name   string // "config.json", "APP_HTTP_ADDR", "-http.addr"
+}
+
+func (o Origin) String() string {
+	if o.Name == "" {
+		return string(o.Source)
+	}
+	return string(o.Source) + " " + o.Name
+}
+
+// Origins maps each key that was set to where its final value came
+// from. A key with no entry kept its zero value.
+type Origins map[string]Origin
+
+// FieldError reports a value that did not convert or did not validate
+type FieldError struct {
+	Key    string
+	Origin Origin // the zero Origin for a value that was never set
+	Value  string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	if e.Origin.Source == "" {
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %q from %s: %v", e.Key, e.Value, e.Origin, e.Err)
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// Validator is implemented by configurations with rules that span
+// fields - "cert and key are both set or both empty" - which tags
+// cannot express. Load calls Validate after the tag rules pass.
+type Validator interface {
+	Validate() error
+}
+
+// Load fills the struct dst points to from the four layers, then
+// validates it. All conversion and validation errors are returned
+// together, joined, so one run shows everything to fix. -h returns
+// flag.ErrHelp after printing the usage.
+func (l *Loader) Load(dst any) (Origins, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: Load needs a pointer to a struct, got %T", dst)
+	}
+	fields, err := bind(v.Elem().Type(), "", nil, l.EnvPrefix)
+	if err != nil {
+		return nil, err
+	}
+	ld := &load{l: l, root: v.Elem(), fields: fields, origins: Origins{}}
+
+	// Flags: parse now, apply last
+	flagged, file, err := ld.parseFlags()
+	if err != nil {
+		return nil, err
+	}
+
+	// Defaults
+	for _, f := range fields {
+		if f.hasDefault {
+			ld.set(f, f.def, Origin{Source: FromDefault})
+		}
+	}
+
+	// File
+	if file.path != "" {
+		if err := ld.applyFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	// Environment
+	for _, f := range fields {
+		if s, ok := l.lookupEnv(f.env); ok {
+			ld.set(f, s, Origin{FromEnv, f.env})
+		}
+	}
+
+	// Flags, in the order they were given
+	for _, fv := range flagged {
+		ld.set(fv.f, fv.value, Origin{FromFlag, "-" + fv.f.key})
+	}
+
+	if len(ld.errs) == 0 {
+		ld.validate()
+	}
+	if len(ld.errs) == 0 {
+		if val, ok := dst.(Validator); ok {
+			if err := val.Validate(); err != nil {
+				ld.errs = append(ld.errs, err)
+			}
+		}
+	}
+	return ld.origins, errors.Join(ld.errs...)
+}
+
+// load is the state of one Load call
+type load struct {
+	l       *Loader
+	root    reflect.Value
+	fields  []*field
+	origins Origins
+	errs    []error
+}
+
+// set converts s into f's field and records where it came from. A
+// value that does not convert is an error, and the field keeps its
+// previous value.
+func (ld *load) set(f *field, s string, o Origin) {
+	if err := f.setString(ld.root.FieldByIndex(f.index), s); err != nil {
+		if f.secret {
+			s = "******"
+		}
+		ld.errs = append(ld.errs, &FieldError{Key: f.key, Origin: o, Value: s, Err: err})
+		return
+	}
+	ld.origins[f.key] = o
+}
+
+// Flags
+// =====
+
+type flagValue struct {
+	f     *field
+	value string
+}
+
+type fileChoice struct {
+	path     string
+	explicit bool // named by -config or the environment
+}
+
+// parseFlags defines a flag per field plus -config, and records what
+// was given. Nothing is converted yet: a bad -http.timeout is reported
+// with the other errors after the layers are applied.
+func (ld *load) parseFlags() ([]flagValue, fileChoice, error) {
+	l := ld.l
+	fs := flag.NewFlagSet(l.Name, flag.ContinueOnError)
+	fs.SetOutput(l.output())
+
+	var given []flagValue
+	for _, f := range ld.fields {
+		usage := f.usage
+		if usage == "" {
+			usage = "set " + f.key
+		}
+		usage += " ($" + f.env + ")"
+		if f.hasDefault {
+			usage += fmt.Sprintf(" (default %q)", f.def)
+		}
+		fs.Var(&recorder{f: f, given: &given}, f.key, usage)
+	}
+	configEnv := envName(l.EnvPrefix, "config")
+	path := fs.String("config", "", "configuration `file` ($"+configEnv+")")
+
+	if err := fs.Parse(l.Args); err != nil {
+		return nil, fileChoice{}, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fileChoice{}, fmt.Errorf("config: unexpected argument %q", fs.Arg(0))
+	}
+
+	switch env, ok := l.lookupEnv(configEnv); {
+	case *path != "":
+		return given, fileChoice{*path, true}, nil
+	case ok && env != "":
+		return given, fileChoice{env, true}, nil
+	}
+	return given, fileChoice{l.File, false}, nil
+}
+
+// recorder is the flag.Value behind every field's flag: Set appends
+// the raw value, and IsBoolFlag lets "-debug" stand for "-debug=true"
+type recorder struct {
+	f     *field
+	given *[]flagValue
+}
+
+func (r *recorder) String() string { return "" }
+
+func (r *recorder) Set(s string) error {
+	*r.given = append(*r.given, flagValue{r.f, s})
+	return nil
+}
+
+func (r *recorder) IsBoolFlag() bool { return r.f.isBool }
+
+// The File
+// ========
+
+// applyFile reads a JSON object whose nesting follows the keys. A key
+// that matches no field is an error: a typo in a file would otherwise
+// be silently ignored, and the default used in its place.
+func (ld *load) applyFile(file fileChoice) error {
+	data, err := ld.l.readFile(file.path)
+	if errors.Is(err, fs.ErrNotExist) && !file.explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("config: %s: %w", file.path, err)
+	}
+	ld.applyObject(file.path, "", obj)
+	return nil
+}
+
+func (ld *load) applyObject(path, prefix string, obj map[string]json.RawMessage) {
+	origin := Origin{FromFile, path}
+	for _, name := range slices.Sorted(maps.Keys(obj)) {
+		key, raw := prefix+name, obj[name]
+		if f := ld.field(key); f != nil {
+			s, err := fileString(raw, f)
+			if err != nil {
+				ld.errs = append(ld.errs, &FieldError{Key: key, Origin: origin, Value: string(raw), Err: err})
+				continue
+			}
+			if s != nil {
+				ld.set(f, *s, origin)
+			}
+			continue
+		}
+		var nested map[string]json.RawMessage
+		if ld.isSection(key) && json.Unmarshal(raw, &nested) == nil {
+			ld.applyObject(path, key+".", nested)
+			continue
+		}
+		ld.errs = append(ld.errs, fmt.Errorf("%s: unknown key %q", path, key))
+	}
+}
+
+// fileString turns a JSON value into the string the other layers
+// would have supplied: strings unquoted, numbers and booleans as
+// written, arrays comma-joined for list fields. null leaves the field
+// alone and returns nil.
+func fileString(raw json.RawMessage, f *field) (*string, error) {
+	var s string
+	switch trimmed := strings.TrimSpace(string(raw)); {
+	case trimmed == "null":
+		return nil, nil
+	case strings.HasPrefix(trimmed, `"`):
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(trimmed, "["):
+		if !f.isList {
+			return nil, errors.New("a list for a single value")
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			p, err := fileString(item, &field{})
+			if err != nil || p == nil {
+				return nil, fmt.Errorf("list element %d: %s", i, item)
+			}
+			if strings.Contains(*p, ",") {
+				return nil, fmt.Errorf("list element %d contains a comma", i)
+			}
+			parts[i] = *p
+		}
+		s = strings.Join(parts, ",")
+	case strings.HasPrefix(trimmed, "{"):
+		return nil, errors.New("an object for a single value")
+	default:
+		s = trimmed
+	}
+	return &s, nil
+}
+
+func (ld *load) field(key string) *field {
+	for _, f := range ld.fields {
+		if f.key == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// isSection reports whether key names a nested struct
+func (ld *load) isSection(key string) bool {
+	for _, f := range ld.fields {
+		if strings.HasPrefix(f.key, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Loader) lookupEnv(name string) (string, bool) {
+	if l.LookupEnv != nil {
+		return l.LookupEnv(name)
+	}
+	return os.LookupEnv(name)
+}
+
+func (l *Loader) readFile(name string) ([]byte, error) {
+	if l.ReadFile != nil {
+		return l.ReadFile(name)
+	}
+	return os.ReadFile(name)
+}
+
+func (l *Loader) output() io.Writer {
+	if l.Output != nil {
+		return l.Output
+	}
+	return os.Stderr
+}
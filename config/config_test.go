@@ -0,0 +1,486 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Layered Configuration - Tests
+// =============================
+// Run with:
+//
+//   cd config
+//   go test -v *.go
+//
+// The environment and the filesystem are maps, so each test states
+// every layer it uses and nothing leaks in from the machine running it.
+
+type Config struct {
+	HTTP struct {
+		Addr    string        `config:"addr" default:":8080" validate:"required" usage:"listen address"`
+		Timeout time.Duration `config:"timeout" default:"5s" validate:"min=1ms,max=1m"`
+	} `config:"http"`
+	DB struct {
+		URL      string `config:"url" validate:"required"`
+		Password string `config:"password,secret" env:"DB_PASSWORD" validate:"min=8"`
+		MaxConns int    `config:"max-conns" default:"10" validate:"min=1,max=100"`
+	} `config:"db"`
+	TLS struct {
+		Cert string `config:"cert"`
+		Key  string `config:"key"`
+	} `config:"tls"`
+	LogLevel slog.Level `config:"log-level" default:"info"`
+	Mode     string     `config:"mode" default:"dev" validate:"oneof=dev|staging|prod"`
+	Debug    bool       `config:"debug"`
+	Origins  []string   `config:"origins"`
+	cache    string     // unexported: ignored
+}
+
+// Validate checks what the tags cannot: a rule across two fields
+func (c *Config) Validate() error {
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		return errors.New("tls.cert and tls.key must be set together")
+	}
+	return nil
+}
+
+// 1. Test Helpers
+// ===============
+
+// layers builds a Loader over an environment and files held in maps.
+// db.url is required, so every environment starts with it.
+func layers(env map[string]string, files map[string]string, args ...string) *Loader {
+	e := map[string]string{"APP_DB_URL": "postgres://localhost/app"}
+	for k, v := range env {
+		e[k] = v
+	}
+	return &Loader{
+		Name:      "app",
+		EnvPrefix: "APP",
+		File:      "config.json",
+		Args:      args,
+		LookupEnv: func(k string) (string, bool) { v, ok := e[k]; return v, ok },
+		ReadFile: func(name string) ([]byte, error) {
+			data, ok := files[name]
+			if !ok {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+			}
+			return []byte(data), nil
+		},
+		Output: &bytes.Buffer{},
+	}
+}
+
+func mustLoad(t *testing.T, l *Loader) (*Config, Origins) {
+	t.Helper()
+	var cfg Config
+	origins, err := l.Load(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cfg, origins
+}
+
+// 2. Defaults
+// ===========
+
+func TestDefaults(t *testing.T) {
+	cfg, origins := mustLoad(t, layers(nil, nil))
+
+	if cfg.HTTP.Addr != ":8080" || cfg.HTTP.Timeout != 5*time.Second || cfg.DB.MaxConns != 10 {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+	if cfg.LogLevel != slog.LevelInfo || cfg.Mode != "dev" {
+		t.Errorf("TextUnmarshaler or string default: %v %q", cfg.LogLevel, cfg.Mode)
+	}
+	if got := origins["http.addr"]; got != (Origin{Source: FromDefault}) {
+		t.Errorf("http.addr origin %v", got)
+	}
+	if got := origins["db.url"]; got != (Origin{FromEnv, "APP_DB_URL"}) {
+		t.Errorf("db.url origin %v", got)
+	}
+	if _, ok := origins["debug"]; ok {
+		t.Errorf("debug was never set but has origin %v", origins["debug"])
+	}
+}
+
+// 3. Precedence
+// =============
+
+func TestPrecedence(t *testing.T) {
+	file := map[string]string{"config.json": `{"http": {"addr": ":7000"}}`}
+	env := map[string]string{"APP_HTTP_ADDR": ":9000"}
+	flags := []string{"-http.addr", ":9999"}
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		env    map[string]string
+		args   []string
+		want   string
+		origin Origin
+	}{
+		{"default", nil, nil, nil, ":8080", Origin{Source: FromDefault}},
+		{"file over default", file, nil, nil, ":7000", Origin{FromFile, "config.json"}},
+		{"env over file", file, env, nil, ":9000", Origin{FromEnv, "APP_HTTP_ADDR"}},
+		{"flag over env", file, env, flags, ":9999", Origin{FromFlag, "-http.addr"}},
+		{"flag over default", nil, nil, flags, ":9999", Origin{FromFlag, "-http.addr"}},
+		{"last flag wins", nil, nil, append(flags, "-http.addr=:1"), ":1", Origin{FromFlag, "-http.addr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, origins := mustLoad(t, layers(tt.env, tt.files, tt.args...))
+			if cfg.HTTP.Addr != tt.want || origins["http.addr"] != tt.origin {
+				t.Errorf("got %q from %v, want %q from %v", cfg.HTTP.Addr, origins["http.addr"], tt.want, tt.origin)
+			}
+		})
+	}
+}
+
+// Layers override key by key, not wholesale: a file setting one key in
+// a section leaves the section's other defaults alone
+func TestPrecedencePerKey(t *testing.T) {
+	cfg, origins := mustLoad(t, layers(
+		map[string]string{"APP_DB_MAX_CONNS": "20"},
+		map[string]string{"config.json": `{"db": {"max-conns": 50}, "http": {"timeout": "2s"}}`},
+		"-debug"))
+
+	if cfg.DB.MaxConns != 20 || cfg.HTTP.Timeout != 2*time.Second || cfg.HTTP.Addr != ":8080" || !cfg.Debug {
+		t.Errorf("got %+v", cfg)
+	}
+	want := map[string]Source{"db.max-conns": FromEnv, "http.timeout": FromFile, "http.addr": FromDefault, "debug": FromFlag}
+	for key, src := range want {
+		if origins[key].Source != src {
+			t.Errorf("%s from %v, want %s", key, origins[key], src)
+		}
+	}
+}
+
+// 4. Choosing the File
+// ====================
+
+func TestFileChoice(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"mode": "dev"}`,
+		"prod.json":   `{"mode": "prod"}`,
+		"stage.json":  `{"mode": "staging"}`,
+	}
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"default file", nil, nil, "dev"},
+		{"env names it", map[string]string{"APP_CONFIG": "stage.json"}, nil, "staging"},
+		{"flag names it", nil, []string{"-config", "prod.json"}, "prod"},
+		{"flag beats env", map[string]string{"APP_CONFIG": "stage.json"}, []string{"-config=prod.json"}, "prod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _ := mustLoad(t, layers(tt.env, files, tt.args...))
+			if cfg.Mode != tt.want {
+				t.Errorf("mode %q, want %q", cfg.Mode, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileMissing(t *testing.T) {
+	// The default file is optional
+	mustLoad(t, layers(nil, nil))
+
+	// A file someone asked for is not
+	_, err := layers(nil, nil, "-config", "nope.json").Load(&Config{})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{`{"http": {"adr": ":1"}}`, `unknown key "http.adr"`},
+		{`{"prot": 1}`, `unknown key "prot"`},
+		{`{"http": ":1"}`, `unknown key "http"`},
+		{`{"mode": ["a"]}`, "a list for a single value"},
+		{`{"mode": {"a": 1}}`, "an object for a single value"},
+		{`{"origins": ["a,b"]}`, "contains a comma"},
+		{`{"db": {"max-conns": "many"}}`, `db.max-conns: "many" from file config.json: invalid syntax`},
+		{`{"mode": `, "unexpected end of JSON input"},
+		{`[]`, "cannot unmarshal array"},
+	}
+	for _, tt := range tests {
+		_, err := layers(nil, map[string]string{"config.json": tt.file}).Load(&Config{})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.file, err, tt.want)
+		}
+	}
+}
+
+func TestFileValues(t *testing.T) {
+	cfg, origins := mustLoad(t, layers(nil, map[string]string{"config.json": `{
+		"debug": true,
+		"origins": ["https://a.example", "https://b.example"],
+		"log-level": "debug",
+		"mode": null
+	}`}))
+	if !cfg.Debug || len(cfg.Origins) != 2 || cfg.LogLevel != slog.LevelDebug {
+		t.Errorf("got %+v", cfg)
+	}
+	// null leaves the default
+	if cfg.Mode != "dev" || origins["mode"].Source != FromDefault {
+		t.Errorf("mode %q from %v", cfg.Mode, origins["mode"])
+	}
+}
+
+// 5. Environment and Flags
+// ========================
+
+func TestEnvNames(t *testing.T) {
+	cfg, origins := mustLoad(t, layers(map[string]string{
+		"DB_PASSWORD":  "correct horse", // from the env tag, no prefix
+		"APP_ORIGINS":  "a, b ,c",
+		"APP_DEBUG":    "1",
+		"DB_MAX_CONNS": "99", // not a name this config reads
+	}, nil))
+	if cfg.DB.Password != "correct horse" || origins["db.password"].Name != "DB_PASSWORD" {
+		t.Errorf("password %q from %v", cfg.DB.Password, origins["db.password"])
+	}
+	if got := fmt.Sprint(cfg.Origins); got != "[a b c]" || !cfg.Debug || cfg.DB.MaxConns != 10 {
+		t.Errorf("origins %s, debug %t, max conns %d", got, cfg.Debug, cfg.DB.MaxConns)
+	}
+}
+
+// An empty variable is set - to the empty string. LookupEnv tells the
+// two apart; os.Getenv could not.
+func TestEnvEmpty(t *testing.T) {
+	cfg, origins := mustLoad(t, layers(map[string]string{"APP_ORIGINS": ""}, map[string]string{"config.json": `{"origins": ["x"]}`}))
+	if len(cfg.Origins) != 0 || origins["origins"].Source != FromEnv {
+		t.Errorf("origins %q from %v", cfg.Origins, origins["origins"])
+	}
+}
+
+func TestFlags(t *testing.T) {
+	cfg, _ := mustLoad(t, layers(nil, nil, "-debug", "-db.max-conns=3", "-log-level", "warn", "-origins", "x,y"))
+	if !cfg.Debug || cfg.DB.MaxConns != 3 || cfg.LogLevel != slog.LevelWarn || len(cfg.Origins) != 2 {
+		t.Errorf("got %+v", cfg)
+	}
+
+	for _, args := range [][]string{{"-nope"}, {"extra"}} {
+		if _, err := layers(nil, nil, args...).Load(&Config{}); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	l := layers(nil, nil, "-h")
+	_, err := l.Load(&Config{})
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("err = %v, want flag.ErrHelp", err)
+	}
+	usage := l.Output.(*bytes.Buffer).String()
+	for _, want := range []string{"-http.addr", "listen address ($APP_HTTP_ADDR) (default \":8080\")", "$DB_PASSWORD", "$APP_CONFIG"} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("usage does not contain %q:\n%s", want, usage)
+		}
+	}
+}
+
+// 6. Errors and Validation
+// ========================
+
+// Every bad value is reported, with where it came from, in one run
+func TestConversionErrors(t *testing.T) {
+	_, err := layers(
+		map[string]string{"APP_HTTP_TIMEOUT": "5 seconds", "DB_PASSWORD": "hunter2hunter2"},
+		map[string]string{"config.json": `{"debug": "maybe"}`},
+		"-db.max-conns=ten").Load(&Config{})
+
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("err = %v, want a *FieldError", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		`http.timeout: "5 seconds" from env APP_HTTP_TIMEOUT`,
+		`debug: "maybe" from file config.json: invalid syntax`,
+		`db.max-conns: "ten" from flag -db.max-conns: invalid syntax`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("missing %q in:\n%s", want, msg)
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"APP_DB_URL": ""}, `db.url: "" from env APP_DB_URL: is required`},
+		{map[string]string{"APP_HTTP_ADDR": ""}, `http.addr: "" from env APP_HTTP_ADDR: is required`},
+		{map[string]string{"APP_HTTP_TIMEOUT": "0s"}, "must be at least 1ms"},
+		{map[string]string{"APP_HTTP_TIMEOUT": "2m"}, "must be at most 1m0s"},
+		{map[string]string{"APP_DB_MAX_CONNS": "0"}, "db.max-conns: \"0\" from env APP_DB_MAX_CONNS: must be at least 1"},
+		{map[string]string{"APP_DB_MAX_CONNS": "101"}, "must be at most 100"},
+		{map[string]string{"APP_MODE": "test"}, "must be one of dev, staging, prod"},
+		{map[string]string{"DB_PASSWORD": "short"}, `db.password: "******" from env DB_PASSWORD: must have at least 8 characters`},
+		{map[string]string{"APP_TLS_CERT": "cert.pem"}, "tls.cert and tls.key must be set together"},
+	}
+	for _, tt := range tests {
+		_, err := layers(tt.env, nil).Load(&Config{})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.env, err, tt.want)
+		}
+		if err != nil && strings.Contains(err.Error(), "short") {
+			t.Errorf("secret in the error: %v", err)
+		}
+	}
+
+	// Never set at all: no origin to report
+	var c struct {
+		Name string `validate:"required,min=3"`
+	}
+	if _, err := layers(nil, nil).Load(&c); err == nil || err.Error() != "name: is required" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// Validation runs on the final values: a default the flags fix is not
+// an error
+func TestValidationAfterLayers(t *testing.T) {
+	type C struct {
+		Workers int `default:"0" validate:"min=1"`
+	}
+	var c C
+	l := layers(nil, nil, "-workers=4")
+	if _, err := l.Load(&c); err != nil || c.Workers != 4 {
+		t.Errorf("workers %d, err %v", c.Workers, err)
+	}
+}
+
+// Mistakes in the struct are the program's, and fail before any value
+// is read
+func TestBadStructs(t *testing.T) {
+	tests := []struct {
+		cfg  any
+		want string
+	}{
+		{&struct{ C chan int }{}, "unsupported type chan int"},
+		{&struct {
+			N int `validate:"positive"`
+		}{}, `unknown rule "positive"`},
+		{&struct {
+			B bool `validate:"min=1"`
+		}{}, "min does not apply to bool"},
+		{&struct {
+			D time.Duration `validate:"max=10"`
+		}{}, "missing unit in duration"},
+		{&struct {
+			A string `config:"x"`
+			B string `config:"x"`
+		}{}, `key "x" or variable APP_X used twice`},
+		{struct{}{}, "needs a pointer to a struct"},
+	}
+	for _, tt := range tests {
+		_, err := layers(nil, nil).Load(tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%T: err = %v, want %q", tt.cfg, err, tt.want)
+		}
+	}
+}
+
+func TestEmbeddedAndSkipped(t *testing.T) {
+	type Common struct {
+		Region string `default:"eu"`
+	}
+	type C struct {
+		Common
+		Token string `config:"-"`
+	}
+	var c C
+	_, err := layers(map[string]string{"APP_TOKEN": "x"}, nil, "-region=us").Load(&c)
+	if err != nil || c.Region != "us" || c.Token != "" {
+		t.Errorf("got %+v, %v", c, err)
+	}
+}
+
+// 7. Dump
+// =======
+
+func TestDump(t *testing.T) {
+	cfg, origins := mustLoad(t, layers(map[string]string{"DB_PASSWORD": "correct horse"}, nil, "-origins=a,b"))
+	var buf bytes.Buffer
+	if err := Dump(&buf, cfg, origins); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`http.addr    = ":8080"  (default)`,
+		`db.password  = ******  (env DB_PASSWORD)`,
+		`tls.cert     = ""  (unset)`,
+		`origins      = [a,b]  (flag -origins)`,
+		`log-level    = INFO  (default)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "horse") {
+		t.Errorf("secret printed:\n%s", out)
+	}
+}
+
+// 8. Benchmarks
+// =============
+
+func BenchmarkLoad(b *testing.B) {
+	l := layers(map[string]string{"APP_HTTP_ADDR": ":9000"},
+		map[string]string{"config.json": `{"db": {"max-conns": 50}}`}, "-debug")
+	for b.Loop() {
+		var cfg Config
+		if _, err := l.Load(&cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 9. Examples
+// ===========
+
+func ExampleLoader_Load() {
+	type Config struct {
+		Addr    string        `default:":8080" validate:"required"`
+		Timeout time.Duration `default:"5s"`
+		Workers int           `default:"4" validate:"min=1,max=64"`
+	}
+	env := map[string]string{"SVC_WORKERS": "16"}
+	l := &Loader{
+		EnvPrefix: "SVC",
+		Args:      []string{"-timeout", "30s"},
+		LookupEnv: func(k string) (string, bool) { v, ok := env[k]; return v, ok },
+	}
+	var cfg Config
+	origins, err := l.Load(&cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	Dump(os.Stdout, cfg, origins)
+	// Output:
+	// addr    = ":8080"  (default)
+	// timeout = 30s  (flag -timeout)
+	// workers = 16  (env SVC_WORKERS)
+}
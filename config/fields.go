@@ -0,0 +1,282 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Binding Fields
+// ==============
+// bind walks the struct type once and turns each field's tags into a
+// field: its key, its variable, its default, its rules and a function
+// that converts a string into it. A bad tag is the program's mistake,
+// not the user's, so it fails Load before any value is read.
+//
+// Tags read, all optional:
+//
+//	config:"name,secret"  the key segment (default: the field name in
+//	                      lower case); "-" skips the field; secret
+//	                      hides the value in errors and Dump
+//	env:"NAME"            the variable, instead of PREFIX_KEY
+//	default:"value"       the value before any layer
+//	validate:"rules"      see validate.go
+//	usage:"text"          the flag's help line
+
+type field struct {
+	key        string // "http.addr"
+	index      []int  // for reflect.Value.FieldByIndex
+	env        string
+	def        string
+	hasDefault bool
+	usage      string
+	secret     bool
+	isBool     bool // "-debug" means "-debug=true"
+	isList     bool // comma-separated
+	typ        reflect.Type
+	required   bool
+	rules      []rule
+	setString  func(v reflect.Value, s string) error
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// bind returns the fields of struct type t, depth first. Nested
+// structs add a key segment; embedded ones do not, as in encoding/json.
+func bind(t reflect.Type, prefix string, index []int, envPrefix string) ([]*field, error) {
+	var fields []*field
+	for sf := range t.Fields() {
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("config"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		idx := append(append([]int(nil), index...), sf.Index...)
+
+		if isSection(sf.Type) {
+			p := prefix + name + "."
+			if sf.Anonymous {
+				p = prefix
+			}
+			nested, err := bind(sf.Type, p, idx, envPrefix)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+
+		f := &field{
+			key:    prefix + name,
+			index:  idx,
+			usage:  sf.Tag.Get("usage"),
+			secret: opts == "secret",
+			isBool: sf.Type.Kind() == reflect.Bool,
+			isList: sf.Type.Kind() == reflect.Slice,
+			typ:    sf.Type,
+		}
+		f.def, f.hasDefault = sf.Tag.Lookup("default")
+		f.env = sf.Tag.Get("env")
+		if f.env == "" {
+			f.env = envName(envPrefix, f.key)
+		}
+		var err error
+		if f.setString, err = setter(sf.Type); err != nil {
+			return nil, fmt.Errorf("config: field %s: %w", sf.Name, err)
+		}
+		if f.rules, f.required, err = parseRules(sf.Type, sf.Tag.Get("validate")); err != nil {
+			return nil, fmt.Errorf("config: field %s: %w", sf.Name, err)
+		}
+		for _, other := range fields {
+			if other.key == f.key || other.env == f.env {
+				return nil, fmt.Errorf("config: field %s: key %q or variable %s used twice", sf.Name, f.key, f.env)
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// isSection reports whether a struct field holds more fields rather
+// than one value. time.Time is a struct, but it parses itself.
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// envName derives a variable from a key: "http.read-timeout" under
+// "APP" is APP_HTTP_READ_TIMEOUT
+func envName(prefix, key string) string {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+	if prefix == "" {
+		return name
+	}
+	return strings.ToUpper(prefix) + "_" + name
+}
+
+// Converting Strings
+// ==================
+// The same conversions as serialization/csvmap's setter, plus
+// durations and lists.
+
+// setter returns a function converting a string into a value of type
+// t. Nothing is stored when conversion fails.
+func setter(t reflect.Type) (func(reflect.Value, string) error, error) {
+	// slog.Level, netip.Addr, time.Time and custom types parse
+	// themselves
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return func(v reflect.Value, s string) error {
+			p := reflect.New(t)
+			if err := p.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+				return err
+			}
+			v.Set(p.Elem())
+			return nil
+		}, nil
+	}
+	if t == durationType {
+		return func(v reflect.Value, s string) error {
+			d, err := time.ParseDuration(s)
+			if err == nil {
+				v.SetInt(int64(d))
+			}
+			return err
+		}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return func(v reflect.Value, s string) error { v.SetString(s); return nil }, nil
+	case reflect.Bool:
+		return func(v reflect.Value, s string) error {
+			b, err := strconv.ParseBool(s)
+			if err == nil {
+				v.SetBool(b)
+			}
+			return unwrapNum(err)
+		}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value, s string) error {
+			n, err := strconv.ParseInt(s, 10, t.Bits())
+			if err == nil {
+				v.SetInt(n)
+			}
+			return unwrapNum(err)
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(v reflect.Value, s string) error {
+			n, err := strconv.ParseUint(s, 10, t.Bits())
+			if err == nil {
+				v.SetUint(n)
+			}
+			return unwrapNum(err)
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		return func(v reflect.Value, s string) error {
+			f, err := strconv.ParseFloat(s, t.Bits())
+			if err == nil {
+				v.SetFloat(f)
+			}
+			return unwrapNum(err)
+		}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Slice {
+			break
+		}
+		elem, err := setter(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		// A list replaces the one before it; layers do not append
+		return func(v reflect.Value, s string) error {
+			var parts []string
+			if strings.TrimSpace(s) != "" {
+				parts = strings.Split(s, ",")
+			}
+			list := reflect.MakeSlice(t, len(parts), len(parts))
+			for i, p := range parts {
+				if err := elem(list.Index(i), strings.TrimSpace(p)); err != nil {
+					return fmt.Errorf("element %d: %w", i, err)
+				}
+			}
+			v.Set(list)
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// unwrapNum drops strconv's `strconv.ParseInt: parsing "x":` prefix -
+// FieldError already shows the value
+func unwrapNum(err error) error {
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		return ne.Err
+	}
+	return err
+}
+
+// Printing
+// ========
+
+// Dump writes the effective configuration, one key per line with the
+// origin of its value. Secrets are masked. Printing this at startup,
+// or behind a -print-config flag, answers "why is it using that
+// value?" without a debugger.
+func Dump(w io.Writer, cfg any, origins Origins) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("config: Dump needs a struct, got %T", cfg)
+	}
+	fields, err := bind(v.Type(), "", nil, "")
+	if err != nil {
+		return err
+	}
+	width := 0
+	for _, f := range fields {
+		width = max(width, len(f.key))
+	}
+	for _, f := range fields {
+		value := display(f, v.FieldByIndex(f.index))
+		if v.FieldByIndex(f.index).Kind() == reflect.String && !f.secret {
+			value = strconv.Quote(value)
+		}
+		origin := "unset"
+		if o, ok := origins[f.key]; ok {
+			origin = o.String()
+		}
+		if _, err := fmt.Fprintf(w, "%-*s = %s  (%s)\n", width, f.key, value, origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// display formats a field's value for Dump and for errors
+func display(f *field, v reflect.Value) string {
+	switch {
+	case f.secret && !v.IsZero():
+		return "******"
+	case v.Kind() == reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
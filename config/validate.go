@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validation
+// ==========
+// The validate tag holds comma-separated rules, checked once every
+// layer has been applied - validating a default that the file then
+// replaces would report errors nobody can see in their config:
+//
+//	required        the value is not the zero value
+//	min=N, max=N    numbers by value, durations by length of time
+//	                ("min=1ms"), strings and lists by length
+//	oneof=a|b|c     the value, as printed, is one of these
+//
+// Rules other than required only check a value some layer set, so an
+// optional password can still say min=8: never set passes, "short"
+// does not. Zero is not the same as unset - "max-conns": 0 fails min=1.
+//
+// Rules are parsed when the struct is bound, so "max=ten" or "min" on
+// a bool fails Load straight away instead of passing every value.
+
+// rule checks a field's value. It returns a message, not an error with
+// context: the caller wraps it in a FieldError.
+type rule func(v reflect.Value) error
+
+// parseRules returns the rules in tag, and whether one is required
+func parseRules(t reflect.Type, tag string) (rules []rule, required bool, err error) {
+	if tag == "" {
+		return nil, false, nil
+	}
+	for _, spec := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(spec, "=")
+		var r rule
+		switch name {
+		case "required":
+			required = true
+			continue
+		case "min", "max":
+			r, err = boundRule(t, name, arg)
+		case "oneof":
+			allowed := strings.Split(arg, "|")
+			r = func(v reflect.Value) error {
+				if !slices.Contains(allowed, fmt.Sprint(v.Interface())) {
+					return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+				}
+				return nil
+			}
+		default:
+			err = fmt.Errorf("unknown rule %q", name)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("validate:%q: %w", tag, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, required, nil
+}
+
+// boundRule builds min or max for t. The bound is parsed as the kind
+// of value it limits: a duration for a duration, a count for a length.
+func boundRule(t reflect.Type, name, arg string) (rule, error) {
+	less := name == "min" // the failing side: value < bound for min
+	out := func(cmp int) bool { return (less && cmp < 0) || (!less && cmp > 0) }
+	word := map[bool]string{true: "at least", false: "at most"}[less]
+
+	switch {
+	case t == durationType:
+		bound, err := time.ParseDuration(arg)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) error {
+			if out(cmpNum(float64(v.Int()), float64(bound))) {
+				return fmt.Errorf("must be %s %v", word, bound)
+			}
+			return nil
+		}, nil
+	case t.Kind() == reflect.String || t.Kind() == reflect.Slice:
+		bound, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, err
+		}
+		unit := map[bool]string{true: "characters", false: "elements"}[t.Kind() == reflect.String]
+		return func(v reflect.Value) error {
+			if out(cmpNum(float64(v.Len()), float64(bound))) {
+				return fmt.Errorf("must have %s %d %s", word, bound, unit)
+			}
+			return nil
+		}, nil
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		bound, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) error {
+			if out(cmpNum(toFloat(v), bound)) {
+				return fmt.Errorf("must be %s %s", word, arg)
+			}
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("%s does not apply to %v", name, t)
+}
+
+func cmpNum(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// validate runs every field's rules. Each field reports its first
+// failing rule only: "is required" and "must have at least 8
+// characters" for the same empty password is noise.
+func (ld *load) validate() {
+	for _, f := range ld.fields {
+		v := ld.root.FieldByIndex(f.index)
+		origin, set := ld.origins[f.key]
+		if f.required && v.IsZero() {
+			ld.errs = append(ld.errs, &FieldError{Key: f.key, Origin: origin, Value: display(f, v), Err: errors.New("is required")})
+			continue
+		}
+		if !set {
+			continue
+		}
+		for _, r := range f.rules {
+			if err := r(v); err != nil {
+				ld.errs = append(ld.errs, &FieldError{Key: f.key, Origin: origin, Value: display(f, v), Err: err})
+				break
+			}
+		}
+	}
+}
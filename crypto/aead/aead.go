@@ -0,0 +1,163 @@
+package aead
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Authenticated Encryption with AES-GCM
+// =====================================
+// Encryption alone hides a message but does not protect it: flip a
+// bit of AES-CTR or AES-CBC ciphertext and the plaintext changes in a
+// predictable way, with nothing to say it happened. Authenticated
+// encryption (AEAD) adds a tag that Open checks before returning
+// anything, so a modified ciphertext fails to decrypt instead of
+// decrypting to something else. AES-GCM is the standard AEAD, with
+// hardware support on most current CPUs.
+//
+//	key      16 or 32 random bytes (AES-128 or AES-256), from crypto/rand
+//	         or derived with HKDF - never a password used directly
+//	nonce    12 bytes, and never the same twice under one key
+//	aad      "additional authenticated data": not encrypted, not stored
+//	         in the output, but the tag covers it. Put the context in
+//	         it - the record ID, the user - and a ciphertext copied to
+//	         another record will not open there
+//	output   ciphertext + 16-byte tag; the nonce is stored alongside,
+//	         usually in front
+//
+// The Nonce
+// =========
+// GCM with a repeated nonce fails badly. Two messages under the same
+// key and nonce have the same keystream, so XORing the ciphertexts
+// gives the XOR of the plaintexts; worse, the tags reveal enough to
+// forge new ones. The choices:
+//
+//	random 12 bytes   simple and stateless; safe for about 2^32
+//	                  messages per key, after which collisions become
+//	                  a real risk. Rotate keys well before that
+//	a counter         no collision risk, but the counter must never go
+//	                  back: not after a restart, not on two servers
+//	                  sharing the key. Easy to get wrong
+//
+// Since Go 1.24, cipher.NewGCMWithRandomNonce does the random-nonce
+// bookkeeping: Seal generates the nonce and prepends it, Open splits
+// it off. Box uses it. Seal and Open below do the same by hand, to show
+// the steps.
+//
+// Anti-patterns
+// =============
+//
+//	ECB mode, or CBC/CTR with no MAC     no integrity; ECB also shows
+//	                                     patterns in the plaintext
+//	a fixed or per-process nonce         see above
+//	the password as the key              derive with crypto/pbkdf2
+//	one key for everything               derive one per purpose (HKDF)
+//	using plaintext before Open returns  GCM's Open does not, which is
+//	                                     the point; streaming decryption
+//	                                     that does is a different design
+//	reporting why decryption failed      one opaque error for all causes
+
+// ErrDecrypt is returned for any ciphertext that does not open: wrong
+// key, wrong aad, truncated or modified. Saying which would help an
+// attacker, so there is one error.
+var ErrDecrypt = errors.New("aead: message authentication failed")
+
+// NewKey returns a random 32-byte AES-256 key
+func NewKey() []byte {
+	return randomBytes(32)
+}
+
+// randomBytes reads n bytes from crypto/rand, which since Go 1.24
+// never returns an error - it crashes the program instead
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+// DeriveKey derives a 32-byte key for one purpose from a master secret
+// with HKDF-SHA256. Different purposes give unrelated keys, so the key
+// that encrypts sessions never also encrypts backups.
+func DeriveKey(master []byte, purpose string) ([]byte, error) {
+	return hkdf.Key(sha256.New, master, nil, purpose, 32)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aead: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts plaintext with AES-GCM under key, binding aad, and
+// returns nonce || ciphertext || tag
+func Seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := randomBytes(gcm.NonceSize())
+	// Seal appends to its first argument: passing the nonce puts the
+	// ciphertext right after it in one allocation
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Open reverses Seal. It returns ErrDecrypt unless sealed was made by
+// Seal with the same key and aad and has not been altered.
+func Open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Box encrypts with one key using random nonces. Build it once: setting
+// up the cipher expands the key, which Seal and Open would otherwise
+// repeat on every call. A Box is safe for concurrent use.
+type Box struct {
+	gcm cipher.AEAD
+}
+
+// NewBox returns a Box for a 16- or 32-byte key
+func NewBox(key []byte) (*Box, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aead: %w", err)
+	}
+	gcm, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{gcm: gcm}, nil
+}
+
+// Seal encrypts plaintext, binding aad. The result is 28 bytes longer
+// than plaintext: a 12-byte nonce and a 16-byte tag.
+func (b *Box) Seal(plaintext, aad []byte) []byte {
+	// With NewGCMWithRandomNonce the nonce argument must be empty
+	return b.gcm.Seal(nil, nil, plaintext, aad)
+}
+
+// Open decrypts what Seal returned for the same aad
+func (b *Box) Open(sealed, aad []byte) ([]byte, error) {
+	plaintext, err := b.gcm.Open(nil, nil, sealed, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
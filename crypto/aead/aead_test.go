@@ -0,0 +1,277 @@
+package aead
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Authenticated Encryption with AES-GCM - Tests
+// =============================================
+// Run with:
+//
+//   cd crypto/aead
+//   go test -v *.go
+//
+// The tamper tests flip every byte of a sealed message in turn; each
+// flip must make Open fail. The nonce-reuse test shows what a repeated
+// nonce gives away without needing the key.
+
+// 1. Round Trips
+// ==============
+
+func TestSealOpen(t *testing.T) {
+	key := NewKey()
+	for _, msg := range []string{"", "a", "the quick brown fox jumps over the lazy dog"} {
+		sealed, err := Seal(key, []byte(msg), []byte("record:42"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(sealed) - len(msg); got != 12+16 {
+			t.Errorf("overhead = %d bytes, want 28 (nonce and tag)", got)
+		}
+		plain, err := Open(key, sealed, []byte("record:42"))
+		if err != nil || string(plain) != msg {
+			t.Errorf("Open = %q, %v; want %q", plain, err, msg)
+		}
+	}
+}
+
+func TestSealUsesAFreshNonce(t *testing.T) {
+	key := NewKey()
+	a, _ := Seal(key, []byte("same message"), nil)
+	b, _ := Seal(key, []byte("same message"), nil)
+	if bytes.Equal(a[:12], b[:12]) {
+		t.Fatal("two Seals used the same nonce")
+	}
+	if bytes.Equal(a, b) {
+		t.Error("equal plaintexts gave equal ciphertexts")
+	}
+}
+
+func TestKeySizes(t *testing.T) {
+	for _, n := range []int{16, 24, 32} {
+		if _, err := Seal(make([]byte, n), []byte("x"), nil); err != nil {
+			t.Errorf("%d-byte key: %v", n, err)
+		}
+	}
+	var sizeErr aes.KeySizeError
+	if _, err := Seal([]byte("password"), []byte("x"), nil); !errors.As(err, &sizeErr) {
+		t.Errorf("8-byte key: %v, want aes.KeySizeError", err)
+	}
+}
+
+// 2. Authentication
+// =================
+
+func TestEveryModificationIsDetected(t *testing.T) {
+	key := NewKey()
+	sealed, _ := Seal(key, []byte("transfer $100 to account 12345"), []byte("user:7"))
+
+	for i := range sealed {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 0x01
+		if _, err := Open(key, tampered, []byte("user:7")); !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("flipping a bit of byte %d: Open = %v, want ErrDecrypt", i, err)
+		}
+	}
+
+	tests := map[string]struct {
+		key, sealed, aad []byte
+	}{
+		"wrong key":        {NewKey(), sealed, []byte("user:7")},
+		"wrong aad":        {key, sealed, []byte("user:8")},
+		"missing aad":      {key, sealed, nil},
+		"truncated":        {key, sealed[:len(sealed)-1], []byte("user:7")},
+		"shorter than tag": {key, sealed[:20], []byte("user:7")},
+		"empty":            {key, nil, []byte("user:7")},
+	}
+	for name, tt := range tests {
+		if _, err := Open(tt.key, tt.sealed, tt.aad); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: Open = %v, want ErrDecrypt", name, err)
+		}
+	}
+}
+
+func TestAADStopsCiphertextSwapping(t *testing.T) {
+	// Two users' encrypted fields in one table. Copying Bob's value
+	// into Alice's row must not decrypt as Alice's.
+	key := NewKey()
+	alice, _ := Seal(key, []byte("alice@example.com"), []byte("users/1/email"))
+	bob, _ := Seal(key, []byte("bob@example.com"), []byte("users/2/email"))
+
+	if _, err := Open(key, bob, []byte("users/1/email")); !errors.Is(err, ErrDecrypt) {
+		t.Error("Bob's ciphertext opened in Alice's row")
+	}
+	if got, _ := Open(key, alice, []byte("users/1/email")); string(got) != "alice@example.com" {
+		t.Errorf("Alice's own value = %q", got)
+	}
+}
+
+// 3. Nonce Reuse
+// ==============
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, min(len(a), len(b)))
+	for i := range out {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+func TestNonceReuseLeaksPlaintext(t *testing.T) {
+	block, _ := aes.NewCipher(NewKey())
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, 12) // the bug: the same nonce every time
+
+	p1 := []byte("attack at dawn, bring snacks")
+	p2 := []byte("retreat at 9, forget snacks")
+	c1 := gcm.Seal(nil, nonce, p1, nil)
+	c2 := gcm.Seal(nil, nonce, p2, nil)
+	// Drop the 16-byte tags; the rest is plaintext XOR keystream
+	c1, c2 = c1[:len(c1)-16], c2[:len(c2)-16]
+
+	// Same key and nonce: same keystream. It cancels out...
+	if !bytes.Equal(xor(c1, c2), xor(p1, p2)) {
+		t.Fatal("c1 ^ c2 should equal p1 ^ p2")
+	}
+	// ...so anyone who knows or guesses one message reads the other,
+	// without the key
+	recovered := xor(xor(c1, c2), p1)
+	if !bytes.Equal(recovered, p2[:len(recovered)]) {
+		t.Fatalf("recovered %q", recovered)
+	}
+	t.Logf("recovered without the key: %q", recovered)
+}
+
+// 4. Box and NewGCMWithRandomNonce
+// ================================
+
+func TestBox(t *testing.T) {
+	key := NewKey()
+	box, err := NewBox(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := box.Seal([]byte("session data"), []byte("sid:abc"))
+	if len(sealed) != len("session data")+28 {
+		t.Errorf("len = %d, want plaintext + 28", len(sealed))
+	}
+	got, err := box.Open(sealed, []byte("sid:abc"))
+	if err != nil || string(got) != "session data" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	if _, err := box.Open(sealed, []byte("sid:xyz")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("wrong aad: %v", err)
+	}
+
+	// The layout is the same as Seal's - nonce, ciphertext, tag - so
+	// the two read each other's output
+	if got, err := Open(key, sealed, []byte("sid:abc")); err != nil || string(got) != "session data" {
+		t.Errorf("Open of a Box's output = %q, %v", got, err)
+	}
+	byHand, _ := Seal(key, []byte("by hand"), nil)
+	if got, err := box.Open(byHand, nil); err != nil || string(got) != "by hand" {
+		t.Errorf("Box.Open of Seal's output = %q, %v", got, err)
+	}
+
+	if _, err := NewBox([]byte("short")); err == nil {
+		t.Error("NewBox accepted a 5-byte key")
+	}
+}
+
+func TestBoxConcurrent(t *testing.T) {
+	box, _ := NewBox(NewKey())
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			msg := fmt.Appendf(nil, "message %d", i)
+			for range 100 {
+				got, err := box.Open(box.Seal(msg, nil), nil)
+				if err != nil || !bytes.Equal(got, msg) {
+					t.Errorf("round trip: %q, %v", got, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// 5. Key Derivation
+// =================
+
+func TestDeriveKey(t *testing.T) {
+	// RFC 5869 test case 3: no salt, no info. HKDF's output for 32
+	// bytes is the first 32 of the RFC's 42.
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	got, err := DeriveKey(ikm, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d"; hex.EncodeToString(got) != want {
+		t.Errorf("DeriveKey = %x, want %s", got, want)
+	}
+
+	master := NewKey()
+	sessions, _ := DeriveKey(master, "sessions v1")
+	backups, _ := DeriveKey(master, "backups v1")
+	again, _ := DeriveKey(master, "sessions v1")
+	if bytes.Equal(sessions, backups) {
+		t.Error("two purposes got the same key")
+	}
+	if !bytes.Equal(sessions, again) {
+		t.Error("DeriveKey is not deterministic")
+	}
+
+	sealed, _ := Seal(sessions, []byte("cookie"), nil)
+	if _, err := Open(backups, sealed, nil); !errors.Is(err, ErrDecrypt) {
+		t.Error("the backups key opened a session")
+	}
+}
+
+// 6. Benchmarks
+// =============
+
+func BenchmarkSeal(b *testing.B) {
+	key := NewKey()
+	msg := bytes.Repeat([]byte("x"), 1024)
+	b.Run("Seal", func(b *testing.B) {
+		b.SetBytes(int64(len(msg)))
+		for b.Loop() {
+			Seal(key, msg, nil)
+		}
+	})
+	b.Run("Box", func(b *testing.B) {
+		box, _ := NewBox(key)
+		b.SetBytes(int64(len(msg)))
+		for b.Loop() {
+			box.Seal(msg, nil)
+		}
+	})
+}
+
+// 7. Examples
+// ===========
+
+func ExampleBox() {
+	box, err := NewBox(NewKey())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	sealed := box.Seal([]byte("4111 1111 1111 1111"), []byte("customer:42"))
+
+	plain, err := box.Open(sealed, []byte("customer:42"))
+	fmt.Printf("%s %v\n", plain, err)
+	_, err = box.Open(sealed, []byte("customer:43"))
+	fmt.Println(err)
+	// Output:
+	// 4111 1111 1111 1111 <nil>
+	// aead: message authentication failed
+}
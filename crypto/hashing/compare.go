@@ -0,0 +1,54 @@
+package hashing
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// Constant-Time Comparison
+// ========================
+// == on strings and bytes.Equal stop at the first byte that differs.
+// Comparing a secret that way - an API key, a MAC, a reset token -
+// takes longer the more leading bytes of a guess are right. Over many
+// requests that difference is measurable, even across a network, and
+// it lets an attacker find the secret one byte at a time: 256 guesses
+// per byte instead of 256^n for the whole thing.
+//
+// crypto/subtle.ConstantTimeCompare looks at every byte whatever it
+// finds, so its time depends only on the length. hmac.Equal is the
+// same function under a name that says what it is for.
+//
+// Length still leaks: ConstantTimeCompare returns at once when the
+// lengths differ. For fixed-size values - a MAC, a SHA-256 digest -
+// that reveals nothing. For secrets of varying length, hash both sides
+// first: the digests have the same length, and comparing them in
+// constant time reveals neither the secret nor its length.
+//
+// LeakyEqual below counts the bytes it examines instead of timing
+// itself, so the tests can show the leak without a stopwatch.
+
+// LeakyEqual compares like bytes.Equal and reports how many bytes it
+// looked at. Do not use it for secrets: examined is what an attacker
+// measures.
+func LeakyEqual(a, b []byte) (equal bool, examined int) {
+	if len(a) != len(b) {
+		return false, 0
+	}
+	for i := range a {
+		examined++
+		if a[i] != b[i] {
+			return false, examined
+		}
+	}
+	return true, examined
+}
+
+// SecretEqual compares two secrets of any length. Its time does not
+// depend on how much of given matches want, and unequal lengths do not
+// return early. Hashing costs a little more per 64 bytes of input, so
+// the sizes can show in 64-byte steps; nothing else does.
+func SecretEqual(given, want string) bool {
+	g := sha256.Sum256([]byte(given))
+	w := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(g[:], w[:]) == 1
+}
@@ -0,0 +1,108 @@
+package hashing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+)
+
+// Hashing with SHA-256
+// ====================
+// A cryptographic hash maps any input to a fixed-size digest - 32 bytes
+// for SHA-256 - such that nobody can find two inputs with the same
+// digest, or an input for a given digest. That makes a digest a name
+// for content: a download's checksum, a cache key, a Git object ID, a
+// deduplication key.
+//
+// A hash.Hash is an io.Writer. Hash a stream by copying it in; the
+// input is never held in memory:
+//
+//	h := sha256.New()
+//	io.Copy(h, file)
+//	sum := h.Sum(nil)
+//
+// For a []byte already in memory, sha256.Sum256(b) returns a [32]byte
+// without allocating.
+//
+// Anti-patterns
+// =============
+//
+//	md5, sha1                    broken: collisions are practical. Use
+//	                             them only where a non-crypto checksum
+//	                             would do, and prefer crc32 or fnv then
+//	sha256(secret + message)     not a MAC: SHA-256 allows length
+//	                             extension - see hmac.go
+//	sha256(password)             not password storage: far too fast to
+//	                             guess - see ../passwords
+//	"hashing" to hide data       a digest of a low-entropy value - a phone
+//	                             number, a birthday - is reversed by
+//	                             hashing every candidate
+//	hash/fnv, hash/maphash       fast and fine for hash tables, and no
+//	                             protection at all against an attacker
+
+// Sum returns the hex SHA-256 digest of b
+func Sum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// SumReader returns the hex SHA-256 digest of everything r yields and
+// the number of bytes read
+func SumReader(r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", n, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// SumFile returns the hex SHA-256 digest of the file at path
+func SumFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sum, _, err := SumReader(f)
+	return sum, err
+}
+
+// VerifyingReader passes reads through while hashing them, and checks
+// the digest at EOF. A download can be verified as it is written to
+// disk instead of read back afterwards.
+type VerifyingReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want string
+}
+
+// MismatchError is returned at EOF when the content does not match
+// the expected digest. The digests are public, so comparing them with
+// != leaks nothing; comparing a MAC is different - see compare.go.
+type MismatchError struct {
+	Want, Got string
+}
+
+func (e *MismatchError) Error() string {
+	return "hashing: sha256 mismatch: want " + e.Want + ", got " + e.Got
+}
+
+// NewVerifyingReader returns a reader that fails with
+// *MismatchError at EOF unless r's content hashes to want (hex)
+func NewVerifyingReader(r io.Reader, want string) *VerifyingReader {
+	h := sha256.New()
+	return &VerifyingReader{r: io.TeeReader(r, h), h: h, want: want}
+}
+
+func (v *VerifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	if err == io.EOF {
+		if got := hex.EncodeToString(v.h.Sum(nil)); got != v.want {
+			return n, &MismatchError{Want: v.want, Got: got}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,276 @@
+package hashing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// Hashing, HMAC and Constant-Time Comparison - Tests
+// ==================================================
+// Run with:
+//
+//   cd crypto/hashing
+//   go test -v *.go
+//
+// Known-answer vectors come from FIPS 180-2 and RFC 4231. The length
+// extension test forges a tag for sha256(key + message) without the
+// key - the reason MACs are built with HMAC.
+
+// 1. SHA-256
+// ==========
+
+func TestSumKnownAnswers(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+	for _, tt := range tests {
+		if got := Sum([]byte(tt.in)); got != tt.want {
+			t.Errorf("Sum(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSumReaderMatchesSum(t *testing.T) {
+	data := bytes.Repeat([]byte("streamed, never held in memory. "), 10_000)
+	// OneByteReader makes io.Copy write the hash one byte at a time:
+	// the digest does not depend on how the input is chunked
+	got, n, err := SumReader(iotest.OneByteReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || got != Sum(data) {
+		t.Errorf("SumReader = %s (%d bytes), want %s (%d)", got, n, Sum(data), len(data))
+	}
+}
+
+func TestSumFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release.tar.gz")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := SumFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != Sum([]byte("abc")) {
+		t.Errorf("SumFile = %s", got)
+	}
+	if _, err := SumFile(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: %v", err)
+	}
+}
+
+func TestVerifyingReader(t *testing.T) {
+	data := []byte("the release archive")
+	good := Sum(data)
+
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, NewVerifyingReader(bytes.NewReader(data), good)); err != nil {
+		t.Fatalf("matching content: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("content changed in transit")
+	}
+
+	tampered := append(bytes.Clone(data), '!')
+	_, err := io.Copy(io.Discard, NewVerifyingReader(bytes.NewReader(tampered), good))
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) || mismatch.Want != good {
+		t.Fatalf("tampered content: %v, want *MismatchError", err)
+	}
+}
+
+// 2. HMAC
+// =======
+
+func TestSignKnownAnswer(t *testing.T) {
+	// RFC 4231, test case 2
+	got := hex.EncodeToString(Sign([]byte("Jefe"), []byte("what do ya want for nothing?")))
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := rand.Text() // 26 random base32 characters: 130 bits
+	msg := []byte(`{"event":"payment.succeeded","amount":4200}`)
+	tag := Sign([]byte(key), msg)
+
+	if !Verify([]byte(key), msg, tag) {
+		t.Fatal("valid tag rejected")
+	}
+	tests := map[string]struct {
+		key, msg, tag []byte
+	}{
+		"changed message": {[]byte(key), []byte(`{"event":"payment.succeeded","amount":9999}`), tag},
+		"wrong key":       {[]byte("guess"), msg, tag},
+		"truncated tag":   {[]byte(key), msg, tag[:16]},
+		"empty tag":       {[]byte(key), msg, nil},
+	}
+	for name, tt := range tests {
+		if Verify(tt.key, tt.msg, tt.tag) {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// sha256Extend continues a SHA-256 computation from a published digest.
+// SHA-256's output is its whole internal state, so anyone can load it
+// back - here through the hash's own MarshalBinary format - and keep
+// writing. That is length extension.
+func sha256Extend(digest []byte, hashedLen int, extra []byte) (glue, forged []byte) {
+	// The padding SHA-256 appended to the original input: 0x80, zeros
+	// to 56 mod 64, then the length in bits
+	glue = append(glue, 0x80)
+	for (hashedLen+len(glue))%64 != 56 {
+		glue = append(glue, 0)
+	}
+	glue = binary.BigEndian.AppendUint64(glue, uint64(hashedLen)*8)
+
+	// "sha\x03", the eight state words, an empty block buffer, and the
+	// number of bytes hashed so far
+	state := append([]byte("sha\x03"), digest...)
+	state = append(state, make([]byte, 64)...)
+	state = binary.BigEndian.AppendUint64(state, uint64(hashedLen+len(glue)))
+
+	h := sha256.New()
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		panic(err)
+	}
+	h.Write(extra)
+	return glue, h.Sum(nil)
+}
+
+func TestLengthExtensionBreaksNaiveMAC(t *testing.T) {
+	key := []byte("server-secret-key") // the attacker knows only its length
+	msg := []byte("user=alice&role=viewer")
+	naiveMAC := func(m []byte) []byte {
+		sum := sha256.Sum256(append(bytes.Clone(key), m...))
+		return sum[:]
+	}
+	tag := naiveMAC(msg)
+
+	// The attacker appends "&role=admin" - later keys win in most query
+	// parsers - and computes a matching tag from tag alone
+	glue, forged := sha256Extend(tag, len(key)+len(msg), []byte("&role=admin"))
+	forgedMsg := append(append(bytes.Clone(msg), glue...), "&role=admin"...)
+
+	if !bytes.Equal(naiveMAC(forgedMsg), forged) {
+		t.Fatal("the forgery should verify under sha256(key + message)")
+	}
+	// The same trick against HMAC produces garbage
+	hmacTag := Sign(key, msg)
+	_, forgedHMAC := sha256Extend(hmacTag, len(key)+len(msg), []byte("&role=admin"))
+	if Verify(key, forgedMsg, forgedHMAC) {
+		t.Fatal("length extension forged an HMAC")
+	}
+}
+
+// 3. Signed Tokens
+// ================
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	s := &Signer{Key: []byte("0123456789abcdef0123456789abcdef"), Now: func() time.Time { return now }}
+
+	token := s.Issue([]byte(`{"user":42}`), time.Hour)
+	payload, err := s.Open(token)
+	if err != nil || string(payload) != `{"user":42}` {
+		t.Fatalf("Open = %q, %v", payload, err)
+	}
+
+	parts := strings.Split(token, ".")
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"payload edited", b64.EncodeToString([]byte(`{"user":1}`)) + "." + parts[1] + "." + parts[2], ErrBadSignature},
+		{"expiry edited", parts[0] + "." + b64.EncodeToString([]byte("9999999999")) + "." + parts[2], ErrBadSignature},
+		{"tag dropped", parts[0] + "." + parts[1], ErrBadSignature},
+		{"tag not base64", parts[0] + "." + parts[1] + ".!!", ErrMalformed},
+		{"no dots", "garbage", ErrMalformed},
+	}
+	for _, tt := range tests {
+		if _, err := s.Open(tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Open = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := s.Open(token); !errors.Is(err, ErrExpired) {
+		t.Errorf("after the TTL: %v, want ErrExpired", err)
+	}
+
+	other := &Signer{Key: []byte("another key, another purpose....")}
+	if _, err := other.Open(token); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("a token from another key: %v, want ErrBadSignature", err)
+	}
+}
+
+// 4. Constant-Time Comparison
+// ===========================
+
+func TestLeakyEqualLeaksThePrefix(t *testing.T) {
+	secret := []byte("sk_live_7f3a9c2e")
+	// Each guess that gets one more leading byte right takes one more
+	// step. A timing attack recovers the secret byte by byte.
+	var prev int
+	for n := 0; n < len(secret); n++ {
+		guess := bytes.Clone(secret)
+		guess[n] ^= 0xff // right up to n, wrong at n
+		_, examined := LeakyEqual(guess, secret)
+		if examined != n+1 {
+			t.Errorf("guess right for %d bytes: examined %d, want %d", n, examined, n+1)
+		}
+		if examined <= prev {
+			t.Error("examined did not grow with the correct prefix")
+		}
+		prev = examined
+	}
+}
+
+func TestSecretEqual(t *testing.T) {
+	tests := []struct {
+		given, want string
+		equal       bool
+	}{
+		{"sk_live_7f3a9c2e", "sk_live_7f3a9c2e", true},
+		{"sk_live_7f3a9c2f", "sk_live_7f3a9c2e", false},
+		{"sk_live", "sk_live_7f3a9c2e", false}, // a prefix is not a match
+		{"", "sk_live_7f3a9c2e", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		if got := SecretEqual(tt.given, tt.want); got != tt.equal {
+			t.Errorf("SecretEqual(%q, %q) = %v", tt.given, tt.want, got)
+		}
+	}
+}
+
+// 5. Examples
+// ===========
+
+func ExampleSign() {
+	key := []byte("webhook-signing-key")
+	body := []byte(`{"event":"ping"}`)
+	fmt.Printf("X-Signature: sha256=%x\n", Sign(key, body))
+	// Output: X-Signature: sha256=5560f0675e713b4ca1a395334e6e39fcb32522dad3e3502dbee7fd9673c32d33
+}
@@ -0,0 +1,125 @@
+package hashing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message Authentication with HMAC
+// ================================
+// A hash proves nothing about who made a message: anyone can hash
+// anything. A MAC mixes in a secret key, so only holders of the key
+// can produce a valid tag, and a tag that checks out means the message
+// is exactly what a key holder sent. Webhook signatures, signed cookies
+// and signed URLs are all MACs.
+//
+// Why not sha256(key + message)? SHA-256 processes input in blocks and
+// its output is its internal state. Given sha256(key + message) and the
+// message's length, an attacker can continue hashing from that state
+// and compute sha256(key + message + padding + anything) - a valid tag
+// for a message they extended, without knowing the key. HMAC hashes
+// twice with the key around the inner hash, which closes that door.
+// (SHA-3 and BLAKE2 are not vulnerable to length extension, but HMAC is
+// the standard and works with any hash.)
+//
+// The key: 32 random bytes from crypto/rand, kept secret, and one key
+// per purpose - the key that signs cookies should not sign password
+// reset links. crypto/hkdf derives several keys from one secret.
+//
+// Verifying: recompute the tag and compare with hmac.Equal, which takes
+// the same time however many bytes match. bytes.Equal or == returns at
+// the first difference, and the time that takes tells an attacker how
+// many leading bytes they guessed right - see compare.go.
+
+// Sign returns the HMAC-SHA256 tag of msg under key
+func Sign(key, msg []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write(msg)
+	return m.Sum(nil)
+}
+
+// Verify reports whether tag is msg's HMAC-SHA256 under key
+func Verify(key, msg, tag []byte) bool {
+	return hmac.Equal(Sign(key, msg), tag)
+}
+
+// Signed Tokens
+// =============
+// A token is "payload.expiry.tag", each part base64url without padding.
+// The tag covers the payload and the expiry together, so neither can be
+// changed alone. The expiry is inside the signed data for the same
+// reason a web framework puts it in a signed cookie: an expiry outside
+// it could be edited.
+//
+// The payload is signed, not encrypted: anyone can read it. For secret
+// contents, encrypt with AES-GCM (../aead), which authenticates too.
+
+// Token errors. Verification reports which check failed to the caller;
+// an HTTP handler should still answer every one with the same 401.
+var (
+	ErrMalformed    = errors.New("hashing: malformed token")
+	ErrBadSignature = errors.New("hashing: bad token signature")
+	ErrExpired      = errors.New("hashing: token expired")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Signer issues and checks signed tokens
+type Signer struct {
+	Key []byte
+	Now func() time.Time // time.Now if nil
+}
+
+func (s *Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Issue returns a token carrying payload that expires after ttl
+func (s *Signer) Issue(payload []byte, ttl time.Duration) string {
+	exp := strconv.FormatInt(s.now().Add(ttl).Unix(), 10)
+	signed := b64.EncodeToString(payload) + "." + b64.EncodeToString([]byte(exp))
+	return signed + "." + b64.EncodeToString(Sign(s.Key, []byte(signed)))
+}
+
+// Open checks a token and returns its payload. The signature is
+// checked before anything in the token is parsed or trusted.
+func (s *Signer) Open(token string) ([]byte, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return nil, ErrMalformed
+	}
+	signed, tagText := token[:i], token[i+1:]
+	tag, err := b64.DecodeString(tagText)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if !Verify(s.Key, []byte(signed), tag) {
+		return nil, ErrBadSignature
+	}
+
+	payloadText, expText, ok := strings.Cut(signed, ".")
+	if !ok {
+		return nil, ErrMalformed
+	}
+	payload, err1 := b64.DecodeString(payloadText)
+	expBytes, err2 := b64.DecodeString(expText)
+	if err1 != nil || err2 != nil {
+		return nil, ErrMalformed
+	}
+	exp, err := strconv.ParseInt(string(expBytes), 10, 64)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if !s.now().Before(time.Unix(exp, 0)) {
+		return nil, ErrExpired
+	}
+	return payload, nil
+}
@@ -0,0 +1,44 @@
+package ids
+
+import "math"
+
+// How Unique Is Random?
+// =====================
+// The birthday bound: among n values drawn from 2^b, the chance that
+// some two are equal is about
+//
+//	p = 1 - e^(-n² / 2^(b+1))
+//
+// It grows with n², not n, so collisions arrive far sooner than 2^b
+// suggests - around the square root of the space:
+//
+//	random bits          1-in-a-billion chance at   50% at
+//	32                   3 IDs                      77,000
+//	48                   750                        20 million
+//	64                   190,000                    5 billion
+//	80 (ULID, per ms)    49 million                 1.3 trillion
+//	122 (UUIDv4)         100 trillion               2.7 quintillion
+//
+// For a ULID only IDs made in the same millisecond can collide, so n
+// is IDs per millisecond across all generators: 49 million in one
+// millisecond for a one-in-a-billion chance. A single Generator never
+// collides with itself, since it increments.
+//
+// The bound assumes the bits are random. Real collisions come from
+// generators that are not: math/rand seeded with the time on machines
+// that boot together, a VM snapshot restored twice with the same
+// generator state, or IDs truncated to fit a column.
+
+// CollisionProbability returns the chance that n IDs with the given
+// number of random bits are not all distinct
+func CollisionProbability(n float64, bits int) float64 {
+	// -Expm1(-x) is 1 - e^-x without cancellation for tiny x, where
+	// 1 - math.Exp(-x) rounds to 0
+	return -math.Expm1(-n * n / math.Ldexp(1, bits+1))
+}
+
+// IDsFor returns how many IDs with the given number of random bits can
+// be made before the chance of a collision reaches p
+func IDsFor(p float64, bits int) float64 {
+	return math.Sqrt(-math.Ldexp(1, bits+1) * math.Log1p(-p))
+}
@@ -0,0 +1,210 @@
+package ids
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ULIDs
+// =====
+// 128 bits, big-endian:
+//
+//	bits 0-47     Unix time in milliseconds - good until the year 10889
+//	bits 48-127   random
+//
+// Because the timestamp comes first, byte order is time order, and the
+// text encoding is chosen to keep it: Crockford base32 uses the digits
+// then the letters in ASCII order, so sorting the strings sorts the IDs.
+// The alphabet leaves out I, L, O and U - read aloud or typed by hand,
+// they get confused with 1, 0 and V - and decoding accepts them as the
+// digits they look like.
+//
+// Monotonicity
+// ============
+// Two IDs made in the same millisecond have random tails, so their
+// order is random too. A Generator fixes that: within one millisecond it
+// increments the previous random part by one instead of drawing a new
+// one, so IDs from one Generator strictly increase. If the clock steps
+// back - NTP correcting it, a VM resuming - it keeps the last timestamp
+// rather than go back with it.
+//
+// The increment makes consecutive IDs guessable from each other within
+// a millisecond. That is the price of the ordering; use NewV4 for
+// tokens that must not be guessed.
+//
+// Order holds per Generator. Across machines, IDs made in the same
+// millisecond sort randomly, and clocks disagree by more than that:
+// a ULID orders events roughly, not causally.
+
+// ULID is a 48-bit millisecond timestamp followed by 80 random bits
+type ULID [16]byte
+
+// ErrOverflow is returned by Generator.New when 2^80 IDs have already
+// been made in one millisecond - in practice, only when the entropy
+// source is broken
+var ErrOverflow = errors.New("ids: ULID random part overflowed within one millisecond")
+
+// crockford is Crockford's base32 alphabet, in ASCII order
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// decodeCrockford maps a byte to its 5-bit value, or 0xff. Lower case
+// is accepted, and I, L and O read as 1, 1 and 0.
+var decodeCrockford = func() (t [256]byte) {
+	for i := range t {
+		t[i] = 0xff
+	}
+	for i := range len(crockford) {
+		t[crockford[i]] = byte(i)
+		t[crockford[i]|0x20] = byte(i) // lower case; digits are unchanged
+	}
+	t['I'], t['i'], t['L'], t['l'] = 1, 1, 1, 1
+	t['O'], t['o'] = 0, 0
+	return t
+}()
+
+// Time returns the timestamp, to the millisecond
+func (id ULID) Time() time.Time {
+	return time.UnixMilli(int64(id.ms()))
+}
+
+func (id ULID) ms() uint64 {
+	return uint64(id[0])<<40 | uint64(id[1])<<32 | uint64(binary.BigEndian.Uint32(id[2:6]))
+}
+
+// Compare returns -1, 0 or +1; it agrees with comparing the strings
+func (id ULID) Compare(other ULID) int {
+	return bytes.Compare(id[:], other[:])
+}
+
+// String returns the 26-character Crockford base32 form. 26 characters
+// hold 130 bits, so the value is read as if it had two leading zero bits
+// and the first character is always 0-7.
+func (id ULID) String() string {
+	return string(id.appendText(make([]byte, 0, 26)))
+}
+
+func (id ULID) appendText(b []byte) []byte {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	b = append(b, make([]byte, 26)...)
+	// Fill back to front, five bits at a time from the low end
+	out := b[len(b)-26:]
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return b
+}
+
+// ParseULID parses the 26-character form, in either case
+func ParseULID(s string) (ULID, error) {
+	var id ULID
+	if len(s) != 26 || decodeCrockford[s[0]] > 7 {
+		// A first character above 7 would need a 131st bit
+		return id, ErrInvalid
+	}
+	var hi, lo uint64
+	for i := range len(s) {
+		v := decodeCrockford[s[i]]
+		if v == 0xff {
+			return id, ErrInvalid
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	binary.BigEndian.PutUint64(id[:8], hi)
+	binary.BigEndian.PutUint64(id[8:], lo)
+	return id, nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (id ULID) MarshalText() ([]byte, error) {
+	return id.appendText(nil), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (id *ULID) UnmarshalText(b []byte) error {
+	parsed, err := ParseULID(string(b))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// Generator makes ULIDs that strictly increase. The zero value uses
+// time.Now and crypto/rand; a Generator is safe for concurrent use.
+type Generator struct {
+	Now     func() time.Time // nil means time.Now
+	Entropy io.Reader        // nil means crypto/rand
+
+	mu   sync.Mutex
+	last ULID
+}
+
+// New returns an ID greater than every earlier one from g
+func (g *Generator) New() (ULID, error) {
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	ms := uint64(now().UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ms <= g.last.ms() {
+		// Same millisecond, or the clock went back: last + 1
+		next := g.last
+		if !increment(next[6:]) {
+			return ULID{}, ErrOverflow
+		}
+		g.last = next
+		return next, nil
+	}
+
+	var id ULID
+	id[0], id[1] = byte(ms>>40), byte(ms>>32)
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if g.Entropy != nil {
+		if _, err := io.ReadFull(g.Entropy, id[6:]); err != nil {
+			return ULID{}, err
+		}
+	} else {
+		rand.Read(id[6:])
+	}
+	g.last = id
+	return id, nil
+}
+
+// increment adds one to the big-endian number in b, reporting false if
+// it wrapped to zero
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultGenerator Generator
+
+// NewULID returns a ULID from a process-wide Generator, so IDs from
+// one process strictly increase
+func NewULID() ULID {
+	id, err := defaultGenerator.New()
+	if err != nil {
+		// Only possible with 2^80 IDs in one millisecond from
+		// crypto/rand, which cannot happen
+		panic(err)
+	}
+	return id
+}
@@ -0,0 +1,117 @@
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// UUIDs and Sortable IDs
+// ======================
+// An ID generated without coordination - no database sequence, no
+// central counter - has to be unique by chance: enough random bits that
+// two generators never pick the same value. The two shapes here:
+//
+//	UUIDv4   122 random bits in the RFC 9562 layout, written as
+//	         f47ac10b-58cc-4372-a567-0e02b2c3d479. Unordered
+//	ULID     a 48-bit millisecond timestamp, then 80 random bits,
+//	         written as 26 Crockford base32 characters:
+//	         01ARYZ6S41TSV4RRFFQ69G5FAV. Sorts by creation time, as
+//	         bytes and as text
+//
+// UUIDv7 (RFC 9562, 2024) is the standard form of the ULID idea: the
+// same 48-bit timestamp up front, in UUID layout and text. Pick it when
+// the ID goes into a UUID column; the generator logic is the same.
+//
+// Both need crypto/rand. math/rand/v2 is fast, but it documents itself
+// as unsuitable for security work, and a PCG's state can be recovered
+// from its output. IDs that double as unguessable references - a share
+// link, a reset token - must not come from it.
+//
+// Layout of a version 4 UUID (16 bytes, big-endian):
+//
+//	byte 6, high nibble   version: 0100
+//	byte 8, high bits     variant: 10 (RFC 9562)
+//	everything else       random
+//
+// Anti-patterns
+// =============
+//
+//	math/rand or time.Now().UnixNano()   guessable, and collides across
+//	as the ID                            machines starting together
+//	UUIDv4 as a B-tree primary key       random inserts touch every page;
+//	                                     a time-ordered ID appends
+//	sequential IDs in public URLs        /invoice/1042 invites /1043
+//	comparing IDs as strings with        UUIDs are case-insensitive; parse
+//	mixed case                           first, compare the bytes
+//	storing UUIDs as 36-char text        16 bytes as binary, or the
+//	                                     database's uuid type
+
+// UUID is a 128-bit RFC 9562 UUID
+type UUID [16]byte
+
+// ErrInvalid is returned for text that is not a well-formed ID
+var ErrInvalid = errors.New("ids: invalid ID")
+
+// NewV4 returns a random (version 4) UUID
+func NewV4() UUID {
+	var u UUID
+	// rand.Read never fails since Go 1.24
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // variant 10
+	return u
+}
+
+// Version returns the version nibble: 4 for NewV4, 7 for a UUIDv7
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// String returns the canonical lower-case 8-4-4-4-12 form
+func (u UUID) String() string {
+	return string(u.appendText(make([]byte, 0, 36)))
+}
+
+func (u UUID) appendText(b []byte) []byte {
+	for i, group := range [...][2]int{{0, 4}, {4, 6}, {6, 8}, {8, 10}, {10, 16}} {
+		if i > 0 {
+			b = append(b, '-')
+		}
+		b = hex.AppendEncode(b, u[group[0]:group[1]])
+	}
+	return b
+}
+
+// ParseUUID parses the canonical form, in either case, with an optional
+// urn:uuid: prefix. Braces and the 32-digit form without hyphens are
+// rejected: accept one spelling and IDs compare as text too.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	s = strings.TrimPrefix(s, "urn:uuid:")
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrInvalid
+	}
+	hexDigits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(hexDigits)); err != nil {
+		return u, ErrInvalid
+	}
+	return u, nil
+}
+
+// MarshalText implements encoding.TextMarshaler, so a UUID is a string
+// in JSON
+func (u UUID) MarshalText() ([]byte, error) {
+	return u.appendText(nil), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(b []byte) error {
+	parsed, err := ParseUUID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
@@ -0,0 +1,163 @@
+package passwords
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Storing Passwords
+// =================
+// A password database will leak one day; storage is about what the
+// thief can do then. Hashing with SHA-256 is not enough: a GPU computes
+// billions of SHA-256 hashes a second, and most passwords are in a
+// list of a few billion guesses. A password hash must be:
+//
+//	slow      a deliberate cost per guess: thousands of iterations, or
+//	          memory an attacker cannot cheaply parallelise
+//	salted    a random value per user, stored with the hash, so equal
+//	          passwords get different hashes and one guess cannot be
+//	          tested against every user - or looked up in a table
+//	          computed in advance
+//	tunable   the cost stored with each hash, so it can be raised as
+//	          hardware gets faster, and old hashes upgraded at login
+//
+// What to use, best first:
+//
+//	argon2id  golang.org/x/crypto/argon2 - memory-hard, the current
+//	          OWASP recommendation (m=19 MiB, t=2, p=1 at minimum)
+//	bcrypt    golang.org/x/crypto/bcrypt - long-standing and fine; note
+//	          it ignores everything after 72 bytes of password
+//	PBKDF2    crypto/pbkdf2 in the standard library since Go 1.24, and
+//	          the FIPS-approved choice. Not memory-hard, so it needs
+//	          many iterations: OWASP asks for 600,000 with SHA-256
+//
+// This package uses PBKDF2 because it needs no module outside the
+// standard library; the format and the upgrade logic are the same
+// whichever function sits underneath.
+//
+// Anti-patterns
+// =============
+//
+//	sha256(password), md5(password)     fast: guessed at GPU speed
+//	sha256(salt + password)             salted but still fast
+//	encrypting passwords                the key sits next to the data;
+//	                                    one leak exposes every password
+//	a global "pepper" instead of salt   same hash for same password
+//	comparing hashes with ==            leaks timing; use subtle
+//	logging the request body at login   the password is in it
+
+// Params is the cost of a hash. The zero value is not valid; start
+// from Default.
+type Params struct {
+	Iterations int
+	SaltLen    int // bytes
+	KeyLen     int // bytes
+}
+
+// Default is OWASP's 2023 minimum for PBKDF2-HMAC-SHA256
+var Default = Params{Iterations: 600_000, SaltLen: 16, KeyLen: 32}
+
+// Errors from Verify. ErrMismatch is the normal "wrong password".
+var (
+	ErrMismatch = errors.New("passwords: password does not match")
+	ErrFormat   = errors.New("passwords: unrecognised hash format")
+)
+
+// The stored form, modelled on the PHC string format used by argon2
+// and scrypt libraries:
+//
+//	$pbkdf2-sha256$i=600000$<salt>$<key>
+//
+// Algorithm, cost and salt travel with the hash, so Verify needs
+// nothing else and different users can have different costs.
+const prefix = "$pbkdf2-sha256$"
+
+var b64 = base64.RawStdEncoding
+
+// Hash returns the stored form of password with a new random salt
+func Hash(password string, p Params) (string, error) {
+	salt := make([]byte, p.SaltLen)
+	rand.Read(salt) // never fails since Go 1.24; it crashes the program instead
+	key, err := pbkdf2.Key(sha256.New, password, salt, p.Iterations, p.KeyLen)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%si=%d$%s$%s", prefix, p.Iterations, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// Verify checks password against a stored hash. It returns nil on a
+// match, ErrMismatch for a wrong password, and ErrFormat for a stored
+// value it cannot read.
+func Verify(password, stored string) error {
+	p, salt, want, err := parse(stored)
+	if err != nil {
+		return err
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, p.Iterations, len(want))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// VerifyUser is Verify for a login, where the user may not exist. A
+// login that returns at once for an unknown name and after 100ms of
+// hashing for a known one tells an attacker which names are real. For
+// a missing user - found false - it verifies against a dummy hash of
+// the same cost and reports ErrMismatch, as for a wrong password.
+func VerifyUser(password, stored string, found bool) error {
+	if !found {
+		Verify(password, dummyHash())
+		return ErrMismatch
+	}
+	return Verify(password, stored)
+}
+
+var dummyHash = sync.OnceValue(func() string {
+	h, _ := Hash("no such user", Default)
+	return h
+})
+
+// NeedsRehash reports whether stored was made with a lower cost than
+// p. Check it after a successful Verify, while the plain password is at
+// hand, and store a new Hash if so: costs go up without a migration.
+func NeedsRehash(stored string, p Params) bool {
+	old, salt, key, err := parse(stored)
+	return err != nil || old.Iterations < p.Iterations || len(salt) < p.SaltLen || len(key) < p.KeyLen
+}
+
+func parse(stored string) (p Params, salt, key []byte, err error) {
+	rest, ok := strings.CutPrefix(stored, prefix)
+	if !ok {
+		return p, nil, nil, ErrFormat
+	}
+	parts := strings.Split(rest, "$")
+	if len(parts) != 3 {
+		return p, nil, nil, ErrFormat
+	}
+	iterText, ok := strings.CutPrefix(parts[0], "i=")
+	if !ok {
+		return p, nil, nil, ErrFormat
+	}
+	if p.Iterations, err = strconv.Atoi(iterText); err != nil || p.Iterations < 1 {
+		return p, nil, nil, ErrFormat
+	}
+	salt, err1 := b64.DecodeString(parts[1])
+	key, err2 := b64.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || len(key) == 0 {
+		return p, nil, nil, ErrFormat
+	}
+	p.SaltLen, p.KeyLen = len(salt), len(key)
+	return p, salt, key, nil
+}
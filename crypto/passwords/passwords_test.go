@@ -0,0 +1,201 @@
+package passwords
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Storing Passwords - Tests
+// =========================
+// Run with:
+//
+//   cd crypto/passwords
+//   go test -v *.go
+//
+// Most tests use a low cost so they run quickly. TestDefaultCost uses
+// the real one to show what it costs; -short skips it.
+
+var fast = Params{Iterations: 1000, SaltLen: 16, KeyLen: 32}
+
+// 1. The Function Underneath
+// ==========================
+
+func TestPBKDF2KnownAnswer(t *testing.T) {
+	// PBKDF2-HMAC-SHA256, P="password", S="salt", c=1, dkLen=32
+	key, err := pbkdf2.Key(sha256.New, "password", []byte("salt"), 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("key = %s, want %s", got, want)
+	}
+}
+
+// 2. Hash and Verify
+// ==================
+
+func TestHashAndVerify(t *testing.T) {
+	stored, err := Hash("correct horse battery staple", fast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored, "$pbkdf2-sha256$i=1000$") {
+		t.Errorf("stored = %q, want the algorithm and cost up front", stored)
+	}
+	if err := Verify("correct horse battery staple", stored); err != nil {
+		t.Errorf("right password: %v", err)
+	}
+	for _, wrong := range []string{"", "Correct horse battery staple", "correct horse battery staple "} {
+		if err := Verify(wrong, stored); !errors.Is(err, ErrMismatch) {
+			t.Errorf("Verify(%q) = %v, want ErrMismatch", wrong, err)
+		}
+	}
+}
+
+func TestSaltMakesEqualPasswordsDiffer(t *testing.T) {
+	a, _ := Hash("hunter2", fast)
+	b, _ := Hash("hunter2", fast)
+	if a == b {
+		t.Fatal("two hashes of one password are equal: no salt")
+	}
+	if Verify("hunter2", a) != nil || Verify("hunter2", b) != nil {
+		t.Error("both hashes should verify")
+	}
+}
+
+func TestVerifyRejectsBadFormats(t *testing.T) {
+	good, _ := Hash("pw", fast)
+	tests := []string{
+		"",
+		"5e884898da28047151d0e56f8dc6292773603d0d",                     // an unsalted SHA-1: refuse, don't guess
+		"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", // bcrypt
+		strings.Replace(good, "i=1000", "i=zero", 1),
+		strings.Replace(good, "i=1000", "i=0", 1),
+		strings.Replace(good, "i=1000", "1000", 1),
+		good[:strings.LastIndexByte(good, '$')] + "$",
+		good + "$extra",
+	}
+	for _, stored := range tests {
+		if err := Verify("pw", stored); !errors.Is(err, ErrFormat) {
+			t.Errorf("Verify(%q) = %v, want ErrFormat", stored, err)
+		}
+	}
+}
+
+// 3. Upgrading the Cost
+// =====================
+
+func TestNeedsRehash(t *testing.T) {
+	old, _ := Hash("pw", Params{Iterations: 1000, SaltLen: 8, KeyLen: 32})
+	tests := []struct {
+		p    Params
+		want bool
+	}{
+		{Params{Iterations: 1000, SaltLen: 8, KeyLen: 32}, false},
+		{Params{Iterations: 500, SaltLen: 8, KeyLen: 32}, false},
+		{Params{Iterations: 2000, SaltLen: 8, KeyLen: 32}, true},
+		{Params{Iterations: 1000, SaltLen: 16, KeyLen: 32}, true},
+	}
+	for _, tt := range tests {
+		if got := NeedsRehash(old, tt.p); got != tt.want {
+			t.Errorf("NeedsRehash(%+v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if !NeedsRehash("not a hash", fast) {
+		t.Error("an unreadable hash should need replacing")
+	}
+}
+
+func TestUpgradeAtLogin(t *testing.T) {
+	// The login flow: verify, then rehash at the current cost while the
+	// password is at hand
+	db := map[string]string{}
+	db["ada"], _ = Hash("analytical", Params{Iterations: 100, SaltLen: 16, KeyLen: 32})
+
+	login := func(user, password string) error {
+		stored, found := db[user]
+		if err := VerifyUser(password, stored, found); err != nil {
+			return err
+		}
+		if NeedsRehash(stored, fast) {
+			db[user], _ = Hash(password, fast)
+		}
+		return nil
+	}
+
+	if err := login("ada", "analytical"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(db["ada"], "i=1000$") {
+		t.Errorf("hash not upgraded: %s", db["ada"])
+	}
+	if err := login("ada", "analytical"); err != nil {
+		t.Errorf("login after upgrade: %v", err)
+	}
+	if err := login("ada", "wrong"); !errors.Is(err, ErrMismatch) {
+		t.Errorf("wrong password: %v", err)
+	}
+	if err := login("nobody", "analytical"); !errors.Is(err, ErrMismatch) {
+		t.Errorf("unknown user: %v, want the same ErrMismatch", err)
+	}
+}
+
+// 4. The Cost Is the Point
+// ========================
+
+func TestDefaultCost(t *testing.T) {
+	if testing.Short() {
+		t.Skip("hashes at full cost")
+	}
+	start := time.Now()
+	stored, err := Hash("pw", Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perHash := time.Since(start)
+	t.Logf("one hash at %d iterations: %v - at that rate one core tries %.0f passwords a second",
+		Default.Iterations, perHash, float64(time.Second)/float64(perHash))
+
+	// An unknown user costs as much as a known one, so response time
+	// does not say which names exist
+	VerifyUser("pw", "", false) // builds the dummy hash once
+	start = time.Now()
+	VerifyUser("pw", "", false)
+	missing := time.Since(start)
+	start = time.Now()
+	VerifyUser("wrong", stored, true)
+	present := time.Since(start)
+	if missing < present/4 {
+		t.Errorf("unknown user took %v, known user %v: the difference reveals which exist", missing, present)
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	for _, iter := range []int{1, 1000, 600_000} {
+		b.Run(fmt.Sprintf("iterations=%d", iter), func(b *testing.B) {
+			p := Params{Iterations: iter, SaltLen: 16, KeyLen: 32}
+			for b.Loop() {
+				Hash("pw", p)
+			}
+		})
+	}
+}
+
+// 5. Examples
+// ===========
+
+func ExampleVerify() {
+	stored, _ := Hash("s3cret", Params{Iterations: 1000, SaltLen: 16, KeyLen: 32})
+	fmt.Println(Verify("s3cret", stored))
+	fmt.Println(Verify("guess", stored))
+	// Output:
+	// <nil>
+	// passwords: password does not match
+}
@@ -0,0 +1,281 @@
+package tlsclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// A TLS-Configured HTTP Client - Tests
+// ====================================
+// Run with:
+//
+//   cd crypto/tlsclient
+//   go test -v *.go
+//
+// httptest.NewTLSServer serves with a self-signed certificate for
+// 127.0.0.1 and example.com. No system root trusts it, which makes it a
+// stand-in for a service behind a private CA.
+
+func hello(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, "hello over ", tls.VersionName(r.TLS.Version))
+}
+
+func serverPool(srv *httptest.Server) *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return pool
+}
+
+func get(t *testing.T, c *http.Client, url string) (string, error) {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+// 1. Trust
+// ========
+
+func TestTrustedRoot(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(hello))
+	defer srv.Close()
+
+	c, err := NewClient(Options{RootCAs: serverPool(srv)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := get(t, c, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "hello over TLS 1.3" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSystemRootsRejectUnknownCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(hello))
+	defer srv.Close()
+
+	c, _ := NewClient(Options{})
+	_, err := get(t, c, srv.URL)
+	var unknown x509.UnknownAuthorityError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("err = %v, want x509.UnknownAuthorityError", err)
+	}
+}
+
+func TestInsecureSkipVerifyTrustsAnyone(t *testing.T) {
+	// The anti-pattern: a client that skips verification talks happily
+	// to a server nobody vouched for - here, one pretending to be the
+	// bank. On a real network that server is whoever sits in the middle.
+	impostor := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "send me your password")
+	}))
+	defer impostor.Close()
+
+	insecure := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	body, err := get(t, insecure, impostor.URL)
+	if err != nil || body != "send me your password" {
+		t.Fatalf("got %q, %v", body, err)
+	}
+
+	secure, _ := NewClient(Options{})
+	if _, err := get(t, secure, impostor.URL); err == nil {
+		t.Error("a verifying client accepted the impostor")
+	}
+}
+
+// 2. Versions
+// ===========
+
+func TestRefusesOldTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(hello))
+	srv.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c, _ := NewClient(Options{RootCAs: serverPool(srv)})
+	if _, err := get(t, c, srv.URL); err == nil {
+		t.Fatal("connected to a TLS 1.1 server")
+	}
+}
+
+func TestMinVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(hello))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c12, _ := NewClient(Options{RootCAs: serverPool(srv)})
+	if body, err := get(t, c12, srv.URL); err != nil || body != "hello over TLS 1.2" {
+		t.Errorf("default minimum: %q, %v", body, err)
+	}
+	c13, _ := NewClient(Options{RootCAs: serverPool(srv), MinVersion: tls.VersionTLS13})
+	if _, err := get(t, c13, srv.URL); err == nil {
+		t.Error("a TLS 1.3-only client connected to a TLS 1.2 server")
+	}
+
+	if _, err := NewClient(Options{MinVersion: tls.VersionTLS10}); err == nil {
+		t.Error("NewClient accepted a TLS 1.0 minimum")
+	}
+}
+
+// 3. Pinning
+// ==========
+
+// selfSigned makes a self-signed certificate and a fresh key for name,
+// usable as a server certificate for 127.0.0.1 or as a client one
+func selfSigned(t *testing.T, name string) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf
+}
+
+func TestPinning(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(hello))
+	defer srv.Close()
+	pin := SPKIHash(srv.Certificate())
+
+	// The current pin plus a backup for the next key
+	c, _ := NewClient(Options{RootCAs: serverPool(srv), Pins: []string{"backup-key-hash=", pin}})
+	if _, err := get(t, c, srv.URL); err != nil {
+		t.Fatalf("pinned key: %v", err)
+	}
+
+	// A server with a valid certificate but another key: a CA that
+	// should not have issued it, say. Every httptest server shares one
+	// built-in key, so this one gets a certificate of its own.
+	otherCert, _ := selfSigned(t, "impostor")
+	other := httptest.NewUnstartedServer(http.HandlerFunc(hello))
+	other.TLS = &tls.Config{Certificates: []tls.Certificate{otherCert}}
+	other.StartTLS()
+	defer other.Close()
+	pool := serverPool(srv)
+	pool.AddCert(other.Certificate())
+	c, _ = NewClient(Options{RootCAs: pool, Pins: []string{pin}})
+	if _, err := get(t, c, other.URL); !errors.Is(err, ErrPinMismatch) {
+		t.Fatalf("unpinned key: %v, want ErrPinMismatch", err)
+	}
+}
+
+// 4. Mutual TLS
+// =============
+
+func TestMutualTLS(t *testing.T) {
+	cert, leaf := selfSigned(t, "billing-service")
+	clients := x509.NewCertPool()
+	clients.AddCert(leaf)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello, ", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c, _ := NewClient(Options{RootCAs: serverPool(srv), Certificates: []tls.Certificate{cert}})
+	body, err := get(t, c, srv.URL)
+	if err != nil || body != "hello, billing-service" {
+		t.Fatalf("with a client certificate: %q, %v", body, err)
+	}
+
+	anonymous, _ := NewClient(Options{RootCAs: serverPool(srv)})
+	if _, err := get(t, anonymous, srv.URL); err == nil {
+		t.Error("the server accepted a client with no certificate")
+	}
+}
+
+// 5. Timeouts
+// ===========
+
+func TestTimeout(t *testing.T) {
+	c, _ := NewClient(Options{})
+	if c.Timeout != 30*time.Second {
+		t.Errorf("default Timeout = %v", c.Timeout)
+	}
+
+	stuck := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stuck:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(stuck)
+
+	c, _ = NewClient(Options{RootCAs: serverPool(srv), Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := get(t, c, srv.URL)
+	if err == nil {
+		t.Fatal("no error from a server that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %v", elapsed)
+	}
+}
+
+// 6. Examples
+// ===========
+
+func ExampleNewClient() {
+	srv := httptest.NewTLSServer(http.HandlerFunc(hello))
+	defer srv.Close()
+
+	// In production the pool would come from the company CA's PEM file:
+	// pool.AppendCertsFromPEM(pemBytes)
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	client, err := NewClient(Options{RootCAs: pool})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Println(resp.Status, string(body))
+	// Output: 200 OK hello over TLS 1.3
+}
@@ -0,0 +1,64 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Drawing the Machine
+// ===================
+// The table is data, so the diagram is generated from it and cannot
+// fall out of date. Two formats:
+//
+//	Mermaid   stateDiagram-v2 text; GitHub and most wikis render it
+//	          inside a ```mermaid block
+//	Graphviz  DOT text; dot -Tsvg order.dot > order.svg
+//
+// Both mark the initial state with an arrow from a start point, end
+// terminal states in a final point (Mermaid) or a double border (DOT),
+// and label each arrow with its event and, in brackets, its guard.
+// States and events are printed with %v, so a String method names them.
+
+// Mermaid returns the machine as a Mermaid state diagram
+func (m *Machine[S, E, T]) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	fmt.Fprintf(&b, "    [*] --> %v\n", m.initial)
+	for _, ed := range m.edges {
+		fmt.Fprintf(&b, "    %v --> %v: %s\n", ed.From, ed.To, label(ed, " "))
+	}
+	for _, s := range m.states {
+		if m.Terminal(s) {
+			fmt.Fprintf(&b, "    %v --> [*]\n", s)
+		}
+	}
+	return b.String()
+}
+
+// Dot returns the machine as a Graphviz digraph called name
+func (m *Machine[S, E, T]) Dot(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", name)
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n")
+	b.WriteString("\tstart [shape=point];\n")
+	for _, s := range m.states {
+		if m.Terminal(s) {
+			fmt.Fprintf(&b, "\t%q [peripheries=2];\n", fmt.Sprint(s))
+		}
+	}
+	fmt.Fprintf(&b, "\tstart -> %q;\n", fmt.Sprint(m.initial))
+	for _, ed := range m.edges {
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", fmt.Sprint(ed.From), fmt.Sprint(ed.To), label(ed, "\n"))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// label is the event, then the guard's name in brackets after sep
+func label[S, E comparable, T any](ed *edge[S, E, T], sep string) string {
+	if ed.guardName == "" {
+		return fmt.Sprint(ed.Event)
+	}
+	return fmt.Sprintf("%v%s[%s]", ed.Event, sep, ed.guardName)
+}
@@ -0,0 +1,256 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Finite State Machines
+// =====================
+// Anything with a status column is a state machine, whether or not the
+// code says so. Written as if-statements scattered through handlers,
+// the rules drift: one path lets a cancelled order ship, another
+// forgets to record when it was paid. Written as a table, the rules
+// are in one place, every transition not in the table is refused, and
+// the table can draw itself:
+//
+//	state        where the thing is: pending, paid, shipped
+//	event        what happens to it: pay, ship, cancel
+//	transition   from a state, on an event, to a state
+//	guard        a condition a transition also needs; an error says why
+//	             it does not hold
+//	hooks        run on leaving a state, entering one, and on every
+//	             transition: set timestamps, write history
+//
+// A Machine is the table, built once and shared. It holds no current
+// state: that belongs to the thing it describes - a row, a struct
+// field - and is passed to Fire, which returns the next one. One
+// definition serves every order.
+//
+// Fire checks guards before it runs any hook, so a refused event
+// changes nothing. Hooks cannot fail: work that can fail belongs in a
+// guard, or after the new state is saved.
+//
+// Pitfalls:
+//
+//	a boolean per state (isPaid,     allows combinations no one meant:
+//	isShipped, isCancelled)          paid, shipped and cancelled at once
+//	checking state in handlers       rules drift apart; the table is
+//	                                 never written down
+//	side effects in guards           a guard runs for Can and Events too,
+//	                                 and may run for a refused event
+//	mutating the Machine after       Fire reads it without a lock
+//	start
+
+// ErrNoTransition is wrapped in the error Fire returns when the event
+// has no transition from the state
+var ErrNoTransition = errors.New("fsm: no transition")
+
+// Transition is one row of the table
+type Transition[S, E comparable] struct {
+	From  S
+	Event E
+	To    S
+}
+
+// TransitionError is returned by Fire for a refused event. Err is
+// ErrNoTransition or the guard's error.
+type TransitionError[S, E comparable] struct {
+	From  S
+	Event E
+	Err   error
+}
+
+func (e *TransitionError[S, E]) Error() string {
+	return fmt.Sprintf("fsm: cannot %v from %v: %v", e.Event, e.From, e.Err)
+}
+
+func (e *TransitionError[S, E]) Unwrap() error { return e.Err }
+
+// Guard reports why a transition may not happen to t, or nil if it may
+type Guard[T any] func(t T) error
+
+// Hook runs during a transition of t
+type Hook[S, E comparable, T any] func(t T, tr Transition[S, E])
+
+type edge[S, E comparable, T any] struct {
+	Transition[S, E]
+	guardName string
+	guard     Guard[T]
+}
+
+type key[S, E comparable] struct {
+	from  S
+	event E
+}
+
+// Machine is a state machine over states S and events E, for subjects
+// of type T. Build it with New and Add, then share it.
+type Machine[S, E comparable, T any] struct {
+	initial S
+	states  []S              // in the order first seen, for diagrams
+	edges   []*edge[S, E, T] // in the order added
+	byKey   map[key[S, E]][]*edge[S, E, T]
+	enter   map[S][]Hook[S, E, T]
+	exit    map[S][]Hook[S, E, T]
+	any     []Hook[S, E, T]
+}
+
+// New returns a Machine whose subjects start in initial
+func New[S, E comparable, T any](initial S) *Machine[S, E, T] {
+	m := &Machine[S, E, T]{
+		initial: initial,
+		byKey:   map[key[S, E]][]*edge[S, E, T]{},
+		enter:   map[S][]Hook[S, E, T]{},
+		exit:    map[S][]Hook[S, E, T]{},
+	}
+	m.addState(initial)
+	return m
+}
+
+func (m *Machine[S, E, T]) addState(s S) {
+	if !slices.Contains(m.states, s) {
+		m.states = append(m.states, s)
+	}
+}
+
+// Add allows event e to move a subject from one state to another
+func (m *Machine[S, E, T]) Add(from S, e E, to S) *Machine[S, E, T] {
+	return m.AddIf(from, e, to, "", nil)
+}
+
+// AddIf allows the transition when guard returns nil. name describes
+// the condition in diagrams. Several transitions may share a state and
+// event: the first added whose guard passes is taken, so a choice - a
+// return refunded in full within 14 days, and in part after - is two
+// guarded transitions.
+func (m *Machine[S, E, T]) AddIf(from S, e E, to S, name string, guard Guard[T]) *Machine[S, E, T] {
+	m.addState(from)
+	m.addState(to)
+	ed := &edge[S, E, T]{Transition[S, E]{from, e, to}, name, guard}
+	m.edges = append(m.edges, ed)
+	k := key[S, E]{from, e}
+	m.byKey[k] = append(m.byKey[k], ed)
+	return m
+}
+
+// OnEnter runs fn whenever a subject enters s, including from s itself
+func (m *Machine[S, E, T]) OnEnter(s S, fn Hook[S, E, T]) *Machine[S, E, T] {
+	m.enter[s] = append(m.enter[s], fn)
+	return m
+}
+
+// OnExit runs fn whenever a subject leaves s, including back to s
+func (m *Machine[S, E, T]) OnExit(s S, fn Hook[S, E, T]) *Machine[S, E, T] {
+	m.exit[s] = append(m.exit[s], fn)
+	return m
+}
+
+// OnTransition runs fn on every transition, after the exit hooks and
+// before the entry hooks
+func (m *Machine[S, E, T]) OnTransition(fn Hook[S, E, T]) *Machine[S, E, T] {
+	m.any = append(m.any, fn)
+	return m
+}
+
+// Initial returns the state new subjects start in
+func (m *Machine[S, E, T]) Initial() S { return m.initial }
+
+// States returns every state, in the order they were first named
+func (m *Machine[S, E, T]) States() []S { return slices.Clone(m.states) }
+
+// find returns the transition e takes t from from. When none applies,
+// the error is ErrNoTransition or the first guard's.
+func (m *Machine[S, E, T]) find(t T, from S, e E) (*edge[S, E, T], error) {
+	candidates := m.byKey[key[S, E]{from, e}]
+	if len(candidates) == 0 {
+		return nil, ErrNoTransition
+	}
+	var first error
+	for _, ed := range candidates {
+		if ed.guard == nil {
+			return ed, nil
+		}
+		err := ed.guard(t)
+		if err == nil {
+			return ed, nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return nil, first
+}
+
+// Fire applies event e to t in state from and returns the new state.
+// A refused event returns from unchanged and a *TransitionError, and
+// runs no hooks.
+func (m *Machine[S, E, T]) Fire(t T, from S, e E) (S, error) {
+	ed, err := m.find(t, from, e)
+	if err != nil {
+		return from, &TransitionError[S, E]{from, e, err}
+	}
+	for _, fn := range m.exit[from] {
+		fn(t, ed.Transition)
+	}
+	for _, fn := range m.any {
+		fn(t, ed.Transition)
+	}
+	for _, fn := range m.enter[ed.To] {
+		fn(t, ed.Transition)
+	}
+	return ed.To, nil
+}
+
+// Can reports whether e would be accepted for t in state from
+func (m *Machine[S, E, T]) Can(t T, from S, e E) bool {
+	_, err := m.find(t, from, e)
+	return err == nil
+}
+
+// Events returns the events t in state from would accept now, in the
+// order their transitions were added: the buttons to show
+func (m *Machine[S, E, T]) Events(t T, from S) []E {
+	var events []E
+	for _, ed := range m.edges {
+		if ed.From == from && !slices.Contains(events, ed.Event) && m.Can(t, from, ed.Event) {
+			events = append(events, ed.Event)
+		}
+	}
+	return events
+}
+
+// Terminal reports whether s has no transitions out
+func (m *Machine[S, E, T]) Terminal(s S) bool {
+	for _, ed := range m.edges {
+		if ed.From == s {
+			return false
+		}
+	}
+	return true
+}
+
+// Unreachable returns the states no sequence of events leads to from
+// the initial state, ignoring guards: usually a missing Add
+func (m *Machine[S, E, T]) Unreachable() []S {
+	seen := map[S]bool{m.initial: true}
+	queue := []S{m.initial}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, ed := range m.edges {
+			if ed.From == s && !seen[ed.To] {
+				seen[ed.To] = true
+				queue = append(queue, ed.To)
+			}
+		}
+	}
+	var out []S
+	for _, s := range m.states {
+		if !seen[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
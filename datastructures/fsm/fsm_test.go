@@ -0,0 +1,420 @@
+package fsm
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// State Machines - Tests
+// ======================
+// Run with:
+//
+//   cd datastructures/fsm
+//   go test -v *.go
+//
+// After changing the order lifecycle, regenerate the diagrams:
+//
+//   go test *.go -run TestDiagrams -update
+//
+// The generic machine is tested on a turnstile - the smallest machine
+// with a guard - and the order lifecycle on every path through it.
+
+var update = flag.Bool("update", false, "rewrite order.mmd and order.dot from the lifecycle")
+
+// turnstile: a coin unlocks it, a push locks it again. Pushing a locked
+// turnstile does nothing; a coin is refused when the box is full.
+type turnstile struct {
+	coins, capacity int
+	log             []string
+}
+
+func newTurnstile() *Machine[string, string, *turnstile] {
+	return New[string, string, *turnstile]("locked").
+		AddIf("locked", "coin", "unlocked", "box not full", func(t *turnstile) error {
+			if t.coins >= t.capacity {
+				return errors.New("coin box full")
+			}
+			return nil
+		}).
+		Add("unlocked", "push", "locked").
+		Add("unlocked", "coin", "unlocked").
+		Add("locked", "push", "locked")
+}
+
+// 1. Transitions
+// ==============
+
+func TestFire(t *testing.T) {
+	m := newTurnstile()
+	ts := &turnstile{capacity: 10}
+	state := m.Initial()
+	for _, step := range []struct{ event, want string }{
+		{"push", "locked"},
+		{"coin", "unlocked"},
+		{"coin", "unlocked"},
+		{"push", "locked"},
+	} {
+		next, err := m.Fire(ts, state, step.event)
+		if err != nil || next != step.want {
+			t.Fatalf("%s from %s: %s, %v; want %s", step.event, state, next, err, step.want)
+		}
+		state = next
+	}
+}
+
+func TestNoTransition(t *testing.T) {
+	m := New[string, string, any]("a").Add("a", "go", "b")
+	next, err := m.Fire(nil, "b", "go")
+	var te *TransitionError[string, string]
+	if next != "b" || !errors.Is(err, ErrNoTransition) || !errors.As(err, &te) {
+		t.Fatalf("got %s, %v", next, err)
+	}
+	if te.From != "b" || te.Event != "go" {
+		t.Errorf("error names %s/%s", te.From, te.Event)
+	}
+	if want := "fsm: cannot go from b: fsm: no transition"; err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	m := newTurnstile()
+	full := &turnstile{coins: 10, capacity: 10}
+	next, err := m.Fire(full, "locked", "coin")
+	if next != "locked" || err == nil || !strings.Contains(err.Error(), "coin box full") {
+		t.Errorf("got %s, %v; want the guard's reason", next, err)
+	}
+	if m.Can(full, "locked", "coin") {
+		t.Error("Can ignores the guard")
+	}
+	if !m.Can(&turnstile{capacity: 1}, "locked", "coin") {
+		t.Error("Can refuses a passing guard")
+	}
+}
+
+// Guarded alternatives are tried in the order they were added; the
+// error when none passes is the first guard's
+func TestGuardedChoice(t *testing.T) {
+	errSmall, errLarge := errors.New("too small"), errors.New("too large")
+	m := New[string, string, int]("start").
+		AddIf("start", "go", "small", "n < 10", func(n int) error {
+			if n >= 10 {
+				return errSmall
+			}
+			return nil
+		}).
+		AddIf("start", "go", "large", "n < 100", func(n int) error {
+			if n >= 100 {
+				return errLarge
+			}
+			return nil
+		})
+	for n, want := range map[int]string{5: "small", 50: "large"} {
+		if got, err := m.Fire(n, "start", "go"); got != want || err != nil {
+			t.Errorf("n=%d: %s, %v; want %s", n, got, err, want)
+		}
+	}
+	if _, err := m.Fire(500, "start", "go"); !errors.Is(err, errSmall) {
+		t.Errorf("n=500: %v, want the first guard's error", err)
+	}
+}
+
+// 2. Hooks
+// ========
+
+func TestHookOrder(t *testing.T) {
+	var log []string
+	record := func(what string) Hook[string, string, any] {
+		return func(_ any, tr Transition[string, string]) {
+			log = append(log, fmt.Sprintf("%s %s->%s", what, tr.From, tr.To))
+		}
+	}
+	m := New[string, string, any]("a").
+		Add("a", "go", "b").
+		Add("b", "stay", "b").
+		OnExit("a", record("exit a")).
+		OnEnter("b", record("enter b")).
+		OnExit("b", record("exit b")).
+		OnTransition(record("any"))
+
+	m.Fire(nil, "a", "go")
+	m.Fire(nil, "b", "stay")
+	want := []string{
+		"exit a a->b", "any a->b", "enter b a->b",
+		// A self-transition leaves and re-enters
+		"exit b b->b", "any b->b", "enter b b->b",
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("hooks ran\n %q\nwant\n %q", log, want)
+	}
+}
+
+func TestRefusedRunsNoHooks(t *testing.T) {
+	ran := false
+	hook := func(any, Transition[string, string]) { ran = true }
+	m := New[string, string, any]("a").
+		AddIf("a", "go", "b", "never", func(any) error { return errors.New("no") }).
+		OnExit("a", hook).OnEnter("b", hook).OnTransition(hook)
+	m.Fire(nil, "a", "go")
+	m.Fire(nil, "a", "unknown")
+	if ran {
+		t.Error("a hook ran for a refused event")
+	}
+}
+
+// 3. Inspection
+// =============
+
+func TestEvents(t *testing.T) {
+	m := newTurnstile()
+	if got := m.Events(&turnstile{capacity: 1}, "locked"); !reflect.DeepEqual(got, []string{"coin", "push"}) {
+		t.Errorf("locked: %v", got)
+	}
+	// The guard hides coin when the box is full
+	if got := m.Events(&turnstile{coins: 1, capacity: 1}, "locked"); !reflect.DeepEqual(got, []string{"push"}) {
+		t.Errorf("locked and full: %v", got)
+	}
+}
+
+func TestStatesAndTerminal(t *testing.T) {
+	m := New[string, string, any]("a").Add("a", "x", "b").Add("b", "y", "c").Add("d", "z", "a")
+	if got := m.States(); !reflect.DeepEqual(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("states %v", got)
+	}
+	if !m.Terminal("c") || m.Terminal("b") {
+		t.Error("c is terminal, b is not")
+	}
+	if got := m.Unreachable(); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Errorf("unreachable %v, want [d]", got)
+	}
+}
+
+// 4. The Order Lifecycle
+// ======================
+
+var t0 = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// newTestOrder returns an order on a clock the test moves
+func newTestOrder(total int64) (*Order, *time.Time) {
+	now := t0
+	o := NewOrder("A-1", total, func() time.Time { return now })
+	o.Address = "12 Analytical Row"
+	return o, &now
+}
+
+func fire(t *testing.T, o *Order, events ...Event) {
+	t.Helper()
+	for _, e := range events {
+		if err := o.Fire(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOrderHappyPath(t *testing.T) {
+	o, now := newTestOrder(4200)
+	fire(t, o, Pay)
+	*now = now.Add(time.Hour)
+	fire(t, o, Ship)
+	*now = now.Add(48 * time.Hour)
+	fire(t, o, Deliver)
+
+	if o.Status != Delivered {
+		t.Fatalf("status %v", o.Status)
+	}
+	if !o.PaidAt.Equal(t0) || !o.ShippedAt.Equal(t0.Add(time.Hour)) || !o.DeliveredAt.Equal(t0.Add(49*time.Hour)) {
+		t.Errorf("timestamps %v %v %v", o.PaidAt, o.ShippedAt, o.DeliveredAt)
+	}
+	want := []string{"pay: pending -> paid", "ship: paid -> shipped", "deliver: shipped -> delivered"}
+	if !reflect.DeepEqual(o.History, want) {
+		t.Errorf("history %q", o.History)
+	}
+}
+
+// Cancel is two rows: nothing to refund before payment
+func TestOrderCancel(t *testing.T) {
+	o, _ := newTestOrder(4200)
+	fire(t, o, Cancel)
+	if o.Status != Cancelled || o.RefundedCents != 0 {
+		t.Errorf("unpaid cancel: %v, refunded %d", o.Status, o.RefundedCents)
+	}
+
+	o, _ = newTestOrder(4200)
+	fire(t, o, Pay, Cancel)
+	if o.Status != Refunded || o.RefundedCents != 4200 {
+		t.Errorf("paid cancel: %v, refunded %d", o.Status, o.RefundedCents)
+	}
+}
+
+func TestOrderGuards(t *testing.T) {
+	free, _ := newTestOrder(0)
+	if err := free.Fire(Pay); !errors.Is(err, ErrNothingDue) {
+		t.Errorf("pay for nothing: %v", err)
+	}
+
+	noAddr, _ := newTestOrder(100)
+	noAddr.Address = ""
+	fire(t, noAddr, Pay)
+	if err := noAddr.Fire(Ship); !errors.Is(err, ErrNoAddress) || noAddr.Status != Paid {
+		t.Errorf("ship without address: %v, status %v", err, noAddr.Status)
+	}
+	if want := "order A-1: fsm: cannot ship from paid: no shipping address"; noAddr.Fire(Ship).Error() != want {
+		t.Errorf("error %q, want %q", noAddr.Fire(Ship), want)
+	}
+}
+
+func TestOrderReturnWindow(t *testing.T) {
+	o, now := newTestOrder(100)
+	fire(t, o, Pay, Ship, Deliver)
+	*now = now.Add(ReturnWindow)
+	if !slices.Contains(o.Actions(), Return) {
+		t.Error("return not offered on the last day")
+	}
+	*now = now.Add(time.Second)
+	if err := o.Fire(Return); !errors.Is(err, ErrWindowExpired) {
+		t.Errorf("late return: %v", err)
+	}
+	if len(o.Actions()) != 0 {
+		t.Errorf("actions after the window: %v", o.Actions())
+	}
+}
+
+// Every event from every state: the table is the whole policy, so
+// anything not in it is refused and leaves the order untouched
+func TestOrderRefusesEverythingElse(t *testing.T) {
+	allowed := map[Status][]Event{
+		Pending:   {Pay, Cancel},
+		Paid:      {Ship, Cancel},
+		Shipped:   {Deliver},
+		Delivered: {Return},
+	}
+	for s := range Refunded + 1 {
+		for e := range Return + 1 {
+			o, _ := newTestOrder(100)
+			o.Status = s
+			o.DeliveredAt = t0
+			err := o.Fire(e)
+			if slices.Contains(allowed[s], e) {
+				if err != nil {
+					t.Errorf("%v from %v refused: %v", e, s, err)
+				}
+				continue
+			}
+			if !errors.Is(err, ErrNoTransition) || o.Status != s || len(o.History) != 0 {
+				t.Errorf("%v from %v: %v, now %v", e, s, err, o.Status)
+			}
+		}
+	}
+}
+
+func TestLifecycleShape(t *testing.T) {
+	m := Lifecycle()
+	if u := m.Unreachable(); len(u) != 0 {
+		t.Errorf("unreachable states: %v", u)
+	}
+	var terminal []Status
+	for _, s := range m.States() {
+		if m.Terminal(s) {
+			terminal = append(terminal, s)
+		}
+	}
+	if !reflect.DeepEqual(terminal, []Status{Cancelled, Refunded}) {
+		t.Errorf("terminal states %v", terminal)
+	}
+}
+
+func TestNames(t *testing.T) {
+	if Status(9).String() != "Status(9)" || Event(-1).String() != "Event(-1)" {
+		t.Error("out-of-range names")
+	}
+}
+
+// 5. Diagrams
+// ===========
+
+// The diagrams are checked in so the README can show them; this keeps
+// them in step with the table
+func TestDiagrams(t *testing.T) {
+	for file, got := range map[string]string{
+		"order.mmd": Lifecycle().Mermaid(),
+		"order.dot": Lifecycle().Dot("order"),
+	} {
+		if *update {
+			if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("wrote %s", file)
+			continue
+		}
+		want, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("%v (run with -update to create it)", err)
+		}
+		if !bytes.Equal([]byte(got), want) {
+			t.Errorf("%s is stale; run: go test *.go -run TestDiagrams -update\n%s", file, got)
+		}
+	}
+}
+
+func TestDotQuoting(t *testing.T) {
+	m := New[string, string, any](`say "hi"`).AddIf(`say "hi"`, "go", "done", "ok", func(any) error { return nil })
+	dot := m.Dot("g")
+	for _, want := range []string{`"say \"hi\"" -> "done" [label="go\n[ok]"];`, `"done" [peripheries=2];`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("missing %s in\n%s", want, dot)
+		}
+	}
+}
+
+// 6. Benchmarks
+// =============
+
+func BenchmarkFire(b *testing.B) {
+	m := newTurnstile()
+	ts := &turnstile{capacity: 1}
+	b.ReportAllocs()
+	for b.Loop() {
+		m.Fire(ts, "locked", "coin")
+	}
+}
+
+// 7. Examples
+// ===========
+
+func ExampleMachine_Mermaid() {
+	m := New[string, string, any]("green").
+		Add("green", "timer", "yellow").
+		Add("yellow", "timer", "red").
+		Add("red", "timer", "green")
+	fmt.Print(m.Mermaid())
+	// Output:
+	// stateDiagram-v2
+	//     [*] --> green
+	//     green --> yellow: timer
+	//     yellow --> red: timer
+	//     red --> green: timer
+}
+
+func ExampleOrder_Fire() {
+	o := NewOrder("A-7", 2500, nil)
+	fmt.Println(o.Status, o.Actions())
+	fmt.Println(o.Fire(Ship))
+	o.Address = "1 Infinite Loop"
+	o.Fire(Pay)
+	o.Fire(Ship)
+	fmt.Println(o.Status, o.Actions())
+	fmt.Println(o.History)
+	// Output:
+	// pending [pay cancel]
+	// order A-7: fsm: cannot ship from pending: fsm: no transition
+	// shipped [deliver]
+	// [pay: pending -> paid ship: paid -> shipped]
+}
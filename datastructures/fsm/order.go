@@ -0,0 +1,131 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Worked Example - An Order's Lifecycle
+// =====================================
+// An order is paid, shipped and delivered, and may be cancelled or
+// returned on the way. The whole policy is the table in lifecycle:
+//
+//	pending    --pay [amount due]------------> paid
+//	pending    --cancel----------------------> cancelled
+//	paid       --ship [has address]----------> shipped
+//	paid       --cancel----------------------> refunded
+//	shipped    --deliver---------------------> delivered
+//	delivered  --return [within 30 days]-----> refunded
+//
+// Cancelling means different things before and after payment; that is
+// two rows, not an if in a handler. The guards explain a refusal, and
+// the hooks keep the timestamps and the history consistent with the
+// state, because nothing else can change the state.
+//
+// order.mmd and order.dot are generated from the table by the tests.
+
+// Status is an order's state
+type Status int
+
+//go:generate go run ../../cmd/genenum/main.go -type Status,Event -case kebab -output order_string_gen.go
+
+const (
+	Pending Status = iota
+	Paid
+	Shipped
+	Delivered
+	Cancelled
+	Refunded
+)
+
+// Event is something that happens to an order
+type Event int
+
+const (
+	Pay Event = iota
+	Ship
+	Deliver
+	Cancel
+	Return
+)
+
+// ReturnWindow is how long after delivery an order may be returned
+const ReturnWindow = 30 * 24 * time.Hour
+
+var (
+	ErrNothingDue    = errors.New("order total is zero")
+	ErrNoAddress     = errors.New("no shipping address")
+	ErrWindowExpired = errors.New("return window has closed")
+)
+
+// Order is the subject of the lifecycle. Its Status changes only
+// through Fire.
+type Order struct {
+	ID         string
+	TotalCents int64
+	Address    string
+
+	Status                         Status
+	PaidAt, ShippedAt, DeliveredAt time.Time
+	RefundedCents                  int64
+	History                        []string
+
+	now func() time.Time
+}
+
+// NewOrder returns a pending order. now is time.Now if nil.
+func NewOrder(id string, totalCents int64, now func() time.Time) *Order {
+	if now == nil {
+		now = time.Now
+	}
+	return &Order{ID: id, TotalCents: totalCents, Status: lifecycle.Initial(), now: now}
+}
+
+// Fire applies e to the order, or returns why it cannot
+func (o *Order) Fire(e Event) error {
+	next, err := lifecycle.Fire(o, o.Status, e)
+	if err != nil {
+		return fmt.Errorf("order %s: %w", o.ID, err)
+	}
+	o.Status = next
+	return nil
+}
+
+// Actions returns the events the order accepts now
+func (o *Order) Actions() []Event {
+	return lifecycle.Events(o, o.Status)
+}
+
+// Lifecycle returns the shared definition, for diagrams and checks
+func Lifecycle() *Machine[Status, Event, *Order] { return lifecycle }
+
+var lifecycle = New[Status, Event, *Order](Pending).
+	AddIf(Pending, Pay, Paid, "amount due", func(o *Order) error {
+		if o.TotalCents <= 0 {
+			return ErrNothingDue
+		}
+		return nil
+	}).
+	Add(Pending, Cancel, Cancelled).
+	AddIf(Paid, Ship, Shipped, "has address", func(o *Order) error {
+		if o.Address == "" {
+			return ErrNoAddress
+		}
+		return nil
+	}).
+	Add(Paid, Cancel, Refunded).
+	Add(Shipped, Deliver, Delivered).
+	AddIf(Delivered, Return, Refunded, "within 30 days", func(o *Order) error {
+		if o.now().Sub(o.DeliveredAt) > ReturnWindow {
+			return ErrWindowExpired
+		}
+		return nil
+	}).
+	OnEnter(Paid, func(o *Order, _ Transition[Status, Event]) { o.PaidAt = o.now() }).
+	OnEnter(Shipped, func(o *Order, _ Transition[Status, Event]) { o.ShippedAt = o.now() }).
+	OnEnter(Delivered, func(o *Order, _ Transition[Status, Event]) { o.DeliveredAt = o.now() }).
+	OnEnter(Refunded, func(o *Order, _ Transition[Status, Event]) { o.RefundedCents = o.TotalCents }).
+	OnTransition(func(o *Order, tr Transition[Status, Event]) {
+		o.History = append(o.History, fmt.Sprintf("%v: %v -> %v", tr.Event, tr.From, tr.To))
+	})
@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"slices"
+)
+
+// Dijkstra - Shortest Weighted Paths
+// ==================================
+// Dijkstra's algorithm grows a set of nodes whose shortest distance from
+// the source is final. It always settles the unsettled node with the
+// smallest tentative distance next, then relaxes its edges: if going
+// through it is shorter, a neighbour's tentative distance drops.
+//
+// With a binary heap that costs O((V + E) log V). container/heap has no
+// "decrease key" without tracking positions, so this version pushes a
+// new entry when a distance drops and skips stale entries when popped -
+// simpler, and the heap grows to at most E entries.
+//
+// The greedy choice is only correct if edges never get cheaper further
+// along a path, so negative weights are rejected.
+
+var (
+	ErrNoPath         = errors.New("no path")
+	ErrNegativeWeight = errors.New("negative edge weight")
+)
+
+// ShortestPaths returns the shortest distance from source to every node
+// reachable from it, and for each the previous node on that path. It
+// returns ErrNegativeWeight if a reachable edge has a negative weight.
+func (g *Graph[T]) ShortestPaths(source T) (dist map[T]float64, prev map[T]T, err error) {
+	s, ok := g.index[source]
+	if !ok {
+		return nil, nil, ErrNoPath
+	}
+	d, p, err := g.dijkstra(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dist = make(map[T]float64)
+	prev = make(map[T]T)
+	for v := range g.nodes {
+		if math.IsInf(d[v], 1) {
+			continue
+		}
+		dist[g.nodes[v]] = d[v]
+		if p[v] >= 0 {
+			prev[g.nodes[v]] = g.nodes[p[v]]
+		}
+	}
+	return dist, prev, nil
+}
+
+// ShortestPath returns the cheapest path from source to target, both
+// included, and its total weight. It returns ErrNoPath if target cannot
+// be reached.
+func (g *Graph[T]) ShortestPath(source, target T) ([]T, float64, error) {
+	s, ok := g.index[source]
+	t, ok2 := g.index[target]
+	if !ok || !ok2 {
+		return nil, 0, ErrNoPath
+	}
+	d, p, err := g.dijkstra(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	if math.IsInf(d[t], 1) {
+		return nil, 0, ErrNoPath
+	}
+
+	var path []T
+	for v := t; v >= 0; v = p[v] {
+		path = append(path, g.nodes[v])
+	}
+	slices.Reverse(path)
+	return path, d[t], nil
+}
+
+// dijkstra returns distances and predecessors by node index. Unreached
+// nodes have distance +Inf and predecessor -1.
+func (g *Graph[T]) dijkstra(s int) ([]float64, []int, error) {
+	dist := make([]float64, len(g.nodes))
+	prev := make([]int, len(g.nodes))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	dist[s] = 0
+
+	settled := make([]bool, len(g.nodes))
+	pq := &distHeap{{node: s, dist: 0}}
+	for pq.Len() > 0 {
+		cur := heap.Pop(pq).(distEntry)
+		if settled[cur.node] {
+			continue // stale: a shorter entry was already popped
+		}
+		settled[cur.node] = true
+		for _, e := range g.adj[cur.node] {
+			if e.weight < 0 {
+				return nil, nil, ErrNegativeWeight
+			}
+			if nd := cur.dist + e.weight; nd < dist[e.to] {
+				dist[e.to] = nd
+				prev[e.to] = cur.node
+				heap.Push(pq, distEntry{node: e.to, dist: nd})
+			}
+		}
+	}
+	return dist, prev, nil
+}
+
+// distEntry is a tentative distance waiting in the heap
+type distEntry struct {
+	node int
+	dist float64
+}
+
+type distHeap []distEntry
+
+func (h distHeap) Len() int { return len(h) }
+func (h distHeap) Less(i, j int) bool {
+	if h[i].dist != h[j].dist {
+		return h[i].dist < h[j].dist
+	}
+	return h[i].node < h[j].node // deterministic ties
+}
+func (h distHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *distHeap) Push(x any)   { *h = append(*h, x.(distEntry)) }
+func (h *distHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
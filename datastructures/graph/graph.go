@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"iter"
+	"slices"
+)
+
+// Graph - Adjacency Lists With Generics
+// =====================================
+// A graph is a set of nodes and the edges between them. The adjacency
+// list stores, for each node, the edges leaving it: memory grows with
+// nodes + edges, and visiting a node's neighbours costs only as many
+// steps as it has. (An adjacency matrix answers "is there an edge?" in
+// O(1) but needs nodes² cells, which only pays off for dense graphs.)
+//
+// Nodes can be any comparable type - strings, ints, small structs. Each
+// node is given a dense index when first seen, so the algorithms work
+// on slices rather than maps, and nodes are always visited in the order
+// they were added. That makes every traversal deterministic, which map
+// iteration would not be.
+
+// Edge is a connection to another node
+type Edge[T comparable] struct {
+	To     T
+	Weight float64
+}
+
+// Graph is a directed or undirected graph with weighted edges. The zero
+// value is not usable; create one with NewDirected or NewUndirected.
+type Graph[T comparable] struct {
+	directed bool
+	index    map[T]int
+	nodes    []T
+	adj      [][]edge // by node index
+}
+
+// edge is Edge with the destination as an index
+type edge struct {
+	to     int
+	weight float64
+}
+
+// NewDirected returns an empty graph whose edges run one way
+func NewDirected[T comparable]() *Graph[T] {
+	return &Graph[T]{directed: true, index: make(map[T]int)}
+}
+
+// NewUndirected returns an empty graph whose edges run both ways
+func NewUndirected[T comparable]() *Graph[T] {
+	return &Graph[T]{index: make(map[T]int)}
+}
+
+// AddNode adds v if it is not already present
+func (g *Graph[T]) AddNode(v T) {
+	g.id(v)
+}
+
+// AddEdge adds an edge of weight 1, adding either node if needed
+func (g *Graph[T]) AddEdge(from, to T) {
+	g.AddWeightedEdge(from, to, 1)
+}
+
+// AddWeightedEdge adds an edge with the given weight, adding either node
+// if needed. In an undirected graph it also adds the reverse edge.
+// Adding the same edge twice gives parallel edges.
+func (g *Graph[T]) AddWeightedEdge(from, to T, weight float64) {
+	f, t := g.id(from), g.id(to)
+	g.adj[f] = append(g.adj[f], edge{t, weight})
+	if !g.directed && f != t {
+		g.adj[t] = append(g.adj[t], edge{f, weight})
+	}
+}
+
+// Directed reports whether edges run one way
+func (g *Graph[T]) Directed() bool { return g.directed }
+
+// Len returns the number of nodes
+func (g *Graph[T]) Len() int { return len(g.nodes) }
+
+// Has reports whether v is a node
+func (g *Graph[T]) Has(v T) bool {
+	_, ok := g.index[v]
+	return ok
+}
+
+// Nodes yields the nodes in the order they were added
+func (g *Graph[T]) Nodes() iter.Seq[T] {
+	return slices.Values(g.nodes)
+}
+
+// Edges returns the edges leaving v, in the order they were added
+func (g *Graph[T]) Edges(v T) []Edge[T] {
+	i, ok := g.index[v]
+	if !ok {
+		return nil
+	}
+	out := make([]Edge[T], len(g.adj[i]))
+	for j, e := range g.adj[i] {
+		out[j] = Edge[T]{To: g.nodes[e.to], Weight: e.weight}
+	}
+	return out
+}
+
+// id returns v's index, adding v if it is new
+func (g *Graph[T]) id(v T) int {
+	if i, ok := g.index[v]; ok {
+		return i
+	}
+	i := len(g.nodes)
+	g.index[v] = i
+	g.nodes = append(g.nodes, v)
+	g.adj = append(g.adj, nil)
+	return i
+}
@@ -0,0 +1,472 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// Graph Algorithms - Tests and Benchmarks
+// =======================================
+// Run with:
+//
+//   cd datastructures/graph
+//   go test -v *.go
+//   go test -bench . *.go
+
+// collect drains an iterator, stopping after limit values if limit > 0
+func collect[T any](seq iter.Seq[T], limit int) []T {
+	var out []T
+	for v := range seq {
+		out = append(out, v)
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+// randomDAG returns a directed acyclic graph: edges only run from a
+// lower to a higher number, but nodes are added in shuffled order so the
+// insertion order gives no hint
+func randomDAG(r *rand.Rand, n, edges int) *Graph[int] {
+	g := NewDirected[int]()
+	for _, v := range r.Perm(n) {
+		g.AddNode(v)
+	}
+	for range edges {
+		a, b := r.IntN(n), r.IntN(n)
+		if a != b {
+			g.AddEdge(min(a, b), max(a, b))
+		}
+	}
+	return g
+}
+
+// 1. Building a Graph
+// ===================
+
+func TestBuild(t *testing.T) {
+	g := NewUndirected[string]()
+	g.AddEdge("a", "b")
+	g.AddWeightedEdge("b", "c", 2.5)
+	g.AddNode("lonely")
+	g.AddNode("a") // already present: no-op
+
+	if g.Len() != 4 || !g.Has("lonely") || g.Has("z") {
+		t.Errorf("Len = %d, nodes = %v", g.Len(), collect(g.Nodes(), 0))
+	}
+	if got := collect(g.Nodes(), 0); !slices.Equal(got, []string{"a", "b", "c", "lonely"}) {
+		t.Errorf("Nodes = %v, want insertion order", got)
+	}
+	// Undirected: every edge is stored from both ends
+	want := []Edge[string]{{"a", 1}, {"c", 2.5}}
+	if got := g.Edges("b"); !slices.Equal(got, want) {
+		t.Errorf("Edges(b) = %v, want %v", got, want)
+	}
+	if got := g.Edges("c"); !slices.Equal(got, []Edge[string]{{"b", 2.5}}) {
+		t.Errorf("Edges(c) = %v", got)
+	}
+	if g.Edges("z") != nil {
+		t.Error("Edges of a missing node should be nil")
+	}
+
+	d := NewDirected[string]()
+	d.AddEdge("a", "b")
+	if len(d.Edges("b")) != 0 {
+		t.Error("a directed edge is stored only at its source")
+	}
+}
+
+func TestStructNodes(t *testing.T) {
+	// Any comparable type works as a node, here grid coordinates
+	type cell struct{ r, c int }
+	g := NewUndirected[cell]()
+	g.AddEdge(cell{0, 0}, cell{0, 1})
+	g.AddEdge(cell{0, 1}, cell{1, 1})
+	if h := g.Hops(cell{0, 0}); h[cell{1, 1}] != 2 {
+		t.Errorf("Hops = %v", h)
+	}
+}
+
+// 2. BFS and DFS
+// ==============
+
+// tree returns
+//
+//	    a
+//	  / | \
+//	 b  c  d
+//	/ \    |
+//	e  f   g
+func tree() *Graph[string] {
+	g := NewDirected[string]()
+	for _, e := range [][2]string{{"a", "b"}, {"a", "c"}, {"a", "d"}, {"b", "e"}, {"b", "f"}, {"d", "g"}} {
+		g.AddEdge(e[0], e[1])
+	}
+	return g
+}
+
+func TestBFS(t *testing.T) {
+	// Level by level
+	if got := collect(tree().BFS("a"), 0); !slices.Equal(got, []string{"a", "b", "c", "d", "e", "f", "g"}) {
+		t.Errorf("BFS = %v", got)
+	}
+	// Only what is reachable, following edge direction
+	if got := collect(tree().BFS("d"), 0); !slices.Equal(got, []string{"d", "g"}) {
+		t.Errorf("BFS(d) = %v", got)
+	}
+	if got := collect(tree().BFS("missing"), 0); got != nil {
+		t.Errorf("BFS(missing) = %v", got)
+	}
+}
+
+func TestDFS(t *testing.T) {
+	// One branch to the bottom before the next
+	if got := collect(tree().DFS("a"), 0); !slices.Equal(got, []string{"a", "b", "e", "f", "c", "d", "g"}) {
+		t.Errorf("DFS = %v", got)
+	}
+
+	// A cycle is visited once per node
+	g := NewDirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 3)
+	g.AddEdge(3, 1)
+	g.AddEdge(1, 3)
+	if got := collect(g.DFS(1), 0); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("DFS over a cycle = %v", got)
+	}
+}
+
+func TestEarlyStop(t *testing.T) {
+	// break ends the search: the rest of the 1,000,000-node chain is
+	// never explored
+	g := NewDirected[int]()
+	for i := range 1_000_000 {
+		g.AddEdge(i, i+1)
+	}
+	if got := collect(g.BFS(0), 3); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("BFS first 3 = %v", got)
+	}
+	if got := collect(g.DFS(0), 3); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("DFS first 3 = %v", got)
+	}
+
+	// The explicit stack handles a path a million deep
+	n := 0
+	for range g.DFS(0) {
+		n++
+	}
+	if n != 1_000_001 {
+		t.Errorf("DFS visited %d nodes", n)
+	}
+}
+
+func TestHops(t *testing.T) {
+	want := map[string]int{"a": 0, "b": 1, "c": 1, "d": 1, "e": 2, "f": 2, "g": 2}
+	got := tree().Hops("a")
+	if len(got) != len(want) {
+		t.Fatalf("Hops = %v", got)
+	}
+	for v, h := range want {
+		if got[v] != h {
+			t.Errorf("Hops[%s] = %d, want %d", v, got[v], h)
+		}
+	}
+}
+
+// 3. Topological Sort
+// ===================
+
+func TestTopoSortOrdersEveryEdge(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		g := randomDAG(r, 200, 600)
+		order, err := g.TopoSort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(order) != g.Len() {
+			t.Fatalf("order has %d nodes, graph %d", len(order), g.Len())
+		}
+		pos := make(map[int]int)
+		for i, v := range order {
+			pos[v] = i
+		}
+		for v := range g.Nodes() {
+			for _, e := range g.Edges(v) {
+				if pos[v] >= pos[e.To] {
+					t.Fatalf("edge %d -> %d points backwards in %v", v, e.To, order)
+				}
+			}
+		}
+	}
+}
+
+func TestTopoSortKeepsInsertionOrderWhenFree(t *testing.T) {
+	// With no edges, nothing constrains the order, so it is the order
+	// the nodes were added. One edge moves only what it must.
+	g := NewDirected[string]()
+	for _, v := range []string{"d", "c", "b", "a"} {
+		g.AddNode(v)
+	}
+	g.AddEdge("a", "c")
+	got, _ := g.TopoSort()
+	if !slices.Equal(got, []string{"d", "b", "a", "c"}) {
+		t.Errorf("TopoSort = %v", got)
+	}
+}
+
+func TestTopoSortCycle(t *testing.T) {
+	g := NewDirected[string]()
+	g.AddEdge("start", "a")
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "c")
+	g.AddEdge("c", "a") // a -> b -> c -> a
+	g.AddEdge("c", "end")
+
+	_, err := g.TopoSort()
+	var cycleErr *CycleError[string]
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("err = %v, want *CycleError", err)
+	}
+	// The cycle can start at any of its nodes; rotate it to start at a
+	c := cycleErr.Cycle
+	i := slices.Index(c, "a")
+	if i < 0 || !slices.Equal(append(c[i:], c[:i]...), []string{"a", "b", "c"}) {
+		t.Errorf("Cycle = %v, want a rotation of [a b c]", c)
+	}
+	t.Log(err)
+
+	self := NewDirected[int]()
+	self.AddEdge(1, 1)
+	if _, err := self.TopoSort(); err == nil || err.Error() != "cycle: 1 -> 1" {
+		t.Errorf("self-loop: err = %v", err)
+	}
+}
+
+func TestTopoSortUndirected(t *testing.T) {
+	g := NewUndirected[int]()
+	g.AddEdge(1, 2)
+	if _, err := g.TopoSort(); !errors.Is(err, ErrUndirected) {
+		t.Errorf("err = %v, want ErrUndirected", err)
+	}
+}
+
+// 4. Dijkstra
+// ===========
+
+func TestShortestPath(t *testing.T) {
+	// The direct road is not the cheapest route
+	g := NewUndirected[string]()
+	g.AddWeightedEdge("home", "work", 10)
+	g.AddWeightedEdge("home", "cafe", 2)
+	g.AddWeightedEdge("cafe", "park", 3)
+	g.AddWeightedEdge("park", "work", 4)
+	g.AddWeightedEdge("cafe", "work", 8)
+	g.AddNode("island")
+
+	path, cost, err := g.ShortestPath("home", "work")
+	if err != nil || cost != 9 || !slices.Equal(path, []string{"home", "cafe", "park", "work"}) {
+		t.Errorf("ShortestPath = %v, %g, %v", path, cost, err)
+	}
+	if path, cost, err := g.ShortestPath("home", "home"); err != nil || cost != 0 || !slices.Equal(path, []string{"home"}) {
+		t.Errorf("path to self = %v, %g, %v", path, cost, err)
+	}
+	if _, _, err := g.ShortestPath("home", "island"); !errors.Is(err, ErrNoPath) {
+		t.Errorf("unreachable: err = %v", err)
+	}
+	if _, _, err := g.ShortestPath("home", "nowhere"); !errors.Is(err, ErrNoPath) {
+		t.Errorf("missing node: err = %v", err)
+	}
+
+	dist, prev, _ := g.ShortestPaths("home")
+	if _, ok := dist["island"]; ok || dist["park"] != 5 || prev["park"] != "cafe" {
+		t.Errorf("ShortestPaths = %v, %v", dist, prev)
+	}
+}
+
+func TestNegativeWeight(t *testing.T) {
+	g := NewDirected[string]()
+	g.AddWeightedEdge("a", "b", 1)
+	g.AddWeightedEdge("b", "c", -5)
+	if _, _, err := g.ShortestPath("a", "c"); !errors.Is(err, ErrNegativeWeight) {
+		t.Errorf("err = %v, want ErrNegativeWeight", err)
+	}
+}
+
+func TestDijkstraMatchesBellmanFord(t *testing.T) {
+	// Bellman-Ford relaxes every edge V-1 times: slow, but too simple to
+	// get wrong. Both must agree on random graphs.
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 20 {
+		const n = 60
+		g := NewDirected[int]()
+		for range 300 {
+			g.AddWeightedEdge(r.IntN(n), r.IntN(n), float64(r.IntN(100)))
+		}
+
+		want := map[int]float64{0: 0}
+		if !g.Has(0) {
+			continue
+		}
+		for range g.Len() - 1 {
+			for v := range g.Nodes() {
+				dv, ok := want[v]
+				if !ok {
+					continue
+				}
+				for _, e := range g.Edges(v) {
+					if d, ok := want[e.To]; !ok || dv+e.Weight < d {
+						want[e.To] = dv + e.Weight
+					}
+				}
+			}
+		}
+
+		got, _, err := g.ShortestPaths(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("reached %d nodes, want %d", len(got), len(want))
+		}
+		for v, d := range want {
+			if got[v] != d {
+				t.Fatalf("dist[%d] = %g, want %g", v, got[v], d)
+			}
+		}
+	}
+}
+
+// 5. Benchmarks
+// =============
+
+func grid(n int) *Graph[int] {
+	g := NewUndirected[int]()
+	for r := range n {
+		for c := range n {
+			if c+1 < n {
+				g.AddEdge(r*n+c, r*n+c+1)
+			}
+			if r+1 < n {
+				g.AddEdge(r*n+c, (r+1)*n+c)
+			}
+		}
+	}
+	return g
+}
+
+func BenchmarkBFSGrid(b *testing.B) {
+	g := grid(300) // 90,000 nodes
+	for b.Loop() {
+		for range g.BFS(0) {
+		}
+	}
+}
+
+func BenchmarkDFSGrid(b *testing.B) {
+	g := grid(300)
+	for b.Loop() {
+		for range g.DFS(0) {
+		}
+	}
+}
+
+func BenchmarkTopoSort(b *testing.B) {
+	g := randomDAG(rand.New(rand.NewPCG(5, 6)), 10_000, 50_000)
+	for b.Loop() {
+		g.TopoSort()
+	}
+}
+
+func BenchmarkDijkstra(b *testing.B) {
+	r := rand.New(rand.NewPCG(7, 8))
+	g := NewDirected[int]()
+	for range 50_000 {
+		g.AddWeightedEdge(r.IntN(10_000), r.IntN(10_000), r.Float64()*100)
+	}
+	for b.Loop() {
+		g.ShortestPath(0, 9_999)
+	}
+}
+
+// Examples
+// ========
+
+func ExampleGraph_TopoSort() {
+	// The repository's topics and their prerequisites: an edge a -> b
+	// means "learn a before b". Nodes are added in README order, so the
+	// result follows it wherever the prerequisites allow.
+	topics := NewDirected[string]()
+	for _, t := range []string{
+		"primitives", "structs", "pointers", "functions", "advanced-concepts",
+		"memory-model", "strings-bytes", "slices-maps", "serialization",
+		"testing", "datastructures", "tools",
+	} {
+		topics.AddNode(t)
+	}
+	for _, dep := range [][2]string{
+		{"primitives", "structs"},
+		{"structs", "pointers"},
+		{"primitives", "functions"},
+		{"functions", "advanced-concepts"},
+		{"pointers", "advanced-concepts"},
+		{"advanced-concepts", "memory-model"},
+		{"primitives", "strings-bytes"},
+		{"functions", "slices-maps"},
+		{"structs", "serialization"},
+		{"strings-bytes", "serialization"},
+		{"functions", "testing"},
+		{"slices-maps", "datastructures"},
+		{"testing", "datastructures"},
+		{"advanced-concepts", "tools"},
+		{"testing", "tools"},
+	} {
+		topics.AddEdge(dep[0], dep[1])
+	}
+
+	order, err := topics.TopoSort()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i, t := range order {
+		fmt.Printf("%2d. %s\n", i+1, t)
+	}
+
+	// Adding a backwards prerequisite is caught
+	topics.AddEdge("memory-model", "primitives")
+	_, err = topics.TopoSort()
+	fmt.Println(err)
+	// Output:
+	//  1. primitives
+	//  2. structs
+	//  3. pointers
+	//  4. functions
+	//  5. advanced-concepts
+	//  6. memory-model
+	//  7. strings-bytes
+	//  8. slices-maps
+	//  9. serialization
+	// 10. testing
+	// 11. datastructures
+	// 12. tools
+	// cycle: primitives -> functions -> advanced-concepts -> memory-model -> primitives
+}
+
+func ExampleGraph_ShortestPath() {
+	g := NewUndirected[string]()
+	g.AddWeightedEdge("A", "B", 4)
+	g.AddWeightedEdge("A", "C", 1)
+	g.AddWeightedEdge("C", "B", 2)
+	g.AddWeightedEdge("B", "D", 5)
+
+	path, cost, _ := g.ShortestPath("A", "D")
+	fmt.Println(path, cost)
+	// Output:
+	// [A C B D] 8
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Topological Sort - Ordering by Dependencies
+// ===========================================
+// In a directed graph where an edge a -> b means "a comes before b", a
+// topological order lists every node after all of its predecessors:
+// build steps, package imports, or lessons and their prerequisites.
+//
+// Kahn's algorithm counts each node's incoming edges, then repeatedly
+// takes a node with none left and removes its outgoing edges. If nodes
+// remain with incoming edges when nothing is ready, they lie on a cycle
+// and no order exists. Ready nodes are taken lowest index first, so the
+// result stays as close to insertion order as the edges allow.
+
+// ErrUndirected is returned by algorithms that need edge direction
+var ErrUndirected = errors.New("graph is undirected")
+
+// CycleError reports a cycle that prevents a topological order
+type CycleError[T comparable] struct {
+	Cycle []T // each node has an edge to the next; the last to the first
+}
+
+func (e *CycleError[T]) Error() string {
+	parts := make([]string, len(e.Cycle)+1)
+	for i, v := range e.Cycle {
+		parts[i] = fmt.Sprint(v)
+	}
+	parts[len(e.Cycle)] = parts[0]
+	return "cycle: " + strings.Join(parts, " -> ")
+}
+
+// TopoSort returns the nodes ordered so that every edge points forward.
+// It returns ErrUndirected for an undirected graph and a *CycleError if
+// the edges form a cycle.
+func (g *Graph[T]) TopoSort() ([]T, error) {
+	if !g.directed {
+		return nil, ErrUndirected
+	}
+	indegree := make([]int, len(g.nodes))
+	for _, edges := range g.adj {
+		for _, e := range edges {
+			indegree[e.to]++
+		}
+	}
+
+	ready := &minInts{}
+	for v, d := range indegree {
+		if d == 0 {
+			heap.Push(ready, v)
+		}
+	}
+	order := make([]T, 0, len(g.nodes))
+	for ready.Len() > 0 {
+		v := heap.Pop(ready).(int)
+		order = append(order, g.nodes[v])
+		for _, e := range g.adj[v] {
+			indegree[e.to]--
+			if indegree[e.to] == 0 {
+				heap.Push(ready, e.to)
+			}
+		}
+	}
+
+	if len(order) < len(g.nodes) {
+		return nil, &CycleError[T]{Cycle: g.findCycle(indegree)}
+	}
+	return order, nil
+}
+
+// findCycle returns one cycle among the nodes Kahn's algorithm could not
+// place (those with indegree > 0). Every such node has a predecessor
+// that is also unplaced, so walking predecessors backwards must revisit
+// a node; the walk from there is the cycle.
+func (g *Graph[T]) findCycle(indegree []int) []T {
+	// pred[v] is some unplaced node with an edge to v
+	pred := make([]int, len(g.nodes))
+	start := -1
+	for v, edges := range g.adj {
+		if indegree[v] == 0 {
+			continue
+		}
+		start = v
+		for _, e := range edges {
+			if indegree[e.to] > 0 {
+				pred[e.to] = v
+			}
+		}
+	}
+
+	// Walk back len(nodes) steps to be sure of standing on the cycle
+	v := start
+	for range len(g.nodes) {
+		v = pred[v]
+	}
+	cycle := []int{v}
+	for u := pred[v]; u != v; u = pred[u] {
+		cycle = append(cycle, u)
+	}
+
+	// The walk went backwards; reverse it to follow the edges
+	out := make([]T, len(cycle))
+	for i, u := range cycle {
+		out[len(cycle)-1-i] = g.nodes[u]
+	}
+	return out
+}
+
+// minInts is a min-heap of node indexes for container/heap
+type minInts []int
+
+func (h minInts) Len() int           { return len(h) }
+func (h minInts) Less(i, j int) bool { return h[i] < h[j] }
+func (h minInts) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minInts) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *minInts) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
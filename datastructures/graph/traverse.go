@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"iter"
+)
+
+// Traversal - BFS and DFS as Iterators
+// ====================================
+// Both searches visit every node reachable from a start node once. They
+// differ only in which discovered node they visit next:
+//
+//   - breadth-first takes the oldest (a queue), so nodes come out in
+//     order of hop count - the basis for unweighted shortest paths
+//   - depth-first takes the newest (a stack), following one path as far
+//     as it goes before backing up - the basis for cycle detection and
+//     topological sorting
+//
+// Returning iter.Seq lets the caller stop early with break: the search
+// does no more work than the loop asks for.
+
+// BFS yields the nodes reachable from start in breadth-first order.
+// Yields nothing if start is not in the graph.
+func (g *Graph[T]) BFS(start T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		s, ok := g.index[start]
+		if !ok {
+			return
+		}
+		seen := make([]bool, len(g.nodes))
+		seen[s] = true
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			if !yield(g.nodes[v]) {
+				return
+			}
+			for _, e := range g.adj[v] {
+				if !seen[e.to] {
+					seen[e.to] = true
+					queue = append(queue, e.to)
+				}
+			}
+		}
+	}
+}
+
+// DFS yields the nodes reachable from start in depth-first preorder,
+// taking edges in the order they were added. Yields nothing if start is
+// not in the graph.
+//
+// It uses an explicit stack rather than recursion, so a long chain of
+// nodes cannot exhaust the goroutine stack.
+func (g *Graph[T]) DFS(start T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		s, ok := g.index[start]
+		if !ok {
+			return
+		}
+		seen := make([]bool, len(g.nodes))
+		stack := []int{s}
+		for len(stack) > 0 {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if seen[v] {
+				continue // pushed twice before it was visited
+			}
+			seen[v] = true
+			if !yield(g.nodes[v]) {
+				return
+			}
+			// Push in reverse so the first edge is popped first
+			for i := len(g.adj[v]) - 1; i >= 0; i-- {
+				if to := g.adj[v][i].to; !seen[to] {
+					stack = append(stack, to)
+				}
+			}
+		}
+	}
+}
+
+// Hops returns the number of edges on the shortest path from start to
+// every node reachable from it
+func (g *Graph[T]) Hops(start T) map[T]int {
+	s, ok := g.index[start]
+	if !ok {
+		return nil
+	}
+	dist := make([]int, len(g.nodes))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[s] = 0
+	queue := []int{s}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, e := range g.adj[v] {
+			if dist[e.to] < 0 {
+				dist[e.to] = dist[v] + 1
+				queue = append(queue, e.to)
+			}
+		}
+	}
+
+	out := make(map[T]int)
+	for i, d := range dist {
+		if d >= 0 {
+			out[g.nodes[i]] = d
+		}
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package skiplist
+
+import (
+	"cmp"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// Skip List - An Ordered Map Built From Coin Flips
+// ================================================
+// A skip list is a sorted linked list with express lanes. Every node is
+// on level 0; each node is also on level 1 with probability p, on level
+// 2 with probability p², and so on. A search starts on the highest lane
+// and drops a level whenever the next node would overshoot, so it skips
+// most of the list and takes O(log n) steps on average.
+//
+// Compared with a balanced tree (AVL, red-black) it gives the same
+// expected bounds with no rotations: inserting is "find the spot, flip
+// coins for the height, splice in one pointer per level". Level 0 is a
+// plain sorted list, so in-order iteration and range scans just follow
+// next pointers.
+//
+// With p = 1/4 a node has 1.33 forward pointers on average, and a search
+// looks at about (1/p)·log₄ n nodes.
+
+const (
+	maxLevel = 32 // enough for 4^32 entries at p = 1/4
+)
+
+// node is one entry. next[i] is the following node on level i; a node of
+// height h has len(next) == h.
+type node[K, V any] struct {
+	key   K
+	value V
+	next  []*node[K, V]
+}
+
+// SkipList is an ordered map from K to V. It is not safe for concurrent
+// use. The zero value is not usable; create one with New or NewFunc.
+type SkipList[K, V any] struct {
+	head  node[K, V] // sentinel; head.next has maxLevel lanes
+	level int        // lanes in use: 1 + height of the tallest node
+	len   int
+	cmp   func(a, b K) int
+	rng   *rand.Rand
+}
+
+// New returns an empty skip list for an ordered key type
+func New[K cmp.Ordered, V any]() *SkipList[K, V] {
+	return NewFunc[K, V](cmp.Compare[K])
+}
+
+// NewFunc returns an empty skip list ordered by compare, which returns
+// a negative number, zero or a positive number as a < b, a == b or a > b
+func NewFunc[K, V any](compare func(a, b K) int) *SkipList[K, V] {
+	s := &SkipList[K, V]{
+		level: 1,
+		cmp:   compare,
+		rng:   rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+	s.head.next = make([]*node[K, V], maxLevel)
+	return s
+}
+
+// Len returns the number of entries
+func (s *SkipList[K, V]) Len() int { return s.len }
+
+// Get returns the value for key
+func (s *SkipList[K, V]) Get(key K) (value V, ok bool) {
+	x := &s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.next[i]; next != nil && s.cmp(next.key, key) < 0; next = x.next[i] {
+			x = next
+		}
+	}
+	if x = x.next[0]; x != nil && s.cmp(x.key, key) == 0 {
+		return x.value, true
+	}
+	return value, false
+}
+
+// Set adds or replaces the value for key and reports whether key was new
+func (s *SkipList[K, V]) Set(key K, value V) bool {
+	var update [maxLevel]*node[K, V]
+	x := s.predecessors(key, &update)
+	if x = x.next[0]; x != nil && s.cmp(x.key, key) == 0 {
+		x.value = value
+		return false
+	}
+
+	h := s.randomHeight()
+	if h > s.level {
+		for i := s.level; i < h; i++ {
+			update[i] = &s.head
+		}
+		s.level = h
+	}
+	n := &node[K, V]{key: key, value: value, next: make([]*node[K, V], h)}
+	for i := range h {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+	s.len++
+	return true
+}
+
+// Delete removes key and reports whether it was present
+func (s *SkipList[K, V]) Delete(key K) bool {
+	var update [maxLevel]*node[K, V]
+	x := s.predecessors(key, &update).next[0]
+	if x == nil || s.cmp(x.key, key) != 0 {
+		return false
+	}
+	for i := range x.next {
+		update[i].next[i] = x.next[i]
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+	s.len--
+	return true
+}
+
+// All yields the entries in ascending key order. The list must not be
+// modified during the iteration.
+func (s *SkipList[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for x := s.head.next[0]; x != nil; x = x.next[0] {
+			if !yield(x.key, x.value) {
+				return
+			}
+		}
+	}
+}
+
+// Range yields the entries with lo <= key < hi in ascending order. It
+// finds lo in O(log n) and then walks level 0.
+func (s *SkipList[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var update [maxLevel]*node[K, V]
+		for x := s.predecessors(lo, &update).next[0]; x != nil && s.cmp(x.key, hi) < 0; x = x.next[0] {
+			if !yield(x.key, x.value) {
+				return
+			}
+		}
+	}
+}
+
+// Min returns the smallest key and its value
+func (s *SkipList[K, V]) Min() (key K, value V, ok bool) {
+	if x := s.head.next[0]; x != nil {
+		return x.key, x.value, true
+	}
+	return key, value, false
+}
+
+// predecessors fills update[i] with the last node on level i whose key
+// is less than key, and returns the one on level 0
+func (s *SkipList[K, V]) predecessors(key K, update *[maxLevel]*node[K, V]) *node[K, V] {
+	x := &s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.next[i]; next != nil && s.cmp(next.key, key) < 0; next = x.next[i] {
+			x = next
+		}
+		update[i] = x
+	}
+	return x
+}
+
+// randomHeight returns h with probability (3/4)·(1/4)^(h-1). Each pair
+// of random bits is a coin with p = 1/4 of both being zero, so counting
+// trailing zero pairs flips 32 coins at once.
+func (s *SkipList[K, V]) randomHeight() int {
+	h := 1 + bits.TrailingZeros64(s.rng.Uint64())/2
+	return min(h, maxLevel)
+}
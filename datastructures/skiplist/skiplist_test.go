@@ -0,0 +1,418 @@
+package skiplist
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Skip List - Tests and Benchmarks Against Slices and Maps
+// ========================================================
+// Run with:
+//
+//   cd datastructures/skiplist
+//   go test -v *.go
+//   go test -bench . *.go
+
+func keys[K, V any](s *SkipList[K, V]) []K {
+	var out []K
+	for k := range s.All() {
+		out = append(out, k)
+	}
+	return out
+}
+
+// checkLevels verifies the structure: every level is sorted, and each
+// level is a subsequence of the one below
+func checkLevels[K, V any](t *testing.T, s *SkipList[K, V]) {
+	t.Helper()
+	for i := range s.level {
+		n := 0
+		for x := s.head.next[i]; x != nil; x = x.next[i] {
+			if y := x.next[i]; y != nil && s.cmp(x.key, y.key) >= 0 {
+				t.Fatalf("level %d out of order at %v, %v", i, x.key, y.key)
+			}
+			if len(x.next) <= i {
+				t.Fatalf("node %v of height %d linked on level %d", x.key, len(x.next), i)
+			}
+			n++
+		}
+		if i == 0 && n != s.len {
+			t.Fatalf("level 0 has %d nodes, Len = %d", n, s.len)
+		}
+	}
+	for i := s.level; i < maxLevel; i++ {
+		if s.head.next[i] != nil {
+			t.Fatalf("lane %d is above level %d but not empty", i, s.level)
+		}
+	}
+}
+
+// 1. Basic Operations
+// ===================
+
+func TestSetGetDelete(t *testing.T) {
+	s := New[string, int]()
+	for i, k := range []string{"m", "c", "x", "a", "q"} {
+		if !s.Set(k, i) {
+			t.Errorf("Set(%q) should be new", k)
+		}
+	}
+	if s.Set("c", 100) {
+		t.Error("Set on an existing key should report false")
+	}
+	if v, ok := s.Get("c"); !ok || v != 100 {
+		t.Errorf("Get(c) = %d, %t", v, ok)
+	}
+	if _, ok := s.Get("b"); ok {
+		t.Error("Get(b) should miss")
+	}
+	if got := keys(s); !slices.Equal(got, []string{"a", "c", "m", "q", "x"}) {
+		t.Errorf("keys = %v, want sorted", got)
+	}
+
+	if !s.Delete("m") || s.Delete("m") || s.Len() != 4 {
+		t.Error("Delete should remove once and report it")
+	}
+	if k, _, ok := s.Min(); !ok || k != "a" {
+		t.Errorf("Min = %q", k)
+	}
+	checkLevels(t, s)
+}
+
+func TestEmpty(t *testing.T) {
+	s := New[int, int]()
+	if _, _, ok := s.Min(); ok || s.Len() != 0 || keys(s) != nil {
+		t.Error("a new list is empty")
+	}
+	if s.Delete(1) {
+		t.Error("Delete on an empty list")
+	}
+	for range s.Range(0, 10) {
+		t.Error("Range over an empty list yielded")
+	}
+}
+
+func TestAgainstModel(t *testing.T) {
+	// Random operations, checked against a map and a sort after each
+	s := New[int, int]()
+	model := map[int]int{}
+	r := rand.New(rand.NewPCG(1, 2))
+	for step := range 20_000 {
+		k := r.IntN(500)
+		switch r.IntN(3) {
+		case 0, 1:
+			_, had := model[k]
+			if s.Set(k, step) == had {
+				t.Fatalf("step %d: Set(%d) new = %t, model had it = %t", step, k, !had, had)
+			}
+			model[k] = step
+		case 2:
+			_, had := model[k]
+			if s.Delete(k) != had {
+				t.Fatalf("step %d: Delete(%d) disagrees with model", step, k)
+			}
+			delete(model, k)
+		}
+		want, inModel := model[k]
+		if v, ok := s.Get(k); ok != inModel || v != want {
+			t.Fatalf("step %d: Get(%d) = %d, %t; model %d, %t", step, k, v, ok, want, inModel)
+		}
+	}
+	if got, want := keys(s), slices.Sorted(maps.Keys(model)); !slices.Equal(got, want) {
+		t.Fatalf("keys differ from the sorted model")
+	}
+	for k, v := range s.All() {
+		if model[k] != v {
+			t.Fatalf("value for %d = %d, model %d", k, v, model[k])
+		}
+	}
+	checkLevels(t, s)
+}
+
+// 2. Ordered Iteration and Ranges
+// ===============================
+
+func TestRange(t *testing.T) {
+	s := New[int, string]()
+	for i := 0; i < 100; i += 10 {
+		s.Set(i, fmt.Sprint(i))
+	}
+	var got []int
+	for k := range s.Range(25, 60) { // half-open: 60 is excluded
+		got = append(got, k)
+	}
+	if !slices.Equal(got, []int{30, 40, 50}) {
+		t.Errorf("Range(25, 60) = %v", got)
+	}
+
+	// Stopping early
+	got = got[:0]
+	for k := range s.All() {
+		if k > 20 {
+			break
+		}
+		got = append(got, k)
+	}
+	if !slices.Equal(got, []int{0, 10, 20}) {
+		t.Errorf("All with break = %v", got)
+	}
+}
+
+func TestCustomOrder(t *testing.T) {
+	// NewFunc takes any comparator: here case-insensitive, so keys that
+	// differ only in case are the same key
+	s := NewFunc[string, int](func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	s.Set("Banana", 1)
+	s.Set("apple", 2)
+	s.Set("BANANA", 3) // replaces Banana's value; the stored key stays
+	if got := keys(s); !slices.Equal(got, []string{"apple", "Banana"}) {
+		t.Errorf("keys = %v", got)
+	}
+	if v, _ := s.Get("banana"); v != 3 {
+		t.Errorf("Get(banana) = %d", v)
+	}
+}
+
+// 3. The Shape of the List
+// ========================
+
+func TestHeightDistribution(t *testing.T) {
+	// Each level should hold about a quarter of the level below, so the
+	// average node has 1/(1-p) = 1.33 forward pointers. The upper levels
+	// hold only a few hundred nodes, so a random seed would push them
+	// outside the window now and then; a fixed seed keeps the test stable
+	s := New[int, struct{}]()
+	s.rng = rand.New(rand.NewPCG(1, 2))
+	const n = 100_000
+	for i := range n {
+		s.Set(i, struct{}{})
+	}
+	perLevel := make([]int, s.level)
+	pointers := 0
+	for x := s.head.next[0]; x != nil; x = x.next[0] {
+		for i := range x.next {
+			perLevel[i]++
+		}
+		pointers += len(x.next)
+	}
+	t.Logf("nodes per level: %v", perLevel)
+	t.Logf("pointers per node: %.2f", float64(pointers)/n)
+
+	for i := 1; i < 6; i++ {
+		ratio := float64(perLevel[i]) / float64(perLevel[i-1])
+		if ratio < 0.2 || ratio > 0.3 {
+			t.Errorf("level %d holds %.2f of level %d, want about 0.25", i, ratio, i-1)
+		}
+	}
+	if avg := float64(pointers) / n; avg < 1.3 || avg > 1.37 {
+		t.Errorf("%.2f pointers per node, want about 1.33", avg)
+	}
+	checkLevels(t, s)
+}
+
+func TestSequentialInsertStaysBalanced(t *testing.T) {
+	// Sorted input turns a naive binary search tree into a linked list.
+	// Heights here come from coin flips, not from the input, so a search
+	// still takes about log n steps.
+	s := New[int, int]()
+	for i := range 1 << 16 {
+		s.Set(i, i)
+	}
+	steps := 0
+	x := &s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.next[i]; next != nil && next.key < 1<<16-1; next = x.next[i] {
+			x = next
+			steps++
+		}
+	}
+	t.Logf("level %d, %d steps to find the last of %d keys", s.level, steps, 1<<16)
+	if steps > 100 {
+		t.Errorf("%d steps: the list is not skipping", steps)
+	}
+}
+
+// 4. Benchmarks: Skip List vs Sorted Slice vs Map
+// ===============================================
+// Each structure wins somewhere:
+//
+//   - a map has the fastest inserts and lookups but no order
+//   - a sorted slice has the fastest lookups among the ordered ones and
+//     the cheapest iteration, but every insert shifts half of it
+//   - the skip list keeps everything O(log n), including inserts, but
+//     chasing pointers costs several times a binary search
+//
+// So a sorted slice is the better ordered map until it is large and
+// written often; the insert benchmark shows where that happens.
+
+const benchN = 10_000
+
+var (
+	benchKeys = rand.New(rand.NewPCG(3, 4)).Perm(benchN)
+	intSink   int
+)
+
+func BenchmarkInsertRandom(b *testing.B) {
+	// The crossover: at 10,000 ints shifting the slice is still cheap
+	// (memmove is fast), by 100,000 the skip list is several times faster
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		ks := rand.New(rand.NewPCG(3, 4)).Perm(n)
+		b.Run(fmt.Sprintf("SkipList/%d", n), func(b *testing.B) {
+			for b.Loop() {
+				s := New[int, int]()
+				for _, k := range ks {
+					s.Set(k, k)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("SortedSlice/%d", n), func(b *testing.B) {
+			for b.Loop() {
+				var sorted []int
+				for _, k := range ks {
+					i, _ := slices.BinarySearch(sorted, k)
+					sorted = slices.Insert(sorted, i, k)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("Map/%d", n), func(b *testing.B) {
+			for b.Loop() {
+				m := make(map[int]int)
+				for _, k := range ks {
+					m[k] = k
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	s := New[int, int]()
+	m := make(map[int]int)
+	for _, k := range benchKeys {
+		s.Set(k, k)
+		m[k] = k
+	}
+	sorted := slices.Sorted(maps.Keys(m))
+
+	b.Run("SkipList", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			intSink, _ = s.Get(benchKeys[i%benchN])
+			i++
+		}
+	})
+	b.Run("SortedSlice", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			intSink, _ = slices.BinarySearch(sorted, benchKeys[i%benchN])
+			i++
+		}
+	})
+	b.Run("Map", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			intSink = m[benchKeys[i%benchN]]
+			i++
+		}
+	})
+}
+
+func BenchmarkOrderedIteration(b *testing.B) {
+	s := New[int, int]()
+	m := make(map[int]int)
+	for _, k := range benchKeys {
+		s.Set(k, k)
+		m[k] = k
+	}
+	sorted := slices.Sorted(maps.Keys(m))
+
+	b.Run("SkipList", func(b *testing.B) {
+		for b.Loop() {
+			for k := range s.All() {
+				intSink += k
+			}
+		}
+	})
+	b.Run("SortedSlice", func(b *testing.B) {
+		for b.Loop() {
+			for _, k := range sorted {
+				intSink += k
+			}
+		}
+	})
+	b.Run("Map", func(b *testing.B) {
+		// A map has no order: collect and sort the keys every time
+		for b.Loop() {
+			for _, k := range slices.Sorted(maps.Keys(m)) {
+				intSink += k
+			}
+		}
+	})
+}
+
+func BenchmarkMixed(b *testing.B) {
+	// 50% lookups, 25% inserts, 25% deletes over a 10,000-key working
+	// set, keeping the data ordered: the workload a skip list is for
+	ops := rand.New(rand.NewPCG(5, 6))
+	b.Run("SkipList", func(b *testing.B) {
+		s := New[int, int]()
+		for _, k := range benchKeys {
+			s.Set(k, k)
+		}
+		for b.Loop() {
+			k := ops.IntN(2 * benchN)
+			switch ops.IntN(4) {
+			case 0, 1:
+				intSink, _ = s.Get(k)
+			case 2:
+				s.Set(k, k)
+			case 3:
+				s.Delete(k)
+			}
+		}
+	})
+	b.Run("SortedSlice", func(b *testing.B) {
+		sorted := slices.Sorted(slices.Values(benchKeys))
+		for b.Loop() {
+			k := ops.IntN(2 * benchN)
+			i, found := slices.BinarySearch(sorted, k)
+			switch ops.IntN(4) {
+			case 0, 1:
+				intSink = i
+			case 2:
+				if !found {
+					sorted = slices.Insert(sorted, i, k)
+				}
+			case 3:
+				if found {
+					sorted = slices.Delete(sorted, i, i+1)
+				}
+			}
+		}
+	})
+}
+
+// Examples
+// ========
+
+func ExampleSkipList_Range() {
+	// Timestamps in seconds mapped to events; ask for a window
+	events := New[int, string]()
+	events.Set(30, "deploy")
+	events.Set(5, "start")
+	events.Set(90, "alert")
+	events.Set(60, "scale up")
+
+	for ts, e := range events.Range(10, 90) {
+		fmt.Println(ts, e)
+	}
+	// Output:
+	// 30 deploy
+	// 60 scale up
+}
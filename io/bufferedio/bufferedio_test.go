@@ -0,0 +1,409 @@
+package bufferedio
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// bufio Deep Dive - Tests With Large Inputs
+// =========================================
+// Run with:
+//
+//   cd io/bufferedio
+//   go test -v *.go
+//   go test -bench . -benchmem *.go
+
+// logWithLongLine returns 100 short lines, one line of n bytes, and 100
+// more short lines
+func logWithLongLine(n int) string {
+	var b strings.Builder
+	for i := range 100 {
+		fmt.Fprintf(&b, "before %d\n", i)
+	}
+	b.WriteString(strings.Repeat("x", n))
+	b.WriteByte('\n')
+	for i := range 100 {
+		fmt.Fprintf(&b, "after %d\n", i)
+	}
+	return b.String()
+}
+
+// 1. Scanner Token Limits
+// =======================
+
+func TestSilentTruncation(t *testing.T) {
+	input := logWithLongLine(100_000)
+
+	// The unchecked loop stops at the long line and returns a count that
+	// looks perfectly plausible
+	if got := CountLinesUnchecked(strings.NewReader(input)); got != 100 {
+		t.Errorf("CountLinesUnchecked = %d, want 100 (the trap)", got)
+	}
+
+	// Checking Err turns the same failure into an error
+	n, err := CountLines(strings.NewReader(input), bufio.MaxScanTokenSize)
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("err = %v, want bufio.ErrTooLong", err)
+	}
+	t.Logf("stopped after %d lines: %v", n, err)
+
+	// A larger limit reads everything
+	if n, err := CountLines(strings.NewReader(input), 1<<20); n != 201 || err != nil {
+		t.Errorf("CountLines with 1 MiB limit = %d, %v; want 201", n, err)
+	}
+}
+
+func TestLimitBoundary(t *testing.T) {
+	// maxLine is the longest line accepted, not counting the newline
+	const maxLine = 1000
+	for _, tt := range []struct {
+		length int
+		ok     bool
+	}{
+		{maxLine - 1, true},
+		{maxLine, true},
+		{maxLine + 1, false},
+	} {
+		input := strings.Repeat("y", tt.length) + "\nend\n"
+		_, err := CountLines(strings.NewReader(input), maxLine)
+		if (err == nil) != tt.ok {
+			t.Errorf("line of %d bytes: err = %v, want ok = %t", tt.length, err, tt.ok)
+		}
+	}
+}
+
+func TestReadLongLines(t *testing.T) {
+	// bufio.Reader has no token limit: a 5 MiB line comes through whole
+	input := logWithLongLine(5 << 20)
+	lines, err := ReadLongLines(iotest.HalfReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 201 || len(lines[100]) != 5<<20 || lines[200] != "after 99" {
+		t.Errorf("got %d lines, long line %d bytes", len(lines), len(lines[100]))
+	}
+
+	// A final line without a newline, and CRLF endings
+	lines, _ = ReadLongLines(strings.NewReader("a\r\nb\r\nc"))
+	if !slices.Equal(lines, []string{"a", "b", "c"}) {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestReaderErrorIsNotEOF(t *testing.T) {
+	// A failing reader must not look like a clean end of input either
+	boom := errors.New("read: connection reset")
+	r := io.MultiReader(strings.NewReader("one\ntwo\n"), iotest.ErrReader(boom))
+	if n, err := CountLines(r, 100); n != 2 || !errors.Is(err, boom) {
+		t.Errorf("CountLines = %d, %v", n, err)
+	}
+}
+
+// 2. Custom SplitFuncs
+// ====================
+
+func scanAll(r io.Reader, split bufio.SplitFunc) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Split(split)
+	var out []string
+	for sc.Scan() {
+		out = append(out, sc.Text())
+	}
+	return out, sc.Err()
+}
+
+func TestSplitOn(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"a--b--c", []string{"a", "b", "c"}},
+		{"a--b--", []string{"a", "b"}},
+		{"--a", []string{"", "a"}},
+		{"a----b", []string{"a", "", "b"}},
+		{"a-b", []string{"a-b"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		// OneByteReader feeds the Scanner a byte at a time, so every
+		// "--" arrives split across two reads
+		for name, r := range map[string]io.Reader{
+			"whole":    strings.NewReader(tt.in),
+			"one byte": iotest.OneByteReader(strings.NewReader(tt.in)),
+		} {
+			got, err := scanAll(r, SplitOn([]byte("--")))
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("%s %q: got %q, %v; want %q", name, tt.in, got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestSplitOnLargeInput(t *testing.T) {
+	// 200,000 records across many buffer refills, checked against
+	// strings.Split
+	var b strings.Builder
+	for i := range 200_000 {
+		fmt.Fprintf(&b, "record-%d<END>", i)
+	}
+	input := b.String()
+	want := strings.Split(strings.TrimSuffix(input, "<END>"), "<END>")
+
+	got, err := scanAll(iotest.HalfReader(strings.NewReader(input)), SplitOn([]byte("<END>")))
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf("got %d records, %v; want %d", len(got), err, len(want))
+	}
+}
+
+func TestScanParagraphs(t *testing.T) {
+	input := "\n\nFirst paragraph,\nline two.\n\n\nSecond.\r\n\r\nThird\nends here"
+	want := []string{"First paragraph,\nline two.", "Second.", "Third\nends here"}
+	for name, r := range map[string]io.Reader{
+		"whole":    strings.NewReader(input),
+		"one byte": iotest.OneByteReader(strings.NewReader(input)),
+	} {
+		got, err := scanAll(r, ScanParagraphs)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("%s: got %q, %v", name, got, err)
+		}
+	}
+	if got, _ := scanAll(strings.NewReader("\n\n\n"), ScanParagraphs); got != nil {
+		t.Errorf("only blank lines: got %q", got)
+	}
+}
+
+// 3. Peek and ReadSlice
+// =====================
+
+func TestMaybeGunzip(t *testing.T) {
+	text := strings.Repeat("compress me ", 1000)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	io.WriteString(zw, text)
+	zw.Close()
+
+	for name, in := range map[string][]byte{
+		"plain": []byte(text),
+		"gzip":  gz.Bytes(),
+	} {
+		r, err := MaybeGunzip(bytes.NewReader(in))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || string(got) != text {
+			t.Errorf("%s: read %d bytes, %v", name, len(got), err)
+		}
+	}
+
+	// Inputs shorter than the magic number are plain text, not errors
+	for _, in := range []string{"", "x"} {
+		r, err := MaybeGunzip(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		if got, _ := io.ReadAll(r); string(got) != in {
+			t.Errorf("%q: got %q", in, got)
+		}
+	}
+}
+
+func TestPeekLimit(t *testing.T) {
+	br := bufio.NewReaderSize(strings.NewReader(strings.Repeat("z", 10_000)), 16)
+	if _, err := br.Peek(16); err != nil {
+		t.Errorf("Peek within the buffer: %v", err)
+	}
+	if _, err := br.Peek(17); !errors.Is(err, bufio.ErrBufferFull) {
+		t.Errorf("Peek beyond the buffer: err = %v, want ErrBufferFull", err)
+	}
+}
+
+func TestReadSliceIsAView(t *testing.T) {
+	// ReadSlice returns part of the Reader's buffer. Keeping it across
+	// another read keeps a window that has since been overwritten.
+	// OneByteReader makes the second read refill the buffer from the
+	// start, over the bytes of the first line.
+	br := bufio.NewReaderSize(iotest.OneByteReader(strings.NewReader("first\nsecond\n")), 16)
+	first, _ := br.ReadSlice('\n')
+	kept := string(first) // a copy
+	br.ReadSlice('\n')
+	t.Logf("the saved slice now reads %q", first)
+	if string(first) == "first\n" {
+		t.Error("expected the second read to overwrite the first slice")
+	}
+	if kept != "first\n" {
+		t.Errorf("the copy should be stable, got %q", kept)
+	}
+
+	// Without the delimiter inside one buffer, ReadSlice gives up
+	br = bufio.NewReaderSize(strings.NewReader(strings.Repeat("w", 100)+"\n"), 16)
+	if _, err := br.ReadSlice('\n'); !errors.Is(err, bufio.ErrBufferFull) {
+		t.Errorf("long line: err = %v, want ErrBufferFull", err)
+	}
+}
+
+// 4. bufio.Writer Flushing
+// ========================
+
+// failAfter accepts n bytes, then fails every write
+type failAfter struct {
+	n   int
+	buf bytes.Buffer
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (f *failAfter) Write(p []byte) (int, error) {
+	if f.buf.Len()+len(p) > f.n {
+		return 0, errDiskFull
+	}
+	return f.buf.Write(p)
+}
+
+func items(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("item with a reasonably long description %d", i)
+	}
+	return out
+}
+
+func TestForgottenFlush(t *testing.T) {
+	// Small output: nothing reaches the writer at all
+	var small bytes.Buffer
+	WriteReportNoFlush(&small, items(3))
+	if small.Len() != 0 {
+		t.Errorf("wrote %d bytes without a flush", small.Len())
+	}
+
+	// Large output: whole 4 KiB buffers get through and the tail does
+	// not, so the file looks almost right
+	var large, want bytes.Buffer
+	WriteReportNoFlush(&large, items(10_000))
+	WriteReport(&want, items(10_000))
+	t.Logf("without Flush: %d of %d bytes", large.Len(), want.Len())
+	if large.Len()%4096 != 0 || large.Len() >= want.Len() {
+		t.Errorf("got %d bytes; want whole buffers only, short of %d", large.Len(), want.Len())
+	}
+	if !bytes.HasPrefix(want.Bytes(), large.Bytes()) {
+		t.Error("what was written should be a prefix of the full report")
+	}
+}
+
+func TestFlushReportsWriteErrors(t *testing.T) {
+	// The destination fails after 100 bytes, but the report fits in the
+	// buffer: every Fprintf succeeds and only Flush sees the failure
+	dst := &failAfter{n: 100}
+	if err := WriteReport(dst, items(10)); !errors.Is(err, errDiskFull) {
+		t.Errorf("err = %v, want the flush error", err)
+	}
+	if err := WriteReportNoFlush(&failAfter{n: 100}, items(10)); err != nil {
+		t.Errorf("without Flush the error is never seen: err = %v", err)
+	}
+
+	// Errors stick: after one failure every call fails, even if the
+	// destination would now accept the data
+	dst = &failAfter{n: 0}
+	bw := bufio.NewWriterSize(dst, 16)
+	bw.WriteString("more than sixteen bytes")
+	dst.n = 1 << 20
+	if _, err := bw.WriteString("ok"); !errors.Is(err, errDiskFull) {
+		t.Errorf("second write: err = %v, want the sticky error", err)
+	}
+	if err := bw.Flush(); !errors.Is(err, errDiskFull) {
+		t.Errorf("Flush: err = %v, want the sticky error", err)
+	}
+}
+
+// 5. Benchmarks
+// =============
+
+var benchLog = strings.Repeat("2024-01-01T00:00:00Z INFO GET /api/items 200 3ms\n", 20_000)
+
+func BenchmarkLinesScanner(b *testing.B) {
+	b.SetBytes(int64(len(benchLog)))
+	for b.Loop() {
+		CountLines(strings.NewReader(benchLog), 4096)
+	}
+}
+
+func BenchmarkLinesReadString(b *testing.B) {
+	// Allocates a string per line
+	b.SetBytes(int64(len(benchLog)))
+	for b.Loop() {
+		br := bufio.NewReader(strings.NewReader(benchLog))
+		for {
+			if _, err := br.ReadString('\n'); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkLinesReadSlice(b *testing.B) {
+	// No copies at all, but each slice is only valid until the next read
+	b.SetBytes(int64(len(benchLog)))
+	for b.Loop() {
+		br := bufio.NewReader(strings.NewReader(benchLog))
+		for {
+			if _, err := br.ReadSlice('\n'); err != nil {
+				break
+			}
+		}
+	}
+}
+
+// syscallWriter stands in for a file: each Write would be one system
+// call. Here a call costs nothing, so the buffered version is no faster
+// in ns/op; writes/op is the number that matters once each one costs a
+// microsecond or more in the kernel.
+type syscallWriter struct{ calls int }
+
+func (w *syscallWriter) Write(p []byte) (int, error) {
+	w.calls++
+	return len(p), nil
+}
+
+func BenchmarkWriteUnbuffered(b *testing.B) {
+	w := &syscallWriter{}
+	for b.Loop() {
+		for i := range 1000 {
+			fmt.Fprintf(w, "line %d\n", i)
+		}
+	}
+	b.ReportMetric(float64(w.calls)/float64(b.N), "writes/op")
+}
+
+func BenchmarkWriteBuffered(b *testing.B) {
+	w := &syscallWriter{}
+	for b.Loop() {
+		bw := bufio.NewWriter(w)
+		for i := range 1000 {
+			fmt.Fprintf(bw, "line %d\n", i)
+		}
+		bw.Flush()
+	}
+	b.ReportMetric(float64(w.calls)/float64(b.N), "writes/op")
+}
+
+// Examples
+// ========
+
+func ExampleSplitOn() {
+	sc := bufio.NewScanner(strings.NewReader("GET /a\r\n\r\nGET /b\r\n\r\n"))
+	sc.Split(SplitOn([]byte("\r\n\r\n")))
+	for sc.Scan() {
+		fmt.Printf("%q\n", sc.Text())
+	}
+	// Output:
+	// "GET /a"
+	// "GET /b"
+}
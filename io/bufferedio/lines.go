@@ -0,0 +1,67 @@
+package bufferedio
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Scanner Limits - The Silent Truncation Trap
+// ===========================================
+// bufio.Scanner refuses tokens longer than its maximum buffer, 64 KiB
+// by default (bufio.MaxScanTokenSize). On a longer line Scan returns
+// false - exactly as it does at the end of the input. The only
+// difference is sc.Err(), which returns bufio.ErrTooLong. A loop that
+// never checks Err stops at the long line and reports success with
+// everything after it missing: a minified JSON line or a base64 blob in
+// a log is enough.
+
+// CountLinesUnchecked counts lines the way most examples do, without
+// calling sc.Err(). It silently stops at the first line over 64 KiB.
+func CountLinesUnchecked(r io.Reader) int {
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+	}
+	return n
+}
+
+// CountLines counts lines of up to maxLine bytes and reports an error,
+// rather than a short count, if a line is longer
+func CountLines(r io.Reader, maxLine int) (int, error) {
+	sc := bufio.NewScanner(r)
+	// Buffer sets the starting buffer and the maximum. The buffer must
+	// hold the line plus its newline, so allow one extra byte.
+	sc.Buffer(make([]byte, 0, min(maxLine+1, 64*1024)), maxLine+1)
+	n := 0
+	for sc.Scan() {
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return n, err // bufio.ErrTooLong, or the reader's own error
+	}
+	return n, nil
+}
+
+// ReadLongLines returns every line in r, however long, using
+// bufio.Reader instead of Scanner. ReadString grows its result as
+// needed, so the only limit is memory - which is why an untrusted input
+// still needs a cap somewhere, such as an io.LimitReader around r.
+func ReadLongLines(r io.Reader) ([]string, error) {
+	br := bufio.NewReader(r)
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		}
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package bufferedio
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// Peek and ReadSlice - Looking Into the Buffer
+// ============================================
+// bufio.Reader exposes its buffer directly through two methods:
+//
+//   - Peek(n) returns the next n bytes without consuming them - enough to
+//     sniff a file format before deciding how to read it
+//   - ReadSlice(delim) returns the bytes up to delim as a slice of the
+//     buffer itself, with no copy
+//
+// Both return views, not copies: the next read may overwrite them. And
+// both are limited by the buffer size (4 KiB by default): Peek beyond it
+// returns bufio.ErrBufferFull, as does ReadSlice when the delimiter is
+// not within one buffer. ReadBytes and ReadString copy and grow instead.
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// MaybeGunzip returns a reader of r's content, transparently
+// decompressing it if it starts with the gzip magic number. Peek looks
+// at the first two bytes without consuming them, so plain input is
+// returned in full.
+func MaybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(head, gzipMagic) {
+		return br, nil // not gzip: br still holds the peeked bytes
+	}
+	return gzip.NewReader(br)
+}
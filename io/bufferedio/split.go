@@ -0,0 +1,86 @@
+package bufferedio
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// Custom SplitFuncs
+// =================
+// bufio.Scanner reads into a buffer and hands it to a SplitFunc, which
+// decides where the next token ends:
+//
+//	func(data []byte, atEOF bool) (advance int, token []byte, err error)
+//
+//   - return (0, nil, nil) to ask for more data; the Scanner reads more
+//     and calls again with a longer data
+//   - return (advance, token, nil) to emit token and drop advance bytes
+//   - when atEOF is true no more data is coming: return what is left as
+//     the final token, or (0, nil, nil) once data is empty
+//   - return bufio.ErrFinalToken to emit token and stop early
+//
+// A SplitFunc sees data, not the reader, so it must cope with a
+// separator split across two reads. Asking for more is always safe.
+
+// SplitOn returns a SplitFunc that breaks input at every occurrence of
+// sep, which may be several bytes long. The separator is not included
+// in the tokens. A trailing separator does not produce an empty final
+// token, matching bufio.ScanLines.
+func SplitOn(sep []byte) bufio.SplitFunc {
+	if len(sep) == 0 {
+		panic("bufferedio: empty separator")
+	}
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, sep); i >= 0 {
+			return i + len(sep), data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil // sep may be split across reads: wait for more
+	}
+}
+
+// ScanParagraphs is a SplitFunc for blocks of text separated by one or
+// more blank lines. Leading blank lines are skipped and each paragraph
+// is returned without its trailing newlines. "\r\n" line endings are
+// treated like "\n".
+func ScanParagraphs(data []byte, atEOF bool) (int, []byte, error) {
+	// Skip blank lines before the paragraph
+	start := 0
+	for start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+	if atEOF && start == len(data) {
+		return len(data), nil, nil
+	}
+
+	for i := start; i < len(data); i++ {
+		if data[i] != '\n' {
+			continue
+		}
+		// A blank line follows if, after optional \r, the next byte is \n
+		j := i + 1
+		if j < len(data) && data[j] == '\r' {
+			j++
+		}
+		if j < len(data) && data[j] == '\n' {
+			return j + 1, trimEOL(data[start:i]), nil
+		}
+		if j == len(data) && !atEOF {
+			return start, nil, nil // the next byte decides: read more
+		}
+	}
+	if atEOF {
+		return len(data), trimEOL(data[start:]), nil
+	}
+	return start, nil, nil
+}
+
+// trimEOL removes trailing \r and \n
+func trimEOL(b []byte) []byte {
+	return bytes.TrimRight(b, "\r\n")
+}
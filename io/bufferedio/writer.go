@@ -0,0 +1,49 @@
+package bufferedio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// bufio.Writer - Flushing Bugs
+// ============================
+// bufio.Writer collects small writes and passes them on in 4 KiB
+// chunks, turning a thousand tiny syscalls into a few. The cost is that
+// written data sits in memory until the buffer fills or Flush is
+// called. Two bugs follow:
+//
+//   - forgetting Flush: the last partial buffer is never written, so
+//     the output is silently short - and only when it is not a multiple
+//     of the buffer size, so small tests can pass by luck
+//   - ignoring Flush's error: writes into the buffer always "succeed";
+//     a full disk or closed connection is only reported by the Write
+//     that triggers a flush, or by Flush itself
+//
+// Once a write fails the Writer keeps that error, and every later Write
+// and Flush returns it.
+
+// WriteReportNoFlush writes one line per item and forgets to flush
+func WriteReportNoFlush(w io.Writer, items []string) error {
+	bw := bufio.NewWriter(w)
+	for i, item := range items {
+		if _, err := fmt.Fprintf(bw, "%d. %s\n", i+1, item); err != nil {
+			return err
+		}
+	}
+	return nil // bug: the buffered tail is lost
+}
+
+// WriteReport writes one line per item and returns the first error,
+// including one that only surfaces when the buffer is flushed
+func WriteReport(w io.Writer, items []string) error {
+	bw := bufio.NewWriter(w)
+	for i, item := range items {
+		// Errors stick, so checking once at the end would also work;
+		// checking here stops early instead of formatting the rest
+		if _, err := fmt.Fprintf(bw, "%d. %s\n", i+1, item); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,95 @@
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// Compression - gzip, zlib and Raw DEFLATE
+// ========================================
+// All three formats carry the same DEFLATE data. They differ only in
+// the wrapping:
+//
+//	format  header   trailer             used by
+//	flate   none     none                inside zip, PNG chunks, WebSocket
+//	zlib    2 bytes  Adler-32            PNG, PDF, Git objects, Content-Encoding: deflate
+//	gzip    10+      CRC-32 + size       .gz files, Content-Encoding: gzip
+//
+// gzip's header can also hold a file name, a comment and a modification
+// time. Both checksums catch corruption when the stream is read to the
+// end - not before, so a consumer may already have used bad bytes.
+//
+// Every one of them is an io.Writer to compress into and an io.Reader
+// to decompress from, so they drop into any pipeline.
+
+// Format selects the wrapping around the DEFLATE data
+type Format int
+
+//go:generate go run ../../cmd/genenum/main.go -type Format -case kebab
+
+const (
+	Gzip Format = iota
+	Zlib
+	Flate
+)
+
+// NewWriter returns a compressor writing to w. Level is from
+// flate.HuffmanOnly (-2) to flate.BestCompression (9);
+// flate.DefaultCompression (-1) is level 6.
+func NewWriter(w io.Writer, f Format, level int) (io.WriteCloser, error) {
+	switch f {
+	case Gzip:
+		return gzip.NewWriterLevel(w, level)
+	case Zlib:
+		return zlib.NewWriterLevel(w, level)
+	case Flate:
+		return flate.NewWriter(w, level)
+	}
+	return nil, fmt.Errorf("compress: unknown format %v", f)
+}
+
+// NewReader returns a decompressor reading from r. For gzip and zlib it
+// reads and checks the header at once, so a wrong format fails here.
+func NewReader(r io.Reader, f Format) (io.ReadCloser, error) {
+	switch f {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zlib:
+		return zlib.NewReader(r)
+	case Flate:
+		return flate.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("compress: unknown format %v", f)
+}
+
+// Compress streams src into dst compressed, returning the number of
+// uncompressed bytes read
+func Compress(dst io.Writer, src io.Reader, f Format, level int) (int64, error) {
+	zw, err := NewWriter(dst, f, level)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(zw, src)
+	if err != nil {
+		zw.Close()
+		return n, err
+	}
+	// Close writes the last block and the trailer. Without it the
+	// output is truncated and the reader fails with ErrUnexpectedEOF.
+	return n, zw.Close()
+}
+
+// Decompress streams the compressed src into dst, returning the number
+// of uncompressed bytes written. Checksum and truncation errors are
+// reported after the data they cover has already been written.
+func Decompress(dst io.Writer, src io.Reader, f Format) (int64, error) {
+	zr, err := NewReader(src, f)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+	return io.Copy(dst, zr)
+}
@@ -0,0 +1,381 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand/v2"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Compression - Tests
+// ===================
+// Run with:
+//
+//   cd io/compress
+//   go test -v *.go
+//   go test -bench . -benchmem *.go
+
+// sampleLogs is repetitive text, like logs, JSON or HTML: it compresses
+// well. sampleRandom has no redundancy to remove.
+var (
+	sampleLogs   = makeLogs(5000)
+	sampleRandom = makeRandom(256 * 1024)
+)
+
+func makeLogs(n int) []byte {
+	var b bytes.Buffer
+	r := rand.New(rand.NewPCG(1, 2))
+	levels := []string{"INFO", "INFO", "INFO", "WARN", "ERROR"}
+	paths := []string{"/api/users", "/api/orders", "/healthz", "/static/app.js"}
+	for i := range n {
+		fmt.Fprintf(&b, "2024-03-01T09:%02d:%02dZ %-5s request path=%s status=%d duration=%dms id=%08x\n",
+			i/60%60, i%60, levels[r.IntN(len(levels))], paths[r.IntN(len(paths))],
+			[]int{200, 200, 304, 404, 500}[r.IntN(5)], r.IntN(900), r.Uint32())
+	}
+	return b.Bytes()
+}
+
+func makeRandom(n int) []byte {
+	b := make([]byte, n)
+	r := rand.New(rand.NewPCG(3, 4))
+	for i := range b {
+		b[i] = byte(r.Uint32())
+	}
+	return b
+}
+
+func compressBytes(t testing.TB, data []byte, f Format, level int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Compress(&buf, bytes.NewReader(data), f, level); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// 1. Round Trips and Framing
+// ==========================
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range []Format{Gzip, Zlib, Flate} {
+		for _, data := range [][]byte{nil, []byte("x"), sampleLogs, sampleRandom} {
+			packed := compressBytes(t, data, f, flate.DefaultCompression)
+			var out bytes.Buffer
+			n, err := Decompress(&out, bytes.NewReader(packed), f)
+			if err != nil || n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+				t.Errorf("%v, %d bytes: got %d bytes, err %v", f, len(data), n, err)
+			}
+		}
+	}
+}
+
+func TestFraming(t *testing.T) {
+	// The same DEFLATE data in three wrappers. Compressing nothing shows
+	// the fixed overhead of each.
+	sizes := map[Format]int{}
+	for _, f := range []Format{Gzip, Zlib, Flate} {
+		sizes[f] = len(compressBytes(t, nil, f, flate.DefaultCompression))
+	}
+	// flate: an empty final block. zlib adds 2 + 4 bytes, gzip 10 + 8.
+	if sizes[Zlib]-sizes[Flate] != 6 || sizes[Gzip]-sizes[Flate] != 18 {
+		t.Errorf("empty stream sizes = %v", sizes)
+	}
+
+	// The headers are recognizable: gzip starts 1f 8b, zlib with 0x78
+	// (DEFLATE, 32 KiB window) and a check byte
+	gz := compressBytes(t, sampleLogs, Gzip, 6)
+	zl := compressBytes(t, sampleLogs, Zlib, 6)
+	if gz[0] != 0x1f || gz[1] != 0x8b || zl[0] != 0x78 || (int(zl[0])<<8|int(zl[1]))%31 != 0 {
+		t.Errorf("headers: gzip % x, zlib % x", gz[:2], zl[:2])
+	}
+
+	// gzip's trailer is the CRC-32 and length of the uncompressed data
+	trailer := gz[len(gz)-8:]
+	crc := uint32(trailer[0]) | uint32(trailer[1])<<8 | uint32(trailer[2])<<16 | uint32(trailer[3])<<24
+	if crc != crc32.ChecksumIEEE(sampleLogs) {
+		t.Errorf("gzip CRC = %08x, want %08x", crc, crc32.ChecksumIEEE(sampleLogs))
+	}
+
+	// Reading with the wrong format fails at the header
+	if _, err := NewReader(bytes.NewReader(zl), Gzip); !errors.Is(err, gzip.ErrHeader) {
+		t.Errorf("zlib data as gzip: err = %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader(gz), Zlib); !errors.Is(err, zlib.ErrHeader) {
+		t.Errorf("gzip data as zlib: err = %v", err)
+	}
+}
+
+func TestGzipHeader(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = "access.log"
+	zw.ModTime = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	zw.Write(sampleLogs[:100])
+	zw.Close()
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.Name != "access.log" || !zr.ModTime.Equal(zw.ModTime) {
+		t.Errorf("header = %q %v", zr.Name, zr.ModTime)
+	}
+}
+
+func TestGzipMultistream(t *testing.T) {
+	// Concatenated gzip files are a valid gzip file (cat a.gz b.gz),
+	// and gzip.Reader reads all members by default. Log rotation and
+	// parallel compressors rely on this.
+	var buf bytes.Buffer
+	Compress(&buf, strings.NewReader("first member\n"), Gzip, 6)
+	Compress(&buf, strings.NewReader("second member\n"), Gzip, 6)
+
+	var out bytes.Buffer
+	if _, err := Decompress(&out, &buf, Gzip); err != nil || out.String() != "first member\nsecond member\n" {
+		t.Errorf("multistream = %q, %v", out.String(), err)
+	}
+}
+
+// 2. Corruption and Truncation
+// ============================
+
+func TestCorruption(t *testing.T) {
+	for _, f := range []Format{Gzip, Zlib} {
+		packed := compressBytes(t, sampleLogs, f, 6)
+
+		// Flip a bit in the checksum: all the data decodes, then the
+		// final check fails. Decompress has already written it all.
+		// gzip ends with CRC-32 then length, zlib with Adler-32.
+		bad := bytes.Clone(packed)
+		if f == Gzip {
+			bad[len(bad)-8] ^= 1
+		} else {
+			bad[len(bad)-1] ^= 1
+		}
+		var out bytes.Buffer
+		_, err := Decompress(&out, bytes.NewReader(bad), f)
+		if !errors.Is(err, gzip.ErrChecksum) && !errors.Is(err, zlib.ErrChecksum) {
+			t.Errorf("%v, bad checksum: err = %v", f, err)
+		}
+		if out.Len() != len(sampleLogs) {
+			t.Errorf("%v: %d bytes written before the checksum error", f, out.Len())
+		}
+
+		// A stream cut short - a writer that never called Close, or a
+		// partial download - is io.ErrUnexpectedEOF
+		_, err = Decompress(io.Discard, bytes.NewReader(packed[:len(packed)/2]), f)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%v, truncated: err = %v", f, err)
+		}
+	}
+}
+
+func TestForgottenClose(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(sampleLogs)
+	// No Close, no Flush: most of the output is still inside zw
+	if _, err := Decompress(io.Discard, bytes.NewReader(buf.Bytes()), Gzip); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("without Close: err = %v", err)
+	}
+	zw.Close()
+	if _, err := Decompress(io.Discard, bytes.NewReader(buf.Bytes()), Gzip); err != nil {
+		t.Errorf("after Close: err = %v", err)
+	}
+}
+
+// 3. Compression Levels
+// =====================
+
+func TestLevels(t *testing.T) {
+	// Higher levels search harder for matches: smaller output, more CPU.
+	// Random data has no matches at any level, and DEFLATE stores it in
+	// raw blocks with a few bytes of overhead.
+	levels := []int{flate.HuffmanOnly, flate.BestSpeed, flate.DefaultCompression, flate.BestCompression}
+	var prev int
+	for i, level := range levels {
+		logs := len(compressBytes(t, sampleLogs, Gzip, level))
+		random := len(compressBytes(t, sampleRandom, Gzip, level))
+		t.Logf("level %2d: logs %6d -> %6d (%4.1f%%), random %d -> %d",
+			level, len(sampleLogs), logs, 100*float64(logs)/float64(len(sampleLogs)), len(sampleRandom), random)
+
+		if i > 0 && logs > prev {
+			t.Errorf("level %d (%d bytes) is larger than the level before it (%d)", level, logs, prev)
+		}
+		prev = logs
+		if random < len(sampleRandom) || random > len(sampleRandom)+len(sampleRandom)/100 {
+			t.Errorf("level %d: random data became %d bytes", level, random)
+		}
+	}
+
+	if _, err := NewWriter(io.Discard, Gzip, 10); err == nil {
+		t.Error("level 10: want an error")
+	}
+}
+
+// 4. Pooling
+// ==========
+
+func TestWriterPool(t *testing.T) {
+	p := NewWriterPool(gzip.BestSpeed)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			msg := []byte(strings.Repeat(fmt.Sprintf("message %d ", i), 100))
+			var buf bytes.Buffer
+			if err := p.Compress(&buf, msg); err != nil {
+				t.Error(err)
+				return
+			}
+			var out bytes.Buffer
+			if _, err := Decompress(&out, &buf, Gzip); err != nil || !bytes.Equal(out.Bytes(), msg) {
+				t.Errorf("message %d: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	// A reused writer carries nothing over: Reset clears the header
+	// fields and the dictionary
+	zw := p.Get(io.Discard)
+	zw.Name = "leak.txt"
+	zw.Close()
+	p.Put(zw)
+	var buf bytes.Buffer
+	p.Compress(&buf, []byte("clean"))
+	zr, err := gzip.NewReader(&buf)
+	if err != nil || zr.Name != "" {
+		t.Errorf("name after reuse = %q, %v", zr.Name, err)
+	}
+}
+
+func TestWriterPoolAllocations(t *testing.T) {
+	msg := sampleLogs[:4096]
+	p := NewWriterPool(gzip.DefaultCompression)
+	p.Compress(io.Discard, msg) // fill the pool
+
+	fresh := testing.AllocsPerRun(20, func() {
+		zw := gzip.NewWriter(io.Discard)
+		zw.Write(msg)
+		zw.Close()
+	})
+	pooled := testing.AllocsPerRun(20, func() {
+		p.Compress(io.Discard, msg)
+	})
+	t.Logf("allocations per message: fresh writer %.0f, pooled %.0f", fresh, pooled)
+	if raceEnabled() {
+		// The race detector makes sync.Pool drop items at random
+		t.Skip("allocation counts are not checked under -race")
+	}
+	// The pool may be emptied by a GC during the run; allow for that
+	if pooled > 1 {
+		t.Errorf("pooled writer: %.0f allocations per message", pooled)
+	}
+}
+
+// raceEnabled reports whether the test binary was built with -race. A
+// "race" build tag would be the usual switch, but "go test *.go" builds
+// every file it is given, whatever its tags.
+func raceEnabled() bool {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return false
+	}
+	for _, s := range info.Settings {
+		if s.Key == "-race" {
+			return s.Value == "true"
+		}
+	}
+	return false
+}
+
+// 5. Benchmarks
+// =============
+// Measured on 440 KB of sample logs:
+//
+//	level  speed      ratio
+//	-2     490 MB/s   1.6   Huffman coding only, no matching
+//	 1     260 MB/s   5.5
+//	 6     130 MB/s   6.0   the default
+//	 9      17 MB/s   7.1
+//
+// Level 9 is 8x slower than level 6 for 15% smaller output, and
+// decompression speed barely depends on the level. BestSpeed is the
+// usual choice for on-the-fly HTTP compression; 9 pays off for files
+// compressed once and downloaded many times.
+//
+// For 2 KB messages a fresh writer takes 120 µs and 1 MB; a pooled one
+// takes 17 µs and nothing.
+
+func BenchmarkLevels(b *testing.B) {
+	levels := []int{flate.HuffmanOnly, flate.BestSpeed, 3, flate.DefaultCompression, flate.BestCompression}
+	for _, level := range levels {
+		b.Run(fmt.Sprintf("level=%d", level), func(b *testing.B) {
+			var buf bytes.Buffer
+			b.SetBytes(int64(len(sampleLogs)))
+			for b.Loop() {
+				buf.Reset()
+				Compress(&buf, bytes.NewReader(sampleLogs), Gzip, level)
+			}
+			b.ReportMetric(float64(len(sampleLogs))/float64(buf.Len()), "ratio")
+		})
+	}
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	for _, level := range []int{flate.BestSpeed, flate.BestCompression} {
+		packed := compressBytes(b, sampleLogs, Gzip, level)
+		b.Run(fmt.Sprintf("level=%d", level), func(b *testing.B) {
+			b.SetBytes(int64(len(sampleLogs)))
+			for b.Loop() {
+				Decompress(io.Discard, bytes.NewReader(packed), Gzip)
+			}
+		})
+	}
+}
+
+// Small messages, like HTTP responses: the writer's setup dominates
+func BenchmarkSmallMessage(b *testing.B) {
+	msg := sampleLogs[:2048]
+	b.Run("NewWriter", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			zw := gzip.NewWriter(io.Discard)
+			zw.Write(msg)
+			zw.Close()
+		}
+	})
+	b.Run("WriterPool", func(b *testing.B) {
+		p := NewWriterPool(gzip.DefaultCompression)
+		b.ReportAllocs()
+		for b.Loop() {
+			p.Compress(io.Discard, msg)
+		}
+	})
+}
+
+// Examples
+// ========
+
+func ExampleCompress() {
+	var packed bytes.Buffer
+	n, _ := Compress(&packed, bytes.NewReader(bytes.Repeat([]byte("gopher "), 1000)), Gzip, flate.BestCompression)
+	fmt.Printf("%d bytes -> %d bytes\n", n, packed.Len())
+
+	var out strings.Builder
+	Decompress(&out, &packed, Gzip)
+	fmt.Println(out.String()[:14])
+	// Output:
+	// 7000 bytes -> 56 bytes
+	// gopher gopher
+}
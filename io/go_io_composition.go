@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Go io Composition - Building Pipelines From Readers and Writers
+// ===============================================================
+// io.Reader and io.Writer have one method each, so almost everything
+// implements them: files, sockets, buffers, hashes, compressors, HTTP
+// bodies. The io package then supplies small adapters that take readers
+// and writers and return new ones. Chaining them builds a pipeline where
+// data streams through in chunks and is never held in memory whole.
+//
+// Run with:
+//
+//	cd io
+//	go run go_io_composition.go
+
+func main() {
+	fmt.Println("=== Go io Composition ===")
+
+	// 1. The two interfaces
+	theInterfaces()
+
+	// 2. TeeReader
+	teeReader()
+
+	// 3. MultiWriter and MultiReader
+	multiWriter()
+
+	// 4. LimitReader and SectionReader
+	limitReader()
+
+	// 5. io.Pipe between goroutines
+	pipe()
+
+	// 6. io.Copy internals
+	copyInternals()
+}
+
+// 1. The Two Interfaces
+// =====================
+func theInterfaces() {
+	fmt.Println("\n1. THE TWO INTERFACES:")
+
+	// Read fills p with up to len(p) bytes and returns how many. It may
+	// return fewer without an error, and may return n > 0 together with
+	// io.EOF: always use the n bytes before looking at err.
+	r := strings.NewReader("hello, readers")
+	buf := make([]byte, 5)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			fmt.Printf("   read %d: %q\n", n, buf[:n])
+		}
+		if err == io.EOF {
+			fmt.Println("   io.EOF: the stream ended cleanly")
+			break
+		}
+		if err != nil {
+			fmt.Println("   error:", err)
+			break
+		}
+	}
+
+	// Write must write all of p or return an error explaining why not.
+	// Anything with a Write method can be the target of fmt.Fprintf.
+	for _, w := range []io.Writer{os.Stdout, &bytes.Buffer{}, sha256.New(), io.Discard} {
+		fmt.Printf("   %T is an io.Writer\n", w)
+	}
+}
+
+// 2. TeeReader
+// ============
+func teeReader() {
+	fmt.Println("\n2. TEEREADER:")
+
+	// TeeReader(r, w) returns a reader that writes everything it reads
+	// from r to w. Here the body is saved and hashed in a single pass,
+	// without reading it twice or buffering it to hash afterwards.
+	body := strings.NewReader("the quick brown fox jumps over the lazy dog")
+	hash := sha256.New()
+	var saved bytes.Buffer
+	if _, err := io.Copy(&saved, io.TeeReader(body, hash)); err != nil {
+		fmt.Println("   copy:", err)
+		return
+	}
+	fmt.Printf("   saved %d bytes, sha256 %x...\n", saved.Len(), hash.Sum(nil)[:8])
+
+	// The write to w happens inside Read, before the bytes are returned.
+	// A failed write becomes a failed read.
+	failing := io.TeeReader(strings.NewReader("data"), errWriter{})
+	_, err := io.ReadAll(failing)
+	fmt.Println("   tee into a failing writer:", err)
+}
+
+// 3. MultiWriter and MultiReader
+// ==============================
+func multiWriter() {
+	fmt.Println("\n3. MULTIWRITER AND MULTIREADER:")
+
+	// MultiWriter duplicates every write to all its writers, in order,
+	// like the Unix tee command. One Copy fills a file, a hash and a
+	// byte counter at once.
+	var file bytes.Buffer // stands in for an *os.File
+	hash := sha256.New()
+	counter := &countingWriter{}
+	w := io.MultiWriter(&file, hash, counter)
+	fmt.Fprintf(w, "line one\nline two\n")
+	fmt.Printf("   file %q\n   sha256 %x..., counted %d bytes\n", file.String(), hash.Sum(nil)[:8], counter.n)
+
+	// It stops at the first writer that fails; later writers never see
+	// the data
+	var after bytes.Buffer
+	_, err := io.MultiWriter(errWriter{}, &after).Write([]byte("lost"))
+	fmt.Printf("   first writer fails: err=%v, second got %d bytes\n", err, after.Len())
+
+	// MultiReader is the reverse: readers one after another, as one
+	// stream - a header, a body and a trailer without concatenating them
+	r := io.MultiReader(
+		strings.NewReader("HEADER|"),
+		strings.NewReader("body|"),
+		strings.NewReader("TRAILER"),
+	)
+	all, _ := io.ReadAll(r)
+	fmt.Printf("   MultiReader: %q\n", all)
+}
+
+// 4. LimitReader and SectionReader
+// ================================
+func limitReader() {
+	fmt.Println("\n4. LIMITREADER AND SECTIONREADER:")
+
+	// LimitReader returns EOF after n bytes. It is how servers cap
+	// request bodies - but it ends silently, so the caller cannot tell
+	// "exactly n bytes" from "more than n, cut off".
+	body := strings.NewReader("0123456789ABCDEF")
+	got, _ := io.ReadAll(io.LimitReader(body, 10))
+	fmt.Printf("   LimitReader(16 bytes, 10): %q - no error\n", got)
+
+	// The fix: read one byte past the limit and check
+	_, err := readAtMost(strings.NewReader("0123456789ABCDEF"), 10)
+	fmt.Println("   readAtMost(16 bytes, 10):", err)
+	data, err := readAtMost(strings.NewReader("0123456789"), 10)
+	fmt.Printf("   readAtMost(10 bytes, 10): %q, err=%v\n", data, err)
+
+	// SectionReader reads a fixed window of an io.ReaderAt (a file, a
+	// bytes.Reader) - a record in a file, without seeking the original
+	src := strings.NewReader("HDR:record-1:record-2:")
+	section := io.NewSectionReader(src, 4, 8)
+	rec, _ := io.ReadAll(section)
+	fmt.Printf("   SectionReader(offset 4, length 8): %q\n", rec)
+}
+
+// ErrTooLarge is returned by readAtMost
+var ErrTooLarge = errors.New("input exceeds limit")
+
+// readAtMost reads all of r, failing if it holds more than limit bytes
+func readAtMost(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// 5. io.Pipe Between Goroutines
+// =============================
+func pipe() {
+	fmt.Println("\n5. IO.PIPE BETWEEN GOROUTINES:")
+
+	// Pipe connects code that wants to write to code that wants to read.
+	// There is no buffer: each Write blocks until Reads have consumed it,
+	// so the writer runs in its own goroutine.
+	//
+	// Here a producer gzips data straight into the pipe and the consumer
+	// decompresses from it. Neither side ever holds the compressed form.
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		for i := range 1000 {
+			fmt.Fprintf(gz, "log line %d\n", i)
+		}
+		// Close the gzip writer first (it flushes the footer), then the
+		// pipe; CloseWithError(nil) is Close
+		pw.CloseWithError(gz.Close())
+	}()
+
+	gr, err := gzip.NewReader(pr)
+	if err != nil {
+		fmt.Println("   gzip:", err)
+		return
+	}
+	n, err := io.Copy(io.Discard, gr)
+	fmt.Printf("   streamed %d decompressed bytes, err=%v\n", n, err)
+
+	// An error on one side reaches the other: the writer's
+	// CloseWithError becomes the reader's error, and closing the reader
+	// makes pending and later Writes fail
+	pr, pw = io.Pipe()
+	go func() {
+		pw.Write([]byte("partial "))
+		pw.CloseWithError(errors.New("upstream disconnected"))
+	}()
+	data, err := io.ReadAll(pr)
+	fmt.Printf("   reader got %q, then: %v\n", data, err)
+
+	pr, pw = io.Pipe()
+	pr.Close()
+	_, err = pw.Write([]byte("anyone?"))
+	fmt.Println("   write after the reader closed:", err)
+
+	// Forgetting to close the writer leaves the reader blocked forever.
+	// A WaitGroup (or errgroup) makes the writer's lifetime explicit.
+	pr, pw = io.Pipe()
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		defer pw.Close()
+		fmt.Fprint(pw, "done")
+	})
+	data, _ = io.ReadAll(pr)
+	wg.Wait()
+	fmt.Printf("   with defer pw.Close(): %q\n", data)
+}
+
+// 6. io.Copy Internals
+// ====================
+func copyInternals() {
+	fmt.Println("\n6. IO.COPY INTERNALS:")
+
+	// io.Copy(dst, src) tries two shortcuts before its generic loop:
+	//   1. if src implements io.WriterTo, call src.WriteTo(dst)
+	//   2. if dst implements io.ReaderFrom, call dst.ReadFrom(src)
+	//   3. otherwise allocate a 32 KiB buffer and loop Read/Write
+	// The shortcuts skip the buffer: bytes.Reader writes its slice in
+	// one call, and *os.File.ReadFrom can use sendfile/splice/
+	// copy_file_range so the data never enters user space.
+	payload := bytes.Repeat([]byte("x"), 100_000)
+
+	spy := &spyReader{r: bytes.NewReader(payload)}
+	io.Copy(&recordingWriter{}, spy)
+	fmt.Printf("   plain Read loop: %d Reads of %v bytes...\n", spy.calls, spy.sizes[:3])
+
+	// io.Discard has ReadFrom, with its own pooled 8 KiB buffer
+	spy = &spyReader{r: bytes.NewReader(payload)}
+	io.Copy(io.Discard, spy)
+	fmt.Printf("   into io.Discard (ReaderFrom): %d Reads of %d bytes...\n", spy.calls, spy.sizes[0])
+
+	// bytes.Reader has WriteTo, so Copy never calls Read at all
+	rec := &recordingWriter{}
+	io.Copy(rec, bytes.NewReader(payload))
+	fmt.Printf("   bytes.Reader (WriterTo): %d Write of %d bytes\n", rec.calls, rec.n)
+
+	// Wrapping hides the method. struct{ io.Reader } has only Read, so
+	// the shortcut is lost - the same happens through LimitReader,
+	// TeeReader and most middleware.
+	rec = &recordingWriter{}
+	io.Copy(rec, struct{ io.Reader }{bytes.NewReader(payload)})
+	fmt.Printf("   same reader, wrapped: %d Writes (32 KiB chunks)\n", rec.calls)
+
+	// CopyBuffer supplies the buffer - useful to reuse one across many
+	// copies - but is ignored when a shortcut applies
+	spy = &spyReader{r: bytes.NewReader(payload)}
+	io.CopyBuffer(&recordingWriter{}, spy, make([]byte, 1024))
+	fmt.Printf("   CopyBuffer(1 KiB): %d Reads\n", spy.calls)
+
+	// A short Write without an error is a bug in the writer; Copy turns
+	// it into io.ErrShortWrite rather than losing data silently
+	_, err := io.Copy(halfWriter{}, strings.NewReader("0123456789"))
+	fmt.Println("   writer that writes half:", err)
+}
+
+// Helper types
+// ============
+
+// countingWriter counts the bytes written to it
+type countingWriter struct{ n int64 }
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// errWriter fails every write
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+// halfWriter claims to write half of p and returns no error
+type halfWriter struct{}
+
+func (halfWriter) Write(p []byte) (int, error) { return len(p) / 2, nil }
+
+// spyReader records the buffer size of each Read. It has no WriteTo,
+// so Copy must use its own buffer.
+type spyReader struct {
+	r     io.Reader
+	calls int
+	sizes []int
+}
+
+func (s *spyReader) Read(p []byte) (int, error) {
+	s.calls++
+	s.sizes = append(s.sizes, len(p))
+	return s.r.Read(p)
+}
+
+// recordingWriter counts Write calls and bytes. It has no ReadFrom.
+type recordingWriter struct {
+	calls int
+	n     int
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	w.n += len(p)
+	return len(p), nil
+}
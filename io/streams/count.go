@@ -0,0 +1,38 @@
+package streams
+
+import (
+	"io"
+)
+
+// Counting Readers and Writers
+// ============================
+// The simplest useful wrapper: pass every call through and remember how
+// many bytes went by. Wrap a request body to log its size, or a
+// response writer to report bytes sent, without buffering either.
+
+// CountingReader counts the bytes read through it
+type CountingReader struct {
+	R io.Reader
+	N int64 // bytes read so far
+}
+
+// Read reads from R and adds the result to N. Only the n bytes actually
+// returned are counted, even when Read also returns an error.
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.R.Read(p)
+	c.N += int64(n)
+	return n, err
+}
+
+// CountingWriter counts the bytes written through it
+type CountingWriter struct {
+	W io.Writer
+	N int64 // bytes written so far
+}
+
+// Write writes to W and adds the bytes W accepted to N
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.W.Write(p)
+	c.N += int64(n)
+	return n, err
+}
@@ -0,0 +1,214 @@
+package streams
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Custom Readers and Writers - Tests
+// ==================================
+// Run with:
+//
+//   cd io/streams
+//   go test -v *.go
+//   go test -bench . -benchmem *.go
+//
+// testing/iotest does most of the work: TestReader checks a reader
+// against the io.Reader contract with reads of every size, and
+// OneByteReader, HalfReader, DataErrReader and ErrReader imitate the
+// awkward sources real code meets.
+
+// 1. Counting
+// ===========
+
+func TestCountingReader(t *testing.T) {
+	src := strings.Repeat("0123456789", 10_000)
+	cr := &CountingReader{R: iotest.HalfReader(strings.NewReader(src))}
+
+	// Counting composes with everything else: hash the stream as it goes
+	h := sha256.New()
+	if _, err := io.Copy(h, cr); err != nil {
+		t.Fatal(err)
+	}
+	if cr.N != int64(len(src)) {
+		t.Errorf("N = %d, want %d", cr.N, len(src))
+	}
+	if want := sha256.Sum256([]byte(src)); !bytes.Equal(h.Sum(nil), want[:]) {
+		t.Error("counting must not change the data")
+	}
+}
+
+func TestCountingReaderCountsDataBeforeError(t *testing.T) {
+	// DataErrReader returns the last bytes together with io.EOF. Those
+	// bytes count.
+	cr := &CountingReader{R: iotest.DataErrReader(strings.NewReader("abc"))}
+	data, err := io.ReadAll(cr)
+	if err != nil || string(data) != "abc" || cr.N != 3 {
+		t.Errorf("ReadAll = %q, %v; N = %d", data, err, cr.N)
+	}
+
+	boom := errors.New("boom")
+	cr = &CountingReader{R: io.MultiReader(strings.NewReader("xy"), iotest.ErrReader(boom))}
+	if _, err := io.ReadAll(cr); !errors.Is(err, boom) || cr.N != 2 {
+		t.Errorf("err = %v, N = %d", err, cr.N)
+	}
+}
+
+func TestCountingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	cw := &CountingWriter{W: &buf}
+	fmt.Fprintf(cw, "%s=%d\n", "answer", 42)
+	io.WriteString(cw, "more")
+	if cw.N != int64(buf.Len()) || cw.N != 14 {
+		t.Errorf("N = %d, buffer has %d", cw.N, buf.Len())
+	}
+
+	// Only what the inner writer accepted is counted
+	f, err := os.CreateTemp(t.TempDir(), "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	cw = &CountingWriter{W: f}
+	if _, err := cw.Write([]byte("lost")); err == nil || cw.N != 0 {
+		t.Errorf("write to a closed file: err = %v, N = %d", err, cw.N)
+	}
+}
+
+// 2. Same-Length Transforms
+// =========================
+
+func TestRot13(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Hello, Gopher!", "Uryyb, Tbcure!"},
+		{"abcxyz ABCXYZ", "nopklm NOPKLM"},
+		{"123 ✓", "123 ✓"}, // multi-byte UTF-8 bytes are never ASCII letters
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := io.ReadAll(MapReader(strings.NewReader(tt.in), Rot13))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Rot13(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		// Applying it twice gives back the input
+		twice, _ := io.ReadAll(MapReader(MapReader(strings.NewReader(tt.in), Rot13), Rot13))
+		if string(twice) != tt.in {
+			t.Errorf("Rot13 twice (%q) = %q", tt.in, twice)
+		}
+	}
+}
+
+func TestMapReaderContract(t *testing.T) {
+	// TestReader reads with every buffer size and checks n, err and the
+	// bytes against the expected content
+	in := strings.Repeat("The Quick Brown Fox. ", 50)
+	want := strings.ToUpper(in)
+	upper := func(c byte) byte {
+		if 'a' <= c && c <= 'z' {
+			return c - 'a' + 'A'
+		}
+		return c
+	}
+	if err := iotest.TestReader(MapReader(strings.NewReader(in), upper), []byte(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+// 3. Length-Changing Transforms
+// =============================
+
+func TestPrefixReader(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"one", "> one"},
+		{"one\n", "> one\n"},
+		{"one\ntwo\n", "> one\n> two\n"},
+		{"\n\n", "> \n> \n"},
+		{"no newline at end\nlast", "> no newline at end\n> last"},
+	}
+	for _, tt := range tests {
+		got, err := io.ReadAll(PrefixReader(strings.NewReader(tt.in), "> "))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("PrefixReader(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestPrefixReaderSmallBuffers(t *testing.T) {
+	// The output is longer than the input. With tiny reads on both ends,
+	// lines and prefixes are split across many calls, and the pending
+	// bytes must come out in order.
+	var in, want strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&in, "line %d\n", i)
+		fmt.Fprintf(&want, "[log] line %d\n", i)
+	}
+
+	r := PrefixReader(iotest.OneByteReader(strings.NewReader(in.String())), "[log] ")
+	if err := iotest.TestReader(r, []byte(want.String())); err != nil {
+		t.Error(err)
+	}
+
+	r = PrefixReader(iotest.HalfReader(strings.NewReader(in.String())), "[log] ")
+	got, err := io.ReadAll(iotest.OneByteReader(r))
+	if err != nil || string(got) != want.String() {
+		t.Errorf("one byte at a time: err = %v, %d bytes, want %d", err, len(got), want.Len())
+	}
+}
+
+func TestPrefixReaderError(t *testing.T) {
+	// Bytes read before the error are transformed and delivered first;
+	// the error follows and stays
+	boom := errors.New("connection reset")
+	r := PrefixReader(io.MultiReader(strings.NewReader("a\nb"), iotest.ErrReader(boom)), "# ")
+	got, err := io.ReadAll(r)
+	if string(got) != "# a\n# b" || !errors.Is(err, boom) {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if n, err := r.Read(make([]byte, 10)); n != 0 || !errors.Is(err, boom) {
+		t.Errorf("read after error = %d, %v; want the same error again", n, err)
+	}
+}
+
+// 4. Benchmarks
+// =============
+
+var benchInput = strings.Repeat("2024-01-01 INFO request served in 3ms\n", 10_000)
+
+func BenchmarkPrefixReader(b *testing.B) {
+	b.SetBytes(int64(len(benchInput)))
+	b.ReportAllocs()
+	for b.Loop() {
+		io.Copy(io.Discard, PrefixReader(strings.NewReader(benchInput), "web-1 | "))
+	}
+}
+
+func BenchmarkMapReader(b *testing.B) {
+	b.SetBytes(int64(len(benchInput)))
+	b.ReportAllocs()
+	for b.Loop() {
+		io.Copy(io.Discard, MapReader(strings.NewReader(benchInput), Rot13))
+	}
+}
+
+// Examples
+// ========
+
+func ExamplePrefixReader() {
+	// Label each line of a subprocess's output, counting what went by
+	logs := strings.NewReader("starting\nlistening on :8080\n")
+	counted := &CountingReader{R: logs}
+	io.Copy(os.Stdout, PrefixReader(counted, "web-1 | "))
+	fmt.Println(counted.N, "bytes from the source")
+	// Output:
+	// web-1 | starting
+	// web-1 | listening on :8080
+	// 28 bytes from the source
+}
@@ -0,0 +1,111 @@
+package streams
+
+import (
+	"bytes"
+	"io"
+)
+
+// Transforming Readers
+// ====================
+// A transforming reader sits between a source and a consumer and
+// changes the bytes as they pass. Two shapes come up:
+//
+//   - same length out as in (case mapping, ROT13, masking): transform
+//     p in place after the inner Read, with no state between calls
+//   - different length (inserting, escaping, decoding): one Read of the
+//     source can produce more than fits in p, so the reader keeps the
+//     overflow and hands it out on later calls
+//
+// Either way the io.Reader contract still applies: return the n bytes
+// produced before any error, never return 0, nil unless len(p) == 0, and
+// keep returning the same error once the source fails.
+
+// MapReader applies f to every byte read from r
+func MapReader(r io.Reader, f func(byte) byte) io.Reader {
+	return &mapReader{r: r, f: f}
+}
+
+type mapReader struct {
+	r io.Reader
+	f func(byte) byte
+}
+
+func (m *mapReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	for i, c := range p[:n] {
+		p[i] = m.f(c)
+	}
+	return n, err
+}
+
+// Rot13 rotates ASCII letters by 13 places; applying it twice restores
+// the input. Pass it to MapReader.
+func Rot13(c byte) byte {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return 'a' + (c-'a'+13)%26
+	case 'A' <= c && c <= 'Z':
+		return 'A' + (c-'A'+13)%26
+	}
+	return c
+}
+
+// PrefixReader inserts prefix at the start of every line read from r,
+// like `sed 's/^/prefix/'`. The output is longer than the input, so
+// bytes that do not fit in the caller's buffer are held for the next
+// Read.
+func PrefixReader(r io.Reader, prefix string) io.Reader {
+	return &prefixReader{r: r, prefix: []byte(prefix), atLineStart: true}
+}
+
+type prefixReader struct {
+	r           io.Reader
+	prefix      []byte
+	atLineStart bool   // the next source byte begins a line
+	pending     []byte // transformed bytes; pending[off:] not yet returned
+	off         int
+	buf         []byte // scratch space for source reads
+	err         error  // sticky source error, returned once pending is empty
+}
+
+func (p *prefixReader) Read(out []byte) (int, error) {
+	if len(out) == 0 {
+		return 0, nil
+	}
+	// Refill until there is something to return or the source is done.
+	// A source Read of 0 bytes with no error is legal, so loop.
+	for p.off == len(p.pending) && p.err == nil {
+		if p.buf == nil {
+			p.buf = make([]byte, 4096)
+		}
+		n, err := p.r.Read(p.buf)
+		p.pending, p.off = p.pending[:0], 0 // reuse the array
+		p.transform(p.buf[:n])
+		p.err = err
+	}
+
+	n := copy(out, p.pending[p.off:])
+	p.off += n
+	if p.off == len(p.pending) && p.err != nil {
+		return n, p.err
+	}
+	return n, nil
+}
+
+// transform appends src with prefixes inserted to p.pending
+func (p *prefixReader) transform(src []byte) {
+	for len(src) > 0 {
+		if p.atLineStart {
+			p.pending = append(p.pending, p.prefix...)
+			p.atLineStart = false
+		}
+		i := bytes.IndexByte(src, '\n')
+		if i < 0 {
+			p.pending = append(p.pending, src...)
+			return
+		}
+		p.pending = append(p.pending, src[:i+1]...)
+		src = src[i+1:]
+		p.atLineStart = true
+	}
+}
@@ -3,13 +3,20 @@ package main
 import (
 	"fmt"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"testing"
 	"time"
+	"unsafe"
 )
 
 // Memory Management Tips and Best Practices
 // =======================================
 
+// stringSink keeps benchmark results alive so the work is not optimized away
+var stringSink string
+
 func main() {
 	fmt.Println("=== Memory Management Tips ===")
 	
@@ -91,10 +98,6 @@ func avoidUnnecessaryPointers() {
 	
 	// GOOD: Pass by value for small structs
 	func() {
-		type SmallStruct struct {
-			Value int
-		}
-		
 		s := SmallStruct{Value: 42}
 		result := processSmallStruct(s)  // Stack allocation
 		fmt.Printf("     Small struct: %d (stack)\n", result)
@@ -102,10 +105,6 @@ func avoidUnnecessaryPointers() {
 	
 	// BAD: Unnecessary pointer for small struct
 	func() {
-		type SmallStruct struct {
-			Value int
-		}
-		
 		s := &SmallStruct{Value: 42}
 		result := processSmallStructPointer(s)  // Heap allocation
 		fmt.Printf("     Small struct: %d (heap)\n", result)
@@ -115,15 +114,7 @@ func avoidUnnecessaryPointers() {
 func valueReceivers() {
 	fmt.Println("   Use Value Receivers:")
 	
-	// GOOD: Value receiver for small structs
-	type Point struct {
-		X, Y int
-	}
-	
-	func (p Point) Distance() float64 {
-		return float64(p.X*p.X + p.Y*p.Y)
-	}
-	
+	// GOOD: Value receiver for small structs (see Point.Distance below)
 	p := Point{X: 3, Y: 4}
 	distance := p.Distance()
 	fmt.Printf("     Distance: %f (value receiver)\n", distance)
@@ -334,24 +325,34 @@ func memoryLeaks() {
 func unnecessaryAllocations() {
 	fmt.Println("   Unnecessary Allocations:")
 	
-	// BAD: String concatenation in loop
-	func() {
-		var result string
-		for i := 0; i < 100; i++ {
-			result += fmt.Sprintf("%d ", i)  // Creates new string each time
+	// BAD: String concatenation in loop - each += copies the whole string
+	concat := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var result string
+			for j := 0; j < 100; j++ {
+				result += strconv.Itoa(j) + " "
+			}
+			stringSink = result
 		}
-		fmt.Printf("     String concatenation: %d chars\n", len(result))
-	}()
-	
-	// GOOD: Use strings.Builder
-	func() {
-		var builder strings.Builder
-		for i := 0; i < 100; i++ {
-			builder.WriteString(fmt.Sprintf("%d ", i))
+	})
+	fmt.Printf("     String concatenation: %d allocs/op, %d B/op\n", concat.AllocsPerOp(), concat.AllocedBytesPerOp())
+	
+	// GOOD: Use strings.Builder, sized up front with Grow
+	builder := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var sb strings.Builder
+			sb.Grow(300)
+			for j := 0; j < 100; j++ {
+				sb.WriteString(strconv.Itoa(j))
+				sb.WriteByte(' ')
+			}
+			stringSink = sb.String()
 		}
-		result := builder.String()
-		fmt.Printf("     strings.Builder: %d chars\n", len(result))
-	}()
+	})
+	fmt.Printf("     strings.Builder: %d allocs/op, %d B/op\n", builder.AllocsPerOp(), builder.AllocedBytesPerOp())
+	fmt.Println("     More variants in ../strings-bytes/concat/")
 }
 
 func largeObjectAllocation() {
@@ -497,3 +498,12 @@ type Person struct {
 	Name string
 	Age  int
 }
+
+// Point is small enough that a value receiver is cheaper than a pointer
+type Point struct {
+	X, Y int
+}
+
+func (p Point) Distance() float64 {
+	return float64(p.X*p.X + p.Y*p.Y)
+}
@@ -3,12 +3,19 @@ package main
 import (
 	"fmt"
 	"runtime"
-	"time"
+	"testing"
 )
 
 // Detailed Stack vs Heap Examples
 // ===============================
 
+// Package-level sinks: the compiler cannot prove them unused, so results
+// stored in them are really computed, and pointers stored in them escape
+var (
+	intSink int
+	ptrSink *int
+)
+
 func main() {
 	fmt.Println("=== Stack vs Heap Allocation Examples ===")
 	
@@ -234,20 +241,22 @@ func performanceComparison() {
 	fmt.Println("\n7. PERFORMANCE COMPARISON:")
 	
 	// Test stack allocation performance
-	start := time.Now()
-	for i := 0; i < 1000000; i++ {
-		stackOperation()
-	}
-	stackTime := time.Since(start)
-	fmt.Printf("   Stack allocation time: %v\n", stackTime)
+	stack := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			intSink = stackOperation()
+		}
+	})
+	fmt.Printf("   Stack allocation: %s\n", benchmarkLine(stack))
 	
 	// Test heap allocation performance
-	start = time.Now()
-	for i := 0; i < 1000000; i++ {
-		heapOperation()
-	}
-	heapTime := time.Since(start)
-	fmt.Printf("   Heap allocation time: %v\n", heapTime)
+	heap := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ptrSink = heapOperation()
+		}
+	})
+	fmt.Printf("   Heap allocation: %s\n", benchmarkLine(heap))
 	
 	// Show memory statistics
 	var m runtime.MemStats
@@ -256,20 +265,27 @@ func performanceComparison() {
 	fmt.Printf("   GC cycles: %d\n", m.NumGC)
 }
 
-func stackOperation() {
+func stackOperation() int {
 	// Fast stack operations
 	var a, b, c int = 1, 2, 3
-	var result int = a + b + c
-	_ = result  // Prevent optimization
+	return a + b + c  // returned by value: nothing escapes
 }
 
-func heapOperation() {
-	// Slower heap operations
+func heapOperation() *int {
+	// Slower heap operations: result is returned by pointer, so it
+	// escapes; a, b and c still stay on the stack
 	a := new(int)
 	b := new(int)
 	c := new(int)
 	*a, *b, *c = 1, 2, 3
 	result := new(int)
 	*result = *a + *b + *c
-	_ = result  // Prevent optimization
+	return result
+}
+
+// benchmarkLine formats a testing.Benchmark result. See
+// testing/go_benchmarking.go for why sinks and b.N matter.
+func benchmarkLine(r testing.BenchmarkResult) string {
+	return fmt.Sprintf("%.1f ns/op, %d B/op, %d allocs/op",
+		float64(r.T.Nanoseconds())/float64(r.N), r.AllocedBytesPerOp(), r.AllocsPerOp())
 }
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// astindex - Tests
+// ================
+// Run with:
+//
+//   cd metaprogramming/astindex
+//   go test -v *.go
+//
+// The analysis is checked on small sources; the last tests check the
+// repository itself: that topics.json matches a fresh scan, and that
+// the copies of a test helper every package must keep still match.
+
+func parse(t *testing.T, path, src string) File {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := File{Path: path}
+	analyze(fset, f, &info)
+	return info
+}
+
+// 1. Headings
+// ===========
+
+func TestHeadings(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"// Title\n// =====\n", []string{"Title"}},
+		{"// 1. First\n// ==\n", nil}, // too short to be an underline
+		{"// Title\n// =====\n// Body text\n//\n// 2. Next\n// =========\n", []string{"Title", "2. Next"}},
+		{"// Title\n// -----\n", nil},
+		{"// ======\n// ======\n", nil},
+		{"/*\nBlock\n=====\n*/", []string{"Block"}},
+		{"// Just a comment\n", nil},
+	}
+	for _, tt := range tests {
+		f, err := parser.ParseFile(token.NewFileSet(), "h.go", "package h\n\n"+tt.src+"\n", parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, g := range f.Comments {
+			got = append(got, headings(g)...)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("headings(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// 2. Analyzing a File
+// ===================
+
+func TestAnalyze(t *testing.T) {
+	src := `package demo
+
+import u "unsafe"
+
+// Demo
+// ====
+
+// 1. Sizes
+// ========
+
+func Size(v int64) uintptr { return u.Sizeof(v) + u.Alignof(v) }
+
+type T struct{}
+
+func (T) M()  {}
+func (*T) N() {}
+
+func TestLike()  {} // not a test outside a _test.go file
+func Testimony() {}
+
+var s = "// Not\n// ==="
+`
+	got := parse(t, "demo.go", src)
+	if got.Package != "demo" || got.Title != "Demo" || !slices.Equal(got.Sections, []string{"1. Sizes"}) {
+		t.Errorf("package %q, title %q, sections %q", got.Package, got.Title, got.Sections)
+	}
+	if got.Funcs != 3 || got.Methods != 2 || got.Tests != 0 {
+		t.Errorf("funcs %d, methods %d, tests %d; want 3, 2, 0", got.Funcs, got.Methods, got.Tests)
+	}
+	want := []Use{{Line: 11, Column: 37, Name: "Sizeof"}, {Line: 11, Column: 51, Name: "Alignof"}}
+	if !slices.Equal(got.Unsafe, want) {
+		t.Errorf("unsafe uses %+v, want %+v", got.Unsafe, want)
+	}
+}
+
+func TestAnalyzeTests(t *testing.T) {
+	src := `package demo
+
+import "testing"
+
+func TestA(t *testing.T)      {}
+func Test(t *testing.T)       {}
+func BenchmarkB(b *testing.B) {}
+func FuzzC(f *testing.F)      {}
+func ExampleD()               {}
+func Testimony()              {}
+func helper()                 {}
+`
+	got := parse(t, "demo_test.go", src)
+	if got.Tests != 5 || got.Funcs != 2 {
+		t.Errorf("tests %d, funcs %d; want 5, 2", got.Tests, got.Funcs)
+	}
+}
+
+func TestUnsafeImportNames(t *testing.T) {
+	tests := []struct {
+		imp  string
+		body string
+		want int
+	}{
+		{`import "unsafe"`, "var _ = unsafe.Sizeof(0)", 1},
+		{`import u "unsafe"`, "var _ = u.Sizeof(0)", 1},
+		{`import u "unsafe"`, "var _ = unsafe.Sizeof", 0}, // not the import's name
+		{`import _ "unsafe"`, "", 0},
+		{`import "strings"`, "var _ = strings.ToUpper", 0},
+	}
+	for _, tt := range tests {
+		got := parse(t, "u.go", "package u\n\n"+tt.imp+"\n\n"+tt.body+"\n")
+		if len(got.Unsafe) != tt.want {
+			t.Errorf("%s; %s: %d uses, want %d", tt.imp, tt.body, len(got.Unsafe), tt.want)
+		}
+	}
+}
+
+// 3. Scanning a Tree
+// ==================
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a/a.go":          "package a\n\n// A\n// =\n\n// Alpha\n// =====\n\nfunc F() {}\n",
+		"a/a_test.go":     "package a\n\n// Tests\n// =====\n\nfunc TestF() {}\n",
+		"a/broken.go":     "package a\n\nfunc (\n",
+		"a/testdata/x.go": "package x\n",
+		".hidden/h.go":    "package h\n",
+		"_skip/s.go":      "package s\n",
+		"b/notes.txt":     "// Not Go\n// ======\n",
+		"b/untitled/u.go": "package untitled\n",
+	}
+	for name, body := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range got {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"a/a.go", "a/a_test.go", "a/broken.go", "b/untitled/u.go"}; !slices.Equal(paths, want) {
+		t.Fatalf("scanned %q, want %q", paths, want)
+	}
+	if got[2].Error == "" || got[2].Package != "a" {
+		t.Errorf("broken.go: error %q, package %q; want an error and the recovered package", got[2].Error, got[2].Package)
+	}
+
+	// Only titled, non-test files are topics; "A" with its short
+	// underline is not a heading
+	topics := Topics(got)
+	if len(topics) != 1 || topics[0].Path != "a/a.go" || topics[0].Title != "Alpha" {
+		t.Errorf("topics %+v", topics)
+	}
+}
+
+// 4. The Repository's Index
+// =========================
+
+func TestTopicsUpToDate(t *testing.T) {
+	root := filepath.Join("..", "..")
+	files, err := Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := WriteTopics(&want, Topics(files)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "topics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Error("topics.json is stale; run: go generate main.go")
+	}
+}
+
+// The index must not depend on which file the walk meets first
+func TestTopicsSorted(t *testing.T) {
+	files := []File{{Path: "b.go", Title: "B"}, {Path: "a.go", Title: "A"}, {Path: "c_test.go", Title: "C"}}
+	topics := Topics(files)
+	if len(topics) != 2 || topics[0].Path != "a.go" || topics[1].Path != "b.go" {
+		t.Errorf("topics %+v", topics)
+	}
+}
+
+// 5. Copied Test Helpers
+// ======================
+
+// requireGo skips a test that runs the go command under -short, or
+// when there is no go command. Without a module, a package cannot
+// import test code from another, so each package whose tests run the
+// go command keeps a copy, with a one-line comment pointing here. The
+// copies must stay one per directory and identical, comment included.
+func TestRequireGoCopiesMatch(t *testing.T) {
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+	copies := map[string]string{} // directory -> source
+	var first string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil // Scan reports files that do not parse
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Name.Name != "requireGo" {
+				continue
+			}
+			start := fn.Pos()
+			if fn.Doc != nil {
+				start = fn.Doc.Pos()
+			}
+			body := string(src[fset.Position(start).Offset:fset.Position(fn.End()).Offset])
+			dir := filepath.Dir(path)
+			if _, dup := copies[dir]; dup {
+				t.Errorf("%s: a second requireGo in %s", path, dir)
+			}
+			copies[dir] = body
+			if first == "" {
+				first = body
+			} else if body != first {
+				t.Errorf("%s: requireGo differs from the other copies:\n%s", path, body)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copies) == 0 {
+		t.Error("no copies of requireGo found")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Indexing the Repository
+// =======================
+// Scan parses every Go file under a root and records what the lessons
+// are made of. Everything comes from the syntax tree, not from text
+// matching, so a "// ===" inside a string literal is not a heading and
+// an unsafe.Pointer in a comment is not a use of unsafe.
+
+// File is what one Go file contains
+type File struct {
+	Path     string   // slash-separated, relative to the root
+	Package  string   // the package clause
+	Title    string   // the first heading
+	Sections []string // the headings after it
+	Funcs    int      // functions without a receiver
+	Methods  int
+	Tests    int // Test, Benchmark, Fuzz and Example functions
+	Unsafe   []Use
+	Error    string // the syntax error, if the file does not parse
+}
+
+// Use is one reference to a member of package unsafe
+type Use struct {
+	Line, Column int
+	Name         string // Pointer, Sizeof, Add...
+}
+
+// Scan parses the Go files under root. Hidden directories, those
+// starting with _ and testdata are skipped, as the go command skips
+// them. A file that does not parse is recorded with its error and
+// whatever the parser recovered.
+func Scan(root string) ([]File, error) {
+	var files []File
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		// ParseComments keeps the comments in f.Comments; without it
+		// the headings would be thrown away
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		info := File{Path: filepath.ToSlash(rel)}
+		if err != nil {
+			info.Error = err.Error()
+		}
+		if f != nil {
+			analyze(fset, f, &info)
+		}
+		files = append(files, info)
+		return nil
+	})
+	return files, err
+}
+
+// analyze fills in info from a parsed file
+func analyze(fset *token.FileSet, f *ast.File, info *File) {
+	info.Package = f.Name.Name
+
+	// Declarations: only the top level holds funcs and methods, so
+	// f.Decls is enough without walking the whole tree
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		switch {
+		case fn.Recv != nil:
+			info.Methods++
+		case strings.HasSuffix(info.Path, "_test.go") && isTest(fn):
+			info.Tests++
+		default:
+			info.Funcs++
+		}
+	}
+
+	// Comments are not nodes of the tree: they sit beside it in
+	// f.Comments, in source order
+	for _, group := range f.Comments {
+		for _, h := range headings(group) {
+			if info.Title == "" {
+				info.Title = h
+			} else {
+				info.Sections = append(info.Sections, h)
+			}
+		}
+	}
+
+	info.Unsafe = unsafeUses(fset, f)
+}
+
+// isTest reports whether fn is a function go test runs: TestXxx(t),
+// BenchmarkXxx(b), FuzzXxx(f) or an ExampleXxx()
+func isTest(fn *ast.FuncDecl) bool {
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+		rest, ok := strings.CutPrefix(fn.Name.Name, prefix)
+		if ok && (rest == "" || !isLower(rest[0])) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLower(c byte) bool { return 'a' <= c && c <= 'z' }
+
+// headings returns the lines of a comment group that are underlined
+// with '=', the repository's heading style:
+//
+//	// 2. TeeReader
+//	// ============
+func headings(group *ast.CommentGroup) []string {
+	// Text strips the comment markers and the first space of each line
+	lines := strings.Split(group.Text(), "\n")
+	var out []string
+	for i := 0; i+1 < len(lines); i++ {
+		text, under := strings.TrimSpace(lines[i]), strings.TrimSpace(lines[i+1])
+		if strings.Trim(text, "=") != "" && len(under) >= 3 && strings.Trim(under, "=") == "" {
+			out = append(out, text)
+			i++
+		}
+	}
+	return out
+}
+
+// unsafeUses finds the selectors on the name package unsafe is
+// imported under: unsafe.Pointer, or u.Pointer after import u "unsafe".
+// A local variable called unsafe would fool it; telling the two apart
+// takes go/types.
+func unsafeUses(fset *token.FileSet, f *ast.File) []Use {
+	local := ""
+	for _, imp := range f.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path != "unsafe" {
+			continue
+		}
+		local = "unsafe"
+		if imp.Name != nil {
+			local = imp.Name.Name
+		}
+	}
+	if local == "" || local == "_" || local == "." {
+		return nil
+	}
+
+	var uses []Use
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if id, ok := sel.X.(*ast.Ident); ok && id.Name == local {
+			pos := fset.Position(sel.Pos())
+			uses = append(uses, Use{Line: pos.Line, Column: pos.Column, Name: sel.Sel.Name})
+		}
+		return true
+	})
+	return uses
+}
+
+// Topic is one entry of the topic index: a lesson file and its
+// headings. learnctl topics searches these.
+type Topic struct {
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Sections []string `json:"sections,omitempty"`
+}
+
+// Topics builds the index from the files with a title. Tests are left
+// out: their headings describe the tests, not the topic.
+func Topics(files []File) []Topic {
+	var topics []Topic
+	for _, f := range files {
+		if f.Title == "" || strings.HasSuffix(f.Path, "_test.go") {
+			continue
+		}
+		topics = append(topics, Topic{Path: f.Path, Title: f.Title, Sections: f.Sections})
+	}
+	slices.SortFunc(topics, func(a, b Topic) int { return strings.Compare(a.Path, b.Path) })
+	return topics
+}
+
+// WriteTopics writes the index as indented JSON, one topic per object,
+// so a change to one lesson is a small diff
+func WriteTopics(w io.Writer, topics []Topic) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if topics == nil {
+		topics = []Topic{}
+	}
+	return enc.Encode(topics)
+}
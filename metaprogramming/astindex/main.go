@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// go/ast and go/parser - Reading Go as Data
+// =========================================
+// go/parser turns source text into a tree of go/ast nodes: an
+// *ast.File holds declarations, a *ast.FuncDecl holds a body, and so
+// down to identifiers and literals. Every node knows its position, a
+// token.Pos that a token.FileSet turns back into file:line:column.
+//
+//	parser.ParseExpr(src)            one expression
+//	parser.ParseFile(fset, ...)      one file; mode bits choose what is kept
+//	ast.Inspect(node, func)          visit every node below node, depth first
+//	ast.Print / ast.Fprint           dump a tree, for learning and debugging
+//
+// The tree is syntax only. It knows that unsafe.Sizeof(x) is a selector
+// on the identifier "unsafe", not that "unsafe" names a package or what
+// type x has - that is go/types. Comments are not nodes either: with
+// parser.ParseComments they are kept in File.Comments, beside the tree.
+//
+// This lesson runs those APIs over the repository itself: it counts
+// functions and sections, finds every use of package unsafe, and writes
+// topics.json, the index that "learnctl topics" searches.
+//
+// Run with:
+//
+//	cd metaprogramming/astindex
+//	go run main.go index.go               # report on the repository
+//	go run main.go index.go -write ../../topics.json
+//	go generate main.go                   # the same, as a directive
+//	go test -v *.go
+
+//go:generate go run main.go index.go -write ../../topics.json
+
+// snippet is parsed in sections 2 and 3
+const snippet = `package demo
+
+import u "unsafe"
+
+// Size Helpers
+// ============
+
+// 1. Sizes
+// ========
+
+// Size reports how large v is
+func Size(v int64) uintptr { return u.Sizeof(v) }
+
+type Box struct{ n int }
+
+func (b *Box) Len() int { return b.n }
+
+var banner = "// not a comment\n// ================"
+`
+
+func main() {
+	root := flag.String("root", filepath.Join("..", ".."), "repository `dir` to scan")
+	write := flag.String("write", "", "write the topic index to `file`")
+	flag.Parse()
+
+	fmt.Println("=== go/ast and go/parser ===")
+
+	// 1. An expression's tree
+	expressionTree()
+
+	// 2. A file's declarations
+	declarations()
+
+	// 3. Comments beside the tree
+	comments()
+
+	files, err := Scan(*root)
+	if err != nil {
+		fmt.Println("scan:", err)
+		os.Exit(1)
+	}
+
+	// 4. The whole repository
+	repository(files)
+
+	// 5. Every use of unsafe
+	unsafeReport(files)
+
+	// 6. The topic index
+	if err := topicIndex(files, *root, *write); err != nil {
+		fmt.Println("topics:", err)
+		os.Exit(1)
+	}
+}
+
+// 1. An Expression's Tree
+// =======================
+func expressionTree() {
+	fmt.Println("\n1. AN EXPRESSION'S TREE:")
+
+	// ParseExpr needs no FileSet: positions are offsets into the string
+	expr, err := parser.ParseExpr("unsafe.Sizeof(x) + 1")
+	if err != nil {
+		fmt.Println("   parse:", err)
+		return
+	}
+	// Fprint writes every field of every node; nil hides the positions
+	var buf bytes.Buffer
+	ast.Fprint(&buf, nil, expr, ast.NotNilFilter)
+	for line := range strings.Lines(buf.String()) {
+		fmt.Print("   ", line)
+	}
+	fmt.Println("   -> a BinaryExpr whose X is a CallExpr on a SelectorExpr:")
+	fmt.Println("      the parser does not know that unsafe is a package")
+}
+
+// 2. A File's Declarations
+// ========================
+func declarations() {
+	fmt.Println("\n2. A FILE'S DECLARATIONS:")
+
+	// One FileSet per run: it maps every token.Pos back to a file
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "demo.go", snippet, parser.ParseComments)
+	if err != nil {
+		fmt.Println("   parse:", err)
+		return
+	}
+	fmt.Printf("   package %s, %d imports, %d top-level declarations\n", f.Name.Name, len(f.Imports), len(f.Decls))
+
+	// f.Decls holds *ast.GenDecl (import, const, type, var) and
+	// *ast.FuncDecl; a type switch tells them apart
+	for _, decl := range f.Decls {
+		pos := fset.Position(decl.Pos())
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			fmt.Printf("   %-12s GenDecl %s\n", pos, d.Tok)
+		case *ast.FuncDecl:
+			kind := "func"
+			if d.Recv != nil {
+				kind = "method on " + typeString(d.Recv.List[0].Type)
+			}
+			fmt.Printf("   %-12s FuncDecl %s (%s)\n", pos, d.Name.Name, kind)
+		}
+	}
+
+	// ast.Inspect reaches nodes at any depth: here, the selector in
+	// Size's body
+	var info File
+	analyze(fset, f, &info)
+	for _, use := range info.Unsafe {
+		fmt.Printf("   demo.go:%d:%d   u.%s - unsafe under its import name\n", use.Line, use.Column, use.Name)
+	}
+}
+
+// typeString renders a receiver type expression: *Box
+func typeString(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		return "*" + typeString(star.X)
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+// 3. Comments Beside the Tree
+// ===========================
+func comments() {
+	fmt.Println("\n3. COMMENTS BESIDE THE TREE:")
+
+	fset := token.NewFileSet()
+	plain, _ := parser.ParseFile(fset, "demo.go", snippet, 0)
+	withComments, _ := parser.ParseFile(fset, "demo.go", snippet, parser.ParseComments)
+	fmt.Printf("   %-22s %d comment groups\n", "mode 0:", len(plain.Comments))
+	fmt.Printf("   %-22s %d comment groups\n", "parser.ParseComments:", len(withComments.Comments))
+
+	// The string literal in banner looks like a heading to grep, but it
+	// is a *ast.BasicLit, not a comment
+	var info File
+	analyze(fset, withComments, &info)
+	fmt.Printf("   title %q, sections %q\n", info.Title, info.Sections)
+	fmt.Println("   a doc comment is also attached to its declaration:")
+	for _, decl := range withComments.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Doc != nil {
+			fmt.Printf("   %s.Doc = %q\n", fn.Name.Name, fn.Doc.Text())
+		}
+	}
+}
+
+// 4. The Whole Repository
+// =======================
+func repository(files []File) {
+	fmt.Println("\n4. THE WHOLE REPOSITORY:")
+
+	type totals struct{ files, funcs, methods, tests, sections int }
+	byDir := map[string]*totals{}
+	var sum totals
+	var broken []File
+	for _, f := range files {
+		top, _, _ := strings.Cut(f.Path, "/")
+		if !strings.Contains(f.Path, "/") {
+			top = "."
+		}
+		t := byDir[top]
+		if t == nil {
+			t = &totals{}
+			byDir[top] = t
+		}
+		for _, tt := range []*totals{t, &sum} {
+			tt.files++
+			tt.funcs += f.Funcs
+			tt.methods += f.Methods
+			tt.tests += f.Tests
+			tt.sections += len(f.Sections)
+		}
+		if f.Error != "" {
+			broken = append(broken, f)
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "   dir\tfiles\tfuncs\tmethods\ttests\tsections\t")
+	dirs := make([]string, 0, len(byDir))
+	for d := range byDir {
+		dirs = append(dirs, d)
+	}
+	slices.Sort(dirs)
+	for _, d := range dirs {
+		t := byDir[d]
+		fmt.Fprintf(tw, "   %s\t%d\t%d\t%d\t%d\t%d\t\n", d, t.files, t.funcs, t.methods, t.tests, t.sections)
+	}
+	fmt.Fprintf(tw, "   total\t%d\t%d\t%d\t%d\t%d\t\n", sum.files, sum.funcs, sum.methods, sum.tests, sum.sections)
+	tw.Flush()
+
+	for _, f := range broken {
+		fmt.Printf("   does not parse: %s\n", f.Error)
+	}
+}
+
+// 5. Every Use of unsafe
+// ======================
+func unsafeReport(files []File) {
+	fmt.Println("\n5. EVERY USE OF UNSAFE:")
+
+	byName := map[string]int{}
+	for _, f := range files {
+		if len(f.Unsafe) == 0 {
+			continue
+		}
+		names := map[string]int{}
+		for _, use := range f.Unsafe {
+			names[use.Name]++
+			byName[use.Name]++
+		}
+		first := f.Unsafe[0]
+		fmt.Printf("   %s:%d:%d  %d uses %v\n", f.Path, first.Line, first.Column, len(f.Unsafe), names)
+	}
+	fmt.Printf("   by member: %v\n", byName)
+	fmt.Println("   (testdata is skipped, as the go command skips it)")
+}
+
+// 6. The Topic Index
+// ==================
+func topicIndex(files []File, root, write string) error {
+	fmt.Println("\n6. THE TOPIC INDEX:")
+
+	topics := Topics(files)
+	var sections int
+	for _, t := range topics {
+		sections += len(t.Sections)
+	}
+	fmt.Printf("   %d lesson files with a title, %d sections\n", len(topics), sections)
+
+	var buf bytes.Buffer
+	if err := WriteTopics(&buf, topics); err != nil {
+		return err
+	}
+	if write != "" {
+		if err := os.WriteFile(write, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("   wrote %s\n", write)
+		return nil
+	}
+	current, err := os.ReadFile(filepath.Join(root, "topics.json"))
+	switch {
+	case err != nil:
+		fmt.Printf("   no topics.json yet: %v\n", err)
+	case bytes.Equal(current, buf.Bytes()):
+		fmt.Println("   topics.json is up to date")
+	default:
+		fmt.Println("   topics.json is stale: run go generate main.go")
+	}
+	fmt.Println("   search it with: learnctl topics unsafe")
+	return nil
+}
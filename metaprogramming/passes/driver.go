@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A Driver in Miniature
+// =====================
+// golang.org/x/tools/go/analysis splits a checker in two. An Analyzer
+// says what to look for; a driver (go vet, gopls, singlechecker,
+// analysistest) loads packages, type-checks them, and runs the
+// analyzers. The types below have the shape of the real ones, cut to
+// what one package needs:
+//
+//	Analyzer  Name, Doc, Requires, Run
+//	Pass      one analyzer on one package: Fset, Files, Pkg, TypesInfo,
+//	          ResultOf for the analyzers it requires, and Report
+//
+// Requires makes a graph. The driver runs each analyzer once per
+// package, its requirements first, and hands their results over in
+// ResultOf - so inspect.Analyzer walks the syntax once however many
+// analyzers use it. The real framework adds Facts, values an analyzer
+// attaches to objects in one package and reads back while analyzing
+// the packages that import it; a single-package driver has no use for
+// them.
+
+// Analyzer is one check
+type Analyzer struct {
+	Name     string
+	Doc      string
+	Requires []*Analyzer
+	Run      func(*Pass) (any, error)
+}
+
+// Pass is an Analyzer applied to one package
+type Pass struct {
+	Analyzer  *Analyzer
+	Fset      *token.FileSet
+	Files     []*ast.File
+	Pkg       *types.Package
+	TypesInfo *types.Info
+	ResultOf  map[*Analyzer]any
+	Report    func(Diagnostic)
+}
+
+// Reportf reports a diagnostic without a fix
+func (p *Pass) Reportf(pos token.Pos, format string, args ...any) {
+	p.Report(Diagnostic{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// Diagnostic is a finding, with the fixes a tool may apply
+type Diagnostic struct {
+	Pos            token.Pos
+	Message        string
+	SuggestedFixes []SuggestedFix
+}
+
+// SuggestedFix is a set of edits that resolves a diagnostic
+type SuggestedFix struct {
+	Message   string
+	TextEdits []TextEdit
+}
+
+// TextEdit replaces the source in [Pos, End) with NewText
+type TextEdit struct {
+	Pos, End token.Pos
+	NewText  []byte
+}
+
+// Finding is a diagnostic with its analyzer and resolved position
+type Finding struct {
+	Analyzer string
+	Pos      token.Position
+	Diagnostic
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.Pos, f.Message, f.Analyzer)
+}
+
+// Package is a loaded, type-checked package
+type Package struct {
+	Dir   string
+	Fset  *token.FileSet
+	Files []*ast.File
+	Pkg   *types.Package
+	Info  *types.Info
+}
+
+// Load parses the files of the package in dir that the build context
+// selects - build constraints apply, _test.go files are left out - and
+// type-checks them with imp. Like go vet, it refuses a package that
+// does not type-check: analyzers may assume TypesInfo is complete.
+func Load(fset *token.FileSet, imp types.Importer, dir string) (*Package, error) {
+	return LoadPath(fset, imp, dir, "")
+}
+
+// LoadPath is Load with the package's import path given. Outside GOPATH
+// go/build calls every directory "."; analysistest names a package
+// under testdata/src by its path below src, and so do the tests here.
+func LoadPath(fset *token.FileSet, imp types.Importer, dir, path string) (*Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path = bp.ImportPath
+	}
+	if err := stdlibOnly(dir, bp.Imports); err != nil {
+		return nil, err
+	}
+	p := &Package{Dir: dir, Fset: fset}
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		p.Files = append(p.Files, f)
+	}
+	return p, p.check(imp, path)
+}
+
+// LoadFiles loads the named files as one package, as go vet does for
+// "go vet a.go b.go": their build constraints do not apply. The lessons
+// are single-file programs that share a directory, so a file is the
+// unit a lesson's analyzer runs on.
+func LoadFiles(fset *token.FileSet, imp types.Importer, names ...string) (*Package, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no files")
+	}
+	p := &Package{Dir: filepath.Dir(names[0]), Fset: fset}
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		var imports []string
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			imports = append(imports, path)
+		}
+		if err := stdlibOnly(name, imports); err != nil {
+			return nil, err
+		}
+		p.Files = append(p.Files, f)
+	}
+	return p, p.check(imp, p.Files[0].Name.Name)
+}
+
+// stdlibOnly refuses imports outside the standard library. The
+// repository has no go.mod, so resolving them would send go/build to
+// "go list" and the network.
+func stdlibOnly(where string, imports []string) error {
+	for _, path := range imports {
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			return fmt.Errorf("%s: imports %s, which is not in the standard library", where, path)
+		}
+	}
+	return nil
+}
+
+// LoadSource type-checks one file given as text, for examples and tests
+func LoadSource(fset *token.FileSet, imp types.Importer, name, src string) (*Package, error) {
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	p := &Package{Dir: filepath.Dir(name), Fset: fset, Files: []*ast.File{f}}
+	return p, p.check(imp, f.Name.Name)
+}
+
+func (p *Package) check(imp types.Importer, path string) error {
+	p.Info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	var errs []error
+	conf := types.Config{Importer: imp, Error: func(err error) { errs = append(errs, err) }}
+	p.Pkg, _ = conf.Check(path, p.Fset, p.Files, p.Info)
+	return errors.Join(errs...)
+}
+
+// Run applies the analyzers, and everything they require, to pkg. Each
+// analyzer runs once; order lists them in the order they ran.
+func Run(pkg *Package, analyzers ...*Analyzer) (findings []Finding, order []string, err error) {
+	results := make(map[*Analyzer]any)
+	var visit func(a *Analyzer, path []string) error
+	visit = func(a *Analyzer, path []string) error {
+		if _, done := results[a]; done {
+			return nil
+		}
+		if slices.Contains(path, a.Name) {
+			return fmt.Errorf("cycle: %s -> %s", strings.Join(path, " -> "), a.Name)
+		}
+		for _, req := range a.Requires {
+			if err := visit(req, append(path, a.Name)); err != nil {
+				return err
+			}
+		}
+
+		pass := &Pass{
+			Analyzer:  a,
+			Fset:      pkg.Fset,
+			Files:     pkg.Files,
+			Pkg:       pkg.Pkg,
+			TypesInfo: pkg.Info,
+			ResultOf:  make(map[*Analyzer]any, len(a.Requires)),
+			Report: func(d Diagnostic) {
+				findings = append(findings, Finding{Analyzer: a.Name, Pos: pkg.Fset.Position(d.Pos), Diagnostic: d})
+			},
+		}
+		// A pass sees the results of what it declared, nothing else
+		for _, req := range a.Requires {
+			pass.ResultOf[req] = results[req]
+		}
+		res, err := a.Run(pass)
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.Name, err)
+		}
+		results[a] = res
+		order = append(order, a.Name)
+		return nil
+	}
+	for _, a := range analyzers {
+		if err := visit(a, nil); err != nil {
+			return nil, order, err
+		}
+	}
+	return findings, order, nil
+}
+
+// ApplyFixes applies the first suggested fix of each finding to src,
+// the file the findings are in. Edits must not overlap.
+func ApplyFixes(fset *token.FileSet, src []byte, findings []Finding) ([]byte, error) {
+	var edits []TextEdit
+	for _, f := range findings {
+		if len(f.SuggestedFixes) > 0 {
+			edits = append(edits, f.SuggestedFixes[0].TextEdits...)
+		}
+	}
+	// Apply from the end so earlier offsets stay valid
+	slices.SortFunc(edits, func(a, b TextEdit) int { return int(b.Pos) - int(a.Pos) })
+	out := slices.Clone(src)
+	end := len(out)
+	for _, e := range edits {
+		start, stop := fset.Position(e.Pos).Offset, fset.Position(e.End).Offset
+		if stop > end {
+			return nil, fmt.Errorf("overlapping edits at %s", fset.Position(e.Pos))
+		}
+		out = slices.Concat(out[:start], e.NewText, out[stop:])
+		end = start
+	}
+	return out, nil
+}
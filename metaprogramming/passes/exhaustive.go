@@ -0,0 +1,157 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// exhaustive on the Miniature Driver
+// ==================================
+// A type switch over a sealed interface that does not handle every
+// implementation. An interface is sealed when it has an unexported
+// method: only its own package can implement it, so the set of
+// implementations is the named types of that package that satisfy it.
+//
+//   - a switch with a default case is treated as deliberate and skipped
+//   - a case naming an interface covers every member implementing it
+//   - for members whose methods have pointer receivers, only *T counts
+//
+// With x/tools it would require inspect.Analyzer for the switches; here
+// it walks the files itself, as callsAnalyzer does.
+
+var exhaustive = &Analyzer{
+	Name: "exhaustive",
+	Doc:  "check that type switches over sealed interfaces are exhaustive",
+	Run:  runExhaustive,
+}
+
+func runExhaustive(pass *Pass) (any, error) {
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			sw, ok := n.(*ast.TypeSwitchStmt)
+			if !ok {
+				return true
+			}
+			if sealed, missing := missingCases(pass.TypesInfo, sw); len(missing) > 0 {
+				pass.Reportf(sw.Pos(), "missing cases in type switch over %s: %s",
+					sealed, strings.Join(missing, ", "))
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// missingCases returns the sealed interface a switch is over and the
+// members it does not handle. It returns nil when the operand is not a
+// sealed interface or the switch has a default case.
+func missingCases(info *types.Info, sw *ast.TypeSwitchStmt) (*types.Named, []string) {
+	sealed := sealedInterface(info.TypeOf(switchOperand(sw)))
+	if sealed == nil {
+		return nil, nil
+	}
+	iface := sealed.Underlying().(*types.Interface)
+
+	members := implementations(sealed, iface)
+	covered := make(map[types.Type]bool)
+	for _, stmt := range sw.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			return nil, nil // default
+		}
+		for _, expr := range clause.List {
+			t := info.TypeOf(expr)
+			if t == nil {
+				continue
+			}
+			if caseIface, ok := t.Underlying().(*types.Interface); ok {
+				// An interface case covers every member that implements it
+				for _, m := range members {
+					if types.Implements(m, caseIface) {
+						covered[m] = true
+					}
+				}
+				continue
+			}
+			for _, m := range members {
+				if types.Identical(t, m) {
+					covered[m] = true
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, m := range members {
+		if !covered[m] {
+			missing = append(missing, m.String())
+		}
+	}
+	slices.Sort(missing)
+	return sealed, missing
+}
+
+// switchOperand returns x from `switch x.(type)` or `switch y := x.(type)`
+func switchOperand(sw *ast.TypeSwitchStmt) ast.Expr {
+	var expr ast.Expr
+	switch s := sw.Assign.(type) {
+	case *ast.ExprStmt:
+		expr = s.X
+	case *ast.AssignStmt:
+		expr = s.Rhs[0]
+	}
+	if ta, ok := ast.Unparen(expr).(*ast.TypeAssertExpr); ok {
+		return ta.X
+	}
+	return nil
+}
+
+// sealedInterface returns t as a named interface type if it has an
+// unexported method, and nil otherwise
+func sealedInterface(t types.Type) *types.Named {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	for i := range iface.NumMethods() {
+		if !iface.Method(i).Exported() {
+			return named
+		}
+	}
+	return nil
+}
+
+// implementations lists the concrete named types in the interface's own
+// package that implement it: T when T's method set is enough, otherwise
+// *T when the pointer's method set is
+func implementations(sealed *types.Named, iface *types.Interface) []types.Type {
+	pkg := sealed.Obj().Pkg()
+	if pkg == nil {
+		return nil
+	}
+	var members []types.Type
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		t := tn.Type()
+		if _, isIface := t.Underlying().(*types.Interface); isIface {
+			continue
+		}
+		switch {
+		case types.Implements(t, iface):
+			members = append(members, t)
+		case types.Implements(types.NewPointer(t), iface):
+			members = append(members, types.NewPointer(t))
+		}
+	}
+	return members
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// go/analysis - How Analysis Passes Work
+// ======================================
+// go vet, gopls and staticcheck share one framework,
+// golang.org/x/tools/go/analysis. An Analyzer is a value: a name, a
+// doc string, the analyzers it requires and a Run func. Run receives a
+// *analysis.Pass - one package, parsed and type-checked - and reports
+// Diagnostics, optionally with SuggestedFixes that editors and
+// "go fix"-style drivers apply.
+//
+//	driver      loads packages, type-checks them, orders the analyzers
+//	Requires    analyzers this one needs; their results arrive in ResultOf
+//	Facts       values carried from a package to its importers
+//	analysistest  runs an analyzer on testdata/src and matches
+//	            diagnostics against // want "regexp" comments
+//
+// The framework lives outside the standard library, so this lesson
+// builds a driver in miniature on go/types (driver.go) and runs two
+// analyzers on it: noprintln (noprintln.go) and exhaustive
+// (exhaustive.go). Their fixtures are laid out as
+// analysistest expects - packages under testdata/src, // want comments,
+// a .golden file for the fixes - and passes_test.go checks them the way
+// analysistest would. Porting an analyzer to x/tools changes the
+// types it is written against, not the rule or the fixtures.
+//
+// Run with:
+//
+//	cd metaprogramming/passes
+//	go run driver.go noprintln.go exhaustive.go main.go         # samples only
+//	go run driver.go noprintln.go exhaustive.go main.go -repo   # and the repository
+//	go test -v *.go
+//
+// -check runs both analyzers on the files named after it, loaded as one
+// package, and exits 1 if anything is found:
+//
+//	go run driver.go noprintln.go exhaustive.go main.go -check ../../advanced-concepts/go_type_switches.go
+
+// library is analyzed in every section
+const library = `package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Summary prints a summary - to stdout, whatever the caller wanted
+func Summary(total int) {
+	fmt.Println("total:", total)
+}
+
+// Table has a writer, but one call ignores it
+func Table(w io.Writer, rows []string) {
+	fmt.Fprintln(w, "rows:", len(rows))
+	for _, r := range rows {
+		fmt.Printf("  %s\n", r)
+	}
+	fmt.Fprintln(os.Stderr, "done")
+}
+
+// Errorf returns text; Sprintf is not output
+func Errorf(n int) string { return fmt.Sprintf("bad row %d", n) }
+
+func debug() {
+	if os.Getenv("DEBUG") != "" {
+		println("here")
+	}
+}
+`
+
+// shapes is the sample for exhaustive
+const shapes = `package shapes
+
+// Shape is sealed: only this package can implement it
+type Shape interface{ isShape() }
+
+type Circle struct{ R float64 }
+type Square struct{ S float64 }
+type Poly struct{ Sides []float64 }
+
+func (Circle) isShape() {}
+func (Square) isShape() {}
+func (*Poly) isShape()  {}
+
+func Area(s Shape) float64 {
+	switch s := s.(type) {
+	case Circle:
+		return 3.14159 * s.R * s.R
+	case Square:
+		return s.S * s.S
+	}
+	return 0
+}
+`
+
+func main() {
+	repo := flag.Bool("repo", false, "also check the repository's library packages")
+	root := flag.String("root", filepath.Join("..", ".."), "repository `dir`")
+	check := flag.Bool("check", false, "run the analyzers on the files named as arguments, and nothing else")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+	if *check {
+		os.Exit(checkFiles(fset, imp, flag.Args()))
+	}
+
+	fmt.Println("=== go/analysis Passes ===")
+	pkg, err := LoadSource(fset, imp, "report/report.go", library)
+	if err != nil {
+		fmt.Println("load:", err)
+		os.Exit(1)
+	}
+
+	// 1. An analyzer on one package
+	onePackage(pkg)
+
+	// 2. Requires and ResultOf
+	requirements(pkg)
+
+	// 3. Suggested fixes
+	fixes(pkg, imp)
+
+	// 4. The output layer
+	outputLayer(pkg)
+
+	// 5. A second analyzer: exhaustive
+	exhaustiveSwitches(imp)
+
+	// 6. The repository's library packages
+	if *repo {
+		repository(fset, imp, *root)
+	}
+}
+
+// 1. An Analyzer on One Package
+// =============================
+func onePackage(pkg *Package) {
+	fmt.Println("\n1. AN ANALYZER ON ONE PACKAGE:")
+
+	findings, _, err := Run(pkg, noprintln)
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+	for _, f := range findings {
+		fmt.Println("  ", f)
+	}
+	fmt.Println("   fmt.Sprintf returns a string: only writes to the process's streams count")
+}
+
+// 2. Requires and ResultOf
+// ========================
+func requirements(pkg *Package) {
+	fmt.Println("\n2. REQUIRES AND RESULTOF:")
+
+	// A second analyzer on the same requirement: calls still runs once
+	fmtcalls := &Analyzer{
+		Name:     "fmtcalls",
+		Doc:      "count calls into package fmt",
+		Requires: []*Analyzer{callsAnalyzer},
+		Run: func(pass *Pass) (any, error) {
+			n := 0
+			for _, c := range pass.ResultOf[callsAnalyzer].([]Call) {
+				if c.Callee != nil && c.Callee.Pkg() != nil && c.Callee.Pkg().Path() == "fmt" {
+					n++
+				}
+			}
+			return n, nil
+		},
+	}
+	_, order, err := Run(pkg, noprintln, fmtcalls)
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+	fmt.Printf("   ran: %s\n", strings.Join(order, ", "))
+	fmt.Println("   calls walked the syntax once; both analyzers read its []Call")
+
+	// A cycle in Requires is a programming error the driver reports
+	a := &Analyzer{Name: "a"}
+	b := &Analyzer{Name: "b", Requires: []*Analyzer{a}}
+	a.Requires = []*Analyzer{b}
+	_, _, err = Run(pkg, a)
+	fmt.Println("   a requires b requires a:", err)
+}
+
+// 3. Suggested Fixes
+// ==================
+func fixes(pkg *Package, imp types.Importer) {
+	fmt.Println("\n3. SUGGESTED FIXES:")
+
+	findings, _, err := Run(pkg, noprintln)
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+	fixed, err := ApplyFixes(pkg.Fset, []byte(library), findings)
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+	// Show the lines the fixes changed
+	before, after := strings.Split(library, "\n"), strings.Split(string(fixed), "\n")
+	for i := range before {
+		if before[i] != after[i] {
+			fmt.Printf("   - %s\n   + %s\n", strings.TrimSpace(before[i]), strings.TrimSpace(after[i]))
+		}
+	}
+	fmt.Println("   Summary has no io.Writer to write to, so its finding has no fix")
+
+	// A fix must leave code that compiles: had the file used os only
+	// for os.Stderr, the fix would leave an unused import.
+	// analysistest's golden files are where such a fix gets caught.
+	_, err = LoadSource(pkg.Fset, imp, "report/fixed.go", string(fixed))
+	fmt.Printf("   fixed file type-checks: %t\n", err == nil)
+}
+
+// 4. The Output Layer
+// ===================
+func outputLayer(pkg *Package) {
+	fmt.Println("\n4. THE OUTPUT LAYER:")
+
+	outputPackages = []string{"report"}
+	defer func() { outputPackages = nil }()
+	findings, _, _ := Run(pkg, noprintln)
+	fmt.Printf("   with report named as an output package: %d findings\n", len(findings))
+	fmt.Println("   package main is always output: that is where a program decides its streams")
+}
+
+// 5. A Second Analyzer: exhaustive
+// =================================
+func exhaustiveSwitches(imp types.Importer) {
+	fmt.Println("\n5. A SECOND ANALYZER: EXHAUSTIVE:")
+
+	fset := token.NewFileSet()
+	pkg, err := LoadSource(fset, imp, "shapes/shapes.go", shapes)
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+	findings, _, err := Run(pkg, exhaustive)
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+	for _, f := range findings {
+		fmt.Println("  ", f)
+	}
+	fmt.Println("   isShape is unexported, so Circle, Square and *Poly are every Shape there is")
+	fmt.Println("   Poly's method has a pointer receiver: *Poly is the member, Poly is not")
+}
+
+// 6. The Repository's Library Packages
+// ====================================
+func repository(fset *token.FileSet, imp types.Importer, root string) {
+	fmt.Println("\n6. THE REPOSITORY'S LIBRARY PACKAGES:")
+
+	start := time.Now()
+	var checked, mains int
+	var findings []Finding
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+			return filepath.SkipDir
+		}
+		switch packageName(fset, path) {
+		case "":
+			return nil
+		case "main":
+			mains++ // the output layer
+			return nil
+		}
+		pkg, err := Load(fset, imp, path)
+		if err != nil {
+			// go vet refuses such a package too
+			fmt.Printf("   skipped %s\n", firstLine(err.Error()))
+			return nil
+		}
+		checked++
+		found, _, err := Run(pkg, noprintln, exhaustive)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, found...)
+		return nil
+	})
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+	for _, f := range findings {
+		fmt.Println("  ", f)
+	}
+	fmt.Printf("   %d library packages checked, %d findings; %d main packages are the output layer (%v)\n",
+		checked, len(findings), mains, time.Since(start).Round(time.Millisecond))
+}
+
+// checkFiles runs every analyzer on the named files and returns the
+// exit status: 1 for findings, 2 when the files do not load
+func checkFiles(fset *token.FileSet, imp types.Importer, names []string) int {
+	pkg, err := LoadFiles(fset, imp, names...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	findings, _, err := Run(pkg, noprintln, exhaustive)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// packageName returns the package clause of the first Go file in dir
+// that is not a test, or ""
+func packageName(fset *token.FileSet, dir string) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, m := range matches {
+		if strings.HasSuffix(m, "_test.go") {
+			continue
+		}
+		if f, err := parser.ParseFile(fset, m, nil, parser.PackageClauseOnly); err == nil {
+			return f.Name.Name
+		}
+	}
+	return ""
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// noprintln on the Miniature Driver
+// =================================
+// An analyzer written against the types in driver.go. Library code
+// takes an io.Writer and lets its caller decide where output goes; only
+// the output layer - package main, and packages named as output
+// packages - writes to the process's stdout and stderr.
+// A call to fmt.Print, Printf or Println, an fmt.Fprint* to os.Stdout or
+// os.Stderr, or the print and println builtins, anywhere else is
+// reported. When the enclosing function already has an io.Writer
+// parameter, the diagnostic carries a fix that writes to it instead.
+//
+// The work is split as the real framework splits it: callsAnalyzer
+// walks the syntax once and resolves every call, and noprintln, which
+// requires it, only reads the result.
+
+// Call is a call expression, its callee if it is a named function or
+// builtin, and the innermost function it appears in
+type Call struct {
+	Expr   *ast.CallExpr
+	Callee types.Object
+	Func   *types.Signature // nil at package level
+	File   *ast.File
+}
+
+var callsAnalyzer = &Analyzer{
+	Name: "calls",
+	Doc:  "resolve every call expression to its callee",
+	Run:  runCalls,
+}
+
+func runCalls(pass *Pass) (any, error) {
+	var calls []Call
+	for _, f := range pass.Files {
+		var funcs []*types.Signature
+		var visit func(n ast.Node) bool
+		visit = func(n ast.Node) bool {
+			var sig *types.Signature
+			var body *ast.BlockStmt
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if fn, ok := pass.TypesInfo.Defs[n.Name].(*types.Func); ok {
+					sig = fn.Signature()
+				}
+				body = n.Body
+			case *ast.FuncLit:
+				sig, _ = pass.TypesInfo.TypeOf(n).(*types.Signature)
+				body = n.Body
+			case *ast.CallExpr:
+				c := Call{Expr: n, Callee: callee(pass.TypesInfo, n), File: f}
+				if len(funcs) > 0 {
+					c.Func = funcs[len(funcs)-1]
+				}
+				calls = append(calls, c)
+			}
+			if body != nil {
+				// Walk the body with this function on the stack; the
+				// signature's own syntax holds no calls
+				funcs = append(funcs, sig)
+				ast.Inspect(body, visit)
+				funcs = funcs[:len(funcs)-1]
+				return false
+			}
+			return true
+		}
+		ast.Inspect(f, visit)
+	}
+	return calls, nil
+}
+
+// callee returns the function or builtin a call names, or nil for a
+// call through a value or a conversion. It is typeutil.Callee, cut down.
+func callee(info *types.Info, call *ast.CallExpr) types.Object {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	switch obj := info.Uses[id].(type) {
+	case *types.Func, *types.Builtin:
+		return obj
+	}
+	return nil
+}
+
+var noprintln = &Analyzer{
+	Name:     "noprintln",
+	Doc:      "report writes to stdout and stderr outside the output layer",
+	Requires: []*Analyzer{callsAnalyzer},
+	Run:      runNoprintln,
+}
+
+// outputPackages are the package paths, besides package main, that may
+// print: the -output flag of the real analyzer
+var outputPackages []string
+
+func runNoprintln(pass *Pass) (any, error) {
+	if pass.Pkg.Name() == "main" || slices.Contains(outputPackages, pass.Pkg.Path()) {
+		return nil, nil
+	}
+	for _, c := range pass.ResultOf[callsAnalyzer].([]Call) {
+		// Tests own their output: an Example's is compared to stdout
+		if strings.HasSuffix(pass.Fset.Position(c.File.Pos()).Filename, "_test.go") {
+			continue
+		}
+		checkCall(pass, c)
+	}
+	return nil, nil
+}
+
+func checkCall(pass *Pass, c Call) {
+	switch fn := c.Callee.(type) {
+	case *types.Builtin:
+		if fn.Name() == "print" || fn.Name() == "println" {
+			pass.Reportf(c.Expr.Pos(), "%s writes to standard error from library package %s; take an io.Writer",
+				fn.Name(), pass.Pkg.Name())
+		}
+
+	case *types.Func:
+		if fn.Pkg() == nil || fn.Pkg().Path() != "fmt" {
+			return
+		}
+		sel, _ := ast.Unparen(c.Expr.Fun).(*ast.SelectorExpr)
+		w := writerParam(c.Func)
+		switch name := fn.Name(); name {
+		case "Print", "Printf", "Println":
+			d := Diagnostic{
+				Pos:     c.Expr.Pos(),
+				Message: "fmt." + name + " writes to standard output from library package " + pass.Pkg.Name() + "; take an io.Writer",
+			}
+			if w != "" && sel != nil {
+				// fmt.Println(x) -> fmt.Fprintln(w, x)
+				sep := ""
+				if len(c.Expr.Args) > 0 {
+					sep = ", "
+				}
+				d.SuggestedFixes = []SuggestedFix{{
+					Message: "write to " + w,
+					TextEdits: []TextEdit{
+						{Pos: sel.Sel.Pos(), End: sel.Sel.End(), NewText: []byte("F" + strings.ToLower(name[:1]) + name[1:])},
+						{Pos: c.Expr.Lparen + 1, End: c.Expr.Lparen + 1, NewText: []byte(w + sep)},
+					},
+				}}
+			}
+			pass.Report(d)
+
+		case "Fprint", "Fprintf", "Fprintln":
+			if len(c.Expr.Args) == 0 {
+				return
+			}
+			std := stdStream(pass.TypesInfo, c.Expr.Args[0])
+			if std == "" {
+				return
+			}
+			d := Diagnostic{
+				Pos:     c.Expr.Pos(),
+				Message: "fmt." + name + " to os." + std + " from library package " + pass.Pkg.Name() + "; take an io.Writer",
+			}
+			if w != "" {
+				arg := c.Expr.Args[0]
+				d.SuggestedFixes = []SuggestedFix{{
+					Message:   "write to " + w,
+					TextEdits: []TextEdit{{Pos: arg.Pos(), End: arg.End(), NewText: []byte(w)}},
+				}}
+			}
+			pass.Report(d)
+		}
+	}
+}
+
+// stdStream returns "Stdout" or "Stderr" when expr is os.Stdout or
+// os.Stderr, however os was imported
+func stdStream(info *types.Info, expr ast.Expr) string {
+	sel, ok := ast.Unparen(expr).(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	v, ok := info.Uses[sel.Sel].(*types.Var)
+	if !ok || v.Pkg() == nil || v.Pkg().Path() != "os" {
+		return ""
+	}
+	if v.Name() == "Stdout" || v.Name() == "Stderr" {
+		return v.Name()
+	}
+	return ""
+}
+
+// writer is io.Writer built by hand, so the check does not depend on
+// the package importing io
+var writer = func() *types.Interface {
+	params := types.NewTuple(types.NewVar(token.NoPos, nil, "p", types.NewSlice(types.Typ[types.Byte])))
+	results := types.NewTuple(
+		types.NewVar(token.NoPos, nil, "n", types.Typ[types.Int]),
+		types.NewVar(token.NoPos, nil, "err", types.Universe.Lookup("error").Type()),
+	)
+	write := types.NewFunc(token.NoPos, nil, "Write", types.NewSignatureType(nil, nil, nil, params, results, false))
+	return types.NewInterfaceType([]*types.Func{write}, nil).Complete()
+}()
+
+// writerParam returns the name of the first parameter of sig that is
+// an io.Writer, or ""
+func writerParam(sig *types.Signature) string {
+	if sig == nil {
+		return ""
+	}
+	for v := range sig.Params().Variables() {
+		if v.Name() != "" && v.Name() != "_" && types.Implements(v.Type(), writer) {
+			return v.Name()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"go/importer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// passes - Tests
+// ==============
+// Run with:
+//
+//   cd metaprogramming/passes
+//   go test -v *.go
+//
+// The rules are checked on sources given as text, and on the packages in
+// testdata/src the way analysistest checks them: // want comments and
+// .golden files. The last test runs it over the repository's library
+// packages, which must stay clean.
+
+var (
+	fset = token.NewFileSet()
+	imp  = importer.ForCompiler(fset, "source", nil)
+)
+
+func load(t *testing.T, name, src string) *Package {
+	t.Helper()
+	pkg, err := LoadSource(fset, imp, name, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+// lines returns the line of each finding
+func lines(findings []Finding) []int {
+	var out []int
+	for _, f := range findings {
+		out = append(out, f.Pos.Line)
+	}
+	return out
+}
+
+// 1. The Rule
+// ===========
+
+func TestNoprintln(t *testing.T) {
+	pkg := load(t, "report/report.go", library)
+	findings, _, err := Run(pkg, noprintln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lines(findings), []int{11, 18, 20, 28}; !slices.Equal(got, want) {
+		for _, f := range findings {
+			t.Log(f)
+		}
+		t.Errorf("findings on lines %v, want %v", got, want)
+	}
+}
+
+func TestNoprintlnCallForms(t *testing.T) {
+	src := `package lib
+
+import (
+	"bytes"
+	f "fmt"
+	"io"
+	stdos "os"
+)
+
+func a()                   { f.Println("renamed import") }
+func b()                   { f.Fprint(stdos.Stdout, "renamed os") }
+func c(buf *bytes.Buffer)  { f.Fprintln(buf, "a buffer is fine") }
+func d(w io.Writer)        { f.Fprintf(w, "%d", 1) }
+func e() string            { return f.Sprint("no output") }
+func g() error             { return f.Errorf("no output") }
+
+var out = stdos.Stdout
+
+func h() { f.Fprintln(out, "through a variable: not seen") }
+
+var i = func() int { f.Print("package-level literal"); return 0 }()
+`
+	findings, _, err := Run(load(t, "lib/lib.go", src), noprintln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lines(findings), []int{10, 11, 21}; !slices.Equal(got, want) {
+		for _, f := range findings {
+			t.Log(f)
+		}
+		t.Errorf("findings on lines %v, want %v", got, want)
+	}
+}
+
+// package main is the output layer, as are the packages named in
+// outputPackages; _test.go files own their output
+func TestOutputLayer(t *testing.T) {
+	tests := []struct {
+		name, src string
+		output    []string
+	}{
+		{"cmd/main.go", "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(1) }\n", nil},
+		{"report/report.go", library, []string{"report"}},
+		{"lib/lib_test.go", "package lib\n\nimport \"fmt\"\n\nfunc ExampleF() { fmt.Println(1) }\n", nil},
+	}
+	for _, tt := range tests {
+		outputPackages = tt.output
+		findings, _, err := Run(load(t, tt.name, tt.src), noprintln)
+		outputPackages = nil
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 0 {
+			t.Errorf("%s: %v, want no findings", tt.name, findings)
+		}
+	}
+}
+
+// 2. The Driver
+// =============
+
+func TestRunOrder(t *testing.T) {
+	var ran []string
+	mk := func(name string, requires ...*Analyzer) *Analyzer {
+		return &Analyzer{Name: name, Requires: requires, Run: func(pass *Pass) (any, error) {
+			ran = append(ran, name)
+			for _, req := range requires {
+				if pass.ResultOf[req] != req.Name+"!" {
+					t.Errorf("%s: ResultOf[%s] = %v", name, req.Name, pass.ResultOf[req])
+				}
+			}
+			return name + "!", nil
+		}}
+	}
+	base := mk("base")
+	left, right := mk("left", base), mk("right", base)
+	top := mk("top", left, right)
+
+	_, order, err := Run(load(t, "p/p.go", "package p\n"), top, left)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"base", "left", "right", "top"}
+	if !slices.Equal(order, want) || !slices.Equal(ran, want) {
+		t.Errorf("order %v, ran %v; want %v", order, ran, want)
+	}
+}
+
+func TestRunCycle(t *testing.T) {
+	a := &Analyzer{Name: "a"}
+	b := &Analyzer{Name: "b", Requires: []*Analyzer{a}}
+	a.Requires = []*Analyzer{b}
+	if _, _, err := Run(load(t, "p/p.go", "package p\n"), a); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("err = %v, want a cycle a -> b -> a", err)
+	}
+}
+
+// 3. Suggested Fixes
+// ==================
+
+func TestApplyFixes(t *testing.T) {
+	pkg := load(t, "report/report.go", library)
+	findings, _, err := Run(pkg, noprintln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ApplyFixes(fset, []byte(library), findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer(
+		`fmt.Printf("  %s\n", r)`, `fmt.Fprintf(w, "  %s\n", r)`,
+		`fmt.Fprintln(os.Stderr, "done")`, `fmt.Fprintln(w, "done")`,
+	).Replace(library)
+	if string(got) != want {
+		t.Errorf("fixed:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := LoadSource(fset, imp, "report/fixed.go", string(got)); err != nil {
+		t.Errorf("fixed file does not type-check: %v", err)
+	}
+}
+
+func TestFixWithoutArguments(t *testing.T) {
+	src := "package lib\n\nimport (\n\t\"fmt\"\n\t\"io\"\n)\n\nfunc f(out io.Writer) {\n\tfmt.Println()\n}\n"
+	findings, _, err := Run(load(t, "lib/lib.go", src), noprintln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ApplyFixes(fset, []byte(src), findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "fmt.Fprintln(out)") {
+		t.Errorf("fixed:\n%s", got)
+	}
+}
+
+// 4. Loading
+// ==========
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": "package lib\n\nfunc A() int { return b }\n",
+		"b.go": "//go:build ignore\n\npackage lib\n\nvar b = 1\n",
+		"c.go": "package lib\n\nimport _ \"example.com/dep\"\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Named files are loaded whatever their build constraints say
+	pkg, err := LoadFiles(fset, imp, filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pkg.Pkg.Path(); got != "lib" {
+		t.Errorf("path %q, want lib", got)
+	}
+	if _, err := LoadFiles(fset, imp, filepath.Join(dir, "c.go")); err == nil {
+		t.Error("loaded an import outside the standard library")
+	}
+	if _, err := LoadFiles(fset, imp); err == nil {
+		t.Error("loaded no files")
+	}
+}
+
+func TestLoadRefuses(t *testing.T) {
+	tests := map[string]string{
+		"imports":   "package lib\n\nimport _ \"example.com/dep\"\n",
+		"typeerror": "package lib\n\nvar x int = \"s\"\n",
+	}
+	for name, src := range tests {
+		dir := filepath.Join(t.TempDir(), name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "lib.go"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(fset, imp, dir); err == nil {
+			t.Errorf("%s: loaded, want an error", name)
+		}
+	}
+}
+
+// 5. Testdata
+// ===========
+
+// checkTestdata does what analysistest.RunWithSuggestedFixes does for
+// one package under testdata/src: each diagnostic must match a
+// // want comment on its line, each want must be matched, and a file
+// with a .golden twin must equal it once the fixes are applied
+func checkTestdata(t *testing.T, a *Analyzer, path string) {
+	t.Helper()
+	dir := filepath.Join("testdata", "src", path)
+	pkg, err := LoadPath(fset, imp, dir, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, _, err := Run(pkg, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type key struct {
+		file string
+		line int
+	}
+	wants := make(map[key][]*regexp.Regexp)
+	for _, f := range pkg.Files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				text, ok := strings.CutPrefix(c.Text, "// want ")
+				if !ok {
+					continue
+				}
+				// One or more Go string literals, each a regexp
+				pos := fset.Position(c.Pos())
+				for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+					lit, err := strconv.QuotedPrefix(text)
+					if err != nil {
+						t.Fatalf("%s: want %s: %v", pos, text, err)
+					}
+					pattern, _ := strconv.Unquote(lit)
+					k := key{pos.Filename, pos.Line}
+					wants[k] = append(wants[k], regexp.MustCompile(pattern))
+					text = text[len(lit):]
+				}
+			}
+		}
+	}
+
+	byFile := make(map[string][]Finding)
+	for _, f := range findings {
+		byFile[f.Pos.Filename] = append(byFile[f.Pos.Filename], f)
+		k := key{f.Pos.Filename, f.Pos.Line}
+		i := slices.IndexFunc(wants[k], func(re *regexp.Regexp) bool { return re.MatchString(f.Message) })
+		if i < 0 {
+			t.Errorf("%s: unexpected diagnostic: %s", f.Pos, f.Message)
+			continue
+		}
+		wants[k] = slices.Delete(wants[k], i, i+1)
+	}
+	for k, res := range wants {
+		for _, re := range res {
+			t.Errorf("%s:%d: no diagnostic matching %q", k.file, k.line, re)
+		}
+	}
+
+	for _, f := range pkg.Files {
+		name := fset.File(f.Pos()).Name()
+		golden, err := os.ReadFile(name + ".golden")
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ApplyFixes(fset, src, byFile[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(golden) {
+			t.Errorf("%s with fixes applied:\n%s\nwant %s.golden:\n%s", name, got, name, golden)
+		}
+	}
+}
+
+func TestTestdata(t *testing.T) {
+	t.Run("library", func(t *testing.T) { checkTestdata(t, noprintln, "library") })
+	t.Run("cmd", func(t *testing.T) { checkTestdata(t, noprintln, "cmd") })
+	t.Run("ui", func(t *testing.T) {
+		outputPackages = []string{"ui"}
+		defer func() { outputPackages = nil }()
+		checkTestdata(t, noprintln, "ui")
+	})
+	t.Run("sealed", func(t *testing.T) { checkTestdata(t, exhaustive, "sealed") })
+}
+
+// The lesson's depth function is incomplete on purpose, and -check
+// reports it where the lesson says it does
+func TestCheckLesson(t *testing.T) {
+	pkg, err := LoadFiles(fset, imp, filepath.Join("..", "..", "advanced-concepts", "go_type_switches.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, _, err := Run(pkg, noprintln, exhaustive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("findings %v, want one", findings)
+	}
+	f := findings[0]
+	want := "go_type_switches.go:188:2: missing cases in type switch over main.Expr: *main.Neg"
+	if got := fmt.Sprintf("%s:%d:%d: %s", filepath.Base(f.Pos.Filename), f.Pos.Line, f.Pos.Column, f.Message); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+// The repository's library packages print nothing themselves, and their
+// switches over sealed interfaces are complete
+func TestRepositoryLibraries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type-checks every library package")
+	}
+	root := filepath.Join("..", "..")
+	checked := 0
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+			return filepath.SkipDir
+		}
+		if name := packageName(fset, path); name == "" || name == "main" {
+			return nil
+		}
+		pkg, err := Load(fset, imp, path)
+		if err != nil {
+			return nil // outside the standard library; see section 6
+		}
+		checked++
+		findings, _, err := Run(pkg, noprintln, exhaustive)
+		for _, f := range findings {
+			t.Error(f)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked == 0 {
+		t.Error("no library packages checked")
+	}
+}
@@ -0,0 +1,8 @@
+package main
+
+import "fmt"
+
+// package main is the output layer
+func main() {
+	fmt.Println("hello")
+}
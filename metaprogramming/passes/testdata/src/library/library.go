@@ -0,0 +1,42 @@
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+func Summary(total int) {
+	fmt.Println("total:", total) // want `fmt.Println writes to standard output from library package library; take an io.Writer`
+}
+
+func Table(w io.Writer, rows []string) {
+	fmt.Fprintln(w, "rows:", len(rows))
+	for _, r := range rows {
+		fmt.Printf("  %s\n", r) // want `fmt.Printf writes to standard output`
+	}
+	fmt.Fprintln(os.Stderr, "done") // want `fmt.Fprintln to os.Stderr from library package library`
+}
+
+func Blank(out *bytes.Buffer) {
+	fmt.Println() // want `fmt.Println writes to standard output`
+}
+
+func Literal(w io.Writer) func() {
+	return func() {
+		fmt.Print("inner") // want `fmt.Print writes to standard output`
+	}
+}
+
+func Text(n int) string { return fmt.Sprintf("row %d", n) }
+
+var out io.Writer = os.Stdout
+
+func Indirect() { fmt.Fprintln(out, "through a variable") }
+
+func debug() {
+	if os.Getenv("DEBUG") != "" {
+		println("here") // want `println writes to standard error`
+	}
+}
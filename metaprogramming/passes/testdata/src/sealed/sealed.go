@@ -0,0 +1,71 @@
+// Package sealed is the fixture for exhaustive
+package sealed
+
+import "fmt"
+
+// Shape is sealed by its unexported method
+type Shape interface{ isShape() }
+
+type Circle struct{ R float64 }
+type Square struct{ S float64 }
+type Poly struct{ Sides []float64 }
+
+func (Circle) isShape() {}
+func (Square) isShape() {}
+func (*Poly) isShape()  {}
+
+// Round is implemented by Circle alone
+type Round interface {
+	Shape
+	Radius() float64
+}
+
+func (c Circle) Radius() float64 { return c.R }
+
+func Missing(s Shape) string {
+	switch s.(type) { // want `missing cases in type switch over sealed.Shape: \*sealed.Poly, sealed.Square`
+	case Circle:
+		return "circle"
+	}
+	return ""
+}
+
+func Complete(s Shape) string {
+	switch s := s.(type) {
+	case Circle, Square:
+		return fmt.Sprint(s)
+	case *Poly:
+		return "poly"
+	}
+	return ""
+}
+
+func InterfaceCase(s Shape) string {
+	switch s.(type) {
+	case Round:
+		return "round"
+	case Square, *Poly:
+		return "angular"
+	}
+	return ""
+}
+
+func Default(s Shape) string {
+	switch s.(type) {
+	case Circle:
+		return "circle"
+	default:
+		return "other"
+	}
+}
+
+// Radiused has only exported methods: any package may implement it
+type Radiused interface{ Radius() float64 }
+
+func Open(v Radiused) string {
+	switch v.(type) {
+	case Circle:
+		return "circle"
+	}
+	return ""
+}
@@ -0,0 +1,8 @@
+// Package ui is named in outputPackages, so it may print
+package ui
+
+import "fmt"
+
+func Banner() {
+	fmt.Println("== ui ==")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Type-Checking a Snippet
+// =======================
+// go/parser gives the syntax; go/types gives the meaning. A
+// types.Config checks one package's files and fills a types.Info with
+// what the compiler knows: the type of every expression, the object
+// every identifier refers to, the value of every constant.
+//
+// Imports need an importer. importer.ForCompiler(fset, "source", nil)
+// type-checks the imported packages from their source, which works on
+// any machine with a GOROOT - slower than export data, but a snippet
+// imports little, and the Checker keeps the importer so each standard
+// package is checked once.
+
+// Checker type-checks snippets. It is not safe for concurrent use.
+type Checker struct {
+	fset *token.FileSet
+	imp  types.Importer
+}
+
+// NewChecker returns a Checker whose importer reads the standard
+// library from source
+func NewChecker() *Checker {
+	fset := token.NewFileSet()
+	return &Checker{fset: fset, imp: importer.ForCompiler(fset, "source", nil)}
+}
+
+// Problem is one reason a snippet does not compile
+type Problem struct {
+	Pos    token.Position
+	Msg    string
+	Syntax bool // from the parser rather than the type checker
+}
+
+// Result is a checked snippet. Pkg and Info are filled even when there
+// are problems: the checker records what it could.
+type Result struct {
+	Fset     *token.FileSet
+	File     *ast.File
+	Pkg      *types.Package
+	Info     *types.Info
+	Problems []Problem
+}
+
+// OK reports whether the snippet compiles
+func (r *Result) OK() bool { return len(r.Problems) == 0 }
+
+// Check type-checks src. A snippet without a package clause is taken
+// to be the body of package main; a //line directive keeps the
+// positions those of the snippet as written.
+func (c *Checker) Check(src string) *Result {
+	if !strings.HasPrefix(strings.TrimSpace(src), "package ") {
+		src = "package main\n//line snippet.go:1:1\n" + src
+	}
+	res := &Result{Fset: c.fset}
+
+	f, err := parser.ParseFile(c.fset, "snippet.go", src, parser.SkipObjectResolution)
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			res.Problems = append(res.Problems, Problem{Pos: e.Pos, Msg: e.Msg, Syntax: true})
+		}
+		return res
+	}
+	res.File = f
+
+	res.Info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{
+		Importer: c.imp,
+		// Without an Error func, Check stops at the first error; with
+		// one it reports them all, as the compiler does
+		Error: func(err error) {
+			var te types.Error
+			if errors.As(err, &te) {
+				res.Problems = append(res.Problems, Problem{Pos: te.Fset.Position(te.Pos), Msg: te.Msg})
+			}
+		},
+	}
+	res.Pkg, _ = conf.Check(f.Name.Name, c.fset, []*ast.File{f}, res.Info)
+	return res
+}
+
+// Lookup returns the type named name in the snippet's package
+func (r *Result) Lookup(name string) types.Type {
+	if r.Pkg == nil {
+		return nil
+	}
+	obj, ok := r.Pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	return obj.Type()
+}
+
+// TypeString prints t relative to the snippet's package: Dog, not
+// main.Dog
+func (r *Result) TypeString(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(r.Pkg))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+	"strings"
+)
+
+// Why Doesn't This Compile?
+// =========================
+// The type checker's messages are exact but terse: "declared and not
+// used: x". Explain pairs each problem with the rule behind it, and for
+// an interface that is not satisfied asks go/types about the method
+// sets involved, so the hint names the methods rather than repeating
+// the message.
+//
+// The hints match the messages' wording, which is stable but not a
+// contract; a message with no hint is still reported.
+
+// Explanation is a problem and the rule behind it
+type Explanation struct {
+	Problem
+	Hint string
+}
+
+func (e Explanation) String() string {
+	s := fmt.Sprintf("%s: %s", e.Pos, firstLine(e.Msg))
+	if e.Hint != "" {
+		s += "\n  -> " + e.Hint
+	}
+	return s
+}
+
+// hints are tried in order; the first pattern that matches a message
+// gives its hint
+var hints = []struct {
+	re   *regexp.Regexp
+	hint string
+}{
+	{regexp.MustCompile(`^declared and not used`), "every local variable must be read: use it, delete it, or assign to _"},
+	{regexp.MustCompile(`imported and not used`), "every import must be used: delete it, or import it as _ for its side effects"},
+	{regexp.MustCompile(`mismatched types (untyped )?\w+ and \w+`), "Go never converts between numeric types implicitly: convert one side, as in float64(n)"},
+	{regexp.MustCompile(`^missing return`), "a function with results must end in a return, a panic or another terminating statement, even after an if that always returns"},
+	{regexp.MustCompile(`^cannot assign to .*\[.*\] \(neither addressable`), "strings are immutable: convert to []byte or []rune, change that, and convert back"},
+	{regexp.MustCompile(`but does have (field|method) `), "names are case-sensitive, and a lower-case name is unexported"},
+	{regexp.MustCompile(`^cannot use nil as`), "nil is the zero value only of pointers, slices, maps, channels, funcs and interfaces"},
+	{regexp.MustCompile(`(not enough|too many) return values`), "a return lists one value per result, in order"},
+	{regexp.MustCompile(`\(overflows\)`), "a constant must fit the type it is given; the check happens at compile time"},
+	{regexp.MustCompile(`is not used$`), "an expression statement must do something: only calls, receives and assignments may stand alone"},
+	{regexp.MustCompile(`^undefined: `), "nothing by that name is in scope here: check the spelling, and the block it was declared in"},
+}
+
+var notImplement = regexp.MustCompile(`(\*?\w+) does not implement (\w+)`)
+
+// Explain turns the problems of a checked snippet into explanations
+func Explain(r *Result) []Explanation {
+	out := make([]Explanation, len(r.Problems))
+	for i, p := range r.Problems {
+		out[i] = Explanation{Problem: p, Hint: hint(r, p)}
+	}
+	return out
+}
+
+func hint(r *Result, p Problem) string {
+	if p.Syntax {
+		return "a syntax error, found by the parser before any type is known"
+	}
+	if m := notImplement.FindStringSubmatch(p.Msg); m != nil {
+		if h := interfaceHint(r, m[1], m[2]); h != "" {
+			return h
+		}
+	}
+	for _, h := range hints {
+		if h.re.MatchString(p.Msg) {
+			return h.hint
+		}
+	}
+	return ""
+}
+
+// interfaceHint explains why the type named concrete (maybe with a *)
+// does not implement the interface named iface, listing the method set
+func interfaceHint(r *Result, concrete, iface string) string {
+	t := r.Lookup(strings.TrimPrefix(concrete, "*"))
+	it := r.Lookup(iface)
+	if t == nil || it == nil {
+		return ""
+	}
+	in, ok := it.Underlying().(*types.Interface)
+	if !ok {
+		return ""
+	}
+	if strings.HasPrefix(concrete, "*") {
+		t = types.NewPointer(t)
+	}
+	_, why := Satisfies(r, t, in)
+	set := MethodSet(r, t)
+	if len(set) == 0 {
+		return why + "; the method set of " + concrete + " is empty"
+	}
+	return why + "; the method set of " + concrete + " is " + strings.Join(set, ", ")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
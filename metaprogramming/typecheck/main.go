@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"slices"
+	"strings"
+)
+
+// go/types - Type-Checking Go Programmatically
+// ============================================
+// go/types is the type checker gopls, vet and the analyzers run on; it
+// follows the spec, and the compiler shares its algorithm. Given the
+// files of one package it answers:
+//
+//	Info.Types        the type, and constant value, of every expression
+//	Info.Defs / Uses  which object each identifier declares or refers to
+//	Info.Selections   what x.f selects: a field, a method, through
+//	                  which embedded fields, via a pointer or not
+//	types.NewMethodSet, types.Implements, types.MissingMethod
+//	                  method sets and interface satisfaction
+//
+// and reports every error the compiler would, with a position. This
+// lesson checks snippets - "does *Dog implement Speaker?" - and turns
+// the errors into explanations, which also write the "Why doesn't
+// this compile?" bank of the quiz site in os-files.
+//
+// Run with:
+//
+//	cd metaprogramming/typecheck
+//	go run check.go methods.go explain.go quiz.go main.go
+//	go generate main.go        # rewrite the quiz bank
+//	go test -v *.go
+
+//go:generate go run check.go methods.go explain.go quiz.go main.go -quiz ../../os-files/embedded/quiz/compile.json
+
+// animals is checked in sections 2 and 3
+const animals = `type Speaker interface{ Speak() string }
+
+type Dog struct{ name string }
+
+func (d *Dog) Speak() string { return d.name + ": woof" }
+func (d Dog) Name() string   { return d.name }
+
+type Cat struct{}
+
+func (Cat) Speak() string { return "meow" }
+
+// Puppy embeds a Dog, and with it Dog's methods
+type Puppy struct{ Dog }
+
+// Robot has a Speak with the wrong result
+type Robot struct{}
+
+func (Robot) Speak() []byte { return []byte("beep") }
+`
+
+func main() {
+	quiz := flag.String("quiz", "", "write the quiz bank to `file`")
+	flag.Parse()
+
+	fmt.Println("=== go/types ===")
+	c := NewChecker()
+
+	// 1. What the checker knows
+	expressions(c)
+
+	// 2. Method sets
+	methodSets(c)
+
+	// 3. Does *Dog implement Speaker?
+	satisfaction(c)
+
+	// 4. Why doesn't this compile?
+	explanations(c)
+
+	// 5. The quiz bank
+	if err := writeQuiz(c, *quiz); err != nil {
+		fmt.Println("quiz:", err)
+		os.Exit(1)
+	}
+}
+
+// 1. What the Checker Knows
+// =========================
+func expressions(c *Checker) {
+	fmt.Println("\n1. WHAT THE CHECKER KNOWS:")
+
+	res := c.Check(`import "strings"
+
+const KiB = 1 << 10
+
+var (
+	ratio = float32(KiB) / 3
+	words = strings.Fields("a b c")
+	n     = len(words) + KiB
+)
+`)
+	if !res.OK() {
+		fmt.Println("  ", res.Problems)
+		return
+	}
+
+	// Info.Types maps every expression to its TypeAndValue; ranging
+	// over a map is unordered, so sort by position
+	type entry struct {
+		expr ast.Expr
+		tv   types.TypeAndValue
+	}
+	var entries []entry
+	for expr, tv := range res.Info.Types {
+		if _, ok := expr.(*ast.CallExpr); ok || tv.Value != nil {
+			entries = append(entries, entry{expr, tv})
+		}
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.expr.Pos(), b.expr.Pos()) })
+	for _, e := range entries {
+		val := ""
+		if e.tv.Value != nil {
+			val = "= " + e.tv.Value.String()
+		}
+		fmt.Printf("   %-26s %-16s %s\n", types.ExprString(e.expr), res.TypeString(e.tv.Type), val)
+	}
+
+	// An untyped constant takes the type its context gives it, and the
+	// checker records the value it computed, exactly
+	fmt.Println("   1 << 10 stays untyped until used; float32(KiB) / 3 is typed float32")
+
+	// Uses: every identifier resolved to its object
+	for id, obj := range res.Info.Uses {
+		if id.Name == "Fields" {
+			fmt.Printf("   %s refers to %s, declared in package %s\n", id.Name, obj, obj.Pkg().Path())
+		}
+	}
+}
+
+// 2. Method Sets
+// ==============
+func methodSets(c *Checker) {
+	fmt.Println("\n2. METHOD SETS:")
+
+	res := c.Check(animals)
+	for _, name := range []string{"Dog", "Cat", "Puppy"} {
+		t := res.Lookup(name)
+		for _, typ := range []types.Type{t, types.NewPointer(t)} {
+			set := MethodSet(res, typ)
+			fmt.Printf("   %-7s %s\n", res.TypeString(typ), strings.Join(set, ", "))
+		}
+	}
+	fmt.Println("   Dog's set lacks Speak: it is declared on *Dog")
+	fmt.Println("   Puppy has Name from its embedded Dog; *Puppy has Speak as well")
+}
+
+// 3. Does *Dog Implement Speaker?
+// ===============================
+func satisfaction(c *Checker) {
+	fmt.Println("\n3. DOES *DOG IMPLEMENT SPEAKER?")
+
+	res := c.Check(animals)
+	speaker := res.Lookup("Speaker").Underlying().(*types.Interface)
+	for _, name := range []string{"Dog", "Cat", "Puppy", "Robot"} {
+		t := res.Lookup(name)
+		for _, typ := range []types.Type{t, types.NewPointer(t)} {
+			ok, why := Satisfies(res, typ, speaker)
+			if ok {
+				fmt.Printf("   %-7s yes\n", res.TypeString(typ))
+				continue
+			}
+			fmt.Printf("   %-7s no: %s\n", res.TypeString(typ), why)
+		}
+	}
+	// types.AssignableTo answers the wider question the compiler asks
+	// at "var s Speaker = x"; for an interface it is Implements
+	dog := res.Lookup("Dog")
+	fmt.Printf("   AssignableTo(Dog, Speaker) = %t, AssignableTo(*Dog, Speaker) = %t\n",
+		types.AssignableTo(dog, res.Lookup("Speaker")), types.AssignableTo(types.NewPointer(dog), res.Lookup("Speaker")))
+}
+
+// 4. Why Doesn't This Compile?
+// ============================
+func explanations(c *Checker) {
+	fmt.Println("\n4. WHY DOESN'T THIS COMPILE?")
+
+	for _, src := range []string{
+		animals + "\nvar s Speaker = Dog{}\n",
+		"func f() {\n\tx := 1\n}\n",
+		"import \"fmt\"\n",
+		"type T struct{ name string }\n\nvar _ = T{}.Name\n",
+		"func f( {\n",
+	} {
+		res := c.Check(src)
+		for _, e := range Explain(res) {
+			for line := range strings.Lines(e.String()) {
+				fmt.Print("   ", line)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// 5. The Quiz Bank
+// ================
+func writeQuiz(c *Checker, path string) error {
+	fmt.Println("\n5. THE QUIZ BANK:")
+
+	bank, err := QuizBank(c)
+	if err != nil {
+		return err
+	}
+	for _, q := range bank.Questions {
+		fmt.Printf("   %s\n", q.Explanation)
+	}
+	var buf bytes.Buffer
+	if err := WriteQuizBank(&buf, bank); err != nil {
+		return err
+	}
+	if path == "" {
+		fmt.Println("   (go generate main.go writes it to os-files/embedded/quiz/compile.json)")
+		return nil
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("   wrote %s\n", path)
+	return nil
+}
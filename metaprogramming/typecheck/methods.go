@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// Method Sets and Interfaces
+// ==========================
+// A type's method set decides which interfaces it implements:
+//
+//	T    the methods declared with a value receiver (t T)
+//	*T   those and the ones declared with a pointer receiver (t *T)
+//
+// So a Dog with func (d *Dog) Speak() does not implement Speaker - a
+// *Dog does. The rule protects callers: a Dog stored in an interface is
+// a copy the interface owns, and a pointer method on it would change
+// the copy, not the caller's Dog.
+//
+// types.NewMethodSet computes a method set, embedded fields included;
+// types.Implements answers the question and types.MissingMethod says
+// which method is to blame.
+
+// MethodSet returns the names of the methods in t's method set, with
+// their receivers: "(*Dog).Speak"
+func MethodSet(r *Result, t types.Type) []string {
+	ms := types.NewMethodSet(t)
+	names := make([]string, ms.Len())
+	for i := range ms.Len() {
+		sel := ms.At(i)
+		recv := sel.Obj().(*types.Func).Signature().Recv().Type()
+		names[i] = fmt.Sprintf("(%s).%s", r.TypeString(recv), sel.Obj().Name())
+	}
+	return names
+}
+
+// Satisfies reports whether t implements iface and, when it does not,
+// explains why in the terms a reader would use
+func Satisfies(r *Result, t types.Type, iface *types.Interface) (bool, string) {
+	if types.Implements(t, iface) {
+		return true, ""
+	}
+	name := r.TypeString(t)
+	method, wrong := types.MissingMethod(t, iface, true)
+	if method == nil {
+		return false, name + " does not implement the interface"
+	}
+
+	// The method is there, but only on the pointer
+	if _, isPtr := t.(*types.Pointer); !isPtr && types.Implements(types.NewPointer(t), iface) {
+		return false, fmt.Sprintf("%s has a pointer receiver: *%s implements the interface, %s does not - use &value",
+			method.Name(), name, name)
+	}
+	if have := lookalike(t, method.Name()); have != nil {
+		if have.Name() != method.Name() {
+			return false, fmt.Sprintf("%s has %s, not %s: method names are case-sensitive", name, have.Name(), method.Name())
+		}
+		if wrong {
+			return false, fmt.Sprintf("%s has the wrong signature: have %s, want %s",
+				method.Name(), signature(r, have), signature(r, method))
+		}
+	}
+	return false, fmt.Sprintf("%s has no method %s", name, method.Name())
+}
+
+// lookalike finds the method of t named name, ignoring case
+func lookalike(t types.Type, name string) *types.Func {
+	ms := types.NewMethodSet(t)
+	for i := range ms.Len() {
+		if f := ms.At(i).Obj().(*types.Func); strings.EqualFold(f.Name(), name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// signature prints a method's parameters and results: func() string
+func signature(r *Result, f *types.Func) string {
+	return types.TypeString(f.Signature(), types.RelativeTo(r.Pkg))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The Quiz Bank
+// =============
+// The quiz site in os-files serves question banks from JSON. The
+// "Why doesn't this compile?" bank is written from the snippets below:
+// each is type-checked, must fail, and gets the checker's message and
+// Explain's hint as its explanation. A question cannot claim an error
+// the compiler does not report.
+
+// quizQuestion matches the question format of os-files/go_embed.go,
+// with the snippet in Code
+type quizQuestion struct {
+	Question    string   `json:"question"`
+	Code        string   `json:"code,omitempty"`
+	Choices     []string `json:"choices"`
+	Answer      int      `json:"answer"`
+	Explanation string   `json:"explanation"`
+}
+
+type quizBank struct {
+	Topic     string         `json:"topic"`
+	Title     string         `json:"title"`
+	Questions []quizQuestion `json:"questions"`
+}
+
+// compileQuiz is the bank's content; explanations are filled in by
+// QuizBank
+var compileQuiz = []quizQuestion{
+	{
+		Question: "Why doesn't this compile?",
+		Code: `type Speaker interface{ Speak() string }
+
+type Dog struct{ name string }
+
+func (d *Dog) Speak() string { return d.name + ": woof" }
+
+var s Speaker = Dog{name: "Rex"}
+`,
+		Choices: []string{"Speak must not return a string", "Speak has a pointer receiver, so only *Dog implements Speaker", "A struct literal cannot be assigned to an interface"},
+		Answer:  1,
+	},
+	{
+		Question: "Why doesn't this compile?",
+		Code: `type Shape interface{ Area() float64 }
+
+type Square struct{ side int }
+
+func (s Square) Area() int { return s.side * s.side }
+
+var _ Shape = Square{side: 2}
+`,
+		Choices: []string{"Square has no Area method", "Area returns int, and Shape wants float64", "Area needs a pointer receiver"},
+		Answer:  1,
+	},
+	{
+		Question: "Why doesn't this compile?",
+		Code: `func average(total int, n float64) float64 {
+	return total / n
+}
+`,
+		Choices: []string{"int and float64 cannot be mixed without a conversion", "Division by a float64 may be by zero", "total must be declared as var"},
+		Answer:  0,
+	},
+	{
+		Question: "Why doesn't this compile?",
+		Code: `func sign(n int) int {
+	if n >= 0 {
+		return 1
+	} else if n < 0 {
+		return -1
+	}
+}
+`,
+		Choices: []string{"n < 0 is unreachable", "The compiler does not prove the if-else chain exhaustive, so the function needs a final return", "sign must be exported"},
+		Answer:  1,
+	},
+	{
+		Question: "Why doesn't this compile?",
+		Code: `func capitalize(s string) string {
+	s[0] = 'H'
+	return s
+}
+`,
+		Choices: []string{"'H' is a rune and s[0] is a byte", "Strings are immutable: s[0] cannot be assigned", "s is a copy, so the change would be lost"},
+		Answer:  1,
+	},
+	{
+		Question: "Why doesn't this compile?",
+		Code: `func sum(xs []int) int {
+	total := 0
+	for i, x := range xs {
+		total += x
+	}
+	return total
+}
+`,
+		Choices: []string{"total must be declared with var", "i is declared and never used", "range over a slice yields only values"},
+		Answer:  1,
+	},
+}
+
+// QuizBank checks every snippet and returns the bank with the
+// explanations written. A snippet that compiles is an error.
+func QuizBank(c *Checker) (quizBank, error) {
+	bank := quizBank{Topic: "compile", Title: "Why Doesn't This Compile?"}
+	for i, q := range compileQuiz {
+		if q.Answer < 0 || q.Answer >= len(q.Choices) {
+			return quizBank{}, fmt.Errorf("question %d: answer %d out of range", i+1, q.Answer)
+		}
+		res := c.Check(q.Code)
+		if res.OK() {
+			return quizBank{}, fmt.Errorf("question %d: the snippet compiles", i+1)
+		}
+		e := Explain(res)[0]
+		q.Explanation = fmt.Sprintf("Line %d: %s.", e.Pos.Line, firstLine(e.Msg))
+		if e.Hint != "" {
+			q.Explanation += " " + capitalize(e.Hint) + "."
+		}
+		bank.Questions = append(bank.Questions, q)
+	}
+	return bank, nil
+}
+
+// WriteQuizBank writes the bank in the layout of the other banks
+func WriteQuizBank(w io.Writer, bank quizBank) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(bank)
+}
+
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"go/types"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// typecheck - Tests
+// =================
+// Run with:
+//
+//   cd metaprogramming/typecheck
+//   go test -v *.go
+//
+// One Checker is shared, as in main: its importer caches the standard
+// packages it has checked. The last test checks that the quiz bank in
+// os-files matches the snippets.
+
+var checker = NewChecker()
+
+// 1. Checking
+// ===========
+
+func TestCheckOK(t *testing.T) {
+	res := checker.Check("import \"strings\"\n\nvar n = len(strings.Fields(\"a b\"))\n")
+	if !res.OK() {
+		t.Fatalf("problems: %v", res.Problems)
+	}
+	if got := res.Pkg.Scope().Lookup("n").Type().String(); got != "int" {
+		t.Errorf("n has type %s, want int", got)
+	}
+}
+
+func TestCheckPositions(t *testing.T) {
+	tests := []struct {
+		src          string
+		line, column int
+		syntax       bool
+	}{
+		// Without a package clause the positions are the snippet's own
+		{"func f() {\n\tx := 1\n}\n", 2, 2, false},
+		{"package p\n\nfunc f() {\n\tx := 1\n}\n", 4, 2, false},
+		{"func f( {\n", 1, 9, true},
+	}
+	for _, tt := range tests {
+		res := checker.Check(tt.src)
+		if len(res.Problems) == 0 {
+			t.Errorf("%q: no problems", tt.src)
+			continue
+		}
+		p := res.Problems[0]
+		if p.Pos.Line != tt.line || p.Pos.Column != tt.column || p.Syntax != tt.syntax {
+			t.Errorf("%q: %v (syntax %t), want %d:%d (syntax %t)", tt.src, p.Pos, p.Syntax, tt.line, tt.column, tt.syntax)
+		}
+	}
+}
+
+func TestCheckReportsAll(t *testing.T) {
+	res := checker.Check("import \"fmt\"\n\nfunc f() {\n\ta, b := 1, 2\n}\n")
+	if len(res.Problems) != 3 {
+		t.Errorf("%d problems, want 3: %v", len(res.Problems), res.Problems)
+	}
+}
+
+// 2. Method Sets and Interfaces
+// =============================
+
+func TestMethodSet(t *testing.T) {
+	res := checker.Check(animals)
+	dog := res.Lookup("Dog")
+	if got, want := MethodSet(res, dog), []string{"(Dog).Name"}; !slices.Equal(got, want) {
+		t.Errorf("Dog: %q, want %q", got, want)
+	}
+	if got, want := MethodSet(res, types.NewPointer(dog)), []string{"(Dog).Name", "(*Dog).Speak"}; !slices.Equal(got, want) {
+		t.Errorf("*Dog: %q, want %q", got, want)
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	res := checker.Check(animals + `
+type Mute struct{}
+
+type Loud struct{}
+
+func (Loud) SPEAK() string { return "WOOF" }
+`)
+	speaker := res.Lookup("Speaker").Underlying().(*types.Interface)
+	tests := []struct {
+		name    string
+		pointer bool
+		ok      bool
+		why     string
+	}{
+		{"Dog", false, false, "pointer receiver"},
+		{"Dog", true, true, ""},
+		{"Cat", false, true, ""},
+		{"Puppy", true, true, ""},
+		{"Robot", false, false, "have func() []byte, want func() string"},
+		{"Mute", false, false, "Mute has no method Speak"},
+		{"Loud", false, false, "Loud has SPEAK, not Speak"},
+	}
+	for _, tt := range tests {
+		typ := res.Lookup(tt.name)
+		if tt.pointer {
+			typ = types.NewPointer(typ)
+		}
+		ok, why := Satisfies(res, typ, speaker)
+		if ok != tt.ok || !strings.Contains(why, tt.why) || (tt.ok && why != "") {
+			t.Errorf("%s: %t, %q; want %t, %q", res.TypeString(typ), ok, why, tt.ok, tt.why)
+		}
+	}
+}
+
+// 3. Explanations
+// ===============
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		src  string
+		hint string
+	}{
+		{animals + "var _ Speaker = Dog{}\n", "the method set of Dog is (Dog).Name"},
+		{animals + "var _ Speaker = Robot{}\n", "wrong signature"},
+		{"func f() {\n\tx := 1\n}\n", "assign to _"},
+		{"import \"os\"\n", "side effects"},
+		{"func f(n int, x float64) float64 { return x * n }\n", "convert one side"},
+		{"func f(b bool) int {\n\tif b {\n\t\treturn 1\n\t}\n}\n", "terminating statement"},
+		{"var s = \"hi\"\n\nfunc f() { s[0] = 'H' }\n", "strings are immutable"},
+		{"type T struct{ name string }\n\nvar _ = T{}.Name\n", "case-sensitive"},
+		{"var _ int = nil\n", "zero value"},
+		{"func f() (int, error) { return 1 }\n", "one value per result"},
+		{"var _ int8 = 300\n", "must fit"},
+		{"func f() { 1 + 2 }\n", "only calls"},
+		{"var _ = missing\n", "in scope"},
+		{"func f( {\n", "syntax error"},
+	}
+	for _, tt := range tests {
+		res := checker.Check(tt.src)
+		got := Explain(res)
+		if len(got) == 0 {
+			t.Errorf("%q: no explanations", tt.src)
+			continue
+		}
+		if !strings.Contains(got[0].Hint, tt.hint) {
+			t.Errorf("%q: %s\nwant a hint containing %q", tt.src, got[0], tt.hint)
+		}
+	}
+}
+
+// A message no hint matches is still explained, without a hint
+func TestExplainNoHint(t *testing.T) {
+	got := Explain(checker.Check("type T int\n\nfunc (T) M() {}\nfunc (T) M() {}\n"))
+	if len(got) == 0 || got[0].Hint != "" || strings.Contains(got[0].String(), "->") {
+		t.Errorf("got %v, want an explanation without a hint", got)
+	}
+}
+
+// 4. The Quiz Bank
+// ================
+
+func TestQuizBank(t *testing.T) {
+	bank, err := QuizBank(checker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bank.Questions) != len(compileQuiz) {
+		t.Fatalf("%d questions, want %d", len(bank.Questions), len(compileQuiz))
+	}
+	for i, q := range bank.Questions {
+		if !strings.HasPrefix(q.Explanation, "Line ") || !strings.HasSuffix(q.Explanation, ".") {
+			t.Errorf("question %d: explanation %q", i+1, q.Explanation)
+		}
+	}
+}
+
+// A snippet that compiles cannot be a question
+func TestQuizBankRejectsCompiling(t *testing.T) {
+	saved := compileQuiz
+	defer func() { compileQuiz = saved }()
+	compileQuiz = []quizQuestion{{Code: "var x = 1\n", Choices: []string{"a"}, Answer: 0}}
+	if _, err := QuizBank(checker); err == nil || !strings.Contains(err.Error(), "compiles") {
+		t.Errorf("err = %v, want one saying the snippet compiles", err)
+	}
+}
+
+func TestQuizBankUpToDate(t *testing.T) {
+	bank, err := QuizBank(checker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := WriteQuizBank(&want, bank); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../../os-files/embedded/quiz/compile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Error("compile.json is stale; run: go generate main.go")
+	}
+}
@@ -0,0 +1,387 @@
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// Archives - Round Trips and Hostile Input
+// ========================================
+// Run with:
+//
+//   cd os-files/archives
+//   go test -v *.go
+
+var mtime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// tree is the source for the round trips: nested directories, an empty
+// one, an executable, a private file and a file that compresses well
+func tree() fstest.MapFS {
+	return fstest.MapFS{
+		"README.md":         {Data: []byte("# Project\n"), Mode: 0o644, ModTime: mtime},
+		"bin/run.sh":        {Data: []byte("#!/bin/sh\necho hi\n"), Mode: 0o755, ModTime: mtime.Add(time.Hour)},
+		"config/secret.env": {Data: []byte("TOKEN=x\n"), Mode: 0o600, ModTime: mtime},
+		"data/logs/app.log": {Data: bytes.Repeat([]byte("GET /healthz 200\n"), 5000), Mode: 0o644, ModTime: mtime},
+		"empty":             {Mode: fs.ModeDir | 0o755, ModTime: mtime},
+		"data/logs/.keep":   {Mode: 0o644, ModTime: mtime},
+	}
+}
+
+// 1. Round Trips
+// ==============
+
+func TestRoundTrip(t *testing.T) {
+	formats := []struct {
+		name    string
+		pack    func(io.Writer, fs.FS) error
+		extract func(data []byte, dir string) error
+	}{
+		{"tar.gz", WriteTarGz, func(data []byte, dir string) error {
+			return ExtractTarGz(bytes.NewReader(data), dir, Limits{})
+		}},
+		{"zip", WriteZip, func(data []byte, dir string) error {
+			return ExtractZip(bytes.NewReader(data), int64(len(data)), dir, Limits{})
+		}},
+	}
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := f.pack(&buf, tree()); err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("%s: %d bytes", f.name, buf.Len())
+
+			dir := t.TempDir()
+			if err := f.extract(buf.Bytes(), dir); err != nil {
+				t.Fatal(err)
+			}
+			compareTrees(t, tree(), os.DirFS(dir))
+		})
+	}
+}
+
+// compareTrees checks that got has the same files, directories,
+// contents, permissions and modification times as want
+func compareTrees(t *testing.T, want, got fs.FS) {
+	t.Helper()
+	seen := map[string]bool{}
+	fs.WalkDir(want, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[name] = true
+		wi, _ := d.Info()
+		gi, err := fs.Stat(got, name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			return nil
+		}
+		if wi.IsDir() != gi.IsDir() {
+			t.Errorf("%s: IsDir %v, want %v", name, gi.IsDir(), wi.IsDir())
+			return nil
+		}
+		if wi.IsDir() {
+			return nil
+		}
+		if gi.Mode().Perm() != wi.Mode().Perm() {
+			t.Errorf("%s: mode %v, want %v", name, gi.Mode().Perm(), wi.Mode().Perm())
+		}
+		// Both headers keep whole seconds: tar rounds to the nearest one
+		// and zip truncates
+		if d := gi.ModTime().Sub(wi.ModTime()); d <= -time.Second || d >= time.Second {
+			t.Errorf("%s: modified %v, want %v", name, gi.ModTime(), wi.ModTime())
+		}
+		wd, _ := fs.ReadFile(want, name)
+		gd, _ := fs.ReadFile(got, name)
+		if !bytes.Equal(wd, gd) {
+			t.Errorf("%s: %d bytes differ from the original %d", name, len(gd), len(wd))
+		}
+		return nil
+	})
+	fs.WalkDir(got, ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !seen[name] {
+			t.Errorf("%s: extracted but not in the source", name)
+		}
+		return err
+	})
+}
+
+func TestRoundTripFromDisk(t *testing.T) {
+	// os.DirFS feeds the same writer: archive a real directory, extract
+	// it elsewhere, and the copy matches
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "a", "b"), 0o755)
+	os.WriteFile(filepath.Join(src, "a", "b", "c.txt"), []byte("deep"), 0o640)
+	os.WriteFile(filepath.Join(src, "top.txt"), []byte("top"), 0o644)
+
+	var buf bytes.Buffer
+	if err := WriteTarGz(&buf, os.DirFS(src)); err != nil {
+		t.Fatal(err)
+	}
+	dst := t.TempDir()
+	if err := ExtractTarGz(&buf, dst, Limits{}); err != nil {
+		t.Fatal(err)
+	}
+	compareTrees(t, os.DirFS(src), os.DirFS(dst))
+}
+
+func TestTarHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	WriteTarGz(&buf, tree())
+	zr, _ := gzip.NewReader(&buf)
+	tr := tar.NewReader(zr)
+
+	// Names are slash-separated with a trailing slash on directories,
+	// in the lexical order of fs.WalkDir
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	want := "README.md bin/ bin/run.sh config/ config/secret.env data/ data/logs/ data/logs/.keep data/logs/app.log empty/"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("entries = %s\nwant      %s", got, want)
+	}
+}
+
+// 2. Hostile Archives
+// ===================
+
+// tarGz builds a tar.gz from raw headers, as an attacker would
+func tarGz(t *testing.T, entries ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, hdr := range entries {
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len("payload"))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			io.WriteString(tw, "payload")
+		}
+	}
+	tw.Close()
+	zw.Close()
+	return buf.Bytes()
+}
+
+// sandbox returns an extraction directory inside an outer directory,
+// so a test can check that nothing landed beside it
+func sandbox(t *testing.T) (outer, dest string) {
+	outer = t.TempDir()
+	dest = filepath.Join(outer, "dest")
+	if err := os.Mkdir(dest, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return outer, dest
+}
+
+func TestHostileTar(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  *tar.Header
+		want error
+	}{
+		{"parent", &tar.Header{Name: "../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644}, ErrUnsafePath},
+		{"nested parent", &tar.Header{Name: "a/../../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644}, ErrUnsafePath},
+		{"absolute", &tar.Header{Name: "/tmp/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644}, ErrUnsafePath},
+		{"empty name", &tar.Header{Name: "", Typeflag: tar.TypeReg, Mode: 0o644}, ErrUnsafePath},
+		{"symlink", &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}, ErrUnsupported},
+		{"hard link", &tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "/etc/passwd"}, ErrUnsupported},
+		{"device", &tar.Header{Name: "null", Typeflag: tar.TypeChar, Devmajor: 1, Devminor: 3}, ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outer, dest := sandbox(t)
+			err := ExtractTarGz(bytes.NewReader(tarGz(t, tt.hdr)), dest, Limits{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			var ee *EntryError
+			if !errors.As(err, &ee) || ee.Name != tt.hdr.Name {
+				t.Errorf("err = %v, want an EntryError for %q", err, tt.hdr.Name)
+			}
+			if _, err := os.Stat(filepath.Join(outer, "evil.txt")); err == nil {
+				t.Error("evil.txt was written outside the destination")
+			}
+		})
+	}
+}
+
+func TestSymlinkOnDisk(t *testing.T) {
+	// The name check cannot see the disk. If the destination already
+	// holds a symlink pointing out - left by an earlier extraction, or
+	// planted - "link/evil.txt" is a local name that leads outside.
+	// os.Root resolves the link and refuses.
+	outer, dest := sandbox(t)
+	if err := os.Symlink(outer, filepath.Join(dest, "link")); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	data := tarGz(t, &tar.Header{Name: "link/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644})
+	if err := ExtractTarGz(bytes.NewReader(data), dest, Limits{}); err == nil {
+		t.Error("extracting through a symlink: want an error")
+	}
+	if _, err := os.Stat(filepath.Join(outer, "evil.txt")); err == nil {
+		t.Error("evil.txt was written through the symlink")
+	}
+}
+
+func TestDuplicateEntry(t *testing.T) {
+	// A second entry with the same name does not replace the first
+	_, dest := sandbox(t)
+	data := tarGz(t,
+		&tar.Header{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		&tar.Header{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+	)
+	if err := ExtractTarGz(bytes.NewReader(data), dest, Limits{}); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("err = %v, want fs.ErrExist", err)
+	}
+}
+
+func TestSpecialBitsDropped(t *testing.T) {
+	_, dest := sandbox(t)
+	data := tarGz(t, &tar.Header{Name: "suid", Typeflag: tar.TypeReg, Mode: 0o4755})
+	if err := ExtractTarGz(bytes.NewReader(data), dest, Limits{}); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(filepath.Join(dest, "suid"))
+	if info.Mode()&fs.ModeSetuid != 0 {
+		t.Errorf("mode = %v: setuid survived extraction", info.Mode())
+	}
+}
+
+func TestHostileZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("../../evil.txt")
+	io.WriteString(w, "payload")
+	zw.Close()
+
+	outer, dest := sandbox(t)
+	err := ExtractZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), dest, Limits{})
+	if !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("err = %v, want ErrUnsafePath", err)
+	}
+	if _, err := os.Stat(filepath.Join(outer, "..", "evil.txt")); err == nil {
+		t.Error("evil.txt was written outside the destination")
+	}
+}
+
+func TestCorruptZip(t *testing.T) {
+	var buf bytes.Buffer
+	WriteZip(&buf, fstest.MapFS{"a.txt": {Data: bytes.Repeat([]byte("abc"), 1000)}})
+	data := buf.Bytes()
+
+	// Change the stored CRC-32 in the central directory: the data
+	// decompresses, and the check at the end of the entry fails
+	i := bytes.LastIndex(data, []byte("PK\x01\x02"))
+	data[i+16] ^= 0xff
+	_, dest := sandbox(t)
+	if err := ExtractZip(bytes.NewReader(data), int64(len(data)), dest, Limits{}); !errors.Is(err, zip.ErrChecksum) {
+		t.Errorf("err = %v, want zip.ErrChecksum", err)
+	}
+
+	// Without the central directory it is not a zip at all
+	if err := ExtractZip(bytes.NewReader(data[:i]), int64(i), dest, Limits{}); !errors.Is(err, zip.ErrFormat) {
+		t.Errorf("truncated: err = %v, want zip.ErrFormat", err)
+	}
+}
+
+// 3. Limits
+// =========
+
+func TestBomb(t *testing.T) {
+	// 10 MB of zeros compresses to about 10 KB: a thousand-fold
+	// expansion. Limits count the bytes written, not the header's claim.
+	bomb := fstest.MapFS{"zeros.bin": {Data: make([]byte, 10<<20)}}
+	var tgz, zipped bytes.Buffer
+	WriteTarGz(&tgz, bomb)
+	WriteZip(&zipped, bomb)
+	t.Logf("10 MiB of zeros: tar.gz %d bytes, zip %d bytes", tgz.Len(), zipped.Len())
+
+	_, dest := sandbox(t)
+	if err := ExtractTarGz(bytes.NewReader(tgz.Bytes()), dest, Limits{MaxBytes: 1 << 20}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("tar.gz: err = %v, want ErrTooLarge", err)
+	}
+	_, dest = sandbox(t)
+	err := ExtractZip(bytes.NewReader(zipped.Bytes()), int64(zipped.Len()), dest, Limits{MaxBytes: 1 << 20})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("zip: err = %v, want ErrTooLarge", err)
+	}
+	// Extraction stops just past the limit
+	if info, err := os.Stat(filepath.Join(dest, "zeros.bin")); err != nil || info.Size() > 1<<20+1 {
+		t.Errorf("partial file: %v, %v", info.Size(), err)
+	}
+
+	// Exactly at the limit is fine
+	_, dest = sandbox(t)
+	if err := ExtractTarGz(bytes.NewReader(tgz.Bytes()), dest, Limits{MaxBytes: 10 << 20}); err != nil {
+		t.Errorf("at the limit: %v", err)
+	}
+}
+
+func TestMaxFiles(t *testing.T) {
+	many := fstest.MapFS{}
+	for i := range 50 {
+		many[fmt.Sprintf("f%02d", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+	var tgz, zipped bytes.Buffer
+	WriteTarGz(&tgz, many)
+	WriteZip(&zipped, many)
+
+	_, dest := sandbox(t)
+	if err := ExtractTarGz(&tgz, dest, Limits{MaxFiles: 10}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("tar.gz: err = %v", err)
+	}
+	// zip knows the count from its central directory and fails before
+	// writing anything
+	_, dest = sandbox(t)
+	if err := ExtractZip(bytes.NewReader(zipped.Bytes()), int64(zipped.Len()), dest, Limits{MaxFiles: 10}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("zip: err = %v", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Errorf("zip wrote %d files before failing", len(entries))
+	}
+}
+
+// Examples
+// ========
+
+func ExampleExtractTarGz() {
+	src := fstest.MapFS{"hello.txt": {Data: []byte("hello, archive\n")}}
+	var buf bytes.Buffer
+	if err := WriteTarGz(&buf, src); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	dir, _ := os.MkdirTemp("", "extract")
+	defer os.RemoveAll(dir)
+	if err := ExtractTarGz(&buf, dir, Limits{MaxFiles: 100, MaxBytes: 1 << 20}); err != nil {
+		fmt.Println(err)
+		return
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "hello.txt"))
+	fmt.Print(string(data))
+	// Output:
+	// hello, archive
+}
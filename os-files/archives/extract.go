@@ -0,0 +1,210 @@
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Extracting Archives Safely
+// ==========================
+// An archive comes from someone else, and its names are just strings.
+// A careless extractor that joins them onto the destination directory
+// ("zip slip") lets an archive write anywhere:
+//
+//	../../home/user/.bashrc       escapes with ".."
+//	/etc/cron.d/job               an absolute path
+//	link -> /etc, then link/x     a symlink entry, then a file through it
+//
+// and a small archive can also expand to fill the disk. Extract checks
+// every entry on the way out:
+//
+//   - filepath.IsLocal rejects absolute names, "..", and on Windows
+//     reserved names like NUL
+//   - os.Root does the writes. Its methods refuse any path that
+//     resolves outside the root, including through a symlink already
+//     on disk - a second line of defence if the check above is wrong.
+//   - only regular files and directories are created; links and
+//     devices are rejected
+//   - files are created with O_EXCL, so an entry cannot replace a file
+//     extracted before it
+//   - Limits caps the number of entries and the bytes actually written,
+//     whatever the headers claim
+
+var (
+	// ErrUnsafePath means an entry's name points outside the destination
+	ErrUnsafePath = errors.New("archives: unsafe path")
+
+	// ErrUnsupported means an entry is neither a file nor a directory
+	ErrUnsupported = errors.New("archives: unsupported entry type")
+
+	// ErrTooLarge means the archive exceeds a limit
+	ErrTooLarge = errors.New("archives: archive too large")
+)
+
+// Limits bounds what an extraction may create. Zero means no limit.
+type Limits struct {
+	MaxFiles int   // entries, files and directories
+	MaxBytes int64 // total size of extracted files
+}
+
+// EntryError reports the entry that stopped an extraction
+type EntryError struct {
+	Name string
+	Err  error
+}
+
+func (e *EntryError) Error() string { return fmt.Sprintf("%s: %v", e.Name, e.Err) }
+func (e *EntryError) Unwrap() error { return e.Err }
+
+// extractor writes entries under a root, keeping count against limits
+type extractor struct {
+	root   *os.Root
+	limits Limits
+	files  int
+	bytes  int64
+}
+
+// add creates one entry. name is slash-separated as in the archive.
+func (x *extractor) add(name string, mode fs.FileMode, modTime time.Time, r io.Reader) error {
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) {
+		return &EntryError{name, ErrUnsafePath}
+	}
+	if x.files++; x.limits.MaxFiles > 0 && x.files > x.limits.MaxFiles {
+		return &EntryError{name, ErrTooLarge}
+	}
+
+	switch {
+	case mode.IsDir():
+		if err := x.root.MkdirAll(local, 0o755); err != nil {
+			return &EntryError{name, err}
+		}
+		return nil
+	case !mode.IsRegular():
+		return &EntryError{name, fmt.Errorf("%w: %v", ErrUnsupported, mode.Type())}
+	}
+
+	// Archives may omit directory entries; create parents as needed
+	if dir := filepath.Dir(local); dir != "." {
+		if err := x.root.MkdirAll(dir, 0o755); err != nil {
+			return &EntryError{name, err}
+		}
+	}
+	// Keep the permission bits only: no setuid, setgid or sticky bit
+	// from an archive
+	f, err := x.root.OpenFile(local, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return &EntryError{name, err}
+	}
+
+	// Count what is written, not what the header says. Read one byte
+	// past the budget to tell "exactly at the limit" from "over it".
+	src := r
+	if x.limits.MaxBytes > 0 {
+		src = io.LimitReader(r, x.limits.MaxBytes-x.bytes+1)
+	}
+	n, err := io.Copy(f, src)
+	x.bytes += n
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && x.limits.MaxBytes > 0 && x.bytes > x.limits.MaxBytes {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		return &EntryError{name, err}
+	}
+	if !modTime.IsZero() {
+		x.root.Chtimes(local, modTime, modTime)
+	}
+	return nil
+}
+
+// ExtractTarGz extracts a gzip-compressed tar from r into dir, which
+// must exist. On error, entries extracted so far are left in place.
+func ExtractTarGz(r io.Reader, dir string, limits Limits) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	x := &extractor{root: root, limits: limits}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		// With GODEBUG=tarinsecurepath=0, Next also reports unsafe names
+		// itself as tar.ErrInsecurePath; add checks them either way
+		if err != nil && !errors.Is(err, tar.ErrInsecurePath) {
+			return err
+		}
+		// A hard link entry reports a regular file mode with no data;
+		// taken at face value it would become an empty file. Check the
+		// type flag, not the mode.
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir {
+			return &EntryError{hdr.Name, fmt.Errorf("%w: tar type %q", ErrUnsupported, hdr.Typeflag)}
+		}
+		// tr is an io.Reader for the current entry's data
+		if err := x.add(hdr.Name, hdr.FileInfo().Mode(), hdr.ModTime, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// ExtractZip extracts the zip archive in r, of the given size, into
+// dir, which must exist. On error, entries extracted so far are left in
+// place.
+func ExtractZip(r io.ReaderAt, size int64, dir string, limits Limits) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	// zip.NewReader reads the central directory. With
+	// GODEBUG=zipinsecurepath=0 it returns ErrInsecurePath alongside a
+	// usable reader when any name is unsafe.
+	zr, err := zip.NewReader(r, size)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return err
+	}
+	// The central directory lists every entry up front, so the count can
+	// be checked before anything is written
+	if limits.MaxFiles > 0 && len(zr.File) > limits.MaxFiles {
+		return ErrTooLarge
+	}
+
+	x := &extractor{root: root, limits: limits}
+	for _, zf := range zr.File {
+		if err := extractZipEntry(x, zf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(x *extractor, zf *zip.File) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return &EntryError{zf.Name, err}
+	}
+	defer rc.Close()
+	// The reader checks the CRC-32 at the end of the entry, so a
+	// corrupted entry fails in io.Copy inside add
+	return x.add(zf.Name, zf.Mode(), zf.Modified, rc)
+}
@@ -0,0 +1,101 @@
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// Building Archives From an fs.FS
+// ===============================
+// Both writers take an fs.FS, so the same code archives a directory
+// (os.DirFS), embedded files or an in-memory tree in a test.
+//
+// A tar file is a sequence of (header, data) records with no index:
+// written and read as a stream, in one pass. Compression wraps the
+// whole stream (tar.gz), so one file cannot be read without
+// decompressing everything before it.
+//
+// A zip file compresses each entry separately and ends with a central
+// directory listing them all. Reading starts from the end, so it needs
+// an io.ReaderAt and the size - a file, not a pipe - but any entry can
+// be opened directly.
+
+// WriteTarGz writes the files and directories of fsys to w as a
+// gzip-compressed tar. Entries other than regular files and
+// directories are an error.
+//
+// tar.Writer.AddFS does the same walk in one call; it is written out
+// here to show the headers.
+func WriteTarGz(w io.Writer, fsys fs.FS) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return fmt.Errorf("archives: %s: unsupported file type %v", name, info.Mode().Type())
+		}
+
+		// FileInfoHeader copies the mode, size and modification time.
+		// The name must be set separately: info.Name() is only the base
+		// name, and tar names are always slash-separated.
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// Exactly hdr.Size bytes must follow the header; a file that
+		// grew since Stat fails with tar.ErrWriteTooLong
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Close order matters: tar writes its end-of-archive blocks into
+	// gzip, then gzip writes its trailer
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// WriteZip writes the files of fsys to w as a zip archive, each entry
+// compressed with DEFLATE
+func WriteZip(w io.Writer, fsys fs.FS) error {
+	zw := zip.NewWriter(w)
+	// AddFS walks fsys and writes a header from each file's FileInfo,
+	// like the tar loop above
+	if err := zw.AddFS(fsys); err != nil {
+		return err
+	}
+	// Close writes the central directory. Without it the archive has
+	// data but no index, and readers reject it.
+	return zw.Close()
+}
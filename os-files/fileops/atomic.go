@@ -0,0 +1,75 @@
+package fileops
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Atomic Writes - Write a Temp File, Then Rename
+// ==============================================
+// os.WriteFile truncates the file and then writes it. A crash, a full
+// disk or a killed process in between leaves a truncated or half-written
+// file, and a reader at the wrong moment sees the same. For config files
+// and saved state that is worse than having no update at all.
+//
+// The fix is to never modify the file in place:
+//
+//  1. create a temp file in the SAME directory (rename cannot cross
+//     filesystems, and /tmp is often a different one)
+//  2. write the data and check every error, including Close
+//  3. Sync, so the bytes are on disk before the name points at them
+//  4. rename over the target - on POSIX, rename replaces it atomically:
+//     readers see the old file or the new one, never a mix
+//  5. on any failure, remove the temp file
+//
+// Syncing the directory afterwards makes the rename itself durable
+// across a power cut; without it the old name may come back.
+
+// WriteFileAtomic writes data to path so that path always holds either
+// its old content or all of data. perm applies to the new file.
+func WriteFileAtomic(path string, data []byte, perm fs.FileMode) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	// CreateTemp always uses 0600; set the mode the caller asked for
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	// Close can report a failed write-back; never ignore it when writing
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory entry change (a create or rename) to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,370 @@
+package fileops
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// File I/O and Walking - Tests in t.TempDir
+// =========================================
+// Run with:
+//
+//   cd os-files/fileops
+//   go test -v *.go
+//   go test -bench . -benchmem *.go
+//
+// Every test works in t.TempDir(): a fresh directory per test, removed
+// automatically when it ends, so tests never touch real files or each
+// other.
+
+// writeFiles creates files under dir from a map of relative path to
+// content, making parent directories as needed
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 1. ReadFile vs Streaming
+// ========================
+
+func TestCountLinesBothWays(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("x", 200_000) // longer than the 64 KiB buffer
+	writeFiles(t, dir, map[string]string{
+		"empty":    "",
+		"one":      "hello\n",
+		"no-final": "a\nb\nc",
+		"long":     "start\n" + long + "\nend\n",
+	})
+	for name, want := range map[string]int{"empty": 0, "one": 1, "no-final": 2, "long": 3} {
+		path := filepath.Join(dir, name)
+		whole, err1 := CountLinesReadFile(path)
+		streamed, err2 := CountLinesStreaming(path)
+		if err1 != nil || err2 != nil || whole != want || streamed != want {
+			t.Errorf("%s: ReadFile %d (%v), streaming %d (%v), want %d", name, whole, err1, streamed, err2, want)
+		}
+	}
+
+	// A missing file is fs.ErrNotExist either way, wrapped in a
+	// *fs.PathError that names the path
+	_, err := CountLinesStreaming(filepath.Join(dir, "missing"))
+	var pathErr *fs.PathError
+	if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &pathErr) || pathErr.Op != "open" {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestStreamingMemory(t *testing.T) {
+	// ReadFile allocates the whole file; streaming allocates one buffer
+	path := filepath.Join(t.TempDir(), "big.log")
+	writeFiles(t, filepath.Dir(path), map[string]string{
+		"big.log": strings.Repeat("2024-01-01 INFO something happened\n", 300_000),
+	})
+	info, _ := os.Stat(path)
+
+	allocated := func(f func(string) (int, error)) uint64 {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := f(path); err != nil {
+			t.Fatal(err)
+		}
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+	whole, streamed := allocated(CountLinesReadFile), allocated(CountLinesStreaming)
+	t.Logf("file %d bytes; ReadFile allocated %d, streaming %d", info.Size(), whole, streamed)
+	if whole < uint64(info.Size()) || streamed > 128*1024 {
+		t.Errorf("expected ReadFile >= file size and streaming under 128 KiB")
+	}
+}
+
+// 2. Atomic Write-Then-Rename
+// ===========================
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	if err := WriteFileAtomic(path, []byte(`{"v":1}`), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte(`{"v":2}`), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"v":2}` {
+		t.Errorf("content = %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640 (not CreateTemp's 0600)", info.Mode().Perm())
+	}
+
+	// The temp file was renamed, not copied: nothing else is left
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only config.json", len(entries))
+	}
+}
+
+func TestWriteFileAtomicFailureKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	// Renaming a file over a non-empty directory fails, so the last step
+	// of the write fails after the temp file is complete
+	target := filepath.Join(dir, "state")
+	writeFiles(t, dir, map[string]string{"state/keep": "original"})
+
+	if err := WriteFileAtomic(target, []byte("new"), 0o644); err == nil {
+		t.Fatal("expected the rename to fail")
+	}
+	if data, _ := os.ReadFile(filepath.Join(target, "keep")); string(data) != "original" {
+		t.Error("the original must be untouched")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temp file %s was left behind", e.Name())
+		}
+	}
+}
+
+func TestReadersNeverSeeAPartialFile(t *testing.T) {
+	// A writer alternates between two 1 MiB contents while readers
+	// read in a loop. Every read must be one version, whole.
+	path := filepath.Join(t.TempDir(), "data")
+	a := bytes.Repeat([]byte("A"), 1<<20)
+	b := bytes.Repeat([]byte("B"), 1<<20)
+
+	run := func(write func([]byte) error) (torn int64) {
+		if err := write(a); err != nil {
+			t.Fatal(err)
+		}
+		var stop atomic.Bool
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				for !stop.Load() {
+					data, err := os.ReadFile(path)
+					if err != nil || (!bytes.Equal(data, a) && !bytes.Equal(data, b)) {
+						atomic.AddInt64(&torn, 1)
+					}
+				}
+			})
+		}
+		for i := range 50 {
+			if err := write([][]byte{a, b}[i%2]); err != nil {
+				t.Error(err)
+			}
+		}
+		stop.Store(true)
+		wg.Wait()
+		return torn
+	}
+
+	inPlace := run(func(d []byte) error { return os.WriteFile(path, d, 0o644) })
+	atomicTorn := run(func(d []byte) error { return WriteFileAtomic(path, d, 0o644) })
+	t.Logf("torn reads: os.WriteFile %d, WriteFileAtomic %d", inPlace, atomicTorn)
+	if atomicTorn != 0 {
+		t.Errorf("WriteFileAtomic: %d reads saw a partial file", atomicTorn)
+	}
+}
+
+// 3. Locking
+// ==========
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+	l, err := CreateLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CreateLockFile(path)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("second lock: err = %v, want ErrLocked", err)
+	}
+	t.Log(err) // names the holder's PID
+
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	l, err = CreateLockFile(path)
+	if err != nil {
+		t.Fatalf("lock after unlock: %v", err)
+	}
+	l.Unlock()
+}
+
+// 4. Walking Directories
+// ======================
+
+func tree(t *testing.T) string {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":                "package main",
+		"README.md":              "# readme",
+		"big.go":                 strings.Repeat("// padding\n", 1000),
+		"pkg/util.go":            "package pkg",
+		"pkg/UTIL_TEST.GO":       "package pkg",
+		"pkg/deep/deeper/far.go": "package deeper",
+		".git/hooks/hook.go":     "package hooks",
+		"node_modules/x/y.go":    "not really",
+	})
+	// A symlink is reported by WalkDir but never followed
+	if err := os.Symlink(filepath.Join(dir, "pkg"), filepath.Join(dir, "link")); err != nil {
+		t.Log("symlinks unavailable:", err)
+	}
+	return dir
+}
+
+// rel turns absolute paths into slash-separated paths relative to dir
+func rel(dir string, paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		r, _ := filepath.Rel(dir, p)
+		out[i] = filepath.ToSlash(r)
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	dir := tree(t)
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"everything", Filter{}, []string{
+			".git/hooks/hook.go", "README.md", "big.go", "main.go",
+			"node_modules/x/y.go", "pkg/UTIL_TEST.GO", "pkg/deep/deeper/far.go", "pkg/util.go",
+		}},
+		{"go files, skipping vendored dirs", Filter{Extensions: []string{".go"}, SkipDirs: []string{".git", "node_modules"}}, []string{
+			"big.go", "main.go", "pkg/UTIL_TEST.GO", "pkg/deep/deeper/far.go", "pkg/util.go",
+		}},
+		{"depth 1", Filter{MaxDepth: 1}, []string{"README.md", "big.go", "main.go"}},
+		{"depth 2, go only", Filter{MaxDepth: 2, Extensions: []string{".go"}, SkipDirs: []string{".git"}}, []string{
+			"big.go", "main.go", "pkg/UTIL_TEST.GO", "pkg/util.go",
+		}},
+		{"at least 1 KiB", Filter{MinSize: 1024}, []string{"big.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, skipped, err := Find(dir, tt.filter)
+			if err != nil || len(skipped) > 0 {
+				t.Fatalf("err = %v, skipped = %v", err, skipped)
+			}
+			if got := rel(dir, paths); !slices.Equal(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	dir := tree(t)
+	locked := filepath.Join(dir, "pkg", "deep")
+	os.Chmod(locked, 0o000)
+	t.Cleanup(func() { os.Chmod(locked, 0o755) }) // or TempDir cannot remove it
+
+	paths, skipped, err := Find(dir, Filter{Extensions: []string{".go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || !errors.Is(skipped[0], fs.ErrPermission) {
+		t.Errorf("skipped = %v, want one permission error", skipped)
+	}
+	if !slices.Contains(rel(dir, paths), "pkg/util.go") {
+		t.Error("the rest of the tree should still be walked")
+	}
+}
+
+func TestFindMissingRoot(t *testing.T) {
+	_, _, err := Find(filepath.Join(t.TempDir(), "nope"), Filter{})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+// 5. Temp Files
+// =============
+
+func TestTempFiles(t *testing.T) {
+	// os.CreateTemp replaces the last "*" in the pattern with a random
+	// string, and opens the file with O_EXCL so two callers never share
+	// one. An empty dir means os.TempDir(), which reads $TMPDIR - so a
+	// test can redirect it. t.Setenv restores it afterwards.
+	t.Setenv("TMPDIR", t.TempDir())
+
+	f, err := os.CreateTemp("", "upload-*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name()) // the caller must remove it
+	defer f.Close()
+
+	name := filepath.Base(f.Name())
+	if !strings.HasPrefix(name, "upload-") || !strings.HasSuffix(name, ".csv") {
+		t.Errorf("name = %s", name)
+	}
+	if filepath.Dir(f.Name()) != os.Getenv("TMPDIR") {
+		t.Errorf("created in %s, not $TMPDIR", filepath.Dir(f.Name()))
+	}
+	if info, _ := f.Stat(); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v: temp files are private to the user", info.Mode().Perm())
+	}
+
+	// MkdirTemp is the same for directories; RemoveAll cleans up
+	d, err := os.MkdirTemp("", "work-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, d, map[string]string{"a/b/c.txt": "x"})
+	if err := os.RemoveAll(d); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(d); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("RemoveAll should remove the whole tree")
+	}
+}
+
+// 6. Benchmarks
+// =============
+
+func BenchmarkCountLines(b *testing.B) {
+	dir := b.TempDir()
+	writeFiles(b, dir, map[string]string{
+		"big.log": strings.Repeat("2024-01-01 INFO something happened\n", 300_000),
+	})
+	path := filepath.Join(dir, "big.log")
+
+	b.Run("ReadFile", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			CountLinesReadFile(path)
+		}
+	})
+	b.Run("Streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			CountLinesStreaming(path)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// File Locking Basics
+// ===================
+// Two processes writing the same file need to take turns. There are two
+// common ways:
+//
+//   - a lock file: create "name.lock" with O_CREATE|O_EXCL, which fails
+//     if it exists. Portable and visible with ls, but if the process dies
+//     the lock stays behind, so it records the owner's PID for a human
+//     (or a stale-lock check) to inspect.
+//   - an OS lock: flock(2) on Unix. The kernel releases it when the
+//     file is closed or the process exits, so it cannot go stale. It is
+//     advisory: it only stops other processes that also ask for it. See
+//     lock_unix.go; it is built only on Unix (Windows has LockFileEx in
+//     golang.org/x/sys/windows instead).
+//
+// Neither protects against a process that ignores the convention, and
+// both are per-host: on a network filesystem, use a real lock service.
+
+// ErrLocked is returned when another holder has the lock
+var ErrLocked = errors.New("file is locked")
+
+// LockFile is a held lock file. Unlock removes it.
+type LockFile struct {
+	path string
+}
+
+// CreateLockFile creates path exclusively and writes the current PID
+// into it. If path already exists it returns an error wrapping
+// ErrLocked that names the holder.
+func CreateLockFile(path string) (*LockFile, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("%w: held by pid %s", ErrLocked, lockOwner(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &LockFile{path: path}, nil
+}
+
+// Unlock removes the lock file
+func (l *LockFile) Unlock() error {
+	return os.Remove(l.path)
+}
+
+// lockOwner returns the PID recorded in a lock file, or "unknown"
+func lockOwner(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "unknown"
+	}
+	pid := strings.TrimSpace(string(data))
+	if _, err := strconv.Atoi(pid); err != nil {
+		return "unknown"
+	}
+	return pid
+}
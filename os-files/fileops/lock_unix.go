@@ -0,0 +1,27 @@
+//go:build unix
+
+package fileops
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// TryLock takes an exclusive flock(2) on f without blocking. It returns
+// ErrLocked if another open file description holds it - including one
+// in the same process, since each os.Open is a separate description.
+// The lock is released by Unlock, by closing f, or when the process
+// exits.
+func TryLock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrLocked
+	}
+	return err
+}
+
+// Unlock releases a lock taken with TryLock
+func Unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package fileops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	os.WriteFile(path, nil, 0o644)
+
+	// Two opens are two open file descriptions, and flock treats them
+	// as separate holders - the same as two processes
+	first, _ := os.Open(path)
+	second, _ := os.Open(path)
+	defer second.Close()
+
+	if err := TryLock(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := TryLock(second); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second TryLock: err = %v, want ErrLocked", err)
+	}
+
+	// Closing the file releases the lock - no stale locks after a crash
+	first.Close()
+	if err := TryLock(second); err != nil {
+		t.Fatalf("after close: %v", err)
+	}
+	if err := Unlock(second); err != nil {
+		t.Fatal(err)
+	}
+}
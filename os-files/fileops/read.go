@@ -0,0 +1,57 @@
+package fileops
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+// Reading Files - Whole vs Streaming
+// ==================================
+// os.ReadFile is the simplest way to read a file: one call, the whole
+// content in a []byte sized from the file's Stat. That is right for
+// config files and anything you need all at once. For large or
+// unbounded files it means memory grows with the file - a 2 GB log
+// needs 2 GB of heap just to count its lines.
+//
+// Streaming reads a chunk at a time (os.Open plus bufio or io.Copy), so
+// memory stays constant whatever the size. The code is a little longer
+// and must close the file; the two functions below compute the same
+// thing both ways.
+
+// CountLinesReadFile counts '\n' bytes after loading the whole file
+func CountLinesReadFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return bytes.Count(data, []byte{'\n'}), nil
+}
+
+// CountLinesStreaming counts '\n' bytes reading 64 KiB at a time
+func CountLinesStreaming(path string) (n int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	// Closing a file opened for reading cannot lose data, so its error
+	// is safe to ignore. (For writes it is not - see atomic.go.)
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 64*1024)
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if len(chunk) > 0 && chunk[len(chunk)-1] == '\n' {
+			n++
+		}
+		switch err {
+		case nil, bufio.ErrBufferFull:
+			// a line longer than the buffer: keep reading it
+		case io.EOF:
+			return n, nil
+		default:
+			return n, err
+		}
+	}
+}
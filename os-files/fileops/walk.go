@@ -0,0 +1,98 @@
+package fileops
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Walking Directories - filepath.WalkDir With Filtering
+// =====================================================
+// filepath.WalkDir visits every file and directory under a root in
+// lexical order, calling a function with each path and its fs.DirEntry.
+// It replaced filepath.Walk, which called os.Lstat on every entry;
+// WalkDir uses the type bits the directory listing already returned,
+// which is much cheaper on large trees.
+//
+// The callback steers the walk with its return value:
+//
+//   - nil: carry on
+//   - fs.SkipDir on a directory: do not descend into it
+//   - fs.SkipDir on a file: skip the rest of its directory
+//   - fs.SkipAll: stop the whole walk, with no error
+//   - any other error: stop and return it
+//
+// If a directory cannot be read, the callback is called a second time
+// for it with the error, and can return nil to skip it and go on.
+// Symbolic links are reported but never followed.
+
+// Filter selects files for Find. Zero values mean "no restriction".
+type Filter struct {
+	Extensions []string // e.g. ".go"; matched case-insensitively
+	SkipDirs   []string // directory names not to enter, e.g. ".git"
+	MaxDepth   int      // 1 means only files directly in root; 0 means any depth
+	MinSize    int64    // bytes
+}
+
+// Find returns the paths of regular files under root that pass f, in
+// lexical order. Unreadable directories are skipped and returned as
+// errors alongside the results; any other failure stops the walk.
+func Find(root string, f Filter) (paths []string, skipped []error, err error) {
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				skipped = append(skipped, err)
+				return nil // a second call, for a directory we could not list
+			}
+			return err
+		}
+
+		depth := depthBelow(root, path)
+		if d.IsDir() {
+			if path != root && slices.Contains(f.SkipDirs, d.Name()) {
+				return fs.SkipDir
+			}
+			if f.MaxDepth > 0 && depth >= f.MaxDepth {
+				return fs.SkipDir // its files would be deeper than allowed
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil // symlinks, sockets, devices
+		}
+		if len(f.Extensions) > 0 && !hasExtension(path, f.Extensions) {
+			return nil
+		}
+		if f.MinSize > 0 {
+			// Info calls Lstat - only pay for it when the filter needs it
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Size() < f.MinSize {
+				return nil
+			}
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return paths, skipped, err
+}
+
+// depthBelow returns how many path elements path is below root
+func depthBelow(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+func hasExtension(path string, exts []string) bool {
+	ext := filepath.Ext(path)
+	return slices.ContainsFunc(exts, func(e string) bool {
+		return strings.EqualFold(e, ext)
+	})
+}
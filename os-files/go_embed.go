@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+)
+
+// Go embed - Compiling Files Into the Binary
+// ==========================================
+// A //go:embed directive above a package-level variable tells the
+// compiler to read files at build time and store them in the binary.
+// The program then needs no files beside it: one executable carries its
+// templates, static assets and data.
+//
+// Here that is a small quiz site: question banks as JSON, HTML
+// templates and a stylesheet, all under embedded/.
+//
+// Run with:
+//
+//	cd os-files
+//	go run go_embed.go                              # the lesson
+//	go run go_embed.go serve                        # browse the quizzes on :8080
+//	go run go_embed.go serve -addr localhost:9000   # elsewhere; learnctl serve does this
+
+// A single file can go into a string or a []byte. These two variables
+// hold copies made at compile time; changing the files afterwards has
+// no effect until the next build.
+
+//go:embed embedded/version.txt
+var version string
+
+//go:embed embedded/quiz/io.json
+var ioBank []byte
+
+// A directory goes into an embed.FS, a read-only fs.FS. Several
+// patterns can share one directive or be split across lines.
+
+//go:embed embedded/quiz embedded/templates
+//go:embed embedded/static/*.css
+var assets embed.FS
+
+// The all: prefix keeps files starting with "." or "_", which directory
+// patterns leave out
+
+//go:embed all:embedded/quiz
+var allQuizzes embed.FS
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		flags := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := flags.String("addr", "localhost:8080", "`address` to listen on")
+		flags.Parse(os.Args[2:])
+		site, err := newSite()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("serving quizzes on http://%s\n", *addr)
+		fmt.Println(http.ListenAndServe(*addr, site))
+		return
+	}
+
+	fmt.Println("=== Go embed ===")
+
+	// 1. One file as a string or []byte
+	singleFiles()
+
+	// 2. Directory trees in an embed.FS
+	fileTrees()
+
+	// 3. Hidden files and the all: prefix
+	hiddenFiles()
+
+	// 4. Loading data from the FS
+	loadingBanks()
+
+	// 5. Templates with template.ParseFS
+	templates()
+
+	// 6. Serving the site
+	serving()
+}
+
+// 1. One File as a String or []byte
+// =================================
+func singleFiles() {
+	fmt.Println("\n1. ONE FILE AS A STRING OR []BYTE:")
+
+	// The variable must be package-level and of type string, []byte or
+	// embed.FS. For string and []byte the package still has to import
+	// "embed", even if only as import _ "embed".
+	fmt.Printf("   version (string): %q\n", strings.TrimSpace(version))
+
+	// []byte is ready for parsers that take bytes
+	var bank quizBank
+	if err := json.Unmarshal(ioBank, &bank); err != nil {
+		fmt.Println("   decode:", err)
+		return
+	}
+	fmt.Printf("   ioBank ([]byte): %d bytes, %d questions\n", len(ioBank), len(bank.Questions))
+
+	// A missing file or a pattern matching nothing is a compile error,
+	// not a runtime one - there is no error to check here
+}
+
+// 2. Directory Trees in an embed.FS
+// =================================
+func fileTrees() {
+	fmt.Println("\n2. DIRECTORY TREES IN AN EMBED.FS:")
+
+	// Paths inside the FS are the paths from the directive, relative to
+	// this file's directory, always with forward slashes
+	fs.WalkDir(assets, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			info, _ := d.Info()
+			fmt.Printf("   %-34s %4d bytes\n", name, info.Size())
+		}
+		return nil
+	})
+
+	// embed.FS implements fs.ReadFileFS and fs.ReadDirFS, so the io/fs
+	// helpers take their fast paths. Reads return the embedded data
+	// without touching the disk.
+	data, _ := fs.ReadFile(assets, "embedded/static/style.css")
+	fmt.Printf("   style.css starts %q\n", data[:4])
+
+	// fs.Sub re-roots the FS, so other code sees "style.css" instead of
+	// "embedded/static/style.css"
+	static, _ := fs.Sub(assets, "embedded/static")
+	_, err := fs.Stat(static, "style.css")
+	fmt.Println("   fs.Sub(assets, \"embedded/static\"), Stat(\"style.css\"):", err)
+
+	// Patterns cannot reach outside the package directory ("..") and
+	// cannot name symlinks - embedded files live beside the code
+}
+
+// 3. Hidden Files and the all: Prefix
+// ===================================
+func hiddenFiles() {
+	fmt.Println("\n3. HIDDEN FILES AND THE ALL: PREFIX:")
+
+	// A directory pattern skips names starting with "." or "_" - editor
+	// swap files, .DS_Store, drafts. The all: prefix includes them, and
+	// a pattern naming the file directly always does.
+	for _, fsys := range []struct {
+		name string
+		fs   embed.FS
+	}{
+		{"//go:embed embedded/quiz    ", assets},
+		{"//go:embed all:embedded/quiz", allQuizzes},
+	} {
+		entries, _ := fsys.fs.ReadDir("embedded/quiz")
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		fmt.Printf("   %s -> %v\n", fsys.name, names)
+	}
+}
+
+// 4. Loading Data From the FS
+// ===========================
+func loadingBanks() {
+	fmt.Println("\n4. LOADING DATA FROM THE FS:")
+
+	// loadBanks takes an fs.FS, not an embed.FS, so tests can pass an
+	// fstest.MapFS and a development build can pass os.DirFS("embedded")
+	// to pick up edits without recompiling
+	banks, err := loadBanks(assets, "embedded/quiz")
+	if err != nil {
+		fmt.Println("   load:", err)
+		return
+	}
+	for _, b := range banks {
+		fmt.Printf("   %-9s %q: %d questions\n", b.Topic, b.Title, len(b.Questions))
+	}
+
+	// The same function, reading the directory on disk instead. Glob
+	// on the disk sees _draft.json, which the embed pattern dropped.
+	banks, err = loadBanks(os.DirFS("embedded"), "quiz")
+	fmt.Printf("   from os.DirFS: %d banks (with _draft.json), err=%v\n", len(banks), err)
+}
+
+// 5. Templates With template.ParseFS
+// ==================================
+func templates() {
+	fmt.Println("\n5. TEMPLATES WITH TEMPLATE.PARSEFS:")
+
+	// ParseFS takes glob patterns, like ParseGlob, but over an fs.FS.
+	// Both pages define a "content" block, so each page gets its own
+	// clone of the layout rather than one shared set where the last
+	// definition would win.
+	site, err := newSite()
+	if err != nil {
+		fmt.Println("   parse:", err)
+		return
+	}
+	fmt.Printf("   pages: %d, each a clone of layout.html\n", len(site.pages))
+
+	var buf bytes.Buffer
+	err = site.pages["quiz"].ExecuteTemplate(&buf, "layout", quizPage{
+		Title:   "io",
+		Version: strings.TrimSpace(version),
+		Bank:    site.banks[0],
+	})
+	if err != nil {
+		fmt.Println("   execute:", err)
+		return
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "<h1>") || strings.Contains(line, "<footer>") {
+			fmt.Println("  ", line)
+		}
+	}
+
+	// Parsing happens once at startup. A template error is found then,
+	// and - because the files are in the binary - it is found in the
+	// first test run, not on the production machine
+}
+
+// 6. Serving the Site
+// ===================
+func serving() {
+	fmt.Println("\n6. SERVING THE SITE:")
+
+	site, err := newSite()
+	if err != nil {
+		fmt.Println("  ", err)
+		return
+	}
+
+	// httptest.NewRecorder exercises the handler without a network. The
+	// mux redirects paths containing "..", and the file server is rooted
+	// at embedded/static, so nothing else in the FS can be fetched.
+	for _, target := range []string{"/", "/quiz/os-files", "/static/style.css", "/quiz/draft", "/static/../quiz/io.json"} {
+		rec := httptest.NewRecorder()
+		site.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		fmt.Printf("   GET %-24s %d %-26s %d bytes\n", target, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Len())
+	}
+
+	// embed.FS files have a zero ModTime, so http.FileServerFS sends no
+	// Last-Modified and cannot answer If-Modified-Since. Content is fixed
+	// per build: a version in the URL or an ETag from a hash does better.
+	rec := httptest.NewRecorder()
+	site.ServeHTTP(rec, httptest.NewRequest("GET", "/static/style.css", nil))
+	fmt.Printf("   Last-Modified for an embedded file: %q\n", rec.Header().Get("Last-Modified"))
+}
+
+// Helper types and functions
+// ==========================
+
+// question is one multiple-choice question; Code, when set, is a
+// snippet shown with it
+type question struct {
+	Question    string   `json:"question"`
+	Code        string   `json:"code,omitempty"`
+	Choices     []string `json:"choices"`
+	Answer      int      `json:"answer"`
+	Explanation string   `json:"explanation"`
+}
+
+type quizBank struct {
+	Topic     string     `json:"topic"`
+	Title     string     `json:"title"`
+	Questions []question `json:"questions"`
+}
+
+// loadBanks decodes every .json file in dir, checking each answer index
+func loadBanks(fsys fs.FS, dir string) ([]quizBank, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var banks []quizBank
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var b quizBank
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for i, q := range b.Questions {
+			if q.Answer < 0 || q.Answer >= len(q.Choices) {
+				return nil, fmt.Errorf("%s: question %d: answer %d out of range", name, i+1, q.Answer)
+			}
+		}
+		banks = append(banks, b)
+	}
+	return banks, nil
+}
+
+type indexPage struct {
+	Title   string
+	Version string
+	Banks   []quizBank
+}
+
+type quizPage struct {
+	Title   string
+	Version string
+	Bank    quizBank
+}
+
+// site serves the quizzes, the pages rendered from templates and the
+// stylesheet straight from the embed.FS
+type site struct {
+	banks []quizBank
+	pages map[string]*template.Template
+	mux   *http.ServeMux
+}
+
+func newSite() (*site, error) {
+	banks, err := loadBanks(assets, "embedded/quiz")
+	if err != nil {
+		return nil, err
+	}
+	layout, err := template.ParseFS(assets, "embedded/templates/layout.html")
+	if err != nil {
+		return nil, err
+	}
+	s := &site{banks: banks, pages: map[string]*template.Template{}, mux: http.NewServeMux()}
+	for _, page := range []string{"index", "quiz"} {
+		t, err := template.Must(layout.Clone()).ParseFS(assets, "embedded/templates/"+page+".html")
+		if err != nil {
+			return nil, err
+		}
+		s.pages[page] = t
+	}
+
+	static, err := fs.Sub(assets, "embedded/static")
+	if err != nil {
+		return nil, err
+	}
+	s.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	s.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		s.render(w, "index", indexPage{Title: "Quizzes", Version: strings.TrimSpace(version), Banks: s.banks})
+	})
+	s.mux.HandleFunc("GET /quiz/{topic}", func(w http.ResponseWriter, r *http.Request) {
+		for _, b := range s.banks {
+			if b.Topic == r.PathValue("topic") {
+				s.render(w, "quiz", quizPage{Title: b.Title, Version: strings.TrimSpace(version), Bank: b})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	return s, nil
+}
+
+func (s *site) ServeHTTP(w http.ResponseWriter, r *http.Request) { s.mux.ServeHTTP(w, r) }
+
+// render executes into a buffer first, so a template error becomes a
+// 500 instead of half a page with a 200 status
+func (s *site) render(w http.ResponseWriter, page string, data any) {
+	var buf bytes.Buffer
+	if err := s.pages[page].ExecuteTemplate(&buf, "layout", data); err != nil {
+		http.Error(w, "template error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
@@ -0,0 +1,49 @@
+package iofs
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Before - Code Tied to the OS
+// ============================
+// ListPagesOS is how this code usually starts: it takes a directory
+// path and calls os and filepath directly. It works, but everything
+// that uses it now depends on a real disk:
+//
+//   - tests must create files (t.TempDir helps, but every case needs
+//     setup code, and some errors cannot be produced at all)
+//   - the pages cannot come from anywhere else - a zip file, the
+//     binary itself, a remote store - without a second implementation
+//
+// pages.go is the same function after the refactor.
+
+// ListPagesOS lists the .md pages under dir, reading each from disk
+func ListPagesOS(dir string) ([]Page, error) {
+	var pages []Page
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, parsePage(filepath.ToSlash(rel), data))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(pages, func(a, b Page) int { return strings.Compare(a.Path, b.Path) })
+	return pages, nil
+}
@@ -0,0 +1,44 @@
+package iofs
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Embedding - The Same Code, Files Inside the Binary
+// ==================================================
+// A //go:embed directive fills an embed.FS with files from the package
+// directory at compile time. embed.FS implements fs.FS, so ListPages
+// and ReadPage work on it unchanged - and so does anything else in the
+// standard library that takes an fs.FS, such as http.FileServerFS and
+// template.ParseFS.
+//
+// Points to remember:
+//   - patterns are relative to the package and cannot use ".." -
+//     embedded files must live beside the code
+//   - files starting with "." or "_" are left out unless the pattern
+//     starts with "all:"
+//   - the FS is rooted at the package directory, so names keep the
+//     "docs/" prefix; fs.Sub strips it
+
+//go:embed docs
+var embedded embed.FS
+
+// Docs returns the embedded pages, rooted at docs/
+func Docs() fs.FS {
+	docs, err := fs.Sub(embedded, "docs")
+	if err != nil {
+		// Only possible for an invalid directory name: a bug, not a
+		// runtime condition
+		panic(err)
+	}
+	return docs
+}
+
+// Handler serves the raw pages of fsys over HTTP. Passing Docs() serves
+// them from the binary; os.DirFS(dir) serves a directory, for editing
+// pages without recompiling.
+func Handler(fsys fs.FS) http.Handler {
+	return http.FileServerFS(fsys)
+}
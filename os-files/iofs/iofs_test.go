@@ -0,0 +1,275 @@
+package iofs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// fs.FS - Tests Without a Disk
+// ============================
+// Run with:
+//
+//   cd os-files/iofs
+//   go test -v *.go
+//
+// fstest.MapFS is a map from path to *fstest.MapFile. Directories are
+// implied by the paths, so a whole tree is one literal, built in memory
+// with no setup or cleanup.
+
+// 1. Before: Tests Need Real Files
+// ================================
+
+func TestListPagesOS(t *testing.T) {
+	// Every case needs directories created and files written first
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "guide"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"index.md":         "# Home\nhello",
+		"guide/install.md": "# Install\nstep one",
+		"notes.txt":        "not a page",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pages, err := ListPagesOS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Page{
+		{Path: "guide/install.md", Title: "Install", Words: 4},
+		{Path: "index.md", Title: "Home", Words: 3},
+	}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("ListPagesOS = %+v, want %+v", pages, want)
+	}
+}
+
+// 2. After: fstest.MapFS
+// ======================
+
+func TestListPages(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want []Page
+	}{
+		{"empty", fstest.MapFS{}, nil},
+		{"title from heading", fstest.MapFS{
+			"a.md": {Data: []byte("# Alpha\none two three")},
+		}, []Page{{"a.md", "Alpha", 5}}},
+		{"title from file name", fstest.MapFS{
+			"faq.md": {Data: []byte("no heading here")},
+		}, []Page{{"faq.md", "faq", 3}}},
+		{"nested and sorted", fstest.MapFS{
+			"z.md":          {Data: []byte("# Z")},
+			"a/b/c/deep.md": {Data: []byte("# Deep")},
+			"m.md":          {Data: []byte("# M")},
+		}, []Page{{"a/b/c/deep.md", "Deep", 2}, {"m.md", "M", 2}, {"z.md", "Z", 2}}},
+		{"other files ignored", fstest.MapFS{
+			"page.md":      {Data: []byte("# Page")},
+			"style.css":    {Data: []byte("body {}")},
+			"README":       {Data: []byte("# Not markdown")},
+			"img/logo.png": {Data: []byte{0x89, 'P', 'N', 'G'}},
+		}, []Page{{"page.md", "Page", 2}}},
+		{"empty file", fstest.MapFS{
+			"blank.md": {},
+		}, []Page{{"blank.md", "blank", 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ListPages(tt.fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListPages = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBothVersionsAgree(t *testing.T) {
+	// os.DirFS turns a directory into an fs.FS, so production code
+	// passes os.DirFS(dir) where it used to pass dir. (os.DirFS does not
+	// stop symlinks pointing outside dir; root.FS() from os.OpenRoot
+	// does.)
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"index.md": "# Home", "b.md": "plain words", "x.txt": "skip",
+	} {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+	}
+
+	fromOS, err1 := ListPagesOS(dir)
+	fromFS, err2 := ListPages(os.DirFS(dir))
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	if !reflect.DeepEqual(fromOS, fromFS) {
+		t.Errorf("ListPagesOS = %+v\nListPages(os.DirFS) = %+v", fromOS, fromFS)
+	}
+}
+
+// 3. Paths and Errors
+// ===================
+
+func TestReadPageInvalidNames(t *testing.T) {
+	fsys := fstest.MapFS{"guide/a.md": {Data: []byte("# A")}}
+
+	for _, name := range []string{"/guide/a.md", "../a.md", "guide/../guide/a.md", "guide//a.md", "./guide/a.md", ""} {
+		if _, err := ReadPage(fsys, name); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("ReadPage(%q): err = %v, want fs.ErrInvalid", name, err)
+		}
+	}
+	// Backslashes are not separators in fs paths, on any OS: this is a
+	// valid name for a file that does not exist
+	if _, err := ReadPage(fsys, `guide\a.md`); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`ReadPage("guide\\a.md"): err = %v, want fs.ErrNotExist`, err)
+	}
+	if p, err := ReadPage(fsys, "guide/a.md"); err != nil || p.Title != "A" {
+		t.Errorf("ReadPage(valid) = %+v, %v", p, err)
+	}
+}
+
+// failFS wraps an FS and fails to open one name. Errors like this are
+// awkward to produce with real files (chmod does nothing for root, and
+// nothing on Windows) but trivial behind an interface.
+type failFS struct {
+	fs.FS
+	name string
+	err  error
+}
+
+func (f failFS) Open(name string) (fs.File, error) {
+	if name == f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: f.err}
+	}
+	return f.FS.Open(name)
+}
+
+func TestListPagesErrors(t *testing.T) {
+	base := fstest.MapFS{
+		"a.md":         {Data: []byte("# A")},
+		"private/b.md": {Data: []byte("# B")},
+	}
+
+	// An unreadable file fails the whole listing, and the error says which
+	fsys := failFS{FS: base, name: "private/b.md", err: fs.ErrPermission}
+	_, err := ListPages(fsys)
+	var pathErr *fs.PathError
+	if !errors.Is(err, fs.ErrPermission) || !errors.As(err, &pathErr) || pathErr.Path != "private/b.md" {
+		t.Errorf("unreadable file: err = %v", err)
+	}
+
+	// So does an unreadable directory, reported by fs.WalkDir
+	fsys = failFS{FS: base, name: "private", err: fs.ErrPermission}
+	if _, err := ListPages(fsys); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("unreadable directory: err = %v", err)
+	}
+
+	// failFS hides MapFS's ReadFile method, so fs.ReadFile falls back to
+	// Open, Stat and Read - the same result through the slower path
+	pages, err := ListPages(failFS{FS: base})
+	if err != nil || len(pages) != 2 {
+		t.Errorf("through Open only: %+v, %v", pages, err)
+	}
+}
+
+// 4. embed.FS
+// ===========
+
+func TestEmbeddedDocs(t *testing.T) {
+	pages, err := ListPages(Docs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, p := range pages {
+		titles = append(titles, p.Path+": "+p.Title)
+	}
+	want := []string{"guide/install.md: Installing", "guide/usage.md: Usage", "index.md: Welcome"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("embedded pages = %q, want %q", titles, want)
+	}
+}
+
+func TestEmbeddedFSContract(t *testing.T) {
+	// fstest.TestFS checks an fs.FS implementation: Open, ReadDir, Stat,
+	// Seek, Glob and fs.Sub all agree, and the listed files exist. Use it
+	// on any FS you write - and on fs.Sub results like this one.
+	if err := fstest.TestFS(Docs(), "index.md", "guide/install.md", "guide/usage.md"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler(Docs()))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/guide/install.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "# Installing\n\nDownload the binary and put it on your PATH.\n" {
+		t.Errorf("GET install.md = %d %q", resp.StatusCode, body)
+	}
+
+	// The handler is no more tied to embed than ListPages is
+	srv2 := httptest.NewServer(Handler(fstest.MapFS{"hi.md": {Data: []byte("hi")}}))
+	defer srv2.Close()
+	resp, err = http.Get(srv2.URL + "/hi.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hi" {
+		t.Errorf("GET from MapFS = %q", body)
+	}
+
+	resp, err = http.Get(srv.URL + "/missing.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET missing.md = %d, want 404", resp.StatusCode)
+	}
+}
+
+// Examples
+// ========
+
+func ExampleListPages() {
+	// The function cannot tell an in-memory FS from a directory or the
+	// binary's embedded files
+	sources := []struct {
+		name string
+		fsys fs.FS
+	}{
+		{"MapFS", fstest.MapFS{"hello.md": {Data: []byte("# Hello\nfrom memory")}}},
+		{"embed", Docs()},
+	}
+	for _, src := range sources {
+		pages, _ := ListPages(src.fsys)
+		fmt.Printf("%s: %d pages, first %q\n", src.name, len(pages), pages[0].Title)
+	}
+	// Output:
+	// MapFS: 1 pages, first "Hello"
+	// embed: 3 pages, first "Installing"
+}
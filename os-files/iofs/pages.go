@@ -0,0 +1,87 @@
+package iofs
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// After - Code That Accepts an fs.FS
+// ==================================
+// io/fs.FS has a single method, Open(name) (fs.File, error). Taking one
+// instead of a directory path changes three things:
+//
+//   - the caller chooses the source: os.DirFS(dir) for a real
+//     directory, an embed.FS compiled into the binary, a zip.Reader,
+//     fstest.MapFS in tests
+//   - names are slash-separated and relative ("guide/install.md"), on
+//     every OS. Use package path, not path/filepath, and never a
+//     leading "/" or ".." - fs.ValidPath checks this.
+//   - the helpers fs.ReadFile, fs.ReadDir, fs.Stat, fs.Glob and
+//     fs.WalkDir work on any FS, using faster methods (ReadFileFS,
+//     ReadDirFS, ...) when the FS has them
+//
+// The function body barely changes; it is the signature that frees it.
+
+// Page is a Markdown page and its metadata
+type Page struct {
+	Path  string // slash-separated, relative to the FS root
+	Title string // the first "# " heading, or the file name
+	Words int
+}
+
+// ListPages lists the .md pages in fsys, sorted by path. The order from
+// fs.WalkDir is already lexical; the sort makes it independent of the FS.
+func ListPages(fsys fs.FS) ([]Page, error) {
+	var pages []Page
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".md" {
+			return nil
+		}
+		p, err := ReadPage(fsys, name)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(pages, func(a, b Page) int { return strings.Compare(a.Path, b.Path) })
+	return pages, nil
+}
+
+// ReadPage reads one page. Names must be valid fs paths: "guide/x.md",
+// not "/guide/x.md" or "../x.md".
+func ReadPage(fsys fs.FS, name string) (Page, error) {
+	// Implementations differ on invalid names (os.DirFS says ErrInvalid,
+	// fstest.MapFS says ErrNotExist), so check once, here
+	if !fs.ValidPath(name) {
+		return Page{}, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Page{}, fmt.Errorf("read page: %w", err)
+	}
+	return parsePage(name, data), nil
+}
+
+// parsePage is shared by both versions: it never knew where the bytes
+// came from, which is why it needed no change
+func parsePage(name string, data []byte) Page {
+	p := Page{Path: name, Words: len(bytes.Fields(data))}
+	first, _, _ := bytes.Cut(data, []byte{'\n'})
+	if title, ok := bytes.CutPrefix(first, []byte("# ")); ok {
+		p.Title = string(bytes.TrimSpace(title))
+	} else {
+		p.Title = strings.TrimSuffix(path.Base(name), ".md")
+	}
+	return p
+}
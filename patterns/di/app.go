@@ -0,0 +1,209 @@
+package di
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The Application
+// ===============
+// Three layers, each depending on the one below through a value it is
+// given, never one it builds or finds in a global:
+//
+//	Handler   HTTP: decodes requests, maps errors to statuses
+//	Service   the rules: validation, unique emails, timestamps
+//	Repo      storage, behind an interface the service declares
+//
+// Because nothing here constructs its own dependencies, the same types
+// are wired three ways in wire.go, and a test can hand the service a
+// fake clock or a failing repo without any framework.
+
+// User is the one entity
+type User struct {
+	ID      int       `json:"id"`
+	Email   string    `json:"email"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+var (
+	ErrNotFound  = errors.New("user not found")
+	ErrDuplicate = errors.New("email already registered")
+	ErrInvalid   = errors.New("invalid user")
+)
+
+// Repo stores users. It is declared here, beside its consumer, with
+// only the methods the service calls.
+type Repo interface {
+	Get(ctx context.Context, id int) (User, error)
+	ByEmail(ctx context.Context, email string) (User, error)
+	Add(ctx context.Context, u User) (User, error)
+}
+
+// MemRepo is an in-memory Repo
+type MemRepo struct {
+	mu    sync.Mutex
+	users []User // ID is index+1
+}
+
+// NewMemRepo returns an empty repo
+func NewMemRepo() *MemRepo { return &MemRepo{} }
+
+// Get returns the user with id, or ErrNotFound
+func (r *MemRepo) Get(_ context.Context, id int) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id < 1 || id > len(r.users) {
+		return User{}, ErrNotFound
+	}
+	return r.users[id-1], nil
+}
+
+// ByEmail returns the user with email, or ErrNotFound
+func (r *MemRepo) ByEmail(_ context.Context, email string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+// Add stores u with the next ID and returns it
+func (r *MemRepo) Add(_ context.Context, u User) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u.ID = len(r.users) + 1
+	r.users = append(r.users, u)
+	return u, nil
+}
+
+// Clock returns the current time. A named type, so a container can
+// tell it from any other func() time.Time.
+type Clock func() time.Time
+
+// Service holds the rules for users
+type Service struct {
+	repo    Repo
+	now     Clock
+	log     *slog.Logger
+	maxName int
+}
+
+// NewService is constructor injection: every dependency is a
+// parameter, and the compiler checks that each is supplied
+func NewService(repo Repo, now Clock, log *slog.Logger) *Service {
+	return &Service{repo: repo, now: now, log: log, maxName: 100}
+}
+
+// Register validates and stores a new user. Checking the email and
+// adding are two calls; a real repo would enforce uniqueness itself.
+func (s *Service) Register(ctx context.Context, email, name string) (User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	name = strings.TrimSpace(name)
+	if _, err := mail.ParseAddress(email); err != nil {
+		return User{}, fmt.Errorf("%w: email %q", ErrInvalid, email)
+	}
+	if name == "" || len(name) > s.maxName {
+		return User{}, fmt.Errorf("%w: name must be 1 to %d bytes", ErrInvalid, s.maxName)
+	}
+	switch _, err := s.repo.ByEmail(ctx, email); {
+	case err == nil:
+		return User{}, ErrDuplicate
+	case !errors.Is(err, ErrNotFound):
+		return User{}, err
+	}
+	u, err := s.repo.Add(ctx, User{Email: email, Name: name, Created: s.now().UTC()})
+	if err != nil {
+		return User{}, err
+	}
+	s.log.InfoContext(ctx, "registered", "id", u.ID)
+	return u, nil
+}
+
+// Get returns a user by ID
+func (s *Service) Get(ctx context.Context, id int) (User, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Handler serves the service over HTTP:
+//
+//	POST /users        {"email", "name"} -> 201 and the user
+//	GET  /users/{id}   -> 200 and the user
+type Handler struct {
+	svc *Service
+	mux *http.ServeMux
+}
+
+// NewHandler returns the HTTP layer over svc
+func NewHandler(svc *Service) *Handler {
+	h := &Handler{svc: svc, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST /users", h.register)
+	h.mux.HandleFunc("GET /users/{id}", h.get)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) { h.mux.ServeHTTP(w, r) }
+
+func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
+	var in struct{ Email, Name string }
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&in); err != nil {
+		writeError(w, fmt.Errorf("%w: %v", ErrInvalid, err))
+		return
+	}
+	u, err := h.svc.Register(r.Context(), in.Email, in.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, u)
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, ErrNotFound)
+		return
+	}
+	u, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrDuplicate):
+		status = http.StatusConflict
+	}
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		msg = "internal error"
+	}
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
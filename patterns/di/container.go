@@ -0,0 +1,107 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// The Container
+// =============
+// A dependency-injection container on reflect. Constructors are
+// registered by their return type; resolving a type calls its
+// constructor with parameters resolved the same way, once, and keeps
+// the result. A missing constructor or a cycle is reported with the
+// path that led to it - at run time, which is the price.
+
+// Container holds constructors and the singletons built from them
+type Container struct {
+	providers map[reflect.Type]reflect.Value
+	instances map[reflect.Type]reflect.Value
+	resolving []reflect.Type // current resolution path, for cycle detection
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// NewContainer returns an empty container
+func NewContainer() *Container {
+	return &Container{
+		providers: make(map[reflect.Type]reflect.Value),
+		instances: make(map[reflect.Type]reflect.Value),
+	}
+}
+
+// Provide registers a constructor. It must be a function returning either
+// T or (T, error); its parameters are resolved from the container.
+func (c *Container) Provide(constructor any) error {
+	fn := reflect.ValueOf(constructor)
+	t := fn.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("provide: %s is not a function", t)
+	}
+	if t.NumOut() == 0 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return fmt.Errorf("provide: %s must return T or (T, error)", t)
+	}
+	out := t.Out(0)
+	if _, exists := c.providers[out]; exists {
+		return fmt.Errorf("provide: a constructor for %s is already registered", out)
+	}
+	c.providers[out] = fn
+	return nil
+}
+
+// Resolve returns the instance for T, building it and its dependencies
+// on first use
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	v, err := c.get(reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	t, _ := v.Interface().(T) // ok is false only for a nil interface
+	return t, nil
+}
+
+func (c *Container) get(t reflect.Type) (reflect.Value, error) {
+	if v, ok := c.instances[t]; ok {
+		return v, nil
+	}
+	for i, r := range c.resolving {
+		if r == t {
+			return reflect.Value{}, fmt.Errorf("dependency cycle: %s", cyclePath(append(append([]reflect.Type(nil), c.resolving[i:]...), t)))
+		}
+	}
+	ctor, ok := c.providers[t]
+	if !ok {
+		if len(c.resolving) > 0 {
+			return reflect.Value{}, fmt.Errorf("no constructor for %s (needed by %s)", t, c.resolving[len(c.resolving)-1])
+		}
+		return reflect.Value{}, fmt.Errorf("no constructor for %s", t)
+	}
+
+	c.resolving = append(c.resolving, t)
+	defer func() { c.resolving = c.resolving[:len(c.resolving)-1] }()
+
+	args := make([]reflect.Value, ctor.Type().NumIn())
+	for i := range args {
+		v, err := c.get(ctor.Type().In(i))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = v
+	}
+	out := ctor.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("constructing %s: %w", t, out[1].Interface().(error))
+	}
+	c.instances[t] = out[0]
+	return out[0], nil
+}
+
+func cyclePath(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
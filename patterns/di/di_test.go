@@ -0,0 +1,279 @@
+package di
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Dependency Injection - Tests
+// ============================
+// Run with:
+//
+//   cd patterns/di
+//   go test -v *.go
+//
+// Section 1 is one suite run against every wiring: if the three ways
+// of building the app differ in behaviour, it fails. The later
+// sections test what differs between them - defaults, and where a
+// wiring mistake is caught.
+
+var wirings = []struct {
+	name string
+	wire Wiring
+}{
+	{"by hand", WireByHand},
+	{"options", WireWithOptions},
+	{"container", WireWithContainer},
+}
+
+var epoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testEnv(logs *bytes.Buffer) Env {
+	return Env{
+		Clock:  func() time.Time { return epoch },
+		Logger: slog.New(slog.NewTextHandler(logs, nil)),
+	}
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: bad JSON %q", method, path, rec.Body)
+	}
+	return rec.Code, out
+}
+
+// 1. One Suite, Three Wirings
+// ===========================
+
+func TestWirings(t *testing.T) {
+	for _, w := range wirings {
+		t.Run(w.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			h, err := w.wire(testEnv(&logs))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			code, u := do(t, h, "POST", "/users", `{"email": " Ada@Example.com ", "name": "Ada"}`)
+			if code != http.StatusCreated || u["id"] != 1.0 || u["email"] != "ada@example.com" {
+				t.Fatalf("register: %d %v", code, u)
+			}
+			if u["created"] != epoch.Format(time.RFC3339) {
+				t.Errorf("created = %v: the clock was not injected", u["created"])
+			}
+			if !strings.Contains(logs.String(), "msg=registered id=1") {
+				t.Errorf("the logger was not injected: %q", logs.String())
+			}
+
+			tests := []struct {
+				method, path, body string
+				want               int
+			}{
+				{"GET", "/users/1", "", http.StatusOK},
+				{"GET", "/users/2", "", http.StatusNotFound},
+				{"GET", "/users/x", "", http.StatusNotFound},
+				{"POST", "/users", `{"email": "ADA@example.com", "name": "Imposter"}`, http.StatusConflict},
+				{"POST", "/users", `{"email": "not an email", "name": "Bob"}`, http.StatusBadRequest},
+				{"POST", "/users", `{"email": "bob@example.com", "name": "  "}`, http.StatusBadRequest},
+				{"POST", "/users", `{"email":`, http.StatusBadRequest},
+				{"POST", "/users", `{"email": "bob@example.com", "name": "Bob"}`, http.StatusCreated},
+			}
+			for _, tt := range tests {
+				if code, body := do(t, h, tt.method, tt.path, tt.body); code != tt.want {
+					t.Errorf("%s %s %s: %d %v, want %d", tt.method, tt.path, tt.body, code, body, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWiringsAreIndependent(t *testing.T) {
+	// Each call builds its own repo: no state leaks between apps
+	for _, w := range wirings {
+		var logs bytes.Buffer
+		a, _ := w.wire(testEnv(&logs))
+		b, _ := w.wire(testEnv(&logs))
+		do(t, a, "POST", "/users", `{"email": "ada@example.com", "name": "Ada"}`)
+		if code, _ := do(t, b, "GET", "/users/1", ""); code != http.StatusNotFound {
+			t.Errorf("%s: second app sees the first app's user", w.name)
+		}
+	}
+}
+
+// 2. Swapping a Dependency
+// ========================
+
+// brokenRepo fails the email lookup, as a database that is down would
+type brokenRepo struct{ *MemRepo }
+
+func (*brokenRepo) ByEmail(context.Context, string) (User, error) {
+	return User{}, errors.New("connection refused")
+}
+
+func TestFailingRepoIsHidden(t *testing.T) {
+	// By hand, a fake goes in where the real one would
+	svc := NewService(&brokenRepo{NewMemRepo()}, time.Now, slog.New(slog.DiscardHandler))
+	code, body := do(t, NewHandler(svc), "POST", "/users", `{"email": "ada@example.com", "name": "Ada"}`)
+	if code != http.StatusInternalServerError || body["error"] != "internal error" {
+		t.Errorf("got %d %v", code, body)
+	}
+}
+
+// 3. Options and Defaults
+// =======================
+
+func TestOptionsDefaults(t *testing.T) {
+	// Options is the only wiring that works with nothing set
+	h, err := WireWithOptions(Env{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now().UTC()
+	_, u := do(t, h, "POST", "/users", `{"email": "ada@example.com", "name": "Ada"}`)
+	created, _ := time.Parse(time.RFC3339Nano, u["created"].(string))
+	if created.Before(before.Add(-time.Second)) {
+		t.Errorf("created = %v, want about now", created)
+	}
+}
+
+func TestWithMaxName(t *testing.T) {
+	svc := NewServiceWith(NewMemRepo(), WithMaxName(3))
+	if _, err := svc.Register(t.Context(), "ada@example.com", "Adaline"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("err = %v, want ErrInvalid", err)
+	}
+	if _, err := svc.Register(t.Context(), "ada@example.com", "Ada"); err != nil {
+		t.Error(err)
+	}
+}
+
+// 4. Where Mistakes Are Caught
+// ============================
+// By hand, leaving out an argument does not compile. With a container
+// the same mistake is an error at startup; these tests pin down that
+// it at least says what is missing.
+
+func TestContainerMissingConstructor(t *testing.T) {
+	c := NewContainer()
+	c.Provide(NewHandler)
+	c.Provide(NewService)
+	c.Provide(func() Clock { return time.Now })
+	_, err := Resolve[*Handler](c)
+	if err == nil || !strings.Contains(err.Error(), "no constructor for di.Repo (needed by *di.Service)") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+type (
+	cycleA struct{}
+	cycleB struct{}
+)
+
+func TestContainerCycle(t *testing.T) {
+	c := NewContainer()
+	c.Provide(func(*cycleB) *cycleA { return nil })
+	c.Provide(func(*cycleA) *cycleB { return nil })
+	_, err := Resolve[*cycleA](c)
+	if err == nil || !strings.Contains(err.Error(), "*di.cycleA -> *di.cycleB -> *di.cycleA") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestContainerSingletons(t *testing.T) {
+	calls := 0
+	c := NewContainer()
+	c.Provide(func() Repo { calls++; return NewMemRepo() })
+	a, _ := Resolve[Repo](c)
+	b, _ := Resolve[Repo](c)
+	if calls != 1 || a != b {
+		t.Errorf("%d calls; same repo: %t", calls, a == b)
+	}
+}
+
+func TestContainerRejectsBadProviders(t *testing.T) {
+	c := NewContainer()
+	for _, p := range []any{42, func() {}, func() (int, string) { return 0, "" }} {
+		if err := c.Provide(p); err == nil {
+			t.Errorf("Provide(%T) accepted", p)
+		}
+	}
+	c.Provide(NewMemRepo)
+	if err := c.Provide(NewMemRepo); err == nil {
+		t.Error("duplicate Provide accepted")
+	}
+}
+
+// A constructor's error names the type it was building
+func TestContainerConstructorError(t *testing.T) {
+	c := NewContainer()
+	c.Provide(NewHandler)
+	c.Provide(NewService)
+	c.Provide(func() (Repo, error) { return nil, ErrNotFound })
+	c.Provide(func() Clock { return time.Now })
+	c.Provide(func() *slog.Logger { return slog.New(slog.DiscardHandler) })
+	_, err := Resolve[*Handler](c)
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "constructing di.Repo") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// A failed resolution leaves nothing behind: the next one starts with
+// an empty path and builds what it can
+func TestContainerRecoversFromFailure(t *testing.T) {
+	c := NewContainer()
+	c.Provide(NewService)
+	c.Provide(func() Clock { return time.Now })
+	c.Provide(func() *slog.Logger { return slog.New(slog.DiscardHandler) })
+	if _, err := Resolve[*Service](c); err == nil {
+		t.Fatal("resolved *Service without a Repo")
+	}
+	if len(c.resolving) != 0 {
+		t.Errorf("resolving = %v after a failure, want empty", c.resolving)
+	}
+	c.Provide(func() Repo { return NewMemRepo() })
+	if svc, err := Resolve[*Service](c); err != nil || svc == nil {
+		t.Errorf("Resolve after the fix: %v, %v", svc, err)
+	}
+}
+
+// 5. Benchmarks
+// =============
+// Wiring runs once per process, so its cost rarely matters; this only
+// shows what the reflection costs.
+
+func BenchmarkWire(b *testing.B) {
+	env := testEnv(&bytes.Buffer{})
+	for _, w := range wirings {
+		b.Run(w.name, func(b *testing.B) {
+			for b.Loop() {
+				w.wire(env)
+			}
+		})
+	}
+}
+
+// 6. Examples
+// ===========
+
+func ExampleWireByHand() {
+	h, _ := WireByHand(Env{
+		Clock:  func() time.Time { return epoch },
+		Logger: slog.New(slog.DiscardHandler),
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/users", strings.NewReader(`{"email": "ada@example.com", "name": "Ada"}`)))
+	fmt.Print(rec.Code, " ", rec.Body)
+	// Output: 201 {"id":1,"email":"ada@example.com","name":"Ada","created":"2026-03-01T12:00:00Z"}
+}
@@ -0,0 +1,112 @@
+package di
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Wiring the Same App Three Ways
+// ==============================
+// Dependency injection is a name for passing things in. The question
+// is only who calls the constructors, and in what order:
+//
+//	by hand        main calls them in order. Every mistake - a missing
+//	               argument, a wrong type - is a compile error, and the
+//	               wiring reads top to bottom.
+//	options        required dependencies stay parameters; optional ones
+//	               become Option values with defaults. Good for
+//	               libraries with many knobs; a typo'd default is
+//	               invisible until it matters.
+//	container      constructors are registered and resolved by type at
+//	               run time (container.go). Order stops mattering,
+//	               and every wiring mistake becomes a run-time error.
+//
+// All three produce the same http.Handler, and di_test.go runs the
+// same tests against each. For an app this size by hand wins: it is
+// five lines. A container starts to pay only when many binaries share
+// a large graph - and a code generator such as google/wire gets that
+// convenience back with compile-time checks.
+//
+// Pitfalls:
+//
+//	constructors that build their    tests cannot swap them; take them
+//	own dependencies                 as parameters
+//	a global logger or DB handle     every test shares it; pass it in
+//	options for required things      forgetting one compiles and fails
+//	                                 at run time, or worse, not at all
+//	a container for six types        reflection and run-time errors to
+//	                                 save five lines
+
+// Env is what main reads from flags and the environment
+type Env struct {
+	Clock  Clock        // required by hand and in the container
+	Logger *slog.Logger // likewise
+}
+
+// Wiring builds the app from env
+type Wiring func(env Env) (http.Handler, error)
+
+// WireByHand calls each constructor in dependency order
+func WireByHand(env Env) (http.Handler, error) {
+	repo := NewMemRepo()
+	svc := NewService(repo, env.Clock, env.Logger)
+	return NewHandler(svc), nil
+}
+
+// Option configures a Service built by NewServiceWith
+type Option func(*Service)
+
+// WithClock sets the time source; the default is time.Now
+func WithClock(c Clock) Option { return func(s *Service) { s.now = c } }
+
+// WithLogger sets the logger; the default discards
+func WithLogger(l *slog.Logger) Option { return func(s *Service) { s.log = l } }
+
+// WithMaxName sets the longest name accepted, in bytes; the default is
+// 100
+func WithMaxName(n int) Option { return func(s *Service) { s.maxName = n } }
+
+// NewServiceWith takes the one dependency with no sensible default as
+// a parameter, and the rest as options
+func NewServiceWith(repo Repo, opts ...Option) *Service {
+	s := NewService(repo, time.Now, slog.New(slog.DiscardHandler))
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WireWithOptions passes only what env sets; defaults cover the rest
+func WireWithOptions(env Env) (http.Handler, error) {
+	var opts []Option
+	if env.Clock != nil {
+		opts = append(opts, WithClock(env.Clock))
+	}
+	if env.Logger != nil {
+		opts = append(opts, WithLogger(env.Logger))
+	}
+	return NewHandler(NewServiceWith(NewMemRepo(), opts...)), nil
+}
+
+// WireWithContainer registers constructors in any order and asks for
+// the handler
+func WireWithContainer(env Env) (http.Handler, error) {
+	c := NewContainer()
+	for _, ctor := range []any{
+		NewHandler,
+		NewService,
+		func() Repo { return NewMemRepo() }, // bind the interface
+		func() Clock { return env.Clock },
+		func() *slog.Logger { return env.Logger },
+	} {
+		if err := c.Provide(ctor); err != nil {
+			return nil, err
+		}
+	}
+	h, err := Resolve[*Handler](c)
+	if err != nil {
+		return nil, err // not h: a nil *Handler is a non-nil http.Handler
+	}
+	return h, nil
+}
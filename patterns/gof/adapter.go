@@ -0,0 +1,118 @@
+package gof
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Adapter
+// =======
+// Our code depends on the interface it wants; an adapter makes
+// something with a different shape satisfy it. The notification code
+// below wants a Notifier. Two vendors' SDKs do the job with other
+// method sets, and a plain function does it for tests - three
+// adapters, and the caller never learns which it has:
+//
+//	SMSAdapter     a struct that holds the vendor client and translates
+//	               the call: phone numbers, byte bodies, a status code
+//	               instead of an error
+//	MailAdapter    the same for a mail SDK with no context parameter
+//	NotifierFunc   a function type with the method on it, like
+//	               http.HandlerFunc: the lightest adapter there is
+//
+// In Go the interface belongs to the consumer, and types satisfy it
+// without saying so. Often the "adapter" is no code at all: if the
+// vendor's method already has the right signature, declare the small
+// interface and pass the client straight in.
+
+// Notifier delivers a message to a recipient
+type Notifier interface {
+	Notify(ctx context.Context, to, msg string) error
+}
+
+// NotifierFunc adapts a function to Notifier
+type NotifierFunc func(ctx context.Context, to, msg string) error
+
+// Notify calls f
+func (f NotifierFunc) Notify(ctx context.Context, to, msg string) error { return f(ctx, to, msg) }
+
+// SMSClient stands in for a vendor SDK whose shape we do not control
+type SMSClient struct {
+	Sent []string
+}
+
+// Send returns a vendor status code: 0 for accepted
+func (c *SMSClient) Send(phone string, body []byte) (status int) {
+	if !strings.HasPrefix(phone, "+") {
+		return 21211 // the vendor's "invalid number"
+	}
+	c.Sent = append(c.Sent, phone+": "+string(body))
+	return 0
+}
+
+// ErrInvalidRecipient is returned for a recipient the channel cannot
+// address
+var ErrInvalidRecipient = errors.New("gof: invalid recipient")
+
+// SMSAdapter makes an SMSClient a Notifier. Messages longer than one
+// SMS are cut, because the vendor would charge for several.
+type SMSAdapter struct {
+	Client *SMSClient
+}
+
+// Notify sends msg to the phone number to
+func (a SMSAdapter) Notify(ctx context.Context, to, msg string) error {
+	if err := ctx.Err(); err != nil {
+		return err // the SDK cannot be cancelled; at least do not start
+	}
+	if len(msg) > 160 {
+		msg = msg[:157] + "..."
+	}
+	switch status := a.Client.Send(to, []byte(msg)); status {
+	case 0:
+		return nil
+	case 21211:
+		return fmt.Errorf("sms to %q: %w", to, ErrInvalidRecipient)
+	default:
+		return fmt.Errorf("sms to %q: vendor status %d", to, status)
+	}
+}
+
+// MailClient stands in for a mail SDK
+type MailClient struct {
+	Outbox []Mail
+}
+
+// Mail is the SDK's message type
+type Mail struct {
+	To, Subject, Body string
+}
+
+// Deliver queues m
+func (c *MailClient) Deliver(m Mail) error {
+	if !strings.Contains(m.To, "@") {
+		return errors.New("mail: bad address")
+	}
+	c.Outbox = append(c.Outbox, m)
+	return nil
+}
+
+// MailAdapter makes a MailClient a Notifier, with the first line of
+// msg as the subject
+type MailAdapter struct {
+	Client *MailClient
+}
+
+// Notify mails msg to the address to
+func (a MailAdapter) Notify(ctx context.Context, to, msg string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !strings.Contains(to, "@") {
+		return fmt.Errorf("mail to %q: %w", to, ErrInvalidRecipient)
+	}
+	subject, body, _ := strings.Cut(msg, "\n")
+	return a.Client.Deliver(Mail{To: to, Subject: subject, Body: body})
+}
@@ -0,0 +1,115 @@
+package gof
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Decorator
+// =========
+// A decorator implements an interface by wrapping another value of the
+// same interface, adding behaviour around the call. Callers cannot
+// tell a decorated Notifier from a plain one, so behaviour is stacked
+// without touching either side:
+//
+//	Logged    logs each call and its outcome
+//	Timeout   gives each call a deadline
+//	Dedupe    drops a message already sent to the same recipient
+//	          within a window
+//
+// Each is written as a Middleware - func(Notifier) Notifier - the same
+// shape as HTTP middleware (see web/server), and Chain applies them so
+// that the first listed is the outermost. Order matters: Dedupe inside
+// Logged logs the duplicates it drops; outside, they never reach the
+// log.
+//
+// io.Reader is the standard library's decorator showcase:
+// bufio.NewReader, gzip.NewReader and io.LimitReader all wrap a Reader
+// and are one.
+
+// Middleware wraps a Notifier with extra behaviour
+type Middleware func(Notifier) Notifier
+
+// Chain wraps n in mws, the first outermost
+func Chain(n Notifier, mws ...Middleware) Notifier {
+	for i := len(mws) - 1; i >= 0; i-- {
+		n = mws[i](n)
+	}
+	return n
+}
+
+// Logged logs every notification and its error
+func Logged(logger *slog.Logger) Middleware {
+	return func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, to, msg string) error {
+			start := time.Now()
+			err := next.Notify(ctx, to, msg)
+			if err != nil {
+				logger.ErrorContext(ctx, "notify", "to", to, "err", err)
+			} else {
+				logger.InfoContext(ctx, "notify", "to", to, "took", time.Since(start))
+			}
+			return err
+		})
+	}
+}
+
+// Timeout gives each notification at most d
+func Timeout(d time.Duration) Middleware {
+	return func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, to, msg string) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next.Notify(ctx, to, msg)
+		})
+	}
+}
+
+// Dedupe drops a message already delivered to the same recipient less
+// than window ago. now is time.Now if nil.
+func Dedupe(window time.Duration, now func() time.Time) Middleware {
+	if now == nil {
+		now = time.Now
+	}
+	return func(next Notifier) Notifier {
+		return &dedupe{next: next, window: window, now: now, seen: map[[2]string]time.Time{}}
+	}
+}
+
+// dedupe is a struct, not a closure, because it carries state; either
+// works as long as the result is a Notifier
+type dedupe struct {
+	next   Notifier
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	seen map[[2]string]time.Time
+}
+
+func (d *dedupe) Notify(ctx context.Context, to, msg string) error {
+	key := [2]string{to, msg}
+	d.mu.Lock()
+	if at, ok := d.seen[key]; ok && d.now().Sub(at) < d.window {
+		d.mu.Unlock()
+		return nil
+	}
+	d.mu.Unlock()
+
+	// Record only what was delivered, so a failure can be retried
+	if err := d.next.Notify(ctx, to, msg); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	for k, at := range d.seen {
+		if now.Sub(at) >= d.window {
+			delete(d.seen, k) // keep the map to one window's worth
+		}
+	}
+	d.seen[key] = now
+	return nil
+}
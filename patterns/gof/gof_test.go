@@ -0,0 +1,419 @@
+package gof
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Design Patterns - Tests
+// =======================
+// Run with:
+//
+//   cd patterns/gof
+//   go test -v *.go
+//   go test -race *.go
+//
+// Every pattern is tested through the interface or function type the
+// caller sees, so the tests read the way the calling code would.
+
+// recorder is a Notifier that remembers what it was asked to send
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+	err  error
+}
+
+func (r *recorder) Notify(ctx context.Context, to, msg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, to+": "+msg)
+	return nil
+}
+
+// fakeClock is a settable time source; see testing/clock for a full
+// version
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// 1. Adapter
+// ==========
+
+func TestAdaptersAreNotifiers(t *testing.T) {
+	sms := &SMSClient{}
+	mail := &MailClient{}
+	var calls []string
+	channels := map[string]Notifier{
+		"sms":  SMSAdapter{sms},
+		"mail": MailAdapter{mail},
+		"func": NotifierFunc(func(_ context.Context, to, msg string) error {
+			calls = append(calls, to)
+			return nil
+		}),
+	}
+	to := map[string]string{"sms": "+15550100", "mail": "ada@example.com", "func": "ada"}
+	for name, n := range channels {
+		if err := n.Notify(t.Context(), to[name], "Order shipped\nIt left the warehouse today."); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	if len(sms.Sent) != 1 || !strings.HasPrefix(sms.Sent[0], "+15550100: Order shipped") {
+		t.Errorf("sms sent %q", sms.Sent)
+	}
+	if want := (Mail{"ada@example.com", "Order shipped", "It left the warehouse today."}); len(mail.Outbox) != 1 || mail.Outbox[0] != want {
+		t.Errorf("mail outbox %+v, want %+v", mail.Outbox, want)
+	}
+	if len(calls) != 1 {
+		t.Errorf("func called %d times", len(calls))
+	}
+}
+
+func TestAdapterTranslatesErrors(t *testing.T) {
+	// Vendor status codes and ad-hoc errors become one error callers
+	// can check
+	for name, n := range map[string]Notifier{
+		"sms":  SMSAdapter{&SMSClient{}},
+		"mail": MailAdapter{&MailClient{}},
+	} {
+		if err := n.Notify(t.Context(), "not an address", "hi"); !errors.Is(err, ErrInvalidRecipient) {
+			t.Errorf("%s: err = %v, want ErrInvalidRecipient", name, err)
+		}
+	}
+}
+
+func TestSMSAdapterTruncates(t *testing.T) {
+	sms := &SMSClient{}
+	SMSAdapter{sms}.Notify(t.Context(), "+1", strings.Repeat("x", 200))
+	body := strings.TrimPrefix(sms.Sent[0], "+1: ")
+	if len(body) != 160 || !strings.HasSuffix(body, "...") {
+		t.Errorf("body is %d bytes: %q", len(body), body)
+	}
+}
+
+func TestAdapterRespectsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	sms := &SMSClient{}
+	if err := (SMSAdapter{sms}).Notify(ctx, "+1", "hi"); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v", err)
+	}
+	if len(sms.Sent) != 0 {
+		t.Error("sent after cancellation")
+	}
+}
+
+// 2. Decorator
+// ============
+
+func TestChainOrder(t *testing.T) {
+	var trace []string
+	tag := func(name string) Middleware {
+		return func(next Notifier) Notifier {
+			return NotifierFunc(func(ctx context.Context, to, msg string) error {
+				trace = append(trace, name+" in")
+				err := next.Notify(ctx, to, msg)
+				trace = append(trace, name+" out")
+				return err
+			})
+		}
+	}
+	n := Chain(&recorder{}, tag("a"), tag("b"))
+	n.Notify(t.Context(), "ada", "hi")
+	if want := []string{"a in", "b in", "b out", "a out"}; !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	rec := &recorder{}
+	n := Chain(rec, Logged(logger))
+
+	n.Notify(t.Context(), "ada", "hi")
+	rec.err = errors.New("vendor down")
+	if err := n.Notify(t.Context(), "bob", "hi"); err == nil {
+		t.Error("the decorator swallowed the error")
+	}
+	logs := buf.String()
+	for _, want := range []string{"level=INFO msg=notify to=ada", `level=ERROR msg=notify to=bob err="vendor down"`} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs missing %q:\n%s", want, logs)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow := NotifierFunc(func(ctx context.Context, _, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	err := Chain(slow, Timeout(10*time.Millisecond)).Notify(t.Context(), "ada", "hi")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)}
+	rec := &recorder{}
+	n := Chain(rec, Dedupe(time.Minute, clock.Now))
+
+	n.Notify(t.Context(), "ada", "build failed")
+	n.Notify(t.Context(), "ada", "build failed") // dropped
+	n.Notify(t.Context(), "bob", "build failed") // another recipient
+	clock.Advance(time.Minute)
+	n.Notify(t.Context(), "ada", "build failed") // window passed
+
+	if len(rec.sent) != 3 {
+		t.Errorf("sent %q, want 3", rec.sent)
+	}
+}
+
+func TestDedupeRetriesFailures(t *testing.T) {
+	rec := &recorder{err: errors.New("down")}
+	n := Chain(rec, Dedupe(time.Hour, nil))
+	n.Notify(t.Context(), "ada", "hi")
+	rec.err = nil
+	n.Notify(t.Context(), "ada", "hi")
+	if len(rec.sent) != 1 {
+		t.Errorf("a failed send was remembered as sent: %q", rec.sent)
+	}
+}
+
+func TestDecoratorsConcurrent(t *testing.T) {
+	rec := &recorder{}
+	logger := slog.New(slog.DiscardHandler)
+	n := Chain(rec, Logged(logger), Timeout(time.Second), Dedupe(time.Hour, nil))
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() { n.Notify(t.Context(), fmt.Sprint(i%5), "hi") })
+	}
+	wg.Wait()
+	if len(rec.sent) != 5 {
+		t.Errorf("sent %d, want one per recipient", len(rec.sent))
+	}
+}
+
+// 3. Strategy
+// ===========
+
+var cart = Cart{Items: []Item{
+	{SKU: "mug", Cents: 1_200, Qty: 3},
+	{SKU: "tea", Cents: 850, Qty: 2},
+}}
+
+func TestDiscounts(t *testing.T) {
+	withCoupon := cart
+	withCoupon.Coupon = "SPRING"
+	tests := []struct {
+		name string
+		d    Discount
+		cart Cart
+		want int64 // total
+	}{
+		{"none", nil, cart, 5_300},
+		{"10%", Percent(10), cart, 4_770},
+		{"3 for 2 mugs", BuyXGetY("mug", 2, 1), cart, 4_100},
+		{"3 for 2 with 2 mugs", BuyXGetY("tea", 2, 1), cart, 5_300},
+		{"coupon missing", Coupon("SPRING", Percent(50)), cart, 5_300},
+		{"coupon present", Coupon("SPRING", Percent(50)), withCoupon, 2_650},
+		{"best of", Best(Percent(10), BuyXGetY("mug", 2, 1)), cart, 4_100},
+		{"capped", Capped(Percent(50), 1_000), cart, 4_300},
+		{"more than the cart", func(Cart) int64 { return 1e9 }, cart, 0},
+		{"negative", func(Cart) int64 { return -500 }, cart, 5_300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Checkout{Discount: tt.d}).Total(tt.cart); got != tt.want {
+				t.Errorf("Total = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestOfNothing(t *testing.T) {
+	if got := Best()(cart); got != 0 {
+		t.Errorf("Best() = %d", got)
+	}
+}
+
+// 4. Observer
+// ===========
+
+func TestFeedDelivers(t *testing.T) {
+	var f Feed[float64]
+	a, b := f.Subscribe(8), f.Subscribe(8)
+	if n := f.Publish(21.5); n != 2 {
+		t.Errorf("Publish reached %d subscribers", n)
+	}
+	f.Publish(22.0)
+	for _, s := range []*Subscription[float64]{a, b} {
+		if got := []float64{<-s.C(), <-s.C()}; !slices.Equal(got, []float64{21.5, 22.0}) {
+			t.Errorf("got %v", got)
+		}
+	}
+}
+
+func TestSlowSubscriberDropsOldest(t *testing.T) {
+	var f Feed[int]
+	slow := f.Subscribe(3)
+	fast := f.Subscribe(100)
+	for i := range 10 {
+		f.Publish(i)
+	}
+	// The publisher never waited; the slow subscriber keeps the latest
+	// three, and the fast one lost nothing
+	f.Close()
+	var got []int
+	for v := range slow.C() {
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{7, 8, 9}) || slow.Dropped() != 7 {
+		t.Errorf("slow got %v, dropped %d", got, slow.Dropped())
+	}
+	n := 0
+	for range fast.C() {
+		n++
+	}
+	if n != 10 || fast.Dropped() != 0 {
+		t.Errorf("fast got %d, dropped %d", n, fast.Dropped())
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	var f Feed[string]
+	s := f.Subscribe(1)
+	s.Unsubscribe()
+	s.Unsubscribe()
+	if _, ok := <-s.C(); ok {
+		t.Error("channel still open after Unsubscribe")
+	}
+	if n := f.Publish("x"); n != 0 {
+		t.Errorf("Publish reached %d subscribers", n)
+	}
+	f.Close()
+	if _, ok := <-f.Subscribe(1).C(); ok {
+		t.Error("subscription to a closed feed is open")
+	}
+}
+
+func TestFeedConcurrent(t *testing.T) {
+	var f Feed[int]
+	var wg sync.WaitGroup
+	for range 4 {
+		s := f.Subscribe(4)
+		wg.Go(func() {
+			last := -1
+			for v := range s.C() {
+				if v <= last {
+					t.Errorf("got %d after %d", v, last)
+				}
+				last = v
+			}
+		})
+	}
+	for i := range 1000 {
+		f.Publish(i)
+	}
+	f.Close()
+	wg.Wait()
+}
+
+// 5. Idioms Instead of Patterns
+// =============================
+
+func TestDefaultCheckoutOnce(t *testing.T) {
+	var wg sync.WaitGroup
+	totals := make([]int64, 10)
+	for i := range totals {
+		wg.Go(func() { totals[i] = DefaultCheckout().Total(cart) })
+	}
+	wg.Wait()
+	for _, got := range totals {
+		if got != 4_100 {
+			t.Errorf("Total = %d, want 4100", got)
+		}
+	}
+}
+
+func TestUnitsStopsEarly(t *testing.T) {
+	var got []string
+	for sku := range cart.Units() {
+		if len(got) == 4 {
+			break
+		}
+		got = append(got, sku)
+	}
+	if want := []string{"mug", "mug", "mug", "tea"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// 6. Benchmarks
+// =============
+
+func BenchmarkChain(b *testing.B) {
+	n := Chain(NotifierFunc(func(context.Context, string, string) error { return nil }),
+		Logged(slog.New(slog.DiscardHandler)), Timeout(time.Second))
+	ctx := context.Background()
+	for b.Loop() {
+		n.Notify(ctx, "ada", "hi")
+	}
+}
+
+func BenchmarkPublish(b *testing.B) {
+	var f Feed[int]
+	for range 8 {
+		f.Subscribe(64)
+	}
+	for b.Loop() {
+		f.Publish(1)
+	}
+}
+
+// 7. Examples
+// ===========
+
+func ExampleChain() {
+	mail := &MailClient{}
+	n := Chain(MailAdapter{mail}, Timeout(time.Second), Dedupe(time.Hour, nil))
+	for range 3 {
+		n.Notify(context.Background(), "ada@example.com", "Your order shipped\nTracking: 1Z999")
+	}
+	fmt.Println(len(mail.Outbox), mail.Outbox[0].Subject)
+	// Output: 1 Your order shipped
+}
+
+func ExampleBest() {
+	co := Checkout{Discount: Best(Percent(10), BuyXGetY("mug", 2, 1))}
+	fmt.Println(co.Total(cart))
+	// Output: 4100
+}
+
+func ExampleFeed() {
+	var temps Feed[float64]
+	sub := temps.Subscribe(4)
+	temps.Publish(20.5)
+	temps.Publish(21.0)
+	temps.Close()
+	for v := range sub.C() {
+		fmt.Println(v)
+	}
+	// Output:
+	// 20.5
+	// 21
+}
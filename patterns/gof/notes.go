@@ -0,0 +1,68 @@
+package gof
+
+import (
+	"iter"
+	"sync"
+)
+
+// Patterns Go Makes Unnecessary
+// =============================
+// Many of the classic patterns work around a missing language feature.
+// Go has most of those features, so the pattern shrinks to an idiom or
+// disappears:
+//
+//	Singleton                a package-level variable, or
+//	                         sync.OnceValue when building it is costly
+//	                         or can fail; see DefaultCheckout
+//	Iterator                 range over a function: iter.Seq; see
+//	                         Cart.Units
+//	Factory Method,          a constructor function, or a func value
+//	Abstract Factory         field when the caller picks the type
+//	Builder                  a struct literal with named fields, or
+//	                         functional options; a generated builder
+//	                         only for wide types (structs/go_builder.go)
+//	Prototype                assigning a struct copies it; deep copies
+//	                         are explicit (structs/clone)
+//	Visitor                  a type switch over the node types
+//	                         (advanced-concepts/go_type_switches.go)
+//	Template Method          a func field or a small interface for the
+//	                         varying step; no base class to override
+//	Chain of Responsibility  middleware: the decorators in decorator.go
+//	Command                  a func value - until it must be undone;
+//	                         then see patterns/undo
+//
+// The patterns that remain - adapter, decorator, strategy, observer -
+// are about how values fit together, and Go's small, implicitly
+// satisfied interfaces make them cheaper than anywhere else.
+//
+// Pitfalls:
+//
+//	an interface per type,       write the interface where it is
+//	declared beside the type     consumed, with only the methods used
+//	a Manager or Factory type    a function does it; a type with no
+//	with one method              state is a function with extra steps
+//	a mutable global singleton   hides a dependency and breaks parallel
+//	                             tests; pass it in where you can
+
+// DefaultCheckout is built on first use, once, however many goroutines
+// ask at the same time
+var DefaultCheckout = sync.OnceValue(func() Checkout {
+	return Checkout{Discount: Best(
+		Capped(Percent(10), 2_000),
+		BuyXGetY("mug", 2, 1),
+	)}
+})
+
+// Units yields the SKU of every unit in the cart: an iterator with no
+// iterator type
+func (c Cart) Units() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, it := range c.Items {
+			for range it.Qty {
+				if !yield(it.SKU) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package gof
+
+import "sync"
+
+// Observer
+// ========
+// A subject tells whoever is listening that something changed, without
+// knowing who they are. The textbook version keeps a list of observer
+// objects and calls each one in turn; in Go the listener usually gets a
+// channel instead:
+//
+//	callbacks   run on the publisher's goroutine: a slow or blocking
+//	            observer stalls every publish, and a panic in one
+//	            takes down the publisher
+//	channels    each subscriber reads at its own pace on its own
+//	            goroutine, with select, timeouts and range
+//
+// Channels move the hard question to one place: what happens when a
+// subscriber stops reading. Blocking lets one stuck reader stop
+// everyone. Feed never blocks: each subscriber has a buffer, and when
+// it is full the oldest pending value is dropped and counted. For a
+// stream of readings, where only recent values matter, that is right;
+// for events that must not be lost, see projects/ledger, where each
+// reader keeps its own position in a log instead.
+//
+// Unsubscribing closes the channel, so a subscriber's range loop ends.
+
+// Feed publishes values of type T to subscribers. It is safe for
+// concurrent use.
+type Feed[T any] struct {
+	mu     sync.Mutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// Subscription is one subscriber's channel
+type Subscription[T any] struct {
+	ch      chan T
+	feed    *Feed[T]
+	dropped int // under feed.mu
+}
+
+// C delivers values in publish order, and is closed by Unsubscribe or
+// the feed's Close
+func (s *Subscription[T]) C() <-chan T { return s.ch }
+
+// Dropped returns how many values were dropped because the buffer was
+// full
+func (s *Subscription[T]) Dropped() int {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.dropped
+}
+
+// Unsubscribe stops delivery and closes C. Calling it again does
+// nothing.
+func (s *Subscription[T]) Unsubscribe() {
+	f := s.feed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subs[s]; ok {
+		delete(f.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscribe returns a subscription buffering up to buf values; buf
+// is at least 1. On a closed feed, C is already closed.
+func (f *Feed[T]) Subscribe(buf int) *Subscription[T] {
+	s := &Subscription[T]{ch: make(chan T, max(buf, 1)), feed: f}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		close(s.ch)
+		return s
+	}
+	if f.subs == nil {
+		f.subs = map[*Subscription[T]]struct{}{}
+	}
+	f.subs[s] = struct{}{}
+	return s
+}
+
+// Publish sends v to every subscriber without blocking, and returns how
+// many there were
+func (f *Feed[T]) Publish(v T) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for s := range f.subs {
+		select {
+		case s.ch <- v:
+			continue
+		default:
+		}
+		// Full: drop the oldest. The subscriber may take it first, so
+		// the receive must not block; either way there is room after,
+		// because only Publish sends and it holds the lock.
+		select {
+		case <-s.ch:
+			s.dropped++
+		default:
+		}
+		s.ch <- v
+	}
+	return len(f.subs)
+}
+
+// Close closes every subscriber's channel; later subscribers get a
+// closed one and Publish reaches no one
+func (f *Feed[T]) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for s := range f.subs {
+		close(s.ch)
+		delete(f.subs, s)
+	}
+}
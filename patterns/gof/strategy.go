@@ -0,0 +1,112 @@
+package gof
+
+import "slices"
+
+// Strategy
+// ========
+// A strategy is an algorithm chosen at run time: the checkout does not
+// know how a discount is worked out, only that something will work it
+// out. In a class-based language that is an interface with one method
+// and a class per algorithm. In Go it is a function type:
+//
+//	type Discount func(Cart) int64
+//
+// A strategy with settings is a function that returns one - Percent(10)
+// - and strategies combine the way functions do: Best tries several
+// and keeps the largest, Capped limits another. The standard library
+// does this throughout: slices.SortFunc takes the comparison,
+// strings.FieldsFunc the separator test, http.Server the handler.
+//
+// Keep an interface instead when the strategy needs several methods
+// that must agree, or carries state a caller wants to inspect.
+
+// Item is a cart line; prices are in cents
+type Item struct {
+	SKU   string
+	Cents int64
+	Qty   int
+}
+
+// Cart is what a Discount prices
+type Cart struct {
+	Items  []Item
+	Coupon string
+}
+
+// Subtotal is the cart's price before discount
+func (c Cart) Subtotal() int64 {
+	var total int64
+	for _, it := range c.Items {
+		total += it.Cents * int64(it.Qty)
+	}
+	return total
+}
+
+// Discount returns how many cents to take off c
+type Discount func(c Cart) int64
+
+// NoDiscount takes nothing off
+func NoDiscount(Cart) int64 { return 0 }
+
+// Percent takes p percent off the subtotal, rounded down
+func Percent(p int64) Discount {
+	return func(c Cart) int64 { return c.Subtotal() * p / 100 }
+}
+
+// BuyXGetY makes every xth unit of sku free, y of them per x+y bought:
+// BuyXGetY("mug", 2, 1) is three for the price of two
+func BuyXGetY(sku string, x, y int) Discount {
+	return func(c Cart) int64 {
+		var off int64
+		for _, it := range c.Items {
+			if it.SKU == sku {
+				free := it.Qty / (x + y) * y
+				off += int64(free) * it.Cents
+			}
+		}
+		return off
+	}
+}
+
+// Coupon applies d only to carts carrying code
+func Coupon(code string, d Discount) Discount {
+	return func(c Cart) int64 {
+		if c.Coupon != code {
+			return 0
+		}
+		return d(c)
+	}
+}
+
+// Best applies whichever of ds takes the most off; discounts do not
+// stack
+func Best(ds ...Discount) Discount {
+	return func(c Cart) int64 {
+		offs := make([]int64, 0, len(ds)+1)
+		offs = append(offs, 0)
+		for _, d := range ds {
+			offs = append(offs, d(c))
+		}
+		return slices.Max(offs)
+	}
+}
+
+// Capped limits d to at most max cents
+func Capped(d Discount, max int64) Discount {
+	return func(c Cart) int64 { return min(d(c), max) }
+}
+
+// Checkout prices carts with a Discount chosen by the caller
+type Checkout struct {
+	Discount Discount // NoDiscount if nil
+}
+
+// Total returns what c costs. A discount never makes it negative.
+func (co Checkout) Total(c Cart) int64 {
+	d := co.Discount
+	if d == nil {
+		d = NoDiscount
+	}
+	sub := c.Subtotal()
+	return sub - min(max(d(c), 0), sub)
+}
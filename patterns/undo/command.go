@@ -0,0 +1,196 @@
+package undo
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The Command Pattern
+// ===================
+// A command is an edit turned into a value: it can be kept in a list,
+// done again, and undone. Each one records, when it runs, exactly
+// what it needs to reverse itself:
+//
+//	Insert      the position and text; undo deletes that many runes
+//	Delete      the position and length, and the text it removed,
+//	            captured by Do - it is not known until then
+//	Func        a closure that returns its own undo closure; whatever
+//	            the undo needs is captured by the first closure's
+//	            variables
+//	Macro       several commands as one; undone in reverse order
+//
+// Two ways to undo, with different costs:
+//
+//	inverse operation   Insert and Delete store only the change, and
+//	                    undo by doing the opposite. Cheap, but each
+//	                    command needs a correct inverse.
+//	snapshot (memento)  ReplaceAll saves the whole text before the
+//	                    change. Always correct, and costs a copy of
+//	                    the document per step.
+//
+// In Go a command needs no class hierarchy. Command is a three-method
+// interface, and Func adapts a pair of closures to it, the way
+// http.HandlerFunc adapts a function to http.Handler.
+//
+// Pitfalls:
+//
+//	capturing state when the      Delete must read what it removes in
+//	command is built              Do; the text may differ by then
+//	undo that depends on the      undo a Replace by replacing back and
+//	current document              a later edit inside it is lost;
+//	                              restore what Do recorded instead
+//	reusing a command value       History keeps it; build a new one
+//	after Execute                 per edit
+
+// Command is a reversible edit. Undo is only called after a successful
+// Do, on the document as Do left it; Do may be called again after Undo
+// to redo.
+type Command interface {
+	Do(d *Document) error
+	Undo(d *Document) error
+	Label() string
+}
+
+// Merger is implemented by commands that can absorb the next one, so
+// that typing a word is one step to undo, not one per key
+type Merger interface {
+	// Merge reports whether next, already done, was absorbed
+	Merge(next Command) bool
+}
+
+type insert struct {
+	pos  int
+	text string
+}
+
+// Insert returns a command that inserts text at pos
+func Insert(pos int, text string) Command {
+	return &insert{pos: pos, text: text}
+}
+
+func (c *insert) Do(d *Document) error { return d.Insert(c.pos, c.text) }
+
+func (c *insert) Undo(d *Document) error {
+	_, err := d.Delete(c.pos, utf8.RuneCountInString(c.text))
+	return err
+}
+
+func (c *insert) Label() string { return "Typing" }
+
+// Merge absorbs an insert that continues this one, up to the end of a
+// word: "hello world" typed key by key undoes as "world", then
+// "hello "
+func (c *insert) Merge(next Command) bool {
+	n, ok := next.(*insert)
+	if !ok || n.pos != c.pos+utf8.RuneCountInString(c.text) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(c.text)
+	first, _ := utf8.DecodeRuneInString(n.text)
+	if unicode.IsSpace(last) && !unicode.IsSpace(first) {
+		return false
+	}
+	c.text += n.text
+	return true
+}
+
+type deletion struct {
+	pos, n  int
+	removed string // set by Do
+}
+
+// Delete returns a command that deletes n runes at pos
+func Delete(pos, n int) Command {
+	return &deletion{pos: pos, n: n}
+}
+
+func (c *deletion) Do(d *Document) error {
+	removed, err := d.Delete(c.pos, c.n)
+	if err != nil {
+		return err
+	}
+	c.removed = removed
+	return nil
+}
+
+func (c *deletion) Undo(d *Document) error { return d.Insert(c.pos, c.removed) }
+
+func (c *deletion) Label() string { return "Delete" }
+
+type funcCommand struct {
+	label string
+	do    func(d *Document) (undo func(d *Document) error, err error)
+	undo  func(d *Document) error
+}
+
+// Func returns a command from a closure. do makes the change and
+// returns a closure that reverses it; each redo calls do again and
+// gets a fresh one.
+func Func(label string, do func(d *Document) (undo func(d *Document) error, err error)) Command {
+	return &funcCommand{label: label, do: do}
+}
+
+func (c *funcCommand) Do(d *Document) error {
+	undo, err := c.do(d)
+	if err != nil {
+		return err
+	}
+	c.undo = undo
+	return nil
+}
+
+func (c *funcCommand) Undo(d *Document) error { return c.undo(d) }
+
+func (c *funcCommand) Label() string { return c.label }
+
+// ReplaceAll returns a command that replaces every old with new. It
+// undoes from a snapshot; working out the inverse of a replace is
+// harder than it looks when new contains old.
+func ReplaceAll(old, new string) Command {
+	return Func("Replace", func(d *Document) (func(*Document) error, error) {
+		before := d.String()
+		d.Set(strings.ReplaceAll(before, old, new))
+		return func(d *Document) error {
+			d.Set(before)
+			return nil
+		}, nil
+	})
+}
+
+type macro struct {
+	label string
+	cmds  []Command
+}
+
+// Macro returns a command that does cmds in order as one step. If one
+// fails, those already done are undone, so the document is unchanged.
+func Macro(label string, cmds ...Command) Command {
+	return &macro{label: label, cmds: cmds}
+}
+
+func (m *macro) Do(d *Document) error {
+	for i, c := range m.cmds {
+		if err := c.Do(d); err != nil {
+			if uerr := undoAll(d, m.cmds[:i]); uerr != nil {
+				return uerr
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *macro) Undo(d *Document) error { return undoAll(d, m.cmds) }
+
+func (m *macro) Label() string { return m.label }
+
+// undoAll undoes cmds last first
+func undoAll(d *Document, cmds []Command) error {
+	for i := len(cmds) - 1; i >= 0; i-- {
+		if err := cmds[i].Undo(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
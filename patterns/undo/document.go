@@ -0,0 +1,60 @@
+package undo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// The Document
+// ============
+// The model the commands edit: a string of runes with insert and
+// delete at a position. Positions count runes, not bytes, so "é" is
+// one place for the cursor and a delete never splits a character.
+//
+// The Document knows nothing of undo. Everything in history.go works
+// through its public methods, which is the point of the pattern: the
+// receiver stays simple, and the commands carry what is needed to
+// reverse themselves.
+
+// ErrRange is returned for a position or length outside the document
+var ErrRange = errors.New("undo: position out of range")
+
+// Document is editable text. It is not safe for concurrent use.
+type Document struct {
+	text []rune
+}
+
+// NewDocument returns a document holding text
+func NewDocument(text string) *Document {
+	return &Document{text: []rune(text)}
+}
+
+func (d *Document) String() string { return string(d.text) }
+
+// Len returns the length in runes
+func (d *Document) Len() int { return len(d.text) }
+
+// Insert puts s before the rune at pos; pos == Len appends
+func (d *Document) Insert(pos int, s string) error {
+	if pos < 0 || pos > len(d.text) {
+		return fmt.Errorf("%w: insert at %d of %d", ErrRange, pos, len(d.text))
+	}
+	d.text = slices.Insert(d.text, pos, []rune(s)...)
+	return nil
+}
+
+// Delete removes n runes from pos and returns them
+func (d *Document) Delete(pos, n int) (string, error) {
+	if pos < 0 || n < 0 || pos+n > len(d.text) {
+		return "", fmt.Errorf("%w: delete %d at %d of %d", ErrRange, n, pos, len(d.text))
+	}
+	removed := string(d.text[pos : pos+n])
+	d.text = slices.Delete(d.text, pos, pos+n)
+	return removed, nil
+}
+
+// Set replaces the whole text
+func (d *Document) Set(text string) {
+	d.text = []rune(text)
+}
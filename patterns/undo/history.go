@@ -0,0 +1,131 @@
+package undo
+
+import "errors"
+
+// Undo and Redo Stacks
+// ====================
+// History runs commands against a document and keeps two stacks:
+//
+//	done     commands applied, newest last; Undo pops from here
+//	undone   commands undone, newest last; Redo pops from here
+//
+// Undo moves a command from done to undone, Redo moves it back. A new
+// command clears undone: after undoing three steps and typing, the
+// three steps are on a branch no key can reach, as in every editor
+// without a history tree.
+//
+// The saved marker is a depth in done. The document is clean when the
+// depth matches, so undoing back to the saved state makes it clean
+// again. If the saved state is cleared off the redo stack or dropped
+// by the limit, no sequence of undo and redo reaches it, and the
+// document stays dirty until the next save.
+//
+// A failed Do records nothing, and a failed Undo or Redo leaves the
+// command where it was.
+
+var (
+	ErrNothingToUndo = errors.New("undo: nothing to undo")
+	ErrNothingToRedo = errors.New("undo: nothing to redo")
+)
+
+// History is the undo and redo stacks for one document. It is not safe
+// for concurrent use.
+type History struct {
+	doc    *Document
+	limit  int
+	done   []Command
+	undone []Command
+	saved  int // len(done) when last saved; -1 once unreachable
+}
+
+// NewHistory returns an empty history for doc that keeps at most limit
+// steps, or every step if limit is 0. doc counts as saved.
+func NewHistory(doc *Document, limit int) *History {
+	return &History{doc: doc, limit: limit}
+}
+
+// Execute does c and records it
+func (h *History) Execute(c Command) error {
+	if err := c.Do(h.doc); err != nil {
+		return err
+	}
+	if h.saved > len(h.done) {
+		h.saved = -1 // the saved state was on the redo branch
+	}
+	h.undone = nil
+
+	// Merge into the last step, unless that would carry it past the
+	// saved state
+	if n := len(h.done); n > 0 && n != h.saved {
+		if m, ok := h.done[n-1].(Merger); ok && m.Merge(c) {
+			return nil
+		}
+	}
+	h.done = append(h.done, c)
+	if h.limit > 0 && len(h.done) > h.limit {
+		h.done[0] = nil
+		h.done = h.done[1:]
+		if h.saved >= 0 {
+			h.saved--
+		}
+	}
+	return nil
+}
+
+// Undo reverses the last step
+func (h *History) Undo() error {
+	n := len(h.done)
+	if n == 0 {
+		return ErrNothingToUndo
+	}
+	c := h.done[n-1]
+	if err := c.Undo(h.doc); err != nil {
+		return err
+	}
+	h.done = h.done[:n-1]
+	h.undone = append(h.undone, c)
+	return nil
+}
+
+// Redo does the last undone step again
+func (h *History) Redo() error {
+	n := len(h.undone)
+	if n == 0 {
+		return ErrNothingToRedo
+	}
+	c := h.undone[n-1]
+	if err := c.Do(h.doc); err != nil {
+		return err
+	}
+	h.undone = h.undone[:n-1]
+	h.done = append(h.done, c)
+	return nil
+}
+
+// CanUndo reports whether there is a step to undo
+func (h *History) CanUndo() bool { return len(h.done) > 0 }
+
+// CanRedo reports whether there is a step to redo
+func (h *History) CanRedo() bool { return len(h.undone) > 0 }
+
+// UndoLabel names the step Undo would reverse, for a menu item
+func (h *History) UndoLabel() (string, bool) {
+	if len(h.done) == 0 {
+		return "", false
+	}
+	return h.done[len(h.done)-1].Label(), true
+}
+
+// RedoLabel names the step Redo would do
+func (h *History) RedoLabel() (string, bool) {
+	if len(h.undone) == 0 {
+		return "", false
+	}
+	return h.undone[len(h.undone)-1].Label(), true
+}
+
+// MarkSaved records the current state as saved
+func (h *History) MarkSaved() { h.saved = len(h.done) }
+
+// Dirty reports whether the document differs from the saved state
+func (h *History) Dirty() bool { return h.saved != len(h.done) }
@@ -0,0 +1,389 @@
+package undo
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// Undo and Redo - Tests
+// =====================
+// Run with:
+//
+//   cd patterns/undo
+//   go test -v *.go
+//
+// The history tests check text and labels after each step. The random
+// test applies a few hundred edits, remembering the text after each,
+// then undoes back to the start and redoes to the end, comparing every
+// state on the way.
+
+func execute(t *testing.T, h *History, cmds ...Command) {
+	t.Helper()
+	for _, c := range cmds {
+		if err := h.Execute(c); err != nil {
+			t.Fatalf("Execute(%s): %v", c.Label(), err)
+		}
+	}
+}
+
+func typeText(t *testing.T, h *History, pos int, s string) {
+	t.Helper()
+	for i, r := range []rune(s) {
+		execute(t, h, Insert(pos+i, string(r)))
+	}
+}
+
+func check(t *testing.T, d *Document, want string) {
+	t.Helper()
+	if got := d.String(); got != want {
+		t.Fatalf("document = %q, want %q", got, want)
+	}
+}
+
+// 1. The Document
+// ===============
+
+func TestDocumentRunePositions(t *testing.T) {
+	d := NewDocument("café")
+	if d.Len() != 4 {
+		t.Errorf("Len = %d, want 4 runes", d.Len())
+	}
+	if err := d.Insert(4, " au lait"); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := d.Delete(3, 1)
+	if err != nil || removed != "é" {
+		t.Errorf("Delete(3, 1) = %q, %v; want \"é\"", removed, err)
+	}
+	check(t, d, "caf au lait")
+
+	for _, bad := range []func() error{
+		func() error { return d.Insert(-1, "x") },
+		func() error { return d.Insert(d.Len()+1, "x") },
+		func() error { _, err := d.Delete(10, 2); return err },
+		func() error { _, err := d.Delete(0, -1); return err },
+	} {
+		if err := bad(); !errors.Is(err, ErrRange) {
+			t.Errorf("err = %v, want ErrRange", err)
+		}
+	}
+}
+
+// 2. History Semantics
+// ====================
+
+func TestUndoRedo(t *testing.T) {
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	// Inserts at the start, so none of them merge
+	execute(t, h, Insert(0, "world"), Insert(0, "hello, "), Delete(0, 1), Insert(0, "H"))
+	check(t, d, "Hello, world")
+
+	for _, want := range []string{"ello, world", "hello, world", "world", ""} {
+		if err := h.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		check(t, d, want)
+	}
+	if err := h.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo on empty history: %v", err)
+	}
+	for _, want := range []string{"world", "hello, world", "ello, world", "Hello, world"} {
+		if err := h.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		check(t, d, want)
+	}
+	if err := h.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Redo at the end: %v", err)
+	}
+}
+
+func TestNewCommandClearsRedo(t *testing.T) {
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	execute(t, h, Insert(0, "c"), Insert(0, "b"), Insert(0, "a"))
+	h.Undo()
+	h.Undo()
+	if !h.CanRedo() {
+		t.Fatal("nothing to redo after two undos")
+	}
+	execute(t, h, Insert(1, "!"))
+	if h.CanRedo() {
+		t.Error("redo survived a new command")
+	}
+	check(t, d, "c!")
+}
+
+func TestFailedDoRecordsNothing(t *testing.T) {
+	d := NewDocument("abc")
+	h := NewHistory(d, 0)
+	if err := h.Execute(Delete(2, 5)); !errors.Is(err, ErrRange) {
+		t.Fatalf("err = %v, want ErrRange", err)
+	}
+	if h.CanUndo() {
+		t.Error("a failed command was recorded")
+	}
+	check(t, d, "abc")
+}
+
+func TestLabels(t *testing.T) {
+	h := NewHistory(NewDocument("a-b"), 0)
+	if _, ok := h.UndoLabel(); ok {
+		t.Error("UndoLabel on empty history")
+	}
+	execute(t, h, ReplaceAll("-", "+"), Delete(0, 1))
+	if l, _ := h.UndoLabel(); l != "Delete" {
+		t.Errorf("UndoLabel = %q, want Delete", l)
+	}
+	h.Undo()
+	if l, _ := h.RedoLabel(); l != "Delete" {
+		t.Errorf("RedoLabel = %q, want Delete", l)
+	}
+	if l, _ := h.UndoLabel(); l != "Replace" {
+		t.Errorf("UndoLabel = %q, want Replace", l)
+	}
+}
+
+// 3. Merging Keystrokes
+// =====================
+
+func TestTypingMergesByWord(t *testing.T) {
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	typeText(t, h, 0, "hello world")
+
+	h.Undo()
+	check(t, d, "hello ")
+	h.Undo()
+	check(t, d, "")
+	h.Redo()
+	h.Redo()
+	check(t, d, "hello world")
+}
+
+func TestTypingElsewhereStartsNewStep(t *testing.T) {
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	typeText(t, h, 0, "ac")
+	execute(t, h, Insert(1, "b")) // moved the cursor back
+	check(t, d, "abc")
+	h.Undo()
+	check(t, d, "ac")
+}
+
+func TestNoMergeAcrossSave(t *testing.T) {
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	typeText(t, h, 0, "ab")
+	h.MarkSaved()
+	typeText(t, h, 2, "cd")
+	h.Undo()
+	check(t, d, "ab")
+	if h.Dirty() {
+		t.Error("dirty at the saved state")
+	}
+}
+
+// 4. Closures, Snapshots and Macros
+// =================================
+
+func TestFuncCommand(t *testing.T) {
+	d := NewDocument("hello")
+	h := NewHistory(d, 0)
+	upper := Func("Uppercase", func(d *Document) (func(*Document) error, error) {
+		before := d.String()
+		d.Set(strings.ToUpper(before))
+		return func(d *Document) error { d.Set(before); return nil }, nil
+	})
+	execute(t, h, upper, Insert(5, "!"))
+	check(t, d, "HELLO!")
+	h.Undo()
+	h.Undo()
+	check(t, d, "hello")
+	h.Redo()
+	check(t, d, "HELLO")
+}
+
+func TestReplaceAllContainingOld(t *testing.T) {
+	// The inverse of "a" -> "aa" is not "aa" -> "a" when the text
+	// already had "aa"; the snapshot does not care
+	d := NewDocument("a aa")
+	h := NewHistory(d, 0)
+	execute(t, h, ReplaceAll("a", "aa"))
+	check(t, d, "aa aaaa")
+	h.Undo()
+	check(t, d, "a aa")
+}
+
+func TestMacroIsOneStep(t *testing.T) {
+	d := NewDocument("name: ada")
+	h := NewHistory(d, 0)
+	execute(t, h, Macro("Capitalize", Delete(6, 1), Insert(6, "A")))
+	check(t, d, "name: Ada")
+	if l, _ := h.UndoLabel(); l != "Capitalize" {
+		t.Errorf("UndoLabel = %q", l)
+	}
+	h.Undo()
+	check(t, d, "name: ada")
+	if h.CanUndo() {
+		t.Error("the macro took more than one step")
+	}
+}
+
+func TestMacroFailureLeavesNoTrace(t *testing.T) {
+	d := NewDocument("abc")
+	h := NewHistory(d, 0)
+	err := h.Execute(Macro("Broken", Insert(0, "x"), Delete(1, 1), Delete(99, 1)))
+	if !errors.Is(err, ErrRange) {
+		t.Fatalf("err = %v, want ErrRange", err)
+	}
+	check(t, d, "abc")
+	if h.CanUndo() {
+		t.Error("a failed macro was recorded")
+	}
+}
+
+// 5. Limits and the Saved State
+// =============================
+
+func TestLimitDropsOldest(t *testing.T) {
+	d := NewDocument("")
+	h := NewHistory(d, 3)
+	for i, s := range []string{"a", "b", "c", "d", "e"} {
+		execute(t, h, Insert(2*i, s+" ")) // spaces: no merging
+	}
+	undos := 0
+	for h.Undo() == nil {
+		undos++
+	}
+	if undos != 3 {
+		t.Errorf("undid %d steps, want 3", undos)
+	}
+	check(t, d, "a b ")
+}
+
+func TestDirty(t *testing.T) {
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	if h.Dirty() {
+		t.Error("new document is dirty")
+	}
+	execute(t, h, Insert(0, "a "), Insert(2, "b "))
+	h.MarkSaved()
+	execute(t, h, Insert(4, "c "))
+	if !h.Dirty() {
+		t.Error("clean after an edit")
+	}
+	h.Undo()
+	if h.Dirty() {
+		t.Error("dirty after undoing back to the save")
+	}
+	h.Undo()
+	if !h.Dirty() {
+		t.Error("clean after undoing past the save")
+	}
+	h.Redo()
+	if h.Dirty() {
+		t.Error("dirty after redoing to the save")
+	}
+
+	// Undo past the save and take another branch: the saved state is
+	// gone, even at the same depth
+	h.Undo()
+	execute(t, h, Insert(2, "x "))
+	if !h.Dirty() {
+		t.Error("clean on a different branch at the saved depth")
+	}
+}
+
+func TestDirtyWhenSaveFallsOffLimit(t *testing.T) {
+	h := NewHistory(NewDocument(""), 2)
+	h.MarkSaved()
+	execute(t, h, Insert(0, "a "), Insert(2, "b "), Insert(4, "c "))
+	for h.Undo() == nil {
+	}
+	if !h.Dirty() {
+		t.Error("clean, though the saved state is no longer reachable")
+	}
+}
+
+// 6. Random Edits
+// ===============
+
+func TestRandomEditsUndoAndRedo(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	states := []string{""}
+	for range 300 {
+		var c Command
+		switch n := d.Len(); {
+		case n > 0 && r.IntN(3) == 0:
+			pos := r.IntN(n)
+			c = Delete(pos, 1+r.IntN(n-pos))
+		case r.IntN(10) == 0:
+			c = ReplaceAll(string(rune('a'+r.IntN(3))), "é")
+		default:
+			c = Insert(r.IntN(n+1), string(rune('a'+r.IntN(3)))+" ")
+		}
+		execute(t, h, c)
+		states = append(states, d.String())
+	}
+
+	for i := len(states) - 2; i >= 0; i-- {
+		if err := h.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		check(t, d, states[i])
+	}
+	for i := 1; i < len(states); i++ {
+		if err := h.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		check(t, d, states[i])
+	}
+}
+
+// 7. Benchmarks
+// =============
+
+func BenchmarkTyping(b *testing.B) {
+	for b.Loop() {
+		d := NewDocument("")
+		h := NewHistory(d, 100)
+		for i, r := range "the quick brown fox jumps over the lazy dog" {
+			h.Execute(Insert(i, string(r)))
+		}
+	}
+}
+
+// 8. Examples
+// ===========
+
+func ExampleHistory() {
+	d := NewDocument("")
+	h := NewHistory(d, 0)
+	for i, r := range "Hi there" {
+		h.Execute(Insert(i, string(r)))
+	}
+	h.Execute(Macro("Exclaim", Delete(2, 6), Insert(2, "!")))
+	fmt.Println(d)
+
+	for h.CanUndo() {
+		label, _ := h.UndoLabel()
+		h.Undo()
+		fmt.Printf("undo %s: %q\n", label, d)
+	}
+	h.Redo()
+	fmt.Printf("redo: %q\n", d)
+	// Output:
+	// Hi!
+	// undo Exclaim: "Hi there"
+	// undo Typing: "Hi "
+	// undo Typing: ""
+	// redo: "Hi "
+}
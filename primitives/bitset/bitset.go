@@ -0,0 +1,116 @@
+package bitset
+
+import (
+	"iter"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Bitset - A Set of Small Integers in Machine Words
+// =================================================
+// A Bitset stores element i as bit i%64 of word i/64. Membership is a
+// shift and a mask, set operations work 64 elements at a time, and a
+// million-element set takes 125 KB instead of the tens of megabytes a
+// map[int]bool would.
+
+const wordSize = 64
+
+// Bitset is a growable set of non-negative integers. The zero value is
+// an empty set ready to use.
+type Bitset struct {
+	words []uint64
+}
+
+// New returns a set with room for elements 0..n-1 without reallocating
+func New(n int) *Bitset {
+	return &Bitset{words: make([]uint64, (n+wordSize-1)/wordSize)}
+}
+
+// Add inserts i, growing the set if needed. It panics if i is negative.
+func (s *Bitset) Add(i int) {
+	w := i / wordSize
+	if w >= len(s.words) {
+		s.words = append(s.words, make([]uint64, w+1-len(s.words))...)
+	}
+	s.words[w] |= 1 << (uint(i) % wordSize)
+}
+
+// Remove deletes i if present
+func (s *Bitset) Remove(i int) {
+	if w := i / wordSize; i >= 0 && w < len(s.words) {
+		s.words[w] &^= 1 << (uint(i) % wordSize)
+	}
+}
+
+// Has reports whether i is in the set
+func (s *Bitset) Has(i int) bool {
+	w := i / wordSize
+	return i >= 0 && w < len(s.words) && s.words[w]&(1<<(uint(i)%wordSize)) != 0
+}
+
+// Len returns the number of elements, using one popcount per word
+func (s *Bitset) Len() int {
+	n := 0
+	for _, w := range s.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Union adds every element of other to s
+func (s *Bitset) Union(other *Bitset) {
+	if len(other.words) > len(s.words) {
+		s.words = append(s.words, make([]uint64, len(other.words)-len(s.words))...)
+	}
+	for i, w := range other.words {
+		s.words[i] |= w
+	}
+}
+
+// Intersect removes elements of s that are not in other
+func (s *Bitset) Intersect(other *Bitset) {
+	for i := range s.words {
+		if i < len(other.words) {
+			s.words[i] &= other.words[i]
+		} else {
+			s.words[i] = 0
+		}
+	}
+}
+
+// Difference removes the elements of other from s
+func (s *Bitset) Difference(other *Bitset) {
+	for i := range min(len(s.words), len(other.words)) {
+		s.words[i] &^= other.words[i]
+	}
+}
+
+// All yields the elements in increasing order. TrailingZeros finds the
+// next set bit directly, so empty stretches cost one step per word.
+func (s *Bitset) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i, w := range s.words {
+			for w != 0 {
+				if !yield(i*wordSize + bits.TrailingZeros64(w)) {
+					return
+				}
+				w &= w - 1 // clear the bit just yielded
+			}
+		}
+	}
+}
+
+// String formats the set like {1 5 64}
+func (s *Bitset) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := range s.All() {
+		if b.Len() > 1 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Itoa(i))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
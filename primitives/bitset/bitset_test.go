@@ -0,0 +1,338 @@
+package bitset
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// Bit Manipulation - math/bits, Tricks and a Bitset
+// =================================================
+// Run with:
+//
+//   cd primitives/bitset
+//   go test -v *.go
+//   go test -bench . -benchmem *.go
+
+// 1. math/bits
+// ============
+
+func TestMathBits(t *testing.T) {
+	x := uint64(0b1011_0000)
+
+	if got := bits.OnesCount64(x); got != 3 {
+		t.Errorf("OnesCount64 = %d, want 3", got)
+	}
+	if got := bits.TrailingZeros64(x); got != 4 {
+		t.Errorf("TrailingZeros64 = %d, want 4", got)
+	}
+	if got := bits.LeadingZeros64(x); got != 56 {
+		t.Errorf("LeadingZeros64 = %d, want 56", got)
+	}
+	if got := bits.Len64(x); got != 8 {
+		t.Errorf("Len64 = %d, want 8 (bits needed to write x)", got)
+	}
+
+	// Zero has no set bit: both counts return the full width
+	if bits.TrailingZeros64(0) != 64 || bits.LeadingZeros64(0) != 64 {
+		t.Error("counts of zero should be 64")
+	}
+
+	// Rotation moves bits that fall off one end back in at the other;
+	// a negative count rotates right
+	if got := bits.RotateLeft8(0b1000_0001, 1); got != 0b0000_0011 {
+		t.Errorf("RotateLeft8 = %08b", got)
+	}
+	if got := bits.RotateLeft8(0b1000_0001, -1); got != 0b1100_0000 {
+		t.Errorf("RotateLeft8(-1) = %08b", got)
+	}
+
+	// A shift drops them instead
+	var b uint8 = 0b1000_0001
+	if got := b << 1; got != 0b0000_0010 {
+		t.Errorf("<< 1 = %08b", got)
+	}
+
+	if got := bits.Reverse8(0b0000_0110); got != 0b0110_0000 {
+		t.Errorf("Reverse8 = %08b", got)
+	}
+	if got := bits.ReverseBytes32(0x11223344); got != 0x44332211 {
+		t.Errorf("ReverseBytes32 = %#x (byte swap for endianness)", got)
+	}
+}
+
+func TestPopCountAgrees(t *testing.T) {
+	inputs := []uint64{0, 1, 0xFF, 1 << 63, math.MaxUint64}
+	for range 1000 {
+		inputs = append(inputs, rand.Uint64())
+	}
+	for _, x := range inputs {
+		want := bits.OnesCount64(x)
+		if PopCountLoop(x) != want || PopCountKernighan(x) != want {
+			t.Fatalf("popcount(%#x): loop %d, kernighan %d, want %d", x, PopCountLoop(x), PopCountKernighan(x), want)
+		}
+	}
+}
+
+// 2. Power-of-Two Tricks
+// ======================
+
+func TestPowerOfTwo(t *testing.T) {
+	tests := []struct {
+		x      uint64
+		isPow2 bool
+		next   uint64
+		log2   int
+	}{
+		{0, false, 1, -1},
+		{1, true, 1, 0},
+		{2, true, 2, 1},
+		{3, false, 4, 1},
+		{1000, false, 1024, 9},
+		{1 << 40, true, 1 << 40, 40},
+		{1<<63 + 1, false, 0, 63}, // next power would need 65 bits
+	}
+	for _, tt := range tests {
+		if got := IsPowerOfTwo(tt.x); got != tt.isPow2 {
+			t.Errorf("IsPowerOfTwo(%d) = %t", tt.x, got)
+		}
+		if got := NextPowerOfTwo(tt.x); got != tt.next {
+			t.Errorf("NextPowerOfTwo(%d) = %d, want %d", tt.x, got, tt.next)
+		}
+		if got := Log2(tt.x); got != tt.log2 {
+			t.Errorf("Log2(%d) = %d, want %d", tt.x, got, tt.log2)
+		}
+	}
+}
+
+func TestAlignUp(t *testing.T) {
+	tests := []struct{ x, align, want uint64 }{
+		{0, 8, 0},
+		{1, 8, 8},
+		{8, 8, 8},
+		{13, 4, 16},
+		{4097, 4096, 8192},
+	}
+	for _, tt := range tests {
+		if got := AlignUp(tt.x, tt.align); got != tt.want {
+			t.Errorf("AlignUp(%d, %d) = %d, want %d", tt.x, tt.align, got, tt.want)
+		}
+	}
+}
+
+func TestLowestSetBit(t *testing.T) {
+	x := uint64(0b1011_0100)
+	if got := LowestSetBit(x); got != 0b100 {
+		t.Errorf("LowestSetBit = %b", got)
+	}
+	// x & (x-1) clears it instead, as PopCountKernighan does
+	if got := x & (x - 1); got != 0b1011_0000 {
+		t.Errorf("x & (x-1) = %b", got)
+	}
+	// Flags: set with |, clear with &^ (AND NOT), test with &
+	const (
+		read uint8 = 1 << iota
+		write
+		exec
+	)
+	perm := read | exec
+	perm &^= exec
+	if perm&write != 0 || perm != read {
+		t.Errorf("flags = %03b", perm)
+	}
+}
+
+// 3. Bitset
+// =========
+
+func TestBitset(t *testing.T) {
+	var s Bitset // zero value is usable
+	for _, i := range []int{3, 64, 1, 200, 64} {
+		s.Add(i)
+	}
+	if s.Len() != 4 {
+		t.Errorf("Len = %d, want 4", s.Len())
+	}
+	if !s.Has(64) || s.Has(63) || s.Has(-1) || s.Has(10_000) {
+		t.Error("Has gave a wrong answer")
+	}
+	if got := s.String(); got != "{1 3 64 200}" {
+		t.Errorf("String = %s", got)
+	}
+
+	s.Remove(3)
+	s.Remove(10_000) // out of range is a no-op
+	if got := slices.Collect(s.All()); !slices.Equal(got, []int{1, 64, 200}) {
+		t.Errorf("All = %v", got)
+	}
+}
+
+func TestBitsetSetOperations(t *testing.T) {
+	build := func(xs ...int) *Bitset {
+		s := New(0)
+		for _, x := range xs {
+			s.Add(x)
+		}
+		return s
+	}
+
+	u := build(1, 2, 3)
+	u.Union(build(3, 100))
+	if got := u.String(); got != "{1 2 3 100}" {
+		t.Errorf("Union = %s", got)
+	}
+
+	i := build(1, 2, 3, 100)
+	i.Intersect(build(2, 3))
+	if got := i.String(); got != "{2 3}" {
+		t.Errorf("Intersect = %s", got)
+	}
+
+	d := build(1, 2, 3, 100)
+	d.Difference(build(2, 100, 500))
+	if got := d.String(); got != "{1 3}" {
+		t.Errorf("Difference = %s", got)
+	}
+}
+
+// The Bitset must agree with a map-based set for any sequence of
+// operations
+func TestBitsetMatchesMap(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	var s Bitset
+	model := map[int]bool{}
+
+	for range 10_000 {
+		x := r.IntN(1000)
+		if r.IntN(3) == 0 {
+			s.Remove(x)
+			delete(model, x)
+		} else {
+			s.Add(x)
+			model[x] = true
+		}
+	}
+
+	if s.Len() != len(model) {
+		t.Fatalf("Len = %d, model has %d", s.Len(), len(model))
+	}
+	for x := range 1000 {
+		if s.Has(x) != model[x] {
+			t.Fatalf("Has(%d) = %t, model %t", x, s.Has(x), model[x])
+		}
+	}
+	if got := slices.Collect(s.All()); !slices.IsSorted(got) || len(got) != len(model) {
+		t.Errorf("All returned %d elements, sorted=%t", len(got), slices.IsSorted(got))
+	}
+}
+
+func TestBitsetEarlyBreak(t *testing.T) {
+	s := New(256)
+	for i := range 256 {
+		s.Add(i)
+	}
+	n := 0
+	for range s.All() {
+		n++
+		if n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Errorf("iterated %d times", n)
+	}
+}
+
+// 4. Benchmarks
+// =============
+
+var intSink int
+
+func BenchmarkPopCount(b *testing.B) {
+	xs := make([]uint64, 1024)
+	for i := range xs {
+		xs[i] = rand.Uint64()
+	}
+	b.Run("loop", func(b *testing.B) {
+		for b.Loop() {
+			for _, x := range xs {
+				intSink += PopCountLoop(x)
+			}
+		}
+	})
+	b.Run("kernighan", func(b *testing.B) {
+		for b.Loop() {
+			for _, x := range xs {
+				intSink += PopCountKernighan(x)
+			}
+		}
+	})
+	b.Run("bits.OnesCount64", func(b *testing.B) {
+		for b.Loop() {
+			for _, x := range xs {
+				intSink += bits.OnesCount64(x)
+			}
+		}
+	})
+}
+
+func BenchmarkMembership(b *testing.B) {
+	const n = 1 << 16
+	s := New(n)
+	m := make(map[int]bool, n/2)
+	for i := 0; i < n; i += 2 {
+		s.Add(i)
+		m[i] = true
+	}
+	b.Run("Bitset", func(b *testing.B) {
+		for b.Loop() {
+			for i := range n {
+				if s.Has(i) {
+					intSink++
+				}
+			}
+		}
+	})
+	b.Run("map[int]bool", func(b *testing.B) {
+		for b.Loop() {
+			for i := range n {
+				if m[i] {
+					intSink++
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkIterateSparse(b *testing.B) {
+	// 100 elements spread over a million: All skips empty words
+	s := New(1 << 20)
+	for i := range 100 {
+		s.Add(i * 10_000)
+	}
+	for b.Loop() {
+		for x := range s.All() {
+			intSink += x
+		}
+	}
+}
+
+// 5. Examples
+// ===========
+
+func ExampleBitset() {
+	var primes Bitset
+	for _, p := range []int{2, 3, 5, 7, 11, 13} {
+		primes.Add(p)
+	}
+	odd := New(16)
+	for i := 1; i < 16; i += 2 {
+		odd.Add(i)
+	}
+	primes.Intersect(odd)
+	fmt.Println(primes.String(), primes.Len())
+	// Output: {3 5 7 11 13} 5
+}
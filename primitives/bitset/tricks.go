@@ -0,0 +1,69 @@
+package bitset
+
+import "math/bits"
+
+// Bit Tricks - math/bits and Friends
+// ==================================
+// math/bits provides popcount, leading and trailing zero counts,
+// rotations and byte reversal. The compiler turns most of them into a
+// single CPU instruction (POPCNT, LZCNT, TZCNT, ROL) where available,
+// so they beat hand-written loops. The helpers below build the common
+// power-of-two and lowest-bit tricks on top of them.
+
+// PopCountLoop counts set bits one at a time. It is here for comparison
+// with bits.OnesCount64 in the benchmarks.
+func PopCountLoop(x uint64) int {
+	n := 0
+	for x != 0 {
+		n += int(x & 1)
+		x >>= 1
+	}
+	return n
+}
+
+// PopCountKernighan clears the lowest set bit until none remain, so it
+// loops once per set bit instead of once per bit
+func PopCountKernighan(x uint64) int {
+	n := 0
+	for x != 0 {
+		x &= x - 1
+		n++
+	}
+	return n
+}
+
+// IsPowerOfTwo reports whether x has exactly one bit set. x-1 flips the
+// lowest set bit and everything below it, so x&(x-1) is 0 only then.
+func IsPowerOfTwo(x uint64) bool {
+	return x != 0 && x&(x-1) == 0
+}
+
+// NextPowerOfTwo returns the smallest power of two >= x. It returns 1
+// for 0 and 0 if the result would not fit in 64 bits.
+func NextPowerOfTwo(x uint64) uint64 {
+	if x <= 1 {
+		return 1
+	}
+	// bits.Len is the position of the highest set bit plus one
+	shift := bits.Len64(x - 1)
+	if shift == 64 {
+		return 0
+	}
+	return 1 << shift
+}
+
+// AlignUp rounds x up to a multiple of align, which must be a power of
+// two. Allocators and binary formats use this for padding.
+func AlignUp(x, align uint64) uint64 {
+	return (x + align - 1) &^ (align - 1)
+}
+
+// LowestSetBit isolates the lowest set bit: x & -x in two's complement
+func LowestSetBit(x uint64) uint64 {
+	return x & -x
+}
+
+// Log2 returns floor(log2(x)) for x > 0, and -1 for 0
+func Log2(x uint64) int {
+	return bits.Len64(x) - 1
+}
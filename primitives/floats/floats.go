@@ -0,0 +1,168 @@
+package floats
+
+import (
+	"fmt"
+	"math"
+)
+
+// Floats - Inside IEEE-754
+// ========================
+// A float64 is 64 bits: 1 sign bit, 11 exponent bits and 52 mantissa
+// (fraction) bits. A normal value is
+//
+//	(-1)^sign × 1.mantissa × 2^(exponent-1023)
+//
+// float32 has the same shape with 8 exponent bits, 23 mantissa bits and
+// a bias of 127. The all-zeros exponent encodes zero and subnormals; the
+// all-ones exponent encodes Inf (mantissa 0) and NaN (mantissa != 0).
+// Because only sums of powers of two are exact, most decimal fractions
+// (0.1, 0.2, 0.3) are rounded, and comparisons need a tolerance.
+
+// Class is the kind of value a bit pattern encodes
+type Class int
+
+const (
+	Zero Class = iota
+	Subnormal
+	Normal
+	Inf
+	NaN
+)
+
+func (c Class) String() string {
+	switch c {
+	case Zero:
+		return "zero"
+	case Subnormal:
+		return "subnormal"
+	case Normal:
+		return "normal"
+	case Inf:
+		return "inf"
+	case NaN:
+		return "NaN"
+	}
+	return fmt.Sprintf("Class(%d)", int(c))
+}
+
+// Parts is a float split into its three fields. Exponent is unbiased
+// (the power of two), so 1.0 has Exponent 0 and 0.5 has Exponent -1.
+type Parts struct {
+	Sign     uint64
+	Exponent int
+	Mantissa uint64
+	Class    Class
+
+	rawExp, expBits, mantBits int
+}
+
+// Decompose64 splits f into sign, exponent and mantissa
+func Decompose64(f float64) Parts {
+	return decompose(math.Float64bits(f), 11, 52)
+}
+
+// Decompose32 splits f into sign, exponent and mantissa
+func Decompose32(f float32) Parts {
+	return decompose(uint64(math.Float32bits(f)), 8, 23)
+}
+
+func decompose(bits uint64, expBits, mantBits int) Parts {
+	p := Parts{
+		Sign:     bits >> (expBits + mantBits),
+		Mantissa: bits & (1<<mantBits - 1),
+		rawExp:   int(bits>>mantBits) & (1<<expBits - 1),
+		expBits:  expBits,
+		mantBits: mantBits,
+	}
+	bias := 1<<(expBits-1) - 1
+	switch {
+	case p.rawExp == 0 && p.Mantissa == 0:
+		p.Class = Zero
+	case p.rawExp == 0:
+		// Subnormals have no implicit leading 1 and the minimum exponent
+		p.Class, p.Exponent = Subnormal, 1-bias
+	case p.rawExp == 1<<expBits-1 && p.Mantissa == 0:
+		p.Class = Inf
+	case p.rawExp == 1<<expBits-1:
+		p.Class = NaN
+	default:
+		p.Class, p.Exponent = Normal, p.rawExp-bias
+	}
+	return p
+}
+
+// String shows the raw bit fields, e.g. for 0.1:
+//
+//	0 01111111011 1001100110011001100110011001100110011001100110011010
+func (p Parts) String() string {
+	return fmt.Sprintf("%d %0*b %0*b", p.Sign, p.expBits, p.rawExp, p.mantBits, p.Mantissa)
+}
+
+// ULP returns the unit in the last place at x: the gap between |x| and
+// the next float64 away from zero. It doubles at every power of two.
+func ULP(x float64) float64 {
+	x = math.Abs(x)
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return math.NaN()
+	}
+	if x == math.MaxFloat64 {
+		return x - math.Nextafter(x, 0)
+	}
+	return math.Nextafter(x, math.Inf(1)) - x
+}
+
+// ULPDistance returns how many representable float64 values lie between
+// a and b. +0 and -0 are 0 apart. It returns math.MaxUint64 if either is
+// NaN.
+func ULPDistance(a, b float64) uint64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.MaxUint64
+	}
+	ia, ib := ordered(a), ordered(b)
+	if ia > ib {
+		return uint64(ia - ib)
+	}
+	return uint64(ib - ia)
+}
+
+// ordered maps float64 bits onto integers in the same order as the
+// floats: positive floats already sort like their bits, negative floats
+// sort in reverse, so they are flipped below zero
+func ordered(f float64) int64 {
+	bits := int64(math.Float64bits(f))
+	if bits < 0 {
+		return math.MinInt64 - bits
+	}
+	return bits
+}
+
+// AlmostEqual reports whether a and b are within maxULPs representable
+// values of each other. This scales with magnitude, unlike a fixed
+// epsilon. NaN is never equal to anything and Inf only equals itself.
+//
+// Near zero ULPs shrink towards the subnormals, so results that should be
+// 0 but carry rounding error (1e-17) are billions of ULPs away. Combine
+// with an absolute tolerance there, as Close does.
+func AlmostEqual(a, b float64, maxULPs uint64) bool {
+	if a == b {
+		return true // also handles +Inf == +Inf and +0 == -0
+	}
+	if math.IsNaN(a) || math.IsNaN(b) || math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return false
+	}
+	return ULPDistance(a, b) <= maxULPs
+}
+
+// Close reports whether |a-b| <= max(relTol × max(|a|, |b|), absTol), the
+// rule used by Python's math.isclose. relTol handles large values;
+// absTol handles comparisons against zero.
+func Close(a, b, relTol, absTol float64) bool {
+	if a == b {
+		return true
+	}
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return false
+	}
+	diff := math.Abs(a - b)
+	return diff <= relTol*math.Max(math.Abs(a), math.Abs(b)) || diff <= absTol
+}
@@ -0,0 +1,338 @@
+package floats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// IEEE-754 - Bits, Rounding and Comparison
+// ========================================
+// Run with:
+//
+//   cd primitives/floats
+//   go test -v *.go
+
+// 1. Decomposition
+// ================
+
+func TestDecompose64(t *testing.T) {
+	tests := []struct {
+		f        float64
+		sign     uint64
+		exponent int
+		mantissa uint64
+		class    Class
+	}{
+		{1, 0, 0, 0, Normal},
+		{-2, 1, 1, 0, Normal},
+		{0.5, 0, -1, 0, Normal},
+		{1.5, 0, 0, 1 << 51, Normal}, // 1.1 in binary: the top mantissa bit
+		{0.1, 0, -4, 0x999999999999a, Normal},
+		{0, 0, 0, 0, Zero},
+		{math.Copysign(0, -1), 1, 0, 0, Zero},
+		{math.SmallestNonzeroFloat64, 0, -1022, 1, Subnormal},
+		{math.Inf(-1), 1, 0, 0, Inf},
+	}
+
+	for _, tt := range tests {
+		p := Decompose64(tt.f)
+		if p.Sign != tt.sign || p.Exponent != tt.exponent || p.Mantissa != tt.mantissa || p.Class != tt.class {
+			t.Errorf("Decompose64(%v) = sign %d exp %d mant %#x %s; want %d %d %#x %s",
+				tt.f, p.Sign, p.Exponent, p.Mantissa, p.Class, tt.sign, tt.exponent, tt.mantissa, tt.class)
+		}
+	}
+
+	if p := Decompose64(math.NaN()); p.Class != NaN {
+		t.Errorf("Decompose64(NaN).Class = %s", p.Class)
+	}
+}
+
+func TestDecomposeRebuilds(t *testing.T) {
+	// For normal values, the fields multiply back to the original
+	for _, f := range []float64{1, 0.1, -123.456, 1e300, 2.5e-300} {
+		p := Decompose64(f)
+		frac := 1 + float64(p.Mantissa)/(1<<52)
+		got := math.Ldexp(frac, p.Exponent)
+		if p.Sign == 1 {
+			got = -got
+		}
+		if got != f {
+			t.Errorf("rebuilt %v as %v", f, got)
+		}
+	}
+}
+
+func TestDecompose32(t *testing.T) {
+	// float32 has 23 mantissa bits, so 0.1 is rounded much earlier
+	p := Decompose32(0.1)
+	if p.Exponent != -4 || p.Mantissa != 0x4ccccd {
+		t.Errorf("Decompose32(0.1) = exp %d mant %#x", p.Exponent, p.Mantissa)
+	}
+	if got := p.String(); got != "0 01111011 10011001100110011001101" {
+		t.Errorf("Decompose32(0.1).String() = %s", got)
+	}
+	if float64(float32(0.1)) == 0.1 {
+		t.Error("expected float32(0.1) widened to float64 to differ from 0.1")
+	}
+}
+
+// 2. Rounding
+// ===========
+
+func TestPointOnePlusPointTwo(t *testing.T) {
+	a, b := 0.1, 0.2 // variables: constant arithmetic is exact at compile time
+	sum := a + b
+
+	if sum == 0.3 {
+		t.Fatal("expected 0.1 + 0.2 != 0.3 at run time")
+	}
+	if const03 := 0.1 + 0.2; const03 != 0.3 {
+		t.Error("untyped constants are exact, so 0.1 + 0.2 == 0.3 as a constant expression")
+	}
+
+	// The result is exactly one ULP above 0.3
+	if d := ULPDistance(sum, 0.3); d != 1 {
+		t.Errorf("ULPDistance(0.1+0.2, 0.3) = %d, want 1", d)
+	}
+	if got := fmt.Sprint(sum); got != "0.30000000000000004" {
+		t.Errorf("0.1+0.2 prints as %s", got)
+	}
+}
+
+func TestULP(t *testing.T) {
+	tests := []struct {
+		x, want float64
+	}{
+		{1, math.Pow(2, -52)}, // machine epsilon
+		{2, math.Pow(2, -51)}, // doubles at each power of two
+		{1 << 53, 2},          // above 2^53, odd integers cannot be stored
+		{0, math.SmallestNonzeroFloat64},
+	}
+	for _, tt := range tests {
+		if got := ULP(tt.x); got != tt.want {
+			t.Errorf("ULP(%v) = %v, want %v", tt.x, got, tt.want)
+		}
+	}
+
+	if f := float64(1<<53) + 1; f != 1<<53 {
+		t.Errorf("2^53 + 1 = %v; expected it to round back to 2^53", f)
+	}
+}
+
+func TestAccumulatedError(t *testing.T) {
+	// Each addition rounds; errors accumulate with the number of steps
+	sum := 0.0
+	for i := 0; i < 1000; i++ {
+		sum += 0.1
+	}
+	if sum == 100 {
+		t.Fatal("expected rounding error")
+	}
+	if !AlmostEqual(sum, 100, 1<<12) {
+		t.Errorf("sum = %v is %d ULPs from 100", sum, ULPDistance(sum, 100))
+	}
+}
+
+// 3. Subnormals, Inf and NaN
+// ==========================
+
+func TestSubnormals(t *testing.T) {
+	// Below the smallest normal, precision is traded for range:
+	// gradual underflow instead of jumping straight to zero
+	smallestNormal := math.Ldexp(1, -1022)
+	if c := Decompose64(smallestNormal).Class; c != Normal {
+		t.Errorf("2^-1022 class = %s, want normal", c)
+	}
+	half := smallestNormal / 2
+	if c := Decompose64(half).Class; c != Subnormal {
+		t.Errorf("2^-1023 class = %s, want subnormal", c)
+	}
+
+	// Subnormals guarantee x - y == 0 only when x == y
+	x, y := smallestNormal*1.5, smallestNormal
+	if x-y == 0 {
+		t.Error("difference of distinct tiny values underflowed to zero")
+	}
+
+	if got := math.SmallestNonzeroFloat64 / 2; got != 0 {
+		t.Errorf("smallest subnormal / 2 = %v, want 0", got)
+	}
+}
+
+func TestInf(t *testing.T) {
+	inf := math.Inf(1)
+	zero := 0.0 // a variable: the constant expression 1/0 does not compile
+
+	if 1/zero != inf || -1/zero != -inf {
+		t.Error("division by zero should give ±Inf, not panic")
+	}
+	huge := math.MaxFloat64
+	if huge*2 != inf {
+		t.Error("overflow should give +Inf")
+	}
+	if inf+1 != inf || inf*-1 != -inf {
+		t.Error("Inf absorbs finite arithmetic")
+	}
+	if !math.IsNaN(inf - inf) {
+		t.Error("Inf - Inf should be NaN")
+	}
+}
+
+func TestNaN(t *testing.T) {
+	nan := math.NaN()
+
+	// NaN is unequal to everything, including itself
+	if nan == nan {
+		t.Error("NaN == NaN")
+	}
+	if nan < 1 || nan > 1 || nan == 1 {
+		t.Error("every comparison with NaN should be false")
+	}
+
+	// NaN propagates through arithmetic, so one bad input poisons a sum
+	values := []float64{1, 2, nan, 4}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	if !math.IsNaN(sum) {
+		t.Errorf("sum = %v, want NaN", sum)
+	}
+
+	// A NaN map key can be inserted but never found again
+	m := map[float64]int{}
+	m[nan] = 1
+	m[nan] = 2
+	if _, ok := m[nan]; ok || len(m) != 2 {
+		t.Errorf("map with NaN keys: len %d, found %t", len(m), ok)
+	}
+
+	zero := 0.0
+	if !math.IsNaN(zero / zero) {
+		t.Error("0/0 should be NaN")
+	}
+}
+
+func TestSignedZero(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	zero := 0.0
+
+	if negZero != zero {
+		t.Error("-0 == +0 should be true")
+	}
+	if !math.Signbit(negZero) || 1/negZero != math.Inf(-1) {
+		t.Error("-0 keeps its sign: 1/-0 is -Inf")
+	}
+}
+
+// 4. Comparison
+// =============
+
+func TestAlmostEqual(t *testing.T) {
+	pointOne, pointTwo := 0.1, 0.2
+	tests := []struct {
+		name    string
+		a, b    float64
+		maxULPs uint64
+		want    bool
+	}{
+		{"0.1+0.2 vs 0.3", pointOne + pointTwo, 0.3, 4, true},
+		{"next float up", 1, math.Nextafter(1, 2), 1, true},
+		{"scales with magnitude", 1e20, math.Nextafter(1e20, 0), 1, true},
+		{"too far", 1, 1.0001, 4, false},
+		{"signed zeros", 0, math.Copysign(0, -1), 0, true},
+		{"across zero", math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64, 2, true},
+		{"Inf equals itself", math.Inf(1), math.Inf(1), 0, true},
+		{"Inf vs MaxFloat64", math.Inf(1), math.MaxFloat64, 1 << 60, false},
+		{"NaN", math.NaN(), math.NaN(), math.MaxUint64, false},
+		{"near zero fails", 1e-17, 0, 1 << 20, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AlmostEqual(tt.a, tt.b, tt.maxULPs); got != tt.want {
+				t.Errorf("AlmostEqual(%v, %v, %d) = %t (distance %d)", tt.a, tt.b, tt.maxULPs, got, ULPDistance(tt.a, tt.b))
+			}
+		})
+	}
+}
+
+func TestFixedEpsilonFails(t *testing.T) {
+	// A fixed epsilon is too strict for large values and too loose for
+	// small ones
+	const eps = 1e-9
+	big1, big2 := 1e15+0.25, 1e15+0.125 // neighbouring representable values
+	if math.Abs(big1-big2) < eps {
+		t.Error("expected a fixed epsilon to reject neighbouring large values")
+	}
+	if !AlmostEqual(big1, big2, 1) {
+		t.Errorf("AlmostEqual rejected values %d ULPs apart", ULPDistance(big1, big2))
+	}
+
+	tiny1, tiny2 := 1e-12, 2e-12 // a factor of two apart
+	if math.Abs(tiny1-tiny2) >= eps {
+		t.Error("expected a fixed epsilon to accept values a factor of two apart")
+	}
+	if AlmostEqual(tiny1, tiny2, 1<<20) {
+		t.Error("AlmostEqual accepted values a factor of two apart")
+	}
+}
+
+func TestClose(t *testing.T) {
+	tests := []struct {
+		a, b, rel, abs float64
+		want           bool
+	}{
+		{0.30000000000000004, 0.3, 1e-9, 0, true},
+		{1e-17, 0, 1e-9, 0, false}, // relative tolerance alone fails at 0
+		{1e-17, 0, 1e-9, 1e-12, true},
+		{100, 101, 0.01, 0, true},
+		{100, 102, 0.01, 0, false},
+		{math.Inf(1), math.Inf(1), 0, 0, true},
+		{math.NaN(), math.NaN(), 1, 1, false},
+	}
+	for _, tt := range tests {
+		if got := Close(tt.a, tt.b, tt.rel, tt.abs); got != tt.want {
+			t.Errorf("Close(%v, %v, %v, %v) = %t, want %t", tt.a, tt.b, tt.rel, tt.abs, got, tt.want)
+		}
+	}
+}
+
+func TestULPDistanceOrdering(t *testing.T) {
+	// Walking with Nextafter visits every float; the distance counts steps
+	x := -3 * math.SmallestNonzeroFloat64
+	for i := uint64(0); i <= 6; i++ {
+		if d := ULPDistance(-3*math.SmallestNonzeroFloat64, x); d != i {
+			t.Errorf("after %d steps distance = %d", i, d)
+		}
+		x = math.Nextafter(x, 1)
+	}
+
+	if d := ULPDistance(-math.MaxFloat64, math.MaxFloat64); d != 2*uint64(math.Float64bits(math.MaxFloat64)) {
+		t.Errorf("full range distance = %d", d)
+	}
+}
+
+// 5. Examples
+// ===========
+
+func ExampleDecompose64() {
+	// -0.0 is a constant expression and constants have no negative
+	// zero: it is +0. Use math.Copysign(0, -1) for -0.
+	for _, f := range []float64{1, 0.1, -0.0, math.Inf(1)} {
+		p := Decompose64(f)
+		fmt.Printf("%-5v %s %s\n", f, p, p.Class)
+	}
+	// Output:
+	// 1     0 01111111111 0000000000000000000000000000000000000000000000000000 normal
+	// 0.1   0 01111111011 1001100110011001100110011001100110011001100110011010 normal
+	// 0     0 00000000000 0000000000000000000000000000000000000000000000000000 zero
+	// +Inf  0 11111111111 0000000000000000000000000000000000000000000000000000 inf
+}
+
+func ExampleAlmostEqual() {
+	a, b := 0.1, 0.2
+	fmt.Println(a+b == 0.3, AlmostEqual(a+b, 0.3, 4), ULPDistance(a+b, 0.3))
+	// Output: false true 1
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Go Constants and iota - Untyped Constants and Enum Patterns
+// ===========================================================
+// This file demonstrates untyped constants, compile-time overflow
+// checks, iota enum patterns, String() methods, bit-flag enums and a
+// String() method generated with stringer.
+//
+// Regenerate and run:
+//   go generate go_constants_iota.go
+//   go run go_constants_iota.go weekday_string.go
+
+//go:generate go run golang.org/x/tools/cmd/stringer@latest -type=Weekday -output weekday_string.go go_constants_iota.go
+
+func main() {
+	fmt.Println("=== Go Constants and iota ===")
+
+	// 1. Untyped constants
+	untypedConstants()
+
+	// 2. Compile-time overflow
+	compileTimeOverflow()
+
+	// 3. iota patterns
+	iotaPatterns()
+
+	// 4. Typed enums with String()
+	typedEnums()
+
+	// 5. Bit-flag enums
+	bitFlags()
+
+	// 6. go:generate stringer
+	generatedStringer()
+}
+
+// 1. Untyped Constants
+// ====================
+func untypedConstants() {
+	fmt.Println("\n1. UNTYPED CONSTANTS:")
+
+	// Untyped constants are exact values with at least 256 bits of
+	// precision. They only get a type (and its limits) when used.
+	const huge = 1 << 100
+	fmt.Printf("   huge >> 98 = %d (1<<100 is fine as a constant)\n", huge>>98)
+
+	// The same untyped constant fits any numeric type it can represent,
+	// with no conversion
+	const timeout = 30
+	var i8 int8 = timeout
+	var f32 float32 = timeout
+	var c complex128 = timeout
+	fmt.Printf("   timeout as int8=%d float32=%g complex128=%v\n", i8, f32, c)
+
+	// math.Pi is untyped too, so it converts to float32 implicitly
+	var radius float32 = 2
+	fmt.Printf("   float32 area: %g\n", math.Pi*radius*radius)
+
+	// Without a context, an untyped constant takes its default type:
+	// int, float64, complex128, rune, string or bool
+	x, y, r := 1, 1.0, 'a'
+	fmt.Printf("   default types: %T %T %T\n", x, y, r)
+
+	// A typed constant has the type's rules from the start
+	const typed int32 = 30
+	// var i8b int8 = typed // error: cannot use typed (constant 30 of type int32) as int8 value
+	fmt.Printf("   typed constant needs conversion: int8(typed)=%d\n", int8(typed))
+
+	// Constant arithmetic is exact: no float rounding at compile time
+	const third = 1.0 / 3
+	fmt.Printf("   const 0.1+0.2 == 0.3: %t; third*3 == 1: %t\n", 0.1+0.2 == 0.3, third*3 == 1)
+}
+
+// 2. Compile-Time Overflow
+// ========================
+func compileTimeOverflow() {
+	fmt.Println("\n2. COMPILE-TIME OVERFLOW:")
+
+	// Constant overflow is a compile error, not a wrap-around. Each line
+	// below fails to build:
+	//
+	//   var b int8 = 128            // cannot use 128 (untyped int constant) as int8 value (overflows)
+	//   const c uint8 = 255 + 1     // constant 256 overflows uint8
+	//   x := 1 << 64                // cannot use 1 << 64 (untyped int constant 18446744073709551616) as int value (overflows)
+	//   var d uint = -1             // cannot use -1 (untyped int constant) as uint value (overflows)
+	fmt.Println("   var b int8 = 128  ->  compile error (overflows)")
+
+	// Intermediate values may exceed every type as long as the final
+	// value fits where it is used
+	const product = (1 << 70) / (1 << 66)
+	var small int8 = product
+	fmt.Printf("   (1<<70)/(1<<66) as int8: %d\n", small)
+
+	// Variables wrap at run time instead; see safeint/ for checked math
+	v := int8(127)
+	v++
+	fmt.Printf("   run-time int8 127+1: %d\n", v)
+
+	// The usual idiom for "all bits set" in an unsigned type
+	const maxUint = ^uint(0)
+	fmt.Printf("   ^uint(0) = %d\n", maxUint)
+}
+
+// 3. iota Patterns
+// ================
+
+// Weekday is a basic iota enum. Its String method is generated by
+// stringer into weekday_string.go.
+type Weekday int
+
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+)
+
+// ByteSize uses iota in an expression; _ skips the zero value
+type ByteSize uint64
+
+const (
+	_           = iota
+	KB ByteSize = 1 << (10 * iota)
+	MB
+	GB
+	TB
+)
+
+func iotaPatterns() {
+	fmt.Println("\n3. IOTA PATTERNS:")
+
+	// iota counts ConstSpecs from 0 in each const block; a spec without
+	// an expression repeats the previous one with the next iota
+	fmt.Printf("   Sunday=%d Saturday=%d\n", Sunday, Saturday)
+	fmt.Printf("   KB=%d MB=%d GB=%d TB=%d\n", KB, MB, GB, TB)
+
+	// iota is the index of the line, not a counter of named constants,
+	// so several names on one line share it
+	const (
+		a, b = iota, iota * 10 // 0, 0
+		c, d                   // 1, 10
+	)
+	fmt.Printf("   a=%d b=%d c=%d d=%d\n", a, b, c, d)
+
+	// Starting at 1 makes the zero value detectably "unset" - see
+	// Status below
+	fmt.Println("   Start enums at 1 (or name 0 Unknown) so zero means unset")
+}
+
+// 4. Typed Enums with String()
+// ============================
+
+// Status has a hand-written String, validation and text encoding
+type Status int
+
+const (
+	StatusUnknown Status = iota // zero value: never set
+	StatusActive
+	StatusSuspended
+	StatusClosed
+)
+
+var statusNames = [...]string{"unknown", "active", "suspended", "closed"}
+
+// String makes fmt print the name instead of the number
+func (s Status) String() string {
+	if s.IsValid() {
+		return statusNames[s]
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// IsValid reports whether s is one of the declared constants. Any int
+// converts to Status, so values from outside must be checked.
+func (s Status) IsValid() bool {
+	return s >= 0 && int(s) < len(statusNames)
+}
+
+// MarshalText encodes Status as its name, so JSON gets "active" rather
+// than a number that breaks if the constants are reordered
+func (s Status) MarshalText() ([]byte, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("invalid status %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText is the inverse of MarshalText
+func (s *Status) UnmarshalText(text []byte) error {
+	for i, name := range statusNames {
+		if name == string(text) {
+			*s = Status(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %q", text)
+}
+
+func typedEnums() {
+	fmt.Println("\n4. TYPED ENUMS WITH STRING():")
+
+	s := StatusSuspended
+	fmt.Printf("   %%v: %v, %%d: %d\n", s, s)
+	fmt.Printf("   out of range: %v (valid=%t)\n", Status(42), Status(42).IsValid())
+
+	// A typed enum prevents mixing with other int types without a
+	// conversion, but untyped constants still convert silently
+	// var s2 Status = Monday // error: cannot use Monday (constant 1 of type Weekday) as Status value
+	var s3 Status = 2 // allowed: 2 is untyped
+	fmt.Printf("   var s Status = 2 -> %v\n", s3)
+
+	type account struct {
+		ID     int    `json:"id"`
+		Status Status `json:"status"`
+	}
+	data, _ := json.Marshal(account{ID: 1, Status: StatusActive})
+	fmt.Printf("   JSON: %s\n", data)
+
+	var decoded account
+	err := json.Unmarshal([]byte(`{"id":2,"status":"archived"}`), &decoded)
+	fmt.Printf("   unknown name: %v\n", err)
+}
+
+// 5. Bit-Flag Enums
+// =================
+
+// Permission is a set of flags; each constant is one bit
+type Permission uint8
+
+const (
+	Read Permission = 1 << iota
+	Write
+	Execute
+
+	ReadWrite = Read | Write // combinations are ordinary constants
+)
+
+// Has reports whether all flags in q are set in p
+func (p Permission) Has(q Permission) bool {
+	return p&q == q
+}
+
+// String lists the set flags, like "read|execute"
+func (p Permission) String() string {
+	if p == 0 {
+		return "none"
+	}
+	var names []string
+	for _, f := range []struct {
+		flag Permission
+		name string
+	}{{Read, "read"}, {Write, "write"}, {Execute, "execute"}} {
+		if p&f.flag != 0 {
+			names = append(names, f.name)
+			p &^= f.flag
+		}
+	}
+	if p != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint8(p))) // unknown bits
+	}
+	return strings.Join(names, "|")
+}
+
+func bitFlags() {
+	fmt.Println("\n5. BIT-FLAG ENUMS:")
+
+	p := Read | Execute
+	fmt.Printf("   Read|Execute = %v (%03b)\n", p, uint8(p))
+	fmt.Printf("   Has(Read)=%t Has(ReadWrite)=%t\n", p.Has(Read), p.Has(ReadWrite))
+
+	p |= Write    // set
+	p &^= Execute // clear
+	fmt.Printf("   after |= Write, &^= Execute: %v\n", p)
+	p ^= Read // toggle
+	fmt.Printf("   after ^= Read: %v\n", p)
+	fmt.Printf("   with an unknown bit: %v\n", Permission(0b1001))
+}
+
+// 6. go:generate stringer
+// =======================
+func generatedStringer() {
+	fmt.Println("\n6. GO:GENERATE STRINGER:")
+
+	// Weekday.String comes from weekday_string.go, written by
+	//   go run golang.org/x/tools/cmd/stringer@latest -type=Weekday
+	// It stores all names in one string and slices it by offset, so
+	// there is no map lookup and no allocation.
+	fmt.Printf("   %v, %v, %v\n", Sunday, Wednesday, Weekday(9))
+
+	// The generated file also contains a func _() that indexes an array
+	// with each constant: if the constants change without regenerating,
+	// the build fails instead of printing wrong names
+	fmt.Println("   Edit the constants, then rerun: go generate go_constants_iota.go")
+}
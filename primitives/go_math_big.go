@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// Go math/big - Arbitrary Precision Numbers
+// =========================================
+// This file demonstrates big.Int, big.Rat and big.Float for values that
+// do not fit, or cannot be represented exactly, in int64 and float64.
+// The last section measures what that exactness costs with
+// testing.Benchmark (see testing/go_benchmarking.go).
+
+// Benchmark sinks keep results alive so the compiler cannot drop the work
+var (
+	intSink   int64
+	floatSink float64
+	bigSink   *big.Int
+	ratSink   *big.Rat
+)
+
+func main() {
+	fmt.Println("=== Go math/big ===")
+
+	// 1. big.Int
+	bigInt()
+
+	// 2. big.Rat
+	bigRat()
+
+	// 3. big.Float
+	bigFloat()
+
+	// 4. Cost vs int64 and float64
+	benchmarks()
+}
+
+// 1. big.Int
+// ==========
+func bigInt() {
+	fmt.Println("\n1. BIG.INT:")
+
+	// int64 overflows silently after 20!
+	var f int64 = 1
+	for i := int64(1); i <= 21; i++ {
+		f *= i
+	}
+	fmt.Printf("   21! as int64: %d (wrapped around)\n", f)
+
+	// big.Int grows as needed. Methods store the result in the receiver
+	// and return it, so z.Mul(z, x) reuses z's memory instead of
+	// allocating a new number on every step.
+	fact := big.NewInt(1)
+	for i := int64(2); i <= 100; i++ {
+		fact.Mul(fact, big.NewInt(i))
+	}
+	s := fact.String()
+	fmt.Printf("   100! has %d digits: %s...%s\n", len(s), s[:20], s[len(s)-10:])
+
+	// MulRange computes the same product directly
+	check := new(big.Int).MulRange(1, 100)
+	fmt.Printf("   MulRange(1, 100) equal: %t\n", fact.Cmp(check) == 0)
+
+	// Values are compared with Cmp: == on *big.Int compares pointers
+	a, b := big.NewInt(42), big.NewInt(42)
+	fmt.Printf("   a.Cmp(b) == 0: %t, a == b: %t\n", a.Cmp(b) == 0, a == b)
+
+	// Parse and format in any base 2..62
+	n, ok := new(big.Int).SetString("ffffffffffffffffffffffffffffffff", 16)
+	fmt.Printf("   2^128-1 from hex: %s (ok=%t), BitLen=%d\n", n, ok, n.BitLen())
+	fmt.Printf("   2^127-1 is prime: %t\n", new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1)).ProbablyPrime(20))
+
+	// Copying the struct shares the digits: use Set to copy the value
+	c := new(big.Int).Set(a)
+	c.Add(c, big.NewInt(1))
+	fmt.Printf("   after Set+Add: a=%s c=%s\n", a, c)
+}
+
+// 2. big.Rat
+// ==========
+func bigRat() {
+	fmt.Println("\n2. BIG.RAT:")
+
+	// float64 cannot represent 0.10, so cents drift
+	total := 0.0
+	for i := 0; i < 10; i++ {
+		total += 0.10
+	}
+	fmt.Printf("   float64: ten × 0.10 = %.17f (== 1.0: %t)\n", total, total == 1.0)
+
+	// big.Rat keeps an exact numerator/denominator
+	exact := new(big.Rat)
+	dime := big.NewRat(1, 10)
+	for i := 0; i < 10; i++ {
+		exact.Add(exact, dime)
+	}
+	fmt.Printf("   big.Rat: ten × 1/10 = %s (== 1: %t)\n", exact.RatString(), exact.Cmp(big.NewRat(1, 1)) == 0)
+
+	// Split 100.00 three ways: the exact share is a repeating decimal.
+	// Round only at the end, and give the remainder cent to someone.
+	bill, _ := new(big.Rat).SetString("100.00")
+	share := new(big.Rat).Quo(bill, big.NewRat(3, 1))
+	fmt.Printf("   100.00 / 3 = %s exactly, %s rounded\n", share.RatString(), share.FloatString(2))
+
+	cents := new(big.Int).Quo(new(big.Int).Mul(bill.Num(), big.NewInt(100)), bill.Denom())
+	each, rem := new(big.Int).QuoRem(cents, big.NewInt(3), new(big.Int))
+	fmt.Printf("   in cents: %s each, %s left over for the first payer\n", each, rem)
+
+	// Compound interest: 5% for 10 years stays exact until formatted
+	balance := big.NewRat(1000, 1)
+	rate := big.NewRat(105, 100)
+	for year := 0; year < 10; year++ {
+		balance.Mul(balance, rate)
+	}
+	fmt.Printf("   1000 at 5%% for 10 years: %s (denominator has %d digits)\n",
+		balance.FloatString(2), len(balance.Denom().String()))
+	fmt.Println("   Exact fractions grow without bound: round at business boundaries")
+}
+
+// 3. big.Float
+// ============
+func bigFloat() {
+	fmt.Println("\n3. BIG.FLOAT:")
+
+	// big.Float is binary floating point with a precision you choose. It
+	// is NOT decimal: 0.1 is still inexact, just with more bits.
+	for _, prec := range []uint{24, 53, 200} {
+		x := new(big.Float).SetPrec(prec)
+		x.SetString("0.1")
+		fmt.Printf("   0.1 at %3d bits: %.64f\n", prec, x)
+	}
+
+	// Operations round to the receiver's precision; a zero receiver
+	// takes the larger precision of the operands
+	const prec = 200
+	two := new(big.Float).SetPrec(prec).SetInt64(2)
+	sqrt2 := new(big.Float).SetPrec(prec).Sqrt(two)
+	fmt.Printf("   sqrt(2) at %d bits: %s\n", prec, sqrt2.Text('f', 55))
+	fmt.Printf("   math.Sqrt(2):         %.55f\n", math.Sqrt2)
+
+	// Accuracy reports which way the last operation rounded
+	f64, acc := sqrt2.Float64()
+	fmt.Printf("   back to float64: %v (%s)\n", f64, acc)
+
+	// Large magnitudes with no overflow
+	huge := new(big.Float).SetMantExp(big.NewFloat(1), 5000)
+	fmt.Printf("   2^5000 = %.6g (float64 would be +Inf)\n", huge)
+}
+
+// 4. Cost vs int64 and float64
+// ============================
+func benchmarks() {
+	fmt.Println("\n4. COST VS INT64 AND FLOAT64:")
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"int64 sum 1..1000", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var s int64
+				for j := int64(1); j <= 1000; j++ {
+					s += j
+				}
+				intSink = s
+			}
+		}},
+		{"big.Int sum (reused)", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s, x := new(big.Int), new(big.Int)
+				for j := int64(1); j <= 1000; j++ {
+					s.Add(s, x.SetInt64(j))
+				}
+				bigSink = s
+			}
+		}},
+		{"big.Int sum (new each)", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s := new(big.Int)
+				for j := int64(1); j <= 1000; j++ {
+					s = new(big.Int).Add(s, big.NewInt(j)) // allocates twice per step
+				}
+				bigSink = s
+			}
+		}},
+		{"float64 sum of 1/j", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var s float64
+				for j := 1; j <= 1000; j++ {
+					s += 1 / float64(j)
+				}
+				floatSink = s
+			}
+		}},
+		{"big.Rat sum of 1/j", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// Exact harmonic number: the denominator grows to
+				// hundreds of digits, so each Add gets slower
+				s, x := new(big.Rat), new(big.Rat)
+				for j := int64(1); j <= 1000; j++ {
+					s.Add(s, x.SetFrac64(1, j))
+				}
+				ratSink = s
+			}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			bm.fn(b)
+		})
+		fmt.Printf("   %-24s %s\n", bm.name, formatResult(r))
+	}
+	fmt.Println("   Reuse receivers to avoid allocation; use big types only where")
+	fmt.Println("   values really overflow or must be exact")
+}
+
+// Helper functions
+// ================
+func formatResult(r testing.BenchmarkResult) string {
+	ns := float64(r.T.Nanoseconds()) / float64(r.N)
+	return fmt.Sprintf("%12.1f ns/op %8d B/op %6d allocs/op", ns, r.AllocedBytesPerOp(), r.AllocsPerOp())
+}
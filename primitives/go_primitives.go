@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"unsafe"
 )
 
@@ -109,6 +110,16 @@ func integerTypes() {
 	fmt.Printf("   2 ^ 3 = %d\n", 2^3)  // XOR
 	fmt.Printf("   2 << 1 = %d\n", 2<<1)  // Left shift
 	fmt.Printf("   8 >> 1 = %d\n", 8>>1)  // Right shift
+	
+	// Overflow wraps around silently at run time - no panic, no error.
+	// (Constant expressions like int8(127)+1 are rejected by the compiler.)
+	i8++
+	i32 *= 2
+	u8++
+	fmt.Printf("   int8 127 + 1 = %d (wrapped)\n", i8)
+	fmt.Printf("   int32 2147483647 * 2 = %d (wrapped)\n", i32)
+	fmt.Printf("   uint8 255 + 1 = %d (wrapped)\n", u8)
+	fmt.Println("   Checked and saturating arithmetic: see safeint/")
 }
 
 // 3. Floating-Point Types
@@ -255,16 +266,30 @@ func typeConversions() {
 	var intPi int = int(pi)
 	fmt.Printf("   float64 to int: %d (truncated)\n", intPi)
 	
-	// String conversions
+	// String conversions go through strconv and can fail
 	var numStr string = "123"
-	var num int = 123
+	num, err := strconv.Atoi(numStr)
+	if err != nil {
+		fmt.Printf("   String to int failed: %v\n", err)
+	}
 	fmt.Printf("   String to int: %d\n", num)
-	fmt.Printf("   Int to string: %s\n", fmt.Sprintf("%d", num))
+	fmt.Printf("   Int to string: %s\n", strconv.Itoa(num))
+	if _, err := strconv.Atoi("12a"); err != nil {
+		fmt.Printf("   Bad input: %v\n", err)
+	}
+	
+	// string(num) is NOT a number conversion: it yields the rune U+007B
+	fmt.Printf("   string(rune(123)): %q\n", string(rune(num)))
 	
 	// Boolean conversions
 	var boolStr string = "true"
-	var boolVal bool = (boolStr == "true")
+	boolVal, err := strconv.ParseBool(boolStr)
+	if err != nil {
+		fmt.Printf("   String to bool failed: %v\n", err)
+	}
 	fmt.Printf("   String to bool: %t\n", boolVal)
+	fmt.Printf("   Bool to string: %s\n", strconv.FormatBool(boolVal))
+	fmt.Println("   More in parsing/ (bases, bit sizes, floats, quoting)")
 }
 
 // 8. Zero Values
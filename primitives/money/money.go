@@ -0,0 +1,269 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Money - Fixed-Point Decimal Arithmetic
+// ======================================
+// float64 is binary, so amounts like 0.10 are stored approximately and
+// errors build up with every operation. Money stores an integer number
+// of minor units (cents) in an int64 instead: addition is exact, and
+// rounding happens only where the program says so, with an explicit
+// rule. The range is about ±92 quadrillion, enough for any ledger.
+//
+// All arithmetic is on integers and allocates nothing. Overflow is an
+// error rather than a wrapped balance.
+
+// Money is an amount in minor units: Money(1234) is 12.34
+type Money int64
+
+// Decimals is the number of digits after the decimal point
+const Decimals = 2
+
+const scale = 100 // 10^Decimals
+
+var (
+	// ErrOverflow means the result does not fit in an int64
+	ErrOverflow = errors.New("money: overflow")
+
+	// ErrSyntax means the input is not a decimal amount
+	ErrSyntax = errors.New("money: invalid syntax")
+
+	// ErrPrecision means the input has more decimals than Money keeps
+	ErrPrecision = errors.New("money: too many decimal places")
+
+	// ErrMinor means minor units that are not a part of one major unit
+	ErrMinor = errors.New("money: minor units out of range")
+)
+
+// RoundingMode selects how a result between two cents is rounded
+type RoundingMode int
+
+const (
+	// HalfEven rounds to the nearest cent and ties to the even one
+	// (banker's rounding), so ties do not bias totals upwards
+	HalfEven RoundingMode = iota
+	// HalfUp rounds to the nearest cent and ties away from zero, as
+	// taught in school
+	HalfUp
+	// TowardZero drops the remainder (truncation)
+	TowardZero
+	// AwayFromZero rounds any remainder up in magnitude
+	AwayFromZero
+)
+
+// New returns major units plus minor units: New(12, 34) is 12.34 and
+// New(-12, -34) is -12.34. minor must be less than one major unit and,
+// unless major is 0, have its sign; otherwise the error is ErrMinor,
+// since New(1, 250) or New(-12, 34) is more likely a mistake than 3.50
+// or -11.66.
+func New(major, minor int64) (Money, error) {
+	if minor <= -scale || minor >= scale || (major > 0 && minor < 0) || (major < 0 && minor > 0) {
+		return 0, ErrMinor
+	}
+	hi, lo := bits.Mul64(abs(major), scale)
+	if hi != 0 || lo > math.MaxInt64 {
+		return 0, ErrOverflow
+	}
+	m := int64(lo)
+	if major < 0 {
+		m = -m
+	}
+	return Money(m).Add(Money(minor))
+}
+
+// Parse reads amounts like "12.34", "-0.5" or "1000". More than
+// Decimals digits after the point is ErrPrecision; round explicitly
+// before parsing if the input may carry more.
+func Parse(s string) (Money, error) {
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && frac == "") || !digits(whole) || !digits(frac) {
+		return 0, ErrSyntax
+	}
+	if len(frac) > Decimals {
+		return 0, ErrPrecision
+	}
+
+	units, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil {
+		return 0, ErrOverflow
+	}
+	cents := uint64(0)
+	for i := range Decimals {
+		cents *= 10
+		if i < len(frac) {
+			cents += uint64(frac[i] - '0')
+		}
+	}
+
+	hi, lo := bits.Mul64(units, scale)
+	total, carry := bits.Add64(lo, cents, 0)
+	limit := uint64(math.MaxInt64)
+	if neg {
+		limit++ // -9223372036854775808 fits, its positive does not
+	}
+	if hi != 0 || carry != 0 || total > limit {
+		return 0, ErrOverflow
+	}
+	if neg {
+		return Money(-total), nil
+	}
+	return Money(total), nil
+}
+
+// MustParse is Parse for constants in code and tests. It panics on error.
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// String formats m with exactly Decimals digits: "-12.30"
+func (m Money) String() string {
+	var buf [24]byte
+	return string(m.Append(buf[:0]))
+}
+
+// Append appends the String form of m to dst
+func (m Money) Append(dst []byte) []byte {
+	if m < 0 {
+		dst = append(dst, '-')
+	}
+	u := abs(int64(m))
+	dst = strconv.AppendUint(dst, u/scale, 10)
+	dst = append(dst, '.')
+	frac := u % scale
+	return append(dst, byte('0'+frac/10), byte('0'+frac%10))
+}
+
+// Add returns m+other, or ErrOverflow
+func (m Money) Add(other Money) (Money, error) {
+	sum := m + other
+	if (other > 0 && sum < m) || (other < 0 && sum > m) {
+		return 0, ErrOverflow
+	}
+	return sum, nil
+}
+
+// Sub returns m-other, or ErrOverflow
+func (m Money) Sub(other Money) (Money, error) {
+	diff := m - other
+	if (other > 0 && diff > m) || (other < 0 && diff < m) {
+		return 0, ErrOverflow
+	}
+	return diff, nil
+}
+
+// Mul returns m times a whole quantity, such as a unit price times a
+// count. The product is exact, so no rounding is involved.
+func (m Money) Mul(qty int64) (Money, error) {
+	return m.MulRat(qty, 1, TowardZero)
+}
+
+// MulRat returns m*num/den rounded to a cent with mode. Rates are given
+// as fractions so they stay exact: 8.25% tax is MulRat(825, 10000, mode).
+// The intermediate product is 128 bits wide, so it cannot overflow
+// before the division.
+func (m Money) MulRat(num, den int64, mode RoundingMode) (Money, error) {
+	if den == 0 {
+		return 0, ErrSyntax
+	}
+	neg := (m < 0) != (num < 0) != (den < 0)
+	d := abs(den)
+
+	hi, lo := bits.Mul64(abs(int64(m)), abs(num))
+	if hi >= d {
+		return 0, ErrOverflow // the quotient would need more than 64 bits
+	}
+	q, r := bits.Div64(hi, lo, d)
+	q, ok := round(q, r, d, mode)
+
+	limit := uint64(math.MaxInt64)
+	if neg {
+		limit++
+	}
+	if !ok || q > limit {
+		return 0, ErrOverflow
+	}
+	if neg {
+		return Money(-q), nil
+	}
+	return Money(q), nil
+}
+
+// Split divides m into n parts that differ by at most one cent and sum
+// to exactly m. Dividing 100.00 by 3 and rounding each share loses a
+// cent; Split hands it to the first share instead.
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	parts := make([]Money, n)
+	share, rem := m/Money(n), m%Money(n)
+	step := Money(1)
+	if rem < 0 {
+		step, rem = -1, -rem
+	}
+	for i := range parts {
+		parts[i] = share
+		if Money(i) < rem {
+			parts[i] += step
+		}
+	}
+	return parts
+}
+
+// round adjusts quotient q of a magnitude division with remainder r and
+// divisor d. Comparing r with d-r avoids overflow in 2*r. It reports
+// false when rounding up would wrap past math.MaxUint64.
+func round(q, r, d uint64, mode RoundingMode) (uint64, bool) {
+	up := false
+	switch {
+	case r == 0, mode == TowardZero:
+	case mode == AwayFromZero:
+		up = true
+	case mode == HalfUp:
+		up = r >= d-r
+	case mode == HalfEven:
+		up = r > d-r || (r == d-r && q%2 == 1)
+	}
+	if !up {
+		return q, true
+	}
+	if q == math.MaxUint64 {
+		return 0, false
+	}
+	return q + 1, true
+}
+
+// abs returns |v| as a uint64, correct for math.MinInt64 as well
+func abs(v int64) uint64 {
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
+}
+
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,311 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// Money - Why float64 Fails and Fixed-Point Works
+// ===============================================
+// Run with:
+//
+//   cd primitives/money
+//   go test -v *.go
+
+// 1. float64 Money Bugs
+// =====================
+// Each test shows a float64 calculation going wrong, then the same
+// calculation with Money.
+
+func TestFloatSumDrifts(t *testing.T) {
+	// 100 payments of one cent
+	f := 0.0
+	for range 100 {
+		f += 0.01
+	}
+	if f == 1.00 {
+		t.Fatal("expected float64 drift")
+	}
+	t.Logf("float64: 100 × 0.01 = %.17f", f)
+
+	var m Money
+	for range 100 {
+		m, _ = m.Add(MustParse("0.01"))
+	}
+	if m != MustParse("1.00") {
+		t.Errorf("Money: 100 × 0.01 = %s", m)
+	}
+}
+
+func TestFloatComparisonFails(t *testing.T) {
+	price, discount := 0.30, 0.10
+	if price-discount == 0.20 {
+		t.Fatal("expected 0.30 - 0.10 != 0.20 in float64")
+	}
+
+	got, _ := MustParse("0.30").Sub(MustParse("0.10"))
+	if got != MustParse("0.20") {
+		t.Errorf("Money: 0.30 - 0.10 = %s", got)
+	}
+}
+
+func TestFloatRoundingSurprise(t *testing.T) {
+	// 1.005 is stored as 1.00499999999999989..., so "round half up to
+	// cents" rounds down
+	f := 1.005
+	if rounded := math.Round(f*100) / 100; rounded != 1.00 {
+		t.Fatalf("float64 rounding gave %v", rounded)
+	}
+	if s := strconv.FormatFloat(f, 'f', 2, 64); s != "1.00" {
+		t.Fatalf("FormatFloat gave %s", s)
+	}
+
+	// With exact input the tie is a real tie and the rule decides:
+	// 1.005 = 201 cents / 2
+	m := MustParse("2.01")
+	half, _ := m.MulRat(1, 2, HalfUp)
+	if half != MustParse("1.01") {
+		t.Errorf("2.01 / 2 HalfUp = %s, want 1.01", half)
+	}
+}
+
+func TestFloatLosesCentsWhenLarge(t *testing.T) {
+	// Above 2^53 float64 cannot even hold every integer, so large
+	// balances lose cents
+	f := 90_071_992_547_409.93
+	if s := strconv.FormatFloat(f, 'f', 2, 64); s == "90071992547409.93" {
+		t.Fatalf("expected float64 to lose the cents, got %s", s)
+	}
+
+	m := MustParse("90071992547409.93")
+	if got := m.String(); got != "90071992547409.93" {
+		t.Errorf("Money = %s", got)
+	}
+}
+
+// 2. Parsing and Formatting
+// =========================
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr error
+	}{
+		{"12.34", 1234, nil},
+		{"12.3", 1230, nil},
+		{"12", 1200, nil},
+		{"-0.05", -5, nil},
+		{"+7.00", 700, nil},
+		{"0", 0, nil},
+		{"92233720368547758.07", math.MaxInt64, nil},
+		{"-92233720368547758.08", math.MinInt64, nil},
+		{"92233720368547758.08", 0, ErrOverflow},
+		{"1.234", 0, ErrPrecision},
+		{"1,000.00", 0, ErrSyntax},
+		{".5", 0, ErrSyntax},
+		{"5.", 0, ErrSyntax},
+		{"1e3", 0, ErrSyntax},
+		{"", 0, ErrSyntax},
+		{"--1", 0, ErrSyntax},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("Parse(%q) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{1230, "12.30"},
+		{-100000, "-1000.00"},
+		{math.MinInt64, "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %s, want %s", int64(tt.m), got, tt.want)
+		}
+		if back, err := Parse(tt.want); back != tt.m || err != nil {
+			t.Errorf("Parse(%s) = %d, %v; round trip failed", tt.want, back, err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if m, err := New(12, 34); m != 1234 || err != nil {
+		t.Errorf("New(12, 34) = %s, %v", m, err)
+	}
+	if m, err := New(-12, -34); m != -1234 || err != nil {
+		t.Errorf("New(-12, -34) = %s, %v", m, err)
+	}
+	if m, err := New(0, -34); m != -34 || err != nil {
+		t.Errorf("New(0, -34) = %s, %v", m, err)
+	}
+	if _, err := New(math.MaxInt64/10, 0); !errors.Is(err, ErrOverflow) {
+		t.Errorf("New(huge) error = %v", err)
+	}
+	// Minor units of a whole major unit or more, or of the other sign
+	for _, tt := range [][2]int64{{1, 250}, {1, 100}, {0, -100}, {-12, 34}, {12, -34}} {
+		if m, err := New(tt[0], tt[1]); !errors.Is(err, ErrMinor) {
+			t.Errorf("New(%d, %d) = %s, %v; want ErrMinor", tt[0], tt[1], m, err)
+		}
+	}
+}
+
+// 3. Arithmetic and Rounding
+// ==========================
+
+func TestOverflowIsAnError(t *testing.T) {
+	if _, err := Money(math.MaxInt64).Add(1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Max + 0.01 error = %v", err)
+	}
+	if _, err := Money(math.MinInt64).Sub(1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Min - 0.01 error = %v", err)
+	}
+	if _, err := MustParse("100000000000000.00").Mul(1000); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Mul overflow error = %v", err)
+	}
+}
+
+func TestMulRatRoundingModes(t *testing.T) {
+	tests := []struct {
+		amount   string
+		num, den int64
+		mode     RoundingMode
+		want     string
+	}{
+		// 0.05 / 2 = 0.025: an exact tie
+		{"0.05", 1, 2, HalfEven, "0.02"},
+		{"0.05", 1, 2, HalfUp, "0.03"},
+		{"0.07", 1, 2, HalfEven, "0.04"}, // 0.035: 3 is odd, round to 4
+		{"-0.05", 1, 2, HalfUp, "-0.03"}, // ties go away from zero
+		{"-0.05", 1, 2, HalfEven, "-0.02"},
+		{"10.00", 1, 3, TowardZero, "3.33"},
+		{"10.00", 1, 3, AwayFromZero, "3.34"},
+		{"10.00", 2, 3, HalfUp, "6.67"},
+		// 8.25% sales tax on 19.99 = 1.649175
+		{"19.99", 825, 10000, HalfEven, "1.65"},
+		{"19.99", 825, 10000, TowardZero, "1.64"},
+		{"100.00", -1, 4, HalfEven, "-25.00"},
+	}
+	for _, tt := range tests {
+		got, err := MustParse(tt.amount).MulRat(tt.num, tt.den, tt.mode)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s × %d/%d (mode %d) = %s, %v; want %s", tt.amount, tt.num, tt.den, tt.mode, got, err, tt.want)
+		}
+	}
+}
+
+// A quotient of math.MaxUint64 with a remainder must not round up to 0
+func TestMulRatRoundingOverflow(t *testing.T) {
+	// 1190112520884487201 × 31 / 2 = 18446744073709551615.5
+	m := Money(1190112520884487201)
+	for _, mode := range []RoundingMode{TowardZero, AwayFromZero, HalfUp, HalfEven} {
+		if got, err := m.MulRat(31, 2, mode); !errors.Is(err, ErrOverflow) {
+			t.Errorf("mode %d: %s, %v; want ErrOverflow", mode, got, err)
+		}
+		if got, err := (-m).MulRat(31, 2, mode); !errors.Is(err, ErrOverflow) {
+			t.Errorf("mode %d, negative: %s, %v; want ErrOverflow", mode, got, err)
+		}
+	}
+}
+
+func TestHalfEvenHasNoBias(t *testing.T) {
+	// Halving 0.01, 0.03, ..., 0.99 makes a tie every time. HalfUp always
+	// rounds the tie up, HalfEven alternates, so its total stays exact.
+	var exactCents, halfUp, halfEven int64
+	for cents := int64(1); cents < 100; cents += 2 {
+		exactCents += cents
+		up, _ := Money(cents).MulRat(1, 2, HalfUp)
+		even, _ := Money(cents).MulRat(1, 2, HalfEven)
+		halfUp += int64(up)
+		halfEven += int64(even)
+	}
+	if 2*halfEven != exactCents {
+		t.Errorf("HalfEven total %d, exact %d/2", halfEven, exactCents)
+	}
+	if 2*halfUp <= exactCents {
+		t.Errorf("HalfUp total %d should be biased above %d/2", halfUp, exactCents)
+	}
+}
+
+func TestMulRatWideIntermediate(t *testing.T) {
+	// m*num overflows int64 here, but the 128-bit intermediate keeps the
+	// result exact
+	m := MustParse("90000000000000000.00")
+	got, err := m.MulRat(1_000_000, 1_000_001, HalfEven)
+	if err != nil || got.String() != "89999910000089999.91" {
+		t.Errorf("MulRat = %s, %v", got, err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		amount string
+		n      int
+		want   []string
+	}{
+		{"100.00", 3, []string{"33.34", "33.33", "33.33"}},
+		{"0.05", 2, []string{"0.03", "0.02"}},
+		{"-0.05", 2, []string{"-0.03", "-0.02"}},
+		{"0.02", 4, []string{"0.01", "0.01", "0.00", "0.00"}},
+	}
+	for _, tt := range tests {
+		m := MustParse(tt.amount)
+		parts := m.Split(tt.n)
+		var sum Money
+		for i, p := range parts {
+			sum += p
+			if p.String() != tt.want[i] {
+				t.Errorf("%s.Split(%d)[%d] = %s, want %s", tt.amount, tt.n, i, p, tt.want[i])
+			}
+		}
+		if sum != m {
+			t.Errorf("%s.Split(%d) sums to %s", tt.amount, tt.n, sum)
+		}
+	}
+}
+
+func TestNoAllocations(t *testing.T) {
+	price := MustParse("19.99")
+	buf := make([]byte, 0, 32)
+	allocs := testing.AllocsPerRun(100, func() {
+		total, _ := price.Mul(3)
+		tax, _ := total.MulRat(825, 10000, HalfEven)
+		total, _ = total.Add(tax)
+		buf = total.Append(buf[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("arithmetic and Append: %v allocs, want 0", allocs)
+	}
+	if string(buf) != "64.92" {
+		t.Errorf("total = %s", buf)
+	}
+}
+
+// 4. Examples
+// ===========
+
+func ExampleMoney_MulRat() {
+	subtotal, _ := MustParse("19.99").Mul(3)
+	tax, _ := subtotal.MulRat(825, 10000, HalfEven) // 8.25%
+	total, _ := subtotal.Add(tax)
+	fmt.Println(subtotal, tax, total)
+	// Output: 59.97 4.95 64.92
+}
+
+func ExampleMoney_Split() {
+	fmt.Println(MustParse("100.00").Split(3))
+	// Output: [33.34 33.33 33.33]
+}
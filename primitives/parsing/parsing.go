@@ -0,0 +1,132 @@
+package parsing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Parsing - strconv Helpers
+// =========================
+// strconv converts between strings and numbers without fmt's reflection.
+// Its parse functions take a base and a bit size and return a
+// *strconv.NumError that says which function failed, on which input, and
+// whether the cause was syntax (strconv.ErrSyntax) or range
+// (strconv.ErrRange). The helpers here add what callers usually rebuild
+// by hand: the bit size derived from the target type, range checks with
+// useful messages, and a human byte-size format that round-trips.
+
+// Signed is the set of signed integer types
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is the set of unsigned integer types
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Int parses s as a T in the given base (0 means use the 0x, 0o or 0b
+// prefix, and allows underscores). The bit size comes from T, so a value
+// that does not fit is an ErrRange error instead of a silent truncation
+// by a later T(n) conversion.
+func Int[T Signed](s string, base int) (T, error) {
+	var zero T
+	n, err := strconv.ParseInt(s, base, int(unsafe.Sizeof(zero))*8)
+	return T(n), err
+}
+
+// Uint is Int for unsigned types. A leading sign is a syntax error.
+func Uint[T Unsigned](s string, base int) (T, error) {
+	var zero T
+	n, err := strconv.ParseUint(s, base, int(unsafe.Sizeof(zero))*8)
+	return T(n), err
+}
+
+// IntInRange parses a base-10 int and checks lo <= n <= hi. Out-of-range
+// values wrap strconv.ErrRange so callers can test with errors.Is.
+func IntInRange(s string, lo, hi int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if n < lo || n > hi {
+		return 0, fmt.Errorf("%d not in [%d, %d]: %w", n, lo, hi, strconv.ErrRange)
+	}
+	return n, nil
+}
+
+// ParsePort parses a TCP/UDP port number (1-65535)
+func ParsePort(s string) (uint16, error) {
+	n, err := IntInRange(s, 1, math.MaxUint16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q: %w", s, err)
+	}
+	return uint16(n), nil
+}
+
+// Byte Sizes
+// ==========
+
+// units are binary (IEC) multiples, largest first so FormatByteSize
+// picks the biggest unit that divides exactly
+var units = []struct {
+	suffix string
+	size   uint64
+}{
+	{"EiB", 1 << 60},
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize parses sizes like "512", "64KiB" or "1.5GiB". Fractions
+// are allowed if the result is a whole number of bytes.
+func ParseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	num, mult := s, uint64(1)
+	for _, u := range units {
+		if rest, ok := strings.CutSuffix(s, u.suffix); ok {
+			num, mult = strings.TrimSpace(rest), u.size
+			break
+		}
+	}
+	// Whole numbers go through ParseUint so values above 2^53 stay exact
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/mult {
+			return 0, fmt.Errorf("parse byte size %q: %w", s, strconv.ErrRange)
+		}
+		return n * mult, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse byte size %q: %w", s, err)
+	}
+	total := f * float64(mult)
+	switch {
+	case f < 0 || math.IsNaN(f):
+		return 0, fmt.Errorf("parse byte size %q: %w", s, strconv.ErrSyntax)
+	case total >= math.MaxUint64:
+		return 0, fmt.Errorf("parse byte size %q: %w", s, strconv.ErrRange)
+	case total != math.Trunc(total):
+		return 0, fmt.Errorf("parse byte size %q: not a whole number of bytes", s)
+	}
+	return uint64(total), nil
+}
+
+// FormatByteSize formats n with the largest unit that divides it exactly,
+// so ParseByteSize(FormatByteSize(n)) == n for every n
+func FormatByteSize(n uint64) string {
+	for _, u := range units {
+		if n != 0 && n%u.size == 0 {
+			return strconv.FormatUint(n/u.size, 10) + u.suffix
+		}
+	}
+	return "0B"
+}
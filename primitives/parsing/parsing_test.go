@@ -0,0 +1,398 @@
+package parsing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+// strconv - Parsing, Formatting and Round Trips
+// =============================================
+// Run with:
+//
+//   cd primitives/parsing
+//   go test -v *.go
+//
+// The round-trip tests use testing/quick to throw random values at a
+// format/parse pair; see testing/proptest for a version with shrinking.
+
+// 1. Errors
+// =========
+
+func TestNumError(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr error
+	}{
+		{"42", nil},
+		{"-42", nil},
+		{" 42", strconv.ErrSyntax}, // no trimming
+		{"4_2", strconv.ErrSyntax}, // underscores only with base 0
+		{"", strconv.ErrSyntax},
+		{"9223372036854775808", strconv.ErrRange}, // MaxInt64 + 1
+	}
+
+	for _, tt := range tests {
+		_, err := strconv.ParseInt(tt.in, 10, 64)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseInt(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+		}
+
+		// Every parse error is a *NumError naming the function and input
+		var numErr *strconv.NumError
+		if err != nil && (!errors.As(err, &numErr) || numErr.Func != "ParseInt" || numErr.Num != tt.in) {
+			t.Errorf("ParseInt(%q) error = %#v, want a *NumError for the input", tt.in, err)
+		}
+	}
+}
+
+func TestRangeErrorReturnsClampedValue(t *testing.T) {
+	// On ErrRange the result is the nearest limit, not zero - check err
+	// before using the value
+	n, err := strconv.ParseInt("300", 10, 8)
+	if !errors.Is(err, strconv.ErrRange) || n != math.MaxInt8 {
+		t.Errorf("ParseInt(\"300\", 10, 8) = %d, %v; want 127, ErrRange", n, err)
+	}
+}
+
+// 2. Bases and Bit Sizes
+// ======================
+
+func TestBases(t *testing.T) {
+	tests := []struct {
+		in   string
+		base int
+		want int64
+	}{
+		{"ff", 16, 255},
+		{"FF", 16, 255},
+		{"777", 8, 511},
+		{"101", 2, 5},
+		{"zz", 36, 1295},
+		// base 0 reads the prefix like a Go literal
+		{"0xff", 0, 255},
+		{"0o17", 0, 15},
+		{"017", 0, 15}, // leading 0 is octal!
+		{"0b101", 0, 5},
+		{"1_000_000", 0, 1000000},
+	}
+
+	for _, tt := range tests {
+		got, err := strconv.ParseInt(tt.in, tt.base, 64)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseInt(%q, %d) = %d, %v; want %d", tt.in, tt.base, got, err, tt.want)
+		}
+	}
+
+	// A prefix with an explicit base is a syntax error
+	if _, err := strconv.ParseInt("0xff", 16, 64); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("ParseInt(\"0xff\", 16) error = %v, want ErrSyntax", err)
+	}
+}
+
+func TestGenericIntUsesTypeBitSize(t *testing.T) {
+	if n, err := Int[int8]("127", 10); n != 127 || err != nil {
+		t.Errorf("Int[int8](\"127\") = %d, %v", n, err)
+	}
+	if _, err := Int[int8]("128", 10); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("Int[int8](\"128\") error = %v, want ErrRange", err)
+	}
+
+	// The bug Int prevents: parse at 64 bits, then convert, and 300
+	// silently becomes 44
+	n64, _ := strconv.ParseInt("300", 10, 64)
+	if int8(n64) != 44 {
+		t.Errorf("int8(300) = %d, want 44", int8(n64))
+	}
+
+	if n, err := Uint[uint16]("0xFFFF", 0); n != math.MaxUint16 || err != nil {
+		t.Errorf("Uint[uint16](\"0xFFFF\") = %d, %v", n, err)
+	}
+	if _, err := Uint[uint32]("-1", 10); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Uint[uint32](\"-1\") error = %v, want ErrSyntax", err)
+	}
+
+	type Celsius int16
+	if c, err := Int[Celsius]("-40", 10); c != -40 || err != nil {
+		t.Errorf("Int[Celsius](\"-40\") = %d, %v", c, err)
+	}
+}
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint16
+		wantErr error
+	}{
+		{"8080", 8080, nil},
+		{" 443 ", 443, nil},
+		{"65535", 65535, nil},
+		{"0", 0, strconv.ErrRange},
+		{"65536", 0, strconv.ErrRange},
+		{"http", 0, strconv.ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePort(tt.in)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParsePort(%q) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// 3. Floats
+// =========
+
+func TestParseFloat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"3.14", 3.14},
+		{"1e3", 1000},
+		{"-0.5", -0.5},
+		{"Inf", math.Inf(1)},
+		{"-infinity", math.Inf(-1)},
+		{"0x1p-2", 0.25}, // hex float: 1 * 2^-2
+		{"1,000.5", 0},   // no thousands separators
+	}
+
+	for _, tt := range tests {
+		got, err := strconv.ParseFloat(tt.in, 64)
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("ParseFloat(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFloat(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if f, err := strconv.ParseFloat("NaN", 64); !math.IsNaN(f) || err != nil {
+		t.Errorf("ParseFloat(\"NaN\") = %v, %v", f, err)
+	}
+
+	// Overflow is ErrRange with ±Inf as the value
+	if f, err := strconv.ParseFloat("1e400", 64); !errors.Is(err, strconv.ErrRange) || !math.IsInf(f, 1) {
+		t.Errorf("ParseFloat(\"1e400\") = %v, %v; want +Inf, ErrRange", f, err)
+	}
+}
+
+func TestFloatBitSize(t *testing.T) {
+	// bitSize 32 rounds to the nearest float32 but still returns float64
+	f, _ := strconv.ParseFloat("0.1", 32)
+	if f == 0.1 || float32(f) != 0.1 {
+		t.Errorf("ParseFloat(\"0.1\", 32) = %v; want float32(0.1) widened", f)
+	}
+
+	// Format with the same size to get the short form back
+	if got := strconv.FormatFloat(f, 'g', -1, 32); got != "0.1" {
+		t.Errorf("FormatFloat(32) = %q, want \"0.1\"", got)
+	}
+	if got := strconv.FormatFloat(f, 'g', -1, 64); got == "0.1" {
+		t.Errorf("FormatFloat(64) = %q; expected the float32 rounding error to show", got)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		fmt  byte
+		prec int
+		want string
+	}{
+		{'f', 2, "1234.57"},
+		{'e', 3, "1.235e+03"},
+		{'g', -1, "1234.5678"}, // -1: fewest digits that parse back exactly
+		{'g', 3, "1.23e+03"},
+	}
+	for _, tt := range tests {
+		if got := strconv.FormatFloat(1234.5678, tt.fmt, tt.prec, 64); got != tt.want {
+			t.Errorf("FormatFloat(%q, %d) = %q, want %q", tt.fmt, tt.prec, got, tt.want)
+		}
+	}
+}
+
+// 4. Quoting
+// ==========
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"Quote", strconv.Quote, "tab\there \"q\"", `"tab\there \"q\""`},
+		{"Quote keeps printable Unicode", strconv.Quote, "café", "\"café\""},
+		{"Quote escapes invalid bytes", strconv.Quote, "a\xffb", `"a\xffb"`},
+		{"QuoteToASCII", strconv.QuoteToASCII, "café \U0001F600", `"caf\u00e9 \U0001f600"`},
+	}
+	for _, tt := range tests {
+		if got := tt.fn(tt.in); got != tt.want {
+			t.Errorf("%s(%q) = %s, want %s", tt.name, tt.in, got, tt.want)
+		}
+	}
+
+	if got := strconv.QuoteRune('\n'); got != `'\n'` {
+		t.Errorf("QuoteRune('\\n') = %s", got)
+	}
+
+	// Unquote accepts all three Go literal forms
+	for _, lit := range []string{`"a\tb"`, "`a\tb`", `'a'`} {
+		if _, err := strconv.Unquote(lit); err != nil {
+			t.Errorf("Unquote(%s) error = %v", lit, err)
+		}
+	}
+	if _, err := strconv.Unquote(`"unterminated`); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Unquote of a bad literal error = %v, want ErrSyntax", err)
+	}
+}
+
+// 5. Byte Sizes
+// =============
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"64KiB", 64 << 10, false},
+		{"1.5 GiB", 3 << 29, false},
+		{"16EiB", 0, true}, // 2^64 overflows
+		{"0.3B", 0, true},  // not a whole number of bytes
+		{"12XB", 0, true},
+		{"-1KiB", 0, true},
+		{"KiB", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d, error %t", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// 6. Round-Trip Properties
+// ========================
+// A formatter and its parser should be inverses. Checking that for
+// random inputs finds the edge cases tables miss.
+
+func TestIntRoundTrip(t *testing.T) {
+	prop := func(n int64, b uint8) bool {
+		base := 2 + int(b)%35 // every base strconv supports: 2..36
+		got, err := strconv.ParseInt(strconv.FormatInt(n, base), base, 64)
+		return err == nil && got == n
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+
+	// quick rarely generates the limits, so check them explicitly
+	for _, n := range []int64{0, -1, math.MinInt64, math.MaxInt64} {
+		if !prop(n, 8) || !prop(n, 34) {
+			t.Errorf("round trip failed for %d", n)
+		}
+	}
+}
+
+func TestFloatRoundTrip(t *testing.T) {
+	// 'g' with precision -1 is the shortest string that parses back to
+	// exactly the same bits. Fixed precision like %.6f is lossy.
+	prop := func(bits uint64) bool {
+		f := math.Float64frombits(bits) // covers subnormals, Inf and NaN
+		got, err := strconv.ParseFloat(strconv.FormatFloat(f, 'g', -1, 64), 64)
+		if math.IsNaN(f) {
+			return err == nil && math.IsNaN(got)
+		}
+		return err == nil && math.Float64bits(got) == bits
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+
+	for _, f := range []float64{0, math.Copysign(0, -1), 0.1, math.SmallestNonzeroFloat64, math.MaxFloat64} {
+		if !prop(math.Float64bits(f)) {
+			t.Errorf("round trip failed for %v", f)
+		}
+	}
+
+	f := 0.1 + 0.2
+	if s := fmt.Sprintf("%.6f", f); s == strconv.FormatFloat(f, 'g', -1, 64) {
+		t.Errorf("%%.6f = %s; expected it to hide the rounding error", s)
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	// Holds for any bytes, including invalid UTF-8, because Quote
+	// escapes what it cannot print
+	prop := func(b []byte) bool {
+		s := string(b)
+		got, err := strconv.Unquote(strconv.Quote(s))
+		return err == nil && got == s
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Runes too, except surrogate halves and out-of-range values, which
+	// are not valid runes and quote as U+FFFD
+	runeProp := func(r rune) bool {
+		got, _, _, err := strconv.UnquoteChar(strconv.QuoteRune(r)[1:], '\'')
+		return err == nil && (got == r || !utf8.ValidRune(r))
+	}
+	if err := quick.Check(runeProp, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestByteSizeRoundTrip(t *testing.T) {
+	prop := func(n uint64, shift uint8) bool {
+		n >>= shift % 64 // spread values over every unit
+		got, err := ParseByteSize(FormatByteSize(n))
+		return err == nil && got == n
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+	for _, n := range []uint64{0, 1, 1024, 1 << 60, math.MaxUint64} {
+		if !prop(n, 0) {
+			t.Errorf("round trip failed for %d (%s)", n, FormatByteSize(n))
+		}
+	}
+}
+
+// 7. Examples
+// ===========
+
+func ExampleInt() {
+	for _, s := range []string{"100", "200", "0x7f", "x"} {
+		n, err := Int[int8](s, 0)
+		fmt.Println(n, err)
+	}
+	// Output:
+	// 100 <nil>
+	// 127 strconv.ParseInt: parsing "200": value out of range
+	// 127 <nil>
+	// 0 strconv.ParseInt: parsing "x": invalid syntax
+}
+
+func ExampleFormatByteSize() {
+	for _, n := range []uint64{0, 1536, 64 << 20, 1<<30 + 1} {
+		fmt.Println(FormatByteSize(n))
+	}
+	// Output:
+	// 0B
+	// 1536B
+	// 64MiB
+	// 1073741825B
+}
@@ -0,0 +1,282 @@
+package random
+
+import (
+	crand "crypto/rand"
+	"maps"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Random Numbers - Sources, Seeding and Sanity Checks
+// ===================================================
+// Run with:
+//
+//   cd primitives/random
+//   go test -v *.go
+//
+// The statistical tests use fixed seeds, so they are deterministic: a
+// seed that passes always passes. The thresholds are generous (about
+// the 99.9th percentile), so a correct generator is nowhere near them
+// and a biased one is far beyond.
+
+// chiSquareLimit is roughly the 99.9th percentile of the chi-squared
+// distribution with df degrees of freedom (Wilson-Hilferty approximation)
+func chiSquareLimit(df int) float64 {
+	k := float64(df)
+	z := 3.09 // 99.9% one-sided
+	return k * math.Pow(1-2/(9*k)+z*math.Sqrt(2/(9*k)), 3)
+}
+
+// 1. Sources and Seeding
+// ======================
+
+func TestSameSeedSameSequence(t *testing.T) {
+	a, b := New(42), New(42)
+	for i := range 100 {
+		if x, y := a.Uint64(), b.Uint64(); x != y {
+			t.Fatalf("step %d: %d != %d", i, x, y)
+		}
+	}
+
+	// Different seeds give unrelated sequences
+	if New(1).Uint64() == New(2).Uint64() {
+		t.Error("different seeds produced the same first value")
+	}
+}
+
+func TestChaCha8AndPCG(t *testing.T) {
+	// PCG is small and very fast. ChaCha8 is a cryptographically strong
+	// stream (it backs the top-level functions), slower but still fast,
+	// with a 32-byte seed. Both are deterministic for a fixed seed.
+	var seed [32]byte
+	copy(seed[:], "a fixed 32-byte seed for tests!!")
+	c1 := rand.New(rand.NewChaCha8(seed))
+	c2 := rand.New(rand.NewChaCha8(seed))
+	if c1.IntN(1000) != c2.IntN(1000) {
+		t.Error("ChaCha8 with the same seed diverged")
+	}
+
+	pcg := rand.NewPCG(1, 2)
+	first := pcg.Uint64()
+	pcg.Seed(1, 2) // reset to replay
+	if pcg.Uint64() != first {
+		t.Error("PCG.Seed did not restart the sequence")
+	}
+}
+
+func TestSeedingMisconceptions(t *testing.T) {
+	// Misconception 1: "call rand.Seed(time.Now().UnixNano()) first".
+	// v2 has no Seed; the top-level functions are already randomly
+	// seeded, differently on every run.
+	//
+	// Misconception 2: "re-seeding per call makes it more random". Two
+	// generators seeded in the same clock tick, or from the same ID,
+	// produce identical output - the seed is the only randomness.
+	for seed := range uint64(3) {
+		if New(seed).IntN(1_000_000) != New(seed).IntN(1_000_000) {
+			t.Error("same seed should mean same output")
+		}
+	}
+
+	// Misconception 3: "a seeded math/rand is fine for tokens". With
+	// the seed, or enough outputs, the sequence is fully predictable.
+	victim := New(12345)
+	attacker := New(12345) // guessed seed: a timestamp, a PID...
+	if victim.Uint64() != attacker.Uint64() {
+		t.Error("attacker should reproduce the victim's values")
+	}
+}
+
+// 2. Generating Values
+// ====================
+
+func TestRanges(t *testing.T) {
+	r := New(7)
+	for range 10_000 {
+		if n := r.IntN(6); n < 0 || n >= 6 {
+			t.Fatalf("IntN(6) = %d", n)
+		}
+		if f := r.Float64(); f < 0 || f >= 1 {
+			t.Fatalf("Float64() = %v", f)
+		}
+		// Dice: [1, 6] is IntN(6)+1; [lo, hi] is lo + IntN(hi-lo+1)
+		if d := 1 + r.IntN(6); d < 1 || d > 6 {
+			t.Fatalf("die = %d", d)
+		}
+		// Generic N works for any integer type, including durations
+		if n := rand.N[uint8](200); n >= 200 {
+			t.Fatalf("N[uint8](200) = %d", n)
+		}
+	}
+
+	// NormFloat64 and ExpFloat64 give other distributions
+	sum := 0.0
+	for range 10_000 {
+		sum += r.NormFloat64()*2 + 10 // mean 10, std dev 2
+	}
+	if mean := sum / 10_000; math.Abs(mean-10) > 0.1 {
+		t.Errorf("normal mean = %v, want about 10", mean)
+	}
+}
+
+func TestModuloBias(t *testing.T) {
+	// byte % 100 is biased: 256 = 2×100 + 56, so 0..55 have three
+	// preimages and 56..99 only two. IntN rejects the excess instead.
+	r := New(3)
+	biased := make([]int, 100)
+	fair := make([]int, 100)
+	for range 200_000 {
+		biased[byte(r.Uint32())%100]++
+		fair[r.IntN(100)]++
+	}
+	limit := chiSquareLimit(99)
+	if stat := ChiSquare(fair); stat > limit {
+		t.Errorf("IntN chi-square = %.1f, limit %.1f", stat, limit)
+	}
+	if stat := ChiSquare(biased); stat < 10*limit {
+		t.Errorf("modulo chi-square = %.1f, expected far above %.1f", stat, limit)
+	}
+}
+
+func TestPermAndShuffle(t *testing.T) {
+	r := New(11)
+	p := r.Perm(10)
+	sorted := slices.Sorted(slices.Values(p))
+	if !slices.Equal(sorted, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("Perm(10) = %v is not a permutation", p)
+	}
+
+	words := []string{"a", "b", "c", "d"}
+	r.Shuffle(len(words), func(i, j int) { words[i], words[j] = words[j], words[i] })
+	slices.Sort(words)
+	if strings.Join(words, "") != "abcd" {
+		t.Errorf("Shuffle lost elements: %v", words)
+	}
+}
+
+// 3. Statistical Sanity
+// =====================
+
+func TestIntNIsUniform(t *testing.T) {
+	r := New(99)
+	counts := make([]int, 10)
+	for range 100_000 {
+		counts[r.IntN(10)]++
+	}
+	if stat, limit := ChiSquare(counts), chiSquareLimit(9); stat > limit {
+		t.Errorf("chi-square = %.1f > %.1f, counts %v", stat, limit, counts)
+	}
+}
+
+func TestShuffleIsUniform(t *testing.T) {
+	// Every one of the 3! orders of three elements should be equally
+	// likely. The classic bug - swapping with r.IntN(n) instead of
+	// r.IntN(i+1) - makes some orders twice as common as others.
+	orderIndex := func(p []int) int { return p[0]*3 + p[1] }
+
+	fair, naive := map[int]int{}, map[int]int{}
+	r := New(5)
+	for range 60_000 {
+		p := []int{0, 1, 2}
+		r.Shuffle(3, func(i, j int) { p[i], p[j] = p[j], p[i] })
+		fair[orderIndex(p)]++
+
+		q := []int{0, 1, 2}
+		for i := range q {
+			j := r.IntN(len(q)) // wrong: 3^3 = 27 paths onto 6 orders
+			q[i], q[j] = q[j], q[i]
+		}
+		naive[orderIndex(q)]++
+	}
+
+	limit := chiSquareLimit(5)
+	if stat := ChiSquare(slices.Collect(maps.Values(fair))); len(fair) != 6 || stat > limit {
+		t.Errorf("Shuffle: %d orders, chi-square %.1f > %.1f", len(fair), stat, limit)
+	}
+	if stat := ChiSquare(slices.Collect(maps.Values(naive))); stat < 10*limit {
+		t.Errorf("naive shuffle chi-square %.1f, expected far above %.1f", stat, limit)
+	}
+}
+
+func TestWeightedIndex(t *testing.T) {
+	r := New(8)
+	weights := []float64{1, 0, 3, -2, 6} // expect 10%, 0, 30%, 0, 60%
+	counts := make([]int, len(weights))
+	for range 100_000 {
+		counts[WeightedIndex(r, weights)]++
+	}
+	if counts[1] != 0 || counts[3] != 0 {
+		t.Errorf("zero and negative weights were picked: %v", counts)
+	}
+	for i, want := range map[int]float64{0: 0.1, 2: 0.3, 4: 0.6} {
+		if got := float64(counts[i]) / 100_000; math.Abs(got-want) > 0.01 {
+			t.Errorf("index %d picked %.3f of the time, want %.1f", i, got, want)
+		}
+	}
+	if WeightedIndex(r, []float64{0, -1}) != -1 {
+		t.Error("no positive weight should return -1")
+	}
+}
+
+func TestSample(t *testing.T) {
+	r := New(21)
+	s := Sample(r, 100, 10)
+	if len(s) != 10 {
+		t.Fatalf("len = %d", len(s))
+	}
+	seen := map[int]bool{}
+	for _, x := range s {
+		if x < 0 || x >= 100 || seen[x] {
+			t.Fatalf("Sample = %v: out of range or repeated", s)
+		}
+		seen[x] = true
+	}
+
+	// Each element should be chosen with probability k/n
+	counts := make([]int, 20)
+	for range 20_000 {
+		for _, x := range Sample(r, 20, 5) {
+			counts[x]++
+		}
+	}
+	if stat, limit := ChiSquare(counts), chiSquareLimit(19); stat > limit {
+		t.Errorf("Sample chi-square = %.1f > %.1f", stat, limit)
+	}
+}
+
+// 4. crypto/rand
+// ==============
+
+func TestToken(t *testing.T) {
+	seen := map[string]bool{}
+	for range 1000 {
+		tok := Token()
+		if len(tok) != 26 || seen[tok] {
+			t.Fatalf("Token() = %q: wrong length or repeated", tok)
+		}
+		seen[tok] = true
+	}
+}
+
+func TestSecureIntN(t *testing.T) {
+	counts := make([]int, 6)
+	for range 6000 {
+		counts[SecureIntN(6)]++
+	}
+	// Not seeded, so this cannot be deterministic; the limit is loose
+	// enough that a false failure is a one-in-a-million event
+	if stat := ChiSquare(counts); stat > 40 {
+		t.Errorf("SecureIntN chi-square = %.1f, counts %v", stat, counts)
+	}
+
+	// crypto/rand.Int is the big.Int equivalent, for ranges beyond int64
+	limit := new(big.Int).Lsh(big.NewInt(1), 100)
+	n, err := crand.Int(crand.Reader, limit)
+	if err != nil || n.Cmp(limit) >= 0 || n.Sign() < 0 {
+		t.Errorf("crypto/rand.Int = %v, %v", n, err)
+	}
+}
@@ -0,0 +1,156 @@
+package safeint
+
+import (
+	"errors"
+	"math/bits"
+	"unsafe"
+)
+
+// Safe Integers - Checked and Saturating Arithmetic
+// =================================================
+// Go integer arithmetic wraps on overflow: int8(127)+1 is -128 and
+// uint8(0)-1 is 255, with no panic and no error. That is right for
+// hashes and checksums and wrong for sizes, counters, money and indexes,
+// where a wrapped value is silently corrupt data.
+//
+// The Checked functions return ErrOverflow instead of a wrapped result.
+// The Saturating functions clamp to the type's minimum or maximum, which
+// suits metrics and rate limiters that should stick at the limit.
+
+// ErrOverflow is returned when a result does not fit in the type
+var ErrOverflow = errors.New("integer overflow")
+
+// Integer is the set of all integer types
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// AddChecked returns a+b, or ErrOverflow if the sum does not fit in T
+func AddChecked[T Integer](a, b T) (T, error) {
+	sum := a + b
+	if signed[T]() {
+		// Overflow is only possible when both operands have the same
+		// sign, and shows up as a result with the other sign
+		if (b > 0 && sum < a) || (b < 0 && sum > a) {
+			return 0, ErrOverflow
+		}
+		return sum, nil
+	}
+	// The carry out of the top bit is the unsigned overflow flag. For
+	// types narrower than 64 bits the sum fits in a uint64, so check it
+	// against T's maximum as well.
+	s, carry := bits.Add64(uint64(a), uint64(b), 0)
+	if carry != 0 || s > uint64(maxOf[T]()) {
+		return 0, ErrOverflow
+	}
+	return sum, nil
+}
+
+// SubChecked returns a-b, or ErrOverflow if the difference does not fit
+// in T
+func SubChecked[T Integer](a, b T) (T, error) {
+	diff := a - b
+	if signed[T]() {
+		if (b > 0 && diff > a) || (b < 0 && diff < a) {
+			return 0, ErrOverflow
+		}
+		return diff, nil
+	}
+	if _, borrow := bits.Sub64(uint64(a), uint64(b), 0); borrow != 0 {
+		return 0, ErrOverflow
+	}
+	return diff, nil
+}
+
+// MulChecked returns a*b, or ErrOverflow if the product does not fit in T
+func MulChecked[T Integer](a, b T) (T, error) {
+	// bits.Mul64 returns the full 128-bit product of the magnitudes, so
+	// overflow is visible in the high word (or above T's limit)
+	limit := uint64(maxOf[T]())
+	negative := (a < 0) != (b < 0)
+	if negative {
+		limit++ // the negative range is one larger: -128..127
+	}
+	hi, lo := bits.Mul64(magnitude(a), magnitude(b))
+	if hi != 0 || lo > limit {
+		return 0, ErrOverflow
+	}
+	return a * b, nil
+}
+
+// AddSaturating returns a+b clamped to T's range
+func AddSaturating[T Integer](a, b T) T {
+	sum, err := AddChecked(a, b)
+	if err == nil {
+		return sum
+	}
+	if b < 0 {
+		return minOf[T]()
+	}
+	return maxOf[T]()
+}
+
+// SubSaturating returns a-b clamped to T's range
+func SubSaturating[T Integer](a, b T) T {
+	diff, err := SubChecked(a, b)
+	if err == nil {
+		return diff
+	}
+	if b > 0 {
+		return minOf[T]()
+	}
+	return maxOf[T]()
+}
+
+// MulSaturating returns a*b clamped to T's range
+func MulSaturating[T Integer](a, b T) T {
+	product, err := MulChecked(a, b)
+	if err == nil {
+		return product
+	}
+	if (a < 0) != (b < 0) {
+		return minOf[T]()
+	}
+	return maxOf[T]()
+}
+
+// Convert converts v to To, or returns ErrOverflow if the value changes.
+// Plain conversions like int32(n) keep the low bits and drop the rest.
+func Convert[To, From Integer](v From) (To, error) {
+	out := To(v)
+	if From(out) != v || (out < 0) != (v < 0) {
+		return 0, ErrOverflow
+	}
+	return out, nil
+}
+
+// signed reports whether T is a signed type: ^0 is -1 for signed
+// types and the maximum value for unsigned ones
+func signed[T Integer]() bool {
+	return ^T(0) < 0
+}
+
+func maxOf[T Integer]() T {
+	if signed[T]() {
+		var zero T
+		return T(uint64(1)<<(unsafe.Sizeof(zero)*8-1) - 1)
+	}
+	return ^T(0)
+}
+
+func minOf[T Integer]() T {
+	if signed[T]() {
+		return -maxOf[T]() - 1
+	}
+	return 0
+}
+
+// magnitude returns |v| as a uint64. For the minimum signed value, whose
+// negation overflows, the two's complement bit pattern is already right.
+func magnitude[T Integer](v T) uint64 {
+	if v < 0 {
+		return uint64(-int64(v))
+	}
+	return uint64(v)
+}
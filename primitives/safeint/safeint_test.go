@@ -0,0 +1,267 @@
+package safeint
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// Integer Overflow - Wrapping, Checking and Saturating
+// ====================================================
+// Run with:
+//
+//   cd primitives/safeint
+//   go test -v *.go
+
+// 1. Silent Overflow
+// ==================
+
+func TestSilentOverflow(t *testing.T) {
+	// Variables, not constants: the compiler rejects constant overflow
+	// like int8(127) + 1, but run-time arithmetic just wraps
+	var i8 int8 = math.MaxInt8
+	i8++
+	if i8 != math.MinInt8 {
+		t.Errorf("int8 127+1 = %d, want -128", i8)
+	}
+
+	var u8 uint8 = 0
+	u8--
+	if u8 != math.MaxUint8 {
+		t.Errorf("uint8 0-1 = %d, want 255", u8)
+	}
+
+	// A realistic bug: a byte count in int32 passes 2 GiB
+	var size int32 = 1 << 30
+	size *= 2
+	if size != math.MinInt32 {
+		t.Errorf("int32 2^30*2 = %d, want %d", size, math.MinInt32)
+	}
+
+	// Negating the minimum value gives the minimum value back
+	var lowest int32 = math.MinInt32
+	if -lowest != lowest {
+		t.Errorf("-MinInt32 = %d", -lowest)
+	}
+
+	// Narrowing conversions keep the low bits
+	n := 300
+	if int8(n) != 44 || uint8(n) != 44 {
+		t.Errorf("int8(300) = %d, uint8(300) = %d, want 44", int8(n), uint8(n))
+	}
+}
+
+func TestLengthTimesSizeBug(t *testing.T) {
+	// count*size computed in uint32 wraps to a small number, so an
+	// allocation guarded by it is far too small for the data
+	var count, size uint32 = 1 << 20, 1 << 13
+	if count*size != 0 {
+		t.Errorf("uint32 2^20*2^13 = %d, want 0 (wrapped)", count*size)
+	}
+	if _, err := MulChecked(count, size); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MulChecked(2^20, 2^13) error = %v, want ErrOverflow", err)
+	}
+}
+
+// 2. Checked Arithmetic
+// =====================
+
+func TestAddChecked(t *testing.T) {
+	tests := []struct {
+		a, b    int32
+		want    int32
+		wantErr bool
+	}{
+		{1, 2, 3, false},
+		{math.MaxInt32, 0, math.MaxInt32, false},
+		{math.MaxInt32, 1, 0, true},
+		{math.MinInt32, -1, 0, true},
+		{math.MaxInt32, math.MinInt32, -1, false}, // mixed signs never overflow
+		{-5, 3, -2, false},
+	}
+	for _, tt := range tests {
+		got, err := AddChecked(tt.a, tt.b)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("AddChecked(%d, %d) = %d, %v", tt.a, tt.b, got, err)
+		}
+	}
+
+	if _, err := AddChecked[uint64](math.MaxUint64, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("AddChecked[uint64](Max, 1) error = %v", err)
+	}
+	if got, err := AddChecked[uint16](65000, 535); got != math.MaxUint16 || err != nil {
+		t.Errorf("AddChecked[uint16](65000, 535) = %d, %v", got, err)
+	}
+}
+
+func TestSubChecked(t *testing.T) {
+	if _, err := SubChecked[uint](0, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("SubChecked[uint](0, 1) error = %v, want ErrOverflow", err)
+	}
+	if _, err := SubChecked[int64](math.MinInt64, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("SubChecked(MinInt64, 1) error = %v, want ErrOverflow", err)
+	}
+	if _, err := SubChecked[int8](0, math.MinInt8); !errors.Is(err, ErrOverflow) {
+		t.Errorf("SubChecked[int8](0, -128) error = %v, want ErrOverflow", err)
+	}
+	if got, err := SubChecked[int8](-1, math.MinInt8); got != 127 || err != nil {
+		t.Errorf("SubChecked[int8](-1, -128) = %d, %v", got, err)
+	}
+}
+
+func TestMulChecked(t *testing.T) {
+	tests := []struct {
+		a, b    int64
+		want    int64
+		wantErr bool
+	}{
+		{6, 7, 42, false},
+		{0, math.MinInt64, 0, false},
+		{-1, math.MaxInt64, -math.MaxInt64, false},
+		{-1, math.MinInt64, 0, true}, // -MinInt64 does not fit
+		{math.MinInt64, 1, math.MinInt64, false},
+		{1 << 32, 1 << 31, 0, true},
+		{-(1 << 32), 1 << 31, math.MinInt64, false}, // exactly the minimum
+		{math.MaxInt64, 2, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := MulChecked(tt.a, tt.b)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("MulChecked(%d, %d) = %d, %v", tt.a, tt.b, got, err)
+		}
+	}
+
+	if _, err := MulChecked[uint64](1<<32, 1<<32); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MulChecked[uint64](2^32, 2^32) error = %v", err)
+	}
+}
+
+// 3. Saturating Arithmetic
+// ========================
+
+func TestSaturating(t *testing.T) {
+	if got := AddSaturating[int8](100, 100); got != math.MaxInt8 {
+		t.Errorf("AddSaturating[int8](100, 100) = %d", got)
+	}
+	if got := AddSaturating[int8](-100, -100); got != math.MinInt8 {
+		t.Errorf("AddSaturating[int8](-100, -100) = %d", got)
+	}
+	if got := SubSaturating[uint32](3, 5); got != 0 {
+		t.Errorf("SubSaturating[uint32](3, 5) = %d", got)
+	}
+	if got := SubSaturating[int16](math.MaxInt16, -1); got != math.MaxInt16 {
+		t.Errorf("SubSaturating[int16](Max, -1) = %d", got)
+	}
+	if got := MulSaturating[int32](-70000, 70000); got != math.MinInt32 {
+		t.Errorf("MulSaturating[int32](-70000, 70000) = %d", got)
+	}
+	if got := MulSaturating[uint8](16, 16); got != math.MaxUint8 {
+		t.Errorf("MulSaturating[uint8](16, 16) = %d", got)
+	}
+
+	// A counter that sticks at the limit instead of wrapping to zero
+	type Hits uint16
+	var h Hits = math.MaxUint16 - 1
+	for i := 0; i < 5; i++ {
+		h = AddSaturating(h, 1)
+	}
+	if h != math.MaxUint16 {
+		t.Errorf("saturating counter = %d", h)
+	}
+}
+
+// 4. Conversions
+// ==============
+
+func TestConvert(t *testing.T) {
+	if got, err := Convert[int8](int64(-128)); got != -128 || err != nil {
+		t.Errorf("Convert[int8](-128) = %d, %v", got, err)
+	}
+	if _, err := Convert[int8](300); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Convert[int8](300) error = %v", err)
+	}
+	if _, err := Convert[uint](-1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Convert[uint](-1) error = %v", err)
+	}
+	if _, err := Convert[int64](uint64(math.MaxUint64)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Convert[int64](MaxUint64) error = %v", err)
+	}
+	if got, err := Convert[uint64](int64(math.MaxInt64)); got != math.MaxInt64 || err != nil {
+		t.Errorf("Convert[uint64](MaxInt64) = %d, %v", got, err)
+	}
+}
+
+// 5. Exhaustive Checks
+// ====================
+// Every 8-bit pair is only 65,536 cases, so compare against arithmetic
+// in int, which cannot overflow for these inputs
+
+func TestExhaustiveInt8(t *testing.T) {
+	ops := []struct {
+		name    string
+		checked func(a, b int8) (int8, error)
+		sat     func(a, b int8) int8
+		exact   func(a, b int) int
+	}{
+		{"add", AddChecked[int8], AddSaturating[int8], func(a, b int) int { return a + b }},
+		{"sub", SubChecked[int8], SubSaturating[int8], func(a, b int) int { return a - b }},
+		{"mul", MulChecked[int8], MulSaturating[int8], func(a, b int) int { return a * b }},
+	}
+	for _, op := range ops {
+		for a := math.MinInt8; a <= math.MaxInt8; a++ {
+			for b := math.MinInt8; b <= math.MaxInt8; b++ {
+				want := op.exact(a, b)
+				got, err := op.checked(int8(a), int8(b))
+				fits := want >= math.MinInt8 && want <= math.MaxInt8
+				if fits != (err == nil) || (fits && int(got) != want) {
+					t.Fatalf("%s(%d, %d) = %d, %v; exact %d", op.name, a, b, got, err, want)
+				}
+				if s := int(op.sat(int8(a), int8(b))); s != min(max(want, math.MinInt8), math.MaxInt8) {
+					t.Fatalf("saturating %s(%d, %d) = %d; exact %d", op.name, a, b, s, want)
+				}
+			}
+		}
+	}
+}
+
+func TestExhaustiveUint8(t *testing.T) {
+	for a := 0; a <= math.MaxUint8; a++ {
+		for b := 0; b <= math.MaxUint8; b++ {
+			_, addErr := AddChecked(uint8(a), uint8(b))
+			_, subErr := SubChecked(uint8(a), uint8(b))
+			_, mulErr := MulChecked(uint8(a), uint8(b))
+			if (a+b > math.MaxUint8) != (addErr != nil) ||
+				(a-b < 0) != (subErr != nil) ||
+				(a*b > math.MaxUint8) != (mulErr != nil) {
+				t.Fatalf("uint8 %d, %d: add %v, sub %v, mul %v", a, b, addErr, subErr, mulErr)
+			}
+		}
+	}
+}
+
+// 6. Examples
+// ===========
+
+func ExampleAddChecked() {
+	var balance int32 = math.MaxInt32 - 10
+	if _, err := AddChecked(balance, 20); err != nil {
+		fmt.Println("deposit rejected:", err)
+	}
+	fmt.Println("wrapped:", balance+20)
+	// Output:
+	// deposit rejected: integer overflow
+	// wrapped: -2147483639
+}
+
+func ExampleAddSaturating() {
+	var level uint8 = 250
+	for i := 0; i < 3; i++ {
+		level = AddSaturating(level, 4)
+		fmt.Println(level)
+	}
+	// Output:
+	// 254
+	// 255
+	// 255
+}
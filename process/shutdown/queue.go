@@ -0,0 +1,95 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A Worker: the Job Queue
+// =======================
+// Handlers Submit jobs and return at once; the queue runs them in the
+// background. It is the consumer that Run drains after the HTTP server
+// - the producer - has stopped.
+
+// ErrClosed is returned by Submit once the queue is draining
+var ErrClosed = errors.New("shutdown: queue closed")
+
+// Job is a unit of background work. ctx is cancelled only if shutdown
+// runs out of time; a job should finish or give up when it is.
+type Job func(ctx context.Context)
+
+// Queue runs submitted jobs on a fixed number of goroutines
+type Queue struct {
+	Concurrency int // default 1
+
+	mu     sync.RWMutex // held for reading by Submit, writing by Drain
+	jobs   chan Job
+	closed bool
+}
+
+// NewQueue returns a queue holding up to size jobs that are not yet
+// running
+func NewQueue(size, concurrency int) *Queue {
+	return &Queue{Concurrency: max(concurrency, 1), jobs: make(chan Job, size)}
+}
+
+// Submit queues j, or returns ErrClosed if the queue is draining. It
+// blocks while the queue is full, until ctx is done.
+//
+// Sending on a closed channel panics, so the closed check and the send
+// must not be split by a Drain. Submits share the read lock, so they do
+// not wait for each other; Drain takes the write lock, so it waits for
+// Submits already sending - the workers are still consuming, so they
+// get through - and every Submit after it sees closed.
+func (q *Queue) Submit(ctx context.Context, j Job) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrClosed
+	}
+	select {
+	case q.jobs <- j:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run starts Concurrency goroutines taking jobs until the queue is
+// drained and empty, or ctx is cancelled. Jobs still queued when ctx is
+// cancelled are dropped - that is what running out of grace costs.
+func (q *Queue) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for range q.Concurrency {
+		wg.Go(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j, ok := <-q.jobs:
+					if !ok {
+						return // drained and empty
+					}
+					j(ctx)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return nil
+}
+
+// Drain closes the queue to new jobs. Run finishes the queued ones and
+// returns. Drain waits only for Submits already in progress.
+func (q *Queue) Drain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+}
+
+// Len reports how many jobs are waiting
+func (q *Queue) Len() int { return len(q.jobs) }
@@ -0,0 +1,188 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Graceful Shutdown of a Whole Process
+// ====================================
+// web/server.Run shuts one HTTP server down. A real service has more:
+// a server taking requests, workers processing what the requests
+// queued, and an orchestrator - systemd, Kubernetes, a developer at a
+// terminal - that sends SIGTERM or SIGINT and, after a while, SIGKILL.
+//
+// The sequence Run follows:
+//
+//	signal.NotifyContext   SIGTERM/SIGINT cancel a context instead of
+//	                       killing the process
+//	1. stop producers      srv.Shutdown: no new requests, the ones in
+//	                       flight finish - they may still queue work
+//	2. drain consumers     Drain each worker: take nothing new, finish
+//	                       what is queued, return
+//	3. bound it all        one deadline, Grace, for both steps; past it
+//	                       the workers' context is cancelled and Run
+//	                       returns ErrTimeout
+//	4. the impatient       a second signal during shutdown exits at once
+//
+// The order is the point. Stopping the workers first would strand the
+// jobs that in-flight requests are about to queue: shut down in the
+// reverse order of the data flow.
+//
+// A detail about signals: once signal.Notify or NotifyContext has a
+// signal, its default action - terminate the process - is off until
+// the registration is stopped. Run keeps SIGTERM and SIGINT registered
+// until it returns, so a signal never kills the process mid-drain
+// except through step 4, which exits deliberately.
+
+var (
+	// ErrTimeout means shutdown took longer than Grace. Work may have
+	// been lost.
+	ErrTimeout = errors.New("shutdown: grace period exceeded")
+
+	// ErrForced means a second signal arrived during shutdown
+	ErrForced = errors.New("shutdown: forced by a second signal")
+)
+
+// Worker is a background loop that Run starts and stops
+type Worker interface {
+	// Run works until Drain has been called and its work is done, or
+	// until ctx is cancelled - which means stop now, and happens only
+	// when the grace period runs out
+	Run(ctx context.Context) error
+
+	// Drain asks Run to finish what it has and return. It must not
+	// block.
+	Drain()
+}
+
+// Service is an HTTP server and the workers behind it
+type Service struct {
+	Server   *http.Server
+	Listener net.Listener
+	Workers  []Worker
+
+	// Grace bounds the whole shutdown, HTTP and workers together.
+	// Orchestrators send SIGKILL after their own grace period - 30s in
+	// Kubernetes by default - so keep it below theirs.
+	Grace time.Duration
+
+	// Signals start the shutdown; the default is SIGINT and SIGTERM
+	Signals []os.Signal
+
+	Logger *slog.Logger
+
+	// Exit is called on a forced shutdown; the default is os.Exit.
+	// Tests replace it, and Run then returns ErrForced.
+	Exit func(code int)
+}
+
+// Run serves until a signal arrives, ctx is cancelled, or the server or
+// a worker fails, then shuts everything down in order. It returns nil
+// after a clean shutdown; otherwise the error that started it, joined
+// with ErrTimeout or ErrForced if the shutdown itself went wrong.
+func (s *Service) Run(ctx context.Context) error {
+	log := s.Logger
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	signals := s.Signals
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	sigCtx, stop := signal.NotifyContext(ctx, signals...)
+	defer stop()
+
+	// The workers' context outlives the signal: it is cancelled only
+	// when the grace period runs out
+	workCtx, stopWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopWork()
+
+	failed := make(chan error, 1+len(s.Workers))
+	go func() {
+		if err := s.Server.Serve(s.Listener); !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("server: %w", err)
+		}
+	}()
+	var workers sync.WaitGroup
+	for i, w := range s.Workers {
+		workers.Go(func() {
+			if err := w.Run(workCtx); err != nil {
+				failed <- fmt.Errorf("worker %d: %w", i, err)
+			}
+		})
+	}
+	log.Info("serving", "addr", s.Listener.Addr().String(), "workers", len(s.Workers))
+
+	// Wait for a reason to stop
+	var cause error
+	select {
+	case <-sigCtx.Done():
+	case cause = <-failed:
+	}
+	// From here a signal forces the exit. Registering only now means
+	// the signal that started the shutdown cannot be mistaken for a
+	// second one; NotifyContext's registration is still in place, so a
+	// signal in between is swallowed, not fatal.
+	force := make(chan os.Signal, 1)
+	signal.Notify(force, signals...)
+	defer signal.Stop(force)
+	if cause != nil {
+		log.Error("shutting down", "cause", cause)
+	} else {
+		log.Info("shutting down", "cause", context.Cause(sigCtx))
+	}
+
+	deadline, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.Grace)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		start := time.Now()
+		// 1. Producers: stop taking requests, let in-flight ones finish
+		err := s.Server.Shutdown(deadline)
+		log.Info("http drained", "took", time.Since(start).Round(time.Millisecond), "err", err)
+		// 2. Consumers: finish what the requests queued
+		for _, w := range s.Workers {
+			w.Drain()
+		}
+		workers.Wait()
+		log.Info("workers drained", "took", time.Since(start).Round(time.Millisecond))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return errors.Join(cause, err)
+		}
+		return cause
+	case <-deadline.Done():
+		// 3. Out of time: stop the workers, cut the connections
+		log.Error("grace period exceeded; stopping now", "grace", s.Grace)
+		stopWork()
+		s.Server.Close()
+		return errors.Join(cause, ErrTimeout)
+	case sig := <-force:
+		// 4. Asked twice
+		log.Error("forced exit", "signal", sig)
+		stopWork()
+		s.Server.Close()
+		exit := s.Exit
+		if exit == nil {
+			exit = os.Exit
+		}
+		exit(1)
+		return errors.Join(cause, ErrForced)
+	}
+}
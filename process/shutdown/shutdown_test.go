@@ -0,0 +1,530 @@
+package shutdown
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Graceful Shutdown - Tests
+// =========================
+// Run with:
+//
+//   cd process/shutdown
+//   go test -v *.go
+//
+// The tests send real signals to their own process with syscall.Kill.
+// That is safe only while a handler is registered - an unhandled
+// SIGTERM kills the test binary - so every test waits until the service
+// answers a request, which happens after Run has registered, and never
+// signals after Run has returned. Signals are process-wide, so none of
+// these tests are parallel.
+//
+// The last test runs the service in a child process (this binary
+// again, see TestMain) to check what only a real process can show: the
+// exit code, including the forced os.Exit.
+
+// 1. Test Helpers
+// ===============
+
+// syncBuffer is a bytes.Buffer for a logger written from several
+// goroutines and read by the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type harness struct {
+	svc   *Service
+	queue *Queue
+	logs  *syncBuffer
+	url   string
+	done  chan error // Run's result
+
+	mu  sync.Mutex
+	ran []string // jobs that finished, in order
+}
+
+// start runs a Service with a queue and these routes:
+//
+//	/health       200
+//	/job?name=x   queues a job that takes 50ms
+//	/slow?name=x  waits for release, then queues a job
+//	/stuck        queues a job that only stops when cancelled
+func start(t *testing.T, grace time.Duration, release <-chan struct{}) *harness {
+	t.Helper()
+	h := &harness{queue: NewQueue(16, 2), logs: &syncBuffer{}, done: make(chan error, 1)}
+	job := func(name string, d time.Duration) Job {
+		return func(ctx context.Context) {
+			select {
+			case <-time.After(d):
+				h.mu.Lock()
+				h.ran = append(h.ran, name)
+				h.mu.Unlock()
+			case <-ctx.Done():
+			}
+		}
+	}
+	submit := func(w http.ResponseWriter, r *http.Request, j Job) {
+		if err := h.queue.Submit(r.Context(), j); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /job", func(w http.ResponseWriter, r *http.Request) {
+		submit(w, r, job(r.FormValue("name"), 50*time.Millisecond))
+	})
+	mux.HandleFunc("POST /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		submit(w, r, job(r.FormValue("name"), 50*time.Millisecond))
+	})
+	mux.HandleFunc("POST /stuck", func(w http.ResponseWriter, r *http.Request) {
+		submit(w, r, job("stuck", time.Hour))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.url = "http://" + ln.Addr().String()
+	h.svc = &Service{
+		Server:   &http.Server{Handler: mux},
+		Listener: ln,
+		Workers:  []Worker{h.queue},
+		Grace:    grace,
+		Logger:   slog.New(slog.NewTextHandler(h.logs, nil)),
+	}
+	return h
+}
+
+// run starts Run and returns once the service answers, which proves the
+// signal handlers are registered
+func (h *harness) run(t *testing.T, ctx context.Context) {
+	t.Helper()
+	go func() { h.done <- h.svc.Run(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(h.url + "/health")
+		if err == nil {
+			resp.Body.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("service did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (h *harness) post(path string) (int, error) {
+	resp, err := http.Post(h.url+path, "", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// wait returns Run's error, failing the test if it takes too long
+func (h *harness) wait(t *testing.T, limit time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-h.done:
+		return err
+	case <-time.After(limit):
+		t.Fatalf("Run did not return within %v; logs:\n%s", limit, h.logs)
+		return nil
+	}
+}
+
+// waitLog waits for a line in the logs
+func (h *harness) waitLog(t *testing.T, substr string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if strings.Contains(h.logs.String(), substr) {
+			return
+		}
+	}
+	t.Fatalf("no %q in logs:\n%s", substr, h.logs)
+}
+
+func kill(t *testing.T, sig syscall.Signal) {
+	t.Helper()
+	if err := syscall.Kill(os.Getpid(), sig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 2. Shutdown on a Signal
+// =======================
+
+// SIGTERM during a request: the request finishes and its job runs,
+// new connections are refused, and the HTTP server drains before the
+// workers do
+func TestSignalDrainsInOrder(t *testing.T) {
+	release := make(chan struct{})
+	h := start(t, 10*time.Second, release)
+	h.run(t, context.Background())
+
+	for _, name := range []string{"a", "b", "c"} {
+		if code, err := h.post("/job?name=" + name); err != nil || code != http.StatusAccepted {
+			t.Fatalf("job %s: %d, %v", name, code, err)
+		}
+	}
+	slow := make(chan int, 1)
+	go func() {
+		code, _ := h.post("/slow?name=late")
+		slow <- code
+	}()
+	time.Sleep(50 * time.Millisecond) // let /slow arrive
+
+	kill(t, syscall.SIGTERM)
+	h.waitLog(t, "shutting down")
+
+	if _, err := net.DialTimeout("tcp", strings.TrimPrefix(h.url, "http://"), time.Second); err == nil {
+		t.Error("new connection accepted during shutdown")
+	}
+
+	// The in-flight request can still queue work: the workers have not
+	// been drained yet
+	close(release)
+	if code := <-slow; code != http.StatusAccepted {
+		t.Errorf("in-flight request got %d", code)
+	}
+	if err := h.wait(t, 5*time.Second); err != nil {
+		t.Errorf("Run = %v", err)
+	}
+
+	// late was queued after the signal, since release closed only then,
+	// but with two workers it can still finish before c
+	ran := slices.Sorted(slices.Values(h.ran))
+	if want := []string{"a", "b", "c", "late"}; !slices.Equal(ran, want) {
+		t.Errorf("jobs run: %q, want %q", ran, want)
+	}
+	logs := h.logs.String()
+	if !strings.Contains(logs, `cause="terminated signal received"`) {
+		t.Errorf("cause not logged:\n%s", logs)
+	}
+	if strings.Index(logs, "http drained") > strings.Index(logs, "workers drained") {
+		t.Errorf("workers drained before http:\n%s", logs)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	h := start(t, 5*time.Second, nil)
+	h.run(t, context.Background())
+	kill(t, syscall.SIGINT)
+	if err := h.wait(t, 5*time.Second); err != nil {
+		t.Errorf("Run = %v", err)
+	}
+	if !strings.Contains(h.logs.String(), "interrupt signal received") {
+		t.Errorf("logs:\n%s", h.logs)
+	}
+}
+
+// Cancelling the parent context works like a signal: the way to stop a
+// service embedded in a larger program, or in a test
+func TestContextCancel(t *testing.T) {
+	h := start(t, 5*time.Second, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	h.run(t, ctx)
+	h.post("/job?name=queued")
+	cancel()
+	if err := h.wait(t, 5*time.Second); err != nil {
+		t.Errorf("Run = %v", err)
+	}
+	if len(h.ran) != 1 {
+		t.Errorf("queued job dropped: %q", h.ran)
+	}
+}
+
+// 3. Shutdown on a Failure
+// ========================
+
+type failingWorker struct{ err error }
+
+func (w failingWorker) Run(ctx context.Context) error { return w.err }
+func (w failingWorker) Drain()                        {}
+
+func TestWorkerFailure(t *testing.T) {
+	h := start(t, 5*time.Second, nil)
+	boom := errors.New("boom")
+	h.svc.Workers = append(h.svc.Workers, failingWorker{boom})
+	go func() { h.done <- h.svc.Run(context.Background()) }()
+
+	err := h.wait(t, 5*time.Second)
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "worker 1") {
+		t.Errorf("Run = %v", err)
+	}
+}
+
+func TestServeFailure(t *testing.T) {
+	h := start(t, 5*time.Second, nil)
+	h.svc.Listener.Close()
+	go func() { h.done <- h.svc.Run(context.Background()) }()
+
+	err := h.wait(t, 5*time.Second)
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Run = %v, want net.ErrClosed", err)
+	}
+}
+
+// 4. Time Limits
+// ==============
+
+// A job that ignores the drain holds shutdown until the grace runs
+// out; then its context is cancelled and Run reports the timeout
+func TestGraceExceeded(t *testing.T) {
+	h := start(t, 200*time.Millisecond, nil)
+	h.run(t, context.Background())
+	h.post("/stuck")
+
+	began := time.Now()
+	kill(t, syscall.SIGTERM)
+	err := h.wait(t, 5*time.Second)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Run = %v, want ErrTimeout", err)
+	}
+	if took := time.Since(began); took < 200*time.Millisecond {
+		t.Errorf("gave up after %v", took)
+	}
+	h.waitLog(t, "grace period exceeded")
+}
+
+// Ctrl-C twice: the second signal exits without waiting for the grace
+func TestSecondSignalForces(t *testing.T) {
+	h := start(t, time.Minute, nil)
+	var code atomic.Int32
+	code.Store(-1)
+	h.svc.Exit = func(c int) { code.Store(int32(c)) }
+	h.run(t, context.Background())
+	h.post("/stuck")
+
+	kill(t, syscall.SIGINT)
+	h.waitLog(t, "shutting down")
+	kill(t, syscall.SIGINT)
+
+	if err := h.wait(t, 5*time.Second); !errors.Is(err, ErrForced) {
+		t.Errorf("Run = %v, want ErrForced", err)
+	}
+	if code.Load() != 1 {
+		t.Errorf("Exit(%d), want Exit(1)", code.Load())
+	}
+}
+
+// 5. The Queue
+// ============
+
+func TestQueueDrain(t *testing.T) {
+	q := NewQueue(8, 2)
+	var n atomic.Int32
+	for range 5 {
+		q.Submit(context.Background(), func(context.Context) { n.Add(1) })
+	}
+	done := make(chan error)
+	go func() { done <- q.Run(context.Background()) }()
+
+	q.Drain()
+	q.Drain() // twice is fine
+	<-done
+	if n.Load() != 5 {
+		t.Errorf("ran %d jobs, want 5", n.Load())
+	}
+	if err := q.Submit(context.Background(), func(context.Context) {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Drain = %v", err)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q := NewQueue(1, 1)
+	q.Submit(context.Background(), func(context.Context) {})
+
+	// Nothing is running the queue, so the second Submit waits
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Submit(ctx, func(context.Context) {}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit to a full queue = %v", err)
+	}
+}
+
+// Submit racing Drain must never send on the closed channel. Run with
+// -race.
+func TestQueueSubmitDuringDrain(t *testing.T) {
+	for range 50 {
+		q := NewQueue(4, 2)
+		go q.Run(context.Background())
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				for {
+					if err := q.Submit(context.Background(), func(context.Context) {}); err != nil {
+						return
+					}
+				}
+			})
+		}
+		time.Sleep(time.Millisecond)
+		q.Drain()
+		wg.Wait()
+	}
+}
+
+// 6. A Real Process
+// =================
+
+const childEnv = "SHUTDOWN_CHILD"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(childEnv) != "" {
+		os.Exit(child())
+	}
+	os.Exit(m.Run())
+}
+
+// child runs a service with a stuck job the way main would, and maps
+// Run's result to an exit code
+func child() int {
+	q := NewQueue(4, 1)
+	q.Submit(context.Background(), func(ctx context.Context) { <-ctx.Done() })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 3
+	}
+	svc := &Service{
+		Server:   &http.Server{Handler: http.NotFoundHandler()},
+		Listener: ln,
+		Workers:  []Worker{q},
+		Grace:    time.Duration(len(os.Getenv(childEnv))) * time.Second, // "xx": 2s
+		Logger:   slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+	go func() {
+		for {
+			if c, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+				c.Close()
+				fmt.Println("ready")
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	if err := svc.Run(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// startChild runs this binary as the service and waits for "ready"
+func startChild(t *testing.T, grace string) (*exec.Cmd, *syncBuffer) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), childEnv+"="+grace)
+	stderr := &syncBuffer{}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || line != "ready\n" {
+		t.Fatalf("child: %q, %v", line, err)
+	}
+	go io.Copy(io.Discard, stdout)
+	return cmd, stderr
+}
+
+func exitCode(t *testing.T, cmd *exec.Cmd, stderr *syncBuffer) int {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("child did not exit; stderr:\n%s", stderr)
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
+func TestProcessExitCodes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts processes")
+	}
+
+	t.Run("grace exceeded", func(t *testing.T) {
+		cmd, stderr := startChild(t, "x") // 1s grace
+		cmd.Process.Signal(syscall.SIGTERM)
+		if code := exitCode(t, cmd, stderr); code != 2 || !strings.Contains(stderr.String(), "grace period exceeded") {
+			t.Errorf("exit code %d; stderr:\n%s", code, stderr)
+		}
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		cmd, stderr := startChild(t, strings.Repeat("x", 60)) // 1m grace
+		cmd.Process.Signal(os.Interrupt)
+		for deadline := time.Now().Add(5 * time.Second); !strings.Contains(stderr.String(), "shutting down"); time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("child did not start shutting down; stderr:\n%s", stderr)
+			}
+		}
+		began := time.Now()
+		cmd.Process.Signal(os.Interrupt)
+		if code := exitCode(t, cmd, stderr); code != 1 || time.Since(began) > 5*time.Second {
+			t.Errorf("exit code %d after %v; stderr:\n%s", code, time.Since(began), stderr)
+		}
+	})
+}
+
+// 7. Examples
+// ===========
+
+func ExampleQueue() {
+	q := NewQueue(10, 1)
+	for i := range 3 {
+		q.Submit(context.Background(), func(context.Context) { fmt.Println("job", i) })
+	}
+	q.Drain() // no new jobs; Run finishes the queued ones and returns
+	q.Run(context.Background())
+
+	err := q.Submit(context.Background(), func(context.Context) {})
+	fmt.Println(err)
+	// Output:
+	// job 0
+	// job 1
+	// job 2
+	// shutdown: queue closed
+}
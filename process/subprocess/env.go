@@ -0,0 +1,55 @@
+package subprocess
+
+import (
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// The Environment
+// ===============
+// cmd.Env is a []string of "KEY=value":
+//
+//	nil                  the child inherits os.Environ() - everything,
+//	                     secrets included
+//	[]string{}           an empty environment: no PATH, no HOME
+//	append(os.Environ(), "GOFLAGS=-mod=mod")
+//	                     the parent's, with one override - when a key
+//	                     repeats, exec keeps the last one
+//
+// cmd.Environ() returns what the child will actually get, including the
+// PWD that exec adds when Dir is set.
+//
+// For builds and tests that must not depend on whoever runs them, start
+// from nothing and let through only what is needed.
+
+// Environ returns a minimal environment: the variables named in keep,
+// copied from this process if set, then set on top. The result is
+// sorted, so the same inputs give the same environment.
+func Environ(keep []string, set map[string]string) []string {
+	env := map[string]string{}
+	for _, k := range keep {
+		if v, ok := os.LookupEnv(k); ok {
+			env[k] = v
+		}
+	}
+	maps.Copy(env, set)
+
+	out := make([]string, 0, len(env))
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		out = append(out, k+"="+env[k])
+	}
+	return out
+}
+
+// Getenv reads a variable from an environment list, as the child would
+// see it: the last definition wins
+func Getenv(env []string, key string) (string, bool) {
+	for _, kv := range slices.Backward(env) {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,121 @@
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Pipelines
+// =========
+// "a | b | c" in Go: an os.Pipe between each pair of stages, the write
+// end as one stage's Stdout and the read end as the next one's Stdin.
+// Both are *os.File, so exec hands them to the children as they are;
+// the data never passes through this process.
+//
+// Two rules, both about this process getting out of the way:
+//
+//   - Start every stage before waiting on any. Wait on the first while
+//     the last is not running, and the first blocks forever on a full
+//     pipe.
+//   - Close this process's copy of each pipe end once the child that
+//     uses it has started. A write end left open means the reader never
+//     sees EOF; a read end left open means the writer never gets
+//     SIGPIPE, and "yes | head -1" runs forever. (cmd.StdoutPipe keeps
+//     the read end until Wait, which is why it is not used here.)
+//
+// A shell reports only the last stage's status unless "set -o
+// pipefail". Pipeline reports every failed stage but one kind: when a
+// reader exits early ("| head -1"), the writer before it dies of SIGPIPE
+// on its next write. That says only that the reader stopped - fine if
+// it succeeded, and reported as its own failure if not - so a stage
+// killed by SIGPIPE is not an error. Whether it gets that far depends
+// on timing, which is one more reason not to report it.
+
+// Pipeline runs stages as a shell pipeline. The first stage reads
+// stages[0].In; the result holds the last stage's stdout, and the
+// stderr of all stages.
+func Pipeline(ctx context.Context, stages ...Command) (*Result, error) {
+	if len(stages) == 0 {
+		return nil, errors.New("subprocess: empty pipeline")
+	}
+	var stdout bytes.Buffer
+	// exec copies each command's stderr in a goroutine of its own, so a
+	// buffer shared by the stages needs a lock
+	stderr := &lockedBuffer{}
+	cmds := make([]*exec.Cmd, len(stages))
+	for i, st := range stages {
+		cmds[i] = st.cmd(ctx)
+		cmds[i].Stderr = stderr
+	}
+	cmds[len(cmds)-1].Stdout = &stdout
+
+	// pipes[i] connects stage i to stage i+1
+	type pipe struct{ r, w *os.File }
+	pipes := make([]pipe, len(cmds)-1)
+	closeAll := func() {
+		for _, p := range pipes {
+			p.r.Close()
+			p.w.Close()
+		}
+	}
+	for i := range pipes {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		pipes[i] = pipe{r, w}
+		cmds[i].Stdout = w
+		cmds[i+1].Stdin = r
+	}
+
+	start := time.Now()
+	for i, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			// Stop what already runs: kill it and reap it
+			closeAll()
+			for _, started := range cmds[:i] {
+				started.Process.Kill()
+				started.Wait()
+			}
+			return nil, fmt.Errorf("stage %d: %w", i+1, err)
+		}
+	}
+	// The children have their own copies now
+	closeAll()
+
+	var errs []error
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil && !(i < len(cmds)-1 && brokenPipe(cmd.ProcessState)) {
+			err = describe(ctx, stages[i], cmd.ProcessState.ExitCode(), nil, err)
+			errs = append(errs, fmt.Errorf("stage %d: %w", i+1, err))
+		}
+	}
+	last := cmds[len(cmds)-1].ProcessState
+	res := &Result{Stdout: stdout.Bytes(), Stderr: stderr.buf.Bytes(), ExitCode: last.ExitCode(), Duration: time.Since(start)}
+	return res, errors.Join(errs...)
+}
+
+// brokenPipe reports whether the process was killed by SIGPIPE
+func brokenPipe(ps *os.ProcessState) bool {
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGPIPE
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
@@ -0,0 +1,134 @@
+package subprocess
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// Streaming Output
+// ================
+// Run buffers everything until the process exits. For a build or a
+// test run that takes minutes, the output should appear as it is
+// written. Two ways:
+//
+//   - Give exec any io.Writer as Stdout. exec starts a goroutine that
+//     copies the pipe into it - LineWriter below prefixes each line.
+//   - Take the pipes with StdoutPipe and StderrPipe and read them
+//     yourself - Stream below. The rule: read them to the end before
+//     calling Wait, because Wait closes them.
+//
+// An *os.File as Stdout is different again: the child writes to the
+// file directly, with no goroutine and no copy.
+
+// Line is one line of a command's output
+type Line struct {
+	Stderr bool
+	Text   string
+}
+
+// maxLine bounds one line of output; a longer one ends the stream with
+// bufio.ErrTooLong rather than growing without limit
+const maxLine = 1 << 20
+
+// Stream runs c and calls fn with each line of output as it arrives.
+// Calls are serialized, so fn needs no locking, and lines from one
+// stream arrive in order; how stdout and stderr interleave is up to
+// the scheduler.
+func Stream(ctx context.Context, c Command, fn func(Line)) error {
+	cmd := c.cmd(ctx)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		errTail  bytes.Buffer // kept for the *Error, as in Run
+		scanErrs [2]error
+	)
+	scan := func(i int, r io.Reader, isErr bool) {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64<<10), maxLine)
+		for sc.Scan() {
+			mu.Lock()
+			fn(Line{Stderr: isErr, Text: sc.Text()})
+			if isErr {
+				errTail.WriteString(sc.Text() + "\n")
+			}
+			mu.Unlock()
+		}
+		if scanErrs[i] = sc.Err(); scanErrs[i] != nil {
+			// Keep draining, or a child blocked writing to a full pipe
+			// would never exit
+			io.Copy(io.Discard, r)
+		}
+	}
+	wg.Go(func() { scan(0, stdout, false) })
+	wg.Go(func() { scan(1, stderr, true) })
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		return describe(ctx, c, cmd.ProcessState.ExitCode(), errTail.Bytes(), err)
+	}
+	for _, err := range scanErrs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LineWriter is an io.Writer that writes whole lines to W, each with
+// Prefix. exec writes pipe output in whatever chunks read returns - a
+// line can arrive in two writes, or ten lines in one - so LineWriter
+// holds a partial line until its newline arrives.
+//
+// It is safe for concurrent use. Set as both Stdout and Stderr, the two
+// streams interleave by line, never mid-line.
+type LineWriter struct {
+	W      io.Writer
+	Prefix string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := io.WriteString(lw.W, lw.Prefix+string(lw.buf[:i+1])); err != nil {
+			return 0, err
+		}
+		lw.buf = lw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a final line that had no newline. Call it after Wait.
+func (lw *LineWriter) Flush() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if len(lw.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(lw.W, lw.Prefix+string(lw.buf)+"\n")
+	lw.buf = nil
+	return err
+}
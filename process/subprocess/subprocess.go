@@ -0,0 +1,148 @@
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// subprocess - Running and Supervising Child Processes
+// ====================================================
+// os/exec starts programs directly - no shell, so no quoting bugs and
+// no injection through arguments: exec.Command("ls", userInput) passes
+// userInput as one argument whatever it contains. The price is that
+// everything a shell does for free is spelled out here:
+//
+//	subprocess.go      Run: capture output, exit codes, errors that
+//	                   explain themselves
+//	stream.go          output line by line while the command runs
+//	supervise_unix.go  timeouts: SIGTERM, a grace period, then SIGKILL,
+//	                   for the whole process group
+//	pipeline.go        a | b | c with kernel pipes, and pipefail
+//	env.go             what the child's environment is, and how to set it
+//
+// The tests run their own binary as the child process (see TestMain),
+// so they need no shell utilities and behave the same everywhere.
+
+// Command describes a process to run. The zero values of the optional
+// fields mean what they mean for exec.Cmd.
+type Command struct {
+	Name string
+	Args []string
+	Dir  string    // working directory; empty is the parent's
+	Env  []string  // "KEY=value"; nil inherits the parent's
+	In   io.Reader // stdin; nil reads from os.DevNull
+
+	// Grace, if set, makes cancellation polite: the process group gets
+	// SIGTERM and, Grace later, SIGKILL. Without it, cancellation kills
+	// the process at once. See Supervise.
+	Grace time.Duration
+}
+
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// cmd builds the exec.Cmd. CommandContext kills the process when ctx
+// ends - that is the whole timeout mechanism.
+func (c Command) cmd(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = c.Env
+	cmd.Stdin = c.In
+	if c.Grace > 0 {
+		Supervise(cmd, c.Grace)
+	}
+	return cmd
+}
+
+// Result is what a finished command produced
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int // -1 if it was killed by a signal
+	Duration time.Duration
+}
+
+// Error is a command that ran but did not succeed. It keeps the tail of
+// stderr: "exit status 1" alone sends everyone to rerun the command by
+// hand to see why.
+type Error struct {
+	Command  string
+	ExitCode int    // -1 if killed by a signal or never started
+	Stderr   string // the last lines of stderr
+	Err      error  // *exec.ExitError, or ctx.Err() if the context ended it
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%q: %v", e.Command, e.Err)
+	if e.Stderr != "" {
+		msg += "\n" + e.Stderr
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// stderrTail is how many lines of stderr an Error keeps
+const stderrTail = 5
+
+// Run runs c to completion and returns its output. Stdout and stderr
+// are captured separately; a command that exits non-zero returns both
+// the Result and an *Error.
+//
+// The errors to tell apart:
+//
+//	exec.ErrNotFound          the program is not on $PATH - it never ran
+//	*exec.ExitError           it ran and exited non-zero, or was killed
+//	ctx.Err()                 we killed it: timeout or cancellation
+//
+// The last case is the subtle one. A process killed for a timeout
+// returns *exec.ExitError "signal: killed" from Wait - exec does not
+// say why. Run checks ctx itself, so errors.Is(err,
+// context.DeadlineExceeded) works.
+func Run(ctx context.Context, c Command) (*Result, error) {
+	cmd := c.cmd(ctx)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	res := &Result{Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), Duration: time.Since(start)}
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		return res, describe(ctx, c, res.ExitCode, stderr.Bytes(), err)
+	}
+	return res, nil
+}
+
+// describe turns a failure from Run or Wait into an *Error, or returns
+// it unchanged when the process never started
+func describe(ctx context.Context, c Command, code int, stderr []byte, err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) && ctx.Err() == nil && !errors.Is(err, exec.ErrWaitDelay) {
+		// Not found, not executable, bad Dir: there is no exit code
+		return err
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return &Error{Command: c.String(), ExitCode: code, Stderr: tail(stderr, stderrTail), Err: err}
+}
+
+// tail returns the last n lines of b
+func tail(b []byte, n int) string {
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
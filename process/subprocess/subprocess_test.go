@@ -0,0 +1,527 @@
+package subprocess
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Subprocesses - Tests
+// ====================
+// Run with:
+//
+//   cd process/subprocess
+//   go test -v *.go
+//
+// The child processes are this test binary. TestMain checks
+// SUBPROCESS_HELPER before running any test: when it is set, the binary
+// acts as the helper it names and exits. A test starts one with
+// helper("echo", "a", "b"). This is the pattern os/exec's own tests
+// use - no dependence on sh, cat or sleep being installed.
+
+// 1. The Helper Process
+// =====================
+
+const helperEnv = "SUBPROCESS_HELPER"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(helperEnv); mode != "" {
+		os.Exit(runHelper(mode, os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// helper returns a Command that runs this binary as the named helper
+func helper(mode string, args ...string) Command {
+	return Command{Name: os.Args[0], Args: args, Env: append(os.Environ(), helperEnv+"="+mode)}
+}
+
+func runHelper(mode string, args []string) int {
+	switch mode {
+	case "echo": // each argument on a line
+		for _, a := range args {
+			fmt.Println(a)
+		}
+	case "fail": // ten lines of stderr, then exit with args[0]
+		for i := 1; i <= 10; i++ {
+			fmt.Fprintf(os.Stderr, "error line %d\n", i)
+		}
+		code, _ := strconv.Atoi(args[0])
+		return code
+	case "sleep":
+		d, _ := time.ParseDuration(args[0])
+		time.Sleep(d)
+	case "upper": // stdin to stdout in upper case
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			fmt.Println(strings.ToUpper(sc.Text()))
+		}
+	case "head": // the first line of stdin
+		sc := bufio.NewScanner(os.Stdin)
+		if sc.Scan() {
+			fmt.Println(sc.Text())
+		}
+	case "yes": // "y" until the pipe breaks
+		for {
+			fmt.Println("y")
+		}
+	case "env":
+		v, ok := os.LookupEnv(args[0])
+		fmt.Printf("%s=%q %t\n", args[0], v, ok)
+	case "pwd":
+		wd, _ := os.Getwd()
+		fmt.Println(wd)
+	case "interleave": // numbered lines, alternating streams, written in pieces
+		for i := range 6 {
+			w := map[bool]*os.File{true: os.Stdout, false: os.Stderr}[i%2 == 0]
+			fmt.Fprintf(w, "line")
+			fmt.Fprintf(w, " %d\n", i)
+		}
+	case "long": // one line longer than maxLine, then a short one
+		os.Stdout.Write(bytes.Repeat([]byte("x"), maxLine+1))
+		fmt.Println("\nafter")
+	case "term": // clean up on SIGTERM
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGTERM)
+		fmt.Println("ready")
+		<-sig
+		fmt.Println("cleaning up")
+	case "stubborn": // ignore SIGTERM
+		signal.Ignore(syscall.SIGTERM)
+		fmt.Println("ready")
+		time.Sleep(time.Minute)
+	case "spawn": // start a grandchild that keeps stdout open; write its pid to args[0]
+		gc := exec.Command(os.Args[0], "30s")
+		gc.Env = append(os.Environ(), helperEnv+"=sleep")
+		gc.Stdout = os.Stdout
+		if err := gc.Start(); err != nil {
+			return 1
+		}
+		os.WriteFile(args[0], []byte(strconv.Itoa(gc.Process.Pid)), 0o644)
+		time.Sleep(time.Minute)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown helper", mode)
+		return 2
+	}
+	return 0
+}
+
+// reapGrandchild kills the process whose pid the spawn helper wrote
+func reapGrandchild(t *testing.T, pidFile string) {
+	t.Cleanup(func() {
+		if b, err := os.ReadFile(pidFile); err == nil {
+			pid, _ := strconv.Atoi(string(b))
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	})
+}
+
+// 2. Run: Output and Exit Codes
+// =============================
+
+func TestRun(t *testing.T) {
+	res, err := Run(context.Background(), helper("echo", "hello", "world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(res.Stdout); got != "hello\nworld\n" || res.ExitCode != 0 {
+		t.Errorf("stdout %q, exit code %d", got, res.ExitCode)
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	res, err := Run(context.Background(), helper("fail", "3"))
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if e.ExitCode != 3 || res.ExitCode != 3 {
+		t.Errorf("exit code %d / %d, want 3", e.ExitCode, res.ExitCode)
+	}
+	// The error keeps the last lines; the Result keeps all of them
+	if !strings.HasPrefix(e.Stderr, "error line 6\n") || !strings.HasSuffix(e.Stderr, "error line 10") {
+		t.Errorf("stderr tail %q", e.Stderr)
+	}
+	if n := bytes.Count(res.Stderr, []byte("\n")); n != 10 {
+		t.Errorf("result has %d stderr lines, want 10", n)
+	}
+	// The *exec.ExitError is still there underneath
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("no *exec.ExitError in %v", err)
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	_, err := Run(context.Background(), Command{Name: "no-such-program-anywhere"})
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("err = %v, want exec.ErrNotFound", err)
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		t.Errorf("a program that never ran has no *Error: %v", e)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	res, err := Run(ctx, helper("sleep", "30s"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if res.ExitCode != -1 {
+		t.Errorf("exit code %d, want -1 for a killed process", res.ExitCode)
+	}
+	if res.Duration > 10*time.Second {
+		t.Errorf("took %v", res.Duration)
+	}
+}
+
+func TestRunInputDirEnv(t *testing.T) {
+	ctx := context.Background()
+
+	c := helper("upper")
+	c.In = strings.NewReader("one\ntwo\n")
+	if res, err := Run(ctx, c); err != nil || string(res.Stdout) != "ONE\nTWO\n" {
+		t.Errorf("stdin: %q, %v", res.Stdout, err)
+	}
+
+	dir := t.TempDir()
+	c = helper("pwd")
+	c.Dir = dir
+	res, err := Run(ctx, c)
+	want, _ := filepath.EvalSymlinks(dir)
+	if got := strings.TrimSpace(string(res.Stdout)); err != nil || got != want {
+		t.Errorf("dir: %q, %v; want %q", got, err, want)
+	}
+
+	// Without In the child reads os.DevNull: EOF at once, no hang
+	if res, err := Run(ctx, helper("upper")); err != nil || len(res.Stdout) != 0 {
+		t.Errorf("no stdin: %q, %v", res.Stdout, err)
+	}
+}
+
+// 3. Streaming
+// ============
+
+func TestStream(t *testing.T) {
+	var lines []Line
+	err := Stream(context.Background(), helper("interleave"), func(l Line) { lines = append(lines, l) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr []string
+	for _, l := range lines {
+		if l.Stderr {
+			stderr = append(stderr, l.Text)
+		} else {
+			stdout = append(stdout, l.Text)
+		}
+	}
+	// Whole lines, in order within each stream, however they were written
+	if want := []string{"line 0", "line 2", "line 4"}; !slices.Equal(stdout, want) {
+		t.Errorf("stdout %q, want %q", stdout, want)
+	}
+	if want := []string{"line 1", "line 3", "line 5"}; !slices.Equal(stderr, want) {
+		t.Errorf("stderr %q, want %q", stderr, want)
+	}
+}
+
+func TestStreamFailure(t *testing.T) {
+	n := 0
+	err := Stream(context.Background(), helper("fail", "1"), func(Line) { n++ })
+	var e *Error
+	if !errors.As(err, &e) || e.ExitCode != 1 || !strings.Contains(e.Stderr, "error line 10") {
+		t.Errorf("err = %v", err)
+	}
+	if n != 10 {
+		t.Errorf("%d lines, want 10", n)
+	}
+}
+
+// A line over the limit stops the scanner, but the pipe is drained so
+// the child can finish writing and exit
+func TestStreamLongLine(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := Stream(ctx, helper("long"), func(Line) {})
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("err = %v, want bufio.ErrTooLong", err)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var out bytes.Buffer
+	lw := &LineWriter{W: &out, Prefix: "> "}
+	for _, chunk := range []string{"hel", "lo\nwor", "ld\n\nlast"} {
+		lw.Write([]byte(chunk))
+	}
+	if got := out.String(); got != "> hello\n> world\n> \n" {
+		t.Errorf("before Flush: %q", got)
+	}
+	lw.Flush()
+	if got := out.String(); !strings.HasSuffix(got, "> last\n") {
+		t.Errorf("after Flush: %q", got)
+	}
+}
+
+// One LineWriter as both Stdout and Stderr: lines from the two streams
+// interleave, but no line is torn
+func TestLineWriterWithExec(t *testing.T) {
+	var out bytes.Buffer
+	lw := &LineWriter{W: &out, Prefix: "[child] "}
+	c := helper("interleave")
+	cmd := exec.Command(c.Name)
+	cmd.Env = c.Env
+	cmd.Stdout, cmd.Stderr = lw, lw
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	lw.Flush()
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %q", lines)
+	}
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "[child] line ") {
+			t.Errorf("torn or unprefixed line %q", l)
+		}
+	}
+}
+
+// 4. Supervision
+// ==============
+
+// waitReady starts c with Stream and cancels the context once the child
+// prints "ready", so the signal arrives after its handler is installed
+func waitReady(t *testing.T, c Command) ([]string, error, time.Duration) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out []string
+	var canceled time.Time
+	err := Stream(ctx, c, func(l Line) {
+		out = append(out, l.Text)
+		if l.Text == "ready" {
+			canceled = time.Now()
+			cancel()
+		}
+	})
+	return out, err, time.Since(canceled)
+}
+
+func TestSuperviseGraceful(t *testing.T) {
+	c := helper("term")
+	c.Grace = 5 * time.Second
+	out, err, took := waitReady(t, c)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	// SIGTERM let it clean up, well before the grace ran out
+	if !slices.Contains(out, "cleaning up") {
+		t.Errorf("output %q: no cleanup", out)
+	}
+	if took > 4*time.Second {
+		t.Errorf("took %v after cancel", took)
+	}
+}
+
+func TestSuperviseKillAfterGrace(t *testing.T) {
+	c := helper("stubborn")
+	c.Grace = 300 * time.Millisecond
+	_, err, took := waitReady(t, c)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v", err)
+	}
+	if took < c.Grace || took > 10*time.Second {
+		t.Errorf("exited %v after cancel, want just over the grace of %v", took, c.Grace)
+	}
+}
+
+// Without the process group, the grandchild survives the kill and
+// holds stdout open: only WaitDelay gets Wait to return
+func TestGrandchildHoldsPipe(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	reapGrandchild(t, pidFile)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	c := helper("spawn", pidFile)
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Env = c.Env
+	cmd.WaitDelay = time.Second
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	start := time.Now()
+	cmd.Run()
+	if took := time.Since(start); took < 300*time.Millisecond+cmd.WaitDelay {
+		t.Errorf("Wait returned after %v; the grandchild should have held the pipe for WaitDelay", took)
+	}
+}
+
+// With Supervise the whole group is signalled: the pipe closes as soon
+// as they are gone
+func TestSuperviseKillsGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	reapGrandchild(t, pidFile)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	c := helper("spawn", pidFile)
+	c.Grace = 5 * time.Second
+
+	res, err := Run(ctx, c)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v", err)
+	}
+	if res.Duration > 3*time.Second {
+		t.Errorf("took %v: the grandchild kept the pipe open", res.Duration)
+	}
+}
+
+// 5. Pipelines
+// ============
+
+func TestPipeline(t *testing.T) {
+	res, err := Pipeline(context.Background(),
+		helper("echo", "alpha", "beta"),
+		helper("upper"),
+		helper("head"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(res.Stdout); got != "ALPHA\n" {
+		t.Errorf("stdout %q", got)
+	}
+}
+
+// pipefail: a failure in the middle is reported even though the last
+// stage succeeded. Whether echo writes before fail exits is up to the
+// scheduler; either way the error is fail's alone
+func TestPipelineFailure(t *testing.T) {
+	res, err := Pipeline(context.Background(),
+		helper("echo", "a"),
+		helper("fail", "4"),
+		helper("upper"))
+	var e *Error
+	if !errors.As(err, &e) || e.ExitCode != 4 || err.Error() != "stage 2: "+e.Error() {
+		t.Errorf("err = %v", err)
+	}
+	if res.ExitCode != 0 || !bytes.Contains(res.Stderr, []byte("error line 1")) {
+		t.Errorf("result %+v", res)
+	}
+}
+
+// "yes | head -1": head exits, and yes dies of SIGPIPE on its next
+// write, which is how a pipeline normally ends
+func TestPipelineSIGPIPE(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := Pipeline(ctx, helper("yes"), helper("head"))
+	if string(res.Stdout) != "y\n" {
+		t.Errorf("stdout %q", res.Stdout)
+	}
+	if err != nil {
+		t.Errorf("err = %v, want the SIGPIPE in stage 1 ignored", err)
+	}
+}
+
+func TestPipelineStartFailure(t *testing.T) {
+	_, err := Pipeline(context.Background(), helper("sleep", "30s"), Command{Name: "no-such-program-anywhere"})
+	if !errors.Is(err, exec.ErrNotFound) || !strings.Contains(err.Error(), "stage 2") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 6. The Environment
+// ==================
+
+func TestEnviron(t *testing.T) {
+	t.Setenv("SUBPROCESS_KEEP", "kept")
+	t.Setenv("SUBPROCESS_DROP", "dropped")
+
+	env := Environ([]string{"SUBPROCESS_KEEP", "SUBPROCESS_MISSING"}, map[string]string{"B": "2", "A": "1"})
+	if want := []string{"A=1", "B=2", "SUBPROCESS_KEEP=kept"}; !slices.Equal(env, want) {
+		t.Errorf("got %q, want %q", env, want)
+	}
+
+	// The child sees exactly that, and nothing else
+	c := helper("env", "SUBPROCESS_DROP")
+	c.Env = Environ(nil, map[string]string{helperEnv: "env"})
+	res, err := Run(context.Background(), c)
+	if got := string(res.Stdout); err != nil || got != "SUBPROCESS_DROP=\"\" false\n" {
+		t.Errorf("child saw %q, %v", got, err)
+	}
+}
+
+func TestEnvLastWins(t *testing.T) {
+	c := helper("env", "MODE")
+	c.Env = append(c.Env, "MODE=first", "MODE=second")
+	res, err := Run(context.Background(), c)
+	if got := string(res.Stdout); err != nil || got != "MODE=\"second\" true\n" {
+		t.Errorf("child saw %q, %v", got, err)
+	}
+	if v, _ := Getenv(c.Env, "MODE"); v != "second" {
+		t.Errorf("Getenv = %q", v)
+	}
+	// An empty Env is empty, not inherited
+	cmd := exec.Command("true")
+	cmd.Env = []string{}
+	if _, ok := Getenv(cmd.Environ(), "PATH"); ok {
+		t.Errorf("empty Env has PATH")
+	}
+}
+
+// 7. Benchmarks
+// =============
+
+// Starting a process costs milliseconds, not microseconds: batch work
+// into one child rather than one child per item
+func BenchmarkRun(b *testing.B) {
+	c := helper("echo", "x")
+	for b.Loop() {
+		if _, err := Run(context.Background(), c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 8. Examples
+// ===========
+
+func ExampleLineWriter() {
+	lw := &LineWriter{W: os.Stdout, Prefix: "[build] "}
+	io.WriteString(lw, "compiling...\nlink")
+	io.WriteString(lw, "ing...\ndone")
+	lw.Flush()
+	// Output:
+	// [build] compiling...
+	// [build] linking...
+	// [build] done
+}
+
+func ExampleGetenv() {
+	env := []string{"HOME=/root", "GOFLAGS=-v", "GOFLAGS=-mod=mod"}
+	v, ok := Getenv(env, "GOFLAGS")
+	fmt.Println(v, ok)
+	// Output: -mod=mod true
+}
@@ -0,0 +1,66 @@
+//go:build unix
+
+package subprocess
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Timeouts and Killing
+// ====================
+// exec.CommandContext kills the process when the context ends. Two
+// things the default gets wrong for anything bigger than "sleep":
+//
+//  1. SIGKILL gives no chance to clean up - temp files stay, a server
+//     drops its connections. cmd.Cancel replaces the kill with any
+//     function, such as sending SIGTERM.
+//  2. Only the process itself is signalled. "sh -c 'make test'" or
+//     "go run" start children of their own; they survive, keep the
+//     stdout pipe open, and Wait blocks until they finish. Starting the
+//     child as the leader of a new process group (Setpgid) lets a
+//     signal to -pgid reach all of them.
+//
+// cmd.WaitDelay is the backstop: once the context is done, or once the
+// process has exited, Wait waits at most WaitDelay for the process
+// and its pipes, then kills it and closes them, and returns
+// exec.ErrWaitDelay if nothing else went wrong.
+
+// Supervise makes cancelling cmd's context polite and thorough: the
+// process and everything it started get SIGTERM, then SIGKILL after
+// grace. Call it before Start, on a cmd made by exec.CommandContext.
+func Supervise(cmd *exec.Cmd, grace time.Duration) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true // pgid = the child's pid
+
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		err := signalGroup(pgid, syscall.SIGTERM)
+		if err == nil {
+			// A lesson-sized supervisor: a real one would also stop
+			// this timer once the group is gone, because a pgid is
+			// eventually reused
+			time.AfterFunc(grace, func() { signalGroup(pgid, syscall.SIGKILL) })
+		}
+		return err
+	}
+	// Wait gives up after twice the grace: SIGKILL has been sent by
+	// then, so this only covers a grandchild that escaped the group
+	cmd.WaitDelay = 2 * grace
+}
+
+// signalGroup signals every process in the group. ESRCH - no such
+// process - means they are all gone, which Cancel reports as
+// os.ErrProcessDone so Wait does not treat it as a failure.
+func signalGroup(pgid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pgid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
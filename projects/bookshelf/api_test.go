@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Bookshelf - Handler Tests
+// =========================
+// Run with:
+//
+//   cd projects/bookshelf
+//   go test -v *.go
+//   go test -race *.go
+//
+// Every test drives the full handler - routing, middleware, decoding,
+// validation, the store and the envelope - through ResponseRecorder.
+// The clock is fixed so timestamps can be compared exactly.
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type testAPI struct {
+	h     http.Handler
+	store *Store
+	logs  *bytes.Buffer
+	clock *time.Time
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	clock := now
+	var logs bytes.Buffer
+	store := NewStore()
+	store.now = func() time.Time { return clock }
+	api := NewAPI(store, slog.New(slog.NewJSONHandler(&logs, nil)))
+	api.now = func() time.Time { return clock }
+	return &testAPI{h: api.Routes(), store: store, logs: &logs, clock: &clock}
+}
+
+func (a *testAPI) do(method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	a.h.ServeHTTP(rec, req)
+	return rec
+}
+
+func (a *testAPI) create(t *testing.T, isbn, title, author string, year int, tags ...string) Book {
+	t.Helper()
+	in, _ := json.Marshal(BookInput{ISBN: isbn, Title: title, Author: author, Year: year, Tags: tags})
+	rec := a.do("POST", "/books", string(in))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create %q: %d %s", title, rec.Code, rec.Body.String())
+	}
+	var b Book
+	json.Unmarshal(rec.Body.Bytes(), &b)
+	return b
+}
+
+// envelopeOf decodes an error response, failing if it is not one
+func envelopeOf(t *testing.T, rec *httptest.ResponseRecorder) errorBody {
+	t.Helper()
+	var env envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil || env.Error.Code == "" {
+		t.Fatalf("not an error envelope: %d %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	return env.Error
+}
+
+// Valid ISBN-13s for the tests
+const (
+	isbnGo     = "9780134190440"
+	isbnAction = "9781617295607"
+	isbnDesign = "9780201633610"
+	isbnClean  = "9780132350884"
+)
+
+// 1. Create and Read
+// ==================
+
+func TestCreate(t *testing.T) {
+	api := newTestAPI(t)
+	rec := api.do("POST", "/books", `{"isbn": "978-0-13-419044-0", "title": "  The Go Programming Language ",
+		"author": "Donovan", "year": 2015, "tags": ["Go", " Programming"]}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/books/1" {
+		t.Fatalf("POST = %d, Location %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+	var b Book
+	json.Unmarshal(rec.Body.Bytes(), &b)
+	want := Book{ID: 1, ISBN: isbnGo, Title: "The Go Programming Language", Author: "Donovan", Year: 2015,
+		Tags: []string{"go", "programming"}, CreatedAt: now, UpdatedAt: now}
+	if fmt.Sprint(b) != fmt.Sprint(want) {
+		t.Errorf("created %+v\nwant    %+v", b, want)
+	}
+
+	rec = api.do("GET", "/books/1", "")
+	var got Book
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != 200 || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("GET = %d %+v", rec.Code, got)
+	}
+}
+
+func TestTagsNeverNull(t *testing.T) {
+	api := newTestAPI(t)
+	rec := api.do("POST", "/books", `{"isbn": "`+isbnGo+`", "title": "T", "author": "A", "year": 2000}`)
+	if !strings.Contains(rec.Body.String(), `"tags":[]`) {
+		t.Errorf("body = %s, want \"tags\":[]", rec.Body.String())
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	api := newTestAPI(t)
+	tests := []struct {
+		target string
+		status int
+		code   string
+	}{
+		{"/books/1", 404, "not_found"},
+		{"/books/abc", 400, "bad_request"},
+		{"/books/0", 400, "bad_request"},
+		{"/nowhere", 404, "not_found"},
+	}
+	for _, tt := range tests {
+		rec := api.do("GET", tt.target, "")
+		if e := envelopeOf(t, rec); rec.Code != tt.status || e.Code != tt.code {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.target, rec.Code, e.Code, tt.status, tt.code)
+		}
+	}
+}
+
+// 2. Validation and Decoding
+// ==========================
+
+func TestValidation(t *testing.T) {
+	api := newTestAPI(t)
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"everything missing", `{}`, []string{"isbn", "title", "author", "year"}},
+		{"bad check digit", `{"isbn": "9780134190441", "title": "T", "author": "A", "year": 2000}`, []string{"isbn"}},
+		{"letters in ISBN", `{"isbn": "97801341904X0", "title": "T", "author": "A", "year": 2000}`, []string{"isbn"}},
+		{"blank title", `{"isbn": "` + isbnGo + `", "title": "   ", "author": "A", "year": 2000}`, []string{"title"}},
+		{"long title", `{"isbn": "` + isbnGo + `", "title": "` + strings.Repeat("é", 201) + `", "author": "A", "year": 2000}`, []string{"title"}},
+		{"future year", `{"isbn": "` + isbnGo + `", "title": "T", "author": "A", "year": 2027}`, []string{"year"}},
+		{"bad tags", `{"isbn": "` + isbnGo + `", "title": "T", "author": "A", "year": 2000, "tags": ["go", "", "Go"]}`, []string{"tags[1]", "tags[2]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.do("POST", "/books", tt.body)
+			e := envelopeOf(t, rec)
+			var fields []string
+			for _, d := range e.Details {
+				fields = append(fields, d.Field)
+			}
+			if rec.Code != http.StatusUnprocessableEntity || e.Code != "validation_failed" || !slices.Equal(fields, tt.fields) {
+				t.Errorf("%d %q, fields %v; want 422 validation_failed, fields %v", rec.Code, e.Code, fields, tt.fields)
+			}
+		})
+	}
+	// Nothing invalid was stored
+	if page, _ := api.store.List(Filter{}, 0, 100); len(page) != 0 {
+		t.Errorf("store has %d books", len(page))
+	}
+}
+
+func TestBadBodies(t *testing.T) {
+	api := newTestAPI(t)
+	valid := `{"isbn": "` + isbnGo + `", "title": "T", "author": "A", "year": 2000}`
+	tests := []struct {
+		name, body, contentType string
+		status                  int
+		message                 string
+	}{
+		{"empty", "", "application/json", 400, "empty"},
+		{"not JSON", "title=T", "application/json", 400, "not valid JSON"},
+		{"truncated", `{"title": "T"`, "application/json", 400, "not valid JSON"},
+		{"wrong type", `{"year": "2000"}`, "application/json", 400, `"year" must be a int`},
+		{"unknown field", `{"titel": "T"}`, "application/json", 400, `unknown field "titel"`},
+		{"server-owned field", `{"id": 99}`, "application/json", 400, `unknown field "id"`},
+		{"two objects", valid + valid, "application/json", 400, "single JSON object"},
+		{"too large", `{"title": "` + strings.Repeat("x", maxBody) + `"}`, "application/json", 413, "too large"},
+		{"form post", valid, "application/x-www-form-urlencoded", 415, "application/json"},
+		{"with charset", valid, "application/json; charset=utf-8", 201, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/books", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			api.h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status < 400 {
+				api.store.Delete(1)
+				return
+			}
+			if e := envelopeOf(t, rec); !strings.Contains(e.Message, tt.message) {
+				t.Errorf("message %q, want it to mention %q", e.Message, tt.message)
+			}
+		})
+	}
+}
+
+// 3. Replace, Delete and Conflicts
+// ================================
+
+func TestReplace(t *testing.T) {
+	api := newTestAPI(t)
+	api.create(t, isbnGo, "Go", "Donovan", 2015, "go", "classic")
+
+	*api.clock = now.Add(time.Hour)
+	rec := api.do("PUT", "/books/1", `{"isbn": "`+isbnGo+`", "title": "The Go Programming Language", "author": "Donovan", "year": 2015}`)
+	var b Book
+	json.Unmarshal(rec.Body.Bytes(), &b)
+	if rec.Code != 200 || b.Title != "The Go Programming Language" {
+		t.Fatalf("PUT = %d %s", rec.Code, rec.Body.String())
+	}
+	// PUT replaces: the omitted tags are gone. Timestamps are the
+	// server's: created_at kept, updated_at moved.
+	if len(b.Tags) != 0 || !b.CreatedAt.Equal(now) || !b.UpdatedAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("after PUT: %+v", b)
+	}
+
+	if rec := api.do("PUT", "/books/9", `{"isbn": "`+isbnGo+`", "title": "T", "author": "A", "year": 2000}`); rec.Code != 404 {
+		t.Errorf("PUT missing = %d", rec.Code)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	api := newTestAPI(t)
+	api.create(t, isbnGo, "Go", "Donovan", 2015)
+	api.create(t, isbnAction, "Go in Action", "Kennedy", 2015)
+
+	rec := api.do("POST", "/books", `{"isbn": "`+isbnGo+`", "title": "Copy", "author": "X", "year": 2020}`)
+	if e := envelopeOf(t, rec); rec.Code != http.StatusConflict || e.Code != "conflict" {
+		t.Errorf("duplicate create = %d %q", rec.Code, e.Code)
+	}
+	// Taking another book's ISBN in a replace conflicts too; keeping
+	// your own does not
+	rec = api.do("PUT", "/books/2", `{"isbn": "`+isbnGo+`", "title": "Go in Action", "author": "Kennedy", "year": 2015}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("replace onto another ISBN = %d", rec.Code)
+	}
+	rec = api.do("PUT", "/books/2", `{"isbn": "`+isbnAction+`", "title": "Go in Action, 2nd", "author": "Kennedy", "year": 2024}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("replace keeping its ISBN = %d", rec.Code)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	api := newTestAPI(t)
+	api.create(t, isbnGo, "Go", "Donovan", 2015)
+	if rec := api.do("DELETE", "/books/1", ""); rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Errorf("DELETE = %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := api.do("DELETE", "/books/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d", rec.Code)
+	}
+	if rec := api.do("GET", "/books/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE = %d", rec.Code)
+	}
+}
+
+// 4. Listing and Pagination
+// =========================
+
+func listPage(t *testing.T, api *testAPI, target string) Page {
+	t.Helper()
+	rec := api.do("GET", target, "")
+	if rec.Code != 200 {
+		t.Fatalf("GET %s = %d %s", target, rec.Code, rec.Body.String())
+	}
+	var p Page
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	return p
+}
+
+func titles(books []Book) []string {
+	var out []string
+	for _, b := range books {
+		out = append(out, b.Title)
+	}
+	return out
+}
+
+func TestListFilters(t *testing.T) {
+	api := newTestAPI(t)
+	api.create(t, isbnGo, "Go", "Alan Donovan", 2015, "go")
+	api.create(t, isbnAction, "Go in Action", "William Kennedy", 2015, "go")
+	api.create(t, isbnDesign, "Design Patterns", "Erich Gamma", 1994, "design")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Go", "Go in Action", "Design Patterns"}},
+		{"?tag=go", []string{"Go", "Go in Action"}},
+		{"?author=kennedy", []string{"Go in Action"}},
+		{"?author=donovan&tag=go", []string{"Go"}},
+		{"?tag=cooking", nil},
+	}
+	for _, tt := range tests {
+		p := listPage(t, api, "/books"+tt.query)
+		if !slices.Equal(titles(p.Data), tt.want) || p.NextCursor != "" {
+			t.Errorf("GET /books%s = %v (cursor %q), want %v", tt.query, titles(p.Data), p.NextCursor, tt.want)
+		}
+	}
+
+	// An empty result is [], not null
+	if rec := api.do("GET", "/books?tag=cooking", ""); !strings.Contains(rec.Body.String(), `"data":[]`) {
+		t.Errorf("empty page = %s", rec.Body.String())
+	}
+}
+
+func TestPagination(t *testing.T) {
+	api := newTestAPI(t)
+	for i := range 25 {
+		api.store.Create(Book{ISBN: fmt.Sprint(i), Title: fmt.Sprintf("Book %02d", i+1), Author: "A", Year: 2000})
+	}
+
+	// Follow next_cursor until it is empty
+	var all []string
+	target := "/books?limit=10"
+	pages := 0
+	for {
+		p := listPage(t, api, target)
+		pages++
+		all = append(all, titles(p.Data)...)
+		if p.NextCursor == "" {
+			break
+		}
+		target = "/books?limit=10&cursor=" + p.NextCursor
+	}
+	if pages != 3 || len(all) != 25 || all[0] != "Book 01" || all[24] != "Book 25" {
+		t.Errorf("%d pages, %d books: %v", pages, len(all), all)
+	}
+}
+
+func TestPaginationWithDeletes(t *testing.T) {
+	// A book deleted from the first page, after the client read it, must
+	// not make the client skip a book on the next page - which is what
+	// ?offset=10 would do
+	api := newTestAPI(t)
+	for i := range 20 {
+		api.store.Create(Book{ISBN: fmt.Sprint(i), Title: fmt.Sprintf("Book %02d", i+1)})
+	}
+	first := listPage(t, api, "/books?limit=10")
+	api.do("DELETE", "/books/3", "")
+	second := listPage(t, api, "/books?limit=10&cursor="+first.NextCursor)
+	if len(second.Data) != 10 || second.Data[0].Title != "Book 11" {
+		t.Errorf("second page starts at %v", titles(second.Data))
+	}
+}
+
+func TestListBadParams(t *testing.T) {
+	api := newTestAPI(t)
+	for _, q := range []string{"limit=0", "limit=101", "limit=ten", "cursor=%21%21", "cursor=" + "bm90LWEtY3Vyc29y"} {
+		rec := api.do("GET", "/books?"+q, "")
+		if e := envelopeOf(t, rec); rec.Code != 400 || e.Code != "bad_request" {
+			t.Errorf("GET /books?%s = %d %q", q, rec.Code, e.Code)
+		}
+	}
+}
+
+// 5. The Envelope and Middleware
+// ==============================
+
+func TestEnvelopeCarriesRequestID(t *testing.T) {
+	api := newTestAPI(t)
+	req := httptest.NewRequest("GET", "/books/42", nil)
+	req.Header.Set("X-Request-ID", "trace-me")
+	rec := httptest.NewRecorder()
+	api.h.ServeHTTP(rec, req)
+	if e := envelopeOf(t, rec); e.RequestID != "trace-me" || rec.Header().Get("X-Request-ID") != "trace-me" {
+		t.Errorf("request_id = %q, header %q", e.RequestID, rec.Header().Get("X-Request-ID"))
+	}
+	if !strings.Contains(api.logs.String(), `"pattern":"GET /books/{id}"`) {
+		t.Errorf("access log = %s", api.logs.String())
+	}
+}
+
+func TestInternalErrorsAreHidden(t *testing.T) {
+	// An error the envelope does not know becomes a generic 500; the
+	// real message goes only to the log
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	rec := httptest.NewRecorder()
+	writeError(rec, httptest.NewRequest("GET", "/", nil), logger, errors.New("db password rejected for user admin"))
+
+	e := envelopeOf(t, rec)
+	if rec.Code != 500 || e.Code != "internal" || strings.Contains(rec.Body.String(), "password") {
+		t.Errorf("500 body = %s", rec.Body.String())
+	}
+	if !strings.Contains(logs.String(), "db password rejected") {
+		t.Errorf("log = %s", logs.String())
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	// Without the method-less /books patterns the catch-all would
+	// answer 404 here
+	api := newTestAPI(t)
+	rec := api.do("PATCH", "/books/1", "")
+	if e := envelopeOf(t, rec); rec.Code != http.StatusMethodNotAllowed || e.Code != "method_not_allowed" ||
+		rec.Header().Get("Allow") != "DELETE, GET, HEAD, PUT" {
+		t.Errorf("PATCH = %d %q, Allow %q", rec.Code, e.Code, rec.Header().Get("Allow"))
+	}
+	if rec := api.do("DELETE", "/books", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE /books = %d", rec.Code)
+	}
+}
+
+// 6. Concurrency
+// ==============
+
+func TestConcurrentCreates(t *testing.T) {
+	// Run with -race. Every create gets its own ID.
+	api := newTestAPI(t)
+	isbns := []string{isbnGo, isbnAction, isbnDesign, isbnClean}
+	var wg sync.WaitGroup
+	ids := make([]int, len(isbns))
+	for i, isbn := range isbns {
+		wg.Go(func() {
+			rec := api.do("POST", "/books", `{"isbn": "`+isbn+`", "title": "T", "author": "A", "year": 2000}`)
+			var b Book
+			json.Unmarshal(rec.Body.Bytes(), &b)
+			ids[i] = b.ID
+			// Reads race with the writes
+			api.do("GET", "/books", "")
+		})
+	}
+	wg.Wait()
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int{1, 2, 3, 4}) {
+		t.Errorf("ids = %v", ids)
+	}
+}
+
+// 7. Over a Real Connection
+// =========================
+
+func TestServer(t *testing.T) {
+	api := newTestAPI(t)
+	srv := httptest.NewServer(api.h)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/books", "application/json",
+		strings.NewReader(`{"isbn": "`+isbnClean+`", "title": "Clean Code", "author": "Martin", "year": 2008}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	resp, err = http.Get(srv.URL + resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var b Book
+	if err := json.NewDecoder(resp.Body).Decode(&b); err != nil || b.Title != "Clean Code" {
+		t.Errorf("GET Location = %+v, %v", b, err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// Decoding Request Bodies
+// =======================
+// json.NewDecoder(r.Body).Decode(&v) on its own accepts too much: any
+// size, unknown fields that the client thinks are being saved, and
+// trailing garbage after the first value. decodeJSON closes each of
+// those, and turns the decoder's messages into ones a client can act
+// on.
+
+const maxBody = 64 << 10
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, _ := mime.ParseMediaType(ct); mt != "application/json" {
+			return &apiError{http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json"}
+		}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		var syntax *json.SyntaxError
+		var typ *json.UnmarshalTypeError
+		var tooBig *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooBig):
+			return err
+		case errors.Is(err, io.EOF):
+			return badRequest("request body is empty")
+		case errors.As(err, &syntax), errors.Is(err, io.ErrUnexpectedEOF):
+			return badRequest("request body is not valid JSON")
+		case errors.As(err, &typ):
+			return badRequest(fmt.Sprintf("field %q must be a %s", typ.Field, typ.Type))
+		default:
+			// Unknown fields: `json: unknown field "x"`
+			return badRequest(err.Error())
+		}
+	}
+	if dec.More() {
+		return badRequest("request body must be a single JSON object")
+	}
+	return nil
+}
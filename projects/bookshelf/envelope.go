@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// The Error Envelope
+// ==================
+// Every error response has the same shape, whatever went wrong:
+//
+//	{"error": {"code": "validation_failed",
+//	           "message": "invalid request: title: is required",
+//	           "details": [{"field": "title", "message": "is required"}],
+//	           "request_id": "9f2c..."}}
+//
+// Clients branch on the stable "code", show "message" to people, and
+// quote "request_id" in bug reports. Handlers never write errors
+// themselves: they return an error and writeError picks the status, so
+// one mapping covers the whole API.
+//
+// Unexpected errors become a 500 with a generic message. The details
+// go to the log, not to the client.
+
+// apiError is an error with an HTTP status and a code for clients
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+func badRequest(msg string) error {
+	return &apiError{http.StatusBadRequest, "bad_request", msg}
+}
+
+type envelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// writeError maps err to a status and writes the envelope
+func writeError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error) {
+	body := errorBody{Message: err.Error(), RequestID: RequestIDFrom(r.Context())}
+	var status int
+
+	var api *apiError
+	var invalid *ValidationError
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &api):
+		status, body.Code = api.Status, api.Code
+	case errors.As(err, &invalid):
+		status, body.Code, body.Details = http.StatusUnprocessableEntity, "validation_failed", invalid.Fields
+	case errors.Is(err, ErrNotFound):
+		status, body.Code = http.StatusNotFound, "not_found"
+	case errors.Is(err, ErrConflict):
+		status, body.Code = http.StatusConflict, "conflict"
+	case errors.As(err, &tooBig):
+		status, body.Code = http.StatusRequestEntityTooLarge, "body_too_large"
+		body.Message = "request body too large"
+	default:
+		logger.ErrorContext(r.Context(), "internal error", "id", body.RequestID, "err", err)
+		status, body.Code, body.Message = http.StatusInternalServerError, "internal", "internal error"
+	}
+	writeJSON(w, status, envelope{body})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
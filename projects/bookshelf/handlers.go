@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Handlers
+// ========
+// Each handler returns an error instead of writing one. handle adapts
+// that signature to http.HandlerFunc and sends any error through
+// writeError, so a handler's failure paths are plain returns:
+//
+//	b, err := a.store.Get(id)
+//	if err != nil {
+//		return err // ErrNotFound becomes a 404 envelope
+//	}
+
+// API serves the bookshelf
+type API struct {
+	store  *Store
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewAPI returns the API for store
+func NewAPI(store *Store, logger *slog.Logger) *API {
+	return &API{store: store, logger: logger, now: time.Now}
+}
+
+// Routes returns the API's handler with its middleware
+func (a *API) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /books", a.handle(a.listBooks))
+	mux.HandleFunc("POST /books", a.handle(a.createBook))
+	mux.HandleFunc("GET /books/{id}", a.handle(a.getBook))
+	mux.HandleFunc("PUT /books/{id}", a.handle(a.replaceBook))
+	mux.HandleFunc("DELETE /books/{id}", a.handle(a.deleteBook))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	// The catch-all below would also match PATCH /books/1, hiding the
+	// mux's 405. Method-less patterns for the known paths keep it, in
+	// envelope form.
+	mux.HandleFunc("/books", a.handle(methodNotAllowed("GET, HEAD, POST")))
+	mux.HandleFunc("/books/{id}", a.handle(methodNotAllowed("DELETE, GET, HEAD, PUT")))
+	// Unknown paths get the envelope too, not the mux's plain text
+	mux.HandleFunc("/", a.handle(func(w http.ResponseWriter, r *http.Request) error {
+		return &apiError{http.StatusNotFound, "not_found", "no route for " + r.URL.Path}
+	}))
+	return Chain(mux, RequestID, Logging(a.logger), Recover(a.logger))
+}
+
+func (a *API) handle(h func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			writeError(w, r, a.logger, err)
+		}
+	}
+}
+
+// Page is the body of GET /books
+type Page struct {
+	Data       []Book `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+func (a *API) listBooks(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	limit := defaultLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			return badRequest(fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		}
+		limit = n
+	}
+	after, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		return err
+	}
+
+	books, more := a.store.List(Filter{Author: q.Get("author"), Tag: q.Get("tag")}, after, limit)
+	page := Page{Data: books}
+	if more {
+		page.NextCursor = encodeCursor(books[len(books)-1].ID)
+	}
+	writeJSON(w, http.StatusOK, page)
+	return nil
+}
+
+func (a *API) createBook(w http.ResponseWriter, r *http.Request) error {
+	var in BookInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	if err := in.validate(a.now()); err != nil {
+		return err
+	}
+	b, err := a.store.Create(in.book(0))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", fmt.Sprintf("/books/%d", b.ID))
+	writeJSON(w, http.StatusCreated, b)
+	return nil
+}
+
+func (a *API) getBook(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	b, err := a.store.Get(id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, b)
+	return nil
+}
+
+// replaceBook is a full replacement: omitted fields are cleared, as PUT
+// means "store this representation"
+func (a *API) replaceBook(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	var in BookInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	if err := in.validate(a.now()); err != nil {
+		return err
+	}
+	b, err := a.store.Replace(in.book(id))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, b)
+	return nil
+}
+
+func (a *API) deleteBook(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	if err := a.store.Delete(id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (in BookInput) book(id int) Book {
+	tags := in.Tags
+	if tags == nil {
+		tags = []string{} // "tags": [] in JSON, never null
+	}
+	return Book{ID: id, ISBN: in.ISBN, Title: in.Title, Author: in.Author, Year: in.Year, Tags: tags}
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, badRequest("book id must be a positive number")
+	}
+	return id, nil
+}
+
+// Cursors are opaque to clients: base64 of the last ID seen. Clients
+// pass them back unchanged, and the format can change without breaking
+// them.
+func encodeCursor(afterID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("after:" + strconv.Itoa(afterID)))
+}
+
+func decodeCursor(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	var id int
+	if err == nil {
+		_, err = fmt.Sscanf(string(raw), "after:%d", &id)
+	}
+	if err != nil || id < 0 {
+		return 0, badRequest("invalid cursor")
+	}
+	return id, nil
+}
+
+func methodNotAllowed(allow string) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Allow", allow)
+		return &apiError{http.StatusMethodNotAllowed, "method_not_allowed", r.Method + " is not allowed here"}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Bookshelf - A JSON CRUD API
+// ===========================
+// The capstone for the web lessons: a small REST API for books that
+// puts the pieces together - ServeMux routing, the middleware chain,
+// request decoding, validation, one error envelope, keyset pagination,
+// graceful shutdown - with handler tests covering every path.
+//
+//	GET    /books?author=&tag=&limit=&cursor=   list, paginated
+//	POST   /books                               create
+//	GET    /books/{id}                          read
+//	PUT    /books/{id}                          replace
+//	DELETE /books/{id}                          delete
+//
+// Run with:
+//
+//	cd projects/bookshelf
+//	go run main.go handlers.go store.go validate.go envelope.go decode.go middleware.go
+//	curl -s localhost:8080/books?limit=2
+//
+// and test with:
+//
+//	go test -v *.go
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "listen address")
+	seed := flag.Bool("seed", true, "start with sample books")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	store := NewStore()
+	if *seed {
+		seedBooks(store)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           NewAPI(store, logger).Routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	// Ctrl-C or SIGTERM cancels ctx; see web/server/run.go for a version
+	// that also cancels in-flight requests when the grace period ends
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	logger.Info("listening", "addr", *addr)
+
+	select {
+	case err := <-serveErr:
+		logger.Error("server failed", "err", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown", "err", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("server", "err", err)
+	}
+}
+
+func seedBooks(s *Store) {
+	for _, b := range []Book{
+		{ISBN: "9780134190440", Title: "The Go Programming Language", Author: "Alan Donovan, Brian Kernighan", Year: 2015, Tags: []string{"go"}},
+		{ISBN: "9781617295607", Title: "Go in Action", Author: "William Kennedy", Year: 2015, Tags: []string{"go"}},
+		{ISBN: "9780201633610", Title: "Design Patterns", Author: "Erich Gamma", Year: 1994, Tags: []string{"design"}},
+		{ISBN: "9780132350884", Title: "Clean Code", Author: "Robert C. Martin", Year: 2008, Tags: []string{"craft"}},
+	} {
+		s.Create(b)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage
+// =======
+// An in-memory store behind a mutex. The handlers only see its methods,
+// so a database could replace it without touching them - the same seam
+// as the doubles in testing/doubles.
+//
+// The store returns copies, never pointers into its map: a handler
+// cannot change stored data by accident, and the race detector has
+// nothing to find.
+
+var (
+	// ErrNotFound means no book has the given ID
+	ErrNotFound = errors.New("book not found")
+
+	// ErrConflict means another book already has the ISBN
+	ErrConflict = errors.New("a book with this ISBN already exists")
+)
+
+// Book is the resource served under /books
+type Book struct {
+	ID        int       `json:"id"`
+	ISBN      string    `json:"isbn"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	Year      int       `json:"year"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Filter selects books for List. Empty fields match everything.
+type Filter struct {
+	Author string // case-insensitive substring
+	Tag    string // exact tag
+}
+
+func (f Filter) match(b Book) bool {
+	if f.Author != "" && !strings.Contains(strings.ToLower(b.Author), strings.ToLower(f.Author)) {
+		return false
+	}
+	return f.Tag == "" || slices.Contains(b.Tags, f.Tag)
+}
+
+// Store holds the books
+type Store struct {
+	mu     sync.RWMutex
+	books  map[int]Book
+	nextID int
+	now    func() time.Time // replaceable in tests
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{books: map[int]Book{}, nextID: 1, now: time.Now}
+}
+
+// Create stores b with a new ID and timestamps and returns it
+func (s *Store) Create(b Book) (Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isbnTaken(b.ISBN, 0) {
+		return Book{}, ErrConflict
+	}
+	b.ID = s.nextID
+	s.nextID++
+	b.CreatedAt = s.now().UTC()
+	b.UpdatedAt = b.CreatedAt
+	b.Tags = slices.Clone(b.Tags)
+	s.books[b.ID] = b
+	return b, nil
+}
+
+// Get returns the book with the given ID
+func (s *Store) Get(id int) (Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.books[id]
+	if !ok {
+		return Book{}, ErrNotFound
+	}
+	b.Tags = slices.Clone(b.Tags)
+	return b, nil
+}
+
+// Replace overwrites the book with b.ID, keeping its creation time
+func (s *Store) Replace(b Book) (Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.books[b.ID]
+	if !ok {
+		return Book{}, ErrNotFound
+	}
+	if s.isbnTaken(b.ISBN, b.ID) {
+		return Book{}, ErrConflict
+	}
+	b.CreatedAt = old.CreatedAt
+	b.UpdatedAt = s.now().UTC()
+	b.Tags = slices.Clone(b.Tags)
+	s.books[b.ID] = b
+	return b, nil
+}
+
+// Delete removes the book with the given ID
+func (s *Store) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.books[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.books, id)
+	return nil
+}
+
+// List returns up to limit books matching f with IDs greater than
+// after, in ID order, and whether more remain.
+//
+// Paging by "IDs after the last one seen" (keyset pagination) stays
+// correct while books are added and deleted between pages. Paging by
+// offset does not: a delete on page 1 shifts a book from page 2 onto
+// page 1, and the client never sees it.
+func (s *Store) List(f Filter, after, limit int) (page []Book, more bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []int
+	for id, b := range s.books {
+		if id > after && f.match(b) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	if len(ids) > limit {
+		ids, more = ids[:limit], true
+	}
+	page = make([]Book, 0, len(ids))
+	for _, id := range ids {
+		b := s.books[id]
+		b.Tags = slices.Clone(b.Tags)
+		page = append(page, b)
+	}
+	return page, more
+}
+
+// isbnTaken reports whether a book other than except has isbn. The
+// caller holds the lock.
+func (s *Store) isbnTaken(isbn string, except int) bool {
+	for id, b := range s.books {
+		if id != except && b.ISBN == isbn {
+			return true
+		}
+	}
+	return false
+}
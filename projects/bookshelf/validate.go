@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validation
+// ==========
+// Validation reports every problem at once, each tied to its field, so
+// a form can mark all of them in one round trip instead of one per
+// submit. It runs in the handler, before the store, and returns a
+// *ValidationError the error envelope turns into a 422.
+
+// FieldError is one invalid field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the invalid fields of a request
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{field, fmt.Sprintf(format, args...)})
+}
+
+// BookInput is the body of POST and PUT /books. Server-owned fields
+// (id, timestamps) are absent, so a client cannot set them.
+type BookInput struct {
+	ISBN   string   `json:"isbn"`
+	Title  string   `json:"title"`
+	Author string   `json:"author"`
+	Year   int      `json:"year"`
+	Tags   []string `json:"tags"`
+}
+
+// validate normalizes in (trimming spaces, lowercasing tags) and checks
+// it. It returns nil or a *ValidationError.
+func (in *BookInput) validate(now time.Time) error {
+	in.ISBN = strings.ReplaceAll(strings.TrimSpace(in.ISBN), "-", "")
+	in.Title = strings.TrimSpace(in.Title)
+	in.Author = strings.TrimSpace(in.Author)
+
+	v := &ValidationError{}
+	if !validISBN13(in.ISBN) {
+		v.add("isbn", "must be a valid ISBN-13")
+	}
+	switch n := utf8.RuneCountInString(in.Title); {
+	case n == 0:
+		v.add("title", "is required")
+	case n > 200:
+		v.add("title", "must be at most 200 characters, got %d", n)
+	}
+	if in.Author == "" {
+		v.add("author", "is required")
+	}
+	if in.Year < 1450 || in.Year > now.Year()+1 {
+		v.add("year", "must be between 1450 and %d", now.Year()+1)
+	}
+	if len(in.Tags) > 10 {
+		v.add("tags", "at most 10 tags, got %d", len(in.Tags))
+	}
+	seen := map[string]bool{}
+	for i, tag := range in.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		in.Tags[i] = tag
+		switch {
+		case tag == "":
+			v.add(fmt.Sprintf("tags[%d]", i), "must not be empty")
+		case seen[tag]:
+			v.add(fmt.Sprintf("tags[%d]", i), "duplicate tag %q", tag)
+		}
+		seen[tag] = true
+	}
+	if len(v.Fields) > 0 {
+		return v
+	}
+	return nil
+}
+
+// validISBN13 checks the length, digits and check digit: the digits,
+// weighted 1, 3, 1, 3, ..., sum to a multiple of 10
+func validISBN13(s string) bool {
+	if len(s) != 13 {
+		return false
+	}
+	sum := 0
+	for i, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
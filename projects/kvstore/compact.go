@@ -0,0 +1,187 @@
+package kvstore
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Compaction
+// ==========
+// Compaction writes every live record into a new log, generation+1,
+// and drops the old one. It runs beside reads and writes:
+//
+//  1. Under the read lock, note the log's end and copy the index.
+//  2. Without any lock, copy those records into <gen+1>.log.tmp.
+//     Writes keep appending to the old log meanwhile.
+//  3. Under the write lock, copy what was appended since step 1 -
+//     verbatim, tombstones included - then sync, rename the file into
+//     place, sync the directory and switch to it.
+//
+// Step 3 is the only pause, and it is as long as the writes that came
+// in during step 2. A crash at any point leaves either the old log
+// alone or a complete new one beside it; recovery keeps the highest.
+//
+// Tombstones from step 2 are dropped: the new log holds nothing older
+// for them to hide. Those in the tail are kept, since the copied part
+// may still hold their key.
+
+type liveRecord struct {
+	key string
+	entry
+}
+
+// Compact rewrites the log without dead records
+func (s *Store) Compact() error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	// 1. Snapshot
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrClosed
+	}
+	src, end, gen := s.f, s.size, s.gen+1
+	live := make([]liveRecord, 0, len(s.index))
+	for k, e := range s.index {
+		live = append(live, liveRecord{k, e})
+	}
+	s.mu.RUnlock()
+
+	// Copy in log order: sequential reads of the old file
+	slices.SortFunc(live, func(a, b liveRecord) int { return cmp.Compare(a.off, b.off) })
+
+	tmpPath := filepath.Join(s.dir, logName(gen)+".tmp")
+	dst, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			dst.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	// 2. Copy the live records. compactMu keeps Close from closing src
+	// under us; writers only ever append past end.
+	w := bufio.NewWriterSize(dst, 256<<10)
+	index := make(map[string]entry, len(live))
+	var off int64
+	var rec []byte
+	for _, lr := range live {
+		n := headerSize + len(lr.key) + int(lr.valLen)
+		rec = slices.Grow(rec[:0], n)[:n]
+		if _, err := src.ReadAt(rec, lr.off); err != nil {
+			return err
+		}
+		// Copying a corrupt record would bless it with a fresh log;
+		// stop and keep the evidence instead
+		if crc32.Checksum(rec[4:], castagnoli) != binary.BigEndian.Uint32(rec) {
+			return fmt.Errorf("compact: key %q at offset %d: %w", lr.key, lr.off, ErrCorrupt)
+		}
+		if _, err := w.Write(rec); err != nil {
+			return err
+		}
+		index[lr.key] = entry{off: off, valLen: lr.valLen}
+		off += int64(n)
+	}
+
+	// 3. Catch up and switch
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	var dead int64
+	tail := bufio.NewReader(io.NewSectionReader(src, end, s.size-end))
+	for {
+		r, err := readRecord(tail)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("compact: reading records written meanwhile: %w", err)
+		}
+		if _, err := w.Write(appendRecord(nil, r)); err != nil {
+			return err
+		}
+		if old, ok := index[string(r.key)]; ok {
+			dead += headerSize + int64(len(r.key)) + int64(old.valLen)
+		}
+		if r.kind == kindDelete {
+			delete(index, string(r.key))
+			dead += r.size()
+		} else {
+			index[string(r.key)] = entry{off: off, valLen: uint32(len(r.value))}
+		}
+		off += r.size()
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		return err
+	}
+	newPath := filepath.Join(s.dir, logName(gen))
+	if err := os.Rename(tmpPath, newPath); err != nil {
+		return err
+	}
+	// From here the new log is the store, whatever happens next: a
+	// crash now finds it as the highest generation
+	committed = true
+	oldPath := filepath.Join(s.dir, logName(s.gen))
+	before := s.size
+	s.f, s.gen, s.size, s.dead, s.index = dst, gen, off, dead, index
+	src.Close()
+
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+	if err := os.Remove(oldPath); err != nil {
+		return err
+	}
+	s.opts.Logger.Info("kvstore: compacted", "generation", gen, "bytes_before", before, "bytes_after", off)
+	return nil
+}
+
+// dueForCompaction applies the thresholds from Options
+func (s *Store) dueForCompaction() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.closed && s.size > 0 && s.dead >= s.opts.CompactMinDead &&
+		float64(s.dead) > s.opts.CompactRatio*float64(s.size)
+}
+
+// compactLoop checks every CompactInterval until Close. A failed
+// compaction is logged and retried at the next tick: the old log is
+// still intact.
+func (s *Store) compactLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.CompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if !s.dueForCompaction() {
+				continue
+			}
+			if err := s.Compact(); err != nil {
+				s.opts.Logger.Error("kvstore: compaction failed", "err", err)
+			}
+		}
+	}
+}
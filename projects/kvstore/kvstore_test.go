@@ -0,0 +1,658 @@
+package kvstore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Key-Value Store - Tests
+// =======================
+// Run with:
+//
+//   cd projects/kvstore
+//   go test -v *.go
+//   go test -race *.go
+//
+// Crash tests come in two kinds. The torn-write tests damage the log
+// file directly - cut at every byte, garbage appended, a bit flipped -
+// and reopen. The kill tests run a writer in a child process (this
+// test binary again, selected by an environment variable), SIGKILL it
+// mid-stream, and check that every write it acknowledged survived.
+
+var quiet = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func open(t *testing.T, dir string, opts Options) *Store {
+	t.Helper()
+	if opts.Logger == nil {
+		opts.Logger = quiet
+	}
+	s, err := Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func get(t *testing.T, s *Store, key string) string {
+	t.Helper()
+	v, err := s.Get([]byte(key))
+	if errors.Is(err, ErrNotFound) {
+		return "<none>"
+	}
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	return string(v)
+}
+
+func put(t *testing.T, s *Store, key, value string) {
+	t.Helper()
+	if err := s.Put([]byte(key), []byte(value)); err != nil {
+		t.Fatalf("Put(%q): %v", key, err)
+	}
+}
+
+// activeLog is the path of the store's current log file
+func activeLog(s *Store) string {
+	return filepath.Join(s.dir, logName(s.Stats().Generation))
+}
+
+// 1. Records
+// ==========
+
+func TestRecordLayout(t *testing.T) {
+	b := appendRecord(nil, record{kind: kindPut, key: []byte("k"), value: []byte("vv")})
+	want := []byte{
+		0, 0, 0, 0, // crc, checked below
+		kindPut,
+		0, 0, 0, 1, // key length
+		0, 0, 0, 2, // value length
+		'k', 'v', 'v',
+	}
+	if !bytes.Equal(b[4:], want[4:]) {
+		t.Errorf("record = % x\nwant     % x", b, want)
+	}
+
+	rec, err := readRecord(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil || string(rec.key) != "k" || string(rec.value) != "vv" || rec.size() != int64(len(b)) {
+		t.Errorf("readRecord = %+v, %v", rec, err)
+	}
+}
+
+func TestReadRecordRejects(t *testing.T) {
+	good := appendRecord(nil, record{kind: kindPut, key: []byte("key"), value: []byte("value")})
+	flip := func(i int) []byte {
+		b := bytes.Clone(good)
+		b[i] ^= 0x01
+		return b
+	}
+	huge := bytes.Clone(good)
+	copy(huge[9:13], []byte{0xff, 0xff, 0xff, 0xff})
+
+	tests := map[string][]byte{
+		"cut in header":   good[:7],
+		"cut in body":     good[:len(good)-1],
+		"crc":             flip(0),
+		"kind":            flip(4),
+		"value byte":      flip(len(good) - 1),
+		"huge value len":  huge,
+		"all zeros":       make([]byte, 64), // preallocated, never written
+		"tombstone value": appendRecord(nil, record{kind: kindDelete, key: []byte("k"), value: []byte("x")}),
+	}
+	for name, b := range tests {
+		if _, err := readRecord(bufio.NewReader(bytes.NewReader(b))); !errors.Is(err, errTorn) {
+			t.Errorf("%s: err = %v, want errTorn", name, err)
+		}
+	}
+	if _, err := readRecord(bufio.NewReader(bytes.NewReader(nil))); err != io.EOF {
+		t.Errorf("empty: err = %v, want io.EOF", err)
+	}
+}
+
+// 2. Basic Operations
+// ===================
+
+func TestPutGetDelete(t *testing.T) {
+	s := open(t, t.TempDir(), Options{})
+	defer s.Close()
+
+	put(t, s, "a", "1")
+	put(t, s, "b", "2")
+	put(t, s, "a", "3")
+	put(t, s, "empty", "")
+	if err := s.Delete([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete([]byte("never")); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+
+	for key, want := range map[string]string{"a": "3", "b": "<none>", "empty": "", "never": "<none>"} {
+		if got := get(t, s, key); got != want {
+			t.Errorf("Get(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := fmt.Sprint(s.Keys()); got != "[a empty]" {
+		t.Errorf("Keys = %s", got)
+	}
+
+	// [a=1], [b=2] and the tombstone are dead
+	st := s.Stats()
+	wantDead := (headerSize + 2) + (headerSize + 2) + (headerSize + 1)
+	if st.Keys != 2 || st.DeadBytes != int64(wantDead) {
+		t.Errorf("Stats = %+v, want 2 keys and %d dead bytes", st, wantDead)
+	}
+}
+
+func TestGetReturnsACopy(t *testing.T) {
+	s := open(t, t.TempDir(), Options{})
+	defer s.Close()
+	val := []byte("original")
+	s.Put([]byte("k"), val)
+	val[0] = 'X' // the caller's slice is not kept
+
+	got, _ := s.Get([]byte("k"))
+	got[0] = 'Y' // nor is the returned one shared
+	if again := get(t, s, "k"); again != "original" {
+		t.Errorf("Get = %q", again)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s := open(t, t.TempDir(), Options{})
+	defer s.Close()
+	tests := []struct {
+		key, value []byte
+		want       error
+	}{
+		{nil, []byte("v"), ErrEmptyKey},
+		{make([]byte, MaxKeySize+1), nil, ErrKeyTooLarge},
+		{[]byte("k"), make([]byte, MaxValueSize+1), ErrValueTooLarge},
+	}
+	for _, tt := range tests {
+		if err := s.Put(tt.key, tt.value); !errors.Is(err, tt.want) {
+			t.Errorf("Put(%d-byte key, %d-byte value) = %v, want %v", len(tt.key), len(tt.value), err, tt.want)
+		}
+	}
+}
+
+func TestClosed(t *testing.T) {
+	s := open(t, t.TempDir(), Options{CompactInterval: time.Millisecond})
+	s.Close()
+	if err := s.Put([]byte("k"), nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Put after Close: %v", err)
+	}
+	if _, err := s.Get([]byte("k")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Get after Close: %v", err)
+	}
+	if err := s.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestLocked(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	if _, err := Open(dir, Options{Logger: quiet}); !errors.Is(err, ErrLocked) {
+		t.Errorf("second Open: %v, want ErrLocked", err)
+	}
+	s.Close()
+	s = open(t, dir, Options{}) // released by Close
+	s.Close()
+}
+
+// 3. Recovery
+// ===========
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	for i := range 100 {
+		put(t, s, fmt.Sprint("key", i%10), fmt.Sprint("value", i))
+	}
+	s.Delete([]byte("key3"))
+	before := s.Stats()
+	s.Close()
+
+	s = open(t, dir, Options{})
+	defer s.Close()
+	if after := s.Stats(); after != before {
+		t.Errorf("Stats after reopen = %+v, want %+v", after, before)
+	}
+	if got := get(t, s, "key7"); got != "value97" {
+		t.Errorf("key7 = %q", got)
+	}
+	if got := get(t, s, "key3"); got != "<none>" {
+		t.Errorf("deleted key3 = %q", got)
+	}
+}
+
+func TestTornTailAtEveryByte(t *testing.T) {
+	// Build a log of three records, then cut it at every length and
+	// reopen: the store holds exactly the records that fit whole
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	put(t, s, "one", "1")
+	put(t, s, "two", "22")
+	put(t, s, "three", "333")
+	path := activeLog(s)
+	s.Close()
+	full, _ := os.ReadFile(path)
+	ends := []int{headerSize + 4, 2*headerSize + 4 + 5, len(full)}
+
+	for cut := 0; cut <= len(full); cut++ {
+		os.WriteFile(path, full[:cut], 0o644)
+		s := open(t, dir, Options{})
+		whole := 0
+		for _, end := range ends {
+			if cut >= end {
+				whole++
+			}
+		}
+		if got := len(s.Keys()); got != whole {
+			t.Errorf("cut at %d: %d keys, want %d", cut, got, whole)
+		}
+		// The torn bytes are gone, so a new write lands on a clean end
+		put(t, s, "after", "x")
+		s.Close()
+
+		s = open(t, dir, Options{})
+		if get(t, s, "after") != "x" || len(s.Keys()) != whole+1 {
+			t.Errorf("cut at %d: write after recovery lost", cut)
+		}
+		s.Close()
+	}
+}
+
+func TestCorruptionInTheMiddle(t *testing.T) {
+	// A bad record ends the log where it is: the records after it are
+	// dropped too, because nothing says where the next one starts.
+	// Stores with per-block checksums can skip; an append-only log
+	// cannot.
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	put(t, s, "a", "1")
+	put(t, s, "b", "2")
+	put(t, s, "c", "3")
+	path := activeLog(s)
+	s.Close()
+
+	data, _ := os.ReadFile(path)
+	data[headerSize+1+headerSize+1] ^= 0xff // b's key byte
+	os.WriteFile(path, data, 0o644)
+
+	s = open(t, dir, Options{})
+	defer s.Close()
+	if got := fmt.Sprint(s.Keys()); got != "[a]" {
+		t.Errorf("Keys = %s, want [a]", got)
+	}
+}
+
+func TestGetDetectsCorruption(t *testing.T) {
+	// Damage under a running store: Get checks the record it reads
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	defer s.Close()
+	put(t, s, "k", "value")
+
+	f, _ := os.OpenFile(activeLog(s), os.O_WRONLY, 0)
+	f.WriteAt([]byte("X"), headerSize+1)
+	f.Close()
+
+	if _, err := s.Get([]byte("k")); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Get = %v, want ErrCorrupt", err)
+	}
+	if err := s.Compact(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Compact = %v, want ErrCorrupt", err)
+	}
+}
+
+func TestLeftoversFromCrashedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	put(t, s, "k", "old")
+	s.Compact() // generation 2
+	put(t, s, "k", "new")
+	s.Close()
+
+	// A compaction that died before its rename, and a superseded log
+	// that was never removed
+	os.WriteFile(filepath.Join(dir, logName(3)+".tmp"), []byte("half a log"), 0o644)
+	os.WriteFile(filepath.Join(dir, logName(1)), appendRecord(nil, record{kind: kindPut, key: []byte("k"), value: []byte("ancient")}), 0o644)
+
+	s = open(t, dir, Options{})
+	defer s.Close()
+	if got := get(t, s, "k"); got != "new" || s.Stats().Generation != 2 {
+		t.Errorf("k = %q in generation %d, want new in 2", got, s.Stats().Generation)
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got, want := fmt.Sprint(names), fmt.Sprintf("[%s LOCK]", logName(2)); got != want {
+		t.Errorf("directory = %s, want %s", got, want)
+	}
+}
+
+// 4. Compaction
+// =============
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	for i := range 1000 {
+		put(t, s, fmt.Sprint("key", i%20), strings.Repeat("v", i%50))
+	}
+	for i := range 5 {
+		s.Delete([]byte(fmt.Sprint("key", i)))
+	}
+	want := map[string]string{}
+	for _, k := range s.Keys() {
+		want[k] = get(t, s, k)
+	}
+	before := s.Stats()
+
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after := s.Stats()
+	if after.DeadBytes != 0 || after.Keys != 15 || after.LogBytes != before.LogBytes-before.DeadBytes || after.Generation != 2 {
+		t.Errorf("before %+v, after %+v", before, after)
+	}
+	for k, v := range want {
+		if got := get(t, s, k); got != v {
+			t.Errorf("%s = %q after compaction, want %q", k, got, v)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, logName(1))); !os.IsNotExist(err) {
+		t.Errorf("old log still there: %v", err)
+	}
+	s.Close()
+
+	// And the compacted log reopens the same
+	s = open(t, dir, Options{})
+	defer s.Close()
+	if st := s.Stats(); st != after {
+		t.Errorf("reopened: %+v, want %+v", st, after)
+	}
+}
+
+func TestCompactDuringWrites(t *testing.T) {
+	// Writers and deleters keep going while compaction runs; every
+	// write must land, in the old log's tail or the new log
+	s := open(t, t.TempDir(), Options{})
+	defer s.Close()
+	for i := range 2000 {
+		put(t, s, fmt.Sprint("k", i%100), "seed")
+	}
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Go(func() {
+			for i := range 300 {
+				key := fmt.Sprintf("w%d-%d", w, i%30)
+				if i%7 == 6 {
+					s.Delete([]byte(key))
+					continue
+				}
+				if err := s.Put([]byte(key), []byte(strconv.Itoa(i))); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		})
+	}
+	for range 5 {
+		if err := s.Compact(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	// The last operation on each key decides: i = 270..299 covers i%30
+	// once each, and 272, 279, 286, 293 are deletes
+	for w := range 8 {
+		for i := 270; i < 300; i++ {
+			key := fmt.Sprintf("w%d-%d", w, i%30)
+			want := strconv.Itoa(i)
+			if i%7 == 6 {
+				want = "<none>"
+			}
+			if got := get(t, s, key); got != want {
+				t.Errorf("%s = %q, want %q", key, got, want)
+			}
+		}
+	}
+	st := s.Stats()
+	s.Compact()
+	if after := s.Stats(); after.Keys != st.Keys || after.DeadBytes != 0 {
+		t.Errorf("final compaction: %+v -> %+v", st, after)
+	}
+}
+
+func TestBackgroundCompaction(t *testing.T) {
+	s := open(t, t.TempDir(), Options{CompactInterval: time.Millisecond, CompactMinDead: 1 << 10})
+	defer s.Close()
+	for i := range 500 {
+		put(t, s, "hot", strings.Repeat("x", i))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Stats().Generation == 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no compaction: %+v", s.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := get(t, s, "hot"); len(got) != 499 {
+		t.Errorf("hot has %d bytes, want 499", len(got))
+	}
+}
+
+func TestCompactThresholds(t *testing.T) {
+	s := open(t, t.TempDir(), Options{CompactMinDead: 100, CompactRatio: 0.5})
+	defer s.Close()
+	put(t, s, "k", "v")
+	if s.dueForCompaction() {
+		t.Error("due with no dead bytes")
+	}
+	put(t, s, "k", "v") // half dead, but below CompactMinDead
+	if s.dueForCompaction() {
+		t.Errorf("due below the minimum: %+v", s.Stats())
+	}
+	for range 20 {
+		put(t, s, "k", "v")
+	}
+	if !s.dueForCompaction() {
+		t.Errorf("not due: %+v", s.Stats())
+	}
+}
+
+// 5. Killing the Process
+// ======================
+
+// TestChildWriter is the child side of TestKill: it only runs when
+// KVSTORE_CHILD_DIR is set. It writes synchronously, printing each
+// acknowledged key, until it is killed.
+func TestChildWriter(t *testing.T) {
+	dir := os.Getenv("KVSTORE_CHILD_DIR")
+	if dir == "" {
+		t.Skip("child process for TestKill")
+	}
+	s, err := Open(dir, Options{
+		SyncWrites:      true,
+		CompactInterval: time.Millisecond,
+		CompactMinDead:  1 << 10,
+		CompactRatio:    0.05,
+		Logger:          quiet,
+	})
+	if err != nil {
+		fmt.Println("open:", err)
+		os.Exit(1)
+	}
+	start, _ := strconv.Atoi(os.Getenv("KVSTORE_CHILD_START"))
+	for i := start; ; i++ {
+		key := fmt.Sprintf("key%07d", i)
+		if err := s.Put([]byte(key), childValue(i)); err != nil {
+			fmt.Println("put:", err)
+			os.Exit(1)
+		}
+		// Overwrites make dead bytes for the background compactions,
+		// and extra ones run beside the writes, so kills land in them
+		s.Put([]byte("counter"), []byte(strconv.Itoa(i)))
+		if i%97 == 0 {
+			go s.Compact()
+		}
+		fmt.Println("ack", i)
+	}
+}
+
+func childValue(i int) []byte {
+	return []byte(strings.Repeat(strconv.Itoa(i), 1+i%40))
+}
+
+func TestKill(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts child processes")
+	}
+	dir := t.TempDir()
+	acked := -1
+	var gen uint64
+	for round := range 5 {
+		// Each round kills the writer after a different number of
+		// acknowledgements, then checks everything acknowledged so far
+		acked = runAndKill(t, dir, acked+1, 50+round*37)
+
+		s := open(t, dir, Options{})
+		for i := 0; i <= acked; i++ {
+			key := fmt.Sprintf("key%07d", i)
+			if got := get(t, s, key); got != string(childValue(i)) {
+				t.Fatalf("round %d: acknowledged %s = %.20q, want %.20q", round, key, got, childValue(i))
+			}
+		}
+		// counter was written after the last ack's key and before the
+		// ack was printed, so it is at least that
+		if n, err := strconv.Atoi(get(t, s, "counter")); err != nil || n < acked {
+			t.Errorf("round %d: counter = %d, %v; want >= %d", round, n, err, acked)
+		}
+		gen = s.Stats().Generation
+		t.Logf("round %d: %d acknowledged, generation %d, %d keys", round, acked+1, gen, s.Stats().Keys)
+		s.Close()
+	}
+	if gen == 1 {
+		t.Error("the child never compacted; the kills only hit plain appends")
+	}
+}
+
+// runAndKill starts a child writing from key start, SIGKILLs it after
+// n acknowledgements, and returns the last acknowledged key number
+func runAndKill(t *testing.T, dir string, start, n int) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestChildWriter$")
+	cmd.Env = append(os.Environ(), "KVSTORE_CHILD_DIR="+dir, "KVSTORE_CHILD_START="+strconv.Itoa(start))
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	last := start - 1
+	sc := bufio.NewScanner(out)
+	for sc.Scan() {
+		num, ok := strings.CutPrefix(sc.Text(), "ack ")
+		if !ok {
+			t.Fatalf("child: %s", sc.Text())
+		}
+		last, _ = strconv.Atoi(num)
+		if last-start+1 >= n {
+			break
+		}
+	}
+	// SIGKILL: no deferred calls, no Close, no flush - as abrupt as a
+	// crash gets short of pulling the plug
+	cmd.Process.Kill()
+	io.Copy(io.Discard, out)
+	cmd.Wait()
+	if last-start+1 < n {
+		t.Fatalf("child stopped after %d acks", last-start+1)
+	}
+	return last
+}
+
+// 6. Benchmarks
+// =============
+
+func BenchmarkPut(b *testing.B) {
+	for _, sync := range []bool{false, true} {
+		b.Run(fmt.Sprintf("sync=%v", sync), func(b *testing.B) {
+			s, _ := Open(b.TempDir(), Options{SyncWrites: sync, Logger: quiet})
+			defer s.Close()
+			value := bytes.Repeat([]byte("v"), 100)
+			var key []byte
+			b.ReportAllocs()
+			i := 0
+			for b.Loop() {
+				key = strconv.AppendInt(key[:0], int64(i%10000), 10)
+				s.Put(key, value)
+				i++
+			}
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	s, _ := Open(b.TempDir(), Options{Logger: quiet})
+	defer s.Close()
+	value := bytes.Repeat([]byte("v"), 100)
+	for i := range 10000 {
+		s.Put([]byte(strconv.Itoa(i)), value)
+	}
+	var key []byte
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		key = strconv.AppendInt(key[:0], int64(i%10000), 10)
+		s.Get(key)
+		i++
+	}
+}
+
+// Examples
+// ========
+
+func ExampleStore() {
+	dir, _ := os.MkdirTemp("", "kvstore-example")
+	defer os.RemoveAll(dir)
+
+	s, _ := Open(dir, Options{SyncWrites: true, Logger: quiet})
+	s.Put([]byte("lang"), []byte("go"))
+	s.Put([]byte("lang"), []byte("Go"))
+	s.Put([]byte("year"), []byte("2009"))
+	s.Delete([]byte("year"))
+	fmt.Printf("%+v\n", s.Stats())
+	s.Close()
+
+	// Reopening replays the log; compacting drops the dead records
+	s, _ = Open(dir, Options{Logger: quiet})
+	defer s.Close()
+	v, _ := s.Get([]byte("lang"))
+	s.Compact()
+	fmt.Printf("lang=%s %+v\n", v, s.Stats())
+	// Output:
+	// {Keys:1 LogBytes:76 DeadBytes:57 Generation:1}
+	// lang=Go {Keys:1 LogBytes:19 DeadBytes:0 Generation:2}
+}
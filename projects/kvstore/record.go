@@ -0,0 +1,118 @@
+package kvstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// The Log Record
+// ==============
+// Every change is one record appended to the log:
+//
+//	+--------+------+---------+---------+-----+-------+
+//	| crc32  | kind | key len | val len | key | value |
+//	+--------+------+---------+---------+-----+-------+
+//	   4        1       4         4      ...    ...
+//
+// The CRC covers everything after itself. A crash can leave the last
+// record half-written - the length fields may even be intact while the
+// bytes after them are zeros the file system allocated but never
+// filled. The checksum is how recovery tells a complete record from a
+// torn one: lengths alone cannot.
+//
+// A delete is a record too, a tombstone with no value: the log is
+// append-only, so forgetting a key means writing that it is gone.
+
+const headerSize = 4 + 1 + 4 + 4
+
+const (
+	kindPut    = 1
+	kindDelete = 2
+)
+
+const (
+	MaxKeySize   = 64 << 10
+	MaxValueSize = 16 << 20
+)
+
+var (
+	ErrKeyTooLarge   = errors.New("kvstore: key too large")
+	ErrValueTooLarge = errors.New("kvstore: value too large")
+	ErrEmptyKey      = errors.New("kvstore: empty key")
+
+	// errTorn marks the end of the valid log: a record cut short or
+	// failing its checksum. Recovery truncates the file there.
+	errTorn = errors.New("kvstore: torn record")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type record struct {
+	kind  byte
+	key   []byte
+	value []byte
+}
+
+func (r record) size() int64 { return int64(headerSize + len(r.key) + len(r.value)) }
+
+// appendRecord appends r's encoding to b
+func appendRecord(b []byte, r record) []byte {
+	start := len(b)
+	b = append(b, 0, 0, 0, 0, r.kind)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(r.key)))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(r.value)))
+	b = append(b, r.key...)
+	b = append(b, r.value...)
+	binary.BigEndian.PutUint32(b[start:], crc32.Checksum(b[start+4:], castagnoli))
+	return b
+}
+
+// readRecord reads the next record. It returns io.EOF at a clean end
+// and errTorn for a record that is incomplete or corrupt. The key and
+// value are freshly allocated.
+func readRecord(r *bufio.Reader) (record, error) {
+	var hdr [headerSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return record{}, errTorn
+		}
+		return record{}, err
+	}
+	kind := hdr[4]
+	keyLen := binary.BigEndian.Uint32(hdr[5:9])
+	valLen := binary.BigEndian.Uint32(hdr[9:13])
+	// Check the lengths before allocating: a torn header can claim
+	// gigabytes
+	if (kind != kindPut && kind != kindDelete) || keyLen == 0 || keyLen > MaxKeySize ||
+		valLen > MaxValueSize || (kind == kindDelete && valLen != 0) {
+		return record{}, errTorn
+	}
+
+	body := make([]byte, keyLen+valLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return record{}, errTorn
+		}
+		return record{}, err
+	}
+	crc := crc32.Update(crc32.Checksum(hdr[4:], castagnoli), castagnoli, body)
+	if crc != binary.BigEndian.Uint32(hdr[:4]) {
+		return record{}, errTorn
+	}
+	return record{kind: kind, key: body[:keyLen:keyLen], value: body[keyLen:]}, nil
+}
+
+func checkKV(key, value []byte) error {
+	switch {
+	case len(key) == 0:
+		return ErrEmptyKey
+	case len(key) > MaxKeySize:
+		return ErrKeyTooLarge
+	case len(value) > MaxValueSize:
+		return ErrValueTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package kvstore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Crash Recovery
+// ==============
+// On startup the directory may hold:
+//
+//	0000000000000003.log       the active log
+//	0000000000000002.log       an old log a compaction had not yet removed
+//	0000000000000004.log.tmp   a compaction that never finished
+//
+// Compaction only renames its output into place once it is complete
+// and synced, so the highest-numbered .log is always whole and always
+// the newest. Everything else is removed.
+//
+// Then the log is replayed from the start. Replay stops at the first
+// record that is cut short or fails its checksum and truncates the file
+// there: records after a torn one cannot be trusted to start where the
+// lengths say. A crash mid-append loses only the record being written -
+// and only if it was never acknowledged, when SyncWrites is on.
+
+func (s *Store) recover() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var gens []uint64
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case strings.HasSuffix(name, ".log.tmp"):
+			s.opts.Logger.Warn("kvstore: removing unfinished compaction", "file", name)
+			if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+				return err
+			}
+		case strings.HasSuffix(name, ".log"):
+			gen, err := strconv.ParseUint(strings.TrimSuffix(name, ".log"), 10, 64)
+			if err != nil {
+				return fmt.Errorf("unexpected file %s in store directory", name)
+			}
+			gens = append(gens, gen)
+		}
+	}
+
+	// ReadDir sorts by name, and the names are zero-padded
+	s.gen = 1
+	if len(gens) > 0 {
+		s.gen = gens[len(gens)-1]
+		for _, old := range gens[:len(gens)-1] {
+			s.opts.Logger.Warn("kvstore: removing superseded log", "file", logName(old))
+			if err := os.Remove(filepath.Join(s.dir, logName(old))); err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, logName(s.gen)), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := s.replay(f); err != nil {
+		f.Close()
+		return err
+	}
+	s.f = f
+	return syncDir(s.dir) // make a newly created log's name durable
+}
+
+// replay rebuilds the index from f and truncates a torn tail
+func (s *Store) replay(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReaderSize(f, 256<<10)
+	var off int64
+	for {
+		rec, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, errTorn) {
+			s.opts.Logger.Warn("kvstore: truncating torn log tail",
+				"file", f.Name(), "offset", off, "discarded_bytes", info.Size()-off)
+			if err := f.Truncate(off); err != nil {
+				return err
+			}
+			if err := f.Sync(); err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		s.apply(rec, off)
+		off += rec.size()
+	}
+	s.size = off
+	return nil
+}
+
+// syncDir fsyncs a directory, making creates, renames and removes in
+// it durable - the step os-files/fileops.WriteFileAtomic ends with too
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
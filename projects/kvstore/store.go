@@ -0,0 +1,259 @@
+package kvstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A Persistent Key-Value Store
+// ============================
+// The design is Bitcask's: every write is appended to a log file, and
+// an in-memory map from key to the offset of its latest record makes
+// reads one pread. Writes never seek, and a crash can only damage the
+// end of the file.
+//
+//	Put("a", "1")  Put("b", "2")  Put("a", "3")  Delete("b")
+//	log:   [a=1][b=2][a=3][b deleted]
+//	index: a -> offset of [a=3]
+//
+// The price is space: [a=1], [b=2] and the tombstone are dead bytes.
+// Compaction copies the live records into a new log and drops the old
+// one (compact.go). Recovery rebuilds the index by reading the log from
+// the start (recover.go), so every key must fit in memory - values need
+// not.
+//
+// Durability is a choice. With SyncWrites each Put returns after
+// fsync: acknowledged data survives a power cut. Without it writes
+// reach the OS page cache and survive a killed process, not a crashed
+// machine, until the next Sync.
+
+var (
+	ErrNotFound = errors.New("kvstore: not found")
+	ErrClosed   = errors.New("kvstore: closed")
+	ErrLocked   = errors.New("kvstore: directory in use by another process")
+	ErrCorrupt  = errors.New("kvstore: record failed its checksum")
+)
+
+// Options configure Open. The zero value syncs nothing and never
+// compacts in the background.
+type Options struct {
+	SyncWrites bool // fsync after every Put and Delete
+
+	// CompactInterval is how often the background goroutine checks
+	// whether compaction is due; 0 disables it. Compaction runs when
+	// dead bytes are at least CompactMinDead and more than
+	// CompactRatio of the log.
+	CompactInterval time.Duration
+	CompactMinDead  int64
+	CompactRatio    float64 // default 0.5
+
+	Logger *slog.Logger // default slog.Default()
+}
+
+// entry locates a key's latest record in the active log
+type entry struct {
+	off    int64
+	valLen uint32
+}
+
+// Store is safe for concurrent use. Reads share the lock; writes and
+// the end of a compaction take it exclusively.
+type Store struct {
+	dir  string
+	opts Options
+	lock *os.File
+
+	mu     sync.RWMutex
+	f      *os.File // active log, opened O_APPEND
+	gen    uint64   // the active log is dir/<gen>.log
+	size   int64    // bytes of valid records; the next record goes here
+	dead   int64    // bytes of superseded records and tombstones
+	index  map[string]entry
+	buf    []byte // encoding buffer for writes
+	closed bool
+
+	compactMu sync.Mutex // one compaction at a time, and none during Close
+	stop      chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+// Open opens the store in dir, creating it if needed, and recovers the
+// index from the log. A torn record at the end - a crash mid-write -
+// is cut off.
+func Open(dir string, opts Options) (*Store, error) {
+	if opts.CompactRatio == 0 {
+		opts.CompactRatio = 0.5
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := lockDir(filepath.Join(dir, "LOCK"))
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, opts: opts, lock: lock, index: map[string]entry{}}
+	if err := s.recover(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	if opts.CompactInterval > 0 {
+		s.stop, s.done = make(chan struct{}), make(chan struct{})
+		go s.compactLoop()
+	}
+	return s, nil
+}
+
+// Get returns a copy of key's value, or ErrNotFound. The whole record
+// is read and its checksum checked: a disk that returns wrong bytes
+// gets ErrCorrupt, not a wrong answer.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	e, ok := s.index[string(key)] // no allocation for the lookup
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rec := make([]byte, headerSize+len(key)+int(e.valLen))
+	if _, err := s.f.ReadAt(rec, e.off); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(rec[4:], castagnoli) != binary.BigEndian.Uint32(rec) {
+		return nil, fmt.Errorf("key %q at offset %d: %w", key, e.off, ErrCorrupt)
+	}
+	return rec[headerSize+len(key):], nil
+}
+
+// Put sets key to value
+func (s *Store) Put(key, value []byte) error {
+	if err := checkKV(key, value); err != nil {
+		return err
+	}
+	return s.write(record{kind: kindPut, key: key, value: value})
+}
+
+// Delete removes key. Deleting a missing key is not an error and
+// writes nothing.
+func (s *Store) Delete(key []byte) error {
+	if err := checkKV(key, nil); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	_, ok := s.index[string(key)]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return s.write(record{kind: kindDelete, key: key})
+}
+
+func (s *Store) write(r record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.buf = appendRecord(s.buf[:0], r)
+	if _, err := s.f.Write(s.buf); err != nil {
+		// Part of the record may be on disk. Cut it off, so the next
+		// write does not land after garbage; if even that fails,
+		// recovery will find the torn record and cut it then.
+		s.f.Truncate(s.size)
+		return err
+	}
+	if s.opts.SyncWrites {
+		if err := s.f.Sync(); err != nil {
+			return err
+		}
+	}
+	s.apply(r, s.size)
+	s.size += r.size()
+	return nil
+}
+
+// apply updates the index and the dead-byte count for a record at off.
+// Writes and recovery share it, so both count the same way.
+func (s *Store) apply(r record, off int64) {
+	if old, ok := s.index[string(r.key)]; ok {
+		s.dead += headerSize + int64(len(r.key)) + int64(old.valLen)
+	}
+	if r.kind == kindDelete {
+		delete(s.index, string(r.key))
+		s.dead += r.size() // the tombstone itself is dead weight
+		return
+	}
+	s.index[string(r.key)] = entry{off: off, valLen: uint32(len(r.value))}
+}
+
+// Sync flushes written records to stable storage
+func (s *Store) Sync() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrClosed
+	}
+	return s.f.Sync()
+}
+
+// Keys returns the live keys, sorted
+func (s *Store) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.index))
+	for k := range s.index {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Stats describes the log
+type Stats struct {
+	Keys       int
+	LogBytes   int64
+	DeadBytes  int64
+	Generation uint64
+}
+
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Stats{Keys: len(s.index), LogBytes: s.size, DeadBytes: s.dead, Generation: s.gen}
+}
+
+// Close stops background compaction, waiting for one in progress, then
+// syncs and closes the log. The directory lock goes last.
+func (s *Store) Close() error {
+	if s.stop != nil {
+		s.stopOnce.Do(func() {
+			close(s.stop)
+			<-s.done
+		})
+	}
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	err := errors.Join(s.f.Sync(), s.f.Close())
+	return errors.Join(err, s.lock.Close())
+}
+
+func logName(gen uint64) string { return fmt.Sprintf("%016d.log", gen) }
@@ -0,0 +1,202 @@
+package kvwire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// The Client
+// ==========
+// A Client multiplexes concurrent calls over one connection. Each call
+// takes the next id, registers a channel under it, and writes its
+// request; a single reader goroutine delivers each response to the
+// channel with the matching id. Calls do not wait for one another, so
+// ten goroutines calling Get at once cost one round trip, not ten.
+//
+// If the connection fails, every waiting call gets the error, and so
+// does every later one: a broken Client is not repaired, it is replaced.
+
+// ErrNotFound is returned by Get and Delete for a missing key
+var ErrNotFound = errors.New("kvwire: key not found")
+
+// ServerError is an OpError response
+type ServerError struct {
+	Code uint16
+	Text string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("kvwire: server error %d: %s", e.Code, e.Text)
+}
+
+// Client is a connection to a kvwire server, safe for concurrent use
+type Client struct {
+	conn net.Conn
+
+	wmu  sync.Mutex // serializes writes; frames must not interleave
+	wbuf []byte
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan Message
+	err     error // set once the connection has failed
+}
+
+// Dial connects to a server
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, pending: map[uint32]chan Message{}}
+	go c.readLoop()
+	return c, nil
+}
+
+// Close closes the connection; waiting calls fail with net.ErrClosed
+func (c *Client) Close() error { return c.conn.Close() }
+
+func (c *Client) readLoop() {
+	r := NewReader(c.conn)
+	for {
+		m, err := r.Read()
+		if err == nil && m.ID == 0 && m.Op == OpError {
+			// Id 0 answers no request: the server is hanging up
+			err = &ServerError{m.Code, m.Text}
+		}
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		c.mu.Lock()
+		ch := c.pending[m.ID]
+		delete(c.pending, m.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- m // buffered; a call that gave up has unregistered
+		}
+	}
+}
+
+// fail records the first error and wakes every waiting call
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.conn.Close()
+}
+
+// call sends m and waits for the response with the same id
+func (c *Client) call(ctx context.Context, m Message) (Message, error) {
+	ch := make(chan Message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return Message{}, c.err
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1 // 0 is reserved for connection-level errors
+	}
+	m.ID = c.nextID
+	c.pending[m.ID] = ch
+	c.mu.Unlock()
+
+	if err := c.send(m); err != nil {
+		c.forget(m.ID)
+		return Message{}, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return Message{}, c.err
+		}
+		if resp.Op == OpError {
+			return resp, &ServerError{resp.Code, resp.Text}
+		}
+		return resp, nil
+	case <-ctx.Done():
+		// The response may still come; the reader will find no one
+		// waiting and drop it
+		c.forget(m.ID)
+		return Message{}, ctx.Err()
+	}
+}
+
+func (c *Client) send(m Message) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	var err error
+	if c.wbuf, err = AppendFrame(c.wbuf[:0], m); err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(c.wbuf); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) forget(id uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// Ping checks the server is answering
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.call(ctx, Message{Op: OpPing})
+	return err
+}
+
+// Get returns the value stored under key
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := c.call(ctx, Message{Op: OpGet, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Op {
+	case OpValue:
+		return resp.Value, nil
+	case OpNotFound:
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("kvwire: unexpected response %#x to Get", uint8(resp.Op))
+}
+
+// Set stores value under key
+func (c *Client) Set(ctx context.Context, key string, value []byte) error {
+	resp, err := c.call(ctx, Message{Op: OpSet, Key: key, Value: value})
+	if err == nil && resp.Op != OpOK {
+		err = fmt.Errorf("kvwire: unexpected response %#x to Set", uint8(resp.Op))
+	}
+	return err
+}
+
+// Delete removes key
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.call(ctx, Message{Op: OpDelete, Key: key})
+	if err != nil {
+		return err
+	}
+	switch resp.Op {
+	case OpOK:
+		return nil
+	case OpNotFound:
+		return ErrNotFound
+	}
+	return fmt.Errorf("kvwire: unexpected response %#x to Delete", uint8(resp.Op))
+}
@@ -0,0 +1,92 @@
+package kvwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Reading Frames
+// ==============
+// Two ways to cope with partial reads.
+//
+// Reader pulls: io.ReadFull keeps calling Read until it has the four
+// length bytes, then again until it has the body. Partial reads are
+// invisible, and the code reads like a file format parser.
+//
+// SplitFrames is pushed data instead: bufio.Scanner hands it whatever
+// has arrived, and it either finds a complete frame or asks for more
+// by returning (0, nil, nil). The same logic suits event loops that
+// receive bytes in callbacks.
+//
+// Both tell a clean end - EOF between frames - from a connection that
+// died mid-frame: the first is io.EOF, the second io.ErrUnexpectedEOF.
+
+// Reader reads framed messages from a stream
+type Reader struct {
+	br *bufio.Reader
+}
+
+// NewReader returns a Reader reading from r through a buffer
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r)}
+}
+
+// ReadFrame returns the next frame's body
+func (r *Reader) ReadFrame() ([]byte, error) {
+	var hdr [headerSize]byte
+	if _, err := io.ReadFull(r.br, hdr[:]); err != nil {
+		return nil, err // io.EOF only if no byte of the frame arrived
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > MaxFrame {
+		return nil, ErrFrameTooLarge
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r.br, body); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// Read returns the next message. A message that does not parse is
+// returned with its error; the stream stays usable, because the frame
+// was consumed whole.
+func (r *Reader) Read() (Message, error) {
+	body, err := r.ReadFrame()
+	if err != nil {
+		return Message{}, err
+	}
+	return ParseMessage(body)
+}
+
+// Buffered reports whether bytes of a further frame are already in the
+// buffer - another pipelined request waiting to be read
+func (r *Reader) Buffered() bool { return r.br.Buffered() > 0 }
+
+// SplitFrames is a bufio.SplitFunc returning one frame body per token.
+// Give the Scanner a buffer of at least MaxFrame+4 bytes.
+func SplitFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) < headerSize {
+		if atEOF && len(data) > 0 {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil // need more, or a clean end
+	}
+	n := binary.BigEndian.Uint32(data)
+	if n > MaxFrame {
+		return 0, nil, ErrFrameTooLarge
+	}
+	end := headerSize + int(n)
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	return end, data[headerSize:end], nil
+}
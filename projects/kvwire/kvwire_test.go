@@ -0,0 +1,642 @@
+package kvwire
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// kvwire - Tests
+// ==============
+// Run with:
+//
+//   cd projects/kvwire
+//   go test -v *.go
+//   go test -race *.go
+//   go test -run XXX -fuzz FuzzParseMessage -fuzztime 30s *.go
+//   go test -run XXX -bench . *.go
+//
+// Codec tests feed the readers bytes one at a time and in random chunks,
+// the way TCP delivers them. Server tests speak to a real listener,
+// sometimes through a raw connection to send what the Client never
+// would.
+
+func frame(t testing.TB, m Message) []byte {
+	t.Helper()
+	b, err := AppendFrame(nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var allKinds = []Message{
+	{Op: OpPing, ID: 1},
+	{Op: OpGet, ID: 2, Key: "user:42"},
+	{Op: OpSet, ID: 3, Key: "user:42", Value: []byte(`{"name":"Ada"}`)},
+	{Op: OpSet, ID: 4, Key: "", Value: []byte{}},
+	{Op: OpDelete, ID: 5, Key: "user:42"},
+	{Op: OpPong, ID: 1},
+	{Op: OpValue, ID: 2, Value: bytes.Repeat([]byte{0, 1, 2}, 100)},
+	{Op: OpOK, ID: 3},
+	{Op: OpNotFound, ID: 5},
+	{Op: OpError, ID: 9, Code: CodeMalformed, Text: "bad"},
+	{Op: OpGet, ID: 1<<32 - 1, Key: strings.Repeat("k", MaxKey)},
+}
+
+// 1. Encoding
+// ===========
+
+func TestWireLayout(t *testing.T) {
+	// The bytes on the wire, field by field
+	got := hex.EncodeToString(frame(t, Message{Op: OpSet, ID: 7, Key: "a", Value: []byte("xyz")}))
+	want := "0000000b" + // length: 11 bytes follow
+		"03" + // OpSet
+		"00000007" + // id
+		"01" + "61" + // key: length 1, "a"
+		"03" + "78797a" // value: length 3, "xyz"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, m := range allKinds {
+		b := frame(t, m)
+		got, err := ParseMessage(b[headerSize:])
+		if err != nil || got.String() != m.String() {
+			t.Errorf("round trip of %v = %v, %v", m, got, err)
+		}
+	}
+}
+
+func TestAppendFrameRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+		err  error
+	}{
+		{"long key", Message{Op: OpGet, Key: strings.Repeat("k", MaxKey+1)}, nil},
+		{"unknown op", Message{Op: 0x42}, ErrUnknownOp},
+		{"huge value", Message{Op: OpValue, Value: make([]byte, MaxFrame)}, ErrFrameTooLarge},
+	}
+	for _, tt := range tests {
+		prefix := []byte("keep")
+		b, err := AppendFrame(prefix, tt.m)
+		if err == nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		// A failed append leaves the buffer as it was
+		if string(b) != "keep" {
+			t.Errorf("%s: buffer is now %d bytes", tt.name, len(b))
+		}
+	}
+}
+
+// 2. Parsing Bad Input
+// ====================
+
+func TestParseErrors(t *testing.T) {
+	header := func(op Op) string { return string([]byte{byte(op), 0, 0, 0, 7}) }
+	tests := []struct {
+		name string
+		body string
+		err  error
+		id   uint32 // kept for the error response when the header was readable
+	}{
+		{"empty", "", ErrMalformed, 0},
+		{"short header", "\x02\x00\x00", ErrMalformed, 0},
+		{"unknown op", header(0x42), ErrUnknownOp, 7},
+		{"missing key", header(OpGet), ErrMalformed, 7},
+		{"key longer than body", header(OpGet) + "\x05abc", ErrMalformed, 7},
+		{"trailing byte", header(OpPing) + "!", ErrMalformed, 7},
+		{"truncated varint", header(OpGet) + "\x80", ErrMalformed, 7},
+		{"overflowing varint", header(OpGet) + strings.Repeat("\xff", 10) + "\x01", ErrMalformed, 7},
+		{"huge length", header(OpGet) + "\xff\xff\xff\xff\x0f", ErrMalformed, 7},
+		{"key over MaxKey", header(OpGet) + "\x81\x08" + strings.Repeat("k", MaxKey+1), ErrMalformed, 7},
+		{"error without code", header(OpError) + "\x01", ErrMalformed, 7},
+		{"set without value", header(OpSet) + "\x01a", ErrMalformed, 7},
+	}
+	for _, tt := range tests {
+		m, err := ParseMessage([]byte(tt.body))
+		if !errors.Is(err, tt.err) || m.ID != tt.id {
+			t.Errorf("%s: id %d, err %v; want id %d, %v", tt.name, m.ID, err, tt.id, tt.err)
+		}
+	}
+}
+
+// 3. Partial Reads
+// ================
+
+// chunky returns data in random-sized pieces, as a network does
+type chunky struct {
+	r   io.Reader
+	rnd *rand.Rand
+}
+
+func (c chunky) Read(p []byte) (int, error) {
+	return c.r.Read(p[:min(len(p), 1+c.rnd.IntN(9))])
+}
+
+func stream(t testing.TB, n int) ([]byte, []Message) {
+	var buf []byte
+	var msgs []Message
+	for i := range n {
+		m := allKinds[i%len(allKinds)]
+		msgs = append(msgs, m)
+		buf = append(buf, frame(t, m)...)
+	}
+	return buf, msgs
+}
+
+func readAllReader(r io.Reader) ([]Message, error) {
+	fr := NewReader(r)
+	var out []Message
+	for {
+		m, err := fr.Read()
+		if err != nil {
+			return out, err
+		}
+		out = append(out, m)
+	}
+}
+
+func readAllScanner(r io.Reader) ([]Message, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 4096), MaxFrame+headerSize)
+	s.Split(SplitFrames)
+	var out []Message
+	for s.Scan() {
+		m, err := ParseMessage(s.Bytes())
+		if err != nil {
+			return out, err
+		}
+		out = append(out, m)
+	}
+	return out, s.Err()
+}
+
+func TestPartialReads(t *testing.T) {
+	data, want := stream(t, 200)
+	sources := map[string]func() io.Reader{
+		"one byte":      func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) },
+		"random chunks": func() io.Reader { return chunky{bytes.NewReader(data), rand.New(rand.NewPCG(1, 2))} },
+		"half reads":    func() io.Reader { return iotest.HalfReader(bytes.NewReader(data)) },
+	}
+	for name, src := range sources {
+		got, err := readAllReader(src())
+		if err != io.EOF || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Reader, %s: %d messages, err %v", name, len(got), err)
+		}
+		got, err = readAllScanner(src())
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("SplitFrames, %s: %d messages, err %v", name, len(got), err)
+		}
+	}
+}
+
+func TestTruncatedStream(t *testing.T) {
+	// A connection dropped mid-frame is not a clean end
+	data, _ := stream(t, 3)
+	for _, cut := range []int{len(data) - 1, len(data) - 7, 2} {
+		if _, err := readAllReader(bytes.NewReader(data[:cut])); err != io.ErrUnexpectedEOF {
+			t.Errorf("Reader, cut at %d: %v", cut, err)
+		}
+		if _, err := readAllScanner(bytes.NewReader(data[:cut])); err != io.ErrUnexpectedEOF {
+			t.Errorf("SplitFrames, cut at %d: %v", cut, err)
+		}
+	}
+}
+
+func TestOversizedFrameIsNotAllocated(t *testing.T) {
+	// Four bytes claiming 4 GiB must fail before any allocation of
+	// that size
+	claim := []byte{0xff, 0xff, 0xff, 0xff}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := NewReader(bytes.NewReader(claim)).ReadFrame(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 64<<10 {
+		t.Errorf("allocated %d bytes", n)
+	}
+	if _, err := readAllScanner(bytes.NewReader(claim)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("SplitFrames: %v", err)
+	}
+}
+
+// 4. Server and Client
+// ====================
+
+type countingConn struct {
+	net.Conn
+	writes *atomic.Int64
+}
+
+func (c countingConn) Write(p []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(p)
+}
+
+// countingListener counts the server's writes across its connections
+type countingListener struct {
+	net.Listener
+	writes atomic.Int64
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{c, &l.writes}, nil
+}
+
+// startServer serves on loopback; configure runs before Serve starts
+func startServer(t *testing.T, configure ...func(*Server)) (*Server, *countingListener) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := &countingListener{Listener: ln}
+	s := NewServer()
+	s.Logger = slog.New(slog.DiscardHandler)
+	for _, f := range configure {
+		f(s)
+	}
+	go s.Serve(cl)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return s, cl
+}
+
+func dialClient(t *testing.T, addr string) *Client {
+	t.Helper()
+	c, err := Dial(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientServer(t *testing.T) {
+	_, ln := startServer(t)
+	c := dialClient(t, ln.Addr().String())
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing: %v", err)
+	}
+	if err := c.Set(ctx, "k", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "v1" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	if err := c.Delete(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: %v", err)
+	}
+	if err := c.Set(ctx, strings.Repeat("k", MaxKey+1), nil); err == nil {
+		t.Error("oversized key sent")
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	// Run with -race. Responses reach the right callers.
+	_, ln := startServer(t)
+	c := dialClient(t, ln.Addr().String())
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for g := range 50 {
+		wg.Go(func() {
+			for i := range 20 {
+				key := fmt.Sprintf("g%d-%d", g, i)
+				if err := c.Set(ctx, key, []byte(key)); err != nil {
+					t.Error(err)
+					return
+				}
+				if v, err := c.Get(ctx, key); err != nil || string(v) != key {
+					t.Errorf("Get(%s) = %q, %v", key, v, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestPipelinedResponsesAreBatched(t *testing.T) {
+	_, ln := startServer(t)
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 100 requests in one write...
+	var batch []byte
+	for i := range 100 {
+		batch, _ = AppendFrame(batch, Message{Op: OpPing, ID: uint32(i + 1)})
+	}
+	conn.Write(batch)
+
+	// ...come back in order, in far fewer than 100 writes
+	r := NewReader(conn)
+	for i := range 100 {
+		m, err := r.Read()
+		if err != nil || m.Op != OpPong || m.ID != uint32(i+1) {
+			t.Fatalf("response %d = %v, %v", i, m, err)
+		}
+	}
+	if n := ln.writes.Load(); n > 10 {
+		t.Errorf("server made %d writes for 100 pipelined requests", n)
+	}
+}
+
+func TestBadRequestsKeepConnection(t *testing.T) {
+	_, ln := startServer(t)
+	conn, _ := net.Dial("tcp", ln.Addr().String())
+	defer conn.Close()
+	r := NewReader(conn)
+
+	// Well-framed but wrong: the server answers with the request's id
+	// and keeps going
+	conn.Write([]byte{0, 0, 0, 5, 0x42, 0, 0, 0, 7})           // unknown op
+	conn.Write([]byte{0, 0, 0, 6, byte(OpGet), 0, 0, 0, 8, 9}) // key length 9, no key
+	conn.Write([]byte{0, 0, 0, 5, byte(OpPong), 0, 0, 0, 9})   // a response sent as a request
+	for _, want := range []Message{
+		{Op: OpError, ID: 7, Code: CodeUnknownOp},
+		{Op: OpError, ID: 8, Code: CodeMalformed},
+		{Op: OpError, ID: 9, Code: CodeUnknownOp},
+	} {
+		m, err := r.Read()
+		if err != nil || m.Op != want.Op || m.ID != want.ID || m.Code != want.Code {
+			t.Errorf("got %v, %v; want id %d code %d", m, err, want.ID, want.Code)
+		}
+	}
+	conn.Write(frame(t, Message{Op: OpPing, ID: 10}))
+	if m, err := r.Read(); err != nil || m.Op != OpPong {
+		t.Errorf("after errors: %v, %v", m, err)
+	}
+}
+
+func TestOversizedFrameClosesConnection(t *testing.T) {
+	_, ln := startServer(t)
+	c := dialClient(t, ln.Addr().String())
+	conn, _ := net.Dial("tcp", ln.Addr().String())
+	defer conn.Close()
+
+	// The stream position is lost: the server explains and hangs up
+	conn.Write([]byte{0x7f, 0xff, 0xff, 0xff})
+	r := NewReader(conn)
+	if m, err := r.Read(); err != nil || m.Op != OpError || m.ID != 0 || m.Code != CodeTooLarge {
+		t.Errorf("got %v, %v", m, err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("after the error: %v, want io.EOF", err)
+	}
+	// Other connections are unaffected
+	if err := c.Ping(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	// A server that accepts and never answers
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer ln.Close()
+	go func() {
+		conn, _ := ln.Accept()
+		if conn != nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+	c := dialClient(t, ln.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get = %v", err)
+	}
+	c.mu.Lock()
+	n := len(c.pending)
+	c.mu.Unlock()
+	if n != 0 {
+		t.Errorf("%d calls still registered after giving up", n)
+	}
+}
+
+func TestConnectionLoss(t *testing.T) {
+	// The server dies while a call waits: the call fails at once, and
+	// so does every later call
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := ln.Accept()
+		accepted <- conn
+	}()
+	c := dialClient(t, ln.Addr().String())
+	server := <-accepted
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), "k")
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	server.Close()
+
+	// The error is io.EOF or a reset, depending on whether the request
+	// reached the server before it closed
+	var first error
+	select {
+	case first = <-errc:
+		if first == nil {
+			t.Fatal("waiting call succeeded")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("waiting call never returned")
+	}
+	if err := c.Ping(context.Background()); err != first {
+		t.Errorf("later call: %v, want the first error %v", err, first)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	_, ln := startServer(t, func(s *Server) { s.IdleTimeout = 50 * time.Millisecond })
+	conn, _ := net.Dial("tcp", ln.Addr().String())
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	start := time.Now()
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read = %v, want io.EOF from the server closing", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("closed after %v", d)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	s := NewServer()
+	s.Logger = slog.New(slog.DiscardHandler)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ln) }()
+
+	c := dialClient(t, ln.Addr().String())
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The idle connection wakes and closes; Shutdown does not wait for
+	// the idle timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("Shutdown took %v", d)
+	}
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Serve = %v", err)
+	}
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("Ping after Shutdown succeeded")
+	}
+}
+
+// 5. Fuzzing
+// ==========
+
+// FuzzParseMessage: any body either fails to parse or parses to a
+// message that encodes and parses back to itself
+func FuzzParseMessage(f *testing.F) {
+	for _, m := range allKinds {
+		f.Add(frame(f, m)[headerSize:])
+	}
+	f.Add([]byte{byte(OpGet), 0, 0, 0, 1, 0x80})
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		m, err := ParseMessage(body)
+		if err != nil {
+			return
+		}
+		enc, err := AppendFrame(nil, m)
+		if err != nil {
+			t.Fatalf("parsed %v but cannot encode it: %v", m, err)
+		}
+		again, err := ParseMessage(enc[headerSize:])
+		if err != nil || again.String() != m.String() {
+			t.Fatalf("%v re-parsed as %v, %v", m, again, err)
+		}
+	})
+}
+
+// FuzzReaders: the pull Reader and the push SplitFrames agree on any
+// byte stream, however it is chunked
+func FuzzReaders(f *testing.F) {
+	data, _ := stream(f, 5)
+	f.Add(data, uint64(1))
+	f.Add(data[:len(data)-3], uint64(2))
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff}, uint64(3))
+
+	f.Fuzz(func(t *testing.T, data []byte, seed uint64) {
+		a, errA := readAllReader(chunky{bytes.NewReader(data), rand.New(rand.NewPCG(seed, 0))})
+		b, errB := readAllScanner(chunky{bytes.NewReader(data), rand.New(rand.NewPCG(seed, 1))})
+		if errA == io.EOF {
+			errA = nil // the Scanner reports a clean end as no error
+		}
+		if fmt.Sprint(a) != fmt.Sprint(b) || fmt.Sprint(errA) != fmt.Sprint(errB) {
+			t.Fatalf("Reader: %v, %v\nSplitFrames: %v, %v", a, errA, b, errB)
+		}
+	})
+}
+
+// 6. Benchmarks
+// =============
+
+// One caller waits for each answer before asking again; with many
+// callers their requests overlap on the wire. Over loopback on one CPU:
+//
+//	go test -run XXX -bench Get -cpu 1,8 *.go
+//	BenchmarkGet/sequential     8.7µs/op
+//	BenchmarkGet/parallel-8     6.7µs/op
+//
+// Loopback has almost no latency to hide. Across a network the
+// sequential caller pays the full round trip for every Get, and the
+// gap widens to the number of calls in flight.
+func BenchmarkGet(b *testing.B) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	s := NewServer()
+	go s.Serve(ln)
+	defer s.Shutdown(context.Background())
+	c, _ := Dial(context.Background(), ln.Addr().String())
+	defer c.Close()
+	ctx := context.Background()
+	c.Set(ctx, "k", []byte("value"))
+
+	b.Run("sequential", func(b *testing.B) {
+		for b.Loop() {
+			c.Get(ctx, "k")
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c.Get(ctx, "k")
+			}
+		})
+	})
+}
+
+func BenchmarkAppendParse(b *testing.B) {
+	m := Message{Op: OpSet, ID: 1, Key: "user:42", Value: bytes.Repeat([]byte("x"), 256)}
+	var buf []byte
+	b.ReportAllocs()
+	for b.Loop() {
+		buf, _ = AppendFrame(buf[:0], m)
+		ParseMessage(buf[headerSize:])
+	}
+}
+
+// Examples
+// ========
+
+func ExampleAppendFrame() {
+	b, _ := AppendFrame(nil, Message{Op: OpGet, ID: 1, Key: "hi"})
+	fmt.Printf("% x\n", b)
+	// Output:
+	// 00 00 00 08 02 00 00 00 01 02 68 69
+}
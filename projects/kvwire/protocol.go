@@ -0,0 +1,202 @@
+package kvwire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The kvwire Protocol
+// ===================
+// A key-value store spoken over TCP. TCP delivers a stream of bytes,
+// not messages: one Read may return half a message or three and a bit.
+// So every message travels in a frame whose length comes first, and
+// the reader always knows how many more bytes belong to it.
+//
+//	frame  = length uint32 | body           big-endian; length counts the body
+//	body   = op uint8 | id uint32 | fields
+//	string = len uvarint | bytes
+//
+//	op            fields                  meaning
+//	0x01 Ping     -                       liveness check
+//	0x02 Get      key string              read a key
+//	0x03 Set      key string, value string write a key
+//	0x04 Delete   key string              remove a key
+//	0x81 Pong     -                       answers Ping
+//	0x82 Value    value string            answers Get
+//	0x83 OK       -                       answers Set and Delete
+//	0x84 NotFound -                       answers Get and Delete
+//	0xFF Error    code uint16, text string the request failed
+//
+// Every request carries an id, and its response carries the same one.
+// Clients can therefore send many requests without waiting - pipelining
+// - and match the answers as they come.
+//
+// Fixed-width integers come from encoding/binary's byte-order helpers;
+// strings use uvarint lengths, which take one byte for anything under
+// 128. Anything after the last field is an error: a parser that
+// ignored it would accept two versions of every message.
+
+// Op identifies a message
+type Op uint8
+
+// Requests and responses. Responses have the high bit set.
+const (
+	OpPing     Op = 0x01
+	OpGet      Op = 0x02
+	OpSet      Op = 0x03
+	OpDelete   Op = 0x04
+	OpPong     Op = 0x81
+	OpValue    Op = 0x82
+	OpOK       Op = 0x83
+	OpNotFound Op = 0x84
+	OpError    Op = 0xFF
+)
+
+// Limits. A frame's length is checked before its body is read, so a
+// peer cannot make us allocate 4 GB by sending four bytes.
+const (
+	MaxFrame = 1 << 20
+	MaxKey   = 1024
+
+	headerSize = 4 // the length prefix
+	bodyHeader = 5 // op and id
+)
+
+// Error codes sent in OpError
+const (
+	CodeMalformed uint16 = 1 // a field was missing, too long, or followed by extra bytes
+	CodeUnknownOp uint16 = 2
+	CodeTooLarge  uint16 = 3 // the frame exceeded MaxFrame; the server closes the connection
+)
+
+var (
+	ErrFrameTooLarge = errors.New("kvwire: frame exceeds MaxFrame")
+	ErrMalformed     = errors.New("kvwire: malformed message")
+	ErrUnknownOp     = errors.New("kvwire: unknown op")
+)
+
+// Message is any request or response. Op decides which fields count.
+type Message struct {
+	Op    Op
+	ID    uint32
+	Key   string // Get, Set, Delete
+	Value []byte // Set, Value
+	Code  uint16 // Error
+	Text  string // Error
+}
+
+func (m Message) String() string {
+	return fmt.Sprintf("{%#x id=%d key=%q value=%q code=%d text=%q}", uint8(m.Op), m.ID, m.Key, m.Value, m.Code, m.Text)
+}
+
+// AppendFrame appends m, framed, to buf
+func AppendFrame(buf []byte, m Message) ([]byte, error) {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0) // the length, filled in below
+	buf = append(buf, byte(m.Op))
+	buf = binary.BigEndian.AppendUint32(buf, m.ID)
+
+	switch m.Op {
+	case OpPing, OpPong, OpOK, OpNotFound:
+	case OpGet, OpDelete:
+		if len(m.Key) > MaxKey {
+			return buf[:start], fmt.Errorf("kvwire: key of %d bytes exceeds MaxKey", len(m.Key))
+		}
+		buf = appendString(buf, m.Key)
+	case OpSet:
+		if len(m.Key) > MaxKey {
+			return buf[:start], fmt.Errorf("kvwire: key of %d bytes exceeds MaxKey", len(m.Key))
+		}
+		buf = appendString(buf, m.Key)
+		buf = appendString(buf, string(m.Value))
+	case OpValue:
+		buf = appendString(buf, string(m.Value))
+	case OpError:
+		buf = binary.BigEndian.AppendUint16(buf, m.Code)
+		buf = appendString(buf, m.Text)
+	default:
+		return buf[:start], fmt.Errorf("%w %#x", ErrUnknownOp, uint8(m.Op))
+	}
+
+	n := len(buf) - start - headerSize
+	if n > MaxFrame {
+		return buf[:start], ErrFrameTooLarge
+	}
+	binary.BigEndian.PutUint32(buf[start:], uint32(n))
+	return buf, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// ParseMessage decodes a frame's body. When the op and id could be
+// read but the rest could not, the returned Message still has them, so
+// a server can address its error response.
+func ParseMessage(body []byte) (Message, error) {
+	if len(body) < bodyHeader {
+		return Message{}, ErrMalformed
+	}
+	m := Message{Op: Op(body[0]), ID: binary.BigEndian.Uint32(body[1:5])}
+	p := parser{b: body[bodyHeader:]}
+
+	switch m.Op {
+	case OpPing, OpPong, OpOK, OpNotFound:
+	case OpGet, OpDelete:
+		m.Key = p.key()
+	case OpSet:
+		m.Key = p.key()
+		m.Value = []byte(p.string())
+	case OpValue:
+		m.Value = []byte(p.string())
+	case OpError:
+		m.Code = p.uint16()
+		m.Text = p.string()
+	default:
+		return Message{Op: m.Op, ID: m.ID}, fmt.Errorf("%w %#x", ErrUnknownOp, uint8(m.Op))
+	}
+	if p.bad || len(p.b) != 0 {
+		return Message{Op: m.Op, ID: m.ID}, ErrMalformed
+	}
+	return m, nil
+}
+
+// parser reads fields from a body, remembering the first failure so the
+// caller checks once at the end
+type parser struct {
+	b   []byte
+	bad bool
+}
+
+func (p *parser) uint16() uint16 {
+	if len(p.b) < 2 {
+		p.bad = true
+		return 0
+	}
+	v := binary.BigEndian.Uint16(p.b)
+	p.b = p.b[2:]
+	return v
+}
+
+func (p *parser) string() string {
+	n, size := binary.Uvarint(p.b)
+	// size <= 0 is a truncated or overflowing varint; the comparison
+	// is in uint64 so a huge n cannot wrap around
+	if size <= 0 || n > uint64(len(p.b)-size) {
+		p.bad = true
+		return ""
+	}
+	s := string(p.b[size : size+int(n)])
+	p.b = p.b[size+int(n):]
+	return s
+}
+
+func (p *parser) key() string {
+	k := p.string()
+	if len(k) > MaxKey {
+		p.bad = true
+	}
+	return k
+}
@@ -0,0 +1,234 @@
+package kvwire
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// The Server
+// ==========
+// One goroutine per connection reads a request, handles it, and writes
+// the response. Responses go into a bufio.Writer that is flushed only
+// when no further request is already buffered: a client pipelining a
+// hundred requests gets its answers in a few large writes instead of a
+// hundred small ones.
+//
+// Bad input gets an OpError. If the frame itself was fine - an unknown
+// op, a malformed field - the connection carries on. A frame over
+// MaxFrame leaves the stream at an unknown position, so the server
+// answers and hangs up.
+//
+// Shutdown stops accepting, wakes idle connections by expiring their
+// read deadline, and lets each finish the request it is handling.
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = errors.New("kvwire: server closed")
+
+// Server is a kvwire server with an in-memory store
+type Server struct {
+	// IdleTimeout closes connections that send nothing for this long
+	IdleTimeout time.Duration
+	Logger      *slog.Logger
+
+	dataMu sync.RWMutex
+	data   map[string][]byte
+
+	mu       sync.Mutex
+	ln       net.Listener
+	conns    map[net.Conn]struct{}
+	shutdown bool
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a server with an empty store
+func NewServer() *Server {
+	return &Server{
+		IdleTimeout: 5 * time.Minute,
+		Logger:      slog.Default(),
+		data:        map[string][]byte{},
+		conns:       map[net.Conn]struct{}{},
+	}
+}
+
+// Serve accepts connections on ln until Shutdown
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.shutdown {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+	conn.Close()
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx
+// ends; then it closes the remaining connections
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shutdown = true
+	if s.ln != nil {
+		s.ln.Close()
+	}
+	for conn := range s.conns {
+		// A connection blocked reading wakes with a timeout error; one
+		// handling a request finishes it first
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// armRead sets the idle deadline for the next request, unless the
+// server is shutting down. Shutdown sets deadlines under the same lock,
+// so it cannot be overwritten here.
+func (s *Server) armRead(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return false
+	}
+	var deadline time.Time
+	if s.IdleTimeout > 0 {
+		deadline = time.Now().Add(s.IdleTimeout)
+	}
+	conn.SetReadDeadline(deadline)
+	return true
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	r := NewReader(conn)
+	w := bufio.NewWriter(conn)
+	var buf []byte
+	log := s.Logger.With("remote", conn.RemoteAddr().String())
+
+	// Answers to requests already handled go out whatever ends the loop
+	defer w.Flush()
+	for {
+		if !s.armRead(conn) {
+			return
+		}
+		body, err := r.ReadFrame()
+		if errors.Is(err, ErrFrameTooLarge) {
+			log.Warn("frame too large")
+			buf, _ = AppendFrame(buf[:0], Message{Op: OpError, Code: CodeTooLarge, Text: "frame too large"})
+			w.Write(buf)
+			return
+		}
+		if err != nil {
+			return // EOF, a reset, the idle timeout or Shutdown
+		}
+
+		resp := s.handle(body)
+		if buf, err = AppendFrame(buf[:0], resp); err != nil {
+			buf, _ = AppendFrame(buf[:0], Message{Op: OpError, ID: resp.ID, Code: CodeMalformed, Text: err.Error()})
+		}
+		if _, err := w.Write(buf); err != nil {
+			return
+		}
+		if !r.Buffered() {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle answers one request
+func (s *Server) handle(body []byte) Message {
+	m, err := ParseMessage(body)
+	switch {
+	case errors.Is(err, ErrUnknownOp):
+		return Message{Op: OpError, ID: m.ID, Code: CodeUnknownOp, Text: err.Error()}
+	case err != nil:
+		return Message{Op: OpError, ID: m.ID, Code: CodeMalformed, Text: err.Error()}
+	}
+
+	switch m.Op {
+	case OpPing:
+		return Message{Op: OpPong, ID: m.ID}
+	case OpGet:
+		s.dataMu.RLock()
+		v, ok := s.data[m.Key]
+		s.dataMu.RUnlock()
+		if !ok {
+			return Message{Op: OpNotFound, ID: m.ID}
+		}
+		return Message{Op: OpValue, ID: m.ID, Value: v}
+	case OpSet:
+		s.dataMu.Lock()
+		s.data[m.Key] = m.Value // ParseMessage made a copy; nothing else holds it
+		s.dataMu.Unlock()
+		return Message{Op: OpOK, ID: m.ID}
+	case OpDelete:
+		s.dataMu.Lock()
+		_, ok := s.data[m.Key]
+		delete(s.data, m.Key)
+		s.dataMu.Unlock()
+		if !ok {
+			return Message{Op: OpNotFound, ID: m.ID}
+		}
+		return Message{Op: OpOK, ID: m.ID}
+	}
+	// A response op sent as a request
+	return Message{Op: OpError, ID: m.ID, Code: CodeUnknownOp, Text: "not a request"}
+}
@@ -0,0 +1,61 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Wiring
+// ======
+// The app is the log in the middle, the command side writing to it,
+// and one consumer per projection reading from it. Nothing calls a
+// projection directly, and the projections do not know about each
+// other: a slow alert sender delays alerts, not balances.
+//
+//	Commands --Append--> Log --Since--> Consumer "balances" --> Balances
+//	                         \-Since--> Consumer "alerts"   --> Alerts
+
+// App wires the pieces together
+type App struct {
+	Log         *Log
+	Commands    *Commands
+	Balances    *Balances
+	Alerts      *Alerts
+	Checkpoints Checkpoints
+}
+
+// NewApp returns an app that alerts on withdrawals of threshold cents
+// or more through send
+func NewApp(threshold int64, send func(owner, msg string) error) *App {
+	log := NewLog()
+	return &App{
+		Log:         log,
+		Commands:    NewCommands(log),
+		Balances:    NewBalances(),
+		Alerts:      NewAlerts(threshold, send),
+		Checkpoints: &MemCheckpoints{},
+	}
+}
+
+// Run runs the consumers until ctx is done or the log is closed. A
+// consumer that fails stops only itself; Run returns the first error
+// that is not ctx's.
+func (a *App) Run(ctx context.Context) error {
+	consumers := []*Consumer{
+		{Name: "balances", Handle: a.Balances.Handle, Checkpoints: a.Checkpoints},
+		{Name: "alerts", Handle: a.Alerts.Handle, Checkpoints: a.Checkpoints},
+	}
+	errs := make([]error, len(consumers))
+	var wg sync.WaitGroup
+	for i, c := range consumers {
+		wg.Go(func() { errs[i] = c.Run(ctx, a.Log) })
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ctx.Err()) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Commands
+// ========
+// A command asks for a change and may be refused; an event records one
+// that happened and never is. The command side is the only writer:
+//
+//	1. load the account by replaying its stream
+//	2. decide: check the rules against that state
+//	3. append the resulting events at the version that was loaded
+//
+// If another command appended in between, step 3 fails with
+// ErrConflict and the whole thing runs again on the new state. That is
+// what stops two concurrent withdrawals from both spending the same
+// balance, without a lock held across the decision.
+//
+// The command side knows nothing of who reads the events. Adding a
+// projection or a notification changes no code here.
+
+// Event types
+const (
+	TypeOpened    = "account.opened"
+	TypeDeposited = "account.deposited"
+	TypeWithdrawn = "account.withdrawn"
+)
+
+// Opened is the payload of TypeOpened
+type Opened struct {
+	Owner string `json:"owner"`
+}
+
+// Moved is the payload of TypeDeposited and TypeWithdrawn
+type Moved struct {
+	Cents int64 `json:"cents"`
+}
+
+var (
+	ErrExists            = errors.New("ledger: account already exists")
+	ErrNoAccount         = errors.New("ledger: no such account")
+	ErrInvalidAmount     = errors.New("ledger: amount must be positive")
+	ErrInsufficientFunds = errors.New("ledger: insufficient funds")
+)
+
+// maxConflicts bounds the reload-and-retry loop; a stream this busy
+// needs a different design, not more retries
+const maxConflicts = 10
+
+// account is the command side's view: just what the rules need
+type account struct {
+	exists  bool
+	balance int64
+	version int
+}
+
+func (a *account) apply(e Event) error {
+	switch e.Type {
+	case TypeOpened:
+		a.exists = true
+	case TypeDeposited, TypeWithdrawn:
+		var m Moved
+		if err := json.Unmarshal(e.Data, &m); err != nil {
+			return fmt.Errorf("ledger: event %d: %w", e.Seq, err)
+		}
+		if e.Type == TypeWithdrawn {
+			m.Cents = -m.Cents
+		}
+		a.balance += m.Cents
+	}
+	a.version = e.Version
+	return nil
+}
+
+// Commands handles requests to change accounts
+type Commands struct {
+	log *Log
+}
+
+// NewCommands returns the command side of log
+func NewCommands(log *Log) *Commands {
+	return &Commands{log: log}
+}
+
+// execute loads the account, lets decide choose the changes, and
+// appends them, retrying on conflict. It returns the Seq of the last
+// event appended, for callers that want to wait for projections.
+func (c *Commands) execute(id string, decide func(account) ([]Change, error)) (uint64, error) {
+	for range maxConflicts {
+		var acct account
+		for _, e := range c.log.Stream(id) {
+			if err := acct.apply(e); err != nil {
+				return 0, err
+			}
+		}
+		changes, err := decide(acct)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", id, err)
+		}
+		appended, err := c.log.Append(id, acct.version, changes...)
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return appended[len(appended)-1].Seq, nil
+	}
+	return 0, fmt.Errorf("%s: %w %d times", id, ErrConflict, maxConflicts)
+}
+
+// Open creates an account
+func (c *Commands) Open(id, owner string) (uint64, error) {
+	return c.execute(id, func(a account) ([]Change, error) {
+		if a.exists {
+			return nil, ErrExists
+		}
+		return []Change{{TypeOpened, Opened{Owner: owner}}}, nil
+	})
+}
+
+// Deposit adds cents to an account
+func (c *Commands) Deposit(id string, cents int64) (uint64, error) {
+	return c.execute(id, func(a account) ([]Change, error) {
+		switch {
+		case !a.exists:
+			return nil, ErrNoAccount
+		case cents <= 0:
+			return nil, ErrInvalidAmount
+		}
+		return []Change{{TypeDeposited, Moved{cents}}}, nil
+	})
+}
+
+// Withdraw takes cents from an account that has them
+func (c *Commands) Withdraw(id string, cents int64) (uint64, error) {
+	return c.execute(id, func(a account) ([]Change, error) {
+		switch {
+		case !a.exists:
+			return nil, ErrNoAccount
+		case cents <= 0:
+			return nil, ErrInvalidAmount
+		case a.balance < cents:
+			return nil, ErrInsufficientFunds
+		}
+		return []Change{{TypeWithdrawn, Moved{cents}}}, nil
+	})
+}
@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Consumers
+// =========
+// A consumer reads the log from its checkpoint, hands each event to a
+// handler, and moves the checkpoint forward. Where it saves the
+// checkpoint decides the delivery guarantee:
+//
+//	save, then handle     at most once: a crash between the two loses
+//	                      the event
+//	handle, then save     at least once: a crash between the two hands
+//	                      the event over again after restart
+//
+// Exactly once is not on the list. A handler that sends an email and a
+// checkpoint in another store cannot both change in one step, so the
+// choice is which failure to live with. Losing a deposit is not an
+// option; seeing one twice is, if the handler can tell. Run therefore
+// saves after handling, and only once per batch - fewer writes, and a
+// wider window of redelivery, which the handlers must survive anyway.
+//
+// A handler error stops the consumer at that event and retries it after
+// a delay, forever: skipping it would break the order later events
+// depend on. A poison event blocks its consumer, and only it - the
+// others run on, because each has its own position. Real systems count
+// the attempts and park the event in a dead-letter queue.
+
+// Handler processes one event. It may see the same event again and
+// must not count it twice.
+type Handler func(Event) error
+
+// Checkpoints stores each consumer's position in the log
+type Checkpoints interface {
+	Load(consumer string) (uint64, error)
+	Save(consumer string, seq uint64) error
+}
+
+// MemCheckpoints is an in-memory Checkpoints
+type MemCheckpoints struct {
+	mu  sync.Mutex
+	pos map[string]uint64
+}
+
+// Load returns the saved position, or 0 for a new consumer
+func (m *MemCheckpoints) Load(consumer string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pos[consumer], nil
+}
+
+// Save records the position
+func (m *MemCheckpoints) Save(consumer string, seq uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pos == nil {
+		m.pos = map[string]uint64{}
+	}
+	m.pos[consumer] = seq
+	return nil
+}
+
+// Consumer delivers the log to one handler
+type Consumer struct {
+	Name        string
+	Handle      Handler
+	Checkpoints Checkpoints
+	RetryDelay  time.Duration // after a handler error; 0 means 10ms
+}
+
+// Run delivers events from the checkpoint on until ctx is done or the
+// log is closed. It returns nil when the log is closed, and the
+// context's error or a checkpoint error otherwise.
+func (c *Consumer) Run(ctx context.Context, log *Log) error {
+	delay := c.RetryDelay
+	if delay == 0 {
+		delay = 10 * time.Millisecond
+	}
+	pos, err := c.Checkpoints.Load(c.Name)
+	if err != nil {
+		return err
+	}
+	for {
+		events, changed := log.Since(pos)
+		for _, e := range events {
+			for c.Handle(e) != nil {
+				// The checkpoint stays behind e until it is handled
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			// A crash here - handled, not saved - is the redelivery
+			// window; the tests stop a consumer in it on purpose
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			pos = e.Seq
+		}
+		if len(events) > 0 {
+			if err := c.Checkpoints.Save(c.Name, pos); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-changed:
+		case <-log.Done():
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
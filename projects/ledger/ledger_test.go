@@ -0,0 +1,515 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Event-Driven Ledger - Tests
+// ===========================
+// Run with:
+//
+//   cd projects/ledger
+//   go test -v *.go
+//   go test -race *.go
+//
+// The redelivery tests stop a consumer in the window between handling
+// an event and saving its checkpoint - by cancelling its context from
+// inside the handler - then start it again from the same checkpoints,
+// which is what a crash and restart look like from the log's side.
+
+// run starts c on log and returns a function that stops it and
+// returns its error
+func run(t *testing.T, c *Consumer, log *Log) func() error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	errc := make(chan error, 1)
+	go func() { errc <- c.Run(ctx, log) }()
+	return func() error {
+		cancel()
+		return <-errc
+	}
+}
+
+func waitFor(t *testing.T, b *Balances, seq uint64) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if err := b.WaitFor(ctx, seq); err != nil {
+		t.Fatalf("WaitFor(%d): %v", seq, err)
+	}
+}
+
+func mustSeq(t *testing.T) func(uint64, error) uint64 {
+	return func(seq uint64, err error) uint64 {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return seq
+	}
+}
+
+// 1. Commands
+// ===========
+
+func TestCommandRules(t *testing.T) {
+	c := NewCommands(NewLog())
+	must := mustSeq(t)
+	must(c.Open("acc-1", "ada"))
+	must(c.Deposit("acc-1", 500))
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"open twice", second(c.Open("acc-1", "bob")), ErrExists},
+		{"deposit to nobody", second(c.Deposit("acc-2", 100)), ErrNoAccount},
+		{"withdraw from nobody", second(c.Withdraw("acc-2", 100)), ErrNoAccount},
+		{"zero deposit", second(c.Deposit("acc-1", 0)), ErrInvalidAmount},
+		{"negative withdrawal", second(c.Withdraw("acc-1", -5)), ErrInvalidAmount},
+		{"overdraw", second(c.Withdraw("acc-1", 501)), ErrInsufficientFunds},
+		{"exact balance", second(c.Withdraw("acc-1", 500)), nil},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+	// Refused commands wrote nothing
+	if n := len(c.log.Stream("acc-1")); n != 3 {
+		t.Errorf("acc-1 has %d events, want 3", n)
+	}
+}
+
+func second[A, B any](_ A, b B) B { return b }
+
+func TestConcurrentWithdrawalsNeverOverdraw(t *testing.T) {
+	log := NewLog()
+	c := NewCommands(log)
+	must := mustSeq(t)
+	must(c.Open("acc-1", "ada"))
+	must(c.Deposit("acc-1", 1000))
+
+	// Twenty withdrawals of 100 race for 1000. Each decides on the
+	// balance it loaded; Append refuses the ones that loaded a stale
+	// version, and they decide again.
+	var ok atomic.Int64
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			_, err := c.Withdraw("acc-1", 100)
+			switch {
+			case err == nil:
+				ok.Add(1)
+			case errors.Is(err, ErrInsufficientFunds), errors.Is(err, ErrConflict):
+			default:
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	var acct account
+	for _, e := range log.Stream("acc-1") {
+		acct.apply(e)
+	}
+	if ok.Load() > 10 {
+		t.Errorf("%d withdrawals of 100 from 1000 succeeded", ok.Load())
+	}
+	if want := 1000 - 100*ok.Load(); acct.balance != want || acct.balance < 0 {
+		t.Errorf("balance = %d, want %d", acct.balance, want)
+	}
+}
+
+// 2. The Log
+// ==========
+
+func TestLogOrdering(t *testing.T) {
+	log := NewLog()
+	c := NewCommands(log)
+	must := mustSeq(t)
+	must(c.Open("a", "ada"))
+	must(c.Open("b", "bob"))
+	must(c.Deposit("a", 1))
+	must(c.Deposit("b", 2))
+	must(c.Deposit("a", 3))
+
+	events, _ := log.Since(0)
+	versions := map[string]int{}
+	for i, e := range events {
+		if e.Seq != uint64(i+1) {
+			t.Errorf("event %d has Seq %d", i, e.Seq)
+		}
+		versions[e.Stream]++
+		if e.Version != versions[e.Stream] {
+			t.Errorf("event %d: %s version %d, want %d", e.Seq, e.Stream, e.Version, versions[e.Stream])
+		}
+	}
+	if tail, _ := log.Since(3); len(tail) != 2 || tail[0].Seq != 4 {
+		t.Errorf("Since(3) = %v", tail)
+	}
+
+	// A writer that read version 1 of "a" is too late
+	_, err := log.Append("a", 1, Change{TypeDeposited, Moved{5}})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("stale Append: err = %v, want ErrConflict", err)
+	}
+}
+
+func TestSinceWakesOnAppend(t *testing.T) {
+	log := NewLog()
+	_, changed := log.Since(0)
+	select {
+	case <-changed:
+		t.Fatal("changed closed before any Append")
+	default:
+	}
+	log.Append("a", 0, Change{TypeOpened, Opened{"ada"}})
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("Append did not close changed")
+	}
+}
+
+// 3. Projections
+// ==============
+
+func TestBalancesSkipsRedelivery(t *testing.T) {
+	log := NewLog()
+	c := NewCommands(log)
+	must := mustSeq(t)
+	must(c.Open("acc-1", "ada"))
+	must(c.Deposit("acc-1", 100))
+	must(c.Withdraw("acc-1", 30))
+
+	b := NewBalances()
+	events, _ := log.Since(0)
+	for range 3 {
+		for _, e := range events {
+			if err := b.Handle(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	got, _ := b.Get("acc-1")
+	if want := (Balance{Owner: "ada", Cents: 70, Version: 3}); got != want {
+		t.Errorf("after three deliveries: %+v, want %+v", got, want)
+	}
+}
+
+func TestBalancesRejectsGap(t *testing.T) {
+	log := NewLog()
+	c := NewCommands(log)
+	must := mustSeq(t)
+	must(c.Open("acc-1", "ada"))
+	must(c.Deposit("acc-1", 100))
+	must(c.Deposit("acc-1", 200))
+	events, _ := log.Since(0)
+
+	b := NewBalances()
+	b.Handle(events[0])
+	if err := b.Handle(events[2]); !errors.Is(err, ErrGap) {
+		t.Fatalf("version 3 after 1: err = %v, want ErrGap", err)
+	}
+	// Nothing was applied, and in order it all goes through
+	b.Handle(events[1])
+	b.Handle(events[2])
+	if got, _ := b.Get("acc-1"); got.Cents != 300 {
+		t.Errorf("balance = %d, want 300", got.Cents)
+	}
+}
+
+func TestBalancesSkipsUnknownTypes(t *testing.T) {
+	// A newer writer adds an event type this projection has never
+	// heard of. It must not stall on it, or lose its place.
+	log := NewLog()
+	log.Append("acc-1", 0,
+		Change{TypeOpened, Opened{"ada"}},
+		Change{"account.renamed", map[string]string{"name": "savings"}},
+		Change{TypeDeposited, Moved{100}},
+	)
+	b := NewBalances()
+	events, _ := log.Since(0)
+	for _, e := range events {
+		if err := b.Handle(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := b.Get("acc-1"); got.Cents != 100 || got.Version != 3 {
+		t.Errorf("got %+v, want 100 cents at version 3", got)
+	}
+}
+
+// 4. At-Least-Once Delivery
+// =========================
+
+// crashAfter wraps h so that the consumer is cancelled right after the
+// nth event is handled: handled, but not checkpointed
+func crashAfter(n int, cancel context.CancelFunc, h Handler) Handler {
+	calls := 0
+	return func(e Event) error {
+		err := h(e)
+		if calls++; calls == n {
+			cancel()
+		}
+		return err
+	}
+}
+
+func TestRedeliveryAfterCrash(t *testing.T) {
+	log := NewLog()
+	c := NewCommands(log)
+	must := mustSeq(t)
+	must(c.Open("acc-1", "ada"))
+	must(c.Deposit("acc-1", 100))
+	must(c.Deposit("acc-1", 100))
+	last := must(c.Deposit("acc-1", 100))
+
+	// The naive projection adds every deposit it is handed
+	var naive int64
+	naiveHandle := func(e Event) error {
+		if e.Type == TypeDeposited {
+			naive += 100
+		}
+		return nil
+	}
+	balances := NewBalances()
+	cps := &MemCheckpoints{}
+
+	for _, h := range []struct {
+		name   string
+		handle Handler
+	}{{"naive", naiveHandle}, {"balances", balances.Handle}} {
+		// First run: handles three events, then "crashes"
+		ctx, cancel := context.WithCancel(t.Context())
+		c1 := &Consumer{Name: h.name, Handle: crashAfter(3, cancel, h.handle), Checkpoints: cps}
+		if err := c1.Run(ctx, log); !errors.Is(err, context.Canceled) {
+			t.Fatalf("%s: first run: %v", h.name, err)
+		}
+		if pos, _ := cps.Load(h.name); pos != 0 {
+			t.Fatalf("%s: checkpoint %d saved mid-batch", h.name, pos)
+		}
+
+		// Restart from the same checkpoints: everything again
+		c2 := &Consumer{Name: h.name, Handle: h.handle, Checkpoints: cps}
+		stop := run(t, c2, log)
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			if pos, _ := cps.Load(h.name); pos == last {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: never caught up", h.name)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		stop()
+	}
+
+	if naive != 500 {
+		t.Errorf("naive total = %d, want the double-counted 500", naive)
+	}
+	if got, _ := balances.Get("acc-1"); got.Cents != 300 {
+		t.Errorf("balance = %d, want 300", got.Cents)
+	}
+}
+
+func TestAlertsSendOnce(t *testing.T) {
+	log := NewLog()
+	c := NewCommands(log)
+	must := mustSeq(t)
+	must(c.Open("acc-1", "ada"))
+	must(c.Deposit("acc-1", 100_000))
+	must(c.Withdraw("acc-1", 50_000))
+	must(c.Withdraw("acc-1", 10)) // under the threshold
+	must(c.Withdraw("acc-1", 20_000))
+
+	var sent []string
+	alerts := NewAlerts(10_000, func(owner, msg string) error {
+		sent = append(sent, owner+" <- "+msg)
+		return nil
+	})
+	events, _ := log.Since(0)
+	for range 2 {
+		for _, e := range events {
+			if err := alerts.Handle(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	want := []string{"ada <- acc-1: withdrawal of 500.00", "ada <- acc-1: withdrawal of 200.00"}
+	if fmt.Sprint(sent) != fmt.Sprint(want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
+
+func TestHandlerErrorRetriesSameEvent(t *testing.T) {
+	log := NewLog()
+	c := NewCommands(log)
+	must := mustSeq(t)
+	must(c.Open("acc-1", "ada"))
+	must(c.Deposit("acc-1", 100_000))
+	must(c.Withdraw("acc-1", 50_000))
+	must(c.Withdraw("acc-1", 50_000))
+
+	// The mail server is down for the first two tries. The second
+	// alert must wait behind the first, not overtake it.
+	var mu sync.Mutex
+	var tries int
+	var sent []string
+	alerts := NewAlerts(10_000, func(owner, msg string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if tries++; tries <= 2 {
+			return errors.New("smtp: 421 try again later")
+		}
+		sent = append(sent, msg)
+		return nil
+	})
+	cps := &MemCheckpoints{}
+	stop := run(t, &Consumer{Name: "alerts", Handle: alerts.Handle, Checkpoints: cps, RetryDelay: time.Millisecond}, log)
+	for deadline := time.Now().Add(5 * time.Second); alerts.Sent() < 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("alerts never sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if tries != 4 || len(sent) != 2 {
+		t.Errorf("%d tries, %d sent; want 4 and 2", tries, len(sent))
+	}
+}
+
+// 5. The App
+// ==========
+
+func TestStuckConsumerBlocksOnlyItself(t *testing.T) {
+	// Alerts can never be sent; balances must not notice
+	app := NewApp(1, func(string, string) error { return errors.New("down") })
+	ctx, cancel := context.WithCancel(t.Context())
+	errc := make(chan error, 1)
+	go func() { errc <- app.Run(ctx) }()
+
+	must := mustSeq(t)
+	must(app.Commands.Open("acc-1", "ada"))
+	must(app.Commands.Deposit("acc-1", 500))
+	seq := must(app.Commands.Withdraw("acc-1", 200))
+	waitFor(t, app.Balances, seq)
+	if got, _ := app.Balances.Get("acc-1"); got.Cents != 300 {
+		t.Errorf("balance = %d, want 300", got.Cents)
+	}
+	if pos, _ := app.Checkpoints.Load("alerts"); pos >= seq {
+		t.Errorf("alerts checkpoint %d passed an unsent alert", pos)
+	}
+
+	cancel()
+	if err := <-errc; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+}
+
+func TestLateProjectionReplaysHistory(t *testing.T) {
+	app := NewApp(1_000_000, func(string, string) error { return nil })
+	must := mustSeq(t)
+	must(app.Commands.Open("a", "ada"))
+	must(app.Commands.Open("b", "bob"))
+	must(app.Commands.Deposit("a", 700))
+	must(app.Commands.Deposit("b", 300))
+	last := must(app.Commands.Withdraw("a", 250))
+
+	// A projection added after the fact starts at 0 and sees it all
+	audit := NewBalances()
+	stop := run(t, &Consumer{Name: "audit", Handle: audit.Handle, Checkpoints: app.Checkpoints}, app.Log)
+	defer stop()
+	waitFor(t, audit, last)
+	for id, want := range map[string]int64{"a": 450, "b": 300} {
+		if got, _ := audit.Get(id); got.Cents != want {
+			t.Errorf("%s = %d, want %d", id, got.Cents, want)
+		}
+	}
+}
+
+func TestRunReturnsWhenLogCloses(t *testing.T) {
+	app := NewApp(1, func(string, string) error { return nil })
+	errc := make(chan error, 1)
+	go func() { errc <- app.Run(t.Context()) }()
+	app.Log.Close()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Close")
+	}
+}
+
+// 6. Benchmarks
+// =============
+
+func BenchmarkWithdraw(b *testing.B) {
+	c := NewCommands(NewLog())
+	c.Open("acc-1", "ada")
+	c.Deposit("acc-1", 1<<62)
+	for b.Loop() {
+		c.Withdraw("acc-1", 1)
+	}
+}
+
+func BenchmarkBalancesHandle(b *testing.B) {
+	log := NewLog()
+	c := NewCommands(log)
+	c.Open("acc-1", "ada")
+	c.Deposit("acc-1", 100)
+	events, _ := log.Since(0)
+	for b.Loop() {
+		bal := NewBalances()
+		for _, e := range events {
+			bal.Handle(e)
+		}
+	}
+}
+
+// 7. Examples
+// ===========
+
+func Example() {
+	app := NewApp(10_000, func(owner, msg string) error {
+		fmt.Println("alert to", owner+":", msg)
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.Run(ctx) }()
+
+	app.Commands.Open("acc-1", "ada")
+	app.Commands.Deposit("acc-1", 50_000)
+	seq, _ := app.Commands.Withdraw("acc-1", 12_500)
+	for app.Alerts.Sent() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err := app.Commands.Withdraw("acc-1", 99_999)
+	fmt.Println(err)
+
+	// Read your own write: wait for the projection to reach it
+	app.Balances.WaitFor(ctx, seq)
+	row, _ := app.Balances.Get("acc-1")
+	fmt.Printf("%s has %d.%02d\n", row.Owner, row.Cents/100, row.Cents%100)
+
+	cancel()
+	<-done
+	// Output:
+	// alert to ada: acc-1: withdrawal of 125.00
+	// acc-1: ledger: insufficient funds
+	// ada has 375.00
+}
@@ -0,0 +1,144 @@
+package ledger
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// The Event Log
+// =============
+// The broker from web/events, changed in two ways. It keeps every
+// event: here the log is the record of what happened, not a buffer
+// for live clients, and a projection added next year must be able to
+// read it from the start. And events belong to streams - one per
+// account - each with its own version, so a writer can say "append
+// only if nothing else has":
+//
+//	Seq       position in the whole log, from 1; consumers resume by it
+//	Stream    the account the event is about
+//	Version   position within the stream, from 1; Append checks it
+//
+// Consumers still wait the same way: Since returns the events after a
+// position and a channel that the next Append closes, under one lock.
+// A real system would put the log in a database table or Kafka; the
+// shape of the code around it is the same.
+
+// ErrConflict means the stream changed between reading and appending:
+// reload, decide again, and retry
+var ErrConflict = errors.New("ledger: stream changed since it was read")
+
+// Event is one fact, as stored. Data is JSON, so consumers decode only
+// the types they care about and skip the rest.
+type Event struct {
+	Seq     uint64          `json:"seq"`
+	Stream  string          `json:"stream"`
+	Version int             `json:"version"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Change is an event to append: a type and a payload to encode
+type Change struct {
+	Type string
+	Data any
+}
+
+// Log is an append-only, in-memory event log. It is safe for
+// concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	events   []Event
+	versions map[string]int
+	changed  chan struct{} // closed and replaced by each Append
+	done     chan struct{} // closed by Close
+	closed   bool
+}
+
+// NewLog returns an empty log
+func NewLog() *Log {
+	return &Log{
+		versions: map[string]int{},
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Append adds changes to stream if its version is still expected - 0
+// for a stream that does not exist yet - and returns them as stored.
+// All of them are appended, or none.
+func (l *Log) Append(stream string, expected int, changes ...Change) ([]Event, error) {
+	// Encode outside the lock; a bad payload fails before anything is
+	// written
+	data := make([]json.RawMessage, len(changes))
+	for i, c := range changes {
+		b, err := json.Marshal(c.Data)
+		if err != nil {
+			return nil, fmt.Errorf("ledger: encoding %s: %w", c.Type, err)
+		}
+		data[i] = b
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.versions[stream] != expected {
+		return nil, fmt.Errorf("%w: %s is at version %d, not %d", ErrConflict, stream, l.versions[stream], expected)
+	}
+	start := len(l.events)
+	for i, c := range changes {
+		l.events = append(l.events, Event{
+			Seq:     uint64(len(l.events) + 1),
+			Stream:  stream,
+			Version: expected + i + 1,
+			Type:    c.Type,
+			Data:    data[i],
+		})
+	}
+	l.versions[stream] = expected + len(changes)
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return slices.Clone(l.events[start:]), nil
+}
+
+// Since returns the events after seq and a channel that is closed by
+// the next Append
+func (l *Log) Since(seq uint64) ([]Event, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Seq n is at index n-1, but search anyway: a log read back from
+	// storage after compaction need not start at 1
+	i, _ := slices.BinarySearchFunc(l.events, seq+1, func(e Event, seq uint64) int {
+		return cmp.Compare(e.Seq, seq)
+	})
+	return slices.Clone(l.events[i:]), l.changed
+}
+
+// Stream returns one stream's events, oldest first
+func (l *Log) Stream(stream string) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []Event
+	for _, e := range l.events {
+		if e.Stream == stream {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Close stops every consumer waiting on the log
+func (l *Log) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		close(l.done)
+	}
+}
+
+// Done is closed by Close
+func (l *Log) Done() <-chan struct{} { return l.done }
@@ -0,0 +1,185 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Projections
+// ===========
+// A projection folds events into a read model shaped for one question:
+// what is each balance, who must hear about a large withdrawal. Each
+// keeps its own state and its own position, so one can be rebuilt,
+// added late or fall behind without touching the others. Two ways to
+// survive redelivery:
+//
+//	Balances   remembers the last version applied per stream and skips
+//	           anything at or below it. Free, because the log already
+//	           numbers the events, and it also catches events out of
+//	           order: a version that jumps ahead is an error, not a
+//	           balance quietly missing a deposit.
+//	Alerts     has a side effect outside its own state, so it records
+//	           which events it has acted on - the idempotency key is
+//	           the event's Seq - and checks before acting.
+//
+// Reads are eventually consistent: a command returns once its events
+// are in the log, before any projection has seen them. A caller that
+// must read its own write waits for the projection to reach the Seq
+// the command returned.
+//
+// Pitfalls:
+//
+//	balance += amount with no       every redelivery counts the money
+//	version check                   again
+//	dedupe set checked after        a crash after sending sends again;
+//	the side effect                 check first, record after success
+//	reading a projection right      it may not have the event yet; wait
+//	after a command                 for the Seq, or read the command side
+
+// ErrGap means an event arrived before one it follows
+var ErrGap = errors.New("ledger: event out of order")
+
+// Balance is one row of the Balances read model
+type Balance struct {
+	Owner   string
+	Cents   int64
+	Version int // last event applied from the account's stream
+}
+
+// Balances projects the log into a balance per account
+type Balances struct {
+	mu       sync.Mutex
+	accounts map[string]Balance
+	seq      uint64        // highest Seq applied
+	changed  chan struct{} // closed and replaced when seq moves
+}
+
+// NewBalances returns an empty projection
+func NewBalances() *Balances {
+	return &Balances{accounts: map[string]Balance{}, changed: make(chan struct{})}
+}
+
+// Handle applies e once, however often it is delivered
+func (b *Balances) Handle(e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	row := b.accounts[e.Stream]
+	switch {
+	case e.Version <= row.Version:
+		return nil // seen before: redelivery
+	case e.Version > row.Version+1:
+		return fmt.Errorf("%w: %s version %d after %d", ErrGap, e.Stream, e.Version, row.Version)
+	}
+
+	switch e.Type {
+	case TypeOpened:
+		var o Opened
+		if err := json.Unmarshal(e.Data, &o); err != nil {
+			return fmt.Errorf("ledger: event %d: %w", e.Seq, err)
+		}
+		row.Owner = o.Owner
+	case TypeDeposited, TypeWithdrawn:
+		var m Moved
+		if err := json.Unmarshal(e.Data, &m); err != nil {
+			return fmt.Errorf("ledger: event %d: %w", e.Seq, err)
+		}
+		if e.Type == TypeWithdrawn {
+			m.Cents = -m.Cents
+		}
+		row.Cents += m.Cents
+	}
+	// Unknown types still move the version: they are in the stream
+	row.Version = e.Version
+	b.accounts[e.Stream] = row
+
+	if e.Seq > b.seq {
+		b.seq = e.Seq
+		close(b.changed)
+		b.changed = make(chan struct{})
+	}
+	return nil
+}
+
+// Get returns an account's row
+func (b *Balances) Get(id string) (Balance, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	row, ok := b.accounts[id]
+	return row, ok
+}
+
+// WaitFor blocks until the projection has applied seq
+func (b *Balances) WaitFor(ctx context.Context, seq uint64) error {
+	for {
+		b.mu.Lock()
+		reached, changed := b.seq >= seq, b.changed
+		b.mu.Unlock()
+		if reached {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Alerts tells owners about withdrawals of at least Threshold cents.
+// Handle is called by one consumer at a time.
+type Alerts struct {
+	threshold int64
+	send      func(owner, msg string) error
+
+	mu     sync.Mutex
+	owners map[string]string
+	sent   map[uint64]bool // Seqs already acted on
+}
+
+// NewAlerts returns an Alerts that delivers with send
+func NewAlerts(threshold int64, send func(owner, msg string) error) *Alerts {
+	return &Alerts{threshold: threshold, send: send, owners: map[string]string{}, sent: map[uint64]bool{}}
+}
+
+// Handle sends at most one alert per event. A send error is returned,
+// so the consumer retries the event.
+func (a *Alerts) Handle(e Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch e.Type {
+	case TypeOpened:
+		// Setting a key is idempotent already
+		var o Opened
+		if err := json.Unmarshal(e.Data, &o); err != nil {
+			return fmt.Errorf("ledger: event %d: %w", e.Seq, err)
+		}
+		a.owners[e.Stream] = o.Owner
+	case TypeWithdrawn:
+		var m Moved
+		if err := json.Unmarshal(e.Data, &m); err != nil {
+			return fmt.Errorf("ledger: event %d: %w", e.Seq, err)
+		}
+		if m.Cents < a.threshold || a.sent[e.Seq] {
+			return nil
+		}
+		// A crash between send and the next line still sends twice. A
+		// real sender takes the Seq as an idempotency key, or the
+		// record is written with the message through an outbox table.
+		msg := fmt.Sprintf("%s: withdrawal of %d.%02d", e.Stream, m.Cents/100, m.Cents%100)
+		if err := a.send(a.owners[e.Stream], msg); err != nil {
+			return err
+		}
+		a.sent[e.Seq] = true
+	}
+	return nil
+}
+
+// Sent returns how many alerts have been sent
+func (a *Alerts) Sent() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.sent)
+}
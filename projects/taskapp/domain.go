@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Domain
+// ======
+// The innermost layer: what a task is and the rules it keeps, with no
+// idea that HTTP or SQL exist. It imports only the standard library's
+// basics, takes the time as an argument instead of reading a clock,
+// and reports broken rules as errors the outer layers translate.
+//
+//	title     1 to 200 characters, trimmed
+//	due       optional; may not be before the day the task is created
+//	done      set once by Complete, with the time; Reopen clears both
+
+// MaxTitle is the longest title, in characters
+const MaxTitle = 200
+
+var (
+	ErrNotFound     = errors.New("task not found")
+	ErrAlreadyDone  = errors.New("task is already done")
+	ErrNotDone      = errors.New("task is not done")
+	ErrInvalidTitle = fmt.Errorf("title must be 1 to %d characters", MaxTitle)
+	ErrDueInPast    = errors.New("due date is in the past")
+)
+
+// Task is one to-do item
+type Task struct {
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Due       *time.Time `json:"due,omitempty"`
+	Done      bool       `json:"done"`
+	Created   time.Time  `json:"created"`
+	Completed *time.Time `json:"completed,omitempty"`
+}
+
+// NewTask returns a valid, unsaved task created at now
+func NewTask(title string, due *time.Time, now time.Time) (Task, error) {
+	title, err := cleanTitle(title)
+	if err != nil {
+		return Task{}, err
+	}
+	// Compare days, not instants: a task due today is fine at 17:00
+	if due != nil && due.Before(now.Truncate(24*time.Hour)) {
+		return Task{}, ErrDueInPast
+	}
+	return Task{Title: title, Due: due, Created: now}, nil
+}
+
+func cleanTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if n := utf8.RuneCountInString(title); n == 0 || n > MaxTitle {
+		return "", ErrInvalidTitle
+	}
+	return title, nil
+}
+
+// Rename changes the title
+func (t *Task) Rename(title string) error {
+	title, err := cleanTitle(title)
+	if err != nil {
+		return err
+	}
+	t.Title = title
+	return nil
+}
+
+// Complete marks the task done at now
+func (t *Task) Complete(now time.Time) error {
+	if t.Done {
+		return ErrAlreadyDone
+	}
+	t.Done, t.Completed = true, &now
+	return nil
+}
+
+// Reopen marks a done task not done
+func (t *Task) Reopen() error {
+	if !t.Done {
+		return ErrNotDone
+	}
+	t.Done, t.Completed = false, nil
+	return nil
+}
+
+// Overdue reports whether the task is open and past its due date
+func (t Task) Overdue(now time.Time) bool {
+	return !t.Done && t.Due != nil && now.After(*t.Due)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Taskapp - End-to-End Tests
+// ==========================
+// Run with:
+//
+//   cd projects/taskapp
+//   go test -v -run E2E *.go
+//
+// The top of the pyramid: buildApp's real wiring - HTTP, use cases,
+// SQLite on a temp file - behind httptest.NewServer, driven by a real
+// http.Client. Few tests, each a whole story; the layers below already
+// cover the corners.
+
+// client is a tiny API client for the tests
+type client struct {
+	t   *testing.T
+	url string
+}
+
+func startApp(t *testing.T, dbPath string, clock *fakeClock) *client {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler, closeApp, err := buildApp(t.Context(), dbPath, clock.Now, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		srv.Close()
+		closeApp()
+	})
+	return &client{t: t, url: srv.URL}
+}
+
+// do sends body (if not "") and decodes the response into out (if not
+// nil), returning the status
+func (c *client) do(method, path, body string, out any) int {
+	c.t.Helper()
+	req, err := http.NewRequestWithContext(c.t.Context(), method, c.url+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			c.t.Fatalf("%s %s: decode %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (c *client) expect(method, path, body string, want int, out any) {
+	c.t.Helper()
+	if got := c.do(method, path, body, out); got != want {
+		c.t.Fatalf("%s %s = %d, want %d", method, path, got, want)
+	}
+}
+
+func TestE2ETaskLifecycle(t *testing.T) {
+	clock := &fakeClock{t: epoch}
+	c := startApp(t, filepath.Join(t.TempDir(), "tasks.db"), clock)
+
+	var task Task
+	due := epoch.Add(24 * time.Hour).Format(time.RFC3339)
+	c.expect("POST", "/tasks", `{"title": "ship it", "due": "`+due+`"}`, http.StatusCreated, &task)
+	if task.ID == 0 || task.Title != "ship it" || task.Due == nil {
+		t.Fatalf("created %+v", task)
+	}
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+
+	c.expect("PATCH", path, `{"title": "ship it today"}`, http.StatusOK, &task)
+	var got Task
+	c.expect("GET", path, "", http.StatusOK, &got)
+	if got.Title != "ship it today" {
+		t.Errorf("after rename: %+v", got)
+	}
+
+	// Two days on, the task is overdue until it is done
+	clock.Advance(48 * time.Hour)
+	var overdue []Task
+	c.expect("GET", "/tasks?overdue=true", "", http.StatusOK, &overdue)
+	if len(overdue) != 1 || overdue[0].ID != task.ID {
+		t.Errorf("overdue = %+v", overdue)
+	}
+	c.expect("POST", path+"/complete", "", http.StatusOK, &task)
+	if !task.Done || !task.Completed.Equal(clock.Now()) {
+		t.Errorf("completed %+v", task)
+	}
+	c.expect("POST", path+"/complete", "", http.StatusConflict, nil)
+	c.expect("GET", "/tasks?overdue=true", "", http.StatusOK, &overdue)
+	if len(overdue) != 0 {
+		t.Errorf("overdue after complete = %+v", overdue)
+	}
+
+	var done []Task
+	c.expect("GET", "/tasks?done=true", "", http.StatusOK, &done)
+	if len(done) != 1 {
+		t.Errorf("done = %+v", done)
+	}
+
+	c.expect("DELETE", path, "", http.StatusNoContent, nil)
+	c.expect("GET", path, "", http.StatusNotFound, nil)
+}
+
+func TestE2EErrors(t *testing.T) {
+	clock := &fakeClock{t: epoch}
+	c := startApp(t, ":memory:", clock)
+	yesterday := epoch.Add(-24 * time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/tasks", `{"title": ""}`, http.StatusUnprocessableEntity},
+		{"POST", "/tasks", `{"title": "x", "due": "` + yesterday + `"}`, http.StatusUnprocessableEntity},
+		{"POST", "/tasks", `{"title": "x", "priority": 1}`, http.StatusBadRequest},
+		{"POST", "/tasks", `not json`, http.StatusBadRequest},
+		{"GET", "/tasks?done=maybe", "", http.StatusBadRequest},
+		{"GET", "/tasks?limit=0", "", http.StatusBadRequest},
+		{"GET", "/tasks/abc", "", http.StatusNotFound},
+		{"GET", "/tasks/999", "", http.StatusNotFound},
+		{"POST", "/tasks/999/reopen", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		var body map[string]string
+		if got := c.do(tt.method, tt.path, tt.body, &body); got != tt.want {
+			t.Errorf("%s %s %s = %d, want %d", tt.method, tt.path, tt.body, got, tt.want)
+		}
+		if body["error"] == "" {
+			t.Errorf("%s %s: no error message in the body", tt.method, tt.path)
+		}
+	}
+	// Routing errors come from ServeMux, in plain text
+	c.expect("PUT", "/tasks/1", "", http.StatusMethodNotAllowed, nil)
+}
+
+func TestE2EInternalErrorsAreHidden(t *testing.T) {
+	var logs bytes.Buffer
+	srv := httptest.NewServer(NewHTTPAdapter(NewTasks(failingRepo{}, time.Now), slog.New(slog.NewTextHandler(&logs, nil))))
+	defer srv.Close()
+	c := &client{t: t, url: srv.URL}
+
+	var body map[string]string
+	c.expect("GET", "/tasks/1", "", http.StatusInternalServerError, &body)
+	if body["error"] != "internal error" {
+		t.Errorf("body = %v, want the details kept back", body)
+	}
+	if !strings.Contains(logs.String(), errDown.Error()) {
+		t.Errorf("the log is missing the cause: %s", logs.String())
+	}
+}
+
+func TestE2EDataSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	clock := &fakeClock{t: epoch}
+
+	// Each startApp is its own server and connection pool; subtests
+	// run their cleanups before the next one starts
+	t.Run("first run", func(t *testing.T) {
+		startApp(t, path, clock).expect("POST", "/tasks", `{"title": "remember me"}`, http.StatusCreated, nil)
+	})
+	t.Run("second run", func(t *testing.T) {
+		var tasks []Task
+		startApp(t, path, clock).expect("GET", "/tasks", "", http.StatusOK, &tasks)
+		if len(tasks) != 1 || tasks[0].Title != "remember me" {
+			t.Errorf("after restart: %+v", tasks)
+		}
+	})
+}
+
+func Example() {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := func() time.Time { return epoch }
+	handler, closeApp, _ := buildApp(context.Background(), ":memory:", now, logger)
+	defer closeApp()
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, _ := http.Post(srv.URL+"/tasks", "application/json", strings.NewReader(`{"title": "read the lesson"}`))
+	resp.Body.Close()
+	fmt.Println(resp.StatusCode, resp.Header.Get("Location"))
+
+	resp, _ = http.Post(srv.URL+"/tasks/1/complete", "", nil)
+	var task Task
+	json.NewDecoder(resp.Body).Decode(&task)
+	resp.Body.Close()
+	fmt.Println(task.Title, task.Done)
+	// Output:
+	// 201 /tasks/1
+	// read the lesson true
+}
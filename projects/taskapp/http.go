@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTP Adapter
+// ============
+// The driving side: it turns requests into use-case calls and results
+// into responses. It knows JSON and status codes, and nothing about
+// storage. Every rule it enforces is about HTTP - a body that is not
+// JSON, an id that is not a number - and the rest are the domain's,
+// mapped to statuses in one place:
+//
+//	GET    /tasks?done=&overdue=&limit=   list
+//	POST   /tasks                         {"title", "due"} -> 201
+//	GET    /tasks/{id}
+//	PATCH  /tasks/{id}                    {"title"}
+//	POST   /tasks/{id}/complete
+//	POST   /tasks/{id}/reopen
+//	DELETE /tasks/{id}                    -> 204
+//
+//	ErrNotFound                      404
+//	ErrInvalidTitle, ErrDueInPast    422
+//	ErrAlreadyDone, ErrNotDone       409
+//	anything else                    500, details to the log only
+
+// HTTPAdapter serves the use cases over HTTP
+type HTTPAdapter struct {
+	tasks *Tasks
+	log   *slog.Logger
+}
+
+// NewHTTPAdapter returns the routes for tasks
+func NewHTTPAdapter(tasks *Tasks, log *slog.Logger) http.Handler {
+	a := &HTTPAdapter{tasks: tasks, log: log}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", a.handle(a.list))
+	mux.HandleFunc("POST /tasks", a.handle(a.add))
+	mux.HandleFunc("GET /tasks/{id}", a.handle(a.get))
+	mux.HandleFunc("PATCH /tasks/{id}", a.handle(a.rename))
+	mux.HandleFunc("POST /tasks/{id}/complete", a.handle(a.complete))
+	mux.HandleFunc("POST /tasks/{id}/reopen", a.handle(a.reopen))
+	mux.HandleFunc("DELETE /tasks/{id}", a.handle(a.delete))
+	return mux
+}
+
+// errBadRequest marks errors in the request itself
+var errBadRequest = errors.New("bad request")
+
+// handle adapts a handler that returns its error, as in
+// projects/bookshelf
+func (a *HTTPAdapter) handle(h func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := h(w, r)
+		if err == nil {
+			return
+		}
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrInvalidTitle), errors.Is(err, ErrDueInPast):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, ErrAlreadyDone), errors.Is(err, ErrNotDone):
+			status = http.StatusConflict
+		}
+		msg := err.Error()
+		if status == http.StatusInternalServerError {
+			a.log.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+			msg = "internal error"
+		}
+		writeJSON(w, status, map[string]string{"error": msg})
+	}
+}
+
+func (a *HTTPAdapter) list(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	if q.Get("overdue") == "true" {
+		tasks, err := a.tasks.Overdue(r.Context())
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, tasks)
+		return nil
+	}
+	var f Filter
+	if s := q.Get("done"); s != "" {
+		done, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%w: done=%q", errBadRequest, s)
+		}
+		f.Done = &done
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return fmt.Errorf("%w: limit=%q", errBadRequest, s)
+		}
+		f.Limit = n
+	}
+	tasks, err := a.tasks.List(r.Context(), f)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, tasks)
+	return nil
+}
+
+func (a *HTTPAdapter) add(w http.ResponseWriter, r *http.Request) error {
+	var in struct {
+		Title string     `json:"title"`
+		Due   *time.Time `json:"due"`
+	}
+	if err := decode(r, &in); err != nil {
+		return err
+	}
+	t, err := a.tasks.Add(r.Context(), in.Title, in.Due)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", fmt.Sprintf("/tasks/%d", t.ID))
+	writeJSON(w, http.StatusCreated, t)
+	return nil
+}
+
+func (a *HTTPAdapter) get(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	t, err := a.tasks.Get(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, t)
+	return nil
+}
+
+func (a *HTTPAdapter) rename(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	var in struct {
+		Title string `json:"title"`
+	}
+	if err := decode(r, &in); err != nil {
+		return err
+	}
+	t, err := a.tasks.Rename(r.Context(), id, in.Title)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, t)
+	return nil
+}
+
+func (a *HTTPAdapter) complete(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	t, err := a.tasks.Complete(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, t)
+	return nil
+}
+
+func (a *HTTPAdapter) reopen(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	t, err := a.tasks.Reopen(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, t)
+	return nil
+}
+
+func (a *HTTPAdapter) delete(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	if err := a.tasks.Delete(r.Context(), id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// pathID parses {id}. A malformed id names no task: 404, not 400.
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, ErrNotFound
+	}
+	return id, nil
+}
+
+// decode reads one JSON object of at most 64 KiB, rejecting unknown
+// fields; projects/bookshelf/decode.go has the full version
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	return nil
+}
+
+// writeJSON sends v. Once the status is written an encoding error
+// cannot change it, so there is nothing to return.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
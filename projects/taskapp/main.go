@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Taskapp - Hexagonal Architecture
+// ================================
+// A to-do API in three layers, where every dependency points inward:
+//
+//	adapters     http.go (driving: calls in)       sqlite.go, memory.go
+//	                                               (driven: called out)
+//	use cases    usecases.go: Tasks, and the ports it needs - TaskRepo,
+//	             Clock - declared as interfaces beside it
+//	domain       domain.go: Task and its rules
+//
+// The domain imports nothing of ours. The use cases import the domain.
+// Adapters import both, and main.go - the composition root - is the one
+// file that knows every concrete type and wires them together.
+//
+// In a module each layer would be a package, and the compiler would
+// enforce the arrows. These lessons run as loose files, so the layers
+// are files in one package, and TestArchitecture in unit_test.go
+// enforces the rule instead: domain.go and usecases.go may not import
+// net/http, database/sql or a driver.
+//
+// The test pyramid follows the layers:
+//
+//	unit_test.go          many: domain rules and use cases on MemRepo
+//	                      and a fake clock; no I/O, microseconds each
+//	integration_test.go   some: one contract suite run against MemRepo
+//	                      and SQLiteRepo on a temp file
+//	e2e_test.go           few: the real wiring from buildApp behind
+//	                      httptest.NewServer, driven by an http.Client
+//
+// Run with:
+//
+//	cd projects/taskapp
+//	go run main.go domain.go usecases.go http.go sqlite.go memory.go minisql_*.go -db tasks.db
+//	curl -s -X POST localhost:8080/tasks -d '{"title": "write the README"}'
+//
+// and test with:
+//
+//	go test -v *.go
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "listen address")
+	dbPath := flag.String("db", "tasks.db", "SQLite database file, or :memory: for the in-memory adapter")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	handler, closeApp, err := buildApp(ctx, *dbPath, time.Now, logger)
+	if err != nil {
+		logger.Error("start", "err", err)
+		os.Exit(1)
+	}
+	defer closeApp()
+
+	srv := &http.Server{Addr: *addr, Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	logger.Info("listening", "addr", *addr, "db", *dbPath)
+
+	select {
+	case err := <-serveErr:
+		logger.Error("server failed", "err", err)
+		closeApp()
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown", "err", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("server", "err", err)
+	}
+}
+
+// buildApp is the composition root: it picks the adapters and wires
+// them to the use cases. The end-to-end tests call it too, so they run
+// the wiring main runs.
+func buildApp(ctx context.Context, dbPath string, now Clock, logger *slog.Logger) (http.Handler, func() error, error) {
+	var repo TaskRepo
+	closeApp := func() error { return nil }
+	if dbPath == ":memory:" {
+		repo = NewMemRepo()
+	} else {
+		db, err := OpenDB(ctx, dbPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo, closeApp = NewSQLiteRepo(db), db.Close
+	}
+	return NewHTTPAdapter(NewTasks(repo, now), logger), closeApp, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// In-Memory Adapter
+// =================
+// A second TaskRepo, for the use-case tests: no files, no driver, and
+// fast enough to run thousands of cases. It is not a mock - it keeps
+// real state and passes the same contract tests as the SQLite adapter
+// (integration_test.go), which is what makes tests against it worth
+// trusting.
+
+// MemRepo is a TaskRepo in a map. It is safe for concurrent use.
+type MemRepo struct {
+	mu     sync.Mutex
+	tasks  map[int64]Task
+	nextID int64
+}
+
+// NewMemRepo returns an empty repo
+func NewMemRepo() *MemRepo {
+	return &MemRepo{tasks: map[int64]Task{}}
+}
+
+// Add stores t with the next ID
+func (r *MemRepo) Add(_ context.Context, t Task) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	t.ID = r.nextID
+	r.tasks[t.ID] = clone(t)
+	return t, nil
+}
+
+// Get returns the task with id
+func (r *MemRepo) Get(_ context.Context, id int64) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tasks[id]
+	if !ok {
+		return Task{}, ErrNotFound
+	}
+	return clone(t), nil
+}
+
+// Update replaces the stored task with t's ID
+func (r *MemRepo) Update(_ context.Context, t Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[t.ID]; !ok {
+		return ErrNotFound
+	}
+	r.tasks[t.ID] = clone(t)
+	return nil
+}
+
+// Delete removes the task with id
+func (r *MemRepo) Delete(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.tasks, id)
+	return nil
+}
+
+// List returns the tasks matching f, by ID
+func (r *MemRepo) List(_ context.Context, f Filter) ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]int64, 0, len(r.tasks))
+	for id := range r.tasks {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	out := []Task{}
+	for _, id := range ids {
+		t := r.tasks[id]
+		if f.Done != nil && t.Done != *f.Done {
+			continue
+		}
+		if f.DueBefore != nil && (t.Due == nil || !t.Due.Before(*f.DueBefore)) {
+			continue
+		}
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+		out = append(out, clone(t))
+	}
+	return out, nil
+}
+
+// clone copies the pointed-to times, so a caller changing its copy
+// cannot change the stored task - the way a database would behave
+func clone(t Task) Task {
+	if t.Due != nil {
+		due := *t.Due
+		t.Due = &due
+	}
+	if t.Completed != nil {
+		c := *t.Completed
+		t.Completed = &c
+	}
+	return t
+}
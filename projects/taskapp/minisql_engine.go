@@ -0,0 +1,895 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"unicode"
+)
+
+// The minisql Engine
+// ==================
+// Tables live in memory; the file is a log. Each committed transaction
+// appends one line - a JSON array of the rows it wrote - and fsyncs
+// before Commit returns. Opening the file replays the log. This is the
+// redo half of write-ahead logging: a commit is durable once its line
+// is on disk, and a crash halfway through the write leaves a torn last
+// line, which replay drops, as if that transaction never committed.
+//
+// Locking is SQLite's rollback-journal model: one writer at a time,
+// holding the lock from BEGIN to COMMIT, and readers wait while it
+// does. A transaction writes the tables in place and keeps an undo
+// function per change, which Rollback runs backwards.
+//
+// There is no query planner and no index: every statement scans its
+// table. CREATE INDEX is accepted so the migrations read as they would
+// on a real database.
+
+// sqlError is an error from the engine. The code says what kind, so
+// the store can translate it the way it would a real driver's codes.
+type sqlError struct {
+	code errCode
+	msg  string
+}
+
+type errCode int
+
+const (
+	codeError errCode = iota // anything without a code of its own
+	codeBusy
+	codeMismatch
+	codeNotNull
+	codeUnique
+	codeCheck
+	codeForeignKey
+)
+
+func (e *sqlError) Error() string { return "minisql: " + e.msg }
+
+func sqlErrorf(code errCode, format string, args ...any) error {
+	return &sqlError{code, fmt.Sprintf(format, args...)}
+}
+
+// database is one file's tables, shared by every connection to it
+type database struct {
+	path string
+	refs int // open connections, under registry's lock
+
+	lock    sync.RWMutex // writers hold it from BEGIN to COMMIT
+	log     *os.File
+	size    int64 // the log's length after the last commit
+	version int   // PRAGMA user_version
+	tables  map[string]*table
+	indexes map[string]string // index name to table
+}
+
+// registry maps each open file to its database, so connections to the
+// same file share one set of tables and one lock
+var registry = struct {
+	sync.Mutex
+	dbs map[string]*database
+}{dbs: map[string]*database{}}
+
+// openDatabase returns the database for path, creating the file if it
+// does not exist and replaying its log if nothing has it open yet
+func openDatabase(path string) (*database, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if db, ok := registry.dbs[abs]; ok {
+		db.refs++
+		return db, nil
+	}
+	f, err := os.OpenFile(abs, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	db := &database{path: abs, refs: 1, log: f, tables: map[string]*table{}, indexes: map[string]string{}}
+	if err := db.replay(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	registry.dbs[abs] = db
+	return db, nil
+}
+
+// release drops one connection's reference, closing the file after
+// the last one. The next open replays the log from disk.
+func (db *database) release() error {
+	registry.Lock()
+	defer registry.Unlock()
+	if db.refs--; db.refs > 0 {
+		return nil
+	}
+	delete(registry.dbs, db.path)
+	return db.log.Close()
+}
+
+// The Log
+// =======
+
+// change is one entry of a commit's log line: a schema statement, a
+// new user_version, a row's new values, or a deleted row
+type change struct {
+	DDL     string `json:"ddl,omitempty"`
+	Version *int   `json:"version,omitempty"`
+	Table   string `json:"table,omitempty"`
+	ID      int64  `json:"id,omitempty"`
+	Row     []any  `json:"row,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+
+	undo func()
+}
+
+func (db *database) replay() error {
+	r := bufio.NewReader(db.log)
+	w := &writer{db: db} // collects the changes replay makes, then dropped
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line with no newline is a commit that never finished
+			if len(line) > 0 {
+				return db.log.Truncate(db.size)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var changes []change
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber() // int64 survives; float64 would round past 2^53
+		if err := dec.Decode(&changes); err != nil {
+			return fmt.Errorf("log at byte %d: %w", db.size, err)
+		}
+		for _, c := range changes {
+			if err := db.redo(w, c); err != nil {
+				return fmt.Errorf("log at byte %d: %w", db.size, err)
+			}
+		}
+		db.size += int64(len(line))
+	}
+}
+
+func (db *database) redo(w *writer, c change) error {
+	switch {
+	case c.DDL != "":
+		stmts, _, err := parse(c.DDL)
+		if err != nil {
+			return err
+		}
+		_, err = db.run(w, stmts[0], nil)
+		return err
+	case c.Version != nil:
+		db.version = *c.Version
+		return nil
+	}
+	t, err := db.table(c.Table)
+	if err != nil {
+		return err
+	}
+	if c.Deleted {
+		t.remove(c.ID)
+		return nil
+	}
+	vals := make([]any, len(c.Row))
+	for i, v := range c.Row {
+		if n, ok := v.(json.Number); ok {
+			if vals[i], err = n.Int64(); err != nil {
+				return err
+			}
+		} else {
+			vals[i] = v
+		}
+	}
+	t.put(c.ID, vals)
+	return nil
+}
+
+// writer is a transaction in progress: the changes to log at commit
+// and to undo at rollback
+type writer struct {
+	db      *database
+	changes []change
+}
+
+func (w *writer) record(c change) {
+	w.changes = append(w.changes, c)
+}
+
+func (w *writer) put(t *table, id int64, vals []any) {
+	old, had := t.get(id)
+	t.put(id, vals)
+	w.record(change{Table: t.name, ID: id, Row: vals, undo: func() {
+		if had {
+			t.put(id, old)
+		} else {
+			t.remove(id)
+		}
+	}})
+}
+
+func (w *writer) remove(t *table, id int64) {
+	old, _ := t.get(id)
+	t.remove(id)
+	w.record(change{Table: t.name, ID: id, Deleted: true, undo: func() { t.put(id, old) }})
+}
+
+// commit appends the changes as one line and syncs it. If the write
+// fails the file is cut back, so no torn line is left behind for the
+// next commit to follow.
+func (w *writer) commit() error {
+	if len(w.changes) == 0 {
+		return nil
+	}
+	line, err := json.Marshal(w.changes)
+	if err != nil {
+		w.rollback(0)
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := w.db.log.Write(line); err != nil {
+		w.db.log.Truncate(w.db.size)
+		w.rollback(0)
+		return err
+	}
+	if err := w.db.log.Sync(); err != nil {
+		w.db.log.Truncate(w.db.size)
+		w.rollback(0)
+		return err
+	}
+	w.db.size += int64(len(line))
+	w.changes = nil
+	return nil
+}
+
+// rollback undoes the changes past the first n, newest first
+func (w *writer) rollback(n int) {
+	for i := len(w.changes) - 1; i >= n; i-- {
+		w.changes[i].undo()
+	}
+	w.changes = w.changes[:n]
+}
+
+// Tables
+// ======
+
+type table struct {
+	name string
+	cols []colDef
+	pk   int   // the INTEGER PRIMARY KEY column, or -1
+	rows []row // by id
+}
+
+// row is a row and its rowid. A table with an INTEGER PRIMARY KEY uses
+// that column as the rowid, as SQLite does.
+type row struct {
+	id   int64
+	vals []any
+}
+
+func (db *database) table(name string) (*table, error) {
+	t, ok := db.tables[name]
+	if !ok {
+		return nil, sqlErrorf(codeError, "no such table: %s", name)
+	}
+	return t, nil
+}
+
+func (t *table) col(name string) (int, error) {
+	for i, c := range t.cols {
+		if c.name == name {
+			return i, nil
+		}
+	}
+	return 0, sqlErrorf(codeError, "no such column: %s.%s", t.name, name)
+}
+
+func (t *table) find(id int64) (int, bool) {
+	return slices.BinarySearchFunc(t.rows, id, func(r row, id int64) int { return cmp.Compare(r.id, id) })
+}
+
+func (t *table) get(id int64) ([]any, bool) {
+	if i, ok := t.find(id); ok {
+		return t.rows[i].vals, true
+	}
+	return nil, false
+}
+
+// put inserts or replaces a row. Rows are never changed in place, so
+// an undo function can keep the old slice.
+func (t *table) put(id int64, vals []any) {
+	i, ok := t.find(id)
+	if ok {
+		t.rows[i].vals = vals
+		return
+	}
+	t.rows = slices.Insert(t.rows, i, row{id, vals})
+}
+
+func (t *table) remove(id int64) {
+	if i, ok := t.find(id); ok {
+		t.rows = slices.Delete(t.rows, i, i+1)
+	}
+}
+
+// nextID is one past the largest rowid, so ids freed by a rollback are
+// used again
+func (t *table) nextID() int64 {
+	if len(t.rows) == 0 {
+		return 1
+	}
+	return t.rows[len(t.rows)-1].id + 1
+}
+
+// validate checks vals against every column's type and constraints
+func (db *database) validate(t *table, id int64, vals []any) error {
+	for i, c := range t.cols {
+		v := vals[i]
+		switch v.(type) {
+		case nil:
+			if c.notNull {
+				return sqlErrorf(codeNotNull, "NOT NULL constraint failed: %s.%s", t.name, c.name)
+			}
+			continue
+		case int64:
+			if c.typ != "INTEGER" {
+				return sqlErrorf(codeMismatch, "datatype mismatch: %s.%s is %s", t.name, c.name, c.typ)
+			}
+		case string:
+			if c.typ != "TEXT" {
+				return sqlErrorf(codeMismatch, "datatype mismatch: %s.%s is %s", t.name, c.name, c.typ)
+			}
+		}
+		if c.check != nil {
+			ok, err := eval(c.check, env{t, vals}, nil)
+			if err != nil {
+				return err
+			}
+			// NULL passes a CHECK; only false fails it
+			if ok == int64(0) {
+				return sqlErrorf(codeCheck, "CHECK constraint failed: %s.%s", t.name, c.name)
+			}
+		}
+		if c.unique && slices.ContainsFunc(t.rows, func(r row) bool { return r.id != id && r.vals[i] == v }) {
+			return sqlErrorf(codeUnique, "UNIQUE constraint failed: %s.%s", t.name, c.name)
+		}
+		if c.refTable != "" {
+			ref, err := db.table(c.refTable)
+			if err != nil {
+				return err
+			}
+			rc, err := ref.col(c.refCol)
+			if err != nil {
+				return err
+			}
+			if !slices.ContainsFunc(ref.rows, func(r row) bool { return r.vals[rc] == v }) {
+				return sqlErrorf(codeForeignKey, "FOREIGN KEY constraint failed: %s.%s", t.name, c.name)
+			}
+		}
+	}
+	return nil
+}
+
+// Running Statements
+// ==================
+
+// result is what a statement produced: rows for a query, counts for
+// the rest
+type result struct {
+	cols     []string
+	rows     [][]any
+	affected int64
+	lastID   int64
+}
+
+// run executes one statement. w is the transaction that records the
+// changes; it is nil only for statements that do not write.
+func (db *database) run(w *writer, s statement, args []any) (*result, error) {
+	switch s := s.(type) {
+	case createTable:
+		return db.createTable(w, s)
+	case createIndex:
+		return db.createIndex(w, s)
+	case insertStmt:
+		return db.insert(w, s, args)
+	case updateStmt:
+		return db.update(w, s, args)
+	case deleteStmt:
+		return db.delete(w, s, args)
+	case selectStmt:
+		return db.query(s, args)
+	case pragmaStmt:
+		if s.name != "user_version" {
+			return nil, sqlErrorf(codeError, "unknown pragma %s", s.name)
+		}
+		if s.value == nil {
+			return &result{cols: []string{"user_version"}, rows: [][]any{{int64(db.version)}}}, nil
+		}
+		v, err := eval(s.value, env{}, args)
+		n, ok := v.(int64)
+		if err != nil || !ok {
+			return nil, sqlErrorf(codeMismatch, "user_version must be an integer")
+		}
+		old, version := db.version, int(n)
+		db.version = version
+		w.record(change{Version: &version, undo: func() { db.version = old }})
+		return &result{}, nil
+	}
+	return nil, sqlErrorf(codeError, "unsupported statement %T", s)
+}
+
+func (db *database) createTable(w *writer, s createTable) (*result, error) {
+	if _, ok := db.tables[s.name]; ok {
+		return nil, sqlErrorf(codeError, "table %s already exists", s.name)
+	}
+	t := &table{name: s.name, cols: s.cols, pk: -1}
+	for i, c := range s.cols {
+		if slices.ContainsFunc(s.cols[:i], func(d colDef) bool { return d.name == c.name }) {
+			return nil, sqlErrorf(codeError, "duplicate column name: %s", c.name)
+		}
+		if c.pk {
+			t.pk = i
+		}
+	}
+	for _, c := range s.cols {
+		if err := checkColumns(c.check, t); err != nil {
+			return nil, err
+		}
+	}
+	db.tables[s.name] = t
+	w.record(change{DDL: s.src, undo: func() { delete(db.tables, s.name) }})
+	return &result{}, nil
+}
+
+func (db *database) createIndex(w *writer, s createIndex) (*result, error) {
+	if _, ok := db.indexes[s.name]; ok {
+		return nil, sqlErrorf(codeError, "index %s already exists", s.name)
+	}
+	t, err := db.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range s.cols {
+		if _, err := t.col(c); err != nil {
+			return nil, err
+		}
+	}
+	db.indexes[s.name] = s.table
+	w.record(change{DDL: s.src, undo: func() { delete(db.indexes, s.name) }})
+	return &result{}, nil
+}
+
+func (db *database) insert(w *writer, s insertStmt, args []any) (*result, error) {
+	t, err := db.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]any, len(t.cols))
+	for i, name := range s.cols {
+		c, err := t.col(name)
+		if err != nil {
+			return nil, err
+		}
+		if vals[c], err = eval(s.vals[i], env{}, args); err != nil {
+			return nil, err
+		}
+	}
+	id := t.nextID()
+	if t.pk >= 0 {
+		switch v := vals[t.pk].(type) {
+		case nil:
+			vals[t.pk] = id
+		case int64:
+			if _, exists := t.get(v); exists {
+				return nil, sqlErrorf(codeUnique, "UNIQUE constraint failed: %s.%s", t.name, t.cols[t.pk].name)
+			}
+			id = v
+		}
+	}
+	if err := db.validate(t, id, vals); err != nil {
+		return nil, err
+	}
+	w.put(t, id, vals)
+
+	res := &result{affected: 1, lastID: id}
+	if s.returning != "" {
+		c, err := t.col(s.returning)
+		if err != nil {
+			return nil, err
+		}
+		res.cols, res.rows = []string{s.returning}, [][]any{{vals[c]}}
+	}
+	return res, nil
+}
+
+func (db *database) update(w *writer, s updateStmt, args []any) (*result, error) {
+	t, err := db.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkColumns(s.where, t); err != nil {
+		return nil, err
+	}
+	cols := make([]int, len(s.set))
+	for i, a := range s.set {
+		if cols[i], err = t.col(a.col); err != nil {
+			return nil, err
+		}
+		if cols[i] == t.pk {
+			return nil, sqlErrorf(codeError, "cannot update the primary key %s.%s", t.name, a.col)
+		}
+		if err := checkColumns(a.val, t); err != nil {
+			return nil, err
+		}
+	}
+	res := &result{}
+	for _, r := range slices.Clone(t.rows) {
+		if ok, err := matches(s.where, env{t, r.vals}, args); err != nil || !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// Every SET sees the row as it was, not half updated
+		vals := slices.Clone(r.vals)
+		for i, a := range s.set {
+			if vals[cols[i]], err = eval(a.val, env{t, r.vals}, args); err != nil {
+				return nil, err
+			}
+		}
+		if err := db.validate(t, r.id, vals); err != nil {
+			return nil, err
+		}
+		w.put(t, r.id, vals)
+		res.affected++
+	}
+	return res, nil
+}
+
+func (db *database) delete(w *writer, s deleteStmt, args []any) (*result, error) {
+	t, err := db.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkColumns(s.where, t); err != nil {
+		return nil, err
+	}
+	res := &result{}
+	for _, r := range slices.Clone(t.rows) {
+		if ok, err := matches(s.where, env{t, r.vals}, args); err != nil || !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := db.referenced(t, r.vals); err != nil {
+			return nil, err
+		}
+		w.remove(t, r.id)
+		res.affected++
+	}
+	return res, nil
+}
+
+// referenced fails if a row of any table still refers to vals, a row
+// of t about to be deleted
+func (db *database) referenced(t *table, vals []any) error {
+	for _, other := range db.tables {
+		for i, c := range other.cols {
+			if c.refTable != t.name {
+				continue
+			}
+			rc, err := t.col(c.refCol)
+			if err != nil {
+				return err
+			}
+			if vals[rc] != nil && slices.ContainsFunc(other.rows, func(r row) bool { return r.vals[i] == vals[rc] }) {
+				return sqlErrorf(codeForeignKey, "FOREIGN KEY constraint failed: %s.%s", other.name, c.name)
+			}
+		}
+	}
+	return nil
+}
+
+func (db *database) query(s selectStmt, args []any) (*result, error) {
+	t, err := db.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkColumns(s.where, t); err != nil {
+		return nil, err
+	}
+	var matched [][]any
+	for _, r := range t.rows {
+		ok, err := matches(s.where, env{t, r.vals}, args)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, r.vals)
+		}
+	}
+	if s.count {
+		return &result{cols: []string{"count(*)"}, rows: [][]any{{int64(len(matched))}}}, nil
+	}
+
+	if s.orderBy != "" {
+		c, err := t.col(s.orderBy)
+		if err != nil {
+			return nil, err
+		}
+		slices.SortStableFunc(matched, func(a, b []any) int {
+			if s.desc {
+				return compare(b[c], a[c])
+			}
+			return compare(a[c], b[c])
+		})
+	}
+	if s.limit != nil {
+		v, err := eval(s.limit, env{}, args)
+		n, ok := v.(int64)
+		if err != nil || !ok {
+			return nil, sqlErrorf(codeMismatch, "LIMIT must be an integer")
+		}
+		// A negative LIMIT means no limit
+		if n >= 0 && int(n) < len(matched) {
+			matched = matched[:n]
+		}
+	}
+
+	idx := make([]int, len(s.cols))
+	for i, name := range s.cols {
+		if idx[i], err = t.col(name); err != nil {
+			return nil, err
+		}
+	}
+	res := &result{cols: s.cols, rows: make([][]any, len(matched))}
+	for i, vals := range matched {
+		out := make([]any, len(idx))
+		for j, c := range idx {
+			out[j] = vals[c]
+		}
+		res.rows[i] = out
+	}
+	return res, nil
+}
+
+// Expressions
+// ===========
+// Values are int64, string or nil for NULL. A comparison yields 1, 0,
+// or NULL when either side is NULL, and WHERE keeps a row only for 1.
+
+// env is the row an expression sees; t is nil where there is none
+type env struct {
+	t    *table
+	vals []any
+}
+
+// checkColumns reports a column that t does not have, before any row
+// is scanned - a typo fails even on an empty table
+func checkColumns(e expr, t *table) error {
+	switch e := e.(type) {
+	case column:
+		_, err := t.col(e.name)
+		return err
+	case binary:
+		return errors.Join(checkColumns(e.l, t), checkColumns(e.r, t))
+	case likeExpr:
+		return errors.Join(checkColumns(e.s, t), checkColumns(e.pattern, t), checkColumns(e.escape, t))
+	}
+	return nil
+}
+
+func matches(where expr, row env, args []any) (bool, error) {
+	if where == nil {
+		return true, nil
+	}
+	v, err := eval(where, row, args)
+	return v == int64(1), err
+}
+
+func eval(e expr, row env, args []any) (any, error) {
+	switch e := e.(type) {
+	case literal:
+		return e.v, nil
+	case param:
+		if e.n > len(args) {
+			return nil, sqlErrorf(codeError, "missing argument ?%d", e.n)
+		}
+		return args[e.n-1], nil
+	case column:
+		if row.t == nil {
+			return nil, sqlErrorf(codeError, "no such column: %s", e.name)
+		}
+		c, err := row.t.col(e.name)
+		if err != nil {
+			return nil, err
+		}
+		return row.vals[c], nil
+	case likeExpr:
+		s, err1 := eval(e.s, row, args)
+		p, err2 := eval(e.pattern, row, args)
+		var esc any
+		var err3 error
+		if e.escape != nil {
+			esc, err3 = eval(e.escape, row, args)
+		}
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return nil, err
+		}
+		if s == nil || p == nil {
+			return nil, nil
+		}
+		ss, ok1 := s.(string)
+		ps, ok2 := p.(string)
+		if !ok1 || !ok2 {
+			return nil, sqlErrorf(codeMismatch, "LIKE needs text")
+		}
+		var escape rune = -1
+		if e.escape != nil {
+			es, ok := esc.(string)
+			if !ok || len([]rune(es)) != 1 {
+				return nil, sqlErrorf(codeError, "ESCAPE expression must be a single character")
+			}
+			escape = []rune(es)[0]
+		}
+		return boolean(like([]rune(ss), []rune(ps), escape)), nil
+	case binary:
+		l, err := eval(e.l, row, args)
+		if err != nil {
+			return nil, err
+		}
+		r, err := eval(e.r, row, args)
+		if err != nil {
+			return nil, err
+		}
+		return apply(e.op, l, r)
+	}
+	return nil, sqlErrorf(codeError, "bad expression %T", e)
+}
+
+func apply(op string, l, r any) (any, error) {
+	switch op {
+	case "AND":
+		if l == int64(0) || r == int64(0) {
+			return int64(0), nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return boolean(truthy(l) && truthy(r)), nil
+	case "OR":
+		if truthy(l) || truthy(r) {
+			return int64(1), nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return int64(0), nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	if op == "+" || op == "-" {
+		a, ok1 := l.(int64)
+		b, ok2 := r.(int64)
+		if !ok1 || !ok2 {
+			return nil, sqlErrorf(codeMismatch, "%T %s %T: arithmetic needs integers", l, op, r)
+		}
+		// SQLite would switch to floating point; here it is an error
+		if op == "+" {
+			if n := a + b; (b > 0) == (n > a) || b == 0 {
+				return n, nil
+			}
+		} else if n := a - b; (b > 0) == (n < a) || b == 0 {
+			return n, nil
+		}
+		return nil, sqlErrorf(codeError, "integer overflow")
+	}
+	c := compare(l, r)
+	switch op {
+	case "=":
+		return boolean(c == 0), nil
+	case "<>":
+		return boolean(c != 0), nil
+	case "<":
+		return boolean(c < 0), nil
+	case "<=":
+		return boolean(c <= 0), nil
+	case ">":
+		return boolean(c > 0), nil
+	case ">=":
+		return boolean(c >= 0), nil
+	}
+	return nil, sqlErrorf(codeError, "unknown operator %s", op)
+}
+
+func boolean(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func truthy(v any) bool {
+	n, ok := v.(int64)
+	return ok && n != 0
+}
+
+// compare orders values as SQLite does: NULL, then integers, then text
+func compare(a, b any) int {
+	rank := func(v any) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case int64:
+			return 1
+		}
+		return 2
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return cmp.Compare(ra, rb)
+	}
+	switch a := a.(type) {
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case string:
+		return cmp.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// like matches s against a LIKE pattern: % is any run of characters, _
+// is one, and the escape character makes the next one literal. Like
+// SQLite's, it ignores case for ASCII letters only.
+func like(s, p []rune, escape rune) bool {
+	for len(p) > 0 {
+		c := p[0]
+		p = p[1:]
+		switch {
+		case c == escape:
+			if len(p) == 0 {
+				return false
+			}
+			c = p[0]
+			p = p[1:]
+		case c == '%':
+			for i := 0; i <= len(s); i++ {
+				if like(s[i:], p, escape) {
+					return true
+				}
+			}
+			return false
+		case c == '_':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			continue
+		}
+		if len(s) == 0 || foldASCII(s[0]) != foldASCII(c) {
+			return false
+		}
+		s = s[1:]
+	}
+	return len(s) == 0
+}
+
+func foldASCII(r rune) rune {
+	if r < unicode.MaxASCII {
+		return unicode.ToLower(r)
+	}
+	return r
+}
@@ -0,0 +1,688 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The SQL minisql Understands
+// ===========================
+// A real driver sends the statement text to its database, which parses
+// and plans it. minisql is its own database, so it parses here: a lexer
+// and a recursive-descent parser for the statements this package and
+// projects/taskapp run, and no more:
+//
+//	CREATE TABLE t (col TYPE [PRIMARY KEY] [NOT NULL] [UNIQUE] [CHECK (e)] [REFERENCES t(col)], ...)
+//	CREATE INDEX i ON t(col, ...)
+//	INSERT INTO t (col, ...) VALUES (e, ...) [RETURNING col]
+//	SELECT col, ... | count(*) FROM t [WHERE e] [ORDER BY col [DESC]] [LIMIT e]
+//	UPDATE t SET col = e, ... [WHERE e]
+//	DELETE FROM t [WHERE e]
+//	PRAGMA name [= value]
+//
+// Expressions are literals, columns, placeholders (? and ?N), + and -,
+// comparisons, LIKE ... ESCAPE, AND and OR. This is what "prepare"
+// buys: the text becomes a tree once, and each execution only
+// evaluates the tree with new arguments.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokParam
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string // identifiers and punctuation as written, strings unquoted
+	num  int64  // a number's value, or a placeholder's ?N (0 for a bare ?)
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		case unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(src) && unicode.IsDigit(rune(src[j])) {
+				j++
+			}
+			n, err := strconv.ParseInt(src[i:j], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("number %s at %d: %w", src[i:j], i, err)
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[i:j], num: n, pos: i})
+			i = j
+		case c == '\'':
+			// '' inside a string is one quote
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j >= len(src) {
+					return nil, fmt.Errorf("unterminated string at %d", i)
+				}
+				if src[j] == '\'' {
+					if j+1 < len(src) && src[j+1] == '\'' {
+						b.WriteByte('\'')
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(src[j])
+				j++
+			}
+			toks = append(toks, token{kind: tokString, text: b.String(), pos: i})
+			i = j + 1
+		case c == '?':
+			j := i + 1
+			for j < len(src) && unicode.IsDigit(rune(src[j])) {
+				j++
+			}
+			var n int64
+			if j > i+1 {
+				n, _ = strconv.ParseInt(src[i+1:j], 10, 64)
+				if n < 1 {
+					return nil, fmt.Errorf("placeholder %s at %d: numbering starts at 1", src[i:j], i)
+				}
+			}
+			toks = append(toks, token{kind: tokParam, text: src[i:j], num: n, pos: i})
+			i = j
+		default:
+			p := string(c)
+			if two := src[i:min(i+2, len(src))]; two == ">=" || two == "<=" || two == "<>" || two == "!=" {
+				p = two
+			} else if !strings.ContainsRune("(),*=<>+-;", rune(c)) {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			toks = append(toks, token{kind: tokPunct, text: p, pos: i})
+			i += len(p)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// Statements
+// ==========
+
+type statement interface {
+	writes() bool
+}
+
+type colDef struct {
+	name, typ        string // typ is INTEGER or TEXT
+	pk, notNull      bool
+	unique           bool
+	check            expr
+	refTable, refCol string
+}
+
+type createTable struct {
+	name string
+	cols []colDef
+	src  string // logged, and parsed again when the log is replayed
+}
+
+type createIndex struct {
+	name, table string
+	cols        []string
+	src         string
+}
+
+type insertStmt struct {
+	table     string
+	cols      []string
+	vals      []expr
+	returning string
+}
+
+type selectStmt struct {
+	table   string
+	cols    []string // nil with count
+	count   bool     // SELECT count(*)
+	where   expr
+	orderBy string
+	desc    bool
+	limit   expr
+}
+
+type assign struct {
+	col string
+	val expr
+}
+
+type updateStmt struct {
+	table string
+	set   []assign
+	where expr
+}
+
+type deleteStmt struct {
+	table string
+	where expr
+}
+
+type pragmaStmt struct {
+	name  string
+	value expr // nil to read
+}
+
+func (createTable) writes() bool  { return true }
+func (createIndex) writes() bool  { return true }
+func (insertStmt) writes() bool   { return true }
+func (selectStmt) writes() bool   { return false }
+func (updateStmt) writes() bool   { return true }
+func (deleteStmt) writes() bool   { return true }
+func (p pragmaStmt) writes() bool { return p.value != nil }
+
+// Expressions
+// ===========
+
+type expr interface{}
+
+type (
+	literal struct{ v any } // int64, string or nil
+	param   struct{ n int } // 1-based
+	column  struct{ name string }
+	binary  struct {
+		op   string // OR AND = <> < <= > >= + -
+		l, r expr
+	}
+	likeExpr struct {
+		s, pattern, escape expr
+	}
+)
+
+// Parsing
+// =======
+
+type parser struct {
+	src    string
+	toks   []token
+	i      int
+	params int // the highest placeholder number used
+}
+
+// parse splits src on semicolons and parses each statement
+func parse(src string) ([]statement, int, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, 0, err
+	}
+	p := &parser{src: src, toks: toks}
+	var stmts []statement
+	for {
+		for p.accept(";") {
+		}
+		if p.peek().kind == tokEOF {
+			break
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, 0, err
+		}
+		stmts = append(stmts, s)
+		if k := p.peek(); k.kind != tokEOF && k.text != ";" {
+			return nil, 0, p.errorf("unexpected %q", k.text)
+		}
+	}
+	if len(stmts) == 0 {
+		return nil, 0, fmt.Errorf("empty statement")
+	}
+	return stmts, p.params, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("syntax error at %d: %s", p.peek().pos, fmt.Sprintf(format, args...))
+}
+
+// accept consumes the next token if it is the keyword or punctuation
+// word, ignoring case
+func (p *parser) accept(word string) bool {
+	t := p.peek()
+	if (t.kind == tokIdent || t.kind == tokPunct) && strings.EqualFold(t.text, word) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(words ...string) error {
+	for _, w := range words {
+		if !p.accept(w) {
+			return p.errorf("expected %s, found %q", w, p.peek().text)
+		}
+	}
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return "", p.errorf("expected a name, found %q", t.text)
+	}
+	p.i++
+	return strings.ToLower(t.text), nil
+}
+
+// identList parses "(a, b, c)"
+func (p *parser) identList() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if !p.accept(",") {
+			return names, p.expect(")")
+		}
+	}
+}
+
+func (p *parser) statement() (statement, error) {
+	start := p.peek().pos
+	switch {
+	case p.accept("CREATE"):
+		if p.accept("INDEX") {
+			s, err := p.createIndex()
+			s.src = p.source(start)
+			return s, err
+		}
+		if err := p.expect("TABLE"); err != nil {
+			return nil, err
+		}
+		s, err := p.createTable()
+		s.src = p.source(start)
+		return s, err
+	case p.accept("INSERT"):
+		return p.insert()
+	case p.accept("SELECT"):
+		return p.selectStmt()
+	case p.accept("UPDATE"):
+		return p.update()
+	case p.accept("DELETE"):
+		return p.delete()
+	case p.accept("PRAGMA"):
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		s := pragmaStmt{name: name}
+		if p.accept("=") {
+			s.value, err = p.expr()
+		}
+		return s, err
+	}
+	return nil, p.errorf("unsupported statement %q", p.peek().text)
+}
+
+// source is the statement's text from start to the current token
+func (p *parser) source(start int) string {
+	return strings.TrimSpace(p.src[start:p.peek().pos])
+}
+
+func (p *parser) createTable() (createTable, error) {
+	var s createTable
+	var err error
+	if s.name, err = p.ident(); err != nil {
+		return s, err
+	}
+	if err := p.expect("("); err != nil {
+		return s, err
+	}
+	for {
+		var c colDef
+		if c.name, err = p.ident(); err != nil {
+			return s, err
+		}
+		typ, err := p.ident()
+		if err != nil {
+			return s, err
+		}
+		c.typ = strings.ToUpper(typ)
+		if c.typ != "INTEGER" && c.typ != "TEXT" {
+			return s, p.errorf("column %s: type %s is not INTEGER or TEXT", c.name, typ)
+		}
+	constraints:
+		for {
+			switch {
+			case p.accept("PRIMARY"):
+				if err := p.expect("KEY"); err != nil {
+					return s, err
+				}
+				c.pk = true
+			case p.accept("NOT"):
+				if err := p.expect("NULL"); err != nil {
+					return s, err
+				}
+				c.notNull = true
+			case p.accept("UNIQUE"):
+				c.unique = true
+			case p.accept("CHECK"):
+				if err := p.expect("("); err != nil {
+					return s, err
+				}
+				if c.check, err = p.expr(); err != nil {
+					return s, err
+				}
+				if err := p.expect(")"); err != nil {
+					return s, err
+				}
+			case p.accept("REFERENCES"):
+				if c.refTable, err = p.ident(); err != nil {
+					return s, err
+				}
+				cols, err := p.identList()
+				if err != nil {
+					return s, err
+				}
+				if len(cols) != 1 {
+					return s, p.errorf("REFERENCES names one column")
+				}
+				c.refCol = cols[0]
+			default:
+				break constraints
+			}
+		}
+		if c.pk && c.typ != "INTEGER" {
+			return s, p.errorf("column %s: only an INTEGER PRIMARY KEY is supported", c.name)
+		}
+		s.cols = append(s.cols, c)
+		if !p.accept(",") {
+			return s, p.expect(")")
+		}
+	}
+}
+
+func (p *parser) createIndex() (createIndex, error) {
+	var s createIndex
+	var err error
+	if s.name, err = p.ident(); err != nil {
+		return s, err
+	}
+	if err := p.expect("ON"); err != nil {
+		return s, err
+	}
+	if s.table, err = p.ident(); err != nil {
+		return s, err
+	}
+	s.cols, err = p.identList()
+	return s, err
+}
+
+func (p *parser) insert() (statement, error) {
+	var s insertStmt
+	var err error
+	if err := p.expect("INTO"); err != nil {
+		return nil, err
+	}
+	if s.table, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if s.cols, err = p.identList(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("VALUES", "("); err != nil {
+		return nil, err
+	}
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		s.vals = append(s.vals, e)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(s.vals) != len(s.cols) {
+		return nil, p.errorf("%d columns but %d values", len(s.cols), len(s.vals))
+	}
+	if p.accept("RETURNING") {
+		if s.returning, err = p.ident(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) selectStmt() (statement, error) {
+	var s selectStmt
+	var err error
+	if p.accept("count") {
+		if err := p.expect("(", "*", ")"); err != nil {
+			return nil, err
+		}
+		s.count = true
+	} else {
+		for {
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			s.cols = append(s.cols, name)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	if s.table, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if p.accept("WHERE") {
+		if s.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		if s.orderBy, err = p.ident(); err != nil {
+			return nil, err
+		}
+		s.desc = p.accept("DESC")
+		if !s.desc {
+			p.accept("ASC")
+		}
+	}
+	if p.accept("LIMIT") {
+		if s.limit, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) update() (statement, error) {
+	var s updateStmt
+	var err error
+	if s.table, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("SET"); err != nil {
+		return nil, err
+	}
+	for {
+		var a assign
+		if a.col, err = p.ident(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		if a.val, err = p.expr(); err != nil {
+			return nil, err
+		}
+		s.set = append(s.set, a)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if p.accept("WHERE") {
+		if s.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) delete() (statement, error) {
+	var s deleteStmt
+	var err error
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	if s.table, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if p.accept("WHERE") {
+		if s.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// expr parses with the usual precedence, loosest first: OR, AND,
+// comparisons and LIKE, then + and -
+func (p *parser) expr() (expr, error) { return p.or() }
+
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	for err == nil && p.accept("OR") {
+		var r expr
+		r, err = p.and()
+		l = binary{"OR", l, r}
+	}
+	return l, err
+}
+
+func (p *parser) and() (expr, error) {
+	l, err := p.comparison()
+	for err == nil && p.accept("AND") {
+		var r expr
+		r, err = p.comparison()
+		l = binary{"AND", l, r}
+	}
+	return l, err
+}
+
+func (p *parser) comparison() (expr, error) {
+	l, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("LIKE") {
+		e := likeExpr{s: l}
+		if e.pattern, err = p.additive(); err != nil {
+			return nil, err
+		}
+		if p.accept("ESCAPE") {
+			e.escape, err = p.additive()
+		}
+		return e, err
+	}
+	for _, op := range []string{"=", "<>", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			r, err := p.additive()
+			if op == "!=" {
+				op = "<>"
+			}
+			return binary{op, l, r}, err
+		}
+	}
+	return l, nil
+}
+
+func (p *parser) additive() (expr, error) {
+	l, err := p.primary()
+	for err == nil {
+		op := p.peek().text
+		if p.peek().kind != tokPunct || (op != "+" && op != "-") {
+			break
+		}
+		p.next()
+		var r expr
+		r, err = p.primary()
+		l = binary{op, l, r}
+	}
+	return l, err
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return literal{t.num}, nil
+	case tokString:
+		return literal{t.text}, nil
+	case tokParam:
+		n := int(t.num)
+		if n == 0 {
+			n = p.params + 1 // a bare ? takes the next number
+		}
+		p.params = max(p.params, n)
+		return param{n}, nil
+	case tokIdent:
+		if strings.EqualFold(t.text, "NULL") {
+			return literal{nil}, nil
+		}
+		return column{strings.ToLower(t.text)}, nil
+	case tokPunct:
+		switch t.text {
+		case "(":
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		case "-":
+			e, err := p.primary()
+			return binary{"-", literal{int64(0)}, e}, err
+		}
+	}
+	p.i--
+	return nil, p.errorf("unexpected %q", t.text)
+}
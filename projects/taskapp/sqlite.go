@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLite Adapter
+// ==============
+// The storage port implemented with database/sql. Opening, pragmas
+// and migrations follow storage/db.go; see that lesson for why each
+// one is there. What is particular to an adapter:
+//
+//	translation     rows become Tasks and back; times are stored as
+//	                Unix nanoseconds, NULL for a missing due date
+//	error mapping   sql.ErrNoRows and "no row changed" become the
+//	                domain's ErrNotFound; no caller sees a driver error
+//	                it would have to understand
+//	nothing else    no rules: a title's length is checked by the
+//	                domain, not by a CHECK the use cases cannot see
+//
+// The SQL is SQLite's, and it runs on minisql, the driver written in
+// storage/: minisql_driver.go, minisql_engine.go and minisql_parse.go
+// are copies of that lesson's driver.go, engine.go and parse.go, as the
+// repository has no go.mod and a project cannot import a lesson's
+// package. To run on SQLite, delete the copies, add
+//
+//	import _ "modernc.org/sqlite" // pure Go, no cgo
+//
+// and change the driver name and DSN in OpenDB; nothing else here
+// changes.
+
+// OpenDB opens (creating if needed) the database at path and brings
+// the schema up to date
+func OpenDB(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("minisql", "file:"+path+"?busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(4)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return db, nil
+}
+
+var migrations = []string{
+	// 1: tasks
+	`CREATE TABLE tasks (
+		id        INTEGER PRIMARY KEY,
+		title     TEXT    NOT NULL,
+		due       INTEGER,
+		done      INTEGER NOT NULL,
+		created   INTEGER NOT NULL,
+		completed INTEGER
+	);`,
+
+	// 2: the overdue query filters on both
+	`CREATE INDEX tasks_done_due ON tasks(done, due);`,
+}
+
+// migrate applies the migrations past the database's user_version, each
+// in a transaction with its version bump
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database is at version %d, newer than this program's %d", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, migrations[i])
+		if err == nil {
+			_, err = tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1))
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("version %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// SQLiteRepo is a TaskRepo backed by a database from OpenDB
+type SQLiteRepo struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepo returns a repo over db; the caller still owns db
+func NewSQLiteRepo(db *sql.DB) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+const taskColumns = `id, title, due, done, created, completed`
+
+// Add inserts t and returns it with its ID
+func (r *SQLiteRepo) Add(ctx context.Context, t Task) (Task, error) {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO tasks (title, due, done, created, completed) VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		t.Title, toNull(t.Due), t.Done, t.Created.UnixNano(), toNull(t.Completed),
+	).Scan(&t.ID)
+	if err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+// Get returns the task with id
+func (r *SQLiteRepo) Get(ctx context.Context, id int64) (Task, error) {
+	t, err := scanTask(r.db.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, ErrNotFound
+	}
+	return t, err
+}
+
+// Update writes every field of t to the row with its ID
+func (r *SQLiteRepo) Update(ctx context.Context, t Task) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE tasks SET title = ?, due = ?, done = ?, created = ?, completed = ? WHERE id = ?`,
+		t.Title, toNull(t.Due), t.Done, t.Created.UnixNano(), toNull(t.Completed), t.ID,
+	)
+	return oneRow(res, err)
+}
+
+// Delete removes the task with id
+func (r *SQLiteRepo) Delete(ctx context.Context, id int64) error {
+	return oneRow(r.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id))
+}
+
+// List returns the tasks matching f, by ID. The WHERE clause is built
+// from fixed pieces; every value is still a placeholder.
+func (r *SQLiteRepo) List(ctx context.Context, f Filter) ([]Task, error) {
+	var where []string
+	var args []any
+	if f.Done != nil {
+		where = append(where, "done = ?")
+		args = append(args, *f.Done)
+	}
+	if f.DueBefore != nil {
+		where = append(where, "due < ?")
+		args = append(args, f.DueBefore.UnixNano())
+	}
+	query := `SELECT ` + taskColumns + ` FROM tasks`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// scanTask reads one row of taskColumns from a *sql.Row or *sql.Rows
+func scanTask(row interface{ Scan(...any) error }) (Task, error) {
+	var t Task
+	var due, completed sql.Null[int64]
+	var created int64
+	if err := row.Scan(&t.ID, &t.Title, &due, &t.Done, &created, &completed); err != nil {
+		return Task{}, err
+	}
+	t.Created = time.Unix(0, created).UTC()
+	t.Due = fromNull(due)
+	t.Completed = fromNull(completed)
+	return t, nil
+}
+
+func toNull(t *time.Time) sql.Null[int64] {
+	if t == nil {
+		return sql.Null[int64]{}
+	}
+	return sql.Null[int64]{V: t.UnixNano(), Valid: true}
+}
+
+func fromNull(n sql.Null[int64]) *time.Time {
+	if !n.Valid {
+		return nil
+	}
+	t := time.Unix(0, n.V).UTC()
+	return &t
+}
+
+// oneRow turns an Exec that changed no row into ErrNotFound
+func oneRow(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	goparser "go/parser" // parser and token are minisql's
+	gotoken "go/token"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Taskapp - Unit Tests
+// ====================
+// Run with:
+//
+//   cd projects/taskapp
+//   go test -v -run 'Domain|Tasks|Architecture' *.go
+//
+// The base of the pyramid: the domain's rules and the use cases, on
+// MemRepo and a fake clock. No files, no sockets - these are the tests
+// to run on every save.
+
+var epoch = time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+
+// fakeClock is a settable time source, safe for the server's
+// goroutines; see testing/clock for a full version
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func newTasks(t *testing.T) (*Tasks, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{t: epoch}
+	return NewTasks(NewMemRepo(), clock.Now), clock
+}
+
+// 1. Domain Rules
+// ===============
+
+func TestDomainNewTask(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		due   *time.Time
+		want  error
+	}{
+		{"plain", "buy milk", nil, nil},
+		{"trimmed to nothing", "   ", nil, ErrInvalidTitle},
+		{"200 characters", strings.Repeat("é", MaxTitle), nil, nil},
+		{"201 characters", strings.Repeat("é", MaxTitle+1), nil, ErrInvalidTitle},
+		{"due later today", "x", ptr(epoch.Add(-time.Hour)), nil},
+		{"due tomorrow", "x", ptr(epoch.Add(24 * time.Hour)), nil},
+		{"due yesterday", "x", ptr(epoch.Add(-24 * time.Hour)), ErrDueInPast},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := NewTask(tt.title, tt.due, epoch)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err == nil && (task.Title != strings.TrimSpace(tt.title) || !task.Created.Equal(epoch)) {
+				t.Errorf("got %+v", task)
+			}
+		})
+	}
+}
+
+func TestDomainCompleteAndReopen(t *testing.T) {
+	task, _ := NewTask("x", nil, epoch)
+	if err := task.Reopen(); !errors.Is(err, ErrNotDone) {
+		t.Errorf("Reopen open task: %v", err)
+	}
+	done := epoch.Add(time.Hour)
+	if err := task.Complete(done); err != nil {
+		t.Fatal(err)
+	}
+	if !task.Done || !task.Completed.Equal(done) {
+		t.Errorf("after Complete: %+v", task)
+	}
+	if err := task.Complete(done); !errors.Is(err, ErrAlreadyDone) {
+		t.Errorf("Complete twice: %v", err)
+	}
+	if err := task.Reopen(); err != nil || task.Done || task.Completed != nil {
+		t.Errorf("after Reopen: %+v, %v", task, err)
+	}
+}
+
+func TestDomainOverdue(t *testing.T) {
+	due := epoch.Add(24 * time.Hour)
+	task, _ := NewTask("x", &due, epoch)
+	if task.Overdue(due) {
+		t.Error("overdue at the due time")
+	}
+	if !task.Overdue(due.Add(time.Second)) {
+		t.Error("not overdue after the due time")
+	}
+	task.Complete(due)
+	if task.Overdue(due.Add(time.Hour)) {
+		t.Error("a done task is overdue")
+	}
+	if noDue, _ := NewTask("x", nil, epoch); noDue.Overdue(epoch.AddDate(10, 0, 0)) {
+		t.Error("a task with no due date is overdue")
+	}
+}
+
+// 2. Use Cases
+// ============
+
+func TestTasksLifecycle(t *testing.T) {
+	ctx := t.Context()
+	tasks, clock := newTasks(t)
+	task, err := tasks.Add(ctx, "  write tests ", nil)
+	if err != nil || task.ID != 1 || task.Title != "write tests" {
+		t.Fatalf("Add = %+v, %v", task, err)
+	}
+
+	clock.Advance(time.Hour)
+	task, err = tasks.Complete(ctx, task.ID)
+	if err != nil || !task.Completed.Equal(epoch.Add(time.Hour)) {
+		t.Fatalf("Complete = %+v, %v", task, err)
+	}
+	// The change was saved, not just returned
+	if got, _ := tasks.Get(ctx, task.ID); !got.Done {
+		t.Error("Complete was not saved")
+	}
+	if _, err := tasks.Complete(ctx, task.ID); !errors.Is(err, ErrAlreadyDone) {
+		t.Errorf("Complete twice: %v", err)
+	}
+	if task, _ = tasks.Reopen(ctx, task.ID); task.Done {
+		t.Error("Reopen did not reopen")
+	}
+	if task, _ = tasks.Rename(ctx, task.ID, "write more tests"); task.Title != "write more tests" {
+		t.Errorf("Rename: %+v", task)
+	}
+	if err := tasks.Delete(ctx, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tasks.Get(ctx, task.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v", err)
+	}
+}
+
+func TestTasksRuleFailuresSaveNothing(t *testing.T) {
+	ctx := t.Context()
+	tasks, _ := newTasks(t)
+	task, _ := tasks.Add(ctx, "keep me", nil)
+	if _, err := tasks.Rename(ctx, task.ID, ""); !errors.Is(err, ErrInvalidTitle) {
+		t.Fatalf("err = %v", err)
+	}
+	if got, _ := tasks.Get(ctx, task.ID); got.Title != "keep me" {
+		t.Errorf("title = %q after a refused rename", got.Title)
+	}
+	for _, op := range []func() error{
+		func() error { _, err := tasks.Rename(ctx, 99, "x"); return err },
+		func() error { _, err := tasks.Complete(ctx, 99); return err },
+		func() error { return tasks.Delete(ctx, 99) },
+	} {
+		if err := op(); !errors.Is(err, ErrNotFound) {
+			t.Errorf("unknown id: %v", err)
+		}
+	}
+}
+
+func TestTasksOverdue(t *testing.T) {
+	ctx := t.Context()
+	tasks, clock := newTasks(t)
+	tomorrow, nextWeek := epoch.Add(24*time.Hour), epoch.AddDate(0, 0, 7)
+	tasks.Add(ctx, "no due date", nil)
+	a, _ := tasks.Add(ctx, "due tomorrow", &tomorrow)
+	b, _ := tasks.Add(ctx, "also due tomorrow", &tomorrow)
+	tasks.Add(ctx, "due next week", &nextWeek)
+
+	if got, _ := tasks.Overdue(ctx); len(got) != 0 {
+		t.Errorf("overdue now: %v", got)
+	}
+	clock.Advance(48 * time.Hour)
+	tasks.Complete(ctx, b.ID)
+	got, _ := tasks.Overdue(ctx)
+	if len(got) != 1 || got[0].ID != a.ID {
+		t.Errorf("overdue after two days: %+v", got)
+	}
+}
+
+// filterSpy records the Filter the use case passes down
+type filterSpy struct {
+	TaskRepo
+	got Filter
+}
+
+func (s *filterSpy) List(ctx context.Context, f Filter) ([]Task, error) {
+	s.got = f
+	return nil, nil
+}
+
+func TestTasksListLimits(t *testing.T) {
+	for _, tt := range []struct{ in, want int }{{0, defaultLimit}, {-1, defaultLimit}, {10, 10}, {1e6, maxLimit}} {
+		spy := &filterSpy{}
+		NewTasks(spy, time.Now).List(t.Context(), Filter{Limit: tt.in})
+		if spy.got.Limit != tt.want {
+			t.Errorf("Limit %d passed as %d, want %d", tt.in, spy.got.Limit, tt.want)
+		}
+	}
+}
+
+// failingRepo fails every call, like a database that has gone away
+type failingRepo struct{ TaskRepo }
+
+var errDown = errors.New("database is down")
+
+func (failingRepo) Get(context.Context, int64) (Task, error) { return Task{}, errDown }
+func (failingRepo) Add(context.Context, Task) (Task, error)  { return Task{}, errDown }
+
+func TestTasksPassRepoErrorsThrough(t *testing.T) {
+	tasks := NewTasks(failingRepo{}, time.Now)
+	if _, err := tasks.Add(t.Context(), "x", nil); !errors.Is(err, errDown) {
+		t.Errorf("Add: %v", err)
+	}
+	if _, err := tasks.Complete(t.Context(), 1); !errors.Is(err, errDown) {
+		t.Errorf("Complete: %v", err)
+	}
+}
+
+// 3. The Dependency Rule
+// ======================
+
+func TestArchitectureInnerLayersImportNoAdapters(t *testing.T) {
+	forbidden := []string{"net/http", "database/sql", "modernc.org/sqlite", "encoding/json"}
+	for _, file := range []string{"domain.go", "usecases.go"} {
+		f, err := goparser.ParseFile(gotoken.NewFileSet(), file, nil, goparser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			for _, bad := range forbidden {
+				if path == bad || strings.HasPrefix(path, bad+"/") {
+					t.Errorf("%s imports %s: the inner layers must not know the adapters", file, path)
+				}
+			}
+		}
+	}
+}
+
+// 4. Benchmarks
+// =============
+
+func BenchmarkTasksAdd(b *testing.B) {
+	tasks := NewTasks(NewMemRepo(), time.Now)
+	ctx := context.Background()
+	for b.Loop() {
+		tasks.Add(ctx, "benchmark", nil)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Use Cases
+// =========
+// The application layer: one method per thing a user can do. Each
+// loads what it needs through a port, lets the domain decide, and
+// saves the result. It owns the ports - the interfaces it needs from
+// the outside - so the dependency arrows point inward:
+//
+//	http.go  ---calls--->  Tasks  ---uses--->  TaskRepo  <---implements---  sqlite.go
+//	                         |                                               memory.go
+//	                         +---uses--->  Clock
+//
+// Nothing here names a table, a status code or a driver. Swapping
+// SQLite for Postgres, or HTTP for a CLI, touches an adapter and
+// main.go, and these methods do not change.
+
+// TaskRepo is the storage port. Get, Update and Delete return
+// ErrNotFound for an unknown id.
+type TaskRepo interface {
+	Add(ctx context.Context, t Task) (Task, error) // assigns the ID
+	Get(ctx context.Context, id int64) (Task, error)
+	Update(ctx context.Context, t Task) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, f Filter) ([]Task, error)
+}
+
+// Filter selects tasks for List, oldest first. Nil fields match all.
+type Filter struct {
+	Done      *bool
+	DueBefore *time.Time // due strictly before this time
+	Limit     int        // at most this many; the use case sets it
+}
+
+// Clock is the time port
+type Clock func() time.Time
+
+// Page sizes for List
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Tasks holds the use cases
+type Tasks struct {
+	repo TaskRepo
+	now  Clock
+}
+
+// NewTasks returns the use cases over repo
+func NewTasks(repo TaskRepo, now Clock) *Tasks {
+	return &Tasks{repo: repo, now: now}
+}
+
+// Add creates a task
+func (s *Tasks) Add(ctx context.Context, title string, due *time.Time) (Task, error) {
+	t, err := NewTask(title, due, s.now().UTC())
+	if err != nil {
+		return Task{}, err
+	}
+	return s.repo.Add(ctx, t)
+}
+
+// Get returns one task
+func (s *Tasks) Get(ctx context.Context, id int64) (Task, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Rename changes a task's title
+func (s *Tasks) Rename(ctx context.Context, id int64, title string) (Task, error) {
+	return s.update(ctx, id, func(t *Task) error { return t.Rename(title) })
+}
+
+// Complete marks a task done
+func (s *Tasks) Complete(ctx context.Context, id int64) (Task, error) {
+	return s.update(ctx, id, func(t *Task) error { return t.Complete(s.now().UTC()) })
+}
+
+// Reopen marks a task not done
+func (s *Tasks) Reopen(ctx context.Context, id int64) (Task, error) {
+	return s.update(ctx, id, (*Task).Reopen)
+}
+
+// Delete removes a task
+func (s *Tasks) Delete(ctx context.Context, id int64) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// List returns tasks matching f, capping its Limit
+func (s *Tasks) List(ctx context.Context, f Filter) ([]Task, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultLimit
+	}
+	f.Limit = min(f.Limit, maxLimit)
+	return s.repo.List(ctx, f)
+}
+
+// Overdue returns the open tasks past their due date
+func (s *Tasks) Overdue(ctx context.Context) ([]Task, error) {
+	open, now := false, s.now().UTC()
+	return s.List(ctx, Filter{Done: &open, DueBefore: &now, Limit: maxLimit})
+}
+
+// update loads a task, applies a domain method and saves it. Two
+// concurrent updates to one task can lose one; a version column
+// checked by Update would catch that, as in projects/ledger.
+func (s *Tasks) update(ctx context.Context, id int64, change func(*Task) error) (Task, error) {
+	t, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := change(&t); err != nil {
+		return Task{}, err
+	}
+	if err := s.repo.Update(ctx, t); err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
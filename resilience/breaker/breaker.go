@@ -0,0 +1,285 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Circuit Breaker
+// ===============
+// A dependency that is down costs every caller a timeout. Retries
+// multiply the load just when it can least take it, and the callers'
+// goroutines and connections pile up waiting. A breaker notices the
+// failures and, for a while, fails calls at once without making them:
+//
+//	          failure rate >= FailureRate
+//	          over >= MinRequests calls
+//	 Closed ----------------------------> Open
+//	   ^                                 |   ^
+//	   | Probes successes                |   | a probe fails
+//	   |                    OpenTimeout  v   |
+//	   +------------------------------ HalfOpen
+//
+//	Closed     calls go through; outcomes are counted in a rolling window
+//	Open       calls fail with ErrOpen, without reaching the dependency
+//	HalfOpen   up to Probes calls go through as a test; the rest get
+//	           ErrOpen. All succeed: Closed. One fails: Open again.
+//
+// What counts as a failure matters as much as the thresholds:
+//
+//	a timeout or a 503            failure: the dependency is struggling
+//	a 404 or a validation error   success: it answered, correctly
+//	the caller's cancellation     not counted: says nothing about it
+//
+// IsFailure makes the first two distinctions; the third is built in.
+//
+// One Breaker guards one dependency - a host, a database - and is shared
+// by every call to it. Wrap adapts it to any func(ctx) (T, error).
+//
+// Pitfalls:
+//
+//	one breaker per request            never sees enough calls to trip
+//	one breaker for many hosts         one bad host cuts off the rest
+//	MinRequests of 1                   a single error opens the circuit
+//	counting client errors             bad input from one user trips
+//	                                   the breaker for everyone
+//	retrying ErrOpen                   the point is not to call; fall
+//	                                   back, or fail fast upstream
+
+// ErrOpen is returned, without calling the function, while the circuit
+// is open and when a half-open circuit already has its probes
+var ErrOpen = errors.New("breaker: circuit open")
+
+// State is the breaker's state
+type State int
+
+//go:generate go run ../../cmd/genenum/main.go -type State -case kebab
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+// Settings configure a Breaker. The zero value gives the defaults.
+type Settings struct {
+	Window      time.Duration // span of the failure rate; default 10s
+	Buckets     int           // the window's resolution; default 10
+	MinRequests int           // calls in the window before it may trip; default 20
+	FailureRate float64       // trips at or above this fraction; default 0.5
+	OpenTimeout time.Duration // time in Open before probing; default 5s
+	Probes      int           // probe calls in HalfOpen; default 1
+
+	// IsFailure reports whether an error counts against the dependency.
+	// The default counts every error. A call cut short by the caller's
+	// own cancellation is not counted either way.
+	IsFailure func(error) bool
+
+	// OnStateChange is called after each transition, outside the lock
+	OnStateChange func(from, to State)
+
+	Now func() time.Time // time.Now if nil
+}
+
+// Breaker tracks one dependency's health. It is safe for concurrent use.
+type Breaker struct {
+	s Settings
+
+	mu       sync.Mutex
+	state    State
+	window   *window
+	openedAt time.Time
+	// probes started and succeeded in the current HalfOpen
+	probing, probed int
+	// generation changes on every transition. A call reports its
+	// outcome with the generation it started in, so a slow call from
+	// before the circuit opened cannot close it, or count twice.
+	generation uint64
+}
+
+// New returns a closed Breaker
+func New(s Settings) *Breaker {
+	if s.Window <= 0 {
+		s.Window = 10 * time.Second
+	}
+	if s.Buckets <= 0 {
+		s.Buckets = 10
+	}
+	if s.MinRequests <= 0 {
+		s.MinRequests = 20
+	}
+	if s.FailureRate <= 0 {
+		s.FailureRate = 0.5
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = 5 * time.Second
+	}
+	if s.Probes <= 0 {
+		s.Probes = 1
+	}
+	if s.IsFailure == nil {
+		s.IsFailure = func(err error) bool { return err != nil }
+	}
+	if s.Now == nil {
+		s.Now = time.Now
+	}
+	return &Breaker{s: s, window: newWindow(s.Window, s.Buckets)}
+}
+
+// State returns the current state. An Open breaker whose timeout has
+// passed reports HalfOpen, which is what the next call will see.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && !b.s.Now().Before(b.openedAt.Add(b.s.OpenTimeout)) {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// Counts returns the calls and failures in the current window, for
+// metrics
+func (b *Breaker) Counts() (requests, failures int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.window.counts(b.s.Now())
+}
+
+// Wrap returns fn guarded by b
+func Wrap[T any](b *Breaker, fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		return Call(ctx, b, fn)
+	}
+}
+
+// Call runs fn if b allows it and records the outcome. A context that
+// is already done returns its error without a call or a count. A panic
+// in fn counts as a failure and is passed on.
+func Call[T any](ctx context.Context, b *Breaker, fn func(context.Context) (T, error)) (result T, err error) {
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	gen, err := b.allow()
+	if err != nil {
+		return result, err
+	}
+	out := failure
+	defer func() {
+		b.done(gen, out)
+	}()
+	result, err = fn(ctx)
+	switch {
+	case ctx.Err() != nil && errors.Is(err, context.Canceled):
+		out = ignored
+	case b.s.IsFailure(err):
+		out = failure
+	default:
+		out = success
+	}
+	return result, err
+}
+
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	ignored
+)
+
+// allow admits a call, or returns ErrOpen
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	var changes []transition
+	defer func() {
+		b.mu.Unlock()
+		b.notify(changes)
+	}()
+
+	now := b.s.Now()
+	switch b.state {
+	case Open:
+		if now.Before(b.openedAt.Add(b.s.OpenTimeout)) {
+			return 0, ErrOpen
+		}
+		changes = append(changes, b.setState(HalfOpen, now))
+		fallthrough
+	case HalfOpen:
+		if b.probing >= b.s.Probes {
+			return 0, ErrOpen
+		}
+		b.probing++
+	}
+	return b.generation, nil
+}
+
+// done records the outcome of a call admitted in generation gen
+func (b *Breaker) done(gen uint64, out outcome) {
+	b.mu.Lock()
+	var changes []transition
+	defer func() {
+		b.mu.Unlock()
+		b.notify(changes)
+	}()
+
+	if gen != b.generation {
+		return
+	}
+	now := b.s.Now()
+	switch b.state {
+	case Closed:
+		if out == ignored {
+			return
+		}
+		b.window.record(now, out == failure)
+		if out == success {
+			return
+		}
+		requests, failures := b.window.counts(now)
+		if requests >= b.s.MinRequests && float64(failures) >= b.s.FailureRate*float64(requests) {
+			changes = append(changes, b.setState(Open, now))
+		}
+	case HalfOpen:
+		switch out {
+		case ignored:
+			b.probing-- // the slot goes to the next caller
+			return
+		case failure:
+			changes = append(changes, b.setState(Open, now))
+			return
+		}
+		b.probed++
+		if b.probed >= b.s.Probes {
+			changes = append(changes, b.setState(Closed, now))
+		}
+	}
+}
+
+type transition struct{ from, to State }
+
+// setState moves to a new state and generation. Closed starts with an
+// empty window: the failures that opened the circuit are history.
+func (b *Breaker) setState(to State, now time.Time) transition {
+	t := transition{b.state, to}
+	b.state = to
+	b.generation++
+	b.probing, b.probed = 0, 0
+	switch to {
+	case Open:
+		b.openedAt = now
+	case Closed:
+		b.window.reset()
+	}
+	return t
+}
+
+func (b *Breaker) notify(changes []transition) {
+	if b.s.OnStateChange == nil {
+		return
+	}
+	for _, t := range changes {
+		b.s.OnStateChange(t.from, t.to)
+	}
+}
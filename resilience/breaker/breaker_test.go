@@ -0,0 +1,508 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Circuit Breaker - Tests
+// =======================
+// Run with:
+//
+//   cd resilience/breaker
+//   go test -v *.go
+//   go test -race *.go
+//
+// Time is a fake clock, so every transition is driven by the test:
+// failures trip the breaker, Advance moves it to half-open, and probe
+// results close or reopen it.
+
+// fakeClock is a manual clock; see testing/clock for a full version
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.t
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.t = f.t.Add(d)
+}
+
+var errDown = errors.New("dependency down")
+
+func ok(context.Context) (int, error)   { return 1, nil }
+func fail(context.Context) (int, error) { return 0, errDown }
+
+// newTest returns a breaker that may trip after 4 calls at 50%, probes
+// once after 5s, and records its transitions
+func newTest(t *testing.T) (*Breaker, *fakeClock, *[]string) {
+	t.Helper()
+	clock := newClock()
+	var changes []string
+	b := New(Settings{
+		MinRequests: 4,
+		FailureRate: 0.5,
+		OpenTimeout: 5 * time.Second,
+		Now:         clock.Now,
+		OnStateChange: func(from, to State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	return b, clock, &changes
+}
+
+func call(b *Breaker, fn func(context.Context) (int, error)) error {
+	_, err := Call(context.Background(), b, fn)
+	return err
+}
+
+func trip(t *testing.T, b *Breaker) {
+	t.Helper()
+	for range 4 {
+		call(b, fail)
+	}
+	if b.State() != Open {
+		t.Fatalf("state %v after 4 failures, want open", b.State())
+	}
+}
+
+// 1. Closed
+// =========
+
+func TestClosedPassesResults(t *testing.T) {
+	b, _, _ := newTest(t)
+	get := Wrap(b, func(ctx context.Context) (string, error) { return "hello", nil })
+	if v, err := get(context.Background()); v != "hello" || err != nil {
+		t.Errorf("got %q, %v", v, err)
+	}
+	if err := call(b, fail); !errors.Is(err, errDown) {
+		t.Errorf("got %v, want the function's own error", err)
+	}
+}
+
+// Below MinRequests even 100% failures do not trip: three errors in a
+// quiet minute are not an outage
+func TestMinRequests(t *testing.T) {
+	b, _, _ := newTest(t)
+	for range 3 {
+		call(b, fail)
+	}
+	if b.State() != Closed {
+		t.Fatalf("tripped after 3 calls with MinRequests 4")
+	}
+	call(b, fail)
+	if b.State() != Open {
+		t.Fatalf("state %v after the fourth failure, want open", b.State())
+	}
+}
+
+func TestFailureRate(t *testing.T) {
+	tests := []struct {
+		ok, failed int
+		want       State
+	}{
+		{10, 0, Closed},
+		{6, 4, Closed}, // 40%
+		{5, 5, Open},   // 50%: at the threshold trips
+		{1, 9, Open},
+	}
+	for _, tt := range tests {
+		b, _, _ := newTest(t)
+		for range tt.ok {
+			call(b, ok)
+		}
+		for range tt.failed {
+			call(b, fail)
+		}
+		if b.State() != tt.want {
+			t.Errorf("%d ok, %d failed: %v, want %v", tt.ok, tt.failed, b.State(), tt.want)
+		}
+	}
+}
+
+// Failures older than the window no longer count
+func TestWindowForgets(t *testing.T) {
+	b, clock, _ := newTest(t)
+	for range 3 {
+		call(b, fail)
+	}
+	clock.Advance(11 * time.Second)
+	if req, failed := b.Counts(); req != 0 || failed != 0 {
+		t.Errorf("counts %d/%d after the window, want 0/0", req, failed)
+	}
+	call(b, fail)
+	if b.State() != Closed {
+		t.Error("old failures tripped the breaker")
+	}
+}
+
+// Half the window later, half the old buckets are still counted
+func TestWindowSlides(t *testing.T) {
+	b, clock, _ := newTest(t)
+	call(b, fail)
+	clock.Advance(5 * time.Second)
+	call(b, fail)
+	clock.Advance(5*time.Second + time.Millisecond)
+	if req, failed := b.Counts(); req != 1 || failed != 1 {
+		t.Errorf("counts %d/%d, want only the later failure", req, failed)
+	}
+}
+
+// 2. Open
+// =======
+
+func TestOpenFailsFast(t *testing.T) {
+	b, _, _ := newTest(t)
+	trip(t, b)
+	var calls atomic.Int32
+	err := call(b, func(context.Context) (int, error) {
+		calls.Add(1)
+		return 0, nil
+	})
+	if !errors.Is(err, ErrOpen) || calls.Load() != 0 {
+		t.Errorf("got %v with %d calls, want ErrOpen and no call", err, calls.Load())
+	}
+}
+
+// 3. Half-Open
+// ============
+
+func TestHalfOpenAfterTimeout(t *testing.T) {
+	b, clock, _ := newTest(t)
+	trip(t, b)
+	clock.Advance(5*time.Second - time.Nanosecond)
+	if b.State() != Open {
+		t.Fatalf("state %v before the timeout", b.State())
+	}
+	clock.Advance(time.Nanosecond)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v at the timeout, want half-open", b.State())
+	}
+}
+
+func TestProbeSuccessCloses(t *testing.T) {
+	b, clock, changes := newTest(t)
+	trip(t, b)
+	clock.Advance(5 * time.Second)
+	if err := call(b, ok); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("state %v after a good probe, want closed", b.State())
+	}
+	// The window starts empty: one failure does not reopen it
+	call(b, fail)
+	if b.State() != Closed {
+		t.Error("failures from before the circuit opened were kept")
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Errorf("transitions %v, want %v", *changes, want)
+	}
+}
+
+func TestProbeFailureReopens(t *testing.T) {
+	b, clock, changes := newTest(t)
+	trip(t, b)
+	clock.Advance(5 * time.Second)
+	call(b, fail)
+	if b.State() != Open {
+		t.Fatalf("state %v after a failed probe, want open", b.State())
+	}
+	// The timeout starts again from the failed probe
+	clock.Advance(4 * time.Second)
+	if err := call(b, ok); !errors.Is(err, ErrOpen) {
+		t.Errorf("got %v 4s after reopening, want ErrOpen", err)
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->open"}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Errorf("transitions %v, want %v", *changes, want)
+	}
+}
+
+// While the probes are in flight, everyone else still fails fast: a
+// recovering dependency gets Probes calls, not the whole backlog
+func TestProbeLimit(t *testing.T) {
+	clock := newClock()
+	b := New(Settings{MinRequests: 1, Probes: 2, Now: clock.Now})
+	call(b, fail)
+	clock.Advance(5 * time.Second)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	slow := func(context.Context) (int, error) {
+		started <- struct{}{}
+		<-release
+		return 1, nil
+	}
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Go(func() { call(b, slow) })
+	}
+	<-started
+	<-started
+	if err := call(b, ok); !errors.Is(err, ErrOpen) {
+		t.Errorf("third call while 2 probes run: %v, want ErrOpen", err)
+	}
+	close(release)
+	wg.Wait()
+	if b.State() != Closed {
+		t.Errorf("state %v after 2 good probes, want closed", b.State())
+	}
+}
+
+// 4. What Counts
+// ==============
+
+func TestIsFailure(t *testing.T) {
+	errNotFound := errors.New("not found")
+	clock := newClock()
+	b := New(Settings{
+		MinRequests: 2,
+		Now:         clock.Now,
+		IsFailure: func(err error) bool {
+			return err != nil && !errors.Is(err, errNotFound)
+		},
+	})
+	notFound := func(context.Context) (int, error) { return 0, errNotFound }
+	for range 10 {
+		if err := call(b, notFound); !errors.Is(err, errNotFound) {
+			t.Fatalf("got %v", err)
+		}
+	}
+	if req, failed := b.Counts(); b.State() != Closed || req != 10 || failed != 0 {
+		t.Errorf("%v with %d/%d: a 404 is an answer, not a failure", b.State(), req, failed)
+	}
+}
+
+// The caller giving up says nothing about the dependency; the
+// dependency being too slow for the deadline does
+func TestCancellation(t *testing.T) {
+	b, _, _ := newTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for range 10 {
+		Call(ctx, b, func(context.Context) (int, error) {
+			cancel()
+			return 0, context.Canceled
+		})
+	}
+	if req, _ := b.Counts(); req != 0 {
+		t.Errorf("cancelled calls counted: %d", req)
+	}
+
+	// Already done: not even attempted
+	var calls int
+	Call(ctx, b, func(context.Context) (int, error) { calls++; return 0, nil })
+	if calls != 0 {
+		t.Error("called with a done context")
+	}
+
+	for range 4 {
+		call(b, func(context.Context) (int, error) { return 0, context.DeadlineExceeded })
+	}
+	if b.State() != Open {
+		t.Errorf("state %v after 4 timeouts, want open", b.State())
+	}
+}
+
+// A cancelled probe gives its slot to the next caller
+func TestCancelledProbe(t *testing.T) {
+	b, clock, _ := newTest(t)
+	trip(t, b)
+	clock.Advance(5 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	Call(ctx, b, func(context.Context) (int, error) {
+		cancel()
+		return 0, context.Canceled
+	})
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after a cancelled probe, want half-open", b.State())
+	}
+	if err := call(b, ok); err != nil || b.State() != Closed {
+		t.Errorf("next probe: %v, state %v", err, b.State())
+	}
+}
+
+func TestPanicCounts(t *testing.T) {
+	b, _, _ := newTest(t)
+	for range 4 {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("panic was swallowed")
+				}
+			}()
+			call(b, func(context.Context) (int, error) { panic("boom") })
+		}()
+	}
+	if b.State() != Open {
+		t.Errorf("state %v after 4 panics, want open", b.State())
+	}
+}
+
+// 5. Stale Results
+// ================
+
+// A call that started before the circuit opened and ends after it has
+// closed again must not count in the new window: its generation is
+// over
+func TestStaleResultIgnored(t *testing.T) {
+	b, clock, _ := newTest(t)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		call(b, func(context.Context) (int, error) {
+			close(started)
+			<-release
+			return 0, errDown
+		})
+		close(done)
+	}()
+	<-started
+	trip(t, b)
+	clock.Advance(5 * time.Second)
+	call(b, ok) // closes
+	close(release)
+	<-done
+	if req, failed := b.Counts(); req != 0 || failed != 0 {
+		t.Errorf("counts %d/%d, want the stale failure ignored", req, failed)
+	}
+}
+
+// A slow success from the closed state cannot stand in for a probe
+func TestStaleSuccessIsNotAProbe(t *testing.T) {
+	b, clock, _ := newTest(t)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		call(b, func(context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		close(done)
+	}()
+	<-started
+	trip(t, b)
+	clock.Advance(5 * time.Second)
+	close(release)
+	<-done
+	if b.State() != HalfOpen {
+		t.Errorf("state %v, want half-open until a real probe", b.State())
+	}
+}
+
+// 6. Concurrency
+// ==============
+
+func TestConcurrentCalls(t *testing.T) {
+	clock := newClock()
+	b := New(Settings{MinRequests: 10, Now: clock.Now})
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() {
+			for j := range 100 {
+				if (i+j)%3 == 0 {
+					call(b, fail)
+				} else {
+					call(b, ok)
+				}
+				if j%10 == 0 {
+					clock.Advance(time.Second)
+				}
+				b.State()
+				b.Counts()
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestStateString(t *testing.T) {
+	for s, want := range map[State]string{Closed: "closed", Open: "open", HalfOpen: "half-open", 7: "State(7)"} {
+		if s.String() != want {
+			t.Errorf("%d: %q, want %q", int(s), s.String(), want)
+		}
+	}
+}
+
+// 7. Benchmarks
+// =============
+
+func BenchmarkCall(b *testing.B) {
+	br := New(Settings{})
+	ctx := context.Background()
+	for b.Loop() {
+		Call(ctx, br, ok)
+	}
+}
+
+func BenchmarkCallParallel(b *testing.B) {
+	br := New(Settings{})
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Call(ctx, br, ok)
+		}
+	})
+}
+
+// 8. Examples
+// ===========
+
+func ExampleWrap() {
+	clock := newClock()
+	b := New(Settings{
+		MinRequests: 3,
+		OpenTimeout: 10 * time.Second,
+		Now:         clock.Now,
+		OnStateChange: func(from, to State) {
+			fmt.Printf("  %v -> %v\n", from, to)
+		},
+	})
+
+	healthy := false
+	fetch := Wrap(b, func(ctx context.Context) (string, error) {
+		if !healthy {
+			return "", errors.New("503 Service Unavailable")
+		}
+		return "price: 42", nil
+	})
+
+	for range 4 {
+		_, err := fetch(context.Background())
+		fmt.Println(err)
+	}
+	clock.Advance(10 * time.Second)
+	healthy = true
+	fmt.Println(fetch(context.Background()))
+	// Output:
+	// 503 Service Unavailable
+	// 503 Service Unavailable
+	//   closed -> open
+	// 503 Service Unavailable
+	// breaker: circuit open
+	//   open -> half-open
+	//   half-open -> closed
+	// price: 42 <nil>
+}
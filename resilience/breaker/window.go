@@ -0,0 +1,69 @@
+package breaker
+
+import "time"
+
+// Rolling Window
+// ==============
+// The failure rate is over the last Window, not since the start: a
+// breaker that counted forever would need as many successes to recover
+// as it had seen failures. The window is a ring of buckets, each
+// covering Window/len(buckets). A bucket is reused when time comes
+// round to it again, so old counts fall out without a timer:
+//
+//	window 10s, 10 buckets          now = 23.4s
+//
+//	bucket   0    1    2    3   ...  9
+//	slot     20   21   22   23  ...  19      slot = now / 1s
+//	                          ^ current; slot 13 was here and is reset
+//
+// The rate moves in steps of one bucket. More buckets are smoother and
+// cost a longer sum on every call; ten is the usual choice.
+
+type bucket struct {
+	slot             int64 // which Window/len(buckets) interval
+	success, failure int
+}
+
+type window struct {
+	buckets []bucket
+	width   time.Duration
+}
+
+func newWindow(size time.Duration, n int) *window {
+	return &window{buckets: make([]bucket, n), width: max(size/time.Duration(n), 1)}
+}
+
+// current returns the bucket for now, emptied if it held an old slot
+func (w *window) current(now time.Time) *bucket {
+	slot := now.UnixNano() / int64(w.width)
+	b := &w.buckets[int(slot%int64(len(w.buckets)))]
+	if b.slot != slot {
+		*b = bucket{slot: slot}
+	}
+	return b
+}
+
+func (w *window) record(now time.Time, failed bool) {
+	b := w.current(now)
+	if failed {
+		b.failure++
+	} else {
+		b.success++
+	}
+}
+
+// counts sums the buckets still inside the window
+func (w *window) counts(now time.Time) (requests, failures int) {
+	slot := now.UnixNano() / int64(w.width)
+	for _, b := range w.buckets {
+		if slot-b.slot < int64(len(w.buckets)) {
+			requests += b.success + b.failure
+			failures += b.failure
+		}
+	}
+	return requests, failures
+}
+
+func (w *window) reset() {
+	clear(w.buckets)
+}
@@ -0,0 +1,59 @@
+package retry
+
+import "sync"
+
+// Retry Budgets
+// =============
+// MaxAttempts caps one call. It does not cap a service: with 4 attempts
+// each, a backend that fails everything receives 4x its normal load
+// from its callers, and more from theirs. A budget caps retries for all
+// calls together, as a fraction of first attempts:
+//
+//	ratio 0.1    each call earns a tenth of a token; a retry spends one.
+//	             Retries stay at about 10% of traffic, however bad
+//	             things get.
+//	burst        the bucket's size, and where it starts: a quiet client
+//	             can still retry its first few failures.
+//
+// When the budget is empty, calls fail after their first attempt,
+// which is what a circuit breaker would do - but only as far as
+// needed, and the budget refills with traffic instead of a timer.
+// gRPC's retry throttling and Finagle's RetryBudget work this way.
+
+// Budget is a token bucket of retries, shared by every call that uses
+// it. It is safe for concurrent use.
+type Budget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+	burst  float64
+}
+
+// NewBudget returns a full Budget allowing retries of ratio times the
+// calls made, with at most burst retries saved up
+func NewBudget(ratio float64, burst int) *Budget {
+	return &Budget{ratio: ratio, tokens: float64(burst), burst: float64(burst)}
+}
+
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.ratio, b.burst)
+}
+
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Tokens returns the retries available now, for metrics
+func (b *Budget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
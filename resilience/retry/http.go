@@ -0,0 +1,162 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retrying HTTP Requests
+// ======================
+// Transport applies a Policy to an http.Client: set it as the client's
+// Transport and every request made through the client is retried. It
+// does what web/client's Retrier does by hand, with the classification
+// expressed as errors:
+//
+//	network error               retried, unless the context is done
+//	429, 502, 503, 504 ...      retried; Retry-After becomes RetryAfter
+//	501 Not Implemented         returned: it will not start working
+//	other statuses              returned: the server answered
+//	POST without an             sent once: repeating it could do the
+//	Idempotency-Key             thing twice
+//
+// When the attempts run out on a bad status, the last response is
+// returned as a response, not an error, exactly as http.Client returns
+// a 503 it did not retry. Earlier responses are drained and closed so
+// their connections can be reused.
+
+// StatusError is the error a retryable response becomes inside Do
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("retry: server answered %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// ErrNotReplayable means a request has a body that cannot be read a
+// second time, so it cannot be retried
+var ErrNotReplayable = errors.New("retry: request body cannot be replayed")
+
+// maxDrain bounds how much of a discarded body is read to keep its
+// connection; past that, closing is cheaper than reading
+const maxDrain = 64 << 10
+
+// Transport is an http.RoundTripper that retries through Policy
+type Transport struct {
+	Base   http.RoundTripper // http.DefaultTransport if nil
+	Policy Policy
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip sends req, retrying network errors and transient statuses.
+// A RoundTripper must not change the request, so each attempt sends a
+// clone.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := t.Policy
+	if !idempotent(req) {
+		policy.MaxAttempts = 1
+	}
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if hasBody && req.GetBody == nil && policy.MaxAttempts != 1 {
+		return nil, ErrNotReplayable
+	}
+	now := policy.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	// last is the most recent retryable response, kept until the next
+	// attempt in case it turns out to be the final answer
+	var last *http.Response
+	discard := func() {
+		if last != nil {
+			io.CopyN(io.Discard, last.Body, maxDrain)
+			last.Body.Close()
+			last = nil
+		}
+	}
+
+	attempt := 0
+	resp, err := DoValue(req.Context(), policy, func(ctx context.Context) (*http.Response, error) {
+		discard()
+		attempt++
+		try := req.Clone(ctx)
+		if attempt > 1 && hasBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, Permanent(err)
+			}
+			try.Body = body
+		}
+
+		resp, err := t.base().RoundTrip(try)
+		if err != nil {
+			return nil, err
+		}
+		if !transient(resp.StatusCode) {
+			return resp, nil
+		}
+		last = resp
+		err = &StatusError{resp.StatusCode}
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), now()); ok {
+			err = RetryAfter(err, d)
+		}
+		return nil, err
+	})
+	if err == nil {
+		return resp, nil
+	}
+	var status *StatusError
+	if errors.As(err, &status) && last != nil && req.Context().Err() == nil {
+		// Out of attempts or time on a bad status: the caller gets the
+		// response
+		return last, nil
+	}
+	discard()
+	return nil, err
+}
+
+// transient reports whether a status may go away on retry
+func transient(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return code >= 500
+}
+
+// idempotent reports whether req may be sent more than once
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryAfter parses a Retry-After value: seconds, or an HTTP date
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
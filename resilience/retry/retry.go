@@ -0,0 +1,268 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Retrying
+// ========
+// A retry turns a transient failure - a dropped connection, a 503 from
+// an overloaded server - into a success. Done carelessly it turns one
+// struggling server into a dead one: every client retries at once, at
+// the same moments, multiplying the load exactly when it is highest.
+// Do retries with the safeguards:
+//
+//	exponential backoff   each wait doubles, up to Max
+//	jitter                waits are randomized, so clients spread out
+//	classification        only errors that may pass are retried;
+//	                      Permanent marks the rest
+//	caps                  attempts, total time, the caller's deadline,
+//	                      and a Budget shared by all calls
+//
+// Pitfalls:
+//
+//	retrying a non-idempotent call     a payment made twice; retry only
+//	                                   what is safe to repeat
+//	retrying at every layer            3 layers x 4 attempts is 64
+//	                                   calls to the bottom one
+//	no jitter                          synchronized waves of retries
+//	sleeping past the deadline         the last attempt can never
+//	                                   finish; Do stops early instead
+//	retrying a context error           the caller has already given up
+
+// ErrBudget is wrapped in the error Do returns when the shared Budget
+// refuses a retry
+var ErrBudget = errors.New("retry: budget exhausted")
+
+// Jitter chooses how a backoff is randomized. For a backoff b:
+//
+//	FullJitter          uniform in [0, b): the most spread, the default
+//	EqualJitter         b/2 plus uniform in [0, b/2): never less than half
+//	DecorrelatedJitter  uniform in [Base, 3 x the previous wait), capped
+//	                    at Max: grows from the last wait, not the count
+//	NoJitter            exactly b: for tests and single clients only
+//
+// Full and decorrelated jitter do about equally well at spreading a
+// crowd of clients; without jitter they retry in lockstep.
+type Jitter int
+
+const (
+	FullJitter Jitter = iota
+	EqualJitter
+	DecorrelatedJitter
+	NoJitter
+)
+
+// Policy says how often and how long to retry. The zero value retries
+// every error up to 4 attempts with full jitter from 100ms.
+type Policy struct {
+	MaxAttempts int           // including the first; default 4
+	Base        time.Duration // backoff before the first retry; default 100ms
+	Max         time.Duration // cap on any one wait; default 10s
+	Multiplier  float64       // growth per retry; default 2
+	Jitter      Jitter
+
+	// MaxElapsed caps the time from the first attempt. Do will not
+	// start a wait that ends past it, or past the context's deadline,
+	// which is compared with Now.
+	MaxElapsed time.Duration
+
+	// Budget, if set, is shared by many calls and caps their retries
+	// together
+	Budget *Budget
+
+	// Retryable reports whether an error may pass on retry. Permanent
+	// errors and the context's own errors are never retried; the
+	// default retries everything else.
+	Retryable func(error) bool
+
+	// OnRetry is called before each wait, for logs and metrics
+	OnRetry func(attempt int, err error, wait time.Duration)
+
+	// Sleep waits for d or until ctx is done, Now reads the time and
+	// Rand returns a float in [0, 1). Tests replace them.
+	Sleep func(ctx context.Context, d time.Duration) error
+	Now   func() time.Time
+	Rand  func() float64
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 4
+	}
+	if p.Base <= 0 {
+		p.Base = 100 * time.Millisecond
+	}
+	if p.Max <= 0 {
+		p.Max = 10 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Retryable == nil {
+		p.Retryable = func(error) bool { return true }
+	}
+	if p.Sleep == nil {
+		p.Sleep = sleep
+	}
+	if p.Now == nil {
+		p.Now = time.Now
+	}
+	if p.Rand == nil {
+		p.Rand = rand.Float64
+	}
+	return p
+}
+
+// Backoff returns the wait before retry n (1 for the first retry),
+// given the previous wait, which only DecorrelatedJitter uses
+func (p Policy) Backoff(n int, prev time.Duration) time.Duration {
+	p = p.withDefaults()
+	// Computed in float64, so a large n saturates at Max instead of
+	// overflowing
+	b := time.Duration(min(float64(p.Base)*math.Pow(p.Multiplier, float64(n-1)), float64(p.Max)))
+	switch p.Jitter {
+	case NoJitter:
+		return b
+	case EqualJitter:
+		return b/2 + time.Duration(p.Rand()*float64(b/2))
+	case DecorrelatedJitter:
+		hi := 3 * max(prev, p.Base)
+		return min(p.Base+time.Duration(p.Rand()*float64(hi-p.Base)), p.Max)
+	}
+	return time.Duration(p.Rand() * float64(b))
+}
+
+// Do calls fn until it succeeds, returns an error that should not be
+// retried, or a cap is reached. The returned error wraps fn's last one.
+func Do(ctx context.Context, p Policy, fn func(context.Context) error) error {
+	_, err := DoValue(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoValue is Do for a function with a result
+func DoValue[T any](ctx context.Context, p Policy, fn func(context.Context) (T, error)) (T, error) {
+	p = p.withDefaults()
+	start := p.Now()
+	if p.Budget != nil {
+		p.Budget.deposit()
+	}
+
+	var wait time.Duration
+	for attempt := 1; ; attempt++ {
+		result, err := fn(ctx)
+		if err == nil {
+			return result, nil
+		}
+
+		var perm *permanentError
+		switch {
+		case errors.As(err, &perm):
+			return result, wrap(attempt, perm.err)
+		case ctx.Err() != nil:
+			// The caller gave up, or its deadline passed: whatever fn
+			// returned, retrying cannot help
+			return result, wrap(attempt, err)
+		case !p.Retryable(err):
+			return result, wrap(attempt, err)
+		case attempt == 1 && p.MaxAttempts == 1:
+			return result, err
+		case attempt == p.MaxAttempts:
+			return result, fmt.Errorf("retry: gave up after %d attempts: %w", attempt, err)
+		}
+
+		wait = p.Backoff(attempt, wait)
+		var ra *retryAfterError
+		if errors.As(err, &ra) {
+			wait = min(ra.after, p.Max)
+		}
+		if !p.fits(ctx, start, wait) {
+			return result, fmt.Errorf("retry: no time for attempt %d: %w", attempt+1, err)
+		}
+		if p.Budget != nil && !p.Budget.withdraw() {
+			return result, fmt.Errorf("%w after %d attempts: %w", ErrBudget, attempt, err)
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		if serr := p.Sleep(ctx, wait); serr != nil {
+			return result, fmt.Errorf("retry: %w while waiting after %d attempts: %w", serr, attempt, err)
+		}
+	}
+}
+
+// fits reports whether a wait ends before MaxElapsed and the context's
+// deadline. Sleeping until the deadline only to fail is a wasted wait
+// and a wasted attempt.
+func (p Policy) fits(ctx context.Context, start time.Time, wait time.Duration) bool {
+	end := p.Now().Add(wait)
+	if p.MaxElapsed > 0 && end.After(start.Add(p.MaxElapsed)) {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && !end.Before(deadline) {
+		return false
+	}
+	return true
+}
+
+func wrap(attempt int, err error) error {
+	if attempt == 1 {
+		return err
+	}
+	return fmt.Errorf("retry: after %d attempts: %w", attempt, err)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Classifying Errors
+// ==================
+// fn decides what its errors mean. Permanent stops at once - a 400, a
+// failed validation, a missing record. RetryAfter asks for a specific
+// wait, as a server's Retry-After header does. Both unwrap to the
+// original error.
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying. Do returns err itself.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter asks Do to wait d, capped at Policy.Max, before the next
+// attempt instead of its own backoff
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err, max(d, 0)}
+}
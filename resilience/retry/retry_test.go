@@ -0,0 +1,637 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// Retry - Tests
+// =============
+// Run with:
+//
+//   cd resilience/retry
+//   go test -v *.go
+//   go test -race *.go
+//
+// A fake clock stands in for time: Sleep records each wait and moves
+// Now forward by it, so a test of a ten-second backoff takes no time
+// and sees exactly the waits Do chose.
+
+// fakeClock records waits and advances by them
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func newClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waits = append(f.waits, d)
+	f.now = f.now.Add(d)
+	return ctx.Err()
+}
+
+func (f *fakeClock) Waits() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.waits
+}
+
+// policy returns a deterministic Policy on clock: no jitter, 100ms
+// doubling to 1s
+func policy(clock *fakeClock) Policy {
+	return Policy{
+		Base:   100 * time.Millisecond,
+		Max:    time.Second,
+		Jitter: NoJitter,
+		Sleep:  clock.Sleep,
+		Now:    clock.Now,
+	}
+}
+
+var errFlaky = errors.New("flaky")
+
+// failing returns a function that fails n times, then succeeds, and
+// counts its calls
+func failing(n int, err error) (func(context.Context) error, *int) {
+	calls := 0
+	return func(context.Context) error {
+		calls++
+		if calls <= n {
+			return err
+		}
+		return nil
+	}, &calls
+}
+
+func ms(ns ...int) []time.Duration {
+	var d []time.Duration
+	for _, n := range ns {
+		d = append(d, time.Duration(n)*time.Millisecond)
+	}
+	return d
+}
+
+// 1. Backoff and Jitter
+// =====================
+
+func TestBackoffNoJitter(t *testing.T) {
+	p := Policy{Base: 100 * time.Millisecond, Max: time.Second, Jitter: NoJitter}
+	var got []time.Duration
+	for n := 1; n <= 6; n++ {
+		got = append(got, p.Backoff(n, 0))
+	}
+	if want := ms(100, 200, 400, 800, 1000, 1000); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Far past where Base<<n would overflow
+	if d := p.Backoff(10_000, 0); d != time.Second {
+		t.Errorf("Backoff(10000) = %v, want the cap", d)
+	}
+	p.Multiplier = 3
+	if d := p.Backoff(3, 0); d != 900*time.Millisecond {
+		t.Errorf("multiplier 3: %v, want 900ms", d)
+	}
+}
+
+// Each strategy stays in its range for any retry number and any random
+// value
+func TestJitterBounds(t *testing.T) {
+	base, maxWait := 100*time.Millisecond, 5*time.Second
+	property := func(n uint8, r float64, prevMs uint16) bool {
+		r = math.Mod(math.Abs(r), 1)
+		attempt := int(n%40) + 1
+		prev := time.Duration(prevMs) * time.Millisecond
+		p := Policy{Base: base, Max: maxWait, Rand: func() float64 { return r }}
+		b := p.Backoff(attempt, prev)
+		ceiling := min(base<<min(attempt-1, 30), maxWait)
+
+		p.Jitter = FullJitter
+		full := p.Backoff(attempt, prev)
+		p.Jitter = EqualJitter
+		equal := p.Backoff(attempt, prev)
+		p.Jitter = DecorrelatedJitter
+		dec := p.Backoff(attempt, prev)
+
+		return b == full && full >= 0 && full < ceiling+1 &&
+			equal >= ceiling/2 && equal <= ceiling &&
+			dec >= base && dec <= maxWait && dec <= 3*max(prev, base)
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// The default jitter spreads clients out: a thousand clients on their
+// third retry do not all wait the same time
+func TestJitterSpreads(t *testing.T) {
+	var p Policy
+	seen := map[time.Duration]bool{}
+	for range 1000 {
+		seen[p.Backoff(3, 0).Truncate(10*time.Millisecond)] = true
+	}
+	// 400ms in 10ms slots: 40 possible values
+	if len(seen) < 30 {
+		t.Errorf("only %d distinct waits in 1000", len(seen))
+	}
+}
+
+// 2. Do
+// =====
+
+func TestRetriesUntilSuccess(t *testing.T) {
+	clock := newClock()
+	fn, calls := failing(3, errFlaky)
+	if err := Do(context.Background(), policy(clock), fn); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 4 {
+		t.Errorf("%d calls, want 4", *calls)
+	}
+	if want := ms(100, 200, 400); !reflect.DeepEqual(clock.Waits(), want) {
+		t.Errorf("waits %v, want %v", clock.Waits(), want)
+	}
+}
+
+func TestGivesUp(t *testing.T) {
+	clock := newClock()
+	fn, calls := failing(100, errFlaky)
+	err := Do(context.Background(), policy(clock), fn)
+	if !errors.Is(err, errFlaky) || *calls != 4 {
+		t.Fatalf("got %v after %d calls, want errFlaky after 4", err, *calls)
+	}
+	if want := "retry: gave up after 4 attempts: flaky"; err.Error() != want {
+		t.Errorf("error %q, want %q", err, want)
+	}
+}
+
+func TestDoValue(t *testing.T) {
+	clock := newClock()
+	calls := 0
+	v, err := DoValue(context.Background(), policy(clock), func(context.Context) (string, error) {
+		if calls++; calls < 2 {
+			return "", errFlaky
+		}
+		return "done", nil
+	})
+	if v != "done" || err != nil {
+		t.Errorf("got %q, %v", v, err)
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	clock := newClock()
+	p := policy(clock)
+	var log []string
+	p.OnRetry = func(attempt int, err error, wait time.Duration) {
+		log = append(log, fmt.Sprintf("attempt %d: %v; waiting %v", attempt, err, wait))
+	}
+	fn, _ := failing(2, errFlaky)
+	Do(context.Background(), p, fn)
+	want := []string{"attempt 1: flaky; waiting 100ms", "attempt 2: flaky; waiting 200ms"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("got %q, want %q", log, want)
+	}
+}
+
+// 3. Classification
+// =================
+
+func TestPermanent(t *testing.T) {
+	clock := newClock()
+	errBadInput := errors.New("bad input")
+	calls := 0
+	err := Do(context.Background(), policy(clock), func(context.Context) error {
+		if calls++; calls == 1 {
+			return errFlaky
+		}
+		return Permanent(fmt.Errorf("validating: %w", errBadInput))
+	})
+	if calls != 2 || !errors.Is(err, errBadInput) {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		t.Error("the Permanent marker leaked to the caller")
+	}
+	if Permanent(nil) != nil || RetryAfter(nil, time.Second) != nil {
+		t.Error("marking nil must give nil")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	clock := newClock()
+	errNotFound := errors.New("not found")
+	p := policy(clock)
+	p.Retryable = func(err error) bool { return !errors.Is(err, errNotFound) }
+	fn, calls := failing(10, errNotFound)
+	if err := Do(context.Background(), p, fn); err != errNotFound || *calls != 1 {
+		t.Errorf("got %v after %d calls, want errNotFound as is after 1", err, *calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	clock := newClock()
+	p := policy(clock)
+	calls := 0
+	Do(context.Background(), p, func(context.Context) error {
+		switch calls++; calls {
+		case 1:
+			return RetryAfter(errFlaky, 700*time.Millisecond)
+		case 2:
+			return RetryAfter(errFlaky, time.Hour) // capped at Max
+		}
+		return nil
+	})
+	if want := ms(700, 1000); !reflect.DeepEqual(clock.Waits(), want) {
+		t.Errorf("waits %v, want %v", clock.Waits(), want)
+	}
+}
+
+// 4. Caps
+// =======
+
+func TestMaxElapsed(t *testing.T) {
+	clock := newClock()
+	p := policy(clock)
+	p.MaxAttempts = 100
+	p.MaxElapsed = 750 * time.Millisecond
+	fn, calls := failing(100, errFlaky)
+	err := Do(context.Background(), p, fn)
+	// 100 + 200 + 400 = 700ms; the next 800ms wait would end at 1.5s
+	if *calls != 4 || !errors.Is(err, errFlaky) || !strings.Contains(err.Error(), "no time for attempt 5") {
+		t.Errorf("got %v after %d calls", err, *calls)
+	}
+}
+
+// A wait that would end after the context's deadline is not started:
+// the attempt after it could never finish
+func TestDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var slept bool
+	p := Policy{Max: time.Hour, Sleep: func(context.Context, time.Duration) error { slept = true; return nil }}
+	start := time.Now()
+	err := Do(ctx, p, func(context.Context) error {
+		return RetryAfter(errFlaky, 2*time.Minute)
+	})
+	if slept || !errors.Is(err, errFlaky) {
+		t.Errorf("slept %v, err %v", slept, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("waited before giving up")
+	}
+}
+
+func TestCancelDuringWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := newClock()
+	p := policy(clock)
+	p.Sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	fn, calls := failing(10, errFlaky)
+	err := Do(ctx, p, fn)
+	if *calls != 1 || !errors.Is(err, context.Canceled) || !errors.Is(err, errFlaky) {
+		t.Errorf("got %v after %d calls, want both errors after 1", err, *calls)
+	}
+}
+
+// An error after the caller's context ended is not retried, whatever
+// fn returned with it
+func TestNoRetryAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := newClock()
+	calls := 0
+	Do(ctx, policy(clock), func(context.Context) error {
+		calls++
+		cancel()
+		return errFlaky
+	})
+	if calls != 1 {
+		t.Errorf("%d calls after cancel", calls)
+	}
+}
+
+// 5. Budgets
+// ==========
+
+func TestBudgetStopsStorm(t *testing.T) {
+	clock := newClock()
+	p := policy(clock)
+	p.MaxAttempts = 10
+	p.Budget = NewBudget(0.1, 5)
+	var attempts int
+	for range 1000 {
+		Do(context.Background(), p, func(context.Context) error {
+			attempts++
+			return errFlaky
+		})
+	}
+	// Without the budget: 10,000 attempts. With it: the calls, a tenth
+	// of them again, and the burst
+	if retries := attempts - 1000; retries > 1000/10+5 {
+		t.Errorf("%d retries for 1000 calls at ratio 0.1", retries)
+	}
+}
+
+func TestBudgetError(t *testing.T) {
+	clock := newClock()
+	p := policy(clock)
+	p.Budget = NewBudget(0.1, 1)
+	fn, calls := failing(100, errFlaky)
+	err := Do(context.Background(), p, fn)
+	if *calls != 2 || !errors.Is(err, ErrBudget) || !errors.Is(err, errFlaky) {
+		t.Errorf("got %v after %d calls, want ErrBudget after 2", err, *calls)
+	}
+	// Successful calls refill it
+	ok, _ := failing(0, nil)
+	for range 10 {
+		Do(context.Background(), p, ok)
+	}
+	if tokens := p.Budget.Tokens(); tokens < 0.99 {
+		t.Errorf("%.2f tokens after 10 calls at 0.1", tokens)
+	}
+}
+
+func TestBudgetConcurrent(t *testing.T) {
+	budget := NewBudget(0.5, 10)
+	var attempts atomic.Int64
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			clock := newClock()
+			p := policy(clock)
+			p.Budget = budget
+			for range 50 {
+				Do(context.Background(), p, func(context.Context) error {
+					attempts.Add(1)
+					return errFlaky
+				})
+			}
+		})
+	}
+	wg.Wait()
+	if retries := attempts.Load() - 1000; retries > 1000/2+10 {
+		t.Errorf("%d retries for 1000 calls at ratio 0.5", retries)
+	}
+}
+
+// 6. HTTP
+// =======
+
+// flaky answers with the given statuses in turn, then 200 with the
+// request body
+func flaky(statuses ...int) (http.Handler, *atomic.Int64) {
+	var calls atomic.Int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			io.WriteString(w, "try again")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "ok %s", body)
+	}), &calls
+}
+
+func httpClient(clock *fakeClock) *http.Client {
+	return &http.Client{Transport: &Transport{Policy: policy(clock)}}
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestHTTPRetriesTransient(t *testing.T) {
+	h, calls := flaky(503, 502, 429)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	clock := newClock()
+
+	req, _ := http.NewRequest("PUT", srv.URL, strings.NewReader("payload"))
+	resp, err := httpClient(clock).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readAll(t, resp); body != "ok payload" || calls.Load() != 4 {
+		t.Errorf("body %q after %d calls: the body must be sent again each time", body, calls.Load())
+	}
+	if want := ms(100, 200, 400); !reflect.DeepEqual(clock.Waits(), want) {
+		t.Errorf("waits %v, want %v", clock.Waits(), want)
+	}
+}
+
+// Out of attempts, the caller gets the last response, not an error
+func TestHTTPGivesUpWithResponse(t *testing.T) {
+	h, calls := flaky(503, 503, 503, 503, 503)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := httpClient(newClock()).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 503 || readAll(t, resp) != "try again" || calls.Load() != 4 {
+		t.Errorf("status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestHTTPNotRetried(t *testing.T) {
+	tests := []struct {
+		name, method string
+		key          string
+		status       int
+		wantCalls    int64
+	}{
+		{"404 is an answer", "GET", "", 404, 1},
+		{"501 will not change", "GET", "", 501, 1},
+		{"POST may not repeat", "POST", "", 503, 1},
+		{"POST with a key", "POST", "order-42", 503, 2},
+		{"DELETE is idempotent", "DELETE", "", 503, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, calls := flaky(tt.status)
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			req, _ := http.NewRequest(tt.method, srv.URL, strings.NewReader("x"))
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			resp, err := httpClient(newClock()).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("%d calls, want %d", calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHTTPRetryAfter(t *testing.T) {
+	clock := newClock()
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+		case 2:
+			// An HTTP date, read against the fake clock
+			w.Header().Set("Retry-After", clock.Now().Add(3*time.Second).Format(http.TimeFormat))
+			w.WriteHeader(503)
+		}
+	}))
+	defer srv.Close()
+
+	p := policy(clock)
+	p.Max = time.Minute
+	resp, err := (&http.Client{Transport: &Transport{Policy: p}}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := ms(0, 3000); !reflect.DeepEqual(clock.Waits(), want) {
+		t.Errorf("waits %v, want %v", clock.Waits(), want)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestHTTPNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	clock := newClock()
+	var calls int
+	c := &http.Client{Transport: &Transport{
+		Policy: policy(clock),
+		Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			if calls++; calls < 3 {
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+			}
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readAll(t, resp) != "ok" || calls != 3 {
+		t.Errorf("%d calls", calls)
+	}
+}
+
+func TestHTTPNotReplayable(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "http://example.invalid", io.NopCloser(strings.NewReader("once")))
+	_, err := httpClient(newClock()).Do(req)
+	if !errors.Is(err, ErrNotReplayable) {
+		t.Errorf("got %v, want ErrNotReplayable", err)
+	}
+}
+
+// Discarded responses are drained, so all attempts share one
+// keep-alive connection
+func TestHTTPReusesConnection(t *testing.T) {
+	h, _ := flaky(503, 503, 503)
+	srv := httptest.NewUnstartedServer(h)
+	var conns atomic.Int64
+	srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	transport := &http.Transport{}
+	defer transport.CloseIdleConnections()
+	c := &http.Client{Transport: &Transport{Base: transport, Policy: policy(newClock())}}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, resp)
+	if conns.Load() != 1 {
+		t.Errorf("%d connections for 4 attempts, want 1", conns.Load())
+	}
+}
+
+// 7. Benchmarks
+// =============
+
+func BenchmarkDoFirstTry(b *testing.B) {
+	ctx := context.Background()
+	var p Policy
+	fn := func(context.Context) error { return nil }
+	b.ReportAllocs()
+	for b.Loop() {
+		Do(ctx, p, fn)
+	}
+}
+
+// 8. Examples
+// ===========
+
+func ExampleDo() {
+	clock := newClock()
+	p := Policy{
+		Base:   time.Second,
+		Jitter: NoJitter, // deterministic output; use jitter in real code
+		Sleep:  clock.Sleep,
+		Now:    clock.Now,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			fmt.Printf("attempt %d: %v, retrying in %v\n", attempt, err, wait)
+		},
+	}
+	attempts := 0
+	err := Do(context.Background(), p, func(ctx context.Context) error {
+		if attempts++; attempts < 3 {
+			return errors.New("503 Service Unavailable")
+		}
+		return nil
+	})
+	fmt.Println("done:", err)
+	// Output:
+	// attempt 1: 503 Service Unavailable, retrying in 1s
+	// attempt 2: 503 Service Unavailable, retrying in 2s
+	// done: <nil>
+}
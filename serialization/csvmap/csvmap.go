@@ -0,0 +1,266 @@
+package csvmap
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// csvmap - CSV Rows Into Structs by Header Name
+// =============================================
+// encoding/csv splits a stream into records ([]string) and handles the
+// hard parts of the format: quoted fields, embedded commas, quotes and
+// newlines. It knows nothing about types. csvmap adds the last step:
+// the header row names the columns, struct tags name the fields, and
+// reflection connects the two once, before the first row.
+//
+//	type Trade struct {
+//		Symbol string    `csv:"symbol"`
+//		Price  float64   `csv:"price"`
+//		Note   string    `csv:"note,optional"`
+//		Cache  int       `csv:"-"`
+//	}
+//
+// Columns are matched by name, so their order in the file does not
+// matter and extra columns are ignored. A field without ",optional"
+// whose column is missing is an error up front, not a silent zero in
+// every row.
+//
+// Rows are decoded one at a time: memory depends on the row, not the
+// file.
+
+var (
+	// ErrMissingColumn means a required field has no column in the header
+	ErrMissingColumn = errors.New("csvmap: missing column")
+
+	// ErrDuplicateColumn means two header columns have the same name
+	ErrDuplicateColumn = errors.New("csvmap: duplicate column")
+)
+
+// FieldError reports a value that could not be converted to its field's
+// type
+type FieldError struct {
+	Line   int    // line in the input, from 1
+	Column string // header name
+	Value  string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("line %d, column %q: cannot use %q: %v", e.Line, e.Column, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// field is a struct field bound to a column of the input
+type field struct {
+	index  []int // reflect index of the struct field
+	column int   // position in the record
+	name   string
+	set    func(v reflect.Value, s string) error
+}
+
+// Decoder reads structs of type T from CSV with a header row
+type Decoder[T any] struct {
+	r      *csv.Reader
+	fields []field
+}
+
+// NewDecoder reads the header row from r and binds T's fields to its
+// columns. T must be a struct type.
+func NewDecoder[T any](r io.Reader) (*Decoder[T], error) {
+	cr := csv.NewReader(r)
+	// The record slice is reused between reads. Safe here: each record
+	// is converted before the next read, and strings are immutable.
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csvmap: no header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The first record sets FieldsPerRecord (it starts at 0), so every
+	// row must now have as many fields as the header
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheets often save UTF-8 with a byte-order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.TrimSpace(name)
+		if _, dup := columns[name]; dup {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateColumn, name)
+		}
+		columns[name] = i
+	}
+
+	fields, err := bind(reflect.TypeFor[T](), columns)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder[T]{r: cr, fields: fields}, nil
+}
+
+// bind matches the fields of struct type t to header columns
+func bind(t reflect.Type, columns map[string]int) ([]field, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csvmap: %v is not a struct", t)
+	}
+	var fields []field
+	var missing []string
+	for sf := range t.Fields() {
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		col, ok := columns[name]
+		if !ok {
+			if opts != "optional" {
+				missing = append(missing, name)
+			}
+			continue
+		}
+		set, err := setter(sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("csvmap: field %s: %w", sf.Name, err)
+		}
+		fields = append(fields, field{index: sf.Index, column: col, name: name, set: set})
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingColumn, strings.Join(missing, ", "))
+	}
+	return fields, nil
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// setter returns a function converting a string into a value of type t.
+// The type switch runs once per field, not once per cell.
+func setter(t reflect.Type) (func(reflect.Value, string) error, error) {
+	// time.Time, net/netip.Addr and custom types parse themselves
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return func(v reflect.Value, s string) error {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return func(v reflect.Value, s string) error { v.SetString(s); return nil }, nil
+	case reflect.Bool:
+		return func(v reflect.Value, s string) error {
+			b, err := strconv.ParseBool(s)
+			v.SetBool(b)
+			return err
+		}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := t.Bits()
+		return func(v reflect.Value, s string) error {
+			n, err := strconv.ParseInt(s, 10, bits)
+			v.SetInt(n)
+			return err
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bits := t.Bits()
+		return func(v reflect.Value, s string) error {
+			n, err := strconv.ParseUint(s, 10, bits)
+			v.SetUint(n)
+			return err
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		bits := t.Bits()
+		return func(v reflect.Value, s string) error {
+			f, err := strconv.ParseFloat(s, bits)
+			v.SetFloat(f)
+			return err
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// Read decodes the next row. It returns io.EOF after the last row.
+//
+// A malformed row - wrong number of fields, a bad quote, a value that
+// does not convert - returns an error, and the decoder moves on: the
+// next Read continues with the following row. The caller decides
+// whether one bad row stops the import.
+func (d *Decoder[T]) Read() (T, error) {
+	var row T
+	record, err := d.r.Read()
+	if err != nil {
+		return row, err
+	}
+	v := reflect.ValueOf(&row).Elem()
+	for _, f := range d.fields {
+		s := record[f.column]
+		if err := f.set(v.FieldByIndex(f.index), s); err != nil {
+			line, _ := d.r.FieldPos(f.column)
+			return row, &FieldError{Line: line, Column: f.name, Value: s, Err: unwrapNum(err)}
+		}
+	}
+	return row, nil
+}
+
+// All iterates over the rows. Each row comes with its error, which is
+// nil for a good row; the iteration ends at the end of the input.
+func (d *Decoder[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			row, err := d.Read()
+			if err == io.EOF {
+				return
+			}
+			if !yield(row, err) {
+				return
+			}
+		}
+	}
+}
+
+// DecodeAll reads every row of r. Rows that fail are collected in bad
+// and skipped; err is set only when the input cannot be read at all -
+// no header, a missing column, or a failing reader.
+func DecodeAll[T any](r io.Reader) (rows []T, bad []error, err error) {
+	d, err := NewDecoder[T](r)
+	if err != nil {
+		return nil, nil, err
+	}
+	for row, err := range d.All() {
+		var fe *FieldError
+		var pe *csv.ParseError
+		switch {
+		case err == nil:
+			rows = append(rows, row)
+		case errors.As(err, &fe), errors.As(err, &pe):
+			bad = append(bad, err)
+		default:
+			return rows, bad, err
+		}
+	}
+	return rows, bad, nil
+}
+
+// unwrapNum drops strconv's *NumError wrapper, which repeats the value
+// and function name that FieldError already reports
+func unwrapNum(err error) error {
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		return ne.Err
+	}
+	return err
+}
@@ -0,0 +1,359 @@
+package csvmap
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// CSV - encoding/csv and Struct Mapping
+// =====================================
+// Run with:
+//
+//   cd serialization/csvmap
+//   go test -v *.go
+//   go test -bench . -benchmem *.go
+
+type Trade struct {
+	Symbol string    `csv:"symbol"`
+	Price  float64   `csv:"price"`
+	Qty    int       `csv:"qty"`
+	Time   time.Time `csv:"time"`
+	Buy    bool      `csv:"buy"`
+	Note   string    `csv:"note,optional"`
+	Cache  int       `csv:"-"`
+}
+
+var t0 = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+// 1. Why Not strings.Split
+// ========================
+
+func TestSplitVsEncodingCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want [][]string
+	}{
+		{"simple", "a,b,c\n", [][]string{{"a", "b", "c"}}},
+		{"quoted comma", `"Smith, J.",42` + "\n", [][]string{{"Smith, J.", "42"}}},
+		{"doubled quote", `"say ""hi""",x` + "\n", [][]string{{`say "hi"`, "x"}}},
+		{"newline in field", "\"line 1\nline 2\",x\n", [][]string{{"line 1\nline 2", "x"}}},
+		{"CRLF", "a,b\r\nc,d\r\n", [][]string{{"a", "b"}, {"c", "d"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := csv.NewReader(strings.NewReader(tt.in)).ReadAll()
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("encoding/csv = %q, %v; want %q", got, err, tt.want)
+			}
+			// Only the first case survives strings.Split
+			naive := SplitParse(tt.in)
+			if ok := reflect.DeepEqual(naive, tt.want); ok != (tt.name == "simple") {
+				t.Errorf("SplitParse = %q (correct: %v)", naive, ok)
+			}
+		})
+	}
+}
+
+// 2. Struct Mapping
+// =================
+
+func TestDecode(t *testing.T) {
+	// Columns in a different order from the struct, an extra column,
+	// no "note" column, and a BOM in front of the header
+	in := "\ufeffqty,time,symbol,exchange,price,buy\n" +
+		"100,2024-03-01T09:30:00Z,GOOG,NASDAQ,140.5,true\n" +
+		"7,2024-03-01T09:31:00Z,\"BRK.B\",NYSE,405.25,false\n"
+
+	rows, bad, err := DecodeAll[Trade](strings.NewReader(in))
+	if err != nil || len(bad) > 0 {
+		t.Fatal(err, bad)
+	}
+	want := []Trade{
+		{Symbol: "GOOG", Price: 140.5, Qty: 100, Time: t0, Buy: true},
+		{Symbol: "BRK.B", Price: 405.25, Qty: 7, Time: t0.Add(time.Minute)},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v\nwant   %+v", rows, want)
+	}
+}
+
+func TestDecodeFieldNames(t *testing.T) {
+	// Without a tag the column name is the field name, exactly
+	type Point struct {
+		X, Y   int8
+		Label  string `csv:"label,optional"`
+		hidden string
+	}
+	rows, bad, err := DecodeAll[Point](strings.NewReader("Y,X\n1,2\n-128,127\n"))
+	if err != nil || len(bad) > 0 {
+		t.Fatal(err, bad)
+	}
+	if want := []Point{{X: 2, Y: 1}, {X: 127, Y: -128}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v, want %+v", rows, want)
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want error
+	}{
+		{"missing columns", "symbol,qty\n", ErrMissingColumn},
+		{"duplicate column", "symbol,price,qty,time,buy,qty\n", ErrDuplicateColumn},
+	}
+	for _, tt := range tests {
+		if _, err := NewDecoder[Trade](strings.NewReader(tt.in)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// The message names every missing column at once
+	_, err := NewDecoder[Trade](strings.NewReader("symbol,qty\n"))
+	if err == nil || !strings.Contains(err.Error(), "price, time, buy") {
+		t.Errorf("err = %v", err)
+	}
+
+	if _, err := NewDecoder[Trade](strings.NewReader("")); err == nil {
+		t.Error("empty input: want an error")
+	}
+	if _, err := NewDecoder[int](strings.NewReader("a\n")); err == nil {
+		t.Error("non-struct type: want an error")
+	}
+	type Bad struct{ Tags []string }
+	if _, err := NewDecoder[Bad](strings.NewReader("Tags\n")); err == nil {
+		t.Error("unsupported field type: want an error")
+	}
+}
+
+// 3. Malformed Rows
+// =================
+
+func TestMalformedRows(t *testing.T) {
+	in := strings.Join([]string{
+		"symbol,price,qty,time,buy",
+		"GOOG,140.5,100,2024-03-01T09:30:00Z,true", // line 2: good
+		"MSFT,410,5",                           // line 3: too few fields
+		"AAPL,abc,1,2024-03-01T09:30:00Z,true", // line 4: bad float
+		"AMZN,178,99999999999999999999,2024-03-01T09:30:00Z,false", // line 5: int overflow
+		`"TSLA,175,3,2024-03-01T09:30:00Z,false`,                   // line 6: quote never closed
+		"",
+	}, "\n")
+
+	rows, bad, err := DecodeAll[Trade](strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Symbol != "GOOG" {
+		t.Errorf("good rows = %+v", rows)
+	}
+	if len(bad) != 4 {
+		t.Fatalf("bad rows = %d: %v", len(bad), bad)
+	}
+
+	// Wrong field count is a *csv.ParseError wrapping csv.ErrFieldCount
+	var pe *csv.ParseError
+	if !errors.As(bad[0], &pe) || !errors.Is(bad[0], csv.ErrFieldCount) || pe.Line != 3 {
+		t.Errorf("bad[0] = %v", bad[0])
+	}
+
+	// Conversion failures are FieldErrors with the line and column name
+	var fe *FieldError
+	if !errors.As(bad[1], &fe) || fe.Line != 4 || fe.Column != "price" || !errors.Is(fe, strconv.ErrSyntax) {
+		t.Errorf("bad[1] = %v", bad[1])
+	}
+	if !errors.As(bad[2], &fe) || fe.Line != 5 || !errors.Is(fe, strconv.ErrRange) {
+		t.Errorf("bad[2] = %v", bad[2])
+	}
+
+	// An unclosed quote swallows the rest of the input into one field
+	if !errors.Is(bad[3], csv.ErrQuote) {
+		t.Errorf("bad[3] = %v", bad[3])
+	}
+}
+
+func TestReadContinuesAfterBadRow(t *testing.T) {
+	d, err := NewDecoder[Trade](strings.NewReader(
+		"symbol,price,qty,time,buy\nA,1,x,2024-03-01T09:30:00Z,true\nB,2,2,2024-03-01T09:30:00Z,true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Read(); err == nil {
+		t.Fatal("first row: want an error")
+	}
+	if row, err := d.Read(); err != nil || row.Symbol != "B" {
+		t.Errorf("second row = %+v, %v", row, err)
+	}
+	if _, err := d.Read(); err != io.EOF {
+		t.Errorf("after the last row: err = %v, want io.EOF", err)
+	}
+}
+
+func TestReaderFailure(t *testing.T) {
+	// An I/O error is not a bad row: DecodeAll stops and returns it
+	boom := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("symbol,price,qty,time,buy\nA,1,1,2024-03-01T09:30:00Z,true\n"), errReader{boom})
+	rows, _, err := DecodeAll[Trade](r)
+	if !errors.Is(err, boom) || len(rows) != 1 {
+		t.Errorf("rows = %d, err = %v", len(rows), err)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// 4. Streaming
+// ============
+
+// tradeSource generates n CSV rows on demand. The input never exists
+// in memory as a whole, as with a large file or a network stream.
+type tradeSource struct {
+	n, i int
+	buf  []byte
+}
+
+func newTradeSource(n int) *tradeSource {
+	return &tradeSource{n: n, buf: []byte("symbol,price,qty,time,buy\n")}
+}
+
+func (s *tradeSource) Read(p []byte) (int, error) {
+	for len(s.buf) < len(p) && s.i < s.n {
+		s.buf = fmt.Appendf(s.buf, "SYM%d,%d.25,%d,2024-03-01T09:30:00Z,%t\n", s.i%500, s.i%1000, s.i, s.i%2 == 0)
+		s.i++
+	}
+	if len(s.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[:copy(s.buf, s.buf[n:])]
+	return n, nil
+}
+
+func TestStreaming(t *testing.T) {
+	const n = 200_000
+	d, err := NewDecoder[Trade](newTradeSource(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, qty := 0, 0
+	for row, err := range d.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		qty += row.Qty
+	}
+	if count != n || qty != n*(n-1)/2 {
+		t.Errorf("decoded %d rows, qty sum %d", count, qty)
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	d, _ := NewDecoder[Trade](newTradeSource(1000))
+	seen := 0
+	for range d.All() {
+		if seen++; seen == 3 {
+			break
+		}
+	}
+	if row, err := d.Read(); err != nil || row.Qty != 3 {
+		t.Errorf("after break, next row = %+v, %v", row, err)
+	}
+}
+
+// 5. Benchmarks
+// =============
+// Every benchmark reads the same 10,000 simple rows (no quotes), where
+// the naive parser still gives the right answer. Measured here:
+//
+//	SplitParse                 1.8 ms   1.8 MB   10k allocs
+//	encoding/csv               2.1 ms   1.3 MB   20k allocs
+//	encoding/csv, ReuseRecord  1.5 ms   0.5 MB   10k allocs
+//	csvmap                     4.5 ms   1.7 MB   30k allocs
+//
+// Correctness costs nothing: with ReuseRecord, encoding/csv beats the
+// split. csvmap's extra time is the per-field conversion - parsing
+// floats, ints and timestamps - that any typed result needs.
+
+var benchData = func() string {
+	b, _ := io.ReadAll(newTradeSource(10_000))
+	return string(b)
+}()
+
+func BenchmarkSplitParse(b *testing.B) {
+	b.SetBytes(int64(len(benchData)))
+	b.ReportAllocs()
+	for b.Loop() {
+		SplitParse(benchData)
+	}
+}
+
+func BenchmarkEncodingCSV(b *testing.B) {
+	for _, reuse := range []bool{false, true} {
+		b.Run(fmt.Sprintf("ReuseRecord=%t", reuse), func(b *testing.B) {
+			b.SetBytes(int64(len(benchData)))
+			b.ReportAllocs()
+			for b.Loop() {
+				r := csv.NewReader(strings.NewReader(benchData))
+				r.ReuseRecord = reuse
+				for {
+					if _, err := r.Read(); err != nil {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCSVMap(b *testing.B) {
+	b.SetBytes(int64(len(benchData)))
+	b.ReportAllocs()
+	for b.Loop() {
+		d, _ := NewDecoder[Trade](strings.NewReader(benchData))
+		for {
+			if _, err := d.Read(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+// Examples
+// ========
+
+func ExampleDecodeAll() {
+	in := `name,age,email
+"Lovelace, Ada",36,ada@example.com
+Babbage,seventy,cb@example.com
+Hopper,85,grace@example.com
+`
+	type Person struct {
+		Name string `csv:"name"`
+		Age  int    `csv:"age"`
+	}
+	people, bad, err := DecodeAll[Person](strings.NewReader(in))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, p := range people {
+		fmt.Printf("%s (%d)\n", p.Name, p.Age)
+	}
+	for _, e := range bad {
+		fmt.Println("skipped:", e)
+	}
+	// Output:
+	// Lovelace, Ada (36)
+	// Hopper (85)
+	// skipped: line 3, column "age": cannot use "seventy": invalid syntax
+}
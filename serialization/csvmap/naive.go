@@ -0,0 +1,23 @@
+package csvmap
+
+import (
+	"strings"
+)
+
+// The Naive Parser
+// ================
+// strings.Split on newlines and commas looks like CSV parsing and works
+// on simple data. It breaks on anything a spreadsheet will happily
+// write: a quoted field with a comma ("Smith, J."), a doubled quote
+// inside quotes (""), a newline inside a quoted field, or "\r\n" line
+// endings. The benchmarks compare it with encoding/csv to show what the
+// correct parser costs.
+
+// SplitParse splits data into records on "\n" and ","
+func SplitParse(data string) [][]string {
+	var records [][]string
+	for line := range strings.Lines(data) {
+		records = append(records, strings.Split(strings.TrimSuffix(line, "\n"), ","))
+	}
+	return records
+}
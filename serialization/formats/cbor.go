@@ -0,0 +1,435 @@
+package formats
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// CBOR
+// ====
+// RFC 8949. Every item starts with one byte: a major type in the top 3
+// bits and, in the low 5, either a small value or how many bytes of
+// value follow:
+//
+//	major 0  unsigned integer       0x00-0x17 is 0-23 in that one byte;
+//	major 1  negative, -1 - n       0x18 + 1 byte, 0x19 + 2, 0x1a + 4,
+//	major 2  byte string            0x1b + 8 (big-endian). For strings,
+//	major 3  text string (UTF-8)    arrays and maps the value is the
+//	major 4  array                  length
+//	major 5  map
+//	major 6  tag - a type hint on the next item, e.g. 1 for a timestamp
+//	major 7  false, true, null; float16/32/64
+//
+// So {"id": 1} is a1 62 69 64 01: a map of one pair, a two-byte text
+// string "id", the integer 1. JSON's model with binary lengths: no
+// quoting or escaping, no number parsing, and []byte without base64.
+//
+// Deterministic encoding (RFC 8949 section 4.2) fixes the choices left
+// open - the shortest head, map keys sorted by their encoded bytes, no
+// indefinite lengths - so equal values give equal bytes, which hashing
+// and signing need. This encoder follows it, except that floats are
+// always 8 bytes where the rules want the shortest exact width.
+//
+// Only the items the lesson needs are handled: no tags, no
+// indefinite-length items, no non-string map keys.
+
+// errBadCBOR is returned for input this decoder cannot read
+var errBadCBOR = errors.New("formats: malformed or unsupported CBOR")
+
+const (
+	cborUint   = 0
+	cborNeg    = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// appendHead writes a major type with its argument in the shortest form
+func appendHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, m|27), n)
+	}
+}
+
+// AppendCBOR appends the encoding of v, which may be nil, bool, int64,
+// uint64, float64, string, []byte, []any or map[string]any, nested
+func AppendCBOR(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xf6), nil
+	case bool:
+		if v {
+			return append(b, 0xf5), nil
+		}
+		return append(b, 0xf4), nil
+	case int64:
+		if v < 0 {
+			return appendHead(b, cborNeg, uint64(-1-v)), nil
+		}
+		return appendHead(b, cborUint, uint64(v)), nil
+	case uint64:
+		return appendHead(b, cborUint, v), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(v)), nil
+	case string:
+		return append(appendHead(b, cborText, uint64(len(v))), v...), nil
+	case []byte:
+		return append(appendHead(b, cborBytes, uint64(len(v))), v...), nil
+	case []any:
+		b = appendHead(b, cborArray, uint64(len(v)))
+		for _, elem := range v {
+			var err error
+			if b, err = AppendCBOR(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		// Keys sorted by their encoded bytes. For text keys that is
+		// shorter first, then bytewise - the head holds the length
+		keys := slices.SortedFunc(maps.Keys(v), func(a, b string) int {
+			return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+		})
+		b = appendHead(b, cborMap, uint64(len(v)))
+		for _, k := range keys {
+			b = append(appendHead(b, cborText, uint64(len(k))), k...)
+			var err error
+			if b, err = AppendCBOR(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("formats: cannot encode %T as CBOR", v)
+}
+
+// maxDepth bounds nesting, so a few kilobytes of 0x81 (an array of one
+// array of one array...) cannot exhaust the stack
+const maxDepth = 64
+
+// DecodeCBOR decodes exactly one item filling b. Integers come back as
+// int64, or uint64 above math.MaxInt64; all floats as float64.
+func DecodeCBOR(b []byte) (any, error) {
+	v, rest, err := decodeItem(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: %d bytes after the item", errBadCBOR, len(rest))
+	}
+	return v, nil
+}
+
+func decodeItem(b []byte, depth int) (v any, rest []byte, err error) {
+	if depth > maxDepth || len(b) == 0 {
+		return nil, nil, errBadCBOR
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	if major == cborSimple {
+		return decodeSimple(info, b)
+	}
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24) // 1, 2, 4 or 8 bytes
+		if len(b) < size {
+			return nil, nil, errBadCBOR
+		}
+		for _, c := range b[:size] {
+			n = n<<8 | uint64(c)
+		}
+		b = b[size:]
+	default:
+		return nil, nil, errBadCBOR // 28-30 reserved, 31 indefinite length
+	}
+
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return n, b, nil
+		}
+		return int64(n), b, nil
+	case cborNeg:
+		if n > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%w: -1-%d does not fit in int64", errBadCBOR, n)
+		}
+		return -1 - int64(n), b, nil
+	case cborBytes, cborText:
+		// Check the length against what is left before allocating
+		if n > uint64(len(b)) {
+			return nil, nil, errBadCBOR
+		}
+		if major == cborText {
+			if !utf8.Valid(b[:n]) {
+				return nil, nil, fmt.Errorf("%w: text string is not UTF-8", errBadCBOR)
+			}
+			return string(b[:n]), b[n:], nil
+		}
+		return bytes.Clone(b[:n]), b[n:], nil
+	case cborArray:
+		// Every item is at least one byte
+		if n > uint64(len(b)) {
+			return nil, nil, errBadCBOR
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], b, err = decodeItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+		}
+		return arr, b, nil
+	case cborMap:
+		if n > uint64(len(b))/2 {
+			return nil, nil, errBadCBOR
+		}
+		m := make(map[string]any, n)
+		for range n {
+			var k any
+			if k, b, err = decodeItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: map key %T", errBadCBOR, k)
+			}
+			if _, dup := m[key]; dup {
+				return nil, nil, fmt.Errorf("%w: duplicate map key %q", errBadCBOR, key)
+			}
+			if m[key], b, err = decodeItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+		}
+		return m, b, nil
+	}
+	return nil, nil, fmt.Errorf("%w: tags are not supported", errBadCBOR)
+}
+
+func decodeSimple(info byte, b []byte) (any, []byte, error) {
+	switch info {
+	case 20:
+		return false, b, nil
+	case 21:
+		return true, b, nil
+	case 22, 23: // null, undefined
+		return nil, b, nil
+	case 25:
+		if len(b) < 2 {
+			return nil, nil, errBadCBOR
+		}
+		return float16(binary.BigEndian.Uint16(b)), b[2:], nil
+	case 26:
+		if len(b) < 4 {
+			return nil, nil, errBadCBOR
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 27:
+		if len(b) < 8 {
+			return nil, nil, errBadCBOR
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	}
+	return nil, nil, errBadCBOR
+}
+
+// float16 converts IEEE 754 half precision, which other encoders use
+// for floats like 1.5 that fit
+func float16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	switch exp {
+	case 0: // subnormal
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(1024+frac, exp-25)
+}
+
+// MarshalCBOR encodes o as a CBOR map with the same keys as its JSON
+func (o *Order) MarshalCBOR() ([]byte, error) {
+	items := make([]any, len(o.Items))
+	for i, it := range o.Items {
+		items[i] = map[string]any{
+			"sku":       it.SKU,
+			"quantity":  int64(it.Quantity),
+			"weight_kg": it.WeightKg,
+		}
+	}
+	m := map[string]any{
+		"id":          o.ID,
+		"customer":    o.Customer,
+		"items":       items,
+		"total_cents": o.TotalCents,
+		"paid":        o.Paid,
+		"created_at":  o.CreatedAt,
+	}
+	if len(o.Tags) > 0 {
+		tags := make([]any, len(o.Tags))
+		for i, t := range o.Tags {
+			tags[i] = t
+		}
+		m["tags"] = tags
+	}
+	return AppendCBOR(nil, m)
+}
+
+// UnmarshalCBOR replaces o with the order in b. Unknown keys are
+// ignored and missing ones left at zero, as encoding/json does; a known
+// key with the wrong type is an error.
+func (o *Order) UnmarshalCBOR(b []byte) error {
+	v, err := DecodeCBOR(b)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%w: order is %T, not a map", errBadCBOR, v)
+	}
+	*o = Order{}
+	r := cborReader{m: m}
+	o.ID = r.uint("id")
+	o.Customer = r.text("customer")
+	o.TotalCents = r.int("total_cents")
+	o.Paid = r.bool("paid")
+	o.CreatedAt = r.int("created_at")
+	for _, elem := range r.array("items") {
+		im, ok := elem.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w: item is %T", errBadCBOR, elem)
+		}
+		ir := cborReader{m: im}
+		q := ir.int("quantity")
+		if q < math.MinInt32 || q > math.MaxInt32 {
+			return fmt.Errorf("%w: quantity %d overflows int32", errBadCBOR, q)
+		}
+		o.Items = append(o.Items, Item{SKU: ir.text("sku"), Quantity: int32(q), WeightKg: ir.float("weight_kg")})
+		if ir.err != nil {
+			return ir.err
+		}
+	}
+	for _, elem := range r.array("tags") {
+		tag, ok := elem.(string)
+		if !ok {
+			return fmt.Errorf("%w: tag is %T", errBadCBOR, elem)
+		}
+		o.Tags = append(o.Tags, tag)
+	}
+	return r.err
+}
+
+// cborReader reads typed fields from a decoded map, keeping the first
+// type error so the caller checks once
+type cborReader struct {
+	m   map[string]any
+	err error
+}
+
+func (r *cborReader) fail(key string, v any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %q is %T", errBadCBOR, key, v)
+	}
+}
+
+func (r *cborReader) int(key string) int64 {
+	switch v := r.m[key].(type) {
+	case nil:
+	case int64:
+		return v
+	default:
+		r.fail(key, v)
+	}
+	return 0
+}
+
+func (r *cborReader) uint(key string) uint64 {
+	switch v := r.m[key].(type) {
+	case nil:
+	case uint64:
+		return v
+	case int64:
+		if v >= 0 {
+			return uint64(v)
+		}
+		r.fail(key, v)
+	default:
+		r.fail(key, v)
+	}
+	return 0
+}
+
+func (r *cborReader) text(key string) string {
+	switch v := r.m[key].(type) {
+	case nil:
+	case string:
+		return v
+	default:
+		r.fail(key, v)
+	}
+	return ""
+}
+
+func (r *cborReader) bool(key string) bool {
+	switch v := r.m[key].(type) {
+	case nil:
+	case bool:
+		return v
+	default:
+		r.fail(key, v)
+	}
+	return false
+}
+
+func (r *cborReader) float(key string) float64 {
+	switch v := r.m[key].(type) {
+	case nil:
+	case float64:
+		return v
+	case int64: // another encoder may write 2.0 as the integer 2
+		return float64(v)
+	default:
+		r.fail(key, v)
+	}
+	return 0
+}
+
+func (r *cborReader) array(key string) []any {
+	switch v := r.m[key].(type) {
+	case nil:
+	case []any:
+		return v
+	default:
+		r.fail(key, v)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// Comparing the Formats
+// =====================
+// Compare encodes one Order in each format and writes a Markdown table
+// of size and speed, measured with testing.Benchmark. The numbers are
+// for this machine and this message; the ordering is what carries over.
+//
+// Schema Evolution
+// ================
+// Size and speed are measured once; evolution is lived with for years.
+// What a reader does with data from a writer one version away, as the
+// tests in formats_test.go check:
+//
+//	change                  JSON      gob       protobuf    CBOR
+//	add a field             skipped   skipped   skipped     skipped
+//	remove a field          zero      zero      zero        zero
+//	rename a field          lost      lost      kept        lost
+//	int32 -> int64          fine      fine      fine        fine
+//	int64 -> int32, large   error     error     truncated   error
+//	int -> string           error     error     dropped     error
+//
+// protobuf finds fields by number, so renames are free and the name is
+// never sent - but a value that no longer fits is truncated silently,
+// and a changed wire type makes the field vanish rather than fail.
+// The name-keyed formats fail loudly instead. Neither is safe by
+// default: evolve by adding fields, and read with the oldest reader
+// still deployed in mind.
+
+// Codec is one format's encoder and decoder for an Order
+type Codec struct {
+	Name      string
+	Marshal   func(*Order) ([]byte, error)
+	Unmarshal func([]byte, *Order) error
+}
+
+// Codecs lists the formats in the order the table shows them
+var Codecs = []Codec{
+	{"JSON", func(o *Order) ([]byte, error) { return json.Marshal(o) }, func(b []byte, o *Order) error {
+		*o = Order{}
+		return json.Unmarshal(b, o)
+	}},
+	{"gob", marshalGob, unmarshalGob},
+	{"protobuf", func(o *Order) ([]byte, error) { return o.AppendProto(nil), nil }, func(b []byte, o *Order) error {
+		return o.UnmarshalProto(b)
+	}},
+	{"CBOR", (*Order).MarshalCBOR, func(b []byte, o *Order) error { return o.UnmarshalCBOR(b) }},
+}
+
+// marshalGob encodes one Order with a fresh Encoder: the type
+// description goes out with it, as for any one-off gob message
+func marshalGob(o *Order) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalGob(b []byte, o *Order) error {
+	*o = Order{}
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(o)
+}
+
+// gobStreamSize is the size of the second Order on one Encoder, once
+// the type description has been sent
+func gobStreamSize(o *Order) (int, error) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(o); err != nil {
+		return 0, err
+	}
+	first := buf.Len()
+	if err := enc.Encode(o); err != nil {
+		return 0, err
+	}
+	return buf.Len() - first, nil
+}
+
+// Result is one row of the comparison
+type Result struct {
+	Name                       string
+	Size                       int
+	Encode, Decode             testing.BenchmarkResult
+	EncodeAllocs, DecodeAllocs int64
+}
+
+// Measure encodes and decodes o with each codec, checking the round
+// trip before timing anything
+func Measure(o *Order) ([]Result, error) {
+	var results []Result
+	for _, c := range Codecs {
+		data, err := c.Marshal(o)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		var back Order
+		if err := c.Unmarshal(data, &back); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+
+		enc := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				c.Marshal(o)
+			}
+		})
+		dec := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			var out Order
+			for b.Loop() {
+				c.Unmarshal(data, &out)
+			}
+		})
+		results = append(results, Result{
+			Name: c.Name, Size: len(data),
+			Encode: enc, Decode: dec,
+			EncodeAllocs: enc.AllocsPerOp(), DecodeAllocs: dec.AllocsPerOp(),
+		})
+	}
+	return results, nil
+}
+
+// Compare measures o and writes the table
+func Compare(w io.Writer, o *Order) error {
+	results, err := Measure(o)
+	if err != nil {
+		return err
+	}
+	jsonSize := results[0].Size
+
+	fmt.Fprintln(w, "| format | bytes | vs JSON | encode ns/op | decode ns/op | encode allocs | decode allocs |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|")
+	for _, r := range results {
+		fmt.Fprintf(w, "| %s | %d | %d%% | %d | %d | %d | %d |\n",
+			r.Name, r.Size, 100*r.Size/jsonSize,
+			r.Encode.NsPerOp(), r.Decode.NsPerOp(), r.EncodeAllocs, r.DecodeAllocs)
+	}
+
+	stream, err := gobStreamSize(o)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\ngob sends the type description once per stream: %d bytes per Order after the first.\n", stream)
+	return nil
+}
@@ -0,0 +1,602 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Serialization Formats - Tests
+// =============================
+// Run with:
+//
+//   cd serialization/formats
+//   go test -v *.go
+//   go test -run TestComparisonTable -v *.go
+//   go test -run XXX -bench . -benchmem *.go
+//   go test -run XXX -fuzz FuzzDecodeCBOR -fuzztime 30s *.go
+//
+// The evolution tests are the table in compare.go, checked: the same
+// change to the writer, decoded by today's Order in each format.
+
+// 1. Round Trips
+// ==============
+
+func TestRoundTrip(t *testing.T) {
+	orders := map[string]*Order{
+		"sample": SampleOrder(),
+		"zero":   {},
+		"edges": {
+			ID:         math.MaxUint64,
+			Customer:   "Zoë ☃ \x00 \"quoted\"",
+			Items:      []Item{{Quantity: -1, WeightKg: -0.5}, {SKU: "X", Quantity: math.MaxInt32}},
+			TotalCents: math.MinInt64,
+			CreatedAt:  -1,
+			Tags:       []string{"", "x"},
+		},
+	}
+	for _, c := range Codecs {
+		for name, o := range orders {
+			t.Run(c.Name+"/"+name, func(t *testing.T) {
+				data, err := c.Marshal(o)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var back Order
+				if err := c.Unmarshal(data, &back); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(&back, o) {
+					t.Errorf("round trip:\n got %+v\nwant %+v", back, *o)
+				}
+			})
+		}
+	}
+}
+
+// Unmarshal replaces the whole value: nothing from a previous decode
+// survives into the next, which reusing a struct in a loop relies on
+func TestUnmarshalReplaces(t *testing.T) {
+	for _, c := range Codecs {
+		data, _ := c.Marshal(&Order{ID: 2})
+		o := *SampleOrder()
+		if err := c.Unmarshal(data, &o); err != nil {
+			t.Fatal(c.Name, err)
+		}
+		if !reflect.DeepEqual(o, Order{ID: 2}) {
+			t.Errorf("%s: left over from the previous value: %+v", c.Name, o)
+		}
+	}
+}
+
+// 2. Protobuf Wire Format
+// =======================
+
+func TestProtoGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  interface{ AppendProto([]byte) []byte }
+		want string
+	}{
+		{"empty message is empty", &Order{}, ""},
+		// field 1 varint: tag 0x08, 1<<20 in three varint bytes
+		{"id", &Order{ID: 1 << 20}, "08808040"},
+		// field 2 bytes: tag 0x12, length, UTF-8
+		{"customer", &Order{Customer: "Ada"}, "1203416461"},
+		// field 5 varint true; false is the default and not sent
+		{"paid", &Order{Paid: true}, "2801"},
+		// an empty repeated string is still an element
+		{"empty tag", &Order{Tags: []string{""}}, "3a00"},
+		// nested message: tag 0x1a, length 5, then the item's fields
+		{"item", &Order{Items: []Item{{SKU: "A", Quantity: 2}}}, "1a050a01411002"},
+		// negative int32 is sign-extended to ten bytes
+		{"negative", &Item{Quantity: -1}, "10ffffffffffffffffff01"},
+		// double is fixed 8 bytes little-endian; 1.5 is 0x3ff8000000000000
+		{"double", &Item{WeightKg: 1.5}, "19000000000000f83f"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(tt.msg.AppendProto(nil)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProtoMalformed(t *testing.T) {
+	for name, in := range map[string]string{
+		"truncated varint":      "0880",
+		"length past the end":   "120541",
+		"truncated double":      "1a0319000000",
+		"field number 0":        "0001",
+		"group wire type":       "0b",
+		"truncated item inside": "1a020a05",
+	} {
+		data, _ := hex.DecodeString(in)
+		var o Order
+		if err := o.UnmarshalProto(data); !errors.Is(err, errBadProto) {
+			t.Errorf("%s: got %v, want errBadProto", name, err)
+		}
+	}
+}
+
+// A field seen twice keeps the last value, and repeated fields may be
+// interleaved with others: messages can be concatenated to merge them
+func TestProtoLastWins(t *testing.T) {
+	a := (&Order{ID: 1, Customer: "a", Tags: []string{"x"}}).AppendProto(nil)
+	b := (&Order{ID: 2, Tags: []string{"y"}}).AppendProto(nil)
+	var o Order
+	if err := o.UnmarshalProto(append(a, b...)); err != nil {
+		t.Fatal(err)
+	}
+	want := Order{ID: 2, Customer: "a", Tags: []string{"x", "y"}}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("got %+v, want %+v", o, want)
+	}
+}
+
+// 3. CBOR Against RFC 8949
+// ========================
+
+func TestCBORVectors(t *testing.T) {
+	// From RFC 8949 Appendix A
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{int64(0), "00"},
+		{int64(23), "17"},
+		{int64(24), "1818"},
+		{int64(1000), "1903e8"},
+		{int64(1000000), "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{int64(-1), "20"},
+		{int64(-1000), "3903e7"},
+		{int64(math.MinInt64), "3b7fffffffffffffff"},
+		{1.1, "fb3ff199999999999a"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"", "60"},
+		{"a", "6161"},
+		{"ü", "62c3bc"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]any{}, "80"},
+		{[]any{int64(1), int64(2), int64(3)}, "83010203"},
+		{map[string]any{}, "a0"},
+		{map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}, "a26161016162820203"},
+	}
+	for _, tt := range tests {
+		got, err := AppendCBOR(nil, tt.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("encode %#v: got %x, want %s", tt.v, got, tt.want)
+		}
+		back, err := DecodeCBOR(got)
+		if err != nil {
+			t.Fatalf("decode %s: %v", tt.want, err)
+		}
+		if !reflect.DeepEqual(back, tt.v) {
+			t.Errorf("decode %s: got %#v, want %#v", tt.want, back, tt.v)
+		}
+	}
+}
+
+// Other encoders write floats at the shortest exact width
+func TestCBORShortFloats(t *testing.T) {
+	for in, want := range map[string]float64{
+		"f90000":     0,
+		"f93c00":     1,
+		"f93e00":     1.5,
+		"f97bff":     65504,
+		"f90001":     5.960464477539063e-8, // smallest float16 subnormal
+		"f9c400":     -4,
+		"fa47c35000": 100000,
+		"f97c00":     math.Inf(1),
+		"f9fc00":     math.Inf(-1),
+	} {
+		data, _ := hex.DecodeString(in)
+		got, err := DecodeCBOR(data)
+		if err != nil {
+			t.Fatal(in, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %v, want %v", in, got, want)
+		}
+	}
+	if v, _ := DecodeCBOR([]byte{0xf9, 0x7e, 0x00}); !math.IsNaN(v.(float64)) {
+		t.Errorf("f97e00: got %v, want NaN", v)
+	}
+}
+
+// Map keys are sorted by their encoding - length first - so the same
+// order always gives the same bytes, whatever Go's map order
+func TestCBORDeterministic(t *testing.T) {
+	got, _ := AppendCBOR(nil, map[string]any{"aa": int64(1), "b": int64(2), "a": int64(3)})
+	if want := "a3616103616202626161" + "01"; hex.EncodeToString(got) != want {
+		t.Errorf("got %x, want %s", got, want)
+	}
+	first, _ := SampleOrder().MarshalCBOR()
+	for range 20 {
+		again, _ := SampleOrder().MarshalCBOR()
+		if !bytes.Equal(again, first) {
+			t.Fatal("two encodings of one order differ")
+		}
+	}
+}
+
+func TestCBORMalformed(t *testing.T) {
+	for name, in := range map[string]string{
+		"empty":             "",
+		"truncated head":    "19 03",
+		"truncated string":  "63 6161",
+		"huge length":       "5b ffffffffffffffff 00",
+		"huge array":        "9b ffffffffffffffff",
+		"indefinite array":  "9f 01 ff",
+		"tag":               "c1 1a514b67b0",
+		"reserved info":     "1c",
+		"invalid UTF-8":     "62 fffe",
+		"integer map key":   "a1 01 02",
+		"duplicate map key": "a2 6161 01 6161 02",
+		"trailing bytes":    "01 02",
+		"negative overflow": "3b ffffffffffffffff",
+		"nested too deep":   strings.Repeat("81", maxDepth+2) + "00",
+		"unassigned simple": "f0",
+		"truncated float64": "fb 3ff0",
+		"missing map value": "a1 6161",
+	} {
+		data, _ := hex.DecodeString(strings.ReplaceAll(in, " ", ""))
+		if _, err := DecodeCBOR(data); !errors.Is(err, errBadCBOR) {
+			t.Errorf("%s: got %v, want errBadCBOR", name, err)
+		}
+	}
+}
+
+func TestCBOROrderWrongShape(t *testing.T) {
+	for name, v := range map[string]any{
+		"not a map":      []any{},
+		"id negative":    map[string]any{"id": int64(-1)},
+		"customer int":   map[string]any{"customer": int64(1)},
+		"items not list": map[string]any{"items": "x"},
+		"item not map":   map[string]any{"items": []any{int64(1)}},
+		"sku int":        map[string]any{"items": []any{map[string]any{"sku": int64(1)}}},
+		"tag not text":   map[string]any{"tags": []any{true}},
+	} {
+		data, _ := AppendCBOR(nil, v)
+		var o Order
+		if err := o.UnmarshalCBOR(data); !errors.Is(err, errBadCBOR) {
+			t.Errorf("%s: got %v, want errBadCBOR", name, err)
+		}
+	}
+}
+
+// 4. Schema Evolution
+// ===================
+// Each change is made on the writer's side and read by Order. The
+// expected outcomes are the table in compare.go.
+
+// Writer types for gob, which matches fields by name
+type (
+	gobOrderAdded struct {
+		ID       uint64
+		Customer string
+		Coupon   string
+	}
+	gobOrderRemoved struct {
+		ID       uint64
+		Customer string
+	}
+	gobOrderRenamed struct {
+		ID     uint64
+		Client string
+	}
+	gobItemWide struct {
+		SKU      string
+		Quantity int64
+	}
+	gobOrderWide struct {
+		ID    uint64
+		Items []gobItemWide
+	}
+	gobOrderRetyped struct {
+		ID         uint64
+		TotalCents string
+	}
+)
+
+type evolution struct {
+	change string
+	// what the writer sends: a value for gob; a map for JSON and CBOR
+	gob     any
+	generic map[string]any
+	proto   []byte
+	// outcome classifies what the reader got
+	outcome func(o Order, err error) string
+	want    map[string]string // by format
+}
+
+func evolutions() []evolution {
+	item := func(quantity int64) []byte {
+		return appendVarintField(appendStringField(nil, 1, "PEN"), 2, uint64(quantity))
+	}
+	wideItems := func(quantity int64) map[string]any {
+		return map[string]any{"id": int64(1), "items": []any{map[string]any{"sku": "PEN", "quantity": quantity}}}
+	}
+	wideOutcome := func(o Order, err error) string {
+		switch {
+		case err != nil:
+			return "error"
+		case len(o.Items) == 1 && o.Items[0].Quantity == 3:
+			return "fine"
+		}
+		return "truncated"
+	}
+	return []evolution{{
+		change:  "add a field",
+		gob:     gobOrderAdded{1, "Ada", "SAVE10"},
+		generic: map[string]any{"id": int64(1), "customer": "Ada", "coupon": "SAVE10"},
+		proto:   appendStringField(appendStringField(appendVarintField(nil, 1, 1), 2, "Ada"), 9, "SAVE10"),
+		outcome: func(o Order, err error) string {
+			if err == nil && o.Customer == "Ada" {
+				return "skipped"
+			}
+			return fmt.Sprint("got ", o, err)
+		},
+		want: map[string]string{"JSON": "skipped", "gob": "skipped", "protobuf": "skipped", "CBOR": "skipped"},
+	}, {
+		change:  "remove a field",
+		gob:     gobOrderRemoved{1, "Ada"},
+		generic: map[string]any{"id": int64(1), "customer": "Ada"},
+		proto:   appendStringField(appendVarintField(nil, 1, 1), 2, "Ada"),
+		outcome: func(o Order, err error) string {
+			if err == nil && o.Customer == "Ada" && !o.Paid {
+				return "zero"
+			}
+			return fmt.Sprint("got ", o, err)
+		},
+		want: map[string]string{"JSON": "zero", "gob": "zero", "protobuf": "zero", "CBOR": "zero"},
+	}, {
+		// In the .proto the field keeps number 2 under its new name
+		change:  "rename a field",
+		gob:     gobOrderRenamed{1, "Ada"},
+		generic: map[string]any{"id": int64(1), "client": "Ada"},
+		proto:   appendStringField(appendVarintField(nil, 1, 1), 2, "Ada"),
+		outcome: func(o Order, err error) string {
+			switch {
+			case err != nil:
+				return "error"
+			case o.Customer == "Ada":
+				return "kept"
+			}
+			return "lost"
+		},
+		want: map[string]string{"JSON": "lost", "gob": "lost", "protobuf": "kept", "CBOR": "lost"},
+	}, {
+		change:  "int32 -> int64",
+		gob:     gobOrderWide{1, []gobItemWide{{"PEN", 3}}},
+		generic: wideItems(3),
+		proto:   appendBytesField(appendVarintField(nil, 1, 1), 3, item(3)),
+		outcome: wideOutcome,
+		want:    map[string]string{"JSON": "fine", "gob": "fine", "protobuf": "fine", "CBOR": "fine"},
+	}, {
+		change:  "int64 -> int32, large",
+		gob:     gobOrderWide{1, []gobItemWide{{"PEN", 1<<32 + 3}}},
+		generic: wideItems(1<<32 + 3),
+		proto:   appendBytesField(appendVarintField(nil, 1, 1), 3, item(1<<32+3)),
+		outcome: func(o Order, err error) string {
+			// 1<<32 + 3 truncated to 32 bits is 3: right-looking, and wrong
+			if r := wideOutcome(o, err); r != "fine" {
+				return r
+			}
+			return "truncated"
+		},
+		want: map[string]string{"JSON": "error", "gob": "error", "protobuf": "truncated", "CBOR": "error"},
+	}, {
+		change:  "int -> string",
+		gob:     gobOrderRetyped{1, "43.97"},
+		generic: map[string]any{"id": int64(1), "total_cents": "43.97"},
+		proto:   appendStringField(appendVarintField(nil, 1, 1), 4, "43.97"),
+		outcome: func(o Order, err error) string {
+			switch {
+			case err != nil:
+				return "error"
+			case o.ID == 1 && o.TotalCents == 0:
+				return "dropped"
+			}
+			return fmt.Sprint("got ", o)
+		},
+		want: map[string]string{"JSON": "error", "gob": "error", "protobuf": "dropped", "CBOR": "error"},
+	}}
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	for _, ev := range evolutions() {
+		t.Run(ev.change, func(t *testing.T) {
+			written := map[string][]byte{"protobuf": ev.proto}
+			var err error
+			if written["JSON"], err = json.Marshal(ev.generic); err != nil {
+				t.Fatal(err)
+			}
+			if written["CBOR"], err = AppendCBOR(nil, ev.generic); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(ev.gob); err != nil {
+				t.Fatal(err)
+			}
+			written["gob"] = buf.Bytes()
+
+			for _, c := range Codecs {
+				var o Order
+				err := c.Unmarshal(written[c.Name], &o)
+				if got := ev.outcome(o, err); got != ev.want[c.Name] {
+					t.Errorf("%s: %s, want %s (err: %v)", c.Name, got, ev.want[c.Name], err)
+				}
+			}
+		})
+	}
+}
+
+// 5. Sizes
+// ========
+
+// gob's first message carries the type description; on a long-lived
+// stream later messages are close to protobuf's size
+func TestGobStream(t *testing.T) {
+	o := SampleOrder()
+	single, _ := marshalGob(o)
+	stream, err := gobStreamSize(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream >= len(single)/2 {
+		t.Errorf("stream message %d bytes, single %d: expected the type description to dominate", stream, len(single))
+	}
+}
+
+func TestBinaryIsSmaller(t *testing.T) {
+	o := SampleOrder()
+	size := map[string]int{}
+	for _, c := range Codecs {
+		data, _ := c.Marshal(o)
+		size[c.Name] = len(data)
+	}
+	if !(size["protobuf"] < size["CBOR"] && size["CBOR"] < size["JSON"] && size["JSON"] < size["gob"]) {
+		t.Errorf("sizes %v: expected protobuf < CBOR < JSON < one-off gob", size)
+	}
+}
+
+// TestComparisonTable prints the generated table. It benchmarks eight
+// functions, so it is skipped with -short.
+func TestComparisonTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("benchmarks every codec")
+	}
+	var buf bytes.Buffer
+	if err := Compare(&buf, SampleOrder()); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + buf.String())
+}
+
+// 6. Fuzzing
+// ==========
+
+// Anything DecodeCBOR accepts re-encodes, and the re-encoding is a
+// fixed point: decoding and encoding again gives the same bytes
+func FuzzDecodeCBOR(f *testing.F) {
+	sample, _ := SampleOrder().MarshalCBOR()
+	for _, seed := range []string{"00", "83010203", "a26161016162820203", "f93e00", "4401020304", "c1"} {
+		b, _ := hex.DecodeString(seed)
+		f.Add(b)
+	}
+	f.Add(sample)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, err := DecodeCBOR(data)
+		if err != nil {
+			return
+		}
+		first, err := AppendCBOR(nil, v)
+		if err != nil {
+			t.Fatalf("decoded %#v but cannot encode it: %v", v, err)
+		}
+		back, err := DecodeCBOR(first)
+		if err != nil {
+			t.Fatalf("cannot decode own encoding %x: %v", first, err)
+		}
+		second, _ := AppendCBOR(nil, back)
+		if !bytes.Equal(first, second) {
+			t.Errorf("not a fixed point: %x then %x", first, second)
+		}
+		var o Order
+		o.UnmarshalCBOR(data) // must not panic
+	})
+}
+
+// The same for protobuf: whatever decodes, re-encodes stably
+func FuzzUnmarshalProto(f *testing.F) {
+	f.Add(SampleOrder().AppendProto(nil))
+	f.Add([]byte{0x1a, 0x02, 0x10, 0x7f})
+	f.Add([]byte{0x4a, 0x01, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var o Order
+		if err := o.UnmarshalProto(data); err != nil {
+			return
+		}
+		first := o.AppendProto(nil)
+		var back Order
+		if err := back.UnmarshalProto(first); err != nil {
+			t.Fatalf("cannot decode own encoding %x: %v", first, err)
+		}
+		if second := back.AppendProto(nil); !bytes.Equal(first, second) {
+			t.Errorf("not a fixed point: %x then %x", first, second)
+		}
+	})
+}
+
+// 7. Benchmarks
+// =============
+
+func BenchmarkMarshal(b *testing.B) {
+	o := SampleOrder()
+	for _, c := range Codecs {
+		b.Run(c.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				c.Marshal(o)
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, c := range Codecs {
+		data, _ := c.Marshal(SampleOrder())
+		b.Run(c.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			var o Order
+			for b.Loop() {
+				c.Unmarshal(data, &o)
+			}
+		})
+	}
+}
+
+// protobuf's append style reuses the caller's buffer: no allocation
+// once it has grown
+func BenchmarkProtoAppend(b *testing.B) {
+	o := SampleOrder()
+	buf := make([]byte, 0, 256)
+	b.ReportAllocs()
+	for b.Loop() {
+		buf = o.AppendProto(buf[:0])
+	}
+}
+
+// 8. Examples
+// ===========
+
+func ExampleCompare() {
+	o := SampleOrder()
+	for _, c := range Codecs {
+		if c.Name == "gob" {
+			// gob's size depends on the type ids this process has
+			// already assigned, so it is left out of the output
+			continue
+		}
+		data, _ := c.Marshal(o)
+		fmt.Printf("%-8s %3d bytes\n", c.Name, len(data))
+	}
+	// Output:
+	// JSON     280 bytes
+	// protobuf 115 bytes
+	// CBOR     225 bytes
+}
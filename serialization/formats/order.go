@@ -0,0 +1,74 @@
+package formats
+
+import "time"
+
+// One Struct, Four Formats
+// ========================
+// The same Order encoded as JSON, gob, Protocol Buffers and CBOR. They
+// differ in what travels with the data and in how a reader finds each
+// field:
+//
+//	            schema     on the wire                fields found by
+//	JSON        none       text: names and values     name
+//	gob         Go types   type descriptions once     name
+//	                       per stream, then values
+//	protobuf    .proto     field numbers and values   number
+//	CBOR        optional   binary JSON: typed items,  map key - a name,
+//	            (CDDL)     names included             or a small integer
+//
+// MessagePack has the same model as CBOR - a type byte, a length, the
+// item - with slightly different byte assignments and no standards
+// body; the sizes and the evolution behaviour are the same. CBOR is the
+// IETF's (RFC 8949), with rules for deterministic encoding, and is what
+// WebAuthn and COSE use.
+//
+// Which to pick:
+//
+//	JSON       anything a person or another language reads; the default
+//	gob        Go to Go only, on a long-lived stream; never for storage
+//	           others will read
+//	protobuf   services with a shared schema, many languages, and
+//	           messages that evolve over years
+//	CBOR       JSON's model in binary, where bytes or parse time matter
+//	           and there is no schema to share - IoT, tokens, caches
+//
+// Real code uses google.golang.org/protobuf and github.com/fxamacker/cbor.
+// This tree has no go.mod for third-party modules, so protobuf.go and
+// cbor.go hold just enough of each format for the lesson, in the same
+// shape. compare.go measures them.
+
+// Order is the message every format encodes
+type Order struct {
+	ID         uint64   `json:"id"`
+	Customer   string   `json:"customer"`
+	Items      []Item   `json:"items"`
+	TotalCents int64    `json:"total_cents"`
+	Paid       bool     `json:"paid"`
+	CreatedAt  int64    `json:"created_at"` // Unix seconds
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// Item is one line of an Order
+type Item struct {
+	SKU      string  `json:"sku"`
+	Quantity int32   `json:"quantity"`
+	WeightKg float64 `json:"weight_kg"`
+}
+
+// SampleOrder returns the order the lesson measures: typical of an API
+// payload, with a few nested items and short strings
+func SampleOrder() *Order {
+	return &Order{
+		ID:       1_048_576,
+		Customer: "Ada Lovelace",
+		Items: []Item{
+			{SKU: "BOOK-0042", Quantity: 1, WeightKg: 0.65},
+			{SKU: "PEN-0007", Quantity: 3, WeightKg: 0.02},
+			{SKU: "INK-0100", Quantity: 2, WeightKg: 0.12},
+		},
+		TotalCents: 4_397,
+		Paid:       true,
+		CreatedAt:  time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC).Unix(),
+		Tags:       []string{"gift", "priority"},
+	}
+}
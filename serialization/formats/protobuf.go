@@ -0,0 +1,204 @@
+package formats
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Protocol Buffers
+// ================
+// Each field is a tag - field_number<<3 | wire_type, as a varint - and
+// a value. The names in order.proto never reach the wire:
+//
+//	wire type 0  varint      uint64, int64, int32, bool, enums
+//	wire type 1  8 bytes     double, fixed64
+//	wire type 2  length +    string, bytes, nested messages, and
+//	             bytes       repeated fields (one tag per element)
+//	wire type 5  4 bytes     float, fixed32
+//
+// Zero values are not sent, so an empty message is zero bytes. A
+// reader skips numbers it does not know, which is what lets old and
+// new programs exchange messages. The rules that keep that working:
+//
+//	never reuse or renumber a field     reserve deleted numbers
+//	change a type only within its       int32 <-> int64 <-> uint64 are
+//	wire type                           all varints; string -> int is not
+//	rename freely                       the name is not sent
+//
+// Negative int32 and int64 values take ten bytes, sign-extended to 64
+// bits. A field that is often negative should be sint64, which
+// zig-zag encodes first.
+
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+var errBadProto = errors.New("formats: malformed protobuf message")
+
+func appendTag(b []byte, num, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(wireType))
+}
+
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+// appendBytesField writes a length-delimited field even when data is
+// empty, as repeated fields need
+func appendBytesField[T string | []byte](b []byte, num int, data T) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendStringField(b []byte, num int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytesField(b, num, s)
+}
+
+func appendDoubleField(b []byte, num int, f float64) []byte {
+	if math.Float64bits(f) == 0 { // -0.0 is not the default, and is sent
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(appendTag(b, num, wireI64), math.Float64bits(f))
+}
+
+// protoField is one decoded field. For wireBytes and the fixed-size
+// types data holds the payload; for wireVarint, varint is the value.
+type protoField struct {
+	num      int
+	wireType int
+	data     []byte
+	varint   uint64
+}
+
+// protoFields calls fn for each field in b. fn ignores the numbers it
+// does not know, and numbers it knows with an unexpected wire type, as
+// protobuf-go does: both are fields from some other version.
+func protoFields(b []byte, fn func(protoField) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag>>3 == 0 || tag>>3 > math.MaxInt32 {
+			return errBadProto
+		}
+		b = b[n:]
+		f := protoField{num: int(tag >> 3), wireType: int(tag & 7)}
+		switch f.wireType {
+		case wireVarint:
+			if f.varint, n = binary.Uvarint(b); n <= 0 {
+				return errBadProto
+			}
+			b = b[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errBadProto
+			}
+			f.data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case wireI64, wireI32:
+			size := 8
+			if f.wireType == wireI32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errBadProto
+			}
+			f.data = b[:size]
+			b = b[size:]
+		default:
+			return errBadProto // groups (3, 4) are long deprecated
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendProto appends o's protobuf encoding to b
+func (o *Order) AppendProto(b []byte) []byte {
+	b = appendVarintField(b, 1, o.ID)
+	b = appendStringField(b, 2, o.Customer)
+	var item []byte
+	for i := range o.Items {
+		// A nested message is length-delimited: encode it, then write
+		// its length. protobuf-go computes sizes first to avoid the copy.
+		item = o.Items[i].AppendProto(item[:0])
+		b = appendBytesField(b, 3, item)
+	}
+	b = appendVarintField(b, 4, uint64(o.TotalCents))
+	if o.Paid {
+		b = appendVarintField(b, 5, 1)
+	}
+	b = appendVarintField(b, 6, uint64(o.CreatedAt))
+	for _, tag := range o.Tags {
+		// Repeated strings are sent even when empty: the element
+		// count is data
+		b = appendBytesField(b, 7, tag)
+	}
+	return b
+}
+
+// UnmarshalProto replaces o with the message decoded from b
+func (o *Order) UnmarshalProto(b []byte) error {
+	*o = Order{}
+	return protoFields(b, func(f protoField) error {
+		switch {
+		case f.num == 1 && f.wireType == wireVarint:
+			o.ID = f.varint
+		case f.num == 2 && f.wireType == wireBytes:
+			o.Customer = string(f.data)
+		case f.num == 3 && f.wireType == wireBytes:
+			var item Item
+			if err := item.UnmarshalProto(f.data); err != nil {
+				return err
+			}
+			o.Items = append(o.Items, item)
+		case f.num == 4 && f.wireType == wireVarint:
+			o.TotalCents = int64(f.varint)
+		case f.num == 5 && f.wireType == wireVarint:
+			o.Paid = f.varint != 0
+		case f.num == 6 && f.wireType == wireVarint:
+			o.CreatedAt = int64(f.varint)
+		case f.num == 7 && f.wireType == wireBytes:
+			o.Tags = append(o.Tags, string(f.data))
+		}
+		return nil
+	})
+}
+
+// AppendProto appends the item's protobuf encoding to b
+func (it *Item) AppendProto(b []byte) []byte {
+	b = appendStringField(b, 1, it.SKU)
+	// int32 is sign-extended: a negative quantity costs ten bytes
+	b = appendVarintField(b, 2, uint64(int64(it.Quantity)))
+	return appendDoubleField(b, 3, it.WeightKg)
+}
+
+// UnmarshalProto replaces it with the message decoded from b
+func (it *Item) UnmarshalProto(b []byte) error {
+	*it = Item{}
+	return protoFields(b, func(f protoField) error {
+		switch {
+		case f.num == 1 && f.wireType == wireBytes:
+			it.SKU = string(f.data)
+		case f.num == 2 && f.wireType == wireVarint:
+			// Truncated to 32 bits, as protobuf does for an int64
+			// value read by an int32 field
+			it.Quantity = int32(f.varint)
+		case f.num == 3 && f.wireType == wireI64:
+			it.WeightKg = math.Float64frombits(binary.LittleEndian.Uint64(f.data))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// Go Serialization - gob and encoding/binary
+// ==========================================
+// This file demonstrates Go-native binary encodings, how they handle
+// schema changes, and how they compare with JSON in size and speed
+
+// Types used throughout the lesson
+// ================================
+type Person struct {
+	Name string
+	Age  int
+}
+
+type Employee struct {
+	Person
+	ID     int
+	Salary float64
+	Skills []string
+}
+
+// EmployeeV1 and EmployeeV2 model two versions of the same message
+type EmployeeV1 struct {
+	ID   int
+	Name string
+	Dept string
+}
+
+type EmployeeV2 struct {
+	ID    int
+	Name  string
+	Email string // new field
+	// Dept removed
+}
+
+// FixedRecord has only fixed-size fields so encoding/binary can handle it
+type FixedRecord struct {
+	ID     uint32
+	Age    uint8
+	_      [3]byte // explicit padding keeps the wire size obvious
+	Salary float64
+}
+
+func main() {
+	fmt.Println("=== Go Serialization: gob and binary ===")
+
+	// 1. gob round trip
+	gobRoundTrip()
+
+	// 2. gob streams and type information
+	gobStreams()
+
+	// 3. Field evolution across versions
+	fieldEvolution()
+
+	// 4. encoding/binary for fixed-size records
+	binaryRecords()
+
+	// 5. Size comparison
+	sizeComparison()
+
+	// 6. Speed comparison
+	speedComparison()
+}
+
+// 1. gob Round Trip
+// =================
+func gobRoundTrip() {
+	fmt.Println("\n1. GOB ROUND TRIP:")
+
+	emp := Employee{
+		Person: Person{Name: "Alice", Age: 30},
+		ID:     7,
+		Salary: 85000,
+		Skills: []string{"go", "sql"},
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(emp); err != nil {
+		fmt.Printf("   Encode error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Encoded %d bytes\n", buf.Len())
+
+	var decoded Employee
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		fmt.Printf("   Decode error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Decoded: %+v\n", decoded)
+
+	// gob only encodes exported fields, and zero values are omitted
+	// entirely - a decoded zero field is indistinguishable from a missing one
+}
+
+// 2. gob Streams and Type Information
+// ===================================
+func gobStreams() {
+	fmt.Println("\n2. GOB STREAMS:")
+
+	// A gob stream sends each type's description once, then only values.
+	// Reusing one Encoder is much cheaper than a new Encoder per message.
+	var stream bytes.Buffer
+	enc := gob.NewEncoder(&stream)
+
+	sizes := []int{}
+	for i := 0; i < 3; i++ {
+		before := stream.Len()
+		enc.Encode(Person{Name: fmt.Sprintf("P%d", i), Age: 20 + i})
+		sizes = append(sizes, stream.Len()-before)
+	}
+	fmt.Printf("   Message sizes on one stream: %v bytes\n", sizes)
+	fmt.Println("   (the first message carries the type definition)")
+
+	dec := gob.NewDecoder(&stream)
+	for i := 0; i < 3; i++ {
+		var p Person
+		dec.Decode(&p)
+		fmt.Printf("   Read back: %+v\n", p)
+	}
+
+	// Interface values need their concrete types registered up front
+	gob.Register(Person{})
+	var withIface bytes.Buffer
+	var value interface{} = Person{Name: "Bob", Age: 41}
+	if err := gob.NewEncoder(&withIface).Encode(&value); err != nil {
+		fmt.Printf("   Encode error: %v\n", err)
+		return
+	}
+	var out interface{}
+	gob.NewDecoder(&withIface).Decode(&out)
+	fmt.Printf("   Interface round trip: %T %+v\n", out, out)
+}
+
+// 3. Field Evolution Across Versions
+// ==================================
+func fieldEvolution() {
+	fmt.Println("\n3. FIELD EVOLUTION:")
+
+	// gob matches fields by name, not position
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(EmployeeV1{ID: 1, Name: "Carol", Dept: "Eng"})
+
+	var v2 EmployeeV2
+	err := gob.NewDecoder(&buf).Decode(&v2)
+	fmt.Printf("   V1 -> V2: %+v (err=%v)\n", v2, err)
+	fmt.Println("   Removed field Dept is silently dropped, new field Email is zero")
+
+	buf.Reset()
+	gob.NewEncoder(&buf).Encode(EmployeeV2{ID: 2, Name: "Dan", Email: "dan@example.com"})
+	var v1 EmployeeV1
+	err = gob.NewDecoder(&buf).Decode(&v1)
+	fmt.Printf("   V2 -> V1: %+v (err=%v)\n", v1, err)
+
+	// Changing a field's type is NOT compatible
+	type EmployeeBadID struct {
+		ID   string
+		Name string
+	}
+	buf.Reset()
+	gob.NewEncoder(&buf).Encode(EmployeeV1{ID: 3, Name: "Eve"})
+	var bad EmployeeBadID
+	err = gob.NewDecoder(&buf).Decode(&bad)
+	fmt.Printf("   int ID -> string ID: err=%v\n", err)
+
+	// JSON behaves the same way for added/removed fields
+	data, _ := json.Marshal(EmployeeV1{ID: 4, Name: "Frank", Dept: "Ops"})
+	var fromJSON EmployeeV2
+	json.Unmarshal(data, &fromJSON)
+	fmt.Printf("   JSON V1 -> V2: %+v\n", fromJSON)
+
+	// encoding/binary has no field names at all: layout IS the schema
+	fmt.Println("   encoding/binary has no schema - any layout change breaks old data")
+}
+
+// 4. encoding/binary for Fixed-Size Records
+// =========================================
+func binaryRecords() {
+	fmt.Println("\n4. ENCODING/BINARY:")
+
+	rec := FixedRecord{ID: 42, Age: 30, Salary: 85000.5}
+	fmt.Printf("   binary.Size(FixedRecord): %d bytes\n", binary.Size(rec))
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, rec); err != nil {
+		fmt.Printf("   Write error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Encoded bytes: % x\n", buf.Bytes())
+
+	var decoded FixedRecord
+	binary.Read(&buf, binary.LittleEndian, &decoded)
+	fmt.Printf("   Decoded: ID=%d Age=%d Salary=%.1f\n", decoded.ID, decoded.Age, decoded.Salary)
+
+	// Variable-length data (strings, slices) is not supported directly;
+	// the usual approach is a length prefix followed by the bytes
+	name := "Alice"
+	var out []byte
+	out = binary.AppendUvarint(out, uint64(len(name)))
+	out = append(out, name...)
+	fmt.Printf("   Length-prefixed %q: % x\n", name, out)
+
+	n, size := binary.Uvarint(out)
+	fmt.Printf("   Read back: %q\n", string(out[size:size+int(n)]))
+
+	// Types with int, string or slices are rejected
+	err := binary.Write(&bytes.Buffer{}, binary.LittleEndian, Person{Name: "x"})
+	fmt.Printf("   binary.Write(Person): %v\n", err)
+}
+
+// 5. Size Comparison
+// ==================
+func sizeComparison() {
+	fmt.Println("\n5. SIZE COMPARISON:")
+
+	emp := sampleEmployee()
+
+	jsonData, _ := json.Marshal(emp)
+
+	var gobSingle bytes.Buffer
+	gob.NewEncoder(&gobSingle).Encode(emp)
+
+	// On a long-lived stream the type description is amortized away
+	var gobStream bytes.Buffer
+	enc := gob.NewEncoder(&gobStream)
+	enc.Encode(emp)
+	first := gobStream.Len()
+	enc.Encode(emp)
+	steady := gobStream.Len() - first
+
+	rec := FixedRecord{ID: 7, Age: 30, Salary: 85000}
+
+	fmt.Printf("   %-28s %5d bytes\n", "JSON", len(jsonData))
+	fmt.Printf("   %-28s %5d bytes\n", "gob (single message)", gobSingle.Len())
+	fmt.Printf("   %-28s %5d bytes\n", "gob (steady-state on stream)", steady)
+	fmt.Printf("   %-28s %5d bytes\n", "binary (fixed fields only)", binary.Size(rec))
+}
+
+// 6. Speed Comparison
+// ===================
+func speedComparison() {
+	fmt.Println("\n6. SPEED COMPARISON:")
+
+	emp := sampleEmployee()
+	jsonData, _ := json.Marshal(emp)
+	rec := FixedRecord{ID: 7, Age: 30, Salary: 85000}
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"json.Marshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				json.Marshal(emp)
+			}
+		}},
+		{"json.Unmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var out Employee
+				json.Unmarshal(jsonData, &out)
+			}
+		}},
+		{"gob encode (new encoder)", func(b *testing.B) {
+			b.ReportAllocs()
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				gob.NewEncoder(&buf).Encode(emp)
+			}
+		}},
+		{"gob encode (reused stream)", func(b *testing.B) {
+			b.ReportAllocs()
+			var buf bytes.Buffer
+			enc := gob.NewEncoder(&buf)
+			for i := 0; i < b.N; i++ {
+				enc.Encode(emp)
+				if buf.Len() > 1<<20 {
+					buf.Reset() // keep memory bounded; type info already sent
+				}
+			}
+		}},
+		{"binary.Write", func(b *testing.B) {
+			b.ReportAllocs()
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				binary.Write(&buf, binary.LittleEndian, rec)
+			}
+		}},
+		{"binary.LittleEndian.Put*", func(b *testing.B) {
+			b.ReportAllocs()
+			out := make([]byte, 16)
+			for i := 0; i < b.N; i++ {
+				binary.LittleEndian.PutUint32(out[0:], rec.ID)
+				out[4] = rec.Age
+				binary.LittleEndian.PutUint64(out[8:], math.Float64bits(rec.Salary))
+			}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(bm.fn)
+		nsPerOp := float64(r.T.Nanoseconds()) / float64(r.N)
+		fmt.Printf("   %-28s %10.1f ns/op %6d B/op %4d allocs/op\n",
+			bm.name, nsPerOp, r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+
+	fmt.Println("   gob is only fast on long-lived streams; a fresh encoder per")
+	fmt.Println("   message pays for the type description every time")
+}
+
+// Helper functions
+// ================
+func sampleEmployee() Employee {
+	return Employee{
+		Person: Person{Name: "Alice Johnson", Age: 30},
+		ID:     7,
+		Salary: 85000,
+		Skills: []string{"go", "sql", "kubernetes"},
+	}
+}
@@ -0,0 +1,130 @@
+package jsonappend
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// The Append Functions
+// ====================
+// Each one takes a buffer, appends one JSON value and returns the
+// buffer, like strconv.AppendInt. None of them allocates unless b runs
+// out of capacity. The output is the bytes encoding/json writes for the
+// same Go value, which the tests check value by value.
+
+const hex = "0123456789abcdef"
+
+// safe reports the ASCII bytes encoding/json copies into a string as
+// they are: printable, and not one of " \ < > &. The last three are
+// escaped so JSON can be embedded in HTML <script> tags
+var safe = func() (s [utf8.RuneSelf]bool) {
+	for c := ' '; c < utf8.RuneSelf; c++ {
+		s[c] = true
+	}
+	for _, c := range `"\<>&` {
+		s[c] = false
+	}
+	return s
+}()
+
+// AppendString appends s as a JSON string. It escapes exactly what
+// encoding/json escapes, HTML characters included, and U+2028 and
+// U+2029, which break JavaScript. Each invalid UTF-8 byte becomes U+FFFD,
+// written as the character itself since encoding/json moved onto the
+// json/v2 engine; older releases wrote the escape \ufffd, which decodes
+// to the same string.
+// Runs of safe bytes are copied at once, so a string with nothing to
+// escape costs one append
+func AppendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if safe[c] {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, s[start:i]...)
+			b = utf8.AppendRune(b, utf8.RuneError)
+		case r == '\u2028' || r == '\u2029':
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// ErrUnsupportedFloat is returned for NaN and the infinities, which JSON
+// cannot represent
+var ErrUnsupportedFloat = errors.New("jsonappend: NaN and Inf have no JSON form")
+
+// AppendFloat appends f as encoding/json formats a float64: the
+// shortest decimal that reads back as f, switching to an exponent below
+// 1e-6 and from 1e21, the cutoffs JavaScript uses
+func AppendFloat(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return b, ErrUnsupportedFloat
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	b = strconv.AppendFloat(b, f, format, -1, 64)
+	if format == 'e' {
+		// 1e-07 -> 1e-7
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+// AppendStrings appends a JSON array of strings. A nil slice is null
+// and an empty one [], as encoding/json has it
+func AppendStrings(b []byte, s []string) []byte {
+	if s == nil {
+		return append(b, "null"...)
+	}
+	b = append(b, '[')
+	for i, v := range s {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = AppendString(b, v)
+	}
+	return append(b, ']')
+}
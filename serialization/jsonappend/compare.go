@@ -0,0 +1,104 @@
+package jsonappend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// Comparing with encoding/json
+// ============================
+// Compare benchmarks four ways of turning an Event into bytes and
+// writes a Markdown table. The first two are encoding/json, the last
+// two this package; each pair is a one-shot call and a long-lived
+// writer. Numbers are for this machine.
+
+// Way is one way of encoding an Event, in a form testing.Benchmark
+// can run repeatedly
+type Way struct {
+	Name string
+	// New returns the function to benchmark, with whatever state it
+	// keeps between calls (a buffer, an encoder) set up already
+	New func() func(*Event) error
+}
+
+// Ways lists the rows of the table
+var Ways = []Way{
+	{"json.Marshal", func() func(*Event) error {
+		return func(e *Event) error {
+			_, err := json.Marshal(e)
+			return err
+		}
+	}},
+	{"json.Encoder", func() func(*Event) error {
+		enc := json.NewEncoder(io.Discard)
+		return func(e *Event) error { return enc.Encode(e) }
+	}},
+	{"AppendJSON", func() func(*Event) error {
+		var buf []byte
+		return func(e *Event) error {
+			var err error
+			buf, err = e.AppendJSON(buf[:0])
+			return err
+		}
+	}},
+	{"Encoder", func() func(*Event) error {
+		enc := NewEncoder(io.Discard)
+		return enc.Encode
+	}},
+}
+
+// Result is one row of the table
+type Result struct {
+	Name   string
+	Encode testing.BenchmarkResult
+}
+
+// Measure checks that this package's output matches json.Marshal for e,
+// then benchmarks every Way
+func Measure(e *Event) ([]Result, error) {
+	want, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	got, err := e.AppendJSON(nil)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(got, want) {
+		return nil, fmt.Errorf("AppendJSON wrote\n%s\njson.Marshal wrote\n%s", got, want)
+	}
+
+	var results []Result
+	for _, w := range Ways {
+		encode := w.New()
+		r := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				encode(e)
+			}
+		})
+		results = append(results, Result{w.Name, r})
+	}
+	return results, nil
+}
+
+// Compare measures e and writes the table
+func Compare(w io.Writer, e *Event) error {
+	results, err := Measure(e)
+	if err != nil {
+		return err
+	}
+	base := float64(results[0].Encode.NsPerOp())
+
+	fmt.Fprintln(w, "| encoder | ns/op | vs json.Marshal | B/op | allocs/op |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|")
+	for _, r := range results {
+		fmt.Fprintf(w, "| %s | %d | %.2fx | %d | %d |\n",
+			r.Name, r.Encode.NsPerOp(), float64(r.Encode.NsPerOp())/base,
+			r.Encode.AllocedBytesPerOp(), r.Encode.AllocsPerOp())
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package jsonappend
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// jsonappend - Zero-Allocation JSON for One Struct
+// ================================================
+// json.Marshal handles any value through reflection. For one struct on a
+// hot path - an access log line per request - a hand-written encoder
+// does the same job several times faster and with no allocations at
+// all. What encoding/json pays for an Event:
+//
+//	the result          json.Marshal returns a new []byte per call; the
+//	                    caller cannot hand one in. json.Encoder reuses
+//	                    its buffer but only writes to an io.Writer
+//	reflection          every field is a reflect.Value walk through an
+//	                    encoder looked up for its type
+//	Marshalers          before encoding/json ran on the json/v2 engine,
+//	                    time.Time went through MarshalJSON: a []byte of
+//	                    its own, re-scanned for validity, per call
+//
+// The append style removes all three. The caller owns one buffer and
+// passes it back in each time:
+//
+//	buf, err = ev.AppendJSON(buf[:0])
+//
+// Once buf has grown to fit the largest event, nothing allocates: the
+// numbers go through strconv.AppendInt and AppendFloat, the time through
+// time.Time.AppendText, strings are escaped straight into buf. The
+// tests prove it with testing.AllocsPerRun, and prove the output is the
+// bytes json.Marshal produces. compare.go measures the difference.
+//
+// The cost is the code: a field added to Event is silently missing from
+// the output until AppendJSON learns it. The tests compare against
+// json.Marshal over many events to catch that. Write this for the one
+// struct a profile points at, not as a habit.
+
+// Event is one access-log entry
+type Event struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	LatencyMs float64   `json:"latency_ms"`
+	Cached    bool      `json:"cached"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Tags      []string  `json:"tags"`
+}
+
+// SampleEvent returns the event the benchmarks encode
+func SampleEvent() *Event {
+	return &Event{
+		Time:      time.Date(2024, 3, 5, 12, 30, 15, 123456789, time.UTC),
+		Level:     "info",
+		Method:    "GET",
+		Path:      "/api/orders/1048576?expand=items",
+		Status:    200,
+		Bytes:     4_397,
+		LatencyMs: 12.75,
+		Cached:    false,
+		UserAgent: "curl/8.5.0",
+		Tags:      []string{"api", "orders"},
+	}
+}
+
+// AppendJSON appends e as a JSON object, field for field what
+// json.Marshal writes. It fails only where json.Marshal does: a time
+// outside years 0-9999, or a LatencyMs that is NaN or infinite. On
+// error b is returned as it was
+func (e *Event) AppendJSON(b []byte) ([]byte, error) {
+	n := len(b)
+	t, err := e.Time.AppendText(append(b, `{"time":"`...))
+	if err != nil {
+		return b[:n], fmt.Errorf("jsonappend: time: %w", err)
+	}
+	b = t
+	b = append(b, `","level":`...)
+	b = AppendString(b, e.Level)
+	b = append(b, `,"method":`...)
+	b = AppendString(b, e.Method)
+	b = append(b, `,"path":`...)
+	b = AppendString(b, e.Path)
+	b = append(b, `,"status":`...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, `,"bytes":`...)
+	b = strconv.AppendInt(b, e.Bytes, 10)
+	b = append(b, `,"latency_ms":`...)
+	if b, err = AppendFloat(b, e.LatencyMs); err != nil {
+		return b[:n], fmt.Errorf("jsonappend: latency_ms: %w", err)
+	}
+	b = append(b, `,"cached":`...)
+	b = strconv.AppendBool(b, e.Cached)
+	if e.UserAgent != "" {
+		b = append(b, `,"user_agent":`...)
+		b = AppendString(b, e.UserAgent)
+	}
+	b = append(b, `,"tags":`...)
+	b = AppendStrings(b, e.Tags)
+	return append(b, '}'), nil
+}
+
+// Encoder writes Events to w one per line, like json.Encoder, reusing
+// one buffer for all of them
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder returns an Encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, buf: make([]byte, 0, 512)}
+}
+
+// Encode writes e and a newline in one Write call
+func (enc *Encoder) Encode(e *Event) error {
+	b, err := e.AppendJSON(enc.buf[:0])
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	enc.buf = b
+	_, err = enc.w.Write(b)
+	return err
+}
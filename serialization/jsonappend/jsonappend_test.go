@@ -0,0 +1,281 @@
+package jsonappend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jsonappend - Tests
+// ==================
+// Run with:
+//
+//   cd serialization/jsonappend
+//   go test -v *.go
+//   go test -run TestComparisonTable -v *.go
+//   go test -run XXX -bench . -benchmem *.go
+//   go test -run XXX -fuzz FuzzAppendString -fuzztime 30s *.go
+//
+// encoding/json is the oracle: every test compares bytes with what
+// json.Marshal writes for the same value.
+
+func marshal(t testing.TB, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// 1. Same Bytes as encoding/json
+// ==============================
+
+func TestAppendString(t *testing.T) {
+	tests := []string{
+		"", "plain", `"quoted"`, `back\slash`, "tab\tnew\nline\rcr",
+		"\b\f\x00\x01\x1f\x7f", "<script>&amp;</script>", "Zoë ☃ 日本",
+		"\u2028 and \u2029", "bad \xff utf-8 \xc3", "emoji 🙂",
+	}
+	for _, s := range tests {
+		if got, want := string(AppendString(nil, s)), marshal(t, s); got != want {
+			t.Errorf("AppendString(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestAppendFloat(t *testing.T) {
+	tests := []float64{
+		0, math.Copysign(0, -1), 1, -1, 12.75, 0.1, 1e-6, 9.99e-7, 1e-7, 1e20, 1e21, 123456789e13,
+		math.MaxFloat64, math.SmallestNonzeroFloat64, math.Pi, -2.5e-10,
+	}
+	for _, f := range tests {
+		got, err := AppendFloat(nil, f)
+		if err != nil {
+			t.Fatalf("AppendFloat(%g): %v", f, err)
+		}
+		if want := marshal(t, f); string(got) != want {
+			t.Errorf("AppendFloat(%g) = %s, want %s", f, got, want)
+		}
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if b, err := AppendFloat([]byte("x"), f); !errors.Is(err, ErrUnsupportedFloat) || string(b) != "x" {
+			t.Errorf("AppendFloat(%g) = %q, %v; want ErrUnsupportedFloat", f, b, err)
+		}
+	}
+}
+
+func TestAppendStrings(t *testing.T) {
+	for _, s := range [][]string{nil, {}, {""}, {"a", "b<"}} {
+		if got, want := string(AppendStrings(nil, s)), marshal(t, s); got != want {
+			t.Errorf("AppendStrings(%#v) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func events() map[string]*Event {
+	return map[string]*Event{
+		"sample": SampleEvent(),
+		"zero":   {},
+		"edges": {
+			Time:      time.Date(9999, 12, 31, 23, 59, 59, 1, time.FixedZone("", -(23*3600+59*60))),
+			Level:     "warn",
+			Path:      "/search?q=<a>&b=\"c\"\n",
+			Status:    -1,
+			Bytes:     math.MinInt64,
+			LatencyMs: 1e-9,
+			Cached:    true,
+			Tags:      []string{},
+		},
+		"no agent": {Time: time.Unix(0, 0), UserAgent: "", Tags: []string{"\u2028"}},
+	}
+}
+
+func TestAppendJSONMatchesMarshal(t *testing.T) {
+	for name, e := range events() {
+		got, err := e.AppendJSON(nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := marshal(t, e); string(got) != want {
+			t.Errorf("%s:\n got %s\nwant %s", name, got, want)
+		}
+		var back Event
+		if err := json.Unmarshal(got, &back); err != nil {
+			t.Errorf("%s: does not decode: %v", name, err)
+		}
+	}
+}
+
+// AppendJSON fails where json.Marshal does, and leaves b as it was
+func TestAppendJSONErrors(t *testing.T) {
+	tests := map[string]*Event{
+		"year":    {Time: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"latency": {LatencyMs: math.NaN()},
+	}
+	for name, e := range tests {
+		if _, err := json.Marshal(e); err == nil {
+			t.Fatalf("%s: json.Marshal succeeded", name)
+		}
+		b, err := e.AppendJSON([]byte("prefix"))
+		if err == nil || string(b) != "prefix" {
+			t.Errorf("%s: %q, %v; want an error and b unchanged", name, b, err)
+		}
+	}
+}
+
+// Every field of Event must be written: a field added to the struct
+// shows up in json.Marshal's output and nowhere else
+func TestEveryField(t *testing.T) {
+	var m map[string]any
+	if err := json.Unmarshal([]byte(marshal(t, SampleEvent())), &m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := SampleEvent().AppendJSON(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key := range m {
+		if !bytes.Contains(got, []byte(`"`+key+`":`)) {
+			t.Errorf("AppendJSON does not write %q", key)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var got, want bytes.Buffer
+	enc, jenc := NewEncoder(&got), json.NewEncoder(&want)
+	for _, e := range []*Event{SampleEvent(), {}, SampleEvent()} {
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+		jenc.Encode(e)
+	}
+	if got.String() != want.String() {
+		t.Errorf("got\n%s\nwant\n%s", got.String(), want.String())
+	}
+}
+
+// 2. Zero Allocations
+// ===================
+
+func TestZeroAllocs(t *testing.T) {
+	e := SampleEvent()
+	buf := make([]byte, 0, 512)
+	if n := testing.AllocsPerRun(100, func() { buf, _ = e.AppendJSON(buf[:0]) }); n != 0 {
+		t.Errorf("AppendJSON: %.0f allocs per run, want 0", n)
+	}
+
+	enc := NewEncoder(io.Discard)
+	if n := testing.AllocsPerRun(100, func() { enc.Encode(e) }); n != 0 {
+		t.Errorf("Encoder.Encode: %.0f allocs per run, want 0", n)
+	}
+
+	// Escaping does not allocate either
+	s := strings.Repeat("<\"\u2028\xff\n>", 20)
+	if n := testing.AllocsPerRun(100, func() { buf = AppendString(buf[:0], s) }); n != 0 {
+		t.Errorf("AppendString: %.0f allocs per run, want 0", n)
+	}
+}
+
+// A buffer too small grows once, then stays
+func TestBufferGrowsOnce(t *testing.T) {
+	e := SampleEvent()
+	var buf []byte
+	buf, _ = e.AppendJSON(buf[:0])
+	if n := testing.AllocsPerRun(100, func() { buf, _ = e.AppendJSON(buf[:0]) }); n != 0 {
+		t.Errorf("%.0f allocs per run after the first, want 0", n)
+	}
+}
+
+// json.Marshal allocates every time; the lesson's table is about this
+func TestMarshalAllocates(t *testing.T) {
+	e := SampleEvent()
+	if n := testing.AllocsPerRun(100, func() { json.Marshal(e) }); n == 0 {
+		t.Error("json.Marshal did not allocate; the package doc needs updating")
+	}
+}
+
+// TestComparisonTable prints the generated table. It benchmarks four
+// encoders, so it is skipped with -short.
+func TestComparisonTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("benchmarks every encoder")
+	}
+	var buf bytes.Buffer
+	if err := Compare(&buf, SampleEvent()); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + buf.String())
+}
+
+// 3. Fuzzing
+// ==========
+
+func FuzzAppendString(f *testing.F) {
+	for _, s := range []string{"", "a\"b", "<>&", "\u2028", "\xff\xfe", "\x00\x1f\x7f"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if got, want := string(AppendString(nil, s)), marshal(t, s); got != want {
+			t.Errorf("AppendString(%q) = %s, want %s", s, got, want)
+		}
+	})
+}
+
+func FuzzAppendFloat(f *testing.F) {
+	for _, v := range []float64{0, 1e-7, 1e21, 12.75} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v float64) {
+		got, err := AppendFloat(nil, v)
+		want, jerr := json.Marshal(v)
+		if (err != nil) != (jerr != nil) || string(got) != string(want) && err == nil {
+			t.Errorf("AppendFloat(%g) = %s, %v; json.Marshal %s, %v", v, got, err, want, jerr)
+		}
+	})
+}
+
+// 4. Benchmarks
+// =============
+
+func BenchmarkEncode(b *testing.B) {
+	e := SampleEvent()
+	for _, w := range Ways {
+		encode := w.New()
+		b.Run(w.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				encode(e)
+			}
+		})
+	}
+}
+
+// 5. Examples
+// ===========
+
+func ExampleEvent_AppendJSON() {
+	e := &Event{
+		Time:   time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+		Level:  "info",
+		Method: "GET",
+		Path:   "/?q=<go>",
+		Status: 200,
+	}
+	buf := make([]byte, 0, 256)
+	buf, err := e.AppendJSON(buf)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(string(buf))
+	// Output:
+	// {"time":"2024-03-05T12:00:00Z","level":"info","method":"GET","path":"/?q=\u003cgo\u003e","status":200,"bytes":0,"latency_ms":0,"cached":false,"tags":null}
+}
@@ -0,0 +1,84 @@
+package textenc
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Text Encodings - base64, hex and URLs
+// =====================================
+// Binary data travels through text: keys in config files, tokens in
+// URLs, hashes in logs, attachments in email. Every encoding trades
+// size for the set of characters it is allowed to use.
+//
+//	encoding   size     alphabet                 typical use
+//	hex        2x       0-9 a-f                  hashes, keys, debugging
+//	base64     1.33x    A-Z a-z 0-9 + /  (=)     MIME, JSON []byte, PEM
+//	base64url  1.33x    A-Z a-z 0-9 - _  (=)     URLs, JWTs, file names
+//
+// Base64 Variants
+// ===============
+// encoding/base64 has four encodings - two alphabets, with or without
+// padding - and they do not read each other's output:
+//
+//	StdEncoding       + /   padded     MIME, encoding/json []byte, PEM,
+//	                                   data: URLs
+//	URLEncoding       - _   padded     rare; the = still needs escaping
+//	                                   in a query string
+//	RawStdEncoding    + /   unpadded   PHC password hashes, SSH keys'
+//	                                   fingerprints
+//	RawURLEncoding    - _   unpadded   JWTs, WebAuthn, anything in a URL
+//
+// Base64 turns every 3 bytes into 4 characters. Padding fills the last
+// group up to 4 with '=', so the length is always a multiple of 4;
+// without it the decoder works out the missing bytes from the length.
+// The padding carries no information - it only makes concatenated
+// encodings splittable, which nobody does.
+//
+// Pitfalls
+// ========
+//
+//	decoding with the wrong variant    "illegal base64 data at input byte
+//	                                   N" - at the first - or _ or =
+//	std base64 in a query string       + decodes as a space and / splits
+//	                                   the path: use RawURLEncoding, or
+//	                                   escape
+//	comparing encoded strings          "QQ==" and "QR==" decode to the
+//	                                   same byte unless .Strict() is set:
+//	                                   compare the decoded bytes
+//	DecodedLen as the result size      an upper bound; use the n returned
+//	                                   by Decode
+//	forgetting Close on NewEncoder     the last 1-2 bytes are never
+//	                                   written - see stream.go
+//	base64 as encryption               anyone can decode it
+
+// ErrMixedAlphabet is returned by DecodeAny for input that uses
+// characters from both base64 alphabets
+var ErrMixedAlphabet = errors.New("textenc: base64 mixes the standard and URL-safe alphabets")
+
+// DecodeAny decodes base64 in any of the four variants, for input from
+// clients that cannot be relied on to pick one. The alphabet is decided
+// by the characters present and padding is optional. Decoding is
+// strict: the unused bits of the last character must be zero, so every
+// byte string has exactly one accepted encoding per variant.
+//
+// Prefer one variant in a format you define. This is for the boundary
+// where others' data arrives.
+func DecodeAny(s string) ([]byte, error) {
+	std := strings.ContainsAny(s, "+/")
+	url := strings.ContainsAny(s, "-_")
+	if std && url {
+		return nil, ErrMixedAlphabet
+	}
+	enc := base64.RawStdEncoding
+	if url {
+		enc = base64.RawURLEncoding
+	}
+	// Padding is at most two '=' and only where the length needs it
+	trimmed := strings.TrimRight(s, "=")
+	if pad := len(s) - len(trimmed); pad > 0 && (pad > 2 || len(s)%4 != 0) {
+		return nil, base64.CorruptInputError(len(trimmed))
+	}
+	return enc.Strict().DecodeString(trimmed)
+}
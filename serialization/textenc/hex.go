@@ -0,0 +1,67 @@
+package textenc
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// Hex
+// ===
+// Two characters per byte, so twice the size, but readable: every
+// character is exactly four bits, and byte boundaries fall on even
+// offsets. Hashes and keys are usually shown in hex for that reason.
+//
+//	hex.EncodeToString(b)      lower case, no separators
+//	hex.AppendEncode(dst, b)   appends, allocating nothing when dst has
+//	                           room (Go 1.22)
+//	hex.DecodeString(s)        accepts either case; an odd length is
+//	                           hex.ErrLength, a bad character is a
+//	                           hex.InvalidByteError
+//	hex.Dump(b)                the hexdump -C layout, for debugging
+//	                           binary protocols
+//
+// Hex written by people and other tools comes in more shapes than
+// DecodeString accepts: "0x" prefixes, colons between bytes in
+// certificate fingerprints, spaces in pasted dumps. ParseHex accepts
+// those; a format of your own should accept just one.
+
+// ParseHex decodes hex with an optional "0x" prefix and optional ':', '-'
+// or ' ' between bytes, in either case. Separators may fall only between
+// whole bytes: "ab:cd" is two bytes, "a:bcd" is an error.
+func ParseHex(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	var digits []byte
+	for i := range len(s) {
+		c := s[i]
+		if c == ':' || c == '-' || c == ' ' {
+			if len(digits)%2 != 0 {
+				return nil, hex.InvalidByteError(c)
+			}
+			continue
+		}
+		digits = append(digits, c)
+	}
+	out := make([]byte, hex.DecodedLen(len(digits)))
+	if _, err := hex.Decode(out, digits); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Fingerprint formats b as upper-case hex bytes separated by colons,
+// the way openssl and browsers show certificate fingerprints
+func Fingerprint(b []byte) string {
+	const digits = "0123456789ABCDEF"
+	var sb strings.Builder
+	sb.Grow(3 * len(b))
+	for i, c := range b {
+		if i > 0 {
+			sb.WriteByte(':')
+		}
+		sb.WriteByte(digits[c>>4])
+		sb.WriteByte(digits[c&0x0f])
+	}
+	return sb.String()
+}
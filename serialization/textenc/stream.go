@@ -0,0 +1,95 @@
+package textenc
+
+import (
+	"encoding/base64"
+	"io"
+)
+
+// Streaming Encoders
+// ==================
+// base64.NewEncoder and hex.NewEncoder wrap an io.Writer, so a large
+// file can be encoded on its way somewhere else without holding it in
+// memory:
+//
+//	enc := base64.NewEncoder(base64.StdEncoding, w)
+//	io.Copy(enc, file)
+//	enc.Close() // required
+//
+// Base64 works in groups of 3 bytes. The encoder holds back the last
+// 1 or 2 bytes of each Write until it knows whether more are coming, and
+// only Close writes them, with the padding. Forget Close and the output
+// is silently short - and still decodes, minus the last bytes. hex has
+// no groups and no Close.
+//
+// The decoders go the other way, wrapping an io.Reader. The base64
+// decoder skips '\r' and '\n', so MIME's line-wrapped base64 decodes
+// without unwrapping first.
+
+// EncodeBase64 writes src to dst as base64 in enc, streaming, and
+// closes the encoder. It returns the number of bytes read from src.
+func EncodeBase64(dst io.Writer, src io.Reader, enc *base64.Encoding) (int64, error) {
+	w := base64.NewEncoder(enc, dst)
+	n, err := io.Copy(w, src)
+	if err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// LineWriter breaks its output into lines of at most Width bytes, each
+// ended by "\r\n". MIME limits base64 bodies to 76 characters a line;
+// PEM uses 64.
+//
+//	base64.NewEncoder(base64.StdEncoding, textenc.NewLineWriter(w, 76))
+//
+// Call Close to end the last line.
+type LineWriter struct {
+	w     io.Writer
+	width int
+	col   int // bytes in the current line
+}
+
+// NewLineWriter returns a LineWriter writing to w. width must be at
+// least 1.
+func NewLineWriter(w io.Writer, width int) *LineWriter {
+	if width < 1 {
+		panic("textenc: line width must be at least 1")
+	}
+	return &LineWriter{w: w, width: width}
+}
+
+var crlf = []byte("\r\n")
+
+// Write writes p, inserting line breaks. The count returned covers p
+// only, not the breaks, as io.Writer requires.
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if lw.col == lw.width {
+			if _, err := lw.w.Write(crlf); err != nil {
+				return written, err
+			}
+			lw.col = 0
+		}
+		chunk := p[:min(len(p), lw.width-lw.col)]
+		n, err := lw.w.Write(chunk)
+		written += n
+		lw.col += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close ends the last line, if anything was written to it. It does not
+// close the underlying writer.
+func (lw *LineWriter) Close() error {
+	if lw.col == 0 {
+		return nil
+	}
+	lw.col = 0
+	_, err := lw.w.Write(crlf)
+	return err
+}
@@ -0,0 +1,449 @@
+package textenc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Text Encodings - Tests
+// ======================
+// Run with:
+//
+//   cd serialization/textenc
+//   go test -v *.go
+//   go test -run XXX -fuzz FuzzBase64RoundTrip -fuzztime 30s *.go
+//
+// The fuzz targets check round trips: whatever bytes go in come back
+// out, through every base64 variant, through the streaming encoder in
+// any chunking, through hex and through a URL path.
+
+var variants = map[string]*base64.Encoding{
+	"Std":    base64.StdEncoding,
+	"URL":    base64.URLEncoding,
+	"RawStd": base64.RawStdEncoding,
+	"RawURL": base64.RawURLEncoding,
+}
+
+// 1. Base64 Variants
+// ==================
+
+func TestVariants(t *testing.T) {
+	// 0xfb 0xff 0xbf uses both of the characters the alphabets differ in
+	in := []byte{0xfb, 0xff, 0xbf, 'h', 'i'}
+	want := map[string]string{
+		"Std":    "+/+/aGk=",
+		"URL":    "-_-_aGk=",
+		"RawStd": "+/+/aGk",
+		"RawURL": "-_-_aGk",
+	}
+	for name, enc := range variants {
+		if got := enc.EncodeToString(in); got != want[name] {
+			t.Errorf("%s: %s, want %s", name, got, want[name])
+		}
+	}
+
+	// Each decoder rejects the others' output
+	for name, enc := range variants {
+		for other, text := range want {
+			_, err := enc.DecodeString(text)
+			if other == name && err != nil {
+				t.Errorf("%s cannot decode its own output: %v", name, err)
+			}
+			var corrupt base64.CorruptInputError
+			if other != name && !errors.As(err, &corrupt) {
+				t.Errorf("%s decoded %s's output %q: %v", name, other, text, err)
+			}
+		}
+	}
+}
+
+func TestEncodedLength(t *testing.T) {
+	for n := range 10 {
+		padded := base64.StdEncoding.EncodedLen(n)
+		raw := base64.RawStdEncoding.EncodedLen(n)
+		if padded != 4*((n+2)/3) || raw != (4*n+2)/3 {
+			t.Errorf("%d bytes: %d padded, %d raw", n, padded, raw)
+		}
+	}
+	// DecodedLen is an upper bound, not the size
+	text := "aGk=" // "hi"
+	buf := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, _ := base64.StdEncoding.Decode(buf, []byte(text))
+	if len(buf) != 3 || n != 2 {
+		t.Errorf("DecodedLen %d, decoded %d", len(buf), n)
+	}
+}
+
+func TestStrictRejectsAlternateEncodings(t *testing.T) {
+	// 'A' is 01000001: "QQ==" carries it with four zero bits left over.
+	// "QR==" sets one of them, and the lenient decoder does not mind.
+	a, _ := base64.StdEncoding.DecodeString("QQ==")
+	b, _ := base64.StdEncoding.DecodeString("QR==")
+	if string(a) != "A" || string(b) != "A" {
+		t.Fatalf("decoded %q and %q", a, b)
+	}
+	// Two strings, one value: compare decoded bytes, never encodings -
+	// or decode with Strict, which accepts one encoding per value
+	if _, err := base64.StdEncoding.Strict().DecodeString("QR=="); err == nil {
+		t.Error("Strict accepted non-zero trailing bits")
+	}
+}
+
+func TestJSONBytesAreStdBase64(t *testing.T) {
+	b, _ := json.Marshal(struct{ Key []byte }{[]byte{0xfb, 0xff}})
+	if string(b) != `{"Key":"+/8="}` {
+		t.Errorf("json = %s", b)
+	}
+	// A URL-safe value in JSON does not decode into []byte
+	var v struct{ Key []byte }
+	if err := json.Unmarshal([]byte(`{"Key":"-_8"}`), &v); err == nil {
+		t.Error("encoding/json decoded URL-safe base64")
+	}
+}
+
+func TestDecodeAny(t *testing.T) {
+	in := []byte{0xfb, 0xff, 0xbf, 'h', 'i'}
+	for _, text := range []string{"+/+/aGk=", "-_-_aGk=", "+/+/aGk", "-_-_aGk"} {
+		got, err := DecodeAny(text)
+		if err != nil || !bytes.Equal(got, in) {
+			t.Errorf("DecodeAny(%q) = %x, %v", text, got, err)
+		}
+	}
+
+	if _, err := DecodeAny("+/-_aGk="); !errors.Is(err, ErrMixedAlphabet) {
+		t.Errorf("mixed alphabets: %v, want ErrMixedAlphabet", err)
+	}
+	var corrupt base64.CorruptInputError
+	for _, text := range []string{
+		"aGk==",    // too much padding
+		"QQ=",      // padding that does not reach a multiple of 4
+		"aGk=aGk=", // padding in the middle
+		"QR==",     // non-zero trailing bits, rejected by Strict
+		"a",        // one character is 6 bits, not a byte
+	} {
+		if _, err := DecodeAny(text); !errors.As(err, &corrupt) {
+			t.Errorf("DecodeAny(%q) = %v, want base64.CorruptInputError", text, err)
+		}
+	}
+}
+
+func FuzzBase64RoundTrip(f *testing.F) {
+	f.Add([]byte(""))
+	f.Add([]byte("a"))
+	f.Add([]byte("ab"))
+	f.Add([]byte{0xfb, 0xff, 0xbf})
+	f.Fuzz(func(t *testing.T, in []byte) {
+		for name, enc := range variants {
+			text := enc.EncodeToString(in)
+			got, err := enc.Strict().DecodeString(text)
+			if err != nil || !bytes.Equal(got, in) {
+				t.Fatalf("%s: %x encoded as %q, decoded as %x, %v", name, in, text, got, err)
+			}
+			// DecodeAny reads every variant's output
+			if got, err := DecodeAny(text); err != nil || !bytes.Equal(got, in) {
+				t.Fatalf("DecodeAny(%q) = %x, %v; want %x", text, got, err, in)
+			}
+		}
+	})
+}
+
+func FuzzDecodeAny(f *testing.F) {
+	f.Add("aGk=")
+	f.Add("-_8")
+	f.Add("====")
+	f.Add("+/-_")
+	f.Fuzz(func(t *testing.T, text string) {
+		got, err := DecodeAny(text)
+		if err != nil {
+			return
+		}
+		// Whatever it accepts is some variant's exact encoding of the
+		// result, apart from the line breaks base64 decoders skip
+		clean := strings.NewReplacer("\r", "", "\n", "").Replace(text)
+		for _, enc := range variants {
+			if enc.EncodeToString(got) == clean {
+				return
+			}
+		}
+		t.Fatalf("DecodeAny(%q) = %x, which no variant encodes that way", text, got)
+	})
+}
+
+// 2. Hex
+// ======
+
+func TestParseHex(t *testing.T) {
+	want := []byte{0xde, 0xad, 0xbe, 0xef}
+	for _, in := range []string{"deadbeef", "DEADBEEF", "0xdeadbeef", "DE:AD:BE:EF", "de ad be ef", "de-ad-be-ef"} {
+		got, err := ParseHex(in)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("ParseHex(%q) = %x, %v", in, got, err)
+		}
+	}
+
+	if _, err := ParseHex("dea"); !errors.Is(err, hex.ErrLength) {
+		t.Errorf("odd length: %v, want hex.ErrLength", err)
+	}
+	var bad hex.InvalidByteError
+	for _, in := range []string{"d:eadbeef", "deadbeeg", "0x0xdeadbeef"} {
+		if _, err := ParseHex(in); !errors.As(err, &bad) {
+			t.Errorf("ParseHex(%q) = %v, want hex.InvalidByteError", in, err)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	if got := Fingerprint([]byte{0xde, 0xad, 0x0b}); got != "DE:AD:0B" {
+		t.Errorf("Fingerprint = %s", got)
+	}
+	if got := Fingerprint(nil); got != "" {
+		t.Errorf("Fingerprint(nil) = %q", got)
+	}
+}
+
+func FuzzHexRoundTrip(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 0xff})
+	f.Fuzz(func(t *testing.T, in []byte) {
+		for _, text := range []string{hex.EncodeToString(in), Fingerprint(in), "0x" + strings.ToUpper(hex.EncodeToString(in))} {
+			got, err := ParseHex(text)
+			if err != nil || !bytes.Equal(got, in) {
+				t.Fatalf("ParseHex(%q) = %x, %v; want %x", text, got, err, in)
+			}
+		}
+	})
+}
+
+// 3. URLs
+// =======
+
+func TestEscapers(t *testing.T) {
+	const s = "a b+c/d&e"
+	if got := url.PathEscape(s); got != "a%20b+c%2Fd&e" {
+		t.Errorf("PathEscape = %s", got)
+	}
+	if got := url.QueryEscape(s); got != "a+b%2Bc%2Fd%26e" {
+		t.Errorf("QueryEscape = %s", got)
+	}
+
+	// The wrong escaper changes the data: QueryEscape's '+' is a
+	// literal '+' in a path
+	u, _ := url.Parse("https://example.com/" + url.QueryEscape("a b"))
+	if u.Path != "/a+b" {
+		t.Errorf("Path = %q", u.Path)
+	}
+}
+
+func TestStdBase64InQueryBreaks(t *testing.T) {
+	token := []byte{0xfb, 0xef, 0xbe} // "++++" in standard base64
+	std := base64.StdEncoding.EncodeToString(token)
+
+	q, _ := url.ParseQuery("token=" + std) // concatenated, not escaped
+	if got := q.Get("token"); got != "    " {
+		t.Fatalf("token = %q; the + should have become spaces", got)
+	}
+	if _, err := base64.StdEncoding.DecodeString(q.Get("token")); err == nil {
+		t.Error("the mangled token decoded")
+	}
+
+	// Either fix works: escape the value, or use the URL-safe alphabet
+	q, _ = url.ParseQuery(url.Values{"token": {std}}.Encode())
+	if got, err := base64.StdEncoding.DecodeString(q.Get("token")); err != nil || !bytes.Equal(got, token) {
+		t.Errorf("escaped std: %x, %v", got, err)
+	}
+	q, _ = url.ParseQuery("token=" + base64.RawURLEncoding.EncodeToString(token))
+	if got, err := base64.RawURLEncoding.DecodeString(q.Get("token")); err != nil || !bytes.Equal(got, token) {
+		t.Errorf("raw url: %x, %v", got, err)
+	}
+}
+
+func TestJoinSegments(t *testing.T) {
+	got, err := JoinSegments("https://example.com/api/", "files", "reports/2024 Q1.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/api/files/reports%2F2024%20Q1.pdf"; got != want {
+		t.Errorf("JoinSegments = %s, want %s", got, want)
+	}
+
+	// url.JoinPath keeps the '/' as a separator: three segments, not two
+	joined, _ := url.JoinPath("https://example.com/api/", "files", "reports/2024 Q1.pdf")
+	if joined != "https://example.com/api/files/reports/2024%20Q1.pdf" {
+		t.Errorf("JoinPath = %s", joined)
+	}
+
+	u, _ := url.Parse(got)
+	segs, err := Segments(u)
+	if err != nil || strings.Join(segs, "|") != "api|files|reports/2024 Q1.pdf" {
+		t.Errorf("Segments = %q, %v", segs, err)
+	}
+	if u.Path != "/api/files/reports/2024 Q1.pdf" {
+		t.Errorf("Path = %q: decoded, the escaped / is lost", u.Path)
+	}
+}
+
+func FuzzJoinSegments(f *testing.F) {
+	f.Add("a", "b")
+	f.Add("a/b", "?x=1#y")
+	f.Add("100%", "a b+c")
+	f.Add(".", "..")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		if a == "" || b == "" {
+			return // an empty segment collapses in some URL parsers
+		}
+		joined, err := JoinSegments("https://example.com/base", a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := url.Parse(joined)
+		if err != nil {
+			t.Fatalf("%q does not parse: %v", joined, err)
+		}
+		if u.Host != "example.com" || u.RawQuery != "" || u.Fragment != "" {
+			t.Fatalf("segments %q, %q changed the URL's structure: %s", a, b, joined)
+		}
+		segs, err := Segments(u)
+		if err != nil || len(segs) != 3 || segs[1] != a || segs[2] != b {
+			t.Fatalf("%s: segments %q, %v; want base, %q, %q", joined, segs, err, a, b)
+		}
+	})
+}
+
+// 4. Streaming
+// ============
+
+func TestForgottenClose(t *testing.T) {
+	var buf bytes.Buffer
+	w := base64.NewEncoder(base64.StdEncoding, &buf)
+	w.Write([]byte("hello"))
+	if got := buf.String(); got != "aGVs" {
+		t.Fatalf("before Close: %q", got)
+	}
+	// "lo" is still inside the encoder. The short output decodes
+	// without complaint, to a shorter message.
+	if got, err := base64.StdEncoding.DecodeString(buf.String()); err != nil || string(got) != "hel" {
+		t.Fatalf("decoded %q, %v", got, err)
+	}
+	w.Close()
+	if got := buf.String(); got != "aGVsbG8=" {
+		t.Errorf("after Close: %q", got)
+	}
+}
+
+func TestLineWrappedBase64(t *testing.T) {
+	data := bytes.Repeat([]byte("attachment "), 50)
+	var buf bytes.Buffer
+	lw := NewLineWriter(&buf, 76)
+	if _, err := EncodeBase64(lw, bytes.NewReader(data), base64.StdEncoding); err != nil {
+		t.Fatal(err)
+	}
+	lw.Close()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	for i, line := range lines {
+		if len(line) > 76 || (i < len(lines)-1 && len(line) != 76) {
+			t.Errorf("line %d is %d characters", i, len(line))
+		}
+	}
+	// The decoder skips the line breaks
+	got, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, &buf))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decoded %d bytes, %v", len(got), err)
+	}
+}
+
+func FuzzStreamingMatchesOneShot(f *testing.F) {
+	f.Add([]byte("hello, world"), 3)
+	f.Add([]byte{}, 1)
+	f.Add(bytes.Repeat([]byte{0xff}, 100), 7)
+	f.Fuzz(func(t *testing.T, in []byte, width int) {
+		width = 1 + abs(width)%100
+		for name, enc := range variants {
+			// Feed the encoder one byte per Write, the worst chunking
+			var buf bytes.Buffer
+			lw := NewLineWriter(&buf, width)
+			if _, err := EncodeBase64(lw, iotest.OneByteReader(bytes.NewReader(in)), enc); err != nil {
+				t.Fatal(err)
+			}
+			lw.Close()
+
+			want := enc.EncodeToString(in)
+			if got := strings.ReplaceAll(buf.String(), "\r\n", ""); got != want {
+				t.Fatalf("%s: streamed %q, want %q", name, got, want)
+			}
+			for line := range strings.SplitSeq(buf.String(), "\r\n") {
+				if len(line) > width {
+					t.Fatalf("%s: line of %d bytes, width %d", name, len(line), width)
+				}
+			}
+			got, err := io.ReadAll(base64.NewDecoder(enc, &buf))
+			if err != nil || !bytes.Equal(got, in) {
+				t.Fatalf("%s: decoded %x, %v; want %x", name, got, err, in)
+			}
+		}
+	})
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// 5. Benchmarks
+// =============
+
+func BenchmarkEncode(b *testing.B) {
+	data := bytes.Repeat([]byte{0xab}, 1024)
+	b.Run("hex", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			hex.EncodeToString(data)
+		}
+	})
+	b.Run("base64", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			base64.StdEncoding.EncodeToString(data)
+		}
+	})
+	b.Run("base64/AppendEncode", func(b *testing.B) {
+		// Reusing the buffer: no allocations
+		buf := make([]byte, 0, base64.StdEncoding.EncodedLen(len(data)))
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			buf = base64.StdEncoding.AppendEncode(buf[:0], data)
+		}
+	})
+}
+
+// 6. Examples
+// ===========
+
+func ExampleDecodeAny() {
+	for _, s := range []string{"aGk=", "aGk", "-_8", "+/8="} {
+		b, err := DecodeAny(s)
+		fmt.Printf("%-5s %x %v\n", s, b, err)
+	}
+	// Output:
+	// aGk=  6869 <nil>
+	// aGk   6869 <nil>
+	// -_8   fbff <nil>
+	// +/8=  fbff <nil>
+}
+
+func ExampleJoinSegments() {
+	u, _ := JoinSegments("https://example.com/users", "ada lovelace", "notes/draft #1")
+	fmt.Println(u)
+	// Output: https://example.com/users/ada%20lovelace/notes%2Fdraft%20%231
+}
@@ -0,0 +1,80 @@
+package textenc
+
+import (
+	"net/url"
+	"strings"
+)
+
+// URL Encoding
+// ============
+// A URL has several parts, and each escapes a different set of
+// characters. net/url has one function per part:
+//
+//	                  "a b+c/d&e" becomes   use for
+//	url.PathEscape    a%20b+c%2Fd&e         one path segment
+//	url.QueryEscape   a+b%2Bc%2Fd%26e       one query key or value
+//	url.Values        k=a+b%2Bc%2Fd%26e     a whole query; Encode sorts
+//	                                        the keys
+//
+// The two disagree on space and '+'. In a query, '+' means space, so a
+// literal '+' must be %2B; in a path, '+' is just '+', and space must
+// be %20. Use the wrong one and a round trip changes the data:
+// QueryEscape in a path turns "a b" into "a+b", which the server reads
+// back as "a+b".
+//
+// Pitfalls
+// ========
+//
+//	fmt.Sprintf("/users/%s", name)     a name with / ? # or % changes the
+//	                                   URL's structure; escape each part
+//	url.JoinPath(base, name)           escapes, but keeps '/' as a
+//	                                   separator: "a/b" becomes two
+//	                                   segments. JoinSegments escapes it
+//	std base64 in a query value        unescaped, + becomes a space on
+//	                                   the server and the token fails to
+//	                                   decode. Use RawURLEncoding
+//	u.Path after parsing               decoded: "a%2Fb" and "a/b" look the
+//	                                   same. Segments splits EscapedPath
+//	                                   first, then unescapes
+//	escaping twice                     %2F becomes %252F; escape once, at
+//	                                   the point the URL is built
+
+// JoinSegments appends path segments to base, escaping each one so
+// that '/', '?', '#' and '%' inside a segment stay data
+func JoinSegments(base string, segments ...string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	escaped := strings.TrimSuffix(u.EscapedPath(), "/")
+	for _, seg := range segments {
+		escaped += "/" + url.PathEscape(seg)
+	}
+	// Path holds the decoded form and RawPath the escaped one; String
+	// uses RawPath when the two disagree, as they do once a segment
+	// contains %2F
+	if u.Path, err = url.PathUnescape(escaped); err != nil {
+		return "", err
+	}
+	u.RawPath = escaped
+	return u.String(), nil
+}
+
+// Segments returns the unescaped segments of u's path, keeping an
+// escaped '/' inside its segment: "/files/a%2Fb" is ["files", "a/b"],
+// where splitting u.Path would give three
+func Segments(u *url.URL) ([]string, error) {
+	path := strings.TrimPrefix(u.EscapedPath(), "/")
+	if path == "" {
+		return nil, nil
+	}
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		seg, err := url.PathUnescape(p)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = seg
+	}
+	return parts, nil
+}
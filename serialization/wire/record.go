@@ -0,0 +1,149 @@
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Wire - A Small Binary Record Format
+// ===================================
+// A Reading is encoded as:
+//
+//	offset size  field
+//	0      4     magic "GLR1"
+//	4      1     byte order: 'B' big endian, 'L' little endian
+//	5      4     sensor ID (uint32)
+//	9      8     time, Unix nanoseconds (int64)
+//	17     4     temperature (float32 bits)
+//	21     1-10  delta from the previous value (signed varint)
+//	...    1-10  tag count (uvarint), then per tag: length (uvarint) + bytes
+//
+// Fixed-size fields come first so a reader can find them at known
+// offsets; variable-size fields follow, each with a length prefix. The
+// order byte makes the format self-describing, like TIFF's "II"/"MM":
+// writers use their preferred order and readers handle both.
+//
+// Varints are the same in both orders: they are a byte sequence, least
+// significant 7 bits first, not a multi-byte integer.
+
+// Magic identifies the format and its version
+const Magic = "GLR1"
+
+// HeaderSize is the size of the fixed part of a record
+const HeaderSize = 21
+
+var (
+	// ErrBadMagic means the data is not a record of this format
+	ErrBadMagic = errors.New("wire: bad magic")
+
+	// ErrBadOrder means the byte-order marker is neither 'B' nor 'L'
+	ErrBadOrder = errors.New("wire: unknown byte order")
+)
+
+// Reading is one sensor sample
+type Reading struct {
+	SensorID    uint32
+	UnixNano    int64
+	Temperature float32
+	Delta       int64
+	Tags        []string
+}
+
+// Append encodes r onto dst in the given byte order and returns the
+// extended slice. Appending into a reused buffer allocates nothing once
+// the buffer is large enough.
+func Append(dst []byte, order binary.AppendByteOrder, r Reading) []byte {
+	dst = append(dst, Magic...)
+	dst = append(dst, orderMarker(order))
+	dst = order.AppendUint32(dst, r.SensorID)
+	dst = order.AppendUint64(dst, uint64(r.UnixNano))
+	dst = order.AppendUint32(dst, math.Float32bits(r.Temperature))
+	dst = binary.AppendVarint(dst, r.Delta)
+	dst = binary.AppendUvarint(dst, uint64(len(r.Tags)))
+	for _, tag := range r.Tags {
+		dst = binary.AppendUvarint(dst, uint64(len(tag)))
+		dst = append(dst, tag...)
+	}
+	return dst
+}
+
+// Decode parses one record from the front of data and returns it with
+// the number of bytes used, so records can be read back to back.
+// Truncated input returns io.ErrUnexpectedEOF.
+func Decode(data []byte) (Reading, int, error) {
+	var r Reading
+	if len(data) < HeaderSize {
+		return r, 0, io.ErrUnexpectedEOF
+	}
+	if string(data[:4]) != Magic {
+		return r, 0, ErrBadMagic
+	}
+
+	var order binary.ByteOrder
+	switch data[4] {
+	case 'B':
+		order = binary.BigEndian
+	case 'L':
+		order = binary.LittleEndian
+	default:
+		return r, 0, fmt.Errorf("%w: %q", ErrBadOrder, data[4])
+	}
+
+	r.SensorID = order.Uint32(data[5:])
+	r.UnixNano = int64(order.Uint64(data[9:]))
+	r.Temperature = math.Float32frombits(order.Uint32(data[17:]))
+	off := HeaderSize
+
+	// Varint readers return n <= 0 for truncated or overlong input
+	delta, n := binary.Varint(data[off:])
+	if n <= 0 {
+		return r, 0, io.ErrUnexpectedEOF
+	}
+	r.Delta = delta
+	off += n
+
+	count, n := binary.Uvarint(data[off:])
+	if n <= 0 {
+		return r, 0, io.ErrUnexpectedEOF
+	}
+	off += n
+
+	// Never trust a length from the wire: each tag needs at least one
+	// byte, so a count larger than the remaining data is corrupt, and
+	// make() with it could allocate gigabytes
+	if count > uint64(len(data)-off) {
+		return r, 0, io.ErrUnexpectedEOF
+	}
+	r.Tags = make([]string, 0, count)
+	for range count {
+		size, n := binary.Uvarint(data[off:])
+		if n <= 0 || size > uint64(len(data)-off-n) {
+			return r, 0, io.ErrUnexpectedEOF
+		}
+		off += n
+		r.Tags = append(r.Tags, string(data[off:off+int(size)]))
+		off += int(size)
+	}
+	return r, off, nil
+}
+
+// nativeMarker is the marker for binary.NativeEndian on this machine
+var nativeMarker = func() byte {
+	if binary.NativeEndian.Uint16([]byte{0, 1}) == 1 {
+		return 'B'
+	}
+	return 'L'
+}()
+
+func orderMarker(order binary.AppendByteOrder) byte {
+	switch order {
+	case binary.BigEndian:
+		return 'B'
+	case binary.NativeEndian:
+		return nativeMarker
+	}
+	return 'L'
+}
@@ -0,0 +1,278 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// Endianness and encoding/binary
+// ==============================
+// Run with:
+//
+//   cd serialization/wire
+//   go test -v *.go
+
+// 1. Byte Order
+// =============
+
+func TestByteOrder(t *testing.T) {
+	// Big endian writes the most significant byte first ("network byte
+	// order", used by TCP/IP headers). Little endian writes the least
+	// significant byte first, like x86 and ARM memory.
+	const x uint32 = 0x0A0B0C0D
+
+	be := binary.BigEndian.AppendUint32(nil, x)
+	le := binary.LittleEndian.AppendUint32(nil, x)
+	if !bytes.Equal(be, []byte{0x0A, 0x0B, 0x0C, 0x0D}) {
+		t.Errorf("big endian = % x", be)
+	}
+	if !bytes.Equal(le, []byte{0x0D, 0x0C, 0x0B, 0x0A}) {
+		t.Errorf("little endian = % x", le)
+	}
+
+	// Reading with the wrong order does not fail - it gives a different
+	// number. The format must say which order it uses.
+	if got := binary.LittleEndian.Uint32(be); got != 0x0D0C0B0A {
+		t.Errorf("misread = %#x", got)
+	}
+}
+
+func TestNativeEndian(t *testing.T) {
+	// The CPU's order is visible by looking at an integer's first byte in
+	// memory. NativeEndian matches it; use it only for data that never
+	// leaves the machine (shared memory, mmap'd caches).
+	x := uint16(1)
+	first := *(*byte)(unsafe.Pointer(&x))
+	native := binary.NativeEndian.AppendUint16(nil, 1)
+	if native[0] != first {
+		t.Errorf("NativeEndian wrote % x, memory starts with %x", native, first)
+	}
+	t.Logf("this machine is %s endian", map[byte]string{0: "big", 1: "little"}[first])
+}
+
+func TestTypeSizesOnTheWire(t *testing.T) {
+	// binary.Size is the encoded size of fixed-size values, which is the
+	// same as unsafe.Sizeof only when the struct has no padding
+	type header struct {
+		Kind  uint8
+		Value uint64
+	}
+	if wire, mem := binary.Size(header{}), unsafe.Sizeof(header{}); wire != 9 || mem != 16 {
+		t.Errorf("binary.Size = %d, unsafe.Sizeof = %d; want 9 and 16", wire, mem)
+	}
+
+	// int and uint have no fixed size, so binary refuses them
+	if binary.Size(int(0)) != -1 {
+		t.Error("binary.Size(int) should be -1")
+	}
+}
+
+// 2. Varints
+// ==========
+
+func TestVarints(t *testing.T) {
+	tests := []struct {
+		v    int64
+		size int
+	}{
+		{0, 1},
+		{-1, 1}, // zig-zag: small negatives stay small
+		{63, 1},
+		{64, 2},
+		{-8192, 2},
+		{math.MaxInt64, 10},
+	}
+	for _, tt := range tests {
+		buf := binary.AppendVarint(nil, tt.v)
+		if len(buf) != tt.size {
+			t.Errorf("AppendVarint(%d) used %d bytes, want %d", tt.v, len(buf), tt.size)
+		}
+		if got, n := binary.Varint(buf); got != tt.v || n != len(buf) {
+			t.Errorf("Varint(% x) = %d, %d", buf, got, n)
+		}
+	}
+
+	// Uvarint on a negative value cast to uint64 is always 10 bytes:
+	// use the signed variant for signed data
+	if n := len(binary.AppendUvarint(nil, uint64(math.MaxUint64))); n != 10 {
+		t.Errorf("uvarint of -1 as uint64 = %d bytes", n)
+	}
+}
+
+// 3. Round Trip
+// =============
+
+var sample = Reading{
+	SensorID:    0x01020304,
+	UnixNano:    1_700_000_000_000_000_000,
+	Temperature: 21.5,
+	Delta:       -3,
+	Tags:        []string{"lab", "north"},
+}
+
+func TestRoundTrip(t *testing.T) {
+	orders := []binary.AppendByteOrder{binary.BigEndian, binary.LittleEndian, binary.NativeEndian}
+	for _, order := range orders {
+		t.Run(fmt.Sprint(order), func(t *testing.T) {
+			data := Append(nil, order, sample)
+			got, n, err := Decode(data)
+			if err != nil || n != len(data) {
+				t.Fatalf("Decode = %d bytes, %v; want %d", n, err, len(data))
+			}
+			if !reflect.DeepEqual(got, sample) {
+				t.Errorf("Decode = %+v, want %+v", got, sample)
+			}
+		})
+	}
+}
+
+func TestBackToBack(t *testing.T) {
+	// Records can be concatenated; Decode reports where each one ends
+	second := Reading{SensorID: 7, Tags: []string{}}
+	data := Append(nil, binary.BigEndian, sample)
+	data = Append(data, binary.LittleEndian, second)
+
+	var got []Reading
+	for len(data) > 0 {
+		r, n, err := Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+		data = data[n:]
+	}
+	if len(got) != 2 || got[1].SensorID != 7 {
+		t.Errorf("decoded %+v", got)
+	}
+}
+
+func TestAppendAllocations(t *testing.T) {
+	buf := make([]byte, 0, 128)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = Append(buf[:0], binary.BigEndian, sample)
+	})
+	if allocs != 0 {
+		t.Errorf("Append into a reused buffer: %v allocs, want 0", allocs)
+	}
+}
+
+// 4. Wire Compatibility
+// =====================
+// Golden bytes pin the layout. If a change to Append breaks these tests,
+// it also breaks every reader already deployed - bump Magic instead.
+
+const (
+	goldenBig = "474c5231" + "42" + // "GLR1", 'B'
+		"01020304" + // sensor ID
+		"17979cfe362a0000" + // time
+		"41ac0000" + // 21.5
+		"05" + // delta -3 (zig-zag 5)
+		"02" + "036c6162" + "056e6f727468" // 2 tags: "lab", "north"
+
+	goldenLittle = "474c5231" + "4c" + // "GLR1", 'L'
+		"04030201" +
+		"00002a36fe9c9717" +
+		"0000ac41" +
+		"05" +
+		"02" + "036c6162" + "056e6f727468"
+)
+
+func TestWireCompatibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  binary.AppendByteOrder
+		golden string
+	}{
+		{"big endian", binary.BigEndian, goldenBig},
+		{"little endian", binary.LittleEndian, goldenLittle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, _ := hex.DecodeString(tt.golden)
+
+			// Writers must produce exactly these bytes...
+			if got := Append(nil, tt.order, sample); !bytes.Equal(got, want) {
+				t.Errorf("Append =\n%x\nwant\n%x", got, want)
+			}
+
+			// ...and readers must keep accepting them
+			got, _, err := Decode(want)
+			if err != nil || !reflect.DeepEqual(got, sample) {
+				t.Errorf("Decode(golden) = %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestBinaryWriteMatchesHeader(t *testing.T) {
+	// binary.Write with a struct of fixed-size fields produces the same
+	// header bytes as the hand-written Append* calls, but uses reflection
+	header := struct {
+		Magic       [4]byte
+		Order       byte
+		SensorID    uint32
+		UnixNano    int64
+		Temperature float32
+	}{[4]byte([]byte(Magic)), 'B', sample.SensorID, sample.UnixNano, sample.Temperature}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.BigEndian, header); err != nil {
+		t.Fatal(err)
+	}
+	if want := Append(nil, binary.BigEndian, sample)[:HeaderSize]; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("binary.Write = %x, want %x", buf.Bytes(), want)
+	}
+}
+
+// 5. Corrupt Input
+// ================
+
+func TestDecodeErrors(t *testing.T) {
+	valid := Append(nil, binary.BigEndian, sample)
+
+	// Every truncation must fail cleanly, never panic
+	for i := range len(valid) {
+		if _, _, err := Decode(valid[:i]); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Decode of %d/%d bytes: %v, want ErrUnexpectedEOF", i, len(valid), err)
+		}
+	}
+
+	bad := bytes.Clone(valid)
+	bad[0] = 'X'
+	if _, _, err := Decode(bad); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("bad magic: %v", err)
+	}
+
+	bad = bytes.Clone(valid)
+	bad[4] = '?'
+	if _, _, err := Decode(bad); !errors.Is(err, ErrBadOrder) {
+		t.Errorf("bad order: %v", err)
+	}
+
+	// A huge tag count from a corrupt or hostile sender is rejected
+	// before anything is allocated
+	huge := binary.AppendUvarint(bytes.Clone(valid[:HeaderSize+1]), 1<<40)
+	if _, _, err := Decode(huge); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("huge count: %v", err)
+	}
+}
+
+// 6. Examples
+// ===========
+
+func ExampleAppend() {
+	r := Reading{SensorID: 1, Temperature: 1, Delta: 1}
+	fmt.Printf("% x\n", Append(nil, binary.BigEndian, r))
+	fmt.Printf("% x\n", Append(nil, binary.LittleEndian, r))
+	// Output:
+	// 47 4c 52 31 42 00 00 00 01 00 00 00 00 00 00 00 00 3f 80 00 00 02 00
+	// 47 4c 52 31 4c 01 00 00 00 00 00 00 00 00 00 00 00 00 00 80 3f 02 00
+}
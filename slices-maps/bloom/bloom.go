@@ -0,0 +1,187 @@
+package bloom
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Bloom Filter - A Set That Can Say "Maybe"
+// =========================================
+// A Bloom filter answers "have I seen this key?" in a fixed number of
+// bits, however long the keys are. Adding a key sets k bits chosen by
+// hashing it; testing checks those k bits. A zero bit proves the key was
+// never added, but all ones only means "probably": other keys may have
+// set them. There are no false negatives, a tunable rate of false
+// positives, and no way to list or remove keys.
+//
+// With m bits, n keys and k hash functions the false-positive rate is
+// about (1 - e^(-kn/m))^k, which is smallest at k = (m/n)·ln 2. For a 1%
+// rate that works out to 9.6 bits and 7 hashes per key - about 1.2 bytes,
+// compared with the tens of bytes a map entry costs before the key
+// itself.
+//
+// Hashing: computing k independent hashes is slow, so the filter hashes
+// once and derives the rest with double hashing (Kirsch and Mitzenmacher):
+// index_i = h1 + i·h2 mod m. This has been shown to keep the same
+// asymptotic false-positive rate. h1 and h2 come from one 64-bit FNV-1a
+// sum passed through an xxhash-style avalanche: in FNV each output bit
+// depends only on input bits at or below it, and "mod m" leans on the
+// low bits.
+
+// Filter is a Bloom filter over byte strings. It is not safe for
+// concurrent writes. Create one with New or NewWithEstimates.
+type Filter struct {
+	words []uint64
+	m     uint64 // number of bits
+	k     int    // number of hash functions
+	n     int    // keys added, counting duplicates
+}
+
+// New returns a filter with m bits and k hash functions. It panics if
+// m or k is less than 1.
+func New(m uint64, k int) *Filter {
+	if m < 1 || k < 1 {
+		panic("bloom: m and k must be at least 1")
+	}
+	return &Filter{words: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// NewWithEstimates returns a filter sized for n keys at false-positive
+// rate p, using OptimalM and OptimalK
+func NewWithEstimates(n int, p float64) *Filter {
+	m := OptimalM(n, p)
+	return New(m, OptimalK(m, n))
+}
+
+// Add inserts key
+func (f *Filter) Add(key []byte) { f.add(hashes(key)) }
+
+// AddString inserts key without converting it to a []byte
+func (f *Filter) AddString(key string) { f.add(hashes(key)) }
+
+// Test reports whether key may have been added. False means it
+// certainly was not.
+func (f *Filter) Test(key []byte) bool { return f.test(hashes(key)) }
+
+// TestString is Test for a string key
+func (f *Filter) TestString(key string) bool { return f.test(hashes(key)) }
+
+func (f *Filter) add(h1, h2 uint64) {
+	for i := range uint64(f.k) {
+		bit := (h1 + i*h2) % f.m
+		f.words[bit/64] |= 1 << (bit % 64)
+	}
+	f.n++
+}
+
+func (f *Filter) test(h1, h2 uint64) bool {
+	for i := range uint64(f.k) {
+		bit := (h1 + i*h2) % f.m
+		if f.words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// M returns the number of bits
+func (f *Filter) M() uint64 { return f.m }
+
+// K returns the number of hash functions
+func (f *Filter) K() int { return f.k }
+
+// Added returns how many times Add was called. Duplicates count, since
+// the filter cannot tell them apart.
+func (f *Filter) Added() int { return f.n }
+
+// SizeBytes returns the memory used by the bit array
+func (f *Filter) SizeBytes() int { return len(f.words) * 8 }
+
+// FillRatio returns the fraction of bits set. At the optimal k a full
+// filter is about half ones.
+func (f *Filter) FillRatio() float64 {
+	ones := 0
+	for _, w := range f.words {
+		ones += bits.OnesCount64(w)
+	}
+	return float64(ones) / float64(f.m)
+}
+
+// EstimatedFPR returns the expected false-positive rate for the keys
+// added so far
+func (f *Filter) EstimatedFPR() float64 {
+	return FalsePositiveRate(f.m, f.n, f.k)
+}
+
+// ApproxCount estimates the number of distinct keys added from the
+// fill ratio: n ≈ -(m/k)·ln(1 - X/m), where X is the number of ones.
+// Unlike Added it ignores duplicates.
+func (f *Filter) ApproxCount() float64 {
+	fill := f.FillRatio()
+	if fill == 1 {
+		return math.Inf(1)
+	}
+	return -float64(f.m) / float64(f.k) * math.Log(1-fill)
+}
+
+// The Math
+// ========
+
+// FalsePositiveRate returns the expected rate for m bits, n keys and
+// k hashes: (1 - e^(-kn/m))^k
+func FalsePositiveRate(m uint64, n, k int) float64 {
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
+
+// OptimalM returns the bits needed for n keys at false-positive rate p:
+// m = -n·ln p / (ln 2)²
+func OptimalM(n int, p float64) uint64 {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	return max(uint64(m), 1)
+}
+
+// OptimalK returns the number of hashes that minimizes the
+// false-positive rate for m bits and n keys: k = (m/n)·ln 2
+func OptimalK(m uint64, n int) int {
+	k := math.Round(float64(m) / float64(max(n, 1)) * math.Ln2)
+	return max(int(k), 1)
+}
+
+// Hashing
+// =======
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// fnv1a is hash/fnv's New64a without the hash.Hash interface, which
+// would cost an allocation per key. The type parameter lets strings be
+// hashed without copying them into a []byte.
+func fnv1a[T []byte | string](key T) uint64 {
+	h := uint64(fnvOffset)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= fnvPrime
+	}
+	return h
+}
+
+// avalanche is xxhash64's final mix: every input bit flips about half
+// the output bits
+func avalanche(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xc2b2ae3d27d4eb4f
+	h ^= h >> 29
+	h *= 0x165667b19e3779f9
+	h ^= h >> 32
+	return h
+}
+
+// hashes returns the two hashes for double hashing. h2 is forced odd so
+// the k probes never collapse onto one bit when m is a power of two.
+func hashes[T []byte | string](key T) (h1, h2 uint64) {
+	h1 = avalanche(fnv1a(key))
+	h2 = avalanche(h1) | 1
+	return h1, h2
+}
@@ -0,0 +1,304 @@
+package bloom
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// Bloom Filter - Tests, Memory and Benchmarks
+// ===========================================
+// Run with:
+//
+//   cd slices-maps/bloom
+//   go test -v *.go
+//   go test -bench . -benchmem *.go
+
+// key returns a distinct key per i. Members use even i, non-members
+// odd, so the two sets never overlap.
+func key(i int) string {
+	return "user:" + strconv.Itoa(i)
+}
+
+// 1. The Math
+// ===========
+
+func TestOptimalParameters(t *testing.T) {
+	// The classic table: bits and hashes per key for a target rate
+	tests := []struct {
+		p          float64
+		bitsPerKey float64
+		k          int
+	}{
+		{0.1, 4.79, 3},
+		{0.01, 9.59, 7},
+		{0.001, 14.38, 10},
+	}
+	const n = 1_000_000
+	for _, tt := range tests {
+		m := OptimalM(n, tt.p)
+		if got := float64(m) / n; math.Abs(got-tt.bitsPerKey) > 0.01 {
+			t.Errorf("p=%g: %.2f bits per key, want %.2f", tt.p, got, tt.bitsPerKey)
+		}
+		if got := OptimalK(m, n); got != tt.k {
+			t.Errorf("p=%g: k = %d, want %d", tt.p, got, tt.k)
+		}
+		// Plugging the optimum back in gives the target rate
+		if got := FalsePositiveRate(m, n, OptimalK(m, n)); math.Abs(got-tt.p)/tt.p > 0.05 {
+			t.Errorf("p=%g: FalsePositiveRate = %g", tt.p, got)
+		}
+	}
+}
+
+func TestOptimalKIsAMinimum(t *testing.T) {
+	// Too few hashes check too few bits; too many fill the filter. The
+	// rate is lowest at the optimal k.
+	const m, n = 10_000, 1_000
+	best := OptimalK(m, n)
+	for k := 1; k <= 20; k++ {
+		if k != best && FalsePositiveRate(m, n, k) < FalsePositiveRate(m, n, best) {
+			t.Errorf("k=%d beats the optimal k=%d", k, best)
+		}
+	}
+}
+
+// 2. No False Negatives
+// =====================
+
+func TestNoFalseNegatives(t *testing.T) {
+	f := NewWithEstimates(10_000, 0.01)
+	for i := 0; i < 20_000; i += 2 {
+		f.AddString(key(i))
+	}
+	for i := 0; i < 20_000; i += 2 {
+		if !f.TestString(key(i)) {
+			t.Fatalf("%s was added but Test says no", key(i))
+		}
+		if !f.Test([]byte(key(i))) {
+			t.Fatalf("string and []byte keys must hash the same")
+		}
+	}
+}
+
+func TestEmptyFilterRejectsEverything(t *testing.T) {
+	f := New(1024, 4)
+	for i := range 1000 {
+		if f.TestString(key(i)) {
+			t.Fatalf("an empty filter has no set bits, but %s matched", key(i))
+		}
+	}
+	if f.EstimatedFPR() != 0 || f.FillRatio() != 0 {
+		t.Error("an empty filter should have rate 0 and no bits set")
+	}
+}
+
+// 3. The Math, Measured
+// =====================
+
+func TestEmpiricalFalsePositiveRate(t *testing.T) {
+	// Fill filters of different shapes, probe 200,000 keys that were
+	// never added, and compare the measured rate with the formula
+	tests := []struct {
+		name string
+		m    uint64
+		k    int
+	}{
+		{"optimal 1%", OptimalM(50_000, 0.01), 7},
+		{"optimal 0.1%", OptimalM(50_000, 0.001), 10},
+		{"k too small", OptimalM(50_000, 0.01), 2},
+		{"k too large", OptimalM(50_000, 0.01), 15},
+		{"power-of-two m", 1 << 19, 7},
+	}
+	const n, probes = 50_000, 200_000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(tt.m, tt.k)
+			for i := range n {
+				f.AddString(key(2 * i))
+			}
+			fp := 0
+			for i := range probes {
+				if f.TestString(key(2*i + 1)) {
+					fp++
+				}
+			}
+			measured, want := float64(fp)/probes, f.EstimatedFPR()
+			t.Logf("m=%d k=%d: measured %.4f%%, formula %.4f%%", tt.m, tt.k, measured*100, want*100)
+
+			// Allow 4 standard deviations of a binomial count, plus 10%
+			// for the approximation in the formula
+			sigma := math.Sqrt(want*(1-want)/probes) * 4
+			if math.Abs(measured-want) > sigma+want*0.1 {
+				t.Errorf("measured %.5f, want %.5f ± %.5f", measured, want, sigma+want*0.1)
+			}
+		})
+	}
+}
+
+func TestOverfilling(t *testing.T) {
+	// Sized for 1,000 keys; add 10,000. Nothing breaks, but nearly every
+	// bit is set and the filter says "maybe" to almost anything.
+	f := NewWithEstimates(1_000, 0.01)
+	for i := range 10_000 {
+		f.AddString(key(2 * i))
+	}
+	fp := 0
+	for i := range 10_000 {
+		if f.TestString(key(2*i + 1)) {
+			fp++
+		}
+	}
+	t.Logf("fill %.1f%%, false positives %.1f%%", f.FillRatio()*100, float64(fp)/100)
+	if f.FillRatio() < 0.99 || fp < 9_000 {
+		t.Errorf("an overfilled filter should be nearly all ones")
+	}
+}
+
+func TestApproxCount(t *testing.T) {
+	// The fill ratio reveals how many distinct keys went in, even though
+	// the keys themselves are gone. Duplicates set no new bits.
+	f := NewWithEstimates(100_000, 0.01)
+	for i := range 60_000 {
+		f.AddString(key(i))
+		f.AddString(key(i)) // duplicate
+	}
+	got := f.ApproxCount()
+	t.Logf("Added %d, ApproxCount %.0f", f.Added(), got)
+	if math.Abs(got-60_000)/60_000 > 0.02 {
+		t.Errorf("ApproxCount = %.0f, want about 60000", got)
+	}
+}
+
+func TestFillRatioAtOptimum(t *testing.T) {
+	// At the optimal k a full filter has half its bits set: each probe
+	// is a coin flip, which is where the information per bit is highest.
+	// k is rounded to a whole number, so the expected fill, 1 - e^(-kn/m),
+	// lands near 0.5 rather than on it.
+	const n = 100_000
+	f := NewWithEstimates(n, 0.01)
+	for i := range n {
+		f.AddString(key(i))
+	}
+	want := 1 - math.Exp(-float64(f.K())*n/float64(f.M()))
+	got := f.FillRatio()
+	t.Logf("fill ratio %.3f, expected %.3f", got, want)
+	if math.Abs(got-want) > 0.005 || math.Abs(got-0.5) > 0.05 {
+		t.Errorf("fill ratio %.3f, want %.3f (about half)", got, want)
+	}
+}
+
+// 4. Memory: Filter vs Map
+// ========================
+
+func TestMemoryVersusMap(t *testing.T) {
+	const n = 100_000
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = key(i)
+	}
+
+	var set map[string]struct{}
+	mapBytes := liveBytes(func() {
+		set = make(map[string]struct{}, n)
+		for _, k := range keys {
+			set[k] = struct{}{}
+		}
+	})
+	var f *Filter
+	filterBytes := liveBytes(func() {
+		f = NewWithEstimates(n, 0.01)
+		for _, k := range keys {
+			f.AddString(k)
+		}
+	})
+	runtime.KeepAlive(keys)
+	runtime.KeepAlive(set)
+	runtime.KeepAlive(f)
+
+	// The map holds 16-byte string headers in its slots; the key bytes
+	// are shared with keys, so this is the map's own cost only
+	t.Logf("%d keys", n)
+	t.Logf("map[string]struct{}: %8d bytes (%.1f per key)", mapBytes, float64(mapBytes)/n)
+	t.Logf("bloom filter (1%%):   %8d bytes (%.1f per key)", filterBytes, float64(filterBytes)/n)
+	if filterBytes*10 > mapBytes {
+		t.Errorf("expected the filter to be over 10x smaller than the map")
+	}
+}
+
+// liveBytes returns how much the live heap grew while f ran
+func liveBytes(f func()) int64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	return int64(after.HeapAlloc) - int64(before.HeapAlloc)
+}
+
+// 5. Benchmarks
+// =============
+
+var (
+	benchKeys = func() []string {
+		keys := make([]string, 1<<16)
+		for i := range keys {
+			keys[i] = key(i)
+		}
+		return keys
+	}()
+	boolSink bool
+)
+
+func BenchmarkAdd(b *testing.B) {
+	f := NewWithEstimates(len(benchKeys), 0.01)
+	i := 0
+	for b.Loop() {
+		f.AddString(benchKeys[i&(len(benchKeys)-1)])
+		i++
+	}
+}
+
+func BenchmarkTest(b *testing.B) {
+	f := NewWithEstimates(len(benchKeys), 0.01)
+	for _, k := range benchKeys[:len(benchKeys)/2] {
+		f.AddString(k)
+	}
+	i := 0
+	for b.Loop() {
+		boolSink = f.TestString(benchKeys[i&(len(benchKeys)-1)]) // half members
+		i++
+	}
+}
+
+func BenchmarkMapLookup(b *testing.B) {
+	set := make(map[string]struct{}, len(benchKeys)/2)
+	for _, k := range benchKeys[:len(benchKeys)/2] {
+		set[k] = struct{}{}
+	}
+	i := 0
+	for b.Loop() {
+		_, boolSink = set[benchKeys[i&(len(benchKeys)-1)]]
+		i++
+	}
+}
+
+// Examples
+// ========
+
+func ExampleFilter() {
+	// Skip a slow lookup for usernames that are certainly free
+	taken := NewWithEstimates(1000, 0.01)
+	for _, name := range []string{"gopher", "rob", "ken", "robert"} {
+		taken.AddString(name)
+	}
+	fmt.Println(taken.M(), "bits,", taken.K(), "hashes")
+	fmt.Println("gopher maybe taken:", taken.TestString("gopher"))
+	fmt.Println("newbie maybe taken:", taken.TestString("newbie"))
+	// Output:
+	// 9586 bits, 7 hashes
+	// gopher maybe taken: true
+	// newbie maybe taken: false
+}
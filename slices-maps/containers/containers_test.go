@@ -0,0 +1,344 @@
+package containers
+
+import (
+	"container/heap"
+	"container/list"
+	"container/ring"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// container/heap, list and ring - Lessons and Tests
+// =================================================
+// Run with:
+//
+//   cd slices-maps/containers
+//   go test -v *.go
+
+// 1. container/heap Directly
+// ==========================
+
+// intHeap is the minimal heap.Interface: sort.Interface plus Push and
+// Pop on a pointer receiver. This is what PriorityQueue hides.
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func TestRawHeap(t *testing.T) {
+	h := &intHeap{5, 2, 8}
+	heap.Init(h) // O(n): heapify an existing slice
+	heap.Push(h, 1)
+
+	// The minimum is always at index 0, but the rest is not sorted
+	if (*h)[0] != 1 {
+		t.Fatalf("h[0] = %d, want the minimum 1", (*h)[0])
+	}
+
+	var got []int
+	for h.Len() > 0 {
+		got = append(got, heap.Pop(h).(int)) // heap.Pop, not h.Pop
+	}
+	if !slices.Equal(got, []int{1, 2, 5, 8}) {
+		t.Errorf("pops = %v", got)
+	}
+}
+
+// 2. PriorityQueue[T]
+// ===================
+
+func TestPriorityQueueOrder(t *testing.T) {
+	q := NewPriorityQueue(func(a, b int) bool { return a < b })
+	r := rand.New(rand.NewPCG(1, 2))
+	want := make([]int, 500)
+	for i := range want {
+		want[i] = r.IntN(100)
+		q.Push(want[i])
+	}
+	slices.Sort(want)
+
+	got := make([]int, 0, len(want))
+	for q.Len() > 0 {
+		v, _ := q.Pop()
+		got = append(got, v)
+	}
+	if !slices.Equal(got, want) {
+		t.Error("popping everything should yield sorted order (heap sort)")
+	}
+	if _, ok := q.Pop(); ok {
+		t.Error("Pop on an empty queue should report !ok")
+	}
+}
+
+func TestPriorityQueueMaxHeap(t *testing.T) {
+	q := NewPriorityQueue(func(a, b string) bool { return a > b })
+	for _, s := range []string{"b", "d", "a", "c"} {
+		q.Push(s)
+	}
+	if top, _ := q.Peek(); top != "d" {
+		t.Errorf("Peek = %q, want d", top)
+	}
+	if q.Len() != 4 {
+		t.Error("Peek must not remove")
+	}
+}
+
+func TestPriorityQueueUpdateAndRemove(t *testing.T) {
+	type task struct {
+		name string
+		pri  int
+	}
+	q := NewPriorityQueue(func(a, b task) bool { return a.pri > b.pri })
+	low := q.Push(task{"low", 1})
+	mid := q.Push(task{"mid", 5})
+	q.Push(task{"high", 9})
+
+	// Raising a priority moves the item up in O(log n)
+	q.Update(low, task{"low", 10})
+	if top, _ := q.Peek(); top.name != "low" {
+		t.Errorf("after Update, top = %q, want low", top.name)
+	}
+
+	if !q.Remove(mid) {
+		t.Fatal("Remove(mid) failed")
+	}
+	if q.Remove(mid) {
+		t.Error("removing twice should report false")
+	}
+
+	var names []string
+	for q.Len() > 0 {
+		v, _ := q.Pop()
+		names = append(names, v.name)
+	}
+	if strings.Join(names, " ") != "low high" {
+		t.Errorf("order = %v", names)
+	}
+
+	// A popped handle is dead: Update changes the value but not the queue
+	q.Update(low, task{"low", 0})
+	if q.Len() != 0 {
+		t.Error("Update on a popped item must not re-add it")
+	}
+}
+
+// 3. Worked Example: Scheduling
+// =============================
+
+func TestSchedule(t *testing.T) {
+	ms := time.Millisecond
+	jobs := []Job{
+		{"backup", 0, 30 * ms, 1},
+		{"email", 0, 10 * ms, 5},
+		{"report", 5 * ms, 10 * ms, 3},
+		{"alert", 12 * ms, 5 * ms, 9},
+		{"cleanup", 100 * ms, 10 * ms, 1},
+	}
+	runs := Schedule(jobs, 2)
+
+	var got []string
+	for _, r := range runs {
+		got = append(got, fmt.Sprintf("%s@w%d %v-%v", r.Job.Name, r.Worker, r.Start, r.End))
+	}
+	want := []string{
+		"email@w0 0s-10ms",       // highest priority at t=0
+		"backup@w1 0s-30ms",      // second worker takes the other released job
+		"report@w0 10ms-20ms",    // released at 5ms, waits for w0
+		"alert@w0 20ms-25ms",     // released at 12ms while both workers are busy
+		"cleanup@w0 100ms-110ms", // workers idle until its release
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("schedule:\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestSchedulePriorityBeatsArrival(t *testing.T) {
+	ms := time.Millisecond
+	jobs := []Job{
+		{"busy", 0, 10 * ms, 0},
+		{"early-low", 1 * ms, ms, 1},
+		{"late-high", 2 * ms, ms, 7},
+	}
+	runs := Schedule(jobs, 1)
+	if runs[1].Job.Name != "late-high" {
+		t.Errorf("second run = %s: when the worker frees up, priority wins over release time", runs[1].Job.Name)
+	}
+}
+
+func TestScheduleInvariants(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	jobs := make([]Job, 200)
+	for i := range jobs {
+		jobs[i] = Job{
+			Name:     fmt.Sprint(i),
+			Release:  time.Duration(r.IntN(1000)) * time.Millisecond,
+			Duration: time.Duration(1+r.IntN(50)) * time.Millisecond,
+			Priority: r.IntN(5),
+		}
+	}
+	runs := Schedule(jobs, 4)
+	if len(runs) != len(jobs) {
+		t.Fatalf("%d runs for %d jobs", len(runs), len(jobs))
+	}
+
+	busyUntil := map[int]time.Duration{}
+	for i, run := range runs {
+		if run.Start < run.Job.Release {
+			t.Errorf("%s started at %v before its release %v", run.Job.Name, run.Start, run.Job.Release)
+		}
+		if run.Start < busyUntil[run.Worker] {
+			t.Errorf("worker %d double-booked at %v", run.Worker, run.Start)
+		}
+		busyUntil[run.Worker] = run.End
+		if i > 0 && run.Start < runs[i-1].Start {
+			t.Error("runs should be in start order")
+		}
+	}
+}
+
+// 4. container/list
+// =================
+
+func TestList(t *testing.T) {
+	// A doubly linked list with O(1) insert and remove at a known
+	// element. Values are any, so reading needs a type assertion.
+	l := list.New()
+	a := l.PushBack("a")
+	l.PushBack("b")
+	c := l.PushBack("c")
+
+	l.MoveToFront(c) // the core move of an LRU cache
+	l.Remove(a)
+
+	if got := listStrings(l); got != "c b" {
+		t.Errorf("list = %q", got)
+	}
+}
+
+func TestListRemoveWhileIterating(t *testing.T) {
+	l := list.New()
+	for i := range 5 {
+		l.PushBack(i)
+	}
+
+	// Remove clears e.Next(), so save it first. Using e = e.Next() after
+	// Remove(e) would stop the loop after the first removal.
+	for e := l.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(int)%2 == 0 {
+			l.Remove(e)
+		}
+		e = next
+	}
+	if got := listStrings(l); got != "1 3" {
+		t.Errorf("after removing evens: %q", got)
+	}
+}
+
+func listStrings(l *list.List) string {
+	var parts []string
+	for e := l.Front(); e != nil; e = e.Next() {
+		parts = append(parts, fmt.Sprint(e.Value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// 5. container/ring
+// =================
+
+func TestRingKeepsLastN(t *testing.T) {
+	// A ring is a circular list with no beginning: writing and moving on
+	// keeps the last N values, the classic "recent history" buffer
+	r := ring.New(3)
+	for i := 1; i <= 5; i++ {
+		r.Value = i
+		r = r.Next()
+	}
+
+	var got []int
+	r.Do(func(v any) { got = append(got, v.(int)) }) // oldest first
+	if !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("ring = %v, want the last three", got)
+	}
+	if r.Len() != 3 {
+		t.Errorf("Len = %d", r.Len())
+	}
+}
+
+// 6. Benchmarks
+// =============
+// Pointer-chasing lists lose to slices for most workloads: compare
+// go test -bench . *.go
+
+func BenchmarkListIterate(b *testing.B) {
+	l := list.New()
+	for i := range 10_000 {
+		l.PushBack(i)
+	}
+	for b.Loop() {
+		sum := 0
+		for e := l.Front(); e != nil; e = e.Next() {
+			sum += e.Value.(int)
+		}
+	}
+}
+
+func BenchmarkSliceIterate(b *testing.B) {
+	s := make([]int, 10_000)
+	for i := range s {
+		s[i] = i
+	}
+	for b.Loop() {
+		sum := 0
+		for _, v := range s {
+			sum += v
+		}
+	}
+}
+
+func BenchmarkPriorityQueue(b *testing.B) {
+	q := NewPriorityQueue(func(a, b int) bool { return a < b })
+	r := rand.New(rand.NewPCG(1, 1))
+	for range 1000 {
+		q.Push(r.IntN(1 << 20))
+	}
+	for b.Loop() {
+		v, _ := q.Pop()
+		q.Push(v + r.IntN(1000))
+	}
+}
+
+// Examples
+// ========
+
+func ExamplePriorityQueue() {
+	type ticket struct {
+		id       int
+		severity int
+	}
+	q := NewPriorityQueue(func(a, b ticket) bool { return a.severity > b.severity })
+	q.Push(ticket{1, 2})
+	q.Push(ticket{2, 5})
+	q.Push(ticket{3, 1})
+
+	for q.Len() > 0 {
+		t, _ := q.Pop()
+		fmt.Printf("ticket %d (severity %d)\n", t.id, t.severity)
+	}
+	// Output:
+	// ticket 2 (severity 5)
+	// ticket 1 (severity 2)
+	// ticket 3 (severity 1)
+}
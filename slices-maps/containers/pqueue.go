@@ -0,0 +1,115 @@
+package containers
+
+import (
+	"container/heap"
+)
+
+// container/heap, list and ring - Typed Wrappers
+// ==============================================
+// The container packages predate generics: container/heap works through
+// an interface you implement, and list and ring store values as any.
+// PriorityQueue wraps heap so callers get a typed API with no interface
+// boilerplate and no type assertions.
+//
+// container/heap is the one worth knowing well: it turns any slice into
+// a binary min-heap with O(log n) Push, Pop, Fix and Remove. For list
+// and ring a slice is usually faster; see containers_test.go.
+
+// Item is a handle to a queued value, used to change its priority or
+// remove it. It is valid until the value is popped or removed.
+type Item[T any] struct {
+	Value T
+	index int // position in the heap, -1 once removed
+}
+
+// PriorityQueue is a min-heap ordered by less: Pop returns the value
+// for which less reports true against every other. The zero value is
+// not usable; create one with NewPriorityQueue.
+type PriorityQueue[T any] struct {
+	h *pqHeap[T]
+}
+
+// NewPriorityQueue returns an empty queue. For a max-heap, pass a less
+// that reports a > b.
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{h: &pqHeap[T]{less: less}}
+}
+
+// Len returns the number of queued values
+func (q *PriorityQueue[T]) Len() int { return q.h.Len() }
+
+// Push adds v and returns a handle for Update and Remove
+func (q *PriorityQueue[T]) Push(v T) *Item[T] {
+	it := &Item[T]{Value: v}
+	heap.Push(q.h, it)
+	return it
+}
+
+// Pop removes and returns the smallest value. ok is false if the queue
+// is empty.
+func (q *PriorityQueue[T]) Pop() (v T, ok bool) {
+	if q.h.Len() == 0 {
+		return v, false
+	}
+	return heap.Pop(q.h).(*Item[T]).Value, true
+}
+
+// Peek returns the smallest value without removing it
+func (q *PriorityQueue[T]) Peek() (v T, ok bool) {
+	if q.h.Len() == 0 {
+		return v, false
+	}
+	return q.h.items[0].Value, true
+}
+
+// Update replaces the value of a queued item and restores heap order in
+// O(log n), instead of removing and pushing again
+func (q *PriorityQueue[T]) Update(it *Item[T], v T) {
+	it.Value = v
+	if it.index >= 0 {
+		heap.Fix(q.h, it.index)
+	}
+}
+
+// Remove deletes a queued item. It reports false if the item was
+// already popped or removed.
+func (q *PriorityQueue[T]) Remove(it *Item[T]) bool {
+	if it.index < 0 {
+		return false
+	}
+	heap.Remove(q.h, it.index)
+	return true
+}
+
+// pqHeap implements heap.Interface. The methods are only called by the
+// heap package; callers use PriorityQueue.
+type pqHeap[T any] struct {
+	items []*Item[T]
+	less  func(a, b T) bool
+}
+
+func (h *pqHeap[T]) Len() int           { return len(h.items) }
+func (h *pqHeap[T]) Less(i, j int) bool { return h.less(h.items[i].Value, h.items[j].Value) }
+
+func (h *pqHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+// Push and Pop only append and truncate; heap.Push and heap.Pop do the
+// sifting around them
+func (h *pqHeap[T]) Push(x any) {
+	it := x.(*Item[T])
+	it.index = len(h.items)
+	h.items = append(h.items, it)
+}
+
+func (h *pqHeap[T]) Pop() any {
+	n := len(h.items) - 1
+	it := h.items[n]
+	h.items[n] = nil // let the GC collect popped items
+	h.items = h.items[:n]
+	it.index = -1
+	return it
+}
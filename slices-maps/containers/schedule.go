@@ -0,0 +1,85 @@
+package containers
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Worked Example - Scheduling Jobs on Workers
+// ===========================================
+// Jobs are released over time and run on a fixed number of workers.
+// When a worker is free it takes the highest-priority released job;
+// ties go to the job released first. Two priority queues do the work:
+// one of released jobs by priority, and one of busy workers by the time
+// they become free.
+
+// Job is a unit of work. Higher Priority runs first.
+type Job struct {
+	Name     string
+	Release  time.Duration // earliest start, from time zero
+	Duration time.Duration
+	Priority int
+}
+
+// Run records when and where a job ran
+type Run struct {
+	Job    Job
+	Worker int
+	Start  time.Duration
+	End    time.Duration
+}
+
+// Schedule simulates running jobs on workers and returns the runs in
+// start order. It panics if workers < 1.
+func Schedule(jobs []Job, workers int) []Run {
+	if workers < 1 {
+		panic("containers: need at least one worker")
+	}
+	pending := slices.SortedStableFunc(slices.Values(jobs), func(a, b Job) int {
+		return cmp.Compare(a.Release, b.Release)
+	})
+
+	ready := NewPriorityQueue(func(a, b Job) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.Release < b.Release
+	})
+
+	type worker struct {
+		id     int
+		freeAt time.Duration
+	}
+	idle := NewPriorityQueue(func(a, b worker) bool {
+		if a.freeAt != b.freeAt {
+			return a.freeAt < b.freeAt
+		}
+		return a.id < b.id
+	})
+	for id := range workers {
+		idle.Push(worker{id: id})
+	}
+
+	var runs []Run
+	var now time.Duration // simulation clock; never moves backwards
+	for len(pending) > 0 || ready.Len() > 0 {
+		w, _ := idle.Pop() // earliest free worker
+		now = max(now, w.freeAt)
+
+		// Nothing released yet: the worker waits for the next release
+		if ready.Len() == 0 && pending[0].Release > now {
+			now = pending[0].Release
+		}
+		for len(pending) > 0 && pending[0].Release <= now {
+			ready.Push(pending[0])
+			pending = pending[1:]
+		}
+
+		job, _ := ready.Pop()
+		run := Run{Job: job, Worker: w.id, Start: now, End: now + job.Duration}
+		runs = append(runs, run)
+		idle.Push(worker{id: w.id, freeAt: run.End})
+	}
+	return runs
+}
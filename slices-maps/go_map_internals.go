@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Go Map Internals - Iteration Order, Growth and Swiss Tables
+// ===========================================================
+// This file demonstrates randomized iteration order, which types can be
+// map keys, how a map's memory grows (measured with runtime.MemStats),
+// why deleting does not give memory back, and the Swiss-table map
+// implementation that replaced the bucket map in Go 1.24.
+//
+// Run with:
+//   go run go_map_internals.go
+//
+// For the "before" numbers, run the same file with a pre-Swiss toolchain
+// (Go 1.23 or earlier) and compare the benchmark section:
+//   GOTOOLCHAIN=go1.23.12 go run go_map_internals.go
+
+// Benchmark sinks keep results alive so the compiler cannot drop the work
+var (
+	intSink  int
+	boolSink bool
+	mapSink  map[int64]int64
+)
+
+func main() {
+	fmt.Println("=== Go Map Internals ===")
+	fmt.Printf("   %s, %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	// 1. Iteration order
+	iterationOrder()
+
+	// 2. Allowed keys
+	allowedKeys()
+
+	// 3. Growth and load factor
+	growth()
+
+	// 4. Delete and tombstones
+	deleteAndTombstones()
+
+	// 5. Swiss tables
+	swissTables()
+}
+
+// 1. Iteration Order
+// ==================
+func iterationOrder() {
+	fmt.Println("\n1. ITERATION ORDER:")
+
+	// The spec leaves map order undefined, and the runtime starts each
+	// range loop at a random position so code cannot come to depend on
+	// an order that happens to be stable
+	m := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+	firsts := map[string]int{}
+	for range 1000 {
+		for k := range m {
+			firsts[k]++
+			break
+		}
+	}
+	fmt.Printf("   first key over 1000 loops: %v\n", firsts)
+
+	// Two loops over the same unchanged map can disagree
+	fmt.Printf("   order 1: %s\n", keyOrder(m))
+	fmt.Printf("   order 2: %s\n", keyOrder(m))
+
+	// fmt sorts map keys when printing, which is why %v output is stable
+	// and golden tests of printed maps work
+	fmt.Printf("   fmt sorts keys: %v\n", m)
+	fmt.Println("   For a fixed order: slices.Sorted(maps.Keys(m))")
+}
+
+// 2. Allowed Keys
+// ===============
+func allowedKeys() {
+	fmt.Println("\n2. ALLOWED KEYS:")
+
+	// A key type must be comparable with ==. Slices, maps and functions
+	// are not, so these fail to compile:
+	//
+	//   map[[]int]bool{}        // invalid map key type []int
+	//   map[func()]bool{}       // invalid map key type func()
+	//   map[map[int]int]bool{}  // invalid map key type map[int]int
+
+	// Arrays and structs of comparable fields are fine, and compare by
+	// value - a natural composite key
+	type cell struct{ row, col int }
+	grid := map[cell]string{{0, 0}: "origin", {2, 3}: "treasure"}
+	fmt.Printf("   struct key: grid[cell{2, 3}] = %q\n", grid[cell{2, 3}])
+
+	visits := map[[2]string]int{}
+	visits[[2]string{"home", "shop"}]++
+	visits[[2]string{"home", "shop"}]++
+	fmt.Printf("   array key: %v\n", visits)
+
+	// Pointer keys compare by address, not by what they point to
+	a, b := &cell{1, 1}, &cell{1, 1}
+	byPtr := map[*cell]bool{a: true}
+	fmt.Printf("   pointer key: byPtr[b] = %t (same value, different address)\n", byPtr[b])
+
+	// Interface keys compile for any type, but hashing a non-comparable
+	// dynamic type panics at run time
+	anyKeys := map[any]int{}
+	anyKeys[1] = 1
+	anyKeys["1"] = 2 // different types are different keys
+	err := catch(func() { anyKeys[[]int{1}] = 3 })
+	fmt.Printf("   map[any]: %d keys; slice key -> %v\n", len(anyKeys), err)
+
+	// NaN != NaN, so every NaN insert adds a new entry that can never be
+	// looked up again. clear removes them.
+	nan := map[float64]int{}
+	for range 3 {
+		nan[math.NaN()]++
+	}
+	_, found := nan[math.NaN()]
+	fmt.Printf("   NaN keys: len=%d, lookup found=%t\n", len(nan), found)
+	clear(nan)
+	fmt.Printf("   after clear: len=%d\n", len(nan))
+
+	// +0.0 and -0.0 are == so they are the same key
+	zero := map[float64]string{0.0: "zero"}
+	fmt.Printf("   zero[-0.0] = %q\n", zero[math.Copysign(0, -1)])
+}
+
+// 3. Growth and Load Factor
+// =========================
+func growth() {
+	fmt.Println("\n3. GROWTH AND LOAD FACTOR:")
+
+	// A Swiss-table map stores entries in groups of 8 slots and grows a
+	// table when it is 7/8 full. Live memory therefore rises in steps:
+	// flat while slots are free, then a jump when a table doubles. Past
+	// 1024 slots a table splits in two instead, so big maps grow a piece
+	// at a time and the steps disappear.
+	fmt.Println("   entries   live bytes   bytes/entry")
+	for _, n := range []int{56, 57, 448, 449, 896, 897, 1792, 1793} {
+		mapSink = nil
+		before := heapInUse()
+		mapSink = make(map[int64]int64)
+		for i := range n {
+			mapSink[int64(i)] = int64(i)
+		}
+		bytes := int64(heapInUse() - before)
+		fmt.Printf("   %7d %12d %13.1f\n", n, bytes, float64(bytes)/float64(n))
+	}
+	mapSink = nil
+	fmt.Println("   (a key+value pair is 16 bytes; the rest is control words and free slots)")
+
+	// A size hint allocates the final table once instead of growing
+	// through every step on the way
+	const n = 100_000
+	grown := measure(func() {
+		m := map[int64]int64{}
+		for i := range n {
+			m[int64(i)] = 0
+		}
+		runtime.KeepAlive(m)
+	})
+	hinted := measure(func() {
+		m := make(map[int64]int64, n)
+		for i := range n {
+			m[int64(i)] = 0
+		}
+		runtime.KeepAlive(m)
+	})
+	fmt.Printf("   %d inserts: no hint %d bytes allocated, make(map, n) %d bytes\n", n, grown, hinted)
+}
+
+// 4. Delete and Tombstones
+// ========================
+func deleteAndTombstones() {
+	fmt.Println("\n4. DELETE AND TOMBSTONES:")
+
+	// Deleting marks a slot as deleted (a tombstone) when a probe might
+	// need to pass through it, or empty when it cannot. Either way the
+	// table keeps its size: maps never shrink.
+	const n = 1_000_000
+	m := make(map[int]int)
+	for i := range n {
+		m[i] = i
+	}
+	full := heapInUse()
+	for i := range n {
+		delete(m, i)
+	}
+	runtime.GC()
+	emptied := heapInUse()
+	fmt.Printf("   1M entries: %d MB; after deleting all: %d MB (len=%d)\n", full>>20, emptied>>20, len(m))
+
+	// clear(m) also keeps the table. To give memory back, drop the map
+	// (or copy the survivors into a new one) and let the GC reclaim it.
+	m = nil
+	runtime.GC()
+	fmt.Printf("   after m = nil and GC: %d MB\n", heapInUse()>>20)
+
+	// Tombstones also mean a map with heavy insert/delete churn can
+	// trigger a same-size rehash to clean them up, which shows up as an
+	// occasional slow insert rather than growth
+	fmt.Println("   Long-lived caches with churn: rebuild periodically, or use a size-bounded structure")
+}
+
+// 5. Swiss Tables
+// ===============
+func swissTables() {
+	fmt.Println("\n5. SWISS TABLES:")
+
+	// Before Go 1.24: an array of buckets, 8 entries each, plus chained
+	// overflow buckets; the whole array doubled at an average of 6.5
+	// entries per bucket, moving entries gradually during later writes.
+	//
+	// Since Go 1.24: Swiss tables. Each group of 8 slots has a control
+	// word holding 7 bits of each key's hash. A lookup compares all 8
+	// control bytes at once (SIMD where available) and only checks keys
+	// whose bits match, so misses are cheap. Tables hold at most 1024
+	// slots; a bigger map is a directory of tables that split one at a
+	// time, so growth never copies the whole map at once.
+	fmt.Println("   groups of 8 slots, 7-bit hash tags, max load 7/8, tables split independently")
+
+	const n = 1 << 16
+	m := make(map[int]int, n)
+	for i := range n {
+		m[i] = i
+	}
+	strs := make(map[string]int, n)
+	keys := make([]string, n)
+	for i := range n {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		strs[keys[i]] = i
+	}
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"lookup hit (int)", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				intSink += m[i&(n-1)]
+			}
+		}},
+		{"lookup miss (int)", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, boolSink = m[n+i]
+			}
+		}},
+		{"lookup hit (string)", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				intSink += strs[keys[i&(n-1)]]
+			}
+		}},
+		{"insert 64K, no hint", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fresh := map[int]int{}
+				for j := range n {
+					fresh[j] = j
+				}
+				intSink += len(fresh)
+			}
+		}},
+		{"insert 64K, hinted", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fresh := make(map[int]int, n)
+				for j := range n {
+					fresh[j] = j
+				}
+				intSink += len(fresh)
+			}
+		}},
+		{"range 64K", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for k := range m {
+					intSink += k
+				}
+			}
+		}},
+		{"delete+insert churn", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				k := i & (n - 1)
+				delete(m, k)
+				m[k] = k
+			}
+		}},
+	}
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			bm.fn(b)
+		})
+		fmt.Printf("   %-22s %s\n", bm.name, formatResult(r))
+	}
+	fmt.Println("   Run with GOTOOLCHAIN=go1.23.12 for the bucket-map numbers. The Go 1.24")
+	fmt.Println("   release notes report about 2-3% less CPU on average across real programs;")
+	fmt.Println("   map-heavy microbenchmarks like these move much more")
+}
+
+// Helper functions
+// ================
+
+// keyOrder returns the keys of m in the order one range loop visits them
+func keyOrder(m map[string]int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return strings.Join(keys, " ")
+}
+
+// catch runs f and returns the value it panicked with, if any
+func catch(f func()) (err any) {
+	defer func() { err = recover() }()
+	f()
+	return nil
+}
+
+// measure returns the bytes allocated while f runs
+func measure(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// heapInUse returns the bytes of live heap after a collection
+func heapInUse() uint64 {
+	var s runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&s)
+	return s.HeapAlloc
+}
+
+func formatResult(r testing.BenchmarkResult) string {
+	ns := float64(r.T.Nanoseconds()) / float64(r.N)
+	return fmt.Sprintf("%12.1f ns/op %8d B/op %6d allocs/op", ns, r.AllocedBytesPerOp(), r.AllocsPerOp())
+}
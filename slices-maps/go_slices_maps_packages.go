@@ -0,0 +1,257 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Go slices and maps Packages - A Tour
+// ====================================
+// This file demonstrates the generic slices and maps packages: searching,
+// sorting, editing, comparing, and the iterator functions that connect
+// the two. Each section ends with the hand-written loop the call replaces.
+//
+// Run with:
+//   go run go_slices_maps_packages.go
+
+type employee struct {
+	Name string
+	Dept string
+	Age  int
+}
+
+var staff = []employee{
+	{"Alice", "eng", 34},
+	{"Bob", "ops", 29},
+	{"Carol", "eng", 41},
+	{"Dan", "sales", 29},
+	{"Eve", "ops", 38},
+}
+
+func main() {
+	fmt.Println("=== Go slices and maps Packages ===")
+
+	// 1. Searching
+	searching()
+
+	// 2. Sorting
+	sorting()
+
+	// 3. Editing
+	editing()
+
+	// 4. Comparing
+	comparing()
+
+	// 5. Iterators
+	iterators()
+
+	// 6. The maps package
+	mapsPackage()
+}
+
+// 1. Searching
+// ============
+func searching() {
+	fmt.Println("\n1. SEARCHING:")
+
+	langs := []string{"go", "rust", "zig", "c"}
+	fmt.Printf("   Contains(zig)=%t Index(c)=%d\n", slices.Contains(langs, "zig"), slices.Index(langs, "c"))
+
+	// The Func variants take a predicate instead of a value
+	i := slices.IndexFunc(staff, func(e employee) bool { return e.Age > 40 })
+	fmt.Printf("   first over 40: %s\n", staff[i].Name)
+	fmt.Printf("   anyone in sales: %t\n", slices.ContainsFunc(staff, func(e employee) bool { return e.Dept == "sales" }))
+
+	// BinarySearch needs sorted input and returns where the value is, or
+	// where it would be inserted
+	sorted := []int{10, 20, 30, 40}
+	pos, found := slices.BinarySearch(sorted, 30)
+	fmt.Printf("   BinarySearch(30) = %d, %t\n", pos, found)
+	pos, found = slices.BinarySearch(sorted, 25)
+	fmt.Printf("   BinarySearch(25) = %d, %t (insert here)\n", pos, found)
+
+	// BinarySearchFunc searches by a key inside each element
+	byAge := slices.SortedFunc(slices.Values(staff), func(a, b employee) int { return cmp.Compare(a.Age, b.Age) })
+	pos, found = slices.BinarySearchFunc(byAge, 38, func(e employee, age int) int { return cmp.Compare(e.Age, age) })
+	fmt.Printf("   age 38 by binary search: %s (%t)\n", byAge[pos].Name, found)
+
+	// Replaces:
+	//   found := false
+	//   for _, l := range langs { if l == "zig" { found = true; break } }
+}
+
+// 2. Sorting
+// ==========
+func sorting() {
+	fmt.Println("\n2. SORTING:")
+
+	nums := []int{5, 2, 8, 1, 9}
+	slices.Sort(nums)
+	fmt.Printf("   Sort: %v; Min=%d Max=%d\n", nums, slices.Min(nums), slices.Max(nums))
+
+	// SortFunc takes a three-way comparison. cmp.Compare handles the
+	// ordering, and cmp.Or chains keys: the first non-zero result wins.
+	people := slices.Clone(staff)
+	slices.SortFunc(people, func(a, b employee) int {
+		return cmp.Or(
+			cmp.Compare(a.Dept, b.Dept),
+			cmp.Compare(b.Age, a.Age), // descending
+		)
+	})
+	fmt.Printf("   by dept, then oldest first: %s\n", names(people))
+
+	// SortStableFunc keeps equal elements in their original order
+	people = slices.Clone(staff)
+	slices.SortStableFunc(people, func(a, b employee) int { return cmp.Compare(a.Age, b.Age) })
+	fmt.Printf("   stable by age: %s (Bob before Dan, as in the input)\n", names(people))
+
+	// MinFunc and MaxFunc return the first minimum or maximum
+	oldest := slices.MaxFunc(staff, func(a, b employee) int { return cmp.Compare(a.Age, b.Age) })
+	fmt.Printf("   oldest: %s; IsSorted: %t\n", oldest.Name, slices.IsSorted(nums))
+
+	// Replaces:
+	//   sort.Slice(people, func(i, j int) bool {
+	//       if people[i].Dept != people[j].Dept { return people[i].Dept < people[j].Dept }
+	//       return people[i].Age > people[j].Age
+	//   })
+}
+
+// 3. Editing
+// ==========
+func editing() {
+	fmt.Println("\n3. EDITING:")
+
+	// Insert and Delete shift elements and return the new slice, like
+	// append - always use the result
+	s := []string{"a", "b", "e"}
+	s = slices.Insert(s, 2, "c", "d")
+	fmt.Printf("   Insert(2, c, d): %v\n", s)
+	s = slices.Delete(s, 1, 3) // removes s[1:3]
+	fmt.Printf("   Delete(1, 3): %v\n", s)
+
+	// DeleteFunc filters in place. Delete, DeleteFunc and Compact zero
+	// the elements past the new length, so dropped pointers can be
+	// collected.
+	nums := []int{1, 2, 3, 4, 5, 6}
+	tail := nums[:cap(nums)]
+	nums = slices.DeleteFunc(nums, func(n int) bool { return n%2 == 0 })
+	fmt.Printf("   DeleteFunc(even): %v; old tail zeroed: %v\n", nums, tail)
+
+	// Compact removes consecutive duplicates: sort first for unique values
+	words := []string{"go", "c", "go", "zig", "c"}
+	slices.Sort(words)
+	words = slices.Compact(words)
+	fmt.Printf("   Sort + Compact: %v\n", words)
+
+	// CompactFunc uses a custom equality, here case-insensitive
+	tags := slices.CompactFunc([]string{"Go", "go", "GO", "Zig"}, strings.EqualFold)
+	fmt.Printf("   CompactFunc(EqualFold): %v\n", tags)
+
+	// Replace, Reverse, Concat, Repeat and Chunk cover the rest
+	r := slices.Replace([]int{1, 2, 3, 4}, 1, 3, 20, 30, 40)
+	slices.Reverse(r)
+	fmt.Printf("   Replace then Reverse: %v\n", r)
+	fmt.Printf("   Concat: %v; Repeat: %v\n", slices.Concat([]int{1}, []int{2, 3}), slices.Repeat([]int{0}, 3))
+	for chunk := range slices.Chunk([]int{1, 2, 3, 4, 5}, 2) {
+		fmt.Printf("   chunk %v\n", chunk)
+	}
+
+	// Replaces the filter loop:
+	//   out := nums[:0]
+	//   for _, n := range nums { if n%2 != 0 { out = append(out, n) } }
+}
+
+// 4. Comparing
+// ============
+func comparing() {
+	fmt.Println("\n4. COMPARING:")
+
+	a, b := []int{1, 2, 3}, []int{1, 2, 4}
+	fmt.Printf("   Equal: %t; Compare: %d\n", slices.Equal(a, b), slices.Compare(a, b))
+
+	// nil and empty slices are Equal: both have no elements
+	fmt.Printf("   Equal(nil, []int{}): %t\n", slices.Equal(nil, []int{}))
+
+	// EqualFunc compares slices of different types element by element
+	ids := []int{1, 2}
+	labels := []string{"1", "2"}
+	same := slices.EqualFunc(ids, labels, func(n int, s string) bool { return fmt.Sprint(n) == s })
+	fmt.Printf("   EqualFunc(ints, strings): %t\n", same)
+
+	// Replaces:
+	//   if len(a) != len(b) { return false }
+	//   for i := range a { if a[i] != b[i] { return false } }
+	//   return true
+}
+
+// 5. Iterators
+// ============
+func iterators() {
+	fmt.Println("\n5. ITERATORS:")
+
+	// All, Values and Backward produce iter.Seq / iter.Seq2 values that a
+	// range loop can consume without building a new slice
+	for i, v := range slices.Backward([]string{"a", "b", "c"}) {
+		fmt.Printf("   Backward: %d=%s\n", i, v)
+	}
+
+	// Collect builds a slice from a sequence; Sorted sorts it too
+	ages := map[string]int{"Carol": 41, "Alice": 34, "Bob": 29}
+	fmt.Printf("   Sorted(maps.Keys): %v\n", slices.Sorted(maps.Keys(ages)))
+	fmt.Printf("   Sorted(maps.Values): %v\n", slices.Sorted(maps.Values(ages)))
+
+	// AppendSeq appends a sequence to an existing slice
+	all := slices.AppendSeq([]string{"Zed"}, maps.Keys(ages))
+	fmt.Printf("   AppendSeq length: %d\n", len(all))
+
+	// Replaces:
+	//   keys := make([]string, 0, len(ages))
+	//   for k := range ages { keys = append(keys, k) }
+	//   sort.Strings(keys)
+}
+
+// 6. The maps Package
+// ===================
+func mapsPackage() {
+	fmt.Println("\n6. THE MAPS PACKAGE:")
+
+	stock := map[string]int{"apple": 5, "pear": 0, "plum": 3}
+
+	// Clone is a shallow copy; Copy merges src into dst, overwriting
+	backup := maps.Clone(stock)
+	maps.Copy(stock, map[string]int{"fig": 7, "plum": 4})
+	fmt.Printf("   after Copy: %v; backup: %v\n", stock, backup)
+
+	// DeleteFunc removes matching entries during the scan
+	maps.DeleteFunc(stock, func(_ string, n int) bool { return n == 0 })
+	fmt.Printf("   DeleteFunc(out of stock): %v\n", stock)
+
+	// Equal compares keys and values; EqualFunc takes a value comparison
+	fmt.Printf("   Equal(stock, backup): %t\n", maps.Equal(stock, backup))
+
+	// Collect builds a map from an iter.Seq2, and Insert adds one to an
+	// existing map. Together with slices.All they index a slice.
+	byIndex := maps.Collect(slices.All([]string{"zero", "one"}))
+	fmt.Printf("   Collect(slices.All): %v\n", byIndex)
+
+	maps.Insert(byIndex, maps.All(map[int]string{2: "two"}))
+	fmt.Printf("   after Insert: %v\n", byIndex)
+
+	// Replaces:
+	//   clone := make(map[string]int, len(stock))
+	//   for k, v := range stock { clone[k] = v }
+}
+
+// Helper functions
+// ================
+func names(es []employee) string {
+	out := make([]string, len(es))
+	for i, e := range es {
+		out[i] = e.Name
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Go Sorting - sort.Interface, sort.Slice and slices.SortFunc
+// ===========================================================
+// This file compares the three ways to sort in Go, shows what stability
+// means and when it matters, builds multi-key comparators with
+// cmp.Compare, and benchmarks the approaches against each other.
+//
+// Run with:
+//   go run go_sorting.go
+
+// Benchmark sinks keep results alive so the compiler cannot drop the work
+var boolSink bool
+
+type order struct {
+	ID       int
+	Customer string
+	Total    int // cents
+	Priority bool
+}
+
+func main() {
+	fmt.Println("=== Go Sorting ===")
+
+	// 1. Three ways to sort
+	threeWays()
+
+	// 2. Stable vs unstable
+	stability()
+
+	// 3. Multi-key comparators
+	multiKey()
+
+	// 4. Comparator pitfalls
+	pitfalls()
+
+	// 5. Benchmarks
+	benchmarks()
+}
+
+// 1. Three Ways to Sort
+// =====================
+
+// byTotal implements sort.Interface: the pre-generics way to sort any
+// collection, at the cost of a named type and three methods
+type byTotal []order
+
+func (s byTotal) Len() int           { return len(s) }
+func (s byTotal) Less(i, j int) bool { return s[i].Total < s[j].Total }
+func (s byTotal) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func threeWays() {
+	fmt.Println("\n1. THREE WAYS TO SORT:")
+
+	// sort.Interface: works on anything with Len/Less/Swap, including
+	// types that are not slices. Each comparison is an interface call.
+	a := sampleOrders()
+	sort.Sort(byTotal(a))
+	fmt.Printf("   sort.Sort(byTotal):  %s\n", ids(a))
+
+	// sort.Slice: a less function over indexes. Swapping goes through
+	// reflection, and the closure must index the same slice it sorts.
+	b := sampleOrders()
+	sort.Slice(b, func(i, j int) bool { return b[i].Total < b[j].Total })
+	fmt.Printf("   sort.Slice:          %s\n", ids(b))
+
+	// slices.SortFunc: generic, takes the elements themselves and a
+	// three-way result (<0, 0, >0). The usual choice since Go 1.21.
+	c := sampleOrders()
+	slices.SortFunc(c, func(x, y order) int { return cmp.Compare(x.Total, y.Total) })
+	fmt.Printf("   slices.SortFunc:     %s\n", ids(c))
+
+	// Ordered element types need no comparator at all
+	totals := []int{300, 100, 200}
+	slices.Sort(totals)
+	fmt.Printf("   slices.Sort(ints):   %v\n", totals)
+
+	// sort.Interface is still the tool for sorting something that is not
+	// one slice, like two parallel slices kept in step
+	names := []string{"c", "a", "b"}
+	scores := []int{3, 1, 2}
+	sort.Sort(parallel{names, scores})
+	fmt.Printf("   parallel slices:     %v %v\n", names, scores)
+}
+
+// parallel sorts two slices by the first, moving both together
+type parallel struct {
+	keys []string
+	vals []int
+}
+
+func (p parallel) Len() int           { return len(p.keys) }
+func (p parallel) Less(i, j int) bool { return p.keys[i] < p.keys[j] }
+func (p parallel) Swap(i, j int) {
+	p.keys[i], p.keys[j] = p.keys[j], p.keys[i]
+	p.vals[i], p.vals[j] = p.vals[j], p.vals[i]
+}
+
+// 2. Stable vs Unstable
+// =====================
+func stability() {
+	fmt.Println("\n2. STABLE VS UNSTABLE:")
+
+	// Orders arrive by ID. Sorting by customer should ideally keep each
+	// customer's orders in arrival order - that is what stable means.
+	byCustomer := func(x, y order) int { return cmp.Compare(x.Customer, y.Customer) }
+
+	unstable := manyOrders(200)
+	slices.SortFunc(unstable, byCustomer)
+	fmt.Printf("   SortFunc keeps ID order within each customer:       %t\n", idsAscendingWithin(unstable))
+
+	stable := manyOrders(200)
+	slices.SortStableFunc(stable, byCustomer)
+	fmt.Printf("   SortStableFunc keeps ID order within each customer: %t\n", idsAscendingWithin(stable))
+
+	// Small inputs are sorted with insertion sort, which happens to be
+	// stable - so an unstable sort can look stable in a unit test with
+	// five elements and fail in production with five hundred
+	small := manyOrders(8)
+	slices.SortFunc(small, byCustomer)
+	fmt.Printf("   SortFunc on 8 orders looked stable:                 %t\n", idsAscendingWithin(small))
+
+	// Stability matters when sorting by a second key after a first, or
+	// when equal elements are distinguishable. Otherwise prefer the
+	// faster unstable sort - or make the comparator total by adding a
+	// tie-breaker, which gives a deterministic order either way.
+	tieBroken := manyOrders(200)
+	slices.SortFunc(tieBroken, func(x, y order) int {
+		return cmp.Or(cmp.Compare(x.Customer, y.Customer), cmp.Compare(x.ID, y.ID))
+	})
+	fmt.Printf("   SortFunc with an ID tie-breaker:                    %t\n", idsAscendingWithin(tieBroken))
+}
+
+// 3. Multi-Key Comparators
+// ========================
+func multiKey() {
+	fmt.Println("\n3. MULTI-KEY COMPARATORS:")
+
+	// cmp.Or returns its first non-zero argument, which is exactly "compare
+	// by this key, then by the next". Negate or swap for descending.
+	a := sampleOrders()
+	slices.SortFunc(a, func(x, y order) int {
+		return cmp.Or(
+			-compareBool(x.Priority, y.Priority), // priority first
+			cmp.Compare(y.Total, x.Total),        // then largest total
+			cmp.Compare(x.ID, y.ID),              // then oldest
+		)
+	})
+	for _, o := range a {
+		fmt.Printf("   %v\n", o)
+	}
+
+	// Case-insensitive keys: compare lowered copies. ToLower allocates
+	// for mixed-case input, so precompute keys for large inputs.
+	names := []string{"bob", "Alice", "carol", "Bob"}
+	slices.SortStableFunc(names, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	fmt.Printf("   case-insensitive: %v\n", names)
+
+	// The same comparator searches the sorted result
+	slices.SortFunc(a, func(x, y order) int { return cmp.Compare(x.ID, y.ID) })
+	i, found := slices.BinarySearchFunc(a, 4, func(o order, id int) int { return cmp.Compare(o.ID, id) })
+	fmt.Printf("   BinarySearchFunc(ID 4): index %d, found %t\n", i, found)
+}
+
+// 4. Comparator Pitfalls
+// ======================
+func pitfalls() {
+	fmt.Println("\n4. COMPARATOR PITFALLS:")
+
+	// Subtraction overflows: for large values a-b wraps and flips sign
+	x, y := int64(-9_000_000_000_000_000_000), int64(1_000_000_000_000_000_000)
+	fmt.Printf("   x-y for x<y gives %d (positive: wrong), cmp.Compare gives %d\n", x-y, cmp.Compare(x, y))
+
+	// NaN breaks a < b orderings; cmp.Compare puts NaNs first
+	floats := []float64{3, math.NaN(), 1, 2}
+	slices.SortFunc(floats, cmp.Compare[float64])
+	fmt.Printf("   floats with NaN via cmp.Compare: %v\n", floats)
+
+	// A less function that is not a strict weak order (here <= instead
+	// of <) is not detected; the result is just unspecified
+	fmt.Println("   Less must be strict: use <, never <=")
+}
+
+// 5. Benchmarks
+// =============
+func benchmarks() {
+	fmt.Println("\n5. BENCHMARKS (10,000 orders by Total):")
+
+	base := manyOrders(10_000)
+	rand.New(rand.NewPCG(1, 2)).Shuffle(len(base), func(i, j int) { base[i], base[j] = base[j], base[i] })
+	work := make([]order, len(base))
+	totals := make([]int, len(base))
+	baseTotals := make([]int, len(base))
+	for i, o := range base {
+		baseTotals[i] = o.Total
+	}
+
+	byTotalFunc := func(x, y order) int { return cmp.Compare(x.Total, y.Total) }
+	benchmarks := []struct {
+		name string
+		fn   func()
+	}{
+		{"sort.Sort (interface)", func() { sort.Sort(byTotal(work)) }},
+		{"sort.Slice", func() { sort.Slice(work, func(i, j int) bool { return work[i].Total < work[j].Total }) }},
+		{"sort.SliceStable", func() { sort.SliceStable(work, func(i, j int) bool { return work[i].Total < work[j].Total }) }},
+		{"slices.SortFunc", func() { slices.SortFunc(work, byTotalFunc) }},
+		{"slices.SortStableFunc", func() { slices.SortStableFunc(work, byTotalFunc) }},
+	}
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				copy(work, base) // sort the same unsorted input every time
+				b.StartTimer()
+				bm.fn()
+			}
+			boolSink = slices.IsSortedFunc(work, byTotalFunc)
+		})
+		fmt.Printf("   %-24s %s\n", bm.name, formatResult(r))
+	}
+
+	r := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			copy(totals, baseTotals)
+			b.StartTimer()
+			slices.Sort(totals)
+		}
+	})
+	fmt.Printf("   %-24s %s\n", "slices.Sort([]int)", formatResult(r))
+	fmt.Println("   slices.SortFunc never allocates, but its comparator gets each 48-byte")
+	fmt.Println("   record by value, so on large structs it can trail sort.Sort. Sorting")
+	fmt.Println("   plain keys is fastest of all; for big records sort pointers or keys")
+}
+
+// Helper functions
+// ================
+func sampleOrders() []order {
+	return []order{
+		{1, "ann", 2500, false},
+		{2, "bo", 900, true},
+		{3, "ann", 4000, false},
+		{4, "cy", 900, false},
+		{5, "bo", 4000, true},
+	}
+}
+
+// manyOrders returns n orders in ID order, spread over a few customers
+// and a small range of totals so there are many ties
+func manyOrders(n int) []order {
+	customers := []string{"ann", "bo", "cy", "dee"}
+	out := make([]order, n)
+	for i := range out {
+		out[i] = order{ID: i, Customer: customers[(i*7)%len(customers)], Total: (i * 37) % 50 * 100}
+	}
+	return out
+}
+
+// idsAscendingWithin reports whether IDs increase within each run of
+// equal customers
+func idsAscendingWithin(orders []order) bool {
+	for i := 1; i < len(orders); i++ {
+		if orders[i].Customer == orders[i-1].Customer && orders[i].ID < orders[i-1].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func ids(orders []order) string {
+	parts := make([]string, len(orders))
+	for i, o := range orders {
+		parts[i] = fmt.Sprint(o.ID)
+	}
+	return strings.Join(parts, " ")
+}
+
+// compareBool orders false before true
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func formatResult(r testing.BenchmarkResult) string {
+	ns := float64(r.T.Nanoseconds()) / float64(r.N)
+	return fmt.Sprintf("%12.1f ns/op %8d B/op %6d allocs/op", ns, r.AllocedBytesPerOp(), r.AllocsPerOp())
+}
@@ -0,0 +1,87 @@
+package internals
+
+import (
+	"unsafe"
+)
+
+// Slice Internals - Headers, Backing Arrays and Aliasing
+// ======================================================
+// A slice is a small header - a pointer to an array, a length and a
+// capacity - passed around by value. Copying a slice copies the header,
+// not the elements, so two slices can share one backing array. Most
+// slice surprises come from forgetting that: a write through one slice
+// shows up in another, and append writes in place whenever there is
+// spare capacity.
+//
+// The helpers here make the header visible so the tests can prove each
+// behaviour instead of describing it.
+
+// Header mirrors the runtime's slice header
+type Header struct {
+	Data uintptr // address of element 0
+	Len  int
+	Cap  int
+}
+
+// HeaderOf returns the header of s. Data is 0 for a nil slice.
+func HeaderOf[T any](s []T) Header {
+	return Header{
+		Data: uintptr(unsafe.Pointer(unsafe.SliceData(s))),
+		Len:  len(s),
+		Cap:  cap(s),
+	}
+}
+
+// SameArray reports whether a and b start on the same backing array
+// element, i.e. whether they have the same Data pointer
+func SameArray[T any](a, b []T) bool {
+	return cap(a) > 0 && cap(b) > 0 && unsafe.SliceData(a) == unsafe.SliceData(b)
+}
+
+// Overlaps reports whether a and b can see any common element, counting
+// the spare capacity that an append would write into
+func Overlaps[T any](a, b []T) bool {
+	if cap(a) == 0 || cap(b) == 0 {
+		return false
+	}
+	size := unsafe.Sizeof(*new(T))
+	if size == 0 {
+		return false // zero-size elements share an address without aliasing
+	}
+	aStart := uintptr(unsafe.Pointer(unsafe.SliceData(a)))
+	bStart := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	aEnd := aStart + uintptr(cap(a))*size
+	bEnd := bStart + uintptr(cap(b))*size
+	return aStart < bEnd && bStart < aEnd
+}
+
+// With returns base followed by v without ever writing into base's
+// backing array. The full slice expression base[:n:n] sets the capacity
+// to the length, so append must allocate a new array.
+func With[T any](base []T, v T) []T {
+	n := len(base)
+	return append(base[:n:n], v)
+}
+
+// WithAliased is the buggy version of With: when base has spare
+// capacity, append writes v into the shared array, and two results from
+// the same base end up sharing their last element
+func WithAliased[T any](base []T, v T) []T {
+	return append(base, v)
+}
+
+// Growth appends n elements one at a time and returns each capacity the
+// slice passed through, showing the runtime's growth steps
+func Growth[T any](n int) []int {
+	var s []T
+	var caps []int
+	var zero T
+	for range n {
+		before := cap(s)
+		s = append(s, zero)
+		if cap(s) != before {
+			caps = append(caps, cap(s))
+		}
+	}
+	return caps
+}
@@ -0,0 +1,420 @@
+package internals
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// Slice Internals - Each Behaviour, Proven
+// ========================================
+// Run with:
+//
+//   cd slices-maps/internals
+//   go test -v *.go
+
+// 1. The Slice Header
+// ===================
+
+func TestHeaderIsCopiedNotTheElements(t *testing.T) {
+	a := []int{1, 2, 3}
+	b := a // copies the three-word header
+
+	if !SameArray(a, b) {
+		t.Fatal("assignment should share the backing array")
+	}
+	b[0] = 100
+	if a[0] != 100 {
+		t.Errorf("a[0] = %d, want 100: both headers point at one array", a[0])
+	}
+
+	// Changing b's length only changes b's header
+	b = b[:1]
+	if len(a) != 3 || len(b) != 1 {
+		t.Errorf("len(a)=%d len(b)=%d, want 3 and 1", len(a), len(b))
+	}
+}
+
+func TestNilAndEmpty(t *testing.T) {
+	var nilSlice []int
+	empty := []int{}
+
+	// Both have length 0 and work with len, range and append, but only
+	// one is nil. JSON encodes them differently (null vs []).
+	if nilSlice != nil || empty == nil {
+		t.Error("var s []int is nil; []int{} is not")
+	}
+	if h := HeaderOf(nilSlice); h.Data != 0 || h.Len != 0 || h.Cap != 0 {
+		t.Errorf("nil header = %+v, want all zero", h)
+	}
+	if len(append(nilSlice, 1)) != 1 {
+		t.Error("append to a nil slice should allocate")
+	}
+}
+
+func TestPassingToAFunction(t *testing.T) {
+	s := make([]int, 3, 10)
+
+	// The function gets a copy of the header: element writes are seen by
+	// the caller, but a new length from append is not
+	modify := func(in []int) {
+		in[0] = 1
+		in = append(in, 4) // writes s's spare capacity, changes only in's length
+		_ = in
+	}
+	modify(s)
+
+	if s[0] != 1 {
+		t.Error("element write should be visible to the caller")
+	}
+	if len(s) != 3 {
+		t.Errorf("len(s) = %d: the caller's header is unchanged", len(s))
+	}
+	// The appended value is there, just beyond s's length
+	if s[:4][3] != 4 {
+		t.Error("append wrote into the shared spare capacity")
+	}
+}
+
+func TestGrowth(t *testing.T) {
+	// append roughly doubles small slices and grows large ones by about
+	// 1.25x, rounded up to allocator size classes. Exact numbers vary
+	// between Go versions; the shape does not.
+	caps := Growth[int](5000)
+	t.Logf("capacities: %v", caps)
+
+	if !slices.IsSorted(caps) || caps[0] < 1 {
+		t.Fatalf("capacities should increase: %v", caps)
+	}
+	for i := 1; i < len(caps); i++ {
+		ratio := float64(caps[i]) / float64(caps[i-1])
+		if ratio < 1.2 || ratio > 2.5 {
+			t.Errorf("growth %d -> %d (x%.2f) outside the expected range", caps[i-1], caps[i], ratio)
+		}
+	}
+	// About log2(5000) reallocations for 5000 appends, not 5000
+	if len(caps) > 25 {
+		t.Errorf("%d reallocations for 5000 appends", len(caps))
+	}
+}
+
+// 2. Shared Backing Arrays
+// ========================
+
+func TestSubslicesShareTheArray(t *testing.T) {
+	arr := [5]int{0, 1, 2, 3, 4}
+	mid := arr[1:3] // len 2, cap 4: capacity runs to the end of arr
+
+	if len(mid) != 2 || cap(mid) != 4 {
+		t.Fatalf("len=%d cap=%d, want 2 and 4", len(mid), cap(mid))
+	}
+	mid[0] = 10
+	if arr[1] != 10 {
+		t.Error("a subslice is a window onto the array, not a copy")
+	}
+
+	// A slice can be re-extended up to its capacity, exposing elements
+	// beyond its length
+	if ext := mid[:4]; ext[3] != 4 {
+		t.Errorf("mid[:4] = %v, want to reach arr[4]", ext)
+	}
+}
+
+func TestSmallSubsliceKeepsBigArrayAlive(t *testing.T) {
+	big := make([]byte, 1<<20)
+	header := big[:16]
+
+	// header holds a pointer into the 1 MiB array, so the whole array
+	// stays reachable. Clone the part you keep.
+	if cap(header) != 1<<20 {
+		t.Errorf("cap = %d: the subslice pins the whole array", cap(header))
+	}
+	kept := slices.Clone(header)
+	if Overlaps(kept, big) || cap(kept) > 64 {
+		t.Errorf("Clone should copy into a small, separate array (cap %d)", cap(kept))
+	}
+}
+
+// 3. The append Aliasing Bug
+// ==========================
+
+func TestAppendAliasingBug(t *testing.T) {
+	base := make([]string, 2, 4)
+	base[0], base[1] = "usr", "local"
+
+	// Two appends to the same base, each writing into base's spare
+	// capacity at index 2: the second overwrites the first
+	bin := WithAliased(base, "bin")
+	lib := WithAliased(base, "lib")
+
+	if bin[2] != "lib" {
+		t.Errorf("bin = %v: expected the bug to show \"lib\"", bin)
+	}
+	if !Overlaps(bin, lib) {
+		t.Error("both results should share base's array")
+	}
+
+	// With uses base[:n:n], so each append allocates
+	bin = With(base, "bin")
+	lib = With(base, "lib")
+	if bin[2] != "bin" || lib[2] != "lib" || Overlaps(bin, lib) || Overlaps(bin, base) {
+		t.Errorf("With: bin=%v lib=%v should be independent", bin, lib)
+	}
+}
+
+func TestAliasingDependsOnCapacity(t *testing.T) {
+	// The bug only appears when there is spare capacity, which is why it
+	// slips through tests: a literal has len == cap, so append copies
+	full := []int{1, 2}
+	a := append(full, 3)
+	b := append(full, 4)
+	if a[2] != 3 || Overlaps(a, b) {
+		t.Error("with len == cap, each append allocates a new array")
+	}
+
+	// After one append the slice has room to spare, and the bug appears
+	grown := append(full, 3) // cap is now 4
+	c := append(grown, 5)
+	d := append(grown, 6)
+	if c[3] != 6 {
+		t.Errorf("c = %v: shared spare capacity, second append won", c)
+	}
+	_ = d
+}
+
+func TestRecursionPathBug(t *testing.T) {
+	// The classic form of the bug: a depth-first search that appends to a
+	// shared path slice and stores the results
+	type node struct {
+		name     string
+		children []*node
+	}
+	leaf := func(n string) *node { return &node{name: n} }
+	// a -> b -> c -> {d, e}: appending "c" grows the path to cap 4, so
+	// d and e are both written into index 3 of one array
+	root := &node{name: "a", children: []*node{
+		{name: "b", children: []*node{
+			{name: "c", children: []*node{leaf("d"), leaf("e")}},
+		}},
+	}}
+
+	walk := func(extend func([]string, string) []string) [][]string {
+		var paths [][]string
+		var visit func(*node, []string)
+		visit = func(n *node, path []string) {
+			path = extend(path, n.name)
+			if len(n.children) == 0 {
+				paths = append(paths, path)
+			}
+			for _, c := range n.children {
+				visit(c, path)
+			}
+		}
+		visit(root, nil)
+		return paths
+	}
+
+	buggy := walk(WithAliased[string])
+	if fmt.Sprint(buggy) != "[[a b c e] [a b c e]]" {
+		t.Errorf("buggy paths = %v: expected the last leaf to overwrite the first", buggy)
+	}
+	if fixed := walk(With[string]); fmt.Sprint(fixed) != "[[a b c d] [a b c e]]" {
+		t.Errorf("fixed paths = %v", fixed)
+	}
+}
+
+// 4. Full Slice Expressions
+// =========================
+
+func TestThreeIndexSlicing(t *testing.T) {
+	arr := []int{0, 1, 2, 3, 4, 5}
+
+	// s[low:high:max] has len high-low and cap max-low
+	s := arr[1:3:4]
+	if len(s) != 2 || cap(s) != 3 {
+		t.Fatalf("len=%d cap=%d, want 2 and 3", len(s), cap(s))
+	}
+
+	// One append fits in the capacity and writes arr[3]...
+	s = append(s, 30)
+	if arr[3] != 30 {
+		t.Error("append within cap should write the shared array")
+	}
+	// ...the next exceeds max and moves to a new array, leaving arr[4]
+	s = append(s, 40)
+	if arr[4] != 4 || Overlaps(s, arr) {
+		t.Error("append past max should reallocate")
+	}
+
+	// Capping at the length is the common case: s[:len(s):len(s)]
+	capped := arr[:2:2]
+	_ = append(capped, 99)
+	if arr[2] != 2 {
+		t.Error("appending to a capped slice must not touch the original")
+	}
+}
+
+func TestThreeIndexLimits(t *testing.T) {
+	arr := []int{0, 1, 2}
+	defer func() {
+		if recover() == nil {
+			t.Error("max beyond cap should panic")
+		}
+	}()
+	m := 4
+	_ = arr[0:1:m] // requires low <= high <= max <= cap
+}
+
+// 5. copy Semantics
+// =================
+
+func TestCopyCopiesMin(t *testing.T) {
+	dst := make([]int, 2)
+	n := copy(dst, []int{1, 2, 3, 4})
+	if n != 2 || !slices.Equal(dst, []int{1, 2}) {
+		t.Errorf("copy = %d, dst = %v: copies min(len(dst), len(src))", n, dst)
+	}
+
+	// copy never grows dst: copying into a zero-length slice copies nothing,
+	// even if it has capacity
+	empty := make([]int, 0, 10)
+	if copy(empty, []int{1}) != 0 {
+		t.Error("copy uses length, not capacity")
+	}
+
+	// A string can be the source when dst is []byte
+	b := make([]byte, 3)
+	copy(b, "Gopher")
+	if string(b) != "Gop" {
+		t.Errorf("b = %q", b)
+	}
+}
+
+func TestCopyHandlesOverlap(t *testing.T) {
+	// copy behaves like memmove: overlapping ranges are handled, so it
+	// can shift elements within one slice
+	s := []int{1, 2, 3, 4, 5}
+	copy(s[1:], s) // shift right by one
+	if !slices.Equal(s, []int{1, 1, 2, 3, 4}) {
+		t.Errorf("shift right = %v", s)
+	}
+
+	s = []int{1, 2, 3, 4, 5}
+	copy(s, s[1:]) // shift left by one
+	if !slices.Equal(s, []int{2, 3, 4, 5, 5}) {
+		t.Errorf("shift left = %v", s)
+	}
+}
+
+func TestCopyIsShallow(t *testing.T) {
+	// Elements are copied by value; for slices of slices or pointers the
+	// inner data is still shared
+	src := [][]int{{1}, {2}}
+	dst := make([][]int, 2)
+	copy(dst, src)
+	dst[0][0] = 100
+	if src[0][0] != 100 {
+		t.Error("inner slices are shared after copy")
+	}
+	dst[1] = []int{200}
+	if src[1][0] != 2 {
+		t.Error("replacing an element of dst does not affect src")
+	}
+}
+
+// 6. slices.Clip, Clone and Grow
+// ==============================
+
+func TestClip(t *testing.T) {
+	// Clip is s[:len(s):len(s)]: same array, no spare capacity, so the
+	// next append cannot clobber anyone else's elements
+	buf := make([]int, 3, 10)
+	clipped := slices.Clip(buf)
+	if !SameArray(clipped, buf) || cap(clipped) != 3 {
+		t.Fatalf("Clip: same array %t, cap %d", SameArray(clipped, buf), cap(clipped))
+	}
+	grown := append(clipped, 1)
+	if Overlaps(grown, buf) {
+		t.Error("append after Clip should allocate")
+	}
+}
+
+func TestClone(t *testing.T) {
+	src := make([]int, 3, 100)
+	c := slices.Clone(src)
+	if Overlaps(c, src) || !slices.Equal(c, src) {
+		t.Error("Clone copies the elements into a new array")
+	}
+	if cap(c) >= 100 {
+		t.Errorf("Clone does not keep spare capacity: cap %d", cap(c))
+	}
+
+	// Clone keeps nil-ness, unlike append([]int{}, s...)
+	if slices.Clone([]int(nil)) != nil {
+		t.Error("Clone(nil) should be nil")
+	}
+	if append([]int{}, []int(nil)...) == nil {
+		t.Error("append([]int{}, nil...) is an empty, non-nil slice")
+	}
+}
+
+func TestGrow(t *testing.T) {
+	// Grow guarantees room for n more appends without reallocating
+	s := []int{1, 2}
+	s = slices.Grow(s, 100)
+	if len(s) != 2 || cap(s) < 102 {
+		t.Fatalf("Grow: len %d cap %d", len(s), cap(s))
+	}
+	before := HeaderOf(s).Data
+	for i := range 100 {
+		s = append(s, i)
+	}
+	if HeaderOf(s).Data != before {
+		t.Error("appends within the grown capacity should not move the array")
+	}
+
+	// If there is already room, Grow returns s unchanged
+	if again := slices.Grow(s[:2], 10); !SameArray(again, s) {
+		t.Error("Grow with enough capacity should not reallocate")
+	}
+}
+
+func TestAllocations(t *testing.T) {
+	// Grow-then-append allocates once; plain appends reallocate as the
+	// slice doubles. Only the comparison is checked: the race detector
+	// changes the counts, and Grow reports 2 under -race.
+	grown := testing.AllocsPerRun(100, func() {
+		s := slices.Grow([]int(nil), 1000)
+		for i := range 1000 {
+			s = append(s, i)
+		}
+	})
+	plain := testing.AllocsPerRun(100, func() {
+		var s []int
+		for i := range 1000 {
+			s = append(s, i)
+		}
+	})
+	if plain <= grown {
+		t.Errorf("allocations: Grow %v, plain %v", grown, plain)
+	}
+}
+
+// Examples
+// ========
+
+func ExampleWith() {
+	base := make([]string, 1, 4)
+	base[0] = "a"
+
+	x := WithAliased(base, "x")
+	y := WithAliased(base, "y")
+	fmt.Println("aliased:", x, y)
+
+	x = With(base, "x")
+	y = With(base, "y")
+	fmt.Println("safe:   ", x, y)
+	// Output:
+	// aliased: [a y] [a y]
+	// safe:    [a x] [a y]
+}
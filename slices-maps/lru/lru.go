@@ -0,0 +1,222 @@
+package lru
+
+import (
+	"iter"
+	"time"
+)
+
+// LRU Cache - Maps, Generics and Pointers Together
+// ================================================
+// A least-recently-used cache keeps at most capacity entries and, when
+// full, evicts the entry that was used longest ago. Two structures give
+// O(1) for every operation:
+//
+//   - a map from key to list node, for lookup
+//   - a doubly linked list in recency order, for "move to front" and
+//     "evict from the back" without shifting anything
+//
+// The list is written out here rather than using container/list, so the
+// nodes are typed (no any, no type assertions) and the pointer surgery
+// is visible. A sentinel node makes the list circular: root.next is the
+// most recent entry and root.prev the least recent, so no operation
+// needs a nil check.
+
+// node is one cache entry and its place in the recency list
+type node[K comparable, V any] struct {
+	key        K
+	value      V
+	expires    time.Time // zero if the cache has no TTL
+	prev, next *node[K, V]
+}
+
+// Stats counts cache outcomes since creation or the last ResetStats
+type Stats struct {
+	Hits        int
+	Misses      int // includes lookups of expired entries
+	Evictions   int // removed to make room
+	Expirations int // removed because their TTL passed
+}
+
+// HitRate returns Hits / (Hits + Misses), or 0 before any lookup
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// LRU is a fixed-capacity least-recently-used cache. It is not safe for
+// concurrent use; see Sync. The zero value is not usable; create one
+// with New or NewTTL.
+type LRU[K comparable, V any] struct {
+	capacity int
+	items    map[K]*node[K, V]
+	root     node[K, V] // sentinel: root.next is newest, root.prev oldest
+
+	ttl time.Duration
+	now func() time.Time
+
+	stats Stats
+}
+
+// New returns an empty cache holding at most capacity entries. It
+// panics if capacity < 1.
+func New[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity < 1 {
+		panic("lru: capacity must be at least 1")
+	}
+	c := &LRU[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*node[K, V], capacity),
+		now:      time.Now,
+	}
+	c.root.next = &c.root
+	c.root.prev = &c.root
+	return c
+}
+
+// NewTTL returns a cache whose entries also expire ttl after they were
+// last written. now supplies the current time; pass nil for time.Now,
+// or a fake clock in tests. Expired entries are dropped lazily, when
+// they are looked up or reach the back of the list.
+func NewTTL[K comparable, V any](capacity int, ttl time.Duration, now func() time.Time) *LRU[K, V] {
+	c := New[K, V](capacity)
+	c.ttl = ttl
+	if now != nil {
+		c.now = now
+	}
+	return c
+}
+
+// Get returns the value for key and marks it most recently used
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	n, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return value, false
+	}
+	if c.expired(n) {
+		c.remove(n)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return value, false
+	}
+	c.moveToFront(n)
+	c.stats.Hits++
+	return n.value, true
+}
+
+// Peek returns the value for key without changing its recency or the
+// stats
+func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
+	n, ok := c.items[key]
+	if !ok || c.expired(n) {
+		return value, false
+	}
+	return n.value, true
+}
+
+// Put adds or replaces the value for key, marks it most recently used
+// and restarts its TTL. It reports whether an entry was evicted.
+func (c *LRU[K, V]) Put(key K, value V) (evicted bool) {
+	if n, ok := c.items[key]; ok {
+		n.value = value
+		n.expires = c.expiry()
+		c.moveToFront(n)
+		return false
+	}
+
+	if len(c.items) >= c.capacity {
+		oldest := c.root.prev
+		if c.expired(oldest) {
+			c.stats.Expirations++
+		} else {
+			c.stats.Evictions++
+			evicted = true
+		}
+		c.remove(oldest)
+	}
+
+	n := &node[K, V]{key: key, value: value, expires: c.expiry()}
+	c.items[key] = n
+	c.insertFront(n)
+	return evicted
+}
+
+// Remove deletes key and reports whether it was present
+func (c *LRU[K, V]) Remove(key K) bool {
+	n, ok := c.items[key]
+	if ok {
+		c.remove(n)
+	}
+	return ok
+}
+
+// Len returns the number of entries, including expired ones not yet
+// dropped
+func (c *LRU[K, V]) Len() int { return len(c.items) }
+
+// Stats returns the counters
+func (c *LRU[K, V]) Stats() Stats { return c.stats }
+
+// ResetStats zeroes the counters
+func (c *LRU[K, V]) ResetStats() { c.stats = Stats{} }
+
+// All yields unexpired entries from most to least recently used,
+// without changing their order. The cache must not be modified during
+// the iteration.
+func (c *LRU[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := c.root.next; n != &c.root; n = n.next {
+			if c.expired(n) {
+				continue
+			}
+			if !yield(n.key, n.value) {
+				return
+			}
+		}
+	}
+}
+
+// List operations
+// ===============
+
+func (c *LRU[K, V]) insertFront(n *node[K, V]) {
+	n.prev = &c.root
+	n.next = c.root.next
+	c.root.next.prev = n
+	c.root.next = n
+}
+
+func (c *LRU[K, V]) unlink(n *node[K, V]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+}
+
+func (c *LRU[K, V]) moveToFront(n *node[K, V]) {
+	if c.root.next == n {
+		return
+	}
+	c.unlink(n)
+	c.insertFront(n)
+}
+
+// remove unlinks n and deletes it from the map. Clearing the pointers
+// stops a stale reference from keeping neighbours reachable.
+func (c *LRU[K, V]) remove(n *node[K, V]) {
+	c.unlink(n)
+	n.prev, n.next = nil, nil
+	delete(c.items, n.key)
+}
+
+func (c *LRU[K, V]) expiry() time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+	return c.now().Add(c.ttl)
+}
+
+func (c *LRU[K, V]) expired(n *node[K, V]) bool {
+	return !n.expires.IsZero() && !c.now().Before(n.expires)
+}
@@ -0,0 +1,386 @@
+package lru
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// LRU Cache - Tests and Benchmarks
+// ================================
+// Run with:
+//
+//   cd slices-maps/lru
+//   go test -v *.go
+//   go test -race *.go
+//   go test -bench . *.go
+
+// checkList verifies the invariants the O(1) operations rely on: the
+// list is consistent in both directions and holds exactly the map's
+// nodes
+func checkList[K comparable, V any](t *testing.T, c *LRU[K, V]) {
+	t.Helper()
+	count := 0
+	for n := c.root.next; n != &c.root; n = n.next {
+		if n.next.prev != n {
+			t.Fatalf("broken back link at %v", n.key)
+		}
+		if c.items[n.key] != n {
+			t.Fatalf("list node %v is not the map's node", n.key)
+		}
+		count++
+	}
+	if count != len(c.items) {
+		t.Fatalf("list has %d nodes, map has %d", count, len(c.items))
+	}
+	if count > c.capacity {
+		t.Fatalf("%d entries exceed capacity %d", count, c.capacity)
+	}
+}
+
+func keys[K comparable, V any](c *LRU[K, V]) []K {
+	var out []K
+	for k := range c.All() {
+		out = append(out, k)
+	}
+	return out
+}
+
+// 1. Recency and Eviction
+// =======================
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](3)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+
+	c.Get("a") // a is now the most recent; b the least
+
+	if evicted := c.Put("d", 4); !evicted {
+		t.Error("Put into a full cache should evict")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was least recently used and should be gone")
+	}
+	if got := keys(c); !slices.Equal(got, []string{"d", "a", "c"}) {
+		t.Errorf("keys = %v", got)
+	}
+	checkList(t, c)
+}
+
+func TestRecencyOrder(t *testing.T) {
+	c := New[int, string](4)
+	for i := range 4 {
+		c.Put(i, fmt.Sprint(i))
+	}
+	c.Get(1)
+	c.Put(2, "two") // updating also counts as use
+	c.Peek(0)       // Peek does not
+
+	if got := keys(c); !slices.Equal(got, []int{2, 1, 3, 0}) {
+		t.Errorf("most to least recent = %v, want [2 1 3 0]", got)
+	}
+	if v, _ := c.Peek(2); v != "two" {
+		t.Errorf("Put should replace the value, got %q", v)
+	}
+	checkList(t, c)
+}
+
+func TestRemoveAndCapacityOne(t *testing.T) {
+	c := New[string, int](1)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	if _, ok := c.Peek("a"); ok || c.Len() != 1 {
+		t.Error("capacity 1 keeps only the latest entry")
+	}
+	if !c.Remove("b") || c.Remove("b") || c.Len() != 0 {
+		t.Error("Remove should delete once and report it")
+	}
+	checkList(t, c)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("capacity 0 should panic")
+		}
+	}()
+	New[string, int](0)
+}
+
+func TestAgainstModel(t *testing.T) {
+	// Compare with an obviously-correct O(n) model: a slice ordered from
+	// most to least recent
+	const capacity = 8
+	c := New[int, int](capacity)
+	var model []int // keys; value is always key*10
+	touch := func(k int) {
+		model = slices.DeleteFunc(model, func(x int) bool { return x == k })
+		model = slices.Insert(model, 0, k)
+	}
+
+	r := rand.New(rand.NewPCG(5, 6))
+	for step := range 5000 {
+		k := r.IntN(20)
+		switch r.IntN(3) {
+		case 0:
+			c.Put(k, k*10)
+			touch(k)
+			if len(model) > capacity {
+				model = model[:capacity]
+			}
+		case 1:
+			v, ok := c.Get(k)
+			inModel := slices.Contains(model, k)
+			if ok != inModel || (ok && v != k*10) {
+				t.Fatalf("step %d: Get(%d) = %d, %t; model has it: %t", step, k, v, ok, inModel)
+			}
+			if ok {
+				touch(k)
+			}
+		case 2:
+			removed := c.Remove(k)
+			if removed != slices.Contains(model, k) {
+				t.Fatalf("step %d: Remove(%d) = %t", step, k, removed)
+			}
+			model = slices.DeleteFunc(model, func(x int) bool { return x == k })
+		}
+		if got := keys(c); !slices.Equal(got, model) {
+			t.Fatalf("step %d: order %v, model %v", step, got, model)
+		}
+	}
+	checkList(t, c)
+}
+
+// 2. TTL
+// ======
+
+// fakeClock is a manual clock; see testing/clock for a full version
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) Now() time.Time          { return f.t }
+func (f *fakeClock) Advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func TestTTL(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := NewTTL[string, int](10, time.Minute, clock.Now)
+
+	c.Put("session", 1)
+	clock.Advance(59 * time.Second)
+	if _, ok := c.Get("session"); !ok {
+		t.Fatal("entry should live for its whole TTL")
+	}
+
+	// Get does not extend the TTL; only Put does
+	clock.Advance(time.Second)
+	if _, ok := c.Get("session"); ok {
+		t.Error("entry should expire exactly at its TTL")
+	}
+	if s := c.Stats(); s.Expirations != 1 || s.Misses != 1 {
+		t.Errorf("stats = %+v, want one expiration counted as a miss", s)
+	}
+
+	c.Put("a", 1)
+	clock.Advance(30 * time.Second)
+	c.Put("a", 2) // restarts the TTL
+	clock.Advance(45 * time.Second)
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Error("Put should restart the TTL")
+	}
+}
+
+func TestTTLExpiredEntriesAreEvictedFirst(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	c := NewTTL[int, int](2, time.Second, clock.Now)
+	c.Put(1, 1)
+	clock.Advance(2 * time.Second)
+	c.Put(2, 2)
+
+	// Entry 1 is both the oldest and expired: dropping it is an
+	// expiration, not an eviction of a live entry
+	if evicted := c.Put(3, 3); evicted {
+		t.Error("replacing an expired entry should not count as an eviction")
+	}
+	if s := c.Stats(); s.Evictions != 0 || s.Expirations != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	if got := keys(c); !slices.Equal(got, []int{3, 2}) {
+		t.Errorf("keys = %v", got)
+	}
+}
+
+// 3. Hit-Rate Metrics
+// ===================
+
+func TestHitRateGrowsWithCapacity(t *testing.T) {
+	// Real access patterns are skewed: a few keys are hot. With a Zipf
+	// distribution over 10,000 keys, a cache of 1% of the keys already
+	// serves a large share of lookups.
+	hitRate := func(capacity int) float64 {
+		c := New[uint64, bool](capacity)
+		zipf := rand.NewZipf(rand.New(rand.NewPCG(7, 8)), 1.1, 1, 9_999)
+		for range 100_000 {
+			k := zipf.Uint64()
+			if _, ok := c.Get(k); !ok {
+				c.Put(k, true)
+			}
+		}
+		return c.Stats().HitRate()
+	}
+
+	small, large := hitRate(100), hitRate(1000)
+	t.Logf("hit rate: 100 entries %.1f%%, 1000 entries %.1f%%", small*100, large*100)
+	if small < 0.5 || large <= small {
+		t.Errorf("hit rates %.2f, %.2f: expected > 0.5 and growing", small, large)
+	}
+}
+
+func TestStats(t *testing.T) {
+	c := New[string, int](1)
+	if c.Stats().HitRate() != 0 {
+		t.Error("no lookups means a hit rate of 0, not NaN")
+	}
+	c.Put("a", 1)
+	c.Get("a")
+	c.Get("b")
+	c.Put("b", 2)
+	want := Stats{Hits: 1, Misses: 1, Evictions: 1}
+	if got := c.Stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+	c.ResetStats()
+	if c.Stats() != (Stats{}) {
+		t.Error("ResetStats should zero the counters")
+	}
+}
+
+// 4. Concurrency
+// ==============
+
+func TestSyncConcurrentUse(t *testing.T) {
+	// Run with -race: an unwrapped LRU fails here, because Get writes
+	s := NewSync(New[int, int](64))
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Go(func() {
+			r := rand.New(rand.NewPCG(uint64(g), 0))
+			for range 2000 {
+				k := r.IntN(128)
+				if _, ok := s.Get(k); !ok {
+					s.Put(k, k)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if s.Len() != 64 {
+		t.Errorf("Len = %d, want a full cache of 64", s.Len())
+	}
+	if st := s.Stats(); st.Hits+st.Misses != 8*2000 {
+		t.Errorf("lookups = %d, want %d", st.Hits+st.Misses, 8*2000)
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	s := NewSync(New[string, int](4))
+	loads := 0
+	load := func(k string) (int, error) {
+		loads++
+		if k == "bad" {
+			return 0, errors.New("not found")
+		}
+		return len(k), nil
+	}
+
+	for range 3 {
+		if v, err := s.GetOrLoad("hello", load); v != 5 || err != nil {
+			t.Fatalf("GetOrLoad = %d, %v", v, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1: later calls hit the cache", loads)
+	}
+
+	// Errors are returned and not cached
+	s.GetOrLoad("bad", load)
+	s.GetOrLoad("bad", load)
+	if loads != 3 {
+		t.Errorf("loads = %d: a failed load must not be cached", loads)
+	}
+}
+
+// 5. Benchmarks
+// =============
+
+func BenchmarkGetHit(b *testing.B) {
+	c := New[int, int](1024)
+	for i := range 1024 {
+		c.Put(i, i)
+	}
+	i := 0
+	for b.Loop() {
+		c.Get(i & 1023)
+		i++
+	}
+}
+
+func BenchmarkPutEvict(b *testing.B) {
+	c := New[int, int](1024)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		c.Put(i, i) // every Put past the first 1024 evicts
+		i++
+	}
+}
+
+func BenchmarkMapOnly(b *testing.B) {
+	// The floor: a bare map lookup, to show what the list costs
+	m := make(map[int]int, 1024)
+	for i := range 1024 {
+		m[i] = i
+	}
+	i := 0
+	for b.Loop() {
+		_ = m[i&1023]
+		i++
+	}
+}
+
+func BenchmarkSyncParallel(b *testing.B) {
+	s := NewSync(New[int, int](1024))
+	for i := range 1024 {
+		s.Put(i, i)
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			s.Get(i & 2047) // half hits, half misses
+			i++
+		}
+	})
+}
+
+// Examples
+// ========
+
+func ExampleLRU() {
+	c := New[string, string](2)
+	c.Put("go", "gopher")
+	c.Put("rust", "crab")
+	c.Get("go")            // go is now most recent
+	c.Put("zig", "lizard") // evicts rust
+
+	for k, v := range c.All() {
+		fmt.Println(k, v)
+	}
+	fmt.Printf("hit rate: %.0f%%\n", c.Stats().HitRate()*100)
+	// Output:
+	// zig lizard
+	// go gopher
+	// hit rate: 100%
+}
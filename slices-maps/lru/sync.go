@@ -0,0 +1,71 @@
+package lru
+
+import (
+	"sync"
+)
+
+// Sync wraps an LRU with a mutex so it can be shared between goroutines.
+//
+// It uses sync.Mutex, not sync.RWMutex: Get moves the entry to the front
+// of the list and updates the stats, so every lookup is a write. A read
+// lock would let two Gets corrupt the list.
+type Sync[K comparable, V any] struct {
+	mu    sync.Mutex
+	cache *LRU[K, V]
+}
+
+// NewSync returns a concurrency-safe cache around c
+func NewSync[K comparable, V any](c *LRU[K, V]) *Sync[K, V] {
+	return &Sync[K, V]{cache: c}
+}
+
+// Get returns the value for key and marks it most recently used
+func (s *Sync[K, V]) Get(key K) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.Get(key)
+}
+
+// Put adds or replaces the value for key
+func (s *Sync[K, V]) Put(key K, value V) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.Put(key, value)
+}
+
+// Remove deletes key and reports whether it was present
+func (s *Sync[K, V]) Remove(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.Remove(key)
+}
+
+// GetOrLoad returns the cached value for key, or calls load and caches
+// its result. The lock is not held during load, so a slow load does not
+// block other keys; two goroutines missing the same key may both load
+// it, and the last Put wins.
+func (s *Sync[K, V]) GetOrLoad(key K, load func(K) (V, error)) (V, error) {
+	if v, ok := s.Get(key); ok {
+		return v, nil
+	}
+	v, err := load(key)
+	if err != nil {
+		return v, err
+	}
+	s.Put(key, v)
+	return v, nil
+}
+
+// Len returns the number of entries
+func (s *Sync[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.Len()
+}
+
+// Stats returns the counters
+func (s *Sync[K, V]) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.Stats()
+}
@@ -0,0 +1,150 @@
+package trie
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Trie - Prefix Search Over Runes
+// ===============================
+// A trie stores strings as paths from the root, one edge per character,
+// so every word sharing a prefix shares the nodes for it. Finding all
+// words with a prefix walks len(prefix) edges and then collects the
+// subtree - the cost depends on the prefix and the results, not on how
+// many words are stored. That makes it a natural fit for autocomplete.
+//
+// It is not automatically the fastest choice. For a word list built
+// once, a sorted slice with binary search (SortedPrefixSearch) answers
+// the same queries faster in a fraction of the memory. The trie wins
+// when words keep arriving, since an insert never shifts other entries,
+// and when each node carries data of its own, like counts for ranking.
+//
+// Edges are runes, not bytes: indexing a string by byte would split
+// multi-byte characters like "é" across two edges, and a prefix ending
+// halfway through one would match nothing sensible.
+
+// node is one position in the trie. children is nil until needed, which
+// keeps the many leaf nodes small.
+type node struct {
+	children map[rune]*node
+	terminal bool // a word ends here
+}
+
+// Trie is a set of strings supporting prefix queries. The zero value is
+// an empty trie ready to use. It is not safe for concurrent writes.
+type Trie struct {
+	root node
+	size int
+}
+
+// Insert adds word and reports whether it was new. The empty string is
+// a valid word.
+func (t *Trie) Insert(word string) bool {
+	n := &t.root
+	for _, r := range word {
+		child := n.children[r]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[rune]*node, 1)
+			}
+			child = &node{}
+			n.children[r] = child
+		}
+		n = child
+	}
+	if n.terminal {
+		return false
+	}
+	n.terminal = true
+	t.size++
+	return true
+}
+
+// Contains reports whether word was inserted
+func (t *Trie) Contains(word string) bool {
+	n := t.find(word)
+	return n != nil && n.terminal
+}
+
+// HasPrefix reports whether any word starts with prefix
+func (t *Trie) HasPrefix(prefix string) bool {
+	return t.find(prefix) != nil
+}
+
+// Len returns the number of words
+func (t *Trie) Len() int { return t.size }
+
+// PrefixSearch returns up to limit words starting with prefix, in rune
+// order (the same order as sorting the strings). A limit <= 0 means no
+// limit.
+func (t *Trie) PrefixSearch(prefix string, limit int) []string {
+	n := t.find(prefix)
+	if n == nil {
+		return nil
+	}
+	var out []string
+	buf := []byte(prefix)
+	collect(n, &buf, &out, limit)
+	return out
+}
+
+// find follows prefix from the root and returns its node, or nil
+func (t *Trie) find(prefix string) *node {
+	n := &t.root
+	for _, r := range prefix {
+		n = n.children[r]
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// collect appends the words below n in rune order. buf holds the path
+// so far and is shared down the recursion, so each word costs one
+// string allocation. It reports false once limit is reached.
+func collect(n *node, buf *[]byte, out *[]string, limit int) bool {
+	if n.terminal {
+		*out = append(*out, string(*buf))
+		if limit > 0 && len(*out) >= limit {
+			return false
+		}
+	}
+	if len(n.children) == 0 {
+		return true
+	}
+	// Map order is random; sort the edges for a stable, sorted result
+	runes := make([]rune, 0, len(n.children))
+	for r := range n.children {
+		runes = append(runes, r)
+	}
+	slices.Sort(runes)
+
+	for _, r := range runes {
+		size := len(*buf)
+		*buf = utf8.AppendRune(*buf, r)
+		more := collect(n.children[r], buf, out, limit)
+		*buf = (*buf)[:size]
+		if !more {
+			return false
+		}
+	}
+	return true
+}
+
+// SortedPrefixSearch is the baseline a trie competes with: binary search
+// for the first word >= prefix in a sorted slice, then take words while
+// they still have the prefix. Same results, a fraction of the memory,
+// but inserting means shifting the slice.
+func SortedPrefixSearch(sorted []string, prefix string, limit int) []string {
+	i, _ := slices.BinarySearch(sorted, prefix)
+	var out []string
+	for ; i < len(sorted) && strings.HasPrefix(sorted[i], prefix); i++ {
+		out = append(out, sorted[i])
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
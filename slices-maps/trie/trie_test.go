@@ -0,0 +1,278 @@
+package trie
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Trie - Tests, Memory and Benchmarks
+// ===================================
+// Run with:
+//
+//   cd slices-maps/trie
+//   go test -v *.go
+//   go test -bench . *.go
+
+// words returns n distinct pseudo-words built from syllables, so they
+// share prefixes the way real vocabulary does
+func words(n int) []string {
+	syllables := []string{"go", "ro", "ut", "in", "er", "fa", "ce", "ma", "p", "sl", "ic", "e", "ch", "an", "né", "über"}
+	r := rand.New(rand.NewPCG(1, 2))
+	seen := make(map[string]bool, n)
+	out := make([]string, 0, n)
+	for len(out) < n {
+		var b strings.Builder
+		for range 2 + r.IntN(4) {
+			b.WriteString(syllables[r.IntN(len(syllables))])
+		}
+		if w := b.String(); !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// 1. Insert and Lookup
+// ====================
+
+func TestInsertContains(t *testing.T) {
+	var tr Trie // zero value is ready
+	for _, w := range []string{"go", "gopher", "goroutine", "golang"} {
+		if !tr.Insert(w) {
+			t.Errorf("Insert(%q) should be new", w)
+		}
+	}
+	if tr.Insert("go") {
+		t.Error("inserting a duplicate should report false")
+	}
+	if tr.Len() != 4 {
+		t.Errorf("Len = %d", tr.Len())
+	}
+
+	// A prefix of a word is not a word unless inserted
+	if tr.Contains("gop") || !tr.HasPrefix("gop") {
+		t.Error("gop is a prefix, not a word")
+	}
+	if !tr.Contains("go") || tr.Contains("gophers") || tr.HasPrefix("x") {
+		t.Error("Contains/HasPrefix mismatch")
+	}
+}
+
+func TestEmptyString(t *testing.T) {
+	var tr Trie
+	if tr.Contains("") {
+		t.Error("an empty trie does not contain the empty word")
+	}
+	if !tr.HasPrefix("") {
+		t.Error("every trie has the empty prefix")
+	}
+	tr.Insert("")
+	if !tr.Contains("") || tr.Len() != 1 {
+		t.Error("the empty string is a valid word")
+	}
+}
+
+// 2. Runes, Not Bytes
+// ===================
+
+func TestRuneAware(t *testing.T) {
+	var tr Trie
+	tr.Insert("café")
+	tr.Insert("cafés")
+	tr.Insert("日本語")
+	tr.Insert("日本")
+
+	if got := tr.PrefixSearch("caf", 0); !slices.Equal(got, []string{"café", "cafés"}) {
+		t.Errorf("caf -> %v", got)
+	}
+	if got := tr.PrefixSearch("日", 0); !slices.Equal(got, []string{"日本", "日本語"}) {
+		t.Errorf("日 -> %v", got)
+	}
+
+	// "é" is two bytes; its first byte alone is not a rune. Ranging over
+	// the prefix turns the stray byte into U+FFFD, which matches nothing.
+	half := "caf\xc3"
+	if tr.HasPrefix(half) {
+		t.Error("half of a multi-byte rune must not match")
+	}
+}
+
+// 3. Prefix Search
+// ================
+
+func TestPrefixSearchSortedAndLimited(t *testing.T) {
+	var tr Trie
+	for _, w := range []string{"map", "maps", "make", "main", "mutex", "max"} {
+		tr.Insert(w)
+	}
+	if got := tr.PrefixSearch("ma", 0); !slices.Equal(got, []string{"main", "make", "map", "maps", "max"}) {
+		t.Errorf("ma -> %v (want sorted)", got)
+	}
+	if got := tr.PrefixSearch("ma", 2); !slices.Equal(got, []string{"main", "make"}) {
+		t.Errorf("ma limit 2 -> %v", got)
+	}
+	if got := tr.PrefixSearch("zz", 0); got != nil {
+		t.Errorf("missing prefix -> %v, want nil", got)
+	}
+	if got := tr.PrefixSearch("maps", 0); !slices.Equal(got, []string{"maps"}) {
+		t.Errorf("a full word is its own match: %v", got)
+	}
+}
+
+func TestMatchesSortedSlice(t *testing.T) {
+	// The trie and the sorted-slice baseline must agree on every prefix
+	ws := words(2000)
+	var tr Trie
+	for _, w := range ws {
+		tr.Insert(w)
+	}
+	sorted := slices.Sorted(slices.Values(ws))
+
+	prefixes := []string{"", "g", "go", "gogo", "né", "übe", "x"}
+	for _, w := range ws[:200] {
+		for i := range w {
+			prefixes = append(prefixes, w[:i])
+		}
+	}
+	for _, p := range prefixes {
+		for _, limit := range []int{0, 5} {
+			got, want := tr.PrefixSearch(p, limit), SortedPrefixSearch(sorted, p, limit)
+			if !slices.Equal(got, want) {
+				t.Fatalf("prefix %q limit %d:\n trie   %v\n sorted %v", p, limit, got, want)
+			}
+		}
+	}
+}
+
+// 4. Memory
+// =========
+
+func TestMemory(t *testing.T) {
+	// Every rune can be a node with its own map, so a trie costs far
+	// more memory than the sorted slice, which holds only the string
+	// headers and bytes
+	ws := words(20_000)
+	totalBytes := 0
+	for _, w := range ws {
+		totalBytes += len(w)
+	}
+
+	var sorted []string
+	sliceBytes := liveBytes(func() {
+		sorted = make([]string, len(ws))
+		for i, w := range ws {
+			sorted[i] = strings.Clone(w) // own the bytes, as a trie does
+		}
+		slices.Sort(sorted)
+	})
+	var tr *Trie
+	trieBytes := liveBytes(func() {
+		tr = &Trie{}
+		for _, w := range ws {
+			tr.Insert(w)
+		}
+	})
+	runtime.KeepAlive(sorted)
+	runtime.KeepAlive(tr)
+
+	t.Logf("%d words, %d bytes of text", len(ws), totalBytes)
+	t.Logf("trie:         %8d bytes (%.0f per word)", trieBytes, float64(trieBytes)/float64(len(ws)))
+	t.Logf("sorted slice: %8d bytes (%.0f per word)", sliceBytes, float64(sliceBytes)/float64(len(ws)))
+	if trieBytes < 2*sliceBytes {
+		t.Errorf("expected the trie to use several times the slice's memory")
+	}
+}
+
+// liveBytes returns how much the live heap grew while f ran
+func liveBytes(f func()) int64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	return int64(after.HeapAlloc) - int64(before.HeapAlloc)
+}
+
+// 5. Benchmarks
+// =============
+
+var benchWords = words(50_000)
+
+func BenchmarkTriePrefixSearch(b *testing.B) {
+	var tr Trie
+	for _, w := range benchWords {
+		tr.Insert(w)
+	}
+	for b.Loop() {
+		tr.PrefixSearch("goro", 10)
+	}
+}
+
+func BenchmarkSortedPrefixSearch(b *testing.B) {
+	sorted := slices.Sorted(slices.Values(benchWords))
+	for b.Loop() {
+		SortedPrefixSearch(sorted, "goro", 10)
+	}
+}
+
+func BenchmarkLinearPrefixSearch(b *testing.B) {
+	// The naive version: scan everything, then sort the matches
+	for b.Loop() {
+		var out []string
+		for _, w := range benchWords {
+			if strings.HasPrefix(w, "goro") {
+				out = append(out, w)
+			}
+		}
+		slices.Sort(out)
+		_ = out[:min(10, len(out))]
+	}
+}
+
+// Where the trie wins: building the set one word at a time. Each trie
+// insert touches len(word) nodes; each sorted insert shifts half the
+// slice on average.
+
+func BenchmarkTrieInsert(b *testing.B) {
+	for b.Loop() {
+		var tr Trie
+		for _, w := range benchWords[:10_000] {
+			tr.Insert(w)
+		}
+	}
+}
+
+func BenchmarkSortedInsert(b *testing.B) {
+	for b.Loop() {
+		var sorted []string
+		for _, w := range benchWords[:10_000] {
+			i, _ := slices.BinarySearch(sorted, w)
+			sorted = slices.Insert(sorted, i, w)
+		}
+	}
+}
+
+// Examples
+// ========
+
+func ExampleTrie_PrefixSearch() {
+	// Autocomplete over lesson titles
+	var tr Trie
+	for _, title := range []string{
+		"slices internals", "slices and maps packages", "sorting",
+		"strings and bytes", "structs", "serialization",
+	} {
+		tr.Insert(title)
+	}
+	fmt.Println(tr.PrefixSearch("sl", 0))
+	fmt.Println(tr.PrefixSearch("st", 1))
+	// Output:
+	// [slices and maps packages slices internals]
+	// [strings and bytes]
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// database/sql With a Driver
+// ==========================
+// database/sql is an interface; a driver package does the talking.
+// A driver registers itself by name, usually from an init function run
+// by a blank import, and sql.Open picks it by that name. Nothing but
+// the name and the DSN below mentions the driver.
+//
+// This repository has no go.mod and uses only the standard library, so
+// the driver here is minisql, written in this package: driver.go is
+// the database/sql side, parse.go and engine.go a small SQL engine
+// that keeps its tables in a file. It runs the SQL this package needs
+// with SQLite's behaviour - one writer at a time, NULL semantics,
+// INTEGER PRIMARY KEY as the rowid - and no more. On a real database
+// the change is two lines: import the driver, say
+//
+//	_ "modernc.org/sqlite" // pure Go, no cgo
+//
+// and pass its name and DSN to sql.Open.
+//
+// A *sql.DB is not a connection. It is a pool that opens connections
+// when needed, hands one to each query, and keeps some idle for reuse.
+// Open one at startup, share it, and close it at exit.
+
+// Pool settings. minisql, like SQLite, allows one writer at a time; a
+// big pool only adds connections that queue on the write lock.
+const (
+	maxOpenConns    = 4
+	maxIdleConns    = 4
+	connMaxLifetime = time.Hour
+	connMaxIdleTime = 5 * time.Minute
+)
+
+// Open opens (creating if needed) the database file at path, applies
+// the pool settings and brings the schema up to date
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("minisql", dsn(path))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+	db.SetConnMaxIdleTime(connMaxIdleTime)
+
+	// sql.Open only checks its arguments; Ping makes the first
+	// connection, so a bad path fails here rather than on first use
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return db, nil
+}
+
+// dsn builds the connection string. Settings are per connection, so
+// they go in the DSN - the driver applies them to every connection the
+// pool opens, not just the first.
+//
+//	busy_timeout=5000  wait up to 5s for the lock instead of failing
+//
+// A transaction takes the write lock at BEGIN. SQLite's default BEGIN
+// is deferred instead: the transaction reads under a shared lock and
+// upgrades on its first write. Two doing that at once deadlock, and
+// SQLite fails one with SQLITE_BUSY at once - busy_timeout cannot
+// help, since waiting would never end. With SQLite, add
+// _txlock=immediate to the DSN to get what minisql always does.
+func dsn(path string) string {
+	return "file:" + path + "?busy_timeout=5000"
+}
+
+// Migrations
+// ==========
+// Each entry moves the schema one version forward; the version lives
+// in PRAGMA user_version, a field of SQLite's file header that minisql
+// keeps in its log. Never edit an entry that has shipped - add a new
+// one.
+
+var migrations = []string{
+	// 1: accounts and the transfers between them. Money is an integer
+	// number of cents; REAL would round.
+	`CREATE TABLE accounts (
+		id         INTEGER PRIMARY KEY,
+		name       TEXT    NOT NULL UNIQUE,
+		balance    INTEGER NOT NULL CHECK (balance >= 0),
+		email      TEXT,
+		created_at INTEGER NOT NULL
+	);
+	CREATE TABLE transfers (
+		id         INTEGER PRIMARY KEY,
+		from_id    INTEGER NOT NULL REFERENCES accounts(id),
+		to_id      INTEGER NOT NULL REFERENCES accounts(id),
+		amount     INTEGER NOT NULL CHECK (amount > 0),
+		created_at INTEGER NOT NULL
+	);`,
+
+	// 2: transfers are listed per account, newest first
+	`CREATE INDEX transfers_from ON transfers(from_id, id);
+	CREATE INDEX transfers_to ON transfers(to_id, id);`,
+}
+
+// migrate applies the migrations past the database's version, each in
+// its own transaction with the version bump, so a failure leaves the
+// schema at the last version that fully applied
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database is at version %d, newer than this program's %d", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		err := withTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+				return err
+			}
+			// PRAGMA takes no placeholders; i is ours, not user input
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("version %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
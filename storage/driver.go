@@ -0,0 +1,312 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A database/sql Driver
+// =====================
+// database/sql is an interface; a driver does the talking. This is the
+// contract a driver signs, small enough to read in one sitting:
+//
+//	driver.Driver  Open(dsn) returns a Conn, once per pooled connection
+//	driver.Conn    Prepare, Begin, Close
+//	driver.Stmt    Exec, Query, with the arguments for the placeholders
+//	driver.Tx      Commit, Rollback
+//	driver.Rows    Columns, Next, Close
+//
+// plus optional interfaces that database/sql looks for. The Context
+// variants are the ones every real driver implements: they are how a
+// cancelled request reaches the database.
+//
+// sql.Register makes the driver available to sql.Open by name.
+//
+// projects/taskapp runs on minisql too: its minisql_*.go files are
+// copies of driver.go, engine.go and parse.go, and a test there fails
+// when they differ.
+
+func init() {
+	sql.Register("minisql", minisqlDriver{})
+}
+
+type minisqlDriver struct{}
+
+// Open opens a connection for the DSN "file:path?busy_timeout=ms"
+func (minisqlDriver) Open(dsn string) (driver.Conn, error) {
+	path, query, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	opts, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("minisql: dsn %q: %w", dsn, err)
+	}
+	c := &conn{}
+	for k, vs := range opts {
+		if k != "busy_timeout" {
+			return nil, fmt.Errorf("minisql: dsn %q: unknown option %s", dsn, k)
+		}
+		ms, err := strconv.Atoi(vs[0])
+		if err != nil {
+			return nil, fmt.Errorf("minisql: dsn %q: busy_timeout: %w", dsn, err)
+		}
+		c.busy = time.Duration(ms) * time.Millisecond
+	}
+	if c.db, err = openDatabase(path); err != nil {
+		return nil, fmt.Errorf("minisql: %w", err)
+	}
+	return c, nil
+}
+
+// conn is one connection. database/sql never uses a connection from
+// two goroutines at once, so its fields need no lock.
+type conn struct {
+	db   *database
+	busy time.Duration // how long to wait for a lock
+	tx   *writer       // the transaction in progress, if any
+}
+
+var (
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.StmtExecContext    = (*stmt)(nil)
+	_ driver.StmtQueryContext   = (*stmt)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext parses the query once; the statement runs it as often
+// as asked
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmts, params, err := parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("minisql: %w", err)
+	}
+	return &stmt{c: c, stmts: stmts, params: params}, nil
+}
+
+func (c *conn) Close() error {
+	if c.tx != nil {
+		c.tx.rollback(0)
+		c.tx = nil
+		c.db.lock.Unlock()
+	}
+	return c.db.release()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx takes the write lock at BEGIN, like SQLite's BEGIN
+// IMMEDIATE: two transactions never both read and then both try to
+// write
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("minisql: a transaction is already open")
+	}
+	if err := c.wait(ctx, c.db.lock.TryLock); err != nil {
+		return nil, err
+	}
+	c.tx = &writer{db: c.db}
+	return tx{c}, nil
+}
+
+// wait takes a lock, trying again until busy_timeout passes or ctx
+// ends
+func (c *conn) wait(ctx context.Context, try func() bool) error {
+	deadline := time.Now().Add(c.busy)
+	for !try() {
+		if !time.Now().Before(deadline) {
+			return sqlErrorf(codeBusy, "database is locked")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// run executes one statement: inside the open transaction if there is
+// one, otherwise on its own - a read under the shared lock, a write as
+// a transaction of one statement
+func (c *conn) run(ctx context.Context, s statement, named []driver.NamedValue) (*result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args, err := values(named)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := s.(pragmaStmt); ok && p.name == "busy_timeout" {
+		return c.busyTimeout(p, args)
+	}
+
+	if c.tx != nil {
+		// A failed statement leaves the transaction as it was before it
+		mark := len(c.tx.changes)
+		res, err := c.db.run(c.tx, s, args)
+		if err != nil {
+			c.tx.rollback(mark)
+		}
+		return res, err
+	}
+	if !s.writes() {
+		if err := c.wait(ctx, c.db.lock.TryRLock); err != nil {
+			return nil, err
+		}
+		defer c.db.lock.RUnlock()
+		return c.db.run(nil, s, args)
+	}
+	if err := c.wait(ctx, c.db.lock.TryLock); err != nil {
+		return nil, err
+	}
+	defer c.db.lock.Unlock()
+	w := &writer{db: c.db}
+	res, err := c.db.run(w, s, args)
+	if err != nil {
+		w.rollback(0)
+		return nil, err
+	}
+	return res, w.commit()
+}
+
+// busyTimeout reads or sets the connection's lock timeout in
+// milliseconds
+func (c *conn) busyTimeout(p pragmaStmt, args []any) (*result, error) {
+	if p.value != nil {
+		v, err := eval(p.value, env{}, args)
+		ms, ok := v.(int64)
+		if err != nil || !ok {
+			return nil, sqlErrorf(codeMismatch, "busy_timeout must be an integer")
+		}
+		c.busy = time.Duration(ms) * time.Millisecond
+		return &result{}, nil
+	}
+	return &result{cols: []string{"busy_timeout"}, rows: [][]any{{c.busy.Milliseconds()}}}, nil
+}
+
+// values unwraps the arguments. database/sql has already converted
+// them to driver.Value - int to int64, a *string to its string or nil.
+func values(named []driver.NamedValue) ([]any, error) {
+	args := make([]any, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, fmt.Errorf("minisql: named argument %s: use ? or ?N", nv.Name)
+		}
+		switch v := nv.Value.(type) {
+		case nil, int64, string:
+			args[i] = v
+		case bool:
+			args[i] = boolean(v)
+		case []byte:
+			args[i] = string(v)
+		default:
+			return nil, fmt.Errorf("minisql: argument %d: %T is not supported", nv.Ordinal, v)
+		}
+	}
+	return args, nil
+}
+
+type tx struct{ c *conn }
+
+func (t tx) Commit() error {
+	w := t.c.tx
+	t.c.tx = nil
+	defer t.c.db.lock.Unlock()
+	return w.commit()
+}
+
+func (t tx) Rollback() error {
+	w := t.c.tx
+	t.c.tx = nil
+	defer t.c.db.lock.Unlock()
+	w.rollback(0)
+	return nil
+}
+
+// stmt is a parsed query. Exec runs every statement in it, so a
+// migration can be several statements separated by semicolons.
+type stmt struct {
+	c      *conn
+	stmts  []statement
+	params int
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return s.params }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var res *result
+	for _, st := range s.stmts {
+		r, err := s.c.run(ctx, st, args)
+		if err != nil {
+			return nil, err
+		}
+		res = r
+	}
+	return execResult{res.lastID, res.affected}, nil
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if len(s.stmts) != 1 {
+		return nil, errors.New("minisql: a query must be one statement")
+	}
+	res, err := s.c.run(ctx, s.stmts[0], args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{cols: res.cols, data: res.rows}, nil
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
+type execResult struct{ lastID, affected int64 }
+
+func (r execResult) LastInsertId() (int64, error) { return r.lastID, nil }
+func (r execResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+// rows hands out a result that was read in full under the lock, so an
+// open *sql.Rows never holds up a writer
+type rows struct {
+	cols []string
+	data [][]any
+}
+
+func (r *rows) Columns() []string { return r.cols }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.data) == 0 {
+		return io.EOF
+	}
+	for i, v := range r.data[0] {
+		dest[i] = v
+	}
+	r.data = r.data[1:]
+	return nil
+}
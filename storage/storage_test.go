@@ -0,0 +1,623 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// database/sql With a Driver - Tests
+// ==================================
+// Run with:
+//
+//   cd storage
+//   go test -v *.go
+//   go test -race *.go
+//
+// Each test opens a fresh database file under t.TempDir(): a real file,
+// the same driver and SQL as the package uses, and no containers or
+// servers to start. The directory is removed when the test ends.
+
+func openTemp(t *testing.T) (*sql.DB, *Store) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "ledger.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStore(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+		db.Close()
+	})
+	return db, s
+}
+
+func mustCreate(t *testing.T, s *Store, name string, balance int64) Account {
+	t.Helper()
+	a, err := s.CreateAccount(context.Background(), name, balance, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// 1. Opening and Migrating
+// ========================
+
+func TestOpenAppliesSettings(t *testing.T) {
+	db, _ := openTemp(t)
+	ctx := context.Background()
+
+	// Settings from the DSN are in force on the pool's connections
+	var busy int
+	if err := db.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busy); err != nil || busy != 5000 {
+		t.Errorf("PRAGMA busy_timeout = %d, %v; want 5000", busy, err)
+	}
+
+	var version int
+	db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	if version != len(migrations) {
+		t.Errorf("user_version = %d, want %d", version, len(migrations))
+	}
+	if stats := db.Stats(); stats.MaxOpenConnections != maxOpenConns {
+		t.Errorf("MaxOpenConnections = %d, want %d", stats.MaxOpenConnections, maxOpenConns)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ledger.db")
+
+	db, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := NewStore(ctx, db)
+	s.CreateAccount(ctx, "kept", 100, nil)
+	s.Close()
+	db.Close()
+
+	// Reopening runs no migration twice and keeps the data
+	db, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	var n int
+	db.QueryRowContext(ctx, "SELECT count(*) FROM accounts").Scan(&n)
+	if n != 1 {
+		t.Errorf("%d accounts after reopening, want 1", n)
+	}
+
+	// A database from a newer program is refused, not downgraded
+	db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", len(migrations)+1))
+	db.Close()
+	if _, err := Open(ctx, path); err == nil {
+		t.Error("opened a database newer than the migrations")
+	}
+}
+
+func TestOpenBadPath(t *testing.T) {
+	// sql.Open alone would succeed; Open pings, so this fails now
+	_, err := Open(context.Background(), filepath.Join(t.TempDir(), "missing", "dir", "x.db"))
+	if err == nil {
+		t.Error("Open in a missing directory succeeded")
+	}
+}
+
+// 2. Queries and Scanning
+// =======================
+
+func TestCreateAndGet(t *testing.T) {
+	_, s := openTemp(t)
+	ctx := context.Background()
+	email := "ada@example.com"
+
+	created, err := s.CreateAccount(ctx, "ada", 1000, &email)
+	if err != nil || created.ID == 0 {
+		t.Fatalf("CreateAccount = %+v, %v", created, err)
+	}
+	got, err := s.GetAccount(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "ada" || got.Balance != 1000 || got.Email == nil || *got.Email != email || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("GetAccount = %+v, want %+v", got, created)
+	}
+
+	// NULL stays nil, and "" stays ""
+	empty := ""
+	noEmail := mustCreate(t, s, "bob", 0)
+	blank, _ := s.CreateAccount(ctx, "cy", 0, &empty)
+	if a, _ := s.GetAccount(ctx, noEmail.ID); a.Email != nil {
+		t.Errorf("NULL email scanned as %q", *a.Email)
+	}
+	if a, _ := s.GetAccount(ctx, blank.ID); a.Email == nil || *a.Email != "" {
+		t.Errorf("empty email scanned as %v", a.Email)
+	}
+}
+
+func TestErrorsAreTranslated(t *testing.T) {
+	_, s := openTemp(t)
+	ctx := context.Background()
+	mustCreate(t, s, "ada", 0)
+
+	if _, err := s.GetAccount(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing account: %v", err)
+	}
+	if _, err := s.CreateAccount(ctx, "ada", 0, nil); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("duplicate name: %v", err)
+	}
+	// The CHECK constraint is not one we translate: it is a bug in the
+	// caller, and the driver's error says so
+	if _, err := s.CreateAccount(ctx, "neg", -1, nil); err == nil || errors.Is(err, ErrDuplicate) {
+		t.Errorf("negative balance: %v", err)
+	}
+}
+
+func TestListAccounts(t *testing.T) {
+	_, s := openTemp(t)
+	for _, name := range []string{"team_b", "team-a", "teamX", "other", "100%"} {
+		mustCreate(t, s, name, 0)
+	}
+
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"team", "[team-a teamX team_b]"},
+		{"team_", "[team_b]"}, // _ is literal, not "any character"
+		{"100%", "[100%]"},
+		{"", "[100% other team-a teamX team_b]"},
+		{"nobody", "[]"},
+	}
+	for _, tt := range tests {
+		accounts, err := s.ListAccounts(context.Background(), tt.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, a := range accounts {
+			names = append(names, a.Name)
+		}
+		if got := fmt.Sprint(names); got != tt.want {
+			t.Errorf("ListAccounts(%q) = %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceholdersAreNotSQL(t *testing.T) {
+	_, s := openTemp(t)
+	evil := "x'); DROP TABLE accounts; --"
+	a := mustCreate(t, s, evil, 0)
+	got, err := s.GetAccount(context.Background(), a.ID)
+	if err != nil || got.Name != evil {
+		t.Errorf("stored name = %q, %v", got.Name, err)
+	}
+}
+
+// 3. Transactions
+// ===============
+
+func TestTransfer(t *testing.T) {
+	_, s := openTemp(t)
+	ctx := context.Background()
+	ada, bob := mustCreate(t, s, "ada", 1000), mustCreate(t, s, "bob", 0)
+
+	tr, err := s.Transfer(ctx, ada.ID, bob.ID, 300)
+	if err != nil || tr.ID == 0 {
+		t.Fatalf("Transfer = %+v, %v", tr, err)
+	}
+	assertBalances(t, s, map[int64]int64{ada.ID: 700, bob.ID: 300})
+
+	list, err := s.TransfersFor(ctx, bob.ID, 10)
+	if err != nil || len(list) != 1 || list[0] != tr {
+		t.Errorf("TransfersFor = %+v, %v; want [%+v]", list, err, tr)
+	}
+}
+
+func TestTransferRollsBack(t *testing.T) {
+	_, s := openTemp(t)
+	ctx := context.Background()
+	ada, bob := mustCreate(t, s, "ada", 100), mustCreate(t, s, "bob", 0)
+
+	tests := []struct {
+		name     string
+		from, to int64
+		amount   int64
+		want     error
+	}{
+		{"too much", ada.ID, bob.ID, 101, ErrInsufficientFunds},
+		{"no sender", 999, bob.ID, 1, ErrNotFound},
+		// The debit has already happened when the credit finds no
+		// account: the rollback must undo it
+		{"no receiver", ada.ID, 999, 50, ErrNotFound},
+		{"zero", ada.ID, bob.ID, 0, ErrInvalidAmount},
+		{"to self", ada.ID, ada.ID, 10, ErrSameAccount},
+	}
+	for _, tt := range tests {
+		if _, err := s.Transfer(ctx, tt.from, tt.to, tt.amount); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	assertBalances(t, s, map[int64]int64{ada.ID: 100, bob.ID: 0})
+	if list, _ := s.TransfersFor(ctx, ada.ID, 10); len(list) != 0 {
+		t.Errorf("failed transfers were recorded: %+v", list)
+	}
+}
+
+func TestTransferCancelled(t *testing.T) {
+	_, s := openTemp(t)
+	ada, bob := mustCreate(t, s, "ada", 100), mustCreate(t, s, "bob", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Transfer(ctx, ada.ID, bob.ID, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled transfer: %v", err)
+	}
+	assertBalances(t, s, map[int64]int64{ada.ID: 100, bob.ID: 0})
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	db, s := openTemp(t)
+	ctx := context.Background()
+	ada := mustCreate(t, s, "ada", 100)
+
+	func() {
+		defer func() { recover() }()
+		withTx(ctx, db, func(tx *sql.Tx) error {
+			tx.ExecContext(ctx, `UPDATE accounts SET balance = 0 WHERE id = ?`, ada.ID)
+			panic("halfway")
+		})
+	}()
+	assertBalances(t, s, map[int64]int64{ada.ID: 100})
+
+	// The connection went back to the pool, not leaked with the tx
+	if stats := db.Stats(); stats.InUse != 0 {
+		t.Errorf("%d connections still in use", stats.InUse)
+	}
+}
+
+func TestConcurrentTransfers(t *testing.T) {
+	// Writers queue on the write lock instead of failing: it is taken
+	// at BEGIN, and busy_timeout waits for it. Money is neither created
+	// nor lost.
+	_, s := openTemp(t)
+	ctx := context.Background()
+	accounts := []Account{mustCreate(t, s, "a", 1000), mustCreate(t, s, "b", 1000), mustCreate(t, s, "c", 1000)}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ok, poor int
+	for i := range 60 {
+		wg.Go(func() {
+			from, to := accounts[i%3], accounts[(i+1)%3]
+			_, err := s.Transfer(ctx, from.ID, to.ID, int64(50+i*7))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				ok++
+			case errors.Is(err, ErrInsufficientFunds):
+				poor++
+			default:
+				t.Errorf("transfer %d: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	var total int64
+	for _, a := range accounts {
+		got, _ := s.GetAccount(ctx, a.ID)
+		if got.Balance < 0 {
+			t.Errorf("%s went negative: %d", got.Name, got.Balance)
+		}
+		total += got.Balance
+	}
+	if total != 3000 || ok+poor != 60 {
+		t.Errorf("total = %d after %d transfers and %d refusals, want 3000", total, ok, poor)
+	}
+}
+
+func assertBalances(t *testing.T, s *Store, want map[int64]int64) {
+	t.Helper()
+	for id, balance := range want {
+		a, err := s.GetAccount(context.Background(), id)
+		if err != nil || a.Balance != balance {
+			t.Errorf("account %d balance = %d, %v; want %d", id, a.Balance, err, balance)
+		}
+	}
+}
+
+// 4. The Driver
+// =============
+// minisql is small, but the behaviour the store relies on is SQLite's,
+// and these tests pin it down.
+
+func TestLogReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ledger.db")
+	db, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := NewStore(ctx, db)
+	ada := mustCreate(t, s, "ada", 100)
+	bob := mustCreate(t, s, "bob", 0)
+	if _, err := s.Transfer(ctx, ada.ID, bob.ID, 30); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	db.Close()
+
+	// A crash in the middle of a commit leaves half a line
+	committed, _ := os.ReadFile(path)
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`[{"table":"accounts","id":1,"row":[1,"ada",`)
+	f.Close()
+
+	db, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	s, _ = NewStore(ctx, db)
+	defer s.Close()
+	assertBalances(t, s, map[int64]int64{ada.ID: 70, bob.ID: 30})
+	if after, _ := os.ReadFile(path); string(after) != string(committed) {
+		t.Errorf("the torn line was kept:\n%s", after[len(committed):])
+	}
+}
+
+func TestFailedStatementKeepsTransaction(t *testing.T) {
+	db, s := openTemp(t)
+	ctx := context.Background()
+	ada, bob := mustCreate(t, s, "ada", 10), mustCreate(t, s, "bob", 0)
+
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance + 5 WHERE id = ?`, ada.ID); err != nil {
+			return err
+		}
+		// ada can pay 10, bob cannot: the CHECK fails on bob's row and
+		// undoes the whole statement, but not the one before it
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance - 10`); !isConstraint(err, codeCheck) {
+			t.Errorf("overdraft: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBalances(t, s, map[int64]int64{ada.ID: 15, bob.ID: 0})
+}
+
+func TestBusyTimeout(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("minisql", "file:"+filepath.Join(t.TempDir(), "busy.db")+"?busy_timeout=20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// A second writer waits out busy_timeout, then gives up
+	start := time.Now()
+	_, err = db.BeginTx(ctx, nil)
+	var se *sqlError
+	if !errors.As(err, &se) || se.code != codeBusy {
+		t.Errorf("second BEGIN: %v", err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("gave up after %v", waited)
+	}
+}
+
+func TestNull(t *testing.T) {
+	_, s := openTemp(t)
+	ctx := context.Background()
+	email := "ada@example.com"
+	s.CreateAccount(ctx, "ada", 0, &email)
+	mustCreate(t, s, "bob", 0)
+
+	// NULL is not equal to anything, NULL included: = NULL matches no
+	// row, and <> matches only the rows with a value
+	for query, want := range map[string]int{
+		`SELECT count(*) FROM accounts WHERE email = NULL`:                 0,
+		`SELECT count(*) FROM accounts WHERE email <> 'x'`:                 1,
+		`SELECT count(*) FROM accounts WHERE email = 'x' OR balance = 0`:   2,
+		`SELECT count(*) FROM accounts WHERE email <> 'x' AND balance = 0`: 1,
+	} {
+		var n int
+		if err := s.db.QueryRowContext(ctx, query).Scan(&n); err != nil || n != want {
+			t.Errorf("%s = %d, %v; want %d", query, n, err, want)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ledger.db")
+	db, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := NewStore(ctx, db)
+	ada, bob, cyd := mustCreate(t, s, "ada", 10), mustCreate(t, s, "bob", 0), mustCreate(t, s, "cyd", 0)
+	if _, err := s.Transfer(ctx, ada.ID, bob.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	// A transfer still refers to ada
+	if _, err := db.ExecContext(ctx, `DELETE FROM accounts WHERE id = ?`, ada.ID); !isConstraint(err, codeForeignKey) {
+		t.Errorf("delete ada: %v", err)
+	}
+	// A rolled-back delete puts the row back
+	tx, _ := db.BeginTx(ctx, nil)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM accounts WHERE id = ?`, bob.ID+100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM accounts WHERE name = 'cyd'`); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if _, err := s.GetAccount(ctx, cyd.ID); err != nil {
+		t.Errorf("after rollback: %v", err)
+	}
+
+	res, err := db.ExecContext(ctx, `DELETE FROM accounts WHERE id = ?`, cyd.ID)
+	if n, _ := res.RowsAffected(); err != nil || n != 1 {
+		t.Fatalf("delete cyd: %d rows, %v", n, err)
+	}
+	s.Close()
+	db.Close()
+
+	// and the log replays it
+	db, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	s, _ = NewStore(ctx, db)
+	defer s.Close()
+	if _, err := s.GetAccount(ctx, cyd.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cyd after reopen: %v", err)
+	}
+	assertBalances(t, s, map[int64]int64{ada.ID: 5, bob.ID: 5})
+}
+
+func TestLike(t *testing.T) {
+	tests := []struct {
+		s, pattern string
+		want       bool
+	}{
+		{"team_b", "team%", true},
+		{"TEAM", "team", true}, // ASCII case is ignored, as in SQLite
+		{"teamX", `team\_%`, false},
+		{"team_b", `team\_%`, true},
+		{"100%", `100\%`, true},
+		{"abc", "a_c", true},
+		{"ac", "a_c", false},
+		{"", "%", true},
+	}
+	for _, tt := range tests {
+		if got := like([]rune(tt.s), []rune(tt.pattern), '\\'); got != tt.want {
+			t.Errorf("%q LIKE %q = %v", tt.s, tt.pattern, got)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		`SELECT FROM accounts`,
+		`SELECT id FROM accounts WHERE`,
+		`INSERT INTO accounts (name) VALUES (?, ?)`,
+		`SELECT 'unterminated FROM accounts`,
+		`DROP TABLE accounts`,
+		`CREATE TABLE t (x REAL)`,
+	} {
+		if _, _, err := parse(query); err == nil {
+			t.Errorf("parsed %q", query)
+		}
+	}
+	// A typo in a column fails even when no row is scanned
+	_, s := openTemp(t)
+	if _, err := s.db.QueryContext(context.Background(), `SELECT id FROM accounts WHERE nmae = ?`, "x"); err == nil {
+		t.Error("unknown column accepted")
+	}
+}
+
+// 5. Benchmarks
+// =============
+
+func BenchmarkGetAccount(b *testing.B) {
+	// The prepared statement against the same query re-parsed each time
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	s, _ := NewStore(ctx, db)
+	defer s.Close()
+	a, _ := s.CreateAccount(ctx, "bench", 1, nil)
+
+	b.Run("prepared", func(b *testing.B) {
+		for b.Loop() {
+			s.GetAccount(ctx, a.ID)
+		}
+	})
+	b.Run("ad hoc", func(b *testing.B) {
+		for b.Loop() {
+			scanAccount(db.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = ?`, a.ID))
+		}
+	})
+}
+
+func BenchmarkTransfer(b *testing.B) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	s, _ := NewStore(ctx, db)
+	defer s.Close()
+	from, _ := s.CreateAccount(ctx, "from", 1<<62, nil)
+	to, _ := s.CreateAccount(ctx, "to", 0, nil)
+
+	// Each commit is a log append and an fsync
+	for b.Loop() {
+		if _, err := s.Transfer(ctx, from.ID, to.ID, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Examples
+// ========
+
+func ExampleStore_Transfer() {
+	ctx := context.Background()
+	dir, _ := os.MkdirTemp("", "storage-example")
+	defer os.RemoveAll(dir)
+	db, err := Open(ctx, filepath.Join(dir, "example.db"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	s, _ := NewStore(ctx, db)
+	defer s.Close()
+	s.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	ada, _ := s.CreateAccount(ctx, "ada", 500, nil)
+	bob, _ := s.CreateAccount(ctx, "bob", 0, nil)
+	s.Transfer(ctx, ada.ID, bob.ID, 200)
+	_, err = s.Transfer(ctx, bob.ID, ada.ID, 300)
+	fmt.Println(err)
+
+	for _, id := range []int64{ada.ID, bob.ID} {
+		a, _ := s.GetAccount(ctx, id)
+		fmt.Println(a.Name, a.Balance)
+	}
+	// Output:
+	// account 2 has 200, needs 300: storage: insufficient funds
+	// ada 300
+	// bob 200
+}
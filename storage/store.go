@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The Store
+// =========
+// Store keeps the queries it runs often as prepared statements: parsed
+// and planned once, then executed with new arguments. database/sql
+// prepares each statement lazily on every pooled connection it lands
+// on, so one *sql.Stmt is safe to share across goroutines.
+//
+// Every query uses placeholders (?) for values. Arguments are sent
+// separately from the SQL text, so no value can change the statement -
+// building SQL with fmt.Sprintf and user input is an injection waiting
+// to happen.
+//
+// Errors callers can act on are translated into this package's own:
+// sql.ErrNoRows becomes ErrNotFound, a UNIQUE violation ErrDuplicate.
+// Callers never import the driver to inspect its error codes.
+
+var (
+	ErrNotFound          = errors.New("storage: not found")
+	ErrDuplicate         = errors.New("storage: already exists")
+	ErrInsufficientFunds = errors.New("storage: insufficient funds")
+	ErrInvalidAmount     = errors.New("storage: amount must be positive")
+	ErrSameAccount       = errors.New("storage: transfer to the same account")
+)
+
+// Account is one row of accounts. Email is nullable: NULL and "" are
+// different values in SQL, and a *string keeps them apart.
+type Account struct {
+	ID        int64
+	Name      string
+	Balance   int64 // cents
+	Email     *string
+	CreatedAt time.Time
+}
+
+// Transfer is one row of transfers
+type Transfer struct {
+	ID        int64
+	FromID    int64
+	ToID      int64
+	Amount    int64
+	CreatedAt time.Time
+}
+
+// Store runs the application's queries against a *sql.DB
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+
+	getAccount *sql.Stmt
+	debit      *sql.Stmt
+	credit     *sql.Stmt
+	addTx      *sql.Stmt
+}
+
+// NewStore prepares the store's statements on db. Close releases them;
+// the caller still owns db.
+func NewStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	s := &Store{db: db, now: time.Now}
+	stmts := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&s.getAccount, `SELECT ` + accountColumns + ` FROM accounts WHERE id = ?`},
+		// The balance check is in the WHERE clause, so checking and
+		// debiting are one statement: no other transfer can slip
+		// between them
+		{&s.debit, `UPDATE accounts SET balance = balance - ? WHERE id = ? AND balance >= ?`},
+		{&s.credit, `UPDATE accounts SET balance = balance + ? WHERE id = ?`},
+		{&s.addTx, `INSERT INTO transfers (from_id, to_id, amount, created_at) VALUES (?, ?, ?, ?) RETURNING id`},
+	}
+	for _, st := range stmts {
+		stmt, err := db.PrepareContext(ctx, st.query)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("prepare %q: %w", st.query, err)
+		}
+		*st.dst = stmt
+	}
+	return s, nil
+}
+
+// Close closes the prepared statements
+func (s *Store) Close() error {
+	var errs []error
+	for _, stmt := range []*sql.Stmt{s.getAccount, s.debit, s.credit, s.addTx} {
+		if stmt != nil {
+			errs = append(errs, stmt.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CreateAccount inserts an account. RETURNING hands back the new row's
+// id in the same round trip; LastInsertId would work too here and on
+// SQLite, but not on every database.
+func (s *Store) CreateAccount(ctx context.Context, name string, balance int64, email *string) (Account, error) {
+	a := Account{Name: name, Balance: balance, Email: email, CreatedAt: s.now().Truncate(time.Millisecond)}
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO accounts (name, balance, email, created_at) VALUES (?, ?, ?, ?) RETURNING id`,
+		a.Name, a.Balance, a.Email, a.CreatedAt.UnixMilli(),
+	).Scan(&a.ID)
+	if isConstraint(err, codeUnique) {
+		return Account{}, fmt.Errorf("account %q: %w", name, ErrDuplicate)
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+// GetAccount returns one account or ErrNotFound
+func (s *Store) GetAccount(ctx context.Context, id int64) (Account, error) {
+	a, err := scanAccount(s.getAccount.QueryRowContext(ctx, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Account{}, fmt.Errorf("account %d: %w", id, ErrNotFound)
+	}
+	return a, err
+}
+
+// ListAccounts returns accounts whose name starts with prefix, by name.
+// LIKE treats % and _ as wildcards, so they are escaped in the prefix.
+func (s *Store) ListAccounts(ctx context.Context, prefix string) ([]Account, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE name LIKE ? ESCAPE '\' ORDER BY name`,
+		escaped+"%",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanAccount)
+}
+
+// Transfer moves amount cents between two accounts in one transaction:
+// both balances change and the transfer is recorded, or nothing is.
+// The prepared statements join the transaction through tx.StmtContext.
+func (s *Store) Transfer(ctx context.Context, fromID, toID, amount int64) (Transfer, error) {
+	if amount <= 0 {
+		return Transfer{}, ErrInvalidAmount
+	}
+	if fromID == toID {
+		return Transfer{}, ErrSameAccount
+	}
+	t := Transfer{FromID: fromID, ToID: toID, Amount: amount, CreatedAt: s.now().Truncate(time.Millisecond)}
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		res, err := tx.StmtContext(ctx, s.debit).ExecContext(ctx, amount, fromID, amount)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			// No row matched: the account is missing or too poor. One
+			// more query, inside the same transaction, tells which.
+			var balance int64
+			err := tx.QueryRowContext(ctx, `SELECT balance FROM accounts WHERE id = ?`, fromID).Scan(&balance)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("account %d: %w", fromID, ErrNotFound)
+			}
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("account %d has %d, needs %d: %w", fromID, balance, amount, ErrInsufficientFunds)
+		}
+
+		res, err = tx.StmtContext(ctx, s.credit).ExecContext(ctx, amount, toID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("account %d: %w", toID, ErrNotFound)
+		}
+
+		return tx.StmtContext(ctx, s.addTx).QueryRowContext(ctx, fromID, toID, amount, t.CreatedAt.UnixMilli()).Scan(&t.ID)
+	})
+	if err != nil {
+		return Transfer{}, err
+	}
+	return t, nil
+}
+
+// TransfersFor lists an account's transfers in either direction,
+// newest first, at most limit of them
+func (s *Store) TransfersFor(ctx context.Context, accountID int64, limit int) ([]Transfer, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, from_id, to_id, amount, created_at FROM transfers
+		 WHERE from_id = ?1 OR to_id = ?1 ORDER BY id DESC LIMIT ?2`,
+		accountID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, func(sc scanner) (Transfer, error) {
+		var t Transfer
+		var created int64
+		err := sc.Scan(&t.ID, &t.FromID, &t.ToID, &t.Amount, &created)
+		t.CreatedAt = time.UnixMilli(created)
+		return t, err
+	})
+}
+
+// Scanning Into Structs
+// =====================
+// Scan copies columns into pointers, in SELECT order. Keeping the
+// column list and the Scan call together - accountColumns and
+// scanAccount - means they change together. *sql.Row and *sql.Rows
+// both have Scan, so one function serves single rows and lists.
+
+const accountColumns = `id, name, balance, email, created_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAccount(sc scanner) (Account, error) {
+	var a Account
+	var email sql.Null[string] // NULL becomes Valid == false
+	var created int64
+	if err := sc.Scan(&a.ID, &a.Name, &a.Balance, &email, &created); err != nil {
+		return Account{}, err
+	}
+	if email.Valid {
+		a.Email = &email.V
+	}
+	a.CreatedAt = time.UnixMilli(created)
+	return a, nil
+}
+
+// collect scans every row and closes rows. rows.Err is checked after
+// the loop: Next returning false means "done" or "failed", and only Err
+// says which.
+func collect[T any](rows *sql.Rows, scan func(scanner) (T, error)) ([]T, error) {
+	defer rows.Close()
+	var out []T
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// isConstraint reports whether err is the driver's constraint failure
+// with the given code. With SQLite this would compare
+// (*sqlite.Error).Code() to SQLITE_CONSTRAINT_UNIQUE and friends.
+func isConstraint(err error, code errCode) bool {
+	var se *sqlError
+	return errors.As(err, &se) && se.code == code
+}
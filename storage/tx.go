@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Transactions
+// ============
+// BeginTx pins one connection from the pool until Commit or Rollback;
+// every statement in between must go through the *sql.Tx, not the
+// *sql.DB, or it runs on another connection outside the transaction.
+//
+// withTx owns the pattern so callers cannot get it wrong: Rollback is
+// deferred, and after a successful Commit it is a no-op returning
+// sql.ErrTxDone. If the context ends first, database/sql rolls back by
+// itself and Commit fails.
+
+// withTx runs fn in a transaction, committing if it returns nil and
+// rolling back otherwise - including when fn panics
+func withTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) && err == nil {
+			err = rbErr
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// concat - Tests
+// ==============
+// Run with:
+//
+//   cd strings-bytes/concat
+//   go test -v *.go
+//   go test -run '^$' -bench . -benchmem *.go
+//
+// The timings vary by machine, so the tests pin what does not: the
+// strategies agree, the allocation counts that follow from how each one
+// works, and the shape of the tables.
+
+// 1. The Strategies
+// =================
+
+func TestStrategiesAgree(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 37} {
+		for _, length := range []int{0, 1, 8} {
+			parts := Parts(n, length)
+			want := strings.Join(parts, " ")
+			for _, s := range Strategies {
+				if got := s.Build(parts); got != want {
+					t.Errorf("%s(%d parts of %d): %q, want %q", s.Name, n, length, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestAllocations(t *testing.T) {
+	parts := Parts(100, 8)
+	tests := map[string]float64{
+		"+=":             99, // one per step after the first
+		"Builder + Grow": 1,
+		"strings.Join":   1,
+	}
+	for _, s := range Strategies {
+		want, ok := tests[s.Name]
+		if !ok {
+			continue
+		}
+		if got := testing.AllocsPerRun(20, func() { stringSink = s.Build(parts) }); got != want {
+			t.Errorf("%s: %.0f allocs, want %.0f", s.Name, got, want)
+		}
+	}
+	// Amortized growth: far fewer than one per part, more than one
+	for _, name := range []string{"strings.Builder", "bytes.Buffer"} {
+		i := slices.IndexFunc(Strategies, func(s Strategy) bool { return s.Name == name })
+		got := testing.AllocsPerRun(20, func() { stringSink = Strategies[i].Build(parts) })
+		if got <= 1 || got > 20 {
+			t.Errorf("%s: %.0f allocs, want a few", name, got)
+		}
+	}
+}
+
+func TestFormattersAgree(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 37} {
+		var nums []string
+		for i := range n {
+			nums = append(nums, strconv.Itoa(i))
+		}
+		want := strings.Join(nums, " ")
+		for _, f := range Formatters {
+			if got := f.Build(n); got != want {
+				t.Errorf("%s(%d): %q, want %q", f.Name, n, got, want)
+			}
+		}
+	}
+}
+
+// Sprintf allocates a string per number, Fprintf only grows the
+// Builder, and AppendInt allocates the []byte and the string
+func TestFormatterAllocations(t *testing.T) {
+	allocs := func(name string) float64 {
+		i := slices.IndexFunc(Formatters, func(f Formatter) bool { return f.Name == name })
+		return testing.AllocsPerRun(20, func() { stringSink = Formatters[i].Build(100) })
+	}
+	if got := allocs("Builder + fmt.Sprintf"); got < 90 {
+		t.Errorf("Builder + fmt.Sprintf: %.0f allocs, want about one per number", got)
+	}
+	if got := allocs("fmt.Fprintf(&Builder)"); got > 20 {
+		t.Errorf("fmt.Fprintf(&Builder): %.0f allocs, want a few", got)
+	}
+	if got := allocs("strconv.AppendInt"); got != 2 {
+		t.Errorf("strconv.AppendInt: %.0f allocs, want 2", got)
+	}
+}
+
+func TestParts(t *testing.T) {
+	got := Parts(3, 4)
+	if want := []string{"p000", "p001", "p002"}; !slices.Equal(got, want) {
+		t.Errorf("Parts(3, 4) = %q, want %q", got, want)
+	}
+	for _, p := range Parts(1000, 8) {
+		if len(p) != 8 {
+			t.Fatalf("%q is not 8 bytes", p)
+		}
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	got, err := parseSizes("2, 10,100")
+	if err != nil || !slices.Equal(got, []int{2, 10, 100}) {
+		t.Errorf("got %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "2,,3", "ten", "-1"} {
+		if _, err := parseSizes(bad); err == nil {
+			t.Errorf("parseSizes(%q) succeeded", bad)
+		}
+	}
+}
+
+// 2. The Tables
+// =============
+
+// result fakes a benchmark that took ns per op with allocs allocations
+func result(name string, parts int, ns, allocs int64) Result {
+	return Result{name, parts, testing.BenchmarkResult{
+		N: 1000, T: time.Duration(ns * 1000), MemAllocs: uint64(allocs * 1000), MemBytes: uint64(allocs * 8000),
+	}}
+}
+
+func TestWriteTables(t *testing.T) {
+	results := []Result{
+		result("+=", 2, 60, 1), result("strings.Builder", 2, 100, 2), result("strings.Join", 2, 50, 1),
+		result("+=", 100, 9000, 99), result("strings.Builder", 100, 2000, 9), result("strings.Join", 100, 1000, 1),
+	}
+	var buf bytes.Buffer
+	WriteTables(&buf, results, []int{2, 100}, 8)
+	out := buf.String()
+	for _, want := range []string{
+		"| strategy | 2 parts | 100 parts |\n|---|---:|---:|\n",
+		"| += | 60 (1.2x) | 9000 (9.0x) |",
+		"| strings.Join | 1, 8 | 1, 8 |",
+		"- 2 parts: fastest strings.Join, then +=; slowest strings.Builder at 2.0x",
+		"- += is 4.5x strings.Builder from 100 parts on",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestFindingsWithoutCrossover(t *testing.T) {
+	var buf bytes.Buffer
+	WriteTables(&buf, []Result{result("+=", 2, 60, 1), result("strings.Builder", 2, 100, 2)}, []int{2}, 8)
+	if !strings.Contains(buf.String(), "+= stays within 2x") {
+		t.Errorf("got\n%s", buf.String())
+	}
+}
+
+// results.md is a sample run; it must still list every strategy
+func TestResultsFile(t *testing.T) {
+	data, err := os.ReadFile("results.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range Strategies {
+		if n := bytes.Count(data, []byte("| "+s.Name+" |")); n != 2 {
+			t.Errorf("results.md has %d rows for %s, want 2: go run main.go strategies.go -o results.md", n, s.Name)
+		}
+	}
+}
+
+// 3. Benchmarks
+// =============
+
+func BenchmarkConcat(b *testing.B) {
+	for _, n := range []int{2, 10, 100, 1000} {
+		parts := Parts(n, 8)
+		for _, s := range Strategies {
+			b.Run(fmt.Sprintf("parts=%d/%s", n, s.Name), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					stringSink = s.Build(parts)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	for _, f := range Formatters {
+		b.Run(f.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				stringSink = f.Build(100)
+			}
+		})
+	}
+}
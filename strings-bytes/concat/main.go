@@ -0,0 +1,246 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// String Concatenation - A Benchmark Suite
+// ========================================
+// This suite runs every way of building a string in strategies.go
+// across sizes and writes the results as Markdown tables, because the
+// answer depends on the size: += is fine for three parts and quadratic
+// for a thousand. The ways of writing numbers, also in strategies.go,
+// are compared by the tests and by BenchmarkFormat.
+//
+// Run with:
+//
+//	cd strings-bytes/concat
+//	go run main.go strategies.go                 the tables on stdout
+//	go run main.go strategies.go -sizes 2,50,5000 -o out.md
+//	go run main.go strategies.go -test.benchtime=1s   steadier, slower
+//	go test -v *.go
+//	go test -run '^$' -bench Format -benchmem *.go
+//
+// results.md is one run on one machine, kept as a sample of the
+// output. Regenerate it with:
+//
+//	go run main.go strategies.go -o results.md
+
+var (
+	sizesFlag = flag.String("sizes", "2,10,100,1000", "comma-separated part counts")
+	partLen   = flag.Int("len", 8, "bytes per part")
+	outFlag   = flag.String("o", "", "write the tables to this file instead of stdout")
+)
+
+// stringSink keeps benchmark results alive
+var stringSink string
+
+func main() {
+	testing.Init()
+	flag.Parse()
+	if !flagSet("test.benchtime") {
+		flag.Set("test.benchtime", "200ms")
+	}
+	sizes, err := parseSizes(*sizesFlag)
+	if err != nil {
+		fail(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outFlag != "" {
+		f, err := os.Create(*outFlag)
+		if err != nil {
+			fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	results, err := Measure(Strategies, sizes, *partLen)
+	if err != nil {
+		fail(err)
+	}
+	WriteTables(w, results, sizes, *partLen)
+	if *outFlag != "" {
+		fmt.Println("wrote", *outFlag)
+	}
+}
+
+// Result is one strategy at one size
+type Result struct {
+	Strategy string
+	Parts    int
+	Bench    testing.BenchmarkResult
+}
+
+// Parts returns n strings of length bytes each: "p0000001" for 8
+func Parts(n, length int) []string {
+	parts := make([]string, n)
+	for i := range parts {
+		s := fmt.Sprintf("p%0*d", max(length-1, 0), i)
+		parts[i] = s[len(s)-min(length, len(s)):]
+	}
+	return parts
+}
+
+// Measure checks that every strategy builds the same string as
+// strings.Join at each size, then benchmarks it
+func Measure(strategies []Strategy, sizes []int, length int) ([]Result, error) {
+	var results []Result
+	for _, n := range sizes {
+		parts := Parts(n, length)
+		want := strings.Join(parts, " ")
+		for _, s := range strategies {
+			if got := s.Build(parts); got != want {
+				return nil, fmt.Errorf("%s with %d parts: got %d bytes, want %d", s.Name, n, len(got), len(want))
+			}
+			r := testing.Benchmark(func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					stringSink = s.Build(parts)
+				}
+			})
+			results = append(results, Result{s.Name, n, r})
+		}
+	}
+	return results, nil
+}
+
+// WriteTables writes the time table, the allocation table and what the
+// numbers say
+func WriteTables(w io.Writer, results []Result, sizes []int, length int) {
+	var names []string
+	cell := map[string]map[int]testing.BenchmarkResult{}
+	for _, r := range results {
+		if cell[r.Strategy] == nil {
+			names = append(names, r.Strategy)
+			cell[r.Strategy] = map[int]testing.BenchmarkResult{}
+		}
+		cell[r.Strategy][r.Parts] = r.Bench
+	}
+
+	fmt.Fprintf(w, "# String Concatenation: %d-byte Parts\n\n", length)
+	fmt.Fprintf(w, "%s, %s/%s, GOMAXPROCS=%d.\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.GOMAXPROCS(0))
+
+	fmt.Fprintln(w, "\n## Time per string (ns/op, and x the fastest at that size)")
+	header(w, sizes)
+	for _, name := range names {
+		fmt.Fprintf(w, "| %s |", name)
+		for _, n := range sizes {
+			ns := cell[name][n].NsPerOp()
+			fmt.Fprintf(w, " %d (%.1fx) |", ns, float64(ns)/float64(fastest(cell, n).NsPerOp()))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "\n## Allocations per string (allocs/op, B/op)")
+	header(w, sizes)
+	for _, name := range names {
+		fmt.Fprintf(w, "| %s |", name)
+		for _, n := range sizes {
+			r := cell[name][n]
+			fmt.Fprintf(w, " %d, %d |", r.AllocsPerOp(), r.AllocedBytesPerOp())
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "\n## What the numbers say")
+	fmt.Fprintln(w)
+	for _, line := range Findings(cell, sizes) {
+		fmt.Fprintln(w, "- "+line)
+	}
+}
+
+func header(w io.Writer, sizes []int) {
+	fmt.Fprint(w, "\n| strategy |")
+	for _, n := range sizes {
+		fmt.Fprintf(w, " %d parts |", n)
+	}
+	fmt.Fprint(w, "\n|---|")
+	fmt.Fprintln(w, strings.Repeat("---:|", len(sizes)))
+}
+
+// fastest returns the quickest result at n parts
+func fastest(cell map[string]map[int]testing.BenchmarkResult, n int) testing.BenchmarkResult {
+	var best testing.BenchmarkResult
+	for _, byN := range cell {
+		if r := byN[n]; best.N == 0 || r.NsPerOp() < best.NsPerOp() {
+			best = r
+		}
+	}
+	return best
+}
+
+// Findings reads the conclusions off the measurements instead of
+// asserting them: which strategy won each size, and where += falls
+// behind a Builder
+func Findings(cell map[string]map[int]testing.BenchmarkResult, sizes []int) []string {
+	var lines []string
+	for _, n := range sizes {
+		var names []string
+		for name := range cell {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		slices.SortStableFunc(names, func(a, b string) int {
+			return int(cell[a][n].NsPerOp() - cell[b][n].NsPerOp())
+		})
+		lines = append(lines, fmt.Sprintf("%d parts: fastest %s, then %s; slowest %s at %.1fx",
+			n, names[0], names[1], names[len(names)-1],
+			float64(cell[names[len(names)-1]][n].NsPerOp())/float64(cell[names[0]][n].NsPerOp())))
+	}
+
+	plus, builder := cell["+="], cell["strings.Builder"]
+	if plus == nil || builder == nil {
+		return lines
+	}
+	crossed := false
+	for _, n := range sizes {
+		if ratio := float64(plus[n].NsPerOp()) / float64(builder[n].NsPerOp()); ratio >= 2 {
+			lines = append(lines, fmt.Sprintf("+= is %.1fx strings.Builder from %d parts on: each step copies the string so far", ratio, n))
+			crossed = true
+			break
+		}
+	}
+	if !crossed {
+		lines = append(lines, "+= stays within 2x of strings.Builder at every size measured")
+	}
+	return lines
+}
+
+// parseSizes reads "2,10,100" into part counts
+func parseSizes(s string) ([]int, error) {
+	var sizes []int
+	for f := range strings.SplitSeq(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("-sizes: %q is not a part count", f)
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
+
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The Strategies
+// ==============
+// Each one joins parts with single spaces - strings.Join(parts, " ") -
+// so the suite can check they agree before timing them:
+//
+//	fmt.Sprintf         += with a format string parsed on every step
+//	+=                  copies everything so far on every step: O(n²)
+//	                    bytes, an allocation per part
+//	bytes.Buffer        amortized growth; String() copies the bytes out
+//	strings.Builder     amortized growth; String() hands over the bytes
+//	Builder + Grow      one allocation when the size is known up front
+//	strings.Join        measures the parts, allocates once, copies once
+//
+// For two or three parts, + is as good as anything: the compiler turns
+// a + " " + b into one call that sizes the result first. The cost of +=
+// is in the loop, where each step only sees the string so far.
+
+// Strategy builds one string from parts
+type Strategy struct {
+	Name  string
+	Build func(parts []string) string
+}
+
+// Strategies are the rows of the table, slowest first
+var Strategies = []Strategy{
+	{"fmt.Sprintf", func(parts []string) string {
+		s := ""
+		for i, p := range parts {
+			if i == 0 {
+				s = fmt.Sprintf("%s", p)
+				continue
+			}
+			s = fmt.Sprintf("%s %s", s, p)
+		}
+		return s
+	}},
+	{"+=", func(parts []string) string {
+		s := ""
+		for i, p := range parts {
+			if i == 0 {
+				s = p
+				continue
+			}
+			s += " " + p
+		}
+		return s
+	}},
+	{"bytes.Buffer", func(parts []string) string {
+		var buf bytes.Buffer
+		for i, p := range parts {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(p)
+		}
+		return buf.String()
+	}},
+	{"strings.Builder", func(parts []string) string {
+		var sb strings.Builder
+		for i, p := range parts {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(p)
+		}
+		return sb.String()
+	}},
+	{"Builder + Grow", func(parts []string) string {
+		n := len(parts) - 1
+		for _, p := range parts {
+			n += len(p)
+		}
+		var sb strings.Builder
+		sb.Grow(max(n, 0))
+		for i, p := range parts {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(p)
+		}
+		return sb.String()
+	}},
+	{"strings.Join", func(parts []string) string {
+		return strings.Join(parts, " ")
+	}},
+}
+
+// Formatting Numbers
+// ==================
+// Writing numbers adds formatting to the copying. Each formatter writes
+// 0 to n-1 with single spaces:
+//
+//	Builder + fmt.Sprintf    a temporary string per number, then a copy
+//	fmt.Fprintf(&Builder)    formats straight into the Builder
+//	strconv.AppendInt        no format string to parse, into a sized []byte
+//
+// fmt parses its format string on every call; strconv.Append* does not.
+
+// Formatter builds one string from the numbers 0 to n-1
+type Formatter struct {
+	Name  string
+	Build func(n int) string
+}
+
+// Formatters are compared by the tests and benchmarks, slowest first
+var Formatters = []Formatter{
+	{"Builder + fmt.Sprintf", func(n int) string {
+		var sb strings.Builder
+		for i := range n {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(fmt.Sprintf("%d", i))
+		}
+		return sb.String()
+	}},
+	{"fmt.Fprintf(&Builder)", func(n int) string {
+		var sb strings.Builder
+		for i := range n {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			fmt.Fprintf(&sb, "%d", i)
+		}
+		return sb.String()
+	}},
+	{"strconv.AppendInt", func(n int) string {
+		buf := make([]byte, 0, 8*n) // room for numbers of up to 7 digits
+		for i := range n {
+			if i > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = strconv.AppendInt(buf, int64(i), 10)
+		}
+		return string(buf)
+	}},
+}
@@ -0,0 +1,193 @@
+package fmtverbs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// fmt Verbs - A Generated Cheat Sheet
+// ===================================
+// Rather than listing verbs from memory, this package runs sample values
+// through each verb and records what fmt actually prints. Render writes
+// the results as Markdown tables; cheatsheet.md is its output, checked
+// by a golden test so the sheet cannot drift from real fmt behaviour.
+//
+// Regenerate after changing the sections:
+//
+//	go test *.go -run TestCheatSheet -update
+
+//go:generate go test fmtverbs.go fmtverbs_test.go -run TestCheatSheet -update
+
+// Sample is a named value to format
+type Sample struct {
+	Name  string
+	Value any
+}
+
+// Section is one table: every verb applied to every sample
+type Section struct {
+	Title   string
+	Note    string
+	Verbs   []string
+	Samples []Sample
+}
+
+type point struct {
+	X, Y int
+}
+
+// celsius has a String method, so %v and %s use it but %d does not
+type celsius float64
+
+func (c celsius) String() string { return fmt.Sprintf("%.1f°C", float64(c)) }
+
+// Sections is the content of the cheat sheet. Pointers are formatted as
+// &{...} for structs, but %p of any pointer changes between runs, so it
+// is left out.
+var Sections = []Section{
+	{
+		Title: "General",
+		Note:  "%v is the default format, %+v adds field names, %#v prints Go syntax, %T the type.",
+		Verbs: []string{"%v", "%+v", "%#v", "%T"},
+		Samples: []Sample{
+			{"int", 42},
+			{"string", "hi"},
+			{"float64", 3.5},
+			{"bool", true},
+			{"nil", nil},
+			{"[]int", []int{1, 2}},
+			{"map", map[string]int{"b": 2, "a": 1}},
+			{"struct", point{1, 2}},
+			{"*struct", &point{1, 2}},
+			{"Stringer", celsius(21.5)},
+			{"error", errors.New("boom")},
+		},
+	},
+	{
+		Title: "Integers",
+		Note:  "Width pads on the left, - pads on the right, 0 pads with zeros. # adds a base prefix.",
+		Verbs: []string{"%d", "%+d", "%6d", "%-6d", "%06d", "%b", "%o", "%O", "%x", "%X", "%#x", "%c", "%q", "%U", "%#U"},
+		Samples: []Sample{
+			{"42", 42},
+			{"-42", -42},
+			{"255", 255},
+			{"'é'", 'é'},
+		},
+	},
+	{
+		Title: "Floats",
+		Note:  "%v is %g: the shortest exact form. Precision means digits after the point for %f/%e, significant digits for %g.",
+		Verbs: []string{"%v", "%f", "%.2f", "%8.2f", "%-8.2f", "%+.1f", "%e", "%.2e", "%g", "%.3g", "%x"},
+		Samples: []Sample{
+			{"pi", math.Pi},
+			{"small", -0.000123},
+			{"large", 1e21},
+			{"whole", 2.0},
+			{"NaN", math.NaN()},
+			{"+Inf", math.Inf(1)},
+		},
+	},
+	{
+		Title: "Strings and Bytes",
+		Note:  "%q quotes with Go escapes, %+q escapes non-ASCII too, %#q uses backquotes when it can. Precision truncates.",
+		Verbs: []string{"%s", "%q", "%+q", "%#q", "%x", "% x", "%X", "%8s", "%-8s", "%.3s"},
+		Samples: []Sample{
+			{"ascii", "hello"},
+			{"tab", "a\tb"},
+			{"unicode", "café"},
+			{"[]byte", []byte("hi")},
+		},
+	},
+	{
+		Title: "Booleans",
+		Note:  "%t is the only boolean verb; other verbs report a bad verb.",
+		Verbs: []string{"%t", "%v", "%d", "%s"},
+		Samples: []Sample{
+			{"true", true},
+			{"false", false},
+		},
+	},
+}
+
+// Special shows format strings that take arguments in unusual ways and
+// the error text fmt prints instead of panicking
+var Special = []struct {
+	Format string
+	Args   []any
+}{
+	{"%*d", []any{6, 42}},          // width from an argument
+	{"%-*d|", []any{6, 42}},        // left-aligned, width from an argument
+	{"%.*f", []any{2, math.Pi}},    // precision from an argument
+	{"%[2]d %[1]d", []any{1, 2}},   // explicit argument indexes
+	{"%d %[1]x %#[1]o", []any{64}}, // reuse one argument
+	{"%v%%", []any{50}},            // a literal percent sign
+	{"%d", []any{"text"}},          // wrong type
+	{"%z", []any{1}},               // unknown verb
+	{"%d %d", []any{1}},            // missing argument
+	{"%d", []any{1, 2}},            // extra argument
+	{"%!", nil},                    // ! is not a verb
+}
+
+// Format applies one verb to a value
+func Format(verb string, v any) string {
+	return fmt.Sprintf(verb, v)
+}
+
+// Render writes the cheat sheet as Markdown
+func Render(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# fmt Verbs Cheat Sheet\n\n")
+	b.WriteString("<!-- Code generated by fmtverbs_test.go; DO NOT EDIT. -->\n")
+	b.WriteString("Every cell is the real output of fmt.Sprintf(verb, value).\n")
+
+	for _, s := range Sections {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n\n", s.Title, s.Note)
+
+		b.WriteString("| verb |")
+		for _, sample := range s.Samples {
+			fmt.Fprintf(&b, " %s |", sample.Name)
+		}
+		b.WriteString("\n|---|")
+		b.WriteString(strings.Repeat("---|", len(s.Samples)))
+		b.WriteByte('\n')
+
+		for _, verb := range s.Verbs {
+			fmt.Fprintf(&b, "| %s |", code(verb))
+			for _, sample := range s.Samples {
+				fmt.Fprintf(&b, " %s |", code(Format(verb, sample.Value)))
+			}
+			b.WriteByte('\n')
+		}
+	}
+
+	b.WriteString("\n## Special Cases\n\n")
+	b.WriteString("Argument widths, indexes and the error text fmt prints instead of panicking.\n\n")
+	b.WriteString("| format | args | output |\n|---|---|---|\n")
+	for _, sp := range Special {
+		args := make([]string, len(sp.Args))
+		for i, a := range sp.Args {
+			args[i] = fmt.Sprintf("%#v", a)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", code(sp.Format), code(strings.Join(args, ", ")), code(fmt.Sprintf(sp.Format, sp.Args...)))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// code wraps s in a Markdown code span so spaces stay visible. Tabs and
+// newlines are shown as escapes, pipes are escaped for the table, and a
+// double-backtick span is used when s contains a backtick.
+func code(s string) string {
+	s = strings.NewReplacer("\t", `\t`, "\n", `\n`, "|", `\|`).Replace(s)
+	if s == "" {
+		return "(empty)"
+	}
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
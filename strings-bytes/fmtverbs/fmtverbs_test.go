@@ -0,0 +1,206 @@
+package fmtverbs
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// fmt Verbs - Golden Tests and a Verb Playground
+// ==============================================
+// Run with:
+//
+//   cd strings-bytes/fmtverbs
+//   go test -v *.go
+//
+// Try your own verb against every sample value:
+//
+//   go test *.go -run TestTry -v -args -verb='%+08.3f'
+//
+// After changing Sections or upgrading Go, regenerate cheatsheet.md:
+//
+//   go test *.go -run TestCheatSheet -update
+
+var (
+	update = flag.Bool("update", false, "rewrite cheatsheet.md from the current output")
+	verb   = flag.String("verb", "", "format every sample with this verb in TestTry")
+)
+
+const golden = "cheatsheet.md"
+
+// 1. The Golden File
+// ==================
+
+func TestCheatSheet(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("wrote %s", golden)
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("%s is stale; run: go test *.go -run TestCheatSheet -update", golden)
+		reportFirstDiff(t, buf.Bytes(), want)
+	}
+}
+
+// reportFirstDiff logs the first line that differs, which is far easier
+// to read than two whole documents
+func reportFirstDiff(t *testing.T, got, want []byte) {
+	t.Helper()
+	gotLines := bytes.Split(got, []byte("\n"))
+	wantLines := bytes.Split(want, []byte("\n"))
+	for i := range max(len(gotLines), len(wantLines)) {
+		var g, w []byte
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if !bytes.Equal(g, w) {
+			t.Logf("line %d:\n  got:  %s\n  want: %s", i+1, g, w)
+			return
+		}
+	}
+}
+
+func TestRenderIsDeterministic(t *testing.T) {
+	// Map keys are printed sorted and no pointer addresses are shown, so
+	// two renders must be byte-identical - otherwise the golden test
+	// would be flaky
+	var a, b bytes.Buffer
+	Render(&a)
+	Render(&b)
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Render output differs between calls")
+	}
+}
+
+// 2. Verbs Worth Remembering
+// ==========================
+// The golden file covers everything; these spell out the cases that
+// surprise people, so a failure names the exact behaviour that changed.
+
+func TestSurprisingVerbs(t *testing.T) {
+	tests := []struct {
+		verb  string
+		value any
+		want  string
+	}{
+		// %v on a nil map or slice prints the empty form, not <nil>
+		{"%v", map[string]int(nil), "map[]"},
+		{"%v", []int(nil), "[]"},
+		{"%#v", []int(nil), "[]int(nil)"},
+
+		// A Stringer is used by %v and %s, but not by %d or %#v
+		{"%v", celsius(20), "20.0°C"},
+		{"%.1f", celsius(20), "20.0"},
+		{"%#v", celsius(20), "20"},
+
+		// %x of a string is hex of its bytes; % x spaces them out
+		{"%x", "Go", "476f"},
+		{"% x", "Go", "47 6f"},
+
+		// %c and %q work on integers as runes
+		{"%c", 65, "A"},
+		{"%q", 65, "'A'"},
+
+		// Padding counts runes, not bytes
+		{"%-5s|", "é", "é    |"},
+
+		// Precision on a string truncates to that many runes
+		{"%.2s", "héllo", "hé"},
+
+		// %g switches to exponent form for large exponents
+		{"%g", 1e20, "1e+20"},
+		{"%g", 100000.0, "100000"},
+
+		// Zero padding goes after the sign
+		{"%06d", -42, "-00042"},
+		{"%+.2e", 12345.678, "+1.23e+04"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.verb, tt.value); got != tt.want {
+			t.Errorf("Sprintf(%q, %#v) = %q, want %q", tt.verb, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestErrorsInOutput(t *testing.T) {
+	// fmt never panics on a bad format: the mistake is written into the
+	// output, and go vet reports it at build time for constant formats
+	tests := []struct {
+		format string
+		args   []any
+		want   string
+	}{
+		{"%d", []any{"x"}, "%!d(string=x)"},
+		{"%d %d", []any{1}, "1 %!d(MISSING)"},
+		{"%d", []any{1, 2}, "1%!(EXTRA int=2)"},
+		{"%z", []any{1}, "%!z(int=1)"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, tt.args...); got != tt.want {
+			t.Errorf("Sprintf(%q, %v) = %q, want %q", tt.format, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"42", "`42`"},
+		{"  42", "`  42`"},
+		{"a\tb", "`a\\tb`"},
+		{"a|b", "`a\\|b`"},
+		{"`hi`", "`` `hi` ``"},
+		{"", "(empty)"},
+	}
+	for _, tt := range tests {
+		if got := code(tt.in); got != tt.want {
+			t.Errorf("code(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// 3. Playground
+// =============
+
+func TestTry(t *testing.T) {
+	if *verb == "" {
+		t.Skip("pass -args -verb='%...' to try a verb")
+	}
+	for _, s := range Sections {
+		for _, sample := range s.Samples {
+			t.Logf("%-8s %-10s %q", s.Title, sample.Name, Format(*verb, sample.Value))
+		}
+	}
+}
+
+// Examples
+// ========
+
+func ExampleFormat() {
+	fmt.Println(Format("[%6.2f]", 3.14159))
+	fmt.Println(Format("[%-6d]", 42))
+	fmt.Println(Format("%#x", 255))
+	fmt.Println(Format("%+v", point{1, 2}))
+	// Output:
+	// [  3.14]
+	// [42    ]
+	// 0xff
+	// {X:1 Y:2}
+}
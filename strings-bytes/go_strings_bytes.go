@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Go Strings and Bytes - Building, Splitting and Comparing Text
+// =============================================================
+// This file demonstrates the strings and bytes packages: building text
+// with strings.Builder and bytes.Buffer, splitting it with Cut, Split and
+// Fields, and comparing it with case folding. Concatenation costs are
+// measured in concat/.
+
+func main() {
+	fmt.Println("=== Go Strings and Bytes ===")
+
+	// 1. strings.Builder
+	stringsBuilder()
+
+	// 2. Cut, Split and Fields
+	cutSplitFields()
+
+	// 3. bytes.Buffer
+	bytesBuffer()
+
+	// 4. Case folding
+	caseFolding()
+
+	// 5. string vs []byte conversions
+	conversions()
+}
+
+// 1. strings.Builder
+// ==================
+func stringsBuilder() {
+	fmt.Println("\n1. STRINGS.BUILDER:")
+
+	// The zero value is ready to use. Grow reserves capacity up front so
+	// the internal []byte is allocated once instead of doubling.
+	var b strings.Builder
+	b.Grow(64)
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&b, "item%d ", i) // Builder is an io.Writer
+	}
+	b.WriteByte('|')
+	b.WriteRune('→')
+	b.WriteString(" done")
+	fmt.Printf("   result: %q (len %d, cap %d)\n", b.String(), b.Len(), b.Cap())
+
+	// String() does not copy: it reinterprets the bytes as a string, which
+	// is only safe because Builder never lets you modify written bytes.
+	// For the same reason a Builder must not be copied after use:
+	//   c := b; c.WriteString("x")  // panics: illegal use of non-zero Builder copied by value
+	fmt.Println("   String() is free; Reset() starts over; never copy a used Builder")
+}
+
+// 2. Cut, Split and Fields
+// ========================
+func cutSplitFields() {
+	fmt.Println("\n2. CUT, SPLIT AND FIELDS:")
+
+	// Cut splits around the FIRST separator - the go-to for key=value
+	// pairs, replacing Index + slicing
+	key, value, found := strings.Cut("host=example.com=alias", "=")
+	fmt.Printf("   Cut(\"host=example.com=alias\", \"=\"): %q %q %t\n", key, value, found)
+	_, _, found = strings.Cut("no separator", "=")
+	fmt.Printf("   Cut without separator: found=%t\n", found)
+
+	// CutPrefix / CutSuffix trim and report in one step
+	if rest, ok := strings.CutPrefix("Bearer abc123", "Bearer "); ok {
+		fmt.Printf("   CutPrefix token: %q\n", rest)
+	}
+
+	// Split keeps empty fields; Fields drops them and splits on any
+	// run of Unicode white space
+	line := " a,b,,c "
+	fmt.Printf("   Split(%q, \",\"): %q\n", line, strings.Split(line, ","))
+	fmt.Printf("   SplitN(%q, \",\", 2): %q\n", line, strings.SplitN(line, ",", 2))
+	fmt.Printf("   Split(\"\", \",\"): %q (one empty field, not zero)\n", strings.Split("", ","))
+	fmt.Printf("   Fields(\"  go  is\\tfun\\n\"): %q\n", strings.Fields("  go  is\tfun\n"))
+	fmt.Printf("   FieldsFunc with ',' and ';': %q\n", strings.FieldsFunc("a,b;;c", func(r rune) bool {
+		return r == ',' || r == ';'
+	}))
+
+	// Go 1.24 iterators avoid building the []string when you only loop
+	fmt.Print("   SplitSeq: ")
+	for part := range strings.SplitSeq("x-y-z", "-") {
+		fmt.Printf("%s ", part)
+	}
+	fmt.Println()
+}
+
+// 3. bytes.Buffer
+// ===============
+func bytesBuffer() {
+	fmt.Println("\n3. BYTES.BUFFER:")
+
+	// Buffer is a read-write byte queue: writes append at the end, reads
+	// consume from the front. Builder only writes; Buffer does both.
+	var buf bytes.Buffer
+	buf.WriteString("GET /index.html HTTP/1.1\r\n")
+	buf.WriteString("Host: example.com\r\n\r\n")
+
+	requestLine, _ := buf.ReadString('\n')
+	fmt.Printf("   ReadString: %q\n", requestLine)
+	fmt.Printf("   Next(4): %q, remaining %d bytes\n", buf.Next(4), buf.Len())
+
+	// Bytes() aliases the internal storage: the slice changes if the
+	// buffer is written again. Copy it (bytes.Clone) if you keep it.
+	snapshot := bytes.Clone(buf.Bytes())
+	buf.Reset()
+	fmt.Printf("   cloned before Reset: %q\n", snapshot)
+
+	// The bytes package mirrors strings for []byte data
+	fields := bytes.Fields([]byte("  raw   bytes "))
+	fmt.Printf("   bytes.Fields: %q\n", fields)
+
+	// Scanner splits a stream into lines or words without loading it all
+	sc := bufio.NewScanner(strings.NewReader("one two\nthree"))
+	sc.Split(bufio.ScanWords)
+	n := 0
+	for sc.Scan() {
+		n++
+	}
+	fmt.Printf("   bufio.ScanWords counted %d words\n", n)
+}
+
+// 4. Case Folding
+// ===============
+func caseFolding() {
+	fmt.Println("\n4. CASE FOLDING:")
+
+	// EqualFold compares under Unicode simple case folding without
+	// allocating - prefer it over ToLower(a) == ToLower(b)
+	fmt.Printf("   EqualFold(\"Go\", \"GO\"): %t\n", strings.EqualFold("Go", "GO"))
+	fmt.Printf("   EqualFold(\"σ\", \"Σ\"): %t\n", strings.EqualFold("σ", "Σ"))
+	fmt.Printf("   EqualFold(\"K\", \"\\u212A\" (Kelvin sign)): %t\n", strings.EqualFold("K", "\u212A"))
+
+	// Simple folding maps one rune to one rune, so multi-rune foldings are
+	// NOT equal: German ß folds to "ss" only under full case folding
+	fmt.Printf("   EqualFold(\"straße\", \"STRASSE\"): %t\n", strings.EqualFold("straße", "STRASSE"))
+	fmt.Printf("   ToUpper(\"straße\"): %q\n", strings.ToUpper("straße"))
+
+	// Case mapping is language dependent: Turkish has dotted and dotless i
+	fmt.Printf("   ToUpper(\"i\"): %q, Turkish ToUpper: %q\n",
+		strings.ToUpper("i"), strings.ToUpperSpecial(unicode.TurkishCase, "i"))
+	fmt.Println("   For user-facing, locale-aware comparison use golang.org/x/text/cases and collate")
+}
+
+// 5. string vs []byte Conversions
+// ===============================
+func conversions() {
+	fmt.Println("\n5. STRING VS []BYTE CONVERSIONS:")
+
+	// A string is immutable; []byte is not. Converting between them
+	// usually copies, so the two can never alias.
+	s := "hello"
+	b := []byte(s) // copy
+	b[0] = 'j'
+	fmt.Printf("   s=%q b=%q (independent)\n", s, b)
+
+	// The compiler skips the copy when it can prove it is unobservable:
+	// map lookups m[string(b)], comparisons string(b) == "x", and
+	// range over []byte(s). Work in one representation where possible.
+	m := map[string]int{"jello": 1}
+	fmt.Printf("   m[string(b)] (no copy): %d\n", m[string(b)])
+
+	// Many functions exist in both packages so no conversion is needed
+	fmt.Printf("   bytes.HasPrefix: %t, strings.HasPrefix: %t\n",
+		bytes.HasPrefix(b, []byte("je")), strings.HasPrefix(s, "he"))
+}
@@ -0,0 +1,106 @@
+package regex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// When Not to Use a Regexp
+// ========================
+// A regexp is a general machine: even a simple pattern runs through
+// the matcher with per-call setup, and the Find and Submatch methods
+// allocate their results. The strings package does fixed jobs with
+// code written for them. For the common tasks below, the benchmarks in
+// regex_test.go show the strings version 5-20x faster, with fewer
+// allocations or none:
+//
+//	task                    regexp                strings
+//	prefix or suffix        ^abc, \.go$           HasPrefix, HasSuffix
+//	split on a literal      Split(s, -1) on ,     Split, Cut
+//	words                   \S+                   Fields
+//	key=value               ^(\w+)=(.*)$          Cut
+//	character class check   ^[a-z_][a-z0-9_]*$    a loop over bytes
+//
+// The exception is a pattern that is all literal, such as `error`:
+// the regexp package spots the literal and searches for it with the
+// same code as strings.Contains, so the two are close
+// (BenchmarkContains). Contains is still simpler to read.
+//
+// A regexp earns its cost when the shape is irregular - optional
+// parts, alternatives, repetition inside structure - when the pattern
+// comes from configuration, or when the hand-written version would be
+// thirty lines that nobody can check at a glance. Each pair below
+// gives the same answers; the tests check that they agree.
+
+var identRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsIdentifierRE reports whether s is an ASCII identifier, using a
+// regexp
+func IsIdentifierRE(s string) bool { return identRE.MatchString(s) }
+
+// IsIdentifier is IsIdentifierRE as a loop
+func IsIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		letter := c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+var keyValueRE = regexp.MustCompile(`^(\w+)=(.*)$`)
+
+// KeyValueRE splits "key=value" with a regexp. The key is one or more
+// word characters; the value may be empty.
+func KeyValueRE(s string) (key, value string, ok bool) {
+	m := keyValueRE.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// KeyValue is KeyValueRE with strings.Cut
+func KeyValue(s string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(s, "=")
+	if !ok || key == "" || strings.ContainsFunc(key, func(r rune) bool {
+		return !(r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		return "", "", false
+	}
+	// The regexp's . stops at a newline; match it
+	if strings.Contains(value, "\n") {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+var wordsRE = regexp.MustCompile(`\S+`)
+
+// WordsRE splits s into runs of non-space characters with a regexp
+func WordsRE(s string) []string { return wordsRE.FindAllString(s, -1) }
+
+// Words is WordsRE with strings.Fields. Fields also splits on Unicode
+// spaces; \S is ASCII-only, so the two differ on input such as U+00A0.
+func Words(s string) []string { return strings.Fields(s) }
+
+var imageRE = regexp.MustCompile(`\.(?:png|jpe?g|gif)$`)
+
+// IsImageRE reports whether name ends in an image extension, using a
+// regexp
+func IsImageRE(name string) bool { return imageRE.MatchString(name) }
+
+// IsImage is IsImageRE with strings.HasSuffix
+func IsImage(name string) bool {
+	for _, ext := range []string{".png", ".jpg", ".jpeg", ".gif"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package regex
+
+// The RE2 Guarantee
+// =================
+// Perl, PCRE, Java, Python and JavaScript match by backtracking: try
+// one way, and on failure go back and try the next. Most patterns
+// finish quickly, but some have exponentially many ways to fail.
+// (a+)+$ against "aaaa...a!" splits the run of a's between the inner
+// and outer + in every possible way before giving up: 2^n attempts for
+// n a's. One such pattern in a WAF rule took Cloudflare down in 2019.
+//
+// Go's regexp is RE2. It simulates all the ways at once, in a single
+// pass, so matching takes time linear in the input - O(pattern x
+// input) - whatever the pattern. The price is what needs backtracking
+// to express:
+//
+//	\1 backreferences          "the same text as group 1"
+//	(?=...) (?!...)            lookahead
+//	(?<=...) (?<!...)          lookbehind
+//	possessive and atomic      a++ (?>...)
+//
+// Compile rejects all of them with an error. Most uses have a simple
+// replacement: match more and check in Go, or use two expressions.
+//
+// Two more semantics to know:
+//
+//   - Alternation is leftmost-first, as in Perl: a|ab on "ab" matches
+//     "a". re.Longest() switches to POSIX leftmost-longest: "ab".
+//   - Matching is over UTF-8; . is a rune, not a byte. Invalid bytes
+//     each match as U+FFFD.
+//
+// Below is a backtracking matcher for exactly (a+)+$, counting its
+// steps, to compare with RE2 on the same input.
+
+// BacktrackNested matches s against ^(a+)+$ the way a backtracking
+// engine would and returns the number of steps it took
+func BacktrackNested(s string) (matched bool, steps int) {
+	var match func(i int) bool
+	match = func(i int) bool {
+		steps++
+		// One repetition of the group: a+, greedy, so the longest run
+		// first and then each shorter one
+		n := 0
+		for i+n < len(s) && s[i+n] == 'a' {
+			n++
+		}
+		for k := n; k >= 1; k-- {
+			if i+k == len(s) { // $
+				return true
+			}
+			if match(i + k) { // the outer +: another repetition
+				return true
+			}
+		}
+		return false
+	}
+	return match(0), steps
+}
@@ -0,0 +1,110 @@
+package regex
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Regular Expressions - Compiling, Reusing and Named Groups
+// =========================================================
+// regexp.Compile turns a pattern into a program. That is the expensive
+// step - microseconds, and several allocations - so it belongs at
+// package level, done once:
+//
+//	var dateRE = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+//
+// MustCompile panics on a bad pattern, which is right for a constant:
+// the first test run finds it. For a pattern that comes from a user or
+// a config file, use Compile and return the error.
+//
+// A *Regexp is safe for concurrent use; share one across goroutines.
+// The package-level helpers - regexp.MatchString(pattern, s) - compile
+// on every call, which is fine once at start-up and slow in a loop
+// (see BenchmarkMatchStringCompilesEachTime).
+//
+// Write patterns as raw strings in backquotes. In "\d" Go sees an
+// invalid escape; in `\d` the regexp package sees a digit class.
+
+// Named Capture Groups
+// ====================
+// (?P<name>...) - or (?<name>...) since Go 1.22 - names a group.
+// Numbered groups break when someone adds a group in front; names do
+// not. SubexpIndex finds a group's number once, so a lookup in the hot
+// path is a slice index rather than a string search.
+
+var logLineRE = regexp.MustCompile(
+	`^(?P<time>\d{4}-\d{2}-\d{2}T[\d:.]+Z?)\s+(?P<level>DEBUG|INFO|WARN|ERROR)\s+(?P<msg>.*)$`)
+
+var (
+	logTime  = logLineRE.SubexpIndex("time")
+	logLevel = logLineRE.SubexpIndex("level")
+	logMsg   = logLineRE.SubexpIndex("msg")
+)
+
+// LogLine is one parsed line of a plain-text log
+type LogLine struct {
+	Time, Level, Msg string
+}
+
+// ParseLogLine parses "2024-03-05T10:00:00Z INFO started". It reports
+// false for a line that does not match.
+func ParseLogLine(s string) (LogLine, bool) {
+	m := logLineRE.FindStringSubmatch(s)
+	if m == nil {
+		return LogLine{}, false
+	}
+	return LogLine{Time: m[logTime], Level: m[logLevel], Msg: m[logMsg]}, true
+}
+
+// NamedGroups returns the named groups of re's first match in s, or
+// nil if there is none. A named group that did not take part in the
+// match - inside an alternative not taken - maps to "".
+func NamedGroups(re *regexp.Regexp, s string) map[string]string {
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+	groups := make(map[string]string)
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			groups[name] = m[i]
+		}
+	}
+	return groups
+}
+
+// Patterns From Users
+// ===================
+// RE2 guarantees linear time in the input, so a user's pattern cannot
+// hang a server the way (a+)+$ hangs a backtracking engine. It does
+// not bound the pattern itself: x{1000}{1000} asks for a million
+// states. Go rejects repetition counts over 1000 and programs over a
+// size limit, but a pattern close to those limits still costs memory
+// and time to compile and run. Limit the length before compiling.
+
+// ErrPatternTooLong is returned by CompileUser for a pattern over the
+// length limit
+var ErrPatternTooLong = errors.New("regex: pattern too long")
+
+// CompileUser compiles a pattern supplied at run time. It refuses
+// patterns longer than maxLen bytes and reports syntax errors with the
+// pattern, instead of panicking.
+func CompileUser(pattern string, maxLen int) (*regexp.Regexp, error) {
+	if len(pattern) > maxLen {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrPatternTooLong, len(pattern), maxLen)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// err already quotes the offending part; *syntax.Error is
+		// available through errors.As for callers that want the code
+		return nil, fmt.Errorf("regex: %w", err)
+	}
+	return re, nil
+}
+
+// Literal returns a pattern that matches s exactly. Without QuoteMeta,
+// a search for "1.5" also matches "105", and "a+b" matches "aab".
+func Literal(s string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(s))
+}
@@ -0,0 +1,425 @@
+package regex
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Regular Expressions - Tests
+// ===========================
+// Run with:
+//
+//   cd strings-bytes/regex
+//   go test -v *.go
+//   go test -run xxx -bench . -benchmem *.go
+
+// 1. Compiling and Reusing
+// ========================
+
+func TestMustCompilePanicsOnBadPattern(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompile(`(`) did not panic")
+		}
+	}()
+	regexp.MustCompile(`(`)
+}
+
+func TestCompileUser(t *testing.T) {
+	if _, err := CompileUser(`^[a-z]+$`, 100); err != nil {
+		t.Fatalf("valid pattern: %v", err)
+	}
+	if _, err := CompileUser(strings.Repeat("a", 101), 100); !errors.Is(err, ErrPatternTooLong) {
+		t.Errorf("long pattern: %v, want ErrPatternTooLong", err)
+	}
+
+	tests := []struct {
+		pattern string
+		code    syntax.ErrorCode
+	}{
+		{`(abc`, syntax.ErrMissingParen},
+		{`[a-`, syntax.ErrMissingBracket},
+		{`a**`, syntax.ErrInvalidRepeatOp},
+		{`x{1001}`, syntax.ErrInvalidRepeatSize},
+	}
+	for _, tt := range tests {
+		_, err := CompileUser(tt.pattern, 100)
+		var se *syntax.Error
+		if !errors.As(err, &se) || se.Code != tt.code {
+			t.Errorf("CompileUser(%q) = %v, want %q", tt.pattern, err, tt.code)
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	// "\\d" in an interpreted string is `\d` in a raw one
+	if regexp.MustCompile("\\d+").String() != regexp.MustCompile(`\d+`).String() {
+		t.Error("the two spellings differ")
+	}
+	// "\b" in an interpreted string is a backspace, not a word boundary
+	if regexp.MustCompile("\bgo\b").MatchString("let's go now") {
+		t.Error(`"\b" matched a word boundary`)
+	}
+	if !regexp.MustCompile(`\bgo\b`).MatchString("let's go now") {
+		t.Error("`\\b` did not match a word boundary")
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	unquoted := regexp.MustCompile("1.5")
+	if !unquoted.MatchString("105") {
+		t.Error("unquoted 1.5 should match 105")
+	}
+	if Literal("1.5").MatchString("105") {
+		t.Error("Literal(1.5) matched 105")
+	}
+	if !Literal("a+b (c)").MatchString("x a+b (c) y") {
+		t.Error("Literal did not match its own text")
+	}
+}
+
+func TestSharedAcrossGoroutines(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			line := fmt.Sprintf("2024-03-05T10:00:0%dZ INFO worker %d", i, i)
+			for range 100 {
+				if _, ok := ParseLogLine(line); !ok {
+					t.Errorf("no match for %q", line)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// 2. Named Groups
+// ===============
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want LogLine
+		ok   bool
+	}{
+		{"2024-03-05T10:00:00Z INFO started", LogLine{"2024-03-05T10:00:00Z", "INFO", "started"}, true},
+		{"2024-03-05T10:00:00.123Z   ERROR  disk full: /var", LogLine{"2024-03-05T10:00:00.123Z", "ERROR", "disk full: /var"}, true},
+		{"2024-03-05T10:00:00Z WARN ", LogLine{"2024-03-05T10:00:00Z", "WARN", ""}, true},
+		{"2024-03-05T10:00:00Z TRACE hello", LogLine{}, false},
+		{"INFO started", LogLine{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLogLine(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseLogLine(%q) = %+v, %v; want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNamedGroups(t *testing.T) {
+	// Both group syntaxes, and an alternative that is not taken
+	re := regexp.MustCompile(`^(?P<user>\w+)@(?:(?<host>[\w.]+)|\[(?P<ip>[\d.]+)\])$`)
+
+	got := NamedGroups(re, "root@[10.0.0.1]")
+	want := map[string]string{"user": "root", "host": "", "ip": "10.0.0.1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("NamedGroups = %v, want %v", got, want)
+	}
+	if NamedGroups(re, "nobody") != nil {
+		t.Error("NamedGroups of a non-match is not nil")
+	}
+	if re.SubexpIndex("host") != 2 || re.SubexpIndex("missing") != -1 {
+		t.Errorf("SubexpIndex: host=%d missing=%d", re.SubexpIndex("host"), re.SubexpIndex("missing"))
+	}
+}
+
+// 3. Replacing
+// ============
+
+func TestTemplateTrap(t *testing.T) {
+	re := regexp.MustCompile(`(\w+)@(\w+)`)
+	tests := []struct {
+		tmpl, want string
+	}{
+		{"$2/$1", "host/user"},
+		{"$1x", ""}, // the group named "1x": empty
+		{"${1}x", "userx"},
+		{"$$1", "$1"}, // $$ is a literal dollar
+	}
+	for _, tt := range tests {
+		if got := re.ReplaceAllString("user@host", tt.tmpl); got != tt.want {
+			t.Errorf("ReplaceAllString(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+	if got := re.ReplaceAllLiteralString("user@host", "$1"); got != "$1" {
+		t.Errorf("ReplaceAllLiteralString = %q, want $1 unexpanded", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"contact alice@example.com today", "contact a***@example.com today"},
+		{"card 4111 1111 1111 1111 charged", "card ************1111 charged"},
+		{"card 4111-1111-1111-1234", "card ************1234"},
+		{"order 12345 shipped", "order 12345 shipped"}, // too short for a card
+		{"from b.o+b@mail.co.uk and c@x.io", "from b***@mail.co.uk and c***@x.io"},
+		{"nothing here", "nothing here"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := string(RedactBytes([]byte(tt.in))); got != tt.want {
+			t.Errorf("RedactBytes(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceGroupsFunc(t *testing.T) {
+	// Swap "last, first" to "first last", upper-casing the last name
+	re := regexp.MustCompile(`(\w+), (\w+)`)
+	got := ReplaceGroupsFunc(re, "Lovelace, Ada; Hopper, Grace", func(g []string) string {
+		return g[2] + " " + strings.ToUpper(g[1])
+	})
+	if want := "Ada LOVELACE; Grace HOPPER"; got != want {
+		t.Errorf("ReplaceGroupsFunc = %q, want %q", got, want)
+	}
+	if got := ReplaceGroupsFunc(re, "no match", nil); got != "no match" {
+		t.Errorf("no match: %q", got)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"name": "Gopher", "lang": "Go"}
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello, {{name}}!", "Hello, Gopher!"},
+		{"{{ lang | upper }} and {{lang|lower}}", "GO and go"},
+		{"{{missing}} stays", "{{missing}} stays"},
+		{"{{name | reverse}}", "Gopher"}, // unknown filters are ignored
+		{"no placeholders", "no placeholders"},
+	}
+	for _, tt := range tests {
+		if got := Expand(tt.in, vars); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// 4. RE2
+// ======
+
+func TestRE2RejectsBacktrackingSyntax(t *testing.T) {
+	for _, pattern := range []string{
+		`(a)\1`,      // backreference
+		`foo(?=bar)`, // lookahead
+		`(?<!x)y`,    // lookbehind
+		`a++`,        // possessive
+	} {
+		if _, err := regexp.Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded", pattern)
+		} else {
+			t.Logf("%-12s %v", pattern, err)
+		}
+	}
+}
+
+func TestLeftmostFirstVersusLongest(t *testing.T) {
+	re := regexp.MustCompile(`a|ab`)
+	if got := re.FindString("ab"); got != "a" {
+		t.Errorf("leftmost-first = %q, want a", got)
+	}
+	re.Longest()
+	if got := re.FindString("ab"); got != "ab" {
+		t.Errorf("leftmost-longest = %q, want ab", got)
+	}
+}
+
+func TestBacktrackingIsExponential(t *testing.T) {
+	prev := 0
+	for n := 10; n <= 20; n++ {
+		matched, steps := BacktrackNested(strings.Repeat("a", n) + "!")
+		if matched {
+			t.Fatal("matched a string ending in !")
+		}
+		if prev > 0 && steps < 2*prev-1 {
+			t.Errorf("n=%d: %d steps, want about double the %d for n=%d", n, steps, prev, n-1)
+		}
+		prev = steps
+	}
+	t.Logf("(a+)+$ against 20 a's and a !: %d steps", prev)
+
+	if matched, _ := BacktrackNested("aaaa"); !matched {
+		t.Error("did not match aaaa")
+	}
+}
+
+func TestRE2IsLinear(t *testing.T) {
+	re := regexp.MustCompile(`^(a+)+$`)
+	// 2^100000 steps for a backtracker; one pass for RE2
+	input := strings.Repeat("a", 100_000) + "!"
+	start := time.Now()
+	if re.MatchString(input) {
+		t.Fatal("matched a string ending in !")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("RE2 took %v", d)
+	}
+}
+
+// 5. Alternatives Agree
+// =====================
+
+func TestAlternativesAgree(t *testing.T) {
+	idents := []string{"", "x", "_x1", "X_Y_9", "9x", "a-b", "a b", "é", "a\n"}
+	for _, s := range idents {
+		if IsIdentifier(s) != IsIdentifierRE(s) {
+			t.Errorf("IsIdentifier(%q) = %v, regexp says %v", s, IsIdentifier(s), IsIdentifierRE(s))
+		}
+	}
+
+	pairs := []string{"k=v", "key=", "=v", "a=b=c", "no equals", "a b=c", "k=line\nbreak", "k_9=ok", "ü=x"}
+	for _, s := range pairs {
+		k1, v1, ok1 := KeyValue(s)
+		k2, v2, ok2 := KeyValueRE(s)
+		if k1 != k2 || v1 != v2 || ok1 != ok2 {
+			t.Errorf("KeyValue(%q) = %q %q %v, regexp says %q %q %v", s, k1, v1, ok1, k2, v2, ok2)
+		}
+	}
+
+	texts := []string{"", "one", "  two  words ", "tabs\tand\nnewlines"}
+	for _, s := range texts {
+		if !slices.Equal(Words(s), WordsRE(s)) {
+			t.Errorf("Words(%q) = %q, regexp says %q", s, Words(s), WordsRE(s))
+		}
+	}
+	// Where they differ: U+00A0 NO-BREAK SPACE is a Unicode space, but
+	// not in \s
+	if nbsp := "a b"; len(Words(nbsp)) == len(WordsRE(nbsp)) {
+		t.Error("Fields and \\S+ agreed on a no-break space")
+	}
+
+	names := []string{"a.png", "b.jpeg", "c.jpg", "d.gif", "e.png.txt", "png", "f.PNG"}
+	for _, s := range names {
+		if IsImage(s) != IsImageRE(s) {
+			t.Errorf("IsImage(%q) = %v, regexp says %v", s, IsImage(s), IsImageRE(s))
+		}
+	}
+}
+
+// 6. Benchmarks
+// =============
+//
+//   go test -run xxx -bench . -benchmem *.go
+
+func BenchmarkMatchStringCompilesEachTime(b *testing.B) {
+	for b.Loop() {
+		regexp.MatchString(`^[A-Za-z_][A-Za-z0-9_]*$`, "some_identifier")
+	}
+}
+
+func BenchmarkIdentifier(b *testing.B) {
+	const s = "some_identifier_42"
+	b.Run("regexp", func(b *testing.B) {
+		for b.Loop() {
+			IsIdentifierRE(s)
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		for b.Loop() {
+			IsIdentifier(s)
+		}
+	})
+}
+
+func BenchmarkKeyValue(b *testing.B) {
+	const s = "database_url=postgres://localhost/app"
+	b.Run("regexp", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			KeyValueRE(s)
+		}
+	})
+	b.Run("cut", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			KeyValue(s)
+		}
+	})
+}
+
+func BenchmarkWords(b *testing.B) {
+	s := strings.Repeat("the quick brown fox ", 20)
+	b.Run("regexp", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			WordsRE(s)
+		}
+	})
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			Words(s)
+		}
+	})
+}
+
+func BenchmarkImageSuffix(b *testing.B) {
+	const s = "/static/images/2024/03/header-background.jpeg"
+	b.Run("regexp", func(b *testing.B) {
+		for b.Loop() {
+			IsImageRE(s)
+		}
+	})
+	b.Run("hassuffix", func(b *testing.B) {
+		for b.Loop() {
+			IsImage(s)
+		}
+	})
+}
+
+func BenchmarkContains(b *testing.B) {
+	s := strings.Repeat("all systems nominal; ", 50) + "error: disk full"
+	re := regexp.MustCompile(`error`)
+	b.Run("regexp", func(b *testing.B) {
+		for b.Loop() {
+			re.MatchString(s)
+		}
+	})
+	b.Run("contains", func(b *testing.B) {
+		for b.Loop() {
+			strings.Contains(s, "error")
+		}
+	})
+}
+
+// 7. Examples
+// ===========
+
+func ExampleRedact() {
+	fmt.Println(Redact("alice@example.com paid with 4111 1111 1111 1111"))
+	// Output: a***@example.com paid with ************1111
+}
+
+func ExampleReplaceGroupsFunc() {
+	// ISO dates to US order, which a template could do too - and
+	// month numbers to names, which it could not
+	re := regexp.MustCompile(`(\d{4})-(\d{2})-(\d{2})`)
+	out := ReplaceGroupsFunc(re, "due 2024-03-05, paid 2024-04-01", func(g []string) string {
+		t, _ := time.Parse("01", g[2])
+		return fmt.Sprintf("%s %s, %s", t.Month(), strings.TrimPrefix(g[3], "0"), g[1])
+	})
+	fmt.Println(out)
+	// Output: due March 5, 2024, paid April 1, 2024
+}
@@ -0,0 +1,105 @@
+package regex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Replacing
+// =========
+// Three ways to build the replacement, from least to most control:
+//
+//	ReplaceAllLiteralString(s, r)   r as is: "$" means a dollar sign
+//	ReplaceAllString(s, tmpl)       tmpl expands $1, ${1}, $name, ${name}
+//	ReplaceAllStringFunc(s, f)      f(match) computes each replacement
+//
+// Template expansion has one classic trap: a name is the longest run
+// of letters, digits and underscores, so "$1x" means the group named
+// "1x" - which does not exist and expands to "". Write "${1}x".
+//
+// The Func forms receive only the whole match, not the groups. To
+// compute a replacement from the groups, walk FindAllStringSubmatchIndex
+// and build the result yourself, as ReplaceGroupsFunc does.
+// ReplaceAllFunc is the []byte version and works the same way.
+
+var (
+	emailRE = regexp.MustCompile(`\b([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})\b`)
+	cardRE  = regexp.MustCompile(`\b(?:\d[ -]?){12,15}\d\b`)
+)
+
+// Redact masks e-mail addresses and card numbers in s: "alice@example.com"
+// becomes "a***@example.com", and a card number keeps its last four
+// digits.
+func Redact(s string) string {
+	s = emailRE.ReplaceAllString(s, "${1}***@${2}")
+	return cardRE.ReplaceAllStringFunc(s, func(card string) string {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, card)
+		return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+	})
+}
+
+// RedactBytes is Redact for []byte input, such as a log buffer. The
+// []byte methods avoid converting the whole buffer to a string.
+func RedactBytes(b []byte) []byte {
+	b = emailRE.ReplaceAll(b, []byte("${1}***@${2}"))
+	return cardRE.ReplaceAllFunc(b, func(card []byte) []byte {
+		var digits []byte
+		for _, c := range card {
+			if c >= '0' && c <= '9' {
+				digits = append(digits, c)
+			}
+		}
+		out := make([]byte, 0, len(digits))
+		for range len(digits) - 4 {
+			out = append(out, '*')
+		}
+		return append(out, digits[len(digits)-4:]...)
+	})
+}
+
+// ReplaceGroupsFunc replaces every match of re in s with fn's result.
+// fn gets the submatches as FindStringSubmatch returns them: the whole
+// match first, then each group, "" for a group that did not take part.
+func ReplaceGroupsFunc(re *regexp.Regexp, s string, fn func(groups []string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, idx := range re.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(s[last:idx[0]])
+		groups := make([]string, len(idx)/2)
+		for i := range groups {
+			if start := idx[2*i]; start >= 0 {
+				groups[i] = s[start:idx[2*i+1]]
+			}
+		}
+		b.WriteString(fn(groups))
+		last = idx[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+var placeholderRE = regexp.MustCompile(`\{\{\s*(\w+)(?:\s*\|\s*(\w+))?\s*\}\}`)
+
+// Expand fills "{{name}}" and "{{name | upper}}" placeholders from
+// vars. Unknown names are left in place, so a missing value is visible
+// rather than silently empty.
+func Expand(tmpl string, vars map[string]string) string {
+	return ReplaceGroupsFunc(placeholderRE, tmpl, func(g []string) string {
+		v, ok := vars[g[1]]
+		if !ok {
+			return g[0]
+		}
+		switch g[2] {
+		case "upper":
+			return strings.ToUpper(v)
+		case "lower":
+			return strings.ToLower(v)
+		}
+		return v
+	})
+}
@@ -0,0 +1,119 @@
+package unicodetext
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Unicode Text - Grapheme Clusters and Safe Truncation
+// ====================================================
+// A Go string is bytes. Ranging over it yields runes (code points). What
+// a reader sees as one character is a grapheme cluster, which can be
+// many runes: "e" + U+0301 COMBINING ACUTE, 👍 + a skin-tone modifier,
+// or a family emoji made of four people joined by U+200D ZERO WIDTH
+// JOINER. Cutting between any of those corrupts the text on screen.
+//
+// The segmentation here is a simplified form of Unicode's UAX #29 rules,
+// enough for combining marks, emoji modifiers, variation selectors, ZWJ
+// sequences, flags and CRLF. Production code should use a full
+// implementation such as github.com/rivo/uniseg.
+
+const zwj = '\u200d'
+
+// Graphemes splits s into grapheme clusters. Invalid UTF-8 bytes become
+// single-byte clusters, so joining the result always gives back s.
+func Graphemes(s string) []string {
+	var out []string
+	for len(s) > 0 {
+		n := clusterLen(s)
+		out = append(out, s[:n])
+		s = s[n:]
+	}
+	return out
+}
+
+// GraphemeCount returns the number of user-perceived characters in s
+func GraphemeCount(s string) int {
+	count := 0
+	for len(s) > 0 {
+		s = s[clusterLen(s):]
+		count++
+	}
+	return count
+}
+
+// TruncateSafe returns the longest prefix of s that is at most n bytes
+// and does not split a grapheme cluster. n is in bytes because that is
+// what storage limits (database columns, headers, log fields) measure.
+func TruncateSafe(s string, n int) string {
+	end := 0
+	for end < len(s) {
+		next := end + clusterLen(s[end:])
+		if next > n {
+			break
+		}
+		end = next
+	}
+	return s[:end]
+}
+
+// clusterLen returns the byte length of the first grapheme cluster in s
+func clusterLen(s string) int {
+	r, i := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError && i <= 1 {
+		return max(i, 1) // invalid byte: a cluster of its own
+	}
+
+	// CR LF is one cluster; other controls never join anything
+	if r == '\r' && i < len(s) && s[i] == '\n' {
+		return 2
+	}
+	if unicode.IsControl(r) {
+		return i
+	}
+
+	// Regional indicators pair up into flags: 🇯 + 🇵 = 🇯🇵
+	if isRegionalIndicator(r) {
+		if r2, n := utf8.DecodeRuneInString(s[i:]); isRegionalIndicator(r2) {
+			i += n
+		}
+		return i
+	}
+
+	for i < len(s) {
+		r2, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r2 == utf8.RuneError && n <= 1:
+			return i
+		case isExtend(r2):
+			i += n
+		case r2 == zwj:
+			// ZWJ glues the next pictograph on: 👨 ZWJ 👩 ZWJ 👧
+			i += n
+			if r3, n3 := utf8.DecodeRuneInString(s[i:]); isPictographic(r3) {
+				i += n3
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// isExtend reports runes that attach to the preceding character
+func isExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r >= 0xFE00 && r <= 0xFE0F || // variation selectors (text vs emoji style)
+		r >= 0x1F3FB && r <= 0x1F3FF || // emoji skin-tone modifiers
+		r >= 0xE0020 && r <= 0xE007F // tags, used in subdivision flags
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isPictographic approximates Extended_Pictographic: symbols, dingbats
+// and the emoji planes
+func isPictographic(r rune) bool {
+	return r >= 0x2600 && r <= 0x27BF || r >= 0x1F000 && r <= 0x1FAFF
+}
@@ -0,0 +1,253 @@
+package unicodetext
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Unicode Text - Bytes, Code Points and Graphemes
+// ===============================================
+// Run with:
+//
+//   cd strings-bytes/unicodetext
+//   go test -v *.go
+
+// Test strings, written with escapes so the bytes are unambiguous
+const (
+	ascii      = "Go"
+	japanese   = "\u65e5\u672c\u8a9e"                         // 日本語
+	eAcuteNFC  = "\u00e9"                                     // é as one code point
+	eAcuteNFD  = "e\u0301"                                    // e + COMBINING ACUTE ACCENT
+	thumbsTone = "\U0001F44D\U0001F3FD"                       // 👍🏽 thumbs up + medium skin tone
+	family     = "\U0001F468\u200d\U0001F469\u200d\U0001F467" // 👨‍👩‍👧 joined by ZWJ
+	flagJP     = "\U0001F1EF\U0001F1F5"                       // 🇯🇵 two regional indicators
+	heartEmoji = "\u2764\ufe0f"                               // ❤️ heart + emoji-style variation selector
+)
+
+// 1. Bytes vs Code Points vs Grapheme Clusters
+// ============================================
+
+func TestThreeLengths(t *testing.T) {
+	tests := []struct {
+		s                      string
+		bytes, runes, clusters int
+	}{
+		{ascii, 2, 2, 2},
+		{japanese, 9, 3, 3},
+		{eAcuteNFC, 2, 1, 1},
+		{eAcuteNFD, 3, 2, 1},
+		{thumbsTone, 8, 2, 1},
+		{family, 18, 5, 1},
+		{flagJP, 8, 2, 1},
+		{heartEmoji, 6, 2, 1},
+		{"a\r\nb", 4, 4, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%+q", tt.s), func(t *testing.T) {
+			// len counts bytes, RuneCountInString counts code points; only
+			// grapheme clusters match what a reader would call characters
+			got := [3]int{len(tt.s), utf8.RuneCountInString(tt.s), GraphemeCount(tt.s)}
+			want := [3]int{tt.bytes, tt.runes, tt.clusters}
+			if got != want {
+				t.Errorf("bytes, runes, clusters = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGraphemesRoundTrip(t *testing.T) {
+	s := "Hi " + thumbsTone + family + flagJP + eAcuteNFD + "\xff!"
+	parts := Graphemes(s)
+	want := []string{"H", "i", " ", thumbsTone, family, flagJP, eAcuteNFD, "\xff", "!"}
+	if !slices.Equal(parts, want) {
+		t.Errorf("Graphemes = %+q, want %+q", parts, want)
+	}
+	if strings.Join(parts, "") != s {
+		t.Error("joining the clusters does not give back the input")
+	}
+}
+
+// 2. Safe Truncation
+// ==================
+
+// truncateBytes is the common bug: it can split a multi-byte rune,
+// leaving invalid UTF-8 that renders as � or is rejected by databases
+func truncateBytes(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	return s[:n]
+}
+
+// truncateRunes keeps UTF-8 valid but still splits clusters: the skin
+// tone, the second half of a flag or an accent can be cut off
+func truncateRunes(s string, n int) string {
+	end := 0
+	for i, r := range s {
+		if i+utf8.RuneLen(r) > n {
+			break
+		}
+		end = i + utf8.RuneLen(r)
+	}
+	return s[:end]
+}
+
+func TestTruncateSafe(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{"ascii", "hello", 3, "hel"},
+		{"fits", "hello", 10, "hello"},
+		{"zero", "hello", 0, ""},
+		{"mid rune", japanese, 4, "\u65e5"},
+		{"combining accent kept with its letter", "caf" + eAcuteNFD + "!", 5, "caf"},
+		{"skin tone not dropped", "ok" + thumbsTone, 6, "ok"},
+		{"family not split at a ZWJ", family + "!", 17, ""},
+		{"whole family fits", family + "!", 18, family},
+		{"half a flag", flagJP + flagJP, 12, flagJP},
+		{"variation selector kept", heartEmoji + "x", 3, ""},
+		{"CRLF not split", "a\r\nb", 2, "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateSafe(tt.s, tt.n)
+			if got != tt.want {
+				t.Errorf("TruncateSafe(%+q, %d) = %+q, want %+q", tt.s, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// The naive versions produce output that looks wrong; TruncateSafe does not
+func TestNaiveTruncationBugs(t *testing.T) {
+	s := "ok" + thumbsTone
+
+	if got := truncateBytes(s, 5); utf8.ValidString(got) {
+		t.Errorf("truncateBytes(%+q, 5) = %+q; expected invalid UTF-8", s, got)
+	}
+	if got := truncateRunes(s, 6); got != "ok\U0001F44D" {
+		t.Errorf("truncateRunes(%+q, 6) = %+q; expected the skin tone to be cut off", s, got)
+	}
+	if got := truncateRunes(flagJP+flagJP, 12); got != flagJP+"\U0001F1EF" {
+		t.Errorf("truncateRunes split flags = %+q; expected a flag and a lone indicator", got)
+	}
+}
+
+// Properties that must hold for every input and every n
+func TestTruncateSafeProperties(t *testing.T) {
+	inputs := []string{"", ascii, japanese, eAcuteNFD + eAcuteNFC, thumbsTone + family + flagJP, heartEmoji + "a\r\n", "a\xffb\xc3"}
+	for _, s := range inputs {
+		boundaries := map[int]bool{0: true}
+		end := 0
+		for _, g := range Graphemes(s) {
+			end += len(g)
+			boundaries[end] = true
+		}
+
+		for n := 0; n <= len(s)+1; n++ {
+			got := TruncateSafe(s, n)
+			switch {
+			case len(got) > n:
+				t.Errorf("TruncateSafe(%+q, %d) = %+q is longer than %d bytes", s, n, got, n)
+			case !strings.HasPrefix(s, got):
+				t.Errorf("TruncateSafe(%+q, %d) = %+q is not a prefix", s, n, got)
+			case !boundaries[len(got)]:
+				t.Errorf("TruncateSafe(%+q, %d) = %+q ends inside a cluster", s, n, got)
+			case utf8.ValidString(s) && !utf8.ValidString(got):
+				t.Errorf("TruncateSafe(%+q, %d) = %+q is invalid UTF-8", s, n, got)
+			}
+		}
+	}
+}
+
+// 3. Invalid UTF-8
+// ================
+
+func TestInvalidUTF8(t *testing.T) {
+	// Strings can hold any bytes: file contents, network data, a byte
+	// slice cut in the wrong place
+	s := "a\xffb\xc3" // \xff is never valid; \xc3 starts a 2-byte rune that never ends
+
+	if utf8.ValidString(s) {
+		t.Fatal("expected invalid UTF-8")
+	}
+
+	// range decodes each bad byte as U+FFFD with width 1, so loops
+	// always make progress - but the original bytes are lost
+	var runes []rune
+	for _, r := range s {
+		runes = append(runes, r)
+	}
+	if want := []rune{'a', utf8.RuneError, 'b', utf8.RuneError}; !slices.Equal(runes, want) {
+		t.Errorf("range = %q, want %q", runes, want)
+	}
+
+	// Repair at the boundary where untrusted text enters the program
+	if got := strings.ToValidUTF8(s, "\uFFFD"); got != "a\uFFFDb\uFFFD" {
+		t.Errorf("ToValidUTF8 = %+q", got)
+	}
+
+	// []rune conversion also replaces, so []rune -> string is not a round trip
+	if string([]rune(s)) == s {
+		t.Error("expected string([]rune(s)) to differ for invalid input")
+	}
+}
+
+// 4. Normalization
+// ================
+
+func TestNormalizationForms(t *testing.T) {
+	// NFC composes (é is U+00E9), NFD decomposes (e + U+0301). Both
+	// render identically, but Go compares bytes, so they are different
+	// strings and different map keys.
+	if eAcuteNFC == eAcuteNFD {
+		t.Fatal("NFC and NFD forms should differ as Go strings")
+	}
+	if strings.EqualFold(eAcuteNFC, eAcuteNFD) {
+		t.Error("case folding does not normalize")
+	}
+	if GraphemeCount(eAcuteNFC) != GraphemeCount(eAcuteNFD) {
+		t.Error("both forms should be one grapheme cluster")
+	}
+
+	// macOS file names often arrive in NFD while typed input is NFC, so
+	// "the same" name fails to match. Normalize before comparing or
+	// storing, with golang.org/x/text/unicode/norm:
+	//   norm.NFC.String(s)
+	// Which form matters less than using one consistently.
+	users := map[string]bool{"Ren" + eAcuteNFC: true}
+	if users["Ren"+eAcuteNFD] {
+		t.Error("expected the NFD spelling to miss the NFC map key")
+	}
+}
+
+// 5. Examples
+// ===========
+
+func ExampleTruncateSafe() {
+	s := "Go " + thumbsTone + "!"
+	for _, n := range []int{4, 11, 12} {
+		fmt.Printf("%2d bytes: %q\n", n, TruncateSafe(s, n))
+	}
+	// Output:
+	//  4 bytes: "Go "
+	// 11 bytes: "Go 👍🏽"
+	// 12 bytes: "Go 👍🏽!"
+}
+
+func ExampleGraphemes() {
+	for _, g := range Graphemes(eAcuteNFD + flagJP) {
+		fmt.Printf("%+q: %d bytes, %d runes\n", g, len(g), utf8.RuneCountInString(g))
+	}
+	// Output:
+	// "e\u0301": 3 bytes, 2 runes
+	// "\U0001f1ef\U0001f1f5": 8 bytes, 2 runes
+}
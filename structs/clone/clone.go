@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// A Generic Deep Copy
+// ===================
+// Clone walks a value with reflection and copies everything it can
+// reach. Three details decide whether the copy is really independent:
+//
+//   - a pointer, map or slice seen before is not copied again, so shared
+//     references stay shared and cycles terminate
+//   - a slice is copied to a new array of exactly its length, so the
+//     spare capacity of the original cannot be reached through the clone
+//   - unexported fields are copied through unsafe, since reflection
+//     will read them but not set them
+
+// Clone returns a deep copy of v. Slices, maps, pointers and struct fields
+// (including unexported ones) are copied recursively. Pointers, maps and
+// slices that are reachable more than once, including cycles, are copied
+// exactly once so the clone has the same shape as the original. Channels and functions
+// are copied by reference since they cannot be meaningfully duplicated.
+func Clone[T any](v T) T {
+	src := reflect.ValueOf(&v).Elem()
+	dst := reflect.New(src.Type()).Elem()
+	c := cloner{seen: make(map[visit]reflect.Value)}
+	c.copy(dst, src)
+	return dst.Interface().(T)
+}
+
+// visit identifies a pointer, map or slice already copied; the type is
+// part of the key because a struct and its first field share the same
+// address, and the length because s[:1] and s[:2] do too
+type visit struct {
+	ptr unsafe.Pointer
+	typ reflect.Type
+	len int
+}
+
+type cloner struct {
+	seen map[visit]reflect.Value
+}
+
+func (c *cloner) copy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		key := visit{src.UnsafePointer(), src.Type(), 0}
+		if existing, ok := c.seen[key]; ok {
+			dst.Set(existing)
+			return
+		}
+		ptr := reflect.New(src.Type().Elem())
+		c.seen[key] = ptr
+		c.copy(ptr.Elem(), src.Elem())
+		dst.Set(ptr)
+
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		key := visit{src.UnsafePointer(), src.Type(), src.Len()}
+		if existing, ok := c.seen[key]; ok {
+			dst.Set(existing)
+			return
+		}
+		out := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		c.seen[key] = out
+		for i := 0; i < src.Len(); i++ {
+			c.copy(out.Index(i), src.Index(i))
+		}
+		dst.Set(out)
+
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.copy(dst.Index(i), src.Index(i))
+		}
+
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		key := visit{src.UnsafePointer(), src.Type(), 0}
+		if existing, ok := c.seen[key]; ok {
+			dst.Set(existing)
+			return
+		}
+		out := reflect.MakeMapWithSize(src.Type(), src.Len())
+		c.seen[key] = out
+		iter := src.MapRange()
+		for iter.Next() {
+			key := reflect.New(src.Type().Key()).Elem()
+			c.copy(key, addressable(iter.Key()))
+			val := reflect.New(src.Type().Elem()).Elem()
+			c.copy(val, addressable(iter.Value()))
+			out.SetMapIndex(key, val)
+		}
+		dst.Set(out)
+
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			c.copy(settable(dst.Field(i)), settable(src.Field(i)))
+		}
+
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		inner := reflect.New(src.Elem().Type()).Elem()
+		c.copy(inner, addressable(src.Elem()))
+		dst.Set(inner)
+
+	default:
+		// Basic kinds, channels, funcs and unsafe pointers copy by value
+		dst.Set(src)
+	}
+}
+
+// settable lifts the read-only restriction reflection places on
+// unexported fields so they can be copied like any other field
+func settable(v reflect.Value) reflect.Value {
+	if v.CanSet() || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// addressable copies map entries and interface contents into a variable,
+// since reflection only exposes unexported fields of addressable structs
+func addressable(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	out.Set(v)
+	return out
+}
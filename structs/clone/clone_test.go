@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// clone - Tests
+// =============
+// Run with:
+//
+//   cd structs/clone
+//   go test -v *.go
+
+// 1. Aliasing
+// ===========
+
+// Each case mutates or inspects a clone and reports whether the
+// original came through untouched
+func TestCloneAliasing(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   func() bool
+	}{
+		{"cloned slice does not share backing array", func() bool {
+			s := []int{1, 2, 3}
+			c := Clone(s)
+			c[0] = 9
+			return s[0] == 1
+		}},
+		{"cloned map is independent", func() bool {
+			m := map[string]int{"a": 1}
+			c := Clone(m)
+			c["a"] = 2
+			c["b"] = 3
+			return m["a"] == 1 && len(m) == 1
+		}},
+		{"nested pointer is copied", func() bool {
+			e := Employee{Home: &Address{City: "Reno"}}
+			c := Clone(e)
+			return c.Home != e.Home && c.Home.City == "Reno"
+		}},
+		{"map of slices is copied all the way down", func() bool {
+			m := map[string][]string{"team": {"alice", "bob"}}
+			c := Clone(m)
+			c["team"][0] = "eve"
+			return m["team"][0] == "alice"
+		}},
+		{"nil slice stays nil", func() bool {
+			var s []int
+			return Clone(s) == nil
+		}},
+		{"empty slice stays non-nil", func() bool {
+			return Clone([]int{}) != nil
+		}},
+		{"spare capacity cannot be reached through clone", func() bool {
+			base := make([]int, 1, 4)
+			c := Clone(base)
+			_ = append(c, 5)
+			return cap(base) == 4 && base[:2][1] == 0
+		}},
+		{"unexported fields are copied", func() bool {
+			e := Employee{Name: "Dave", nickname: "D"}
+			return Clone(e).nickname == "D"
+		}},
+		{"interface holding a pointer is copied", func() bool {
+			a := &Address{City: "Reno"}
+			c := Clone(any(a)).(*Address)
+			c.City = "Oslo"
+			return a.City == "Reno"
+		}},
+		{"self-referencing struct terminates", func() bool {
+			e := &Employee{Name: "loop"}
+			e.Manager = e
+			c := Clone(e)
+			return c.Manager == c && c != e
+		}},
+		{"cycle through a slice is preserved", func() bool {
+			boss := &Employee{Name: "Erin"}
+			boss.Reports = []*Employee{{Name: "Frank", Manager: boss}}
+			c := Clone(boss)
+			return c.Reports[0].Manager == c && c.Reports[0] != boss.Reports[0]
+		}},
+		{"map containing itself terminates", func() bool {
+			m := map[string]any{}
+			m["self"] = m
+			c := Clone(m)
+			return reflect.ValueOf(c["self"]).UnsafePointer() == reflect.ValueOf(c).UnsafePointer() &&
+				reflect.ValueOf(c).UnsafePointer() != reflect.ValueOf(m).UnsafePointer()
+		}},
+		{"slice containing itself terminates", func() bool {
+			s := make([]any, 1)
+			s[0] = s
+			c := Clone(s)
+			return &c[0].([]any)[0] == &c[0] && &c[0] != &s[0]
+		}},
+		{"slices of one array keep their own lengths", func() bool {
+			s := []int{1, 2, 3}
+			c := Clone([][]int{s[:1], s[:2]})
+			return len(c[0]) == 1 && len(c[1]) == 2
+		}},
+		{"shared pointer stays shared", func() bool {
+			home := &Address{City: "Portland"}
+			c := Clone([]*Address{home, home})
+			return c[0] == c[1] && c[0] != home
+		}},
+		{"channels and funcs are copied by reference", func() bool {
+			type handle struct {
+				ch chan int
+				fn func() int
+			}
+			h := handle{make(chan int, 1), func() int { return 7 }}
+			c := Clone(h)
+			return c.ch == h.ch && c.fn() == 7
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.ok() {
+				t.Error("failed")
+			}
+		})
+	}
+}
+
+// 2. Equal Contents
+// =================
+
+func TestCloneIsDeepEqual(t *testing.T) {
+	boss := &Employee{Name: "Erin", Skills: []string{"go"}, Scores: map[string]int{"review": 4}, Home: &Address{City: "Austin"}}
+	boss.Reports = []*Employee{{Name: "Frank", Manager: boss, nickname: "F"}}
+
+	tests := []any{
+		42,
+		"text",
+		[3]int{1, 2, 3},
+		[][]int{{1, 2}, {3, 4}},
+		map[Address][]int{{City: "Reno"}: {1}},
+		*boss,
+		boss,
+	}
+	for _, v := range tests {
+		if c := Clone(v); !reflect.DeepEqual(c, v) {
+			t.Errorf("Clone(%#v) = %#v", v, c)
+		}
+	}
+}
+
+// The hand-written DeepCopy covers the fields it was written for
+func TestManualDeepCopy(t *testing.T) {
+	e := Employee{Skills: []string{"go"}, Scores: map[string]int{"review": 5}, Home: &Address{City: "Austin"}}
+	c := e.DeepCopy()
+	c.Skills[0], c.Scores["review"], c.Home.City = "java", 2, "Seattle"
+	if e.Skills[0] != "go" || e.Scores["review"] != 5 || e.Home.City != "Austin" {
+		t.Errorf("original changed through the copy: %+v", e)
+	}
+}
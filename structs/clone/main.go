@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Go Deep Copy - Shallow vs Deep Copies
+// =====================================
+// This lesson demonstrates why assigning a struct only copies the top
+// level, and uses the reflection-based Clone[T] in clone.go, which
+// copies everything reachable. clone_test.go pins the aliasing bugs
+// Clone exists to prevent.
+//
+// Run with:
+//
+//   cd structs/clone
+//   go run clone.go main.go
+//   go test -v *.go
+
+// Types used throughout the lesson
+// ================================
+type Address struct {
+	Street string
+	City   string
+}
+
+type Employee struct {
+	Name     string
+	Skills   []string
+	Scores   map[string]int
+	Home     *Address
+	Manager  *Employee
+	Reports  []*Employee
+	nickname string // unexported fields are copied too
+}
+
+func main() {
+	fmt.Println("=== Go Deep Copy ===")
+
+	// 1. Shallow copy with plain assignment
+	shallowCopy()
+
+	// 2. The slice aliasing bug
+	sliceAliasing()
+
+	// 3. Manual deep copy
+	manualDeepCopy()
+
+	// 4. Generic reflection-based Clone
+	genericClone()
+
+	// 5. Cycles and shared pointers
+	cyclesAndSharing()
+}
+
+// 1. Shallow Copy with Plain Assignment
+// =====================================
+func shallowCopy() {
+	fmt.Println("\n1. SHALLOW COPY:")
+
+	original := Employee{
+		Name:   "Alice",
+		Skills: []string{"go", "sql"},
+		Scores: map[string]int{"review": 4},
+		Home:   &Address{Street: "1 Main St", City: "Boston"},
+	}
+
+	// Assignment copies the struct header: strings and ints are values,
+	// but slices, maps and pointers still point at the same memory
+	copied := original
+	copied.Name = "Bob"
+	copied.Skills[0] = "rust"
+	copied.Scores["review"] = 1
+	copied.Home.City = "Denver"
+
+	fmt.Printf("   original.Name: %s (independent)\n", original.Name)
+	fmt.Printf("   original.Skills: %v (changed through the copy!)\n", original.Skills)
+	fmt.Printf("   original.Scores: %v (changed through the copy!)\n", original.Scores)
+	fmt.Printf("   original.Home.City: %s (changed through the copy!)\n", original.Home.City)
+}
+
+// 2. The Slice Aliasing Bug
+// =========================
+func sliceAliasing() {
+	fmt.Println("\n2. SLICE ALIASING BUG:")
+
+	base := make([]int, 3, 10)
+	copy(base, []int{1, 2, 3})
+
+	// Both appends fit in the spare capacity, so they write to the
+	// same backing array slot - the second append overwrites the first
+	a := append(base, 4)
+	b := append(base, 99)
+
+	fmt.Printf("   a: %v\n", a)
+	fmt.Printf("   b: %v\n", b)
+	fmt.Printf("   a[3] was overwritten by b's append: %t\n", a[3] == 99)
+
+	// A clone owns its own backing array
+	c := Clone(base)
+	c = append(c, 4)
+	d := append(base, 7)
+	fmt.Printf("   cloned c: %v, d: %v (no interference)\n", c, d)
+}
+
+// 3. Manual Deep Copy
+// ===================
+func manualDeepCopy() {
+	fmt.Println("\n3. MANUAL DEEP COPY:")
+
+	original := Employee{
+		Name:   "Carol",
+		Skills: []string{"go"},
+		Scores: map[string]int{"review": 5},
+		Home:   &Address{City: "Austin"},
+	}
+
+	copied := original.DeepCopy()
+	copied.Skills[0] = "java"
+	copied.Scores["review"] = 2
+	copied.Home.City = "Seattle"
+
+	fmt.Printf("   original: %v %v %s\n", original.Skills, original.Scores, original.Home.City)
+	fmt.Printf("   copied:   %v %v %s\n", copied.Skills, copied.Scores, copied.Home.City)
+	fmt.Println("   Manual copies are fast and explicit, but must be updated")
+	fmt.Println("   every time a reference field is added to the struct")
+}
+
+// DeepCopy copies the reference fields of Employee by hand
+func (e Employee) DeepCopy() Employee {
+	out := e
+	if e.Skills != nil {
+		out.Skills = make([]string, len(e.Skills))
+		copy(out.Skills, e.Skills)
+	}
+	if e.Scores != nil {
+		out.Scores = make(map[string]int, len(e.Scores))
+		for k, v := range e.Scores {
+			out.Scores[k] = v
+		}
+	}
+	if e.Home != nil {
+		home := *e.Home
+		out.Home = &home
+	}
+	return out
+}
+
+// 4. Generic Reflection-Based Clone
+// =================================
+func genericClone() {
+	fmt.Println("\n4. GENERIC CLONE:")
+
+	original := Employee{
+		Name:     "Dave",
+		Skills:   []string{"go", "k8s"},
+		Scores:   map[string]int{"review": 3},
+		Home:     &Address{City: "Chicago"},
+		nickname: "D",
+	}
+
+	copied := Clone(original)
+	copied.Skills[1] = "docker"
+	copied.Scores["review"] = 5
+	copied.Home.City = "Miami"
+
+	fmt.Printf("   original: %v %v %s %q\n", original.Skills, original.Scores, original.Home.City, original.nickname)
+	fmt.Printf("   copied:   %v %v %s %q\n", copied.Skills, copied.Scores, copied.Home.City, copied.nickname)
+
+	// Clone works for any type, not just structs
+	matrix := [][]int{{1, 2}, {3, 4}}
+	matrixCopy := Clone(matrix)
+	matrixCopy[0][0] = 100
+	fmt.Printf("   matrix: %v, clone: %v\n", matrix, matrixCopy)
+
+	nested := map[string][]string{"team": {"alice", "bob"}}
+	nestedCopy := Clone(nested)
+	nestedCopy["team"][0] = "eve"
+	fmt.Printf("   map of slices: %v, clone: %v\n", nested, nestedCopy)
+}
+
+// 5. Cycles and Shared Pointers
+// =============================
+func cyclesAndSharing() {
+	fmt.Println("\n5. CYCLES AND SHARED POINTERS:")
+
+	// boss -> report -> boss forms a cycle; a naive recursive copy
+	// would never terminate
+	boss := &Employee{Name: "Erin"}
+	report := &Employee{Name: "Frank", Manager: boss}
+	boss.Reports = []*Employee{report}
+
+	bossCopy := Clone(boss)
+	fmt.Printf("   cloned boss: %s, report: %s\n", bossCopy.Name, bossCopy.Reports[0].Name)
+	fmt.Printf("   cycle preserved: %t\n", bossCopy.Reports[0].Manager == bossCopy)
+	fmt.Printf("   cycle detached from original: %t\n", bossCopy.Reports[0].Manager != boss)
+
+	// Two fields pointing at one Address stay shared in the clone
+	home := &Address{City: "Portland"}
+	pair := []*Address{home, home}
+	pairCopy := Clone(pair)
+	fmt.Printf("   shared pointer still shared: %t\n", pairCopy[0] == pairCopy[1])
+}
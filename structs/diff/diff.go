@@ -0,0 +1,157 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Struct Diff - Field-by-Field Equality
+// =====================================
+// Diff walks two values with reflection and reports each difference as
+// a path and the two values: "Address.City", "Hobbies[1]",
+// "Scores[math]". reflect.DeepEqual answers yes or no; Diff says where.
+//
+// Three things make a walk over arbitrary values safe:
+//
+//   - nil: reflect.ValueOf(nil) is the invalid Value, which has no
+//     Type; it must be checked before anything else is asked of it
+//   - map keys: a key is looked up with MapIndex on both maps, so keys
+//     that print alike (1 and "1" in a map[any]int) stay distinct
+//   - cycles: a pointer, map or slice pair already on the walk is not
+//     walked again, as reflect.DeepEqual does, so a list whose last node
+//     points at its first does not overflow the stack
+
+// Diff returns one line per difference between a and b
+func Diff(a, b any) []string {
+	d := differ{visited: make(map[visit]bool)}
+	d.values("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return d.diffs
+}
+
+// visit is a pair of references being compared
+type visit struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+type differ struct {
+	diffs   []string
+	visited map[visit]bool
+}
+
+func (d *differ) addf(path, format string, args ...any) {
+	if path == "" {
+		path = "value"
+	}
+	d.diffs = append(d.diffs, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (d *differ) values(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.addf(path, "%s != %s", show(a), show(b))
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.addf(path, "type %s != %s", a.Type(), b.Type())
+		return
+	}
+
+	// A pair already on the walk is equal as far as this walk can tell
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if !a.IsNil() && !b.IsNil() {
+			v := visit{a.Pointer(), b.Pointer(), a.Type()}
+			if d.visited[v] {
+				return
+			}
+			d.visited[v] = true
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := range a.NumField() {
+			name := a.Type().Field(i).Name
+			if path != "" {
+				name = path + "." + name
+			}
+			d.values(name, a.Field(i), b.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			d.addf(path, "length %d != %d", a.Len(), b.Len())
+		}
+		for i := range min(a.Len(), b.Len()) {
+			d.values(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i))
+		}
+	case reflect.Map:
+		// Every key of a, then the keys only b has; sorted by how they
+		// print so the output is stable between runs
+		keys := a.MapKeys()
+		for _, k := range b.MapKeys() {
+			if !a.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		slices.SortStableFunc(keys, func(x, y reflect.Value) int {
+			return cmp.Compare(key(x), key(y))
+		})
+		for _, k := range keys {
+			av, bv := a.MapIndex(k), b.MapIndex(k)
+			keyPath := fmt.Sprintf("%s[%s]", path, key(k))
+			switch {
+			case !av.IsValid():
+				d.addf(keyPath, "missing != %v", bv)
+			case !bv.IsValid():
+				d.addf(keyPath, "%v != missing", av)
+			default:
+				d.values(keyPath, av, bv)
+			}
+		}
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.addf(path, "%v != %v", a, b)
+			}
+			return
+		}
+		d.values(path, a.Elem(), b.Elem())
+	case reflect.Func:
+		// Functions are only comparable to nil
+		if a.IsNil() != b.IsNil() {
+			d.addf(path, "func nil=%t != nil=%t", a.IsNil(), b.IsNil())
+		}
+	default:
+		if !a.CanInterface() {
+			// Unexported fields cannot be read through Interface()
+			if fmt.Sprint(a) != fmt.Sprint(b) {
+				d.addf(path, "%v != %v", a, b)
+			}
+			return
+		}
+		if a.Interface() != b.Interface() {
+			d.addf(path, "%#v != %#v", a.Interface(), b.Interface())
+		}
+	}
+}
+
+// key prints a map key for a path: as is, unless the key type is an
+// interface, where 1 and "1" must not look alike
+func key(k reflect.Value) string {
+	if k.Kind() == reflect.Interface {
+		return fmt.Sprintf("%#v", k.Elem())
+	}
+	return fmt.Sprint(k)
+}
+
+// show prints a value that may be the invalid Value of a nil interface
+func show(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%#v", v)
+}
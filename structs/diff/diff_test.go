@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// diff - Tests
+// ============
+// Run with:
+//
+//   cd structs/diff
+//   go test -v *.go
+
+// 1. Differences
+// ==============
+
+func TestDiff(t *testing.T) {
+	a := Profile{Name: "Alice", Hobbies: []string{"chess", "go"}, Scores: map[string]int{"math": 90}}
+	a.Address.City = "Boston"
+	b := Profile{Name: "Alice", Hobbies: []string{"chess", "golf", "tennis"}, Scores: map[string]int{"math": 95, "art": 70}}
+	b.Address.City = "Denver"
+
+	want := []string{
+		`Hobbies: length 2 != 3`,
+		`Hobbies[1]: "go" != "golf"`,
+		`Scores[art]: missing != 70`,
+		`Scores[math]: 90 != 95`,
+		`Address.City: "Boston" != "Denver"`,
+	}
+	if got := Diff(a, b); !slices.Equal(got, want) {
+		t.Errorf("Diff =\n%q\nwant\n%q", got, want)
+	}
+	if got := Diff(a, a); len(got) != 0 {
+		t.Errorf("Diff(a, a) = %q, want none", got)
+	}
+}
+
+func TestDiffTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b any
+		want []string
+	}{
+		{"different types", 1, "1", []string{`value: type int != string`}},
+		{"nil pointer", &Node{}, (*Node)(nil), []string{`value: &{0 <nil>} != <nil>`}},
+		{"func", struct{ F func() }{func() {}}, struct{ F func() }{}, []string{`F: func nil=false != nil=true`}},
+		{"unexported", struct{ n int }{1}, struct{ n int }{2}, []string{`n: 1 != 2`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.a, tt.b); !slices.Equal(got, tt.want) {
+				t.Errorf("Diff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 2. Walking Safely
+// =================
+
+// Rings would be walked forever without the visited set
+func TestDiffCycles(t *testing.T) {
+	ring := func(values ...int) *Node {
+		head := &Node{Value: values[0]}
+		n := head
+		for _, v := range values[1:] {
+			n.Next = &Node{Value: v}
+			n = n.Next
+		}
+		n.Next = head
+		return head
+	}
+	if got, want := Diff(ring(1, 2, 3), ring(1, 2, 4)), []string{`Next.Next.Value: 3 != 4`}; !slices.Equal(got, want) {
+		t.Errorf("Diff = %q, want %q", got, want)
+	}
+	r := ring(1, 2)
+	if got := Diff(r, r); len(got) != 0 {
+		t.Errorf("Diff(r, r) = %q, want none", got)
+	}
+
+	// A slice that holds itself
+	s := []any{1, nil}
+	s[1] = s
+	u := []any{2, nil}
+	u[1] = u
+	if got, want := Diff(s, u), []string{`[0]: 1 != 2`}; !slices.Equal(got, want) {
+		t.Errorf("Diff = %q, want %q", got, want)
+	}
+}
+
+// Keys that print alike are still different keys
+func TestDiffMapKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[any]int
+		want []string
+	}{
+		{"value differs", map[any]int{1: 10, "1": 20}, map[any]int{1: 10, "1": 21}, []string{`["1"]: 20 != 21`}},
+		{"key type differs", map[any]int{1: 10}, map[any]int{"1": 10}, []string{`["1"]: missing != 10`, `[1]: 10 != missing`}},
+		{"equal", map[any]int{1: 10, "1": 20}, map[any]int{"1": 20, 1: 10}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.a, tt.b); !slices.Equal(got, tt.want) {
+				t.Errorf("Diff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// reflect.ValueOf(nil) is the invalid Value; asking its Type panics
+func TestDiffNil(t *testing.T) {
+	if got := Diff(nil, nil); len(got) != 0 {
+		t.Errorf("Diff(nil, nil) = %q, want none", got)
+	}
+	if got, want := Diff(nil, 1), []string{`value: <nil> != 1`}; !slices.Equal(got, want) {
+		t.Errorf("Diff(nil, 1) = %q, want %q", got, want)
+	}
+	if got, want := Diff(1, nil), []string{`value: 1 != <nil>`}; !slices.Equal(got, want) {
+		t.Errorf("Diff(1, nil) = %q, want %q", got, want)
+	}
+
+	// A nil interface inside a struct is not the invalid Value, but
+	// its Elem would be
+	type box struct{ V any }
+	if got, want := Diff(box{}, box{V: 1}), []string{`V: <nil> != 1`}; !slices.Equal(got, want) {
+		t.Errorf("Diff = %q, want %q", got, want)
+	}
+}
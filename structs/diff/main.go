@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Struct Diff - Which Fields Differ
+// =================================
+// go_structs.go shows that a struct holding a slice, map or func has no
+// == operator. This lesson answers the question that leaves: when two
+// such values are not equal, where do they differ?
+//
+// Run with:
+//
+//   cd structs/diff
+//   go run diff.go main.go
+//   go test -v *.go
+
+// Profile cannot be compared with ==: Hobbies and Scores rule it out
+type Profile struct {
+	Name    string
+	Hobbies []string
+	Scores  map[string]int
+	Address struct{ City string }
+}
+
+// Node is a list that can loop back on itself
+type Node struct {
+	Value int
+	Next  *Node
+}
+
+func main() {
+	fmt.Println("=== Struct Diff ===")
+
+	// 1. A yes/no answer and a diff
+	profiles()
+
+	// 2. Cycles
+	cycles()
+
+	// 3. Map keys and nil
+	keysAndNil()
+}
+
+// 1. A Yes/No Answer and a Diff
+// =============================
+func profiles() {
+	fmt.Println("\n1. A YES/NO ANSWER AND A DIFF:")
+
+	a := Profile{Name: "Alice", Hobbies: []string{"chess", "go"}, Scores: map[string]int{"math": 90}}
+	a.Address.City = "Boston"
+	b := Profile{Name: "Alice", Hobbies: []string{"chess", "golf"}, Scores: map[string]int{"math": 95, "art": 70}}
+	b.Address.City = "Denver"
+
+	// fmt.Println(a == b) // compile error: Profile cannot be compared
+	fmt.Printf("   reflect.DeepEqual(a, b): %t (but which fields differ?)\n", reflect.DeepEqual(a, b))
+	fmt.Println("   Diff(a, b):")
+	for _, line := range Diff(a, b) {
+		fmt.Printf("     %s\n", line)
+	}
+
+	// Alternatives to ==:
+	// - reflect.DeepEqual for a yes/no answer (slow, but handles everything)
+	// - slices.Equal / maps.Equal for individual fields
+	// - a hand-written Equal method when equality has domain meaning
+	// - a comparable key struct (e.g. just ID and Name) for use as a map key
+	c := Profile{Name: "Alice", Hobbies: []string{"chess", "go"}, Scores: map[string]int{"math": 90}}
+	c.Address.City = "Boston"
+	fmt.Printf("   Diff of a and an identical copy: %d differences\n", len(Diff(a, c)))
+}
+
+// 2. Cycles
+// =========
+func cycles() {
+	fmt.Println("\n2. CYCLES:")
+
+	// Two rings of three: each walk would go round forever
+	ring := func(values ...int) *Node {
+		head := &Node{Value: values[0]}
+		n := head
+		for _, v := range values[1:] {
+			n.Next = &Node{Value: v}
+			n = n.Next
+		}
+		n.Next = head
+		return head
+	}
+	a, b := ring(1, 2, 3), ring(1, 2, 4)
+	fmt.Printf("   Diff(ring 1 2 3, ring 1 2 4): %v\n", Diff(a, b))
+	fmt.Println("   each pair of pointers is walked once; the second lap is skipped")
+}
+
+// 3. Map Keys and nil
+// ===================
+func keysAndNil() {
+	fmt.Println("\n3. MAP KEYS AND NIL:")
+
+	// 1 and "1" print alike but are different keys
+	a := map[any]int{1: 10, "1": 20}
+	b := map[any]int{1: 10, "1": 21}
+	fmt.Printf("   Diff of map[any]int: %v\n", Diff(a, b))
+
+	fmt.Printf("   Diff(nil, Profile{Name: \"Bob\"}): %v\n", Diff(nil, Profile{Name: "Bob"}))
+	fmt.Printf("   Diff(nil, nil): %d differences\n", len(Diff(nil, nil)))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+)
+
+// Type-Checking Snippets
+// ======================
+// The lesson shows what the compiler says about code that does not
+// compile. typeCheck runs go/types on a snippet, so the errors are the
+// compiler's own; methodNames lists a method set the way the spec
+// defines it.
+
+// typeCheck parses src as the body of a package and type-checks it
+func typeCheck(src string) (*types.Package, []error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "example.go", "package example\n"+src, 0)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var errs []error
+	conf := types.Config{
+		Importer: importer.Default(),
+		Error:    func(err error) { errs = append(errs, err) },
+	}
+	pkg, _ := conf.Check("example", fset, []*ast.File{file}, nil)
+	return pkg, errs
+}
+
+// showCompileError type-checks a snippet and prints either the compiler's
+// errors or a note that the snippet compiles
+func showCompileError(title, src string) {
+	_, errs := typeCheck(src)
+	if len(errs) == 0 {
+		fmt.Printf("   [ok]    %s\n", title)
+		return
+	}
+	fmt.Printf("   [error] %s\n", title)
+	for _, err := range errs {
+		msg := err.Error()
+		if te, ok := err.(types.Error); ok {
+			msg = te.Msg
+		}
+		fmt.Printf("           %s\n", msg)
+	}
+}
+
+func methodNames(t types.Type) []string {
+	mset := types.NewMethodSet(t)
+	names := make([]string, 0, mset.Len())
+	for i := 0; i < mset.Len(); i++ {
+		names = append(names, mset.At(i).Obj().Name())
+	}
+	return names
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Go Struct Embedding - Tests
+// ===========================
+// Run with:
+//
+//   cd structs/embedding
+//   go test -v *.go
+//
+// The lesson prints "[ok]" or "[error]" for each snippet it
+// type-checks. These tests pin down which is which, so a snippet that
+// stops failing for the reason the lesson gives - or a typeCheck that
+// stops reporting errors at all - fails here instead of printing the
+// wrong verdict.
+
+// 1. Snippets
+// ===========
+
+func TestTypeCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // a substring of the first error, or "" if it compiles
+	}{
+		{"ambiguous selector", `
+type Counter struct{}
+func (Counter) Name() string { return "counter" }
+type Timer struct{}
+func (Timer) Name() string { return "timer" }
+type Service struct {
+	Counter
+	Timer
+}
+func use(s Service) string { return s.Name() }`, "ambiguous selector s.Name"},
+		{"outer method shadows both", `
+type Counter struct{}
+func (Counter) Name() string { return "counter" }
+type Timer struct{}
+func (Timer) Name() string { return "timer" }
+type Service struct {
+	Counter
+	Timer
+}
+func (Service) Name() string { return "service" }
+func use(s Service) string { return s.Name() }`, ""},
+		{"ambiguous method does not satisfy interface", `
+type Namer interface{ Name() string }
+type Counter struct{}
+func (Counter) Name() string { return "counter" }
+type Timer struct{}
+func (Timer) Name() string { return "timer" }
+type Service struct {
+	Counter
+	Timer
+}
+var _ Namer = Service{}`, "does not implement Namer"},
+		{"value-embedded pointer method is not in S's method set", `
+type Incrementer interface{ Increment() }
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+type S struct{ Counter }
+var _ Incrementer = S{}`, "method Increment has pointer receiver"},
+		{"*S has the pointer method", `
+type Incrementer interface{ Increment() }
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+type S struct{ Counter }
+var _ Incrementer = &S{}`, ""},
+		{"embedding *Counter puts Increment in S's method set", `
+type Incrementer interface{ Increment() }
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+type S struct{ *Counter }
+var _ Incrementer = S{Counter: &Counter{}}`, ""},
+		{"Derived cannot be used as Base", `
+type Base struct{}
+type Derived struct{ Base }
+func takesBase(b Base) {}
+func use() { takesBase(Derived{}) }`, "cannot use Derived{}"},
+		{"pass the embedded field explicitly", `
+type Base struct{}
+type Derived struct{ Base }
+func takesBase(b Base) {}
+func use() { takesBase(Derived{}.Base) }`, ""},
+		{"*Derived cannot be converted to *Base", `
+type Base struct{}
+type Derived struct{ Base }
+var d = &Derived{}
+var b = (*Base)(d)`, "cannot convert d"},
+		// A syntax error comes back from the parser, not go/types
+		{"syntax error", `func (`, "expected"},
+		// Imports resolve through the default importer
+		{"standard library import", `
+import "strings"
+var s = strings.ToUpper("ok")`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := typeCheck(tt.src)
+			switch {
+			case tt.want == "" && len(errs) > 0:
+				t.Errorf("want it to compile, got %v", errs)
+			case tt.want != "" && len(errs) == 0:
+				t.Errorf("compiled, want an error containing %q", tt.want)
+			case tt.want != "" && !strings.Contains(errs[0].Error(), tt.want):
+				t.Errorf("error %q, want it to contain %q", errs[0], tt.want)
+			}
+		})
+	}
+}
+
+// 2. Method Sets
+// ==============
+
+func TestMethodSets(t *testing.T) {
+	pkg, errs := typeCheck(`
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+func (c Counter) Name() string { return "counter" }
+type ByValue struct{ Counter }
+type ByPointer struct{ *Counter }`)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	tests := []struct {
+		name    string
+		pointer bool
+		want    []string
+	}{
+		{"ByValue", false, []string{"Name"}},
+		{"ByValue", true, []string{"Increment", "Name"}},
+		{"ByPointer", false, []string{"Increment", "Name"}},
+		{"ByPointer", true, []string{"Increment", "Name"}},
+	}
+	for _, tt := range tests {
+		typ := pkg.Scope().Lookup(tt.name).Type()
+		if tt.pointer {
+			typ = types.NewPointer(typ)
+		}
+		if got := methodNames(typ); !slices.Equal(got, tt.want) {
+			t.Errorf("method set of %s = %v, want %v", typ, got, tt.want)
+		}
+	}
+}
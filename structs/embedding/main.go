@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+)
+
+// Go Struct Embedding - Method Promotion Deep Dive
+// ================================================
+// This file demonstrates how embedding promotes fields and methods,
+// where ambiguity comes from, and why embedding is not inheritance.
+// Examples that do not compile are type-checked with go/types so the
+// real compiler errors are shown instead of just described. The
+// type-checking helpers live in check.go; embedding_test.go checks the
+// verdicts the lesson prints.
+//
+// Run with:
+//
+//   cd structs/embedding
+//   go run check.go main.go
+//   go test -v *.go
+
+// Types used throughout the lesson
+// ================================
+type Logger struct {
+	Prefix string
+}
+
+func (l Logger) Log(msg string) string { return l.Prefix + msg }
+func (l *Logger) SetPrefix(p string)   { l.Prefix = p }
+
+type Counter struct {
+	Count int
+}
+
+func (c *Counter) Increment()  { c.Count++ }
+func (c Counter) Name() string { return "counter" }
+
+type Timer struct {
+	Millis int
+}
+
+func (t Timer) Name() string { return "timer" }
+
+// Service embeds two value types and one pointer type
+type Service struct {
+	Logger
+	Counter
+	*Timer
+}
+
+// Base and Derived show that promoted methods do not dispatch virtually
+type Base struct{}
+
+func (b Base) Describe() string { return "I am " + b.kind() }
+func (Base) kind() string       { return "Base" }
+
+type Derived struct {
+	Base
+}
+
+func (Derived) kind() string { return "Derived" }
+
+func main() {
+	fmt.Println("=== Go Struct Embedding Deep Dive ===")
+
+	// 1. Promotion of fields and methods
+	promotion()
+
+	// 2. Multiple embedding and ambiguity
+	ambiguity()
+
+	// 3. Embedding pointers
+	embeddingPointers()
+
+	// 4. Embedding interfaces in structs
+	embeddingInterfaces()
+
+	// 5. Method sets of embedded types
+	methodSets()
+
+	// 6. Embedding is not inheritance
+	notInheritance()
+}
+
+// 1. Promotion of Fields and Methods
+// ==================================
+func promotion() {
+	fmt.Println("\n1. PROMOTION:")
+
+	svc := Service{Logger: Logger{Prefix: "[svc] "}, Timer: &Timer{Millis: 5}}
+
+	// Fields and methods of embedded types are promoted to Service
+	fmt.Printf("   svc.Prefix: %q (same as svc.Logger.Prefix)\n", svc.Prefix)
+	fmt.Printf("   svc.Log(\"hi\"): %q\n", svc.Log("hi"))
+
+	// Pointer-receiver methods are promoted too; svc is addressable
+	svc.SetPrefix("[new] ")
+	svc.Increment()
+	svc.Increment()
+	fmt.Printf("   after SetPrefix/Increment: %q count=%d\n", svc.Prefix, svc.Count)
+
+	// The embedded field's name is its type name
+	fmt.Printf("   svc.Counter: %+v, svc.Timer.Millis: %d\n", svc.Counter, svc.Timer.Millis)
+}
+
+// 2. Multiple Embedding and Ambiguity
+// ===================================
+func ambiguity() {
+	fmt.Println("\n2. MULTIPLE EMBEDDING AND AMBIGUITY:")
+
+	svc := Service{Timer: &Timer{}}
+
+	// Counter and Timer both have Name() at the same depth. That is
+	// legal to declare, but svc.Name() is ambiguous and will not compile.
+	fmt.Printf("   svc.Counter.Name(): %s\n", svc.Counter.Name())
+	fmt.Printf("   svc.Timer.Name(): %s\n", svc.Timer.Name())
+
+	showCompileError("ambiguous selector", `
+type Counter struct{}
+func (Counter) Name() string { return "counter" }
+type Timer struct{}
+func (Timer) Name() string { return "timer" }
+type Service struct {
+	Counter
+	Timer
+}
+func use(s Service) string { return s.Name() }`)
+
+	// A shallower name wins: defining Name on the outer type resolves it
+	showCompileError("outer method shadows both (compiles)", `
+type Counter struct{}
+func (Counter) Name() string { return "counter" }
+type Timer struct{}
+func (Timer) Name() string { return "timer" }
+type Service struct {
+	Counter
+	Timer
+}
+func (Service) Name() string { return "service" }
+func use(s Service) string { return s.Name() }`)
+
+	// Ambiguity also stops a type from satisfying an interface
+	showCompileError("ambiguous method does not satisfy interface", `
+type Namer interface{ Name() string }
+type Counter struct{}
+func (Counter) Name() string { return "counter" }
+type Timer struct{}
+func (Timer) Name() string { return "timer" }
+type Service struct {
+	Counter
+	Timer
+}
+var _ Namer = Service{}`)
+}
+
+// 3. Embedding Pointers
+// =====================
+func embeddingPointers() {
+	fmt.Println("\n3. EMBEDDING POINTERS:")
+
+	// Embedding *Timer shares one Timer between copies of Service
+	shared := &Timer{Millis: 1}
+	a := Service{Timer: shared}
+	b := a // copies the pointer, not the Timer
+	b.Millis = 99
+	fmt.Printf("   a.Millis after b.Millis = 99: %d (shared)\n", a.Millis)
+
+	// Whereas embedded values are copied
+	b.Count = 42
+	fmt.Printf("   a.Count after b.Count = 42: %d (copied)\n", a.Count)
+
+	// A nil embedded pointer panics when a promoted field is used
+	var empty Service
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("   empty.Millis panicked: %v\n", r)
+			}
+		}()
+		fmt.Println(empty.Millis)
+	}()
+}
+
+// 4. Embedding Interfaces in Structs
+// ==================================
+type Store interface {
+	Get(key string) string
+	Put(key, value string)
+}
+
+type mapStore map[string]string
+
+func (m mapStore) Get(key string) string { return m[key] }
+func (m mapStore) Put(key, value string) { m[key] = value }
+
+// upperStore embeds Store and overrides only Get - a lightweight decorator
+type upperStore struct {
+	Store
+}
+
+func (u upperStore) Get(key string) string { return strings.ToUpper(u.Store.Get(key)) }
+
+// partialStore embeds the interface but leaves it nil - a common trick in
+// tests to implement only the methods a test actually calls
+type partialStore struct {
+	Store
+}
+
+func (partialStore) Get(key string) string { return "stub:" + key }
+
+func embeddingInterfaces() {
+	fmt.Println("\n4. EMBEDDING INTERFACES:")
+
+	inner := mapStore{}
+	var s Store = upperStore{Store: inner}
+	s.Put("name", "gopher") // promoted from the embedded interface value
+	fmt.Printf("   decorated Get: %s, inner Get: %s\n", s.Get("name"), inner.Get("name"))
+
+	var p Store = partialStore{}
+	fmt.Printf("   partial stub Get: %s\n", p.Get("x"))
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("   partial Put panicked (nil embedded interface): %v\n", r)
+			}
+		}()
+		p.Put("x", "y")
+	}()
+
+	// Embedding an io.Writer gives a struct the Write method for free
+	type prefixed struct {
+		io.Writer
+		prefix string
+	}
+	var sb strings.Builder
+	w := prefixed{Writer: &sb, prefix: "> "}
+	fmt.Fprintf(w, "%shello", w.prefix)
+	fmt.Printf("   embedded io.Writer wrote: %q\n", sb.String())
+}
+
+// 5. Method Sets of Embedded Types
+// ================================
+func methodSets() {
+	fmt.Println("\n5. METHOD SETS:")
+
+	// Embedding T promotes T's value methods to S and *S, but T's pointer
+	// methods only to *S. Embedding *T promotes both to S and *S.
+	showCompileError("value-embedded pointer method is not in S's method set", `
+type Incrementer interface{ Increment() }
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+type S struct{ Counter }
+var _ Incrementer = S{}`)
+
+	showCompileError("*S has the pointer method (compiles)", `
+type Incrementer interface{ Increment() }
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+type S struct{ Counter }
+var _ Incrementer = &S{}`)
+
+	showCompileError("embedding *Counter puts Increment in S's method set (compiles)", `
+type Incrementer interface{ Increment() }
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+type S struct{ *Counter }
+var _ Incrementer = S{Counter: &Counter{}}`)
+
+	// go/types can list the method sets directly
+	src := `
+type Counter struct{ Count int }
+func (c *Counter) Increment() { c.Count++ }
+func (c Counter) Name() string { return "counter" }
+type ByValue struct{ Counter }
+type ByPointer struct{ *Counter }`
+	pkg, _ := typeCheck(src)
+	if pkg == nil {
+		return
+	}
+	for _, name := range []string{"ByValue", "ByPointer"} {
+		t := pkg.Scope().Lookup(name).Type()
+		fmt.Printf("   method set of %-10s %v\n", name+":", methodNames(t))
+		fmt.Printf("   method set of %-10s %v\n", "*"+name+":", methodNames(types.NewPointer(t)))
+	}
+}
+
+// 6. Embedding Is Not Inheritance
+// ===============================
+func notInheritance() {
+	fmt.Println("\n6. EMBEDDING IS NOT INHERITANCE:")
+
+	// Describe is promoted from Base and runs with a Base receiver; it has
+	// no idea it was called through a Derived, so Derived.kind is ignored
+	d := Derived{}
+	fmt.Printf("   d.Describe(): %q (not \"I am Derived\")\n", d.Describe())
+	fmt.Printf("   d.kind(): %q\n", d.kind())
+
+	// A Derived is not a Base: there is no implicit upcast
+	showCompileError("Derived cannot be used as Base", `
+type Base struct{}
+type Derived struct{ Base }
+func takesBase(b Base) {}
+func use() { takesBase(Derived{}) }`)
+
+	// The explicit field works, because it IS a Base
+	showCompileError("pass the embedded field explicitly (compiles)", `
+type Base struct{}
+type Derived struct{ Base }
+func takesBase(b Base) {}
+func use() { takesBase(Derived{}.Base) }`)
+
+	// Pointers do not convert either: a *Derived is not a *Base
+	showCompileError("*Derived cannot be converted to *Base", `
+type Base struct{}
+type Derived struct{ Base }
+var d = &Derived{}
+var b = (*Base)(d)`)
+
+	fmt.Println("   Use interfaces for polymorphism; use embedding to reuse code")
+}
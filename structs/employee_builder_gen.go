@@ -0,0 +1,94 @@
+// Code generated by genbuilder from go_builder.go; DO NOT EDIT.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EmployeeBuilder builds Employee values one field at a time
+type EmployeeBuilder struct {
+	value Employee
+	set   map[string]bool
+}
+
+// NewEmployeeBuilder returns an empty EmployeeBuilder
+func NewEmployeeBuilder() *EmployeeBuilder {
+	return &EmployeeBuilder{set: make(map[string]bool)}
+}
+
+// WithID sets Employee.ID
+func (b *EmployeeBuilder) WithID(v int) *EmployeeBuilder {
+	b.value.ID = v
+	b.set["ID"] = true
+	return b
+}
+
+// WithName sets Employee.Name
+func (b *EmployeeBuilder) WithName(v string) *EmployeeBuilder {
+	b.value.Name = v
+	b.set["Name"] = true
+	return b
+}
+
+// WithEmail sets Employee.Email
+func (b *EmployeeBuilder) WithEmail(v string) *EmployeeBuilder {
+	b.value.Email = v
+	b.set["Email"] = true
+	return b
+}
+
+// WithDepartment sets Employee.Department
+func (b *EmployeeBuilder) WithDepartment(v string) *EmployeeBuilder {
+	b.value.Department = v
+	b.set["Department"] = true
+	return b
+}
+
+// WithSalary sets Employee.Salary
+func (b *EmployeeBuilder) WithSalary(v float64) *EmployeeBuilder {
+	b.value.Salary = v
+	b.set["Salary"] = true
+	return b
+}
+
+// WithSkills sets Employee.Skills
+func (b *EmployeeBuilder) WithSkills(v []string) *EmployeeBuilder {
+	b.value.Skills = v
+	b.set["Skills"] = true
+	return b
+}
+
+// Build checks required fields, runs the Validate hook if Employee has
+// one, and returns the finished value
+func (b *EmployeeBuilder) Build() (Employee, error) {
+	var errs []error
+	if !b.set["ID"] {
+		errs = append(errs, fmt.Errorf("Employee.ID is required"))
+	}
+	if !b.set["Name"] {
+		errs = append(errs, fmt.Errorf("Employee.Name is required"))
+	}
+	if !b.set["Email"] {
+		errs = append(errs, fmt.Errorf("Employee.Email is required"))
+	}
+	if len(errs) > 0 {
+		return Employee{}, errors.Join(errs...)
+	}
+	if v, ok := any(b.value).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return Employee{}, fmt.Errorf("Employee: %w", err)
+		}
+	}
+	return b.value, nil
+}
+
+// MustBuild is like Build but panics on error
+func (b *EmployeeBuilder) MustBuild() Employee {
+	v, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
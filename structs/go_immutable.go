@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Go Immutable Value Types - Copy-on-Write Patterns
+// =================================================
+// This file demonstrates building types that cannot be changed after
+// construction, "With" setters that return modified copies, and a
+// copy-on-write slice that is safe to share between goroutines
+
+// Config is immutable: its fields are unexported and every "setter"
+// returns a new Config, leaving the receiver untouched
+type Config struct {
+	host    string
+	port    int
+	timeout time.Duration
+	tags    []string // reference field: must be copied, never shared
+}
+
+// NewConfig returns a Config with sensible defaults
+func NewConfig() Config {
+	return Config{host: "localhost", port: 8080, timeout: 30 * time.Second}
+}
+
+func (c Config) Host() string           { return c.host }
+func (c Config) Port() int              { return c.port }
+func (c Config) Timeout() time.Duration { return c.timeout }
+
+// Tags returns a copy so callers cannot mutate the Config's slice
+func (c Config) Tags() []string {
+	return append([]string(nil), c.tags...)
+}
+
+// Value receivers already give us a copy to modify and return
+func (c Config) WithHost(host string) Config {
+	c.host = host
+	return c
+}
+
+func (c Config) WithPort(port int) Config {
+	c.port = port
+	return c
+}
+
+func (c Config) WithTimeout(d time.Duration) Config {
+	c.timeout = d
+	return c
+}
+
+// WithTag must allocate a new slice: appending to c.tags could write into
+// spare capacity shared with the original Config
+func (c Config) WithTag(tag string) Config {
+	tags := make([]string, len(c.tags), len(c.tags)+1)
+	copy(tags, c.tags)
+	c.tags = append(tags, tag)
+	return c
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("%s:%d timeout=%v tags=%v", c.host, c.port, c.timeout, c.tags)
+}
+
+// COWSlice is a copy-on-write slice. Readers load the current snapshot
+// without locking; writers copy the snapshot, modify the copy and swap
+// it in. Reads are cheap, writes cost O(n).
+type COWSlice[T any] struct {
+	mu   sync.Mutex // serializes writers only
+	data atomic.Pointer[[]T]
+}
+
+func NewCOWSlice[T any](items ...T) *COWSlice[T] {
+	s := &COWSlice[T]{}
+	snapshot := append([]T(nil), items...)
+	s.data.Store(&snapshot)
+	return s
+}
+
+// Snapshot returns the current contents. The returned slice must be
+// treated as read-only: it is shared with every other reader.
+func (s *COWSlice[T]) Snapshot() []T {
+	return *s.data.Load()
+}
+
+func (s *COWSlice[T]) Append(item T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := *s.data.Load()
+	next := make([]T, len(old), len(old)+1)
+	copy(next, old)
+	next = append(next, item)
+	s.data.Store(&next)
+}
+
+func (s *COWSlice[T]) Set(i int, item T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := *s.data.Load()
+	next := append([]T(nil), old...)
+	next[i] = item
+	s.data.Store(&next)
+}
+
+func (s *COWSlice[T]) Len() int {
+	return len(*s.data.Load())
+}
+
+func main() {
+	fmt.Println("=== Go Immutable Value Types ===")
+
+	// 1. With-setters return modified copies
+	withSetters()
+
+	// 2. Hidden sharing through reference fields
+	hiddenSharing()
+
+	// 3. Copy-on-write slice
+	copyOnWrite()
+
+	// 4. Concurrent readers and writers
+	concurrentAccess()
+
+	// 5. Cost profile vs mutation
+	costProfile()
+}
+
+// 1. With-Setters Return Modified Copies
+// ======================================
+func withSetters() {
+	fmt.Println("\n1. WITH-SETTERS:")
+
+	base := NewConfig()
+	prod := base.WithHost("api.example.com").WithPort(443).WithTimeout(5 * time.Second)
+
+	fmt.Printf("   base: %s\n", base)
+	fmt.Printf("   prod: %s\n", prod)
+	fmt.Println("   base is unchanged - each With call returned a new value")
+
+	// Values derived from the same base are independent
+	staging := base.WithHost("staging.example.com")
+	fmt.Printf("   staging: %s\n", staging)
+}
+
+// 2. Hidden Sharing Through Reference Fields
+// ==========================================
+func hiddenSharing() {
+	fmt.Println("\n2. HIDDEN SHARING:")
+
+	// A naive WithTag that appends directly shares the backing array
+	naiveWithTag := func(c Config, tag string) Config {
+		c.tags = append(c.tags, tag)
+		return c
+	}
+
+	base := NewConfig()
+	base.tags = make([]string, 0, 4) // spare capacity makes the bug visible
+	a := naiveWithTag(base, "blue")
+	b := naiveWithTag(base, "green")
+	fmt.Printf("   naive: a.tags=%v b.tags=%v (a was overwritten!)\n", a.tags, b.tags)
+
+	c := base.WithTag("blue")
+	d := base.WithTag("green")
+	fmt.Printf("   safe:  c.tags=%v d.tags=%v\n", c.Tags(), d.Tags())
+
+	// The getter returns a copy too, so callers cannot reach inside
+	tags := c.Tags()
+	tags[0] = "hacked"
+	fmt.Printf("   after mutating Tags() result: c.tags=%v\n", c.Tags())
+}
+
+// 3. Copy-on-Write Slice
+// ======================
+func copyOnWrite() {
+	fmt.Println("\n3. COPY-ON-WRITE SLICE:")
+
+	routes := NewCOWSlice("/home", "/about")
+	before := routes.Snapshot()
+
+	routes.Append("/contact")
+	routes.Set(0, "/")
+
+	fmt.Printf("   snapshot taken before writes: %v\n", before)
+	fmt.Printf("   current snapshot: %v\n", routes.Snapshot())
+	fmt.Println("   Old snapshots stay valid forever; readers never see a half-written slice")
+}
+
+// 4. Concurrent Readers and Writers
+// =================================
+func concurrentAccess() {
+	fmt.Println("\n4. CONCURRENT ACCESS:")
+
+	s := NewCOWSlice[int]()
+	var wg sync.WaitGroup
+	var reads atomic.Int64
+
+	// Readers never lock and never block writers
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					snap := s.Snapshot()
+					sum := 0
+					for _, v := range snap {
+						sum += v
+					}
+					_ = sum
+					reads.Add(1)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 1000; i++ {
+		s.Append(i)
+	}
+	close(stop)
+	wg.Wait()
+
+	fmt.Printf("   final length: %d, reads performed meanwhile: >0 = %t\n", s.Len(), reads.Load() > 0)
+	fmt.Println("   Run with `go run -race go_immutable.go` - no data races are reported")
+}
+
+// 5. Cost Profile vs Mutation
+// ===========================
+func costProfile() {
+	fmt.Println("\n5. COST PROFILE:")
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"mutable config: set 3 fields", func(b *testing.B) {
+			b.ReportAllocs()
+			c := &Config{}
+			for i := 0; i < b.N; i++ {
+				c.host = "h"
+				c.port = i
+				c.timeout = time.Second
+			}
+		}},
+		{"immutable config: 3 With calls", func(b *testing.B) {
+			b.ReportAllocs()
+			c := NewConfig()
+			for i := 0; i < b.N; i++ {
+				c = c.WithHost("h").WithPort(i).WithTimeout(time.Second)
+			}
+		}},
+		{"immutable config: WithTag", func(b *testing.B) {
+			b.ReportAllocs()
+			c := NewConfig().WithTag("a").WithTag("b")
+			for i := 0; i < b.N; i++ {
+				_ = c.WithTag("c")
+			}
+		}},
+		{"mutable slice: read (mutex)", func(b *testing.B) {
+			var mu sync.RWMutex
+			data := make([]int, 100)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				mu.RLock()
+				_ = data[i%100]
+				mu.RUnlock()
+			}
+		}},
+		{"COW slice: read", func(b *testing.B) {
+			s := NewCOWSlice(make([]int, 100)...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = s.Snapshot()[i%100]
+			}
+		}},
+		{"mutable slice: write (mutex)", func(b *testing.B) {
+			var mu sync.Mutex
+			data := make([]int, 100)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				mu.Lock()
+				data[i%100] = i
+				mu.Unlock()
+			}
+		}},
+		{"COW slice: write (100 items)", func(b *testing.B) {
+			s := NewCOWSlice(make([]int, 100)...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.Set(i%100, i)
+			}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(bm.fn)
+		nsPerOp := float64(r.T.Nanoseconds()) / float64(r.N)
+		fmt.Printf("   %-32s %9.1f ns/op %6d B/op %3d allocs/op\n",
+			bm.name, nsPerOp, r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+
+	fmt.Println("   With-setters on small structs are nearly free (stack copies);")
+	fmt.Println("   copy-on-write makes reads lock-free and writes O(n) - use it")
+	fmt.Println("   for data that is read constantly and changed rarely")
+}
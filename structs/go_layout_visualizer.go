@@ -0,0 +1,397 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Go Struct Layout Visualizer
+// ===========================
+// This file turns the memory-layout section of go_structs.go into a tool:
+// it renders field offsets, sizes and padding for a struct as an ASCII
+// diagram, and optionally as an SVG image.
+//
+// The three types below are compiled in and laid out with reflect. Any
+// other type is named as <dir>.<Type> or <file.go>.<Type>, relative to
+// the repository root, and laid out from source: go/types type-checks
+// the package and types.SizesFor gives the offsets the gc compiler uses
+// for this GOARCH - the same numbers reflect reports at run time.
+//
+// Usage:
+//   go run go_layout_visualizer.go                       # all known types
+//   go run go_layout_visualizer.go structs.ExampleStruct # one type
+//   go run go_layout_visualizer.go -svg layout.svg structs.ExampleStruct
+//   go run go_layout_visualizer.go storage.conn slices-maps/trie.node
+//   go run go_layout_visualizer.go structs/go_structs.go.Employee
+//
+// learnctl layout runs it from the repository root.
+
+// Types available to the visualizer
+// =================================
+type ExampleStruct struct {
+	A bool  // 1 byte
+	B int32 // 4 bytes
+	C int64 // 8 bytes
+	D bool  // 1 byte
+}
+
+type OptimizedStruct struct {
+	C int64
+	B int32
+	A bool
+	D bool
+}
+
+type MixedStruct struct {
+	Flag    bool
+	Name    string
+	Count   int16
+	Tags    []string
+	Ratio   float32
+	Enabled bool
+	Next    *MixedStruct
+}
+
+// layoutTypes maps the names accepted on the command line to types.
+// Reflection cannot look a type up by name, so tools register them.
+var layoutTypes = map[string]reflect.Type{
+	"structs.ExampleStruct":   reflect.TypeOf(ExampleStruct{}),
+	"structs.OptimizedStruct": reflect.TypeOf(OptimizedStruct{}),
+	"structs.MixedStruct":     reflect.TypeOf(MixedStruct{}),
+}
+
+// Segment is one contiguous run of bytes in a struct: a field or padding
+type Segment struct {
+	Name    string
+	Type    string
+	Offset  uintptr
+	Size    uintptr
+	Align   uintptr
+	Padding bool
+}
+
+// Layout describes the memory layout of a struct type
+type Layout struct {
+	TypeName string
+	Size     uintptr
+	Align    uintptr
+	Segments []Segment
+}
+
+// Padding returns the total number of bytes wasted on alignment
+func (l Layout) Padding() uintptr {
+	var total uintptr
+	for _, s := range l.Segments {
+		if s.Padding {
+			total += s.Size
+		}
+	}
+	return total
+}
+
+func main() {
+	svgPath := flag.String("svg", "", "also write an SVG diagram to this file")
+	root := flag.String("root", defaultRoot(), "repository `dir` that type names are relative to")
+	flag.Parse()
+
+	names := flag.Args()
+	if len(names) == 0 {
+		names = knownTypes()
+	}
+
+	fmt.Println("=== Go Struct Layout Visualizer ===")
+
+	var layouts []Layout
+	for _, name := range names {
+		var layout Layout
+		if t, ok := layoutTypes[name]; ok {
+			layout = ComputeLayout(t)
+		} else {
+			var err error
+			if layout, err = SourceLayout(*root, name); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		layouts = append(layouts, layout)
+		fmt.Println()
+		fmt.Print(RenderASCII(layout))
+	}
+
+	if *svgPath != "" {
+		if err := os.WriteFile(*svgPath, []byte(RenderSVG(layouts)), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "write svg: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nSVG written to %s\n", *svgPath)
+	}
+}
+
+// ComputeLayout walks the fields of a struct type and records every field
+// and every gap the compiler inserted for alignment, including tail padding
+func ComputeLayout(t reflect.Type) Layout {
+	layout := Layout{TypeName: t.String(), Size: t.Size(), Align: uintptr(t.Align())}
+	if t.Kind() != reflect.Struct {
+		layout.Segments = []Segment{{Name: "(value)", Type: t.String(), Size: t.Size(), Align: uintptr(t.Align())}}
+		return layout
+	}
+
+	var cursor uintptr
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Offset > cursor {
+			layout.Segments = append(layout.Segments, Segment{Name: "padding", Offset: cursor, Size: f.Offset - cursor, Padding: true})
+		}
+		layout.Segments = append(layout.Segments, Segment{
+			Name:   f.Name,
+			Type:   f.Type.String(),
+			Offset: f.Offset,
+			Size:   f.Type.Size(),
+			Align:  uintptr(f.Type.Align()),
+		})
+		cursor = f.Offset + f.Type.Size()
+	}
+	if t.Size() > cursor {
+		layout.Segments = append(layout.Segments, Segment{Name: "padding (tail)", Offset: cursor, Size: t.Size() - cursor, Padding: true})
+	}
+	return layout
+}
+
+// SourceLayout lays out the type named <dir>.<Type> or <file.go>.<Type>
+// under root without compiling it: the directory's files (or the one
+// file) are type-checked from source, and types.SizesFor supplies the
+// gc compiler's sizes and alignments for this GOARCH.
+//
+// A directory of single-file lessons redeclares main and more, which
+// the type checker reports and this ignores; an error inside the type's
+// own declaration would make its sizes wrong, so that one is refused.
+func SourceLayout(root, name string) (Layout, error) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 || i == len(name)-1 {
+		return Layout{}, fmt.Errorf("%q: want <dir>.<Type> or <file.go>.<Type> (built in: %s)", name, strings.Join(knownTypes(), ", "))
+	}
+	where, typeName := name[:i], name[i+1:]
+	path := filepath.Join(root, filepath.FromSlash(where))
+
+	var names []string
+	if strings.HasSuffix(where, ".go") {
+		names = []string{path}
+	} else {
+		bp, err := build.ImportDir(path, 0)
+		if err != nil {
+			return Layout{}, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, f := range bp.GoFiles {
+			names = append(names, filepath.Join(path, f))
+		}
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	var specs []*ast.TypeSpec
+	for _, n := range names {
+		f, err := parser.ParseFile(fset, n, nil, 0)
+		if err != nil {
+			return Layout{}, err
+		}
+		files = append(files, f)
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+				for _, spec := range gd.Specs {
+					if ts := spec.(*ast.TypeSpec); ts.Name.Name == typeName {
+						specs = append(specs, ts)
+					}
+				}
+			}
+		}
+	}
+	switch {
+	case len(specs) == 0:
+		return Layout{}, fmt.Errorf("%s: no type %s in %s", name, typeName, where)
+	case len(specs) > 1:
+		return Layout{}, fmt.Errorf("%s: %s is declared more than once, at %s and %s; name the file",
+			name, typeName, fset.Position(specs[0].Pos()), fset.Position(specs[1].Pos()))
+	}
+	spec := specs[0]
+
+	var errs []error
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			if te, ok := err.(types.Error); ok && spec.Pos() <= te.Pos && te.Pos < spec.End() {
+				errs = append(errs, err)
+			}
+		},
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, _ := conf.Check(where, fset, files, info)
+	if len(errs) > 0 {
+		return Layout{}, fmt.Errorf("%s: %w", name, errors.Join(errs...))
+	}
+	obj := info.Defs[spec.Name]
+	if obj == nil {
+		return Layout{}, fmt.Errorf("%s: %s was not type-checked", name, typeName)
+	}
+	if types.IsInterface(obj.Type()) || obj.Type().Underlying() == nil {
+		return Layout{}, fmt.Errorf("%s: %s is an interface", name, typeName)
+	}
+	if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return Layout{}, fmt.Errorf("%s: %s is generic; its layout depends on the type arguments", name, typeName)
+	}
+	return typesLayout(types.SizesFor("gc", runtime.GOARCH), types.RelativeTo(pkg), name, obj.Type()), nil
+}
+
+// typesLayout is ComputeLayout on a go/types type and a Sizes
+func typesLayout(sizes types.Sizes, qual types.Qualifier, name string, t types.Type) Layout {
+	size, align := uintptr(sizes.Sizeof(t)), uintptr(sizes.Alignof(t))
+	layout := Layout{TypeName: name, Size: size, Align: align}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		layout.Segments = []Segment{{Name: "(value)", Type: types.TypeString(t.Underlying(), qual), Size: size, Align: align}}
+		return layout
+	}
+
+	fields := make([]*types.Var, st.NumFields())
+	for i := range fields {
+		fields[i] = st.Field(i)
+	}
+	offsets := sizes.Offsetsof(fields)
+	var cursor uintptr
+	for i, f := range fields {
+		offset := uintptr(offsets[i])
+		if offset > cursor {
+			layout.Segments = append(layout.Segments, Segment{Name: "padding", Offset: cursor, Size: offset - cursor, Padding: true})
+		}
+		fsize := uintptr(sizes.Sizeof(f.Type()))
+		layout.Segments = append(layout.Segments, Segment{
+			Name:   f.Name(),
+			Type:   types.TypeString(f.Type(), qual),
+			Offset: offset,
+			Size:   fsize,
+			Align:  uintptr(sizes.Alignof(f.Type())),
+		})
+		cursor = offset + fsize
+	}
+	if size > cursor {
+		layout.Segments = append(layout.Segments, Segment{Name: "padding (tail)", Offset: cursor, Size: size - cursor, Padding: true})
+	}
+	return layout
+}
+
+// RenderASCII draws a field table followed by a byte map with one row
+// per 8-byte word; each byte shows the letter of the field that owns it
+func RenderASCII(l Layout) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s  size=%d align=%d padding=%d\n", l.TypeName, l.Size, l.Align, l.Padding())
+	fmt.Fprintf(&sb, "   %-3s %-16s %-18s %6s %5s %5s\n", "", "field", "type", "offset", "size", "align")
+
+	owner := make([]byte, l.Size)
+	letter := byte('A')
+	for _, s := range l.Segments {
+		mark := byte('.')
+		key := "."
+		if !s.Padding {
+			mark = letter
+			key = string(letter)
+			letter++
+			if letter > 'Z' {
+				letter = 'a'
+			}
+		}
+		for b := s.Offset; b < s.Offset+s.Size; b++ {
+			owner[b] = mark
+		}
+		if s.Padding {
+			fmt.Fprintf(&sb, "   %-3s %-16s %-18s %6d %5d %5s\n", key, s.Name, "", s.Offset, s.Size, "")
+		} else {
+			fmt.Fprintf(&sb, "   %-3s %-16s %-18s %6d %5d %5d\n", key, s.Name, s.Type, s.Offset, s.Size, s.Align)
+		}
+	}
+
+	const word = 8
+	border := "   +" + strings.Repeat("---+", word) + "\n"
+	sb.WriteString(border)
+	for row := uintptr(0); row < l.Size; row += word {
+		sb.WriteString("   |")
+		for col := uintptr(0); col < word; col++ {
+			if row+col < l.Size {
+				fmt.Fprintf(&sb, " %c |", owner[row+col])
+			} else {
+				sb.WriteString("   |")
+			}
+		}
+		fmt.Fprintf(&sb, " %d-%d\n", row, min(row+word, l.Size)-1)
+		sb.WriteString(border)
+	}
+	return sb.String()
+}
+
+// RenderSVG draws each layout as a row of byte cells, colored by field,
+// with padding cells hatched in grey
+func RenderSVG(layouts []Layout) string {
+	const cell, rowHeight, left = 28, 90, 10
+	palette := []string{"#4e79a7", "#f28e2b", "#59a14f", "#e15759", "#76b7b2", "#edc948", "#b07aa1", "#9c755f"}
+
+	width := left * 2
+	for _, l := range layouts {
+		width = max(width, left*2+int(l.Size)*cell)
+	}
+	height := len(layouts)*rowHeight + 10
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="11">`+"\n", width, height)
+	sb.WriteString(`<defs><pattern id="pad" width="6" height="6" patternUnits="userSpaceOnUse" patternTransform="rotate(45)">` +
+		`<rect width="6" height="6" fill="#eee"/><line x1="0" y1="0" x2="0" y2="6" stroke="#bbb" stroke-width="3"/></pattern></defs>` + "\n")
+
+	for i, l := range layouts {
+		y := 10 + i*rowHeight
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-weight="bold">%s (size %d, padding %d)</text>`+"\n", left, y+12, l.TypeName, l.Size, l.Padding())
+		color := 0
+		for _, s := range l.Segments {
+			x := left + int(s.Offset)*cell
+			w := int(s.Size) * cell
+			fill := "url(#pad)"
+			if !s.Padding {
+				fill = palette[color%len(palette)]
+				color++
+			}
+			fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#333"/>`+"\n", x, y+20, w, cell, fill)
+			if !s.Padding {
+				fmt.Fprintf(&sb, `<text x="%d" y="%d" fill="#fff">%s</text>`+"\n", x+3, y+38, s.Name)
+			}
+		}
+		for b := uintptr(0); b <= l.Size; b += 8 {
+			fmt.Fprintf(&sb, `<text x="%d" y="%d" fill="#666">%d</text>`+"\n", left+int(b)*cell, y+62, b)
+		}
+	}
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+// Helper functions
+// ================
+func knownTypes() []string {
+	return slices.Sorted(maps.Keys(layoutTypes))
+}
+
+// defaultRoot is the directory above this file, wherever it is run from
+func defaultRoot() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return ".."
+	}
+	return filepath.Dir(filepath.Dir(file))
+}
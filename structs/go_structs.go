@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"reflect"
 	"unsafe"
 )
 
@@ -357,6 +358,34 @@ func structComparison() {
 	person2 := Person{Name: "Alice"}
 	
 	fmt.Printf("   Person 1 == Person 2: %t\n", person1 == person2)
+	
+	// Why slices and maps break ==
+	// A slice is a (pointer, len, cap) header: comparing headers would only
+	// tell you whether two slices share memory, and comparing contents would
+	// make == an O(n) operation that can recurse forever. Go refuses both, so
+	// a struct containing a slice, map or func simply has no == operator.
+	type Profile struct {
+		Name    string
+		Hobbies []string
+		Scores  map[string]int
+		Address struct{ City string }
+	}
+	
+	a := Profile{Name: "Alice", Hobbies: []string{"chess", "go"}, Scores: map[string]int{"math": 90}}
+	a.Address.City = "Boston"
+	b := Profile{Name: "Alice", Hobbies: []string{"chess", "golf"}, Scores: map[string]int{"math": 95, "art": 70}}
+	b.Address.City = "Denver"
+	
+	// fmt.Println(a == b) // compile error: Profile cannot be compared
+	fmt.Printf("   reflect.DeepEqual(a, b): %t (but which fields differ?)\n", reflect.DeepEqual(a, b))
+	
+	fmt.Println("   structs/diff walks both values field by field and says where they differ")
+	
+	// Alternatives to ==:
+	// - reflect.DeepEqual for a yes/no answer (slow, but handles everything)
+	// - slices.Equal / maps.Equal for individual fields
+	// - a hand-written Equal method when equality has domain meaning
+	// - a comparable key struct (e.g. just ID and Name) for use as a map key
 }
 
 // 10. Struct Memory Layout
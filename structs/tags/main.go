@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Go Struct Tags - Writing Your Own Tag Parser
+// ============================================
+// This file demonstrates that struct tags are plain strings with a
+// convention on top. It parses tags by hand, reads a custom key with
+// options, and uses `default:"..."` tags to fill in configuration values.
+// The parser and the defaulting live in tags.go; tags_test.go checks
+// them.
+//
+// Run with:
+//
+//   cd structs/tags
+//   go run tags.go main.go
+//   go test -v *.go
+
+// ServerConfig uses two custom tag keys: conf (name plus options) and
+// default (the value to use when the field is still zero)
+type ServerConfig struct {
+	Host     string        `conf:"host" default:"localhost"`
+	Port     int           `conf:"port,required" default:"8080"`
+	Debug    bool          `conf:"debug" default:"false"`
+	Timeout  time.Duration `conf:"timeout" default:"30s"`
+	MaxConns uint16        `conf:"max_conns" default:"100"`
+	Ratio    float64       `conf:"ratio" default:"0.75"`
+	Origins  []string      `conf:"origins" default:"https://a.example,https://b.example"`
+	Secret   string        `conf:"secret,omitempty,redact"`
+	internal string        `default:"ignored"` // unexported: cannot be set
+}
+
+func main() {
+	fmt.Println("=== Go Struct Tags: Custom Parser ===")
+
+	// 1. Tags are just strings
+	tagsAreStrings()
+
+	// 2. Parsing key:"value" pairs by hand
+	parsingByHand()
+
+	// 3. Tag values with options
+	tagOptions()
+
+	// 4. Config defaulting with default:"..."
+	configDefaults()
+}
+
+// 1. Tags Are Just Strings
+// ========================
+func tagsAreStrings() {
+	fmt.Println("\n1. TAGS ARE JUST STRINGS:")
+
+	field, _ := reflect.TypeOf(ServerConfig{}).FieldByName("Port")
+	fmt.Printf("   Raw tag of Port: %s\n", string(field.Tag))
+	fmt.Printf("   Type of the tag: %T (a named string type)\n", field.Tag)
+
+	// The compiler does not check tag contents - typos are silent. A field
+	// tagged `json: "name"` (space after the colon) compiles fine, but the
+	// key is never found. go vet's structtag check is what catches it.
+	typo := reflect.StructTag(`json: "name"`)
+	_, ok := typo.Lookup("json")
+	fmt.Printf("   `json: \"name\"` found by Lookup: %t\n", ok)
+}
+
+// 2. Parsing key:"value" Pairs by Hand
+// ====================================
+func parsingByHand() {
+	fmt.Println("\n2. PARSING BY HAND:")
+
+	field, _ := reflect.TypeOf(ServerConfig{}).FieldByName("Timeout")
+	pairs, err := parseTagPairs(string(field.Tag))
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	for _, p := range pairs {
+		fmt.Printf("   key=%-8s value=%q\n", p[0], p[1])
+	}
+
+	// Our parser agrees with reflect.StructTag.Get
+	fmt.Printf("   reflect says conf=%q default=%q\n", field.Tag.Get("conf"), field.Tag.Get("default"))
+
+	_, err = parseTagPairs(`conf:"unterminated`)
+	fmt.Printf("   Malformed tag: %v\n", err)
+}
+
+// 3. Tag Values with Options
+// ==========================
+func tagOptions() {
+	fmt.Println("\n3. TAG OPTIONS:")
+
+	t := reflect.TypeOf(ServerConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := ParseTag(f.Tag, "conf")
+		if !ok {
+			continue
+		}
+		fmt.Printf("   %-9s name=%-10s options=%v required=%t\n",
+			f.Name, tag.Name, tag.Options, tag.Has("required"))
+	}
+}
+
+// 4. Config Defaulting with default:"..."
+// =======================================
+func configDefaults() {
+	fmt.Println("\n4. CONFIG DEFAULTS:")
+
+	// Fields already set by the user keep their values
+	cfg := ServerConfig{Port: 9090, Secret: "s3cr3t"}
+	if err := ApplyDefaults(&cfg); err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+
+	v := reflect.ValueOf(cfg)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		value := fmt.Sprint(v.Field(i))
+		if tag, _ := ParseTag(f.Tag, "conf"); tag.Has("redact") {
+			value = "[redacted]"
+		}
+		fmt.Printf("   %-9s = %s\n", f.Name, value)
+	}
+
+	// Bad defaults are reported with the field name
+	type Broken struct {
+		Port int `default:"eighty"`
+	}
+	err := ApplyDefaults(&Broken{})
+	fmt.Printf("   Broken default: %v\n", err)
+
+	err = ApplyDefaults(ServerConfig{})
+	fmt.Printf("   Non-pointer argument: %v\n", err)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Tag Parser and Config Defaults
+// ================================
+// ParseTag reads one key of a struct tag the way encoding/json reads
+// "json": a name, then comma-separated options. ApplyDefaults fills
+// the zero fields of a struct from their `default:"..."` tags, parsing
+// the text with strconv by the field's kind. parseTagPairs is the
+// key:"value" grammar that reflect.StructTag.Lookup implements.
+
+// Tag is a parsed tag value of the form "name,opt1,opt2"
+type Tag struct {
+	Name    string
+	Options []string
+}
+
+// Has reports whether the tag carries the given option
+func (t Tag) Has(option string) bool {
+	for _, o := range t.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTag reads key from a struct tag and splits it into a name and
+// options, following the same convention as encoding/json
+func ParseTag(tag reflect.StructTag, key string) (Tag, bool) {
+	value, ok := tag.Lookup(key)
+	if !ok {
+		return Tag{}, false
+	}
+	name, rest, _ := strings.Cut(value, ",")
+	t := Tag{Name: name}
+	if rest != "" {
+		t.Options = strings.Split(rest, ",")
+	}
+	return t, true
+}
+
+// ApplyDefaults sets every zero-valued exported field of the struct that
+// ptr points to from its `default:"..."` tag
+func ApplyDefaults(ptr any) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ApplyDefaults: want pointer to struct, got %T", ptr)
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		def, ok := f.Tag.Lookup("default")
+		if !ok || !f.IsExported() || !v.Field(i).IsZero() {
+			continue
+		}
+		if err := setFromString(v.Field(i), def); err != nil {
+			return fmt.Errorf("field %s: default %q: %w", f.Name, def, err)
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setFromString converts s to the field's type and stores it
+func setFromString(field reflect.Value, s string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", field.Type())
+		}
+		field.Set(reflect.ValueOf(strings.Split(s, ",")))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// parseTagPairs splits a raw tag into key/value pairs the way
+// reflect.StructTag.Lookup does: key:"quoted value" separated by spaces
+func parseTagPairs(tag string) ([][2]string, error) {
+	var pairs [][2]string
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			break
+		}
+		colon := strings.IndexByte(tag, ':')
+		if colon <= 0 || colon+1 >= len(tag) || tag[colon+1] != '"' {
+			return nil, fmt.Errorf("bad tag syntax near %q", tag)
+		}
+		key := tag[:colon]
+		rest := tag[colon+1:]
+
+		// Find the closing quote, skipping escaped characters
+		end := 1
+		for end < len(rest) && rest[end] != '"' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) {
+			return nil, fmt.Errorf("unterminated value for key %q", key)
+		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+		pairs = append(pairs, [2]string{key, value})
+		tag = rest[end+1:]
+	}
+	return pairs, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tags - Tests
+// ============
+// Run with:
+//
+//   cd structs/tags
+//   go test -v *.go
+
+// 1. Parsing
+// ==========
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag     reflect.StructTag
+		key     string
+		name    string
+		options []string
+		ok      bool
+	}{
+		{`conf:"port"`, "conf", "port", nil, true},
+		{`conf:"port,required"`, "conf", "port", []string{"required"}, true},
+		{`conf:",omitempty"`, "conf", "", []string{"omitempty"}, true},
+		{`conf:"-"`, "conf", "-", nil, true},
+		{`json:"x" conf:"y,a,b"`, "conf", "y", []string{"a", "b"}, true},
+		{`json:"x"`, "conf", "", nil, false},
+		{``, "conf", "", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTag(tt.tag, tt.key)
+		if ok != tt.ok || got.Name != tt.name || !slices.Equal(got.Options, tt.options) {
+			t.Errorf("ParseTag(`%s`, %q) = %q %q %t, want %q %q %t",
+				tt.tag, tt.key, got.Name, got.Options, ok, tt.name, tt.options, tt.ok)
+		}
+	}
+}
+
+func TestTagHas(t *testing.T) {
+	tag, _ := ParseTag(`conf:"secret,omitempty,redact"`, "conf")
+	if !tag.Has("redact") || !tag.Has("omitempty") || tag.Has("required") {
+		t.Errorf("%+v: Has is wrong", tag)
+	}
+}
+
+// parseTagPairs agrees with reflect.StructTag.Lookup on every key
+func TestParseTagPairs(t *testing.T) {
+	tests := []string{
+		`conf:"timeout" default:"30s"`,
+		`json:"name,omitempty"`,
+		`a:"x y"   b:"quote \" inside"`,
+		``,
+	}
+	for _, raw := range tests {
+		pairs, err := parseTagPairs(raw)
+		if err != nil {
+			t.Errorf("parseTagPairs(`%s`): %v", raw, err)
+			continue
+		}
+		for _, p := range pairs {
+			if want, _ := reflect.StructTag(raw).Lookup(p[0]); p[1] != want {
+				t.Errorf("parseTagPairs(`%s`): %s=%q, Lookup gives %q", raw, p[0], p[1], want)
+			}
+		}
+	}
+
+	for _, raw := range []string{`conf:"unterminated`, `conf:bare`, `:"no key"`, `conf "x"`} {
+		if _, err := parseTagPairs(raw); err == nil {
+			t.Errorf("parseTagPairs(`%s`): no error", raw)
+		}
+	}
+}
+
+// 2. Defaults
+// ===========
+
+func TestApplyDefaults(t *testing.T) {
+	cfg := ServerConfig{Host: "example.com"}
+	if err := ApplyDefaults(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := ServerConfig{
+		Host:     "example.com", // explicit values are kept
+		Port:     8080,
+		Timeout:  30 * time.Second,
+		MaxConns: 100,
+		Ratio:    0.75,
+		Origins:  []string{"https://a.example", "https://b.example"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("ApplyDefaults:\n got  %+v\n want %+v", cfg, want)
+	}
+	if cfg.internal != "" {
+		t.Errorf("unexported field set to %q", cfg.internal)
+	}
+}
+
+func TestApplyDefaultsErrors(t *testing.T) {
+	type badInt struct {
+		Port int `default:"eighty"`
+	}
+	type overflow struct {
+		Small int8 `default:"300"`
+	}
+	type badSlice struct {
+		Ports []int `default:"1,2"`
+	}
+	type badKind struct {
+		Ch chan int `default:"x"`
+	}
+	tests := []struct {
+		name string
+		arg  any
+		want string
+	}{
+		{"not a number", &badInt{}, "field Port"},
+		{"out of range", &overflow{}, "field Small"},
+		{"slice of int", &badSlice{}, "unsupported slice type"},
+		{"channel", &badKind{}, "unsupported type"},
+		{"not a pointer", ServerConfig{}, "want pointer to struct"},
+		{"pointer to int", new(int), "want pointer to struct"},
+	}
+	for _, tt := range tests {
+		err := ApplyDefaults(tt.arg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package basics
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Go Testing Fundamentals - Tables, Subtests, Helpers and Golden Files
+// ====================================================================
+// This file demonstrates the everyday tools of the testing package on
+// the helpers in helpers.go: contains and indexOf from primitives/ and
+// mapInts from functions/. fuzz_test.go fuzzes the same functions.
+//
+//   cd testing/basics
+//   go test -v -run 'Contains|IndexOf|MapInts' *.go
+//   go test -v -run 'IndexOf/empty' *.go
+//   go test -run Golden *.go -update    (rewrite the golden file)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/")
+
+// 1. Table-Driven Tests
+// =====================
+
+// TestContains lists inputs and expected results as data. Adding a case
+// is one line, and the loop body is written once.
+func TestContains(t *testing.T) {
+	cases := []struct {
+		s, substr string
+		want      bool
+	}{
+		{"hello world", "world", true},
+		{"hello world", "hello", true},
+		{"hello world", "o w", true},
+		{"hello world", "planet", false},
+		{"hello", "hello world", false}, // substr longer than s
+		{"hello", "", true},             // empty substr is everywhere
+		{"", "", true},
+		{"", "a", false},
+		{"Go", "go", false}, // case-sensitive
+	}
+
+	for _, c := range cases {
+		if got := contains(c.s, c.substr); got != c.want {
+			// Errorf records the failure and keeps going, so one run
+			// reports every broken case; Fatalf would stop here
+			t.Errorf("contains(%q, %q) = %t, want %t", c.s, c.substr, got, c.want)
+		}
+	}
+}
+
+// 2. Subtests with t.Run
+// ======================
+
+// TestIndexOf gives every case a name. Each subtest passes or fails on its
+// own, shows up in -v output, and can be selected with -run
+// TestIndexOf/empty.
+func TestIndexOf(t *testing.T) {
+	cases := []struct {
+		name      string
+		s, substr string
+		want      int
+	}{
+		{"at start", "gopher", "go", 0},
+		{"in middle", "gopher", "ph", 2},
+		{"at end", "gopher", "er", 4},
+		{"first of many", "abcabc", "bc", 1},
+		{"missing", "gopher", "rust", -1},
+		{"empty substr", "gopher", "", 0},
+		{"empty s", "", "x", -1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := indexOf(c.s, c.substr); got != c.want {
+				t.Errorf("indexOf(%q, %q) = %d, want %d", c.s, c.substr, got, c.want)
+			}
+		})
+	}
+}
+
+// 3. Test Helpers with t.Helper
+// =============================
+
+// TestIndexOfAgreesWithContains checks a property shared by both helpers:
+// contains is true exactly when indexOf finds a match at a position
+// where the substring really occurs
+func TestIndexOfAgreesWithContains(t *testing.T) {
+	inputs := [][2]string{
+		{"hello", "ll"}, {"hello", "lo"}, {"hello", "x"}, {"", ""}, {"aaa", "aa"},
+	}
+	for _, in := range inputs {
+		assertConsistent(t, in[0], in[1])
+	}
+}
+
+// assertConsistent is a test helper. t.Helper() makes a failure report
+// the caller's line (the loop above) instead of a line in here, which is
+// what you want when the same helper is called from many tests.
+func assertConsistent(t *testing.T, s, substr string) {
+	t.Helper()
+	i := indexOf(s, substr)
+	if found := contains(s, substr); found != (i >= 0) {
+		t.Errorf("contains(%q, %q) = %t but indexOf = %d", s, substr, found, i)
+		return
+	}
+	if i >= 0 && s[i:i+len(substr)] != substr {
+		t.Errorf("indexOf(%q, %q) = %d, but s[%d:] does not start with it", s, substr, i, i)
+	}
+}
+
+// 4. Parallel Subtests
+// ====================
+
+// TestMapInts runs its subtests in parallel. t.Parallel pauses a subtest
+// until its parent's body returns, then runs all paused siblings at
+// once. Since Go 1.22 each loop iteration has its own c, so capturing it
+// in the closure is safe.
+func TestMapInts(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []int
+		fn   func(int) int
+		want []int
+	}{
+		{"double", []int{1, 2, 3}, func(x int) int { return x * 2 }, []int{2, 4, 6}},
+		{"square", []int{-2, 0, 3}, func(x int) int { return x * x }, []int{4, 0, 9}},
+		{"empty", []int{}, func(x int) int { return x }, []int{}},
+		{"nil input", nil, func(x int) int { return x }, []int{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			got := mapInts(c.in, c.fn)
+			if !equalInts(got, c.want) {
+				t.Errorf("mapInts(%v) = %v, want %v", c.in, got, c.want)
+			}
+		})
+	}
+
+	// The input slice must not be modified
+	t.Run("does not mutate input", func(t *testing.T) {
+		t.Parallel()
+		in := []int{1, 2, 3}
+		mapInts(in, func(x int) int { return x + 100 })
+		if !equalInts(in, []int{1, 2, 3}) {
+			t.Errorf("input changed to %v", in)
+		}
+	})
+}
+
+// 5. Golden Files
+// ===============
+
+// TestMapIntsReportGolden compares multi-line output with a file checked
+// into testdata/. When the output changes on purpose, rerun with -update
+// and review the diff of the golden file like any other code change.
+func TestMapIntsReportGolden(t *testing.T) {
+	got := mapIntsReport([]int{1, 2, 3, 4, 5})
+	golden := filepath.Join("testdata", "mapints_report.golden")
+
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+		t.Logf("updated %s", golden)
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		// Fatalf: without the golden file there is nothing to compare
+		t.Fatalf("reading golden file (pass -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("report does not match %s\n--- got ---\n%s--- want ---\n%s", golden, got, want)
+	}
+}
+
+// 6. Fixtures, t.TempDir and t.Cleanup
+// ====================================
+
+// TestContainsFixture reads its inputs from testdata/words.txt (go build
+// ignores directories named testdata) and writes scratch files to a
+// t.TempDir, which is removed automatically when the test ends
+func TestContainsFixture(t *testing.T) {
+	words := loadWords(t, filepath.Join("testdata", "words.txt"))
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "matches.txt")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	matches := 0
+	for _, w := range words {
+		if contains(w, "go") {
+			fmt.Fprintln(f, w)
+			matches++
+		}
+	}
+	if matches != 5 {
+		t.Errorf("found %d words containing \"go\", want 5", matches)
+	}
+	t.Logf("%d of %d fixture words contain \"go\" (scratch file in %s)", matches, len(words), dir)
+}
+
+// loadWords is a fixture loader: it fails the calling test itself, so
+// tests stay free of setup error handling
+func loadWords(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("loading fixture: %v", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	return words
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package basics
+
+import (
+	"maps"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Go Fuzzing - Native Fuzz Targets
+// ================================
+// This file demonstrates Go's built-in fuzzing (Go 1.18+). A fuzz target
+// is a FuzzXxx(f *testing.F) function in a _test.go file: f.Add seeds the
+// corpus and f.Fuzz gives the property that must hold for every input.
+//
+// Without -fuzz, `go test` runs each target once per seed, so fuzz targets
+// are ordinary regression tests in CI. With -fuzz, the engine mutates the
+// seeds, keeps inputs that reach new code, and stops at the first failure:
+//
+//   cd testing/basics
+//   go test helpers.go fuzz_test.go                    (seeds only)
+//   go test -fuzz=FuzzParseKV -fuzztime=30s helpers.go fuzz_test.go
+//
+// Only one target can be fuzzed at a time, and -fuzz must match exactly
+// one of them.
+
+// 1. Differential Fuzzing: contains vs strings.Contains
+// =====================================================
+
+// FuzzContains compares the hand-rolled contains with the standard
+// library on every input. When a trusted implementation exists, "same
+// answer as the reference" is the strongest property you can write.
+func FuzzContains(f *testing.F) {
+	f.Add("hello world", "world")
+	f.Add("", "")
+	f.Add("abc", "")
+	f.Add("", "abc")
+	f.Add("aaa", "aaaa") // substr longer than s
+	f.Add("héllo", "é")  // multi-byte runes
+
+	f.Fuzz(func(t *testing.T, s, substr string) {
+		if got, want := contains(s, substr), strings.Contains(s, substr); got != want {
+			t.Errorf("contains(%q, %q) = %t, strings.Contains = %t", s, substr, got, want)
+		}
+	})
+}
+
+// 2. Property Fuzzing: indexOf
+// ============================
+
+// FuzzIndexOf checks properties instead of exact answers. These hold for
+// any correct implementation, so the target keeps working if indexOf is
+// rewritten to be faster.
+func FuzzIndexOf(f *testing.F) {
+	f.Add("gopher", "ph")
+	f.Add("abcabc", "bc")
+	f.Add("", "")
+	f.Add("日本語", "語")
+	f.Add("\xff\xfe", "\xfe") // invalid UTF-8 is a valid Go string
+
+	f.Fuzz(func(t *testing.T, s, substr string) {
+		i := indexOf(s, substr)
+
+		if (i >= 0) != contains(s, substr) {
+			t.Fatalf("indexOf(%q, %q) = %d disagrees with contains", s, substr, i)
+		}
+		if i < 0 {
+			return
+		}
+		if !strings.HasPrefix(s[i:], substr) {
+			t.Fatalf("indexOf(%q, %q) = %d, but substr is not at that offset", s, substr, i)
+		}
+		if substr == "" && i != 0 || substr != "" && strings.Contains(s[:i+len(substr)-1], substr) {
+			t.Fatalf("indexOf(%q, %q) = %d is not the first match", s, substr, i)
+		}
+
+		// indexOf returns a BYTE offset. For valid UTF-8 a match always
+		// starts on a rune boundary, but the offset only equals the rune
+		// index for ASCII - a common bug in callers that slice []rune(s)
+		if utf8.ValidString(s) && utf8.ValidString(substr) && substr != "" && !utf8.RuneStart(s[i]) {
+			t.Fatalf("indexOf(%q, %q) = %d splits a rune", s, substr, i)
+		}
+	})
+}
+
+// 3. Round-Trip Fuzzing: ParseKV and FormatKV
+// ===========================================
+
+// FuzzParseKV feeds arbitrary strings to the parser. Most are rejected,
+// which is fine; the properties are that it never panics, and that
+// anything it accepts survives Format -> Parse unchanged.
+//
+// Seeds come from two places: f.Add below, and every file in
+// testdata/fuzz/FuzzParseKV/. Failing inputs found by the fuzzer are
+// written there automatically, so committing them turns each bug into a
+// permanent regression test.
+func FuzzParseKV(f *testing.F) {
+	f.Add(`host=example.com; port=8080`)
+	f.Add(`name="a \"quoted\" value"; path="C:\\dir"`)
+	f.Add(`empty=""; spaced= padded ;`)
+	f.Add(`bad key=1`)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		m, err := ParseKV(s)
+		if err != nil {
+			return
+		}
+		out, err := FormatKV(m)
+		if err != nil {
+			t.Fatalf("FormatKV(%q): %v", m, err)
+		}
+		again, err := ParseKV(out)
+		if err != nil {
+			t.Fatalf("ParseKV(FormatKV(m)) failed: %q -> %q: %v", s, out, err)
+		}
+		if !maps.Equal(m, again) {
+			t.Fatalf("round trip changed the map: %q -> %q -> %q", s, out, again)
+		}
+	})
+}
+
+// 4. Minimization
+// ===============
+// When a fuzz input fails, the engine does not report the random blob it
+// happened to generate. It first minimizes it: it repeatedly removes and
+// simplifies bytes, keeping each change that still fails, and writes the
+// smallest input it finds to testdata/fuzz/FuzzXxx/<hash>.
+//
+// The first version of ParseKV had no bounds check after a backslash in
+// a quoted value. Within a second the fuzzer hit an index-out-of-range
+// panic, and minimization reduced the input to 4 bytes:
+//
+//   go test fuzz v1
+//   string("0=\"\\")
+//
+// i.e. the input `0="\`. That file is committed as
+// testdata/fuzz/FuzzParseKV/trailing_backslash (corpus files can have
+// any name), so a plain `go test` reruns it forever:
+//
+//   go test -run=FuzzParseKV/trailing_backslash helpers.go fuzz_test.go
+//
+// Minimization time is bounded with -fuzzminimizetime (default 60s).
@@ -0,0 +1,185 @@
+package basics
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Go Testing and Fuzzing - Code Under Test
+// ========================================
+// This package holds the functions tested by basics_test.go and fuzzed
+// by fuzz_test.go: the hand-rolled contains and indexOf from
+// primitives/go_primitives.go, mapInts from functions/go_functions.go,
+// and a small key=value list parser of the kind that hides edge cases
+// fuzzers are good at finding.
+
+// contains is copied from primitives/go_primitives.go
+func contains(s, substr string) bool {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
+
+// indexOf is copied from primitives/go_primitives.go. It returns a byte
+// offset, not a rune index - for non-ASCII text the two differ.
+func indexOf(s, substr string) int {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
+
+// mapInts is copied from functions/go_functions.go
+func mapInts(numbers []int, fn func(int) int) []int {
+	result := make([]int, len(numbers))
+	for i, num := range numbers {
+		result[i] = fn(num)
+	}
+	return result
+}
+
+// mapIntsReport is the code under test for the golden file
+func mapIntsReport(in []int) string {
+	var sb strings.Builder
+	transforms := []struct {
+		name string
+		fn   func(int) int
+	}{
+		{"double", func(x int) int { return x * 2 }},
+		{"square", func(x int) int { return x * x }},
+		{"negate", func(x int) int { return -x }},
+	}
+	fmt.Fprintf(&sb, "input:  %v\n", in)
+	for _, tr := range transforms {
+		fmt.Fprintf(&sb, "%-7s %v\n", tr.name+":", mapInts(in, tr.fn))
+	}
+	return sb.String()
+}
+
+// ParseKV parses a list like `host=example.com; name="a \"quoted\" value"`.
+// Keys are letters, digits, '_', '-' and '.'. Values are either bare
+// (trimmed, no ';', '"' or '\') or double-quoted with \" and \\ escapes.
+func ParseKV(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	i := 0
+	for i < len(s) {
+		// key
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ';' {
+			i++
+		}
+		key := strings.TrimSpace(s[start:i])
+		if key == "" && i == len(s) {
+			break // trailing whitespace
+		}
+		if i == len(s) || s[i] != '=' {
+			return nil, fmt.Errorf("key %q: missing '='", key)
+		}
+		if !validKey(key) {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		i++ // '='
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+
+		// value
+		var value string
+		if i < len(s) && s[i] == '"' {
+			var sb strings.Builder
+			i++
+			for {
+				if i == len(s) {
+					return nil, fmt.Errorf("key %q: unterminated quoted value", key)
+				}
+				c := s[i]
+				if c == '"' {
+					i++
+					break
+				}
+				if c == '\\' {
+					// Found by FuzzParseKV: `k="\` used to index past the
+					// end here (testdata/fuzz/FuzzParseKV/trailing_backslash)
+					i++
+					if i == len(s) {
+						return nil, fmt.Errorf("key %q: unterminated quoted value", key)
+					}
+					c = s[i]
+					if c != '"' && c != '\\' {
+						return nil, fmt.Errorf("key %q: bad escape \\%c", key, c)
+					}
+				}
+				sb.WriteByte(c)
+				i++
+			}
+			value = sb.String()
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+		} else {
+			start := i
+			for i < len(s) && s[i] != ';' {
+				if s[i] == '"' || s[i] == '\\' {
+					return nil, fmt.Errorf("key %q: quote or backslash in bare value", key)
+				}
+				i++
+			}
+			value = strings.TrimSpace(s[start:i])
+		}
+
+		if i < len(s) && s[i] != ';' {
+			return nil, fmt.Errorf("key %q: expected ';' after value", key)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		m[key] = value
+		i++ // ';'
+	}
+	return m, nil
+}
+
+// FormatKV is the inverse of ParseKV: keys are sorted and values are
+// quoted only when they need to be
+func FormatKV(m map[string]string) (string, error) {
+	keys := slices.Sorted(maps.Keys(m))
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if !validKey(k) {
+			return "", errors.New("invalid key " + fmt.Sprintf("%q", k))
+		}
+		v := m[k]
+		if needsQuotes(v) {
+			v = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+		}
+		parts[i] = k + "=" + v
+	}
+	return strings.Join(parts, "; "), nil
+}
+
+func validKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, r := range k {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func needsQuotes(v string) bool {
+	return v == "" || v != strings.TrimSpace(v) || strings.ContainsAny(v, `;"\`)
+}
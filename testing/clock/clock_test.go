@@ -0,0 +1,215 @@
+package clock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Testable Time - Deterministic Tests
+// ===================================
+// None of these tests sleep. Time only passes when a test calls Advance,
+// so a 30-second idle timeout is checked in microseconds, and "not yet"
+// assertions cannot flake because the clock cannot move on its own.
+//
+// Run with:
+//
+//   cd testing/clock
+//   go test -v *.go
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// receive returns the next value from ch. The real-time guard only
+// matters if the code under test is broken and never sends.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a value; the code under test is stuck")
+		panic("unreachable")
+	}
+}
+
+// notReady fails if ch already has a value
+func notReady[T any](t *testing.T, ch <-chan T, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+		t.Fatalf("%s fired early", what)
+	default:
+	}
+}
+
+// 1. The Fake Clock Itself
+// ========================
+
+func TestFakeAfter(t *testing.T) {
+	clk := NewFake(start)
+	ch := clk.After(10 * time.Second)
+
+	clk.Advance(9 * time.Second)
+	notReady(t, ch, "After(10s)")
+
+	clk.Advance(time.Second)
+	if got := receive(t, ch); !got.Equal(start.Add(10 * time.Second)) {
+		t.Errorf("After fired with %v, want %v", got, start.Add(10*time.Second))
+	}
+}
+
+func TestFakeTimerStopAndReset(t *testing.T) {
+	clk := NewFake(start)
+	timer := clk.NewTimer(time.Minute)
+
+	if !timer.Stop() {
+		t.Error("Stop on a pending timer returned false")
+	}
+	clk.Advance(time.Hour)
+	notReady(t, timer.C(), "stopped timer")
+
+	timer.Reset(time.Second)
+	clk.Advance(time.Second)
+	receive(t, timer.C())
+}
+
+func TestFakeTickerFiresInOrder(t *testing.T) {
+	clk := NewFake(start)
+	ticker := clk.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		clk.Advance(time.Second)
+		if got, want := receive(t, ticker.C()), start.Add(time.Duration(i)*time.Second); !got.Equal(want) {
+			t.Errorf("tick %d at %v, want %v", i, got, want)
+		}
+	}
+
+	// Like time.Ticker, ticks nobody reads are dropped, not queued
+	clk.Advance(5 * time.Second)
+	receive(t, ticker.C())
+	notReady(t, ticker.C(), "dropped tick")
+}
+
+// 2. The Worker
+// =============
+
+func newWorker(clk *Fake) *Worker {
+	return &Worker{
+		Clock:       clk,
+		JobTimeout:  5 * time.Second,
+		IdleTimeout: 30 * time.Second,
+		Heartbeat:   10 * time.Second,
+	}
+}
+
+// runAsync starts w.Run and returns a channel that receives its results
+func runAsync(w *Worker, jobs <-chan Job) <-chan []Result {
+	done := make(chan []Result, 1)
+	go func() { done <- w.Run(jobs) }()
+	return done
+}
+
+func TestWorkerRunsJobs(t *testing.T) {
+	clk := NewFake(start)
+	jobs := make(chan Job)
+	done := runAsync(newWorker(clk), jobs)
+
+	jobs <- func() string { return "a" }
+	jobs <- func() string { return "b" }
+	close(jobs)
+
+	results := receive(t, done)
+	if len(results) != 2 || results[0].Value != "a" || results[1].Value != "b" {
+		t.Errorf("results = %+v, want a and b", results)
+	}
+}
+
+func TestWorkerJobTimeout(t *testing.T) {
+	clk := NewFake(start)
+	jobs := make(chan Job)
+	done := runAsync(newWorker(clk), jobs)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	jobs <- func() string {
+		close(started)
+		<-release // a job that hangs
+		return "late"
+	}
+
+	// Wait for the worker to arm the job timer (the heartbeat ticker is
+	// the other pending waiter), otherwise Advance could run first
+	receive(t, started)
+	clk.BlockUntil(2)
+	clk.Advance(5 * time.Second)
+
+	close(jobs)
+	results := receive(t, done)
+	if len(results) != 1 || !errors.Is(results[0].Err, ErrJobTimeout) {
+		t.Errorf("results = %+v, want one ErrJobTimeout", results)
+	}
+}
+
+func TestWorkerIdleTimeout(t *testing.T) {
+	clk := NewFake(start)
+	w := newWorker(clk)
+	w.Heartbeat = time.Hour // keep heartbeats out of this test
+	done := runAsync(w, make(chan Job))
+
+	clk.BlockUntil(2) // ticker and idle timer
+	clk.Advance(30*time.Second - time.Nanosecond)
+	notReady(t, done, "idle timeout")
+
+	clk.Advance(time.Nanosecond)
+	if results := receive(t, done); len(results) != 0 {
+		t.Errorf("results = %+v, want none", results)
+	}
+}
+
+func TestWorkerIdleTimerRestartsAfterJob(t *testing.T) {
+	clk := NewFake(start)
+	w := newWorker(clk)
+	w.Heartbeat = time.Hour
+	processed := make(chan Result)
+	w.OnResult = func(r Result) { processed <- r }
+	jobs := make(chan Job)
+	done := runAsync(w, jobs)
+
+	clk.BlockUntil(2)
+	clk.Advance(20 * time.Second)
+
+	// OnResult runs after the idle timer is re-armed. BlockUntil cannot
+	// tell that moment apart: while the job runs, the job timer makes the
+	// count 2 as well.
+	jobs <- func() string { return "ok" }
+	receive(t, processed)
+
+	// 20s + 20s is past the original deadline, but only 20s of idleness
+	clk.Advance(20 * time.Second)
+	notReady(t, done, "idle timeout")
+
+	clk.Advance(10 * time.Second)
+	receive(t, done)
+}
+
+func TestWorkerHeartbeat(t *testing.T) {
+	clk := NewFake(start)
+	w := newWorker(clk)
+	beats := make(chan time.Time)
+	w.OnHeartbeat = func(now time.Time) { beats <- now }
+	jobs := make(chan Job)
+	done := runAsync(w, jobs)
+
+	clk.BlockUntil(2)
+	for i := 1; i <= 2; i++ {
+		clk.Advance(10 * time.Second)
+		if got, want := receive(t, beats), start.Add(time.Duration(i)*10*time.Second); !got.Equal(want) {
+			t.Errorf("heartbeat %d at %v, want %v", i, got, want)
+		}
+	}
+
+	close(jobs)
+	receive(t, done)
+}
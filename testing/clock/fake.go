@@ -0,0 +1,172 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Testable Time - The Fake Clock
+// ==============================
+// Fake only moves when the test calls Advance. Timers and tickers whose
+// deadline is reached fire in deadline order, each seeing Now() equal to
+// its own deadline, exactly as if that much real time had passed.
+//
+// The one subtlety is ordering between goroutines: Advance only fires
+// timers that already exist. BlockUntil lets a test wait until the code
+// under test has created the timers it is about to wait on.
+
+// Fake is a Clock controlled by the test
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a pending timer (period 0) or ticker
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewFake returns a Fake clock reading start
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{f: f, w: &waiter{ch: make(chan time.Time, 1)}}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.w.at = f.now.Add(d)
+	f.add(t.w)
+	return t
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &waiter{period: d, ch: make(chan time.Time, 1)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.at = f.now.Add(d)
+	f.add(w)
+	return &fakeTicker{f: f, w: w}
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker
+// tick that falls due on the way
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := f.now.Add(d)
+	for {
+		w := f.next(end)
+		if w == nil {
+			break
+		}
+		f.now = w.at
+		// Like the time package, drop the tick if the last one is unread
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+	}
+	f.now = end
+}
+
+// BlockUntil waits until at least n timers and tickers are pending
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// next returns the earliest waiter due at or before end
+func (f *Fake) next(end time.Time) *waiter {
+	var first *waiter
+	for _, w := range f.waiters {
+		if !w.at.After(end) && (first == nil || w.at.Before(first.at)) {
+			first = w
+		}
+	}
+	return first
+}
+
+func (f *Fake) add(w *waiter) {
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+}
+
+// remove reports whether w was pending
+func (f *Fake) remove(w *waiter) bool {
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.remove(t.w)
+}
+
+// Reset follows the Go 1.23 semantics: any unread tick is discarded, so
+// the next receive sees only the new deadline
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	active := t.f.remove(t.w)
+	select {
+	case <-t.w.ch:
+	default:
+	}
+	t.w.at = t.f.now.Add(d)
+	t.f.add(t.w)
+	return active
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
@@ -0,0 +1,121 @@
+package clock
+
+import (
+	"errors"
+	"time"
+)
+
+// Testable Time - A Timeout-Based Worker
+// ======================================
+// A worker takes jobs from a channel, gives each one JobTimeout to
+// finish, sends a heartbeat every Heartbeat, and exits after IdleTimeout
+// without work. RunConcrete is the version written against the time
+// package; Worker.Run is the same logic written against Clock.
+
+// Job is a unit of work; it returns a short description of its result
+type Job func() string
+
+// Result is the outcome of one Job
+type Result struct {
+	Value string
+	Err   error
+}
+
+var ErrJobTimeout = errors.New("job timed out")
+
+// 1. The Concrete Starting Point
+// ==============================
+
+// RunConcrete can only be tested in real time: checking the idle exit
+// takes idleTimeout, and a test that expects a job to time out has to
+// pick durations that are "long enough" on every machine it runs on.
+func RunConcrete(jobs <-chan Job, jobTimeout, idleTimeout time.Duration) []Result {
+	var results []Result
+	for {
+		select {
+		case job, ok := <-jobs:
+			if !ok {
+				return results
+			}
+			done := make(chan string, 1)
+			go func() { done <- job() }()
+			select {
+			case v := <-done:
+				results = append(results, Result{Value: v})
+			case <-time.After(jobTimeout):
+				results = append(results, Result{Err: ErrJobTimeout})
+			}
+		case <-time.After(idleTimeout):
+			return results
+		}
+	}
+}
+
+// 2. The Same Worker Against Clock
+// ================================
+
+// Worker runs jobs with a timeout. Clock defaults to Real{}.
+type Worker struct {
+	Clock       Clock
+	JobTimeout  time.Duration
+	IdleTimeout time.Duration
+	Heartbeat   time.Duration
+	OnHeartbeat func(now time.Time) // optional
+	OnResult    func(r Result)      // optional, called once the worker is idle again
+}
+
+// Run processes jobs until the channel is closed or the worker has been
+// idle for IdleTimeout
+func (w *Worker) Run(jobs <-chan Job) []Result {
+	clk := w.Clock
+	if clk == nil {
+		clk = Real{}
+	}
+
+	ticker := clk.NewTicker(w.Heartbeat)
+	defer ticker.Stop()
+	// One timer, reset after each job, instead of time.After per loop:
+	// the idle deadline only runs while the worker is actually idle
+	idle := clk.NewTimer(w.IdleTimeout)
+	defer idle.Stop()
+
+	var results []Result
+	for {
+		select {
+		case job, ok := <-jobs:
+			if !ok {
+				return results
+			}
+			idle.Stop()
+			r := w.runJob(clk, job)
+			results = append(results, r)
+			idle.Reset(w.IdleTimeout)
+			if w.OnResult != nil {
+				w.OnResult(r)
+			}
+		case now := <-ticker.C():
+			if w.OnHeartbeat != nil {
+				w.OnHeartbeat(now)
+			}
+		case <-idle.C():
+			return results
+		}
+	}
+}
+
+func (w *Worker) runJob(clk Clock, job Job) Result {
+	// Buffered, so a job that finishes after its timeout does not leak a
+	// blocked goroutine. The job itself keeps running: real code would
+	// also pass it a context to cancel.
+	done := make(chan string, 1)
+	go func() { done <- job() }()
+
+	timeout := clk.NewTimer(w.JobTimeout)
+	defer timeout.Stop()
+	select {
+	case v := <-done:
+		return Result{Value: v}
+	case <-timeout.C():
+		return Result{Err: ErrJobTimeout}
+	}
+}
@@ -0,0 +1,186 @@
+package doubles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Test Doubles - Code Under Test
+// ==============================
+// This package holds a user-signup function in two versions: a concrete
+// one that talks to a JSON file and an SMTP server directly, and one that
+// reaches them through two small interfaces (seams). doubles_test.go
+// tests the second version with stubs, spies, fakes and a mock.
+
+// User is a registered user
+type User struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+var (
+	ErrInvalidEmail   = errors.New("invalid email")
+	ErrDuplicate      = errors.New("email already registered")
+	ErrWelcomeNotSent = errors.New("welcome mail not sent")
+)
+
+// 1. The Concrete Starting Point
+// ==============================
+
+// RegisterConcrete works, but a test needs a writable directory and a
+// running SMTP server, and cannot make either of them fail on demand.
+// Which error paths are covered depends on the machine, not the test.
+func RegisterConcrete(dir, smtpAddr, email, name string) error {
+	if !strings.Contains(email, "@") {
+		return ErrInvalidEmail
+	}
+
+	path := filepath.Join(dir, "users.json")
+	var users []User
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &users); err != nil {
+			return err
+		}
+	}
+	for _, u := range users {
+		if u.Email == email {
+			return ErrDuplicate
+		}
+	}
+
+	users = append(users, User{Email: email, Name: name})
+	data, err = json.Marshal(users)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+
+	msg := "Subject: Welcome\r\n\r\nHi " + name + ", thanks for signing up.\r\n"
+	if err := smtp.SendMail(smtpAddr, nil, "noreply@example.com", []string{email}, []byte(msg)); err != nil {
+		return fmt.Errorf("%w: %v", ErrWelcomeNotSent, err)
+	}
+	return nil
+}
+
+// 2. Interface Seams
+// ==================
+// A seam is a place where behaviour can be swapped without editing the
+// code that uses it. Each interface has only the methods Register calls,
+// so a test double is a few lines.
+
+// UserStore persists users
+type UserStore interface {
+	Exists(email string) (bool, error)
+	Save(u User) error
+}
+
+// Mailer sends a plain-text mail
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// Signup registers users. It is the same logic as RegisterConcrete, with
+// the file and the SMTP server moved behind UserStore and Mailer.
+type Signup struct {
+	Store UserStore
+	Mail  Mailer
+}
+
+// Register saves a new user and sends a welcome mail. A failed mail does
+// not undo the registration: the error wraps ErrWelcomeNotSent so the
+// caller can retry the mail alone.
+func (s *Signup) Register(email, name string) error {
+	if !strings.Contains(email, "@") {
+		return ErrInvalidEmail
+	}
+	exists, err := s.Store.Exists(email)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", email, err)
+	}
+	if exists {
+		return ErrDuplicate
+	}
+	if err := s.Store.Save(User{Email: email, Name: name}); err != nil {
+		return fmt.Errorf("saving %s: %w", email, err)
+	}
+	if err := s.Mail.Send(email, "Welcome", "Hi "+name+", thanks for signing up."); err != nil {
+		return fmt.Errorf("%w: %v", ErrWelcomeNotSent, err)
+	}
+	return nil
+}
+
+// 3. The Real Implementations
+// ===========================
+// The adapters keep as little logic as possible: what is left in them is
+// covered by contract tests (FileUserStore) or a function seam
+// (SMTPMailer), and the rest of the program is tested with doubles.
+
+// FileUserStore keeps users in a JSON file
+type FileUserStore struct {
+	Path string
+}
+
+func (s FileUserStore) Exists(email string) (bool, error) {
+	users, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(users, func(u User) bool { return u.Email == email }), nil
+}
+
+func (s FileUserStore) Save(u User) error {
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(append(users, u))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Path, data, 0o644)
+}
+
+func (s FileUserStore) load() ([]User, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Path, err)
+	}
+	return users, nil
+}
+
+// SMTPMailer sends mail through an SMTP server. SendMail is a function
+// seam: nil means smtp.SendMail, and a test can set it to capture the
+// message instead of needing a server.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func (m SMTPMailer) Send(to, subject, body string) error {
+	send := m.SendMail
+	if send == nil {
+		send = smtp.SendMail
+	}
+	msg := "From: " + m.From + "\r\nTo: " + to + "\r\nSubject: " + subject + "\r\n\r\n" + body + "\r\n"
+	return send(m.Addr, nil, m.From, []string{to}, []byte(msg))
+}
@@ -0,0 +1,296 @@
+package doubles
+
+import (
+	"errors"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Test Doubles - Dummies, Stubs, Spies, Fakes and Mocks
+// =====================================================
+// A test double stands in for a real dependency. The kinds differ in
+// what they do and what the test checks afterwards:
+//
+//   dummy - passed but never used; fails loudly if it is
+//   stub  - returns canned answers, to steer the code down a path
+//   spy   - a stub that also records how it was called
+//   fake  - a real, simplified implementation (a map instead of a file)
+//   mock  - pre-programmed with expected calls, and verifies them itself
+//
+// Run with:
+//
+//   cd testing/doubles
+//   go test -v doubles.go doubles_test.go
+
+// 1. Dummies and Stubs
+// ====================
+
+// panicMailer is a dummy: the test expects Register to fail before
+// sending, and a call would be a bug worth a loud failure
+type panicMailer struct{}
+
+func (panicMailer) Send(to, subject, body string) error {
+	panic("Send must not be called")
+}
+
+// stubStore answers Exists and Save with fixed values
+type stubStore struct {
+	exists  bool
+	err     error
+	saveErr error
+}
+
+func (s stubStore) Exists(string) (bool, error) { return s.exists, s.err }
+func (s stubStore) Save(User) error             { return s.saveErr }
+
+// stubMailer always returns err
+type stubMailer struct{ err error }
+
+func (m stubMailer) Send(to, subject, body string) error { return m.err }
+
+func TestRegisterErrorPaths(t *testing.T) {
+	diskErr := errors.New("disk full")
+
+	tests := []struct {
+		name    string
+		email   string
+		store   UserStore
+		mail    Mailer
+		wantErr error
+	}{
+		{"invalid email", "nobody", stubStore{}, panicMailer{}, ErrInvalidEmail},
+		{"duplicate", "a@example.com", stubStore{exists: true}, panicMailer{}, ErrDuplicate},
+		{"lookup fails", "a@example.com", stubStore{err: diskErr}, panicMailer{}, diskErr},
+		{"save fails", "a@example.com", stubStore{saveErr: diskErr}, panicMailer{}, diskErr},
+		{"mail fails", "a@example.com", stubStore{}, stubMailer{err: errors.New("550")}, ErrWelcomeNotSent},
+		{"success", "a@example.com", stubStore{}, stubMailer{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Signup{Store: tt.store, Mail: tt.mail}
+			err := s.Register(tt.email, "Ann")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Register() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 2. Spies
+// ========
+
+type sentMail struct {
+	to, subject, body string
+}
+
+// spyMailer records every mail, so the test can check WHAT was sent
+// after the fact, without the spy deciding what is correct
+type spyMailer struct {
+	sent []sentMail
+	err  error
+}
+
+func (m *spyMailer) Send(to, subject, body string) error {
+	m.sent = append(m.sent, sentMail{to, subject, body})
+	return m.err
+}
+
+func TestRegisterSendsWelcomeMail(t *testing.T) {
+	mail := &spyMailer{}
+	s := &Signup{Store: newMemStore(), Mail: mail}
+
+	if err := s.Register("ann@example.com", "Ann"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mail.sent) != 1 {
+		t.Fatalf("sent %d mails, want 1", len(mail.sent))
+	}
+	got := mail.sent[0]
+	if got.to != "ann@example.com" || got.subject != "Welcome" || !strings.Contains(got.body, "Ann") {
+		t.Errorf("sent %+v, want a welcome mail to ann@example.com mentioning Ann", got)
+	}
+}
+
+func TestRegisterKeepsUserWhenMailFails(t *testing.T) {
+	store := newMemStore()
+	s := &Signup{Store: store, Mail: &spyMailer{err: errors.New("connection refused")}}
+
+	err := s.Register("ann@example.com", "Ann")
+	if !errors.Is(err, ErrWelcomeNotSent) {
+		t.Fatalf("Register() error = %v, want ErrWelcomeNotSent", err)
+	}
+	// The fake's state is the assertion: no need to spy on Save
+	if exists, _ := store.Exists("ann@example.com"); !exists {
+		t.Error("user was not kept after the mail failed")
+	}
+}
+
+// 3. Fakes and Contract Tests
+// ===========================
+
+// memStore is a fake: a working UserStore backed by a map. Unlike a stub
+// it has behaviour, so a test can Register twice and see ErrDuplicate
+// without scripting the answers.
+type memStore struct {
+	users map[string]User
+}
+
+func newMemStore() *memStore {
+	return &memStore{users: make(map[string]User)}
+}
+
+func (s *memStore) Exists(email string) (bool, error) {
+	_, ok := s.users[email]
+	return ok, nil
+}
+
+func (s *memStore) Save(u User) error {
+	s.users[u.Email] = u
+	return nil
+}
+
+func TestRegisterTwiceWithFake(t *testing.T) {
+	s := &Signup{Store: newMemStore(), Mail: &spyMailer{}}
+	if err := s.Register("ann@example.com", "Ann"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register("ann@example.com", "Ann again"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second Register() error = %v, want ErrDuplicate", err)
+	}
+}
+
+// A fake is only useful if it behaves like the real thing. Running the
+// same contract test against both keeps them from drifting apart.
+func TestUserStoreContract(t *testing.T) {
+	stores := map[string]func(t *testing.T) UserStore{
+		"memStore": func(t *testing.T) UserStore { return newMemStore() },
+		"FileUserStore": func(t *testing.T) UserStore {
+			return FileUserStore{Path: filepath.Join(t.TempDir(), "users.json")}
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			testUserStore(t, store)
+		})
+	}
+}
+
+func testUserStore(t *testing.T, store UserStore) {
+	t.Helper()
+	if exists, err := store.Exists("ann@example.com"); err != nil || exists {
+		t.Fatalf("empty store: Exists = %t, %v; want false, nil", exists, err)
+	}
+	if err := store.Save(User{Email: "ann@example.com", Name: "Ann"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if exists, err := store.Exists("ann@example.com"); err != nil || !exists {
+		t.Errorf("after Save: Exists = %t, %v; want true, nil", exists, err)
+	}
+	if exists, _ := store.Exists("bob@example.com"); exists {
+		t.Error("Exists reports a user that was never saved")
+	}
+}
+
+// The real store has failure modes the fake does not; they are tested
+// directly on the real type rather than faked everywhere
+func TestFileUserStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (FileUserStore{Path: path}).Exists("ann@example.com"); err == nil {
+		t.Error("Exists on a corrupt file: want an error")
+	}
+}
+
+// 4. Mocks
+// ========
+
+// mockMailer is what a mock framework generates: expectations are set up
+// front and Verify fails the test if the calls differ. The test now says
+// HOW Register must talk to the Mailer, not only what the outcome is.
+type mockMailer struct {
+	t        *testing.T
+	expected []sentMail
+	calls    int
+}
+
+func (m *mockMailer) ExpectSend(to, subject, body string) {
+	m.expected = append(m.expected, sentMail{to, subject, body})
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	m.t.Helper()
+	if m.calls >= len(m.expected) {
+		m.t.Fatalf("unexpected Send(%q, %q, %q)", to, subject, body)
+	}
+	if want := m.expected[m.calls]; want != (sentMail{to, subject, body}) {
+		m.t.Fatalf("Send(%q, %q, %q), want Send(%q, %q, %q)", to, subject, body, want.to, want.subject, want.body)
+	}
+	m.calls++
+	return nil
+}
+
+func (m *mockMailer) Verify() {
+	m.t.Helper()
+	if m.calls != len(m.expected) {
+		m.t.Errorf("Send called %d times, want %d", m.calls, len(m.expected))
+	}
+}
+
+func TestRegisterWithMock(t *testing.T) {
+	mail := &mockMailer{t: t}
+	mail.ExpectSend("ann@example.com", "Welcome", "Hi Ann, thanks for signing up.")
+	defer mail.Verify()
+
+	s := &Signup{Store: newMemStore(), Mail: mail}
+	if err := s.Register("ann@example.com", "Ann"); err != nil {
+		t.Fatal(err)
+	}
+	// The expectation pins the exact body: rewording the welcome text
+	// breaks this test although nothing is wrong. The spy test above only
+	// checks what matters (recipient, subject, the name in the body).
+	//
+	// Prefer fakes and spies: they assert on outcomes and survive
+	// refactoring. Reach for mocks (or gomock/testify) when the interaction
+	// itself is the behaviour - "charge the card exactly once" - or when
+	// an interface is too large to fake by hand.
+}
+
+// 5. Function Seams
+// =================
+
+func TestSMTPMailerFormatsMessage(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	m := SMTPMailer{
+		Addr: "mail.example.com:25",
+		From: "noreply@example.com",
+		// A function field is the smallest seam: no interface needed
+		SendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+			return nil
+		},
+	}
+
+	if err := m.Send("ann@example.com", "Welcome", "Hi Ann"); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotAddr != "mail.example.com:25" || gotFrom != "noreply@example.com" || !slices.Equal(gotTo, []string{"ann@example.com"}) {
+		t.Errorf("SendMail(%q, %q, %q), want the configured server, sender and recipient", gotAddr, gotFrom, gotTo)
+	}
+	want := "From: noreply@example.com\r\nTo: ann@example.com\r\nSubject: Welcome\r\n\r\nHi Ann\r\n"
+	if string(gotMsg) != want {
+		t.Errorf("message = %q, want %q", gotMsg, want)
+	}
+}
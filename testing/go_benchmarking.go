@@ -0,0 +1,290 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// Go Benchmarking - Measuring Correctly
+// =====================================
+// This file demonstrates how to write benchmarks that measure what you
+// think they measure: letting the framework pick b.N, reporting
+// allocations, keeping setup out of the timing, stopping the compiler
+// from deleting the work, sub-benchmarks, and comparing runs with
+// benchstat. The memory-model lessons use the same techniques.
+//
+//   go run go_benchmarking.go                         (the lesson)
+//   go run go_benchmarking.go -bench -test.count=10   (go test -bench output)
+
+var benchMode = flag.Bool("bench", false, "run the BenchmarkXxx functions and print go test -bench output for benchstat")
+
+// Sinks are package-level variables the compiler cannot prove unused, so
+// a result stored in one must really be computed
+var (
+	intSink   int
+	floatSink float64
+	ptrSink   *int
+	bytesSink []byte
+)
+
+func main() {
+	testing.Init()
+	flag.Parse()
+	if *benchMode {
+		runBenchstatMode()
+		return
+	}
+
+	fmt.Println("=== Go Benchmarking ===")
+
+	// 1. Why time.Now loops lie
+	timeNowLoops()
+
+	// 2. testing.Benchmark, b.N and ReportAllocs
+	benchmarkBasics()
+
+	// 3. Defeating dead-code elimination
+	deadCodeElimination()
+
+	// 4. Keeping setup out of the timing
+	resetTimer()
+
+	// 5. Sub-benchmarks and benchstat
+	subBenchmarks()
+}
+
+// 1. Why time.Now Loops Lie
+// =========================
+func timeNowLoops() {
+	fmt.Println("\n1. WHY TIME.NOW LOOPS LIE:")
+
+	// This is the loop from memory-model/performance_implications.go
+	iterations := 1000000
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		a := new(int)
+		*a = i
+		_ = a // does not keep anything alive
+	}
+	duration := time.Since(start)
+	fmt.Printf("   \"heap\" loop: %v for %d iterations (%.2f ns/op)\n",
+		duration, iterations, float64(duration.Nanoseconds())/float64(iterations))
+	fmt.Println("   Problems:")
+	fmt.Println("   - new(int) never escapes, so nothing is heap allocated at all")
+	fmt.Println("   - the result is unused, so the compiler may delete the loop body")
+	fmt.Println("   - a fixed iteration count gives a single, noisy, too-short sample")
+	fmt.Println("   - the first run pays for warm-up (page faults, CPU frequency)")
+}
+
+// 2. testing.Benchmark, b.N and ReportAllocs
+// ==========================================
+func benchmarkBasics() {
+	fmt.Println("\n2. TESTING.BENCHMARK BASICS:")
+
+	// The framework grows b.N (1, 100, 10000, ...) until the run takes
+	// about a second, then divides. Never pick the iteration count yourself.
+	r := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ptrSink = new(int)
+		}
+	})
+	fmt.Printf("   escaping new(int): b.N=%d, %s\n", r.N, formatResult(r))
+
+	r = testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bytesSink = make([]byte, 1024)
+		}
+	})
+	fmt.Printf("   make([]byte, 1024): b.N=%d, %s\n", r.N, formatResult(r))
+	fmt.Println("   ReportAllocs adds B/op and allocs/op - often more stable than ns/op")
+}
+
+// 3. Defeating Dead-Code Elimination
+// ==================================
+func deadCodeElimination() {
+	fmt.Println("\n3. DEFEATING DEAD-CODE ELIMINATION:")
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"result discarded", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// math.Sqrt is a pure intrinsic: with the result unused
+				// the compiler deletes the call and times an empty loop
+				math.Sqrt(float64(i))
+			}
+		}},
+		{"result stored in a sink", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				floatSink = math.Sqrt(float64(i))
+			}
+		}},
+		{"b.Loop (Go 1.24+)", func(b *testing.B) {
+			// b.Loop keeps the arguments and results of calls in the loop
+			// alive, and times only the loop
+			i := 0
+			for b.Loop() {
+				math.Sqrt(float64(i))
+				i++
+			}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(bm.fn)
+		fmt.Printf("   %-24s %s\n", bm.name, formatResult(r))
+	}
+	fmt.Println("   A benchmark that is suspiciously fast is usually measuring nothing")
+}
+
+// sum is the function measured in the remaining sections
+func sum(xs []int) int {
+	s := 0
+	for _, x := range xs {
+		s += x
+	}
+	return s
+}
+
+// 4. Keeping Setup Out of the Timing
+// ==================================
+func resetTimer() {
+	fmt.Println("\n4. KEEPING SETUP OUT OF THE TIMING:")
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"setup timed", func(b *testing.B) {
+			data := expensiveSetup()
+			for i := 0; i < b.N; i++ {
+				intSink = sum(data)
+			}
+		}},
+		{"b.ResetTimer after setup", func(b *testing.B) {
+			data := expensiveSetup()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				intSink = sum(data)
+			}
+		}},
+		{"per-iteration StopTimer", func(b *testing.B) {
+			data := make([]int, 100)
+			for i := 0; i < b.N; i++ {
+				// StopTimer/StartTimer exclude per-iteration setup, but
+				// each call has overhead - prefer restructuring the loop
+				b.StopTimer()
+				for j := range data {
+					data[j] = j
+				}
+				b.StartTimer()
+				intSink = sum(data)
+			}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(bm.fn)
+		fmt.Printf("   %-26s %s\n", bm.name, formatResult(r))
+	}
+	fmt.Println("   Setup runs again for every b.N round the framework tries, and its")
+	fmt.Println("   cost is divided into ns/op unless the timer is reset after it")
+}
+
+func expensiveSetup() []int {
+	time.Sleep(100 * time.Millisecond) // e.g. loading a fixture
+	data := make([]int, 100)
+	for i := range data {
+		data[i] = i
+	}
+	return data
+}
+
+// 5. Sub-Benchmarks and benchstat
+// ===============================
+func subBenchmarks() {
+	fmt.Println("\n5. SUB-BENCHMARKS AND BENCHSTAT:")
+
+	// In a _test.go file this is one BenchmarkSum with b.Run per size;
+	// see BenchmarkSum below, which -bench mode runs through testing.Main
+	for _, size := range []int{10, 1000, 100000} {
+		data := make([]int, size)
+		r := testing.Benchmark(func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				intSink = sum(data)
+			}
+		})
+		fmt.Printf("   Sum/size=%-7d %s\n", size, formatResult(r))
+	}
+
+	fmt.Println("   One run proves little; compare many runs statistically:")
+	fmt.Println("   $ go run go_benchmarking.go -bench -test.count=10 > old.txt")
+	fmt.Println("   (change the code)")
+	fmt.Println("   $ go run go_benchmarking.go -bench -test.count=10 > new.txt")
+	fmt.Println("   $ go run golang.org/x/perf/cmd/benchstat@latest old.txt new.txt")
+	fmt.Println("   benchstat reports the median, the variation, and whether the")
+	fmt.Println("   difference is significant (p-value) - '~' means it is not")
+}
+
+// Benchmarks for -bench mode
+// ==========================
+
+// BenchmarkSum shows sub-benchmarks: each b.Run gets its own b.N and its
+// own result line, named BenchmarkSum/size=N
+func BenchmarkSum(b *testing.B) {
+	for _, size := range []int{10, 1000, 100000} {
+		data := make([]int, size)
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			for b.Loop() {
+				sum(data)
+			}
+		})
+	}
+}
+
+// BenchmarkAlloc compares a value that stays on the stack with one that
+// escapes to the heap through a sink
+func BenchmarkAlloc(b *testing.B) {
+	b.Run("stack", func(b *testing.B) {
+		s := 0
+		for i := 0; i < b.N; i++ {
+			x := i
+			s += x
+		}
+		intSink = s
+	})
+	b.Run("heap", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x := new(int)
+			*x = i
+			ptrSink = x
+		}
+	})
+}
+
+func runBenchstatMode() {
+	flag.Set("test.run", "^$")
+	flag.Set("test.benchmem", "true")
+	if flag.Lookup("test.bench").Value.String() == "" {
+		flag.Set("test.bench", ".")
+	}
+	benchmarks := []testing.InternalBenchmark{
+		{Name: "BenchmarkSum", F: BenchmarkSum},
+		{Name: "BenchmarkAlloc", F: BenchmarkAlloc},
+	}
+	testing.Main(regexp.MatchString, nil, benchmarks, nil)
+}
+
+// Helper functions
+// ================
+func formatResult(r testing.BenchmarkResult) string {
+	ns := float64(r.T.Nanoseconds()) / float64(r.N)
+	return fmt.Sprintf("%9.1f ns/op %6d B/op %2d allocs/op", ns, r.AllocedBytesPerOp(), r.AllocsPerOp())
+}
@@ -0,0 +1,109 @@
+package httptesting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Testing HTTP - The Client Under Test
+// ====================================
+// Client fetches items and retries what is worth retrying: network
+// errors (timeouts, resets) and 5xx/429 responses. 4xx responses are the
+// caller's fault and are returned at once.
+
+var (
+	ErrNotFound     = errors.New("item not found")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// StatusError is a response status the client gave up on
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// Client calls the item API
+type Client struct {
+	BaseURL     string
+	Token       string
+	HTTP        *http.Client  // nil means http.DefaultClient
+	MaxAttempts int           // default 3
+	Backoff     time.Duration // wait before the 2nd attempt, doubled each retry
+}
+
+// GetItem fetches /items/{id}, retrying transient failures
+func (c *Client) GetItem(ctx context.Context, id string) (Item, error) {
+	attempts := c.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+
+	var err error
+	backoff := c.Backoff
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var item Item
+		var retry bool
+		item, retry, err = c.getOnce(ctx, id)
+		if err == nil || !retry {
+			return item, err
+		}
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return Item{}, ctx.Err()
+		}
+	}
+	return Item{}, fmt.Errorf("after %d attempts: %w", attempts, err)
+}
+
+// getOnce makes one request and reports whether a failure is retryable
+func (c *Client) getOnce(ctx context.Context, id string) (Item, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/items/"+url.PathEscape(id), nil)
+	if err != nil {
+		return Item{}, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		// The caller cancelling is final; anything else on the wire
+		// (timeout, reset, refused) may work next time
+		return Item{}, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var item Item
+		if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+			return Item{}, false, fmt.Errorf("decoding item: %w", err)
+		}
+		return item, false, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return Item{}, false, ErrNotFound
+	case resp.StatusCode == http.StatusUnauthorized:
+		return Item{}, false, ErrUnauthorized
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		io.Copy(io.Discard, resp.Body) // lets the connection be reused
+		return Item{}, true, &StatusError{Code: resp.StatusCode}
+	default:
+		return Item{}, false, &StatusError{Code: resp.StatusCode}
+	}
+}
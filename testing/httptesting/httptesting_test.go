@@ -0,0 +1,243 @@
+package httptesting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Testing HTTP - httptest.ResponseRecorder and httptest.Server
+// ============================================================
+// net/http/httptest gives two tools:
+//
+//   ResponseRecorder - an http.ResponseWriter that records status, headers
+//                      and body. Call handler.ServeHTTP directly: no
+//                      network, no goroutines, fastest for handler logic.
+//   Server           - a real server on a loopback port. Use it for
+//                      clients, and for failures that only exist on the
+//                      wire: timeouts and dropped connections.
+//
+// Run with:
+//
+//   cd testing/httptesting
+//   go test -v *.go
+
+var items = map[string]Item{"1": {ID: "1", Name: "gopher"}}
+
+// 1. Handlers with ResponseRecorder
+// =================================
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(items, "secret")
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"found", "/items/1", "secret", http.StatusOK, `{"id":"1","name":"gopher"}`},
+		{"not found", "/items/2", "secret", http.StatusNotFound, "not found"},
+		{"missing token", "/items/1", "", http.StatusUnauthorized, "unauthorized"},
+		{"wrong token", "/items/1", "guess", http.StatusUnauthorized, "unauthorized"},
+		{"panic is recovered", "/items/boom", "secret", http.StatusInternalServerError, "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if rec.Header().Get("X-Request-ID") == "" {
+				t.Error("X-Request-ID not set")
+			}
+		})
+	}
+}
+
+func TestHandlerMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/items/1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+
+	NewHandler(items, "secret").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// Middleware is tested on its own by wrapping a trivial handler
+func TestRequestIDKeepsClientID(t *testing.T) {
+	h := RequestID(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Request-ID"); got != "abc-123" {
+		t.Errorf("X-Request-ID = %q, want the client's abc-123", got)
+	}
+}
+
+// 2. Clients with httptest.Server
+// ===============================
+
+func newClient(url string) *Client {
+	return &Client{
+		BaseURL:     url,
+		Token:       "secret",
+		HTTP:        &http.Client{Timeout: time.Second},
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}
+}
+
+func TestClientAgainstRealHandler(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(items, "secret"))
+	defer srv.Close()
+
+	item, err := newClient(srv.URL).GetItem(context.Background(), "1")
+	if err != nil || item.Name != "gopher" {
+		t.Fatalf("GetItem = %+v, %v; want gopher", item, err)
+	}
+
+	if _, err := newClient(srv.URL).GetItem(context.Background(), "2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing item: err = %v, want ErrNotFound", err)
+	}
+
+	c := newClient(srv.URL)
+	c.Token = "wrong"
+	if _, err := c.GetItem(context.Background(), "1"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("bad token: err = %v, want ErrUnauthorized", err)
+	}
+}
+
+// 3. Failure Injection
+// ====================
+// A scripted handler decides, per attempt, how the server misbehaves.
+// The atomic counter lets each test assert how many attempts were made.
+
+// scripted serves steps[n] on the n-th request and the last step after that
+func scripted(t *testing.T, calls *atomic.Int32, steps ...http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		steps[min(n, len(steps)-1)](w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(items["1"])
+}
+
+func status(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+// hang waits until the client gives up, simulating a stuck server
+func hang(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+// reset closes the TCP connection with an RST instead of responding.
+// Hijack takes the raw connection away from net/http; SetLinger(0)
+// makes Close send RST, so the client sees "connection reset by peer".
+func reset(w http.ResponseWriter, r *http.Request) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		panic(err)
+	}
+	conn.(*net.TCPConn).SetLinger(0)
+	conn.Close()
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []http.HandlerFunc
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"success first time", []http.HandlerFunc{ok}, 1, false},
+		{"500 then success", []http.HandlerFunc{status(500), ok}, 2, false},
+		{"503, 429 then success", []http.HandlerFunc{status(503), status(429), ok}, 3, false},
+		{"connection reset then success", []http.HandlerFunc{reset, ok}, 2, false},
+		{"timeout then success", []http.HandlerFunc{hang, ok}, 2, false},
+		{"500 every time", []http.HandlerFunc{status(500)}, 3, true},
+		{"400 is not retried", []http.HandlerFunc{status(400), ok}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := scripted(t, &calls, tt.steps...)
+			c := newClient(srv.URL)
+			c.HTTP.Timeout = 100 * time.Millisecond // keeps "hang" short
+
+			item, err := c.GetItem(context.Background(), "1")
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetItem error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && item.Name != "gopher" {
+				t.Errorf("item = %+v, want gopher", item)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("server saw %d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClientGivesUpWithStatusError(t *testing.T) {
+	var calls atomic.Int32
+	srv := scripted(t, &calls, status(http.StatusBadGateway))
+
+	_, err := newClient(srv.URL).GetItem(context.Background(), "1")
+
+	var se *StatusError
+	if !errors.As(err, &se) || se.Code != http.StatusBadGateway {
+		t.Errorf("err = %v, want a StatusError with 502", err)
+	}
+}
+
+func TestClientStopsWhenContextCancelled(t *testing.T) {
+	var calls atomic.Int32
+	srv := scripted(t, &calls, hang)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := newClient(srv.URL).GetItem(ctx, "1")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want 1: cancellation must not be retried", got)
+	}
+}
@@ -0,0 +1,93 @@
+package httptesting
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Testing HTTP - The Server Under Test
+// ====================================
+// A tiny item API: one route behind three middlewares. httptesting_test.go
+// tests the handler in-process with httptest.ResponseRecorder, and the
+// client in client.go against it with httptest.Server.
+
+// Item is the resource served at /items/{id}
+type Item struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewHandler returns the API: GET /items/{id} with a bearer token
+func NewHandler(items map[string]Item, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "boom" {
+			panic("handler bug") // lets tests exercise Recover
+		}
+		item, ok := items[id]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(item)
+	})
+	return Chain(mux, RequestID, Recover, RequireToken(token))
+}
+
+// Middleware wraps a handler with extra behaviour
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies middlewares so the first one listed runs first
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+var requestCounter atomic.Int64
+
+// RequestID sets X-Request-ID on the response, reusing the client's one
+// if it sent it
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = strconv.FormatInt(requestCounter.Add(1), 10)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Recover turns a panic in a handler into a 500
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("panic serving %s: %v", r.URL.Path, err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireToken rejects requests without "Authorization: Bearer <token>"
+func RequireToken(token string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer %s", token) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
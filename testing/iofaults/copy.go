@@ -0,0 +1,113 @@
+package iofaults
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// I/O Fault Injection - Code Under Test
+// =====================================
+// Each function comes in two versions. The naive ones pass every test
+// that uses a strings.Reader and a bytes.Buffer; iofaults_test.go shows
+// the faults that break them and the hardened versions that survive.
+
+// maxTimeoutRetries is how many timeouts in a row Copy tolerates
+const maxTimeoutRetries = 3
+
+// 1. Copying a Stream
+// ===================
+
+// CopyNaive has three bugs:
+//   - data returned together with io.EOF is dropped
+//   - write errors and short writes are ignored
+//   - a timeout aborts the copy, even though the next Read would succeed
+func CopyNaive(dst io.Writer, src io.Reader) (int64, error) {
+	buf := make([]byte, 32)
+	var total int64
+	for {
+		n, err := src.Read(buf)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		dst.Write(buf[:n])
+		total += int64(n)
+	}
+}
+
+// Copy copies src to dst until EOF. It follows the io.Reader contract -
+// use n bytes BEFORE looking at the error - checks every write, and
+// retries a few consecutive timeouts.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := make([]byte, 32)
+	var total int64
+	timeouts := 0
+	for {
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			timeouts = 0
+			w, werr := dst.Write(buf[:n])
+			total += int64(w)
+			if werr != nil {
+				return total, werr
+			}
+			if w != n {
+				return total, io.ErrShortWrite
+			}
+		}
+		switch {
+		case rerr == nil:
+		case rerr == io.EOF:
+			return total, nil
+		case IsTimeout(rerr) && timeouts < maxTimeoutRetries:
+			timeouts++
+		default:
+			return total, rerr
+		}
+	}
+}
+
+// 2. Reading a Length-Prefixed Frame
+// ==================================
+// A frame is a 4-byte big-endian length followed by that many bytes.
+
+// ReadFrameNaive assumes one Read fills the buffer. With a bytes.Reader
+// it always does; with a socket it often does not.
+func ReadFrameNaive(r io.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := r.Read(hdr[:]); err != nil {
+		return nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(hdr[:]))
+	if _, err := r.Read(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// ReadFrame uses io.ReadFull, which loops until the buffer is full and
+// turns a stream that ends early into io.ErrUnexpectedEOF
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err // io.EOF here means a clean end between frames
+	}
+	body := make([]byte, binary.BigEndian.Uint32(hdr[:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("frame body: %w", err)
+	}
+	return body, nil
+}
+
+// AppendFrame appends the encoding of body to buf
+func AppendFrame(buf, body []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(body)))
+	return append(buf, body...)
+}
@@ -0,0 +1,103 @@
+package iofaults
+
+import (
+	"errors"
+	"io"
+)
+
+// I/O Fault Injection - Flaky Readers and Writers
+// ===============================================
+// Real readers and writers misbehave in ways a strings.Reader or a
+// bytes.Buffer never do: a network read returns a few bytes at a time, a
+// deadline expires mid-stream, a pipe accepts half a write. These
+// wrappers reproduce those behaviours on demand so code can be tested
+// against them. testing/iotest has more (OneByteReader, HalfReader,
+// DataErrReader, TimeoutReader, ErrReader); these cover the gaps.
+
+// ErrTimeout is returned by TimeoutEvery. Like net.Error it has a
+// Timeout method, so callers can tell it apart from a fatal error.
+var ErrTimeout error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// IsTimeout reports whether err, or an error it wraps, is a timeout
+func IsTimeout(err error) bool {
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+// ShortReader returns at most n bytes per Read, like a socket that
+// delivers data in small packets
+type ShortReader struct {
+	R io.Reader
+	N int
+}
+
+func (r *ShortReader) Read(p []byte) (int, error) {
+	if len(p) > r.N {
+		p = p[:r.N]
+	}
+	return r.R.Read(p)
+}
+
+// TimeoutEvery fails every Nth Read with ErrTimeout without consuming
+// data, like a read deadline expiring on a slow connection. The next
+// Read continues where the stream left off.
+type TimeoutEvery struct {
+	R     io.Reader
+	N     int
+	calls int
+}
+
+func (r *TimeoutEvery) Read(p []byte) (int, error) {
+	r.calls++
+	if r.calls%r.N == 0 {
+		return 0, ErrTimeout
+	}
+	return r.R.Read(p)
+}
+
+// ErrAfterReader returns Err once N bytes have been read, like a
+// connection dropped mid-transfer
+type ErrAfterReader struct {
+	R   io.Reader
+	N   int
+	Err error
+}
+
+func (r *ErrAfterReader) Read(p []byte) (int, error) {
+	if r.N <= 0 {
+		return 0, r.Err
+	}
+	if len(p) > r.N {
+		p = p[:r.N]
+	}
+	n, err := r.R.Read(p)
+	r.N -= n
+	return n, err
+}
+
+// PartialWriter accepts at most N bytes per Write and returns the short
+// count with Err. The io.Writer contract requires an error whenever
+// n < len(p), but leaving Err nil reproduces writers that break it - the
+// short count is then the only sign that data was lost.
+type PartialWriter struct {
+	W   io.Writer
+	N   int
+	Err error
+}
+
+func (w *PartialWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.N {
+		return w.W.Write(p)
+	}
+	n, err := w.W.Write(p[:w.N])
+	if err == nil {
+		err = w.Err
+	}
+	return n, err
+}
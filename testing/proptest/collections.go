@@ -0,0 +1,105 @@
+package proptest
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Property-Based Testing - Code Under Test
+// ========================================
+// Map, Filter and Reduce are generic versions of mapInts, filterInts and
+// reduceInts from functions/go_functions.go. proptest_test.go checks
+// their properties.
+//
+// COWSlice is a fixture: a copy of the type in structs/go_immutable.go,
+// which is a package main this package cannot import. The model test
+// shows how to test such a type; it checks this copy, not the lesson's.
+
+func Map[T, U any](xs []T, fn func(T) U) []U {
+	result := make([]U, len(xs))
+	for i, x := range xs {
+		result[i] = fn(x)
+	}
+	return result
+}
+
+func Filter[T any](xs []T, fn func(T) bool) []T {
+	var result []T
+	for _, x := range xs {
+		if fn(x) {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+func Reduce[T, A any](xs []T, initial A, fn func(A, T) A) A {
+	result := initial
+	for _, x := range xs {
+		result = fn(result, x)
+	}
+	return result
+}
+
+func Reverse[T any](xs []T) []T {
+	result := make([]T, len(xs))
+	for i, x := range xs {
+		result[len(xs)-1-i] = x
+	}
+	return result
+}
+
+// Chunk splits xs into slices of n > 0 elements; the last may be shorter
+func Chunk[T any](xs []T, n int) [][]T {
+	var chunks [][]T
+	for len(xs) > n {
+		chunks = append(chunks, xs[:n:n])
+		xs = xs[n:]
+	}
+	if len(xs) > 0 {
+		chunks = append(chunks, xs)
+	}
+	return chunks
+}
+
+// COWSlice is a copy-on-write slice: writers copy the snapshot, modify
+// the copy and swap it in, so a Snapshot never changes once taken. It
+// is a fixture copied from structs/go_immutable.go.
+type COWSlice[T any] struct {
+	mu   sync.Mutex // serializes writers only
+	data atomic.Pointer[[]T]
+}
+
+func NewCOWSlice[T any](items ...T) *COWSlice[T] {
+	s := &COWSlice[T]{}
+	snapshot := append([]T(nil), items...)
+	s.data.Store(&snapshot)
+	return s
+}
+
+func (s *COWSlice[T]) Snapshot() []T {
+	return *s.data.Load()
+}
+
+func (s *COWSlice[T]) Append(item T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := *s.data.Load()
+	next := make([]T, len(old), len(old)+1)
+	copy(next, old)
+	next = append(next, item)
+	s.data.Store(&next)
+}
+
+func (s *COWSlice[T]) Set(i int, item T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := *s.data.Load()
+	next := append([]T(nil), old...)
+	next[i] = item
+	s.data.Store(&next)
+}
+
+func (s *COWSlice[T]) Len() int {
+	return len(*s.data.Load())
+}
@@ -0,0 +1,252 @@
+package proptest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+)
+
+// Property-Based Testing - Generators and Shrinkers
+// =================================================
+// A Gen pairs a way to make random values with a way to make them smaller.
+// Size is a hint that grows over a run: generators use it to bound
+// lengths, so early runs try short slices and strings.
+//
+// Shrink returns candidates that are "simpler" than v, simplest first.
+// Every candidate must be strictly simpler, or shrinking never ends.
+
+// Gen generates and shrinks values of type T
+type Gen[T any] struct {
+	Generate func(r *rand.Rand, size int) T
+	Shrink   func(v T) []T
+}
+
+// Int generates integers in [lo, hi] and shrinks towards the value in
+// that range closest to zero
+func Int(lo, hi int) Gen[int] {
+	target := min(max(0, lo), hi)
+	return Gen[int]{
+		Generate: func(r *rand.Rand, size int) int {
+			return lo + r.IntN(hi-lo+1)
+		},
+		Shrink: func(v int) []int {
+			return shrinkInt(v, target)
+		},
+	}
+}
+
+// Bool generates true and false and shrinks true to false
+func Bool() Gen[bool] {
+	return Gen[bool]{
+		Generate: func(r *rand.Rand, size int) bool { return r.IntN(2) == 1 },
+		Shrink: func(v bool) []bool {
+			if v {
+				return []bool{false}
+			}
+			return nil
+		},
+	}
+}
+
+// alphabet is mostly ASCII, with a few multi-byte runes so properties
+// that assume one byte per character fail
+var alphabet = []rune("abcdefghijklmnopqrstuvwxyzABCXYZ0123456789 _-.;=\"\\é日語")
+
+// String generates strings of up to size runes and shrinks by removing
+// runes, then by replacing runes with 'a'
+func String() Gen[string] {
+	return Gen[string]{
+		Generate: func(r *rand.Rand, size int) string {
+			return string(genRunes(r, size))
+		},
+		Shrink: func(v string) []string {
+			var out []string
+			for _, rs := range shrinkSeq([]rune(v), shrinkRune) {
+				out = append(out, string(rs))
+			}
+			return out
+		},
+	}
+}
+
+// SliceOf generates slices of up to size elements from elem and shrinks
+// by removing elements, then by shrinking single elements
+func SliceOf[T any](elem Gen[T]) Gen[[]T] {
+	return Gen[[]T]{
+		Generate: func(r *rand.Rand, size int) []T {
+			xs := make([]T, r.IntN(size+1))
+			for i := range xs {
+				xs[i] = elem.Generate(r, size)
+			}
+			return xs
+		},
+		Shrink: func(v []T) [][]T {
+			return shrinkSeq(v, elem.Shrink)
+		},
+	}
+}
+
+// Struct generates structs by reflection, filling every exported field.
+// Supported field kinds are ints, bools, strings, and slices and structs
+// of those. Shrinking shrinks one field at a time.
+func Struct[T any]() Gen[T] {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("proptest.Struct: %v is not a struct", typ))
+	}
+	checkSupported(typ)
+	return Gen[T]{
+		Generate: func(r *rand.Rand, size int) T {
+			return genValue(typ, r, size).Interface().(T)
+		},
+		Shrink: func(v T) []T {
+			var out []T
+			for _, c := range shrinkValue(reflect.ValueOf(v)) {
+				out = append(out, c.Interface().(T))
+			}
+			return out
+		},
+	}
+}
+
+// Shrinking helpers
+// =================
+
+// shrinkInt returns target, then values halfway back towards v: for
+// v=10, target=0 that is 0, 5, 8, 9
+func shrinkInt(v, target int) []int {
+	var out []int
+	for d := v - target; d != 0; d /= 2 {
+		out = append(out, v-d)
+	}
+	return out
+}
+
+func shrinkRune(c rune) []rune {
+	if c != 'a' {
+		return []rune{'a'}
+	}
+	return nil
+}
+
+// shrinkSeq removes chunks of len/2, len/4, ... 1 elements, then shrinks
+// each element in place. Removing first makes big steps early.
+func shrinkSeq[T any](xs []T, elem func(T) []T) [][]T {
+	var out [][]T
+	for n := len(xs); n > 0; n /= 2 {
+		for i := 0; i+n <= len(xs); i += n {
+			c := make([]T, 0, len(xs)-n)
+			c = append(c, xs[:i]...)
+			c = append(c, xs[i+n:]...)
+			out = append(out, c)
+		}
+	}
+	for i, x := range xs {
+		for _, s := range elem(x) {
+			c := append([]T(nil), xs...)
+			c[i] = s
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func genRunes(r *rand.Rand, size int) []rune {
+	rs := make([]rune, r.IntN(size+1))
+	for i := range rs {
+		rs[i] = alphabet[r.IntN(len(alphabet))]
+	}
+	return rs
+}
+
+// Reflection-based generation for Struct
+// ======================================
+
+func checkSupported(t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Bool, reflect.String:
+	case reflect.Slice:
+		checkSupported(t.Elem())
+	case reflect.Struct:
+		for f := range t.Fields() {
+			if f.IsExported() {
+				checkSupported(f.Type)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("proptest.Struct: unsupported kind %v", t.Kind()))
+	}
+}
+
+func genValue(t reflect.Type, r *rand.Rand, size int) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// small values fit every int kind, and are what shrinking aims for anyway
+		v.SetInt(int64(r.IntN(2*size+1) - size))
+	case reflect.Bool:
+		v.SetBool(r.IntN(2) == 1)
+	case reflect.String:
+		v.SetString(string(genRunes(r, size)))
+	case reflect.Slice:
+		n := r.IntN(size + 1)
+		v.Set(reflect.MakeSlice(t, n, n))
+		for i := range n {
+			v.Index(i).Set(genValue(t.Elem(), r, size))
+		}
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if t.Field(i).IsExported() {
+				v.Field(i).Set(genValue(t.Field(i).Type, r, size))
+			}
+		}
+	}
+	return v
+}
+
+func shrinkValue(v reflect.Value) []reflect.Value {
+	var out []reflect.Value
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		for _, n := range shrinkInt(int(v.Int()), 0) {
+			c := reflect.New(v.Type()).Elem()
+			c.SetInt(int64(n))
+			out = append(out, c)
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			out = append(out, reflect.Zero(v.Type()))
+		}
+	case reflect.String:
+		for _, s := range String().Shrink(v.String()) {
+			c := reflect.New(v.Type()).Elem()
+			c.SetString(s)
+			out = append(out, c)
+		}
+	case reflect.Slice:
+		elems := make([]reflect.Value, v.Len())
+		for i := range elems {
+			elems[i] = v.Index(i)
+		}
+		for _, es := range shrinkSeq(elems, shrinkValue) {
+			c := reflect.MakeSlice(v.Type(), len(es), len(es))
+			for i, e := range es {
+				c.Index(i).Set(e)
+			}
+			out = append(out, c)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			for _, f := range shrinkValue(v.Field(i)) {
+				c := reflect.New(v.Type()).Elem()
+				c.Set(v)
+				c.Field(i).Set(f)
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package proptest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// Property-Based Testing - The Runner
+// ===================================
+// A property is a function that must return true for EVERY input. Instead
+// of listing cases by hand, the runner generates random inputs of growing
+// size, and when one fails it shrinks it to the smallest input that still
+// fails. Generators and shrinkers live in gen.go.
+//
+// This is a teaching-sized version of what testing/quick (frozen) and
+// libraries like rapid or gopter do, with no dependencies beyond the
+// standard library.
+
+// Config controls a run. The zero value is usable.
+type Config struct {
+	Runs       int    // inputs to try (default 100)
+	MaxSize    int    // size hint passed to generators on the last run (default 50)
+	Seed       uint64 // 0 picks a random seed, which is reported on failure
+	MaxShrinks int    // successful shrink steps before giving up (default 1000)
+}
+
+// Failure describes a property that did not hold
+type Failure[T any] struct {
+	Seed     uint64 // rerun with Config{Seed: Seed} to reproduce
+	Run      int    // which run produced Original
+	Original T      // the random input that first failed
+	Shrunk   T      // the smallest failing input found
+	Shrinks  int    // successful shrink steps from Original to Shrunk
+	Panic    any    // non-nil if the property panicked rather than returned false
+}
+
+func (f *Failure[T]) String() string {
+	msg := fmt.Sprintf("property failed after %d run(s), seed %d\n  original: %#v\n  shrunk (%d steps): %#v",
+		f.Run+1, f.Seed, f.Original, f.Shrinks, f.Shrunk)
+	if f.Panic != nil {
+		msg += fmt.Sprintf("\n  panic: %v", f.Panic)
+	}
+	return msg
+}
+
+// Run checks prop against cfg.Runs generated inputs and returns nil if it
+// held for all of them
+func Run[T any](cfg Config, g Gen[T], prop func(T) bool) *Failure[T] {
+	if cfg.Runs <= 0 {
+		cfg.Runs = 100
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 50
+	}
+	if cfg.MaxShrinks <= 0 {
+		cfg.MaxShrinks = 1000
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = rand.Uint64()
+	}
+	r := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))
+
+	for run := 0; run < cfg.Runs; run++ {
+		// Start small: most bugs show up on small inputs, and small
+		// failures are cheap to shrink
+		size := run * cfg.MaxSize / max(cfg.Runs-1, 1)
+		input := g.Generate(r, size)
+		if ok, p := holds(prop, input); !ok {
+			f := &Failure[T]{Seed: cfg.Seed, Run: run, Original: input, Panic: p}
+			f.Shrunk, f.Shrinks, f.Panic = shrink(g, prop, input, p, cfg.MaxShrinks)
+			return f
+		}
+	}
+	return nil
+}
+
+// Check is Run for use inside a test: it fails t with the shrunk
+// counterexample and the seed needed to reproduce it
+func Check[T any](t testing.TB, cfg Config, g Gen[T], prop func(T) bool) {
+	t.Helper()
+	if f := Run(cfg, g, prop); f != nil {
+		t.Fatal(f)
+	}
+}
+
+// shrink greedily walks towards a minimal failing input: take the first
+// candidate that still fails, and repeat until no candidate fails. The
+// result is a local minimum, which in practice is usually tiny.
+func shrink[T any](g Gen[T], prop func(T) bool, input T, p any, limit int) (T, int, any) {
+	steps := 0
+	for steps < limit {
+		progressed := false
+		for _, candidate := range g.Shrink(input) {
+			if ok, cp := holds(prop, candidate); !ok {
+				input, p = candidate, cp
+				steps++
+				progressed = true
+				break
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+	return input, steps, p
+}
+
+// holds runs prop, treating a panic as a failure
+func holds[T any](prop func(T) bool, input T) (ok bool, p any) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok, p = false, r
+		}
+	}()
+	return prop(input), nil
+}
@@ -0,0 +1,257 @@
+package proptest
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Property-Based Testing - The Lesson
+// ===================================
+// Example-based tests check the cases you thought of. Property-based tests
+// state what must be true for ALL inputs and let a generator look for the
+// cases you did not think of. Useful kinds of property:
+//
+//   - invariants:      len(Map(xs, f)) == len(xs)
+//   - round trips:     Reverse(Reverse(xs)) == xs
+//   - oracles/models:  COWSlice behaves like a plain []int
+//   - algebraic laws:  Reduce over a concatenation == combining two Reduces
+//
+// Run with:
+//
+//   cd testing/proptest
+//   go test -v *.go
+//
+// A failure prints the seed; set it in Config to replay the exact run.
+
+// 1. Invariants: Map and Filter
+// =============================
+
+func TestMapProperties(t *testing.T) {
+	ints := SliceOf(Int(-1000, 1000))
+	double := func(x int) int { return 2 * x }
+	inc := func(x int) int { return x + 1 }
+
+	t.Run("preserves length", func(t *testing.T) {
+		Check(t, Config{}, ints, func(xs []int) bool {
+			return len(Map(xs, double)) == len(xs)
+		})
+	})
+	t.Run("identity", func(t *testing.T) {
+		Check(t, Config{}, ints, func(xs []int) bool {
+			return slices.Equal(Map(xs, func(x int) int { return x }), xs)
+		})
+	})
+	t.Run("composition", func(t *testing.T) {
+		Check(t, Config{}, ints, func(xs []int) bool {
+			fused := Map(xs, func(x int) int { return double(inc(x)) })
+			return slices.Equal(fused, Map(Map(xs, inc), double))
+		})
+	})
+}
+
+func TestFilterProperties(t *testing.T) {
+	ints := SliceOf(Int(-1000, 1000))
+	even := func(x int) bool { return x%2 == 0 }
+	odd := func(x int) bool { return !even(x) }
+
+	t.Run("every kept element matches", func(t *testing.T) {
+		Check(t, Config{}, ints, func(xs []int) bool {
+			for _, x := range Filter(xs, even) {
+				if !even(x) {
+					return false
+				}
+			}
+			return true
+		})
+	})
+	t.Run("partition", func(t *testing.T) {
+		Check(t, Config{}, ints, func(xs []int) bool {
+			return len(Filter(xs, even))+len(Filter(xs, odd)) == len(xs)
+		})
+	})
+	t.Run("idempotent", func(t *testing.T) {
+		Check(t, Config{}, ints, func(xs []int) bool {
+			once := Filter(xs, even)
+			return slices.Equal(Filter(once, even), once)
+		})
+	})
+}
+
+// 2. Round Trips: Reverse and Chunk
+// =================================
+
+func TestReverseInvolution(t *testing.T) {
+	Check(t, Config{}, SliceOf(String()), func(xs []string) bool {
+		return slices.Equal(Reverse(Reverse(xs)), xs)
+	})
+}
+
+// chunkCase is generated by Struct: every exported field gets a random
+// value, and shrinking simplifies one field at a time
+type chunkCase struct {
+	Items []int
+	Size  int
+}
+
+func TestChunkProperties(t *testing.T) {
+	cases := Struct[chunkCase]()
+	Check(t, Config{}, cases, func(c chunkCase) bool {
+		if c.Size <= 0 {
+			return true // precondition: Chunk needs n > 0
+		}
+		chunks := Chunk(c.Items, c.Size)
+		for i, ch := range chunks {
+			last := i == len(chunks)-1
+			if len(ch) == 0 || len(ch) > c.Size || !last && len(ch) != c.Size {
+				return false
+			}
+		}
+		return slices.Equal(slices.Concat(chunks...), c.Items)
+	})
+}
+
+// 3. Algebraic Laws: Reduce
+// =========================
+
+func TestReduceHomomorphism(t *testing.T) {
+	// Splitting the input anywhere and combining the two partial sums gives
+	// the same answer - the law that makes a parallel Reduce correct
+	type split struct {
+		Xs  []int
+		Cut int
+	}
+	add := func(acc, x int) int { return acc + x }
+	Check(t, Config{}, Struct[split](), func(s split) bool {
+		cut := min(max(s.Cut, 0), len(s.Xs))
+		whole := Reduce(s.Xs, 0, add)
+		return whole == Reduce(s.Xs[:cut], 0, add)+Reduce(s.Xs[cut:], 0, add)
+	})
+}
+
+// 4. Models: COWSlice vs a Plain Slice
+// ====================================
+
+// The COWSlice here is a fixture copied from structs/go_immutable.go:
+// what this section teaches is the model test, not that the lesson's
+// type is correct.
+
+// op is one step of a random program run against COWSlice and a model
+type op struct {
+	Append bool
+	Index  int
+	Value  int
+}
+
+func TestCOWSliceMatchesModel(t *testing.T) {
+	Check(t, Config{}, SliceOf(Struct[op]()), func(ops []op) bool {
+		cow := NewCOWSlice[int]()
+		var model []int
+		for _, o := range ops {
+			before := slices.Clone(cow.Snapshot())
+			snapshot := cow.Snapshot()
+			switch {
+			case o.Append:
+				cow.Append(o.Value)
+				model = append(model, o.Value)
+			case len(model) > 0:
+				i := (o.Index%len(model) + len(model)) % len(model)
+				cow.Set(i, o.Value)
+				model[i] = o.Value
+			}
+			// Copy-on-write: a snapshot taken before a write never changes
+			if !slices.Equal(snapshot, before) {
+				return false
+			}
+			if !slices.Equal(cow.Snapshot(), model) || cow.Len() != len(model) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// 5. Shrinking: Finding the Smallest Counterexample
+// =================================================
+
+// truncate is deliberately wrong: it cuts at a byte offset, which can
+// split a multi-byte rune
+func truncate(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	return s[:n]
+}
+
+func TestShrinkingFindsSmallCounterexample(t *testing.T) {
+	type truncateCase struct {
+		S string
+		N int
+	}
+	f := Run(Config{Runs: 1000}, Struct[truncateCase](), func(c truncateCase) bool {
+		return c.N < 0 || utf8.ValidString(truncate(c.S, c.N))
+	})
+	if f == nil {
+		t.Fatal("expected the truncate property to fail")
+	}
+	t.Logf("%v", f)
+
+	// Shrinking only keeps candidates that still fail, so the result is
+	// always a real counterexample, and never bigger than the original
+	if utf8.ValidString(truncate(f.Shrunk.S, f.Shrunk.N)) {
+		t.Errorf("shrunk %#v is not a counterexample", f.Shrunk)
+	}
+	if len(f.Shrunk.S) > len(f.Original.S) {
+		t.Errorf("shrunk S %q is longer than original %q", f.Shrunk.S, f.Original.S)
+	}
+
+	// Greedy shrinking stops at a LOCAL minimum. Usually that is a lone
+	// rune such as {"語", 1}, but {"aaé", 3} can also be final: removing
+	// an 'a' alone moves the cut to the end of the string, which passes,
+	// and shrinking changes one field at a time.
+	if utf8.RuneCountInString(f.Shrunk.S) > utf8.RuneCountInString(f.Original.S) {
+		t.Errorf("shrunk S %q has more runes than original %q", f.Shrunk.S, f.Original.S)
+	}
+}
+
+func TestShrinkIntSlice(t *testing.T) {
+	// "every element is below 100" fails; the smallest failing slice is [100]
+	f := Run(Config{}, SliceOf(Int(0, 1000)), func(xs []int) bool {
+		return !slices.ContainsFunc(xs, func(x int) bool { return x >= 100 })
+	})
+	if f == nil {
+		t.Fatal("expected the property to fail")
+	}
+	if !slices.Equal(f.Shrunk, []int{100}) {
+		t.Errorf("shrunk = %v, want [100] (original %v)", f.Shrunk, f.Original)
+	}
+}
+
+func TestPanicIsFailure(t *testing.T) {
+	f := Run(Config{}, SliceOf(Int(0, 10)), func(xs []int) bool {
+		return xs[0] >= 0 // panics on an empty slice
+	})
+	if f == nil || f.Panic == nil {
+		t.Fatalf("expected a panic to be reported, got %v", f)
+	}
+	if len(f.Shrunk) != 0 {
+		t.Errorf("shrunk = %v, want []", f.Shrunk)
+	}
+}
+
+func TestSeedReproducesRun(t *testing.T) {
+	prop := func(s string) bool { return !strings.Contains(s, "é") }
+	first := Run(Config{Seed: 42}, String(), prop)
+	second := Run(Config{Seed: 42}, String(), prop)
+	if first == nil || second == nil {
+		t.Fatal("expected seed 42 to find a string containing 'é'")
+	}
+	if first.Original != second.Original || first.Run != second.Run {
+		t.Errorf("same seed gave different runs: %q (run %d) vs %q (run %d)",
+			first.Original, first.Run, second.Original, second.Run)
+	}
+	if first.Shrunk != "é" {
+		t.Errorf("shrunk = %q, want %q", first.Shrunk, "é")
+	}
+}
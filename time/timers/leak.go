@@ -0,0 +1,70 @@
+package timers
+
+import (
+	"sync"
+	"time"
+)
+
+// Timer Leaks
+// ===========
+// Since Go 1.23 an unstopped timer nothing refers to is collected, so
+// the timer itself no longer leaks. What still leaks is a goroutine
+// waiting on one:
+//
+//	go func() { <-t.C; fn() }()     t.Stop() later: C is never closed
+//	                                and never sent on, so the goroutine
+//	                                waits forever, holding t and fn
+//	go func() {                     t.Stop() later: the same, one tick
+//	    for range tk.C { poll() }   short of forever
+//	}()
+//
+// Stop means "do not send", not "wake the receivers". A goroutine
+// waiting on a timer needs a second case in its select that the owner
+// controls - a done channel or a context - or no goroutine at all:
+// time.AfterFunc starts one only when the timer fires.
+//
+// The tests count goroutines with runtime.NumGoroutine before and after,
+// which is how these leaks are usually found; go.uber.org/goleak does
+// the same with stack traces.
+
+// WatchLeaky calls fn in a new goroutine when t fires. If t is stopped
+// instead, that goroutine blocks on t.C for the life of the process.
+func WatchLeaky(t *time.Timer, fn func()) {
+	go func() {
+		<-t.C
+		fn()
+	}()
+}
+
+// Watch calls fn in a new goroutine when a timer of d fires. The
+// returned stop cancels it, reports whether it did so before fn was
+// started, and ends the goroutine either way.
+func Watch(d time.Duration, fn func()) (stop func() bool) {
+	t := time.NewTimer(d)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-t.C:
+			fn()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() bool {
+		stopped := t.Stop()
+		once.Do(func() { close(done) })
+		return stopped
+	}
+}
+
+// PollLeaky calls fn on every tick of a new ticker and returns the
+// ticker. Stopping it does not end the goroutine ranging over C.
+func PollLeaky(period time.Duration, fn func()) *time.Ticker {
+	t := time.NewTicker(period)
+	go func() {
+		for range t.C {
+			fn()
+		}
+	}()
+	return t
+}
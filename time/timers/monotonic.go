@@ -0,0 +1,105 @@
+package timers
+
+import (
+	"strings"
+	"time"
+)
+
+// Wall Clock and Monotonic Clock
+// ==============================
+// The machine has two clocks. The wall clock says what time it is; NTP
+// corrects it, an administrator sets it, and it can jump backwards. The
+// monotonic clock only counts up from an arbitrary start and is useless
+// as a date, but it is the right clock for "how long did that take".
+//
+// time.Now() reads both and keeps both in the Time it returns. Every
+// operation chooses:
+//
+//	t.Sub(u), time.Since(t),   monotonic, if both t and u carry a
+//	time.Until(t), Before,     reading; otherwise wall. A clock change
+//	After, Equal, Compare      between Now calls does not skew them
+//	t.Add(d)                   keeps the reading, shifted by d
+//	t.Round(0)                 strips it - the documented way to get a
+//	                           wall-clock-only Time
+//	t.In, Local, UTC, Truncate strip it too: they produce wall times
+//	Format, MarshalJSON,       only the wall clock; a Time read back
+//	MarshalBinary              from JSON or a database has no reading
+//	t == u                     compares the struct, reading included
+//
+// The last line is the trap. Two Times for the same instant differ
+// under == if one has a reading and the other does not, or if their
+// locations differ; the same goes for Times as map keys. Compare with
+// Equal, and strip the reading and normalise the location - say
+// t.Round(0).UTC() - before using a Time as a key.
+//
+// String shows the reading as a trailing "m=+0.000123456": seconds
+// since the process started.
+
+// HasMonotonic reports whether t carries a monotonic clock reading.
+// The time package has no accessor for it; String is the only place
+// it shows.
+func HasMonotonic(t time.Time) bool {
+	return strings.Contains(t.String(), " m=")
+}
+
+// MapKey returns the form of t to use as a map key or to compare with
+// ==: no monotonic reading, and in UTC
+func MapKey(t time.Time) time.Time {
+	return t.Round(0).UTC()
+}
+
+// Truncate and Round
+// ==================
+// t.Truncate(d) and t.Round(d) work on the time elapsed since the zero
+// Time - January 1, year 1, UTC - not on the clock face in t's
+// location. For units up to an hour that makes no difference in zones
+// offset by whole hours. For a day it does: Truncate(24*time.Hour) is
+// midnight UTC, which in New York is 19:00 or 20:00 the day before.
+// Offsets like India's +05:30 shift even Truncate(time.Hour).
+//
+// Calendar rounding goes through time.Date in the location wanted,
+// which also handles days that are 23 or 25 hours long.
+
+// StartOfDay returns midnight at the start of t's day, in t's location
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Measuring
+// =========
+
+// Stopwatch measures elapsed time with the monotonic clock. Its zero
+// value is not started.
+type Stopwatch struct {
+	start, last time.Time
+	laps        []time.Duration
+}
+
+// Start (re)starts the stopwatch and forgets earlier laps
+func (s *Stopwatch) Start() {
+	s.start = time.Now()
+	s.last = s.start
+	s.laps = s.laps[:0]
+}
+
+// Lap records and returns the time since Start or the previous Lap
+func (s *Stopwatch) Lap() time.Duration {
+	now := time.Now()
+	lap := now.Sub(s.last)
+	s.last = now
+	s.laps = append(s.laps, lap)
+	return lap
+}
+
+// Elapsed returns the time since Start. A Stopwatch whose start was
+// restored from a stored wall time - no monotonic reading - would
+// measure with the wall clock instead, and could go negative.
+func (s *Stopwatch) Elapsed() time.Duration {
+	return time.Since(s.start)
+}
+
+// Laps returns the recorded laps
+func (s *Stopwatch) Laps() []time.Duration {
+	return s.laps
+}
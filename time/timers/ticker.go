@@ -0,0 +1,73 @@
+package timers
+
+import (
+	"context"
+	"time"
+)
+
+// Tickers
+// =======
+// A *time.Ticker sends on C every period until stopped. Three things
+// surprise people:
+//
+//   - The first tick comes after one period, not at once. Do the work
+//     before the loop if it should also run at the start.
+//   - A slow receiver does not get a backlog. The ticker holds at most
+//     one pending tick and drops the rest, so "count the ticks" is not
+//     a way to measure elapsed time; take time.Since instead.
+//   - Stop does not close C. A "for range t.C" loop never ends on its
+//     own; it needs a second way out, such as a context.
+//
+// Reset(d) changes the period, counting from now. Polling that backs
+// off while nothing changes, and speeds up again when something does,
+// is a ticker that Resets itself.
+
+// Every calls fn once per period until ctx is done. A call that takes
+// longer than the period makes the next one start late rather than
+// pile up, because the ticker drops the ticks fn missed.
+func Every(ctx context.Context, period time.Duration, fn func(time.Time)) error {
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			// When a tick and Done are both ready, select picks one at
+			// random; without this check a slow fn, which always finds
+			// a tick waiting, could run on well past cancellation
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			fn(now)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Poll calls check every interval, starting at fastest. While check
+// reports no change, the interval doubles up to slowest; a change
+// resets it to fastest. It returns when check returns an error or ctx is done.
+func Poll(ctx context.Context, fastest, slowest time.Duration, check func() (changed bool, err error)) error {
+	interval := fastest
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		changed, err := check()
+		if err != nil {
+			return err
+		}
+		next := fastest
+		if !changed {
+			next = min(interval*2, slowest)
+		}
+		if next != interval {
+			interval = next
+			t.Reset(interval)
+		}
+	}
+}
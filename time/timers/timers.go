@@ -0,0 +1,175 @@
+package timers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Timers - Stop, Reset and AfterFunc
+// ==================================
+// A *time.Timer delivers one value on its channel C after a duration.
+// Its lifecycle has three calls, and each one's return value matters:
+//
+//	t := time.NewTimer(d)   armed; C receives once, d from now
+//	t.Stop()                disarm; true if this call stopped it, false
+//	                        if it had already fired or been stopped
+//	t.Reset(d)              re-arm for d from now, whatever its state;
+//	                        returns what Stop would have returned
+//
+// Go 1.23 changed what C is, and most advice about timers predates it:
+//
+//	before 1.23             a buffered channel (cap 1). A timer that
+//	                        fired left its value in C, so Stop or Reset
+//	                        without draining C let a stale value arrive
+//	                        later. The fix was the drain idiom:
+//	                            if !t.Stop() { <-t.C }
+//	                        which blocks forever if the value was
+//	                        already received - a bug of its own
+//	1.23 and later          C is synchronous (cap 0). After Stop or
+//	                        Reset returns, no stale value is ever
+//	                        received: no draining, no idiom
+//
+// The new behaviour follows the go line of the main module: a go.mod
+// saying "go 1.22" still gets the old buffered channel, as does
+// GODEBUG=asynctimerchan=1. Lessons run as files outside a module, like
+// these, get the toolchain's own version and so the new behaviour.
+//
+// The other 1.23 change: a timer or ticker nothing refers to is garbage
+// collected even if it was never stopped. time.After in a loop no
+// longer leaks timers until they fire - but each call still allocates
+// one (see BenchmarkAfterInLoop), and a goroutine blocked on a timer
+// that will never fire still leaks (see leak.go).
+
+// Sleep pauses for d, or until ctx is done. time.Sleep cannot be
+// interrupted; this is the version for code that must stop promptly.
+//
+// The deferred Stop is not needed to free the timer since Go 1.23, but
+// it releases it at once instead of at the next collection, and keeps
+// the function correct under an older go line.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reusing One Timer
+// =================
+// A select loop that needs "nothing happened for a while" is often
+// written with time.After:
+//
+//	for {
+//	    select {
+//	    case v := <-in:       // ...
+//	    case <-time.After(idle): return ErrIdle
+//	    }
+//	}
+//
+// Every iteration allocates a new timer for a value that usually
+// arrives first. One timer, Reset after each value, does the same job
+// without the garbage.
+
+// ErrIdle is returned by Consume when no value arrives in time
+var ErrIdle = errors.New("timers: idle timeout")
+
+// Consume calls fn with each value from in until in is closed, ctx is
+// done, or no value arrives for idle. The idle period restarts with
+// every value.
+func Consume[T any](ctx context.Context, in <-chan T, idle time.Duration, fn func(T)) error {
+	t := time.NewTimer(idle)
+	defer t.Stop()
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return nil
+			}
+			fn(v)
+			// Since Go 1.23 Reset alone is enough: had the timer fired
+			// while fn ran, its value is discarded, not left in C
+			t.Reset(idle)
+		case <-t.C:
+			return ErrIdle
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// AfterFunc: a Debouncer
+// ======================
+// time.AfterFunc(d, f) runs f in its own goroutine after d; the timer's
+// C is nil. Stop returning false here means more than "too late": f
+// has been started and may be running right now, concurrently with the
+// caller. Reset does not wait for it either - a Reset after f started
+// schedules a second, independent call.
+//
+// A debouncer shows both: each Trigger pushes the call back, so a burst
+// of triggers - keystrokes, file-change events - produces one call,
+// quiet for d after the last of them.
+
+// Debouncer calls a function once a burst of Triggers has been quiet
+// for a while
+type Debouncer struct {
+	d  time.Duration
+	fn func()
+
+	mu    sync.Mutex
+	timer *time.Timer
+	gen   int // bumped by every Trigger and Stop
+}
+
+// NewDebouncer returns a Debouncer that calls fn d after the last
+// Trigger. fn runs in its own goroutine.
+func NewDebouncer(d time.Duration, fn func()) *Debouncer {
+	return &Debouncer{d: d, fn: fn}
+}
+
+// Trigger starts or restarts the quiet period
+func (db *Debouncer) Trigger() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.gen++
+	gen := db.gen
+	if db.timer != nil {
+		db.timer.Stop()
+	}
+	// A new timer per burst rather than Reset: the callback captures
+	// gen, and a callback that was already started by the previous
+	// timer sees that its generation is stale and does nothing. With
+	// Reset, that racing callback could not tell itself apart from the
+	// rescheduled one.
+	db.timer = time.AfterFunc(db.d, func() { db.fire(gen) })
+}
+
+func (db *Debouncer) fire(gen int) {
+	db.mu.Lock()
+	current := gen == db.gen
+	if current {
+		db.timer = nil
+	}
+	db.mu.Unlock()
+	if current {
+		db.fn()
+	}
+}
+
+// Stop cancels a pending call. It reports whether one was pending. A
+// call that had already started is not interrupted.
+func (db *Debouncer) Stop() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.gen++
+	if db.timer == nil {
+		return false
+	}
+	db.timer.Stop()
+	db.timer = nil
+	return true
+}
@@ -0,0 +1,594 @@
+package timers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Timers, Tickers and the Monotonic Clock - Tests
+// ===============================================
+// Run with:
+//
+//   cd time/timers
+//   go test -v *.go
+//   go test -bench . *.go
+//
+// These tests use real time, in milliseconds, and assert only what a
+// loaded machine cannot break: that something happened at all, or
+// happened no earlier than it should. For tests of code whose timing
+// must be exact, put time behind an interface - see testing/clock.
+//
+// The leak tests compare runtime.NumGoroutine before and after, so
+// none of the tests here are parallel.
+
+// 1. Stop and Reset Since Go 1.23
+// ===============================
+
+func requireSyncTimers(t *testing.T) {
+	t.Helper()
+	if cap(time.NewTimer(time.Hour).C) != 0 {
+		t.Skip("timer channels are buffered: GODEBUG=asynctimerchan=1 or a go line before 1.23")
+	}
+}
+
+func TestStopAfterExpiryLeavesNoStaleValue(t *testing.T) {
+	requireSyncTimers(t)
+	tm := time.NewTimer(time.Millisecond)
+	time.Sleep(20 * time.Millisecond) // fired, nobody received
+
+	// Before Go 1.23 the value sat in C: Stop returned false and a
+	// later receive got it. Now the send is still pending, so Stop
+	// cancels it and reports that it did.
+	if !tm.Stop() {
+		t.Error("Stop on an expired, unreceived timer = false, want true")
+	}
+	select {
+	case v := <-tm.C:
+		t.Errorf("received a stale value %v after Stop", v)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestStopAfterReceiveReportsFalse(t *testing.T) {
+	tm := time.NewTimer(time.Millisecond)
+	<-tm.C
+	if tm.Stop() {
+		t.Error("Stop after the value was received = true, want false")
+	}
+	// The old drain idiom, if !t.Stop() { <-t.C }, would now block
+	// forever: the value it means to drain was received above.
+	select {
+	case <-tm.C:
+		t.Error("a stopped timer sent a second value")
+	default:
+	}
+}
+
+func TestResetAfterExpiryWaitsTheNewDuration(t *testing.T) {
+	requireSyncTimers(t)
+	tm := time.NewTimer(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	const d = 50 * time.Millisecond
+	start := time.Now()
+	tm.Reset(d)
+	<-tm.C
+	// With a buffered channel the stale value would have been
+	// received at once
+	if got := time.Since(start); got < d {
+		t.Errorf("fired %v after Reset(%v), want no earlier", got, d)
+	}
+}
+
+// 2. Sleep and Consume
+// ====================
+
+func TestSleep(t *testing.T) {
+	if err := Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Fatalf("Sleep = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := Sleep(ctx, time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Sleep with a deadline = %v, want DeadlineExceeded", err)
+	}
+	if got := time.Since(start); got > time.Second {
+		t.Errorf("Sleep returned %v after the deadline", got)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	t.Run("closed input", func(t *testing.T) {
+		in := make(chan int, 3)
+		in <- 1
+		in <- 2
+		in <- 3
+		close(in)
+		var sum int
+		if err := Consume(context.Background(), in, time.Second, func(v int) { sum += v }); err != nil {
+			t.Fatalf("Consume = %v", err)
+		}
+		if sum != 6 {
+			t.Errorf("sum = %d, want 6", sum)
+		}
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		in := make(chan int)
+		err := Consume(context.Background(), in, 10*time.Millisecond, func(int) {})
+		if !errors.Is(err, ErrIdle) {
+			t.Fatalf("Consume = %v, want ErrIdle", err)
+		}
+	})
+
+	t.Run("each value restarts the idle period", func(t *testing.T) {
+		const idle = 40 * time.Millisecond
+		in := make(chan int)
+		go func() {
+			// Six values, idle/4 apart: 60ms in all, longer than idle
+			for i := range 6 {
+				time.Sleep(idle / 4)
+				in <- i
+			}
+			close(in)
+		}()
+		var n int
+		if err := Consume(context.Background(), in, idle, func(int) { n++ }); err != nil {
+			t.Fatalf("Consume = %v after %d values, want nil", err, n)
+		}
+		if n != 6 {
+			t.Errorf("consumed %d values, want 6", n)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := Consume(ctx, make(chan int), time.Hour, func(int) {}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Consume = %v, want Canceled", err)
+		}
+	})
+}
+
+// 3. AfterFunc: the Debouncer
+// ===========================
+
+func TestDebouncerCallsOncePerBurst(t *testing.T) {
+	calls := make(chan time.Time, 10)
+	db := NewDebouncer(30*time.Millisecond, func() { calls <- time.Now() })
+
+	var last time.Time
+	for range 5 {
+		db.Trigger()
+		last = time.Now()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case at := <-calls:
+		if quiet := at.Sub(last); quiet < 30*time.Millisecond {
+			t.Errorf("called %v after the last Trigger, want at least 30ms", quiet)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fn was never called")
+	}
+	select {
+	case <-calls:
+		t.Error("fn called twice for one burst")
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	// A new burst after the call gets a call of its own
+	db.Trigger()
+	select {
+	case <-calls:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fn not called for the second burst")
+	}
+}
+
+func TestDebouncerStop(t *testing.T) {
+	var calls atomic.Int32
+	db := NewDebouncer(20*time.Millisecond, func() { calls.Add(1) })
+
+	if db.Stop() {
+		t.Error("Stop with nothing pending = true")
+	}
+	db.Trigger()
+	if !db.Stop() {
+		t.Error("Stop with a call pending = false")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if n := calls.Load(); n != 0 {
+		t.Errorf("fn called %d times after Stop", n)
+	}
+}
+
+// 4. Tickers
+// ==========
+
+func TestTickerDropsTicksForSlowReceivers(t *testing.T) {
+	tk := time.NewTicker(5 * time.Millisecond)
+	defer tk.Stop()
+	time.Sleep(60 * time.Millisecond) // about 12 periods
+
+	pending := 0
+	for {
+		select {
+		case <-tk.C:
+			pending++
+			continue
+		default:
+		}
+		break
+	}
+	if pending > 1 {
+		t.Errorf("%d ticks waiting after 12 periods, want at most 1", pending)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var n atomic.Int32
+	done := make(chan error)
+	go func() {
+		done <- Every(ctx, time.Millisecond, func(time.Time) {
+			if n.Add(1) == 3 {
+				cancel()
+			}
+		})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Every = %v, want Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Every did not return after cancel")
+	}
+}
+
+func TestEveryDoesNotBurstAfterASlowCall(t *testing.T) {
+	const period = 2 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first call takes 25 periods. Were the missed ticks queued,
+	// the next calls would run back to back as soon as it returned; the
+	// ticker kept only one of them.
+	var calls []time.Time
+	var slowEnd time.Time
+	Every(ctx, period, func(now time.Time) {
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			time.Sleep(25 * period)
+			slowEnd = time.Now()
+		}
+		if len(calls) == 6 {
+			cancel()
+		}
+	})
+	burst := 0
+	for _, at := range calls[1:] {
+		if at.Sub(slowEnd) < period/2 {
+			burst++
+		}
+	}
+	if burst > 1 {
+		t.Errorf("%d calls right after the slow one, want at most the 1 kept tick", burst)
+	}
+}
+
+func TestEveryStopsWhenATickIsAlsoReady(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var n int
+	Every(ctx, time.Millisecond, func(time.Time) {
+		n++
+		if n == 1 {
+			cancel()
+			time.Sleep(5 * time.Millisecond) // a tick is waiting now
+		}
+	})
+	if n != 1 {
+		t.Errorf("fn ran %d times, want 1: it ran again after cancel", n)
+	}
+}
+
+func TestPollBacksOff(t *testing.T) {
+	count := func(changed bool) int {
+		ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+		defer cancel()
+		var n int
+		Poll(ctx, time.Millisecond, 16*time.Millisecond, func() (bool, error) {
+			n++
+			return changed, nil
+		})
+		return n
+	}
+	busy, quiet := count(true), count(false)
+	// Quiet: 1+2+4+8ms, then every 16ms - about 12 checks in 150ms.
+	// Busy stays at 1ms, short of 150 only on a loaded machine.
+	if quiet*2 > busy {
+		t.Errorf("quiet polling checked %d times, busy %d; want quiet to back off", quiet, busy)
+	}
+	t.Logf("checks in 150ms: %d busy, %d quiet", busy, quiet)
+
+	boom := errors.New("boom")
+	err := Poll(context.Background(), time.Millisecond, time.Millisecond, func() (bool, error) { return false, boom })
+	if !errors.Is(err, boom) {
+		t.Errorf("Poll = %v, want the check's error", err)
+	}
+}
+
+// 5. The Monotonic Clock
+// ======================
+
+func TestMonotonicReading(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"Now", now, true},
+		{"Add", now.Add(time.Second), true},
+		{"Round(0)", now.Round(0), false},
+		{"UTC", now.UTC(), false},
+		{"Truncate", now.Truncate(time.Second), false},
+		{"Date", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := HasMonotonic(tt.t); got != tt.want {
+			t.Errorf("HasMonotonic(%s) = %v, want %v (%v)", tt.name, got, tt.want, tt.t)
+		}
+	}
+}
+
+func TestEqualVersusDoubleEquals(t *testing.T) {
+	now := time.Now()
+	wall := now.Round(0)
+
+	if now == wall {
+		t.Error("== ignored the monotonic reading")
+	}
+	if !now.Equal(wall) {
+		t.Error("Equal should compare instants")
+	}
+	if now.UTC() == now.In(time.FixedZone("X", 3600)) {
+		t.Error("== ignored the location")
+	}
+
+	// As map keys, the same instant in three forms is three keys
+	// unless the key is normalised
+	raw := map[time.Time]int{now: 1, wall: 2, now.UTC(): 3}
+	if len(raw) != 3 {
+		t.Errorf("raw keys: %d entries, want 3", len(raw))
+	}
+	keyed := map[time.Time]int{MapKey(now): 1, MapKey(wall): 2, MapKey(now.UTC()): 3}
+	if len(keyed) != 1 {
+		t.Errorf("MapKey keys: %d entries, want 1", len(keyed))
+	}
+}
+
+func TestSerialisingDropsTheReading(t *testing.T) {
+	now := time.Now()
+	data, err := json.Marshal(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back time.Time
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if HasMonotonic(back) {
+		t.Error("a Time read from JSON has a monotonic reading")
+	}
+	if !back.Equal(now) {
+		t.Errorf("round trip changed the instant: %v vs %v", back, now)
+	}
+}
+
+func TestSubFallsBackToTheWallClock(t *testing.T) {
+	start := time.Now()
+	end := time.Now()
+	if d := end.Sub(start); d < 0 {
+		t.Fatalf("monotonic Sub = %v, cannot be negative", d)
+	}
+
+	// A wall reading taken after the clock was set back an hour - the
+	// kind of end time that comes from a file or a database. Without a
+	// reading on both sides, Sub compares wall clocks.
+	setBack := end.Round(0).Add(-time.Hour)
+	if d := setBack.Sub(start); d > -59*time.Minute {
+		t.Errorf("wall Sub = %v, want about -1h", d)
+	}
+}
+
+func TestTruncateIsNotCalendarAware(t *testing.T) {
+	newYork := time.FixedZone("EST", -5*3600)
+	evening := time.Date(2024, 3, 1, 21, 30, 0, 0, newYork)
+
+	if got, want := evening.Truncate(24*time.Hour), time.Date(2024, 3, 1, 19, 0, 0, 0, newYork); !got.Equal(want) {
+		t.Errorf("Truncate(24h) = %v, want %v: midnight UTC", got, want)
+	}
+	if got, want := StartOfDay(evening), time.Date(2024, 3, 1, 0, 0, 0, 0, newYork); !got.Equal(want) {
+		t.Errorf("StartOfDay = %v, want %v", got, want)
+	}
+
+	india := time.FixedZone("IST", 5*3600+30*60)
+	if got := time.Date(2024, 3, 1, 10, 45, 0, 0, india).Truncate(time.Hour); got.Minute() != 30 {
+		t.Errorf("Truncate(1h) at +05:30 = %v, want minute 30", got)
+	}
+}
+
+func TestStartOfDayAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	// Clocks went forward at 02:00 on March 10, 2024: a 23-hour day
+	noon := time.Date(2024, 3, 10, 12, 0, 0, 0, loc)
+	start := StartOfDay(noon)
+	next := StartOfDay(noon.AddDate(0, 0, 1))
+	if d := next.Sub(start); d != 23*time.Hour {
+		t.Errorf("day length = %v, want 23h", d)
+	}
+	if start.Hour() != 0 {
+		t.Errorf("StartOfDay = %v, want midnight", start)
+	}
+}
+
+func TestStopwatch(t *testing.T) {
+	var sw Stopwatch
+	sw.Start()
+	time.Sleep(5 * time.Millisecond)
+	first := sw.Lap()
+	time.Sleep(5 * time.Millisecond)
+	second := sw.Lap()
+
+	if first < 5*time.Millisecond || second < 5*time.Millisecond {
+		t.Errorf("laps %v, %v, want each at least 5ms", first, second)
+	}
+	if sum, total := first+second, sw.Elapsed(); sum > total {
+		t.Errorf("laps sum to %v, more than the elapsed %v", sum, total)
+	}
+	sw.Start()
+	if n := len(sw.Laps()); n != 0 {
+		t.Errorf("%d laps after a restart, want 0", n)
+	}
+}
+
+// 6. Leaks, Counted
+// =================
+
+// settle waits for the goroutine count to reach want and returns the
+// last count seen. Goroutines take a moment to start and to exit.
+func settle(want int) int {
+	n := runtime.NumGoroutine()
+	for deadline := time.Now().Add(time.Second); n != want && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	return n
+}
+
+func TestWatchLeakyLeaksAGoroutinePerStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for range 10 {
+		tm := time.NewTimer(time.Hour)
+		WatchLeaky(tm, func() {})
+		tm.Stop()
+	}
+	// Stop does not close C: all ten goroutines are still waiting on
+	// it, and will be until the process exits
+	if leaked := settle(before+10) - before; leaked != 10 {
+		t.Errorf("%d goroutines leaked, want 10", leaked)
+	}
+	t.Logf("goroutines: %d before, %d after stopping 10 watched timers", before, before+10)
+}
+
+func TestWatchStopEndsTheGoroutine(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for range 10 {
+		stop := Watch(time.Hour, func() {})
+		if !stop() {
+			t.Error("stop before firing = false")
+		}
+	}
+	if leaked := settle(before) - before; leaked != 0 {
+		t.Errorf("%d goroutines leaked, want 0", leaked)
+	}
+
+	fired := make(chan struct{})
+	stop := Watch(time.Millisecond, func() { close(fired) })
+	<-fired
+	if stop() {
+		t.Error("stop after firing = true")
+	}
+}
+
+func TestTickerLeaks(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	tk := PollLeaky(time.Millisecond, func() {})
+	tk.Stop()
+	if leaked := settle(before+1) - before; leaked != 1 {
+		t.Errorf("PollLeaky: %d goroutines left after Stop, want 1", leaked)
+	}
+
+	// Every, given a way out, leaves nothing behind
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Every(ctx, time.Millisecond, func(time.Time) {}) }()
+	cancel()
+	<-done
+	if leaked := settle(before+1) - before; leaked != 1 {
+		t.Errorf("Every: %d goroutines left after cancel, want only PollLeaky's 1", leaked)
+	}
+}
+
+// 7. Benchmarks
+// =============
+// time.After allocates a timer per call; a reused timer allocates once.
+// Since Go 1.23 the abandoned timers are collected, but they are still
+// allocated:
+//
+//   go test -bench Loop -benchmem *.go
+
+func BenchmarkAfterInLoop(b *testing.B) {
+	ch := make(chan int, 1)
+	b.ReportAllocs()
+	for b.Loop() {
+		ch <- 1
+		select {
+		case <-ch:
+		case <-time.After(time.Hour):
+		}
+	}
+}
+
+func BenchmarkResetInLoop(b *testing.B) {
+	ch := make(chan int, 1)
+	tm := time.NewTimer(time.Hour)
+	defer tm.Stop()
+	b.ReportAllocs()
+	for b.Loop() {
+		ch <- 1
+		select {
+		case <-ch:
+		case <-tm.C:
+		}
+		tm.Reset(time.Hour)
+	}
+}
+
+// 8. Examples
+// ===========
+
+func ExampleStartOfDay() {
+	newYork := time.FixedZone("EST", -5*3600)
+	evening := time.Date(2024, 3, 1, 21, 30, 0, 0, newYork)
+
+	fmt.Println(evening.Truncate(24 * time.Hour).Format(time.DateTime))
+	fmt.Println(StartOfDay(evening).Format(time.DateTime))
+	// Output:
+	// 2024-03-01 19:00:00
+	// 2024-03-01 00:00:00
+}
+
+func ExampleMapKey() {
+	now := time.Now()
+	fmt.Println(now == now.Round(0), now.Equal(now.Round(0)))
+	fmt.Println(MapKey(now) == MapKey(now.UTC()))
+	// Output:
+	// false true
+	// true
+}
@@ -0,0 +1,115 @@
+package zones
+
+import (
+	"fmt"
+	"time"
+)
+
+// Civil Dates
+// ===========
+// A birthday, a due date, the day a subscription renews: these are
+// dates, not instants. "2024-03-10" is the same date in Tokyo and New
+// York, though it starts fourteen hours apart. Storing it as a
+// time.Time at midnight invites the bugs this file avoids:
+//
+//	midnight UTC, shown in New York     the day before
+//	t.Add(24 * time.Hour) across DST    23:00 or 01:00 on the right day,
+//	                                    or a day off once truncated
+//	t.Sub(u).Hours() / 24               23 or 25 hours on DST days, and
+//	                                    an integer division rounds it
+//
+// Date keeps year, month and day only. Arithmetic goes through
+// time.Date in UTC, where every day is 24 hours and normalisation does
+// the carrying - January 32 is February 1 - and a Date becomes an
+// instant only when a location is given, at the last moment.
+
+// Date is a calendar date with no time of day and no location
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date t falls on in its own location. Convert t
+// with In first to ask "what date is it there".
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{y, m, d}
+}
+
+// ParseDate parses "2006-01-02"
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// utc is the date at midnight UTC, the one place day arithmetic is safe
+func (d Date) utc() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+// IsValid reports whether d names a real day: not February 30
+func (d Date) IsValid() bool {
+	return DateOf(d.utc()) == d
+}
+
+// In returns the first instant of d in loc. That is usually midnight,
+// but not always: where DST starts at midnight, as it has in Santiago
+// and Havana, the day begins at 01:00.
+func (d Date) In(loc *time.Location) time.Time {
+	t, _ := Resolve(d.utc(), loc, Earlier)
+	return t
+}
+
+// AddDays returns d plus n days; n may be negative
+func (d Date) AddDays(n int) Date {
+	return DateOf(d.utc().AddDate(0, 0, n))
+}
+
+// AddMonths returns d plus n months, keeping the day where the month
+// has it and using the month's last day where it does not: January 31
+// plus one month is February 29 in a leap year. time.AddDate instead
+// normalises the overflow, giving March 2.
+func (d Date) AddMonths(n int) Date {
+	first := time.Date(d.Year, d.Month+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return Date{first.Year(), first.Month(), min(d.Day, last)}
+}
+
+// DaysUntil returns the number of days from d to e, negative if e is
+// earlier
+func (d Date) DaysUntil(e Date) int {
+	return int(e.utc().Sub(d.utc()) / (24 * time.Hour))
+}
+
+// Weekday returns the day of the week
+func (d Date) Weekday() time.Weekday {
+	return d.utc().Weekday()
+}
+
+// Before reports whether d is earlier than e
+func (d Date) Before(e Date) bool {
+	return d.utc().Before(e.utc())
+}
+
+// MarshalText and UnmarshalText make Date a "2006-01-02" string in
+// JSON, and usable with flag.TextVar
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(b []byte) error {
+	p, err := ParseDate(string(b))
+	if err != nil {
+		return err
+	}
+	*d = p
+	return nil
+}
@@ -0,0 +1,157 @@
+package zones
+
+import (
+	"fmt"
+	"time"
+)
+
+// Daylight Saving Time: Local Times That Do Not Exist or Exist Twice
+// ==================================================================
+// Twice a year a zone with DST moves its clocks, and the mapping from
+// local time to instant stops being one-to-one:
+//
+//	spring forward   New York, 2024-03-10: 01:59:59 EST is followed by
+//	                 03:00:00 EDT. 02:30 never happens - it is skipped
+//	fall back        New York, 2024-11-03: 01:59:59 EDT is followed by
+//	                 01:00:00 EST. 01:30 happens twice - it is ambiguous
+//
+// Not every zone moves by an hour at 02:00: Lord Howe Island moves by
+// thirty minutes, and some zones have changed their standard offset
+// outright with no DST involved at all.
+//
+// time.Date accepts skipped and ambiguous times and returns some
+// instant. The documentation says only that which one "is not
+// guaranteed", and in practice it differs between zones:
+//
+//	2024-03-10 02:30 America/New_York     -> 01:30 EST, earlier
+//	2024-10-06 02:15 Australia/Lord_Howe  -> 02:45 +11, later
+//
+// Code that schedules by local time - "run at 02:30 every day", "the
+// alarm at 01:30" - has to choose what happens on those days. Resolve
+// makes the choice explicit.
+
+// Kind classifies a local time in a location
+type Kind int
+
+//go:generate go run ../../cmd/genenum/main.go -type Kind -case kebab
+
+const (
+	Normal    Kind = iota // exactly one instant
+	Skipped               // in a spring-forward gap: no instant
+	Ambiguous             // in a fall-back overlap: two instants
+)
+
+// Policy decides which instant Resolve returns when there is not
+// exactly one
+type Policy int
+
+const (
+	// Earlier picks the first of two instants, and for a skipped time
+	// the instant the clocks changed - 03:00 for a 02:30 that never
+	// happened. A job scheduled then runs once, as early as it can.
+	Earlier Policy = iota
+	// Later picks the second of two instants, and for a skipped time
+	// shifts it forward by the gap - 02:30 becomes 03:30, as if the
+	// clocks had not been told
+	Later
+	// Reject makes Resolve return an error
+	Reject
+)
+
+// LocalTimeError is returned by Resolve under Reject
+type LocalTimeError struct {
+	Wall     string // the local time asked for
+	Location string
+	Kind     Kind
+}
+
+func (e *LocalTimeError) Error() string {
+	return fmt.Sprintf("zones: %s is %s in %s", e.Wall, e.Kind, e.Location)
+}
+
+// Candidates returns the instants that show the given wall time in loc
+// - none, one or two, earliest first - and the wall time's Kind.
+//
+// Only the wall clock fields of wall are used, not its location: pass
+// time.Date(..., time.UTC) or a value from ParseInLocation.
+func Candidates(wall time.Time, loc *time.Location) ([]time.Time, Kind) {
+	w := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), time.UTC)
+
+	// The offsets in effect around this wall time: a day either side
+	// is enough to straddle any one transition. Each offset gives a
+	// candidate instant, which is real if loc shows w at that instant.
+	var found []time.Time
+	for _, probe := range []time.Time{w.Add(-24 * time.Hour), w.Add(24 * time.Hour)} {
+		_, offset := probe.In(loc).Zone()
+		instant := w.Add(-time.Duration(offset) * time.Second).In(loc)
+		if !sameWall(instant, w) {
+			continue
+		}
+		if len(found) == 1 && found[0].Equal(instant) {
+			continue
+		}
+		found = append(found, instant)
+	}
+	switch len(found) {
+	case 0:
+		return nil, Skipped
+	case 1:
+		return found, Normal
+	}
+	if found[1].Before(found[0]) {
+		found[0], found[1] = found[1], found[0]
+	}
+	return found, Ambiguous
+}
+
+// Resolve returns the instant for a wall time in loc, choosing by
+// policy when the wall time is skipped or ambiguous
+func Resolve(wall time.Time, loc *time.Location, policy Policy) (time.Time, error) {
+	found, kind := Candidates(wall, loc)
+	if kind != Normal && policy == Reject {
+		return time.Time{}, &LocalTimeError{Wall: wall.Format("2006-01-02 15:04:05"), Location: loc.String(), Kind: kind}
+	}
+	switch kind {
+	case Normal:
+		return found[0], nil
+	case Ambiguous:
+		if policy == Later {
+			return found[1], nil
+		}
+		return found[0], nil
+	}
+
+	// Skipped: read the wall time with the offset from before the gap.
+	// The instant lands after the transition, shifted by the gap's
+	// length: 02:30 with EST's -5h is 07:30 UTC, which is 03:30 EDT.
+	w := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), time.UTC)
+	_, before := w.Add(-24 * time.Hour).In(loc).Zone()
+	later := w.Add(-time.Duration(before) * time.Second).In(loc)
+	if policy == Later {
+		return later, nil
+	}
+	return transitionBefore(later, loc), nil
+}
+
+// transitionBefore returns the instant of the last offset change in
+// loc at or before t, searching back up to a day
+func transitionBefore(t time.Time, loc *time.Location) time.Time {
+	_, offset := t.In(loc).Zone()
+	lo, hi := t.Add(-24*time.Hour), t // offset differs at lo, matches at hi
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		if _, o := mid.In(loc).Zone(); o == offset {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi.Truncate(time.Second).In(loc)
+}
+
+func sameWall(t, w time.Time) bool {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := w.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2 &&
+		t.Hour() == w.Hour() && t.Minute() == w.Minute() && t.Second() == w.Second() && t.Nanosecond() == w.Nanosecond()
+}
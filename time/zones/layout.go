@@ -0,0 +1,108 @@
+package zones
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Time Zones, Formatting and Parsing
+// ==================================
+// Go has no "YYYY-MM-DD". A layout is the reference time written the
+// way you want yours to look:
+//
+//	Mon Jan 2 15:04:05 MST 2006    - in numeric order: 01/02 03:04:05PM '06 -0700
+//
+//	2006 06          year, four or two digits
+//	01 1 Jan January month, padded, unpadded, or by name
+//	02 2 _2          day, zero-padded, unpadded, space-padded
+//	002              day of the year
+//	Mon Monday       weekday
+//	15               hour, 24-hour clock
+//	03 3 PM          hour, 12-hour clock, and AM/PM
+//	04 05            minute, second
+//	.000 .999        fraction: fixed width, or trailing zeros cut
+//	MST              zone abbreviation
+//	-0700 -07:00     offset; Z07:00 prints Z for UTC
+//
+// Any other text is copied literally, so the mistakes are silent:
+//
+//	"2006-02-01"        day and month swapped: formats wrong dates, and
+//	                    parses the 1st to 12th of each month wrongly
+//	"2006-01-02 03:04"  a 12-hour clock without PM: 15:00 prints 03:00
+//	"15:04:05.999"      the width varies with the trailing zeros; use
+//	                    .000 where columns must line up
+//
+// Parsing Rules That Bite
+// =======================
+//   - time.Parse with no zone in the value returns UTC. ParseInLocation
+//     returns the wall time in the location given - which is almost
+//     always what a form field or log line from a known place means.
+//   - A zone abbreviation is looked up in the location (Local for
+//     Parse). If it is not known there, Parse invents a zone with that
+//     name and offset zero: "12:00 PDT" parsed in New York is 12:00 UTC
+//     labelled PDT. Abbreviations are ambiguous anyway - CST is three
+//     different zones - so exchange offsets, not abbreviations.
+//   - time.RFC3339 requires the "T" and a zone, but accepts fractional
+//     seconds: parsing ignores the layout's lack of them. Format with
+//     RFC3339Nano if they must survive a round trip.
+//
+// RFC 3339 with an offset - "2024-03-10T02:30:00-05:00" - names an
+// instant unambiguously. A local time plus a zone name - "2024-03-10
+// 02:30 in America/New_York" - may name no instant or two: see dst.go.
+
+// Layouts are the formats ParseAny tries by default, most specific
+// first
+var Layouts = []string{
+	time.RFC3339Nano, // accepts RFC3339, with or without fractions
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// ParseAny parses value with the first of layouts that accepts it, or
+// with Layouts if none are given. Values without an offset are read as
+// wall time in loc.
+//
+// Trying layouts in turn is convenient for input from people; for data
+// between programs, pick one layout - RFC 3339 - and reject the rest.
+func ParseAny(value string, loc *time.Location, layouts ...string) (time.Time, error) {
+	if len(layouts) == 0 {
+		layouts = Layouts
+	}
+	var errs []error
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			return t, nil
+		}
+		errs = append(errs, err)
+	}
+	return time.Time{}, fmt.Errorf("zones: %q matches none of %d layouts: %w", value, len(layouts), errors.Join(errs...))
+}
+
+// ErrUnknownZone is returned by ParseStrict for a zone abbreviation
+// the location does not use
+var ErrUnknownZone = errors.New("zones: unknown zone abbreviation")
+
+// ParseStrict is time.ParseInLocation that refuses the zero-offset zone
+// Parse invents for an abbreviation it does not recognise. A value
+// whose layout has no abbreviation is never refused.
+func ParseStrict(layout, value string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// A recognised abbreviation gives a time in loc itself; an
+	// unrecognised one, a fixed zone of that name at offset zero.
+	// Numeric offsets also give fixed zones, but never named ones.
+	name, offset := t.Zone()
+	if t.Location() == loc || offset != 0 || name == "" || name == "UTC" || name == "GMT" {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w %q in %s", ErrUnknownZone, name, loc)
+}
@@ -0,0 +1,95 @@
+package zones
+
+import (
+	"sync"
+	"time"
+)
+
+// Locations
+// =========
+// A *time.Location is a zone's whole history: every offset change and
+// every abbreviation since records began, from the IANA database.
+// time.LoadLocation("America/New_York") finds it by searching, in
+// order:
+//
+//	$ZONEINFO                        a directory or a zip file
+//	/usr/share/zoneinfo and others   the system's copy, on Unix
+//	$GOROOT/lib/time/zoneinfo.zip    the toolchain's copy
+//	time/tzdata                      compiled into the binary, if
+//	                                 imported or built with
+//	                                 -tags timetzdata (about 450 KB)
+//
+// A scratch or distroless container usually has none of the first
+// three, and LoadLocation fails at run time. Import _ "time/tzdata" in
+// main - or in the tests, as these do - to carry the database along.
+//
+// Three locations need no database:
+//
+//	time.UTC                   offset zero, always
+//	time.Local                 from $TZ, else /etc/localtime; UTC if
+//	                           neither works. Servers should not rely
+//	                           on it: store and compute in UTC, convert
+//	                           for display
+//	time.FixedZone(name, off)  one offset, no DST - what an RFC 3339
+//	                           value's "-05:00" becomes. It knows the
+//	                           offset of one instant, not the zone
+//
+// LoadLocation reads and parses the zone file on every call. A server
+// converting each request's time to the user's zone should load each
+// zone once.
+
+// Cache loads each location once. The zero value is ready to use and
+// safe for concurrent use.
+type Cache struct {
+	mu   sync.Mutex
+	locs map[string]*time.Location
+}
+
+// Load returns the named location, loading it on first use. Failures
+// are not cached, so a zone database installed later is picked up.
+func (c *Cache) Load(name string) (*time.Location, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if loc, ok := c.locs[name]; ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.locs == nil {
+		c.locs = make(map[string]*time.Location)
+	}
+	c.locs[name] = loc
+	return loc, nil
+}
+
+// WallClock is what a clock on the wall shows somewhere at an instant
+type WallClock struct {
+	Zone   string // the location name, "America/New_York"
+	Time   time.Time
+	Abbrev string // "EST"; "+11" in zones without an abbreviation
+	Offset time.Duration
+}
+
+// WallClocks shows the instant t in each named zone - the "what time is
+// the meeting for everyone" table. t's own location does not matter:
+// it names the same instant in all of them.
+func (c *Cache) WallClocks(t time.Time, names ...string) ([]WallClock, error) {
+	clocks := make([]WallClock, 0, len(names))
+	for _, name := range names {
+		loc, err := c.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		local := t.In(loc)
+		abbrev, offset := local.Zone()
+		clocks = append(clocks, WallClock{
+			Zone:   name,
+			Time:   local,
+			Abbrev: abbrev,
+			Offset: time.Duration(offset) * time.Second,
+		})
+	}
+	return clocks, nil
+}
@@ -0,0 +1,501 @@
+package zones
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	_ "time/tzdata" // the zone database, so results do not depend on the machine
+)
+
+// Time Zones, Formatting and Parsing - Tests
+// ==========================================
+// Run with:
+//
+//   cd time/zones
+//   go test -v *.go
+//
+// The DST tests pin dates in 2024, and the time/tzdata import pins the
+// rules: a zone whose government changes its clocks changes the
+// database, not the past, so these stay true.
+
+var cache Cache
+
+func load(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := cache.Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// wall is a wall clock time with no location attached
+func wall(y int, m time.Month, d, h, min int) time.Time {
+	return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+}
+
+// 1. Layouts
+// ==========
+
+func TestFormat(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 15, 4, 5, 120_000_000, time.FixedZone("", -5*3600))
+	tests := []struct {
+		layout, want string
+	}{
+		{time.RFC3339, "2024-03-05T15:04:05-05:00"},
+		{time.RFC3339Nano, "2024-03-05T15:04:05.12-05:00"},
+		{"2006-01-02 15:04:05.000", "2024-03-05 15:04:05.120"},
+		{"2006-01-02 15:04:05.999", "2024-03-05 15:04:05.12"},
+		{"Jan _2 3:04PM", "Mar  5 3:04PM"},
+		{"Monday 2 January 2006", "Tuesday 5 March 2024"},
+		{"2006-002", "2024-065"},
+		// The swapped layout is valid and wrong
+		{"2006-02-01", "2024-05-03"},
+		// A 12-hour clock without PM loses the afternoon
+		{"15:04 vs 03:04", "15:04 vs 03:04"},
+	}
+	for _, tt := range tests {
+		if got := ts.Format(tt.layout); got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+	if got := ts.UTC().Format(time.RFC3339); got != "2024-03-05T20:04:05Z" {
+		t.Errorf("UTC in RFC3339 = %q, want the Z form", got)
+	}
+}
+
+func TestRFC3339(t *testing.T) {
+	// Parsing accepts fractions the layout does not show...
+	ts, err := time.Parse(time.RFC3339, "2024-07-01T12:00:00.123+05:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Nanosecond() != 123_000_000 {
+		t.Errorf("fraction = %d ns, want 123ms", ts.Nanosecond())
+	}
+	// ...but formatting drops them, so only Nano round-trips
+	if back, _ := time.Parse(time.RFC3339, ts.Format(time.RFC3339)); back.Equal(ts) {
+		t.Error("RFC3339 round trip kept the fraction")
+	}
+	if back, _ := time.Parse(time.RFC3339, ts.Format(time.RFC3339Nano)); !back.Equal(ts) {
+		t.Error("RFC3339Nano round trip lost the fraction")
+	}
+
+	for _, bad := range []string{
+		"2024-07-01 12:00:00Z",  // space, not T
+		"2024-07-01T12:00:00",   // no zone
+		"2024-07-01T12:00:00+5", // short offset
+	} {
+		if _, err := time.Parse(time.RFC3339, bad); err == nil {
+			t.Errorf("Parse(RFC3339, %q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseAny(t *testing.T) {
+	ny := load(t, "America/New_York")
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-07-01T12:00:00Z", time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{"2024-07-01T12:00:00.5+02:00", time.Date(2024, 7, 1, 10, 0, 0, 500_000_000, time.UTC)},
+		// No offset: wall time in New York, EDT in July
+		{"2024-07-01 12:00", time.Date(2024, 7, 1, 16, 0, 0, 0, time.UTC)},
+		{"2024-07-01T12:00:00", time.Date(2024, 7, 1, 16, 0, 0, 0, time.UTC)},
+		{"2024-01-15", time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC)},
+		{"Mon, 01 Jul 2024 12:00:00 +0100", time.Date(2024, 7, 1, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseAny(tt.in, ny)
+		if err != nil {
+			t.Errorf("ParseAny(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseAny(%q) = %v, want %v", tt.in, got.UTC(), tt.want)
+		}
+	}
+
+	if _, err := ParseAny("07/01/2024", ny); err == nil {
+		t.Error("ParseAny accepted a layout it does not know")
+	}
+	if _, err := ParseAny("07/01/2024", ny, "01/02/2006"); err != nil {
+		t.Errorf("ParseAny with a layout given: %v", err)
+	}
+}
+
+func TestParseInLocationVersusParse(t *testing.T) {
+	ny := load(t, "America/New_York")
+	const layout, value = "2006-01-02 15:04", "2024-07-01 12:00"
+
+	utc, _ := time.Parse(layout, value)
+	local, _ := time.ParseInLocation(layout, value, ny)
+	if d := utc.Sub(local); d != -4*time.Hour {
+		t.Errorf("Parse and ParseInLocation differ by %v, want -4h", d)
+	}
+}
+
+func TestZoneAbbreviations(t *testing.T) {
+	ny := load(t, "America/New_York")
+	const layout = "2006-01-02 15:04 MST"
+	tests := []struct {
+		value      string
+		wantUTC    time.Time
+		wantStrict bool
+	}{
+		// Known in New York: its real offset
+		{"2024-07-01 12:00 EDT", time.Date(2024, 7, 1, 16, 0, 0, 0, time.UTC), true},
+		{"2024-01-01 12:00 EST", time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC), true},
+		// Unknown: silently offset zero
+		{"2024-07-01 12:00 PDT", time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC), false},
+		{"2024-07-01 12:00 BST", time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC), false},
+		{"2024-07-01 12:00 UTC", time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		got, err := time.ParseInLocation(layout, tt.value, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tt.wantUTC) {
+			t.Errorf("ParseInLocation(%q) = %v, want %v", tt.value, got.UTC(), tt.wantUTC)
+		}
+		_, err = ParseStrict(layout, tt.value, ny)
+		if ok := err == nil; ok != tt.wantStrict {
+			t.Errorf("ParseStrict(%q) error = %v, want ok %v", tt.value, err, tt.wantStrict)
+		}
+		if err != nil && !errors.Is(err, ErrUnknownZone) {
+			t.Errorf("ParseStrict(%q) = %v, want ErrUnknownZone", tt.value, err)
+		}
+	}
+}
+
+// 2. Locations
+// ============
+
+func TestCache(t *testing.T) {
+	var c Cache
+	a, err := c.Load("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := c.Load("Asia/Tokyo")
+	if a != b {
+		t.Error("second Load returned a different *Location")
+	}
+	if _, err := c.Load("Mars/Olympus_Mons"); err == nil {
+		t.Error("Load of an unknown zone succeeded")
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"Europe/Paris", "Asia/Kolkata", "Europe/Paris", "UTC"} {
+		wg.Go(func() {
+			if _, err := c.Load(name); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestFixedZoneIsNotALocation(t *testing.T) {
+	ny := load(t, "America/New_York")
+	// An RFC 3339 value from New York in winter carries -05:00...
+	winter, _ := time.Parse(time.RFC3339, "2024-01-15T09:00:00-05:00")
+	// ...and the same wall time six months later is a different
+	// instant in New York, but not in the fixed zone
+	summerFixed := winter.AddDate(0, 6, 0)
+	summerNY := winter.In(ny).AddDate(0, 6, 0)
+	if summerFixed.Hour() != 9 || summerNY.Hour() != 9 {
+		t.Fatalf("AddDate should keep the wall time: %v, %v", summerFixed, summerNY)
+	}
+	if d := summerFixed.Sub(summerNY); d != time.Hour {
+		t.Errorf("fixed zone vs New York in July: %v apart, want 1h", d)
+	}
+}
+
+// 3. DST Transitions
+// ==================
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		zone    string
+		wall    time.Time
+		kind    Kind
+		offsets []string // of each candidate, earliest first
+	}{
+		{"America/New_York", wall(2024, 3, 10, 1, 59), Normal, []string{"EST"}},
+		{"America/New_York", wall(2024, 3, 10, 2, 0), Skipped, nil},
+		{"America/New_York", wall(2024, 3, 10, 2, 30), Skipped, nil},
+		{"America/New_York", wall(2024, 3, 10, 3, 0), Normal, []string{"EDT"}},
+		{"America/New_York", wall(2024, 11, 3, 0, 59), Normal, []string{"EDT"}},
+		{"America/New_York", wall(2024, 11, 3, 1, 0), Ambiguous, []string{"EDT", "EST"}},
+		{"America/New_York", wall(2024, 11, 3, 1, 30), Ambiguous, []string{"EDT", "EST"}},
+		{"America/New_York", wall(2024, 11, 3, 2, 0), Normal, []string{"EST"}},
+		{"Europe/London", wall(2024, 3, 31, 1, 30), Skipped, nil},
+		{"Europe/London", wall(2024, 10, 27, 1, 30), Ambiguous, []string{"BST", "GMT"}},
+		// Southern hemisphere: forward in October, back in April
+		{"Australia/Sydney", wall(2024, 10, 6, 2, 30), Skipped, nil},
+		{"Australia/Sydney", wall(2024, 4, 7, 2, 30), Ambiguous, []string{"AEDT", "AEST"}},
+		// A thirty-minute shift
+		{"Australia/Lord_Howe", wall(2024, 10, 6, 2, 15), Skipped, nil},
+		{"Australia/Lord_Howe", wall(2024, 10, 6, 2, 30), Normal, []string{"+11"}},
+		{"Australia/Lord_Howe", wall(2024, 4, 7, 1, 45), Ambiguous, []string{"+11", "+1030"}},
+		// DST starting at midnight: the day has no 00:00
+		{"America/Santiago", wall(2024, 9, 8, 0, 0), Skipped, nil},
+		// No DST at all
+		{"Asia/Kolkata", wall(2024, 3, 10, 2, 30), Normal, []string{"IST"}},
+		{"UTC", wall(2024, 3, 10, 2, 30), Normal, []string{"UTC"}},
+	}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s %s", tt.zone, tt.wall.Format("2006-01-02 15:04"))
+		t.Run(name, func(t *testing.T) {
+			found, kind := Candidates(tt.wall, load(t, tt.zone))
+			if kind != tt.kind {
+				t.Errorf("kind = %v, want %v", kind, tt.kind)
+			}
+			var got []string
+			for _, f := range found {
+				abbrev, _ := f.Zone()
+				got = append(got, abbrev)
+				if f.Format("15:04") != tt.wall.Format("15:04") {
+					t.Errorf("candidate %v does not show %s", f, tt.wall.Format("15:04"))
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.offsets) {
+				t.Errorf("candidates in %v, want %v", got, tt.offsets)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		zone   string
+		wall   time.Time
+		policy Policy
+		want   string // RFC 3339 in the zone
+	}{
+		{"America/New_York", wall(2024, 3, 10, 2, 30), Earlier, "2024-03-10T03:00:00-04:00"},
+		{"America/New_York", wall(2024, 3, 10, 2, 30), Later, "2024-03-10T03:30:00-04:00"},
+		{"America/New_York", wall(2024, 11, 3, 1, 30), Earlier, "2024-11-03T01:30:00-04:00"},
+		{"America/New_York", wall(2024, 11, 3, 1, 30), Later, "2024-11-03T01:30:00-05:00"},
+		{"America/New_York", wall(2024, 7, 1, 9, 0), Later, "2024-07-01T09:00:00-04:00"},
+		{"Australia/Lord_Howe", wall(2024, 10, 6, 2, 15), Earlier, "2024-10-06T02:30:00+11:00"},
+		{"Australia/Lord_Howe", wall(2024, 10, 6, 2, 15), Later, "2024-10-06T02:45:00+11:00"},
+		{"America/Santiago", wall(2024, 9, 8, 0, 0), Earlier, "2024-09-08T01:00:00-03:00"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.wall, load(t, tt.zone), tt.policy)
+		if err != nil {
+			t.Errorf("Resolve(%s %v): %v", tt.zone, tt.wall, err)
+			continue
+		}
+		if s := got.Format(time.RFC3339); s != tt.want {
+			t.Errorf("Resolve(%s %s, %d) = %s, want %s", tt.zone, tt.wall.Format("2006-01-02 15:04"), tt.policy, s, tt.want)
+		}
+	}
+}
+
+func TestResolveReject(t *testing.T) {
+	ny := load(t, "America/New_York")
+	for _, tt := range []struct {
+		wall time.Time
+		kind Kind
+	}{
+		{wall(2024, 3, 10, 2, 30), Skipped},
+		{wall(2024, 11, 3, 1, 30), Ambiguous},
+	} {
+		_, err := Resolve(tt.wall, ny, Reject)
+		var lte *LocalTimeError
+		if !errors.As(err, &lte) || lte.Kind != tt.kind {
+			t.Errorf("Resolve(%v, Reject) = %v, want a %v LocalTimeError", tt.wall, err, tt.kind)
+		}
+	}
+	if _, err := Resolve(wall(2024, 7, 1, 9, 0), ny, Reject); err != nil {
+		t.Errorf("Reject refused a normal time: %v", err)
+	}
+}
+
+// TestTimeDateIsNotConsistent records what time.Date does with the same
+// situations - the reason Resolve exists
+func TestTimeDateIsNotConsistent(t *testing.T) {
+	tests := []struct {
+		zone string
+		wall time.Time
+		want string
+	}{
+		{"America/New_York", wall(2024, 3, 10, 2, 30), "01:30 EST"},
+		{"Australia/Lord_Howe", wall(2024, 10, 6, 2, 15), "02:45 +11"},
+		{"America/New_York", wall(2024, 11, 3, 1, 30), "01:30 EDT"},
+		{"Australia/Lord_Howe", wall(2024, 4, 7, 1, 45), "01:45 +1030"},
+	}
+	for _, tt := range tests {
+		w := tt.wall
+		got := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), 0, 0, load(t, tt.zone))
+		if s := got.Format("15:04 MST"); s != tt.want {
+			t.Errorf("time.Date(%s %s) = %s, want %s", tt.zone, w.Format("2006-01-02 15:04"), s, tt.want)
+		}
+	}
+}
+
+// 4. Civil Dates
+// ==============
+
+func TestAddDayVersusAdd24h(t *testing.T) {
+	ny := load(t, "America/New_York")
+	tests := []struct {
+		from     Date
+		wantHour int // hour of Add(24h) on the next day
+	}{
+		{Date{2024, 3, 10}, 1},  // 23-hour day: 24h later is 01:00
+		{Date{2024, 11, 3}, 23}, // 25-hour day: 24h later is 23:00, the same day
+		{Date{2024, 7, 1}, 0},
+	}
+	for _, tt := range tests {
+		start := tt.from.In(ny)
+		plus24 := start.Add(24 * time.Hour)
+		if plus24.Hour() != tt.wantHour {
+			t.Errorf("%v + 24h = %v, want hour %d", tt.from, plus24, tt.wantHour)
+		}
+		next := tt.from.AddDays(1).In(ny)
+		if next.Hour() != 0 || DateOf(next) != tt.from.AddDays(1) {
+			t.Errorf("%v.AddDays(1).In(ny) = %v, want midnight the next day", tt.from, next)
+		}
+	}
+}
+
+func TestDaysUntil(t *testing.T) {
+	ny := load(t, "America/New_York")
+	a, b := Date{2024, 3, 1}, Date{2024, 3, 15} // DST starts in between
+
+	if got := a.DaysUntil(b); got != 14 {
+		t.Errorf("DaysUntil = %d, want 14", got)
+	}
+	if got := b.DaysUntil(a); got != -14 {
+		t.Errorf("DaysUntil backwards = %d, want -14", got)
+	}
+	// The same from instants in the zone: 13 days and 23 hours
+	naive := int(b.In(ny).Sub(a.In(ny)).Hours() / 24)
+	if naive != 13 {
+		t.Errorf("naive day count = %d; this test expects the off-by-one of 13", naive)
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		from Date
+		n    int
+		want Date
+	}{
+		{Date{2024, 1, 31}, 1, Date{2024, 2, 29}},
+		{Date{2023, 1, 31}, 1, Date{2023, 2, 28}},
+		{Date{2024, 3, 31}, 1, Date{2024, 4, 30}},
+		{Date{2024, 1, 15}, 1, Date{2024, 2, 15}},
+		{Date{2024, 11, 30}, 3, Date{2025, 2, 28}},
+		{Date{2024, 3, 31}, -1, Date{2024, 2, 29}},
+		{Date{2024, 1, 31}, -13, Date{2022, 12, 31}},
+		{Date{2024, 2, 29}, 12, Date{2025, 2, 28}},
+	}
+	for _, tt := range tests {
+		if got := tt.from.AddMonths(tt.n); got != tt.want {
+			t.Errorf("%v.AddMonths(%d) = %v, want %v", tt.from, tt.n, got, tt.want)
+		}
+	}
+	// What AddDate does instead
+	if got := DateOf(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)); got != (Date{2024, 3, 2}) {
+		t.Errorf("AddDate(0, 1, 0) from January 31 = %v, want the overflow to March 2", got)
+	}
+}
+
+func TestDateOfDependsOnTheLocation(t *testing.T) {
+	instant := time.Date(2024, 3, 10, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		zone string
+		want Date
+	}{
+		{"UTC", Date{2024, 3, 10}},
+		{"America/Los_Angeles", Date{2024, 3, 9}},
+		{"Asia/Tokyo", Date{2024, 3, 10}},
+		{"Pacific/Kiritimati", Date{2024, 3, 10}},
+		{"Pacific/Pago_Pago", Date{2024, 3, 9}},
+	}
+	for _, tt := range tests {
+		if got := DateOf(instant.In(load(t, tt.zone))); got != tt.want {
+			t.Errorf("date in %s = %v, want %v", tt.zone, got, tt.want)
+		}
+	}
+}
+
+func TestDateValidityAndText(t *testing.T) {
+	if (Date{2024, 2, 30}).IsValid() {
+		t.Error("February 30 is valid")
+	}
+	if !(Date{2024, 2, 29}).IsValid() {
+		t.Error("February 29, 2024 is not valid")
+	}
+	if got := (Date{2024, 3, 10}).Weekday(); got != time.Sunday {
+		t.Errorf("Weekday = %v, want Sunday", got)
+	}
+
+	type due struct {
+		On Date `json:"on"`
+	}
+	data, err := json.Marshal(due{Date{2024, 3, 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"on":"2024-03-05"}` {
+		t.Errorf("JSON = %s", data)
+	}
+	var back due
+	if err := json.Unmarshal(data, &back); err != nil || back.On != (Date{2024, 3, 5}) {
+		t.Errorf("round trip = %v, %v", back.On, err)
+	}
+	if err := json.Unmarshal([]byte(`{"on":"2024-02-30"}`), &back); err == nil {
+		t.Error("unmarshalled February 30")
+	}
+}
+
+// 5. Examples
+// ===========
+
+func ExampleCache_WallClocks() {
+	var c Cache
+	meeting := time.Date(2024, 3, 12, 16, 0, 0, 0, time.UTC)
+	clocks, err := c.WallClocks(meeting, "America/Los_Angeles", "Europe/Berlin", "Asia/Kolkata", "Australia/Sydney")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, w := range clocks {
+		fmt.Printf("%-20s %s %s\n", w.Zone, w.Time.Format("Mon 15:04"), w.Abbrev)
+	}
+	// Output:
+	// America/Los_Angeles  Tue 09:00 PDT
+	// Europe/Berlin        Tue 17:00 CET
+	// Asia/Kolkata         Tue 21:30 IST
+	// Australia/Sydney     Wed 03:00 AEDT
+}
+
+func ExampleResolve() {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	alarm := time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC) // only the wall clock counts
+	for _, p := range []Policy{Earlier, Later} {
+		t, _ := Resolve(alarm, ny, p)
+		fmt.Println(t.Format("15:04 MST"))
+	}
+	_, err = Resolve(alarm, ny, Reject)
+	fmt.Println(err)
+	// Output:
+	// 03:00 EDT
+	// 03:30 EDT
+	// zones: 2024-03-10 02:30:00 is skipped in America/New_York
+}
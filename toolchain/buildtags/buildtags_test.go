@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Build Tags - Tests
+// ==================
+// Run with:
+//
+//   cd toolchain/buildtags
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// The rules in constraints.go are tested twice: against tables, and
+// against go list on a set of deliberately awkward files for many
+// targets. When a go release changes a rule, the second kind fails.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// 1. Tags and File Names
+// ======================
+
+func TestTargetTag(t *testing.T) {
+	tests := []struct {
+		target Target
+		tag    string
+		want   bool
+	}{
+		{Target{GOOS: "linux", GOARCH: "amd64"}, "linux", true},
+		{Target{GOOS: "linux", GOARCH: "amd64"}, "amd64", true},
+		{Target{GOOS: "linux", GOARCH: "amd64"}, "unix", true},
+		{Target{GOOS: "windows", GOARCH: "amd64"}, "unix", false},
+		{Target{GOOS: "js", GOARCH: "wasm"}, "unix", false},
+		{Target{GOOS: "android", GOARCH: "arm64"}, "linux", true},
+		{Target{GOOS: "ios", GOARCH: "arm64"}, "darwin", true},
+		{Target{GOOS: "illumos", GOARCH: "amd64"}, "solaris", true},
+		{Target{GOOS: "linux", GOARCH: "amd64"}, "android", false},
+		{Target{GOOS: "linux", GOARCH: "amd64", Go: 22}, "go1.21", true},
+		{Target{GOOS: "linux", GOARCH: "amd64", Go: 22}, "go1.22", true},
+		{Target{GOOS: "linux", GOARCH: "amd64", Go: 22}, "go1.23", false},
+		{Target{GOOS: "linux", GOARCH: "amd64", Tags: []string{"demo_unsafe"}}, "demo_unsafe", true},
+		{Target{GOOS: "linux", GOARCH: "amd64"}, "demo_unsafe", false},
+		{Target{GOOS: "linux", GOARCH: "amd64"}, "gc", true},
+	}
+	for _, tt := range tests {
+		if got := tt.target.Tag(tt.tag); got != tt.want {
+			t.Errorf("%v: Tag(%q) = %t, want %t", tt.target, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestNameMatches(t *testing.T) {
+	linux := Target{GOOS: "linux", GOARCH: "arm64"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"main.go", true},
+		{"x_linux.go", true},
+		{"x_windows.go", false},
+		{"x_unix.go", true}, // not a GOOS: no effect
+		{"x_arm64.go", true},
+		{"x_amd64.go", false},
+		{"x_linux_arm64.go", true},
+		{"x_linux_amd64.go", false},
+		{"x_windows_test.go", false},
+		{"x_linux_test.go", true},
+		{"linux.go", true}, // a suffix needs an underscore before it
+		{"x_zos.go", false},
+	}
+	for _, tt := range tests {
+		if got := linux.nameMatches(tt.name); got != tt.want {
+			t.Errorf("%s: %t, want %t", tt.name, got, tt.want)
+		}
+	}
+	if !(Target{GOOS: "android", GOARCH: "arm64"}).nameMatches("x_linux.go") {
+		t.Error("android does not build _linux.go files")
+	}
+}
+
+func TestBuildLine(t *testing.T) {
+	tests := []struct {
+		name, src, want string // want "" means no constraint
+	}{
+		{"plain", "//go:build linux\n\npackage p\n", "linux"},
+		{"expression", "//go:build (linux || darwin) && !cgo\n\npackage p\n", "(linux || darwin) && !cgo"},
+		{"after comments", "// Copyright\n\n//go:build unix\n\npackage p\n", "unix"},
+		{"no blank line", "//go:build ignore\npackage p\n", "ignore"},
+		{"doc comment between", "//go:build unix\n// Package p does things\npackage p\n", "unix"},
+		{"none", "package p\n", ""},
+		{"after package", "package p\n\n//go:build linux\n", ""},
+	}
+	for _, tt := range tests {
+		expr, err := BuildLine(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := ""
+		if expr != nil {
+			got = expr.String()
+		}
+		if got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"//go:build linux &&\n\npackage p\n", "//go:build linux\n//go:build amd64\n\npackage p\n"} {
+		if _, err := BuildLine(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+// 2. Predictions Against the go Command
+// =====================================
+
+// awkward are files whose fate a careless reading gets wrong
+var awkward = map[string]string{
+	"main.go":           "package main\n",
+	"x_unix.go":         "package main\n",
+	"tagged_unix.go":    "//go:build unix\n\npackage main\n",
+	"y_linux.go":        "package main\n",
+	"y_darwin_arm64.go": "package main\n",
+	"y_windows.go":      "package main\n",
+	"y_arm64.go":        "package main\n",
+	"nodoc.go":          "//go:build ignore\npackage main\n",
+	"ignored.go":        "//go:build ignore\n\npackage main\n",
+	"typo.go":           "//go:build linx\n\npackage main\n",
+	"release.go":        "//go:build go1.21\n\npackage main\n",
+	"future.go":         "//go:build go1.999\n\npackage main\n",
+	"custom.go":         "//go:build demo_unsafe && !windows\n\npackage main\n",
+	"both.go":           "// Leading comment\n\n//go:build linux || windows\n\npackage main\n",
+}
+
+func TestPredictionsMatchGoList(t *testing.T) {
+	requireGo(t)
+	dir := t.TempDir()
+	for name, src := range awkward {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/awkward\n\ngo 1.24\n"), 0o644)
+	sb := &Sandbox{Dir: dir}
+
+	goVersion := host().Go
+	for _, target := range []Target{
+		{GOOS: "linux", GOARCH: "amd64"},
+		{GOOS: "linux", GOARCH: "arm64", Tags: []string{"demo_unsafe"}},
+		{GOOS: "android", GOARCH: "arm64"},
+		{GOOS: "darwin", GOARCH: "arm64"},
+		{GOOS: "windows", GOARCH: "arm64", Tags: []string{"demo_unsafe"}},
+		{GOOS: "illumos", GOARCH: "amd64"},
+		{GOOS: "plan9", GOARCH: "386"},
+		{GOOS: "js", GOARCH: "wasm"},
+	} {
+		target.Go = goVersion
+		t.Run(strings.ReplaceAll(target.String(), "/", "_"), func(t *testing.T) {
+			t.Parallel()
+			got, err := sb.Compiled(t.Context(), target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := predict(target, awkward); !slices.Equal(got, want) {
+				t.Errorf("go list: %v\npredicted: %v", got, want)
+			}
+		})
+	}
+}
+
+// 3. The Variants Program
+// =======================
+
+func TestVariantsVetForEveryTarget(t *testing.T) {
+	requireGo(t)
+	sb, err := NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range append(targets, Target{GOOS: "windows", GOARCH: "amd64", Tags: []string{"demo_unsafe"}},
+		Target{GOOS: "wasip1", GOARCH: "wasm"}, Target{GOOS: "freebsd", GOARCH: "amd64"}) {
+		t.Run(strings.ReplaceAll(target.String(), "/", "_"), func(t *testing.T) {
+			t.Parallel()
+			if out, err := sb.Go(t.Context(), target, "vet", "."); err != nil {
+				t.Errorf("%v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func TestVariantsReportWhatWasCompiled(t *testing.T) {
+	requireGo(t)
+	sb, err := NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := sb.Files()
+	for _, target := range []Target{host(), host("demo_unsafe")} {
+		out, code, err := sb.Exec(t.Context(), target)
+		if err != nil || code != 0 {
+			t.Fatalf("%v: exit %d, %v\n%s", target, code, err, out)
+		}
+		// The binary's own list must be the predicted one
+		want := "files:       " + strings.Join(predict(target, files), " ")
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("%v: output lacks %q:\n%s", target, want, out)
+		}
+	}
+}
+
+func TestCrashDemosAreGated(t *testing.T) {
+	requireGo(t)
+	sb, err := NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, code, err := sb.Exec(t.Context(), host(), "nilmap")
+	if err != nil || code != 2 || !strings.Contains(out, "not compiled in") {
+		t.Errorf("without the tag: exit %d, %v\n%s", code, err, out)
+	}
+
+	for demo, want := range map[string]string{
+		"nilmap":    "panic: assignment to entry in nil map",
+		"closed":    "panic: close of closed channel",
+		"deadlock":  "fatal error: all goroutines are asleep - deadlock!",
+		"goroutine": "invalid memory address or nil pointer dereference",
+	} {
+		out, code, err := sb.Exec(t.Context(), host("demo_unsafe"), demo)
+		if err != nil || code != 2 || !strings.Contains(out, want) {
+			t.Errorf("%s: exit %d, %v, want %q in\n%s", demo, code, err, want, out)
+		}
+	}
+}
+
+func TestFileListsIgnoreConstraints(t *testing.T) {
+	requireGo(t)
+	sb, err := NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := sb.Files()
+	out, err := sb.Go(t.Context(), host(), "build", slices.Sorted(maps.Keys(files))...)
+	if err == nil || !strings.Contains(out, "redeclared") {
+		t.Errorf("building the file list: %v\n%s", err, out)
+	}
+}
+
+// 4. Examples
+// ===========
+
+func ExampleTarget_Included() {
+	src := "//go:build unix\n\npackage main\n"
+	for _, t := range []Target{{GOOS: "darwin", GOARCH: "arm64"}, {GOOS: "windows", GOARCH: "amd64"}} {
+		a, _ := t.Included("platform_unix.go", src)
+		b, _ := t.Included("x_unix.go", "package main\n")
+		fmt.Printf("%s: tagged %t, name only %t\n", t, a, b)
+	}
+	// Output:
+	// darwin/arm64: tagged true, name only true
+	// windows/amd64: tagged false, name only true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/build/constraint"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Which Files Are Built
+// =====================
+// The go command decides file by file, from two sources:
+//
+//	the name      x_linux.go, x_arm64.go, x_linux_arm64.go: a known
+//	              GOOS and/or GOARCH before .go (or _test.go) limits the
+//	              file to it. Only known names count: x_unix.go is not a
+//	              platform file, unix is not a GOOS
+//	//go:build    a boolean expression over tags, in the comments
+//	              before the package clause:
+//	                //go:build (linux || darwin) && !demo_unsafe
+//
+// A file is built when both allow it. The tags that are true for a
+// build are:
+//
+//	GOOS, GOARCH          linux, amd64
+//	implied OSes          android -> linux, ios -> darwin,
+//	                      illumos -> solaris
+//	unix                  any Unix-like GOOS; only in //go:build
+//	compiler and cgo      gc, gccgo; cgo when CGO_ENABLED=1
+//	release tags          go1.1 ... the toolchain's version
+//	-tags a,b             whatever the build asks for
+//
+// Tag evaluates these rules itself with go/build/constraint, so the
+// lesson can predict a build for any target without running one; the
+// tests check every prediction against go list.
+//
+// Pitfalls:
+//
+//	go run *.go, go test *.go   listed files are built whatever their
+//	                            tags and names say; only a package
+//	                            (go run .) applies the rules
+//	//go:build after package    just a comment; only the header
+//	                            counts
+//	an unknown tag              is simply false: //go:build linx
+//	                            builds nowhere, silently
+//	x_unix.go without a tag     built everywhere, Windows included
+
+// knownOS and knownArch are the names the go command recognizes in file
+// names - more than it can build for (go/build/syslist.go)
+var (
+	knownOS = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos",
+		"ios", "js", "linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos"}
+	knownArch = []string{"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64",
+		"mips", "mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le",
+		"riscv", "riscv64", "s390", "s390x", "sparc", "sparc64", "wasm"}
+	unixOS = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos",
+		"ios", "linux", "netbsd", "openbsd", "solaris"}
+)
+
+// Target is one build: a platform, a toolchain version and -tags
+type Target struct {
+	GOOS, GOARCH string
+	Go           int // the minor version: 27 for go1.27
+	Tags         []string
+}
+
+func (t Target) String() string {
+	s := t.GOOS + "/" + t.GOARCH
+	if len(t.Tags) > 0 {
+		s += " -tags " + strings.Join(t.Tags, ",")
+	}
+	return s
+}
+
+// Tag reports whether tag is true for the target
+func (t Target) Tag(tag string) bool {
+	switch {
+	case tag == t.GOOS, tag == t.GOARCH, tag == "gc":
+		return true
+	case tag == "unix":
+		return slices.Contains(unixOS, t.GOOS)
+	case tag == "linux":
+		return t.GOOS == "android"
+	case tag == "darwin":
+		return t.GOOS == "ios"
+	case tag == "solaris":
+		return t.GOOS == "illumos"
+	}
+	if v, ok := strings.CutPrefix(tag, "go1."); ok {
+		n, err := strconv.Atoi(v)
+		return err == nil && n <= t.Go
+	}
+	return slices.Contains(t.Tags, tag)
+}
+
+// Included reports whether a file with this name and source is part of
+// the target's build
+func (t Target) Included(name, src string) (bool, error) {
+	if !t.nameMatches(name) {
+		return false, nil
+	}
+	expr, err := BuildLine(src)
+	if err != nil || expr == nil {
+		return err == nil, err
+	}
+	return expr.Eval(t.Tag), nil
+}
+
+// nameMatches applies the _GOOS, _GOARCH and _GOOS_GOARCH suffixes
+func (t Target) nameMatches(name string) bool {
+	name = strings.TrimSuffix(name, ".go")
+	name = strings.TrimSuffix(name, "_test")
+	parts := strings.Split(name, "_")
+	if len(parts) < 2 {
+		return true // a name like "linux.go" is not a suffix
+	}
+	last, prev := parts[len(parts)-1], parts[len(parts)-2]
+	switch {
+	case len(parts) > 2 && slices.Contains(knownOS, prev) && slices.Contains(knownArch, last):
+		return t.Tag(prev) && t.Tag(last)
+	case slices.Contains(knownOS, last), slices.Contains(knownArch, last):
+		return t.Tag(last)
+	}
+	return true
+}
+
+// BuildLine returns the file's //go:build expression, or nil if it has
+// none. Like the go command, it only looks at the comments before the
+// package clause, and a file may have only one.
+func BuildLine(src string) (constraint.Expr, error) {
+	var found constraint.Expr
+	for line := range strings.Lines(src) {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "//"):
+			if !constraint.IsGoBuild(line) {
+				continue
+			}
+			if found != nil {
+				return nil, errors.New("more than one //go:build line")
+			}
+			expr, err := constraint.Parse(line)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", line, err)
+			}
+			found = expr
+		default:
+			return found, nil // the package clause, or anything else
+		}
+	}
+	return found, nil
+}
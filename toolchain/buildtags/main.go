@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Build Tags and Conditional Compilation
+// ======================================
+// Go has no #ifdef. A build chooses whole files instead, by their
+// names and their //go:build lines (constraints.go), and the code in
+// them is ordinary Go. Two shapes cover most uses:
+//
+//	a file per variant     platform_unix.go / platform_windows.go /
+//	                       platform_other.go each define the same
+//	                       symbols; exactly one is built per target
+//	a constant per tag     demos_on.go / demos_off.go define only
+//	                       const demoUnsafe; the code that uses it
+//	                       lives in one file, is type-checked in every
+//	                       build, and is dropped by the compiler when
+//	                       the constant is false
+//
+// The first is for code that cannot compile elsewhere (syscall.Umask
+// has no Windows version). Prefer the second whenever the code does
+// compile everywhere: a file that is left out is a file nobody's build
+// checks.
+//
+// testdata/variants is a small program built both ways. This lesson
+// predicts which of its files each target builds, asks the go command
+// (variants.go), vets every variant, and runs the host's.
+//
+// Custom tags gate what ordinary builds must not contain. Here that is
+// a set of programs that crash on purpose - a nil map write, a double
+// close, a deadlock, a panic in a goroutine - compiled in only with
+// -tags demo_unsafe.
+//
+// Pitfalls:
+//
+//	go run *.go          ignores every constraint: all files are built
+//	                     together and the variants collide (section 5)
+//	a variant missing    a target with no file defining a symbol fails
+//	for some target      to build; go vet with GOOS=... finds it first
+//	tags spelled wrong   are false, not errors
+//	// +build lines      the syntax before Go 1.17; go fix rewrites
+//	                     them as //go:build
+//
+// Run with:
+//
+//	cd toolchain/buildtags
+//	go run constraints.go main.go variants.go
+//	go run constraints.go main.go variants.go -crash deadlock
+//	go test -v *.go
+//
+// Listing the files is safe here: this directory has no variants of its
+// own, only the lesson that builds the ones in testdata. *.go would also
+// pick up buildtags_test.go, which go run refuses.
+
+// targets are the builds the lesson compares
+var targets = []Target{
+	{GOOS: "linux", GOARCH: "amd64"},
+	{GOOS: "darwin", GOARCH: "arm64"},
+	{GOOS: "windows", GOARCH: "amd64"},
+	{GOOS: "plan9", GOARCH: "386"},
+	{GOOS: "js", GOARCH: "wasm"},
+	{GOOS: "linux", GOARCH: "arm64", Tags: []string{"demo_unsafe"}},
+}
+
+func main() {
+	crash := flag.String("crash", "nilmap", "crash demo to run: nilmap, closed, deadlock or goroutine")
+	flag.Parse()
+
+	fmt.Println("=== Build Tags and Conditional Compilation ===")
+	ctx := context.Background()
+	work, err := os.MkdirTemp("", "buildtags-")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(work)
+	sb, err := NewSandbox(work)
+	if err != nil {
+		fail(err)
+	}
+	files, err := sb.Files()
+	if err != nil {
+		fail(err)
+	}
+
+	predictions(files)
+	theGoCommandAgrees(ctx, sb, files)
+	everyVariantVets(ctx, sb)
+	runTheHost(ctx, sb, *crash)
+	fileListsIgnoreConstraints(ctx, sb, files)
+}
+
+// 1. Predicting a Build
+// =====================
+func predictions(files map[string]string) {
+	fmt.Println("\n1. WHICH FILES EACH TARGET BUILDS (predicted from names and //go:build):")
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		expr, _ := BuildLine(files[name])
+		line := "(no //go:build)"
+		if expr != nil {
+			line = "//go:build " + expr.String()
+		}
+		fmt.Printf("   %-20s %s\n", name, line)
+	}
+	fmt.Println()
+	for _, t := range targets {
+		fmt.Printf("   %-32s %s\n", t, strings.Join(predict(t, files), " "))
+	}
+}
+
+// predict lists the files t builds, sorted
+func predict(t Target, files map[string]string) []string {
+	var in []string
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if ok, err := t.Included(name, files[name]); err == nil && ok {
+			in = append(in, name)
+		}
+	}
+	return in
+}
+
+// 2. Asking the go Command
+// ========================
+func theGoCommandAgrees(ctx context.Context, sb *Sandbox, files map[string]string) {
+	fmt.Println("\n2. GO LIST, FOR THE SAME TARGETS:")
+	for _, t := range targets {
+		got, err := sb.Compiled(ctx, t)
+		if err != nil {
+			fmt.Printf("   %-32s error: %v\n", t, err)
+			continue
+		}
+		verdict := "matches the prediction"
+		if !slices.Equal(got, predict(t, files)) {
+			verdict = "DIFFERS from the prediction"
+		}
+		fmt.Printf("   %-32s %s\n", t, verdict)
+	}
+}
+
+// 3. Checking Every Variant
+// =========================
+func everyVariantVets(ctx context.Context, sb *Sandbox) {
+	fmt.Println("\n3. GO VET FOR EACH TARGET (type-checks without building):")
+	for _, t := range targets {
+		status := "ok"
+		if out, err := sb.Go(ctx, t, "vet", "."); err != nil {
+			status = strings.TrimSpace(out)
+		}
+		fmt.Printf("   %-32s %s\n", t, status)
+	}
+}
+
+// 4. The Host's Build, With and Without the Tag
+// =============================================
+func runTheHost(ctx context.Context, sb *Sandbox, crash string) {
+	fmt.Println("\n4. RUNNING THE HOST'S BUILD:")
+	plain, tagged := host(), host("demo_unsafe")
+	for _, run := range []struct {
+		t    Target
+		args []string
+	}{{plain, nil}, {plain, []string{crash}}, {tagged, []string{crash}}} {
+		fmt.Printf("\n   $ ./variants %s    (built for %s)\n", strings.Join(run.args, " "), run.t)
+		out, code, err := sb.Exec(ctx, run.t, run.args...)
+		if err != nil {
+			fmt.Printf("   build failed: %v\n%s", err, out)
+			continue
+		}
+		fmt.Print(indent(firstLines(out, 8)))
+		fmt.Printf("   exit status %d\n", code)
+	}
+}
+
+// 5. The Pitfall: Listing Files
+// =============================
+func fileListsIgnoreConstraints(ctx context.Context, sb *Sandbox, files map[string]string) {
+	fmt.Println("\n5. go build *.go IGNORES CONSTRAINTS:")
+	out, err := sb.Go(ctx, host(), "build", slices.Sorted(maps.Keys(files))...)
+	if err == nil {
+		fmt.Println("   built?! expected the variants to collide")
+		return
+	}
+	fmt.Print(indent(firstLines(out, 4)))
+	fmt.Println("   Every listed file is compiled, so each variant's symbols are defined twice.")
+	fmt.Println("   Only a package path (. or ./...) applies file names and //go:build lines.")
+}
+
+// host is the machine running the lesson, with tags
+func host(tags ...string) Target {
+	minor, _ := strconv.Atoi(strings.Split(strings.TrimPrefix(runtime.Version(), "go1."), ".")[0])
+	return Target{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, Go: minor, Tags: tags}
+}
+
+func firstLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > n {
+		lines = append(lines[:n], "...\n")
+	}
+	return strings.Join(lines, "")
+}
+
+func indent(s string) string {
+	var b strings.Builder
+	for line := range strings.Lines(s) {
+		b.WriteString("   " + line)
+	}
+	return b.String()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,7 @@
+//go:build !demo_unsafe
+
+package main
+
+const demoUnsafe = false
+
+func init() { compiledIn = append(compiledIn, "demos_off.go") }
@@ -0,0 +1,38 @@
+//go:build demo_unsafe
+
+package main
+
+import "time"
+
+// These demos crash the program on purpose. They are behind a tag so
+// that no ordinary build - and no test run over the repository - can
+// reach them by accident.
+
+const demoUnsafe = true
+
+func init() {
+	compiledIn = append(compiledIn, "demos_on.go")
+
+	crashDemos["nilmap"] = func() {
+		var m map[string]int
+		m["boom"] = 1 // panic: assignment to entry in nil map
+	}
+	crashDemos["closed"] = func() {
+		c := make(chan int)
+		close(c)
+		close(c) // panic: close of closed channel
+	}
+	crashDemos["deadlock"] = func() {
+		c := make(chan int)
+		<-c // fatal error: all goroutines are asleep - deadlock!
+	}
+	crashDemos["goroutine"] = func() {
+		// A panic in any goroutine ends the whole program; recover in
+		// main cannot catch it
+		go func() {
+			var p *struct{ n int }
+			p.n++ // panic: invalid memory address or nil pointer dereference
+		}()
+		time.Sleep(time.Second)
+	}
+}
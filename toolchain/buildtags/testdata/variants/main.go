@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Build Variants
+// ==============
+// A program whose files are chosen at build time. Each file adds its
+// name to compiledIn from init, so the binary can say what it was
+// built from:
+//
+//	platform_unix.go      //go:build unix - the name alone is not enough
+//	platform_windows.go   no //go:build: the _windows suffix is the rule
+//	platform_other.go     //go:build !unix && !windows - every target
+//	                      needs one definition of each symbol
+//	demos_on.go           //go:build demo_unsafe - the crash demos
+//	demos_off.go          //go:build !demo_unsafe
+//
+// It lives in testdata, which the go command and learnctl skip, because
+// it only builds as a package: "go run *.go" would compile every file
+// at once and fail on the symbols defined twice. Build it with
+//
+//	go run .                          (inside a module)
+//	go run -tags demo_unsafe . nilmap
+//	GOOS=windows go vet .
+
+// compiledIn lists the files in this build; each adds itself
+var compiledIn = []string{"main.go"}
+
+// crashDemos is filled only when demos_on.go is compiled in
+var crashDemos = map[string]func(){}
+
+func main() {
+	slices.Sort(compiledIn)
+	fmt.Printf("target:      %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("files:       %s\n", strings.Join(compiledIn, " "))
+	fmt.Printf("platform:    %s: %s\n", platform, permissions())
+	fmt.Printf("demo_unsafe: %t\n", demoUnsafe)
+
+	if len(os.Args) < 2 {
+		return
+	}
+	// demoUnsafe is a constant, so without the tag the compiler drops
+	// the demos' call entirely - yet this code is still type-checked
+	// in every build, unlike a file that is left out
+	if !demoUnsafe {
+		fmt.Fprintln(os.Stderr, "the crash demos are not compiled in; build with -tags demo_unsafe")
+		os.Exit(2)
+	}
+	demo, ok := crashDemos[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "no demo %q; have %s\n", os.Args[1], strings.Join(slices.Sorted(maps.Keys(crashDemos)), ", "))
+		os.Exit(2)
+	}
+	fmt.Printf("crashing on purpose: %s\n", os.Args[1])
+	demo()
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package main
+
+// Everything else - plan9, js/wasm, wasip1. Without this file those
+// targets would not build at all: main.go calls permissions.
+
+const platform = "other"
+
+func init() { compiledIn = append(compiledIn, "platform_other.go") }
+
+func permissions() string {
+	return "no Unix permissions on this platform"
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// _unix is not a GOOS, so the name alone would build this file
+// everywhere - and syscall.Umask does not exist on Windows. The
+// //go:build line is what keeps it out.
+
+const platform = "unix"
+
+func init() { compiledIn = append(compiledIn, "platform_unix.go") }
+
+// permissions reports the file mode mask. Umask can only be read by
+// setting it, so it is set back at once.
+func permissions() string {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return fmt.Sprintf("new files get mode 0666 &^ umask %03o", mask)
+}
@@ -0,0 +1,12 @@
+package main
+
+// No //go:build line: the _windows suffix limits this file to
+// GOOS=windows by itself.
+
+const platform = "windows"
+
+func init() { compiledIn = append(compiledIn, "platform_windows.go") }
+
+func permissions() string {
+	return "no umask; access is decided by ACLs, and os.Chmod only sets read-only"
+}
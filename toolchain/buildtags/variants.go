@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Building the Variants
+// =====================
+// testdata/variants only builds as a package, and a package needs a
+// module. Sandbox copies it into a temp dir beside a go.mod and runs
+// the go command there, with the environment pinned as in
+// toolchain/modules/gocmd.go - trimmed here, since lessons cannot
+// import each other.
+//
+//	go list    which files a target would compile, from the go
+//	           command itself - the ground truth for Target.Included
+//	go vet     type-checks for any GOOS/GOARCH without a toolchain for
+//	           it: the cheapest proof that every variant still builds
+//	go build   then run the binary, to see its own report and its
+//	           real exit status when a crash demo is compiled in
+
+// variantsDir is the program this lesson builds
+const variantsDir = "testdata/variants"
+
+// Sandbox is a temp module holding a copy of the variants
+type Sandbox struct {
+	Dir string
+}
+
+// NewSandbox copies variantsDir into dir and adds a go.mod
+func NewSandbox(dir string) (*Sandbox, error) {
+	entries, err := os.ReadDir(variantsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(variantsDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	gomod := "module example.com/variants\n\ngo 1.24\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		return nil, err
+	}
+	return &Sandbox{Dir: dir}, nil
+}
+
+// Files reads the variants' sources, by name
+func (s *Sandbox) Files() (map[string]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.Dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, m := range matches {
+		data, err := os.ReadFile(m)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Base(m)] = string(data)
+	}
+	return files, nil
+}
+
+// Go runs a go subcommand for target t; -tags is added from t.Tags.
+// The output is stdout and stderr together.
+func (s *Sandbox) Go(ctx context.Context, t Target, sub string, args ...string) (string, error) {
+	full := []string{sub}
+	if len(t.Tags) > 0 {
+		full = append(full, "-tags", strings.Join(t.Tags, ","))
+	}
+	cmd := exec.CommandContext(ctx, "go", append(full, args...)...)
+	cmd.Dir = s.Dir
+	cmd.Env = append(os.Environ(), "GOOS="+t.GOOS, "GOARCH="+t.GOARCH,
+		"GOFLAGS=", "GOWORK=off", "GOPROXY=off", "GOTOOLCHAIN=local")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// Compiled asks the go command which files t builds
+func (s *Sandbox) Compiled(ctx context.Context, t Target) ([]string, error) {
+	out, err := s.Go(ctx, t, "list", "-f", `{{join .GoFiles " "}}`, ".")
+	if err != nil {
+		return nil, errors.New(strings.TrimSpace(out))
+	}
+	return strings.Fields(out), nil
+}
+
+// Exec builds the variants for the host with t's tags and runs the
+// binary with args. The exit code is the program's own - go run would
+// report 1 for any failure.
+func (s *Sandbox) Exec(ctx context.Context, t Target, args ...string) (string, int, error) {
+	bin := filepath.Join(s.Dir, "variants.exe")
+	if out, err := s.Go(ctx, t, "build", "-o", bin, "."); err != nil {
+		return out, 0, err
+	}
+	out, err := exec.CommandContext(ctx, bin, args...).CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return string(out), exit.ExitCode(), nil
+	}
+	return string(out), 0, err
+}
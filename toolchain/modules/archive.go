@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Archives
+// ========
+// Every example module here is written as one string in the txtar
+// format, the one the go command's own tests use for the same job:
+//
+//	-- app/go.mod --
+//	module example.com/app
+//	-- app/main.go --
+//	package main
+//	...
+//
+// A line "-- name --" starts a file and everything up to the next such
+// line is its content. Text before the first marker is a comment. It
+// reads like the tree it describes, diffs well, and needs no escaping
+// of backquotes or tabs.
+//
+// Pitfalls:
+//
+//	names from an archive are paths   "../../.bashrc" is a valid name;
+//	                                  filepath.IsLocal rejects it
+//	a marker inside a file            cannot be written; this format has
+//	                                  no escape, so pick other content
+//	the last line                     always ends in "\n" once parsed
+
+// File is one file of an archive
+type File struct {
+	Name string
+	Data string
+}
+
+// ParseArchive splits a txtar archive into its files, in order
+func ParseArchive(archive string) []File {
+	var files []File
+	var cur *File
+	for line := range strings.Lines(archive) {
+		if name, ok := marker(line); ok {
+			files = append(files, File{Name: name})
+			cur = &files[len(files)-1]
+			continue
+		}
+		if cur != nil {
+			cur.Data += line
+		}
+	}
+	if cur != nil && cur.Data != "" && !strings.HasSuffix(cur.Data, "\n") {
+		cur.Data += "\n"
+	}
+	return files
+}
+
+// marker reports whether line is "-- name --", and the name
+func marker(line string) (string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	name, ok := strings.CutPrefix(line, "-- ")
+	if !ok {
+		return "", false
+	}
+	name, ok = strings.CutSuffix(name, " --")
+	name = strings.TrimSpace(name)
+	return name, ok && name != ""
+}
+
+// ErrUnsafePath is returned for a file name that would land outside the
+// target directory
+var ErrUnsafePath = errors.New("path escapes the target directory")
+
+// Scaffold writes files under dir, creating directories as needed.
+// Every name is checked before anything is written.
+func Scaffold(dir string, files []File) error {
+	for _, f := range files {
+		if !filepath.IsLocal(filepath.FromSlash(f.Name)) {
+			return fmt.Errorf("%q: %w", f.Name, ErrUnsafePath)
+		}
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(f.Data), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
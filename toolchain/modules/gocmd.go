@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Running the go Command
+// ======================
+// The examples call the real go command, so its output is the real
+// output - the same errors a learner would meet. For that to be the
+// same on every machine, the environment is pinned:
+//
+//	GOPROXY=off      nothing is downloaded; every dependency is a local
+//	                 directory, so the examples run offline
+//	GOSUMDB=off      nothing to verify against a checksum database
+//	GOWORK=off       no go.work from above the temp dir is picked up,
+//	                 unless a step turns workspaces on itself
+//	GOTOOLCHAIN=local
+//	                 a "go" line newer than this toolchain is an error,
+//	                 not a download of a newer one
+//	GOFLAGS=         the learner's own -mod=... does not change results
+//
+// Everything else is dropped except what the go command needs to find
+// itself and its caches (see process/subprocess Environ).
+
+// passThrough are the variables the go command needs from this process
+var passThrough = []string{"PATH", "HOME", "USERPROFILE", "LOCALAPPDATA", "TMPDIR", "TEMP", "TMP",
+	"GOROOT", "GOPATH", "GOCACHE", "GOMODCACHE", "XDG_CACHE_HOME", "SYSTEMROOT"}
+
+// pinned is the environment every example runs with
+var pinned = []string{"GOPROXY=off", "GOSUMDB=off", "GOWORK=off", "GOTOOLCHAIN=local", "GOFLAGS="}
+
+// Result is what one command printed, and how it ended
+type Result struct {
+	Output   string // stdout and stderr, interleaved as printed
+	ExitCode int
+}
+
+// Runner runs go commands in directories under Work
+type Runner struct {
+	Work string
+	Env  []string // added after the pinned environment; later wins
+}
+
+// Go runs "go args..." in dir, relative to r.Work. A command that ran
+// and failed is a Result with a non-zero ExitCode, not an error: here a
+// failure is often the lesson. The error is for a go that never ran.
+func (r *Runner) Go(ctx context.Context, dir string, args ...string) (Result, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = filepath.Join(r.Work, filepath.FromSlash(dir))
+	cmd.Env = r.environ()
+	out, err := cmd.CombinedOutput()
+	res := Result{Output: r.clean(string(out))}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		res.ExitCode = exit.ExitCode()
+		return res, nil
+	}
+	return res, err
+}
+
+func (r *Runner) environ() []string {
+	var env []string
+	for _, k := range passThrough {
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, k+"="+v)
+		}
+	}
+	return slices.Concat(env, pinned, r.Env)
+}
+
+// clean replaces the temp dir with $WORK, as go build -x does, so the
+// output reads the same on every run
+func (r *Runner) clean(out string) string {
+	if r.Work == "" {
+		return out
+	}
+	out = strings.ReplaceAll(out, r.Work, "$WORK")
+	// macOS reports /var/... temp dirs as /private/var/...
+	return strings.ReplaceAll(out, "/private$WORK", "$WORK")
+}
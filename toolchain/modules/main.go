@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Go Modules and Workspaces
+// =========================
+// A module is a tree of packages with a go.mod at its root. go.mod
+// names the module, the language version, and the minimum version of
+// every other module it needs:
+//
+//	module example.com/app          the import path prefix of every
+//	                                package in this tree
+//	go 1.22                         the language version; it changes
+//	                                semantics, not just the minimum
+//	require example.com/lib v1.3.0  at least v1.3.0 - not exactly
+//	replace example.com/lib => ../lib
+//	                                build from here instead; only the
+//	                                main module's replaces count
+//
+// This lesson does not describe the go command's behaviour; it shows
+// it. main scaffolds each scenario into a temp dir (archive.go), runs
+// the real go command there with a pinned, offline environment
+// (gocmd.go), and prints a transcript. scenarios.go has the trees.
+//
+// Key ideas:
+//
+//	semantic import versioning   v2+ of a module has /v2 in its path,
+//	                             so v1 and v2 are two modules and can
+//	                             both be in one build
+//	minimal version selection    for each module path, the highest of
+//	                             the versions required anywhere - not
+//	                             the newest that exists; builds do not
+//	                             change when someone publishes
+//	go.work                      a local list of modules built together
+//	                             as main modules; it edits no go.mod, so
+//	                             commit it rarely, if ever
+//
+// Pitfalls:
+//
+//	a replace in a dependency's go.mod   ignored; only the main module's
+//	                                     (or go.work's) replaces apply
+//	committing replace => ../lib         breaks everyone without that
+//	                                     directory; use go.work locally
+//	v2.0.0 tag on a path without /v2     refused: "should be v0 or v1"
+//	a go line edited by hand             changes loop variables, range
+//	                                     over int and more - it is not
+//	                                     documentation
+//
+// Run with:
+//
+//	cd toolchain/modules
+//	go run archive.go gocmd.go main.go scenarios.go                 every scenario
+//	go run archive.go gocmd.go main.go scenarios.go -run workspace  one scenario
+//	go run archive.go gocmd.go main.go scenarios.go -keep           leave the temp dirs to explore
+//
+// and test with:
+//
+//	go test -v *.go
+
+func main() {
+	run := flag.String("run", "", "run only the scenarios whose name matches this regexp")
+	keep := flag.Bool("keep", false, "keep the temp directories")
+	flag.Parse()
+
+	match, err := regexp.Compile(*run)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ctx := context.Background()
+	failed := 0
+	for _, s := range scenarios {
+		if !match.MatchString(s.Name) {
+			continue
+		}
+		work, err := os.MkdirTemp("", "modules-"+s.Name+"-")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := s.Run(ctx, os.Stdout, work); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", s.Name, err)
+			failed++
+		}
+		if *keep {
+			fmt.Printf("(kept in %s)\n", work)
+		} else {
+			os.RemoveAll(work)
+		}
+		fmt.Println()
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// Run scaffolds s into work and runs its steps, writing a transcript to
+// w. It stops at the first step whose outcome is not the expected one.
+func (s Scenario) Run(ctx context.Context, w io.Writer, work string) error {
+	fmt.Fprintf(w, "=== %s: %s\n", s.Name, s.Title)
+	if err := Scaffold(work, ParseArchive(s.Files)); err != nil {
+		return err
+	}
+	for i, step := range s.Steps {
+		if step.Note != "" {
+			fmt.Fprintf(w, "\n# %s\n", step.Note)
+		}
+		fmt.Fprintf(w, "%s$ %s\n", prompt(step.Dir), strings.Join(quote(slices.Concat(step.Env, step.Args)), " "))
+
+		res, err := step.run(ctx, &Runner{Work: work, Env: step.Env})
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		fmt.Fprint(w, res.Output)
+		switch {
+		case step.Fail && res.ExitCode == 0:
+			return fmt.Errorf("step %d: succeeded, want a failure", i+1)
+		case !step.Fail && res.ExitCode != 0:
+			return fmt.Errorf("step %d: exit status %d", i+1, res.ExitCode)
+		case !strings.Contains(res.Output, step.Want):
+			return fmt.Errorf("step %d: output does not contain %q", i+1, step.Want)
+		}
+	}
+	return nil
+}
+
+func (step Step) run(ctx context.Context, r *Runner) (Result, error) {
+	switch step.Args[0] {
+	case "go":
+		return r.Go(ctx, step.Dir, step.Args[1:]...)
+	case "cat":
+		data, err := os.ReadFile(filepath.Join(r.Work, step.Dir, step.Args[1]))
+		return Result{Output: string(data)}, err
+	}
+	return Result{}, fmt.Errorf("unknown command %q", step.Args[0])
+}
+
+func prompt(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return dir + " "
+}
+
+// quote shell-quotes the arguments that need it, so the transcript can
+// be pasted into a terminal
+func quote(args []string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " {}$'\"") {
+			a = "'" + a + "'"
+		}
+		out[i] = a
+	}
+	return out
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Go Modules - Tests
+// ==================
+// Run with:
+//
+//   cd toolchain/modules
+//   go test -v *.go
+//   go test -short -v *.go   skip the scenarios, which run the go command
+//
+// The archive and the environment are tested directly. The scenarios
+// run for real, one subtest each, so a go release that changes a rule
+// fails here with the full transcript in the log.
+
+// 1. Archives
+// ===========
+
+func TestParseArchive(t *testing.T) {
+	got := ParseArchive(`a comment, ignored
+-- go.mod --
+module example.com/m
+-- dir/empty.go --
+--   spaced.txt   --
+no final newline`)
+	want := []File{
+		{"go.mod", "module example.com/m\n"},
+		{"dir/empty.go", ""},
+		{"spaced.txt", "no final newline\n"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestParseArchiveNotMarkers(t *testing.T) {
+	for _, line := range []string{"-- --", "--no-spaces--", "-- open", "x -- y --"} {
+		if name, ok := marker(line); ok {
+			t.Errorf("marker(%q) = %q, want none", line, name)
+		}
+	}
+	if files := ParseArchive("no files at all\n"); len(files) != 0 {
+		t.Errorf("got %q", files)
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	dir := t.TempDir()
+	err := Scaffold(dir, []File{{"a/b/c.go", "package b\n"}, {"top.txt", "x\n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "a", "b", "c.go"))
+	if err != nil || string(data) != "package b\n" {
+		t.Errorf("a/b/c.go = %q, %v", data, err)
+	}
+}
+
+func TestScaffoldRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"../outside.go", "/etc/passwd", "a/../../b", ""} {
+		dir := t.TempDir()
+		// The bad name comes second: nothing may be written at all
+		err := Scaffold(dir, []File{{"ok.go", "x"}, {name, "x"}})
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%q: err = %v, want ErrUnsafePath", name, err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%q: wrote %d entries before failing", name, len(entries))
+		}
+	}
+}
+
+// 2. The Environment
+// ==================
+
+func TestRunnerEnvironment(t *testing.T) {
+	t.Setenv("SECRET_TOKEN", "hunter2")
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	r := &Runner{Env: []string{"GOWORK="}}
+	env := r.environ()
+	lookup := func(key string) (string, bool) {
+		for _, kv := range slices.Backward(env) {
+			if k, v, _ := strings.Cut(kv, "="); k == key {
+				return v, true
+			}
+		}
+		return "", false
+	}
+	if _, ok := lookup("SECRET_TOKEN"); ok {
+		t.Error("an unrelated variable was passed through")
+	}
+	if v, _ := lookup("GOFLAGS"); v != "" {
+		t.Errorf("GOFLAGS = %q, want it pinned empty", v)
+	}
+	if v, _ := lookup("GOPROXY"); v != "off" {
+		t.Errorf("GOPROXY = %q", v)
+	}
+	if v, ok := lookup("GOWORK"); !ok || v != "" {
+		t.Errorf("a step's GOWORK= did not override the pinned off: %q", v)
+	}
+}
+
+func TestRunnerCleansPaths(t *testing.T) {
+	r := &Runner{Work: "/tmp/modules-x-123"}
+	got := r.clean("open /tmp/modules-x-123/app/go.mod\nopen /private/tmp/modules-x-123/lib\n")
+	if want := "open $WORK/app/go.mod\nopen $WORK/lib\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	got := strings.Join(quote([]string{"go", "list", "-f", "{{.Path}} {{.Version}}", ""}), " ")
+	if want := `go list -f '{{.Path}} {{.Version}}' ''`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// 3. Scenarios
+// ============
+
+func TestScenarios(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+	names := map[string]bool{}
+	for _, s := range scenarios {
+		if names[s.Name] {
+			t.Fatalf("two scenarios are named %q", s.Name)
+		}
+		names[s.Name] = true
+		t.Run(s.Name, func(t *testing.T) {
+			t.Parallel()
+			var transcript bytes.Buffer
+			if err := s.Run(t.Context(), &transcript, t.TempDir()); err != nil {
+				t.Errorf("%v\n%s", err, transcript.String())
+			}
+		})
+	}
+}
+
+func TestScenarioReportsWrongOutcome(t *testing.T) {
+	s := Scenario{Name: "cat", Files: "-- f.txt --\nhello\n", Steps: []Step{
+		{Args: []string{"cat", "f.txt"}, Want: "goodbye"},
+	}}
+	err := s.Run(t.Context(), new(bytes.Buffer), t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), `"goodbye"`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 4. Examples
+// ===========
+
+func ExampleParseArchive() {
+	for _, f := range ParseArchive("-- go.mod --\nmodule example.com/hello\n-- main.go --\npackage main\n") {
+		fmt.Printf("%s: %q\n", f.Name, f.Data)
+	}
+	// Output:
+	// go.mod: "module example.com/hello\n"
+	// main.go: "package main\n"
+}
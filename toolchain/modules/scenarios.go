@@ -0,0 +1,310 @@
+package main
+
+// Scenarios
+// =========
+// Each scenario is a tree of throwaway modules and the commands to run
+// in it. The Want strings are checked by the tests, so a go release
+// that changes a rule breaks the lesson loudly instead of quietly
+// teaching the old behaviour.
+//
+//	gomod      go.mod, the module path, and what the "go" line changes
+//	versions   semantic import versioning: v2 is a different path
+//	replace    pointing a requirement at a local directory
+//	mvs        minimal version selection across dependencies
+//	workspace  go.work: several modules edited together, no replace
+
+// Scenario is a set of modules and the commands that show one idea
+type Scenario struct {
+	Name  string
+	Title string
+	Files string // a txtar archive, see archive.go
+	Steps []Step
+}
+
+// Step is one command. Args[0] is "go", or "cat" to print a file.
+type Step struct {
+	Note string // printed before the command
+	Dir  string // relative to the scenario's root
+	Args []string
+	Env  []string // on top of the pinned environment
+	Fail bool     // the command is expected to fail
+	Want string   // the output must contain this
+}
+
+var scenarios = []Scenario{
+	{
+		Name:  "gomod",
+		Title: "go.mod: a module is a path, a go version and requirements",
+		Files: `
+-- hello/go.mod --
+module example.com/hello
+
+go 1.22
+-- hello/main.go --
+package main
+
+import "fmt"
+
+func main() {
+	var prints []func()
+	for i := 0; i < 3; i++ {
+		prints = append(prints, func() { fmt.Print(i, " ") })
+	}
+	for _, p := range prints {
+		p()
+	}
+	fmt.Println()
+}
+-- hello/internal/greet/greet.go --
+package greet
+`,
+		Steps: []Step{
+			{Note: "The module path is the prefix of every import path inside it",
+				Dir: "hello", Args: []string{"go", "list", "./..."}, Want: "example.com/hello/internal/greet"},
+			{Dir: "hello", Args: []string{"go", "list", "-m", "-f", "{{.Path}} go {{.GoVersion}}"}, Want: "example.com/hello go 1.22"},
+			{Note: "The go line sets the language version: from 1.22 each iteration has its own i",
+				Dir: "hello", Args: []string{"go", "run", "."}, Want: "0 1 2"},
+			{Dir: "hello", Args: []string{"go", "mod", "edit", "-go=1.21"}},
+			{Note: "The same source under go 1.21 shares one i across the loop",
+				Dir: "hello", Args: []string{"go", "run", "."}, Want: "3 3 3"},
+		},
+	},
+	{
+		Name:  "versions",
+		Title: "Semantic import versioning: a new major version is a new module path",
+		Files: `
+-- greet/go.mod --
+module example.com/greet
+
+go 1.22
+-- greet/greet.go --
+package greet
+
+func Hello(name string) string { return "hello, " + name }
+-- greet-v2/go.mod --
+module example.com/greet/v2
+
+go 1.22
+-- greet-v2/greet.go --
+// Package greet v2 changed Hello's signature: a breaking change, so a
+// new major version
+package greet
+
+func Hello(name string, loud bool) string {
+	if loud {
+		return "HELLO, " + name + "!"
+	}
+	return "hello, " + name
+}
+-- app/go.mod --
+module example.com/app
+
+go 1.22
+
+require (
+	example.com/greet v1.4.0
+	example.com/greet/v2 v2.0.0
+)
+
+replace (
+	example.com/greet v1.4.0 => ../greet
+	example.com/greet/v2 v2.0.0 => ../greet-v2
+)
+-- app/main.go --
+package main
+
+import (
+	"fmt"
+
+	"example.com/greet"
+	greetv2 "example.com/greet/v2"
+)
+
+func main() {
+	fmt.Println(greet.Hello("gopher"))
+	fmt.Println(greetv2.Hello("gopher", true))
+}
+-- wrong/go.mod --
+module example.com/wrong
+
+go 1.22
+
+require example.com/greet v2.0.0
+-- wrong/main.go --
+package main
+
+import _ "example.com/greet"
+
+func main() {}
+`,
+		Steps: []Step{
+			{Note: "v1 and v2 are different paths, so one build can use both",
+				Dir: "app", Args: []string{"go", "run", "."}, Want: "HELLO, gopher!"},
+			{Dir: "app", Args: []string{"go", "list", "-m", "all"}, Want: "example.com/greet/v2 v2.0.0 => ../greet-v2"},
+			{Note: "A v2 version of a path without /v2 is refused before anything is fetched",
+				Dir: "wrong", Args: []string{"go", "build", "."}, Fail: true, Want: "should be v0 or v1, not v2"},
+		},
+	},
+	{
+		Name:  "replace",
+		Title: "Building against a local copy of a dependency",
+		Files: `
+-- lib/go.mod --
+module example.com/lib
+
+go 1.22
+-- lib/lib.go --
+package lib
+
+const Where = "the released lib"
+-- lib-fork/go.mod --
+module example.com/lib
+
+go 1.22
+-- lib-fork/lib.go --
+package lib
+
+const Where = "my fork, with the fix"
+-- app/go.mod --
+module example.com/app
+
+go 1.22
+
+require example.com/lib v1.3.0
+
+replace example.com/lib v1.3.0 => ../lib
+-- app/main.go --
+package main
+
+import (
+	"fmt"
+
+	"example.com/lib"
+)
+
+func main() { fmt.Println("using", lib.Where) }
+`,
+		Steps: []Step{
+			{Note: "A versioned replace applies to that version only",
+				Dir: "app", Args: []string{"go", "run", "."}, Want: "using the released lib"},
+			{Dir: "app", Args: []string{"go", "list", "-m", "-f", "{{.Path}} {{.Version}} => {{.Replace.Path}}", "example.com/lib"},
+				Want: "example.com/lib v1.3.0 => ../lib"},
+			{Note: "Without a version on the left, every version is replaced",
+				Dir: "app", Args: []string{"go", "mod", "edit", "-dropreplace=example.com/lib@v1.3.0", "-replace=example.com/lib=../lib-fork"}},
+			{Dir: "app", Args: []string{"cat", "go.mod"}, Want: "replace example.com/lib => ../lib-fork"},
+			{Dir: "app", Args: []string{"go", "run", "."}, Want: "using my fork, with the fix"},
+		},
+	},
+	{
+		Name:  "mvs",
+		Title: "Minimal version selection: the highest version anyone requires wins",
+		Files: `
+-- a-v1.1/go.mod --
+module example.com/a
+
+go 1.22
+-- a-v1.1/a.go --
+package a
+
+const Version = "v1.1.0"
+-- a-v1.2/go.mod --
+module example.com/a
+
+go 1.22
+-- a-v1.2/a.go --
+package a
+
+const Version = "v1.2.0"
+-- b/go.mod --
+module example.com/b
+
+go 1.22
+
+require example.com/a v1.2.0
+
+// Ignored: only the main module's replace directives apply
+replace example.com/a v1.2.0 => ./nowhere
+-- b/b.go --
+package b
+
+import "example.com/a"
+
+func Sees() string { return a.Version }
+-- app/go.mod --
+module example.com/app
+
+go 1.22
+
+require (
+	example.com/a v1.1.0
+	example.com/b v1.0.0
+)
+
+replace (
+	example.com/a v1.1.0 => ../a-v1.1
+	example.com/a v1.2.0 => ../a-v1.2
+	example.com/b v1.0.0 => ../b
+)
+-- app/main.go --
+package main
+
+import (
+	"fmt"
+
+	"example.com/a"
+	"example.com/b"
+)
+
+func main() { fmt.Println("app sees a", a.Version, "and b sees a", b.Sees()) }
+`,
+		Steps: []Step{
+			{Note: "app asks for a v1.1.0, b for v1.2.0; go.mod must record the selection",
+				Dir: "app", Args: []string{"go", "run", "."}, Fail: true, Want: "go mod tidy"},
+			{Dir: "app", Args: []string{"go", "mod", "tidy"}},
+			{Dir: "app", Args: []string{"cat", "go.mod"}, Want: "example.com/a v1.2.0"},
+			{Note: "One version per module path, for everyone: not the newest released, the newest required",
+				Dir: "app", Args: []string{"go", "run", "."}, Want: "app sees a v1.2.0 and b sees a v1.2.0"},
+			{Dir: "app", Args: []string{"go", "mod", "graph"}, Want: "example.com/b@v1.0.0 example.com/a@v1.2.0"},
+		},
+	},
+	{
+		Name:  "workspace",
+		Title: "go.work: develop several modules together without replace",
+		Files: `
+-- lib/go.mod --
+module example.com/lib
+
+go 1.22
+-- lib/lib.go --
+package lib
+
+func Version() string { return "lib from the workspace" }
+-- app/go.mod --
+module example.com/app
+
+go 1.22
+-- app/main.go --
+package main
+
+import (
+	"fmt"
+
+	"example.com/lib"
+)
+
+func main() { fmt.Println(lib.Version()) }
+`,
+		Steps: []Step{
+			{Note: "On its own, app cannot find lib: nothing requires it and nothing may be downloaded",
+				Dir: "app", Args: []string{"go", "run", "."}, Fail: true, Want: "no required module provides package example.com/lib"},
+			{Note: "GOWORK= (empty) searches upward for a go.work, which is the default",
+				Args: []string{"go", "work", "init", "./app", "./lib"}, Env: []string{"GOWORK="}},
+			{Args: []string{"cat", "go.work"}, Want: "./lib"},
+			{Dir: "app", Args: []string{"go", "run", "."}, Env: []string{"GOWORK="}, Want: "lib from the workspace"},
+			{Note: "Every module in the workspace is a main module",
+				Dir: "app", Args: []string{"go", "list", "-m"}, Env: []string{"GOWORK="}, Want: "example.com/lib"},
+			{Note: "go.work changed nothing in go.mod: outside the workspace, app still cannot find lib",
+				Dir: "app", Args: []string{"go", "run", "."}, Fail: true, Want: "no required module provides package"},
+		},
+	},
+}
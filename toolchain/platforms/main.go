@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Platform Differences
+// ====================
+// One source tree, many platforms - and Go's portability ends where
+// the platform shows through:
+//
+//	int, uint, uintptr   32 or 64 bits with the pointer size; int64 is
+//	                     always 64, but on 386 and arm it is only
+//	                     4-aligned in a struct
+//	untyped constants    var n int = 1 << 40 compiles on amd64 and is a
+//	                     compile error on 386: build for 32-bit targets
+//	                     in CI even if you never ship them
+//	filepath             the host's separator and rules: "\" and C:\ on
+//	                     Windows; path is always "/" - use it for URLs,
+//	                     embed.FS and archive names
+//	os.DevNull, .exe     "/dev/null" vs "NUL"; executables need .exe
+//	os.FileInfo.Sys()    *syscall.Stat_t on Unix, Win32FileAttributeData
+//	                     on Windows: reaching into it needs a file per
+//	                     OS (testdata/probe)
+//	signals              os.Interrupt is portable; syscall.SIGTERM
+//	                     exists on Windows but is never delivered
+//	syscall              frozen and different per OS; new code uses
+//	                     golang.org/x/sys/unix and x/sys/windows
+//
+// Pitfalls:
+//
+//	filepath.Join for a URL       backslashes on Windows; use path.Join
+//	int for file sizes, offsets   overflows at 2 GiB on 32-bit; use int64
+//	atomic on a plain int64 field misaligned on 386 and arm: use
+//	                              atomic.Int64, which is always aligned
+//	case-sensitive names          "Readme.md" and "README.md" are one
+//	                              file on default macOS and Windows
+//	                              volumes
+//	line endings                  Go never translates "\r\n"; a file
+//	                              checked out on Windows may have them
+//
+// Run with:
+//
+//	cd toolchain/platforms
+//	go run main.go probe.go
+//	go test -v *.go
+//
+// and compare binary sizes across targets with tools/xbuild:
+//
+//	go run tools/xbuild/main.go toolchain/platforms/testdata/probe
+
+// probeTargets are tried in order; the host always runs
+var probeTargets = [][2]string{
+	{runtime.GOOS, runtime.GOARCH},
+	{"linux", "386"},
+	{"windows", "386"},
+	{"darwin", "amd64"},
+	{"linux", "arm64"},
+}
+
+// vetTargets are type-checked, which needs no way to run them
+var vetTargets = [][2]string{
+	{"linux", "amd64"}, {"linux", "386"}, {"darwin", "arm64"}, {"windows", "amd64"},
+	{"freebsd", "amd64"}, {"plan9", "amd64"}, {"js", "wasm"}, {"wasip1", "wasm"},
+}
+
+func main() {
+	fmt.Println("=== Platform Differences ===")
+
+	// 1. This machine
+	thisMachine()
+
+	// 2. Paths
+	paths()
+
+	// 3. The probe, built for several targets
+	ctx := context.Background()
+	work, err := os.MkdirTemp("", "platforms-")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(work)
+	p, err := NewProber(work)
+	if err != nil {
+		fail(err)
+	}
+	probeTargetsSideBySide(ctx, p)
+
+	// 4. Every variant type-checks
+	vetEveryTarget(ctx, p)
+}
+
+// 1. This Machine
+// ===============
+func thisMachine() {
+	fmt.Println("\n1. THIS MACHINE (computed in-process, no build tags needed):")
+	f := HostFacts()
+	fmt.Printf("   %s/%s: int is %d bits, pointers %d bytes, int64 fields %d-aligned\n",
+		f.GOOS, f.GOARCH, f.IntSize, f.PointerSize, f.Int64Align)
+	fmt.Printf("   math.MaxInt = %s\n", f.MaxInt)
+	fmt.Printf("   separator %q, list separator %q, null device %q\n", f.Separator, f.ListSeparator, f.DevNull)
+	fmt.Printf("   os.FileInfo.Sys() is %s\n", f.FileSys)
+}
+
+// 2. path and path/filepath
+// =========================
+func paths() {
+	fmt.Println("\n2. PATH vs PATH/FILEPATH:")
+	fmt.Printf("   path.Join(\"static\", \"css\", \"site.css\")     = %q  (always /)\n", path.Join("static", "css", "site.css"))
+	fmt.Printf("   filepath.Join(\"static\", \"css\", \"site.css\") = %q  (the host's separator)\n", filepath.Join("static", "css", "site.css"))
+	fmt.Printf("   filepath.FromSlash(\"a/b\")  = %q\n", filepath.FromSlash("a/b"))
+	fmt.Printf("   filepath.IsAbs(`C:\\x`)      = %t   (only true on Windows)\n", filepath.IsAbs(`C:\x`))
+	fmt.Printf("   filepath.VolumeName(`C:\\x`) = %q   (\"C:\" on Windows)\n", filepath.VolumeName(`C:\x`))
+	fmt.Println("   Store and compare slash paths; convert with FromSlash at the file system edge.")
+}
+
+// 3. The Probe, Side by Side
+// ==========================
+func probeTargetsSideBySide(ctx context.Context, p *Prober) {
+	fmt.Println("\n3. THE PROBE, CROSS-COMPILED AND RUN WHERE POSSIBLE:")
+	var ran []Facts
+	for _, t := range probeTargets {
+		f, err := p.Run(ctx, t[0], t[1])
+		switch {
+		case errors.Is(err, ErrCannotRun):
+			fmt.Printf("   %s/%s: built, but %v\n", t[0], t[1], err)
+		case err != nil:
+			fmt.Printf("   %s/%s: %v\n", t[0], t[1], err)
+		default:
+			ran = append(ran, f)
+		}
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	rows := []struct {
+		name string
+		get  func(Facts) string
+	}{
+		{"target", func(f Facts) string { return f.GOOS + "/" + f.GOARCH }},
+		{"int bits", func(f Facts) string { return strconv.Itoa(f.IntSize) }},
+		{"pointer bytes", func(f Facts) string { return strconv.Itoa(int(f.PointerSize)) }},
+		{"int64 field align", func(f Facts) string { return strconv.Itoa(int(f.Int64Align)) }},
+		{"math.MaxInt", func(f Facts) string { return f.MaxInt }},
+		{"Join(dir, file.txt)", func(f Facts) string { return f.Join }},
+		{"FileInfo.Sys()", func(f Facts) string { return f.FileSys }},
+		{"file detail", func(f Facts) string { return f.FileDetail }},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(w, "   %s", row.name)
+		for _, f := range ran {
+			fmt.Fprintf(w, "\t%s", row.get(f))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	if len(ran) > 0 {
+		fmt.Printf("   signals: %s\n", ran[0].Signals)
+	}
+}
+
+// 4. Type-Checking Every Target
+// =============================
+func vetEveryTarget(ctx context.Context, p *Prober) {
+	fmt.Println("\n4. GO VET FOR TARGETS THAT CANNOT RUN HERE:")
+	for _, t := range vetTargets {
+		status := "ok"
+		if _, err := p.Go(ctx, t[0], t[1], "vet", "."); err != nil {
+			status = err.Error()
+		}
+		fmt.Printf("   %-16s %s\n", t[0]+"/"+t[1], status)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
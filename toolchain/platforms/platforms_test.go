@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Platform Differences - Tests
+// ============================
+// Run with:
+//
+//   cd toolchain/platforms
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// The probe is the lesson's source of truth for other platforms, so the
+// tests first check that it agrees with this process about the host,
+// then that a 32-bit build really is 32-bit where one can run.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// 1. Paths
+// ========
+
+func TestPathIsAlwaysSlash(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{path.Join("static", "css", "site.css"), "static/css/site.css"},
+		{path.Join("a/b", "../c"), "a/c"},
+		{path.Clean(`a\b`), `a\b`}, // a backslash is an ordinary character to path
+		{path.Ext("archive.tar.gz"), ".gz"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestFilepathFollowsTheHost(t *testing.T) {
+	sep := string(filepath.Separator)
+	if got, want := filepath.Join("dir", "file.txt"), "dir"+sep+"file.txt"; got != want {
+		t.Errorf("Join: %q, want %q", got, want)
+	}
+	if got := filepath.ToSlash(filepath.FromSlash("a/b/c")); got != "a/b/c" {
+		t.Errorf("round trip: %q", got)
+	}
+	if got, want := filepath.IsAbs(`C:\Windows`), runtime.GOOS == "windows"; got != want {
+		t.Errorf(`IsAbs(C:\Windows) = %t on %s`, got, runtime.GOOS)
+	}
+}
+
+// 2. The Probe
+// ============
+
+func TestProbeAgreesWithHostFacts(t *testing.T) {
+	requireGo(t)
+	p, err := NewProber(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Run(t.Context(), runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FileDetail == "" || got.Signals == "" {
+		t.Errorf("the OS-specific facts are empty: %+v", got)
+	}
+	// HostFacts leaves out what needs a file per OS
+	got.FileDetail, got.Signals = "", ""
+	if want := HostFacts(); got != want {
+		t.Errorf("probe:\n%+v\nhost:\n%+v", got, want)
+	}
+}
+
+func TestProbe32Bit(t *testing.T) {
+	requireGo(t)
+	p, err := NewProber(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := p.Run(t.Context(), "linux", "386")
+	if errors.Is(err, ErrCannotRun) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.IntSize != 32 || f.PointerSize != 4 || f.Int64Align != 4 || f.MaxInt != "2147483647" {
+		t.Errorf("linux/386: %+v", f)
+	}
+}
+
+func TestProbeVetsForEveryTarget(t *testing.T) {
+	requireGo(t)
+	p, err := NewProber(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range append(vetTargets, [2]string{"windows", "arm64"}, [2]string{"aix", "ppc64"}) {
+		t.Run(target[0]+"_"+target[1], func(t *testing.T) {
+			t.Parallel()
+			if _, err := p.Go(t.Context(), target[0], target[1], "vet", "."); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunReportsBuildErrors(t *testing.T) {
+	requireGo(t)
+	p, err := NewProber(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Run(t.Context(), "linux", "no-such-arch")
+	if err == nil || errors.Is(err, ErrCannotRun) || !strings.Contains(err.Error(), "unsupported GOOS/GOARCH") {
+		t.Errorf("got %v", err)
+	}
+}
+
+// 3. Examples
+// ===========
+
+func ExampleHostFacts() {
+	f := HostFacts()
+	fmt.Println(f.IntSize == 8*int(f.PointerSize))
+	// Output: true
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Running the Probe
+// =================
+// testdata/probe reports the facts of the platform it was built for.
+// A cross-compiled binary usually cannot run here, but some can:
+//
+//	linux/386 on linux/amd64        the kernel runs 32-bit x86 code
+//	windows/386 on windows/amd64    WOW64
+//	darwin/amd64 on darwin/arm64    Rosetta 2
+//
+// so the lesson tries each target and reports the ones that fail with
+// the operating system's own error. The probe builds as a package in a
+// temp module, as in toolchain/buildtags/variants.go.
+
+// Facts is the probe's report; the fields match testdata/probe/main.go
+type Facts struct {
+	GOOS, GOARCH  string
+	IntSize       int
+	PointerSize   uintptr
+	Int64Align    uintptr
+	MaxInt        string
+	Separator     string
+	ListSeparator string
+	DevNull       string
+	Join          string
+	SlashIsAbs    bool
+	DriveIsAbs    bool
+	FileSys       string
+	FileDetail    string
+	Signals       string
+}
+
+// HostFacts computes in this process what the probe reports for the
+// host. Everything here is portable: %T names the Sys() type without
+// importing it. The last two facts need a file per OS, so they are left
+// to the probe.
+func HostFacts() Facts {
+	var s struct {
+		b byte
+		n int64
+	}
+	f := Facts{
+		GOOS:          runtime.GOOS,
+		GOARCH:        runtime.GOARCH,
+		IntSize:       strconv.IntSize,
+		PointerSize:   unsafe.Sizeof(uintptr(0)),
+		Int64Align:    unsafe.Alignof(s.n),
+		MaxInt:        strconv.Itoa(math.MaxInt),
+		Separator:     string(filepath.Separator),
+		ListSeparator: string(filepath.ListSeparator),
+		DevNull:       os.DevNull,
+		Join:          filepath.Join("dir", "file.txt"),
+		SlashIsAbs:    filepath.IsAbs("/etc/hosts"),
+		DriveIsAbs:    filepath.IsAbs(`C:\Windows`),
+	}
+	if exe, err := os.Executable(); err == nil {
+		if fi, err := os.Stat(exe); err == nil {
+			f.FileSys = fmt.Sprintf("%T", fi.Sys())
+		}
+	}
+	return f
+}
+
+// probeDir is the program the lesson cross-compiles
+const probeDir = "testdata/probe"
+
+// Prober builds the probe in a temp module
+type Prober struct {
+	Dir string
+}
+
+// NewProber copies probeDir into dir and adds a go.mod
+func NewProber(dir string) (*Prober, error) {
+	entries, err := os.ReadDir(probeDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(probeDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	gomod := "module example.com/probe\n\ngo 1.24\n"
+	return &Prober{Dir: dir}, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644)
+}
+
+// Go runs a go subcommand with GOOS and GOARCH set. CGO_ENABLED=0
+// keeps every target buildable without a C cross-compiler.
+func (p *Prober) Go(ctx context.Context, goos, goarch string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = p.Dir
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0",
+		"GOFLAGS=", "GOWORK=off", "GOPROXY=off", "GOTOOLCHAIN=local")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("go %s: %w\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out), nil
+}
+
+// ErrCannotRun is returned for a binary this machine cannot execute
+var ErrCannotRun = errors.New("cannot run here")
+
+// Run builds the probe for goos/goarch and runs it
+func (p *Prober) Run(ctx context.Context, goos, goarch string) (Facts, error) {
+	bin := filepath.Join(p.Dir, "probe-"+goos+"-"+goarch)
+	if goos == "windows" {
+		bin += ".exe"
+	}
+	if _, err := p.Go(ctx, goos, goarch, "build", "-o", bin, "."); err != nil {
+		return Facts{}, err
+	}
+	out, err := exec.CommandContext(ctx, bin).Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return Facts{}, fmt.Errorf("probe failed: %w", err)
+		}
+		return Facts{}, fmt.Errorf("%w: %v", ErrCannotRun, err)
+	}
+	var f Facts
+	if err := json.Unmarshal(out, &f); err != nil {
+		return Facts{}, err
+	}
+	return f, nil
+}
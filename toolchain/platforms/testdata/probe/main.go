@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"unsafe"
+)
+
+// Platform Probe
+// ==============
+// Prints, as JSON, the facts of the platform it was compiled for. The
+// platforms lesson cross-compiles it, runs every build this machine can
+// execute, and compares the answers; tools/xbuild builds it for a list
+// of targets to compare sizes.
+//
+// The OS-specific facts come from one of
+//
+//	probe_unix.go      //go:build unix - syscall.Stat_t, POSIX signals
+//	probe_windows.go   file attributes, and no SIGTERM to catch
+//	probe_other.go     //go:build !unix && !windows
+//
+// It only builds as a package (go build .), never from a file list,
+// which is why it lives in testdata.
+
+// Facts is what the probe reports
+type Facts struct {
+	GOOS, GOARCH  string
+	IntSize       int // bits in int and uint
+	PointerSize   uintptr
+	Int64Align    uintptr // alignment of an int64 struct field
+	MaxInt        string
+	Separator     string
+	ListSeparator string
+	DevNull       string
+	Join          string // filepath.Join("dir", "file.txt")
+	SlashIsAbs    bool   // filepath.IsAbs("/etc/hosts")
+	DriveIsAbs    bool   // filepath.IsAbs(`C:\Windows`)
+	FileSys       string // the type behind os.FileInfo.Sys()
+	FileDetail    string
+	Signals       string
+}
+
+func main() {
+	var s struct {
+		b byte
+		n int64
+	}
+	f := Facts{
+		GOOS:          runtime.GOOS,
+		GOARCH:        runtime.GOARCH,
+		IntSize:       strconv.IntSize,
+		PointerSize:   unsafe.Sizeof(uintptr(0)),
+		Int64Align:    unsafe.Alignof(s.n),
+		MaxInt:        strconv.Itoa(math.MaxInt),
+		Separator:     string(filepath.Separator),
+		ListSeparator: string(filepath.ListSeparator),
+		DevNull:       os.DevNull,
+		Join:          filepath.Join("dir", "file.txt"),
+		SlashIsAbs:    filepath.IsAbs("/etc/hosts"),
+		DriveIsAbs:    filepath.IsAbs(`C:\Windows`),
+		Signals:       signals,
+	}
+	f.FileSys, f.FileDetail = fileDetail(os.Args[0])
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		os.Exit(1)
+	}
+}
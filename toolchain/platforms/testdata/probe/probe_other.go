@@ -0,0 +1,18 @@
+//go:build !unix && !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const signals = "none that a program can catch"
+
+func fileDetail(path string) (string, string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err.Error()
+	}
+	return fmt.Sprintf("%T", fi.Sys()), "no Unix or Windows metadata"
+}
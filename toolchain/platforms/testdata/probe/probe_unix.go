@@ -0,0 +1,27 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const signals = "SIGINT, SIGTERM, SIGHUP, SIGUSR1 ... - a process can catch SIGTERM and shut down"
+
+// fileDetail reads fields only Unix has: the inode, the link count and
+// the owner. Stat_t's field types differ between Unix systems too -
+// Nlink is uint64 on linux/amd64 and uint32 on linux/386 - so they are
+// converted before use.
+func fileDetail(path string) (string, string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err.Error()
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Sprintf("%T", fi.Sys()), ""
+	}
+	return fmt.Sprintf("%T", st), fmt.Sprintf("%d link(s), owner uid %d", uint64(st.Nlink), uint64(st.Uid))
+}
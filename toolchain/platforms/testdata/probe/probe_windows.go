@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const signals = "os.Interrupt (Ctrl-C) only; there is no SIGTERM to catch, and Process.Kill cannot be handled"
+
+// fileDetail reads Windows file attributes; there are no inodes or
+// owner ids in what os.Stat returns
+func fileDetail(path string) (string, string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err.Error()
+	}
+	attr, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return fmt.Sprintf("%T", fi.Sys()), ""
+	}
+	return fmt.Sprintf("%T", attr), fmt.Sprintf("attributes %#x", attr.FileAttributes)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Go in the Browser: WebAssembly
+// ==============================
+// GOOS=js GOARCH=wasm compiles an ordinary Go program - runtime,
+// garbage collector, goroutines and all - into one .wasm module that a
+// browser or node can run. syscall/js is the bridge to its host:
+//
+//	js.Global()                 globalThis: window in a browser
+//	v.Get, v.Set, v.Call        property access and method calls
+//	js.FuncOf(f)                a Go func JavaScript can call; runs on
+//	                            a goroutine of the Go program
+//	js.ValueOf(x)               bool, numbers, string, []any and
+//	                            map[string]any become JS values
+//	v.String(), v.Int(), ...    and back
+//
+// testdata/layout is the struct layout visualizer from structs/, moved
+// into the browser: the page calls Go (goLayout), and Go edits the page
+// (addEventListener, innerHTML). This lesson builds it, measures it and
+// calls it from node; learnctl web serves it to a browser.
+//
+// The other WebAssembly port, GOOS=wasip1, targets WASI runtimes
+// (wasmtime, wazero) instead of JavaScript, with files and a clock but
+// no DOM; tools/xbuild builds both.
+//
+// Pitfalls:
+//
+//	main returns                  every js.Func dies with the program:
+//	                              "Go program has already exited"
+//	blocking in a js.FuncOf       the JavaScript event loop waits on it;
+//	callback                      a channel receive there deadlocks.
+//	                              Start a goroutine and return
+//	js.ValueOf(struct)            panics; build a map[string]any
+//	js.FuncOf in a loop           each one is kept until Release
+//	int64 through JavaScript      numbers are float64: exact only up
+//	                              to 2^53
+//	wasm_exec.js from another Go  the glue and the runtime change
+//	version                       together; serve the matching one
+//	binary size                   megabytes, since the runtime comes
+//	                              too; serve it compressed
+//
+// Run with:
+//
+//	cd toolchain/wasm
+//	go run main.go wasm.go
+//	go test -v *.go
+//
+// and in a browser, from the repository root:
+//
+//	go build -o learnctl ./cmd/learnctl/*.go
+//	./learnctl web toolchain/wasm
+
+func main() {
+	fmt.Println("=== Go in the Browser: WebAssembly ===")
+	ctx := context.Background()
+	work, err := os.MkdirTemp("", "wasm-")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(work)
+
+	// 1. Building for js/wasm
+	wasm := filepath.Join(work, "main.wasm")
+	building(ctx, wasm)
+
+	// 2. The glue
+	glue := theGlue(ctx)
+
+	// 3. Calling Go from JavaScript
+	callingGo(ctx, glue, wasm)
+
+	// 4. In a browser
+	fmt.Println("\n4. IN A BROWSER:")
+	fmt.Println("   From the repository root:")
+	fmt.Println("      go build -o learnctl ./cmd/learnctl/*.go")
+	fmt.Println("      ./learnctl web toolchain/wasm")
+	fmt.Println("   builds main.wasm, serves it with index.html and wasm_exec.js, and")
+	fmt.Println("   prints the address. Go draws the layout as you type; the button")
+	fmt.Println("   reorders the fields by alignment.")
+}
+
+// 1. Building for js/wasm
+// =======================
+func building(ctx context.Context, wasm string) {
+	fmt.Println("\n1. GOOS=js GOARCH=wasm go build:")
+	if err := Build(ctx, appDir, wasm); err != nil {
+		fail(err)
+	}
+	data, err := os.ReadFile(wasm)
+	if err != nil {
+		fail(err)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(data)
+	w.Close()
+	fmt.Printf("   main.wasm: %.2f MiB, %.2f MiB gzipped\n", mib(len(data)), mib(gz.Len()))
+	fmt.Printf("   magic %q: a WebAssembly module, version %d\n", data[:4], data[4])
+	fmt.Println("   Most of it is the Go runtime; a server sends it with Content-Encoding: gzip.")
+}
+
+// 2. The Glue
+// ===========
+func theGlue(ctx context.Context) string {
+	fmt.Println("\n2. THE GLUE, FROM THE SAME GOROOT:")
+	glue, err := GlueDir(ctx)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("   %s\n", filepath.Join(glue, "wasm_exec.js"))
+	fmt.Println("   new Go() provides the imports main.wasm expects; go.run(instance) starts main.")
+	return glue
+}
+
+// 3. Calling Go from JavaScript
+// =============================
+func callingGo(ctx context.Context, glue, wasm string) {
+	fmt.Println("\n3. CALLING goLayout FROM NODE:")
+	for _, fields := range []string{"Flag bool\nCount int64\nDone bool", "Count int64\nFlag bool\nDone bool", "Name nope"} {
+		l, err := CallLayout(ctx, glue, wasm, fields)
+		if errors.Is(err, ErrNoNode) {
+			fmt.Println("   skipped:", err)
+			return
+		}
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("\n   goLayout(%q)\n", fields)
+		if l.Error != "" {
+			fmt.Printf("   -> {error: %q}\n", l.Error)
+			continue
+		}
+		fmt.Printf("   -> size %d, align %d, padding %d\n", l.Size, l.Align, l.Padding)
+		for _, s := range l.Segments {
+			fmt.Printf("      %3d  %-16s %-8s %d bytes\n", s.Offset, s.Name, s.Type, s.Size)
+		}
+	}
+}
+
+func mib(n int) float64 {
+	return float64(n) / (1 << 20)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
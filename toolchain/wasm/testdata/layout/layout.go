@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Struct Layout, From Text
+// ========================
+// The layout half of structs/go_layout_visualizer.go, reworked to take
+// its struct from the user instead of from compiled types: each line of
+// the input is "Name type", reflect.StructOf builds the struct at run
+// time, and the compiler's own rules give the offsets. Nothing here
+// touches JavaScript, so it is plain Go; main.go binds it to the page.
+//
+// GOARCH=wasm is a 64-bit architecture - 8-byte int and pointers - so
+// the browser shows the same layout as linux/amd64.
+
+// Field is one line of the input
+type Field struct {
+	Name string
+	Type reflect.Type
+}
+
+// Segment is a field or a run of padding
+type Segment struct {
+	Name    string
+	Type    string
+	Offset  uintptr
+	Size    uintptr
+	Padding bool
+}
+
+// Layout is the memory layout of the struct the fields make
+type Layout struct {
+	Size, Align, Padding uintptr
+	Segments             []Segment
+}
+
+// ParseFields reads "Name type" lines; blank lines and // comments are
+// skipped
+func ParseFields(src string) ([]Field, error) {
+	var fields []Field
+	for i, line := range strings.Split(src, "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		name, typ, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if name == "" {
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: want \"Name type\"", i+1)
+		}
+		t, err := parseType(strings.TrimSpace(typ))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		fields = append(fields, Field{Name: name, Type: t})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields")
+	}
+	return fields, nil
+}
+
+var basicTypes = map[string]reflect.Type{
+	"bool": reflect.TypeFor[bool](), "string": reflect.TypeFor[string](),
+	"int": reflect.TypeFor[int](), "int8": reflect.TypeFor[int8](), "int16": reflect.TypeFor[int16](),
+	"int32": reflect.TypeFor[int32](), "int64": reflect.TypeFor[int64](),
+	"uint": reflect.TypeFor[uint](), "uint8": reflect.TypeFor[uint8](), "uint16": reflect.TypeFor[uint16](),
+	"uint32": reflect.TypeFor[uint32](), "uint64": reflect.TypeFor[uint64](), "uintptr": reflect.TypeFor[uintptr](),
+	"byte": reflect.TypeFor[byte](), "rune": reflect.TypeFor[rune](),
+	"float32": reflect.TypeFor[float32](), "float64": reflect.TypeFor[float64](),
+	"complex64": reflect.TypeFor[complex64](), "complex128": reflect.TypeFor[complex128](),
+	"any": reflect.TypeFor[any](), "interface {}": reflect.TypeFor[any](), "error": reflect.TypeFor[error](),
+}
+
+// parseType understands the basic types and *T, []T, [N]T and
+// map[K]V built from them
+func parseType(s string) (reflect.Type, error) {
+	switch {
+	case basicTypes[s] != nil:
+		return basicTypes[s], nil
+	case strings.HasPrefix(s, "*"):
+		elem, err := parseType(s[1:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+	case strings.HasPrefix(s, "[]"):
+		elem, err := parseType(s[2:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case strings.HasPrefix(s, "["):
+		n, elem, ok := strings.Cut(s[1:], "]")
+		count, err := strconv.Atoi(n)
+		if !ok || err != nil || count < 0 {
+			return nil, fmt.Errorf("bad array type %q", s)
+		}
+		t, err := parseType(elem)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(count, t), nil
+	case strings.HasPrefix(s, "map["):
+		k, v, ok := strings.Cut(s[len("map["):], "]")
+		if !ok {
+			return nil, fmt.Errorf("bad map type %q", s)
+		}
+		kt, err := parseType(k)
+		if err != nil {
+			return nil, err
+		}
+		vt, err := parseType(v)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(kt, vt), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", s)
+}
+
+// ComputeLayout builds the struct and records every field and gap.
+// StructOf needs exported names, so the fields are F0, F1... and the
+// user's names are put back afterwards.
+func ComputeLayout(fields []Field) Layout {
+	sf := make([]reflect.StructField, len(fields))
+	for i, f := range fields {
+		sf[i] = reflect.StructField{Name: "F" + strconv.Itoa(i), Type: f.Type}
+	}
+	t := reflect.StructOf(sf)
+
+	l := Layout{Size: t.Size(), Align: uintptr(t.Align())}
+	var cursor uintptr
+	for i, f := range fields {
+		off := t.Field(i).Offset
+		if off > cursor {
+			l.Segments = append(l.Segments, Segment{Name: "padding", Offset: cursor, Size: off - cursor, Padding: true})
+		}
+		l.Segments = append(l.Segments, Segment{Name: f.Name, Type: f.Type.String(), Offset: off, Size: f.Type.Size()})
+		cursor = off + f.Type.Size()
+	}
+	if t.Size() > cursor {
+		l.Segments = append(l.Segments, Segment{Name: "padding (tail)", Offset: cursor, Size: t.Size() - cursor, Padding: true})
+	}
+	for _, s := range l.Segments {
+		if s.Padding {
+			l.Padding += s.Size
+		}
+	}
+	return l
+}
+
+// Optimize orders fields by alignment, largest first - the rule that
+// removes most padding
+func Optimize(fields []Field) []Field {
+	out := slices.Clone(fields)
+	slices.SortStableFunc(out, func(a, b Field) int { return b.Type.Align() - a.Type.Align() })
+	return out
+}
+
+// Format writes fields back in the input syntax
+func Format(fields []Field) string {
+	var b strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s %s\n", f.Name, f.Type)
+	}
+	return b.String()
+}
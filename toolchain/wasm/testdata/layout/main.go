@@ -0,0 +1,115 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"syscall/js"
+)
+
+// Struct Layout in the Browser
+// ============================
+// Compiled with GOOS=js GOARCH=wasm and loaded by index.html through
+// wasm_exec.js, the glue every Go release ships. The bindings go both
+// ways:
+//
+//	JS -> Go   main registers goLayout on the global object; the page,
+//	           or node, calls it like any JavaScript function
+//	Go -> JS   main finds the page's elements, listens for input and
+//	           writes the diagram into the document itself
+//
+// main must not return: the callbacks live in this program, and they
+// die with it. Without a document (under node) only goLayout is set up.
+
+func main() {
+	goLayout := js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return js.ValueOf(map[string]any{"error": "goLayout(fields: string)"})
+		}
+		return layoutValue(args[0].String())
+	})
+	js.Global().Set("goLayout", goLayout)
+
+	if doc := js.Global().Get("document"); doc.Truthy() {
+		bindPage(doc)
+	}
+	// A callback runs on this program's goroutines, so main blocks
+	// instead of exiting; nothing is ever sent
+	select {}
+}
+
+// layoutValue is goLayout's result. js.ValueOf converts only basic
+// types, []any and map[string]any - a struct must be taken apart.
+func layoutValue(src string) js.Value {
+	fields, err := ParseFields(src)
+	if err != nil {
+		return js.ValueOf(map[string]any{"error": err.Error()})
+	}
+	l := ComputeLayout(fields)
+	segs := make([]any, len(l.Segments))
+	for i, s := range l.Segments {
+		segs[i] = map[string]any{
+			"name": s.Name, "type": s.Type, "offset": int(s.Offset), "size": int(s.Size), "padding": s.Padding,
+		}
+	}
+	return js.ValueOf(map[string]any{
+		"size": int(l.Size), "align": int(l.Align), "padding": int(l.Padding), "segments": segs,
+	})
+}
+
+// bindPage wires the textarea and the button to the diagram. The
+// js.Funcs are never released: they live as long as the page.
+func bindPage(doc js.Value) {
+	input := doc.Call("getElementById", "fields")
+	out := doc.Call("getElementById", "layout")
+	render := func() {
+		fields, err := ParseFields(input.Get("value").String())
+		if err != nil {
+			out.Set("textContent", err.Error())
+			return
+		}
+		out.Set("innerHTML", renderHTML(ComputeLayout(fields)))
+	}
+	input.Call("addEventListener", "input", js.FuncOf(func(js.Value, []js.Value) any {
+		render()
+		return nil
+	}))
+	doc.Call("getElementById", "optimize").Call("addEventListener", "click", js.FuncOf(func(js.Value, []js.Value) any {
+		if fields, err := ParseFields(input.Get("value").String()); err == nil {
+			input.Set("value", Format(Optimize(fields)))
+			render()
+		}
+		return nil
+	}))
+	render()
+	js.Global().Get("console").Call("log", "layout: Go is ready")
+}
+
+// renderHTML draws one cell per byte, eight to a row, colored by field.
+// Names come from the user, so they are escaped like any other input.
+func renderHTML(l Layout) string {
+	palette := []string{"#4e79a7", "#f28e2b", "#59a14f", "#e15759", "#76b7b2", "#edc948", "#b07aa1", "#9c755f"}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<p>size %d, align %d, <strong>padding %d</strong></p><div class=\"bytes\">", l.Size, l.Align, l.Padding)
+	color := 0
+	for _, s := range l.Segments {
+		class, style := "pad", ""
+		if !s.Padding {
+			class, style = "field", "background:"+palette[color%len(palette)]
+			color++
+		}
+		name := html.EscapeString(s.Name)
+		for i := uintptr(0); i < s.Size; i++ {
+			label := ""
+			if i == 0 && !s.Padding {
+				label = name
+			}
+			fmt.Fprintf(&b, "<span class=%q style=%q title=\"%s %s @%d\">%s</span>",
+				class, style, name, html.EscapeString(s.Type), s.Offset+i, label)
+		}
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
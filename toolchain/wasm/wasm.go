@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Building and Calling the WebAssembly Program
+// ============================================
+// testdata/layout only compiles for js/wasm - it imports syscall/js -
+// so it lives where go test *.go does not see it, and is built with
+// GOOS=js GOARCH=wasm from its file list. Running it needs a JavaScript
+// host: a browser (learnctl web), or node with the glue from GOROOT:
+//
+//	$GOROOT/lib/wasm/wasm_exec.js        the Go class: imports, memory,
+//	                                     the syscall/js bridge
+//	$GOROOT/lib/wasm/wasm_exec_node.js   the globals node lacks, then
+//	                                     runs a program to completion
+//
+// Before Go 1.24 both were in misc/wasm. The glue and the compiler
+// change together: always serve the wasm_exec.js of the Go that built
+// the binary.
+
+// appDir is the browser program
+const appDir = "testdata/layout"
+
+// goEnv runs the go command with a pinned environment
+func goEnv(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), append([]string{"GOFLAGS=", "GOWORK=off", "GOTOOLCHAIN=local"}, env...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("go %s: %w\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out), nil
+}
+
+// GlueDir finds wasm_exec.js in the installed Go
+func GlueDir(ctx context.Context) (string, error) {
+	out, err := goEnv(ctx, "", nil, "env", "GOROOT")
+	if err != nil {
+		return "", err
+	}
+	root := strings.TrimSpace(out)
+	for _, dir := range []string{filepath.Join(root, "lib", "wasm"), filepath.Join(root, "misc", "wasm")} {
+		if _, err := os.Stat(filepath.Join(dir, "wasm_exec.js")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no wasm_exec.js under %s", root)
+}
+
+// Build compiles the program in src to out for js/wasm
+func Build(ctx context.Context, src, out string) error {
+	files, err := filepath.Glob(filepath.Join(src, "*.go"))
+	if err != nil || len(files) == 0 {
+		return fmt.Errorf("no Go files in %s", src)
+	}
+	args := []string{"build", "-o", out}
+	for _, f := range files {
+		args = append(args, filepath.Base(f))
+	}
+	_, err = goEnv(ctx, src, []string{"GOOS=js", "GOARCH=wasm"}, args...)
+	return err
+}
+
+// harness loads the program in node the way index.html does in a
+// browser, then calls the function Go registered. The globals are the
+// ones wasm_exec_node.js sets up.
+const harness = `"use strict";
+globalThis.require = require;
+globalThis.fs = require("fs");
+globalThis.path = require("path");
+globalThis.TextEncoder = require("util").TextEncoder;
+globalThis.TextDecoder = require("util").TextDecoder;
+globalThis.performance ??= require("performance");
+globalThis.crypto ??= require("crypto");
+require(process.argv[2]);
+
+const go = new Go();
+WebAssembly.instantiate(fs.readFileSync(process.argv[3]), go.importObject).then(({ instance }) => {
+	go.run(instance); // main blocks; its callbacks stay registered
+	console.log(JSON.stringify(goLayout(process.argv[4])));
+	process.exit(0);
+}).catch((err) => {
+	console.error(err);
+	process.exit(1);
+});
+`
+
+// JSLayout is goLayout's result, as JSON
+type JSLayout struct {
+	Size     int    `json:"size"`
+	Align    int    `json:"align"`
+	Padding  int    `json:"padding"`
+	Error    string `json:"error"`
+	Segments []struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Offset  int    `json:"offset"`
+		Size    int    `json:"size"`
+		Padding bool   `json:"padding"`
+	} `json:"segments"`
+}
+
+// ErrNoNode is returned when node is not installed
+var ErrNoNode = errors.New("node not found in PATH")
+
+// CallLayout runs the wasm binary in node and calls goLayout(fields)
+func CallLayout(ctx context.Context, glue, wasm, fields string) (JSLayout, error) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		return JSLayout{}, ErrNoNode
+	}
+	dir, err := os.MkdirTemp("", "wasm-harness-")
+	if err != nil {
+		return JSLayout{}, err
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "harness.js")
+	if err := os.WriteFile(script, []byte(harness), 0o644); err != nil {
+		return JSLayout{}, err
+	}
+	out, err := exec.CommandContext(ctx, node, script, filepath.Join(glue, "wasm_exec.js"), wasm, fields).Output()
+	if err != nil {
+		return JSLayout{}, fmt.Errorf("node: %w", err)
+	}
+	var l JSLayout
+	return l, json.Unmarshal(out, &l)
+}
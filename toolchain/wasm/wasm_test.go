@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// WebAssembly - Tests
+// ===================
+// Run with:
+//
+//   cd toolchain/wasm
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// The browser program is built for real and called through node, the
+// same JavaScript engine family a browser uses. Its answers are checked
+// against reflect in this process: wasm is 64-bit, so on a 64-bit host
+// every layout must match.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// buildApp builds testdata/layout once per test
+func buildApp(t *testing.T) string {
+	t.Helper()
+	requireGo(t)
+	wasm := filepath.Join(t.TempDir(), "main.wasm")
+	if err := Build(t.Context(), appDir, wasm); err != nil {
+		t.Fatal(err)
+	}
+	return wasm
+}
+
+// 1. Building
+// ===========
+
+func TestBuildProducesAWasmModule(t *testing.T) {
+	wasm := buildApp(t)
+	data, err := os.ReadFile(wasm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("\x00asm\x01\x00\x00\x00")) {
+		t.Errorf("not a version 1 wasm module: % x", data[:8])
+	}
+}
+
+func TestVetForJSWasm(t *testing.T) {
+	requireGo(t)
+	files, _ := filepath.Glob(filepath.Join(appDir, "*.go"))
+	args := []string{"vet"}
+	for _, f := range files {
+		args = append(args, filepath.Base(f))
+	}
+	if _, err := goEnv(t.Context(), appDir, []string{"GOOS=js", "GOARCH=wasm"}, args...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTheHostCannotBuildIt(t *testing.T) {
+	requireGo(t)
+	_, err := goEnv(t.Context(), appDir, nil, "build", "-o", os.DevNull, "main.go", "layout.go")
+	if err == nil || !strings.Contains(err.Error(), "syscall/js") {
+		t.Errorf("a host build should fail on syscall/js: %v", err)
+	}
+}
+
+func TestGlueDir(t *testing.T) {
+	requireGo(t)
+	dir, err := GlueDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "wasm_exec.js"))
+	if err != nil || !bytes.Contains(src, []byte("globalThis.Go")) {
+		t.Errorf("wasm_exec.js in %s does not define Go: %v", dir, err)
+	}
+}
+
+// 2. Calling Go From JavaScript
+// =============================
+
+func callLayout(t *testing.T, wasm, fields string) JSLayout {
+	t.Helper()
+	glue, err := GlueDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := CallLayout(t.Context(), glue, wasm, fields)
+	if errors.Is(err, ErrNoNode) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestGoLayoutMatchesReflect(t *testing.T) {
+	wasm := buildApp(t)
+	if reflect.TypeFor[int]().Size() != 8 {
+		t.Skip("wasm is 64-bit; this host is not")
+	}
+	tests := []struct {
+		fields string
+		host   reflect.Type
+	}{
+		{"A bool\nB int32\nC int64\nD bool", reflect.TypeFor[struct {
+			A bool
+			B int32
+			C int64
+			D bool
+		}]()},
+		{"C int64\nB int32\nA bool\nD bool", reflect.TypeFor[struct {
+			C int64
+			B int32
+			A bool
+			D bool
+		}]()},
+		{"Flag bool\nName string\nTags []string\nNext *int\nM map[string]int\nErr error", reflect.TypeFor[struct {
+			Flag bool
+			Name string
+			Tags []string
+			Next *int
+			M    map[string]int
+			Err  error
+		}]()},
+		{"B [3]byte\nX complex128 // a comment\n\nR rune", reflect.TypeFor[struct {
+			B [3]byte
+			X complex128
+			R rune
+		}]()},
+	}
+	for _, tt := range tests {
+		l := callLayout(t, wasm, tt.fields)
+		if l.Error != "" {
+			t.Errorf("%q: %s", tt.fields, l.Error)
+			continue
+		}
+		if l.Size != int(tt.host.Size()) || l.Align != tt.host.Align() {
+			t.Errorf("%q: size %d align %d, host %d %d", tt.fields, l.Size, l.Align, tt.host.Size(), tt.host.Align())
+		}
+		var fields []string
+		for _, s := range l.Segments {
+			if s.Padding {
+				continue
+			}
+			f, ok := tt.host.FieldByName(s.Name)
+			if !ok || s.Offset != int(f.Offset) || s.Size != int(f.Type.Size()) {
+				t.Errorf("%q: %s at %d, %d bytes; host %+v", tt.fields, s.Name, s.Offset, s.Size, f)
+			}
+			fields = append(fields, s.Name)
+		}
+		if len(fields) != tt.host.NumField() {
+			t.Errorf("%q: fields %v", tt.fields, fields)
+		}
+	}
+}
+
+func TestGoLayoutErrors(t *testing.T) {
+	wasm := buildApp(t)
+	for fields, want := range map[string]string{
+		"A nope":        `line 1: unknown type "nope"`,
+		"A int\nB":      `line 2: want "Name type"`,
+		"":              "no fields",
+		"A [x]int":      `line 1: bad array type "[x]int"`,
+		"A map[string":  `line 1: bad map type "map[string"`,
+		"// just notes": "no fields",
+	} {
+		if l := callLayout(t, wasm, fields); l.Error != want {
+			t.Errorf("%q: error %q, want %q", fields, l.Error, want)
+		}
+	}
+}
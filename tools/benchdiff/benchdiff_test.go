@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// benchdiff - Tests
+// =================
+// Run with:
+//
+//   cd tools/benchdiff
+//   go test -v *.go
+//
+// No test runs a benchmark: the statistics are checked against values
+// worked out by hand, and the comparison on made-up samples.
+
+const output = `goos: linux
+goarch: amd64
+cpu: Intel(R) Xeon(R) CPU @ 2.20GHz
+BenchmarkConcat/parts=2/+=-8         	 1000000	        40.0 ns/op	       8 B/op	       1 allocs/op
+BenchmarkConcat/parts=2/+=-8         	 1000000	        42.0 ns/op	       8 B/op	       1 allocs/op
+    concat_test.go:12: a log line from the benchmark
+BenchmarkCopy-8   	     500	   2000000 ns/op	 524.29 MB/s
+PASS
+ok  	command-line-arguments	3.2s
+`
+
+// 1. Reading go test Output
+// =========================
+
+func TestParse(t *testing.T) {
+	run, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Machine != "linux-amd64-intel-xeon-cpu-2-20ghz" {
+		t.Errorf("machine %q", run.Machine)
+	}
+	if len(run.Benchmarks) != 2 {
+		t.Fatalf("got %+v", run.Benchmarks)
+	}
+	concat := run.Benchmarks[0]
+	if concat.Name != "Concat/parts=2/+=-8" || !slices.Equal(concat.Samples["ns/op"], []float64{40, 42}) ||
+		!slices.Equal(concat.Samples["allocs/op"], []float64{1, 1}) {
+		t.Errorf("got %+v", concat)
+	}
+	if got := run.Benchmarks[1].Samples["MB/s"]; !slices.Equal(got, []float64{524.29}) {
+		t.Errorf("MB/s %v", got)
+	}
+
+	if _, err := Parse(strings.NewReader("PASS\n")); err == nil {
+		t.Error("output with no benchmarks parsed")
+	}
+}
+
+// TestParseSharedFixture checks Parse against the fixture and result
+// that tools/benchreport's test checks its copy of Parse against, so the
+// two parsers cannot drift apart unnoticed
+func TestParseSharedFixture(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "testdata", "gotest-bench.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	run, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "gotest-bench.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want struct {
+		CPU        string
+		Benchmarks []Benchmark
+	}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if run.CPU != want.CPU {
+		t.Errorf("CPU = %q, want %q", run.CPU, want.CPU)
+	}
+	if !reflect.DeepEqual(run.Benchmarks, want.Benchmarks) {
+		t.Errorf("Benchmarks =\n%+v\nwant\n%+v", run.Benchmarks, want.Benchmarks)
+	}
+}
+
+func TestMachineName(t *testing.T) {
+	for _, tt := range [][4]string{
+		{"darwin", "arm64", "Apple M2 Pro", "darwin-arm64-apple-m2-pro"},
+		{"linux", "amd64", "AMD EPYC 7B13 64-Core Processor", "linux-amd64-amd-epyc-7b13-64-core-processor"},
+		{"", "", "", "unknown"},
+	} {
+		if got := MachineName(tt[0], tt[1], tt[2]); got != tt[3] {
+			t.Errorf("MachineName(%q, %q, %q) = %q, want %q", tt[0], tt[1], tt[2], got, tt[3])
+		}
+	}
+}
+
+func TestLessonName(t *testing.T) {
+	root := t.TempDir()
+	if got, err := lessonName(root, filepath.Join(root, "concurrency", "maps")); err != nil || got != "concurrency/maps" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	for _, bad := range []string{root, filepath.Dir(root)} {
+		if _, err := lessonName(root, bad); err == nil {
+			t.Errorf("%s is a lesson", bad)
+		}
+	}
+}
+
+// 2. Statistics
+// =============
+
+func TestMannWhitney(t *testing.T) {
+	tests := []struct {
+		a, b []float64
+		want float64
+	}{
+		// Fully separated: one ordering in C(n+m, n), both tails
+		{[]float64{1, 2, 3}, []float64{4, 5, 6}, 2.0 / 20},
+		{[]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{[]float64{6, 7, 8, 9, 10, 11}, []float64{1, 2, 3, 4, 5, 0}, 2.0 / 924},
+		// Interleaved: U = 6 of 9, and 7 of the 20 orderings are as far out
+		{[]float64{1, 3, 5}, []float64{2, 4, 6}, 14.0 / 20},
+		{[]float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		// U = 1 of 9: P(U <= 1) = 2/20, both tails
+		{[]float64{1, 2, 4}, []float64{3, 5, 6}, 4.0 / 20},
+		{nil, []float64{1}, 1},
+	}
+	for _, tt := range tests {
+		if got := MannWhitney(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("MannWhitney(%v, %v) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMedian(t *testing.T) {
+	if got := median([]float64{5, 1, 3}); got != 3 {
+		t.Errorf("odd: %g", got)
+	}
+	if got := median([]float64{4, 1, 3, 2}); got != 2.5 {
+		t.Errorf("even: %g", got)
+	}
+}
+
+// 3. Comparing
+// ============
+
+func samples(name, unit string, xs ...float64) Benchmark {
+	return Benchmark{Name: name, Samples: map[string][]float64{unit: xs}}
+}
+
+func TestCompare(t *testing.T) {
+	opts := Options{Threshold: 0.10, Alpha: 0.05}
+	base := Run{Benchmarks: []Benchmark{
+		samples("Slower", "ns/op", 100, 101, 99, 100, 102, 98),
+		samples("Noisy", "ns/op", 100, 150, 90, 130, 95, 160),
+		samples("Small", "ns/op", 100, 101, 99, 100, 102, 98),
+		samples("Rate", "MB/s", 500, 501, 499, 500, 502, 498),
+		samples("Removed", "ns/op", 1),
+	}}
+	run := Run{Benchmarks: []Benchmark{
+		samples("Slower", "ns/op", 130, 131, 129, 130, 132, 128),
+		samples("Noisy", "ns/op", 120, 100, 170, 110, 140, 95),
+		samples("Small", "ns/op", 105, 106, 104, 105, 107, 103),
+		samples("Rate", "MB/s", 300, 301, 299, 300, 302, 298),
+		samples("Added", "ns/op", 1),
+	}}
+	want := map[string]Verdict{
+		"Slower":  Slower, // +30%, every sample above every old one
+		"Noisy":   Same,   // the median moved, the samples overlap
+		"Small":   Same,   // consistent, but under 10%
+		"Rate":    Slower, // fewer MB/s is worse
+		"Added":   Added,
+		"Removed": Gone,
+	}
+	rows := Compare(base, run, opts)
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows: %+v", len(rows), rows)
+	}
+	for _, r := range rows {
+		if r.Verdict != want[r.Name] {
+			t.Errorf("%s: %s (delta %+.2f, p %.3f), want %s", r.Name, r.Verdict, r.Delta, r.P, want[r.Name])
+		}
+	}
+}
+
+func TestFaster(t *testing.T) {
+	r := judge("X", "allocs/op", []float64{3, 3, 3, 3, 3}, []float64{1, 1, 1, 1, 1}, Options{Threshold: 0.1, Alpha: 0.05})
+	if r.Verdict != Faster || r.Delta > -0.6 {
+		t.Errorf("got %+v", r)
+	}
+}
+
+// 4. Baselines and Output
+// =======================
+
+func TestBaselineRoundTrip(t *testing.T) {
+	run, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.Lesson = "strings-bytes/concat"
+	path := baselinePath(t.TempDir(), run.Machine, run.Lesson)
+	if !strings.HasSuffix(filepath.ToSlash(path), "/linux-amd64-intel-xeon-cpu-2-20ghz/strings-bytes/concat.json") {
+		t.Errorf("path %s", path)
+	}
+	if _, err := readBaseline(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing baseline: %v", err)
+	}
+	if err := writeBaseline(path, run); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Saved.IsZero() || got.Lesson != run.Lesson || len(got.Benchmarks) != 2 ||
+		!slices.Equal(got.Benchmarks[0].Samples["ns/op"], run.Benchmarks[0].Samples["ns/op"]) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	base := Run{Lesson: "strings-bytes/concat", Machine: "m", GoVersion: "go1.27.1"}
+	rows := []Row{
+		{Name: "A", Unit: "ns/op", Old: 100, New: 130, Delta: 0.3, P: 0.002, N: [2]int{6, 6}, Verdict: Slower},
+		{Name: "A", Unit: "allocs/op", Old: 1, New: 1, P: 1, N: [2]int{6, 6}, Verdict: Same},
+		{Name: "B", Unit: "ns/op", Old: 12.5, New: 12.25, Delta: -0.02, P: 1, N: [2]int{1, 1}, Verdict: Same},
+		{Name: "C", Unit: "ns/op", New: 7, Verdict: Added},
+	}
+	var buf bytes.Buffer
+	write(&buf, base, rows, Options{Alpha: 0.05})
+	out := buf.String()
+	for _, want := range []string{
+		"strings-bytes/concat on m: baseline saved 0001-01-01 with go1.27.1",
+		"A          ns/op  100       130    +30.0%  0.002  slower",
+		"B          ns/op  12.50     12.25  -2.0%   1.000  ~",
+		"no change can reach p < 0.05",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "allocs/op") {
+		t.Errorf("an unchanged allocs/op row:\n%s", out)
+	}
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// benchdiff - Benchmark Baselines and Regressions
+// ===============================================
+// benchdiff runs a lesson's benchmarks, keeps the results as a
+// baseline for the machine they ran on, and compares later runs with
+// it:
+//
+//   go run tools/benchdiff/main.go -save concurrency/maps      run, and store the baseline
+//   go run tools/benchdiff/main.go concurrency/maps            run, and compare
+//   go run tools/benchdiff/main.go -check concurrency/maps     ...and exit 1 on a regression
+//   go test -run '^$' -bench . -count 6 *.go | go run tools/benchdiff/main.go -in - lesson/dir
+//
+// learnctl bench runs it over every lesson with benchmarks.
+//
+// Baselines live in <machine>/<lesson>.json under -dir, benchdata at
+// the repository root by default. The machine is named from the goos,
+// goarch and cpu lines go test prints, so numbers from a laptop are
+// never held against a CI runner's. A lesson with no baseline for this
+// machine is reported and passes.
+//
+// One run of a benchmark proves little: the same code varies by a few
+// percent from run to run. Each benchmark runs -count times, and a
+// change counts only if it is both
+//   - large: the medians differ by more than -threshold percent
+//   - consistent: a Mann-Whitney U test on the two sets of samples
+//     gives p below -alpha, so it is unlikely to be noise
+// With -count 6 on both sides the smallest p is 0.002; below 4 no
+// change can pass alpha 0.05, and benchdiff says so.
+//
+// Every unit go test reports is compared. Lower is better, except for
+// rates such as MB/s.
+
+func main() {
+	root := flag.String("root", "", "repository root (default: the one holding this tool)")
+	dir := flag.String("dir", "", "baseline directory (default: benchdata under the root)")
+	save := flag.Bool("save", false, "store this run as the machine's baseline")
+	check := flag.Bool("check", false, "exit 1 if any benchmark regressed")
+	count := flag.Int("count", 6, "runs of each benchmark (go test -count)")
+	bench := flag.String("bench", ".", "benchmarks to run (go test -bench)")
+	threshold := flag.Float64("threshold", 10, "smallest change that counts, in percent")
+	alpha := flag.Float64("alpha", 0.05, "largest p-value that counts")
+	machine := flag.String("machine", "", "baseline name (default: from go test's goos, goarch and cpu)")
+	in := flag.String("in", "", "read go test -bench output from this file (- for stdin) instead of running; one lesson only")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: benchdiff [flags] lesson-dir...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *count < 1 || (*in != "" && flag.NArg() > 1) || (*save && *check) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *root == "" {
+		*root = defaultRoot()
+	}
+	if *dir == "" {
+		*dir = filepath.Join(*root, "benchdata")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := Options{Threshold: *threshold / 100, Alpha: *alpha}
+	var regressions []string
+	for _, lessonDir := range flag.Args() {
+		lesson, err := lessonName(*root, lessonDir)
+		if err != nil {
+			fail(err)
+		}
+		var out io.Reader
+		if *in != "" {
+			if out, err = open(*in); err != nil {
+				fail(err)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "benchdiff: running %s (-count %d)\n", lesson, *count)
+			if out, err = runBenchmarks(ctx, lessonDir, *bench, *count); err != nil {
+				fail(fmt.Errorf("%s: %w", lesson, err))
+			}
+		}
+		run, err := Parse(out)
+		if err != nil {
+			fail(fmt.Errorf("%s: %w", lesson, err))
+		}
+		run.Lesson = lesson
+		if *machine != "" {
+			run.Machine = *machine
+		}
+		path := baselinePath(*dir, run.Machine, lesson)
+
+		if *save {
+			if err := writeBaseline(path, run); err != nil {
+				fail(err)
+			}
+			fmt.Printf("%s: saved %d benchmarks to %s\n", lesson, len(run.Benchmarks), path)
+			continue
+		}
+		base, err := readBaseline(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("%s: no baseline for %s yet; store one with -save\n", lesson, run.Machine)
+			continue
+		}
+		if err != nil {
+			fail(err)
+		}
+		rows := Compare(base, run, opts)
+		write(os.Stdout, base, rows, opts)
+		for _, r := range rows {
+			if r.Verdict == Slower {
+				regressions = append(regressions, lesson+" "+r.Name+" "+r.Unit)
+			}
+		}
+	}
+
+	if len(regressions) > 0 {
+		fmt.Printf("\n%d regressions:\n", len(regressions))
+		for _, r := range regressions {
+			fmt.Println("  " + r)
+		}
+		if *check {
+			stop()
+			os.Exit(1)
+		}
+	}
+}
+
+// defaultRoot is two directories above this file, wherever it is run
+// from
+func defaultRoot() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return "."
+	}
+	return filepath.Join(filepath.Dir(file), "..", "..")
+}
+
+// lessonName is dir relative to the root, with slashes: the key its
+// baseline is stored under
+func lessonName(root, dir string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	name, err := filepath.Rel(absRoot, absDir)
+	if err != nil || name == "." || strings.HasPrefix(name, "..") {
+		return "", fmt.Errorf("%s is not a lesson directory under %s", dir, root)
+	}
+	return filepath.ToSlash(name), nil
+}
+
+func open(name string) (io.Reader, error) {
+	if name == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(name)
+}
+
+// runBenchmarks runs go test on the lesson's files, as the lessons'
+// READMEs do, and returns what it printed
+func runBenchmarks(ctx context.Context, dir, bench string, count int) (io.Reader, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil || len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	args := []string{"test", "-run", "^$", "-bench", bench, "-benchmem", "-count", strconv.Itoa(count)}
+	for _, f := range files {
+		args = append(args, filepath.Base(f))
+	}
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go test: %w\n%s", err, out)
+	}
+	return strings.NewReader(string(out)), nil
+}
+
+// Reading go test Output
+// ======================
+
+// Run is one lesson's benchmarks on one machine: what -save stores
+type Run struct {
+	Lesson     string
+	Machine    string
+	GOOS       string
+	GOARCH     string
+	CPU        string
+	GoVersion  string
+	Saved      time.Time `json:",omitzero"`
+	Benchmarks []Benchmark
+}
+
+// Benchmark holds every sample of one benchmark, by unit: with -count
+// 6, Samples["ns/op"] has six values
+type Benchmark struct {
+	Name    string
+	Samples map[string][]float64
+}
+
+// Parse reads the output of go test -bench. The header lines name the
+// machine; each Benchmark line adds one sample per unit
+func Parse(r io.Reader) (Run, error) {
+	run := Run{GoVersion: runtime.Version()}
+	byName := map[string]int{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if k, v, ok := strings.Cut(line, ": "); ok {
+			switch k {
+			case "goos":
+				run.GOOS = v
+			case "goarch":
+				run.GOARCH = v
+			case "cpu":
+				run.CPU = v
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || len(fields)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue // a benchmark's own log line
+		}
+		name := strings.TrimPrefix(fields[0], "Benchmark")
+		i, ok := byName[name]
+		if !ok {
+			i = len(run.Benchmarks)
+			byName[name] = i
+			run.Benchmarks = append(run.Benchmarks, Benchmark{Name: name, Samples: map[string][]float64{}})
+		}
+		for j := 2; j+1 < len(fields); j += 2 {
+			v, err := strconv.ParseFloat(fields[j], 64)
+			if err != nil {
+				return run, fmt.Errorf("benchmark %s: %q is not a number", name, fields[j])
+			}
+			run.Benchmarks[i].Samples[fields[j+1]] = append(run.Benchmarks[i].Samples[fields[j+1]], v)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return run, err
+	}
+	if len(run.Benchmarks) == 0 {
+		return run, errors.New("no benchmark results in the output")
+	}
+	run.Machine = MachineName(run.GOOS, run.GOARCH, run.CPU)
+	return run, nil
+}
+
+// MachineName makes a directory name from the machine's description:
+// linux, amd64 and "Intel(R) Xeon(R) CPU @ 2.20GHz" give
+// linux-amd64-intel-xeon-cpu-2-20ghz
+func MachineName(goos, goarch, cpu string) string {
+	for _, noise := range []string{"(R)", "(TM)", "(tm)"} {
+		cpu = strings.ReplaceAll(cpu, noise, "")
+	}
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.Join([]string{goos, goarch, cpu}, " ")) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// Baselines
+// =========
+
+func baselinePath(dir, machine, lesson string) string {
+	return filepath.Join(dir, machine, filepath.FromSlash(lesson)+".json")
+}
+
+func writeBaseline(path string, run Run) error {
+	run.Saved = time.Now().UTC().Truncate(time.Second)
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readBaseline(path string) (Run, error) {
+	var run Run
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return run, err
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return run, fmt.Errorf("%s: %w", path, err)
+	}
+	return run, nil
+}
+
+// Comparing
+// =========
+
+// Verdict is what one comparison concluded
+type Verdict string
+
+const (
+	Same   Verdict = "~"      // too small or too noisy to count
+	Slower Verdict = "slower" // a regression: worse by more than the threshold, and consistently
+	Faster Verdict = "faster"
+	Added  Verdict = "new"  // not in the baseline
+	Gone   Verdict = "gone" // in the baseline, not in this run
+)
+
+// Options are the thresholds a change must pass to count
+type Options struct {
+	Threshold float64 // fraction: 0.10 is 10%
+	Alpha     float64
+}
+
+// Row is one benchmark in one unit, compared
+type Row struct {
+	Name, Unit string
+	Old, New   float64 // medians
+	Delta      float64 // (New - Old) / Old
+	P          float64
+	N          [2]int // samples on each side
+	Verdict    Verdict
+}
+
+// Compare matches benchmarks by name and unit and judges each pair
+func Compare(base, run Run, opts Options) []Row {
+	var rows []Row
+	for _, nb := range run.Benchmarks {
+		i := slices.IndexFunc(base.Benchmarks, func(b Benchmark) bool { return b.Name == nb.Name })
+		for _, unit := range units(nb) {
+			row := Row{Name: nb.Name, Unit: unit, New: median(nb.Samples[unit]), Verdict: Added}
+			if i >= 0 && len(base.Benchmarks[i].Samples[unit]) > 0 {
+				row = judge(nb.Name, unit, base.Benchmarks[i].Samples[unit], nb.Samples[unit], opts)
+			}
+			rows = append(rows, row)
+		}
+	}
+	for _, ob := range base.Benchmarks {
+		if !slices.ContainsFunc(run.Benchmarks, func(b Benchmark) bool { return b.Name == ob.Name }) {
+			for _, unit := range units(ob) {
+				rows = append(rows, Row{Name: ob.Name, Unit: unit, Old: median(ob.Samples[unit]), Verdict: Gone})
+			}
+		}
+	}
+	return rows
+}
+
+// units lists a benchmark's units in go test's order
+func units(b Benchmark) []string {
+	order := []string{"ns/op", "B/op", "allocs/op"}
+	var us []string
+	for u := range b.Samples {
+		us = append(us, u)
+	}
+	slices.SortFunc(us, func(a, b string) int {
+		ia, ib := slices.Index(order, a), slices.Index(order, b)
+		if ia < 0 {
+			ia = len(order)
+		}
+		if ib < 0 {
+			ib = len(order)
+		}
+		if ia != ib {
+			return ia - ib
+		}
+		return strings.Compare(a, b)
+	})
+	return us
+}
+
+func judge(name, unit string, old, new []float64, opts Options) Row {
+	r := Row{Name: name, Unit: unit, Old: median(old), New: median(new), N: [2]int{len(old), len(new)}, Verdict: Same}
+	switch {
+	case r.Old == r.New:
+		r.P = 1
+		return r
+	case r.Old == 0:
+		r.Delta = math.Inf(1)
+	default:
+		r.Delta = (r.New - r.Old) / r.Old
+	}
+	r.P = MannWhitney(old, new)
+	if math.Abs(r.Delta) <= opts.Threshold || r.P >= opts.Alpha {
+		return r
+	}
+	worse := r.Delta > 0
+	if strings.HasSuffix(unit, "/s") { // a rate: more is better
+		worse = !worse
+	}
+	if worse {
+		r.Verdict = Slower
+	} else {
+		r.Verdict = Faster
+	}
+	return r
+}
+
+func median(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	s := slices.Sorted(slices.Values(xs))
+	if len(s)%2 == 1 {
+		return s[len(s)/2]
+	}
+	return (s[len(s)/2-1] + s[len(s)/2]) / 2
+}
+
+// MannWhitney is the two-sided p-value of the Mann-Whitney U test: how
+// likely samples this far apart are if both came from one
+// distribution. It makes no assumption about the shape of that
+// distribution, which suits timings with their long tail of slow runs.
+//
+// U counts the pairs (a, b) with a > b, ties as a half. Its exact
+// distribution when nothing differs comes from counting orderings:
+// ways(n, m, u) = ways(n-1, m, u-m) + ways(n, m-1, u), as the largest
+// value is from a or from b. Ties are rare in timings; the counts
+// assume none.
+func MannWhitney(a, b []float64) float64 {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 1
+	}
+	var u2 int // 2U, so ties stay whole
+	for _, x := range a {
+		for _, y := range b {
+			switch {
+			case x > y:
+				u2 += 2
+			case x == y:
+				u2++
+			}
+		}
+	}
+	u2 = min(u2, 2*n*m-u2) // the tail nearer to the observation
+
+	// ways[j][u] for the current i, over i = 0..n
+	ways := make([][]float64, m+1)
+	for j := range ways {
+		ways[j] = []float64{1}
+	}
+	for i := 1; i <= n; i++ {
+		next := make([][]float64, m+1)
+		next[0] = []float64{1}
+		for j := 1; j <= m; j++ {
+			row := make([]float64, i*j+1)
+			for u := range row {
+				if u-j >= 0 && u-j < len(ways[j]) {
+					row[u] += ways[j][u-j]
+				}
+				if u < len(next[j-1]) {
+					row[u] += next[j-1][u]
+				}
+			}
+			next[j] = row
+		}
+		ways = next
+	}
+
+	var tail, total float64
+	for u, w := range ways[m] {
+		total += w
+		if 2*u <= u2 {
+			tail += w
+		}
+	}
+	return min(1, 2*tail/total)
+}
+
+// Output
+// ======
+
+func write(w io.Writer, base Run, rows []Row, opts Options) {
+	fmt.Fprintf(w, "\n%s on %s: baseline saved %s with %s\n", base.Lesson, base.Machine,
+		base.Saved.Format(time.DateOnly), base.GoVersion)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "benchmark\tunit\tbaseline\tnow\tdelta\tp\t")
+	few := false
+	for _, r := range rows {
+		if r.Unit != "ns/op" && r.Old == r.New {
+			continue // bytes and allocations that did not move are noise in the table
+		}
+		delta, p := "", ""
+		switch r.Verdict {
+		case Added, Gone:
+		default:
+			delta = fmt.Sprintf("%+.1f%%", 100*r.Delta)
+			p = fmt.Sprintf("%.3f", r.P)
+			few = few || min(r.N[0], r.N[1]) < 4
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.Unit, number(r.Old, r.Verdict == Added),
+			number(r.New, r.Verdict == Gone), delta, p, r.Verdict)
+	}
+	tw.Flush()
+	if few {
+		fmt.Fprintf(w, "fewer than 4 samples on a side: no change can reach p < %g; use -count 6\n", opts.Alpha)
+	}
+}
+
+func number(v float64, blank bool) string {
+	switch {
+	case blank:
+		return ""
+	case v >= 100 || v == math.Trunc(v):
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "benchdiff:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchreport - Tests
+// ===================
+// Run with:
+//
+//   cd tools/benchreport
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// The charts are checked as numbers - where each bar ends and each
+// point lands - and the page for what it must and must not contain.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+const output = `goos: linux
+goarch: amd64
+cpu: Intel(R) Xeon(R) CPU @ 2.20GHz
+BenchmarkAlloc/stack-8   	1000000000	         0.50 ns/op	       0 B/op	       0 allocs/op
+BenchmarkAlloc/stack-8   	1000000000	         0.40 ns/op	       0 B/op	       0 allocs/op
+BenchmarkAlloc/stack-8   	1000000000	         0.60 ns/op	       0 B/op	       0 allocs/op
+BenchmarkAlloc/heap-8    	 80000000	        15.00 ns/op	       8 B/op	       1 allocs/op
+BenchmarkAlloc/heap-8    	 80000000	        14.00 ns/op	       8 B/op	       1 allocs/op
+BenchmarkAlloc/heap-8    	 80000000	        16.00 ns/op	       8 B/op	       1 allocs/op
+    go_benchmarking.go:12: a log line from the benchmark
+BenchmarkSum-8           	  5000000	       240 ns/op
+PASS
+`
+
+// 1. Reading go test Output
+// =========================
+
+func TestParse(t *testing.T) {
+	run, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.CPU != "Intel(R) Xeon(R) CPU @ 2.20GHz" || len(run.Benchmarks) != 3 {
+		t.Fatalf("got %+v", run)
+	}
+	if b := run.Benchmarks[1]; b.Name != "Alloc/heap-8" || !slices.Equal(b.Samples["ns/op"], []float64{15, 14, 16}) {
+		t.Errorf("got %+v", b)
+	}
+	if _, err := Parse(strings.NewReader("PASS\n")); err == nil {
+		t.Error("output with no benchmarks parsed")
+	}
+}
+
+// TestParseSharedFixture checks Parse against the fixture and result
+// that tools/benchdiff's test checks its copy of Parse against, so the
+// two parsers cannot drift apart unnoticed
+func TestParseSharedFixture(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "testdata", "gotest-bench.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	run, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "gotest-bench.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want struct {
+		CPU        string
+		Benchmarks []Benchmark
+	}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if run.CPU != want.CPU {
+		t.Errorf("CPU = %q, want %q", run.CPU, want.CPU)
+	}
+	if !reflect.DeepEqual(run.Benchmarks, want.Benchmarks) {
+		t.Errorf("Benchmarks =\n%+v\nwant\n%+v", run.Benchmarks, want.Benchmarks)
+	}
+}
+
+// 2. Charts
+// =========
+
+func TestBarCharts(t *testing.T) {
+	run, _ := Parse(strings.NewReader(output))
+	charts := BarCharts(run.Benchmarks)
+	var got []string
+	for _, c := range charts {
+		got = append(got, c.Title+" "+c.Unit)
+	}
+	// Alloc's sub-benchmarks share charts; the flat Sum gets its own
+	want := []string{"Alloc ns/op", "Alloc B/op", "Alloc allocs/op", " ns/op"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("charts %q, want %q", got, want)
+	}
+
+	c := charts[0]
+	stack, heap := c.Bars[0], c.Bars[1]
+	if stack.Label != "stack" || heap.Label != "heap" || heap.Median != 15 || heap.Runs != 3 {
+		t.Errorf("bars %+v", c.Bars)
+	}
+	// The slowest sample reaches the end of the plot, and each bar is
+	// drawn to scale
+	plot := float64(chartWidth - labelWidth - valueWidth)
+	if heap.MaxX != plot || heap.W != 15.0/16*plot || stack.W != 0.5/16*plot {
+		t.Errorf("heap %+v, stack %+v in %g px", heap, stack, plot)
+	}
+	if stack.Y != 0 || heap.Y != barHeight+barGap || c.Height != 2*barHeight+barGap {
+		t.Errorf("rows at %g and %g, height %d", stack.Y, heap.Y, c.Height)
+	}
+}
+
+func TestBarChartsSkipZero(t *testing.T) {
+	charts := BarCharts([]Benchmark{
+		{Name: "X/a", Samples: map[string][]float64{"ns/op": {1}, "B/op": {0}, "allocs/op": {0}}},
+		{Name: "X/b", Samples: map[string][]float64{"ns/op": {2}, "B/op": {0}, "allocs/op": {0}}},
+	})
+	if len(charts) != 1 || charts[0].Unit != "ns/op" {
+		t.Errorf("got %+v", charts)
+	}
+}
+
+func TestBarNotes(t *testing.T) {
+	run, _ := Parse(strings.NewReader(output))
+	notes := barNotes(BarCharts(run.Benchmarks))
+	want := []string{"Alloc/heap takes 30.0x as long as Alloc/stack (median 15 ns/op against 0.5)"}
+	if !slices.Equal(notes, want) {
+		t.Errorf("got %q, want %q", notes, want)
+	}
+}
+
+func TestTicks(t *testing.T) {
+	tests := []struct {
+		top  float64
+		want []float64
+	}{
+		{4, []float64{0, 1, 2, 3, 4}},
+		{7.3, []float64{0, 2, 4, 6, 8}},
+		{1.2, []float64{0, 0.5, 1, 1.5}},
+		{96, []float64{0, 20, 40, 60, 80, 100}},
+		{0, []float64{0, 0.2, 0.4, 0.6, 0.8, 1}},
+		{0.03, []float64{0, 0.01, 0.02, 0.03}},
+	}
+	for _, tt := range tests {
+		got, top := ticks(tt.top)
+		if !slices.Equal(got, tt.want) || top != got[len(got)-1] {
+			t.Errorf("ticks(%g) = %v, %g; want %v", tt.top, got, top, tt.want)
+		}
+	}
+}
+
+func TestScalingCharts(t *testing.T) {
+	r := ScalingReport{NumCPU: 4, Rows: []ScalingRow{
+		{Workload: "cpu", Procs: 1, Speedup: 1}, {Workload: "cpu", Procs: 4, Speedup: 3.8}, {Workload: "cpu", Procs: 8, Speedup: 3.9},
+		{Workload: "serial", Procs: 1, Speedup: 1}, {Workload: "serial", Procs: 4, Speedup: 2.5}, {Workload: "serial", Procs: 8, Speedup: 2.4},
+	}}
+	charts, notes := ScalingCharts(r)
+	c := charts[0]
+	if len(c.Series) != 3 || c.Series[2].Name != "ideal" || !c.Series[2].Dashed {
+		t.Fatalf("series %+v", c.Series)
+	}
+	// The ideal levels off at NumCPU
+	ideal := c.Series[2].Points
+	if len(ideal) != 8 || ideal[3].Y != 4 || ideal[7].Y != 4 {
+		t.Errorf("ideal %+v", ideal)
+	}
+	// x from 0 to 8 and y from 0 to 4 fill the plot
+	p := c.Series[0].Points[1]
+	if p.PX != 4.0/8*c.PlotW || p.PY != c.PlotH-3.8/4*c.PlotH {
+		t.Errorf("cpu at 4 drawn at %g,%g in %gx%g", p.PX, p.PY, c.PlotW, c.PlotH)
+	}
+	if !strings.HasPrefix(c.Series[0].Path, "M66.8 ") || strings.Count(c.Series[0].Path, "L") != 2 {
+		t.Errorf("path %q", c.Series[0].Path)
+	}
+	if len(c.Marks) != 1 || c.Marks[0].Label != "NumCPU" || c.Marks[0].Pos != c.PlotW/2 {
+		t.Errorf("marks %+v", c.Marks)
+	}
+	for _, want := range []string{"cpu: at best 3.90x, at GOMAXPROCS=8 (49% efficient)", "serial: at best 2.50x, at GOMAXPROCS=4 (62% efficient)", "past GOMAXPROCS=4"} {
+		if !slices.ContainsFunc(notes, func(n string) bool { return strings.HasPrefix(n, want) }) {
+			t.Errorf("missing %q in %q", want, notes)
+		}
+	}
+}
+
+// 3. The Page
+// ===========
+
+func TestRender(t *testing.T) {
+	run, _ := Parse(strings.NewReader(output))
+	bench := run.Benchmarks[:2]
+	bench[0].Name = "Alloc/<stack>"
+	lines, _ := ScalingCharts(ScalingReport{NumCPU: 2, Rows: []ScalingRow{{Workload: "cpu", Procs: 1, Speedup: 1}, {Workload: "cpu", Procs: 2, Speedup: 1.9}}})
+	r := Report{
+		Title:     "Go Benchmarks",
+		Generated: time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+		GoVersion: "go1.27.1", GOOS: "linux", GOARCH: "amd64", NumCPU: 2,
+		Sections: []Section{
+			{Name: "stack-heap", Title: "Stack vs Heap", Command: "go test", Bars: BarCharts(bench), Notes: []string{"a & b"}},
+			{Name: "scaling", Title: "Scaling", Lines: lines},
+			{Name: "pooling", Title: "Pooling", Err: "go test: exit status 1"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"go1.27.1 on linux/amd64, 2 CPUs. Generated 2026-10-16 09:30 UTC.",
+		`<a href="#stack-heap">Stack vs Heap</a>`,
+		// Names are escaped in text and in the tooltips
+		`>&lt;stack&gt;</text>`,
+		`<title>&lt;stack&gt;: median 0.5 ns/op, 0.4 to 0.6 over 3 runs</title>`,
+		`<li>a &amp; b</li>`,
+		`<path class="line dashed" stroke="#718096"`,
+		`<pre class="err">go test: exit status 1</pre>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, "<svg"); got != 4 {
+		t.Errorf("%d charts, want 3 bar charts and 1 line chart", got)
+	}
+	// Self-contained: nothing for the browser to fetch
+	for _, ref := range []string{"<script", "<link", " src=", "@import", "url("} {
+		if strings.Contains(out, ref) {
+			t.Errorf("the page refers to %s", ref)
+		}
+	}
+}
+
+// The suites run against the real lessons and tool
+func TestSuites(t *testing.T) {
+	requireGo(t)
+	cfg := config{root: defaultRoot(), count: 1, benchtime: "1000x", procs: "1,2", scale: 0.05}
+	for _, s := range suites {
+		sec, err := s.run(t.Context(), cfg)
+		if err != nil {
+			t.Errorf("%s: %v", s.name, err)
+			continue
+		}
+		if len(sec.Bars)+len(sec.Lines) == 0 || sec.Command == "" {
+			t.Errorf("%s: %+v", s.name, sec)
+		}
+	}
+}
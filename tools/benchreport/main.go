@@ -0,0 +1,780 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchreport - Benchmark Suites as an HTML Report
+// ================================================
+// benchreport runs the repository's benchmark suites and renders them
+// into one self-contained HTML file of bar and line charts, for slides
+// and course notes:
+//
+//   go run tools/benchreport/main.go -o report.html
+//   go run tools/benchreport/main.go -suites pooling,scaling -count 10 -o report.html
+//   go run tools/benchreport/main.go -suites "" -o concat.html benchdata/<machine>/strings-bytes/concat.json
+//
+// The suites:
+//   - stack-heap: BenchmarkAlloc in testing/go_benchmarking.go, a value
+//     that stays on the stack beside one that escapes to the heap
+//   - pooling: BenchmarkSmallMessage in io/compress, a new gzip.Writer
+//     per message against one from a sync.Pool
+//   - scaling: tools/scaling -json, speedup against GOMAXPROCS beside
+//     the ideal line
+// Files named as arguments are baselines written by tools/benchdiff,
+// each rendered as one more section.
+//
+// Bars are medians of -count runs, and the thin line through each bar
+// spans the fastest and slowest run, so a reader sees how much the
+// numbers moved. The charts are SVG written by the templates in
+// templates/, which go:embed compiles into the binary; the page loads
+// nothing, so it can be mailed, attached or opened offline.
+//
+// A suite that fails gets a section saying why, the report is still
+// written, and benchreport exits 1.
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var page = template.Must(template.New("").Funcs(template.FuncMap{
+	"number": number,
+	"px":     func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) },
+}).ParseFS(templates, "templates/*.html"))
+
+// config is what the suites need from the flags
+type config struct {
+	root      string
+	count     int
+	benchtime string
+	procs     string
+	scale     float64
+}
+
+func main() {
+	root := flag.String("root", "", "repository root (default: the one holding this tool)")
+	out := flag.String("o", "-", "write the report to this file (- for stdout)")
+	suitesFlag := flag.String("suites", "stack-heap,pooling,scaling", "comma-separated suites to run")
+	count := flag.Int("count", 5, "runs of each benchmark (go test -count)")
+	benchtime := flag.String("benchtime", "", "time or iterations per run (go test -benchtime)")
+	procs := flag.String("procs", "", "GOMAXPROCS values for the scaling suite (default 1..NumCPU)")
+	scale := flag.Float64("scale", 1, "multiply the scaling suite's work")
+	timeout := flag.Duration("timeout", 15*time.Minute, "give up after this long")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: benchreport [flags] [benchdiff-baseline.json...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	selected, err := pick(*suitesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "benchreport:", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *count < 1 || *scale <= 0 || (len(selected) == 0 && flag.NArg() == 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *root == "" {
+		*root = defaultRoot()
+	}
+	cfg := config{*root, *count, *benchtime, *procs, *scale}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	report := Report{
+		Title:     "Go Benchmarks",
+		Generated: time.Now().UTC().Truncate(time.Second),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+	}
+	failed := 0
+	for _, s := range selected {
+		fmt.Fprintf(os.Stderr, "benchreport: running %s\n", s.name)
+		sec, err := s.run(ctx, cfg)
+		sec.Name, sec.Title, sec.About = s.name, s.title, s.about
+		if err != nil {
+			sec.Err = err.Error()
+			failed++
+		}
+		if sec.CPU != "" {
+			report.CPU = sec.CPU
+		}
+		report.Sections = append(report.Sections, sec)
+	}
+	for _, path := range flag.Args() {
+		sec, err := baselineSection(path)
+		if err != nil {
+			fail(err)
+		}
+		report.Sections = append(report.Sections, sec)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := Render(w, report); err != nil {
+		fail(err)
+	}
+	if *out != "-" {
+		fmt.Fprintf(os.Stderr, "benchreport: wrote %d sections to %s\n", len(report.Sections), *out)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "benchreport: %d of %d suites failed\n", failed, len(selected))
+		stop()
+		os.Exit(1)
+	}
+}
+
+// defaultRoot is two directories above this file, wherever it is run
+// from
+func defaultRoot() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return "."
+	}
+	return filepath.Join(filepath.Dir(file), "..", "..")
+}
+
+// Suites
+// ======
+
+type suite struct {
+	name, title, about string
+	run                func(ctx context.Context, cfg config) (Section, error)
+}
+
+var suites = []suite{
+	{"stack-heap", "Stack vs Heap",
+		"The same int, kept in a local or stored through a package-level pointer. " +
+			"The local stays on the stack and costs almost nothing; the stored one " +
+			"escapes, so every iteration asks the allocator for 8 bytes and leaves " +
+			"them for the garbage collector.",
+		stackHeap},
+	{"pooling", "Pooling gzip Writers",
+		"Compressing a 2 KB message with a new gzip.Writer each time, and with " +
+			"one taken from a sync.Pool and Reset. A writer carries about 800 KB of " +
+			"tables, so for small messages the setup is most of the work.",
+		pooling},
+	{"scaling", "Scaling With GOMAXPROCS",
+		"A fixed amount of work run at each GOMAXPROCS: speedup is the time at " +
+			"GOMAXPROCS=1 divided by the time at this one. The dashed line is " +
+			"perfect scaling up to the number of CPUs; the serial workload holds " +
+			"a mutex for a tenth of each chunk, so Amdahl's law caps it near 10x.",
+		scaling},
+}
+
+func pick(list string) ([]suite, error) {
+	var picked []suite
+	for name := range strings.SplitSeq(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		i := slices.IndexFunc(suites, func(s suite) bool { return s.name == name })
+		if i < 0 {
+			var names []string
+			for _, s := range suites {
+				names = append(names, s.name)
+			}
+			return nil, fmt.Errorf("unknown suite %q (have %s)", name, strings.Join(names, ", "))
+		}
+		picked = append(picked, suites[i])
+	}
+	return picked, nil
+}
+
+func stackHeap(ctx context.Context, cfg config) (Section, error) {
+	args := []string{"run", "go_benchmarking.go", "-bench",
+		"-test.bench", "^BenchmarkAlloc$", "-test.count", strconv.Itoa(cfg.count)}
+	if cfg.benchtime != "" {
+		args = append(args, "-test.benchtime", cfg.benchtime)
+	}
+	return benchSection(ctx, cfg.root, "testing", args)
+}
+
+func pooling(ctx context.Context, cfg config) (Section, error) {
+	dir := "io/compress"
+	args := []string{"test", "-run", "^$", "-bench", "^BenchmarkSmallMessage$", "-benchmem", "-count", strconv.Itoa(cfg.count)}
+	if cfg.benchtime != "" {
+		args = append(args, "-benchtime", cfg.benchtime)
+	}
+	files, err := filepath.Glob(filepath.Join(cfg.root, filepath.FromSlash(dir), "*.go"))
+	if err != nil || len(files) == 0 {
+		return Section{}, fmt.Errorf("no Go files in %s", dir)
+	}
+	for _, f := range files {
+		args = append(args, filepath.Base(f))
+	}
+	return benchSection(ctx, cfg.root, dir, args)
+}
+
+// benchSection runs a go command that prints benchmark results in dir,
+// a slash path under root, and charts them
+func benchSection(ctx context.Context, root, dir string, args []string) (Section, error) {
+	sec := Section{Command: "cd " + dir + " && go " + strings.Join(args, " ")}
+	out, err := goCommand(ctx, filepath.Join(root, filepath.FromSlash(dir)), args)
+	if err != nil {
+		return sec, err
+	}
+	run, err := Parse(strings.NewReader(out))
+	if err != nil {
+		return sec, err
+	}
+	sec.CPU = run.CPU
+	sec.Bars = BarCharts(run.Benchmarks)
+	sec.Notes = barNotes(sec.Bars)
+	return sec, nil
+}
+
+// ScalingRow and ScalingReport are the fields of tools/scaling's -json
+// output this tool reads
+type ScalingRow struct {
+	Workload   string
+	Procs      int
+	Seconds    float64
+	Speedup    float64
+	Efficiency float64
+}
+
+type ScalingReport struct {
+	NumCPU int
+	Rows   []ScalingRow
+}
+
+func scaling(ctx context.Context, cfg config) (Section, error) {
+	args := []string{"run", "tools/scaling/main.go", "-json", "-scale", fmt.Sprint(cfg.scale)}
+	if cfg.procs != "" {
+		args = append(args, "-procs", cfg.procs)
+	}
+	sec := Section{Command: "go " + strings.Join(args, " ")}
+	out, err := goCommand(ctx, cfg.root, args)
+	if err != nil {
+		return sec, err
+	}
+	var r ScalingReport
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		return sec, fmt.Errorf("scaling -json: %w", err)
+	}
+	sec.Lines, sec.Notes = ScalingCharts(r)
+	return sec, nil
+}
+
+func goCommand(ctx context.Context, dir string, args []string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go %s: %w\n%s%s", args[0], err, out, stderr.String())
+	}
+	return string(out), nil
+}
+
+// Baseline is the part of a tools/benchdiff baseline file this tool
+// reads
+type Baseline struct {
+	Lesson     string
+	Machine    string
+	CPU        string
+	GoVersion  string
+	Saved      time.Time
+	Benchmarks []Benchmark
+}
+
+func baselineSection(path string) (Section, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Section{}, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Section{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(b.Benchmarks) == 0 {
+		return Section{}, fmt.Errorf("%s: no benchmarks; is it a benchdiff baseline?", path)
+	}
+	sec := Section{
+		Name:    "baseline-" + strings.ReplaceAll(b.Lesson, "/", "-"),
+		Title:   b.Lesson,
+		About:   fmt.Sprintf("The baseline benchdiff stored for %s on %s with %s.", b.Machine, b.Saved.Format(time.DateOnly), b.GoVersion),
+		Command: filepath.ToSlash(path),
+		CPU:     b.CPU,
+		Bars:    BarCharts(b.Benchmarks),
+	}
+	sec.Notes = barNotes(sec.Bars)
+	return sec, nil
+}
+
+// Reading go test Output
+// ======================
+
+// Run is the benchmark results one go command printed
+type Run struct {
+	CPU        string
+	Benchmarks []Benchmark
+}
+
+// Benchmark holds every sample of one benchmark, by unit, as
+// tools/benchdiff stores them
+type Benchmark struct {
+	Name    string
+	Samples map[string][]float64
+}
+
+// Parse reads the output of go test -bench: one sample per unit from
+// each Benchmark line, in the order the benchmarks first appear. It is
+// tools/benchdiff's Parse without the machine fields - two main
+// packages cannot share code without a module - and both tests check
+// their Parse against ../testdata/gotest-bench.txt, so a fix to one
+// that misses the other fails a test.
+func Parse(r io.Reader) (Run, error) {
+	var run Run
+	byName := map[string]int{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if v, ok := strings.CutPrefix(line, "cpu: "); ok {
+			run.CPU = v
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || len(fields)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue // a benchmark's own log line
+		}
+		name := strings.TrimPrefix(fields[0], "Benchmark")
+		i, ok := byName[name]
+		if !ok {
+			i = len(run.Benchmarks)
+			byName[name] = i
+			run.Benchmarks = append(run.Benchmarks, Benchmark{Name: name, Samples: map[string][]float64{}})
+		}
+		for j := 2; j+1 < len(fields); j += 2 {
+			v, err := strconv.ParseFloat(fields[j], 64)
+			if err != nil {
+				return run, fmt.Errorf("benchmark %s: %q is not a number", name, fields[j])
+			}
+			run.Benchmarks[i].Samples[fields[j+1]] = append(run.Benchmarks[i].Samples[fields[j+1]], v)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return run, err
+	}
+	if len(run.Benchmarks) == 0 {
+		return run, errors.New("no benchmark results in the output")
+	}
+	return run, nil
+}
+
+// Charts
+// ======
+// The charts are laid out here, in pixels, so the templates only place
+// what they are given.
+
+const (
+	chartWidth = 720
+	labelWidth = 200 // benchmark names, left of the bars
+	valueWidth = 90  // the median, right of the longest bar
+	barHeight  = 26
+	barGap     = 8
+)
+
+// BarChart is one unit of one benchmark group: a bar per benchmark
+type BarChart struct {
+	Title string
+	Unit  string
+	Bars  []Bar
+	// Set by layout
+	Width, Height int
+	Left, BarH    int     // where the bars start, and how thick they are
+	Mid           float64 // halfway down a bar, for its labels
+}
+
+// Bar is a benchmark's median, with the range of its samples
+type Bar struct {
+	Label            string
+	Median, Min, Max float64
+	Runs             int
+	// Set by layout
+	Y, W, MinX, MaxX float64
+}
+
+// procsSuffix is the -GOMAXPROCS go test adds to names when it is not 1
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// BarCharts groups benchmarks by their top-level name, so the
+// sub-benchmarks of BenchmarkAlloc share a chart, and draws one chart
+// per unit that is not zero throughout: ns/op, B/op and allocs/op
+// first, then the rest
+func BarCharts(benchmarks []Benchmark) []BarChart {
+	type group struct {
+		name    string
+		members []Benchmark
+	}
+	var groups []*group
+	for _, b := range benchmarks {
+		name := procsSuffix.ReplaceAllString(b.Name, "")
+		top, sub, ok := strings.Cut(name, "/")
+		if !ok {
+			top, sub = "", name // flat benchmarks share one chart
+		}
+		i := slices.IndexFunc(groups, func(g *group) bool { return g.name == top })
+		if i < 0 {
+			groups = append(groups, &group{name: top})
+			i = len(groups) - 1
+		}
+		groups[i].members = append(groups[i].members, Benchmark{Name: sub, Samples: b.Samples})
+	}
+
+	var charts []BarChart
+	for _, g := range groups {
+		for _, unit := range units(g.members) {
+			c := BarChart{Title: g.name, Unit: unit}
+			zero := true
+			for _, b := range g.members {
+				s := b.Samples[unit]
+				if len(s) == 0 {
+					continue
+				}
+				bar := Bar{Label: b.Name, Median: median(s), Min: slices.Min(s), Max: slices.Max(s), Runs: len(s)}
+				zero = zero && bar.Max == 0
+				c.Bars = append(c.Bars, bar)
+			}
+			if !zero {
+				charts = append(charts, c.layout())
+			}
+		}
+	}
+	return charts
+}
+
+// units lists the units measured, the usual three first
+func units(benchmarks []Benchmark) []string {
+	order := []string{"ns/op", "B/op", "allocs/op"}
+	var extra []string
+	for _, b := range benchmarks {
+		for u := range b.Samples {
+			if !slices.Contains(order, u) && !slices.Contains(extra, u) {
+				extra = append(extra, u)
+			}
+		}
+	}
+	slices.Sort(extra)
+	return append(order, extra...)
+}
+
+func (c BarChart) layout() BarChart {
+	top := 0.0
+	for _, b := range c.Bars {
+		top = max(top, b.Max)
+	}
+	plot := float64(chartWidth - labelWidth - valueWidth)
+	x := func(v float64) float64 {
+		if top == 0 {
+			return 0
+		}
+		return v / top * plot
+	}
+	for i := range c.Bars {
+		b := &c.Bars[i]
+		b.Y = float64(i * (barHeight + barGap))
+		b.W, b.MinX, b.MaxX = x(b.Median), x(b.Min), x(b.Max)
+	}
+	c.Width, c.Left, c.BarH, c.Mid = chartWidth, labelWidth, barHeight, barHeight/2
+	c.Height = len(c.Bars)*(barHeight+barGap) - barGap
+	return c
+}
+
+// barNotes compares the fastest and slowest benchmark of each ns/op
+// chart
+func barNotes(charts []BarChart) []string {
+	var notes []string
+	for _, c := range charts {
+		if c.Unit != "ns/op" || len(c.Bars) < 2 {
+			continue
+		}
+		fast, slow := c.Bars[0], c.Bars[0]
+		for _, b := range c.Bars {
+			if b.Median < fast.Median {
+				fast = b
+			}
+			if b.Median > slow.Median {
+				slow = b
+			}
+		}
+		if fast.Median <= 0 || slow.Median == fast.Median {
+			continue
+		}
+		name := func(b Bar) string { return strings.TrimLeft(c.Title+"/"+b.Label, "/") }
+		notes = append(notes, fmt.Sprintf("%s takes %.1fx as long as %s (median %s ns/op against %s)",
+			name(slow), slow.Median/fast.Median, name(fast), number(slow.Median), number(fast.Median)))
+	}
+	return notes
+}
+
+func median(xs []float64) float64 {
+	s := slices.Sorted(slices.Values(xs))
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// LineChart plots series against a numeric x axis
+type LineChart struct {
+	Title          string
+	XLabel, YLabel string
+	Series         []Series
+	Marks          []Tick // labelled vertical lines, such as NumCPU
+	// Set by layout
+	Width, Height  int
+	Left, Top      int // the plot's corner
+	PlotW, PlotH   float64
+	XTicks, YTicks []Tick
+}
+
+// Series is one line; Dashed ones are references, not measurements
+type Series struct {
+	Name   string
+	Color  string
+	Dashed bool
+	Points []Point
+	// Set by layout
+	Path    string
+	LegendY float64
+}
+
+// Point is a value and, once laid out, its position
+type Point struct {
+	X, Y   float64
+	PX, PY float64
+}
+
+// Tick is a position on an axis and its label
+type Tick struct {
+	Pos   float64
+	Label string
+}
+
+const (
+	lineHeight = 340
+	marginL    = 56  // y tick labels
+	marginR    = 130 // the legend
+	marginT    = 16
+	marginB    = 44 // x tick labels and the axis title
+)
+
+var palette = []string{"#2b6cb0", "#c05621", "#2f855a", "#6b46c1", "#b83280", "#975a16"}
+
+func (c LineChart) layout() LineChart {
+	xMax, yMax := 0.0, 0.0
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			xMax, yMax = max(xMax, p.X), max(yMax, p.Y)
+		}
+	}
+	for _, m := range c.Marks {
+		xMax = max(xMax, m.Pos)
+	}
+	c.Width, c.Height = chartWidth, lineHeight
+	c.Left, c.Top = marginL, marginT
+	c.PlotW = float64(chartWidth - marginL - marginR)
+	c.PlotH = float64(lineHeight - marginT - marginB)
+	xs, xTop := ticks(xMax)
+	ys, yTop := ticks(yMax)
+	px := func(x float64) float64 { return x / xTop * c.PlotW }
+	py := func(y float64) float64 { return c.PlotH - y/yTop*c.PlotH }
+	c.XTicks, c.YTicks = nil, nil
+	label := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, x := range xs {
+		c.XTicks = append(c.XTicks, Tick{px(x), label(x)})
+	}
+	for _, y := range ys {
+		c.YTicks = append(c.YTicks, Tick{py(y), label(y)})
+	}
+	for i := range c.Marks {
+		c.Marks[i].Pos = px(c.Marks[i].Pos)
+	}
+	for i := range c.Series {
+		s := &c.Series[i]
+		if s.Color == "" {
+			s.Color = palette[i%len(palette)]
+		}
+		s.LegendY = float64(20 * i)
+		var path strings.Builder
+		for j := range s.Points {
+			p := &s.Points[j]
+			p.PX, p.PY = px(p.X), py(p.Y)
+			cmd := "L"
+			if j == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&path, "%s%.1f %.1f ", cmd, p.PX, p.PY)
+		}
+		s.Path = strings.TrimSpace(path.String())
+	}
+	return c
+}
+
+// ticks picks round steps from 0 to at least top: 1, 2 or 5 times a
+// power of ten, about five of them. It returns the ticks and the axis
+// maximum, the last tick
+func ticks(top float64) ([]float64, float64) {
+	if top <= 0 {
+		top = 1
+	}
+	// Steps below 1 divide by a power of ten, so 3 * 0.2 is 0.6 and not
+	// 0.6000000000000001
+	exp := math.Floor(math.Log10(top / 5))
+	pow := math.Pow(10, math.Abs(exp))
+	value := func(n float64) float64 {
+		if exp < 0 {
+			return n / pow
+		}
+		return n * pow
+	}
+	m := 1.0
+	for _, m = range []float64{1, 2, 5, 10} {
+		if top/value(m) <= 5 {
+			break
+		}
+	}
+	var ts []float64
+	for i := 0.0; ; i++ {
+		v := value(i * m)
+		ts = append(ts, v)
+		if v >= top-1e-9 {
+			return ts, v
+		}
+	}
+}
+
+// ScalingCharts draws speedup against GOMAXPROCS, one line per
+// workload and a dashed ideal that levels off at NumCPU, and notes each
+// workload's best setting
+func ScalingCharts(r ScalingReport) ([]LineChart, []string) {
+	c := LineChart{Title: "Speedup over GOMAXPROCS=1", XLabel: "GOMAXPROCS", YLabel: "speedup"}
+	var notes []string
+	top := 0
+	for _, row := range r.Rows {
+		i := slices.IndexFunc(c.Series, func(s Series) bool { return s.Name == row.Workload })
+		if i < 0 {
+			c.Series = append(c.Series, Series{Name: row.Workload})
+			i = len(c.Series) - 1
+		}
+		c.Series[i].Points = append(c.Series[i].Points, Point{X: float64(row.Procs), Y: row.Speedup})
+		top = max(top, row.Procs)
+	}
+	for _, s := range c.Series {
+		best := slices.MaxFunc(s.Points, func(a, b Point) int { return cmpFloat(a.Y, b.Y) })
+		notes = append(notes, fmt.Sprintf("%s: at best %.2fx, at GOMAXPROCS=%d (%.0f%% efficient)",
+			s.Name, best.Y, int(best.X), 100*best.Y/best.X))
+	}
+	ideal := Series{Name: "ideal", Color: "#718096", Dashed: true}
+	for p := 1; p <= top; p++ {
+		ideal.Points = append(ideal.Points, Point{X: float64(p), Y: float64(min(p, max(r.NumCPU, 1)))})
+	}
+	c.Series = append(c.Series, ideal)
+	if r.NumCPU > 0 && top > r.NumCPU {
+		c.Marks = append(c.Marks, Tick{float64(r.NumCPU), "NumCPU"})
+		notes = append(notes, fmt.Sprintf("past GOMAXPROCS=%d there are more Ps than CPUs: they take turns, so no line can rise", r.NumCPU))
+	}
+	if top < 2 {
+		notes = append(notes, "only GOMAXPROCS=1 was measured; run with -procs 1,2,4 or on a machine with more CPUs")
+	}
+	return []LineChart{c.layout()}, notes
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Rendering
+// =========
+
+// Report is everything the page shows
+type Report struct {
+	Title     string
+	Generated time.Time
+	GoVersion string
+	GOOS      string
+	GOARCH    string
+	CPU       string
+	NumCPU    int
+	Sections  []Section
+}
+
+// Section is one suite's charts and what they show
+type Section struct {
+	Name    string // the anchor in the page
+	Title   string
+	About   string
+	Command string // what produced the numbers
+	CPU     string
+	Bars    []BarChart
+	Lines   []LineChart
+	Notes   []string
+	Err     string
+}
+
+// Render writes the report as one HTML page. html/template escapes
+// every name and note, in text and in attributes alike
+func Render(w io.Writer, r Report) error {
+	return page.ExecuteTemplate(w, "report", r)
+}
+
+func number(v float64) string {
+	switch {
+	case v >= 100 || v == math.Trunc(v):
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	case v >= 1:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "benchreport:", err)
+	os.Exit(1)
+}
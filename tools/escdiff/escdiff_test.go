@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// escdiff - Tests
+// ===============
+// Run with:
+//
+//   cd tools/escdiff
+//   go test -v *.go
+//   go test -short -v *.go   only the tests that do not run the go command
+//
+// Only one toolchain is certain to be here, so the diff is tested on
+// -m output written by hand, and the real compiler only against itself.
+
+// requireGo is a per-package copy; metaprogramming/astindex checks that the copies match
+func requireGo(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command in PATH")
+	}
+}
+
+// oldOut and newOut are -m output for one file from two compilers that
+// disagree about a closure and one inlined call
+const oldOut = `# command-line-arguments
+./lesson.go:8:6: can inline add
+./lesson.go:12:2: moved to heap: p
+./lesson.go:20:10: func literal escapes to heap
+./lesson.go:25:12: leaking param: ps to result ~r0 level=0
+./lesson.go:30:11: make([]int, n) escapes to heap
+./other.go:3:2: moved to heap: q
+`
+
+const newOut = `# command-line-arguments
+./lesson.go:8:6: can inline add
+./lesson.go:14:13: inlining call to add
+./lesson.go:12:2: moved to heap: p
+./lesson.go:20:10: func literal does not escape
+./lesson.go:25:12: leaking param: ps to result ~r0 level=0
+./lesson.go:30:11: make([]int, n) escapes to heap
+./lesson.go:30:11: make([]int, n) escapes to heap
+`
+
+// 1. Reading -m
+// =============
+
+func TestParse(t *testing.T) {
+	got := Parse(oldOut, "/src/lesson.go", false)
+	want := []Decision{
+		{12, 2, "p", "moved to heap"},
+		{20, 10, "func literal", "escapes to heap"},
+		{25, 12, "ps to result ~r0 level=0", "leaking param"},
+		{30, 11, "make([]int, n)", "escapes to heap"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+	// -inline keeps the inlining lines; a message repeated is one decision
+	got = Parse(newOut, "lesson.go", true)
+	if len(got) != 6 || got[0].String() != "can inline: add" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestDecisionString(t *testing.T) {
+	for d, want := range map[Decision]string{
+		{Subject: "p", Verdict: "moved to heap"}:          "moved to heap: p",
+		{Subject: "&x", Verdict: "escapes to heap"}:       "&x escapes to heap",
+		{Subject: "ps", Verdict: "leaking param content"}: "leaking param content: ps",
+		{Subject: "add", Verdict: "inlining call to"}:     "inlining call to: add",
+	} {
+		if got := d.String(); got != want {
+			t.Errorf("%q, want %q", got, want)
+		}
+	}
+}
+
+func TestToolchainEnv(t *testing.T) {
+	if env := toolchainEnv("go1.23.12"); !slices.Contains(env, "GOTOOLCHAIN=go1.23.12") {
+		t.Errorf("version: %v", env)
+	}
+	for _, spec := range []string{"local", "/opt/go1.22/bin/go"} {
+		if env := toolchainEnv(spec); !slices.Contains(env, "GOTOOLCHAIN=local") || !slices.Contains(env, "GOFLAGS=") {
+			t.Errorf("%s: %v", spec, env)
+		}
+	}
+}
+
+// 2. Comparing
+// ============
+
+func TestDiff(t *testing.T) {
+	got := Diff(Parse(oldOut, "lesson.go", true), Parse(newOut, "lesson.go", true))
+	want := []Change{
+		{Line: 14, Col: 13, New: []string{"inlining call to: add"}},
+		{Line: 20, Col: 10, Old: []string{"func literal escapes to heap"}, New: []string{"func literal does not escape"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Line != want[i].Line || got[i].Col != want[i].Col ||
+			!slices.Equal(got[i].Old, want[i].Old) || !slices.Equal(got[i].New, want[i].New) {
+			t.Errorf("change %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if c := Diff(Parse(oldOut, "lesson.go", false), Parse(oldOut, "lesson.go", false)); c != nil {
+		t.Errorf("a file differs from itself: %v", c)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	old, new := Parse(oldOut, "lesson.go", false), Parse(newOut, "lesson.go", false)
+	r := Report{
+		Old: Toolchain{Spec: "go1.22.0", Version: "go1.22.0"},
+		New: Toolchain{Spec: "local", Version: "go1.27.1"},
+		Files: []FileReport{
+			{File: "/src/lesson.go", Old: count(old), New: count(new), Changes: Diff(old, new)},
+			{File: "/src/broken.go", Err: "does not build with go1.22.0: undefined: x"},
+		},
+	}
+	var buf bytes.Buffer
+	write(&buf, r)
+	out := buf.String()
+	for _, want := range []string{
+		"escdiff: go1.22.0 (go1.22.0) -> go1.27.1 (local)",
+		"lesson.go  3->2  0->1   1        1",
+		"lesson.go:20:10\n  - func literal escapes to heap\n  + func literal does not escape\n",
+		"broken.go: does not build with go1.22.0: undefined: x",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if !r.Changed() {
+		t.Error("Changed = false")
+	}
+}
+
+// 3. The Real Compiler
+// ====================
+
+// The go on PATH makes the decisions testdata/escapes.go was written
+// to show, and agrees with itself
+func TestLocal(t *testing.T) {
+	requireGo(t)
+	tc, err := resolve(t.Context(), "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := escapes(t.Context(), tc, "testdata/escapes.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range Parse(out, "escapes.go", false) {
+		got = append(got, d.String())
+	}
+	for _, want := range []string{"moved to heap: p", "ps does not escape", "leaking param: ps to result ~r0 level=1"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("missing %q in %q", want, got)
+		}
+	}
+	if fr := compare(t.Context(), tc, tc, "testdata/escapes.go", true); fr.Err != "" || len(fr.Changes) > 0 {
+		t.Errorf("%s against itself: %+v", tc.Version, fr)
+	}
+}
+
+// The escape lessons still build, so the tool's default list is usable
+func TestLessonsBuild(t *testing.T) {
+	requireGo(t)
+	tc, err := resolve(t.Context(), "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := defaultFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if _, err := escapes(t.Context(), tc, f); err != nil {
+			t.Errorf("%s: %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// escdiff - Escape Analysis Decisions Across Go Versions
+// ======================================================
+// escdiff compiles lessons with -gcflags=-m under two toolchains and
+// reports where the compiler decided differently:
+//
+//   go run tools/escdiff/main.go -old go1.23.12                  the escape lessons, against the go on PATH
+//   go run tools/escdiff/main.go -old go1.22.0 -new go1.24.0 memory-model/stack_heap_examples.go
+//   go run tools/escdiff/main.go -old ~/sdk/go1.23.12/bin/go -inline lesson.go
+//   go run tools/escdiff/main.go -json -old go1.23.12 > escape.json
+//
+// A toolchain is a Go version, which the go command fetches through
+// GOTOOLCHAIN the first time (it needs the module proxy once, then the
+// module cache), "local" for the go on PATH, or the path of a go binary,
+// such as one installed by golang.org/dl.
+//
+// The decisions compared are -m's verdicts on values: moved to heap,
+// escapes to heap, does not escape, leaking param. -inline adds the
+// inlining decisions, which often explain the rest: a call that is no
+// longer inlined can move its caller's values to the heap.
+//
+// Rules:
+//   - each file is built on its own, the way the lessons are run
+//   - decisions are matched by position, so a changed verdict shows as
+//     a - line and a + line under one file:line:col
+//   - a file that does not build under a toolchain is listed, not
+//     compared
+//   - the exit status is 1 if any decision differs, so a CI job can
+//     watch that the lessons still say what the compiler does
+//
+// With no files it compares memory-model's escape analysis lessons.
+
+// lessons are the files compared when none are named, relative to the
+// repository root
+var lessons = []string{
+	"memory-model/escape_analysis_checker.go",
+	"memory-model/escape_analysis_detailed.go",
+	"memory-model/escape_analysis_examples.go",
+	"memory-model/performance_implications.go",
+	"memory-model/stack_heap_examples.go",
+}
+
+func main() {
+	oldFlag := flag.String("old", "", "the toolchain to compare from: a version, local, or a go binary")
+	newFlag := flag.String("new", "local", "the toolchain to compare to")
+	inline := flag.Bool("inline", false, "compare inlining decisions too")
+	asJSON := flag.Bool("json", false, "write the report as JSON")
+	timeout := flag.Duration("timeout", 5*time.Minute, "how long to wait for a toolchain to download")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: escdiff -old toolchain [-new toolchain] [flags] [file.go...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *oldFlag == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		var err error
+		if files, err = defaultFiles(); err != nil {
+			fail(err)
+		}
+	}
+	var toolchains [2]Toolchain
+	for i, spec := range []string{*oldFlag, *newFlag} {
+		tctx, cancel := context.WithTimeout(ctx, *timeout)
+		tc, err := resolve(tctx, spec)
+		cancel()
+		if err != nil {
+			fail(fmt.Errorf("toolchain %s is not available: %w", spec, err))
+		}
+		toolchains[i] = tc
+	}
+
+	report := Report{Old: toolchains[0], New: toolchains[1]}
+	for _, f := range files {
+		report.Files = append(report.Files, compare(ctx, report.Old, report.New, f, *inline))
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fail(err)
+		}
+	} else {
+		write(os.Stdout, report)
+	}
+	if report.Changed() {
+		stop()
+		os.Exit(1)
+	}
+}
+
+// defaultFiles finds the lessons from this file's own path, so the
+// tool works from any directory of the repository
+func defaultFiles() ([]string, error) {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return nil, errors.New("name the files to compare")
+	}
+	root := filepath.Join(filepath.Dir(file), "..", "..")
+	var files []string
+	for _, l := range lessons {
+		files = append(files, filepath.Join(root, filepath.FromSlash(l)))
+	}
+	return files, nil
+}
+
+// Toolchain is a go command and the version it reported
+type Toolchain struct {
+	Spec    string // as given: go1.23.12, local or a path
+	Version string // go env GOVERSION
+	goCmd   string
+	env     []string
+}
+
+// resolve turns a -old or -new value into a go command, and asks it for
+// its version: with GOTOOLCHAIN set, that is when the download happens
+func resolve(ctx context.Context, spec string) (Toolchain, error) {
+	tc := Toolchain{Spec: spec, goCmd: "go", env: toolchainEnv(spec)}
+	if strings.ContainsRune(spec, filepath.Separator) || strings.HasPrefix(spec, "~") {
+		tc.goCmd = spec
+		if rest, ok := strings.CutPrefix(spec, "~"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return tc, err
+			}
+			tc.goCmd = filepath.Join(home, rest)
+		}
+	}
+	cmd := exec.CommandContext(ctx, tc.goCmd, "env", "GOVERSION")
+	cmd.Env = append(os.Environ(), tc.env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return tc, fmt.Errorf("gave up after -timeout: %w", ctx.Err())
+		}
+		return tc, errors.New(firstLine(out, err))
+	}
+	tc.Version = strings.TrimSpace(string(out))
+	return tc, nil
+}
+
+// toolchainEnv is the environment that makes the go command run spec's
+// compiler: a version goes in GOTOOLCHAIN, and anything else runs the
+// go command it names as it is
+func toolchainEnv(spec string) []string {
+	env := []string{"GOFLAGS=", "GOWORK=off"}
+	if strings.HasPrefix(spec, "go1") {
+		return append(env, "GOTOOLCHAIN="+spec)
+	}
+	return append(env, "GOTOOLCHAIN=local")
+}
+
+// Decision is one -m verdict on one value or function
+type Decision struct {
+	Line, Col int
+	Subject   string // the variable, expression or function
+	Verdict   string // moved to heap, escapes to heap, ...
+}
+
+func (d Decision) String() string {
+	if d.Verdict == "moved to heap" || strings.HasPrefix(d.Verdict, "leaking") || d.Verdict == "can inline" || d.Verdict == "inlining call to" {
+		return d.Verdict + ": " + d.Subject
+	}
+	return d.Subject + " " + d.Verdict
+}
+
+// patterns recognise the verdicts -m prints, most specific first
+var patterns = []struct {
+	re      *regexp.Regexp
+	verdict string
+	inline  bool
+}{
+	{regexp.MustCompile(`^moved to heap: (.+)$`), "moved to heap", false},
+	{regexp.MustCompile(`^leaking param content: (.+)$`), "leaking param content", false},
+	{regexp.MustCompile(`^leaking param: (.+)$`), "leaking param", false},
+	{regexp.MustCompile(`^(.+) escapes to heap$`), "escapes to heap", false},
+	{regexp.MustCompile(`^(.+) does not escape$`), "does not escape", false},
+	{regexp.MustCompile(`^can inline (\S+)`), "can inline", true},
+	{regexp.MustCompile(`^inlining call to (.+)$`), "inlining call to", true},
+}
+
+// position matches the start of a compiler message: ./file.go:12:6:
+var position = regexp.MustCompile(`^(?:\./)?([^:]+\.go):(\d+):(\d+): (.+)$`)
+
+// Parse picks file's decisions out of the compiler's -m output
+func Parse(out, file string, inline bool) []Decision {
+	var ds []Decision
+	for line := range strings.Lines(out) {
+		m := position.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil || filepath.Base(m[1]) != filepath.Base(file) {
+			continue
+		}
+		for _, p := range patterns {
+			if p.inline && !inline {
+				continue
+			}
+			if sub := p.re.FindStringSubmatch(m[4]); sub != nil {
+				ln, _ := strconv.Atoi(m[2])
+				col, _ := strconv.Atoi(m[3])
+				ds = append(ds, Decision{ln, col, sub[1], p.verdict})
+				break
+			}
+		}
+	}
+	slices.SortFunc(ds, func(a, b Decision) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Col, b.Col), strings.Compare(a.String(), b.String()))
+	})
+	return slices.Compact(ds)
+}
+
+// Counts sum a file's decisions: heap is moved to heap plus escapes to
+// heap, stack is does not escape
+type Counts struct {
+	Heap, Stack, Leaks int
+}
+
+func count(ds []Decision) Counts {
+	var c Counts
+	for _, d := range ds {
+		switch d.Verdict {
+		case "moved to heap", "escapes to heap":
+			c.Heap++
+		case "does not escape":
+			c.Stack++
+		case "leaking param", "leaking param content":
+			c.Leaks++
+		}
+	}
+	return c
+}
+
+// Change is one position where the toolchains decided differently
+type Change struct {
+	Line, Col int
+	Old, New  []string // the decisions only that toolchain made
+}
+
+// Diff matches decisions by position and returns the positions whose
+// decisions differ
+func Diff(old, new []Decision) []Change {
+	type pos struct{ line, col int }
+	byPos := map[pos]*Change{}
+	var order []pos
+	add := func(d Decision, isNew bool) {
+		p := pos{d.Line, d.Col}
+		c, ok := byPos[p]
+		if !ok {
+			c = &Change{Line: d.Line, Col: d.Col}
+			byPos[p] = c
+			order = append(order, p)
+		}
+		if isNew {
+			c.New = append(c.New, d.String())
+		} else {
+			c.Old = append(c.Old, d.String())
+		}
+	}
+	for _, d := range old {
+		if !slices.Contains(new, d) {
+			add(d, false)
+		}
+	}
+	for _, d := range new {
+		if !slices.Contains(old, d) {
+			add(d, true)
+		}
+	}
+	slices.SortFunc(order, func(a, b pos) int { return cmp.Or(cmp.Compare(a.line, b.line), cmp.Compare(a.col, b.col)) })
+	var changes []Change
+	for _, p := range order {
+		changes = append(changes, *byPos[p])
+	}
+	return changes
+}
+
+// FileReport is one file under both toolchains
+type FileReport struct {
+	File     string
+	Old, New Counts
+	Changes  []Change
+	Err      string `json:",omitempty"` // the file did not build
+}
+
+// Report is what -json writes
+type Report struct {
+	Old, New Toolchain
+	Files    []FileReport
+}
+
+// Changed reports whether any decision differs
+func (r Report) Changed() bool {
+	return slices.ContainsFunc(r.Files, func(f FileReport) bool { return len(f.Changes) > 0 })
+}
+
+// compare builds file under both toolchains and diffs their decisions
+func compare(ctx context.Context, old, new Toolchain, file string, inline bool) FileReport {
+	fr := FileReport{File: file}
+	var decisions [2][]Decision
+	for i, tc := range []Toolchain{old, new} {
+		out, err := escapes(ctx, tc, file)
+		if err != nil {
+			fr.Err = fmt.Sprintf("does not build with %s: %v", tc.Version, err)
+			return fr
+		}
+		decisions[i] = Parse(out, file, inline)
+	}
+	fr.Old, fr.New = count(decisions[0]), count(decisions[1])
+	fr.Changes = Diff(decisions[0], decisions[1])
+	return fr
+}
+
+// escapes runs go build -gcflags=-m on one file and returns what the
+// compiler printed. The go command replays the messages of a cached
+// build, so a second run is quick and prints the same
+func escapes(ctx context.Context, tc Toolchain, file string) (string, error) {
+	cmd := exec.CommandContext(ctx, tc.goCmd, "build", "-gcflags=-m", "-o", os.DevNull, filepath.Base(file))
+	cmd.Dir = filepath.Dir(file)
+	cmd.Env = append(os.Environ(), tc.env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.New(firstLine(out, err))
+	}
+	return string(out), nil
+}
+
+// firstLine is the first line of a failed command's output that is
+// not a package header, or the error if it printed nothing
+func firstLine(out []byte, err error) string {
+	for line := range strings.Lines(string(bytes.TrimSpace(out))) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return err.Error()
+}
+
+// write prints a table of counts, then each change as - and + lines
+func write(w io.Writer, r Report) {
+	fmt.Fprintf(w, "escdiff: %s (%s) -> %s (%s)\n", r.Old.Version, r.Old.Spec, r.New.Version, r.New.Spec)
+	if r.Old.Version == r.New.Version {
+		fmt.Fprintln(w, "both are the same version: nothing can differ")
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "file\theap\tstack\tleaking\tchanged\t")
+	for _, f := range r.Files {
+		if f.Err != "" {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t\n", filepath.Base(f.File),
+			arrow(f.Old.Heap, f.New.Heap), arrow(f.Old.Stack, f.New.Stack), arrow(f.Old.Leaks, f.New.Leaks), len(f.Changes))
+	}
+	tw.Flush()
+
+	for _, f := range r.Files {
+		for _, c := range f.Changes {
+			fmt.Fprintf(w, "\n%s:%d:%d\n", filepath.Base(f.File), c.Line, c.Col)
+			for _, d := range c.Old {
+				fmt.Fprintf(w, "  - %s\n", d)
+			}
+			for _, d := range c.New {
+				fmt.Fprintf(w, "  + %s\n", d)
+			}
+		}
+	}
+	for _, f := range r.Files {
+		if f.Err != "" {
+			fmt.Fprintf(w, "\n%s: %s\n", filepath.Base(f.File), f.Err)
+		}
+	}
+}
+
+// arrow shows a count that stayed the same once, and one that changed
+// as old->new
+func arrow(old, new int) string {
+	if old == new {
+		return strconv.Itoa(old)
+	}
+	return fmt.Sprintf("%d->%d", old, new)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "escdiff:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+// escdiff's test program: one value of each kind the tool reports
+
+type point struct{ x, y int }
+
+//go:noinline
+func newPoint(x, y int) *point {
+	p := point{x, y}
+	return &p
+}
+
+//go:noinline
+func sum(ps []point) int {
+	n := 0
+	for _, p := range ps {
+		n += p.x + p.y
+	}
+	return n
+}
+
+//go:noinline
+func first(ps []*point) *point {
+	return ps[0]
+}
+
+func main() {
+	ps := make([]point, 4)
+	fmt.Println(sum(ps), first([]*point{newPoint(1, 2)}).x)
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// scaling - How Far a Workload Scales With GOMAXPROCS
+// ===================================================
+// scaling runs a fixed amount of work at GOMAXPROCS=1, 2, ... N and
+// reports how much faster each setting finished than the first:
+//
+//   go run tools/scaling/main.go                      every workload, 1..NumCPU
+//   go run tools/scaling/main.go -work cpu -procs 1,2,4,8,16
+//   go run tools/scaling/main.go -json > scaling.json
+//
+// The workloads:
+//   - cpu: arithmetic on registers; nothing shared, nothing allocated
+//   - alloc: builds and walks binary trees, so the allocator and the
+//     garbage collector do most of the work
+//   - serial: cpu with a tenth of every chunk under one global mutex,
+//     a known serial fraction to check the fit against
+//
+// Each run splits the chunks between GOMAXPROCS goroutines, which take
+// the next chunk from an atomic counter until none are left, so a slow
+// goroutine does not hold the others up. The time reported is the best
+// of -runs, the run least disturbed by the rest of the machine.
+//
+// For each setting it prints the speedup over GOMAXPROCS=1, the
+// efficiency (speedup / GOMAXPROCS), and the Karp-Flatt metric: the
+// serial fraction Amdahl's law would need to explain that speedup. A
+// fit over all settings gives the ceiling no number of cores can pass.
+// concurrency/amdahl reads the -json output and explains it.
+
+// workload is work split into chunks that goroutines can run in any
+// order
+type workload struct {
+	name, about string
+	chunks      int
+	chunk       func() int
+}
+
+var serialMu sync.Mutex
+
+var workloads = []workload{
+	{"cpu", "Spin(20000) per chunk: no shared state, no allocation", 4000, func() int {
+		return spin(20000)
+	}},
+	{"alloc", "a binary tree of 2047 nodes per chunk, built and walked", 4000, func() int {
+		return newTree(10).count()
+	}},
+	{"serial", "Spin(18000), then Spin(2000) holding one global mutex", 4000, func() int {
+		n := spin(18000)
+		serialMu.Lock()
+		defer serialMu.Unlock()
+		return n + spin(2000)
+	}},
+}
+
+// spin does rounds of xorshift: work the compiler cannot skip
+func spin(rounds int) int {
+	x := uint64(rounds) | 1
+	for range rounds {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	return int(x & 1)
+}
+
+type tree struct {
+	left, right *tree
+}
+
+func newTree(depth int) *tree {
+	if depth == 0 {
+		return &tree{}
+	}
+	return &tree{newTree(depth - 1), newTree(depth - 1)}
+}
+
+func (t *tree) count() int {
+	if t.left == nil {
+		return 1
+	}
+	return 1 + t.left.count() + t.right.count()
+}
+
+// Row is one workload at one GOMAXPROCS
+type Row struct {
+	Workload   string
+	Procs      int
+	Seconds    float64
+	Speedup    float64 // time at GOMAXPROCS=1 / time at this one
+	Efficiency float64 // Speedup / Procs
+	KarpFlatt  float64 // the serial fraction that explains Speedup; 0 at one proc
+}
+
+// Report is what -json writes
+type Report struct {
+	GoVersion string
+	GOOS      string
+	GOARCH    string
+	NumCPU    int
+	Rows      []Row
+}
+
+var sink atomic.Int64
+
+func main() {
+	procsFlag := flag.String("procs", "", "comma-separated GOMAXPROCS values (default 1..NumCPU)")
+	workFlag := flag.String("work", "cpu,alloc,serial", "comma-separated workloads")
+	runs := flag.Int("runs", 3, "runs per setting; the fastest counts")
+	scale := flag.Float64("scale", 1, "multiply every workload's chunk count")
+	asJSON := flag.Bool("json", false, "write the report as JSON")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: scaling [flags]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 || *runs < 1 || *scale <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	procs, err := parseProcs(*procsFlag, runtime.NumCPU())
+	if err != nil {
+		fail(err)
+	}
+	selected, err := pick(*workFlag)
+	if err != nil {
+		fail(err)
+	}
+	for i := range selected {
+		selected[i].chunks = max(1, int(*scale*float64(selected[i].chunks)))
+	}
+
+	report := Report{runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), nil}
+	for _, w := range selected {
+		var times []time.Duration
+		for _, p := range procs {
+			best := time.Duration(math.MaxInt64)
+			for range *runs {
+				best = min(best, measure(w, p))
+			}
+			times = append(times, best)
+		}
+		report.Rows = append(report.Rows, Rows(w.name, procs, times)...)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fail(err)
+		}
+		return
+	}
+	fmt.Printf("scaling: %s %s/%s, %d CPUs, best of %d runs\n", report.GoVersion, report.GOOS, report.GOARCH, report.NumCPU, *runs)
+	for _, w := range selected {
+		var rows []Row
+		for _, r := range report.Rows {
+			if r.Workload == w.name {
+				rows = append(rows, r)
+			}
+		}
+		fmt.Printf("\n%s: %d chunks, %s\n", w.name, w.chunks, w.about)
+		table(os.Stdout, rows, report.NumCPU)
+		fmt.Println()
+		chart(os.Stdout, rows, 40)
+		if s, ok := Fit(rows, report.NumCPU); ok {
+			fmt.Printf("Amdahl fit: serial fraction %.3f, so at most %s on any number of cores\n", s, ceiling(s))
+		} else {
+			fmt.Println("no Amdahl fit: it needs a GOMAXPROCS between 2 and NumCPU")
+		}
+	}
+}
+
+// measure runs w's chunks on procs goroutines at GOMAXPROCS=procs and
+// returns the wall time
+func measure(w workload, procs int) time.Duration {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+	runtime.GC() // start every run with the same heap
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range procs {
+		wg.Go(func() {
+			n := 0
+			for next.Add(1) <= int64(w.chunks) {
+				n += w.chunk()
+			}
+			sink.Add(int64(n))
+		})
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+// Rows turns the times for one workload into rows, relative to the
+// time at GOMAXPROCS=1, which procs must include
+func Rows(name string, procs []int, times []time.Duration) []Row {
+	base := times[slices.Index(procs, 1)]
+	var rows []Row
+	for i, p := range procs {
+		speedup := base.Seconds() / times[i].Seconds()
+		rows = append(rows, Row{
+			Workload:   name,
+			Procs:      p,
+			Seconds:    times[i].Seconds(),
+			Speedup:    speedup,
+			Efficiency: speedup / float64(p),
+			KarpFlatt:  KarpFlatt(speedup, p),
+		})
+	}
+	return rows
+}
+
+// KarpFlatt is the serial fraction e for which Amdahl's law,
+// speedup = 1 / (e + (1-e)/p), gives the measured speedup at p procs.
+// If e grows with p, the overhead is growing too - contention, GC, the
+// memory bus - not a fixed serial part
+func KarpFlatt(speedup float64, p int) float64 {
+	if p <= 1 {
+		return 0
+	}
+	return (1/speedup - 1/float64(p)) / (1 - 1/float64(p))
+}
+
+// Fit finds the serial fraction s that best explains the rows with
+// Amdahl's law, by least squares on 1/speedup - 1/p = s(1 - 1/p).
+// Rows above numCPU are left out: Amdahl's p is processors, and extra
+// Ps only take turns on the same ones. ok is false if no row is left
+// above one proc
+func Fit(rows []Row, numCPU int) (s float64, ok bool) {
+	var xy, xx float64
+	for _, r := range rows {
+		if r.Procs <= 1 || r.Procs > numCPU {
+			continue
+		}
+		x := 1 - 1/float64(r.Procs)
+		y := 1/r.Speedup - 1/float64(r.Procs)
+		xy += x * y
+		xx += x * x
+	}
+	if xx == 0 {
+		return 0, false
+	}
+	return min(max(xy/xx, 0), 1), true
+}
+
+// ceiling is Amdahl's limit 1/s, written for a person
+func ceiling(s float64) string {
+	if s < 0.001 {
+		return "a speedup as large as the core count"
+	}
+	return fmt.Sprintf("%.1fx", 1/s)
+}
+
+func table(w io.Writer, rows []Row, numCPU int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GOMAXPROCS\ttime\tspeedup\tefficiency\tKarp-Flatt\t")
+	for _, r := range rows {
+		kf, note := "", ""
+		if r.Procs > 1 {
+			kf = fmt.Sprintf("%.3f", r.KarpFlatt)
+		}
+		if r.Procs > numCPU {
+			note = "more than NumCPU"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%.2fx\t%.0f%%\t%s\t%s\n", r.Procs,
+			time.Duration(r.Seconds*float64(time.Second)).Round(time.Millisecond), r.Speedup, 100*r.Efficiency, kf, note)
+	}
+	tw.Flush()
+}
+
+// chart draws each row's speedup as a bar, with | where a perfect
+// speedup would end. width is the length of the longest ideal bar
+func chart(w io.Writer, rows []Row, width int) {
+	top := slices.MaxFunc(rows, func(a, b Row) int { return a.Procs - b.Procs }).Procs
+	per := float64(width) / float64(top)
+	fmt.Fprintln(w, "speedup (# measured, | ideal):")
+	for _, r := range rows {
+		ideal := int(math.Round(float64(r.Procs) * per))
+		got := min(int(math.Round(r.Speedup*per)), width)
+		bar := strings.Repeat("#", got)
+		if got < ideal {
+			bar += strings.Repeat(" ", ideal-got-1) + "|"
+		}
+		fmt.Fprintf(w, "%4d %-*s %5.2fx\n", r.Procs, width, bar, r.Speedup)
+	}
+}
+
+// parseProcs reads "2,4,8" and adds 1, the base every speedup is
+// measured from, if it is missing. Empty means 1 to numCPU, or powers
+// of two and numCPU itself on machines with more than 16
+func parseProcs(s string, numCPU int) ([]int, error) {
+	if s == "" {
+		var procs []int
+		for p := 1; p <= numCPU; p++ {
+			if numCPU <= 16 || p&(p-1) == 0 || p == numCPU {
+				procs = append(procs, p)
+			}
+		}
+		return procs, nil
+	}
+	var procs []int
+	for f := range strings.SplitSeq(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("-procs: %q is not a GOMAXPROCS value", f)
+		}
+		procs = append(procs, n)
+	}
+	if !slices.Contains(procs, 1) {
+		procs = append([]int{1}, procs...)
+	}
+	return procs, nil
+}
+
+// pick returns the named workloads, in the order given
+func pick(names string) ([]workload, error) {
+	var picked []workload
+	for name := range strings.SplitSeq(names, ",") {
+		i := slices.IndexFunc(workloads, func(w workload) bool { return w.name == strings.TrimSpace(name) })
+		if i < 0 {
+			return nil, errors.New("-work: no workload " + strconv.Quote(name))
+		}
+		picked = append(picked, workloads[i])
+	}
+	return picked, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "scaling:", err)
+	os.Exit(1)
+}
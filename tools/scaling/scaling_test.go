@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scaling - Tests
+// ===============
+// Run with:
+//
+//   cd tools/scaling
+//   go test -v *.go
+//
+// The arithmetic is checked on made-up times that follow Amdahl's law
+// exactly; real runs depend on the machine, so only their shape is.
+
+// amdahlTimes returns the times a program with serial fraction s would
+// take at each of procs, if it took one second on one
+func amdahlTimes(s float64, procs []int) []time.Duration {
+	var times []time.Duration
+	for _, p := range procs {
+		times = append(times, time.Duration(float64(time.Second)*(s+(1-s)/float64(p))))
+	}
+	return times
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-3 }
+
+// 1. The Numbers
+// ==============
+
+func TestRows(t *testing.T) {
+	procs := []int{1, 2, 4, 8}
+	rows := Rows("x", procs, amdahlTimes(0.1, procs))
+	for i, want := range []float64{1, 1 / 0.55, 1 / 0.325, 1 / 0.2125} {
+		if !near(rows[i].Speedup, want) {
+			t.Errorf("procs %d: speedup %.3f, want %.3f", rows[i].Procs, rows[i].Speedup, want)
+		}
+		if !near(rows[i].Efficiency, want/float64(procs[i])) {
+			t.Errorf("procs %d: efficiency %.3f", rows[i].Procs, rows[i].Efficiency)
+		}
+	}
+	// The base is GOMAXPROCS=1 wherever it is in the list
+	rows = Rows("x", []int{4, 1}, []time.Duration{time.Second, 2 * time.Second})
+	if !near(rows[0].Speedup, 2) || !near(rows[1].Speedup, 1) {
+		t.Errorf("speedups %.2f, %.2f; want 2, 1", rows[0].Speedup, rows[1].Speedup)
+	}
+}
+
+// On an exact Amdahl curve Karp-Flatt is the serial fraction at every p
+func TestKarpFlatt(t *testing.T) {
+	procs := []int{1, 2, 3, 8, 64}
+	for _, r := range Rows("x", procs, amdahlTimes(0.05, procs)) {
+		want := 0.05
+		if r.Procs == 1 {
+			want = 0
+		}
+		if !near(r.KarpFlatt, want) {
+			t.Errorf("procs %d: Karp-Flatt %.4f, want %.2f", r.Procs, r.KarpFlatt, want)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	procs := []int{1, 2, 4, 8, 16}
+	for _, s := range []float64{0, 0.02, 0.1, 0.5} {
+		got, ok := Fit(Rows("x", procs, amdahlTimes(s, procs)), 16)
+		if !ok || !near(got, s) {
+			t.Errorf("Fit = %.4f, %t; want %.2f", got, ok, s)
+		}
+	}
+	// Superlinear speedups clamp at 0 rather than going negative
+	rows := Rows("x", []int{1, 2}, []time.Duration{time.Second, time.Second / 3})
+	if got, _ := Fit(rows, 2); got != 0 {
+		t.Errorf("superlinear: Fit = %.3f, want 0", got)
+	}
+}
+
+// Rows beyond NumCPU are taking turns, not running in parallel
+func TestFitSkipsOversubscribed(t *testing.T) {
+	procs := []int{1, 2, 4, 8}
+	rows := Rows("x", procs, amdahlTimes(0.1, []int{1, 2, 4, 4}))
+	got, ok := Fit(rows, 4)
+	if !ok || !near(got, 0.1) {
+		t.Errorf("Fit = %.4f, %t; want 0.1 from procs 2 and 4 only", got, ok)
+	}
+	if _, ok := Fit(rows, 1); ok {
+		t.Error("Fit with one CPU succeeded")
+	}
+}
+
+// 2. The Output
+// =============
+
+func TestChart(t *testing.T) {
+	rows := []Row{{Procs: 1, Speedup: 1}, {Procs: 2, Speedup: 1.5}, {Procs: 4, Speedup: 4}}
+	var buf bytes.Buffer
+	chart(&buf, rows, 8)
+	want := "speedup (# measured, | ideal):\n" +
+		"   1 ##        1.00x\n" +
+		"   2 ###|      1.50x\n" +
+		"   4 ########  4.00x\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTable(t *testing.T) {
+	rows := Rows("x", []int{1, 2}, []time.Duration{time.Second, 600 * time.Millisecond})
+	var buf bytes.Buffer
+	table(&buf, rows, 1)
+	out := buf.String()
+	for _, want := range []string{"1.67x", "83%", "0.200", "more than NumCPU"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestParseProcs(t *testing.T) {
+	tests := []struct {
+		in     string
+		numCPU int
+		want   []int
+	}{
+		{"", 4, []int{1, 2, 3, 4}},
+		{"", 24, []int{1, 2, 4, 8, 16, 24}},
+		{"2, 4", 8, []int{1, 2, 4}},
+		{"1,3", 8, []int{1, 3}},
+	}
+	for _, tt := range tests {
+		got, err := parseProcs(tt.in, tt.numCPU)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("parseProcs(%q, %d) = %v, %v; want %v", tt.in, tt.numCPU, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"0", "two", "1,,2"} {
+		if _, err := parseProcs(bad, 4); err == nil {
+			t.Errorf("parseProcs(%q) succeeded", bad)
+		}
+	}
+	if _, err := pick("cpu,nope"); err == nil {
+		t.Error("pick found a workload called nope")
+	}
+}
+
+// 3. Running
+// ==========
+
+// Every chunk runs exactly once, whatever the GOMAXPROCS
+func TestMeasureRunsEveryChunk(t *testing.T) {
+	for _, procs := range []int{1, 3, 8} {
+		var calls atomic.Int64
+		measure(workload{name: "count", chunks: 1000, chunk: func() int { calls.Add(1); return 0 }}, procs)
+		if calls.Load() != 1000 {
+			t.Errorf("GOMAXPROCS=%d: %d chunks run, want 1000", procs, calls.Load())
+		}
+	}
+}
+
+func TestWorkloads(t *testing.T) {
+	if got := newTree(10).count(); got != 2047 {
+		t.Errorf("tree of depth 10 has %d nodes, want 2047", got)
+	}
+	for _, w := range workloads {
+		w.chunk() // none panics, and serial releases its lock
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// xbuild - Cross-Compile a Lesson for Many Targets
+// ================================================
+// xbuild builds one program for a list of GOOS/GOARCH targets and
+// reports the size of each binary, as built and with symbols stripped:
+//
+//   go run tools/xbuild/main.go toolchain/platforms/testdata/probe
+//   go run tools/xbuild/main.go -all -o /tmp/bins projects/bookshelf
+//   go run tools/xbuild/main.go -targets linux/386,windows/arm64 lesson/
+//
+// The program is a directory or a list of .go files. Either way the
+// non-test files are copied into one package in a temp module, so build
+// constraints apply - a plain go build *.go would ignore them - and a
+// lesson with per-platform files builds once per target.
+//
+// Rules:
+//   - CGO_ENABLED=0 unless -cgo: cross-compiling cgo needs a C
+//     toolchain for each target
+//   - "stripped" is -trimpath -ldflags="-s -w": no symbol table or
+//     DWARF; panics still print file and line
+//   - the exit status is 1 if any target failed
+//
+// Only the standard library is available to the copied program: a
+// lesson importing a third-party module fails for every target.
+
+// defaultTargets cover the common operating systems, both word sizes
+// and both WebAssembly hosts
+const defaultTargets = "linux/amd64,linux/arm64,linux/386,darwin/arm64,windows/amd64,freebsd/amd64,js/wasm,wasip1/wasm"
+
+func main() {
+	targetList := flag.String("targets", defaultTargets, "comma-separated GOOS/GOARCH pairs")
+	all := flag.Bool("all", false, "every first-class port (go tool dist list)")
+	cgo := flag.Bool("cgo", false, "build with CGO_ENABLED=1")
+	out := flag.String("o", "", "keep the binaries in this directory")
+	jobs := flag.Int("j", runtime.NumCPU(), "builds to run at once")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: xbuild [flags] dir | file.go...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *jobs < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	targets := strings.Split(*targetList, ",")
+	if *all {
+		var err error
+		if targets, err = firstClass(ctx); err != nil {
+			fail(err)
+		}
+	}
+
+	work, err := os.MkdirTemp("", "xbuild-")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(work)
+	name, err := copySources(filepath.Join(work, "src"), flag.Args())
+	if err != nil {
+		fail(err)
+	}
+	bin := *out
+	if bin == "" {
+		bin = filepath.Join(work, "bin")
+	}
+	if bin, err = filepath.Abs(bin); err != nil {
+		fail(err)
+	}
+	if err := os.MkdirAll(bin, 0o755); err != nil {
+		fail(err)
+	}
+
+	b := &builder{src: filepath.Join(work, "src"), bin: bin, name: name, cgo: *cgo}
+	results := make([]result, len(targets))
+	sem := make(chan struct{}, *jobs)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = b.build(ctx, strings.TrimSpace(t))
+		})
+	}
+	wg.Wait()
+
+	fmt.Printf("%s, %s, CGO_ENABLED=%d\n\n", name, runtime.Version(), b.cgoValue())
+	failed := report(os.Stdout, results)
+	if *out != "" {
+		fmt.Printf("\nbinaries in %s\n", bin)
+	}
+	if failed {
+		stop()
+		os.Exit(1)
+	}
+}
+
+// copySources copies the program into dir as a module and returns its
+// name, taken from the directory or the first file
+func copySources(dir string, args []string) (string, error) {
+	files := args
+	name := strings.TrimSuffix(filepath.Base(args[0]), ".go")
+	if fi, err := os.Stat(args[0]); err == nil && fi.IsDir() {
+		if len(args) > 1 {
+			return "", errors.New("give one directory or a list of files")
+		}
+		abs, err := filepath.Abs(args[0])
+		if err != nil {
+			return "", err
+		}
+		name = filepath.Base(abs)
+		if files, err = filepath.Glob(filepath.Join(args[0], "*.go")); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	n := 0
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		if !strings.HasSuffix(f, ".go") {
+			return "", fmt.Errorf("%s: not a .go file", f)
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(f)), data, 0o644); err != nil {
+			return "", err
+		}
+		n++
+	}
+	if n == 0 {
+		return "", fmt.Errorf("%s: no .go files", strings.Join(args, " "))
+	}
+	gomod := "module xbuild.example/" + name + "\n\ngo 1.24\n"
+	return name, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644)
+}
+
+// firstClass lists the ports the Go project treats as first class
+func firstClass(ctx context.Context) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "go", "tool", "dist", "list", "-json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("go tool dist list: %w", err)
+	}
+	var ports []struct {
+		GOOS, GOARCH string
+		FirstClass   bool
+	}
+	if err := json.Unmarshal(out, &ports); err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, p := range ports {
+		if p.FirstClass {
+			targets = append(targets, p.GOOS+"/"+p.GOARCH)
+		}
+	}
+	return targets, nil
+}
+
+// builder builds the copied program for one target at a time
+type builder struct {
+	src, bin, name string
+	cgo            bool
+}
+
+// result is one target's row in the report
+type result struct {
+	target         string
+	size, stripped int64
+	elapsed        time.Duration
+	err            error
+}
+
+func (b *builder) cgoValue() int {
+	if b.cgo {
+		return 1
+	}
+	return 0
+}
+
+// build compiles the target twice: as go build does by default, and
+// stripped
+func (b *builder) build(ctx context.Context, target string) result {
+	r := result{target: target}
+	goos, goarch, ok := strings.Cut(target, "/")
+	if !ok || goos == "" || goarch == "" {
+		r.err = errors.New("want GOOS/GOARCH")
+		return r
+	}
+	exe := filepath.Join(b.bin, b.name+"-"+goos+"-"+goarch)
+	if goos == "windows" {
+		exe += ".exe"
+	}
+	start := time.Now()
+	if r.size, r.err = b.goBuild(ctx, goos, goarch, exe); r.err != nil {
+		return r
+	}
+	r.elapsed = time.Since(start)
+	stripped := strings.TrimSuffix(exe, ".exe") + "-stripped" + filepath.Ext(exe)
+	r.stripped, r.err = b.goBuild(ctx, goos, goarch, stripped, "-trimpath", "-ldflags=-s -w")
+	return r
+}
+
+// goBuild runs go build and returns the binary's size
+func (b *builder) goBuild(ctx context.Context, goos, goarch, exe string, flags ...string) (int64, error) {
+	args := append([]string{"build", "-o", exe}, flags...)
+	cmd := exec.CommandContext(ctx, "go", append(args, ".")...)
+	cmd.Dir = b.src
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch,
+		fmt.Sprintf("CGO_ENABLED=%d", b.cgoValue()), "GOFLAGS=", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg, _, _ := strings.Cut(string(bytes.TrimSpace(out)), "\n"); msg != "" {
+			return 0, errors.New(msg)
+		}
+		return 0, err
+	}
+	fi, err := os.Stat(exe)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// report prints the table, then the targets that failed, and returns
+// whether any did. An error in the table would break tabwriter's columns.
+func report(w io.Writer, results []result) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "target\tsize\tstripped\tsaved\tbuild")
+	var errs []result
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, r)
+			continue
+		}
+		saved := 100 - 100*float64(r.stripped)/float64(r.size)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.0f%%\t%s\n", r.target, mib(r.size), mib(r.stripped),
+			saved, r.elapsed.Round(10*time.Millisecond))
+	}
+	tw.Flush()
+	if len(errs) > 0 {
+		fmt.Fprintln(w, "\nfailed:")
+		for _, r := range errs {
+			fmt.Fprintf(w, "  %s: %v\n", r.target, r.err)
+		}
+	}
+	return len(errs) > 0
+}
+
+func mib(n int64) string {
+	return fmt.Sprintf("%.2f MiB", float64(n)/(1<<20))
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "xbuild:", err)
+	os.Exit(1)
+}
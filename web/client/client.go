@@ -0,0 +1,71 @@
+package client
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// A Shared, Configured http.Client
+// ================================
+// http.DefaultClient has no timeout: a server that accepts the
+// connection and never answers holds the goroutine forever. And a new
+// http.Client per request usually means a new Transport, which means a
+// new connection pool - every request pays for DNS, TCP and TLS again.
+//
+// The rule: build one Client with timeouts at startup, share it (it is
+// safe for concurrent use), and give each request a context for its own
+// deadline and cancellation.
+
+// Options are the timeouts and pool sizes for New
+type Options struct {
+	// Timeout bounds a whole request, including reading the body.
+	// Streaming responses need 0 here and a context deadline instead.
+	Timeout time.Duration
+
+	// ResponseHeaderTimeout bounds the wait for the status line after
+	// the request is sent: a stuck server fails fast even when Timeout
+	// is generous
+	ResponseHeaderTimeout time.Duration
+
+	// MaxIdleConnsPerHost is how many keep-alive connections to keep per
+	// host. The default of 2 is low for a client that sends many
+	// concurrent requests to one service: the rest are closed after use
+	// and reopened next time.
+	MaxIdleConnsPerHost int
+}
+
+// New returns a client with its own connection pool
+func New(opts Options) *http.Client {
+	// Clone the default transport to keep its proxy, HTTP/2 and dial
+	// settings, then tighten it
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = 5 * time.Second
+	t.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	t.IdleConnTimeout = 90 * time.Second
+	if opts.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	return &http.Client{Transport: t, Timeout: opts.Timeout}
+}
+
+// maxDrain is how much of an unread body DrainAndClose will read to
+// save the connection. Past that, closing and dialling again is cheaper.
+const maxDrain = 4 << 20
+
+// DrainAndClose reads what is left of a response body, up to 4 MiB,
+// and closes it.
+//
+// A keep-alive connection goes back to the pool only once its response
+// has been read to the end. When a body is closed early the transport
+// drains the rest itself, but only up to 256 KiB and for at most 50ms;
+// a larger or slower remainder closes the connection, and the next
+// request dials a new one. Every response body must be closed; draining
+// first keeps the connection when the caller stops reading a large
+// response early.
+func DrainAndClose(body io.ReadCloser) error {
+	io.CopyN(io.Discard, body, maxDrain)
+	return body.Close()
+}
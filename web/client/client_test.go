@@ -0,0 +1,429 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// HTTP Client - Tests
+// ===================
+// Run with:
+//
+//   cd web/client
+//   go test -v *.go
+//
+// Every test runs against an httptest.Server. Retrier.Sleep is replaced
+// to record the waits, so retry tests take no time.
+
+// recordSleeps returns a Sleep function that records each wait
+func recordSleeps(waits *[]time.Duration) func(context.Context, time.Duration) error {
+	return func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+}
+
+// flaky answers with the given statuses in turn, then 200
+func flaky(statuses ...int) (http.Handler, *atomic.Int64) {
+	var calls atomic.Int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			io.WriteString(w, "try again")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "ok %s", body)
+	}), &calls
+}
+
+// 1. Connection Reuse
+// ===================
+
+func TestDrainingReusesConnections(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		io.WriteString(w, strings.Repeat("x", size))
+	}))
+	defer srv.Close()
+
+	// run makes 10 requests, reads only the first bytes of each body -
+	// as code checking a prefix or decoding a small JSON value from a
+	// larger body would - and finishes the body with finish
+	run := func(size int, finish func(io.ReadCloser)) *ConnStats {
+		c := New(Options{Timeout: 5 * time.Second})
+		defer c.CloseIdleConnections()
+		var stats ConnStats
+		ctx := WithConnStats(context.Background(), &stats)
+		for range 10 {
+			req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?size=%d", srv.URL, size), nil)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.ReadFull(resp.Body, make([]byte, 10))
+			finish(resp.Body)
+		}
+		return &stats
+	}
+	closeOnly := func(b io.ReadCloser) { b.Close() }
+	drain := func(b io.ReadCloser) { DrainAndClose(b) }
+
+	// A small remainder is drained by the transport on Close
+	if s := run(8<<10, closeOnly); s.New.Load() != 1 {
+		t.Errorf("8 KiB, Close: %d new connections, want 1", s.New.Load())
+	}
+
+	// Past 256 KiB it gives up and closes the connection
+	closed := run(1<<20, closeOnly)
+	drained := run(1<<20, drain)
+	t.Logf("1 MiB bodies: Close only %d new connections, DrainAndClose %d", closed.New.Load(), drained.New.Load())
+	if closed.New.Load() != 10 {
+		t.Errorf("1 MiB, Close: %d new connections, want 10", closed.New.Load())
+	}
+	if drained.New.Load() != 1 || drained.Reused.Load() != 9 {
+		t.Errorf("1 MiB, DrainAndClose: %d new, %d reused; want 1 and 9", drained.New.Load(), drained.Reused.Load())
+	}
+}
+
+func TestIdlePoolSize(t *testing.T) {
+	// 20 concurrent requests open 20 connections. Afterwards the pool
+	// keeps MaxIdleConnsPerHost of them; the next burst dials the rest.
+	release := make(chan struct{})
+	var arrived sync.WaitGroup
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("wait") != "" {
+			arrived.Done()
+			<-release
+		}
+	}))
+	defer srv.Close()
+
+	for _, perHost := range []int{2, 20} {
+		c := New(Options{MaxIdleConnsPerHost: perHost})
+		burst := func(stats *ConnStats, wait bool) {
+			ctx := WithConnStats(context.Background(), stats)
+			var wg sync.WaitGroup
+			if wait {
+				arrived.Add(20)
+			}
+			for range 20 {
+				wg.Go(func() {
+					url := srv.URL
+					if wait {
+						url += "?wait=1"
+					}
+					req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+					if resp, err := c.Do(req); err == nil {
+						DrainAndClose(resp.Body)
+					}
+				})
+			}
+			if wait {
+				// All 20 are in the handler at once, on 20 connections
+				arrived.Wait()
+				release <- struct{}{}
+				for range 19 {
+					release <- struct{}{}
+				}
+			}
+			wg.Wait()
+		}
+		var first, second ConnStats
+		burst(&first, true)
+		burst(&second, false)
+		t.Logf("MaxIdleConnsPerHost=%d: second burst reused %d of 20", perHost, second.Reused.Load())
+		if perHost == 20 && second.New.Load() != 0 {
+			t.Errorf("pool of 20: %d new connections in the second burst", second.New.Load())
+		}
+		if perHost == 2 && second.New.Load() == 0 {
+			t.Errorf("pool of 2: every connection was reused")
+		}
+		c.CloseIdleConnections()
+	}
+}
+
+// 2. Timeouts and Context
+// =======================
+
+func TestTimeouts(t *testing.T) {
+	stall := make(chan struct{})
+	defer close(stall)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stall:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	// The default client would wait forever here
+	c := New(Options{Timeout: 5 * time.Second, ResponseHeaderTimeout: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := c.Get(srv.URL)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v: ResponseHeaderTimeout did not apply", elapsed)
+	}
+
+	// A context deadline is per request, and ends it just the same
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if _, err := New(Options{}).Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("context deadline: err = %v", err)
+	}
+}
+
+func TestCancellationReachesServer(t *testing.T) {
+	// Cancelling the client's context closes the connection, and the
+	// server's request context is cancelled - the server stops working
+	// on an answer nobody will read
+	serverSaw := make(chan error, 1)
+	started := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		serverSaw <- r.Context().Err()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if _, err := New(Options{}).Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("client: err = %v", err)
+	}
+	select {
+	case err := <-serverSaw:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("server: ctx.Err() = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("the server never noticed")
+	}
+}
+
+// 3. Retries
+// ==========
+
+func TestRetryTransient(t *testing.T) {
+	h, calls := flaky(503, 502, 429)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var waits []time.Duration
+	r := &Retrier{Client: New(Options{}), Base: 100 * time.Millisecond, Sleep: recordSleeps(&waits)}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := r.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 || calls.Load() != 4 || len(waits) != 3 {
+		t.Errorf("status %d after %d calls, %d waits", resp.StatusCode, calls.Load(), len(waits))
+	}
+	// Full jitter: retry n waits less than Base * 2^(n-1)
+	for i, w := range waits {
+		if ceiling := 100 * time.Millisecond << i; w < 0 || w >= ceiling {
+			t.Errorf("wait %d = %v, want [0, %v)", i+1, w, ceiling)
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	h, calls := flaky(500, 500, 500, 500, 500, 500)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var waits []time.Duration
+	r := &Retrier{Client: New(Options{}), MaxAttempts: 3, Sleep: recordSleeps(&waits)}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := r.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last response comes back for the caller to inspect
+	defer resp.Body.Close()
+	if resp.StatusCode != 500 || calls.Load() != 3 {
+		t.Errorf("status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		method string
+		key    string
+		calls  int64
+	}{
+		{"client error", 404, "GET", "", 1},
+		{"not implemented", 501, "GET", "", 1},
+		{"POST", 503, "POST", "", 1},
+		{"POST with Idempotency-Key", 503, "POST", "order-7", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, calls := flaky(tt.status)
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			var waits []time.Duration
+			r := &Retrier{Client: New(Options{}), Sleep: recordSleeps(&waits)}
+			req, _ := http.NewRequest(tt.method, srv.URL, strings.NewReader("{}"))
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			resp, err := r.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if calls.Load() != tt.calls {
+				t.Errorf("%d calls, want %d", calls.Load(), tt.calls)
+			}
+		})
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	h, _ := flaky(503)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var waits []time.Duration
+	r := &Retrier{Client: New(Options{}), Sleep: recordSleeps(&waits)}
+	req, _ := http.NewRequest("PUT", srv.URL, strings.NewReader("payload"))
+	resp, err := r.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok payload" {
+		t.Errorf("second attempt got body %q", body)
+	}
+
+	// An io.Reader http.NewRequest does not recognize has no GetBody:
+	// the first attempt would consume it
+	req, _ = http.NewRequest("PUT", srv.URL, io.MultiReader(strings.NewReader("payload")))
+	if _, err := r.Do(req); !errors.Is(err, ErrNotReplayable) {
+		t.Errorf("unreplayable body: err = %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	r := &Retrier{Client: New(Options{}), MaxAttempts: 2, Max: 10 * time.Second, Sleep: recordSleeps(&waits)}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	if resp, err := r.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	if len(waits) != 1 || waits[0] != 3*time.Second {
+		t.Errorf("waits = %v, want [3s] from Retry-After", waits)
+	}
+
+	// Max caps what a server can ask for
+	waits = nil
+	r.Max = time.Second
+	if resp, err := r.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	if len(waits) != 1 || waits[0] != time.Second {
+		t.Errorf("waits = %v, want [1s], capped by Max", waits)
+	}
+
+	for _, v := range []string{"", "soon", "-1"} {
+		if _, ok := retryAfter(v); ok {
+			t.Errorf("retryAfter(%q) accepted", v)
+		}
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := retryAfter(date); !ok || d < 59*time.Minute {
+		t.Errorf("retryAfter(%q) = %v, %v", date, d, ok)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	h, calls := flaky(503, 503, 503, 503)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// The real timer this time: a long backoff, cut short by the context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r := &Retrier{Client: New(Options{}), Base: time.Hour, Max: time.Hour}
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+
+	// rand.N can pick a tiny wait; with Base = 1h the chance is negligible
+	start := time.Now()
+	_, err := r.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second || calls.Load() > 2 {
+		t.Errorf("err = %v after %v and %d calls", err, time.Since(start), calls.Load())
+	}
+}
+
+func TestBackoffCeiling(t *testing.T) {
+	r := &Retrier{Base: 100 * time.Millisecond, Max: 2 * time.Second}
+	for n := 1; n <= 100; n++ {
+		ceiling := min(100*time.Millisecond<<min(n-1, 30), 2*time.Second)
+		for range 100 {
+			if d := r.Backoff(n); d < 0 || d >= ceiling {
+				t.Fatalf("Backoff(%d) = %v, want [0, %v)", n, d, ceiling)
+			}
+		}
+	}
+}
+
+// Examples
+// ========
+
+func ExampleRetrier() {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "done")
+	}))
+	defer srv.Close()
+
+	r := &Retrier{Client: New(Options{Timeout: 5 * time.Second}), Base: time.Millisecond}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := r.Do(req)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("%s after %d attempts\n", body, calls)
+	// Output:
+	// done after 3 attempts
+}
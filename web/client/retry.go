@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retries With Exponential Backoff and Jitter
+// ===========================================
+// A retry helps with a failure that goes away: a dropped connection, a
+// 503 from an overloaded server, a 429. It hurts when every client
+// retries at once: the server recovers, and synchronized retries knock
+// it over again. So retries
+//
+//   - wait longer each time (exponential backoff), capped at a maximum
+//   - wait a random fraction of that (jitter), which spreads clients out.
+//     "Full jitter" picks uniformly from [0, backoff).
+//   - honour Retry-After when the server sends it
+//   - stop when the caller's context ends
+//   - only repeat requests that are safe to repeat. GET, PUT and DELETE
+//     are idempotent; a POST is retried only if it carries an
+//     Idempotency-Key the server uses to drop duplicates.
+//
+// The request body is sent again on each attempt, so it must be
+// replayable: http.NewRequest sets GetBody for bytes.Reader,
+// strings.Reader and bytes.Buffer bodies.
+
+// ErrNotReplayable means a request with a body cannot be retried because
+// its body cannot be read a second time
+var ErrNotReplayable = errors.New("client: request body cannot be replayed")
+
+// Retrier sends requests, retrying transient failures
+type Retrier struct {
+	Client      *http.Client
+	MaxAttempts int           // including the first; default 4
+	Base        time.Duration // backoff before the second attempt; default 100ms
+	Max         time.Duration // cap on any one wait; default 10s
+
+	// Sleep waits for d or until ctx is done. Tests replace it to record
+	// the waits instead of taking them. Nil means a real timer.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Backoff returns the wait before retry number n (1 for the first
+// retry): a uniform random duration in [0, min(Max, Base*2^(n-1))).
+func (r *Retrier) Backoff(n int) time.Duration {
+	base, maxWait := r.Base, r.Max
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if maxWait <= 0 {
+		maxWait = 10 * time.Second
+	}
+	ceiling := maxWait
+	// Stop doubling before it overflows
+	if n-1 < 32 && base<<(n-1) < maxWait {
+		ceiling = base << (n - 1)
+	}
+	return rand.N(ceiling)
+}
+
+// Do sends req, retrying network errors, 429 and 5xx responses. The
+// returned response is the last one received; on success the caller
+// must close its body, as with http.Client.Do.
+func (r *Retrier) Do(req *http.Request) (*http.Response, error) {
+	attempts := r.MaxAttempts
+	if attempts <= 0 {
+		attempts = 4
+	}
+	if !retryable(req) {
+		attempts = 1
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil && attempts > 1 {
+		return nil, ErrNotReplayable
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		try := req.Clone(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			try.Body = body
+		}
+
+		resp, err := client.Do(try)
+		wait, retry := r.Backoff(attempt), false
+		switch {
+		case err != nil:
+			// A cancelled or expired context is the caller's decision
+			retry = ctx.Err() == nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			retry = resp.StatusCode != http.StatusNotImplemented
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = after
+			}
+		}
+		if !retry || attempt == attempts {
+			if err != nil && attempt > 1 {
+				err = fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return resp, err
+		}
+		if resp != nil {
+			// Only the last response is returned; free the connection
+			// of this one for the next attempt
+			DrainAndClose(resp.Body)
+		}
+		if err := r.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *Retrier) sleep(ctx context.Context, d time.Duration) error {
+	if r.Max > 0 {
+		d = min(d, r.Max)
+	}
+	if r.Sleep != nil {
+		return r.Sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryable reports whether req may be sent more than once
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryAfter parses a Retry-After value: seconds, or an HTTP date
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
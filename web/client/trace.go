@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+// Watching the Connection Pool With httptrace
+// ===========================================
+// Whether a request reused a pooled connection or dialled a new one is
+// invisible in the response. httptrace hooks into the transport and
+// reports each step - DNS, connect, TLS, getting a connection, the
+// first response byte - for requests whose context carries a
+// ClientTrace.
+//
+// ConnStats counts the connection events: a high New count under
+// steady load points at unread bodies, a per-request Client, or
+// MaxIdleConnsPerHost set too low.
+
+// ConnStats counts how requests got their connections. It is safe for
+// concurrent requests.
+type ConnStats struct {
+	Requests atomic.Int64 // connections obtained, one per request attempt
+	Reused   atomic.Int64 // taken from the idle pool
+	New      atomic.Int64 // dialled for this request
+	DNS      atomic.Int64 // DNS lookups started
+}
+
+// WithConnStats returns a context that records connection events for
+// requests made with it into s
+func WithConnStats(ctx context.Context, s *ConnStats) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { s.DNS.Add(1) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			s.Requests.Add(1)
+			if info.Reused {
+				s.Reused.Add(1)
+			} else {
+				s.New.Add(1)
+			}
+		},
+	})
+}
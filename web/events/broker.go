@@ -0,0 +1,111 @@
+package events
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Broadcasting Without Subscriber Lists
+// =====================================
+// A server that pushes events needs every waiting handler to wake when
+// something is published. A list of per-subscriber channels works, but
+// then the publisher must decide what to do with a subscriber that is
+// not reading: block everyone, or drop its events.
+//
+// The broker here keeps a bounded history instead, and a channel that
+// is closed on every publish. A closed channel wakes every receiver at
+// once, costs the publisher nothing per waiter, and never blocks. Each
+// handler remembers the last ID it sent and asks for what came after:
+//
+//	for {
+//		events, changed := b.Since(last)
+//		send(events) // last = the final ID sent
+//		<-changed
+//	}
+//
+// Since returns the events and the channel under one lock, so an event
+// published between the two cannot be missed. The same history lets a
+// reconnecting client resume from its Last-Event-ID.
+
+// Event is one message. IDs start at 1 and increase by one per event.
+type Event struct {
+	ID   uint64 `json:"id"`
+	Type string `json:"type,omitempty"`
+	Data string `json:"data"`
+}
+
+// Broker keeps the most recent events and wakes waiters on each
+// publish. It is safe for concurrent use.
+type Broker struct {
+	mu      sync.Mutex
+	history []Event // oldest first, at most max
+	max     int
+	lastID  uint64
+	changed chan struct{} // closed and replaced by each Publish
+	done    chan struct{} // closed by Close
+	closed  bool
+}
+
+// NewBroker returns a broker remembering the last history events
+func NewBroker(history int) *Broker {
+	return &Broker{
+		max:     max(history, 1),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Publish records an event and wakes everyone waiting on Since
+func (b *Broker) Publish(typ, data string) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e := Event{ID: b.lastID, Type: typ, Data: data}
+	if len(b.history) == b.max {
+		// Shift rather than reslice, so the backing array does not
+		// creep forward and reallocate on every append
+		copy(b.history, b.history[1:])
+		b.history = b.history[:len(b.history)-1]
+	}
+	b.history = append(b.history, e)
+
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return e
+}
+
+// Since returns the remembered events with IDs after id, and a channel
+// that is closed by the next Publish.
+//
+// If events after id have already left the history, the first event
+// returned has an ID above id+1 and the caller can tell it missed some.
+func (b *Broker) Since(id uint64) ([]Event, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i, _ := slices.BinarySearchFunc(b.history, id+1, func(e Event, id uint64) int {
+		return cmp.Compare(e.ID, id)
+	})
+	return slices.Clone(b.history[i:]), b.changed
+}
+
+// LastID returns the ID of the newest event, or 0 before the first
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Close ends every stream and poll. Streams never finish on their own,
+// so without it srv.Shutdown would wait for them until its deadline.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+}
+
+// Done is closed by Close
+func (b *Broker) Done() <-chan struct{} { return b.done }
@@ -0,0 +1,553 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server-Sent Events and Long-Polling - Tests
+// ===========================================
+// Run with:
+//
+//   cd web/events
+//   go test -v *.go
+//   go test -race *.go
+//
+// Streams need a real connection: httptest.NewServer, a request whose
+// context the test cancels to play a client leaving, and a wrapper that
+// reports when the handler has returned.
+
+// tracked reports on the returned channel each time h returns
+func tracked(h http.Handler) (http.Handler, <-chan struct{}) {
+	done := make(chan struct{}, 16)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { done <- struct{}{} }()
+		h.ServeHTTP(w, r)
+	}), done
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+// stream opens an SSE connection; cancel is the client leaving
+func stream(t *testing.T, url, lastID string) (*Reader, *http.Response, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cancel(); resp.Body.Close() })
+	return NewReader(resp.Body), resp, cancel
+}
+
+func next(t *testing.T, r *Reader) Event {
+	t.Helper()
+	got := make(chan Event, 1)
+	errc := make(chan error, 1)
+	go func() {
+		e, err := r.Next()
+		if err != nil {
+			errc <- err
+			return
+		}
+		got <- e
+	}()
+	select {
+	case e := <-got:
+		return e
+	case err := <-errc:
+		t.Fatalf("Next: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event within 2s")
+	}
+	return Event{}
+}
+
+// 1. The Broker
+// =============
+
+func TestBrokerSince(t *testing.T) {
+	b := NewBroker(3)
+	for i := range 5 {
+		b.Publish("n", fmt.Sprint(i+1))
+	}
+	tests := []struct {
+		after uint64
+		want  []uint64
+	}{
+		{0, []uint64{3, 4, 5}}, // 1 and 2 have left the history: the gap shows
+		{3, []uint64{4, 5}},
+		{5, nil},
+		{9, nil},
+	}
+	for _, tt := range tests {
+		events, _ := b.Since(tt.after)
+		var ids []uint64
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("Since(%d) = %v, want %v", tt.after, ids, tt.want)
+		}
+	}
+	if b.LastID() != 5 {
+		t.Errorf("LastID = %d", b.LastID())
+	}
+}
+
+func TestBrokerWakesAllWaiters(t *testing.T) {
+	b := NewBroker(10)
+	var wg sync.WaitGroup
+	got := make(chan Event, 100)
+	for range 100 {
+		_, changed := b.Since(0)
+		wg.Go(func() {
+			<-changed
+			events, _ := b.Since(0)
+			got <- events[0]
+		})
+	}
+	b.Publish("", "hello")
+	wg.Wait()
+	close(got)
+	n := 0
+	for e := range got {
+		if e.Data != "hello" {
+			t.Fatalf("woke to %+v", e)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Errorf("%d waiters woke, want 100", n)
+	}
+}
+
+func TestBrokerConcurrentPublish(t *testing.T) {
+	// Run with -race. IDs are unique and the history stays ordered.
+	b := NewBroker(1000)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 100 {
+				b.Publish("", "x")
+			}
+		})
+	}
+	wg.Wait()
+	events, _ := b.Since(0)
+	for i, e := range events {
+		if e.ID != uint64(i+1) {
+			t.Fatalf("events[%d].ID = %d", i, e.ID)
+		}
+	}
+	if len(events) != 800 {
+		t.Errorf("%d events", len(events))
+	}
+}
+
+// 2. The Wire Format
+// ==================
+
+func TestWriteEvent(t *testing.T) {
+	tests := []struct {
+		e    Event
+		want string
+	}{
+		{Event{ID: 1, Data: "hi"}, "id: 1\ndata: hi\n\n"},
+		{Event{ID: 2, Type: "build", Data: "a\nb"}, "id: 2\nevent: build\ndata: a\ndata: b\n\n"},
+		{Event{ID: 3, Data: "crlf\r\nand\rcr"}, "id: 3\ndata: crlf\ndata: and\ndata: cr\n\n"},
+		{Event{ID: 4, Data: ""}, "id: 4\ndata: \n\n"},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		WriteEvent(&sb, tt.e)
+		if sb.String() != tt.want {
+			t.Errorf("WriteEvent(%+v) = %q, want %q", tt.e, sb.String(), tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := []Event{
+		{ID: 1, Data: "plain"},
+		{ID: 2, Type: "log", Data: "line one\nline two\n"},
+		{ID: 3, Data: ": not a comment"},
+		{ID: 4, Data: ""},
+		{ID: 5, Data: "  leading spaces"},
+	}
+	var sb strings.Builder
+	for _, e := range in {
+		WriteEvent(&sb, e)
+	}
+	r := NewReader(strings.NewReader(sb.String()))
+	for _, want := range in {
+		got, err := r.Next()
+		if err != nil || got != want {
+			t.Errorf("Next = %+v, %v; want %+v", got, err, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("after the last event: %v", err)
+	}
+}
+
+func TestReaderSpecCases(t *testing.T) {
+	stream := strings.Join([]string{
+		"retry: 1500",
+		": a comment",
+		"",
+		"id: 10",
+		"event: first",
+		"data:no space",
+		"",
+		"data: inherits id 10, not the type",
+		"",
+		"id: ten", // not a number: ignored
+		"event: empty",
+		"",     // no data: nothing dispatched, type reset
+		"id",   // no colon: an empty id, which resets it
+		"data", // an empty data line
+		"data: x",
+		"",
+		"id: 11\r", // CRLF line endings
+		"data: crlf\r",
+		"\r",
+		"data: unterminated",
+	}, "\n")
+	r := NewReader(strings.NewReader(stream))
+	want := []Event{
+		{ID: 10, Type: "first", Data: "no space"},
+		{ID: 10, Data: "inherits id 10, not the type"},
+		{ID: 0, Data: "\nx"},
+		{ID: 11, Data: "crlf"},
+	}
+	for _, w := range want {
+		if got, err := r.Next(); err != nil || got != w {
+			t.Errorf("Next = %+v, %v; want %+v", got, err, w)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("an event without its blank line must be dropped, got err %v", err)
+	}
+	if r.Retry != 1500*time.Millisecond || r.LastID() != 11 {
+		t.Errorf("Retry = %v, LastID = %d", r.Retry, r.LastID())
+	}
+}
+
+// 3. Streaming
+// ============
+
+func TestSSEStreams(t *testing.T) {
+	b := NewBroker(10)
+	ts := httptest.NewServer(SSE(b, time.Minute))
+	t.Cleanup(ts.Close)
+
+	r, resp, _ := stream(t, ts.URL, "")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" || resp.Header.Get("Cache-Control") != "no-cache" {
+		t.Errorf("headers: %v", resp.Header)
+	}
+
+	// Each event arrives as it is published - flushing works
+	for i := range 3 {
+		b.Publish("tick", fmt.Sprint(i))
+		if e := next(t, r); e.Data != fmt.Sprint(i) || e.ID != uint64(i+1) || e.Type != "tick" {
+			t.Errorf("event %d = %+v", i, e)
+		}
+	}
+	if r.Retry != RetryAfter {
+		t.Errorf("retry = %v", r.Retry)
+	}
+}
+
+func TestSSEResumes(t *testing.T) {
+	// A reconnecting client gets exactly what it missed, then live events
+	b := NewBroker(10)
+	for i := range 5 {
+		b.Publish("", fmt.Sprint(i+1))
+	}
+	ts := httptest.NewServer(SSE(b, time.Minute))
+	t.Cleanup(ts.Close)
+
+	r, _, _ := stream(t, ts.URL, "3")
+	for _, want := range []uint64{4, 5} {
+		if e := next(t, r); e.ID != want {
+			t.Errorf("replayed %d, want %d", e.ID, want)
+		}
+	}
+	b.Publish("", "live")
+	if e := next(t, r); e.ID != 6 || e.Data != "live" {
+		t.Errorf("live event = %+v", e)
+	}
+
+	// ?after= does the same for a first connection
+	r, _, _ = stream(t, ts.URL+"?after=5", "")
+	if e := next(t, r); e.ID != 6 {
+		t.Errorf("?after=5 gave %d first", e.ID)
+	}
+}
+
+func TestSSEBadLastEventID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Last-Event-ID", "yesterday")
+	SSE(NewBroker(1), time.Minute).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d", rec.Code)
+	}
+}
+
+func TestSSEClientDisconnect(t *testing.T) {
+	b := NewBroker(10)
+	h, returned := tracked(SSE(b, time.Minute))
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	r, _, cancel := stream(t, ts.URL, "")
+	b.Publish("", "one")
+	next(t, r)
+
+	// No publish and no heartbeat is due: only the request context can
+	// tell the handler its client is gone
+	cancel()
+	waitFor(t, returned, "the handler to notice the disconnect")
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	b := NewBroker(10)
+	ts := httptest.NewServer(SSE(b, 20*time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	_, resp, _ := stream(t, ts.URL, "")
+	// Heartbeats are comments: Reader skips them, so read the raw lines
+	br := bufio.NewReader(resp.Body)
+	pings := 0
+	deadline := time.After(2 * time.Second)
+	for pings < 3 {
+		line := make(chan string, 1)
+		go func() { s, _ := br.ReadString('\n'); line <- s }()
+		select {
+		case s := <-line:
+			if s == ": ping\n" {
+				pings++
+			}
+		case <-deadline:
+			t.Fatalf("%d pings in 2s", pings)
+		}
+	}
+}
+
+func TestSSEOutlivesWriteTimeout(t *testing.T) {
+	// WriteTimeout covers the whole response. The handler clears it, so
+	// the stream keeps working after the timeout has passed.
+	b := NewBroker(10)
+	ts := httptest.NewUnstartedServer(SSE(b, time.Minute))
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	r, _, _ := stream(t, ts.URL, "")
+	time.Sleep(150 * time.Millisecond)
+	b.Publish("", "still here")
+	if e := next(t, r); e.Data != "still here" {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestSSEBrokerClose(t *testing.T) {
+	// Close ends every stream, so a graceful Shutdown can finish
+	b := NewBroker(10)
+	h, returned := tracked(SSE(b, time.Minute))
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	r, _, _ := stream(t, ts.URL, "")
+	b.Publish("", "last")
+	next(t, r)
+	b.Close()
+	waitFor(t, returned, "the stream to end")
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("client sees %v, want io.EOF", err)
+	}
+}
+
+// noFlush hides the recorder's Flush, as a wrapper without Unwrap would
+type noFlush struct{ rec *httptest.ResponseRecorder }
+
+func (w noFlush) Header() http.Header         { return w.rec.Header() }
+func (w noFlush) Write(p []byte) (int, error) { return w.rec.Write(p) }
+func (w noFlush) WriteHeader(code int)        { w.rec.WriteHeader(code) }
+
+func TestSSEWithoutFlusher(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SSE(NewBroker(1), time.Minute).ServeHTTP(noFlush{rec}, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "streaming unsupported") {
+		t.Errorf("%d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// 4. Long-Polling
+// ===============
+
+func poll(t *testing.T, url string) pollResponse {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var p pollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatalf("%d: %v", resp.StatusCode, err)
+	}
+	return p
+}
+
+func TestLongPollImmediate(t *testing.T) {
+	b := NewBroker(10)
+	b.Publish("", "a")
+	b.Publish("", "b")
+	ts := httptest.NewServer(LongPoll(b, time.Minute))
+	t.Cleanup(ts.Close)
+
+	start := time.Now()
+	p := poll(t, ts.URL+"?after=0")
+	if len(p.Events) != 2 || p.LastID != 2 || time.Since(start) > time.Second {
+		t.Errorf("poll = %+v after %v", p, time.Since(start))
+	}
+}
+
+func TestLongPollWaits(t *testing.T) {
+	b := NewBroker(10)
+	ts := httptest.NewServer(LongPoll(b, time.Minute))
+	t.Cleanup(ts.Close)
+
+	got := make(chan pollResponse, 1)
+	go func() { got <- poll(t, ts.URL+"?after=0") }()
+	select {
+	case p := <-got:
+		t.Fatalf("answered before any event: %+v", p)
+	case <-time.After(50 * time.Millisecond):
+	}
+	b.Publish("", "arrived")
+	select {
+	case p := <-got:
+		if len(p.Events) != 1 || p.Events[0].Data != "arrived" || p.LastID != 1 {
+			t.Errorf("poll = %+v", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("publish did not answer the poll")
+	}
+}
+
+func TestLongPollTimeout(t *testing.T) {
+	b := NewBroker(10)
+	b.Publish("", "old")
+	ts := httptest.NewServer(LongPoll(b, 30*time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	// Nothing new: an empty answer with the cursor unchanged
+	p := poll(t, ts.URL+"?after=1")
+	if p.Events == nil || len(p.Events) != 0 || p.LastID != 1 {
+		t.Errorf("timeout = %+v", p)
+	}
+}
+
+func TestLongPollLosesNothing(t *testing.T) {
+	// Events published between polls wait in the history
+	b := NewBroker(100)
+	ts := httptest.NewServer(LongPoll(b, time.Second))
+	t.Cleanup(ts.Close)
+
+	var got []string
+	var cursor uint64
+	done := make(chan struct{})
+	go func() {
+		for i := range 50 {
+			b.Publish("", fmt.Sprint(i))
+		}
+		close(done)
+	}()
+	for len(got) < 50 {
+		p := poll(t, fmt.Sprintf("%s?after=%d", ts.URL, cursor))
+		for _, e := range p.Events {
+			got = append(got, e.Data)
+		}
+		cursor = p.LastID
+	}
+	<-done
+	for i, s := range got {
+		if s != fmt.Sprint(i) {
+			t.Fatalf("got[%d] = %s", i, s)
+		}
+	}
+}
+
+func TestLongPollClientLeaves(t *testing.T) {
+	b := NewBroker(10)
+	h, returned := tracked(LongPoll(b, time.Minute))
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	go http.DefaultClient.Do(req)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	waitFor(t, returned, "the poll to end")
+}
+
+func TestLongPollBadCursor(t *testing.T) {
+	rec := httptest.NewRecorder()
+	LongPoll(NewBroker(1), time.Minute).ServeHTTP(rec, httptest.NewRequest("GET", "/?after=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d", rec.Code)
+	}
+}
+
+// Examples
+// ========
+
+func ExampleSSE() {
+	b := NewBroker(100)
+	b.Publish("build", "compiling")
+	b.Publish("build", "ok\n3 packages")
+
+	ts := httptest.NewServer(SSE(b, time.Minute))
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	r := NewReader(resp.Body)
+	for range 2 {
+		e, _ := r.Next()
+		fmt.Printf("%d %s %q\n", e.ID, e.Type, e.Data)
+	}
+	// Output:
+	// 1 build "compiling"
+	// 2 build "ok\n3 packages"
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Long-Polling
+// ============
+// Where streaming is not possible - a proxy that buffers responses, a
+// client without an SSE parser - long-polling gets close. The client
+// asks for events after the last one it has; the server answers at once
+// if there are any, and otherwise holds the request until one arrives
+// or a timeout passes. Then the client asks again.
+//
+//	GET /poll?after=7   ->  {"events": [...], "last_id": 9}
+//	GET /poll?after=9   ->  (waits up to the timeout)
+//
+// The cursor makes it lossless: events published while no request is
+// open are in the history, and the next poll collects them.
+//
+// Keep the timeout below every idle timeout on the way - load
+// balancers often close connections quiet for 30 or 60 seconds - and
+// below the server's WriteTimeout.
+
+type pollResponse struct {
+	Events []Event `json:"events"`
+	LastID uint64  `json:"last_id"`
+}
+
+// LongPoll answers ?after=N (0 if absent) with the events after N, waiting up to
+// timeout for the first one. An empty answer keeps the cursor, so the
+// client can poll again with it unchanged.
+func LongPoll(b *Broker, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var after uint64
+		if s := r.URL.Query().Get("after"); s != "" {
+			var err error
+			if after, err = strconv.ParseUint(s, 10, 64); err != nil {
+				http.Error(w, "after must be an event id", http.StatusBadRequest)
+				return
+			}
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		for {
+			events, changed := b.Since(after)
+			if len(events) > 0 {
+				writePoll(w, pollResponse{Events: events, LastID: events[len(events)-1].ID})
+				return
+			}
+			select {
+			case <-changed:
+			case <-timer.C:
+				writePoll(w, pollResponse{Events: []Event{}, LastID: after})
+				return
+			case <-b.Done():
+				writePoll(w, pollResponse{Events: []Event{}, LastID: after})
+				return
+			case <-r.Context().Done():
+				return // nobody to answer
+			}
+		}
+	})
+}
+
+func writePoll(w http.ResponseWriter, resp pollResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
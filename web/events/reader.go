@@ -0,0 +1,95 @@
+package events
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reading a Stream
+// ================
+// Outside a browser there is no EventSource, but the format is simple
+// enough to parse with a bufio.Scanner. The rules, from the HTML spec:
+//   - A line starting with ":" is a comment.
+//   - "field: value" sets a field; one space after the colon is
+//     dropped. A line without a colon is a field with an empty value.
+//   - data lines accumulate, joined with "\n".
+//   - A blank line dispatches the event - unless no data arrived, in
+//     which case there is nothing to dispatch.
+//   - The id persists: an event without an id: line has the previous
+//     event's id. The event type does not.
+//   - Lines end with "\n" or "\r\n". (The spec also allows a lone "\r",
+//     which bufio.ScanLines does not split on; no server sends it.)
+
+// Reader parses a text/event-stream
+type Reader struct {
+	s      *bufio.Scanner
+	lastID uint64
+
+	// Retry is the reconnection delay last requested by the server
+	Retry time.Duration
+}
+
+// NewReader returns a Reader parsing r. Lines may be up to 1 MiB.
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), 1<<20)
+	return &Reader{s: s}
+}
+
+// Next returns the next event, or io.EOF at the end of the stream
+func (r *Reader) Next() (Event, error) {
+	var (
+		e       Event
+		data    strings.Builder
+		hasData bool
+	)
+	for r.s.Scan() {
+		line := r.s.Text()
+		if line == "" {
+			if !hasData {
+				e.Type = ""
+				continue
+			}
+			e.ID = r.lastID
+			e.Data = strings.TrimSuffix(data.String(), "\n")
+			return e, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "event":
+			e.Type = value
+		case "id":
+			// An empty id resets it; one that is not a number is
+			// ignored, as the spec ignores ids containing NUL
+			if value == "" {
+				r.lastID = 0
+			} else if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+				r.lastID = id
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				r.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := r.s.Err(); err != nil {
+		return Event{}, err
+	}
+	// An event not ended by a blank line is discarded
+	return Event{}, io.EOF
+}
+
+// LastID returns the id a reconnecting client should send as
+// Last-Event-ID
+func (r *Reader) LastID() uint64 { return r.lastID }
@@ -0,0 +1,138 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Server-Sent Events
+// ==================
+// SSE is a long-lived GET whose response is text/event-stream: blocks of
+// "field: value" lines, each block ended by a blank line.
+//
+//	id: 7
+//	event: build
+//	data: compiling
+//	data: 3 packages
+//
+// Browsers read it with new EventSource(url), reconnect on their own
+// when the connection drops, and send the last id they saw in a
+// Last-Event-ID header so the server can replay what was missed.
+//
+// The server side is ordinary net/http, with three things to get right:
+//   - Flush after each event. Without it the bytes sit in the server's
+//     buffer and the client sees nothing until several KB pile up.
+//   - Stop when the client leaves. r.Context() is cancelled when the
+//     connection closes; a handler that ignores it streams into the
+//     void and leaks a goroutine per departed client.
+//   - Clear the write deadline. A server WriteTimeout covers the whole
+//     response, so it would cut every stream after that long.
+//
+// Periodic comment lines (": ping") keep proxies from closing an idle
+// connection, and make a write fail - ending the handler - when a
+// client vanished without closing its connection.
+
+// RetryAfter is sent to clients as the reconnection delay
+const RetryAfter = 3 * time.Second
+
+// WriteEvent writes e in text/event-stream format. Data spanning several
+// lines becomes several data: lines, which the client joins with "\n".
+func WriteEvent(w io.Writer, e Event) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "id: %d\n", e.ID)
+	if e.Type != "" {
+		fmt.Fprintf(&sb, "event: %s\n", e.Type)
+	}
+	// A bare \r is a line ending too; left in the data it would end the
+	// line early and the rest would be read as a field name
+	data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for line := range strings.SplitSeq(data, "\n") {
+		fmt.Fprintf(&sb, "data: %s\n", line)
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// SSE streams b's events, starting after the client's Last-Event-ID
+// header or ?after= query parameter, and sends a comment every
+// heartbeat while idle. The stream ends when the client disconnects or
+// b is closed.
+func SSE(b *Broker, heartbeat time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last, err := lastEventID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no") // tells nginx not to buffer the stream
+
+		// The first Flush sends the headers. If the writer cannot flush -
+		// a middleware wrapper without Unwrap - nothing has been sent
+		// yet and there is still time to say so.
+		if err := rc.Flush(); err != nil {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return
+		}
+		fmt.Fprintf(w, "retry: %d\n\n", RetryAfter.Milliseconds())
+
+		tick := time.NewTicker(heartbeat)
+		defer tick.Stop()
+		for {
+			events, changed := b.Since(last)
+			for _, e := range events {
+				if err := WriteEvent(w, e); err != nil {
+					return
+				}
+				last = e.ID
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case <-changed:
+			case <-tick.C:
+				if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			case <-b.Done():
+				return
+			}
+		}
+	})
+}
+
+// lastEventID reads where the client wants to resume. EventSource sends
+// the header on reconnects; the query parameter serves the first
+// connection.
+func lastEventID(r *http.Request) (uint64, error) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("after")
+	}
+	if s == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad event id %q", s)
+	}
+	return id, nil
+}
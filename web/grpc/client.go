@@ -0,0 +1,330 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The Client
+// ==========
+// A ClientConn is a target and an HTTP/2 transport. Every call is a
+// request on one multiplexed connection: a hundred concurrent calls
+// share a TCP connection instead of opening a hundred.
+//
+// Invoke makes unary calls and NewStream streaming ones; the stub
+// GreeterClient wraps both in typed methods. The caller's context
+// carries the deadline, sent as grpc-timeout, and outgoing metadata,
+// sent as headers. Whatever goes wrong - a refused connection, a
+// deadline, an error from the handler - comes back as a *Status.
+
+// DialOption configures NewClient
+type DialOption func(*ClientConn)
+
+// WithContextDialer replaces the network dialer - the hook in-memory
+// test listeners use
+func WithContextDialer(dial func(ctx context.Context, addr string) (net.Conn, error)) DialOption {
+	return func(cc *ClientConn) { cc.dial = dial }
+}
+
+// WithChainUnaryInterceptor adds client interceptors, outermost first
+func WithChainUnaryInterceptor(ints ...UnaryClientInterceptor) DialOption {
+	return func(cc *ClientConn) { cc.unary = append(cc.unary, ints...) }
+}
+
+// UnaryInvoker performs a unary call
+type UnaryInvoker func(ctx context.Context, method string, req, reply any, cc *ClientConn, opts ...CallOption) error
+
+// UnaryClientInterceptor runs around each unary call the client makes:
+// add metadata, log, retry, measure
+type UnaryClientInterceptor func(ctx context.Context, method string, req, reply any, cc *ClientConn, invoker UnaryInvoker, opts ...CallOption) error
+
+// CallOption configures one call
+type CallOption func(*callInfo)
+
+type callInfo struct {
+	trailer *MD
+}
+
+// Trailer stores the trailer metadata the server set into md once the
+// call completes
+func Trailer(md *MD) CallOption {
+	return func(ci *callInfo) { ci.trailer = md }
+}
+
+// ClientStream is the client's side of a streaming call
+type ClientStream interface {
+	Context() context.Context
+	SendMsg(m any) error
+	CloseSend() error
+	// RecvMsg returns io.EOF when the call ended with status OK,
+	// and the status error otherwise
+	RecvMsg(m any) error
+}
+
+// ClientConn sends calls to one target
+type ClientConn struct {
+	target string
+	dial   func(ctx context.Context, addr string) (net.Conn, error)
+	unary  []UnaryClientInterceptor
+	hc     *http.Client
+}
+
+// NewClient prepares calls to target, a host:port. Like grpc-go's
+// NewClient it does not connect: the first call does.
+func NewClient(target string, opts ...DialOption) (*ClientConn, error) {
+	cc := &ClientConn{target: target}
+	for _, opt := range opts {
+		opt(cc)
+	}
+	if cc.dial == nil {
+		var d net.Dialer
+		cc.dial = func(ctx context.Context, addr string) (net.Conn, error) {
+			return d.DialContext(ctx, "tcp", addr)
+		}
+	}
+
+	// Only UnencryptedHTTP2: http:// URLs use h2c with prior
+	// knowledge, no Upgrade round trip. No Client.Timeout either -
+	// it would cut streams; deadlines come from the call's context.
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	cc.hc = &http.Client{Transport: &http.Transport{
+		Protocols: &protocols,
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return cc.dial(ctx, addr)
+		},
+	}}
+	return cc, nil
+}
+
+// Close closes idle connections. Calls in flight finish.
+func (cc *ClientConn) Close() error {
+	cc.hc.CloseIdleConnections()
+	return nil
+}
+
+// Invoke makes a unary call through the client's interceptors
+func (cc *ClientConn) Invoke(ctx context.Context, method string, req, reply any, opts ...CallOption) error {
+	invoker := UnaryInvoker(invoke)
+	for i := len(cc.unary) - 1; i >= 0; i-- {
+		next, in := invoker, cc.unary[i]
+		invoker = func(ctx context.Context, method string, req, reply any, cc *ClientConn, opts ...CallOption) error {
+			return in(ctx, method, req, reply, cc, next, opts...)
+		}
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// invoke is a unary call: a stream with one message each way, and a
+// status that must be OK after the reply
+func invoke(ctx context.Context, method string, req, reply any, cc *ClientConn, opts ...CallOption) error {
+	cs, err := cc.NewStream(ctx, &StreamDesc{}, method, opts...)
+	if err != nil {
+		return err
+	}
+	if err := cs.SendMsg(req); err != nil {
+		return err
+	}
+	if err := cs.CloseSend(); err != nil {
+		return err
+	}
+	if err := cs.RecvMsg(reply); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Errorf(Internal, "grpc: server sent no reply")
+		}
+		return err
+	}
+	// Read on to the trailers: the status decides, not the reply
+	if err := cs.RecvMsg(discard{}); !errors.Is(err, io.EOF) {
+		if err == nil {
+			return Errorf(Internal, "grpc: server sent more than one reply")
+		}
+		return err
+	}
+	return nil
+}
+
+// NewStream starts a call. The request body is a pipe, so SendMsg
+// writes go out as they are made and replies can arrive before the
+// client has finished sending.
+func (cc *ClientConn) NewStream(ctx context.Context, desc *StreamDesc, method string, opts ...CallOption) (ClientStream, error) {
+	cs := &clientStream{desc: desc, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(&cs.call)
+	}
+	cs.ctx, cs.cancel = context.WithCancel(ctx)
+
+	pr, pw := io.Pipe()
+	cs.body = pw
+	req, err := http.NewRequestWithContext(cs.ctx, http.MethodPost, "http://"+cc.target+method, pr)
+	if err != nil {
+		cs.cancel()
+		return nil, Errorf(Internal, "grpc: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", encodeTimeout(time.Until(deadline)))
+	}
+	if md, ok := FromOutgoingContext(ctx); ok {
+		setHeaders(req.Header, md)
+	}
+
+	go func() {
+		cs.resp, cs.err = cc.hc.Do(req)
+		if cs.err != nil {
+			pr.CloseWithError(cs.err) // unblock a SendMsg waiting on the pipe
+		}
+		close(cs.done)
+	}()
+	return cs, nil
+}
+
+type clientStream struct {
+	desc   *StreamDesc
+	ctx    context.Context
+	cancel context.CancelFunc
+	call   callInfo
+	body   *io.PipeWriter
+
+	done chan struct{} // closed when resp or err is set
+	resp *http.Response
+	err  error
+
+	mu       sync.Mutex
+	finished error // io.EOF or the status error, once the call is over
+}
+
+func (cs *clientStream) Context() context.Context { return cs.ctx }
+
+// SendMsg returns io.EOF once the call is over, as grpc-go's does:
+// RecvMsg has the status that says why. A call that sends a single
+// message skips that step and goes straight to RecvMsg.
+func (cs *clientStream) SendMsg(m any) error {
+	if _, ok := m.(message); !ok {
+		return Errorf(Internal, "grpc: %T is not a protobuf message", m)
+	}
+	if err := writeMessage(cs.body, m); err != nil && cs.desc.ClientStreams {
+		return io.EOF
+	}
+	return nil
+}
+
+func (cs *clientStream) CloseSend() error { return cs.body.Close() }
+
+func (cs *clientStream) RecvMsg(m any) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.finished != nil {
+		return cs.finished
+	}
+	err := cs.recv(m)
+	if err != nil {
+		cs.finish(err)
+	}
+	return err
+}
+
+func (cs *clientStream) recv(m any) error {
+	select {
+	case <-cs.done:
+	case <-cs.ctx.Done():
+		return StatusOf(cs.ctx.Err())
+	}
+	if cs.err != nil {
+		return cs.transportError(cs.err)
+	}
+	resp := cs.resp
+	if resp.StatusCode != http.StatusOK {
+		return httpStatus(resp.StatusCode)
+	}
+	// A call that fails before any message may put grpc-status in the
+	// headers and send no body: a "trailers-only" response
+	if resp.Header.Get("Grpc-Status") != "" {
+		return cs.statusFrom(resp.Header)
+	}
+
+	err := readMessage(resp.Body, m)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, io.EOF):
+		// The body ended; resp.Trailer is filled in now
+		return cs.statusFrom(resp.Trailer)
+	case errors.As(err, new(*Status)):
+		return err
+	}
+	return cs.transportError(err)
+}
+
+// statusFrom reads the call's status from h, keeping the rest as
+// trailer metadata. OK becomes io.EOF: the stream is over, cleanly.
+func (cs *clientStream) statusFrom(h http.Header) error {
+	if cs.call.trailer != nil {
+		*cs.call.trailer = metadataFromHeader(h)
+	}
+	v := h.Get("Grpc-Status")
+	if v == "" {
+		return Errorf(Internal, "grpc: server sent no status")
+	}
+	code, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return Errorf(Internal, "grpc: malformed grpc-status %q", v)
+	}
+	if code == uint64(OK) {
+		return io.EOF
+	}
+	return &Status{Code: Code(code), Message: decodeMessage(h.Get("Grpc-Message"))}
+}
+
+// transportError maps a failure below gRPC to a status. The context is
+// checked first: a cancelled request surfaces as a network error.
+func (cs *clientStream) transportError(err error) error {
+	if ctxErr := cs.ctx.Err(); ctxErr != nil {
+		return StatusOf(ctxErr)
+	}
+	return &Status{Code: Unavailable, Message: err.Error()}
+}
+
+// finish ends the call: the context is released and the body closed,
+// which resets the HTTP/2 stream if the server is still sending
+func (cs *clientStream) finish(err error) {
+	cs.finished = err
+	cs.cancel()
+	cs.body.CloseWithError(err)
+	if cs.resp != nil {
+		cs.resp.Body.Close()
+	}
+}
+
+// discard reads and drops a message
+type discard struct{}
+
+func (discard) AppendProto(b []byte) []byte   { return b }
+func (discard) UnmarshalProto(b []byte) error { return nil }
+
+// httpStatus maps an HTTP error from something that is not a gRPC
+// server - a proxy, a load balancer - per the gRPC HTTP mapping
+func httpStatus(code int) error {
+	c := Unknown
+	switch code {
+	case http.StatusBadRequest:
+		c = Internal
+	case http.StatusUnauthorized:
+		c = Unauthenticated
+	case http.StatusForbidden:
+		c = PermissionDenied
+	case http.StatusNotFound:
+		c = Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		c = Unavailable
+	}
+	return &Status{Code: c, Message: "unexpected HTTP status " + strconv.Itoa(code) + " " + strings.ToLower(http.StatusText(code))}
+}
@@ -0,0 +1,126 @@
+package grpc
+
+// Greeter's Messages, Encoded by Hand
+// ===================================
+// The messages of greeter.proto, with the field names and getters
+// protoc-gen-go gives them. What protoc-gen-go also emits - protobuf
+// reflection, and the google.golang.org/protobuf runtime behind it - is
+// not in the standard library, so each message has AppendProto and
+// UnmarshalProto instead, written on wire.go. Nothing generates this
+// file: change it together with greeter.proto.
+
+// HelloRequest is greeter.v1.HelloRequest
+type HelloRequest struct {
+	Name string
+}
+
+func (x *HelloRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *HelloRequest) AppendProto(b []byte) []byte {
+	return appendStringField(b, 1, x.Name)
+}
+
+func (x *HelloRequest) UnmarshalProto(b []byte) error {
+	*x = HelloRequest{}
+	return fields(b, func(f field) error {
+		if f.num == 1 && f.wireType == wireBytes {
+			x.Name = string(f.data)
+		}
+		return nil
+	})
+}
+
+// HelloReply is greeter.v1.HelloReply
+type HelloReply struct {
+	Message string
+}
+
+func (x *HelloReply) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *HelloReply) AppendProto(b []byte) []byte {
+	return appendStringField(b, 1, x.Message)
+}
+
+func (x *HelloReply) UnmarshalProto(b []byte) error {
+	*x = HelloReply{}
+	return fields(b, func(f field) error {
+		if f.num == 1 && f.wireType == wireBytes {
+			x.Message = string(f.data)
+		}
+		return nil
+	})
+}
+
+// CountdownRequest is greeter.v1.CountdownRequest
+type CountdownRequest struct {
+	From       int32
+	IntervalMs int32
+}
+
+func (x *CountdownRequest) GetFrom() int32 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *CountdownRequest) GetIntervalMs() int32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+func (x *CountdownRequest) AppendProto(b []byte) []byte {
+	b = appendInt32Field(b, 1, x.From)
+	return appendInt32Field(b, 2, x.IntervalMs)
+}
+
+func (x *CountdownRequest) UnmarshalProto(b []byte) error {
+	*x = CountdownRequest{}
+	return fields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.wireType == wireVarint:
+			x.From = int32(f.varint)
+		case f.num == 2 && f.wireType == wireVarint:
+			x.IntervalMs = int32(f.varint)
+		}
+		return nil
+	})
+}
+
+// Tick is greeter.v1.Tick
+type Tick struct {
+	Remaining int32
+}
+
+func (x *Tick) GetRemaining() int32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *Tick) AppendProto(b []byte) []byte {
+	return appendInt32Field(b, 1, x.Remaining)
+}
+
+func (x *Tick) UnmarshalProto(b []byte) error {
+	*x = Tick{}
+	return fields(b, func(f field) error {
+		if f.num == 1 && f.wireType == wireVarint {
+			x.Remaining = int32(f.varint)
+		}
+		return nil
+	})
+}
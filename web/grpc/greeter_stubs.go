@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+)
+
+// Greeter's Stubs, Written by Hand
+// ================================
+// The client, the server interface and the ServiceDesc that
+// protoc-gen-go-grpc would write for the Greeter service, kept in its
+// shape and naming so the lesson reads like a grpc-go codebase. With
+// grpc-go the runtime identifiers would be qualified (grpc.ServiceDesc,
+// grpc.CallOption); here the runtime is this package.
+
+const (
+	Greeter_SayHello_FullMethodName  = "/greeter.v1.Greeter/SayHello"
+	Greeter_Countdown_FullMethodName = "/greeter.v1.Greeter/Countdown"
+)
+
+// GreeterClient is the client API for the Greeter service
+type GreeterClient interface {
+	// SayHello greets one person. Unary: one request, one response.
+	SayHello(ctx context.Context, in *HelloRequest, opts ...CallOption) (*HelloReply, error)
+	// Countdown sends a Tick per interval, from `from` down to 1.
+	// Server streaming: one request, many responses.
+	Countdown(ctx context.Context, in *CountdownRequest, opts ...CallOption) (Greeter_CountdownClient, error)
+}
+
+type greeterClient struct {
+	cc *ClientConn
+}
+
+func NewGreeterClient(cc *ClientConn) GreeterClient {
+	return &greeterClient{cc}
+}
+
+func (c *greeterClient) SayHello(ctx context.Context, in *HelloRequest, opts ...CallOption) (*HelloReply, error) {
+	out := new(HelloReply)
+	err := c.cc.Invoke(ctx, Greeter_SayHello_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *greeterClient) Countdown(ctx context.Context, in *CountdownRequest, opts ...CallOption) (Greeter_CountdownClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Greeter_ServiceDesc.Streams[0], Greeter_Countdown_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &greeterCountdownClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Greeter_CountdownClient interface {
+	Recv() (*Tick, error)
+	ClientStream
+}
+
+type greeterCountdownClient struct {
+	ClientStream
+}
+
+func (x *greeterCountdownClient) Recv() (*Tick, error) {
+	m := new(Tick)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GreeterServer is the server API for the Greeter service. Embed
+// UnimplementedGreeterServer so adding an RPC to the .proto does not
+// break implementations that have not caught up.
+type GreeterServer interface {
+	SayHello(context.Context, *HelloRequest) (*HelloReply, error)
+	Countdown(*CountdownRequest, Greeter_CountdownServer) error
+	mustEmbedUnimplementedGreeterServer()
+}
+
+// UnimplementedGreeterServer answers every RPC with Unimplemented
+type UnimplementedGreeterServer struct{}
+
+func (UnimplementedGreeterServer) SayHello(context.Context, *HelloRequest) (*HelloReply, error) {
+	return nil, Errorf(Unimplemented, "method SayHello not implemented")
+}
+func (UnimplementedGreeterServer) Countdown(*CountdownRequest, Greeter_CountdownServer) error {
+	return Errorf(Unimplemented, "method Countdown not implemented")
+}
+func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
+
+func RegisterGreeterServer(s *Server, srv GreeterServer) {
+	s.RegisterService(&Greeter_ServiceDesc, srv)
+}
+
+func _Greeter_SayHello_Handler(srv any, ctx context.Context, dec func(any) error, interceptor UnaryServerInterceptor) (any, error) {
+	in := new(HelloRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreeterServer).SayHello(ctx, in)
+	}
+	info := &UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Greeter_SayHello_FullMethodName,
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(GreeterServer).SayHello(ctx, req.(*HelloRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Greeter_Countdown_Handler(srv any, stream ServerStream) error {
+	m := new(CountdownRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GreeterServer).Countdown(m, &greeterCountdownServer{stream})
+}
+
+type Greeter_CountdownServer interface {
+	Send(*Tick) error
+	ServerStream
+}
+
+type greeterCountdownServer struct {
+	ServerStream
+}
+
+func (x *greeterCountdownServer) Send(m *Tick) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Greeter_ServiceDesc is the ServiceDesc for the Greeter service
+var Greeter_ServiceDesc = ServiceDesc{
+	ServiceName: "greeter.v1.Greeter",
+	HandlerType: (*GreeterServer)(nil),
+	Methods: []MethodDesc{
+		{
+			MethodName: "SayHello",
+			Handler:    _Greeter_SayHello_Handler,
+		},
+	},
+	Streams: []StreamDesc{
+		{
+			StreamName:    "Countdown",
+			Handler:       _Greeter_Countdown_Handler,
+			ServerStreams: true,
+		},
+	},
+}
@@ -0,0 +1,800 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gRPC - Tests
+// ============
+// Run with:
+//
+//   cd web/grpc
+//   go test -v *.go
+//   go test -race *.go
+//
+// Servers listen on a memListener, what grpc-go's test/bufconn
+// provides: connections are in-memory pipes, so tests need no ports
+// and the real client and server code runs end to end, HTTP/2 and all.
+
+// memListener is a net.Listener whose Dial hands one end of a net.Pipe
+// to Accept
+type memListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newMemListener() *memListener {
+	return &memListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *memListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *memListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *memListener) Addr() net.Addr { return memAddr{} }
+
+func (l *memListener) Dial(ctx context.Context, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, errors.New("memListener closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type memAddr struct{}
+
+func (memAddr) Network() string { return "mem" }
+func (memAddr) String() string  { return "bufconn" }
+
+// start serves a Greeter with opts and returns a connected client.
+// Everything is torn down when the test ends.
+func start(t *testing.T, impl GreeterServer, opts []ServerOption, dialOpts ...DialOption) (GreeterClient, *ClientConn) {
+	t.Helper()
+	ln := newMemListener()
+	srv := NewServer(opts...)
+	RegisterGreeterServer(srv, impl)
+	go srv.Serve(ln)
+
+	cc, err := NewClient("bufconn", append(dialOpts, WithContextDialer(ln.Dial))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cc.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+	return NewGreeterClient(cc), cc
+}
+
+// 1. Protobuf Encoding
+// ====================
+
+func TestMessageEncoding(t *testing.T) {
+	tests := []struct {
+		m    message
+		want []byte
+	}{
+		{&HelloRequest{Name: "gopher"}, []byte{0x0a, 6, 'g', 'o', 'p', 'h', 'e', 'r'}},
+		{&HelloRequest{}, nil}, // zero values are not sent
+		{&Tick{Remaining: 3}, []byte{0x08, 3}},
+		{&CountdownRequest{From: 300, IntervalMs: 5}, []byte{0x08, 0xac, 0x02, 0x10, 5}},
+		// A negative int32 costs ten bytes
+		{&Tick{Remaining: -1}, []byte{0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	}
+	for _, tt := range tests {
+		if got := tt.m.AppendProto(nil); !bytes.Equal(got, tt.want) {
+			t.Errorf("%T%+v = % x, want % x", tt.m, tt.m, got, tt.want)
+		}
+	}
+
+	var tick Tick
+	if err := tick.UnmarshalProto([]byte{0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}); err != nil || tick.Remaining != -1 {
+		t.Errorf("negative round trip = %d, %v", tick.Remaining, err)
+	}
+}
+
+func TestUnknownFieldsAreSkipped(t *testing.T) {
+	// A newer client sends field 2 (a string), 3 (a varint) and 4 (a
+	// fixed32) that this HelloRequest has never heard of
+	b := (&HelloRequest{Name: "ada"}).AppendProto(nil)
+	b = appendStringField(b, 2, "extra")
+	b = appendInt32Field(b, 3, 7)
+	b = append(appendTag(b, 4, wireI32), 1, 2, 3, 4)
+
+	var req HelloRequest
+	if err := req.UnmarshalProto(b); err != nil || req.Name != "ada" {
+		t.Errorf("got %+v, %v", req, err)
+	}
+}
+
+func TestMalformedMessages(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x0a},             // tag, no length
+		{0x0a, 5, 'a'},     // length past the end
+		{0x08},             // varint missing
+		{0x00, 1},          // field number 0
+		{0x0b},             // wire type 3, a group
+		{0x0d, 1, 2},       // fixed32 cut short
+		{0x0a, 0xff, 0xff}, // unterminated length varint
+	} {
+		var req HelloRequest
+		if err := req.UnmarshalProto(b); !errors.Is(err, errBadProto) {
+			t.Errorf("% x: err = %v, want errBadProto", b, err)
+		}
+	}
+}
+
+// 2. Framing and Timeouts
+// =======================
+
+func TestFraming(t *testing.T) {
+	var buf bytes.Buffer
+	writeMessage(&buf, &HelloRequest{Name: "gopher"})
+	writeMessage(&buf, &HelloRequest{})
+	want := []byte{0, 0, 0, 0, 8, 0x0a, 6, 'g', 'o', 'p', 'h', 'e', 'r', 0, 0, 0, 0, 0}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("frames = % x\nwant     % x", buf.Bytes(), want)
+	}
+
+	var req HelloRequest
+	for _, wantName := range []string{"gopher", ""} {
+		if err := readMessage(&buf, &req); err != nil || req.Name != wantName {
+			t.Errorf("read = %q, %v; want %q", req.Name, err, wantName)
+		}
+	}
+	if err := readMessage(&buf, &req); err != io.EOF {
+		t.Errorf("at end: err = %v, want io.EOF", err)
+	}
+
+	tests := []struct {
+		frame []byte
+		want  Code
+	}{
+		{[]byte{0, 0, 0}, Internal},             // header cut short
+		{[]byte{0, 0, 0, 0, 4, 0x0a}, Internal}, // body cut short
+		{[]byte{1, 0, 0, 0, 0}, Unimplemented},  // compressed
+		{[]byte{0, 0xff, 0xff, 0xff, 0xff}, ResourceExhausted},
+		{[]byte{0, 0, 0, 0, 1, 0x0a}, Internal}, // bad protobuf
+	}
+	for _, tt := range tests {
+		if err := readMessage(bytes.NewReader(tt.frame), &req); CodeOf(err) != tt.want {
+			t.Errorf("% x: err = %v, want %s", tt.frame, err, tt.want)
+		}
+	}
+}
+
+func TestTimeoutEncoding(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{250 * time.Millisecond, "250000u"},
+		{time.Nanosecond, "1n"},
+		{0, "1n"},
+		{99_999_999 * time.Nanosecond, "99999999n"},
+		{100 * time.Millisecond, "100000u"},
+		{1500 * time.Microsecond, "1500000n"},
+		{2 * time.Minute, "120000m"},
+		{30 * time.Hour, "108000S"},
+	}
+	for _, tt := range tests {
+		got := encodeTimeout(tt.d)
+		if got != tt.want {
+			t.Errorf("encodeTimeout(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+		// Rounding is up, never down
+		if back, err := parseTimeout(got); err != nil || back < tt.d {
+			t.Errorf("parseTimeout(%q) = %v, %v; want >= %v", got, back, err, tt.d)
+		}
+	}
+
+	for _, bad := range []string{"", "5", "m", "123456789m", "5x", "-5m", "1.5S"} {
+		if _, err := parseTimeout(bad); err == nil {
+			t.Errorf("parseTimeout(%q) succeeded", bad)
+		}
+	}
+	if d, err := parseTimeout("99999999H"); err != nil || d <= 0 {
+		t.Errorf("99999999H = %v, %v; want a clamped positive duration", d, err)
+	}
+}
+
+func TestStatusMessageEncoding(t *testing.T) {
+	for _, msg := range []string{"plain", "100% sure", "nom «vide»", "line\nbreak", "🙂"} {
+		enc := encodeMessage(msg)
+		for _, c := range []byte(enc) {
+			if c < 0x20 || c > 0x7e {
+				t.Errorf("encodeMessage(%q) = %q: not printable ASCII", msg, enc)
+				break
+			}
+		}
+		if got := decodeMessage(enc); got != msg {
+			t.Errorf("round trip %q -> %q -> %q", msg, enc, got)
+		}
+	}
+	if got := decodeMessage("50%zz"); got != "50%zz" {
+		t.Errorf("malformed escape = %q, want it kept", got)
+	}
+}
+
+// 3. Unary Calls
+// ==============
+
+func TestSayHello(t *testing.T) {
+	client, _ := start(t, Greeter{}, nil)
+	ctx := context.Background()
+
+	reply, err := client.SayHello(ctx, &HelloRequest{Name: "gopher"})
+	if err != nil || reply.GetMessage() != "Hello, gopher" {
+		t.Fatalf("SayHello = %v, %v", reply, err)
+	}
+
+	// Metadata chooses the language
+	reply, err = client.SayHello(NewOutgoingContext(ctx, Pairs("lang", "es")), &HelloRequest{Name: "gopher"})
+	if err != nil || reply.GetMessage() != "Hola, gopher" {
+		t.Errorf("with lang=es: %v, %v", reply, err)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	client, _ := start(t, Greeter{}, nil)
+	ctx := context.Background()
+
+	// The handler's code and message arrive intact - message encoding
+	// included
+	_, err := client.SayHello(NewOutgoingContext(ctx, Pairs("lang", "tlh«")), &HelloRequest{Name: "x"})
+	var st *Status
+	if !errors.As(err, &st) || st.Code != InvalidArgument || st.Message != `unsupported language "tlh«"` {
+		t.Errorf("err = %#v", err)
+	}
+
+	if _, err := client.SayHello(ctx, &HelloRequest{Name: "  "}); CodeOf(err) != InvalidArgument {
+		t.Errorf("blank name: %v", err)
+	}
+}
+
+// halfGreeter implements only SayHello; the embedded
+// UnimplementedGreeterServer answers the rest
+type halfGreeter struct {
+	UnimplementedGreeterServer
+}
+
+func TestUnimplemented(t *testing.T) {
+	client, cc := start(t, halfGreeter{}, nil)
+	ctx := context.Background()
+
+	if _, err := client.SayHello(ctx, &HelloRequest{Name: "x"}); CodeOf(err) != Unimplemented {
+		t.Errorf("embedded default: %v", err)
+	}
+
+	// Methods and services the server has never heard of
+	for _, method := range []string{"/greeter.v1.Greeter/Nope", "/other.Service/Call", "/nonsense"} {
+		err := cc.Invoke(ctx, method, &HelloRequest{}, &HelloReply{})
+		if CodeOf(err) != Unimplemented {
+			t.Errorf("%s: err = %v, want Unimplemented", method, err)
+		}
+	}
+}
+
+func TestRegisterChecksType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a value that is not a GreeterServer did not panic")
+		}
+	}()
+	NewServer().RegisterService(&Greeter_ServiceDesc, "not a server")
+}
+
+func TestNotAGRPCServer(t *testing.T) {
+	// A client talking to a plain HTTP/2 server gets a status from the
+	// HTTP code: 404 reads as Unimplemented, 503 as Unavailable
+	for code, want := range map[int]Code{http.StatusNotFound: Unimplemented, http.StatusServiceUnavailable: Unavailable} {
+		ln := newMemListener()
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		hs := &http.Server{Protocols: &protocols, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		})}
+		go hs.Serve(ln)
+
+		cc, _ := NewClient("bufconn", WithContextDialer(ln.Dial))
+		_, err := NewGreeterClient(cc).SayHello(context.Background(), &HelloRequest{Name: "x"})
+		if CodeOf(err) != want {
+			t.Errorf("HTTP %d: err = %v, want %s", code, err, want)
+		}
+		cc.Close()
+		hs.Close()
+	}
+
+	// An unreachable server is Unavailable
+	cc, _ := NewClient("bufconn", WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}))
+	if _, err := NewGreeterClient(cc).SayHello(context.Background(), &HelloRequest{Name: "x"}); CodeOf(err) != Unavailable {
+		t.Errorf("refused: %v", err)
+	}
+}
+
+func TestHTTPLevelChecks(t *testing.T) {
+	srv := NewServer()
+	RegisterGreeterServer(srv, Greeter{})
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{"HTTP/1.1", func() *http.Request {
+			return httptest.NewRequest("POST", Greeter_SayHello_FullMethodName, nil)
+		}, http.StatusHTTPVersionNotSupported},
+		{"GET", func() *http.Request {
+			r := httptest.NewRequest("GET", Greeter_SayHello_FullMethodName, nil)
+			r.ProtoMajor = 2
+			return r
+		}, http.StatusMethodNotAllowed},
+		{"JSON", func() *http.Request {
+			r := httptest.NewRequest("POST", Greeter_SayHello_FullMethodName, strings.NewReader("{}"))
+			r.ProtoMajor = 2
+			r.Header.Set("Content-Type", "application/json")
+			return r
+		}, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, tt.req())
+		if rec.Code != tt.want || rec.Header().Get("Grpc-Status") != "" {
+			t.Errorf("%s: %d, grpc-status %q; want %d and no status", tt.name, rec.Code, rec.Header().Get("Grpc-Status"), tt.want)
+		}
+	}
+
+	// The content-type variants are all gRPC
+	for ct, want := range map[string]bool{
+		"application/grpc": true, "application/grpc+proto": true, "application/grpc;charset=utf-8": true,
+		"application/grpcx": false, "application/json": false, "": false,
+	} {
+		if isGRPCContentType(ct) != want {
+			t.Errorf("isGRPCContentType(%q) = %v", ct, !want)
+		}
+	}
+}
+
+// 4. Server Streaming
+// ===================
+
+func TestCountdown(t *testing.T) {
+	client, _ := start(t, Greeter{}, nil)
+
+	stream, err := client.Countdown(context.Background(), &CountdownRequest{From: 3, IntervalMs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int32
+	for {
+		tick, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tick.GetRemaining())
+	}
+	if fmt.Sprint(got) != "[3 2 1]" {
+		t.Errorf("ticks = %v", got)
+	}
+	// The end stays the end
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Recv after EOF = %v", err)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	client, _ := start(t, Greeter{}, nil)
+
+	// Starting the stream succeeds: the status only arrives with the
+	// first Recv
+	stream, err := client.Countdown(context.Background(), &CountdownRequest{From: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); CodeOf(err) != InvalidArgument {
+		t.Errorf("Recv = %v, want InvalidArgument", err)
+	}
+}
+
+// failAfter sends n ticks, then fails: the status follows the messages
+type failAfter struct {
+	UnimplementedGreeterServer
+	n int32
+}
+
+func (f failAfter) Countdown(req *CountdownRequest, stream Greeter_CountdownServer) error {
+	for i := range f.n {
+		stream.Send(&Tick{Remaining: i})
+	}
+	return Errorf(DataLoss, "lost the rest")
+}
+
+func TestStatusAfterMessages(t *testing.T) {
+	client, _ := start(t, failAfter{n: 2}, nil)
+	stream, _ := client.Countdown(context.Background(), &CountdownRequest{From: 5})
+
+	for i := range 2 {
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("tick %d: %v", i, err)
+		}
+	}
+	if _, err := stream.Recv(); CodeOf(err) != DataLoss {
+		t.Errorf("after two ticks: %v, want DataLoss", err)
+	}
+}
+
+func TestClientCancelsStream(t *testing.T) {
+	// The server notices a client that stops listening: its context
+	// ends and Countdown returns early
+	done := make(chan error, 1)
+	impl := streamSpy{done: done}
+	client, _ := start(t, impl, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, _ := client.Countdown(ctx, &CountdownRequest{From: 1000, IntervalMs: 1})
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := stream.Recv(); CodeOf(err) != Canceled {
+		t.Errorf("Recv after cancel = %v, want Canceled", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("server handler returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server handler still running after the client cancelled")
+	}
+}
+
+// streamSpy is Greeter with the handler's result reported on done
+type streamSpy struct {
+	Greeter
+	done chan<- error
+}
+
+func (s streamSpy) Countdown(req *CountdownRequest, stream Greeter_CountdownServer) error {
+	err := s.Greeter.Countdown(req, stream)
+	s.done <- err
+	return err
+}
+
+// 5. Deadlines
+// ============
+
+func TestDeadlinePropagates(t *testing.T) {
+	// An interceptor records the deadline the server saw
+	seen := make(chan time.Duration, 1)
+	spy := func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			seen <- -1
+		} else {
+			seen <- time.Until(deadline)
+		}
+		return handler(ctx, req)
+	}
+	client, _ := start(t, Greeter{}, []ServerOption{ChainUnaryInterceptor(spy)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.SayHello(ctx, &HelloRequest{Name: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if left := <-seen; left <= 4*time.Second || left > 5*time.Second {
+		t.Errorf("server saw %v left, want just under 5s", left)
+	}
+
+	// Without a deadline, none is sent
+	client.SayHello(context.Background(), &HelloRequest{Name: "x"})
+	if left := <-seen; left != -1 {
+		t.Errorf("no deadline: server saw %v", left)
+	}
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	done := make(chan error, 1)
+	client, _ := start(t, streamSpy{done: done}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stream, err := client.Countdown(ctx, &CountdownRequest{From: 1000, IntervalMs: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ticks int
+	for {
+		_, err = stream.Recv()
+		if err != nil {
+			break
+		}
+		ticks++
+	}
+	if CodeOf(err) != DeadlineExceeded || ticks == 0 || ticks > 10 {
+		t.Errorf("after %d ticks: %v, want DeadlineExceeded after a few", ticks, err)
+	}
+
+	// The server got the same deadline through grpc-timeout and stopped
+	// on its own
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			t.Errorf("server handler returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server handler still running past the deadline")
+	}
+}
+
+// 6. Metadata and Interceptors
+// ============================
+
+func TestAuthInterceptor(t *testing.T) {
+	client, _ := start(t, Greeter{}, []ServerOption{
+		ChainUnaryInterceptor(AuthInterceptor("s3cret")),
+		ChainStreamInterceptor(StreamAuthInterceptor("s3cret")),
+	})
+	ctx := context.Background()
+
+	if _, err := client.SayHello(ctx, &HelloRequest{Name: "x"}); CodeOf(err) != Unauthenticated {
+		t.Errorf("no token: %v", err)
+	}
+	bad := AppendToOutgoingContext(ctx, "authorization", "Bearer guess")
+	if _, err := client.SayHello(bad, &HelloRequest{Name: "x"}); CodeOf(err) != Unauthenticated {
+		t.Errorf("wrong token: %v", err)
+	}
+	good := AppendToOutgoingContext(ctx, "authorization", "Bearer s3cret")
+	if _, err := client.SayHello(good, &HelloRequest{Name: "x"}); err != nil {
+		t.Errorf("right token: %v", err)
+	}
+
+	// Streams are guarded by the stream interceptor
+	stream, _ := client.Countdown(ctx, &CountdownRequest{From: 1})
+	if _, err := stream.Recv(); CodeOf(err) != Unauthenticated {
+		t.Errorf("stream without token: %v", err)
+	}
+	stream, _ = client.Countdown(good, &CountdownRequest{From: 1})
+	if _, err := stream.Recv(); err != nil {
+		t.Errorf("stream with token: %v", err)
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+			mu.Lock()
+			order = append(order, name+" in "+info.FullMethod)
+			mu.Unlock()
+			reply, err := handler(ctx, req)
+			mu.Lock()
+			order = append(order, name+" out")
+			mu.Unlock()
+			return reply, err
+		}
+	}
+	client, _ := start(t, Greeter{}, []ServerOption{
+		ChainUnaryInterceptor(record("A"), record("B")),
+		ChainUnaryInterceptor(record("C")), // options accumulate
+	})
+	client.SayHello(context.Background(), &HelloRequest{Name: "x"})
+
+	want := "[A in /greeter.v1.Greeter/SayHello B in /greeter.v1.Greeter/SayHello C in /greeter.v1.Greeter/SayHello C out B out A out]"
+	if fmt.Sprint(order) != want {
+		t.Errorf("order = %v\nwant    %s", order, want)
+	}
+}
+
+// panicky panics for one name
+type panicky struct {
+	Greeter
+}
+
+func (p panicky) SayHello(ctx context.Context, req *HelloRequest) (*HelloReply, error) {
+	if req.GetName() == "boom" {
+		panic("boom")
+	}
+	return p.Greeter.SayHello(ctx, req)
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	client, _ := start(t, panicky{}, []ServerOption{
+		ChainUnaryInterceptor(LoggingInterceptor(logger), RecoveryInterceptor(logger)),
+	})
+
+	_, err := client.SayHello(context.Background(), &HelloRequest{Name: "boom"})
+	if st := StatusOf(err); st.Code != Internal || st.Message != "internal error" {
+		t.Errorf("panic became %v; want Internal without details", err)
+	}
+	// The server keeps serving
+	if _, err := client.SayHello(context.Background(), &HelloRequest{Name: "x"}); err != nil {
+		t.Errorf("after the panic: %v", err)
+	}
+
+	// Recovery is inside logging, so the log shows the Internal code
+	out := logs.String()
+	for _, want := range []string{"panic in handler", "code=Internal", "code=OK", "method=/greeter.v1.Greeter/SayHello"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestClientInterceptorAndTrailers(t *testing.T) {
+	// The server echoes the incoming metadata back as trailers
+	echo := func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+		md, _ := FromIncomingContext(ctx)
+		SetTrailer(ctx, Pairs("saw-lang", strings.Join(md.Get("lang"), ","), "saw-request-id", strings.Join(md.Get("x-request-id"), ",")))
+		return handler(ctx, req)
+	}
+	client, _ := start(t, Greeter{}, []ServerOption{ChainUnaryInterceptor(echo)},
+		WithChainUnaryInterceptor(MetadataInterceptor("x-request-id", "req-42")))
+
+	ctx := AppendToOutgoingContext(context.Background(), "lang", "fr")
+	var trailer MD
+	reply, err := client.SayHello(ctx, &HelloRequest{Name: "x"}, Trailer(&trailer))
+	if err != nil || reply.GetMessage() != "Bonjour, x" {
+		t.Fatalf("SayHello = %v, %v", reply, err)
+	}
+	if got := trailer.Get("saw-request-id"); len(got) != 1 || got[0] != "req-42" {
+		t.Errorf("request id in trailer = %v", got)
+	}
+	if got := trailer.Get("saw-lang"); len(got) != 1 || got[0] != "fr" {
+		t.Errorf("lang in trailer = %v", got)
+	}
+	// The interceptor copied: the caller's context is unchanged
+	if md, _ := FromOutgoingContext(ctx); len(md.Get("x-request-id")) != 0 {
+		t.Error("the client interceptor changed the caller's metadata")
+	}
+
+	if err := SetTrailer(context.Background(), Pairs("k", "v")); err == nil {
+		t.Error("SetTrailer outside a handler succeeded")
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	// Many calls share the one HTTP/2 connection
+	var dials int
+	var mu sync.Mutex
+	ln := newMemListener()
+	srv := NewServer()
+	RegisterGreeterServer(srv, Greeter{})
+	go srv.Serve(ln)
+	cc, _ := NewClient("bufconn", WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		mu.Lock()
+		dials++
+		mu.Unlock()
+		return ln.Dial(ctx, addr)
+	}))
+	t.Cleanup(func() { cc.Close(); ln.Close() })
+	client := NewGreeterClient(cc)
+
+	// One call first, so the rest find the connection already open
+	client.SayHello(context.Background(), &HelloRequest{Name: "first"})
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() {
+			name := fmt.Sprint("g", i)
+			reply, err := client.SayHello(context.Background(), &HelloRequest{Name: name})
+			if err != nil || reply.GetMessage() != "Hello, "+name {
+				t.Errorf("call %d: %v, %v", i, reply, err)
+			}
+		})
+	}
+	wg.Wait()
+	if dials != 1 {
+		t.Errorf("%d connections for 51 calls, want 1", dials)
+	}
+}
+
+// 7. Benchmarks
+// =============
+
+func BenchmarkSayHello(b *testing.B) {
+	ln := newMemListener()
+	srv := NewServer()
+	RegisterGreeterServer(srv, Greeter{})
+	go srv.Serve(ln)
+	defer ln.Close()
+	cc, _ := NewClient("bufconn", WithContextDialer(ln.Dial))
+	defer cc.Close()
+	client := NewGreeterClient(cc)
+	ctx := context.Background()
+	req := &HelloRequest{Name: "gopher"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := client.SayHello(ctx, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	req := &CountdownRequest{From: 300, IntervalMs: 25}
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for b.Loop() {
+		buf = req.AppendProto(buf[:0])
+	}
+}
+
+// Examples
+// ========
+
+func ExampleGreeterClient() {
+	ln := newMemListener()
+	srv := NewServer()
+	RegisterGreeterServer(srv, Greeter{})
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	cc, _ := NewClient("bufconn", WithContextDialer(ln.Dial))
+	defer cc.Close()
+	client := NewGreeterClient(cc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	reply, _ := client.SayHello(AppendToOutgoingContext(ctx, "lang", "de"), &HelloRequest{Name: "Gopher"})
+	fmt.Println(reply.GetMessage())
+
+	stream, _ := client.Countdown(ctx, &CountdownRequest{From: 3})
+	for {
+		tick, err := stream.Recv()
+		if err != nil {
+			fmt.Println("end:", err)
+			break
+		}
+		fmt.Println(tick.GetRemaining())
+	}
+
+	_, err := client.SayHello(ctx, &HelloRequest{})
+	fmt.Println(CodeOf(err), StatusOf(err).Message)
+	// Output:
+	// Hallo, Gopher
+	// 3
+	// 2
+	// 1
+	// end: EOF
+	// InvalidArgument name is required
+}
@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Metadata
+// ========
+// Metadata is gRPC's name for request and response headers: keys with
+// lists of values, sent alongside the messages. Authentication tokens,
+// request IDs and tracing context travel this way, not inside the
+// protobuf messages.
+//
+// Keys are lowercase. The grpc- prefix and the HTTP/2 pseudo-headers
+// are reserved; keys ending in -bin carry binary values, base64 encoded
+// on the wire (this lesson only sends text).
+//
+// A client attaches outgoing metadata to its context; the server finds
+// it in the handler's context as incoming metadata. The two use
+// different keys so a server that calls another service does not pass
+// its own caller's headers along by accident.
+
+// MD is a set of metadata
+type MD map[string][]string
+
+// Pairs builds an MD from key, value, key, value...
+func Pairs(kv ...string) MD {
+	if len(kv)%2 == 1 {
+		panic("grpc: Pairs got an odd number of arguments")
+	}
+	md := MD{}
+	for i := 0; i < len(kv); i += 2 {
+		k := strings.ToLower(kv[i])
+		md[k] = append(md[k], kv[i+1])
+	}
+	return md
+}
+
+// Get returns the values for key, in any case
+func (md MD) Get(key string) []string { return md[strings.ToLower(key)] }
+
+type outgoingKey struct{}
+type incomingKey struct{}
+
+// NewOutgoingContext attaches md to requests made with ctx, replacing
+// any metadata attached before
+func NewOutgoingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, outgoingKey{}, md)
+}
+
+// AppendToOutgoingContext adds pairs to ctx's outgoing metadata,
+// copying rather than changing what a parent context holds
+func AppendToOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromOutgoingContext(ctx)
+	merged := MD{}
+	for k, v := range md {
+		merged[k] = append([]string(nil), v...)
+	}
+	for k, v := range Pairs(kv...) {
+		merged[k] = append(merged[k], v...)
+	}
+	return NewOutgoingContext(ctx, merged)
+}
+
+// FromOutgoingContext returns the metadata a client call will send
+func FromOutgoingContext(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(outgoingKey{}).(MD)
+	return md, ok
+}
+
+// FromIncomingContext returns the metadata the caller sent
+func FromIncomingContext(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(incomingKey{}).(MD)
+	return md, ok
+}
+
+func newIncomingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, incomingKey{}, md)
+}
+
+// reservedHeader reports whether an HTTP header belongs to the protocol
+// rather than to the application
+func reservedHeader(key string) bool {
+	switch key {
+	case "content-type", "te", "user-agent", "content-length", "accept-encoding", "trailer":
+		return true
+	}
+	return strings.HasPrefix(key, "grpc-") || strings.HasPrefix(key, ":")
+}
+
+// metadataFromHeader collects the application's keys from h
+func metadataFromHeader(h http.Header) MD {
+	md := MD{}
+	for k, v := range h {
+		k = strings.ToLower(k)
+		if !reservedHeader(k) {
+			md[k] = append(md[k], v...)
+		}
+	}
+	return md
+}
+
+// setHeaders copies md into h, dropping reserved keys
+func setHeaders(h http.Header, md MD) {
+	for k, v := range md {
+		k = strings.ToLower(k)
+		if reservedHeader(k) {
+			continue
+		}
+		for _, s := range v {
+			h.Add(k, s)
+		}
+	}
+}
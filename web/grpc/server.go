@@ -0,0 +1,340 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The Server
+// ==========
+// Server is an http.Handler. It routes /package.Service/Method to a
+// registered handler, turns grpc-timeout into a context deadline and
+// headers into incoming metadata, runs the interceptors and writes the
+// status as trailers.
+//
+// Registration goes through a ServiceDesc, the table that
+// protoc-gen-go-grpc writes for each service: method names paired with
+// small functions that decode the request and call the implementation.
+// The server never needs to know the concrete message types.
+
+// ServiceDesc describes a service for RegisterService
+type ServiceDesc struct {
+	ServiceName string // "greeter.v1.Greeter"
+	HandlerType any    // (*GreeterServer)(nil), checked at registration
+	Methods     []MethodDesc
+	Streams     []StreamDesc
+}
+
+// MethodDesc is a unary method
+type MethodDesc struct {
+	MethodName string
+	Handler    func(srv any, ctx context.Context, dec func(any) error, interceptor UnaryServerInterceptor) (any, error)
+}
+
+// StreamDesc is a streaming method
+type StreamDesc struct {
+	StreamName    string
+	Handler       func(srv any, stream ServerStream) error
+	ServerStreams bool
+	ClientStreams bool
+}
+
+// UnaryHandler is the rest of a unary call, past an interceptor
+type UnaryHandler func(ctx context.Context, req any) (any, error)
+
+// UnaryServerInfo tells an interceptor which method it is wrapping
+type UnaryServerInfo struct {
+	Server     any
+	FullMethod string // "/greeter.v1.Greeter/SayHello"
+}
+
+// UnaryServerInterceptor runs around every unary call: it may inspect
+// or replace the context and request, call handler or refuse to, and
+// inspect or replace the reply and error
+type UnaryServerInterceptor func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error)
+
+// StreamHandler is the rest of a streaming call, past an interceptor
+type StreamHandler func(srv any, stream ServerStream) error
+
+// StreamServerInfo tells a stream interceptor which method it wraps
+type StreamServerInfo struct {
+	FullMethod     string
+	IsClientStream bool
+	IsServerStream bool
+}
+
+// StreamServerInterceptor runs around every streaming call. To change
+// the context it wraps the stream: ServerStream.Context is the only way
+// a streaming handler sees one.
+type StreamServerInterceptor func(srv any, ss ServerStream, info *StreamServerInfo, handler StreamHandler) error
+
+// ServerStream is the server's side of a streaming call
+type ServerStream interface {
+	Context() context.Context
+	SendMsg(m any) error
+	RecvMsg(m any) error
+}
+
+// ServerOption configures NewServer
+type ServerOption func(*Server)
+
+// ChainUnaryInterceptor adds interceptors; the first added is the
+// outermost, so it runs first on the way in and last on the way out
+func ChainUnaryInterceptor(ints ...UnaryServerInterceptor) ServerOption {
+	return func(s *Server) { s.unary = append(s.unary, ints...) }
+}
+
+// ChainStreamInterceptor is ChainUnaryInterceptor for streams
+func ChainStreamInterceptor(ints ...StreamServerInterceptor) ServerOption {
+	return func(s *Server) { s.stream = append(s.stream, ints...) }
+}
+
+// Server dispatches gRPC calls to registered services
+type Server struct {
+	services map[string]*service
+	unary    []UnaryServerInterceptor
+	stream   []StreamServerInterceptor
+
+	mu   sync.Mutex
+	http *http.Server
+}
+
+type service struct {
+	impl    any
+	methods map[string]*MethodDesc
+	streams map[string]*StreamDesc
+}
+
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{services: map[string]*service{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RegisterService adds impl under sd; the RegisterXServer stubs call
+// it. It panics on a duplicate or on an impl of the
+// wrong type - both are programming errors found at startup.
+func (s *Server) RegisterService(sd *ServiceDesc, impl any) {
+	if _, dup := s.services[sd.ServiceName]; dup {
+		panic("grpc: service " + sd.ServiceName + " registered twice")
+	}
+	if !implements(impl, sd.HandlerType) {
+		panic("grpc: implementation does not satisfy " + sd.ServiceName)
+	}
+	svc := &service{impl: impl, methods: map[string]*MethodDesc{}, streams: map[string]*StreamDesc{}}
+	for i := range sd.Methods {
+		svc.methods[sd.Methods[i].MethodName] = &sd.Methods[i]
+	}
+	for i := range sd.Streams {
+		svc.streams[sd.Streams[i].StreamName] = &sd.Streams[i]
+	}
+	s.services[sd.ServiceName] = svc
+}
+
+// Serve accepts plaintext HTTP/2 connections on ln until Shutdown
+func (s *Server) Serve(ln net.Listener) error {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	s.mu.Lock()
+	if s.http == nil {
+		s.http = &http.Server{Handler: s, Protocols: &protocols, ReadHeaderTimeout: 10 * time.Second}
+	}
+	hs := s.http
+	s.mu.Unlock()
+	return hs.Serve(ln)
+}
+
+// Shutdown stops accepting calls and waits for running ones, like
+// grpc-go's GracefulStop bounded by ctx. Open streams keep it waiting
+// until they end or ctx does.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	hs := s.http
+	s.mu.Unlock()
+	if hs == nil {
+		return nil
+	}
+	return hs.Shutdown(ctx)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Errors before the call is identified are HTTP errors: the peer
+	// may not be a gRPC client, and a grpc-status would mean nothing
+	switch {
+	case r.ProtoMajor != 2:
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	case r.Method != http.MethodPost:
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "gRPC requires POST", http.StatusMethodNotAllowed)
+		return
+	case !isGRPCContentType(r.Header.Get("Content-Type")):
+		http.Error(w, "content-type must be application/grpc", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	ctx := r.Context()
+	if t := r.Header.Get("Grpc-Timeout"); t != "" {
+		d, err := parseTimeout(t)
+		if err != nil {
+			writeStatus(w, &Status{Code: Internal, Message: err.Error()})
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	ctx = newIncomingContext(ctx, metadataFromHeader(r.Header))
+
+	ss := &serverStream{ctx: ctx, body: r.Body, w: w, rc: http.NewResponseController(w)}
+	ss.ctx = context.WithValue(ctx, streamKey{}, ss)
+	writeStatus(w, StatusOf(s.dispatch(ss, r.URL.Path)))
+}
+
+func (s *Server) dispatch(ss *serverStream, fullMethod string) error {
+	name, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok || !strings.HasPrefix(fullMethod, "/") {
+		return Errorf(Unimplemented, "malformed method name %q", fullMethod)
+	}
+	svc, ok := s.services[name]
+	if !ok {
+		return Errorf(Unimplemented, "unknown service %s", name)
+	}
+
+	if md, ok := svc.methods[method]; ok {
+		reply, err := md.Handler(svc.impl, ss.ctx, ss.recvOne, s.chainUnary(fullMethod, svc.impl))
+		if err != nil {
+			return err
+		}
+		return ss.SendMsg(reply)
+	}
+	if sd, ok := svc.streams[method]; ok {
+		info := &StreamServerInfo{FullMethod: fullMethod, IsClientStream: sd.ClientStreams, IsServerStream: sd.ServerStreams}
+		return s.chainStream(info, sd.Handler)(svc.impl, ss)
+	}
+	return Errorf(Unimplemented, "unknown method %s for service %s", method, name)
+}
+
+// chainUnary folds the interceptors into the one the stub handler
+// calls. Built per call so each knows the method it wraps.
+func (s *Server) chainUnary(fullMethod string, impl any) UnaryServerInterceptor {
+	if len(s.unary) == 0 {
+		return nil
+	}
+	info := &UnaryServerInfo{Server: impl, FullMethod: fullMethod}
+	return func(ctx context.Context, req any, _ *UnaryServerInfo, handler UnaryHandler) (any, error) {
+		for i := len(s.unary) - 1; i >= 0; i-- {
+			next, in := handler, s.unary[i]
+			handler = func(ctx context.Context, req any) (any, error) {
+				return in(ctx, req, info, next)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (s *Server) chainStream(info *StreamServerInfo, handler StreamHandler) StreamHandler {
+	for i := len(s.stream) - 1; i >= 0; i-- {
+		next, in := handler, s.stream[i]
+		handler = func(srv any, ss ServerStream) error { return in(srv, ss, info, next) }
+	}
+	return handler
+}
+
+// writeStatus ends the call with grpc-status and grpc-message
+// trailers, plus any trailer metadata the handler set. TrailerPrefix
+// declares them after the body has been written.
+func writeStatus(w http.ResponseWriter, st *Status) {
+	h := w.Header()
+	h.Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(st.Code)))
+	if st.Message != "" {
+		h.Set(http.TrailerPrefix+"Grpc-Message", encodeMessage(st.Message))
+	}
+}
+
+type streamKey struct{}
+
+// serverStream reads request messages from the body and writes each
+// reply as soon as it is sent
+type serverStream struct {
+	ctx  context.Context
+	body io.Reader
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+
+	mu sync.Mutex // guards trailer writes to the header map
+}
+
+func (ss *serverStream) Context() context.Context { return ss.ctx }
+
+func (ss *serverStream) SendMsg(m any) error {
+	if err := ss.ctx.Err(); err != nil {
+		return err
+	}
+	if err := writeMessage(ss.w, m); err != nil {
+		return err
+	}
+	return ss.rc.Flush()
+}
+
+func (ss *serverStream) RecvMsg(m any) error {
+	err := readMessage(ss.body, m)
+	if err != nil && ss.ctx.Err() != nil {
+		return ss.ctx.Err() // the body broke because the call ended
+	}
+	return err
+}
+
+// recvOne reads a unary request: exactly one message
+func (ss *serverStream) recvOne(m any) error {
+	if err := ss.RecvMsg(m); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Errorf(Internal, "grpc: unary request has no message")
+		}
+		return err
+	}
+	return nil
+}
+
+// SetTrailer adds metadata to the trailers of the call running in ctx.
+// The client reads it with the Trailer call option.
+func SetTrailer(ctx context.Context, md MD) error {
+	ss, ok := ctx.Value(streamKey{}).(*serverStream)
+	if !ok {
+		return errors.New("grpc: SetTrailer called outside a server handler")
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	h := ss.w.Header()
+	for k, v := range md {
+		k = strings.ToLower(k)
+		if reservedHeader(k) {
+			continue
+		}
+		for _, s := range v {
+			h.Add(http.TrailerPrefix+k, s)
+		}
+	}
+	return nil
+}
+
+// implements reports whether impl satisfies the interface that
+// handlerType points to, the check grpc-go makes at registration
+func implements(impl any, handlerType any) bool {
+	if handlerType == nil {
+		return true
+	}
+	iface := reflect.TypeOf(handlerType).Elem()
+	return impl != nil && reflect.TypeOf(impl).Implements(iface)
+}
@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Status Codes
+// ============
+// Every RPC ends with a status: a code from a fixed list of seventeen
+// and an optional message. It travels in the grpc-status and
+// grpc-message trailers after the last response message, so a stream
+// can send a hundred messages and still fail.
+//
+// The HTTP status is 200 even for failed calls. 404 and 500 only mean
+// the request never reached a gRPC server at all.
+
+// Code is a gRPC status code
+type Code uint32
+
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	OutOfRange         Code = 11
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	DataLoss           Code = 15
+	Unauthenticated    Code = 16
+)
+
+var codeNames = [...]string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded",
+	"NotFound", "AlreadyExists", "PermissionDenied", "ResourceExhausted",
+	"FailedPrecondition", "Aborted", "OutOfRange", "Unimplemented",
+	"Internal", "Unavailable", "DataLoss", "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return "Code(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
+
+// Status is an RPC's outcome. A non-OK *Status is also the error that
+// handlers return and clients receive.
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("rpc error: code = %s desc = %s", s.Code, s.Message)
+}
+
+// Errorf returns a *Status error. With code OK it returns nil, as
+// grpc-go's status.Errorf does.
+func Errorf(code Code, format string, args ...any) error {
+	if code == OK {
+		return nil
+	}
+	return &Status{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// StatusOf turns any error into a status: nil is OK, a wrapped *Status
+// is itself, context errors get their own codes and anything else is
+// Unknown - the code for a handler that forgot to choose one.
+func StatusOf(err error) *Status {
+	var st *Status
+	switch {
+	case err == nil:
+		return &Status{Code: OK}
+	case errors.As(err, &st):
+		return st
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Status{Code: DeadlineExceeded, Message: err.Error()}
+	case errors.Is(err, context.Canceled):
+		return &Status{Code: Canceled, Message: err.Error()}
+	}
+	return &Status{Code: Unknown, Message: err.Error()}
+}
+
+// CodeOf is StatusOf(err).Code
+func CodeOf(err error) Code { return StatusOf(err).Code }
+
+// encodeMessage percent-encodes grpc-message. Header values must be
+// printable ASCII, so anything else - including UTF-8 - becomes %XX,
+// and so does '%' itself.
+func encodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// decodeMessage reverses encodeMessage. A malformed escape is kept as
+// it is; a status message is worth more than strictness here.
+func decodeMessage(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
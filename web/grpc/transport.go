@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gRPC Over HTTP/2
+// ================
+// A call is one HTTP/2 POST. The path names the method, the body
+// carries the request messages and the response body the replies:
+//
+//	POST /greeter.v1.Greeter/SayHello
+//	content-type: application/grpc
+//	te: trailers
+//	grpc-timeout: 250m                 the caller's deadline, 250ms
+//	authorization: Bearer t0ken        metadata, as ordinary headers
+//
+//	  00 | 00 00 00 08 | 0a 06 67 6f 70 68 65 72      one message
+//
+//	200 OK, content-type: application/grpc
+//	  00 | 00 00 00 0f | 0a 0d 48 65 6c 6c 6f ...      the reply
+//	grpc-status: 0                     trailers, after the body
+//
+// Each message is prefixed by a compressed flag and a 4-byte
+// big-endian length - the body is a stream of messages, so a streaming
+// RPC is the same request with more of them. Trailers are why gRPC
+// needs HTTP/2: the status can only be known once the last message has
+// gone, and HTTP/1.1 clients and proxies rarely handle trailers.
+//
+// Everything above the transport - protobuf encoding, this framing,
+// deadlines, metadata, status trailers - is written by hand in this
+// package, as gRPC's wire format in plain Go; the HTTP/2 underneath is
+// net/http's own. Plaintext HTTP/2 ("h2c") needs no TLS. net/http speaks it once
+// Protocols enables UnencryptedHTTP2, on both the server and the
+// transport; production traffic would use TLS and ALPN instead.
+
+// maxMessage is grpc-go's default receive limit
+const maxMessage = 4 << 20
+
+// message is what the message types implement in place of
+// proto.Message
+type message interface {
+	AppendProto([]byte) []byte
+	UnmarshalProto([]byte) error
+}
+
+// writeMessage frames m onto w in one Write
+func writeMessage(w io.Writer, m any) error {
+	pm, ok := m.(message)
+	if !ok {
+		return Errorf(Internal, "grpc: %T is not a protobuf message", m)
+	}
+	b := make([]byte, 5, 64)
+	b = pm.AppendProto(b)
+	binary.BigEndian.PutUint32(b[1:5], uint32(len(b)-5))
+	_, err := w.Write(b)
+	return err
+}
+
+// readMessage reads one framed message into m. It returns io.EOF when
+// the body ends cleanly between messages.
+func readMessage(r io.Reader, m any) error {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Errorf(Internal, "grpc: truncated message header")
+		}
+		return err
+	}
+	if hdr[0] != 0 {
+		return Errorf(Unimplemented, "grpc: compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxMessage {
+		return Errorf(ResourceExhausted, "grpc: message of %d bytes exceeds the limit of %d", n, maxMessage)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return Errorf(Internal, "grpc: truncated message")
+		}
+		return err
+	}
+	pm, ok := m.(message)
+	if !ok {
+		return Errorf(Internal, "grpc: %T is not a protobuf message", m)
+	}
+	if err := pm.UnmarshalProto(data); err != nil {
+		return Errorf(Internal, "grpc: unmarshal %T: %v", m, err)
+	}
+	return nil
+}
+
+// isGRPCContentType accepts application/grpc and its +proto, +json...
+// and ;parameter variants
+func isGRPCContentType(ct string) bool {
+	rest, ok := strings.CutPrefix(ct, "application/grpc")
+	return ok && (rest == "" || rest[0] == '+' || rest[0] == ';')
+}
+
+// Timeouts
+// ========
+// grpc-timeout is at most eight digits and a unit: H, M, S, m, u or n.
+// The client sends the time left, not a wall-clock deadline, so clocks
+// need not agree; the server starts its own timer on arrival. Each hop
+// that forwards the context forwards what is left of it.
+
+var timeoutUnits = []struct {
+	unit byte
+	d    time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// encodeTimeout picks the finest unit that fits in eight digits,
+// rounding up so a tiny positive timeout never becomes zero
+func encodeTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "1n"
+	}
+	for _, u := range timeoutUnits {
+		v := (d + u.d - 1) / u.d
+		if v < 1e8 {
+			return strconv.FormatInt(int64(v), 10) + string(u.unit)
+		}
+	}
+	return "99999999H"
+}
+
+func parseTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, fmt.Errorf("grpc: bad timeout %q", s)
+	}
+	v, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("grpc: bad timeout %q", s)
+	}
+	for _, u := range timeoutUnits {
+		if u.unit == s[len(s)-1] {
+			if u.d == time.Hour && v > uint64(time.Duration(1<<63-1)/time.Hour) {
+				return 1<<63 - 1, nil
+			}
+			return time.Duration(v) * u.d, nil
+		}
+	}
+	return 0, fmt.Errorf("grpc: bad timeout unit in %q", s)
+}
@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Protocol Buffers on the Wire
+// ============================
+// A protobuf message is a sequence of fields, each a tag followed by a
+// value. The tag is a varint holding field_number<<3 | wire_type:
+//
+//	wire type 0  varint            int32, int64, bool, enums
+//	wire type 2  length-delimited  string, bytes, nested messages
+//	(1 and 5 are fixed 64- and 32-bit values)
+//
+// HelloRequest{name: "gopher"} is eight bytes:
+//
+//	0a           field 1, wire type 2
+//	06           length 6
+//	67 6f ...    "gopher"
+//
+// Fields at their zero value are not sent at all, and a reader skips
+// field numbers it does not know. That is how old and new versions of
+// a message interoperate - add fields, never renumber them.
+//
+// Real code uses google.golang.org/protobuf. This tree has no go.mod
+// for third-party modules, so the few helpers the lesson's messages
+// need are here, in the spirit of protowire.
+
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+var errBadProto = errors.New("grpc: malformed protobuf message")
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendStringField(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendInt32Field(b []byte, field int, v int32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	// Negative int32s are sign-extended to 64 bits: always ten bytes.
+	// That is why .proto files offer sint32 for values often negative.
+	return binary.AppendUvarint(b, uint64(int64(v)))
+}
+
+// field is one decoded field. For wireBytes, data is the payload; for
+// wireVarint, num is the value.
+type field struct {
+	num      int
+	wireType int
+	data     []byte
+	varint   uint64
+}
+
+// fields calls fn for each field in b, skipping none: fn decides what
+// to do with numbers it does not know (usually nothing)
+func fields(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errBadProto
+		}
+		b = b[n:]
+		f := field{num: int(tag >> 3), wireType: int(tag & 7)}
+		if f.num == 0 {
+			return errBadProto
+		}
+		switch f.wireType {
+		case wireVarint:
+			if f.varint, n = binary.Uvarint(b); n <= 0 {
+				return errBadProto
+			}
+			b = b[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errBadProto
+			}
+			f.data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case wireI64, wireI32:
+			size := 8
+			if f.wireType == wireI32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errBadProto
+			}
+			f.data = b[:size]
+			b = b[size:]
+		default:
+			return errBadProto // groups (3, 4) are long deprecated
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
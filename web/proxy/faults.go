@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault Injection
+// ===============
+// A proxy's error paths only run when a backend misbehaves, and real
+// backends misbehave rarely and unpredictably. FaultTransport wraps the
+// real transport and misbehaves on demand - slow, unreachable, failing
+// or cut off mid-body - so each path can be tested, and so a staging
+// proxy can rehearse an outage.
+
+// Fault describes how one round trip should fail. The zero Fault
+// passes the request through.
+type Fault struct {
+	Delay    time.Duration // wait before sending; cut short if the request is cancelled
+	Err      error         // fail the round trip with this error, as a refused connection would
+	Status   int           // answer with this status and an empty body, without sending
+	CutAfter int64         // end the body with io.ErrUnexpectedEOF after this many bytes
+}
+
+// FaultTransport injects the Fault that Inject chooses for each request
+type FaultTransport struct {
+	Base   http.RoundTripper
+	Inject func(*http.Request) Fault
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var f Fault
+	if t.Inject != nil {
+		f = t.Inject(req)
+	}
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-req.Context().Done():
+			return nil, context.Cause(req.Context())
+		}
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.Status != 0 {
+		return &http.Response{
+			Status:     http.StatusText(f.Status),
+			StatusCode: f.Status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil || f.CutAfter <= 0 {
+		return resp, err
+	}
+	resp.Body = &cutBody{resp.Body, f.CutAfter}
+	return resp, nil
+}
+
+// cutBody fails after n bytes, like a backend that crashed mid-response
+type cutBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *cutBody) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > b.n {
+		p = p[:b.n]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Reverse Proxies With httputil.ReverseProxy
+// ==========================================
+// A reverse proxy accepts a request, sends a copy to a backend, and
+// copies the response back. httputil.ReverseProxy does the copying -
+// including streaming bodies both ways and removing hop-by-hop headers
+// - and leaves four decisions to you:
+//
+//	Rewrite        where the request goes and which headers it carries
+//	Transport      how it gets there (pooling, timeouts, fault injection)
+//	ModifyResponse what to change on the way back
+//	ErrorHandler   what the client sees when the backend fails
+//
+// Use Rewrite, not the older Director. Rewrite's outgoing request
+// starts without the client's X-Forwarded-* headers, and SetXForwarded
+// adds trustworthy ones; Director passes the client's along, so anyone
+// can claim any X-Forwarded-For.
+
+// Route sends requests under Prefix to Backend
+type Route struct {
+	Prefix       string   // a ServeMux pattern ending in "/", such as "/api/"
+	Backend      *url.URL // scheme, host and an optional base path
+	StripPrefix  bool     // "/api/users" reaches the backend as "/users"
+	PreserveHost bool     // send the client's Host header instead of the backend's
+
+	// Timeout bounds the wait for the backend's response headers. The
+	// body may take longer, so streams are not cut.
+	Timeout time.Duration
+}
+
+// Options configure New
+type Options struct {
+	Transport http.RoundTripper // defaults to a clone of http.DefaultTransport
+	Logger    *slog.Logger      // defaults to slog.Default()
+}
+
+// ErrUpstreamTimeout is the error for a backend too slow to answer.
+// The proxy responds with 504 Gateway Timeout.
+var ErrUpstreamTimeout = errors.New("proxy: upstream timed out")
+
+// New returns a handler proxying each route to its backend. Requests
+// matching no route get 404.
+func New(routes []Route, opts Options) (http.Handler, error) {
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	mux := http.NewServeMux()
+	seen := map[string]bool{}
+	for _, rt := range routes {
+		if !strings.HasSuffix(rt.Prefix, "/") || rt.Backend == nil {
+			return nil, fmt.Errorf("proxy: route %q needs a prefix ending in / and a backend", rt.Prefix)
+		}
+		if seen[rt.Prefix] {
+			return nil, fmt.Errorf("proxy: duplicate route %q", rt.Prefix)
+		}
+		seen[rt.Prefix] = true
+		mux.Handle(rt.Prefix, newProxy(rt, opts))
+	}
+	return mux, nil
+}
+
+func newProxy(rt Route, opts Options) *httputil.ReverseProxy {
+	transport := opts.Transport
+	if rt.Timeout > 0 {
+		transport = headerTimeout{transport, rt.Timeout}
+	}
+	logger := opts.Logger.With("route", rt.Prefix, "backend", rt.Backend.Host)
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if rt.StripPrefix {
+				// Strip before SetURL, which joins the backend's base
+				// path with the outgoing path
+				p := strings.TrimPrefix(pr.In.URL.Path, strings.TrimSuffix(rt.Prefix, "/"))
+				pr.Out.URL.Path = "/" + strings.TrimPrefix(p, "/")
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(rt.Backend)
+			pr.SetXForwarded()
+			if rt.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+		},
+		Transport: transport,
+		ModifyResponse: func(resp *http.Response) error {
+			// Backends should not advertise their software, and
+			// X-Internal-* headers are for the services behind the proxy
+			resp.Header.Del("Server")
+			for name := range resp.Header {
+				if strings.HasPrefix(name, "X-Internal-") {
+					resp.Header.Del(name)
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if r.Context().Err() != nil {
+				// The client left; nobody is there to read a 502
+				logger.Debug("client gone", "path", r.URL.Path)
+				return
+			}
+			status := http.StatusBadGateway
+			var ne net.Error
+			if errors.Is(err, ErrUpstreamTimeout) || errors.As(err, &ne) && ne.Timeout() {
+				status = http.StatusGatewayTimeout
+			}
+			logger.Warn("upstream failed", "path", r.URL.Path, "status", status, "err", err)
+			http.Error(w, http.StatusText(status), status)
+		},
+	}
+}
+
+// headerTimeout fails a round trip whose response headers take longer
+// than d. Unlike a context deadline on the whole request, it stops
+// counting once the headers arrive, so a long body can keep flowing.
+type headerTimeout struct {
+	base http.RoundTripper
+	d    time.Duration
+}
+
+func (t headerTimeout) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(t.d, func() { cancel(ErrUpstreamTimeout) })
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() {
+		// The timer fired: whatever came back is too late
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel(nil)
+		return nil, ErrUpstreamTimeout
+	}
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+	// The context must outlive RoundTrip - cancelling it would abort the
+	// body - so release it when the body is closed
+	resp.Body = &cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelCauseFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
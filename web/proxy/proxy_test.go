@@ -0,0 +1,392 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Reverse Proxy - Tests
+// =====================
+// Run with:
+//
+//   cd web/proxy
+//   go test -v *.go
+//   go test -race *.go
+//
+// Backends are httptest servers that report what they received. The
+// failure tests put a FaultTransport under the proxy instead of
+// arranging for real backends to break.
+
+// seen is what an echo backend reports about the request it got
+type seen struct {
+	Backend string
+	Path    string
+	Query   string
+	Host    string
+	Header  http.Header
+}
+
+func echoBackend(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "backend/1.0")
+		w.Header().Set("X-Internal-Shard", "7")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(seen{name, r.URL.Path, r.URL.RawQuery, r.Host, r.Header})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func mustURL(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func newProxyServer(t *testing.T, routes []Route, opts Options) *httptest.Server {
+	t.Helper()
+	if opts.Logger == nil {
+		opts.Logger = slog.New(slog.DiscardHandler)
+	}
+	h, err := New(routes, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func get(t *testing.T, req *http.Request) (*http.Response, seen) {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var s seen
+	json.NewDecoder(resp.Body).Decode(&s)
+	return resp, s
+}
+
+// 1. Routing
+// ==========
+
+func TestRouting(t *testing.T) {
+	users := echoBackend(t, "users")
+	assets := echoBackend(t, "assets")
+	ts := newProxyServer(t, []Route{
+		{Prefix: "/api/users/", Backend: mustURL(users.URL + "/v2"), StripPrefix: true},
+		{Prefix: "/static/", Backend: mustURL(assets.URL)},
+	}, Options{})
+
+	tests := []struct {
+		target      string
+		backend     string
+		path, query string
+	}{
+		{"/api/users/42?fields=name", "users", "/v2/42", "fields=name"},
+		{"/api/users/", "users", "/v2/", ""},
+		{"/static/css/site.css", "assets", "/static/css/site.css", ""},
+	}
+	for _, tt := range tests {
+		resp, s := get(t, must(http.NewRequest("GET", ts.URL+tt.target, nil)))
+		if resp.StatusCode != 200 || s.Backend != tt.backend || s.Path != tt.path || s.Query != tt.query {
+			t.Errorf("%s -> %d %s %s?%s; want %s %s?%s", tt.target, resp.StatusCode, s.Backend, s.Path, s.Query, tt.backend, tt.path, tt.query)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/admin/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unrouted path: %d", resp.StatusCode)
+	}
+}
+
+func TestBadRoutes(t *testing.T) {
+	b := mustURL("http://localhost:1")
+	for _, routes := range [][]Route{
+		{{Prefix: "/api", Backend: b}},
+		{{Prefix: "/api/"}},
+		{{Prefix: "/api/", Backend: b}, {Prefix: "/api/", Backend: b}},
+	} {
+		if _, err := New(routes, Options{}); err == nil {
+			t.Errorf("New(%+v) accepted", routes)
+		}
+	}
+}
+
+// 2. Headers
+// ==========
+
+func TestRequestHeaders(t *testing.T) {
+	backend := echoBackend(t, "b")
+	ts := newProxyServer(t, []Route{
+		{Prefix: "/plain/", Backend: mustURL(backend.URL)},
+		{Prefix: "/keep-host/", Backend: mustURL(backend.URL), PreserveHost: true},
+	}, Options{})
+
+	req, _ := http.NewRequest("GET", ts.URL+"/plain/x", nil)
+	req.Host = "shop.example"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4") // a spoofing attempt
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "for the proxy only")
+	req.Header.Set("X-Trace", "abc")
+	_, s := get(t, req)
+
+	checks := []struct{ name, got, want string }{
+		{"X-Forwarded-For", s.Header.Get("X-Forwarded-For"), "127.0.0.1"},
+		{"X-Forwarded-Host", s.Header.Get("X-Forwarded-Host"), "shop.example"},
+		{"X-Forwarded-Proto", s.Header.Get("X-Forwarded-Proto"), "http"},
+		{"Host", s.Host, mustURL(backend.URL).Host},
+		{"X-Hop (named in Connection)", s.Header.Get("X-Hop"), ""},
+		{"X-Trace (end-to-end)", s.Header.Get("X-Trace"), "abc"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.name, c.got, c.want)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", ts.URL+"/keep-host/x", nil)
+	req.Host = "shop.example"
+	if _, s := get(t, req); s.Host != "shop.example" {
+		t.Errorf("PreserveHost: backend saw Host %q", s.Host)
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	backend := echoBackend(t, "b")
+	ts := newProxyServer(t, []Route{{Prefix: "/", Backend: mustURL(backend.URL)}}, Options{})
+	resp, _ := get(t, must(http.NewRequest("GET", ts.URL+"/", nil)))
+	if resp.Header.Get("Server") != "" || resp.Header.Get("X-Internal-Shard") != "" {
+		t.Errorf("leaked headers: %v", resp.Header)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+}
+
+// 3. Streaming
+// ============
+
+func TestStreamsResponses(t *testing.T) {
+	// The backend sends one event and waits. The client must get it
+	// before the backend finishes: the proxy does not buffer.
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first\n\n")
+		http.NewResponseController(w).Flush()
+		<-release
+		io.WriteString(w, "data: second\n\n")
+	}))
+	t.Cleanup(backend.Close)
+	t.Cleanup(func() { close(release) })
+	ts := newProxyServer(t, []Route{{Prefix: "/", Backend: mustURL(backend.URL), Timeout: 50 * time.Millisecond}}, Options{})
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	line := make(chan string, 1)
+	go func() {
+		s, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		line <- s
+	}()
+	select {
+	case s := <-line:
+		if s != "data: first\n" {
+			t.Errorf("first line %q", s)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("first event held back by the proxy")
+	}
+
+	// Still open well past the route's header timeout
+	time.Sleep(100 * time.Millisecond)
+	if err := resp.Body.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStreamsRequests(t *testing.T) {
+	// A 32 MiB upload passes through without being held in memory by
+	// the proxy; the backend counts what arrives
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		json.NewEncoder(w).Encode(n)
+	}))
+	t.Cleanup(backend.Close)
+	ts := newProxyServer(t, []Route{{Prefix: "/", Backend: mustURL(backend.URL)}}, Options{})
+
+	const size = 32 << 20
+	pr, pw := io.Pipe()
+	go func() {
+		chunk := bytes.Repeat([]byte("x"), 1<<20)
+		for range size >> 20 {
+			pw.Write(chunk)
+		}
+		pw.Close()
+	}()
+	resp, err := http.Post(ts.URL+"/upload", "application/octet-stream", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var n int64
+	json.NewDecoder(resp.Body).Decode(&n)
+	if n != size {
+		t.Errorf("backend received %d bytes, want %d", n, size)
+	}
+}
+
+// 4. Upstream Failures
+// ====================
+
+func TestFaults(t *testing.T) {
+	backend := echoBackend(t, "b")
+	var fault Fault
+	ft := &FaultTransport{Base: http.DefaultTransport, Inject: func(*http.Request) Fault { return fault }}
+	ts := newProxyServer(t, []Route{{Prefix: "/", Backend: mustURL(backend.URL), Timeout: 100 * time.Millisecond}}, Options{Transport: ft})
+
+	tests := []struct {
+		name   string
+		fault  Fault
+		status int
+	}{
+		{"healthy", Fault{}, http.StatusOK},
+		{"connection refused", Fault{Err: syscall.ECONNREFUSED}, http.StatusBadGateway},
+		{"backend overloaded", Fault{Status: http.StatusServiceUnavailable}, http.StatusServiceUnavailable},
+		{"slow but in time", Fault{Delay: 20 * time.Millisecond}, http.StatusOK},
+		{"too slow", Fault{Delay: time.Second}, http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fault = tt.fault
+			start := time.Now()
+			resp, err := http.Get(ts.URL + "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			// The timeout cut the one-second delay short
+			if time.Since(start) > 500*time.Millisecond {
+				t.Errorf("took %v", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestBackendDown(t *testing.T) {
+	backend := echoBackend(t, "b")
+	backend.Close()
+	var logs bytes.Buffer
+	ts := newProxyServer(t, []Route{{Prefix: "/", Backend: mustURL(backend.URL)}},
+		Options{Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || !strings.Contains(logs.String(), "upstream failed") {
+		t.Errorf("status %d, log %q", resp.StatusCode, logs.String())
+	}
+}
+
+func TestCutMidBody(t *testing.T) {
+	// Headers and part of the body are already sent when the backend
+	// fails: too late for a 502. ReverseProxy aborts the connection, so
+	// the client sees a truncated body instead of a short "success".
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10000")
+		w.Write(bytes.Repeat([]byte("y"), 10000))
+	}))
+	t.Cleanup(backend.Close)
+	ft := &FaultTransport{Base: http.DefaultTransport, Inject: func(*http.Request) Fault { return Fault{CutAfter: 4000} }}
+	ts := newProxyServer(t, []Route{{Prefix: "/", Backend: mustURL(backend.URL)}}, Options{Transport: ft})
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !errors.Is(err, io.ErrUnexpectedEOF) || len(body) >= 10000 {
+		t.Errorf("status %d, %d bytes, err %v", resp.StatusCode, len(body), err)
+	}
+}
+
+func TestClientCancelReachesBackend(t *testing.T) {
+	// When the client gives up, the backend's request context ends too,
+	// so it stops working on an answer nobody will read
+	cancelled := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	t.Cleanup(backend.Close)
+	ts := newProxyServer(t, []Route{{Prefix: "/", Backend: mustURL(backend.URL)}}, Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/slow", nil)
+	if _, err := http.DefaultClient.Do(req); err == nil {
+		t.Fatal("request succeeded")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("backend never saw the cancellation")
+	}
+}
+
+// Examples
+// ========
+
+func ExampleNew() {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "backend got %s from %s", r.URL.Path, r.Header.Get("X-Forwarded-For"))
+	}))
+	defer backend.Close()
+
+	u, _ := url.Parse(backend.URL)
+	h, _ := New([]Route{{Prefix: "/api/", Backend: u, StripPrefix: true}}, Options{})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/orders/7", nil))
+	fmt.Println(rec.Code, rec.Body.String())
+	// Output:
+	// 200 backend got /orders/7 from 192.0.2.1
+}
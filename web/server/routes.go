@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Routing With http.ServeMux
+// ==========================
+// Since Go 1.22 the standard mux understands methods and wildcards, so
+// most APIs need no router package:
+//
+//	"GET /todos/{id}"       method + path; r.PathValue("id")
+//	"POST /todos"           other methods on this path get 405 + Allow
+//	"GET /files/{path...}"  a final wildcard matching the rest of the path
+//	"GET /{$}"              exactly "/", not every path (plain "/" is a
+//	                        catch-all)
+//	"GET /todos/"           a trailing slash matches the whole subtree
+//
+// When two patterns match, the more specific one wins, whatever the
+// registration order: "/todos/new" beats "/todos/{id}". Two patterns
+// where neither is more specific (say "/todos/{id}" and "/{x}/new")
+// make Handle panic at registration, not at request time.
+//
+// GET patterns also match HEAD.
+
+// Todo is the resource served under /todos
+type Todo struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// Store is an in-memory todo list, safe for concurrent handlers
+type Store struct {
+	mu     sync.Mutex
+	todos  map[int]Todo
+	nextID int
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{todos: map[int]Todo{}, nextID: 1}
+}
+
+func (s *Store) add(title string) Todo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := Todo{ID: s.nextID, Title: title}
+	s.todos[t.ID] = t
+	s.nextID++
+	return t
+}
+
+func (s *Store) get(id int) (Todo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.todos[id]
+	return t, ok
+}
+
+func (s *Store) list() []Todo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Todo, 0, len(s.todos))
+	for _, t := range s.todos {
+		out = append(out, t)
+	}
+	slices.SortFunc(out, func(a, b Todo) int { return a.ID - b.ID })
+	return out
+}
+
+func (s *Store) setDone(id int, done bool) (Todo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.todos[id]
+	if ok {
+		t.Done = done
+		s.todos[t.ID] = t
+	}
+	return t, ok
+}
+
+func (s *Store) delete(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.todos[id]
+	delete(s.todos, id)
+	return ok
+}
+
+// New returns the API with its middleware: request IDs first, so every
+// log line and error has one, then logging, then panic recovery closest
+// to the handlers.
+func New(store *Store, logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "todo API: GET /todos")
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /todos", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, store.list())
+	})
+	mux.HandleFunc("POST /todos", func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Title string `json:"title"`
+		}
+		// Cap the body: a client can send gigabytes to any POST route
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, r, errStatus(err), err.Error())
+			return
+		}
+		if in.Title == "" {
+			writeError(w, r, http.StatusBadRequest, "title is required")
+			return
+		}
+		t := store.add(in.Title)
+		w.Header().Set("Location", fmt.Sprintf("/todos/%d", t.ID))
+		writeJSON(w, http.StatusCreated, t)
+	})
+
+	// More specific than "GET /todos/{id}", so it wins for this path
+	mux.HandleFunc("GET /todos/new", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `POST /todos with {"title": "..."}`)
+	})
+	mux.HandleFunc("GET /todos/{id}", withTodo(store, func(w http.ResponseWriter, r *http.Request, t Todo) {
+		writeJSON(w, http.StatusOK, t)
+	}))
+	mux.HandleFunc("PUT /todos/{id}/done", withTodo(store, func(w http.ResponseWriter, r *http.Request, t Todo) {
+		t, _ = store.setDone(t.ID, true)
+		writeJSON(w, http.StatusOK, t)
+	}))
+	mux.HandleFunc("DELETE /todos/{id}/done", withTodo(store, func(w http.ResponseWriter, r *http.Request, t Todo) {
+		t, _ = store.setDone(t.ID, false)
+		writeJSON(w, http.StatusOK, t)
+	}))
+	mux.HandleFunc("DELETE /todos/{id}", withTodo(store, func(w http.ResponseWriter, r *http.Request, t Todo) {
+		store.delete(t.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// {path...} takes the rest of the path, slashes included
+	mux.HandleFunc("GET /echo/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "path=%q\n", r.PathValue("path"))
+	})
+
+	// A deliberate bug, so the recovery middleware has work to do
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		var todos map[int]Todo
+		todos[1] = Todo{}
+	})
+
+	return Chain(mux, RequestID, Logging(logger), Recover(logger))
+}
+
+// withTodo parses the {id} wildcard and loads the todo, answering 400
+// or 404 itself so the handlers only see valid todos
+func withTodo(store *Store, h func(http.ResponseWriter, *http.Request, Todo)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "id must be a number")
+			return
+		}
+		t, ok := store.get(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "no such todo")
+			return
+		}
+		h(w, r, t)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends a JSON error that includes the request ID, so a user
+// reporting it gives support something to search the logs for
+func writeError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg, "request_id": RequestIDFrom(r.Context())})
+}
+
+// errStatus maps an error from a body read to a status
+func errStatus(err error) int {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Graceful Shutdown
+// =================
+// srv.Shutdown(ctx) stops accepting connections, closes idle ones, and
+// waits for active requests to finish - or for ctx to expire, in which
+// case it returns ctx's error and the caller can Close the rest.
+//
+// Two details catch people out:
+//   - Serve returns http.ErrServerClosed as soon as Shutdown begins,
+//     not when it ends. Returning from main at that point kills the
+//     requests Shutdown is waiting for; wait for Shutdown instead.
+//   - Shutdown does not cancel the contexts of running requests. Give
+//     the server a BaseContext derived from one you cancel, so long
+//     handlers notice and stop early.
+
+// Run serves h on ln until ctx is done, then shuts down. Requests still
+// running get up to grace to finish; after that their contexts are
+// cancelled and their connections closed.
+//
+// Run returns nil after a clean shutdown, or the error that stopped
+// the server.
+func Run(ctx context.Context, ln net.Listener, h http.Handler, grace time.Duration) error {
+	// Request contexts derive from baseCtx, which outlives ctx: a request
+	// in flight when shutdown begins keeps running during the grace
+	// period
+	baseCtx, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 5 * time.Second, // bounds slow-header clients (slowloris)
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ln) }()
+
+	select {
+	case err := <-serveErr:
+		// Serve failed on its own: the listener broke
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Grace is over: tell the stragglers to stop, and cut them off
+		cancelRequests()
+		err = errors.Join(err, srv.Close())
+	}
+	// Serve has returned ErrServerClosed by now; that is the expected
+	// result, not a failure
+	if serr := <-serveErr; !errors.Is(serr, http.ErrServerClosed) {
+		err = errors.Join(err, serr)
+	}
+	return err
+}
@@ -0,0 +1,400 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// HTTP Server - Tests
+// ===================
+// Run with:
+//
+//   cd web/server
+//   go test -v *.go
+//
+// Handlers are tested in-process with httptest.ResponseRecorder; the
+// full server, with real connections, with httptest.Server or Run on a
+// loopback listener.
+
+// newTestAPI returns the API with two todos and a logger writing JSON
+// lines into the returned buffer
+func newTestAPI() (http.Handler, *bytes.Buffer) {
+	var logs bytes.Buffer
+	store := NewStore()
+	store.add("write tests")
+	store.add("ship it")
+	return New(store, slog.New(slog.NewJSONHandler(&logs, nil))), &logs
+}
+
+func do(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+// 1. Routing
+// ==========
+
+func TestRouting(t *testing.T) {
+	h, _ := newTestAPI()
+	tests := []struct {
+		method, target string
+		status         int
+		body           string // a substring of the response
+	}{
+		{"GET", "/", 200, "todo API"},
+		{"GET", "/nope", 404, ""}, // "/{$}" matches only "/"
+		{"GET", "/healthz", 204, ""},
+		{"HEAD", "/healthz", 204, ""}, // GET patterns match HEAD
+		{"GET", "/todos", 200, `"title":"ship it"`},
+		{"GET", "/todos/1", 200, `"title":"write tests"`},
+		{"GET", "/todos/99", 404, "no such todo"},
+		{"GET", "/todos/abc", 400, "id must be a number"},
+		{"GET", "/todos/new", 200, "POST /todos"}, // the literal beats {id}
+		{"PATCH", "/todos/1", 405, ""},
+		{"GET", "/todos/1/extra", 404, ""},
+		{"GET", "/echo/a/b/c.txt", 200, `path="a/b/c.txt"`},
+		{"GET", "/echo/", 200, `path=""`},
+	}
+	for _, tt := range tests {
+		rec := do(h, tt.method, tt.target, "")
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s %s = %d %q, want %d containing %q", tt.method, tt.target, rec.Code, rec.Body.String(), tt.status, tt.body)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	// The mux knows every method registered for a path and lists them
+	h, _ := newTestAPI()
+	rec := do(h, "PATCH", "/todos/1", "")
+	if allow := rec.Header().Get("Allow"); allow != "DELETE, GET, HEAD" {
+		t.Errorf("Allow = %q", allow)
+	}
+}
+
+func TestConflictingPatterns(t *testing.T) {
+	// Neither pattern is more specific than the other: "/todos/new"
+	// matches both. The mux refuses at registration time.
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /todos/{id}", func(http.ResponseWriter, *http.Request) {})
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || !strings.Contains(err.Error(), "conflicts") {
+			t.Errorf("recover() = %v, want a conflict panic", err)
+		}
+	}()
+	mux.HandleFunc("GET /{collection}/new", func(http.ResponseWriter, *http.Request) {})
+}
+
+// 2. The API End to End
+// =====================
+
+func TestCRUD(t *testing.T) {
+	h, _ := newTestAPI()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	client := srv.Client()
+
+	resp, err := client.Post(srv.URL+"/todos", "application/json", strings.NewReader(`{"title":"learn ServeMux"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created Todo
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || created.ID != 3 || resp.Header.Get("Location") != "/todos/3" {
+		t.Fatalf("POST = %d %+v, Location %q", resp.StatusCode, created, resp.Header.Get("Location"))
+	}
+
+	req, _ := http.NewRequest("PUT", srv.URL+"/todos/3/done", nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var done Todo
+	json.NewDecoder(resp.Body).Decode(&done)
+	resp.Body.Close()
+	if !done.Done {
+		t.Errorf("PUT done = %+v", done)
+	}
+
+	req, _ = http.NewRequest("DELETE", srv.URL+"/todos/3", nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE = %d", resp.StatusCode)
+	}
+	if rec := do(h, "GET", "/todos/3", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE = %d", rec.Code)
+	}
+}
+
+func TestBadBodies(t *testing.T) {
+	h, _ := newTestAPI()
+	tests := []struct {
+		name, body string
+		status     int
+	}{
+		{"not JSON", "title=x", 400},
+		{"missing title", `{"done":true}`, 400},
+		{"too large", `{"title":"` + strings.Repeat("x", 2<<20) + `"}`, 413},
+	}
+	for _, tt := range tests {
+		rec := do(h, "POST", "/todos", tt.body)
+		var body map[string]string
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != tt.status || body["request_id"] == "" {
+			t.Errorf("%s: %d %v, want %d with a request_id", tt.name, rec.Code, body, tt.status)
+		}
+	}
+}
+
+// 3. Middleware
+// =============
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name+" in")
+				next.ServeHTTP(w, r)
+				order = append(order, name+" out")
+			})
+		}
+	}
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	}), mw("A"), mw("B"))
+	do(h, "GET", "/", "")
+	if got := strings.Join(order, ", "); got != "A in, B in, handler, B out, A out" {
+		t.Errorf("order = %s", got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+
+	rec := do(h, "GET", "/", "")
+	if len(seen) != 16 || rec.Header().Get("X-Request-ID") != seen {
+		t.Errorf("generated id %q, header %q", seen, rec.Header().Get("X-Request-ID"))
+	}
+
+	// A client's ID is kept, so one ID follows a request across services
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "upstream-42")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != "upstream-42" {
+		t.Errorf("forwarded id = %q", seen)
+	}
+
+	// ...unless it is unreasonable
+	req.Header.Set("X-Request-ID", strings.Repeat("x", 1000))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if len(seen) != 16 {
+		t.Errorf("oversized id was kept")
+	}
+
+	if id := RequestIDFrom(context.Background()); id != "" {
+		t.Errorf("no middleware: id = %q", id)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	h, logs := newTestAPI()
+	req := httptest.NewRequest("GET", "/todos/2", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line struct {
+		ID, Method, Path, Pattern string
+		Status, Bytes             int
+	}
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("log %q: %v", logs.String(), err)
+	}
+	// The pattern groups /todos/1, /todos/2, ... under one route, which
+	// is what metrics and dashboards want
+	if line.ID != "abc" || line.Pattern != "GET /todos/{id}" || line.Status != 200 || line.Bytes == 0 {
+		t.Errorf("log line = %+v", line)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	h, logs := newTestAPI()
+	rec := do(h, "GET", "/panic", "")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "internal error") {
+		t.Errorf("GET /panic = %d %q", rec.Code, rec.Body.String())
+	}
+	// Logged twice: the panic with its stack, then the 500 by Logging
+	if !strings.Contains(logs.String(), "assignment to entry in nil map") || !strings.Contains(logs.String(), `"status":500`) {
+		t.Errorf("logs = %s", logs.String())
+	}
+
+	// The server keeps serving
+	if rec := do(h, "GET", "/healthz", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("after a panic: %d", rec.Code)
+	}
+}
+
+func TestRecoverAfterHeaders(t *testing.T) {
+	// Once the status is sent it cannot be changed: the client gets a
+	// 200 with a truncated body. Panics late in a handler are worse.
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("late")
+	}))
+	rec := do(h, "GET", "/", "")
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d", rec.Code)
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := Recover(logger)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recover() = %v, want http.ErrAbortHandler passed on", err)
+		}
+	}()
+	do(h, "GET", "/", "")
+}
+
+func TestResponseControllerThroughWrapper(t *testing.T) {
+	// Flush reaches the recorder through statusRecorder.Unwrap
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+	}))
+	rec := do(h, "GET", "/", "")
+	if !rec.Flushed {
+		t.Error("response was not flushed")
+	}
+}
+
+// 4. Graceful Shutdown
+// ====================
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln
+}
+
+func TestRunGraceful(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "finished")
+	})
+
+	ln := listen(t)
+	url := "http://" + ln.Addr().String()
+	ctx, stop := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- Run(ctx, ln, h, 5*time.Second) }()
+
+	// A request is in flight when shutdown begins...
+	type result struct {
+		body string
+		err  error
+	}
+	got := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			got <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		got <- result{string(b), err}
+	}()
+	<-started
+	stop()
+
+	// ...new connections are refused...
+	time.Sleep(50 * time.Millisecond)
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err == nil {
+		t.Error("dial after shutdown began: want connection refused")
+	}
+	select {
+	case err := <-runErr:
+		t.Fatalf("Run returned %v while a request was active", err)
+	default:
+	}
+
+	// ...and the request still completes
+	close(release)
+	if r := <-got; r.err != nil || r.body != "finished" {
+		t.Errorf("in-flight request = %q, %v", r.body, r.err)
+	}
+	if err := <-runErr; err != nil {
+		t.Errorf("Run = %v, want nil", err)
+	}
+}
+
+func TestRunGraceExpires(t *testing.T) {
+	cancelled := make(chan struct{})
+	started := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done() // a handler that only stops when told to
+		close(cancelled)
+	})
+
+	ln := listen(t)
+	ctx, stop := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- Run(ctx, ln, h, 50*time.Millisecond) }()
+
+	go http.Get("http://" + ln.Addr().String())
+	<-started
+	stop()
+
+	if err := <-runErr; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run = %v, want context.DeadlineExceeded", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the handler's context was never cancelled")
+	}
+}
+
+func TestRunListenerFails(t *testing.T) {
+	ln := listen(t)
+	ln.Close()
+	if err := Run(context.Background(), ln, http.NotFoundHandler(), time.Second); err == nil || errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Run on a closed listener = %v", err)
+	}
+}
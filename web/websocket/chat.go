@@ -0,0 +1,183 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Echo and Chat
+// =============
+// Echo is the smallest useful endpoint: one goroutine reads a message
+// and writes it back.
+//
+// Chat needs two goroutines per connection, because a client must be
+// able to receive while it has nothing to say. The reader publishes
+// what the client sends to the hub; the writer sends everything the hub
+// publishes. They meet only at the hub and at Conn's write lock.
+//
+// The hub is the broker from web/events with the SSE parts removed: a
+// bounded history and a channel closed on each publish. A client that
+// falls so far behind that its next message has left the history is
+// disconnected rather than allowed to hold up the room.
+
+// Echo sends every message back to its sender
+func Echo() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			typ, msg, err := c.ReadMessage()
+			if err != nil {
+				return // includes the peer's *CloseError, already answered
+			}
+			if err := c.WriteMessage(typ, msg); err != nil {
+				return
+			}
+		}
+	})
+}
+
+type chatMessage struct {
+	id   uint64
+	text string
+}
+
+// Hub is a chat room. It is safe for concurrent use.
+type Hub struct {
+	mu      sync.Mutex
+	history []chatMessage // oldest first, at most max
+	max     int
+	lastID  uint64
+	changed chan struct{} // closed and replaced by each publish
+	done    chan struct{} // closed by Close
+	closed  bool
+}
+
+// NewHub returns a room that buffers up to history messages for slow
+// clients
+func NewHub(history int) *Hub {
+	return &Hub{max: max(history, 1), changed: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Publish sends text to everyone in the room
+func (h *Hub) Publish(text string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	if len(h.history) == h.max {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:len(h.history)-1]
+	}
+	h.history = append(h.history, chatMessage{h.lastID, text})
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// since returns the messages after id and a channel closed by the next
+// publish
+func (h *Hub) since(id uint64) ([]chatMessage, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i := slices.IndexFunc(h.history, func(m chatMessage) bool { return m.id > id })
+	if i < 0 {
+		return nil, h.changed
+	}
+	return slices.Clone(h.history[i:]), h.changed
+}
+
+func (h *Hub) last() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastID
+}
+
+// Close asks every client to leave
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+}
+
+// Chat joins clients to the hub as ?name=, pinging each every ping
+func Chat(h *Hub, ping time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		stopPings := c.KeepAlive(ping, 2*ping)
+		defer stopPings()
+
+		// Start from now: a newcomer sees its own join, not the backlog
+		last := h.last()
+		h.Publish(name + " joined")
+		defer h.Publish(name + " left")
+
+		readErr := make(chan error, 1)
+		go func() {
+			for {
+				typ, msg, err := c.ReadMessage()
+				if err != nil {
+					readErr <- err
+					return
+				}
+				if typ != TextMessage {
+					c.WriteClose(CloseUnsupportedData, "text only")
+					continue // until the peer's close arrives
+				}
+				h.Publish(name + ": " + string(msg))
+			}
+		}()
+
+		for {
+			msgs, changed := h.since(last)
+			if len(msgs) > 0 && msgs[0].id > last+1 {
+				c.WriteClose(ClosePolicyViolation, "too slow")
+				return
+			}
+			for _, m := range msgs {
+				if err := c.WriteMessage(TextMessage, []byte(m.text)); err != nil {
+					return
+				}
+				last = m.id
+			}
+
+			select {
+			case <-changed:
+			case <-readErr:
+				return
+			case <-h.done:
+				// Close properly: send our close, give the client a moment
+				// to answer, then drop the connection
+				c.WriteClose(CloseGoingAway, "server shutting down")
+				select {
+				case <-readErr:
+				case <-time.After(time.Second):
+				}
+				return
+			}
+		}
+	})
+}
+
+// IsClose reports whether err is the peer closing with one of codes,
+// or with any code if none are given
+func IsClose(err error, codes ...int) bool {
+	var ce *CloseError
+	return errors.As(err, &ce) && (len(codes) == 0 || slices.Contains(codes, ce.Code))
+}
@@ -0,0 +1,235 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Connections
+// ===========
+// A Conn allows one reader and any number of writers at a time.
+//
+// Reads happen in one goroutine by design: frames arrive in order and
+// a message is assembled from consecutive frames. ReadMessage also
+// answers control frames as they arrive - a ping with a pong, a close
+// with a close - so a connection nobody reads from never answers
+// pings, and keepalive will drop it.
+//
+// Writes come from several places: the handler's replies, the pongs
+// sent from inside ReadMessage, the keepalive pinger. Two goroutines
+// writing the same socket without coordination interleave their bytes
+// and corrupt both frames. Every write here builds the whole frame
+// first and sends it under a mutex, with a deadline, so a peer that
+// stops reading cannot hold the lock forever.
+
+var (
+	// ErrCloseSent is returned by writes after a close frame was sent
+	ErrCloseSent = errors.New("websocket: close sent")
+
+	// ErrMessageType is returned by WriteMessage for a type other than
+	// TextMessage and BinaryMessage; control frames have methods of their own
+	ErrMessageType = errors.New("websocket: not a data message type")
+
+	// ErrControlTooLong is returned by Ping for more than 125 bytes, which
+	// the peer would reject as a protocol error
+	ErrControlTooLong = errors.New("websocket: control payload over 125 bytes")
+)
+
+// Conn is a WebSocket connection
+type Conn struct {
+	nc     net.Conn
+	br     *bufio.Reader
+	client bool // clients mask what they send; servers require it
+
+	// ReadLimit caps the size of a message, all fragments together.
+	// A larger one fails ReadMessage with ErrTooLarge.
+	ReadLimit int64
+
+	// WriteTimeout bounds each write
+	WriteTimeout time.Duration
+
+	// OnPong, if set, is called by ReadMessage for each pong received
+	OnPong func(data []byte)
+
+	wmu       sync.Mutex
+	wbuf      []byte
+	closeSent bool
+}
+
+func newConn(nc net.Conn, br *bufio.Reader, client bool) *Conn {
+	return &Conn{nc: nc, br: br, client: client, ReadLimit: 1 << 20, WriteTimeout: 10 * time.Second}
+}
+
+// ReadMessage returns the next data message, answering pings and
+// closes on the way. When the peer closes, it returns a *CloseError.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ MessageType
+		msg []byte
+	)
+	for {
+		f, err := readFrame(c.br, !c.client, c.ReadLimit-int64(len(msg)))
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+		switch f.op {
+		case opPing:
+			if err := c.write(opPong, f.payload); err != nil && !errors.Is(err, ErrCloseSent) {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.OnPong != nil {
+				c.OnPong(f.payload)
+			}
+			continue
+		case opClose:
+			return 0, nil, c.closed(f.payload)
+		case opText, opBinary:
+			if typ != 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "new message before the last one finished"})
+			}
+			typ = MessageType(f.op)
+		case opContinuation:
+			if typ == 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "continuation without a message"})
+			}
+		default:
+			return 0, nil, c.fail(&protocolError{CloseProtocolError, "unknown opcode"})
+		}
+
+		msg = append(msg, f.payload...)
+		if !f.fin {
+			continue
+		}
+		if typ == TextMessage && !utf8.Valid(msg) {
+			return 0, nil, c.fail(&protocolError{CloseInvalidPayload, "text message is not UTF-8"})
+		}
+		if msg == nil {
+			msg = []byte{}
+		}
+		return typ, msg, nil
+	}
+}
+
+// fail tells the peer why the connection is ending, when that is the
+// peer's fault, and returns err
+func (c *Conn) fail(err error) error {
+	var pe *protocolError
+	switch {
+	case errors.As(err, &pe):
+		c.WriteClose(pe.code, pe.msg)
+	case errors.Is(err, ErrTooLarge):
+		c.WriteClose(CloseTooLarge, "message too large")
+	}
+	return err
+}
+
+// closed handles the peer's close frame: echo it, unless we started
+// the closing handshake, and report it
+func (c *Conn) closed(payload []byte) error {
+	ce := &CloseError{Code: CloseNoStatus}
+	switch {
+	case len(payload) == 1:
+		return c.fail(&protocolError{CloseProtocolError, "close frame of one byte"})
+	case len(payload) >= 2:
+		ce.Code = int(binary.BigEndian.Uint16(payload))
+		ce.Reason = string(payload[2:])
+		if !utf8.ValidString(ce.Reason) {
+			return c.fail(&protocolError{CloseInvalidPayload, "close reason is not UTF-8"})
+		}
+	}
+	c.WriteClose(ce.Code, "")
+	return ce
+}
+
+// WriteMessage sends a data message. It is safe to call concurrently
+// with other writes and with ReadMessage.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return ErrMessageType
+	}
+	return c.write(byte(typ), data)
+}
+
+// Ping sends a ping of at most 125 bytes; the peer's ReadMessage
+// answers with a pong
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return ErrControlTooLong
+	}
+	return c.write(opPing, data)
+}
+
+// WriteClose starts the closing handshake, or answers the peer's. The
+// peer replies with its own close frame, which ReadMessage returns as
+// a *CloseError; after that, Close the connection. Only the first call
+// sends anything.
+func (c *Conn) WriteClose(code int, reason string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+	return c.writeLocked(opClose, closePayload(code, reason))
+}
+
+func (c *Conn) write(op byte, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	return c.writeLocked(op, data)
+}
+
+func (c *Conn) writeLocked(op byte, data []byte) error {
+	c.wbuf = appendFrame(c.wbuf[:0], op, data, c.client)
+	if c.WriteTimeout > 0 {
+		c.nc.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+	}
+	_, err := c.nc.Write(c.wbuf)
+	return err
+}
+
+// SetReadDeadline sets the deadline for ReadMessage
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.nc.SetReadDeadline(t) }
+
+// Close closes the network connection without a closing handshake
+func (c *Conn) Close() error { return c.nc.Close() }
+
+// KeepAlive pings every period and fails ReadMessage if no pong has
+// arrived within wait, which should be longer than period. It replaces
+// OnPong, so call it before reading. Pings stop when stop is called or
+// a write fails.
+//
+// Without it a peer that vanished - a laptop lid closed, a phone out of
+// range - leaves a connection that looks open until TCP gives up, which
+// can take hours.
+func (c *Conn) KeepAlive(period, wait time.Duration) (stop func()) {
+	c.SetReadDeadline(time.Now().Add(wait))
+	c.OnPong = func([]byte) { c.SetReadDeadline(time.Now().Add(wait)) }
+
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(period)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if err := c.Ping(nil); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return sync.OnceFunc(func() { close(done) })
+}
@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Frames
+// ======
+// After the handshake both sides exchange frames (RFC 6455, section 5):
+//
+//	byte 0   FIN | RSV1-3 | opcode (4 bits)
+//	byte 1   MASK | payload length (7 bits: 0-125, or 126, or 127)
+//	         then 2 or 8 more length bytes for 126 and 127
+//	         then a 4-byte masking key if MASK is set
+//	         then the payload
+//
+// A message is one frame with FIN set, or a text or binary frame
+// followed by continuation frames, the last one with FIN set. Control
+// frames - close, ping, pong - have at most 125 bytes, are never
+// fragmented, and may arrive between the fragments of a message.
+//
+// Every frame a client sends is masked: its payload XORed with a
+// random 4-byte key. The mask is not for secrecy - the key travels with
+// the frame - but stops a malicious page from writing bytes that a
+// caching proxy on the path would mistake for an HTTP request. Servers
+// must reject unmasked frames and must not mask their own.
+
+// MessageType is the kind of a data message
+type MessageType int
+
+// The data message types, with their opcodes as values
+const (
+	TextMessage   MessageType = 1 // UTF-8 text
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	maxControlPayload = 125
+)
+
+// Close codes used by this package (RFC 6455, section 7.4.1)
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001 // server shutting down, page navigated away
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003 // e.g. binary sent to a text-only endpoint
+	CloseNoStatus        = 1005 // received a close frame without a code; never sent
+	CloseInvalidPayload  = 1007 // text that is not UTF-8
+	ClosePolicyViolation = 1008
+	CloseTooLarge        = 1009
+)
+
+// protocolError is a violation by the peer. ReadMessage answers it with
+// a close frame carrying code.
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string { return "websocket: " + e.msg }
+
+// ErrTooLarge is returned by ReadMessage for a message over ReadLimit
+var ErrTooLarge = errors.New("websocket: message exceeds read limit")
+
+type frame struct {
+	fin     bool
+	op      byte
+	payload []byte
+}
+
+// readFrame reads one frame, unmasking it. masked says whether the
+// peer must mask (it is a client); limit caps the payload.
+func readFrame(r io.Reader, masked bool, limit int64) (frame, error) {
+	var h [8]byte
+	if _, err := io.ReadFull(r, h[:2]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: h[0]&0x80 != 0, op: h[0] & 0x0F}
+	if h[0]&0x70 != 0 {
+		// RSV bits belong to extensions such as compression, and none
+		// was negotiated
+		return frame{}, &protocolError{CloseProtocolError, "reserved bits set"}
+	}
+	if (h[1]&0x80 != 0) != masked {
+		if masked {
+			return frame{}, &protocolError{CloseProtocolError, "client frame not masked"}
+		}
+		return frame{}, &protocolError{CloseProtocolError, "server frame masked"}
+	}
+
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		if _, err := io.ReadFull(r, h[:2]); err != nil {
+			return frame{}, err
+		}
+		n = uint64(binary.BigEndian.Uint16(h[:2]))
+	case 127:
+		if _, err := io.ReadFull(r, h[:8]); err != nil {
+			return frame{}, err
+		}
+		n = binary.BigEndian.Uint64(h[:8])
+		if n>>63 != 0 {
+			return frame{}, &protocolError{CloseProtocolError, "length has its top bit set"}
+		}
+	}
+	if f.op >= opClose && (n > maxControlPayload || !f.fin) {
+		return frame{}, &protocolError{CloseProtocolError, "control frame too long or fragmented"}
+	}
+	// Check the length before allocating: the header alone can claim
+	// 2^63 bytes
+	if n > uint64(max(limit, 0)) {
+		return frame{}, ErrTooLarge
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return frame{}, err
+		}
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+	if masked {
+		maskBytes(key, f.payload)
+	}
+	return f, nil
+}
+
+// appendFrame appends a complete frame to buf. mask is set for frames
+// a client sends; the payload is copied before masking, never changed.
+func appendFrame(buf []byte, op byte, payload []byte, mask bool) []byte {
+	buf = append(buf, 0x80|op) // FIN: this package sends whole messages
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if !mask {
+		return append(buf, payload...)
+	}
+	// The key must be unpredictable to the page that chose the payload
+	var key [4]byte
+	rand.Read(key[:])
+	buf = append(buf, key[:]...)
+	start := len(buf)
+	buf = append(buf, payload...)
+	maskBytes(key, buf[start:])
+	return buf
+}
+
+// maskBytes XORs b with the key in place; applying it twice undoes it
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// closePayload encodes a close frame's body: a 2-byte code, then a
+// UTF-8 reason, together at most 125 bytes
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatus {
+		return nil
+	}
+	if len(reason) > maxControlPayload-2 {
+		// Cut at a rune boundary, so the reason stays valid UTF-8
+		reason = reason[:maxControlPayload-2]
+		for !utf8.ValidString(reason) {
+			reason = reason[:len(reason)-1]
+		}
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+// CloseError is returned by ReadMessage when the peer closes the
+// connection
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
@@ -0,0 +1,193 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The Opening Handshake
+// =====================
+// A WebSocket starts as an HTTP/1.1 GET asking to switch protocols:
+//
+//	GET /chat HTTP/1.1
+//	Connection: Upgrade
+//	Upgrade: websocket
+//	Sec-WebSocket-Version: 13
+//	Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==
+//
+// The server proves it understood by hashing the key with a fixed GUID:
+//
+//	HTTP/1.1 101 Switching Protocols
+//	Connection: Upgrade
+//	Upgrade: websocket
+//	Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=
+//
+// After the 101 the connection no longer carries HTTP. The handler
+// takes the raw connection from net/http with Hijack and owns it from
+// then on: the server's timeouts no longer apply, and the server will
+// not close it on Shutdown - the handler must.
+//
+// Browsers send cookies with the handshake and do not apply the
+// same-origin policy to it, so any page could open a socket as the
+// logged-in user. Checking the Origin header is the defence.
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// acceptKey computes Sec-WebSocket-Accept for a Sec-WebSocket-Key
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Upgrade completes the handshake and returns the connection. On
+// failure it has already answered with an HTTP error.
+//
+// A request with an Origin header must come from a page on the same
+// host; requests without one come from programs, not browsers.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	fail := func(status int, msg string) (*Conn, error) {
+		http.Error(w, msg, status)
+		return nil, errors.New("websocket: " + msg)
+	}
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "handshake must be a GET")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return fail(http.StatusBadRequest, "bad Sec-WebSocket-Key")
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			return fail(http.StatusForbidden, "cross-origin websocket")
+		}
+	}
+
+	nc, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		// HTTP/2 connections cannot be hijacked
+		return fail(http.StatusInternalServerError, "cannot take over the connection")
+	}
+	// Deadlines set by the server's ReadTimeout and WriteTimeout stay on
+	// the connection after Hijack; they would cut the socket mid-chat
+	nc.SetDeadline(time.Time{})
+
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := brw.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	// brw.Reader may already hold frames the client sent right after
+	// its request; reading through it keeps them
+	return newConn(nc, brw.Reader, false), nil
+}
+
+// headerHasToken reports whether a comma-separated header contains
+// token, ignoring case: "Connection: keep-alive, Upgrade" counts
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for t := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Dial opens a client connection to a ws:// URL. (wss:// is the same
+// handshake over crypto/tls, left out to keep the lesson short.) ctx
+// bounds the dial and the handshake, not the connection.
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ws" {
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "80")
+	}
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	// Cancelling ctx during the handshake unblocks it by expiring the
+	// deadline
+	stop := context.AfterFunc(ctx, func() { nc.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	c, err := handshake(nc, u)
+	if err != nil {
+		nc.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if !stop() {
+		// ctx ended after the handshake but before stop: the deadline
+		// may be set
+		nc.Close()
+		return nil, ctx.Err()
+	}
+	return c, nil
+}
+
+func handshake(nc net.Conn, u *url.URL) (*Conn, error) {
+	var nonce [16]byte
+	rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{Path: u.Path, RawQuery: u.RawQuery},
+		Host:   u.Host,
+		Header: http.Header{
+			"Connection":            {"Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-WebSocket-Version": {"13"},
+			"Sec-WebSocket-Key":     {key},
+		},
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	if err := req.Write(nc); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("websocket: handshake answered %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, errors.New("websocket: bad Sec-WebSocket-Accept")
+	}
+	return newConn(nc, br, true), nil
+}
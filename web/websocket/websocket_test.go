@@ -0,0 +1,579 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// WebSocket Echo and Chat - Tests
+// ===============================
+// Run with:
+//
+//   cd web/websocket
+//   go test -v *.go
+//   go test -race *.go
+//
+// Handshake and lifecycle tests run against httptest.Server with Dial.
+// Protocol errors need frames a well-behaved client would never send,
+// so those tests write raw bytes over a loopback TCP pair.
+
+func wsURL(ts *httptest.Server) string { return "ws" + strings.TrimPrefix(ts.URL, "http") }
+
+func dial(t *testing.T, url string) *Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// tracked reports each return of h on the channel
+func tracked(h http.Handler) (http.Handler, <-chan struct{}) {
+	done := make(chan struct{}, 16)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { done <- struct{}{} }()
+		h.ServeHTTP(w, r)
+	}), done
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+// read returns the next text message, failing the test after 2s
+func read(t *testing.T, c *Conn) string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer c.SetReadDeadline(time.Time{})
+	_, msg, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	return string(msg)
+}
+
+// tcpPair returns a server-side Conn and the raw client end of a
+// loopback TCP connection
+func tcpPair(t *testing.T) (*Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(); server.Close() })
+	return newConn(server, bufio.NewReader(server), false), client
+}
+
+// 1. The Handshake
+// ================
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455, section 1.3
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("acceptKey = %q", got)
+	}
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	valid := http.Header{
+		"Connection":            {"keep-alive, Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+	}
+	with := func(name, value string) http.Header {
+		h := valid.Clone()
+		if value == "" {
+			h.Del(name)
+		} else {
+			h.Set(name, value)
+		}
+		return h
+	}
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		status int
+	}{
+		{"POST", "POST", valid, http.StatusMethodNotAllowed},
+		{"no Upgrade", "GET", with("Upgrade", ""), http.StatusBadRequest},
+		{"no Connection token", "GET", with("Connection", "keep-alive"), http.StatusBadRequest},
+		{"old version", "GET", with("Sec-WebSocket-Version", "8"), http.StatusUpgradeRequired},
+		{"short key", "GET", with("Sec-WebSocket-Key", "c2hvcnQ="), http.StatusBadRequest},
+		{"other origin", "GET", with("Origin", "https://evil.example"), http.StatusForbidden},
+		// Same origin passes the checks; a ResponseRecorder, like an
+		// HTTP/2 stream, cannot be hijacked
+		{"same origin", "GET", with("Origin", "http://example.com"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/ws", nil)
+			req.Header = tt.header
+			rec := httptest.NewRecorder()
+			if _, err := Upgrade(rec, req); err == nil || rec.Code != tt.status {
+				t.Errorf("status %d, err %v; want %d", rec.Code, err, tt.status)
+			}
+		})
+	}
+}
+
+func TestEcho(t *testing.T) {
+	ts := httptest.NewServer(Echo())
+	t.Cleanup(ts.Close)
+	c := dial(t, wsURL(ts))
+
+	for _, m := range []struct {
+		typ  MessageType
+		data string
+	}{
+		{TextMessage, "hello"},
+		{TextMessage, ""},
+		{BinaryMessage, "\x00\xff\xfe"},
+		{TextMessage, strings.Repeat("long ", 20_000)}, // 64-bit length
+	} {
+		if err := c.WriteMessage(m.typ, []byte(m.data)); err != nil {
+			t.Fatal(err)
+		}
+		typ, got, err := c.ReadMessage()
+		if err != nil || typ != m.typ || string(got) != m.data {
+			t.Errorf("echo of %d bytes: type %d, %d bytes, %v", len(m.data), typ, len(got), err)
+		}
+	}
+}
+
+func TestDialFailures(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(plain.Close)
+	if _, err := Dial(context.Background(), wsURL(plain)); err == nil || !strings.Contains(err.Error(), "200 OK") {
+		t.Errorf("plain HTTP server: %v", err)
+	}
+	if _, err := Dial(context.Background(), plain.URL); err == nil {
+		t.Error("http:// URL accepted")
+	}
+
+	// A server that accepts but never answers: ctx ends the handshake
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	t.Cleanup(func() { ln.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Dial(ctx, "ws://"+ln.Addr().String()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("silent server: %v", err)
+	}
+}
+
+func TestHijackClearsServerTimeouts(t *testing.T) {
+	// The server's timeouts would otherwise cut the socket
+	ts := httptest.NewUnstartedServer(Echo())
+	ts.Config.ReadTimeout = 50 * time.Millisecond
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	c := dial(t, wsURL(ts))
+	time.Sleep(150 * time.Millisecond)
+	c.WriteMessage(TextMessage, []byte("still open"))
+	if got := read(t, c); got != "still open" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// 2. Frames
+// =========
+
+func TestFrameLengths(t *testing.T) {
+	tests := []struct {
+		size   int
+		header int // bytes before the payload, unmasked
+	}{
+		{0, 2}, {125, 2}, {126, 4}, {65535, 4}, {65536, 10},
+	}
+	for _, tt := range tests {
+		payload := []byte(strings.Repeat("x", tt.size))
+		for _, mask := range []bool{false, true} {
+			buf := appendFrame(nil, opBinary, payload, mask)
+			want := tt.header + tt.size
+			if mask {
+				want += 4
+			}
+			if len(buf) != want {
+				t.Errorf("size %d, mask %v: frame is %d bytes, want %d", tt.size, mask, len(buf), want)
+			}
+			f, err := readFrame(strings.NewReader(string(buf)), mask, 1<<20)
+			if err != nil || !f.fin || f.op != opBinary || string(f.payload) != string(payload) {
+				t.Errorf("size %d, mask %v: read back %d bytes, %v", tt.size, mask, len(f.payload), err)
+			}
+		}
+	}
+
+	// The frame holds a masked copy; the caller's slice is untouched
+	b := []byte("abc")
+	appendFrame(nil, opText, b, true)
+	if string(b) != "abc" {
+		t.Errorf("masking changed the caller's slice to %q", b)
+	}
+}
+
+// rawFrame builds a client frame, then lets fix adjust its first byte
+func rawFrame(op byte, payload string, fix func(b0 byte) byte) []byte {
+	buf := appendFrame(nil, op, []byte(payload), true)
+	if fix != nil {
+		buf[0] = fix(buf[0])
+	}
+	return buf
+}
+
+// closeCodeFrom reads the close frame the server sent to client
+func closeCodeFrom(t *testing.T, client net.Conn) int {
+	t.Helper()
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	f, err := readFrame(client, false, maxControlPayload)
+	if err != nil || f.op != opClose || len(f.payload) < 2 {
+		t.Fatalf("expected a close frame, got op %x %q, %v", f.op, f.payload, err)
+	}
+	return int(binary.BigEndian.Uint16(f.payload))
+}
+
+func TestProtocolErrors(t *testing.T) {
+	notFin := func(b byte) byte { return b &^ 0x80 }
+	tests := []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		{"unmasked", appendFrame(nil, opText, []byte("hi"), false), CloseProtocolError},
+		{"reserved bit", rawFrame(opText, "hi", func(b byte) byte { return b | 0x40 }), CloseProtocolError},
+		{"unknown opcode", rawFrame(0x3, "hi", nil), CloseProtocolError},
+		{"long ping", rawFrame(opPing, strings.Repeat("p", 126), nil), CloseProtocolError},
+		{"fragmented ping", rawFrame(opPing, "p", notFin), CloseProtocolError},
+		{"stray continuation", rawFrame(opContinuation, "hi", nil), CloseProtocolError},
+		{"interrupted message", append(rawFrame(opText, "a", notFin), rawFrame(opText, "b", nil)...), CloseProtocolError},
+		{"bad UTF-8", rawFrame(opText, "caf\xe9", nil), CloseInvalidPayload},
+		{"too large", rawFrame(opBinary, strings.Repeat("x", 11), nil), CloseTooLarge},
+		{"too large in fragments", append(rawFrame(opBinary, "123456", notFin), rawFrame(opContinuation, "789012", nil)...), CloseTooLarge},
+		{"one-byte close", rawFrame(opClose, "x", nil), CloseProtocolError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := tcpPair(t)
+			server.ReadLimit = 10
+			client.Write(tt.frame)
+			if _, _, err := server.ReadMessage(); err == nil {
+				t.Fatal("ReadMessage succeeded")
+			}
+			if code := closeCodeFrom(t, client); code != tt.code {
+				t.Errorf("close code %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestFragmentsAndInterleavedPing(t *testing.T) {
+	server, client := tcpPair(t)
+	notFin := func(b byte) byte { return b &^ 0x80 }
+	var stream []byte
+	stream = append(stream, rawFrame(opText, "Hel", notFin)...)
+	stream = append(stream, rawFrame(opPing, "are you there", nil)...)
+	stream = append(stream, rawFrame(opContinuation, "lo, ", notFin)...)
+	stream = append(stream, rawFrame(opContinuation, "world", nil)...)
+	client.Write(stream)
+
+	typ, msg, err := server.ReadMessage()
+	if err != nil || typ != TextMessage || string(msg) != "Hello, world" {
+		t.Fatalf("ReadMessage = %d %q, %v", typ, msg, err)
+	}
+	// The ping in the middle was answered while the message was read
+	f, err := readFrame(client, false, 125)
+	if err != nil || f.op != opPong || string(f.payload) != "are you there" {
+		t.Errorf("reply = op %x %q, %v", f.op, f.payload, err)
+	}
+}
+
+// Control frames are not messages, and a ping over 125 bytes is one
+// our own readFrame would refuse: neither leaves this end
+func TestWriteRejects(t *testing.T) {
+	server, client := tcpPair(t)
+	if err := server.Ping(make([]byte, maxControlPayload+1)); !errors.Is(err, ErrControlTooLong) {
+		t.Errorf("Ping(126 bytes) = %v", err)
+	}
+	for _, typ := range []MessageType{0, MessageType(opClose), MessageType(opPing), MessageType(opPong)} {
+		if err := server.WriteMessage(typ, []byte("x")); !errors.Is(err, ErrMessageType) {
+			t.Errorf("WriteMessage(%d) = %v", typ, err)
+		}
+	}
+	// The first frame on the wire is the ping that fits
+	if err := server.Ping(make([]byte, maxControlPayload)); err != nil {
+		t.Fatal(err)
+	}
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	f, err := readFrame(client, false, 1<<10)
+	if err != nil || f.op != opPing || len(f.payload) != maxControlPayload {
+		t.Errorf("first frame: op %d, %d bytes, %v", f.op, len(f.payload), err)
+	}
+}
+
+// 3. Closing
+// ==========
+
+func TestCloseHandshake(t *testing.T) {
+	h, returned := tracked(Echo())
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	c := dial(t, wsURL(ts))
+
+	// We start the handshake; the server echoes our code and its
+	// handler ends. Only then do we close the TCP connection.
+	if err := c.WriteClose(CloseNormal, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := c.ReadMessage()
+	if !IsClose(err, CloseNormal) {
+		t.Errorf("after our close: %v", err)
+	}
+	waitFor(t, returned, "the handler to return")
+
+	if err := c.WriteMessage(TextMessage, []byte("late")); !errors.Is(err, ErrCloseSent) {
+		t.Errorf("write after close: %v", err)
+	}
+}
+
+func TestCloseReasonStaysUTF8(t *testing.T) {
+	p := closePayload(CloseGoingAway, strings.Repeat("é", 100))
+	if len(p) > maxControlPayload || !strings.HasSuffix(string(p), "é") {
+		t.Errorf("close payload %d bytes, ends %q", len(p), p[len(p)-2:])
+	}
+}
+
+// 4. Concurrent Writes
+// ====================
+
+func TestConcurrentWrites(t *testing.T) {
+	// Run with -race. Without the write lock frames from different
+	// goroutines interleave and the echo server sees garbage.
+	ts := httptest.NewServer(Echo())
+	t.Cleanup(ts.Close)
+	c := dial(t, wsURL(ts))
+
+	const writers, each = 8, 50
+	go func() {
+		var wg sync.WaitGroup
+		for w := range writers {
+			wg.Go(func() {
+				for i := range each {
+					msg := fmt.Sprintf("%d:%d:%s", w, i, strings.Repeat(string(rune('a'+w)), 500+i))
+					if err := c.WriteMessage(TextMessage, []byte(msg)); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			})
+		}
+		wg.Wait()
+	}()
+
+	next := make([]int, writers)
+	for range writers * each {
+		var w, i int
+		var body string
+		msg := read(t, c)
+		if _, err := fmt.Sscanf(msg, "%d:%d:%s", &w, &i, &body); err != nil ||
+			body != strings.Repeat(string(rune('a'+w)), 500+i) {
+			t.Fatalf("corrupt message %.40q", msg)
+		}
+		// Each writer's messages stay in its own order
+		if i != next[w] {
+			t.Fatalf("writer %d: message %d, want %d", w, i, next[w])
+		}
+		next[w]++
+	}
+}
+
+// 5. Keepalive
+// ============
+
+// keepAliveServer pings every 20ms and gives up after 60ms without a
+// pong. It reports how the connection ended and how many pongs came.
+func keepAliveServer(t *testing.T) (*httptest.Server, <-chan error, *atomic.Int64) {
+	ended := make(chan error, 1)
+	var pongs atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		defer c.KeepAlive(20*time.Millisecond, 60*time.Millisecond)()
+		onPong := c.OnPong
+		c.OnPong = func(b []byte) { pongs.Add(1); onPong(b) }
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				ended <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, ended, &pongs
+}
+
+func TestKeepAliveDropsSilentPeer(t *testing.T) {
+	// A client that never reads never answers pings - as a vanished
+	// laptop would not
+	ts, ended, _ := keepAliveServer(t)
+	dial(t, wsURL(ts))
+	select {
+	case err := <-ended:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("ended with %v, want a deadline error", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("silent peer was not dropped")
+	}
+}
+
+func TestKeepAliveKeepsLivePeer(t *testing.T) {
+	ts, ended, pongs := keepAliveServer(t)
+	c := dial(t, wsURL(ts))
+	// Reading is what answers pings
+	go c.ReadMessage()
+
+	select {
+	case err := <-ended:
+		t.Fatalf("live peer dropped: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if n := pongs.Load(); n < 5 {
+		t.Errorf("%d pongs in 300ms, want about 15", n)
+	}
+}
+
+// 6. Chat
+// =======
+
+func TestChat(t *testing.T) {
+	hub := NewHub(100)
+	ts := httptest.NewServer(Chat(hub, time.Minute))
+	t.Cleanup(ts.Close)
+
+	alice := dial(t, wsURL(ts)+"?name=alice")
+	if got := read(t, alice); got != "alice joined" {
+		t.Fatalf("alice sees %q first", got)
+	}
+	bob := dial(t, wsURL(ts)+"?name=bob")
+	for _, c := range []*Conn{alice, bob} {
+		if got := read(t, c); got != "bob joined" {
+			t.Errorf("got %q, want bob joined", got)
+		}
+	}
+
+	alice.WriteMessage(TextMessage, []byte("hi bob"))
+	bob.WriteMessage(TextMessage, []byte("hi alice"))
+	for _, c := range []*Conn{alice, bob} {
+		// Both see the same order, whichever it is
+		first, second := read(t, c), read(t, c)
+		if first+"|"+second != "alice: hi bob|bob: hi alice" && first+"|"+second != "bob: hi alice|alice: hi bob" {
+			t.Errorf("got %q, %q", first, second)
+		}
+	}
+
+	bob.WriteClose(CloseNormal, "")
+	if got := read(t, alice); got != "bob left" {
+		t.Errorf("after bob closed: %q", got)
+	}
+}
+
+func TestChatTextOnly(t *testing.T) {
+	ts := httptest.NewServer(Chat(NewHub(10), time.Minute))
+	t.Cleanup(ts.Close)
+	c := dial(t, wsURL(ts)+"?name=carol")
+	read(t, c) // carol joined
+
+	c.WriteMessage(BinaryMessage, []byte{0xCA, 0xFE})
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := c.ReadMessage(); !IsClose(err, CloseUnsupportedData) {
+		t.Errorf("after binary: %v", err)
+	}
+}
+
+func TestChatShutdown(t *testing.T) {
+	hub := NewHub(10)
+	h, returned := tracked(Chat(hub, time.Minute))
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	c := dial(t, wsURL(ts)+"?name=dave")
+	read(t, c)
+
+	// Hijacked connections are invisible to srv.Shutdown: the hub must
+	// end them. The client sees a clean close, not a reset.
+	hub.Close()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := c.ReadMessage(); !IsClose(err, CloseGoingAway) {
+		t.Errorf("on shutdown: %v", err)
+	}
+	waitFor(t, returned, "the handler to return")
+}
+
+func TestChatNeedsName(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Chat(NewHub(1), time.Minute).ServeHTTP(rec, httptest.NewRequest("GET", "/chat", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d", rec.Code)
+	}
+}
+
+// Examples
+// ========
+
+func ExampleDial() {
+	ts := httptest.NewServer(Echo())
+	defer ts.Close()
+
+	c, err := Dial(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer c.Close()
+
+	c.WriteMessage(TextMessage, []byte("ping?"))
+	_, msg, _ := c.ReadMessage()
+	fmt.Println(string(msg))
+
+	c.WriteClose(CloseNormal, "done")
+	_, _, err = c.ReadMessage()
+	fmt.Println(err)
+	// Output:
+	// ping?
+	// websocket: closed with code 1000
+}